@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/docs"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/fixtures"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/masking"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+)
+
+/**
+ * The fixtures command writes the example responses of the API, as ROLE_USER and ROLE_ADMIN see them,
+ * for the contract tests of the frontends. The consumer listing policy and the data masking are read from the
+ * environment like the server does, e.g. DATA_MASKING_ENABLED=TRUE writes the responses of a staging environment.
+ */
+
+func init() {
+	logger.Init()
+}
+
+func main() {
+	out := flag.String("out", "docs/fixtures", "Directory the fixtures are written to, one subdirectory per role")
+	flag.Parse()
+
+	gin.SetMode(gin.ReleaseMode)
+	if !masking.Init() {
+		logger.Fatal("Failed to initialize the data masking", nil)
+	}
+
+	generated, err := fixtures.Generate(docs.OpenAPI, policyconfig.LoadConsumerListingPolicy(), pagination.LoadConfig())
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to generate the fixtures: %v", err), nil)
+	}
+	if err := fixtures.Write(*out, generated); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to write the fixtures: %v", err), nil)
+	}
+
+	fmt.Printf("Wrote %d fixtures to %s\n", len(generated), *out)
+}
@@ -2,7 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,9 +14,22 @@ import (
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 
+	appconfig "github.com/yoanesber/go-jwt-auth-demo/config/app-config"
 	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/anomaly"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/basepath"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/cache"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/captcha"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/certreload"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
 	"github.com/yoanesber/go-jwt-auth-demo/pkg/diagnostics"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/fieldcrypt"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/geoip"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/localeformat"
 	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/masking"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/readiness"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/tracing"
 	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
 	"github.com/yoanesber/go-jwt-auth-demo/routes"
 )
@@ -20,6 +37,9 @@ import (
 var (
 	validatorInitialized bool
 	dbInitialized        bool
+
+	// stopTracing flushes the pending spans on shutdown, once tracing.Init has set it up
+	stopTracing = func(context.Context) error { return nil }
 )
 
 func init() {
@@ -27,76 +47,167 @@ func init() {
 }
 
 func main() {
+	// Parse command line flags
+	validateOnly := flag.Bool("validate-config", false, "Validate the configuration and exit without starting the server")
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML file with the settings not set in the environment")
+	flag.Parse()
+
+	// Run the migrations of the database instead of the server, e.g. main migrate up
+	if flag.Arg(0) == "migrate" {
+		runMigrate(*configFile, flag.Args()[1:])
+		return
+	}
+
+	// Load and validate the configuration before starting anything
+	// All problems are reported at once so they can be fixed in a single pass
+	cfg := loadConfiguration(*configFile, *validateOnly)
+
 	// Create base context with cancel for graceful shutdown
-	_, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Get environment variables
-	env := os.Getenv("ENV")
-	port := os.Getenv("PORT")
-	isSSL := os.Getenv("IS_SSL")
-	apiVersion := os.Getenv("API_VERSION")
-	sslKeys := os.Getenv("SSL_KEYS")
-	sslCert := os.Getenv("SSL_CERT")
-
-	if env == "" || port == "" || isSSL == "" || apiVersion == "" || sslKeys == "" || sslCert == "" {
+	// Check the settings of the server
+	server := cfg.Server
+	if server.Env == "" || server.Port == "" || server.APIVersion == "" || server.SSLKeys == "" || server.SSLCert == "" {
 		logger.Panic("One or more required environment variables are not set", log.Fields{
-			"ENV":         env,
-			"PORT":        port,
-			"IS_SSL":      isSSL,
-			"API_VERSION": apiVersion,
-			"SSL_KEYS":    sslKeys,
-			"SSL_CERT":    sslCert,
+			"ENV":         server.Env,
+			"PORT":        server.Port,
+			"IS_SSL":      server.SSL,
+			"API_VERSION": server.APIVersion,
+			"SSL_KEYS":    server.SSLKeys,
+			"SSL_CERT":    server.SSLCert,
 		})
 		return
 	}
 
 	// Set Gin mode
 	gin.SetMode(gin.DebugMode)
-	if env == "PRODUCTION" {
+	if server.Env == "PRODUCTION" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// Setup router
-	r := routes.SetupRouter()
-	r.SetTrustedProxies(nil) // Set trusted proxies to nil to avoid issues with forwarded headers
+	// Initialize the anomaly detector for authentication traffic, the GeoIP locator used to annotate the logins
+	// with the location of the clients, and the verifier of the CAPTCHA tokens of the challenged clients,
+	// before the routes using them are set up
+	anomaly.InitWithConfig(cfg.Anomaly, clock.New())
+	geoip.InitWithConfig(cfg.GeoIP)
+	captcha.InitWithConfig(cfg.Captcha)
+
+	// Trace the requests with OpenTelemetry when TRACING_ENABLED is TRUE, before the routes set up the tracing middleware
+	shutdownTracing, err := tracing.Init(ctx, cfg.Tracing)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to set up the tracing: %v", err), nil)
+	}
+	stopTracing = shutdownTracing
 
-	// Log memory stats before initialization
-	diagnostics.LogMemoryStats("Before initialization")
+	// Setup router
+	// The trusted proxies are configured by the router with TRUSTED_PROXIES
+	r := routes.SetupRouterWithConfig(cfg)
 
 	// Init all dependencies
-	initializeDependencies()
+	initializeDependencies(cfg)
+
+	// Complete the setup of the routes now that the database is initialized
+	if err := routes.Startup(); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to start the routes: %v", err), nil)
+	}
 
-	// Log memory stats after initialization
-	diagnostics.LogMemoryStats("After initialization")
+	// Log a single structured event describing the started service, for the fleet-wide inventory
+	diagnostics.LogServiceStarted(diagnostics.CollectStartupInfo(routes.EnabledFeatures(cfg)))
+
+	// The server is shut down gracefully, the request contexts derive from the base context
+	srv := &http.Server{
+		Addr:        ":" + server.Port,
+		Handler:     basepath.Handler(r),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
 
 	// Graceful shutdown
-	gracefulShutdown(cancel)
+	done := gracefulShutdown(srv, cancel)
+
+	// Warm up while the server starts accepting connections, /readyz reports ready once it is completed
+	go func() {
+		if err := routes.WarmUp(ctx); err != nil {
+			logger.Warn(fmt.Sprintf("Warm-up interrupted: %v", err), nil)
+		}
+	}()
 
 	// Start the server
-	var err error
-	if isSSL == "TRUE" {
+	if server.SSL {
 		//Generated using sh generate-certificate.sh
-		err = r.RunTLS(":"+port, sslCert, sslKeys)
+		// The certificate is served from memory and reloaded when its files change or on SIGHUP, without restarting the listener
+		reloader, loadErr := certreload.New(server.SSLCert, server.SSLKeys)
+		if loadErr != nil {
+			logger.Fatal(fmt.Sprintf("Failed to load the TLS certificate: %v", loadErr), nil)
+		}
+		reloader.Watch(certreload.LoadInterval())
+		defer reloader.Close()
+		reloadOnHangup(reloader)
+
+		srv.TLSConfig = reloader.TLSConfig()
+		err = srv.ListenAndServeTLS("", "")
 
 	} else {
-		err = r.Run(":" + port)
+		err = srv.ListenAndServe()
+	}
+
+	// The server is closed by the graceful shutdown, wait for it to complete
+	if errors.Is(err, http.ErrServerClosed) {
+		<-done
+		return
 	}
 
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to start server with SSL: %v", err), log.Fields{
-			"environment": env,
-			"port":        port,
-			"is_ssl":      isSSL,
-			"api_version": apiVersion,
-			"ssl_cert":    sslCert,
-			"ssl_keys":    sslKeys,
+			"environment": server.Env,
+			"port":        server.Port,
+			"is_ssl":      server.SSL,
+			"api_version": server.APIVersion,
+			"ssl_cert":    server.SSLCert,
+			"ssl_keys":    server.SSLKeys,
 		})
 		return
 	}
 }
 
-func initializeDependencies() {
+// loadConfiguration loads the configuration file, if any, runs the configuration validation pass and reports all problems found,
+// then returns the settings. If validateOnly is true, the process exits after the validation without starting the server.
+func loadConfiguration(path string, validateOnly bool) appconfig.Config {
+	cfg, err := appconfig.Load(path, true)
+
+	var verr *appconfig.ValidationError
+	if err != nil && !errors.As(err, &verr) {
+		if validateOnly {
+			fmt.Printf("Configuration is invalid: %v\n", err)
+			os.Exit(1)
+		}
+		logger.Fatal(fmt.Sprintf("Failed to load the configuration: %v", err), nil)
+	}
+
+	if validateOnly {
+		if verr == nil {
+			fmt.Println("Configuration is valid")
+			os.Exit(0)
+		}
+
+		fmt.Printf("Configuration is invalid, found %d problem(s):\n", len(verr.Problems))
+		for _, problem := range verr.Problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+		os.Exit(1)
+	}
+
+	if verr != nil {
+		for _, problem := range verr.Problems {
+			logger.Error(fmt.Sprintf("Configuration problem: %s", problem), nil)
+		}
+		logger.Fatal(verr.Error(), nil)
+	}
+
+	return cfg
+}
+
+func initializeDependencies(cfg appconfig.Config) {
 	if !validatorInitialized {
 		if !validation.Init() {
 			logger.Fatal("Failed to initialize validator", nil)
@@ -105,27 +216,124 @@ func initializeDependencies() {
 		}
 	}
 
+	// Initialize the cipher of the personal data of the consumers, before the database stores any of it
+	if !fieldcrypt.Init() {
+		logger.Fatal("Failed to initialize the PII encryption", nil)
+	}
+
+	// Initialize the masking of the personal data of the consumers in the responses, for the non-production environments
+	if !masking.Init() {
+		logger.Fatal("Failed to initialize the data masking", nil)
+	}
+
+	// Initialize the rendering of the phone numbers and the addresses of the consumers in the locale of the requests
+	if !localeformat.Init() {
+		logger.Fatal("Failed to initialize the locale formatting", nil)
+	}
+
+	if !dbInitialized && cfg.Database.IsMemory() {
+		if !database.InitMemory() {
+			logger.Fatal("Failed to initialize the memory database driver", nil)
+		} else {
+			dbInitialized = true
+		}
+	}
+
 	if !dbInitialized {
-		if !database.InitPostgres() {
+		if err := cfg.Database.Check(); err != nil {
+			logger.Fatal(fmt.Sprintf("Invalid database configuration: %v", err), nil)
+		}
+		if !database.InitPostgresWithConfig(cfg.Database.Config) {
 			logger.Fatal("Failed to initialize Postgres database", nil)
 		} else {
 			dbInitialized = true
 		}
 	}
+
+	// Initialize the Redis client sharing the revoked tokens between the instances, if configured
+	if cfg.Redis.Enabled() && cache.GetRedis() == nil {
+		if !cache.InitRedis(cfg.Redis) {
+			logger.Fatal("Failed to initialize the Redis client", nil)
+		}
+	}
 }
 
-func gracefulShutdown(cancel context.CancelFunc) {
+// reloadOnHangup reloads the TLS certificate on SIGHUP, e.g. sent by the tool renewing it.
+func reloadOnHangup(reloader *certreload.Reloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		for range hup {
+			logger.Info("Received signal: hangup. Reloading the TLS certificate...", nil)
+			if err := reloader.Reload(); err != nil {
+				logger.Error(fmt.Sprintf("Failed to reload the TLS certificate, keeping the previous one: %v", err), nil)
+			}
+		}
+	}()
+}
+
+// gracefulShutdown drains the service and shuts it down on SIGINT or SIGTERM.
+// /readyz reports not ready during the drain period, so that the load balancers stop routing to the instance,
+// then the server stops accepting connections and waits for the in-flight requests before the resources are released.
+// The returned channel is closed once the shutdown is complete.
+func gracefulShutdown(srv *http.Server, cancel context.CancelFunc) <-chan struct{} {
+	done := make(chan struct{})
+
 	// Handle graceful shutdown signals
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
 		sig := <-quit
-		logger.Info(fmt.Sprintf("Received signal: %s. Initiating graceful shutdown...", sig), nil)
+		policy := readiness.LoadShutdownPolicy()
+		logger.Info(fmt.Sprintf("Received signal: %s. Initiating graceful shutdown...", sig), log.Fields{
+			"drain_period": policy.DrainPeriod.String(),
+			"timeout":      policy.Timeout.String(),
+		})
+
+		// Report not ready and close the idle connections, so that the load balancers stop routing to the instance
+		// A second signal skips the rest of the drain period
+		srv.SetKeepAlivesEnabled(false)
+		drainCtx, stopDrain := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-quit:
+				stopDrain()
+			case <-drainCtx.Done():
+			}
+		}()
+		readiness.GetGate().Drain(drainCtx, policy.DrainPeriod)
+		stopDrain()
+
+		// Stop accepting connections and wait for the in-flight requests
+		logger.Info("Shutting down the HTTP server...", nil)
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), policy.Timeout)
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Warn(fmt.Sprintf("In-flight requests did not complete in time, closing the connections: %v", err), nil)
+			cancel()
+			srv.Close()
+		}
+		cancelShutdown()
 
 		// Cancel context
 		cancel()
 
+		// Flush the pending background writes while the database is still open
+		logger.Info("Flushing pending background writes...", nil)
+		routes.Shutdown()
+
+		// Export the spans of the last requests
+		tracingCtx, cancelTracing := context.WithTimeout(context.Background(), policy.Timeout)
+		if err := stopTracing(tracingCtx); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to export the pending spans: %v", err), nil)
+		}
+		cancelTracing()
+
+		if cache.GetRedis() != nil {
+			logger.Info("Closing Redis connection...", nil)
+			cache.CloseRedis()
+		}
 		if dbInitialized {
 			logger.Info("Closing Postgres connection...", nil)
 			database.ClosePostgres()
@@ -139,6 +347,8 @@ func gracefulShutdown(cancel context.CancelFunc) {
 
 		logger.Info("Shutdown complete. Bye 👋", nil)
 		logger.Exit()
-		os.Exit(0)
+		close(done)
 	}()
+
+	return done
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/fieldcrypt"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+// migrateUsage describes the arguments of the migrate command.
+const migrateUsage = "usage: main [-config file] migrate up [N] | down [N] | status"
+
+// runMigrate runs the migrate command with the given arguments, e.g. up, down 1, or status, against the PostgreSQL database
+// of the configuration, then exits. up applies the pending migrations, at most N of them, down reverts the last N applied migrations,
+// 1 by default, and status lists the migrations and whether they are applied.
+func runMigrate(configFile string, args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Println(migrateUsage)
+		os.Exit(2)
+	}
+
+	steps := 0
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 || args[0] == "status" {
+			fmt.Println(migrateUsage)
+			os.Exit(2)
+		}
+		steps = n
+	}
+
+	cfg := loadConfiguration(configFile, false).Database.Config
+	if cfg.IsMemory() {
+		fmt.Println("The migrations apply to the PostgreSQL database, DB_DRIVER is memory")
+		os.Exit(1)
+	}
+
+	// The migrations are run by the command only, not again by the connection
+	cfg.Migrate = false
+	if err := cfg.Check(); err != nil {
+		logger.Fatal(fmt.Sprintf("Invalid database configuration: %v", err), nil)
+	}
+	if !database.InitPostgresWithConfig(cfg) {
+		logger.Fatal("Failed to initialize Postgres database", nil)
+	}
+	defer database.ClosePostgres()
+	conn := database.GetPostgres()
+
+	var err error
+	switch args[0] {
+	case "up":
+		// The seeded consumers are encrypted by the baseline migration
+		if !fieldcrypt.Init() {
+			logger.Fatal("Failed to initialize the PII encryption", nil)
+		}
+
+		var applied []database.Migration
+		applied, err = database.MigrateUp(conn, cfg, steps)
+		for _, m := range applied {
+			fmt.Printf("Applied %d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("No pending migration")
+		}
+
+	case "down":
+		var reverted []database.Migration
+		reverted, err = database.MigrateDown(conn, cfg, steps)
+		for _, m := range reverted {
+			fmt.Printf("Reverted %d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Println("No applied migration")
+		}
+
+	case "status":
+		var states []database.MigrationState
+		states, err = database.MigrationStatus(conn, cfg)
+		for _, s := range states {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied at " + s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%4d %-40s %s\n", s.Version, s.Name, applied)
+		}
+
+	default:
+		fmt.Println(migrateUsage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Printf("Migration failed: %v\n", err)
+		database.ClosePostgres()
+		os.Exit(1)
+	}
+}
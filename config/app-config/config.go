@@ -0,0 +1,371 @@
+package app_config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	jwtconfig "github.com/yoanesber/go-jwt-auth-demo/config/jwt-config"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	proxyconfig "github.com/yoanesber/go-jwt-auth-demo/config/proxy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/config/validate"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/alerting"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/anomaly"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/basepath"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/cache"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/captcha"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/chaos"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/eventbus"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/featureflag"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/geoip"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/mailer"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/headers"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/oidc"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/quota"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/ratelimit"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/secrets"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/signedurl"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/tracing"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+)
+
+/**
+ * app_config package loads the settings of the application once, at startup, into a typed Config
+ * which is injected into the components when the routes are wired, instead of each component reading
+ * the environment on its own the first time it is used.
+ * The settings are read from the environment variables and, optionally, from a YAML file whose keys are
+ * the names of the environment variables (CONFIG_FILE or the -config flag), through the env package.
+ * The environment variables take precedence over the file, so that a deployment can override a shared file,
+ * e.g. with its secrets; the values of the file are never copied into the environment of the process.
+ * The settings are validated by the validate package before the Config is built, all problems at once.
+ */
+
+// Default rate limits, when the environment variables are missing or invalid.
+const (
+	// DefaultCheckAvailabilityRateLimit is the number of consumer availability checks allowed per user and minute.
+	DefaultCheckAvailabilityRateLimit = 30
+
+	// DefaultRegisterRateLimit is the number of registrations allowed per client IP and hour.
+	DefaultRegisterRateLimit = 10
+
+	// DefaultForgotPasswordRateLimit is the number of password reset links requested per client IP and hour.
+	DefaultForgotPasswordRateLimit = 5
+
+	// DefaultChangePasswordRateLimit is the number of password changes attempted per user and hour.
+	DefaultChangePasswordRateLimit = 5
+
+	// DefaultMFARateLimit is the number of codes of the authenticator apps checked per user (or client IP for the logins) and hour.
+	DefaultMFARateLimit = 10
+)
+
+// keyPattern matches the keys of the configuration file, which are names of environment variables.
+var keyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// Config holds the settings of the application. It is passed by value and never modified after it is loaded.
+type Config struct {
+	Server        ServerConfig
+	Database      DatabaseConfig
+	UserStore     policyconfig.UserStoreConfig
+	JWT           jwtconfig.JWTConfig
+	KeyRotation   time.Duration
+	CORS          headers.CorsConfig
+	Sessions      policyconfig.SessionPolicy
+	PasswordReset policyconfig.PasswordResetPolicy
+	Credentials   policyconfig.PasswordPolicy
+	MFA           policyconfig.MFAPolicy
+	Caches        CacheConfig
+	Redis         cache.Config
+	RateLimits    RateLimitConfig
+	GeoIP         geoip.Config
+	Anomaly       anomaly.Config
+	Captcha       captcha.Config
+	Tracing       tracing.Config
+	SignedURLs    signedurl.Config
+	Chaos         chaos.Config
+	SLO           metrics.SLOConfig
+
+	Pagination       pagination.Config
+	Consumers        ConsumerConfig
+	Webhooks         policyconfig.WebhookPolicy
+	DisabledFeatures []string
+	Quota            quota.Policy
+	Events           eventbus.Policy
+	OIDC             []oidc.Config
+	Mailer           mailer.Config
+	Alerts           AlertConfig
+}
+
+// ServerConfig holds the settings of the HTTP server.
+type ServerConfig struct {
+	Env               string
+	Port              string
+	APIVersion        string
+	SSL               bool
+	SSLCert           string
+	SSLKeys           string
+	BasePath          string
+	Proxy             proxyconfig.Config
+	RequestValidation bool
+}
+
+// DatabaseConfig holds the settings of the database connection, and how the users and the consumers are read.
+type DatabaseConfig struct {
+	database.Config
+	JoinRoles   bool
+	ReadRetry   policyconfig.RetryPolicy
+	WriteBuffer policyconfig.WriteBufferPolicy
+}
+
+// CacheConfig holds the lifetimes of the cached entries, 0 disables a cache,
+// and the number of users whose roles are cached by the warm-up, 0 disables it.
+type CacheConfig struct {
+	UserTTL               time.Duration
+	RoleTTL               time.Duration
+	RoleWarmUpUsers       int
+	ConsumerTTL           time.Duration
+	SecuritySettingsTTL   time.Duration
+	FeatureFlagsTTL       time.Duration
+	RateLimitOverridesTTL time.Duration
+}
+
+// RateLimitConfig holds the number of requests allowed per window of the rate limited routes.
+type RateLimitConfig struct {
+	CheckAvailability int
+	Register          int
+	ForgotPassword    int
+	ChangePassword    int
+	MFA               int
+
+	// Auth holds the token buckets of the logins and the token refreshes, per client IP and per username
+	Auth ratelimit.AuthConfig
+}
+
+// ConsumerConfig holds which consumers each role sees in the listing, and the rules of the deployment the consumers are checked against.
+type ConsumerConfig struct {
+	Listing policyconfig.ConsumerListingPolicy
+	Rules   policyconfig.ConsumerRules
+}
+
+// AlertConfig holds the routes and the channels of the operational alerts,
+// and how long before its expiry the TLS certificate is alerted on.
+type AlertConfig struct {
+	alerting.Config
+	CertificateExpiryWarning time.Duration
+}
+
+// ValidationError is returned by Load when the settings are invalid, with every problem found.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("configuration is invalid, found %d problem(s)", len(e.Problems))
+}
+
+// Load reads the configuration file at the given path, if not empty, validates the settings,
+// and returns them. If checkDB is true, the validation also checks the connectivity to the database.
+// It returns a *ValidationError listing the problems found when the settings are invalid.
+func Load(path string, checkDB bool) (Config, error) {
+	if err := LoadFile(path); err != nil {
+		return Config{}, err
+	}
+
+	if problems := validate.Run(checkDB); len(problems) > 0 {
+		return Config{}, &ValidationError{Problems: problems}
+	}
+
+	return FromEnv()
+}
+
+// LoadFile reads the YAML configuration file at the given path and sets its values as the file layer of the env package,
+// read by the settings which are not set in the environment. The environment of the process is not modified.
+// An empty path is ignored. The file is a mapping of the names of the environment variables to scalar values,
+// the booleans are set as TRUE or FALSE.
+func LoadFile(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the configuration file: %w", err)
+	}
+
+	values, err := ParseFile(content)
+	if err != nil {
+		return fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	env.SetFile(values)
+
+	return nil
+}
+
+// ParseFile parses the content of a YAML configuration file into the values of its environment variables.
+// It returns an error if a key is not the name of an environment variable, or a value is not a scalar.
+func ParseFile(content []byte) (map[string]string, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if !keyPattern.MatchString(key) {
+			return nil, fmt.Errorf("key %q is not the name of an environment variable, e.g. DB_HOST", key)
+		}
+
+		switch v := value.(type) {
+		case nil:
+			values[key] = ""
+		case string:
+			values[key] = v
+		case bool:
+			values[key] = strings.ToUpper(strconv.FormatBool(v))
+		case int, int64, uint64, float64:
+			values[key] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("value of %s must be a string, a number, or a boolean", key)
+		}
+	}
+
+	return values, nil
+}
+
+// FromEnv reads the settings from the environment variables and the configuration file, without validating them.
+// Missing or invalid optional values fall back to their defaults; it returns an error if a setting the routes
+// cannot be wired without is invalid, such as the trusted proxies, the base path, or the OIDC providers. The PostgreSQL settings are
+// checked when the connection is opened, see database.Config.Check.
+func FromEnv() (Config, error) {
+	proxy, err := proxyconfig.Load()
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid trusted proxy configuration: %w", err)
+	}
+
+	basePath, err := basepath.Load()
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid base path: %w", err)
+	}
+
+	signedURLs, err := signedurl.LoadConfig(secrets.Default())
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid signed URL configuration: %w", err)
+	}
+
+	disabledFeatures, err := featureflag.LoadDisabled()
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid DISABLED_FEATURES: %w", err)
+	}
+
+	events, err := eventbus.LoadPolicy()
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid event configuration: %w", err)
+	}
+
+	oidcConfigs, err := oidc.LoadConfigs()
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid OIDC configuration: %w", err)
+	}
+
+	alerts, err := alerting.LoadConfig()
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid alerting configuration: %w", err)
+	}
+
+	// The tables of the service do not reference the users of an external user store
+	userStore := policyconfig.LoadUserStoreConfig()
+	db := database.ReadConfig()
+	db.ExternalUsers = userStore.IsExternal()
+
+	return Config{
+		Server: ServerConfig{
+			Env:               env.Getenv("ENV"),
+			Port:              env.Getenv("PORT"),
+			APIVersion:        env.Getenv("API_VERSION"),
+			SSL:               env.Getenv("IS_SSL") == "TRUE",
+			SSLCert:           env.Getenv("SSL_CERT"),
+			SSLKeys:           env.Getenv("SSL_KEYS"),
+			BasePath:          basePath,
+			Proxy:             proxy,
+			RequestValidation: env.Getenv("OPENAPI_REQUEST_VALIDATION") == "TRUE",
+		},
+		Database: DatabaseConfig{
+			Config:      db,
+			JoinRoles:   env.Getenv("DB_JOIN_ROLES") == "TRUE",
+			ReadRetry:   policyconfig.LoadRetryPolicy(),
+			WriteBuffer: policyconfig.LoadWriteBufferPolicy(),
+		},
+		UserStore:     userStore,
+		JWT:           jwtconfig.Load(),
+		KeyRotation:   policyconfig.LoadKeyRotationInterval(),
+		CORS:          headers.LoadCorsConfig(),
+		Sessions:      policyconfig.LoadSessionPolicy(),
+		PasswordReset: policyconfig.LoadPasswordResetPolicy(),
+		Credentials:   policyconfig.LoadPasswordPolicy(),
+		MFA:           policyconfig.LoadMFAPolicy(),
+		Caches: CacheConfig{
+			UserTTL:               seconds("USER_CACHE_TTL_SECOND"),
+			RoleTTL:               seconds("ROLE_CACHE_TTL_SECOND"),
+			RoleWarmUpUsers:       positiveInt("WARMUP_ROLE_CACHE_USERS", 0),
+			ConsumerTTL:           seconds("CONSUMER_CACHE_TTL_SECOND"),
+			SecuritySettingsTTL:   policyconfig.LoadSecuritySettingsCacheTTL(),
+			FeatureFlagsTTL:       policyconfig.LoadFeatureFlagsCacheTTL(),
+			RateLimitOverridesTTL: policyconfig.LoadRateLimitOverridesCacheTTL(),
+		},
+		Redis: cache.LoadConfig(),
+		RateLimits: RateLimitConfig{
+			CheckAvailability: positiveInt("CHECK_AVAILABILITY_RATE_LIMIT", DefaultCheckAvailabilityRateLimit),
+			Register:          positiveInt("REGISTER_RATE_LIMIT", DefaultRegisterRateLimit),
+			ForgotPassword:    positiveInt("FORGOT_PASSWORD_RATE_LIMIT", DefaultForgotPasswordRateLimit),
+			ChangePassword:    positiveInt("CHANGE_PASSWORD_RATE_LIMIT", DefaultChangePasswordRateLimit),
+			MFA:               positiveInt("MFA_RATE_LIMIT", DefaultMFARateLimit),
+			Auth:              ratelimit.LoadAuthConfig(),
+		},
+		GeoIP:      geoip.LoadConfig(),
+		Anomaly:    anomaly.LoadConfig(),
+		Captcha:    captcha.LoadConfig(),
+		Tracing:    tracing.LoadConfig(),
+		SignedURLs: signedURLs,
+		Chaos:      chaos.LoadConfig(),
+		SLO:        metrics.LoadSLOConfig(),
+		Pagination: pagination.LoadConfig(),
+		Consumers: ConsumerConfig{
+			Listing: policyconfig.LoadConsumerListingPolicy(),
+			Rules:   policyconfig.LoadConsumerRules(),
+		},
+		Webhooks:         policyconfig.LoadWebhookPolicy(),
+		DisabledFeatures: disabledFeatures,
+		Quota:            quota.LoadPolicy(),
+		Events:           events,
+		OIDC:             oidcConfigs,
+		Mailer:           mailer.LoadConfig(),
+		Alerts:           AlertConfig{Config: alerts, CertificateExpiryWarning: alerting.LoadCertificateExpiryWarning()},
+	}, nil
+}
+
+// seconds returns the number of seconds of the environment variable as a duration, 0 if it is missing or invalid.
+func seconds(key string) time.Duration {
+	n, err := strconv.Atoi(env.Getenv(key))
+	if err != nil || n < 0 {
+		return 0
+	}
+
+	return time.Duration(n) * time.Second
+}
+
+// positiveInt returns the positive integer of the environment variable, or the default value if it is missing or invalid.
+func positiveInt(key string, def int) int {
+	n, err := strconv.Atoi(env.Getenv(key))
+	if err != nil || n <= 0 {
+		return def
+	}
+
+	return n
+}
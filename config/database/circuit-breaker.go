@@ -0,0 +1,263 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+)
+
+/**
+ * CircuitBreaker is a GORM plugin failing the statements at once while the database is down, instead of letting every request
+ * wait for its connection to be refused or to time out. After DB_CIRCUIT_BREAKER_THRESHOLD consecutive statements failing
+ * because the database is unavailable (a connection lost or refused, or a database shutting down or refusing the connections),
+ * the circuit opens: the statements fail with ErrCircuitOpen, answered with 503 by the handlers, for DB_CIRCUIT_BREAKER_OPEN_SECOND.
+ * The circuit is then half-open: a single statement is let through to probe the database, closing the circuit when the database
+ * answers, and opening it again otherwise. The other errors, e.g. a unique violation or a serialization failure, are answered
+ * by the database, which is up, and close the circuit. The state is exported as db_circuit_breaker_state on /metrics.
+ */
+
+// Default settings of the circuit breaker, when the environment variables are not set.
+const (
+	DefaultCircuitBreakerThreshold    = 5
+	DefaultCircuitBreakerOpenDuration = 30 * time.Second
+)
+
+// States of the circuit breaker.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// SQLSTATE codes of the PostgreSQL errors of a database unavailable.
+const (
+	pgTooManyConnections = "53300"
+	pgAdminShutdown      = "57P01"
+	pgCrashShutdown      = "57P02"
+	pgCannotConnectNow   = "57P03"
+
+	// pgConnectionExceptionClass is the class of the SQLSTATE codes of the connection errors (08000, 08006, ...).
+	pgConnectionExceptionClass = "08"
+)
+
+// circuitAllowedKey is the key set on the statements let through by the circuit breaker, whose result is recorded.
+const circuitAllowedKey = "circuit_breaker:allowed"
+
+// ErrCircuitOpen is returned for the statements not run because the circuit breaker is open, the database being unavailable.
+var ErrCircuitOpen = errors.New("the database is unavailable: the circuit breaker is open")
+
+// CircuitBreakerConfig holds the settings of the circuit breaker: the consecutive failures opening the circuit,
+// and how long it stays open before a statement probes the database. The circuit breaker is disabled when Threshold is 0.
+type CircuitBreakerConfig struct {
+	Threshold    int
+	OpenDuration time.Duration
+}
+
+// readCircuitBreakerConfig reads the settings of the circuit breaker of ReadConfig from the environment variables,
+// DB_CIRCUIT_BREAKER_THRESHOLD and DB_CIRCUIT_BREAKER_OPEN_SECOND. Missing or invalid values fall back to the defaults.
+func readCircuitBreakerConfig() CircuitBreakerConfig {
+	cfg := CircuitBreakerConfig{
+		Threshold:    DefaultCircuitBreakerThreshold,
+		OpenDuration: DefaultCircuitBreakerOpenDuration,
+	}
+
+	if n, err := strconv.Atoi(env.Getenv("DB_CIRCUIT_BREAKER_THRESHOLD")); err == nil && n >= 0 {
+		cfg.Threshold = n
+	}
+	if n, err := strconv.Atoi(env.Getenv("DB_CIRCUIT_BREAKER_OPEN_SECOND")); err == nil && n > 0 {
+		cfg.OpenDuration = time.Duration(n) * time.Second
+	}
+
+	return cfg
+}
+
+// Enabled reports whether the circuit breaker is enabled.
+func (c CircuitBreakerConfig) Enabled() bool {
+	return c.Threshold > 0
+}
+
+// CircuitBreaker is the GORM plugin failing the statements while the database is unavailable. It is safe for concurrent use.
+type CircuitBreaker struct {
+	cfg   CircuitBreakerConfig
+	clock clock.Clock
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+	probedAt time.Time
+}
+
+// NewCircuitBreaker creates a new instance of CircuitBreaker with the given settings, closed.
+func NewCircuitBreaker(cfg CircuitBreakerConfig, clk clock.Clock) *CircuitBreaker {
+	metrics.DatabaseCircuitState(CircuitClosed)
+	return &CircuitBreaker{cfg: cfg, clock: clk, state: CircuitClosed}
+}
+
+// Name returns the name of the plugin.
+func (b *CircuitBreaker) Name() string {
+	return "circuit_breaker"
+}
+
+// Initialize registers the callbacks checking the circuit before the statements of every operation, and recording their results.
+func (b *CircuitBreaker) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	processors := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("*").Register, callbacks.Create().After("*").Register},
+		{"query", callbacks.Query().Before("*").Register, callbacks.Query().After("*").Register},
+		{"update", callbacks.Update().Before("*").Register, callbacks.Update().After("*").Register},
+		{"delete", callbacks.Delete().Before("*").Register, callbacks.Delete().After("*").Register},
+		{"row", callbacks.Row().Before("*").Register, callbacks.Row().After("*").Register},
+		{"raw", callbacks.Raw().Before("*").Register, callbacks.Raw().After("*").Register},
+	}
+
+	for _, p := range processors {
+		if err := p.before("circuit_breaker:before_"+p.operation, b.beforeStatement); err != nil {
+			return err
+		}
+		if err := p.after("circuit_breaker:after_"+p.operation, b.afterStatement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// beforeStatement fails the statement with ErrCircuitOpen, before it is run, if the circuit does not let it through.
+func (b *CircuitBreaker) beforeStatement(db *gorm.DB) {
+	if err := b.Allow(); err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	db.InstanceSet(circuitAllowedKey, true)
+}
+
+// afterStatement records the result of the statement let through by the circuit.
+func (b *CircuitBreaker) afterStatement(db *gorm.DB) {
+	if _, ok := db.InstanceGet(circuitAllowedKey); ok {
+		b.Record(db.Error)
+	}
+}
+
+// Allow returns ErrCircuitOpen if the circuit is open, or half-open with its probe in progress, and nil otherwise.
+// Once the circuit has been open for its duration, the first statement allowed probes the database.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.clock.Now().Before(b.openedAt.Add(b.cfg.OpenDuration)) {
+			return ErrCircuitOpen
+		}
+		b.setState(CircuitHalfOpen)
+		b.startProbe()
+		return nil
+	case CircuitHalfOpen:
+		// A probe whose result is never recorded, e.g. lost with its request, is replaced after the open duration
+		if b.probing && b.clock.Now().Before(b.probedAt.Add(b.cfg.OpenDuration)) {
+			return ErrCircuitOpen
+		}
+		b.startProbe()
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record records the result of a statement let through: the errors of a database unavailable count as failures,
+// opening the circuit once they reach the threshold, or at once for the probe of a half-open circuit;
+// the statements answered by the database, successfully or not, close the circuit.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !IsUnavailableError(err) {
+		b.failures = 0
+		b.probing = false
+		if b.state != CircuitClosed {
+			logger.Info("The database is available again, the circuit breaker is closed", nil)
+			b.setState(CircuitClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || (b.state == CircuitClosed && b.failures >= b.cfg.Threshold) {
+		logger.Warn(fmt.Sprintf("The database is unavailable, the circuit breaker is open for %s: %v", b.cfg.OpenDuration, err), nil)
+		b.openedAt = b.clock.Now()
+		b.probing = false
+		b.setState(CircuitOpen)
+	}
+}
+
+// startProbe records the statement let through to probe the database. It is called with the lock held.
+func (b *CircuitBreaker) startProbe() {
+	b.probing = true
+	b.probedAt = b.clock.Now()
+}
+
+// State returns the state of the circuit: CircuitClosed, CircuitOpen, or CircuitHalfOpen.
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// setState sets the state of the circuit, and exports it on /metrics. It is called with the lock held.
+func (b *CircuitBreaker) setState(state string) {
+	b.state = state
+	metrics.DatabaseCircuitState(state)
+}
+
+// IsUnavailableError reports whether the error shows the database is unavailable: a connection lost, refused, or timed out,
+// too many connections, or a database shutting down or not accepting the connections yet.
+// The cancellations and the deadlines of the requests are not, neither are the errors answered by the database.
+func IsUnavailableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgTooManyConnections, pgAdminShutdown, pgCrashShutdown, pgCannotConnectNow:
+			return true
+		}
+		return len(pgErr.Code) == 5 && pgErr.Code[:2] == pgConnectionExceptionClass
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	gormLogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+/**
+ * The memory driver is used when DB_DRIVER=memory to run the application without PostgreSQL.
+ * The data is kept by the in-memory repositories, so the GORM instance returned by GetPostgres
+ * does not execute any SQL: it only lets the services open (no-op) transactions as usual.
+ */
+
+const (
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
+)
+
+// errMemoryDriver is returned when SQL is executed against the memory driver.
+var errMemoryDriver = fmt.Errorf("the memory database driver does not execute SQL")
+
+// GetDriver returns the database driver configured with DB_DRIVER, defaulting to postgres.
+func GetDriver() string {
+	driver := strings.ToLower(env.Getenv("DB_DRIVER"))
+	if driver == "" {
+		return DriverPostgres
+	}
+	return driver
+}
+
+// IsMemoryDriver reports whether the application runs with the in-memory repositories.
+func IsMemoryDriver() bool {
+	return GetDriver() == DriverMemory
+}
+
+// IsMemory reports whether the settings select the in-memory repositories.
+func (c Config) IsMemory() bool {
+	return c.Driver == DriverMemory
+}
+
+// InitMemory initializes the GORM instance used with the memory driver.
+func InitMemory() bool {
+	isSuccess := true
+	once.Do(func() {
+		var err error
+		db, err = gorm.Open(memoryDialector{}, &gorm.Config{
+			SkipDefaultTransaction: true,
+			Logger:                 gormLogger.Default.LogMode(gormLogger.Silent),
+		})
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to initialize the memory database driver: %v", err), nil)
+			isSuccess = false
+			return
+		}
+	})
+
+	return isSuccess
+}
+
+// memoryDialector is a GORM dialector that does not connect to any database.
+type memoryDialector struct{}
+
+func (memoryDialector) Name() string {
+	return DriverMemory
+}
+
+func (memoryDialector) Initialize(db *gorm.DB) error {
+	db.ConnPool = &memoryConnPool{}
+	return nil
+}
+
+func (memoryDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return nil
+}
+
+func (memoryDialector) DataTypeOf(*schema.Field) string {
+	return ""
+}
+
+func (memoryDialector) DefaultValueOf(*schema.Field) clause.Expression {
+	return clause.Expr{}
+}
+
+func (memoryDialector) BindVarTo(writer clause.Writer, stmt *gorm.Statement, v interface{}) {
+	writer.WriteByte('?')
+}
+
+func (memoryDialector) QuoteTo(writer clause.Writer, str string) {
+	writer.WriteString(str)
+}
+
+func (memoryDialector) Explain(sql string, vars ...interface{}) string {
+	return sql
+}
+
+// SavePoint and RollbackTo make nested transactions no-ops as well.
+func (memoryDialector) SavePoint(tx *gorm.DB, name string) error {
+	return nil
+}
+
+func (memoryDialector) RollbackTo(tx *gorm.DB, name string) error {
+	return nil
+}
+
+// memoryConnPool is a connection pool that rejects SQL but supports beginning transactions.
+type memoryConnPool struct{}
+
+func (*memoryConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errMemoryDriver
+}
+
+func (*memoryConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, errMemoryDriver
+}
+
+func (*memoryConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errMemoryDriver
+}
+
+func (*memoryConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *memoryConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return &memoryTx{}, nil
+}
+
+// memoryTx is a no-op transaction of the memory driver.
+// Like sql.Tx, it can be committed or rolled back only once.
+type memoryTx struct {
+	memoryConnPool
+	mu   sync.Mutex
+	done bool
+}
+
+func (t *memoryTx) Commit() error {
+	return t.end()
+}
+
+func (t *memoryTx) Rollback() error {
+	return t.end()
+}
+
+func (t *memoryTx) end() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	return nil
+}
@@ -0,0 +1,457 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/migrations"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+/**
+ * The migrator applies the versioned migrations of the schema in order, and records the applied versions
+ * in the schema_migrations table, so that a migration is applied once and the data is never dropped.
+ * Version 1 is the baseline: the tables of the original schema, as created by the application before the versioned migrations,
+ * frozen in the SQL files of the migrations package and seeded with DB_SEED=TRUE. The databases created before the versioned
+ * migrations are baselined as they are, provided their tables and columns are the ones of the baseline, and upgraded by the next versions.
+ * The next versions are the up and down SQL files of the migrations package, a change of the entities being released
+ * with the version migrating the schema and its data; the data that cannot be migrated in SQL, e.g. the encryption of the consumers,
+ * is migrated by the data step of its version. Each migration runs in a transaction recording its version, under an advisory lock,
+ * so that a failed migration leaves no trace and the instances starting together do not apply it twice.
+ * The migrations are applied on startup with DB_MIGRATE=TRUE, or with the migrate command: migrate up, down, or status.
+ */
+
+// BaselineVersion is the version of the baseline migration, creating the tables of the original schema.
+const BaselineVersion = 1
+
+// ConsumerEncryptionVersion is the version encrypting the personal data of the consumers, with its data step.
+const ConsumerEncryptionVersion = 12
+
+// migrationLockKey is the key of the PostgreSQL advisory lock taken while a migration runs.
+const migrationLockKey = 4_535_002
+
+// baselineTablePattern matches the tables created by the baseline, and their definition.
+var baselineTablePattern = regexp.MustCompile(`(?s)CREATE TABLE (\w+) \((.*?)\n\);`)
+
+// migrationFilePattern matches the names of the migration files, e.g. 0002_audit_event_targets.up.sql.
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// ErrIrreversibleMigration is returned when a migration without down file is reverted.
+var ErrIrreversibleMigration = errors.New("migration cannot be reverted")
+
+// Migration is a version of the schema, applied by Up and reverted by Down, nil if it cannot be reverted.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// MigrationState is a migration and whether it is applied, and when.
+type MigrationState struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// schemaMigration is a row of the schema_migrations table, recording an applied migration.
+type schemaMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+// dataStep migrates the data of a version with Go code: Up runs after the up file of the version, Down before its down file.
+type dataStep struct {
+	Up   func(tx *gorm.DB) error
+	Down func(tx *gorm.DB) error
+}
+
+// dataSteps are the data steps of the versions, by version.
+var dataSteps = map[int64]dataStep{
+	ConsumerEncryptionVersion: {Up: ProtectConsumers, Down: RevealConsumers},
+}
+
+// Migrations returns the migrations of the schema in order: the baseline, seeded as set by the given settings,
+// and the migrations of the embedded files, with the data steps of their versions.
+func Migrations(cfg Config) ([]Migration, error) {
+	files, err := LoadMigrations(migrations.Versions)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, m := range files {
+		if step, ok := dataSteps[m.Version]; ok {
+			files[i] = withDataStep(m, step)
+		}
+	}
+
+	return append([]Migration{baselineMigration(cfg)}, files...), nil
+}
+
+// withDataStep returns the migration running the data step after its up file, and before its down file.
+func withDataStep(m Migration, step dataStep) Migration {
+	up, down := m.Up, m.Down
+	m.Up = func(tx *gorm.DB) error {
+		if err := up(tx); err != nil {
+			return err
+		}
+		return step.Up(tx)
+	}
+	if down != nil {
+		m.Down = func(tx *gorm.DB) error {
+			if err := step.Down(tx); err != nil {
+				return err
+			}
+			return down(tx)
+		}
+	}
+
+	return m
+}
+
+// LoadMigrations reads the migrations of the up and down SQL files found in the file system, sorted by version.
+// It returns an error if a file name is malformed, a version has two names or no up file, or a version is not above the baseline.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, name := range names {
+		match := migrationFilePattern.FindStringSubmatch(path.Base(name))
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s, expected NNNN_name.up.sql or NNNN_name.down.sql", name)
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		if version <= BaselineVersion {
+			return nil, fmt.Errorf("invalid migration file %s, the versions start after the baseline (%d)", name, BaselineVersion)
+		}
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file %s: %v", name, err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, m.Name, match[2])
+		}
+
+		run := execSQL(string(content))
+		if match[3] == "up" {
+			m.Up = run
+		} else {
+			m.Down = run
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == nil {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	return result, nil
+}
+
+// execSQL returns a step running the SQL script.
+func execSQL(script string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Exec(script).Error
+	}
+}
+
+// baselineMigration returns the baseline migration, creating the tables of the original schema and, if DB_SEED is TRUE,
+// importing the seed file, written for the original schema and upgraded with the data by the next versions.
+// The tables do not reference the users with an external user store, whose users are not in the users table.
+// A database created before the versioned migrations is baselined as it is, if its tables and columns are the ones of the baseline.
+// Reverting the baseline drops the tables.
+func baselineMigration(cfg Config) Migration {
+	return Migration{
+		Version: BaselineVersion,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			schema, err := fs.ReadFile(migrations.Baseline, "baseline/schema.sql")
+			if err != nil {
+				return err
+			}
+
+			// The databases created before the versioned migrations are baselined as they are, and not seeded again
+			if tx.Migrator().HasTable("roles") {
+				return checkBaseline(tx, string(schema))
+			}
+
+			if err := tx.Exec(string(schema)).Error; err != nil {
+				return err
+			}
+			if cfg.ExternalUsers {
+				if err := execFile(tx, "baseline/external_users.sql"); err != nil {
+					return err
+				}
+			}
+			if !cfg.Seed {
+				return nil
+			}
+
+			// Import initial data from the seed file
+			if cfg.SeedFile == "" {
+				return fmt.Errorf("DB_SEED_FILE environment variable is not set")
+			}
+			seedData, err := os.ReadFile(cfg.SeedFile)
+			if err != nil {
+				return fmt.Errorf("failed to read seed file: %v", err)
+			}
+			if err := tx.Exec(string(seedData)).Error; err != nil {
+				return fmt.Errorf("failed to execute seed data: %v", err)
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return execFile(tx, "baseline/schema.down.sql")
+		},
+	}
+}
+
+// checkBaseline checks that the tables of the database, created before the versioned migrations, are the ones of the baseline schema
+// with the same columns, so that the next versions can upgrade it. It returns an error listing the differences otherwise.
+func checkBaseline(tx *gorm.DB, schema string) error {
+	var problems []string
+	for _, match := range baselineTablePattern.FindAllStringSubmatch(schema, -1) {
+		table := match[1]
+		if !tx.Migrator().HasTable(table) {
+			problems = append(problems, fmt.Sprintf("table %s is missing", table))
+			continue
+		}
+
+		columnTypes, err := tx.Migrator().ColumnTypes(table)
+		if err != nil {
+			return fmt.Errorf("failed to read the columns of table %s: %v", table, err)
+		}
+		existing := make(map[string]bool, len(columnTypes))
+		for _, c := range columnTypes {
+			existing[c.Name()] = true
+		}
+
+		var missing []string
+		for _, line := range strings.Split(match[2], "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 || fields[0] == "PRIMARY" || fields[0] == "CONSTRAINT" {
+				continue
+			}
+			if !existing[fields[0]] {
+				missing = append(missing, fields[0])
+			}
+			delete(existing, fields[0])
+		}
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("table %s lacks the columns %s", table, strings.Join(missing, ", ")))
+		}
+		if len(existing) > 0 {
+			unknown := make([]string, 0, len(existing))
+			for name := range existing {
+				unknown = append(unknown, name)
+			}
+			sort.Strings(unknown)
+			problems = append(problems, fmt.Sprintf("table %s has the columns %s not in the baseline", table, strings.Join(unknown, ", ")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("the database predates the versioned migrations but does not match their baseline: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// execFile runs the SQL script of the baseline files.
+func execFile(tx *gorm.DB, name string) error {
+	script, err := fs.ReadFile(migrations.Baseline, name)
+	if err != nil {
+		return err
+	}
+
+	return tx.Exec(string(script)).Error
+}
+
+// MigrateUp applies the pending migrations in order, at most the given number of them if steps is positive.
+// It returns the migrations applied, up to the one that failed.
+func MigrateUp(conn *gorm.DB, cfg Config, steps int) ([]Migration, error) {
+	all, err := Migrations(cfg)
+	if err != nil {
+		return nil, err
+	}
+	table, err := prepareMigrations(conn, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, m := range all {
+		if steps > 0 && len(applied) == steps {
+			break
+		}
+
+		ran, err := runMigration(conn, table, m, true)
+		if err != nil {
+			return applied, fmt.Errorf("migration %d_%s failed: %v", m.Version, m.Name, err)
+		}
+		if ran {
+			applied = append(applied, m)
+			logger.Info(fmt.Sprintf("Applied migration %d_%s", m.Version, m.Name), nil)
+		}
+	}
+
+	return applied, nil
+}
+
+// MigrateDown reverts the given number of applied migrations, the most recent first (1 if steps is not positive).
+// It returns the migrations reverted, up to the one that failed.
+func MigrateDown(conn *gorm.DB, cfg Config, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	all, err := Migrations(cfg)
+	if err != nil {
+		return nil, err
+	}
+	table, err := prepareMigrations(conn, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(all) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := all[i]
+		ran, err := runMigration(conn, table, m, false)
+		if err != nil {
+			return reverted, fmt.Errorf("reverting migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		if ran {
+			reverted = append(reverted, m)
+			logger.Info(fmt.Sprintf("Reverted migration %d_%s", m.Version, m.Name), nil)
+		}
+	}
+
+	return reverted, nil
+}
+
+// MigrationStatus returns the migrations of the schema in order, with the time they were applied, if applied.
+func MigrationStatus(conn *gorm.DB, cfg Config) ([]MigrationState, error) {
+	all, err := Migrations(cfg)
+	if err != nil {
+		return nil, err
+	}
+	table, err := prepareMigrations(conn, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []schemaMigration
+	if err := conn.Table(table).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read the applied migrations: %v", err)
+	}
+	appliedAt := make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		appliedAt[row.Version] = row.AppliedAt
+	}
+
+	states := make([]MigrationState, 0, len(all))
+	for _, m := range all {
+		state := MigrationState{Version: m.Version, Name: m.Name}
+		if at, ok := appliedAt[m.Version]; ok {
+			state.AppliedAt = &at
+		}
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+// prepareMigrations creates the schema of the settings and its schema_migrations table, if they do not exist,
+// and returns the qualified name of the table.
+func prepareMigrations(conn *gorm.DB, cfg Config) (string, error) {
+	if cfg.Schema == "" {
+		return "", fmt.Errorf("DB_SCHEMA environment variable is not set")
+	}
+	if !schemaNamePattern.MatchString(cfg.Schema) {
+		return "", fmt.Errorf("invalid schema name %q", cfg.Schema)
+	}
+
+	if err := conn.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", cfg.Schema)).Error; err != nil {
+		return "", fmt.Errorf("failed to create schema %s: %v", cfg.Schema, err)
+	}
+
+	table := cfg.Schema + ".schema_migrations"
+	err := conn.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version bigint NOT NULL PRIMARY KEY,
+		name varchar(255) NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`, table)).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to create the schema_migrations table: %v", err)
+	}
+
+	return table, nil
+}
+
+// runMigration applies the migration if up is true and it is not applied yet, or reverts it if up is false and it is applied,
+// in a transaction recording the change under the advisory lock of the migrations. It reports whether the migration ran.
+func runMigration(conn *gorm.DB, table string, m Migration, up bool) (bool, error) {
+	ran := false
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+			return fmt.Errorf("failed to lock the migrations: %v", err)
+		}
+
+		// Another instance may have run the migration while the lock was waited for
+		var count int64
+		if err := tx.Table(table).Where("version = ?", m.Version).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to read the applied migrations: %v", err)
+		}
+		if applied := count > 0; applied == up {
+			return nil
+		}
+
+		if !up {
+			if m.Down == nil {
+				return ErrIrreversibleMigration
+			}
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			ran = true
+			return tx.Table(table).Where("version = ?", m.Version).Delete(&schemaMigration{}).Error
+		}
+
+		if err := m.Up(tx); err != nil {
+			return err
+		}
+		ran = true
+		return tx.Table(table).Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return ran, nil
+}
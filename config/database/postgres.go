@@ -1,102 +1,215 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"gorm.io/driver/postgres"        // Import the PostgreSQL driver for GORM
 	"gorm.io/gorm"                   // Import GORM for ORM functionalities
 	gormLogger "gorm.io/gorm/logger" // Import GORM logger for logging SQL queries
 	"gorm.io/gorm/schema"
 
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/diagnostics"
 	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/tracing"
 )
 
+// Default settings of the connection pool, when the environment variables are not set.
+const (
+	DefaultMaxOpenConns    = 25
+	DefaultMaxIdleConns    = 10
+	DefaultConnMaxLifetime = 30 * time.Minute
+	DefaultConnMaxIdleTime = 5 * time.Minute
+)
+
+// schemaNamePattern matches the unquoted PostgreSQL identifiers accepted as schema names.
+var schemaNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// Config holds the database settings. It is passed by value and never modified after it is loaded.
+// The PostgreSQL settings are empty with the memory driver, except the seeding of the in-memory store.
+type Config struct {
+	Driver   string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+	Schema   string
+	SSLMode  string
+	TimeZone string
+	Migrate  bool
+	Seed     bool
+	SeedFile string
+	LogLevel string
+	Pool     PoolConfig
+
+	// CircuitBreaker fails the statements at once while the database is unavailable, enabled by default
+	CircuitBreaker CircuitBreakerConfig
+
+	// ReplicaDSNs are the connection strings of the read replicas serving the read-heavy queries, none by default
+	ReplicaDSNs []string
+
+	// ExternalUsers is set when the users are read from an external user store, so that the tables created
+	// by the baseline migration do not reference the users table with a foreign key.
+	ExternalUsers bool
+}
+
+// PoolConfig holds the settings of the connection pool: the connections open at most, the idle connections kept,
+// and how long a connection is reused, and kept idle, before it is closed.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
 var (
-	once       sync.Once
-	db         *gorm.DB
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPass     string
-	DBName     string
-	DBSchema   string
-	DBSSLMode  string
-	DBTimeZone string
-	DBMigrate  string
-	DBSeed     string
-	DBSeedFile string
-	DBLog      string
+	once   sync.Once
+	db     *gorm.DB
+	config Config // settings of the open connection, set once together with db
 )
 
-// LoadPostgresEnv loads environment variables from the .env file
-// It sets the database connection parameters such as host, port, user, password, etc.
-func LoadPostgresEnv() bool {
-	DBHost = os.Getenv("DB_HOST")
-	DBPort = os.Getenv("DB_PORT")
-	DBUser = os.Getenv("DB_USER")
-	DBPass = os.Getenv("DB_PASS")
-	DBName = os.Getenv("DB_NAME")
-	DBSchema = os.Getenv("DB_SCHEMA")
-	DBSSLMode = os.Getenv("DB_SSL_MODE")
-	DBTimeZone = os.Getenv("DB_TIMEZONE")
-	DBMigrate = os.Getenv("DB_MIGRATE")
-	DBSeed = os.Getenv("DB_SEED")
-	DBSeedFile = os.Getenv("DB_SEED_FILE")
-	DBLog = os.Getenv("DB_LOG")
-
-	if DBHost == "" || DBPort == "" || DBUser == "" || DBPass == "" || DBName == "" || DBSchema == "" {
+// LoadConfig reads the database settings from the environment variables, such as the driver, host, port, user, password, etc.
+// It returns an error if a required PostgreSQL setting is not set, with the postgres driver.
+func LoadConfig() (Config, error) {
+	cfg := ReadConfig()
+	if err := cfg.Check(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// ReadConfig reads the database settings from the environment variables, without checking the required settings.
+func ReadConfig() Config {
+	cfg := Config{
+		Driver:   GetDriver(),
+		Host:     env.Getenv("DB_HOST"),
+		Port:     env.Getenv("DB_PORT"),
+		User:     env.Getenv("DB_USER"),
+		Password: env.Getenv("DB_PASS"),
+		Name:     env.Getenv("DB_NAME"),
+		Schema:   env.Getenv("DB_SCHEMA"),
+		SSLMode:  env.Getenv("DB_SSL_MODE"),
+		TimeZone: env.Getenv("DB_TIMEZONE"),
+		Migrate:  env.Getenv("DB_MIGRATE") == "TRUE",
+		Seed:     env.Getenv("DB_SEED") == "TRUE",
+		SeedFile: env.Getenv("DB_SEED_FILE"),
+		LogLevel: env.Getenv("DB_LOG"),
+		Pool:     ReadPoolConfig(),
+
+		CircuitBreaker: readCircuitBreakerConfig(),
+		ReplicaDSNs:    readReplicaDSNs(),
+	}
+
+	if cfg.Driver == DriverMemory {
+		return Config{Driver: cfg.Driver, Seed: cfg.Seed}
+	}
+
+	return cfg
+}
+
+// ReadPoolConfig reads the settings of the connection pool from the environment variables,
+// DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS, DB_CONN_MAX_LIFETIME_SECOND and DB_CONN_MAX_IDLE_TIME_SECOND.
+// The settings not set, or not positive, keep their defaults.
+func ReadPoolConfig() PoolConfig {
+	pool := PoolConfig{
+		MaxOpenConns:    DefaultMaxOpenConns,
+		MaxIdleConns:    DefaultMaxIdleConns,
+		ConnMaxLifetime: DefaultConnMaxLifetime,
+		ConnMaxIdleTime: DefaultConnMaxIdleTime,
+	}
+
+	if n, err := strconv.Atoi(env.Getenv("DB_MAX_OPEN_CONNS")); err == nil && n > 0 {
+		pool.MaxOpenConns = n
+	}
+	if n, err := strconv.Atoi(env.Getenv("DB_MAX_IDLE_CONNS")); err == nil && n > 0 {
+		pool.MaxIdleConns = n
+	}
+	if n, err := strconv.Atoi(env.Getenv("DB_CONN_MAX_LIFETIME_SECOND")); err == nil && n > 0 {
+		pool.ConnMaxLifetime = time.Duration(n) * time.Second
+	}
+	if n, err := strconv.Atoi(env.Getenv("DB_CONN_MAX_IDLE_TIME_SECOND")); err == nil && n > 0 {
+		pool.ConnMaxIdleTime = time.Duration(n) * time.Second
+	}
+
+	// The idle connections beyond the open ones would be closed anyway
+	if pool.MaxIdleConns > pool.MaxOpenConns {
+		pool.MaxIdleConns = pool.MaxOpenConns
+	}
+
+	return pool
+}
+
+// Apply sets the settings of the connection pool of the given database.
+func (p PoolConfig) Apply(db *sql.DB) {
+	db.SetMaxOpenConns(p.MaxOpenConns)
+	db.SetMaxIdleConns(p.MaxIdleConns)
+	db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+}
+
+// Check returns an error if a required PostgreSQL setting is not set, with the postgres driver.
+func (c Config) Check() error {
+	if c.Driver == DriverMemory {
+		return nil
+	}
+	if c.Host == "" || c.Port == "" || c.User == "" || c.Password == "" || c.Name == "" || c.Schema == "" {
+		return fmt.Errorf("one or more required database environment variables are not set")
+	}
+
+	return nil
+}
+
+// InitPostgres initializes the GORM database connection with the settings read from the environment variables
+func InitPostgres() bool {
+	cfg, err := LoadConfig()
+	if err != nil {
 		logger.Panic("One or more required environment variables are not set", nil)
 		return false
 	}
 
-	return true
+	return InitPostgresWithConfig(cfg)
 }
 
-// InitPostgres initializes the GORM database connection
-func InitPostgres() bool {
+// InitPostgresWithConfig initializes the GORM database connection with the given settings
+func InitPostgresWithConfig(cfg Config) bool {
 	isSuccess := true
 	once.Do(func() {
-		if !LoadPostgresEnv() {
-			isSuccess = false
-			return
-		}
-
 		// Create the connection string
-		dsn := fmt.Sprintf(
-			"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=%s search_path=%s",
-			DBHost,
-			DBPort,
-			DBUser,
-			DBPass,
-			DBName,
-			DBSSLMode,
-			DBTimeZone,
-			DBSchema,
-		)
-
-		// Set the log level based on the environment variable
+		dsn := buildPostgresDSN(cfg)
+
+		// Set the log level based on the settings
 		var logLevel gormLogger.LogLevel
-		if DBLog == "INFO" {
+		if cfg.LogLevel == "INFO" {
 			logLevel = gormLogger.Info
-		} else if DBLog == "ERROR" {
+		} else if cfg.LogLevel == "ERROR" {
 			logLevel = gormLogger.Error
-		} else if DBLog == "SILENT" {
+		} else if cfg.LogLevel == "SILENT" {
 			logLevel = gormLogger.Silent
 		} else {
 			logLevel = gormLogger.Warn
 		}
 
 		// Open the connection using GORM and PostgreSQL driver
-		var err error
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
+		conn, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
 			NamingStrategy: schema.NamingStrategy{
-				TablePrefix:   DBSchema + ".",
+				TablePrefix:   cfg.Schema + ".",
 				SingularTable: false,
 			},
 			Logger: gormLogger.Default.LogMode(logLevel),
+			// Report the unique violations as gorm.ErrDuplicatedKey, answered with 409 by the handlers
+			TranslateError: true,
 		})
 		if err != nil {
 			logger.Fatal(fmt.Sprintf("Failed to connect to PostgreSQL: %v", err), nil)
@@ -104,100 +217,266 @@ func InitPostgres() bool {
 			return
 		}
 
-		logger.Info("Connected to PostgreSQL database", nil)
+		// Size the connection pool, and report its statistics in the diagnostics
+		sqlDB, err := conn.DB()
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to get the connection pool: %v", err), nil)
+			isSuccess = false
+			return
+		}
+		cfg.Pool.Apply(sqlDB)
+		diagnostics.RegisterDatabasePool("postgres", sqlDB)
+
+		// Route the read-heavy queries to the read replicas, if any
+		if err = useReplicas(conn, cfg); err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to set up the read replicas: %v", err), nil)
+			isSuccess = false
+			return
+		}
+
+		// Fail the statements at once while the database is unavailable, instead of waiting for their connections
+		if cfg.CircuitBreaker.Enabled() {
+			if err = conn.Use(NewCircuitBreaker(cfg.CircuitBreaker, clock.New())); err != nil {
+				logger.Fatal(fmt.Sprintf("Failed to register the circuit breaker: %v", err), nil)
+				isSuccess = false
+				return
+			}
+		}
+
+		// Record the queries on /metrics, by table and operation, and the connection pool
+		if metrics.Enabled() {
+			if err = conn.Use(QueryMetrics{}); err != nil {
+				logger.Fatal(fmt.Sprintf("Failed to register the query metrics: %v", err), nil)
+				isSuccess = false
+				return
+			}
+
+			// Export the connection pool: the open, in use, and idle connections, and the waits for a connection
+			if err = metrics.RegisterDatabase("postgres", sqlDB); err != nil {
+				logger.Fatal(fmt.Sprintf("Failed to register the connection pool metrics: %v", err), nil)
+				isSuccess = false
+				return
+			}
+		}
+
+		// Trace the queries in the spans of their requests
+		if tracing.Enabled() {
+			if err = conn.Use(QueryTracing{}); err != nil {
+				logger.Fatal(fmt.Sprintf("Failed to register the query tracing: %v", err), nil)
+				isSuccess = false
+				return
+			}
+		}
 
 		// Migrate the database schema and all tables
-		if DBMigrate == "TRUE" {
-			if err = MigratePostgres(); err != nil {
+		if cfg.Migrate {
+			if err = MigratePostgres(conn, cfg); err != nil {
 				logger.Fatal(fmt.Sprintf("Failed to migrate PostgreSQL database: %v", err), nil)
 				isSuccess = false
 				return
 			}
 		}
+
+		db = conn
+		config = cfg
 	})
 
 	return isSuccess
 }
 
+// buildPostgresDSN creates the connection string from the given settings.
+func buildPostgresDSN(cfg Config) string {
+	return fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=%s search_path=%s",
+		cfg.Host,
+		cfg.Port,
+		cfg.User,
+		cfg.Password,
+		cfg.Name,
+		cfg.SSLMode,
+		cfg.TimeZone,
+		cfg.Schema,
+	)
+}
+
+// PingPostgres opens a short-lived connection to PostgreSQL with the given settings and pings it.
+// It is used to check the database connectivity without initializing the shared connection.
+func PingPostgres(cfg Config, timeout time.Duration) error {
+	if err := cfg.Check(); err != nil {
+		return err
+	}
+
+	conn, err := gorm.Open(postgres.Open(buildPostgresDSN(cfg)), &gorm.Config{
+		Logger:         gormLogger.Default.LogMode(gormLogger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %v", err)
+	}
+
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get SQL DB from GORM: %v", err)
+	}
+	defer sqlDB.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping PostgreSQL: %v", err)
+	}
+
+	return nil
+}
+
+// Ping pings the database of the shared connection, e.g. to check it is reachable before accepting traffic.
+// The memory driver has no connection to ping.
+func Ping(ctx context.Context) error {
+	if IsMemoryDriver() {
+		return nil
+	}
+
+	conn := GetPostgres()
+	if conn == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get SQL DB from GORM: %v", err)
+	}
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping PostgreSQL: %v", err)
+	}
+
+	return nil
+}
+
 // MigratePostgres migrates the PostgreSQL database schema
-// It creates the schema if it does not exist, sets the search path, and migrates the tables.
-func MigratePostgres() error {
+// It creates the schema if it does not exist, sets the search path, and applies the pending migrations
+// of the given connection according to the given settings. The applied migrations are never run again,
+// so that the data of the tables is kept across restarts.
+func MigratePostgres(conn *gorm.DB, cfg Config) error {
 	// Create the schema in the database
-	if DBSchema != "" {
-		if err := db.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", DBSchema)).Error; err != nil {
-			return fmt.Errorf("failed to create schema %s: %v", DBSchema, err)
+	if cfg.Schema != "" {
+		if err := conn.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", cfg.Schema)).Error; err != nil {
+			return fmt.Errorf("failed to create schema %s: %v", cfg.Schema, err)
 		}
-		logger.Info(fmt.Sprintf("Schema %s created successfully", DBSchema), nil)
+		logger.Info(fmt.Sprintf("Schema %s created successfully", cfg.Schema), nil)
 
 		// Set the schema for the database connection
-		if err := db.Exec(fmt.Sprintf("SET search_path TO %s", DBSchema)).Error; err != nil {
-			return fmt.Errorf("failed to set search path to schema %s: %v", DBSchema, err)
+		if err := conn.Exec(fmt.Sprintf("SET search_path TO %s", cfg.Schema)).Error; err != nil {
+			return fmt.Errorf("failed to set search path to schema %s: %v", cfg.Schema, err)
 		}
-		logger.Info(fmt.Sprintf("Search path set to schema %s", DBSchema), nil)
+		logger.Info(fmt.Sprintf("Search path set to schema %s", cfg.Schema), nil)
 	} else {
 		return fmt.Errorf("DB_SCHEMA environment variable is not set")
 	}
 
-	// Perform database migration within a transaction
-	err := db.Transaction(func(tx *gorm.DB) error {
-		// Check if the transaction is valid
-		if tx == nil {
-			return fmt.Errorf("transaction is nil")
-		}
+	// Apply the pending migrations, each one within a transaction
+	applied, err := MigrateUp(conn, cfg, 0)
+	if err != nil {
+		return fmt.Errorf("database migration failed: %v", err)
+	}
 
-		// Drop and recreate tables if they exist
-		err := tx.Migrator().DropTable(
-			&entity.Consumer{},
-			&entity.User{},
-			&entity.Role{},
-			&entity.UserRole{},
-			&entity.RefreshToken{})
-		if err != nil {
-			return fmt.Errorf("failed to drop tables: %v", err)
-		}
+	logger.Info(fmt.Sprintf("Database migrated successfully, %d migrations applied", len(applied)), nil)
 
-		// Migrate the database schema
-		err = tx.AutoMigrate(
-			&entity.Role{},
-			&entity.User{},
-			&entity.RefreshToken{},
-			&entity.Consumer{})
-		if err != nil {
-			return fmt.Errorf("failed to migrate database: %v", err)
-		}
+	return nil
+}
 
-		if DBSeed == "TRUE" {
-			// Import initial data from the seed file
-			if DBSeedFile == "" {
-				return fmt.Errorf("DB_SEED_FILE environment variable is not set")
-			}
+// ProtectConsumers encrypts the personal data of the consumers inserted without blind indexes,
+// e.g. by the seed file, by hand, or before the encryption, and sets their blind indexes. The consumers saved by the application are skipped.
+// It runs as the data step of the version encrypting the consumers, and does not depend on the columns added after it.
+func ProtectConsumers(tx *gorm.DB) error {
+	tx = tx.Unscoped()
 
-			// Read the seed file
-			seedData, err := os.ReadFile(DBSeedFile)
+	var consumers []entity.Consumer
+	result := tx.Where("phone_index IS NULL OR email_index IS NULL").FindInBatches(&consumers, 100, func(batch *gorm.DB, _ int) error {
+		for i := range consumers {
+			c := &consumers[i]
+			c.EmailIndex = entity.ConsumerEmailIndex(c.Email)
+			c.PhoneIndex = entity.ConsumerPhoneIndex(c.Phone)
+
+			// The columns are written as they are, without touching updated_at
+			err := tx.Model(c).Select("Phone", "PhoneIndex", "EmailIndex", "Address", "BirthDate").UpdateColumns(c).Error
 			if err != nil {
-				return fmt.Errorf("failed to read seed file: %v", err)
+				return fmt.Errorf("failed to protect consumer %s: %w", c.ID, err)
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		logger.Info(fmt.Sprintf("Protected the personal data of %d consumers", result.RowsAffected), nil)
+	}
+
+	return nil
+}
+
+// RevealConsumers decrypts the personal data of the consumers and stores it in plain text, the birth dates as YYYY-MM-DD.
+// It runs as the data step reverting the version encrypting the consumers, before their columns get their original types.
+func RevealConsumers(tx *gorm.DB) error {
+	tx = tx.Unscoped()
+
+	var consumers []entity.Consumer
+	result := tx.Select("id", "phone", "address", "birth_date").FindInBatches(&consumers, 100, func(batch *gorm.DB, _ int) error {
+		for _, c := range consumers {
+			var birthDate interface{}
+			if c.BirthDate != nil && !c.BirthDate.Time.IsZero() {
+				birthDate = c.BirthDate.String()
 			}
 
-			// Execute the seed data
-			if err := tx.Exec(string(seedData)).Error; err != nil {
-				return fmt.Errorf("failed to execute seed data: %v", err)
+			err := tx.Table("consumers").Where("id = ?", c.ID).UpdateColumns(map[string]interface{}{
+				"phone":      c.Phone,
+				"address":    c.Address,
+				"birth_date": birthDate,
+			}).Error
+			if err != nil {
+				return fmt.Errorf("failed to decrypt consumer %s: %w", c.ID, err)
 			}
 		}
-
 		return nil
 	})
 
-	if err != nil {
-		return fmt.Errorf("database migration failed: %v", err)
+	return result.Error
+}
+
+// DropPostgresSchema drops the configured schema and all of its tables.
+// It is used to clean up the ephemeral schemas created for end-to-end test runs,
+// so it refuses to drop the public schema.
+func DropPostgresSchema() error {
+	if db == nil {
+		return fmt.Errorf("database connection is not initialized")
+	}
+	cfg := config
+	if !schemaNamePattern.MatchString(cfg.Schema) {
+		return fmt.Errorf("invalid schema name %q", cfg.Schema)
+	}
+	if strings.EqualFold(cfg.Schema, "public") {
+		return fmt.Errorf("refusing to drop the public schema")
 	}
 
-	logger.Info("Database migrated successfully", nil)
+	if err := db.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", cfg.Schema)).Error; err != nil {
+		return fmt.Errorf("failed to drop schema %s: %v", cfg.Schema, err)
+	}
+
+	logger.Info(fmt.Sprintf("Schema %s dropped successfully", cfg.Schema), nil)
 
 	return nil
 }
 
 // GetPostgres returns the GORM database instance
+// With DB_DRIVER=memory, it returns the instance of the memory driver instead.
 func GetPostgres() *gorm.DB {
+	if db == nil && IsMemoryDriver() {
+		InitMemory()
+		return db
+	}
 	if db == nil {
 		if !InitPostgres() {
 			logger.Panic("Failed to initialize PostgreSQL database", nil)
@@ -209,17 +488,30 @@ func GetPostgres() *gorm.DB {
 
 // ClosePostgres closes the database connection (optional, for when needed)
 func ClosePostgres() {
+	// The memory driver has no connection to close
+	if IsMemoryDriver() {
+		once = sync.Once{}
+		db = nil
+		return
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil || sqlDB == nil {
 		logger.Error(fmt.Sprintf("Failed to get SQL DB from GORM: %v", err), nil)
 		return
 	}
 
+	// Log the pool as it was used, e.g. the waits for a connection, before it is closed
+	diagnostics.LogDatabasePoolStats("Before closing the database")
+	diagnostics.UnregisterDatabasePool("postgres")
+	closeReplicas()
+
 	if err := sqlDB.Close(); err != nil {
 		logger.Error(fmt.Sprintf("Failed to close database connection: %v", err), nil)
 	}
 
 	once = sync.Once{} // Reset the once to allow re-initialization
 	db = nil           // Clear the db variable to prevent further use
+	config = Config{}
 	logger.Info("Database connection closed successfully", nil)
 }
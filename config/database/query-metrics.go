@@ -0,0 +1,99 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+)
+
+/**
+ * QueryMetrics is a GORM plugin recording every query of the connection on /metrics, with its duration and whether it failed,
+ * labeled by table and operation (create, query, update, delete, row, or raw), so that the dashboards tell the slow
+ * consumer searches apart from the user lookups of the logins. The schema is left out of the table, and the raw SQL
+ * statements not naming their table are labeled "unknown". The lookups finding no record are not counted as errors.
+ */
+
+// UnknownTable is the table label of the queries whose table is not known, e.g. the raw SQL statements.
+const UnknownTable = "unknown"
+
+// queryStartKey is the key of the start time of the query, set on the statement by the before callbacks.
+const queryStartKey = "query_metrics:start"
+
+// QueryMetrics is the GORM plugin recording the queries on /metrics.
+type QueryMetrics struct{}
+
+// Name returns the name of the plugin.
+func (QueryMetrics) Name() string {
+	return "query_metrics"
+}
+
+// Initialize registers the callbacks timing the queries of every operation.
+func (QueryMetrics) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	processors := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("*").Register, callbacks.Create().After("*").Register},
+		{"query", callbacks.Query().Before("*").Register, callbacks.Query().After("*").Register},
+		{"update", callbacks.Update().Before("*").Register, callbacks.Update().After("*").Register},
+		{"delete", callbacks.Delete().Before("*").Register, callbacks.Delete().After("*").Register},
+		{"row", callbacks.Row().Before("*").Register, callbacks.Row().After("*").Register},
+		{"raw", callbacks.Raw().Before("*").Register, callbacks.Raw().After("*").Register},
+	}
+
+	for _, p := range processors {
+		if err := p.before("query_metrics:before_"+p.operation, startQuery); err != nil {
+			return err
+		}
+		if err := p.after("query_metrics:after_"+p.operation, recordQuery(p.operation)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startQuery records the start time of the query on its statement.
+func startQuery(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+// recordQuery returns the callback recording the queries of the given operation, once they are run.
+func recordQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+
+		failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+		metrics.DatabaseQuery(queryTable(db.Statement), operation, time.Since(start), failed)
+	}
+}
+
+// queryTable returns the table of the statement without its schema, or UnknownTable if it is not known.
+func queryTable(stmt *gorm.Statement) string {
+	table := stmt.Table
+	if table == "" && stmt.Schema != nil {
+		table = stmt.Schema.Table
+	}
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		table = table[i+1:]
+	}
+	table = strings.Trim(table, `"`)
+	if table == "" {
+		return UnknownTable
+	}
+
+	return table
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/tracing"
+)
+
+/**
+ * QueryTracing is a GORM plugin tracing every query of the connection with OpenTelemetry, as a child span of the span
+ * of the context of the query, so the queries run with tx.WithContext(ctx) appear in the trace of their request.
+ * The spans carry the table, the operation, and the SQL statement with its placeholders, never the values of its
+ * parameters, which may be passwords or personal data. The lookups finding no record are not recorded as errors.
+ */
+
+// querySpanKey is the key of the span of the query, set on the statement by the before callbacks.
+const querySpanKey = "query_tracing:span"
+
+// QueryTracing is the GORM plugin tracing the queries.
+type QueryTracing struct{}
+
+// Name returns the name of the plugin.
+func (QueryTracing) Name() string {
+	return "query_tracing"
+}
+
+// Initialize registers the callbacks tracing the queries of every operation.
+func (QueryTracing) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	processors := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("*").Register, callbacks.Create().After("*").Register},
+		{"query", callbacks.Query().Before("*").Register, callbacks.Query().After("*").Register},
+		{"update", callbacks.Update().Before("*").Register, callbacks.Update().After("*").Register},
+		{"delete", callbacks.Delete().Before("*").Register, callbacks.Delete().After("*").Register},
+		{"row", callbacks.Row().Before("*").Register, callbacks.Row().After("*").Register},
+		{"raw", callbacks.Raw().Before("*").Register, callbacks.Raw().After("*").Register},
+	}
+
+	for _, p := range processors {
+		if err := p.before("query_tracing:before_"+p.operation, startQuerySpan(p.operation)); err != nil {
+			return err
+		}
+		if err := p.after("query_tracing:after_"+p.operation, endQuerySpan); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startQuerySpan returns the callback starting the span of the queries of the given operation.
+func startQuerySpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		_, span := tracing.Tracer().Start(db.Statement.Context, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "postgresql"),
+				attribute.String("db.operation.name", operation),
+			),
+		)
+		db.InstanceSet(querySpanKey, span)
+	}
+}
+
+// endQuerySpan ends the span of the query once it is run, with its table, statement, and error.
+func endQuerySpan(db *gorm.DB) {
+	v, ok := db.InstanceGet(querySpanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(
+		attribute.String("db.collection.name", queryTable(db.Statement)),
+		attribute.String("db.query.text", db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/diagnostics"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+)
+
+/**
+ * The read replicas of DB_REPLICA_DSN serve the read-heavy queries opting in with ReadReplicas, e.g. the consumer listings,
+ * so that they do not load the primary. The other queries, the writes, and the queries of a transaction stay on the primary:
+ * a replica lags behind the primary, so the reads following a write, such as the lookups of the logins, must not use it.
+ * The replicas are picked at random and sized like the primary, with the DB_MAX_OPEN_CONNS settings.
+ */
+
+// ReplicaResolver is the name of the resolver routing the queries opting in to the read replicas.
+const ReplicaResolver = "read_replicas"
+
+// replicaDBs are the connection pools of the read replicas, closed with the primary.
+var replicaDBs []*sql.DB
+
+// readReplicaDSNs returns the connection strings of the read replicas of ReadConfig, DB_REPLICA_DSN, separated by commas.
+func readReplicaDSNs() []string {
+	var dsns []string
+	for _, dsn := range strings.Split(env.Getenv("DB_REPLICA_DSN"), ",") {
+		if dsn = strings.TrimSpace(dsn); dsn != "" {
+			dsns = append(dsns, dsn)
+		}
+	}
+	return dsns
+}
+
+// ReadReplicas returns the query running on a read replica, if any are configured and it is not part of a transaction,
+// on the primary otherwise. It is meant for the read-heavy queries tolerating the replication lag.
+func ReadReplicas(tx *gorm.DB) *gorm.DB {
+	return tx.Clauses(dbresolver.Use(ReplicaResolver))
+}
+
+// useReplicas registers the read replicas of the settings on the given connection, nothing if there is none.
+// The connection pools of the replicas are sized like the one of the primary, and reported as postgres_replica_<n>.
+func useReplicas(conn *gorm.DB, cfg Config) error {
+	if len(cfg.ReplicaDSNs) == 0 {
+		return nil
+	}
+
+	dialectors := make([]gorm.Dialector, 0, len(cfg.ReplicaDSNs))
+	for i, dsn := range cfg.ReplicaDSNs {
+		sqlDB, err := sql.Open("pgx", dsn)
+		if err != nil {
+			return fmt.Errorf("failed to open read replica %d: %v", i+1, err)
+		}
+		cfg.Pool.Apply(sqlDB)
+		replicaDBs = append(replicaDBs, sqlDB)
+
+		name := fmt.Sprintf("postgres_replica_%d", i+1)
+		diagnostics.RegisterDatabasePool(name, sqlDB)
+		if metrics.Enabled() {
+			if err := metrics.RegisterDatabase(name, sqlDB); err != nil {
+				return fmt.Errorf("failed to register the connection pool metrics of read replica %d: %v", i+1, err)
+			}
+		}
+
+		dialectors = append(dialectors, postgres.New(postgres.Config{Conn: sqlDB}))
+	}
+
+	if err := UseReadReplicas(conn, dialectors...); err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("Routing the read-heavy queries to %d read replicas", len(dialectors)), nil)
+	return nil
+}
+
+// UseReadReplicas routes the queries of the given connection using ReadReplicas to the given replicas, picked at random.
+func UseReadReplicas(conn *gorm.DB, replicas ...gorm.Dialector) error {
+	// The resolver is registered under a name matching no table, so that only the queries using it are routed to the replicas
+	resolver := dbresolver.Register(dbresolver.Config{Replicas: replicas, Policy: dbresolver.RandomPolicy{}}, ReplicaResolver)
+	if err := conn.Use(resolver); err != nil {
+		return fmt.Errorf("failed to register the read replicas: %v", err)
+	}
+	return nil
+}
+
+// PingReplicas opens a short-lived connection to each read replica of the settings and pings it.
+// It is used to check the connectivity of the replicas without initializing the shared connection.
+func PingReplicas(cfg Config, timeout time.Duration) error {
+	for i, dsn := range cfg.ReplicaDSNs {
+		if err := pingReplica(dsn, timeout); err != nil {
+			return fmt.Errorf("read replica %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// pingReplica pings the read replica of the given connection string.
+func pingReplica(dsn string, timeout time.Duration) error {
+	sqlDB, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %v", err)
+	}
+	defer sqlDB.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping PostgreSQL: %v", err)
+	}
+	return nil
+}
+
+// closeReplicas closes the connection pools of the read replicas.
+func closeReplicas() {
+	for i, sqlDB := range replicaDBs {
+		diagnostics.UnregisterDatabasePool(fmt.Sprintf("postgres_replica_%d", i+1))
+		if err := sqlDB.Close(); err != nil {
+			logger.Error(fmt.Sprintf("Failed to close read replica %d: %v", i+1, err), nil)
+		}
+	}
+	replicaDBs = nil
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// DataStore provides the database instance to the services, which receive it with their constructor
+// instead of reading the connection of the process, so that a test or a second database can provide its own.
+type DataStore interface {
+	// DB returns the transaction of the request if the context carries one, opened by the transaction middleware,
+	// or the database instance bound to the context otherwise. It returns nil if the database is not available.
+	DB(ctx context.Context) *gorm.DB
+}
+
+// StoreFunc is an adapter allowing a function returning the database instance to be used as a DataStore.
+type StoreFunc func() *gorm.DB
+
+// DB returns the transaction carried by the context, or the database instance returned by the function bound to the context.
+func (f StoreFunc) DB(ctx context.Context) *gorm.DB {
+	if tx, ok := TransactionFromContext(ctx); ok {
+		return tx
+	}
+
+	db := f()
+	if db == nil {
+		return nil
+	}
+	return db.WithContext(ctx)
+}
+
+// NewStore returns a DataStore providing the given database instance, e.g. the connection of a test.
+func NewStore(db *gorm.DB) DataStore {
+	return StoreFunc(func() *gorm.DB { return db })
+}
+
+// DefaultStore returns the DataStore of the connection of the process, opened by InitPostgres or InitMemory.
+// The connection is resolved on every call, so that the services can be created before it is opened.
+func DefaultStore() DataStore {
+	return StoreFunc(GetPostgres)
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// transactionKeyType is the type of the key storing the transaction of a request in its context.
+type transactionKeyType struct{}
+
+var transactionKey = transactionKeyType{}
+
+// WithTransaction returns a copy of the context carrying the given transaction.
+// It is used by the transaction middleware to share the transaction of a request with its handlers.
+func WithTransaction(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, transactionKey, tx)
+}
+
+// TransactionFromContext returns the transaction carried by the context, if any.
+func TransactionFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(transactionKey).(*gorm.DB)
+	return tx, ok && tx != nil
+}
+
+// GetPostgresContext returns the transaction of the request if the context carries one, or the database instance bound to the context otherwise.
+// The transactions opened on the returned instance with db.Transaction are nested as savepoints in the transaction of the request,
+// so the writes of a handler are committed or rolled back together. The services get it from their DataStore the same way.
+func GetPostgresContext(ctx context.Context) *gorm.DB {
+	return DefaultStore().DB(ctx)
+}
+
+// commitHooksKeyType is the type of the key storing the commit hooks of a transaction in its context.
+type commitHooksKeyType struct{}
+
+var commitHooksKey = commitHooksKeyType{}
+
+// commitHooks holds the functions to run once a transaction is committed.
+type commitHooks struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+// add registers a function to run after the commit.
+func (h *commitHooks) add(fn func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.fns = append(h.fns, fn)
+}
+
+// len returns the number of registered functions.
+func (h *commitHooks) len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.fns)
+}
+
+// truncate drops the functions registered after the first n ones.
+func (h *commitHooks) truncate(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if n < len(h.fns) {
+		h.fns = h.fns[:n]
+	}
+}
+
+// run runs the registered functions, in their order of registration.
+func (h *commitHooks) run() {
+	h.mu.Lock()
+	fns := h.fns
+	h.fns = nil
+	h.mu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// commitHooksOf returns the commit hooks of the transaction of tx, if it was opened with Transaction or Begin.
+func commitHooksOf(tx *gorm.DB) (*commitHooks, bool) {
+	if tx == nil || tx.Statement == nil || tx.Statement.Context == nil {
+		return nil, false
+	}
+
+	hooks, ok := tx.Statement.Context.Value(commitHooksKey).(*commitHooks)
+	return hooks, ok
+}
+
+// Transaction runs fc in a transaction of db, like db.Transaction, and then the functions registered with AfterCommit
+// if the transaction is committed. A transaction nested in a transaction opened with Transaction or Begin is a savepoint,
+// whose functions run with the ones of the outer transaction, once it is committed.
+func Transaction(db *gorm.DB, fc func(tx *gorm.DB) error) error {
+	if hooks, nested := commitHooksOf(db); nested {
+		// The functions registered by a savepoint rolled back are dropped
+		n := hooks.len()
+		err := db.Transaction(fc)
+		if err != nil {
+			hooks.truncate(n)
+		}
+		return err
+	}
+
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	hooks := &commitHooks{}
+
+	if err := db.WithContext(context.WithValue(ctx, commitHooksKey, hooks)).Transaction(fc); err != nil {
+		return err
+	}
+	hooks.run()
+
+	return nil
+}
+
+// Begin begins a transaction of db, like db.Begin, whose functions registered with AfterCommit run when it is committed with Commit.
+func Begin(db *gorm.DB) *gorm.DB {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return db.WithContext(context.WithValue(ctx, commitHooksKey, &commitHooks{})).Begin()
+}
+
+// Commit commits the transaction opened with Begin, and then runs the functions registered with AfterCommit.
+func Commit(tx *gorm.DB) error {
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	if hooks, ok := commitHooksOf(tx); ok {
+		hooks.run()
+	}
+
+	return nil
+}
+
+// AfterCommit runs fn once the transaction of tx is committed, e.g. to invalidate a cache so that it is not filled again
+// with the rows read before the commit. The function is dropped if the transaction is rolled back.
+// It runs at once if tx is not in a transaction opened with Transaction or Begin, whose statements are committed when they return.
+func AfterCommit(tx *gorm.DB, fn func()) {
+	if hooks, ok := commitHooksOf(tx); ok {
+		hooks.add(fn)
+		return
+	}
+
+	fn()
+}
@@ -0,0 +1,49 @@
+package env
+
+import (
+	"maps"
+	"os"
+	"sync"
+)
+
+/**
+ * env package looks up the settings of the application by the names of their environment variables.
+ * A setting is read from the environment of the process first, then from the values of the configuration
+ * file set with SetFile, so that a deployment can override a shared file, e.g. with its secrets.
+ * The environment of the process is never modified: the values of the file are not inherited by the
+ * child processes, and a test setting a file does not change the environment of the other tests.
+ */
+
+var (
+	mu   sync.RWMutex
+	file map[string]string
+)
+
+// SetFile sets the values of the configuration file, replacing the ones set before. A nil map clears them.
+func SetFile(values map[string]string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	file = maps.Clone(values)
+}
+
+// LookupEnv returns the value of the setting, from the environment or else from the configuration file,
+// and whether it is set in either of them.
+func LookupEnv(key string) (string, bool) {
+	if value, ok := os.LookupEnv(key); ok {
+		return value, true
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	value, ok := file[key]
+	return value, ok
+}
+
+// Getenv returns the value of the setting, from the environment or else from the configuration file,
+// or an empty string if it is set in neither of them.
+func Getenv(key string) string {
+	value, _ := LookupEnv(key)
+	return value
+}
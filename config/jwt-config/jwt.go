@@ -0,0 +1,167 @@
+package jwt_config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	jwtutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
+)
+
+/**
+ * jwt_config package holds the JWT settings shared by the token issuer and the JWT validation middleware.
+ * The settings are read from the environment once, when the application is wired, into an immutable
+ * JWTConfig value that is passed to the components using it. Nothing is stored in package globals,
+ * so parallel tests can build components with different settings without interfering with each other.
+ */
+
+// DefaultAccessTokenTTL is the lifetime of the access tokens when JWT_ACCESS_TOKEN_TTL_MINUTES is missing or invalid.
+const DefaultAccessTokenTTL = 24 * time.Hour
+
+// JWTConfig holds the JWT settings. It is passed by value and never modified after it is loaded.
+type JWTConfig struct {
+	Secret        string
+	TokenType     string
+	SigningMethod string
+	Audience      string
+	Issuer        string
+	TTL           TTLPolicy
+	Keys          jwtutil.KeyFiles
+}
+
+// TTLPolicy holds the lifetimes of the access tokens, resolved when a token is issued.
+// The lifetime of the client of the login applies first, then the shortest lifetime of the roles of the user,
+// then the lifetime of the type of the user (SERVICE_ACCOUNT or USER_ACCOUNT), and finally the default one.
+type TTLPolicy struct {
+	Default   time.Duration
+	UserTypes map[string]time.Duration
+	Roles     map[string]time.Duration
+	Clients   map[string]time.Duration
+}
+
+// Load reads the JWT settings from the environment variables.
+func Load() JWTConfig {
+	return JWTConfig{
+		Secret:        env.Getenv("JWT_SECRET"),
+		TokenType:     env.Getenv("TOKEN_TYPE"),
+		SigningMethod: env.Getenv("JWT_ALGORITHM"),
+		Audience:      env.Getenv("JWT_AUDIENCE"),
+		Issuer:        env.Getenv("JWT_ISSUER"),
+		TTL:           LoadTTLPolicy(),
+		Keys:          LoadKeyFiles(),
+	}
+}
+
+// LoadKeyFiles reads the paths of the key files of the RS256, ES256, and EdDSA tokens from the environment variables.
+func LoadKeyFiles() jwtutil.KeyFiles {
+	return jwtutil.KeyFiles{
+		PublicKeyPath:  env.Getenv("JWT_PUBLIC_KEY_PATH"),
+		PrivateKeyPath: env.Getenv("JWT_PRIVATE_KEY_PATH"),
+		KeySetDir:      env.Getenv("JWT_KEYSET_DIR"),
+	}
+}
+
+// AudienceIssuerOptions returns the parser options rejecting the tokens whose aud claim does not contain the audience
+// of the settings, or whose iss claim is not the issuer of the settings, e.g. the tokens minted for other services
+// sharing the signing key. An empty audience or issuer is not checked.
+func (c JWTConfig) AudienceIssuerOptions() []jwt.ParserOption {
+	var opts []jwt.ParserOption
+	if c.Audience != "" {
+		opts = append(opts, jwt.WithAudience(c.Audience))
+	}
+	if c.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(c.Issuer))
+	}
+
+	return opts
+}
+
+// LoadTTLPolicy reads the lifetimes of the access tokens from the environment variables.
+// The overrides are comma separated lists of name=minutes pairs, e.g. "SERVICE_ACCOUNT=43200,USER_ACCOUNT=120".
+// Invalid values are ignored, the configuration validation reports them at boot.
+func LoadTTLPolicy() TTLPolicy {
+	policy := TTLPolicy{Default: DefaultAccessTokenTTL}
+	if n, err := strconv.Atoi(env.Getenv("JWT_ACCESS_TOKEN_TTL_MINUTES")); err == nil && n > 0 {
+		policy.Default = time.Duration(n) * time.Minute
+	}
+
+	policy.UserTypes, _ = ParseTTLOverrides(env.Getenv("JWT_ACCESS_TOKEN_TTL_BY_USER_TYPE"))
+	policy.Roles, _ = ParseTTLOverrides(env.Getenv("JWT_ACCESS_TOKEN_TTL_BY_ROLE"))
+	policy.Clients, _ = ParseTTLOverrides(env.Getenv("JWT_ACCESS_TOKEN_TTL_BY_CLIENT"))
+
+	return policy
+}
+
+// ParseTTLOverrides parses a comma separated list of name=minutes pairs.
+// It returns an error if a pair is malformed or its lifetime is not a positive number of minutes.
+func ParseTTLOverrides(value string) (map[string]time.Duration, error) {
+	overrides := make(map[string]time.Duration)
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		name, minutes, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid TTL override %q, expected name=minutes", pair)
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(minutes))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid TTL override %q, the minutes must be a positive integer", pair)
+		}
+		overrides[name] = time.Duration(n) * time.Minute
+	}
+
+	return overrides, nil
+}
+
+// Resolve returns the lifetime of an access token issued to a user of the given type and roles for the given client.
+func (p TTLPolicy) Resolve(userType string, roles []string, clientID string) time.Duration {
+	if ttl, ok := p.Clients[clientID]; ok && clientID != "" {
+		return ttl
+	}
+
+	var roleTTL time.Duration
+	for _, role := range roles {
+		if ttl, ok := p.Roles[role]; ok && (roleTTL == 0 || ttl < roleTTL) {
+			roleTTL = ttl
+		}
+	}
+	if roleTTL > 0 {
+		return roleTTL
+	}
+
+	if ttl, ok := p.UserTypes[userType]; ok {
+		return ttl
+	}
+
+	if p.Default <= 0 {
+		return DefaultAccessTokenTTL
+	}
+
+	return p.Default
+}
+
+// Max returns the longest lifetime an access token can be issued with.
+func (p TTLPolicy) Max() time.Duration {
+	max := p.Default
+	if max <= 0 {
+		max = DefaultAccessTokenTTL
+	}
+
+	for _, overrides := range []map[string]time.Duration{p.UserTypes, p.Roles, p.Clients} {
+		for _, ttl := range overrides {
+			if ttl > max {
+				max = ttl
+			}
+		}
+	}
+
+	return max
+}
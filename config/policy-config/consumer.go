@@ -0,0 +1,231 @@
+package policy_config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// DefaultConsumerListingExclusions is used when CONSUMER_LISTING_EXCLUDED_STATUSES is not set:
+// the users do not see the suspended consumers, while the administrators see all of them.
+const DefaultConsumerListingExclusions = "ROLE_USER=suspended,ROLE_ADMIN="
+
+const (
+	// DefaultWebhookMaxAttempts is the number of attempts of a delivery before it is dead-lettered.
+	DefaultWebhookMaxAttempts = 5
+
+	// DefaultWebhookBackoff is the delay before the first retry, doubled after every failed attempt.
+	DefaultWebhookBackoff = 30 * time.Second
+
+	// DefaultWebhookMaxBackoff is the maximum delay between two attempts.
+	DefaultWebhookMaxBackoff = time.Hour
+
+	// DefaultWebhookTimeout is the time the receiver has to answer an attempt.
+	DefaultWebhookTimeout = 10 * time.Second
+)
+
+// ConsumerListingPolicy holds, per role, the consumer statuses left out of the consumer listing.
+// A caller with several roles sees a status if at least one of their roles does, and roles without an entry see all the statuses.
+type ConsumerListingPolicy struct {
+	ExcludedStatuses map[string][]string
+}
+
+// LoadConsumerListingPolicy reads the consumer listing policy from the environment variables.
+// Missing or invalid values fall back to the defaults, the configuration validation reports them at boot.
+func LoadConsumerListingPolicy() ConsumerListingPolicy {
+	value, ok := env.LookupEnv("CONSUMER_LISTING_EXCLUDED_STATUSES")
+	if !ok {
+		value = DefaultConsumerListingExclusions
+	}
+
+	policy, err := ParseConsumerListingPolicy(value)
+	if err != nil {
+		policy, _ = ParseConsumerListingPolicy(DefaultConsumerListingExclusions)
+	}
+
+	return policy
+}
+
+// ParseConsumerListingPolicy parses a comma separated list of role=statuses pairs, the statuses being separated by "|",
+// e.g. "ROLE_USER=suspended|inactive,ROLE_ADMIN=". It returns an error if a pair is malformed or a status is unknown.
+func ParseConsumerListingPolicy(value string) (ConsumerListingPolicy, error) {
+	policy := ConsumerListingPolicy{ExcludedStatuses: make(map[string][]string)}
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		role, statuses, ok := strings.Cut(pair, "=")
+		role = strings.TrimSpace(role)
+		if !ok || role == "" {
+			return ConsumerListingPolicy{}, fmt.Errorf("invalid consumer listing exclusion %q, expected role=statuses", pair)
+		}
+
+		excluded := []string{}
+		for _, status := range strings.Split(statuses, "|") {
+			status = strings.ToLower(strings.TrimSpace(status))
+			switch status {
+			case "":
+				continue
+			case entity.ConsumerStatusActive, entity.ConsumerStatusInactive, entity.ConsumerStatusSuspended:
+				excluded = append(excluded, status)
+			default:
+				return ConsumerListingPolicy{}, fmt.Errorf("invalid consumer listing exclusion %q, unknown status %q", pair, status)
+			}
+		}
+		policy.ExcludedStatuses[role] = excluded
+	}
+
+	return policy, nil
+}
+
+// Excluded returns the consumer statuses left out of the listing for a caller with the given roles,
+// which are the statuses excluded by every one of their roles.
+func (p ConsumerListingPolicy) Excluded(roles []string) []string {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	var excluded []string
+	for i, role := range roles {
+		statuses, ok := p.ExcludedStatuses[role]
+		if !ok {
+			return nil
+		}
+
+		if i == 0 {
+			excluded = append(excluded, statuses...)
+			continue
+		}
+
+		kept := excluded[:0]
+		for _, status := range excluded {
+			for _, s := range statuses {
+				if s == status {
+					kept = append(kept, status)
+					break
+				}
+			}
+		}
+		excluded = kept
+	}
+
+	return excluded
+}
+
+// ConsumerRules holds the validation rules of the consumers set per deployment, checked by the consumer service
+// on top of the validation of the fields, so that a deployment can enforce its own data policy without a rebuild:
+// the metadata keys every consumer must have, the pattern of the usernames, and the email domains refused.
+// The zero value has no rule.
+type ConsumerRules struct {
+	RequiredMetadataKeys []string
+	UsernamePattern      *regexp.Regexp
+	BlockedEmailDomains  []string
+}
+
+// LoadConsumerRules reads the consumer rules from the environment variables: CONSUMER_REQUIRED_METADATA_KEYS,
+// CONSUMER_USERNAME_PATTERN, and CONSUMER_BLOCKED_EMAIL_DOMAINS.
+// Invalid values are ignored, the configuration validation reports them at boot.
+func LoadConsumerRules() ConsumerRules {
+	rules, err := ParseConsumerRules(env.Getenv("CONSUMER_REQUIRED_METADATA_KEYS"), env.Getenv("CONSUMER_USERNAME_PATTERN"), env.Getenv("CONSUMER_BLOCKED_EMAIL_DOMAINS"))
+	if err != nil {
+		return ConsumerRules{}
+	}
+
+	return rules
+}
+
+// ParseConsumerRules parses the consumer rules: a comma separated list of required metadata keys, e.g. "tenant,region",
+// a regular expression the whole username must match, e.g. "[a-z][a-z0-9_.]*", and a comma separated list of blocked
+// email domains, e.g. "mailinator.com,example.org", whose subdomains are blocked as well. Empty values set no rule.
+// It returns an error if the pattern is not a valid regular expression or a domain is not a host name.
+func ParseConsumerRules(requiredKeys string, usernamePattern string, blockedDomains string) (ConsumerRules, error) {
+	var rules ConsumerRules
+	for _, key := range strings.Split(requiredKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			rules.RequiredMetadataKeys = append(rules.RequiredMetadataKeys, key)
+		}
+	}
+
+	if pattern := strings.TrimSpace(usernamePattern); pattern != "" {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return ConsumerRules{}, fmt.Errorf("invalid username pattern %q: %w", pattern, err)
+		}
+		rules.UsernamePattern = re
+	}
+
+	for _, domain := range strings.Split(blockedDomains, ",") {
+		domain = strings.ToLower(strings.Trim(strings.TrimSpace(domain), "."))
+		if domain == "" {
+			continue
+		}
+		if strings.ContainsAny(domain, "@/: ") {
+			return ConsumerRules{}, fmt.Errorf("invalid blocked email domain %q, expected a domain such as example.com", domain)
+		}
+		rules.BlockedEmailDomains = append(rules.BlockedEmailDomains, domain)
+	}
+
+	return rules, nil
+}
+
+// WebhookPolicy holds the URL the consumer events are sent to, and the retries of the failed deliveries.
+// The webhooks are disabled when the URL is empty.
+type WebhookPolicy struct {
+	URL         string
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Timeout     time.Duration
+}
+
+// LoadWebhookPolicy reads the webhook policy from the environment variables.
+// Missing or invalid values fall back to the defaults.
+func LoadWebhookPolicy() WebhookPolicy {
+	policy := WebhookPolicy{
+		URL:         env.Getenv("CONSUMER_WEBHOOK_URL"),
+		MaxAttempts: DefaultWebhookMaxAttempts,
+		Backoff:     DefaultWebhookBackoff,
+		MaxBackoff:  DefaultWebhookMaxBackoff,
+		Timeout:     DefaultWebhookTimeout,
+	}
+
+	if n, err := strconv.Atoi(env.Getenv("CONSUMER_WEBHOOK_MAX_ATTEMPTS")); err == nil && n > 0 {
+		policy.MaxAttempts = n
+	}
+	if n, err := strconv.Atoi(env.Getenv("CONSUMER_WEBHOOK_BACKOFF_SECOND")); err == nil && n > 0 {
+		policy.Backoff = time.Duration(n) * time.Second
+	}
+	if n, err := strconv.Atoi(env.Getenv("CONSUMER_WEBHOOK_MAX_BACKOFF_SECOND")); err == nil && n > 0 {
+		policy.MaxBackoff = time.Duration(n) * time.Second
+	}
+	if n, err := strconv.Atoi(env.Getenv("CONSUMER_WEBHOOK_TIMEOUT_SECOND")); err == nil && n > 0 {
+		policy.Timeout = time.Duration(n) * time.Second
+	}
+
+	return policy
+}
+
+// Enabled reports whether the consumer events are sent to a webhook.
+func (p WebhookPolicy) Enabled() bool {
+	return p.URL != ""
+}
+
+// RetryDelay returns the delay before the next attempt of a delivery that failed the given number of times:
+// the backoff doubled after every failed attempt, up to the maximum backoff.
+func (p WebhookPolicy) RetryDelay(attempts int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempts && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+
+	return delay
+}
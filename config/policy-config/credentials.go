@@ -0,0 +1,133 @@
+package policy_config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+)
+
+const (
+	// DefaultMaxSessions is the number of active sessions per user when MAX_SESSIONS_PER_USER is missing or invalid.
+	DefaultMaxSessions = 5
+
+	// DefaultRefreshTokenTTL is the lifetime of the refresh tokens when JWT_REFRESH_TOKEN_EXPIRATION_HOUR is missing or invalid.
+	DefaultRefreshTokenTTL = 24 * time.Hour
+
+	SessionLimitModeEvictOldest = "EVICT_OLDEST"
+	SessionLimitModeReject      = "REJECT"
+)
+
+const (
+	// DefaultCredentialsTTL is the lifetime of the passwords when CREDENTIALS_TTL_DAYS is missing or invalid.
+	DefaultCredentialsTTL = 90 * 24 * time.Hour
+
+	// DefaultPasswordResetTokenTTL is the lifetime of the password reset tokens when PASSWORD_RESET_TOKEN_TTL_MINUTES is missing or invalid.
+	DefaultPasswordResetTokenTTL = 30 * time.Minute
+)
+
+const (
+	// DefaultMFATokenTTL is the lifetime of the MFA tokens when MFA_TOKEN_TTL_MINUTES is missing or invalid.
+	DefaultMFATokenTTL = 5 * time.Minute
+
+	// DefaultMFAIssuer is the name of the service shown by the authenticator apps when MFA_ISSUER is not set.
+	DefaultMFAIssuer = "Go JWT Auth Demo"
+)
+
+// SessionPolicy holds the limit of active sessions per user, what happens when it is exceeded, and the lifetime of the refresh tokens.
+type SessionPolicy struct {
+	MaxSessions     int
+	Mode            string
+	RefreshTokenTTL time.Duration
+}
+
+// LoadSessionPolicy reads the session policy from the environment variables.
+// Missing or invalid values fall back to the defaults.
+func LoadSessionPolicy() SessionPolicy {
+	policy := SessionPolicy{MaxSessions: DefaultMaxSessions, Mode: SessionLimitModeEvictOldest, RefreshTokenTTL: DefaultRefreshTokenTTL}
+
+	if n, err := strconv.Atoi(env.Getenv("MAX_SESSIONS_PER_USER")); err == nil && n > 0 {
+		policy.MaxSessions = n
+	}
+	if strings.EqualFold(env.Getenv("SESSION_LIMIT_MODE"), SessionLimitModeReject) {
+		policy.Mode = SessionLimitModeReject
+	}
+	if n, err := strconv.Atoi(env.Getenv("JWT_REFRESH_TOKEN_EXPIRATION_HOUR")); err == nil && n > 0 {
+		policy.RefreshTokenTTL = time.Duration(n) * time.Hour
+	}
+
+	return policy
+}
+
+// PasswordPolicy holds the lifetime of the passwords, 0 if they never expire.
+type PasswordPolicy struct {
+	CredentialsTTL time.Duration
+}
+
+// LoadPasswordPolicy reads the password policy from the environment variables.
+// CREDENTIALS_TTL_DAYS=0 disables the expiration of the passwords, missing or invalid values fall back to the default.
+func LoadPasswordPolicy() PasswordPolicy {
+	policy := PasswordPolicy{CredentialsTTL: DefaultCredentialsTTL}
+
+	if n, err := strconv.Atoi(env.Getenv("CREDENTIALS_TTL_DAYS")); err == nil && n >= 0 {
+		policy.CredentialsTTL = time.Duration(n) * 24 * time.Hour
+	}
+
+	return policy
+}
+
+// CredentialsExpiration returns the expiration date of a password set at the given time, or nil if the passwords never expire.
+func (p PasswordPolicy) CredentialsExpiration(now time.Time) *time.Time {
+	if p.CredentialsTTL <= 0 {
+		return nil
+	}
+
+	expiresAt := now.Add(p.CredentialsTTL)
+	return &expiresAt
+}
+
+// PasswordResetPolicy holds the lifetime of the password reset tokens, the URL of the reset page of the frontend,
+// and the policy setting the expiration date of the new passwords.
+type PasswordResetPolicy struct {
+	TokenTTL    time.Duration
+	ResetURL    string
+	Credentials PasswordPolicy
+}
+
+// LoadPasswordResetPolicy reads the password reset policy from the environment variables.
+// PASSWORD_RESET_URL is the page the links point to, it defaults to the reset-password page of FRONTEND_URL.
+// Missing or invalid values fall back to the defaults.
+func LoadPasswordResetPolicy() PasswordResetPolicy {
+	policy := PasswordResetPolicy{TokenTTL: DefaultPasswordResetTokenTTL, ResetURL: env.Getenv("PASSWORD_RESET_URL"), Credentials: LoadPasswordPolicy()}
+
+	if n, err := strconv.Atoi(env.Getenv("PASSWORD_RESET_TOKEN_TTL_MINUTES")); err == nil && n > 0 {
+		policy.TokenTTL = time.Duration(n) * time.Minute
+	}
+	if policy.ResetURL == "" {
+		policy.ResetURL = strings.TrimRight(env.Getenv("FRONTEND_URL"), "/") + "/reset-password"
+	}
+
+	return policy
+}
+
+// MFAPolicy holds the name of the service shown by the authenticator apps and the lifetime of the MFA tokens.
+type MFAPolicy struct {
+	Issuer   string
+	TokenTTL time.Duration
+}
+
+// LoadMFAPolicy reads the two-factor authentication policy from the environment variables.
+// Missing or invalid values fall back to the defaults.
+func LoadMFAPolicy() MFAPolicy {
+	policy := MFAPolicy{Issuer: env.Getenv("MFA_ISSUER"), TokenTTL: DefaultMFATokenTTL}
+
+	if policy.Issuer == "" {
+		policy.Issuer = DefaultMFAIssuer
+	}
+	if n, err := strconv.Atoi(env.Getenv("MFA_TOKEN_TTL_MINUTES")); err == nil && n > 0 {
+		policy.TokenTTL = time.Duration(n) * time.Minute
+	}
+
+	return policy
+}
@@ -0,0 +1,179 @@
+package policy_config
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+)
+
+const (
+	// DefaultReadRetries is the number of times a read failing with a transient error is retried.
+	DefaultReadRetries = 2
+
+	// DefaultReadRetryDelay is the maximum delay before the first retry, doubled after every attempt.
+	DefaultReadRetryDelay = 50 * time.Millisecond
+
+	// DefaultReadRetryMaxDelay is the maximum delay between two attempts.
+	DefaultReadRetryMaxDelay = time.Second
+)
+
+const (
+	// DefaultWriteBufferSize is the maximum number of writes waiting to be retried.
+	DefaultWriteBufferSize = 1000
+
+	// DefaultWriteBufferTimeout is how long a write is retried before it is dropped.
+	DefaultWriteBufferTimeout = time.Minute
+
+	// DefaultWriteBufferRetryInterval is how often the pending writes are retried.
+	DefaultWriteBufferRetryInterval = time.Second
+)
+
+// Kinds of user stores, set by USER_STORE.
+const (
+	UserStoreDatabase = "database"
+	UserStoreREST     = "rest"
+	UserStoreSCIM     = "scim"
+)
+
+// DefaultUserStoreTimeout is the time an external user store has to answer a request,
+// when USER_STORE_TIMEOUT_SECOND is not set or invalid.
+const DefaultUserStoreTimeout = 5 * time.Second
+
+// RetryPolicy holds the number of retries of the reads failing with a transient error, and the delays between the attempts.
+// The reads are not retried when Retries is 0.
+type RetryPolicy struct {
+	Retries  int
+	Delay    time.Duration
+	MaxDelay time.Duration
+
+	// Sleep waits between the attempts, e.g. in the tests; when nil, the attempts wait for the delay or the end of the context of the read.
+	Sleep func(time.Duration)
+}
+
+// LoadRetryPolicy reads the retry policy of the reads from the environment variables.
+// Missing or invalid values fall back to the defaults.
+func LoadRetryPolicy() RetryPolicy {
+	policy := RetryPolicy{
+		Retries:  DefaultReadRetries,
+		Delay:    DefaultReadRetryDelay,
+		MaxDelay: DefaultReadRetryMaxDelay,
+	}
+
+	if n, err := strconv.Atoi(env.Getenv("DB_READ_RETRIES")); err == nil && n >= 0 {
+		policy.Retries = n
+	}
+	if n, err := strconv.Atoi(env.Getenv("DB_READ_RETRY_DELAY_MS")); err == nil && n > 0 {
+		policy.Delay = time.Duration(n) * time.Millisecond
+	}
+	if policy.MaxDelay < policy.Delay {
+		policy.MaxDelay = policy.Delay
+	}
+
+	return policy
+}
+
+// Enabled reports whether the reads failing with a transient error are retried.
+func (p RetryPolicy) Enabled() bool {
+	return p.Retries > 0
+}
+
+// RetryDelay returns the delay before the given retry (1 for the first one): a random duration up to the delay
+// doubled after every attempt, itself up to the maximum delay.
+func (p RetryPolicy) RetryDelay(retry int) time.Duration {
+	ceiling := p.Delay
+	for i := 1; i < retry && ceiling < p.MaxDelay; i++ {
+		ceiling *= 2
+	}
+	if ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+
+	return rand.N(ceiling + 1)
+}
+
+// WriteBufferPolicy holds whether the failed writes are buffered, how many of them, for how long, and how often they are retried.
+type WriteBufferPolicy struct {
+	Enabled       bool
+	Size          int
+	Timeout       time.Duration
+	RetryInterval time.Duration
+}
+
+// LoadWriteBufferPolicy reads the policy of the write buffer from the environment variables.
+// Missing or invalid values fall back to the defaults.
+func LoadWriteBufferPolicy() WriteBufferPolicy {
+	policy := WriteBufferPolicy{
+		Enabled:       env.Getenv("DB_WRITE_BUFFER") == "TRUE",
+		Size:          DefaultWriteBufferSize,
+		Timeout:       DefaultWriteBufferTimeout,
+		RetryInterval: DefaultWriteBufferRetryInterval,
+	}
+
+	if n, err := strconv.Atoi(env.Getenv("DB_WRITE_BUFFER_SIZE")); err == nil && n > 0 {
+		policy.Size = n
+	}
+	if n, err := strconv.Atoi(env.Getenv("DB_WRITE_BUFFER_TIMEOUT_SECOND")); err == nil && n > 0 {
+		policy.Timeout = time.Duration(n) * time.Second
+	}
+	if n, err := strconv.Atoi(env.Getenv("DB_WRITE_BUFFER_RETRY_INTERVAL_MS")); err == nil && n > 0 {
+		policy.RetryInterval = time.Duration(n) * time.Millisecond
+	}
+
+	return policy
+}
+
+// UserStoreConfig holds the settings of the user store.
+type UserStoreConfig struct {
+	Kind    string
+	URL     string
+	Timeout time.Duration
+}
+
+// LoadUserStoreConfig reads the settings of the user store from USER_STORE (database, rest, or scim, database by default),
+// USER_STORE_URL, the base URL of an external store, and USER_STORE_TIMEOUT_SECOND.
+func LoadUserStoreConfig() UserStoreConfig {
+	cfg := UserStoreConfig{
+		Kind:    strings.ToLower(strings.TrimSpace(env.Getenv("USER_STORE"))),
+		URL:     strings.TrimSuffix(strings.TrimSpace(env.Getenv("USER_STORE_URL")), "/"),
+		Timeout: DefaultUserStoreTimeout,
+	}
+	if cfg.Kind == "" {
+		cfg.Kind = UserStoreDatabase
+	}
+	if n, err := strconv.Atoi(env.Getenv("USER_STORE_TIMEOUT_SECOND")); err == nil && n > 0 {
+		cfg.Timeout = time.Duration(n) * time.Second
+	}
+
+	return cfg
+}
+
+// IsExternal reports whether the users are read from an external store instead of the database.
+func (c UserStoreConfig) IsExternal() bool {
+	return c.Kind != UserStoreDatabase
+}
+
+// Check returns an error if the settings of an external store are invalid.
+func (c UserStoreConfig) Check() error {
+	switch c.Kind {
+	case UserStoreDatabase:
+		return nil
+	case UserStoreREST, UserStoreSCIM:
+	default:
+		return fmt.Errorf("unknown user store %q, must be %s, %s, or %s", c.Kind, UserStoreDatabase, UserStoreREST, UserStoreSCIM)
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("USER_STORE_URL must be the absolute http(s) URL of the %s user store", c.Kind)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package policy_config
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+)
+
+/**
+ * policy_config package holds the policies of the services and the repositories set per deployment,
+ * such as the session limits, the retries of the reads, the consumer rules, or the webhook retries.
+ * They are read once at startup into the Config of the app_config package, and passed to the constructors
+ * of the services and the repositories when the routes are wired. The package only depends on the model,
+ * so the configuration packages never import the services or the repositories they configure.
+ */
+
+const (
+	// DefaultSecuritySettingsCacheTTL is the age at which an instance reloads the security settings,
+	// when SECURITY_SETTINGS_CACHE_TTL_SECOND is not set or invalid.
+	DefaultSecuritySettingsCacheTTL = 30 * time.Second
+
+	// DefaultFeatureFlagsCacheTTL is the age at which an instance reloads the feature flags,
+	// when FEATURE_FLAGS_CACHE_TTL_SECOND is not set or invalid.
+	DefaultFeatureFlagsCacheTTL = 30 * time.Second
+
+	// DefaultRateLimitOverridesCacheTTL is the age at which an instance reloads the rate limit overrides,
+	// when RATE_LIMIT_OVERRIDES_CACHE_TTL_SECOND is not set or invalid.
+	DefaultRateLimitOverridesCacheTTL = 30 * time.Second
+)
+
+// LoadKeyRotationInterval reads the age at which the signing key is rotated at startup from JWT_KEY_ROTATION_DAYS.
+// It returns 0 if it is not set or invalid, the key is then only rotated by the administrators.
+func LoadKeyRotationInterval() time.Duration {
+	if n, err := strconv.Atoi(env.Getenv("JWT_KEY_ROTATION_DAYS")); err == nil && n > 0 {
+		return time.Duration(n) * 24 * time.Hour
+	}
+
+	return 0
+}
+
+// LoadSecuritySettingsCacheTTL reads the age at which the security settings are reloaded from SECURITY_SETTINGS_CACHE_TTL_SECOND.
+// 0 reloads them only after a change made by the instance itself, for the deployments with a single instance.
+func LoadSecuritySettingsCacheTTL() time.Duration {
+	return cacheTTL("SECURITY_SETTINGS_CACHE_TTL_SECOND", DefaultSecuritySettingsCacheTTL)
+}
+
+// LoadFeatureFlagsCacheTTL reads the age at which the feature flags are reloaded from FEATURE_FLAGS_CACHE_TTL_SECOND.
+// 0 reloads them only after a change made by the instance itself, for the deployments with a single instance.
+func LoadFeatureFlagsCacheTTL() time.Duration {
+	return cacheTTL("FEATURE_FLAGS_CACHE_TTL_SECOND", DefaultFeatureFlagsCacheTTL)
+}
+
+// LoadRateLimitOverridesCacheTTL reads the age at which the rate limit overrides are reloaded from RATE_LIMIT_OVERRIDES_CACHE_TTL_SECOND.
+// 0 reloads them only after a change made by the instance itself, for the deployments with a single instance.
+func LoadRateLimitOverridesCacheTTL() time.Duration {
+	return cacheTTL("RATE_LIMIT_OVERRIDES_CACHE_TTL_SECOND", DefaultRateLimitOverridesCacheTTL)
+}
+
+// cacheTTL returns the number of seconds of the environment variable as a duration, or the default value if it is missing or invalid.
+func cacheTTL(key string, def time.Duration) time.Duration {
+	if n, err := strconv.Atoi(env.Getenv(key)); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second
+	}
+
+	return def
+}
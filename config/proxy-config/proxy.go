@@ -0,0 +1,83 @@
+package proxy_config
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+)
+
+/**
+ * proxy_config package configures how the real client IP of a request is resolved.
+ * By default no proxy is trusted: the client IP is the address of the TCP peer and the forwarding
+ * headers are ignored, so they cannot be spoofed. Behind a load balancer, TRUSTED_PROXIES lists the
+ * IPs or CIDRs of the proxies, and the client IP is read from REMOTE_IP_HEADERS (X-Forwarded-For and
+ * X-Real-IP by default) only when the request comes from one of them. In X-Forwarded-For, the addresses
+ * are read from right to left and the first one that is not a trusted proxy is the client IP, so the
+ * entries prepended by the client itself are never used.
+ */
+
+// DefaultRemoteIPHeaders are the headers holding the client IP set by the trusted proxies, in order of preference.
+var DefaultRemoteIPHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+
+// Config holds the trusted proxy settings. It is passed by value and never modified after it is loaded.
+type Config struct {
+	TrustedProxies  []string
+	RemoteIPHeaders []string
+}
+
+// Load reads the trusted proxy settings from the TRUSTED_PROXIES and REMOTE_IP_HEADERS environment variables.
+// Both are comma-separated lists. It returns an error if a trusted proxy is neither an IP nor a CIDR.
+func Load() (Config, error) {
+	cfg := Config{
+		TrustedProxies:  splitList(env.Getenv("TRUSTED_PROXIES")),
+		RemoteIPHeaders: splitList(env.Getenv("REMOTE_IP_HEADERS")),
+	}
+	if len(cfg.RemoteIPHeaders) == 0 {
+		cfg.RemoteIPHeaders = append([]string{}, DefaultRemoteIPHeaders...)
+	}
+
+	for _, proxy := range cfg.TrustedProxies {
+		if net.ParseIP(proxy) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil {
+			return Config{}, fmt.Errorf("TRUSTED_PROXIES entry %q is neither an IP nor a CIDR", proxy)
+		}
+	}
+	for i, header := range cfg.RemoteIPHeaders {
+		cfg.RemoteIPHeaders[i] = http.CanonicalHeaderKey(header)
+	}
+
+	return cfg, nil
+}
+
+// Apply configures the router to resolve the client IP with the settings.
+// Without trusted proxies, the forwarding headers are ignored.
+func (c Config) Apply(r *gin.Engine) error {
+	r.ForwardedByClientIP = len(c.TrustedProxies) > 0
+	r.RemoteIPHeaders = append([]string{}, c.RemoteIPHeaders...)
+
+	var proxies []string
+	if len(c.TrustedProxies) > 0 {
+		proxies = append(proxies, c.TrustedProxies...)
+	}
+
+	return r.SetTrustedProxies(proxies)
+}
+
+// splitList splits a comma-separated list, trimming the entries and skipping the empty ones.
+func splitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
@@ -0,0 +1,686 @@
+package validate
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	jwtconfig "github.com/yoanesber/go-jwt-auth-demo/config/jwt-config"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	proxyconfig "github.com/yoanesber/go-jwt-auth-demo/config/proxy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/alerting"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/anomaly"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/basepath"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/cache"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/captcha"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/chaos"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/eventbus"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/featureflag"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/fieldcrypt"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/geoip"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/localeformat"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/mailer"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/masking"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/oidc"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/quota"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/ratelimit"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/secrets"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/signedurl"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/tracing"
+	jwtutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+)
+
+/**
+ * validate package provides a configuration validation pass executed at boot.
+ * It checks the application, JWT, database, pagination, and proxy settings and collects every problem found,
+ * so that all misconfigurations can be reported at once instead of failing on the first one.
+ */
+
+const (
+	// Minimum length of the HMAC secret in bytes (256 bits)
+	minSecretLength = 32
+
+	// Timeout used when checking the database connectivity
+	dbPingTimeout = 5 * time.Second
+)
+
+// Problems represents the list of configuration problems found during validation.
+type Problems []string
+
+// add appends a formatted problem to the list.
+func (p *Problems) add(format string, args ...interface{}) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+// Run validates the configuration and returns all problems found.
+// If checkDB is true, it also checks the connectivity to the database.
+func Run(checkDB bool) Problems {
+	var problems Problems
+
+	validateServer(&problems)
+	validateJWT(&problems)
+	validateDatabase(&problems, checkDB)
+	validateUserStore(&problems)
+	validatePagination(&problems)
+	validateSessions(&problems)
+	validatePasswordReset(&problems)
+	validateMFA(&problems)
+	validateConsumerListing(&problems)
+	validateConsumerRules(&problems)
+	validateConsumerCache(&problems)
+	validateSecuritySettingsCache(&problems)
+	validateFeatureFlags(&problems)
+	validateConsumerWebhooks(&problems)
+	validateRateLimits(&problems)
+	validateRateLimitOverrides(&problems)
+	validateQuotas(&problems)
+	validateWarmUp(&problems)
+	validateShutdown(&problems)
+	validateProxies(&problems)
+	validateMailer(&problems)
+	validateAlerting(&problems)
+	validateRedis(&problems)
+	validateEvents(&problems)
+	validateGeoIP(&problems)
+	validateAnomaly(&problems)
+	validateTracing(&problems)
+	validateSLO(&problems)
+	validateSignedURLs(&problems)
+	validateOIDC(&problems)
+	validatePIIEncryption(&problems)
+	validateDataMasking(&problems)
+	validateLocaleFormatting(&problems)
+	validateChaos(&problems)
+
+	return problems
+}
+
+// validateServer checks the application and TLS settings.
+func validateServer(p *Problems) {
+	for _, key := range []string{"ENV", "PORT", "IS_SSL", "API_VERSION"} {
+		if env.Getenv(key) == "" {
+			p.add("%s is not set", key)
+		}
+	}
+
+	if port := env.Getenv("PORT"); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			p.add("PORT must be a number between 1 and 65535, got %q", port)
+		}
+	}
+
+	if env.Getenv("IS_SSL") == "TRUE" {
+		certOK := checkReadableFile(p, "SSL_CERT")
+		keyOK := checkReadableFile(p, "SSL_KEYS")
+		if certOK && keyOK {
+			if _, err := tls.LoadX509KeyPair(env.Getenv("SSL_CERT"), env.Getenv("SSL_KEYS")); err != nil {
+				p.add("SSL_CERT and SSL_KEYS must be a matching PEM certificate and key: %v", err)
+			}
+		}
+	}
+
+	// 0 disables the checks of the certificate files, the certificate being reloaded only on SIGHUP
+	if v := env.Getenv("TLS_RELOAD_INTERVAL_SECOND"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			p.add("TLS_RELOAD_INTERVAL_SECOND must be a non-negative integer, got %q", v)
+		}
+	}
+
+	if _, err := basepath.Load(); err != nil {
+		p.add("%v", err)
+	}
+}
+
+// validateJWT checks the JWT settings such as secret strength, key files, and TTLs.
+func validateJWT(p *Problems) {
+	keys := jwtconfig.Load().Keys
+	if env.Getenv("TOKEN_TYPE") == "" {
+		p.add("TOKEN_TYPE is not set")
+	}
+
+	switch alg := env.Getenv("JWT_ALGORITHM"); alg {
+	case jwt.SigningMethodHS256.Alg():
+		secret := env.Getenv("JWT_SECRET")
+		if secret == "" {
+			p.add("JWT_SECRET is required when JWT_ALGORITHM is HS256")
+		} else if len(secret) < minSecretLength {
+			p.add("JWT_SECRET must be at least %d bytes long, got %d", minSecretLength, len(secret))
+		}
+	case jwt.SigningMethodRS256.Alg():
+		if keys.KeySetDir != "" {
+			validateKeySet(p, keys)
+			break
+		}
+		if checkReadableFile(p, "JWT_PRIVATE_KEY_PATH") {
+			if _, err := keys.LoadPrivateKey(); err != nil {
+				p.add("JWT_PRIVATE_KEY_PATH does not contain a valid RSA private key: %v", err)
+			}
+		}
+		if checkReadableFile(p, "JWT_PUBLIC_KEY_PATH") {
+			if _, err := keys.LoadPublicKey(); err != nil {
+				p.add("JWT_PUBLIC_KEY_PATH does not contain a valid RSA public key: %v", err)
+			}
+		}
+	case jwt.SigningMethodES256.Alg():
+		if checkReadableFile(p, "JWT_PRIVATE_KEY_PATH") {
+			if _, err := keys.LoadECPrivateKey(); err != nil {
+				p.add("JWT_PRIVATE_KEY_PATH does not contain a valid ECDSA P-256 private key: %v", err)
+			}
+		}
+		if checkReadableFile(p, "JWT_PUBLIC_KEY_PATH") {
+			if _, err := keys.LoadECPublicKey(); err != nil {
+				p.add("JWT_PUBLIC_KEY_PATH does not contain a valid ECDSA P-256 public key: %v", err)
+			}
+		}
+	case jwt.SigningMethodEdDSA.Alg():
+		if checkReadableFile(p, "JWT_PRIVATE_KEY_PATH") {
+			if _, err := keys.LoadEdPrivateKey(); err != nil {
+				p.add("JWT_PRIVATE_KEY_PATH does not contain a valid Ed25519 private key: %v", err)
+			}
+		}
+		if checkReadableFile(p, "JWT_PUBLIC_KEY_PATH") {
+			if _, err := keys.LoadEdPublicKey(); err != nil {
+				p.add("JWT_PUBLIC_KEY_PATH does not contain a valid Ed25519 public key: %v", err)
+			}
+		}
+	case "":
+		p.add("JWT_ALGORITHM is not set")
+	default:
+		p.add("JWT_ALGORITHM %q is not supported, use HS256, RS256, ES256, or EdDSA", alg)
+	}
+
+	checkPositiveInt(p, "JWT_ACCESS_TOKEN_TTL_MINUTES")
+	for _, key := range []string{"JWT_ACCESS_TOKEN_TTL_BY_USER_TYPE", "JWT_ACCESS_TOKEN_TTL_BY_ROLE", "JWT_ACCESS_TOKEN_TTL_BY_CLIENT"} {
+		if _, err := jwtconfig.ParseTTLOverrides(env.Getenv(key)); err != nil {
+			p.add("%s: %v", key, err)
+		}
+	}
+
+	// The refresh tokens must outlive every access token
+	maxAccess := jwtconfig.LoadTTLPolicy().Max()
+	refreshHours := checkPositiveInt(p, "JWT_REFRESH_TOKEN_EXPIRATION_HOUR")
+	if refreshHours > 0 && time.Duration(refreshHours)*time.Hour <= maxAccess {
+		p.add("JWT_REFRESH_TOKEN_EXPIRATION_HOUR (%d) must be greater than the longest access token TTL (%s)", refreshHours, maxAccess)
+	}
+}
+
+// validateKeySet checks the keys of JWT_KEYSET_DIR. A missing or empty directory is valid,
+// the first key is created at startup.
+func validateKeySet(p *Problems, keys jwtutil.KeyFiles) {
+	if _, err := keys.LoadKeySet(); err != nil && !errors.Is(err, jwtutil.ErrNoSigningKey) {
+		p.add("JWT_KEYSET_DIR does not contain valid RSA keys: %v", err)
+	}
+	checkPositiveInt(p, "JWT_KEY_ROTATION_DAYS")
+}
+
+// validateDatabase checks the database settings and optionally the connectivity.
+func validateDatabase(p *Problems, checkDB bool) {
+	switch driver := database.GetDriver(); driver {
+	case database.DriverPostgres:
+	case database.DriverMemory:
+		// The in-memory repositories do not need any database settings
+		return
+	default:
+		p.add("DB_DRIVER %q is not supported, use postgres or memory", driver)
+		return
+	}
+
+	missing := false
+	for _, key := range []string{"DB_HOST", "DB_PORT", "DB_USER", "DB_PASS", "DB_NAME", "DB_SCHEMA"} {
+		if env.Getenv(key) == "" {
+			p.add("%s is not set", key)
+			missing = true
+		}
+	}
+
+	if env.Getenv("DB_SEED") == "TRUE" {
+		checkReadableFile(p, "DB_SEED_FILE")
+	}
+
+	if v := env.Getenv("DB_READ_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			p.add("DB_READ_RETRIES must be a non-negative integer, got %q", v)
+		}
+	}
+	checkPositiveInt(p, "DB_READ_RETRY_DELAY_MS")
+	if v := env.Getenv("DB_CIRCUIT_BREAKER_THRESHOLD"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			p.add("DB_CIRCUIT_BREAKER_THRESHOLD must be a non-negative integer, got %q", v)
+		}
+	}
+	checkPositiveInt(p, "DB_CIRCUIT_BREAKER_OPEN_SECOND")
+	checkPositiveInt(p, "DB_WRITE_BUFFER_SIZE")
+	checkPositiveInt(p, "DB_WRITE_BUFFER_TIMEOUT_SECOND")
+	checkPositiveInt(p, "DB_WRITE_BUFFER_RETRY_INTERVAL_MS")
+	checkPositiveInt(p, "DB_CONN_MAX_LIFETIME_SECOND")
+	checkPositiveInt(p, "DB_CONN_MAX_IDLE_TIME_SECOND")
+
+	// The idle connections are kept within the open ones
+	maxOpen := checkPositiveInt(p, "DB_MAX_OPEN_CONNS")
+	if maxIdle := checkPositiveInt(p, "DB_MAX_IDLE_CONNS"); maxOpen > 0 && maxIdle > maxOpen {
+		p.add("DB_MAX_IDLE_CONNS (%d) must not be greater than DB_MAX_OPEN_CONNS (%d)", maxIdle, maxOpen)
+	}
+
+	// Only check the connectivity when all required settings are present
+	if checkDB && !missing {
+		cfg := database.ReadConfig()
+		if err := database.PingPostgres(cfg, dbPingTimeout); err != nil {
+			p.add("database is not reachable: %v", err)
+		}
+		if err := database.PingReplicas(cfg, dbPingTimeout); err != nil {
+			p.add("DB_REPLICA_DSN is not reachable: %v", err)
+		}
+	}
+}
+
+// validateUserStore checks the kind and the URL of the user store, and its timeout.
+func validateUserStore(p *Problems) {
+	if err := policyconfig.LoadUserStoreConfig().Check(); err != nil {
+		p.add("%v", err)
+	}
+	checkPositiveInt(p, "USER_STORE_TIMEOUT_SECOND")
+}
+
+// validatePagination checks the maximum page size and the mode applied to larger limits,
+// the cap of the exports, and their overrides per role.
+func validatePagination(p *Problems) {
+	checkPositiveInt(p, "PAGINATION_MAX_LIMIT")
+
+	switch mode := strings.ToUpper(env.Getenv("PAGINATION_LIMIT_MODE")); mode {
+	case "", pagination.ModeReject, pagination.ModeClamp:
+	default:
+		p.add("PAGINATION_LIMIT_MODE %q is not supported, use REJECT or CLAMP", mode)
+	}
+
+	// 0 leaves the exports unlimited
+	if v := env.Getenv("EXPORT_MAX_ROWS"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			p.add("EXPORT_MAX_ROWS must be a non-negative integer, got %q", v)
+		}
+	}
+
+	if _, err := pagination.ParseRoleLimits(env.Getenv("PAGINATION_LIMITS_BY_ROLE")); err != nil {
+		p.add("PAGINATION_LIMITS_BY_ROLE: %v", err)
+	}
+}
+
+// validateSessions checks the limit of active sessions per user.
+func validateSessions(p *Problems) {
+	checkPositiveInt(p, "MAX_SESSIONS_PER_USER")
+
+	switch mode := strings.ToUpper(env.Getenv("SESSION_LIMIT_MODE")); mode {
+	case "", policyconfig.SessionLimitModeEvictOldest, policyconfig.SessionLimitModeReject:
+	default:
+		p.add("SESSION_LIMIT_MODE %q is not supported, use EVICT_OLDEST or REJECT", mode)
+	}
+}
+
+// validatePasswordReset checks the lifetime of the password reset tokens, the URL of the reset page, and the lifetime of the passwords.
+func validatePasswordReset(p *Problems) {
+	checkPositiveInt(p, "PASSWORD_RESET_TOKEN_TTL_MINUTES")
+
+	if v := env.Getenv("PASSWORD_RESET_URL"); v != "" {
+		if u, err := url.Parse(v); err != nil || u.Scheme == "" || u.Host == "" {
+			p.add("PASSWORD_RESET_URL must be an absolute URL, got %q", v)
+		}
+	}
+
+	// 0 disables the expiration of the passwords
+	if v := env.Getenv("CREDENTIALS_TTL_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			p.add("CREDENTIALS_TTL_DAYS must be a non-negative integer, got %q", v)
+		}
+	}
+}
+
+// validateMFA checks the lifetime of the MFA tokens of the logins with two-factor authentication.
+func validateMFA(p *Problems) {
+	checkPositiveInt(p, "MFA_TOKEN_TTL_MINUTES")
+}
+
+// validateConsumerListing checks that the consumer listing exclusions are role=statuses pairs of known statuses.
+func validateConsumerListing(p *Problems) {
+	if _, err := policyconfig.ParseConsumerListingPolicy(env.Getenv("CONSUMER_LISTING_EXCLUDED_STATUSES")); err != nil {
+		p.add("CONSUMER_LISTING_EXCLUDED_STATUSES: %v", err)
+	}
+}
+
+// validateConsumerRules checks that the username pattern of the consumer rules is a valid regular expression,
+// and that the blocked email domains are domains.
+func validateConsumerRules(p *Problems) {
+	if _, err := policyconfig.ParseConsumerRules(env.Getenv("CONSUMER_REQUIRED_METADATA_KEYS"), env.Getenv("CONSUMER_USERNAME_PATTERN"), env.Getenv("CONSUMER_BLOCKED_EMAIL_DOMAINS")); err != nil {
+		p.add("consumer rules: %v", err)
+	}
+}
+
+// validateConsumerCache checks that the TTL of the consumer cache is not negative.
+func validateConsumerCache(p *Problems) {
+	if v := env.Getenv("CONSUMER_CACHE_TTL_SECOND"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			p.add("CONSUMER_CACHE_TTL_SECOND must be a non-negative integer, got %q", v)
+		}
+	}
+}
+
+// validateSecuritySettingsCache checks that the TTL of the security settings cache is not negative.
+func validateSecuritySettingsCache(p *Problems) {
+	if v := env.Getenv("SECURITY_SETTINGS_CACHE_TTL_SECOND"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			p.add("SECURITY_SETTINGS_CACHE_TTL_SECOND must be a non-negative integer, got %q", v)
+		}
+	}
+}
+
+// validateFeatureFlags checks that the features of DISABLED_FEATURES are known, and that the TTL of the feature flags cache is not negative.
+func validateFeatureFlags(p *Problems) {
+	if _, err := featureflag.LoadDisabled(); err != nil {
+		p.add("DISABLED_FEATURES: %v", err)
+	}
+	if v := env.Getenv("FEATURE_FLAGS_CACHE_TTL_SECOND"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			p.add("FEATURE_FLAGS_CACHE_TTL_SECOND must be a non-negative integer, got %q", v)
+		}
+	}
+}
+
+// validateConsumerWebhooks checks the URL the consumer events are sent to, and the retries of the failed deliveries.
+func validateConsumerWebhooks(p *Problems) {
+	if v := env.Getenv("CONSUMER_WEBHOOK_URL"); v != "" {
+		if u, err := url.Parse(v); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			p.add("CONSUMER_WEBHOOK_URL must be an absolute http or https URL, got %q", v)
+		}
+	}
+
+	checkPositiveInt(p, "CONSUMER_WEBHOOK_MAX_ATTEMPTS")
+	checkPositiveInt(p, "CONSUMER_WEBHOOK_BACKOFF_SECOND")
+	checkPositiveInt(p, "CONSUMER_WEBHOOK_MAX_BACKOFF_SECOND")
+	checkPositiveInt(p, "CONSUMER_WEBHOOK_TIMEOUT_SECOND")
+}
+
+// validateRateLimits checks the number of requests allowed per user on the rate limited routes.
+func validateRateLimits(p *Problems) {
+	checkPositiveInt(p, "CHECK_AVAILABILITY_RATE_LIMIT")
+	checkPositiveInt(p, "REGISTER_RATE_LIMIT")
+	checkPositiveInt(p, "FORGOT_PASSWORD_RATE_LIMIT")
+	checkPositiveInt(p, "CHANGE_PASSWORD_RATE_LIMIT")
+	checkPositiveInt(p, "MFA_RATE_LIMIT")
+	validateAuthRateLimits(p)
+}
+
+// validateAuthRateLimits checks the token buckets of the logins and the token refreshes, and that Redis is configured to share them.
+func validateAuthRateLimits(p *Problems) {
+	for _, key := range []string{"AUTH_RATE_LIMIT_IP_BURST", "AUTH_RATE_LIMIT_IP_PER_MINUTE", "AUTH_RATE_LIMIT_USERNAME_BURST", "AUTH_RATE_LIMIT_USERNAME_PER_MINUTE"} {
+		if v := env.Getenv(key); v != "" {
+			if n, err := strconv.Atoi(v); err != nil || n < 0 {
+				p.add("%s must be a non-negative integer, got %q", key, v)
+			}
+		}
+	}
+
+	switch backend := strings.ToLower(strings.TrimSpace(env.Getenv("AUTH_RATE_LIMIT_BACKEND"))); backend {
+	case "", ratelimit.BackendMemory:
+	case ratelimit.BackendRedis:
+		if env.Getenv("REDIS_URL") == "" {
+			p.add("AUTH_RATE_LIMIT_BACKEND=redis requires REDIS_URL")
+		}
+	default:
+		p.add("AUTH_RATE_LIMIT_BACKEND must be %s or %s, got %q", ratelimit.BackendMemory, ratelimit.BackendRedis, backend)
+	}
+}
+
+// validateRateLimitOverrides checks that the TTL of the rate limit overrides cache is not negative.
+func validateRateLimitOverrides(p *Problems) {
+	if v := env.Getenv("RATE_LIMIT_OVERRIDES_CACHE_TTL_SECOND"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			p.add("RATE_LIMIT_OVERRIDES_CACHE_TTL_SECOND must be a non-negative integer, got %q", v)
+		}
+	}
+}
+
+// validateQuotas checks the default budgets, the budgets of the clients, and the costs of the routes.
+func validateQuotas(p *Problems) {
+	for _, key := range []string{"QUOTA_DAILY_LIMIT", "QUOTA_MONTHLY_LIMIT"} {
+		if v := env.Getenv(key); v != "" {
+			if n, err := strconv.ParseInt(v, 10, 64); err != nil || n < 0 {
+				p.add("%s must be a non-negative integer, got %q", key, v)
+			}
+		}
+	}
+
+	if _, err := quota.ParseBudgets(env.Getenv("QUOTA_LIMITS_BY_CLIENT")); err != nil {
+		p.add("QUOTA_LIMITS_BY_CLIENT: %v", err)
+	}
+	if _, err := quota.ParseCosts(env.Getenv("QUOTA_ENDPOINT_COSTS")); err != nil {
+		p.add("QUOTA_ENDPOINT_COSTS: %v", err)
+	}
+}
+
+// validateWarmUp checks that the number of users whose roles are cached while warming up is not negative.
+func validateWarmUp(p *Problems) {
+	if v := env.Getenv("WARMUP_ROLE_CACHE_USERS"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			p.add("WARMUP_ROLE_CACHE_USERS must be a non-negative integer, got %q", v)
+		}
+	}
+}
+
+// validateShutdown checks the drain period and the timeout of the graceful shutdown.
+func validateShutdown(p *Problems) {
+	// 0 disables the drain period
+	if v := env.Getenv("SHUTDOWN_DRAIN_SECOND"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			p.add("SHUTDOWN_DRAIN_SECOND must be a non-negative integer, got %q", v)
+		}
+	}
+	checkPositiveInt(p, "SHUTDOWN_TIMEOUT_SECOND")
+}
+
+// validateProxies checks that the trusted proxies are IPs or CIDRs.
+func validateProxies(p *Problems) {
+	if _, err := proxyconfig.Load(); err != nil {
+		p.add("%v", err)
+	}
+}
+
+// validateMailer checks that the mailer driver is supported and, for SMTP, configured, and that the mail templates parse.
+func validateMailer(p *Problems) {
+	if _, err := mailer.New(mailer.LoadConfig()); err != nil {
+		p.add("%v", err)
+	}
+	if _, err := mailer.LoadTemplates(); err != nil {
+		p.add("%v", err)
+	}
+}
+
+// validateAlerting checks the routes of the alerts, that the routed channels are configured, and the alert settings.
+func validateAlerting(p *Problems) {
+	cfg, err := alerting.LoadConfig()
+	if err != nil {
+		p.add("ALERT_ROUTES: %v", err)
+	} else if err := cfg.Validate(); err != nil {
+		p.add("ALERT_ROUTES: %v", err)
+	}
+
+	for _, key := range []string{"ALERT_SLACK_WEBHOOK_URL", "ALERT_SMS_API_URL"} {
+		if v := env.Getenv(key); v != "" {
+			if u, err := url.Parse(v); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				p.add("%s must be an absolute http or https URL, got %q", key, v)
+			}
+		}
+	}
+
+	// 0 disables the deduplication of the alerts
+	if v := env.Getenv("ALERT_DEDUP_MINUTES"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			p.add("ALERT_DEDUP_MINUTES must be a non-negative integer, got %q", v)
+		}
+	}
+	checkPositiveInt(p, "ALERT_CERT_EXPIRY_DAYS")
+}
+
+// validateRedis checks that REDIS_URL, if configured, is a redis:// or rediss:// URL.
+func validateRedis(p *Problems) {
+	if redis := cache.LoadConfig(); redis.Enabled() {
+		if _, err := redis.ParseOptions(); err != nil {
+			p.add("%v", err)
+		}
+	}
+}
+
+// validateEvents checks that EVENTS_ROLE_TOPICS, if configured, grants known topics to the roles.
+func validateEvents(p *Problems) {
+	if _, err := eventbus.LoadPolicy(); err != nil {
+		p.add("EVENTS_ROLE_TOPICS: %v", err)
+	}
+}
+
+// validateGeoIP checks that the GeoIP databases are readable, if configured, and that the blocked countries are ISO codes.
+func validateGeoIP(p *Problems) {
+	cfg := geoip.LoadConfig()
+	if cfg.DBPath != "" {
+		checkReadableFile(p, "GEOIP_DB_PATH")
+	}
+	if cfg.ASNDBPath != "" {
+		checkReadableFile(p, "GEOIP_ASN_DB_PATH")
+	}
+
+	for _, c := range cfg.BlockedAdminCountries {
+		if len(c) != 2 || strings.Trim(c, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			p.add("GEOIP_BLOCKED_ADMIN_COUNTRIES must be a comma separated list of ISO 3166-1 alpha-2 codes, got %q", c)
+		}
+	}
+
+	if len(cfg.BlockedAdminCountries) > 0 && cfg.DBPath == "" {
+		p.add("GEOIP_BLOCKED_ADMIN_COUNTRIES requires GEOIP_DB_PATH to be set")
+	}
+}
+
+// validateAnomaly checks the ASN threshold, the tarpit and the CAPTCHA challenge of the anomaly detection,
+// and that the CAPTCHA provider is configured when the anomalies challenge the logins with a CAPTCHA,
+// or require a step-up authentication, completed with a CAPTCHA by the users without two-factor authentication.
+func validateAnomaly(p *Problems) {
+	for _, key := range []string{"ANOMALY_FAILED_LOGIN_ASN_THRESHOLD", "ANOMALY_TARPIT_MINUTES", "ANOMALY_TARPIT_DELAY_MS", "ANOMALY_CAPTCHA_MINUTES", "ANOMALY_MAX_TRACKED_KEYS"} {
+		checkPositiveInt(p, key)
+	}
+
+	if v := env.Getenv("CAPTCHA_VERIFY_URL"); v != "" {
+		if u, err := url.Parse(v); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			p.add("CAPTCHA_VERIFY_URL must be an absolute http or https URL, got %q", v)
+		}
+	}
+
+	cfg := anomaly.LoadConfig()
+	for _, action := range []anomaly.Action{anomaly.ActionCaptcha, anomaly.ActionStepUp} {
+		if cfg.Enabled && slices.Contains(cfg.Actions, action) && !captcha.LoadConfig().Enabled() {
+			p.add("ANOMALY_ACTIONS=%s requires CAPTCHA_VERIFY_URL and CAPTCHA_SECRET to be set", action)
+		}
+	}
+}
+
+// validateTracing checks the collector endpoint and the sample ratio, if the tracing is enabled.
+func validateTracing(p *Problems) {
+	if cfg := tracing.LoadConfig(); cfg.Enabled {
+		if err := cfg.Validate(); err != nil {
+			p.add("%v", err)
+		}
+	}
+}
+
+// validateSignedURLs checks that the secret of the signed URLs, if configured, is long enough, and their lifetime.
+func validateSignedURLs(p *Problems) {
+	if _, err := signedurl.LoadConfig(secrets.Default()); err != nil {
+		p.add("%v", err)
+	}
+}
+
+// validateOIDC checks that the OpenID Connect providers are known or have an issuer, and have their client credentials.
+func validateOIDC(p *Problems) {
+	if _, err := oidc.LoadConfigs(); err != nil {
+		p.add("%v", err)
+	}
+}
+
+// validatePIIEncryption checks that the PII encryption key, if configured, is a base64-encoded 32-byte key.
+func validatePIIEncryption(p *Problems) {
+	if _, err := fieldcrypt.LoadKey(secrets.Default()); err != nil {
+		p.add("%s is invalid: %v", fieldcrypt.KeySecretName, err)
+	}
+}
+
+// validateDataMasking checks that the masked fields are known.
+func validateDataMasking(p *Problems) {
+	if _, err := masking.LoadConfig(); err != nil {
+		p.add("%v", err)
+	}
+}
+
+// validateLocaleFormatting checks that the locales the consumers are formatted for are supported.
+func validateLocaleFormatting(p *Problems) {
+	if _, err := localeformat.LoadConfig(); err != nil {
+		p.add("%v", err)
+	}
+}
+
+// validateChaos checks the faults injected into the calls to the database, and that the injection is not enabled in production.
+func validateChaos(p *Problems) {
+	if err := chaos.LoadConfig().Validate(env.Getenv("ENV")); err != nil {
+		p.add("%v", err)
+	}
+}
+
+// validateSLO checks the availability and latency objectives of the route groups, and their latency thresholds.
+func validateSLO(p *Problems) {
+	if err := metrics.LoadSLOConfig().Validate(); err != nil {
+		p.add("%v", err)
+	}
+	if _, err := metrics.ParseSLOLatencyThresholds(env.Getenv("SLO_LATENCY_THRESHOLDS_MS_BY_GROUP")); err != nil {
+		p.add("SLO_LATENCY_THRESHOLDS_MS_BY_GROUP: %v", err)
+	}
+}
+
+// checkReadableFile checks that the environment variable is set and points to a readable file.
+func checkReadableFile(p *Problems, key string) bool {
+	path := env.Getenv(key)
+	if path == "" {
+		p.add("%s is not set", key)
+		return false
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		p.add("%s points to an unreadable file: %v", key, err)
+		return false
+	}
+	f.Close()
+
+	return true
+}
+
+// checkPositiveInt checks that the environment variable, if set, is a positive integer.
+// It returns the parsed value, or 0 if the variable is not set or invalid.
+func checkPositiveInt(p *Problems, key string) int {
+	v := env.Getenv(key)
+	if v == "" {
+		return 0
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		p.add("%s must be a positive integer, got %q", key, v)
+		return 0
+	}
+
+	return n
+}
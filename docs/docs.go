@@ -0,0 +1,13 @@
+package docs
+
+import _ "embed"
+
+/**
+ * docs package embeds the OpenAPI document of the API,
+ * so that it can be served or validated against without reading it from the filesystem.
+ */
+
+// OpenAPI contains the OpenAPI 3 document describing the API.
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
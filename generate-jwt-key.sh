@@ -1,5 +1,13 @@
+# Usage: ./generate-jwt-key.sh [RS256|ES256|EdDSA] (defaults to RS256)
+ALGORITHM=${1:-RS256}
+
 # Generate private key
-openssl genpkey -algorithm RSA -out privateKey.pem -pkeyopt rsa_keygen_bits:2048
+case "$ALGORITHM" in
+  RS256) openssl genpkey -algorithm RSA -out privateKey.pem -pkeyopt rsa_keygen_bits:2048 ;;
+  ES256) openssl genpkey -algorithm EC -out privateKey.pem -pkeyopt ec_paramgen_curve:P-256 ;;
+  EdDSA) openssl genpkey -algorithm ED25519 -out privateKey.pem ;;
+  *) echo "Unsupported algorithm: $ALGORITHM, use RS256, ES256, or EdDSA" >&2; exit 1 ;;
+esac
 
 # Extract public key
-openssl rsa -pubout -in privateKey.pem -out publicKey.pem
+openssl pkey -pubout -in privateKey.pem -out publicKey.pem
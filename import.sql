@@ -1,3 +1,6 @@
+-- Description: SQL script importing the initial data into the tables of the baseline schema, version 1 of the migrations.
+-- The next versions migrate the data with the schema, e.g. the states of the users and the permissions of the roles.
+
 -- Description: SQL script to import initial user data into the database.
 INSERT INTO users (username,"password",email,firstname,lastname,is_enabled,is_account_non_expired,is_account_non_locked,is_credentials_non_expired,is_deleted,account_expiration_date,credentials_expiration_date,user_type,last_login,created_by,updated_by) VALUES
 	 ('admin','$2a$10$eP5Sddi7Q5Jv6seppeF93.XsWGY8r4PnsqprWGb5AxsZ9TpwULIGa','admin@mygmail.com','Admin','Admin',true,true,true,true,false,'2025-04-23 21:52:38.000','2025-02-28 01:58:35.000','USER_ACCOUNT','2025-02-11 22:54:32.000',0,0),
@@ -0,0 +1,58 @@
+package entity
+
+import (
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// ApiKey represents an API key of a service account, authenticating its requests with the X-API-Key header.
+// Only the SHA-256 hash of the key is stored, the key is returned once when it is created or rotated.
+// The Prefix is the beginning of the key, kept so that the administrators can tell the keys of an account apart.
+type ApiKey struct {
+	ID        int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    int64      `gorm:"column:user_id;index;not null" json:"userId"`
+	Name      string     `gorm:"column:name;type:varchar(100);not null" json:"name"`
+	Prefix    string     `gorm:"column:prefix;type:varchar(16);not null" json:"prefix"`
+	KeyHash   string     `gorm:"column:key_hash;type:varchar(64);unique;not null" json:"-"`
+	ExpiresAt *time.Time `gorm:"column:expires_at;type:timestamptz" json:"expiresAt,omitempty"`
+	RevokedAt *time.Time `gorm:"column:revoked_at;type:timestamptz" json:"revokedAt,omitempty"`
+	CreatedBy int64      `gorm:"column:created_by;not null" json:"createdBy"`
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamptz;not null;default:now()" json:"createdAt"`
+}
+
+// TableName override the table name used by ApiKey to `api_keys`.
+func (ApiKey) TableName() string {
+	return "api_keys"
+}
+
+// IsUsable reports whether the key was not revoked and is not expired at the given time.
+func (k *ApiKey) IsUsable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// IssuedApiKey represents an API key as returned when it is created or rotated, the only time the key itself is returned.
+type IssuedApiKey struct {
+	ApiKey
+	Key string `json:"key"`
+}
+
+// ApiKeyRequest represents the request payload for the creation of an API key.
+// The key never expires when ExpiresInDays is not given.
+type ApiKeyRequest struct {
+	Name          string `json:"name" validate:"required,max=100"`
+	ExpiresInDays int    `json:"expiresInDays,omitempty" validate:"omitempty,min=1,max=3650"`
+}
+
+// Validate validates the ApiKeyRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (r *ApiKeyRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(r); err != nil {
+		return err
+	}
+	return nil
+}
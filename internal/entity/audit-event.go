@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"time"
+)
+
+// Actions of the audit events.
+// The admin actions are the requests changing the state of the service through the admin routes, their target is the route.
+const (
+	AuditActionLogin                = "login"
+	AuditActionLogout               = "logout"
+	AuditActionTokenRefresh         = "token_refresh"
+	AuditActionRoleChange           = "role_change"
+	AuditActionConsumerStatusChange = "consumer_status_change"
+	AuditActionConsumerUpdate       = "consumer_update"
+	AuditActionConsumerDelete       = "consumer_delete"
+	AuditActionConsumerRestore      = "consumer_restore"
+	AuditActionAdmin                = "admin_action"
+)
+
+// Types of the targets of the audit events.
+const (
+	AuditTargetUser     = "user"
+	AuditTargetRole     = "role"
+	AuditTargetConsumer = "consumer"
+	AuditTargetRoute    = "route"
+)
+
+// AuditEvent represents a security-relevant action recorded in the audit log: who did what to what, from where,
+// and in which request. The audit events are only inserted, never updated nor deleted by the service.
+// The EventID is generated when the event is recorded, so that an event written again after a lost connection is not duplicated.
+type AuditEvent struct {
+	ID            int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventID       string    `gorm:"column:event_id;type:varchar(36);uniqueIndex;not null" json:"eventId"`
+	Action        string    `gorm:"column:action;type:varchar(50);index;not null" json:"action"`
+	ActorID       *int64    `gorm:"column:actor_id;index" json:"actorId,omitempty"`
+	ActorUsername string    `gorm:"column:actor_username;type:varchar(50)" json:"actorUsername,omitempty"`
+	TargetType    string    `gorm:"column:target_type;type:varchar(50)" json:"targetType,omitempty"`
+	TargetID      string    `gorm:"column:target_id;type:varchar(255)" json:"targetId,omitempty"`
+	Details       string    `gorm:"column:details;type:text" json:"details,omitempty"`
+	IPAddress     string    `gorm:"column:ip_address;type:varchar(45)" json:"ipAddress,omitempty"`
+	RequestID     string    `gorm:"column:request_id;type:varchar(128)" json:"requestId,omitempty"`
+	CreatedAt     time.Time `gorm:"column:created_at;type:timestamptz;index;not null;default:now()" json:"createdAt"`
+}
+
+// TableName override the table name used by AuditEvent to `audit_events`.
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+// AuditEventFilter represents the criteria of a search of the audit log, the empty criteria match every event.
+// From is inclusive and To is exclusive.
+type AuditEventFilter struct {
+	Action     string
+	ActorID    *int64
+	TargetType string
+	TargetID   string
+	From       *time.Time
+	To         *time.Time
+}
+
+// Matches reports whether the audit event meets the criteria of the filter.
+func (f AuditEventFilter) Matches(e AuditEvent) bool {
+	switch {
+	case f.Action != "" && e.Action != f.Action:
+		return false
+	case f.ActorID != nil && (e.ActorID == nil || *e.ActorID != *f.ActorID):
+		return false
+	case f.TargetType != "" && e.TargetType != f.TargetType:
+		return false
+	case f.TargetID != "" && e.TargetID != f.TargetID:
+		return false
+	case f.From != nil && e.CreatedAt.Before(*f.From):
+		return false
+	case f.To != nil && !e.CreatedAt.Before(*f.To):
+		return false
+	}
+
+	return true
+}
@@ -1,23 +1,39 @@
 package entity
 
 import (
+	"time"
+
 	"gopkg.in/go-playground/validator.v9"
 
 	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
 )
 
 // LoginRequest represents the request payload for user login.
+// The ClientID, DeviceID, IPAddress, and UserAgent are not part of the payload, they are set from the request.
+// StepUp is set when the anomaly detection requires an additional verification of the account.
 type LoginRequest struct {
-	Username string `json:"username" validate:"required,min=3,max=20"`
-	Password string `json:"password" validate:"required,min=8,max=20"`
+	Username  string `json:"username" validate:"required,min=3,max=20"`
+	Password  string `json:"password" validate:"required,min=8,max=20"`
+	ClientID  string `json:"-"`
+	DeviceID  string `json:"-" validate:"omitempty,max=64"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+	Country   string `json:"-"`
+	City      string `json:"-"`
+	StepUp    bool   `json:"-"`
 }
 
 // LoginResponse represents the response payload for user login.
+// For the users with two-factor authentication, the login only checks the password: MFARequired is set
+// and no token is issued, the client completes the login with the MFAToken and a code of the authenticator app.
 type LoginResponse struct {
-	AccessToken    string `json:"accessToken"`
-	RefreshToken   string `json:"refreshToken"`
-	ExpirationDate string `json:"expirationDate"`
-	TokenType      string `json:"tokenType"`
+	AccessToken            string `json:"accessToken,omitempty"`
+	RefreshToken           string `json:"refreshToken,omitempty"`
+	ExpirationDate         string `json:"expirationDate,omitempty"`
+	TokenType              string `json:"tokenType,omitempty"`
+	MFARequired            bool   `json:"mfaRequired,omitempty"`
+	MFAToken               string `json:"mfaToken,omitempty"`
+	MFATokenExpirationDate string `json:"mfaTokenExpirationDate,omitempty"`
 }
 
 // Validate validates the LoginRequest struct using the validator package.
@@ -30,3 +46,59 @@ func (a *LoginRequest) Validate() error {
 	}
 	return nil
 }
+
+// RegisterRequest represents the request payload for the self-registration of a user.
+// The password must contain uppercase and lowercase letters, a digit, and a special character.
+type RegisterRequest struct {
+	Username  string  `json:"username" validate:"required,min=3,max=20"`
+	Password  string  `json:"password" validate:"required,min=8,max=20,password_complexity"`
+	Email     string  `json:"email" validate:"required,email,max=100"`
+	Firstname string  `json:"firstName" validate:"required,max=20"`
+	Lastname  *string `json:"lastName,omitempty" validate:"omitempty,max=20"`
+}
+
+// RegisterResponse represents the response payload for the self-registration of a user.
+// It never contains the password of the user.
+type RegisterResponse struct {
+	ID        int64      `json:"id"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	Firstname string     `json:"firstName"`
+	Lastname  *string    `json:"lastName,omitempty"`
+	State     UserState  `json:"state"`
+	Roles     []string   `json:"roles"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
+
+// Validate validates the RegisterRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (a *RegisterRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(a); err != nil {
+		return err
+	}
+	return nil
+}
+
+// LogoutRequest represents the request payload for logging out.
+// It contains the refresh token of the session to end.
+// The UserID, TokenID, and TokenExpiresAt are not part of the payload, they are set from the access token of the caller.
+type LogoutRequest struct {
+	RefreshToken   string    `json:"refreshToken" validate:"required"`
+	UserID         int64     `json:"-"`
+	TokenID        string    `json:"-"`
+	TokenExpiresAt time.Time `json:"-"`
+	IPAddress      string    `json:"-"`
+}
+
+// Validate validates the LogoutRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (a *LogoutRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(a); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/diagnostics"
+)
+
+// ConfigSnapshot represents the effective runtime configuration of the instance serving the request,
+// for the support engineers verifying the state of an environment without a shell on its hosts.
+// The secrets of the configuration are masked, and the credentials are removed from its URLs.
+type ConfigSnapshot struct {
+	Version      string               `json:"version"`
+	Commit       string               `json:"commit"`
+	GoVersion    string               `json:"goVersion"`
+	Config       map[string]string    `json:"config"`
+	Features     map[string]bool      `json:"features"`
+	FeatureFlags []FeatureFlag        `json:"featureFlags"`
+	RateLimits   ConfigSnapshotLimits `json:"rateLimits"`
+	Quotas       ConfigSnapshotQuotas `json:"quotas"`
+	SigningKeys  ConfigSnapshotKeys   `json:"signingKeys"`
+	GeneratedAt  time.Time            `json:"generatedAt"`
+
+	// DatabasePools holds the statistics of the connection pools of the instance, by database, none with the memory driver
+	DatabasePools map[string]diagnostics.PoolStats `json:"databasePools,omitempty"`
+}
+
+// RateLimitSetting is the number of requests a client may send per window to the routes sharing a rate limit,
+// set by the environment variable of the given name. The token buckets allow a burst of requests on top of it.
+type RateLimitSetting struct {
+	Name   string   `json:"name"`
+	Routes []string `json:"routes"`
+	Limit  int      `json:"limit"`
+	Window string   `json:"window"`
+	Burst  int      `json:"burst,omitempty"`
+}
+
+// ConfigSnapshotLimits holds the rate limits of the routes, and the factors scaling them for individual clients.
+type ConfigSnapshotLimits struct {
+	Routes    []RateLimitSetting  `json:"routes"`
+	Overrides []RateLimitOverride `json:"overrides"`
+}
+
+// QuotaBudget is the number of units a client may spend per day and per month, 0 meaning unlimited.
+type QuotaBudget struct {
+	Daily   int64 `json:"daily"`
+	Monthly int64 `json:"monthly"`
+}
+
+// ConfigSnapshotQuotas holds the default budget of the clients, their own budgets, and the costs of the routes.
+type ConfigSnapshotQuotas struct {
+	Default QuotaBudget            `json:"default"`
+	Clients map[string]QuotaBudget `json:"clients"`
+	Costs   map[string]int64       `json:"costs"`
+}
+
+// ConfigSnapshotKeys identifies the keys of the access tokens: the algorithm signing them, the key signing the new tokens,
+// and the keys still verifying them, the current one included. The HS256 secret has no key ID.
+type ConfigSnapshotKeys struct {
+	Algorithm    string   `json:"algorithm"`
+	CurrentKeyID string   `json:"currentKid,omitempty"`
+	KeyIDs       []string `json:"kids"`
+}
@@ -1,11 +1,18 @@
 package entity
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
 	"gopkg.in/go-playground/validator.v9"
+	"gorm.io/gorm"
 
 	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/fieldcrypt"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/localeformat"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/masking"
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
 )
 
 const (
@@ -15,17 +22,248 @@ const (
 )
 
 // Consumer represents the consumer entity in the database.
+// The consumers are soft-deleted: a deleted consumer keeps its row, with its deletion time, and is left out of the queries
+// by the soft delete scope of GORM until it is restored. It keeps its username, email, and phone number, unique over all the rows.
+// The phone number, the address, and the birth date are encrypted at rest by the "encrypted" serializer, see fieldcrypt.
+// The phone number and the email are looked up by their blind indexes, set before the consumer is saved.
 type Consumer struct {
-	ID        string           `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Fullname  string           `gorm:"type:varchar(100);not null" json:"fullname" validate:"required,max=100"`
-	Username  string           `gorm:"type:varchar(50);unique;not null" json:"username" validate:"required,max=50"`
-	Email     string           `gorm:"type:varchar(100);unique;not null" json:"email" validate:"required,email,max=100"`
-	Phone     string           `gorm:"type:varchar(20);unique;not null" json:"phone" validate:"required,max=20"`
-	Address   string           `gorm:"type:text;not null" json:"address" validate:"required"`
-	BirthDate *customtype.Date `gorm:"type:date" json:"birthDate,omitempty" validate:"required,omitempty"`
-	Status    string           `gorm:"type:varchar(20);not null;default:'inactive';check:status IN ('active','inactive','suspended')" json:"status"`
-	CreatedAt time.Time        `gorm:"column:created_at;type:timestamptz;autoCreateTime;default:now()" json:"createdAt,omitempty"`
-	UpdatedAt time.Time        `gorm:"column:updated_at;type:timestamptz;autoUpdateTime;default:now()" json:"updatedAt,omitempty"`
+	ID         string           `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Fullname   string           `gorm:"type:varchar(100);not null" json:"fullname" validate:"required,max=100"`
+	Username   string           `gorm:"type:varchar(50);unique;not null" json:"username" validate:"required,max=50"`
+	Email      string           `gorm:"type:varchar(100);unique;not null" json:"email" validate:"required,email,max=100"`
+	EmailIndex string           `gorm:"column:email_index;type:varchar(64);uniqueIndex" json:"-"`
+	Phone      string           `gorm:"type:text;not null;serializer:encrypted" json:"phone" validate:"required,max=20,e164"`
+	PhoneIndex string           `gorm:"column:phone_index;type:varchar(64);uniqueIndex" json:"-"`
+	Address    string           `gorm:"type:text;not null;serializer:encrypted" json:"address" validate:"required"`
+	BirthDate  *customtype.Date `gorm:"type:text;serializer:encrypted" json:"birthDate,omitempty" validate:"required,omitempty,notfuture"`
+	Status     string           `gorm:"type:varchar(20);not null;default:'inactive';check:status IN ('active','inactive','suspended')" json:"status" validate:"omitempty,consumer_status"`
+	Metadata   Metadata         `gorm:"type:jsonb;serializer:json" json:"metadata,omitempty" validate:"omitempty,max=20,dive,keys,required,max=50,endkeys,max=255"`
+	CreatedAt  time.Time        `gorm:"column:created_at;type:timestamptz;autoCreateTime;default:now()" json:"createdAt,omitempty"`
+	UpdatedAt  time.Time        `gorm:"column:updated_at;type:timestamptz;autoUpdateTime;default:now()" json:"updatedAt,omitempty"`
+	DeletedAt  gorm.DeletedAt   `gorm:"column:deleted_at;type:timestamptz;index" json:"-"`
+}
+
+// Metadata holds the free-form attributes of a consumer set by the deployment, e.g. its tenant or its region,
+// as string keys and values. The keys required on every consumer are set by the consumer rules of the service.
+type Metadata map[string]string
+
+// ConsumerCreateRequest represents the request payload for the creation of a consumer, version 1 of the API.
+// The request and the response payloads of the consumers are mapped explicitly from and to the Consumer entity,
+// so that a change of its columns does not change the API, and that another version of a payload,
+// e.g. a ConsumerResponseV2, can be served next to this one.
+// The consumer it maps to is validated by the service, once its phone number is normalized.
+type ConsumerCreateRequest struct {
+	Fullname  string           `json:"fullname"`
+	Username  string           `json:"username"`
+	Email     string           `json:"email"`
+	Phone     string           `json:"phone"`
+	Address   string           `json:"address"`
+	BirthDate *customtype.Date `json:"birthDate"`
+	Metadata  Metadata         `json:"metadata"`
+}
+
+// ToConsumer returns the consumer to create from the request. Its status is left to the default one.
+func (r ConsumerCreateRequest) ToConsumer() Consumer {
+	return Consumer{
+		Fullname:  r.Fullname,
+		Username:  r.Username,
+		Email:     r.Email,
+		Phone:     r.Phone,
+		Address:   r.Address,
+		BirthDate: r.BirthDate,
+		Metadata:  r.Metadata,
+	}
+}
+
+// ConsumerUpdateRequest represents the request payload for the partial update of a consumer, version 1 of the API.
+// The fields not given, or null, are left unchanged; the username, the status, and the metadata are not updated with it.
+// The consumer it is applied to is validated by the service, once its phone number is normalized.
+type ConsumerUpdateRequest struct {
+	Fullname  *string          `json:"fullname"`
+	Email     *string          `json:"email"`
+	Phone     *string          `json:"phone"`
+	Address   *string          `json:"address"`
+	BirthDate *customtype.Date `json:"birthDate"`
+}
+
+// IsEmpty reports whether the request changes none of the fields of the consumer.
+func (r ConsumerUpdateRequest) IsEmpty() bool {
+	return r.Fullname == nil && r.Email == nil && r.Phone == nil && r.Address == nil && r.BirthDate == nil
+}
+
+// ApplyTo returns the consumer with the fields given by the request changed.
+func (r ConsumerUpdateRequest) ApplyTo(c Consumer) Consumer {
+	if r.Fullname != nil {
+		c.Fullname = *r.Fullname
+	}
+	if r.Email != nil {
+		c.Email = *r.Email
+	}
+	if r.Phone != nil {
+		c.Phone = *r.Phone
+	}
+	if r.Address != nil {
+		c.Address = *r.Address
+	}
+	if r.BirthDate != nil {
+		birthDate := *r.BirthDate
+		c.BirthDate = &birthDate
+	}
+
+	return c
+}
+
+// ConsumerResponse represents a consumer as returned by the consumer endpoints, version 1 of the API.
+// The blind indexes of the consumer are internal and never returned. The deletion time is only returned for the soft-deleted
+// consumers, listed by the administrators with includeDeleted.
+type ConsumerResponse struct {
+	ID        string           `json:"id"`
+	Fullname  string           `json:"fullname"`
+	Username  string           `json:"username"`
+	Email     string           `json:"email"`
+	Phone     string           `json:"phone"`
+	Address   string           `json:"address"`
+	BirthDate *customtype.Date `json:"birthDate,omitempty"`
+	Status    string           `json:"status"`
+	Metadata  Metadata         `json:"metadata,omitempty"`
+	CreatedAt time.Time        `json:"createdAt"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+	DeletedAt *time.Time       `json:"deletedAt,omitempty"`
+}
+
+// NewConsumerResponse returns the representation of the consumer returned by the consumer endpoints,
+// with its personal data masked when the data masking is enabled.
+func NewConsumerResponse(consumer Consumer) ConsumerResponse {
+	consumer = consumer.Masked()
+
+	return ConsumerResponse{
+		ID:        consumer.ID,
+		Fullname:  consumer.Fullname,
+		Username:  consumer.Username,
+		Email:     consumer.Email,
+		Phone:     consumer.Phone,
+		Address:   consumer.Address,
+		BirthDate: consumer.BirthDate,
+		Status:    consumer.Status,
+		Metadata:  consumer.Metadata,
+		CreatedAt: consumer.CreatedAt,
+		UpdatedAt: consumer.UpdatedAt,
+		DeletedAt: consumer.DeletedTime(),
+	}
+}
+
+// Localized returns the response with its phone number and its address rendered in the conventions of the given locale,
+// e.g. the national format of the phone numbers of its country. The masked fields are returned as they are.
+func (r ConsumerResponse) Localized(locale localeformat.Locale) ConsumerResponse {
+	if !masking.Masks(masking.FieldPhone) {
+		r.Phone = locale.Phone(r.Phone)
+	}
+	if !masking.Masks(masking.FieldAddress) {
+		r.Address = locale.Address(r.Address)
+	}
+
+	return r
+}
+
+// NewConsumerResponses returns the representations of the consumers returned by the consumer endpoints.
+func NewConsumerResponses(consumers []Consumer) []ConsumerResponse {
+	resp := make([]ConsumerResponse, 0, len(consumers))
+	for _, consumer := range consumers {
+		resp = append(resp, NewConsumerResponse(consumer))
+	}
+
+	return resp
+}
+
+// ConsumerAvailabilityRequest represents the request payload for checking whether a username, an email,
+// or a phone number is still available, before submitting a new consumer. At least one of them must be given.
+type ConsumerAvailabilityRequest struct {
+	Username string `json:"username,omitempty" validate:"required_without_all=Email Phone,omitempty,max=50"`
+	Email    string `json:"email,omitempty" validate:"required_without_all=Username Phone,omitempty,email,max=100"`
+	Phone    string `json:"phone,omitempty" validate:"required_without_all=Username Email,omitempty,max=20,e164"`
+}
+
+// FieldAvailability reports whether a value is still available for a new consumer.
+type FieldAvailability struct {
+	Value     string `json:"value"`
+	Available bool   `json:"available"`
+}
+
+// ConsumerAvailability represents the response payload of the availability check, with an entry per value checked.
+// The phone number is reported in its normalized form, as it would be stored.
+type ConsumerAvailability struct {
+	Username *FieldAvailability `json:"username,omitempty"`
+	Email    *FieldAvailability `json:"email,omitempty"`
+	Phone    *FieldAvailability `json:"phone,omitempty"`
+}
+
+// ConsumerEmailIndex returns the blind index of the email, compared case-insensitively.
+func ConsumerEmailIndex(email string) string {
+	return fieldcrypt.BlindIndex(strings.ToLower(email))
+}
+
+// ConsumerPhoneIndex returns the blind index of the phone number, in its stored form.
+func ConsumerPhoneIndex(phone string) string {
+	return fieldcrypt.BlindIndex(phone)
+}
+
+// BeforeSave sets the blind indexes of the email and the phone number before the consumer is created or saved.
+func (c *Consumer) BeforeSave(tx *gorm.DB) error {
+	c.EmailIndex = ConsumerEmailIndex(c.Email)
+	c.PhoneIndex = ConsumerPhoneIndex(c.Phone)
+	return nil
+}
+
+// MarshalJSON encodes the consumer as JSON, with its personal data masked when the data masking is enabled.
+func (c Consumer) MarshalJSON() ([]byte, error) {
+	// The alias has the fields of the consumer without its methods, so that it is encoded by the default encoder
+	type consumer Consumer
+	return json.Marshal(consumer(c.Masked()))
+}
+
+// Masked returns a copy of the consumer with the personal data listed in DATA_MASKING_FIELDS masked,
+// or the consumer itself when the data masking is disabled.
+// The masked birth date is omitted, since a placeholder would not be a valid date.
+func (c Consumer) Masked() Consumer {
+	if !masking.Enabled() {
+		return c
+	}
+
+	if masking.Masks(masking.FieldFullname) {
+		c.Fullname = masking.Name(c.Fullname)
+	}
+	if masking.Masks(masking.FieldUsername) {
+		c.Username = masking.Name(c.Username)
+	}
+	if masking.Masks(masking.FieldEmail) {
+		c.Email = masking.Email(c.Email)
+	}
+	if masking.Masks(masking.FieldPhone) {
+		c.Phone = masking.Phone(c.Phone)
+	}
+	if masking.Masks(masking.FieldAddress) {
+		c.Address = masking.Redacted
+	}
+	if masking.Masks(masking.FieldBirthDate) {
+		c.BirthDate = nil
+	}
+
+	return c
+}
+
+// IsDeleted reports whether the consumer is soft-deleted.
+func (c Consumer) IsDeleted() bool {
+	return c.DeletedAt.Valid
+}
+
+// DeletedTime returns the deletion time of the consumer, or nil if it is not deleted.
+func (c Consumer) DeletedTime() *time.Time {
+	if !c.DeletedAt.Valid {
+		return nil
+	}
+
+	deletedAt := c.DeletedAt.Time
+	return &deletedAt
 }
 
 // TableName overrides the table name used by Consumer to `consumers`.
@@ -59,10 +297,20 @@ func (c *Consumer) Equals(other *Consumer) bool {
 
 // Validate validates the Consumer struct using the validator package.
 func (c *Consumer) Validate() error {
-	var v *validator.Validate = validator.New()
+	var v *validator.Validate = validation.GetValidator()
 
 	if err := v.Struct(c); err != nil {
 		return err
 	}
 	return nil
 }
+
+// Validate validates the ConsumerAvailabilityRequest struct using the validator package.
+func (r *ConsumerAvailabilityRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(r); err != nil {
+		return err
+	}
+	return nil
+}
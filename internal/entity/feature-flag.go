@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// FeatureFlag represents the switch of a feature changed at runtime by the administrators, see the featureflag package.
+// A feature without row is enabled, unless it is disabled by the environment.
+type FeatureFlag struct {
+	Name                  string     `gorm:"primaryKey;type:varchar(50)" json:"name"`
+	Description           string     `gorm:"-" json:"description"`
+	Disabled              bool       `gorm:"not null;default:false" json:"disabled"`
+	DisabledByEnvironment bool       `gorm:"-" json:"disabledByEnvironment"`
+	Reason                *string    `gorm:"type:varchar(255)" json:"reason,omitempty"`
+	UpdatedBy             *int64     `json:"updatedBy,omitempty"`
+	UpdatedAt             *time.Time `gorm:"type:timestamptz" json:"updatedAt,omitempty"`
+}
+
+// Override the TableName method to specify the table name
+// in the database. This is optional if you want to use the default naming convention.
+func (FeatureFlag) TableName() string {
+	return "feature_flags"
+}
+
+// FeatureFlagRequest represents the request payload for the switch of a feature by an administrator.
+// The reason is returned to the clients of the disabled feature, e.g. the expected end of a maintenance.
+type FeatureFlagRequest struct {
+	Disabled *bool   `json:"disabled" validate:"required"`
+	Reason   *string `json:"reason" validate:"omitempty,max=255"`
+}
+
+// Validate validates the FeatureFlagRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (r *FeatureFlagRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(r); err != nil {
+		return err
+	}
+	return nil
+}
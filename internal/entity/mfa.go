@@ -0,0 +1,109 @@
+package entity
+
+import (
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// UserMFA represents the two-factor authentication settings of a user, with the secret of their authenticator app.
+// The enrollment is pending until the user confirms it with a code, EnabledAt is then set.
+// LastUsedStep is the time step of the last code accepted, so that a code cannot be used twice.
+type UserMFA struct {
+	UserID       int64      `gorm:"column:user_id;primaryKey;autoIncrement:false" json:"userId"`
+	Secret       string     `gorm:"column:secret;type:varchar(64);not null" json:"-"`
+	EnabledAt    *time.Time `gorm:"column:enabled_at;type:timestamptz" json:"enabledAt,omitempty"`
+	LastUsedStep int64      `gorm:"column:last_used_step;not null;default:0" json:"-"`
+	CreatedAt    time.Time  `gorm:"column:created_at;type:timestamptz;not null;default:now()" json:"createdAt"`
+}
+
+// TableName override the table name used by UserMFA to `user_mfa`.
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// IsEnabled reports whether the enrollment was confirmed.
+func (m *UserMFA) IsEnabled() bool {
+	return m.EnabledAt != nil
+}
+
+// MFAChallenge represents the second step of a login of a user with two-factor authentication.
+// The token is given to the client once the password is checked, only its SHA-256 hash is stored.
+// It is used up by the first valid code, or after too many invalid ones, or when it expires.
+type MFAChallenge struct {
+	ID        int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    int64      `gorm:"column:user_id;index;not null" json:"userId"`
+	TokenHash string     `gorm:"column:token_hash;type:varchar(64);unique;not null" json:"-"`
+	Attempts  int        `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;type:timestamptz;not null" json:"expiresAt"`
+	UsedAt    *time.Time `gorm:"column:used_at;type:timestamptz" json:"usedAt,omitempty"`
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamptz;not null;default:now()" json:"createdAt"`
+}
+
+// TableName override the table name used by MFAChallenge to `mfa_challenges`.
+func (MFAChallenge) TableName() string {
+	return "mfa_challenges"
+}
+
+// IsUsable reports whether the challenge was not used up yet and is not expired at the given time.
+func (c *MFAChallenge) IsUsable(now time.Time) bool {
+	return c.UsedAt == nil && now.Before(c.ExpiresAt)
+}
+
+// MFAEnrollResponse represents the response payload of the enrollment of two-factor authentication.
+// The secret is shown once, to be entered in the authenticator app if the provisioning URI cannot be scanned.
+type MFAEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+// MFACodeRequest represents the request payload holding a code of the authenticator app,
+// to confirm the enrollment or to disable two-factor authentication.
+// The IPAddress is not part of the payload, it is set from the request.
+type MFACodeRequest struct {
+	Code      string `json:"code" validate:"required,len=6,numeric"`
+	IPAddress string `json:"-"`
+}
+
+// Validate validates the MFACodeRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (a *MFACodeRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(a); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MFAStatusResponse represents the two-factor authentication status of a user.
+type MFAStatusResponse struct {
+	Enabled   bool       `json:"enabled"`
+	EnabledAt *time.Time `json:"enabledAt,omitempty"`
+}
+
+// VerifyMFARequest represents the request payload of the second step of a login with two-factor authentication.
+// The ClientID, DeviceID, IPAddress, UserAgent, Country, and City are not part of the payload, they are set from the request.
+type VerifyMFARequest struct {
+	MFAToken  string `json:"mfaToken" validate:"required,max=128"`
+	Code      string `json:"code" validate:"required,len=6,numeric"`
+	ClientID  string `json:"-"`
+	DeviceID  string `json:"-" validate:"omitempty,max=64"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+	Country   string `json:"-"`
+	City      string `json:"-"`
+}
+
+// Validate validates the VerifyMFARequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (a *VerifyMFARequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(a); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package entity
+
+import (
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// Types of the security events users are notified about.
+const (
+	SecurityEventLogin           = "LOGIN"
+	SecurityEventPasswordChanged = "PASSWORD_CHANGED"
+	SecurityEventMFADisabled     = "MFA_DISABLED"
+)
+
+// SecurityEvent represents a security-relevant event of a user account, such as a login.
+// It is not stored, it is passed to the notification subsystem which decides whether to notify the user.
+type SecurityEvent struct {
+	Type      string
+	UserID    int64
+	Username  string
+	Email     string
+	IPAddress string
+	UserAgent string
+	Country   string
+	City      string
+	ClientID  string
+	At        time.Time
+}
+
+// NotificationPreference represents the security notifications a user wants to receive.
+// A user without preferences receives all of them.
+type NotificationPreference struct {
+	UserID          int64     `gorm:"column:user_id;primaryKey" json:"userId"`
+	NewDeviceLogin  bool      `gorm:"column:new_device_login;not null" json:"newDeviceLogin"`
+	PasswordChanged bool      `gorm:"column:password_changed;not null" json:"passwordChanged"`
+	MFADisabled     bool      `gorm:"column:mfa_disabled;not null" json:"mfaDisabled"`
+	UpdatedAt       time.Time `gorm:"column:updated_at;type:timestamptz;autoUpdateTime" json:"updatedAt"`
+}
+
+// NotificationPreferenceRequest represents the request payload for updating the notification preferences.
+// All preferences must be given.
+type NotificationPreferenceRequest struct {
+	NewDeviceLogin  *bool `json:"newDeviceLogin" validate:"required"`
+	PasswordChanged *bool `json:"passwordChanged" validate:"required"`
+	MFADisabled     *bool `json:"mfaDisabled" validate:"required"`
+}
+
+// UserDevice represents a device a user has logged in from, identified by a fingerprint
+// of its user agent and client. It is used to detect the logins from new devices.
+type UserDevice struct {
+	UserID      int64     `gorm:"column:user_id;primaryKey" json:"userId"`
+	Fingerprint string    `gorm:"column:fingerprint;type:varchar(64);primaryKey" json:"fingerprint"`
+	UserAgent   string    `gorm:"column:user_agent;type:text" json:"userAgent"`
+	LastIP      string    `gorm:"column:last_ip;type:varchar(45)" json:"lastIp"`
+	LastCountry string    `gorm:"column:last_country;type:varchar(2)" json:"lastCountry"`
+	LastCity    string    `gorm:"column:last_city;type:varchar(100)" json:"lastCity"`
+	FirstSeenAt time.Time `gorm:"column:first_seen_at;type:timestamptz;not null" json:"firstSeenAt"`
+	LastSeenAt  time.Time `gorm:"column:last_seen_at;type:timestamptz;not null" json:"lastSeenAt"`
+}
+
+// TableName override the table name used by NotificationPreference to `notification_preferences`.
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
+
+// TableName override the table name used by UserDevice to `user_devices`.
+func (UserDevice) TableName() string {
+	return "user_devices"
+}
+
+// DefaultNotificationPreference returns the preferences of a user who has not set any: all notifications are enabled.
+func DefaultNotificationPreference(userID int64) NotificationPreference {
+	return NotificationPreference{
+		UserID:          userID,
+		NewDeviceLogin:  true,
+		PasswordChanged: true,
+		MFADisabled:     true,
+	}
+}
+
+// Validate validates the NotificationPreferenceRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (r *NotificationPreferenceRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(r); err != nil {
+		return err
+	}
+	return nil
+}
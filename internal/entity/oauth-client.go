@@ -0,0 +1,77 @@
+package entity
+
+import (
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// GrantTypeClientCredentials is the OAuth2 grant of the machine clients, authenticated by their client ID and secret.
+const GrantTypeClientCredentials = "client_credentials"
+
+// OAuthClient represents an OAuth2 client of a service account, exchanging its client ID and secret for an access token
+// with the client_credentials grant. Only the SHA-256 hash of the secret is stored, the secret is returned once when the client is created.
+type OAuthClient struct {
+	ID         int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     int64      `gorm:"column:user_id;index;not null" json:"userId"`
+	Name       string     `gorm:"column:name;type:varchar(100);not null" json:"name"`
+	ClientID   string     `gorm:"column:client_id;type:varchar(64);unique;not null" json:"clientId"`
+	SecretHash string     `gorm:"column:secret_hash;type:varchar(64);not null" json:"-"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at;type:timestamptz" json:"revokedAt,omitempty"`
+	CreatedBy  int64      `gorm:"column:created_by;not null" json:"createdBy"`
+	CreatedAt  time.Time  `gorm:"column:created_at;type:timestamptz;not null;default:now()" json:"createdAt"`
+}
+
+// TableName override the table name used by OAuthClient to `oauth_clients`.
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// IssuedOAuthClient represents an OAuth2 client as returned when it is created, the only time its secret is returned.
+type IssuedOAuthClient struct {
+	OAuthClient
+	ClientSecret string `json:"clientSecret"`
+}
+
+// OAuthClientRequest represents the request payload for the creation of an OAuth2 client.
+type OAuthClientRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// Validate validates the OAuthClientRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (r *OAuthClientRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ClientCredentialsRequest represents the form parameters of a token request with the client_credentials grant (RFC 6749, section 4.4).
+// The client ID and secret are read from the form or from the HTTP Basic authentication of the request.
+// The Scope is a space-delimited list of the requested scopes, the token carries every scope of the client when it is empty.
+type ClientCredentialsRequest struct {
+	GrantType    string `form:"grant_type"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	Scope        string `form:"scope"`
+}
+
+// ClientCredentialsResponse represents the response of a successful token request (RFC 6749, section 5.1).
+// Like the OAuth2 error responses, it is not wrapped in the response envelope, as expected by the OAuth2 libraries.
+type ClientCredentialsResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// OAuthErrorResponse represents the response of a failed token request (RFC 6749, section 5.2).
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// PasswordResetToken represents a password reset token sent to a user by email.
+// Only the SHA-256 hash of the token is stored, and the token can be used once, before it expires.
+type PasswordResetToken struct {
+	ID        int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    int64      `gorm:"column:user_id;index;not null" json:"userId"`
+	TokenHash string     `gorm:"column:token_hash;type:varchar(64);unique;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;type:timestamptz;not null" json:"expiresAt"`
+	UsedAt    *time.Time `gorm:"column:used_at;type:timestamptz" json:"usedAt,omitempty"`
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamptz;not null;default:now()" json:"createdAt"`
+}
+
+// TableName override the table name used by PasswordResetToken to `password_reset_tokens`.
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// IsUsable reports whether the token was not used yet and is not expired at the given time.
+func (t *PasswordResetToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// ForgotPasswordRequest represents the request payload for requesting a password reset link.
+// The locale of the email is set from the Accept-Language header of the request, not from the payload.
+type ForgotPasswordRequest struct {
+	Email  string `json:"email" validate:"required,email,max=100"`
+	Locale string `json:"-"`
+}
+
+// Validate validates the ForgotPasswordRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (a *ForgotPasswordRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(a); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ResetPasswordRequest represents the request payload for resetting a password with the token of a reset link.
+// The new password must contain uppercase and lowercase letters, a digit, and a special character.
+// The IPAddress is not part of the payload, it is set from the request.
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required,max=128"`
+	NewPassword string `json:"newPassword" validate:"required,min=8,max=20,password_complexity"`
+	IPAddress   string `json:"-"`
+}
+
+// Validate validates the ResetPasswordRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (a *ResetPasswordRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(a); err != nil {
+		return err
+	}
+	return nil
+}
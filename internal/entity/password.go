@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// ChangePasswordRequest represents the request payload for changing the password of the authenticated user.
+// The new password must contain uppercase and lowercase letters, a digit, and a special character, and differ from the current one.
+// The IPAddress is not part of the payload, it is set from the request.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required,max=20"`
+	NewPassword     string `json:"newPassword" validate:"required,min=8,max=20,password_complexity,nefield=CurrentPassword"`
+	IPAddress       string `json:"-"`
+}
+
+// Validate validates the ChangePasswordRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (a *ChangePasswordRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(a); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ChangePasswordResponse represents the response payload for a password change.
+// The CredentialsExpirationDate is omitted when the credentials never expire.
+type ChangePasswordResponse struct {
+	UserID                    int64      `json:"userId"`
+	CredentialsExpirationDate *time.Time `json:"credentialsExpirationDate,omitempty"`
+	ChangedAt                 time.Time  `json:"changedAt"`
+}
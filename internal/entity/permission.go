@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"gopkg.in/go-playground/validator.v9"
+
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// Permissions, seeded with the database and granted to the roles. They are named <resource>:<action>,
+// and carried by the access tokens in the scopes claim checked by the authorization.RequireScope middleware.
+// They are not created at runtime, since the routes require them by name.
+const (
+	PermissionConsumersRead  = "consumers:read"
+	PermissionConsumersWrite = "consumers:write"
+	PermissionUsersRead      = "users:read"
+	PermissionUsersWrite     = "users:write"
+	PermissionRolesRead      = "roles:read"
+	PermissionRolesWrite     = "roles:write"
+)
+
+// DefaultRolePermissions lists the permissions granted to the built-in roles by the seed data.
+var DefaultRolePermissions = map[string][]string{
+	RoleUser:      {PermissionConsumersRead},
+	RoleModerator: {PermissionConsumersRead, PermissionUsersRead},
+	RoleAdmin: {PermissionConsumersRead, PermissionConsumersWrite, PermissionUsersRead, PermissionUsersWrite,
+		PermissionRolesRead, PermissionRolesWrite},
+}
+
+// Permission represents the permission entity in the database.
+type Permission struct {
+	ID          uint    `gorm:"primaryKey;autoIncrement" json:"permissionId"`
+	Name        string  `gorm:"type:varchar(100);not null;uniqueIndex" json:"permissionName"`
+	Description *string `gorm:"type:varchar(255)" json:"description,omitempty"`
+}
+
+// RolePermission represents the many-to-many relationship between roles and permissions.
+type RolePermission struct {
+	RoleID       uint `gorm:"primaryKey;not null"`
+	PermissionID uint `gorm:"primaryKey;not null"`
+}
+
+// Override the TableName method to specify the table name
+// in the database. This is optional if you want to use the default naming convention.
+func (Permission) TableName() string {
+	return "permissions"
+}
+
+// Override the TableName method to specify the table name
+// in the database. This is optional if you want to use the default naming convention.
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+// RolePermissionsRequest represents the request payload for the replacement of the permissions of a role.
+// An empty list removes all the permissions of the role.
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions" validate:"max=50,dive,required,max=100"`
+}
+
+// Validate validates the RolePermissionsRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (r *RolePermissionsRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(r); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// RateLimitOverride represents the factor scaling the rate limits of a client, set at runtime by the administrators.
+// The client is the one of the quotas: the client ID carried by the access tokens, or "user:<id>" for the tokens issued without client.
+// A client without row is allowed the default limits.
+type RateLimitOverride struct {
+	Client    string     `gorm:"primaryKey;type:varchar(100)" json:"client"`
+	Factor    float64    `gorm:"not null" json:"factor"`
+	Reason    *string    `gorm:"type:varchar(255)" json:"reason,omitempty"`
+	UpdatedBy *int64     `json:"updatedBy,omitempty"`
+	UpdatedAt *time.Time `gorm:"type:timestamptz" json:"updatedAt,omitempty"`
+}
+
+// Override the TableName method to specify the table name
+// in the database. This is optional if you want to use the default naming convention.
+func (RateLimitOverride) TableName() string {
+	return "rate_limit_overrides"
+}
+
+// RateLimitOverrideRequest represents the request payload for the override of the rate limits of a client by an administrator.
+// The factor multiplies every rate limit of the client, e.g. 10 for ten times the default limits, or 0.1 to throttle the client.
+type RateLimitOverrideRequest struct {
+	Factor *float64 `json:"factor" validate:"required,gt=0,lte=1000"`
+	Reason *string  `json:"reason" validate:"omitempty,max=255"`
+}
+
+// Validate validates the RateLimitOverrideRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (r *RateLimitOverrideRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(r); err != nil {
+		return err
+	}
+	return nil
+}
@@ -9,17 +9,54 @@ import (
 )
 
 // RefreshToken represents the refresh token entity in the database.
+// Each refresh token is an active session of the user, a user may have several of them, e.g. one per device.
+// The SessionID identifies the session, it is kept when the refresh token is rotated.
+// The DeviceID, ClientID, UserAgent, and IPAddress describe the device which started the session.
 type RefreshToken struct {
-	Token      string    `gorm:"column:token;type:text;primaryKey;unique;not null" json:"token" validate:"required"`
-	UserID     int64     `gorm:"column:user_id;primaryKey;unique;not null" json:"userId" validate:"required"`
-	User       *User     `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"user,omitempty"`
-	ExpiryDate time.Time `gorm:"column:expiry_date;type:timestamptz;not null" json:"expiryDate" validate:"required"`
+	Token            string    `gorm:"column:token;type:text;primaryKey;not null" json:"token" validate:"required"`
+	SessionID        string    `gorm:"column:session_id;type:varchar(36);index;not null" json:"sessionId"`
+	UserID           int64     `gorm:"column:user_id;index;not null" json:"userId" validate:"required"`
+	User             *User     `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"user,omitempty"`
+	DeviceID         string    `gorm:"column:device_id;type:varchar(64)" json:"deviceId,omitempty"`
+	ClientID         string    `gorm:"column:client_id;type:varchar(100)" json:"clientId,omitempty"`
+	UserAgent        string    `gorm:"column:user_agent;type:varchar(255)" json:"userAgent,omitempty"`
+	IPAddress        string    `gorm:"column:ip_address;type:varchar(45)" json:"ipAddress,omitempty"`
+	ExpiryDate       time.Time `gorm:"column:expiry_date;type:timestamptz;not null" json:"expiryDate" validate:"required"`
+	SessionStartedAt time.Time `gorm:"column:session_started_at;type:timestamptz;not null;default:now()" json:"sessionStartedAt"`
+	CreatedAt        time.Time `gorm:"column:created_at;type:timestamptz;not null;default:now()" json:"createdAt"`
+}
+
+// SessionDevice describes the device starting a session, it is recorded with the refresh token of the session.
+// A new session from a device with a DeviceID replaces the previous session of the same device.
+type SessionDevice struct {
+	DeviceID  string
+	ClientID  string
+	UserAgent string
+	IPAddress string
+}
+
+// Session represents an active session of the user, as listed to the user.
+// It never contains the refresh token of the session.
+type Session struct {
+	SessionID    string    `json:"sessionId"`
+	DeviceID     string    `json:"deviceId,omitempty"`
+	ClientID     string    `json:"clientId,omitempty"`
+	UserAgent    string    `json:"userAgent,omitempty"`
+	IPAddress    string    `json:"ipAddress,omitempty"`
+	StartedAt    time.Time `json:"startedAt"`
+	LastActiveAt time.Time `json:"lastActiveAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
 }
 
 // RefreshTokenRequest represents the request payload for refreshing a token.
 // It contains the refresh token that needs to be validated and used to obtain a new access token.
+// The AccessToken is optional, it is the access token issued with the refresh token, which may be expired.
+// The ClientID is not part of the payload, it is set from the X-Client-ID header.
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refreshToken" validate:"required"`
+	AccessToken  string `json:"accessToken,omitempty"`
+	ClientID     string `json:"-"`
+	IPAddress    string `json:"-"`
 }
 
 // RefreshTokenResponse represents the response payload for refreshing a token.
@@ -47,14 +84,35 @@ func (r *RefreshToken) Equals(other *RefreshToken) bool {
 	}
 
 	if (r.Token != other.Token) ||
+		(r.SessionID != other.SessionID) ||
 		(r.UserID != other.UserID) ||
-		(r.ExpiryDate != other.ExpiryDate) {
+		(r.DeviceID != other.DeviceID) ||
+		(r.ClientID != other.ClientID) ||
+		(r.UserAgent != other.UserAgent) ||
+		(r.IPAddress != other.IPAddress) ||
+		(r.ExpiryDate != other.ExpiryDate) ||
+		(r.SessionStartedAt != other.SessionStartedAt) ||
+		(r.CreatedAt != other.CreatedAt) {
 		return false
 	}
 
 	return true
 }
 
+// ToSession returns the session of the refresh token, the time it was last rotated is the last activity of the session.
+func (r *RefreshToken) ToSession() Session {
+	return Session{
+		SessionID:    r.SessionID,
+		DeviceID:     r.DeviceID,
+		ClientID:     r.ClientID,
+		UserAgent:    r.UserAgent,
+		IPAddress:    r.IPAddress,
+		StartedAt:    r.SessionStartedAt,
+		LastActiveAt: r.CreatedAt,
+		ExpiresAt:    r.ExpiryDate,
+	}
+}
+
 // Validate validates the RefreshTokenRequest struct using the validator package.
 // It checks if the struct fields meet the specified validation rules.
 func (a *RefreshTokenRequest) Validate() error {
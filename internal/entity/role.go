@@ -6,10 +6,20 @@ import (
 	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
 )
 
+// Built-in roles, seeded with the database. They cannot be renamed or deleted,
+// since the routes grant their access with these names.
+const (
+	RoleUser      = "ROLE_USER"
+	RoleModerator = "ROLE_MODERATOR"
+	RoleAdmin     = "ROLE_ADMIN"
+)
+
 // Role represents the role entity in the database.
+// Besides the built-in roles, the administrators can create custom roles named ROLE_<NAME>.
 type Role struct {
-	ID   uint   `gorm:"primaryKey;autoIncrement" json:"roleId"`
-	Name string `gorm:"type:varchar(20);not null;check:name IN ('ROLE_USER','ROLE_MODERATOR','ROLE_ADMIN')" json:"roleName" validate:"required,max=20,oneof=ROLE_USER ROLE_MODERATOR ROLE_ADMIN"`
+	ID          uint    `gorm:"primaryKey;autoIncrement" json:"roleId"`
+	Name        string  `gorm:"type:varchar(50);not null;uniqueIndex" json:"roleName" validate:"required,max=50,role_name"`
+	Description *string `gorm:"type:varchar(255)" json:"description,omitempty" validate:"omitempty,max=255"`
 }
 
 // UserRole represents the many-to-many relationship between users and roles.
@@ -41,6 +51,50 @@ func (r *Role) Validate() error {
 	return nil
 }
 
+// IsBuiltInRole reports whether the role name is one of the built-in roles.
+func IsBuiltInRole(name string) bool {
+	switch name {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// RoleRequest represents the request payload for the creation or the update of a role by an administrator.
+type RoleRequest struct {
+	Name        string  `json:"roleName" validate:"required,max=50,role_name"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=255"`
+}
+
+// Validate validates the RoleRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (r *RoleRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UserRolesRequest represents the request payload for the assignment and the removal of roles of a user.
+// The roles of the user not named in the request are kept.
+type UserRolesRequest struct {
+	Add    []string `json:"add,omitempty" validate:"required_without_all=Remove,omitempty,max=10,dive,required,max=50"`
+	Remove []string `json:"remove,omitempty" validate:"omitempty,max=10,dive,required,max=50"`
+}
+
+// Validate validates the UserRolesRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (r *UserRolesRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(r); err != nil {
+		return err
+	}
+	return nil
+}
+
 // Equals compares two Role objects for equality.
 func (r *Role) Equals(other *Role) bool {
 	if r == nil && other == nil {
@@ -52,7 +106,8 @@ func (r *Role) Equals(other *Role) bool {
 	}
 
 	if (r.ID != other.ID) ||
-		(r.Name != other.Name) {
+		(r.Name != other.Name) ||
+		(r.Description != other.Description) {
 		return false
 	}
 
@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// SecuritySettingsID is the ID of the single row of the security settings.
+const SecuritySettingsID = 1
+
+// SecuritySettings represents the CORS and security headers changed at runtime by the administrators.
+// The allowed origins and headers extend the ones of the environment, and the security headers replace the default ones,
+// an empty value removing the header. There is a single row, a database without it has no settings.
+type SecuritySettings struct {
+	ID              uint              `gorm:"primaryKey" json:"-"`
+	AllowedOrigins  []string          `gorm:"type:text;serializer:json;not null" json:"allowedOrigins"`
+	AllowedHeaders  []string          `gorm:"type:text;serializer:json;not null" json:"allowedHeaders"`
+	SecurityHeaders map[string]string `gorm:"type:text;serializer:json;not null" json:"securityHeaders"`
+	UpdatedBy       *int64            `json:"updatedBy,omitempty"`
+	UpdatedAt       *time.Time        `gorm:"type:timestamptz" json:"updatedAt,omitempty"`
+}
+
+// Override the TableName method to specify the table name
+// in the database. This is optional if you want to use the default naming convention.
+func (SecuritySettings) TableName() string {
+	return "security_settings"
+}
+
+// SecuritySettingsRequest represents the request payload for the replacement of the security settings by an administrator.
+// All settings must be given, an empty list or object clearing them.
+// The origins are checked to be HTTP or HTTPS origins and the security headers to be overridable by the service.
+type SecuritySettingsRequest struct {
+	AllowedOrigins  []string          `json:"allowedOrigins" validate:"required,max=50,dive,required,max=255"`
+	AllowedHeaders  []string          `json:"allowedHeaders" validate:"required,max=50,dive,required,max=100,header_name"`
+	SecurityHeaders map[string]string `json:"securityHeaders" validate:"required,max=20,dive,max=1024"`
+}
+
+// Validate validates the SecuritySettingsRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (r *SecuritySettingsRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(r); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+// SignedURLResponse represents a signed download URL issued to the authenticated user,
+// valid until ExpiresAt without the access token.
+type SignedURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
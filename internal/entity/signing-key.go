@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// SigningKeyRotation represents the rotation of the key signing the access tokens.
+// The previous key keeps verifying the tokens it signed until PreviousKeyExpiresAt, when none of them can still be valid.
+type SigningKeyRotation struct {
+	KeyID                string     `json:"kid"`
+	CreatedAt            time.Time  `json:"createdAt"`
+	PreviousKeyID        string     `json:"previousKid,omitempty"`
+	PreviousKeyExpiresAt *time.Time `json:"previousKeyExpiresAt,omitempty"`
+}
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// TokenRevocation represents the revocation of all the tokens of a user.
+// The access tokens carrying a version older than TokenVersion are rejected,
+// and the refresh tokens of the user are deleted.
+type TokenRevocation struct {
+	UserID       int64     `json:"userId"`
+	TokenVersion int64     `json:"tokenVersion"`
+	RevokedAt    time.Time `json:"revokedAt"`
+}
+
+// RevokedToken represents an access token revoked before its expiration, e.g. when the user logged out.
+// The token is identified by its jti claim, and the entry is only needed until the token expires.
+type RevokedToken struct {
+	TokenID   string    `gorm:"column:token_id;type:varchar(64);primaryKey;not null" json:"tokenId"`
+	UserID    int64     `gorm:"column:user_id;index;not null" json:"userId"`
+	ExpiresAt time.Time `gorm:"column:expires_at;type:timestamptz;index;not null" json:"expiresAt"`
+	RevokedAt time.Time `gorm:"column:revoked_at;type:timestamptz;not null;default:now()" json:"revokedAt"`
+}
+
+// TableName override the table name used by RevokedToken to `revoked_tokens`.
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+)
+
+const (
+	// UnknownClient is the client of the tokens issued without the X-Client-ID header.
+	UnknownClient = "unknown"
+
+	// MaxTokenStatsDays is the maximum number of days covered by a token statistics query.
+	MaxTokenStatsDays = 366
+)
+
+// TokenUsage represents the number of tokens issued, refreshed, and revoked for a user and a client on a day.
+// It is an aggregate row of the token_usage table, its counters are incremented by the auth service.
+type TokenUsage struct {
+	Day       customtype.Date `gorm:"column:day;type:date;primaryKey" json:"day"`
+	UserID    int64           `gorm:"column:user_id;primaryKey" json:"userId"`
+	Client    string          `gorm:"column:client;type:varchar(100);primaryKey" json:"client"`
+	Issued    int64           `gorm:"column:issued;not null;default:0" json:"issued"`
+	Refreshed int64           `gorm:"column:refreshed;not null;default:0" json:"refreshed"`
+	Revoked   int64           `gorm:"column:revoked;not null;default:0" json:"revoked"`
+}
+
+// TokenUsageTotals holds the number of tokens issued, refreshed, and revoked over a date range.
+type TokenUsageTotals struct {
+	Issued    int64 `json:"issued"`
+	Refreshed int64 `json:"refreshed"`
+	Revoked   int64 `json:"revoked"`
+}
+
+// TokenStatsFilter selects the token usage rows of a date range, both days included.
+// The UserID and Client filters are ignored when they are zero or empty.
+type TokenStatsFilter struct {
+	From   time.Time
+	To     time.Time
+	UserID int64
+	Client string
+}
+
+// TokenStats represents the token usage over a date range, with the daily rows and their totals.
+type TokenStats struct {
+	From   customtype.Date  `json:"from"`
+	To     customtype.Date  `json:"to"`
+	Totals TokenUsageTotals `json:"totals"`
+	Usage  []TokenUsage     `json:"usage"`
+}
+
+// TableName override the table name used by TokenUsage to `token_usage`.
+func (TokenUsage) TableName() string {
+	return "token_usage"
+}
+
+// Validate checks that the date range of the filter is set, ordered, and not longer than MaxTokenStatsDays.
+func (f *TokenStatsFilter) Validate() error {
+	if f.From.IsZero() || f.To.IsZero() {
+		return fmt.Errorf("the date range is not set")
+	}
+	if f.To.Before(f.From) {
+		return fmt.Errorf("from must not be after to")
+	}
+	if days := int(f.To.Sub(f.From).Hours()/24) + 1; days > MaxTokenStatsDays {
+		return fmt.Errorf("the date range must not exceed %d days", MaxTokenStatsDays)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"time"
+)
+
+// UserIdentity represents the account of a user at an OpenID Connect provider, identified by the provider and its subject.
+// It links the account to the local user, which is provisioned on the first login with the provider.
+type UserIdentity struct {
+	ID          int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      int64      `gorm:"column:user_id;index;not null" json:"userId"`
+	Provider    string     `gorm:"column:provider;type:varchar(20);not null;uniqueIndex:idx_user_identities_provider_subject" json:"provider"`
+	Subject     string     `gorm:"column:subject;type:varchar(255);not null;uniqueIndex:idx_user_identities_provider_subject" json:"subject"`
+	Email       string     `gorm:"column:email;type:varchar(100);not null" json:"email"`
+	LastLoginAt *time.Time `gorm:"column:last_login_at;type:timestamptz" json:"lastLoginAt,omitempty"`
+	CreatedAt   time.Time  `gorm:"column:created_at;type:timestamptz;not null;default:now()" json:"createdAt"`
+}
+
+// TableName override the table name used by UserIdentity to `user_identities`.
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// OIDCLoginState holds the values of a login with an OpenID Connect provider, kept by the browser of the user
+// between the redirection to the provider and the callback: the state and the nonce the provider must echo back,
+// and the PKCE code verifier of the authorization request.
+type OIDCLoginState struct {
+	Provider     string    `json:"provider"`
+	State        string    `json:"state"`
+	Nonce        string    `json:"nonce"`
+	CodeVerifier string    `json:"codeVerifier"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// OIDCAuthorization represents the start of a login with an OpenID Connect provider:
+// the URL of the provider the user is redirected to, and the state of the login kept until the callback.
+type OIDCAuthorization struct {
+	URL        string
+	LoginState OIDCLoginState
+}
+
+// OIDCCallbackRequest represents the query parameters of the callback of an OpenID Connect provider (OpenID Connect Core 1.0, section 3.1.2.5).
+// The provider sends either the authorization code or an error, along with the state of the authorization request.
+// The device and the location of the client are set from the request, like for the logins with a password.
+type OIDCCallbackRequest struct {
+	Code             string `form:"code"`
+	State            string `form:"state"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+
+	ClientID  string `form:"-"`
+	DeviceID  string `form:"-"`
+	IPAddress string `form:"-"`
+	UserAgent string `form:"-"`
+	Country   string `form:"-"`
+	City      string `form:"-"`
+}
@@ -0,0 +1,119 @@
+package entity
+
+import (
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// UserState represents the state of a user account, which decides whether the user can authenticate.
+type UserState string
+
+// States of the user accounts.
+// A disabled account is a soft disable: the user cannot log in or refresh their tokens,
+// but the access tokens already issued stay valid until they expire.
+// A suspended account is a hard suspend: all the tokens of the user are revoked immediately.
+const (
+	UserStateActive              UserState = "ACTIVE"
+	UserStatePendingVerification UserState = "PENDING_VERIFICATION"
+	UserStateDisabled            UserState = "DISABLED"
+	UserStateSuspended           UserState = "SUSPENDED"
+)
+
+// userStateTransitions holds the states each state can change to.
+// An account is pending verification only when it is created, it cannot go back to it.
+var userStateTransitions = map[UserState][]UserState{
+	UserStatePendingVerification: {UserStateActive, UserStateDisabled, UserStateSuspended},
+	UserStateActive:              {UserStateDisabled, UserStateSuspended},
+	UserStateDisabled:            {UserStateActive, UserStateSuspended},
+	UserStateSuspended:           {UserStateActive, UserStateDisabled},
+}
+
+// IsValid reports whether the state is one of the known states.
+func (s UserState) IsValid() bool {
+	_, ok := userStateTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether a user account in the state can change to the given state.
+func (s UserState) CanTransitionTo(next UserState) bool {
+	for _, allowed := range userStateTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequiresReason reports whether a reason must be given when changing a user account to the state.
+func (s UserState) RequiresReason() bool {
+	return s == UserStateDisabled || s == UserStateSuspended
+}
+
+// UserStateRequest represents the request payload for changing the state of a user account.
+// The reason is required when the account is disabled or suspended.
+type UserStateRequest struct {
+	State  UserState `json:"state" validate:"required,oneof=ACTIVE DISABLED SUSPENDED"`
+	Reason string    `json:"reason" validate:"max=255"`
+}
+
+// UserStateChange represents the state a user account was changed to.
+type UserStateChange struct {
+	UserID        int64     `json:"userId"`
+	PreviousState UserState `json:"previousState"`
+	State         UserState `json:"state"`
+	Reason        *string   `json:"reason,omitempty"`
+	ChangedAt     time.Time `json:"changedAt"`
+}
+
+// Validate validates the UserStateRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (r *UserStateRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Actions changing the state of a user account with PATCH /api/v1/users/{id}.
+// Enabling activates the account, disabling prevents new logins, and locking suspends the account and revokes all its tokens.
+const (
+	UserActionEnable  = "enable"
+	UserActionDisable = "disable"
+	UserActionLock    = "lock"
+)
+
+// userActionStates holds the state each action changes a user account to.
+var userActionStates = map[string]UserState{
+	UserActionEnable:  UserStateActive,
+	UserActionDisable: UserStateDisabled,
+	UserActionLock:    UserStateSuspended,
+}
+
+// UserActionRequest represents the request payload for enabling, disabling, or locking a user account.
+// The reason is required when the account is disabled or locked.
+type UserActionRequest struct {
+	Action string `json:"action" validate:"required,oneof=enable disable lock"`
+	Reason string `json:"reason" validate:"max=255"`
+}
+
+// Validate validates the UserActionRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (r *UserActionRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// StateRequest returns the change of state performed by the action.
+func (r *UserActionRequest) StateRequest() UserStateRequest {
+	return UserStateRequest{State: userActionStates[r.Action], Reason: r.Reason}
+}
@@ -9,6 +9,12 @@ import (
 	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
 )
 
+// Types of the user accounts.
+const (
+	UserTypeServiceAccount = "SERVICE_ACCOUNT"
+	UserTypeUserAccount    = "USER_ACCOUNT"
+)
+
 // User represents the user entity in the database.
 type User struct {
 	ID                        int64           `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -17,15 +23,15 @@ type User struct {
 	Email                     string          `gorm:"type:varchar(100);not null;unique" json:"email" validate:"required,email,max=100"`
 	Firstname                 string          `gorm:"type:varchar(20);not null" json:"firstName" validate:"required,max=20"`
 	Lastname                  *string         `gorm:"type:varchar(20)" json:"lastName,omitempty" validate:"omitempty,max=20"`
-	IsEnabled                 *bool           `gorm:"not null;default:false" json:"isEnabled,omitempty"`
-	IsAccountNonExpired       *bool           `gorm:"not null;default:false" json:"isAccountNonExpired,omitempty"`
-	IsAccountNonLocked        *bool           `gorm:"not null;default:false" json:"isAccountNonLocked,omitempty"`
-	IsCredentialsNonExpired   *bool           `gorm:"not null;default:false" json:"isCredentialsNonExpired,omitempty"`
+	State                     UserState       `gorm:"type:varchar(20);not null;default:'PENDING_VERIFICATION';check:state IN ('ACTIVE','PENDING_VERIFICATION','DISABLED','SUSPENDED')" json:"state"`
+	StateReason               *string         `gorm:"type:varchar(255)" json:"stateReason,omitempty"`
+	StateChangedAt            *time.Time      `gorm:"type:timestamptz" json:"stateChangedAt,omitempty"`
 	IsDeleted                 *bool           `gorm:"not null;default:false" json:"isDeleted,omitempty"`
 	AccountExpirationDate     *time.Time      `gorm:"type:timestamptz" json:"accountExpirationDate,omitempty"`
 	CredentialsExpirationDate *time.Time      `gorm:"type:timestamptz" json:"credentialsExpirationDate,omitempty"`
 	UserType                  string          `gorm:"type:varchar(20);not null;check:user_type IN ('SERVICE_ACCOUNT','USER_ACCOUNT')" json:"userType" validate:"required,max=20,oneof=SERVICE_ACCOUNT USER_ACCOUNT"`
 	LastLogin                 *time.Time      `json:"lastLogin,omitempty"`
+	TokenVersion              int64           `gorm:"not null;default:0" json:"-"`
 	CreatedBy                 *int64          `json:"createdBy,omitempty"`
 	CreatedAt                 *time.Time      `gorm:"type:timestamptz;autoCreateTime;default:now()" json:"createdAt,omitempty"`
 	UpdatedBy                 *int64          `json:"updatedBy,omitempty"`
@@ -33,6 +39,12 @@ type User struct {
 	DeletedBy                 *int64          `json:"deletedBy,omitempty"`
 	DeletedAt                 *gorm.DeletedAt `gorm:"type:timestamptz;index" json:"deletedAt,omitempty"`
 	Roles                     []Role          `gorm:"many2many:user_roles;constraint:OnUpdate:RESTRICT,OnDelete:SET NULL" json:"roles,omitempty"`
+
+	// Scopes are the permissions granted to the roles of the user, resolved when an access token is issued
+	Scopes []string `gorm:"-" json:"-"`
+
+	// Claims are the additional claims of the access tokens of the user, set by the login hooks
+	Claims map[string]interface{} `gorm:"-" json:"-"`
 }
 
 // Override the TableName method to specify the table name
@@ -57,10 +69,9 @@ func (u *User) Equals(other *User) bool {
 		(u.Email != other.Email) ||
 		(u.Firstname != other.Firstname) ||
 		(u.Lastname != other.Lastname) ||
-		(u.IsEnabled != other.IsEnabled) ||
-		(u.IsAccountNonExpired != other.IsAccountNonExpired) ||
-		(u.IsAccountNonLocked != other.IsAccountNonLocked) ||
-		(u.IsCredentialsNonExpired != other.IsCredentialsNonExpired) ||
+		(u.State != other.State) ||
+		(u.StateReason != other.StateReason) ||
+		(u.StateChangedAt != other.StateChangedAt) ||
 		(u.IsDeleted != other.IsDeleted) ||
 		(u.AccountExpirationDate != other.AccountExpirationDate) ||
 		(u.CredentialsExpirationDate != other.CredentialsExpirationDate) ||
@@ -83,3 +94,91 @@ func (u *User) Validate() error {
 	}
 	return nil
 }
+
+// CreateUserRequest represents the request payload for the creation of a user account by an administrator.
+// The account is active right away. The roles default to ROLE_USER and the user type to USER_ACCOUNT.
+type CreateUserRequest struct {
+	Username  string   `json:"username" validate:"required,min=3,max=20"`
+	Password  string   `json:"password" validate:"required,min=8,max=20,password_complexity"`
+	Email     string   `json:"email" validate:"required,email,max=100"`
+	Firstname string   `json:"firstName" validate:"required,max=20"`
+	Lastname  *string  `json:"lastName,omitempty" validate:"omitempty,max=20"`
+	UserType  string   `json:"userType,omitempty" validate:"omitempty,oneof=SERVICE_ACCOUNT USER_ACCOUNT"`
+	Roles     []string `json:"roles,omitempty" validate:"omitempty,max=10,dive,required,max=50"`
+}
+
+// Validate validates the CreateUserRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (r *CreateUserRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateUserRequest represents the request payload for the update of a user account by an administrator.
+// It replaces the profile and the roles of the user; the username, the password, and the state are not changed.
+type UpdateUserRequest struct {
+	Email     string   `json:"email" validate:"required,email,max=100"`
+	Firstname string   `json:"firstName" validate:"required,max=20"`
+	Lastname  *string  `json:"lastName,omitempty" validate:"omitempty,max=20"`
+	UserType  string   `json:"userType" validate:"required,oneof=SERVICE_ACCOUNT USER_ACCOUNT"`
+	Roles     []string `json:"roles" validate:"required,min=1,max=10,dive,required,max=50"`
+}
+
+// Validate validates the UpdateUserRequest struct using the validator package.
+// It checks if the struct fields meet the specified validation rules.
+func (r *UpdateUserRequest) Validate() error {
+	var v *validator.Validate = validation.GetValidator()
+
+	if err := v.Struct(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UserResponse represents a user account as returned by the administration endpoints.
+// It never contains the password of the user.
+type UserResponse struct {
+	ID                        int64      `json:"id"`
+	Username                  string     `json:"username"`
+	Email                     string     `json:"email"`
+	Firstname                 string     `json:"firstName"`
+	Lastname                  *string    `json:"lastName,omitempty"`
+	State                     UserState  `json:"state"`
+	StateReason               *string    `json:"stateReason,omitempty"`
+	UserType                  string     `json:"userType"`
+	Roles                     []string   `json:"roles"`
+	LastLogin                 *time.Time `json:"lastLogin,omitempty"`
+	AccountExpirationDate     *time.Time `json:"accountExpirationDate,omitempty"`
+	CredentialsExpirationDate *time.Time `json:"credentialsExpirationDate,omitempty"`
+	CreatedAt                 *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt                 *time.Time `json:"updatedAt,omitempty"`
+}
+
+// NewUserResponse returns the representation of the user returned by the administration endpoints.
+func NewUserResponse(user User) UserResponse {
+	roles := make([]string, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		roles = append(roles, role.Name)
+	}
+
+	return UserResponse{
+		ID:                        user.ID,
+		Username:                  user.Username,
+		Email:                     user.Email,
+		Firstname:                 user.Firstname,
+		Lastname:                  user.Lastname,
+		State:                     user.State,
+		StateReason:               user.StateReason,
+		UserType:                  user.UserType,
+		Roles:                     roles,
+		LastLogin:                 user.LastLogin,
+		AccountExpirationDate:     user.AccountExpirationDate,
+		CredentialsExpirationDate: user.CredentialsExpirationDate,
+		CreatedAt:                 user.CreatedAt,
+		UpdatedAt:                 user.UpdatedAt,
+	}
+}
@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"time"
+)
+
+// Types of the consumer events sent to the webhook.
+const (
+	WebhookEventConsumerCreated       = "consumer.created"
+	WebhookEventConsumerStatusChanged = "consumer.status_changed"
+)
+
+// Statuses of the webhook deliveries.
+// A delivery is PENDING until its first attempt, FAILED while it waits for a retry, and DEAD once all its attempts failed.
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliverySucceeded = "SUCCEEDED"
+	WebhookDeliveryFailed    = "FAILED"
+	WebhookDeliveryDead      = "DEAD"
+)
+
+// WebhookDelivery represents the delivery of a consumer event to the webhook, with the outcome of its last attempt.
+// The failed attempts are retried with an exponential backoff, until the delivery is dead-lettered.
+type WebhookDelivery struct {
+	ID             int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Event          string     `gorm:"column:event;type:varchar(50);not null" json:"event"`
+	URL            string     `gorm:"column:url;type:text;not null" json:"url"`
+	Payload        string     `gorm:"column:payload;type:text;not null" json:"payload"`
+	Status         string     `gorm:"column:status;type:varchar(20);index;not null" json:"status"`
+	Attempts       int        `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	NextAttemptAt  *time.Time `gorm:"column:next_attempt_at;type:timestamptz;index" json:"nextAttemptAt,omitempty"`
+	LastAttemptAt  *time.Time `gorm:"column:last_attempt_at;type:timestamptz" json:"lastAttemptAt,omitempty"`
+	ResponseStatus int        `gorm:"column:response_status;not null;default:0" json:"responseStatus,omitempty"`
+	ResponseBody   string     `gorm:"column:response_body;type:text" json:"responseBody,omitempty"`
+	LastError      string     `gorm:"column:last_error;type:text" json:"lastError,omitempty"`
+	CreatedAt      time.Time  `gorm:"column:created_at;type:timestamptz;not null;default:now()" json:"createdAt"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;type:timestamptz;not null;default:now()" json:"updatedAt"`
+}
+
+// TableName override the table name used by WebhookDelivery to `webhook_deliveries`.
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookEvent represents the body of the requests sent to the webhook.
+type WebhookEvent struct {
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Data       interface{} `json:"data"`
+}
+
+// ConsumerStatusChange represents the data of the consumer.status_changed events.
+type ConsumerStatusChange struct {
+	Consumer       Consumer `json:"consumer"`
+	PreviousStatus string   `json:"previousStatus"`
+}
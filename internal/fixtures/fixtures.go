@@ -0,0 +1,241 @@
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+)
+
+/**
+ * fixtures package generates the example responses of the API as each role sees them, for the examples of the
+ * OpenAPI document and the contract tests of the frontends. The examples are the responses of the actual handlers,
+ * with the access control, the given consumer listing policy and pagination, and the data masking, to a fixed set
+ * of consumers, so they change only when what a role sees changes.
+ *
+ * Every example is validated against the OpenAPI document before it is returned, so no invalid example is published.
+ */
+
+// Roles lists the roles the examples are generated for.
+var Roles = []string{"ROLE_USER", "ROLE_ADMIN"}
+
+// Timestamp replaces the times of the responses, e.g. the timestamp of the envelope, so that the examples are stable.
+var Timestamp = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// volatileKeys are the keys of the responses holding a time, replaced by Timestamp.
+var volatileKeys = map[string]bool{"timestamp": true, "createdAt": true, "updatedAt": true}
+
+// The IDs of the example consumers, one per status.
+const (
+	ActiveConsumerID    = "6f1c2b0e-3d4a-4e5f-8a9b-0c1d2e3f4a5b"
+	InactiveConsumerID  = "7a2d3c1f-4e5b-4f60-9b0c-1d2e3f4a5b6c"
+	SuspendedConsumerID = "8b3e4d20-5f6c-4071-8c1d-2e3f4a5b6c7d"
+)
+
+// Consumers returns the example consumers, one per status.
+func Consumers() []entity.Consumer {
+	birthDate := func(year int, month time.Month, day int) *customtype.Date {
+		return &customtype.Date{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+	}
+
+	return []entity.Consumer{
+		{ID: ActiveConsumerID, Fullname: "John Doe", Username: "johndoe", Email: "john.doe@example.com", Phone: "+6281234567890",
+			Address: "Jl. Merdeka No. 123, Jakarta", BirthDate: birthDate(1990, time.May, 10), Status: entity.ConsumerStatusActive},
+		{ID: InactiveConsumerID, Fullname: "Jane Smith", Username: "janesmith", Email: "jane.smith@example.com", Phone: "+6289876543210",
+			Address: "Jl. Sudirman No. 45, Bandung", BirthDate: birthDate(1988, time.November, 23), Status: entity.ConsumerStatusInactive},
+		{ID: SuspendedConsumerID, Fullname: "Maria Clara", Username: "mariaclara", Email: "maria.clara@example.com", Phone: "+6289988776655",
+			Address: "Jl. Gajah Mada No. 10, Yogyakarta", BirthDate: birthDate(1995, time.July, 1), Status: entity.ConsumerStatusSuspended},
+	}
+}
+
+// Operation is a documented operation an example response is generated for.
+type Operation struct {
+	ID     string
+	Method string
+	Path   string
+}
+
+// Operations lists the operations the examples are generated for, the read operations on the consumers.
+var Operations = []Operation{
+	{ID: "getAllConsumers", Method: http.MethodGet, Path: "/api/v1/consumers"},
+	{ID: "getConsumerByID", Method: http.MethodGet, Path: "/api/v1/consumers/" + SuspendedConsumerID},
+	{ID: "getActiveConsumers", Method: http.MethodGet, Path: "/api/v1/consumers/active"},
+	{ID: "getInactiveConsumers", Method: http.MethodGet, Path: "/api/v1/consumers/inactive"},
+	{ID: "getSuspendedConsumers", Method: http.MethodGet, Path: "/api/v1/consumers/suspended"},
+}
+
+// Fixture is the response of an operation to a caller with a role.
+type Fixture struct {
+	OperationID string          `json:"operationId"`
+	Role        string          `json:"role"`
+	Method      string          `json:"method"`
+	Path        string          `json:"path"`
+	Status      int             `json:"status"`
+	Body        json.RawMessage `json:"body"`
+}
+
+// Generate returns the fixtures of every operation for every role, in the order of Operations and Roles.
+// It returns an error if a response does not match the given OpenAPI document.
+// The example consumers are served by the in-memory database driver, so it must not run in a process using PostgreSQL.
+func Generate(document []byte, listing policyconfig.ConsumerListingPolicy, paging pagination.Config) ([]Fixture, error) {
+	if !database.InitMemory() {
+		return nil, fmt.Errorf("failed to initialize the memory database driver")
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the OpenAPI document: %w", err)
+	}
+	specRouter, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to route the OpenAPI document: %w", err)
+	}
+
+	repo := repository.NewMemoryConsumerRepository(repository.NewMemoryStore())
+	for _, c := range Consumers() {
+		c.CreatedAt = Timestamp
+		if _, err := repo.CreateConsumer(context.Background(), nil, c); err != nil {
+			return nil, fmt.Errorf("failed to create the example consumer %s: %w", c.Username, err)
+		}
+	}
+	router := newRouter(handler.NewConsumerHandler(service.NewConsumerService(database.DefaultStore(), repo, listing), paging))
+
+	var fixtures []Fixture
+	for _, op := range Operations {
+		for _, role := range Roles {
+			req := httptest.NewRequest(op.Method, op.Path, nil)
+			req.Header.Set(roleHeader, role)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if err := validate(specRouter, req, w); err != nil {
+				return nil, fmt.Errorf("the response of %s to %s does not match the OpenAPI document: %w", op.ID, role, err)
+			}
+
+			body, err := normalize(w.Body.Bytes())
+			if err != nil {
+				return nil, fmt.Errorf("invalid response of %s to %s: %w", op.ID, role, err)
+			}
+
+			fixtures = append(fixtures, Fixture{OperationID: op.ID, Role: role, Method: op.Method, Path: op.Path, Status: w.Code, Body: body})
+		}
+	}
+
+	return fixtures, nil
+}
+
+// Write writes each fixture to <dir>/<role>/<operationId>.json, overwriting the fixture generated before.
+func Write(dir string, fixtures []Fixture) error {
+	for _, f := range fixtures {
+		data, err := json.MarshalIndent(f, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, f.Role, f.OperationID+".json")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// roleHeader carries the role of the caller to the router of the generator, in place of an access token.
+const roleHeader = "X-Fixture-Role"
+
+// newRouter mirrors the consumer read routes of routes.SetupRouter, the caller having the role of roleHeader.
+func newRouter(h *handler.ConsumerHandler) *gin.Engine {
+	r := gin.New()
+
+	consumers := r.Group("/api/v1/consumers", func(c *gin.Context) {
+		meta := metacontext.UserInformationMeta{UserID: 1, Username: "example", Roles: []string{c.GetHeader(roleHeader)}}
+		c.Request = c.Request.WithContext(metacontext.InjectUserInformationMeta(c.Request.Context(), meta))
+		c.Next()
+	}, authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"))
+	{
+		consumers.GET("", h.GetAllConsumers)
+		consumers.GET("/:id", h.GetConsumerByID)
+		consumers.GET("/active", h.GetActiveConsumers)
+		consumers.GET("/inactive", h.GetInactiveConsumers)
+		consumers.GET("/suspended", h.GetSuspendedConsumers)
+	}
+
+	return r
+}
+
+// validate validates the response against the documented operation of the request.
+func validate(specRouter routers.Router, req *http.Request, w *httptest.ResponseRecorder) error {
+	route, pathParams, err := specRouter.FindRoute(req)
+	if err != nil {
+		return err
+	}
+
+	return openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+		},
+		Status:  w.Code,
+		Header:  w.Header(),
+		Body:    io.NopCloser(bytes.NewReader(w.Body.Bytes())),
+		Options: &openapi3filter.Options{IncludeResponseStatus: true, MultiError: true},
+	})
+}
+
+// normalize replaces the times of the response by Timestamp.
+func normalize(body []byte) (json.RawMessage, error) {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, err
+	}
+
+	var replace func(v any)
+	replace = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, field := range v {
+				if _, ok := field.(string); ok && volatileKeys[key] {
+					v[key] = Timestamp.Format(time.RFC3339)
+					continue
+				}
+				replace(field)
+			}
+		case []any:
+			for _, item := range v {
+				replace(item)
+			}
+		}
+	}
+	replace(value)
+
+	return json.Marshal(value)
+}
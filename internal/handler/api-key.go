@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// This struct defines the ApiKeyHandler which handles HTTP requests related to the API keys of the service accounts.
+// It contains a service field of type ApiKeyService which is used to manage the API keys.
+type ApiKeyHandler struct {
+	Service service.ApiKeyService
+}
+
+// NewApiKeyHandler creates a new instance of ApiKeyHandler.
+// It initializes the ApiKeyHandler struct with the provided ApiKeyService.
+func NewApiKeyHandler(apiKeyService service.ApiKeyService) *ApiKeyHandler {
+	return &ApiKeyHandler{Service: apiKeyService}
+}
+
+// GetApiKeys retrieves the API keys of a user.
+// @Summary      Get the API keys of a user
+// @Description  Get the API keys of a service account, the revoked and expired ones included. The keys themselves are never returned
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  model.HttpResponse for successful retrieval
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for user not found
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/users/{id}/api-keys [get]
+func (h *ApiKeyHandler) GetApiKeys(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		httputil.BadRequest(c, "Invalid ID", "ID must be a positive integer")
+		return
+	}
+
+	keys, err := h.Service.GetApiKeys(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "User not found", "No user found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to retrieve API keys", err)
+		return
+	}
+
+	if keys == nil {
+		keys = []entity.ApiKey{}
+	}
+	httputil.Success(c, "API keys retrieved successfully", keys)
+}
+
+// CreateApiKey creates a new API key for a service account.
+// @Summary      Create an API key
+// @Description  Create a new API key for a service account. The key is returned once, it cannot be retrieved afterward
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                true  "User ID"
+// @Param        request  body      entity.ApiKeyRequest  true  "API key request"
+// @Success      201  {object}  model.HttpResponse for successful creation
+// @Failure      400  {object}  model.HttpResponse for bad request or user which is not a service account
+// @Failure      404  {object}  model.HttpResponse for user not found
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/users/{id}/api-keys [post]
+func (h *ApiKeyHandler) CreateApiKey(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		httputil.BadRequest(c, "Invalid ID", "ID must be a positive integer")
+		return
+	}
+
+	var req entity.ApiKeyRequest
+	if !bindJSONStrict(c, &req, "Invalid request body") {
+		return
+	}
+
+	issued, err := h.Service.CreateApiKey(c.Request.Context(), id, req, meta.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "User not found", "No user found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to create API key", err)
+		return
+	}
+
+	httputil.Created(c, "API key created successfully", issued)
+}
+
+// RotateApiKey replaces an API key with a new one, and revokes it.
+// @Summary      Rotate an API key
+// @Description  Replace an API key with a new one, with the same name and lifetime. The previous key is revoked at once
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "API key ID"
+// @Success      200  {object}  model.HttpResponse for successful rotation
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for API key not found
+// @Failure      409  {object}  model.HttpResponse for API key already revoked
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/api-keys/{id}/rotate [post]
+func (h *ApiKeyHandler) RotateApiKey(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		httputil.BadRequest(c, "Invalid ID", "ID must be a positive integer")
+		return
+	}
+
+	issued, err := h.Service.RotateApiKey(c.Request.Context(), id, meta.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "API key not found", "No API key found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to rotate API key", err)
+		return
+	}
+
+	httputil.Success(c, "API key rotated successfully", issued)
+}
+
+// RevokeApiKey revokes an API key.
+// @Summary      Revoke an API key
+// @Description  Revoke an API key, the requests using it are rejected at once
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "API key ID"
+// @Success      200  {object}  model.HttpResponse for successful revocation
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for API key not found
+// @Failure      409  {object}  model.HttpResponse for API key already revoked
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/api-keys/{id} [delete]
+func (h *ApiKeyHandler) RevokeApiKey(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		httputil.BadRequest(c, "Invalid ID", "ID must be a positive integer")
+		return
+	}
+
+	if err := h.Service.RevokeApiKey(c.Request.Context(), id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "API key not found", "No API key found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to revoke API key", err)
+		return
+	}
+
+	httputil.Success(c, "API key revoked successfully", nil)
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+)
+
+// auditedKey is the key of the gin context marking a request as audited, so that a request
+// audited by the middleware of its route is not audited again by the middleware of its group.
+const auditedKey = "audited"
+
+// This struct defines the AuditHandler which handles HTTP requests related to the audit log.
+// It contains a service field of type AuditService which is used to search the audit log,
+// a recorder field of type AuditRecorder which is used to record the audited requests, and the pagination settings capping the pages.
+type AuditHandler struct {
+	Service  service.AuditService
+	Recorder service.AuditRecorder
+	Paging   pagination.Config
+}
+
+// NewAuditHandler creates a new instance of AuditHandler.
+// It initializes the AuditHandler struct with the provided AuditService, AuditRecorder, and pagination settings.
+func NewAuditHandler(auditService service.AuditService, recorder service.AuditRecorder, paging pagination.Config) *AuditHandler {
+	return &AuditHandler{Service: auditService, Recorder: recorder, Paging: paging}
+}
+
+// GetAuditEvents retrieves a page of the audit events meeting the criteria of the query and returns them as JSON.
+// @Summary      Get audit events
+// @Description  Search the audit log by action, actor, target, and time, the most recent events first
+// @Tags         admin
+// @Produce      json
+// @Param        action      query     string  false "login, logout, token_refresh, role_change, consumer_status_change, consumer_update, consumer_delete, consumer_restore, or admin_action"
+// @Param        actorId     query     string  false "ID of the user who performed the action"
+// @Param        targetType  query     string  false "user, role, consumer, or route"
+// @Param        targetId    query     string  false "ID of the target"
+// @Param        from        query     string  false "Earliest time of the events, RFC 3339 (inclusive)"
+// @Param        to          query     string  false "Latest time of the events, RFC 3339 (exclusive)"
+// @Param        page        query     string  false "Page number (default is 1)"
+// @Param        limit       query     string  false "Number of events per page (default is 10, at most PAGINATION_MAX_LIMIT)"
+// @Success      200  {array}   model.HttpResponse for successful retrieval
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /audit-events [get]
+func (h *AuditHandler) GetAuditEvents(c *gin.Context) {
+	params, perr := pagination.Parse(c, h.Paging)
+	if perr != nil {
+		httputil.BadRequest(c, perr.Message, perr.Detail)
+		return
+	}
+
+	filter, err := parseAuditEventFilter(c)
+	if err != nil {
+		httputil.BadRequest(c, "Invalid filter", err.Error())
+		return
+	}
+
+	events, err := h.Service.GetAuditEvents(c.Request.Context(), filter, params.Page, params.Limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAuditAction) {
+			httputil.BadRequest(c, "Invalid filter", err.Error())
+			return
+		}
+
+		respondError(c, "Failed to retrieve audit events", err)
+		return
+	}
+
+	httputil.Success(c, "Audit events retrieved successfully", events)
+}
+
+// Audit returns a middleware recording the requests of the routes changing the state of the service in the audit log,
+// with the given action and target type. The target is the id parameter of the route, or the route itself for the
+// route target type. Only the successful requests are recorded, and the reads (GET, HEAD, OPTIONS) are not.
+// The middleware must run after the authentication, the actor of the event is the authenticated user.
+func (h *AuditHandler) Audit(action string, targetType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		if c.Writer.Status() >= http.StatusBadRequest || c.GetBool(auditedKey) {
+			return
+		}
+		c.Set(auditedKey, true)
+
+		targetID := c.Param("id")
+		if targetType == entity.AuditTargetRoute {
+			targetID = fmt.Sprintf("%s %s", c.Request.Method, c.FullPath())
+		}
+
+		h.Recorder.Record(c.Request.Context(), entity.AuditEvent{
+			Action:     action,
+			TargetType: targetType,
+			TargetID:   targetID,
+			Details:    fmt.Sprintf("%s %s", c.Request.Method, c.Request.URL.Path),
+			IPAddress:  c.ClientIP(),
+		})
+	}
+}
+
+// parseAuditEventFilter parses the criteria of a search of the audit log from the query of the request.
+func parseAuditEventFilter(c *gin.Context) (entity.AuditEventFilter, error) {
+	filter := entity.AuditEventFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("targetType"),
+		TargetID:   c.Query("targetId"),
+	}
+
+	if v := c.Query("actorId"); v != "" {
+		actorID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || actorID <= 0 {
+			return entity.AuditEventFilter{}, fmt.Errorf("actorId must be a positive integer")
+		}
+		filter.ActorID = &actorID
+	}
+
+	for _, bound := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		v := c.Query(bound.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return entity.AuditEventFilter{}, fmt.Errorf("%s must be an RFC 3339 time, e.g. 2025-01-15T10:00:00Z", bound.name)
+		}
+		*bound.dst = &t
+	}
+
+	return filter, nil
+}
@@ -2,27 +2,62 @@ package handler
 
 import (
 	"errors"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"gopkg.in/go-playground/validator.v9"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
 	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/anomaly"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/captcha"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/geoip"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
 	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
-	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+const (
+	// ClientIDHeader is the request header identifying the client application, counted by the token usage analytics.
+	ClientIDHeader = "X-Client-ID"
+
+	// DeviceIDHeader is the request header identifying the device logging in, a new login from a device replaces its previous session.
+	DeviceIDHeader = "X-Device-ID"
+
+	// SessionLimitReachedCode is the error code returned to the clients when a login exceeds the session limit.
+	SessionLimitReachedCode = "SESSION_LIMIT_REACHED"
+
+	// CaptchaTokenHeader is the request header carrying the CAPTCHA token solved by a client challenged by the anomaly detection.
+	CaptchaTokenHeader = "X-Captcha-Token"
+
+	// CaptchaRequiredCode is the error code returned to the clients whose login requires a solved CAPTCHA.
+	CaptchaRequiredCode = "CAPTCHA_REQUIRED"
+
+	// CaptchaInvalidCode is the error code returned to the clients whose CAPTCHA token is rejected by the provider.
+	CaptchaInvalidCode = "CAPTCHA_INVALID"
+
+	// StepUpRequiredCode is the error code returned to the users without two-factor authentication whose valid login
+	// requires a step-up authentication, completed by sending the login again with a solved CAPTCHA.
+	StepUpRequiredCode = "STEP_UP_REQUIRED"
 )
 
 // This struct defines the AuthHandler which handles HTTP requests related to authentication.
-// It contains a service field of type AuthService which is used to interact with the authentication data layer.
+// It contains a service field of type AuthService which is used to interact with the authentication data layer,
+// a locator used to look up the location of the clients, the anomaly detector of the logins,
+// and the verifier of the CAPTCHA tokens of the challenged clients.
 type AuthHandler struct {
-	Service service.AuthService
+	Service  service.AuthService
+	Locator  geoip.Locator
+	Detector *anomaly.Detector
+	Captcha  captcha.Verifier
 }
 
 // NewAuthHandler creates a new instance of AuthHandler.
-// It initializes the AuthHandler struct with the provided AuthService.
+// It initializes the AuthHandler struct with the provided AuthService, and the GeoIP locator,
+// the anomaly detector, and the CAPTCHA verifier of the process.
 func NewAuthHandler(authService service.AuthService) *AuthHandler {
-	return &AuthHandler{Service: authService}
+	return &AuthHandler{Service: authService, Locator: geoip.GetLocator(), Detector: anomaly.GetDetector(), Captcha: captcha.GetVerifier()}
 }
 
 // Login handles user login requests.
@@ -35,34 +70,248 @@ func NewAuthHandler(authService service.AuthService) *AuthHandler {
 // @Param        request  body      Auth  true  "Login request"
 // @Success      200  {object}  model.HttpResponse for successful login
 // @Failure      400  {object}  model.HttpResponse for bad request
-// @Failure      401  {object}  model.HttpResponse for unauthorized
+// @Failure      401  {object}  model.HttpResponse for unauthorized, or a CAPTCHA required from a suspicious source or for a step-up authentication
+// @Failure      403  {object}  model.HttpResponse for admin login from a blocked country, login rejected by a login hook, or a locked or unverified account
+// @Failure      409  {object}  model.HttpResponse for session limit reached
+// @Failure      429  {object}  model.HttpResponse for too many requests
+// @Failure      503  {object}  model.HttpResponse for a CAPTCHA token that cannot be verified
 // @Router       /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	// Bind the request body to the LoginRequest struct
 	// This struct contains the username and password fields
 	var loginReq entity.LoginRequest
-	if err := c.ShouldBindJSON(&loginReq); err != nil {
-		httputil.BadRequest(c, "Invalid request", err.Error())
+	if !bindJSONStrict(c, &loginReq, "Invalid request") {
 		return
 	}
+	loginReq.ClientID = c.GetHeader(ClientIDHeader)
+	loginReq.DeviceID = c.GetHeader(DeviceIDHeader)
+	loginReq.IPAddress = c.ClientIP()
+	loginReq.UserAgent = c.Request.UserAgent()
+	location := h.Locator.Lookup(loginReq.IPAddress)
+	loginReq.Country = location.Country
+	loginReq.City = location.City
+
+	// Reject the request if the source or the account is temporarily blocked
+	// or has been flagged by the anomaly detection subsystem
+	// An account requiring a step-up authentication completes the login with its two-factor authentication,
+	// or with a solved CAPTCHA if it has none
+	detector := h.Detector
+	source := anomaly.Source{IP: c.ClientIP(), Account: loginReq.Username, ASN: location.ASN}
+	stepUp := detector.RequiresStepUp(loginReq.Username)
+	captchaPassed, ok := h.screenLogin(c, source, location, stepUp)
+	if !ok {
+		return
+	}
+	loginReq.StepUp = stepUp && !captchaPassed
 
 	// Call the service to authenticate the user and get the token
-	loginResp, err := h.Service.Login(loginReq)
+	loginResp, err := h.Service.Login(c.Request.Context(), loginReq)
+
+	// The credentials are valid but the user has too many active sessions,
+	// this is not a failed login attempt for the anomaly detection
+	if errors.Is(err, service.ErrSessionLimitReached) {
+		httputil.ConflictMap(c, "Session limit reached", []map[string]string{{
+			"code":    SessionLimitReachedCode,
+			"message": "The maximum number of active sessions is reached, log out of another session and try again",
+		}})
+		return
+	}
+
+	// The credentials are valid but the administrators cannot log in from the location of the client
+	if errors.Is(err, service.ErrAdminLoginBlocked) {
+		logger.WarnContext(c.Request.Context(), "Admin login blocked from the location of the client", logrus.Fields{
+			"username": loginReq.Username,
+			"ip":       loginReq.IPAddress,
+			"country":  location.Country,
+			"city":     location.City,
+		})
+		httputil.Forbidden(c, "Login blocked", "Admin login is not allowed from your location")
+		return
+	}
+
+	// A login hook of the deployment rejected the login
+	if errors.Is(err, service.ErrLoginRejected) {
+		httputil.Forbidden(c, "Login rejected", err.Error())
+		return
+	}
+
+	// The password is valid, but the user has no two-factor authentication to complete the step-up authentication with
+	if errors.Is(err, service.ErrStepUpRequired) {
+		httputil.UnauthorizedMap(c, "Step-up authentication required", []map[string]string{{
+			"code":    StepUpRequiredCode,
+			"message": "Additional verification is required for this account, solve the CAPTCHA and send its token in the " + CaptchaTokenHeader + " header",
+		}})
+		return
+	}
 
 	if err != nil {
-		// Check if the error is a validation error
-		var ve validator.ValidationErrors
-		if errors.As(err, &ve) {
-			httputil.BadRequestMap(c, "Failed to login", validation.FormatValidationErrors(err))
-			return
-		}
+		// Record the failed login attempt for anomaly detection
+		detector.Record(anomaly.Event{
+			Type:    anomaly.EventFailedLogin,
+			IP:      c.ClientIP(),
+			Account: loginReq.Username,
+			Country: location.Country,
+			ASN:     location.ASN,
+		})
 
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		// The unknown usernames and the wrong passwords are not told apart
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, service.ErrInvalidCredentials) {
 			httputil.Unauthorized(c, "Invalid credentials", "Username or password is incorrect")
 			return
 		}
 
-		httputil.Unauthorized(c, "Failed to login", err.Error())
+		respondError(c, "Failed to login", err)
+		return
+	}
+
+	// Record the successful login and reset the failure counters of the account
+	detector.Record(anomaly.Event{
+		Type:    anomaly.EventLoginSuccess,
+		IP:      c.ClientIP(),
+		Account: loginReq.Username,
+		Country: location.Country,
+		ASN:     location.ASN,
+	})
+	detector.Reset(loginReq.Username)
+
+	// The password is valid, the user completes the login with a code of their authenticator app
+	if loginResp.MFARequired {
+		httputil.Success(c, "Two-factor authentication required", loginResp)
+		return
+	}
+
+	httputil.Success(c, "Login successful", loginResp)
+}
+
+// screenLogin applies the restrictions of the anomaly detection subsystem to the login of the source, emitting their security events.
+// A blocked source is rejected, a source challenged with a CAPTCHA must send a token solved for it in the X-Captcha-Token header,
+// and the login of a tarpitted source is delayed, so that the attacks slow down without locking out the legitimate users.
+// The token sent for the step-up authentication of an account, if stepUp is set, is verified as well.
+// It reports whether a CAPTCHA was solved, and whether the login goes on, the response is written otherwise.
+func (h *AuthHandler) screenLogin(c *gin.Context, source anomaly.Source, location geoip.Location, stepUp bool) (captchaPassed bool, ok bool) {
+	ctx := c.Request.Context()
+	verdict := h.Detector.Check(source)
+	event := anomaly.SecurityEvent{IP: source.IP, Account: source.Account, Country: location.Country, ASN: location.ASN}
+
+	if verdict.Blocked {
+		event.Type = anomaly.SecurityEventLoginBlocked
+		h.Detector.Emit(ctx, event)
+		httputil.TooManyRequests(c, "Login blocked", "Too many suspicious login attempts, please try again later")
+		return false, false
+	}
+
+	token := c.GetHeader(CaptchaTokenHeader)
+	if verdict.Captcha || (stepUp && token != "") {
+		if token == "" {
+			event.Type = anomaly.SecurityEventCaptchaMissing
+			h.Detector.Emit(ctx, event)
+			captchaError(c, CaptchaRequiredCode, "Too many suspicious login attempts, solve the CAPTCHA and send its token in the "+CaptchaTokenHeader+" header")
+			return false, false
+		}
+
+		solved, err := h.Captcha.Verify(ctx, token, source.IP)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to verify the CAPTCHA token of a login: "+err.Error(), nil)
+			httputil.ServiceUnavailable(c, "Failed to verify CAPTCHA", "The CAPTCHA token cannot be verified, please try again later")
+			return false, false
+		}
+		if !solved {
+			event.Type = anomaly.SecurityEventCaptchaFailed
+			h.Detector.Emit(ctx, event)
+			captchaError(c, CaptchaInvalidCode, "The CAPTCHA token is invalid or expired, solve the CAPTCHA again")
+			return false, false
+		}
+
+		event.Type = anomaly.SecurityEventCaptchaPassed
+		h.Detector.Emit(ctx, event)
+		captchaPassed = true
+	}
+
+	if verdict.Tarpit > 0 {
+		event.Type = anomaly.SecurityEventLoginTarpitted
+		h.Detector.Emit(ctx, event)
+
+		timer := time.NewTimer(verdict.Tarpit)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			c.Abort()
+			return false, false
+		}
+	}
+
+	return captchaPassed, true
+}
+
+// captchaError writes a 401 Unauthorized response with the error code of the CAPTCHA challenge.
+func captchaError(c *gin.Context, code string, message string) {
+	httputil.UnauthorizedMap(c, "CAPTCHA required", []map[string]string{{
+		"code":    code,
+		"message": message,
+	}})
+}
+
+// VerifyMFA handles the second step of the logins of the users with two-factor authentication.
+// It checks the code of the authenticator app for the MFA token returned by the login, and returns a JWT token if successful.
+// @Summary      Verify two-factor authentication code
+// @Description  Complete the login of a user with two-factor authentication with the MFA token and a code of the authenticator app
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.VerifyMFARequest  true  "MFA verification request"
+// @Success      200  {object}  model.HttpResponse for successful login
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      401  {object}  model.HttpResponse for invalid code or MFA token
+// @Failure      403  {object}  model.HttpResponse for admin login from a blocked country, or login rejected by a login hook
+// @Failure      409  {object}  model.HttpResponse for session limit reached
+// @Failure      429  {object}  model.HttpResponse for too many requests
+// @Router       /auth/mfa/verify [post]
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var verifyReq entity.VerifyMFARequest
+	if err := c.ShouldBindJSON(&verifyReq); err != nil {
+		httputil.BadRequest(c, "Invalid request", err.Error())
+		return
+	}
+	verifyReq.ClientID = c.GetHeader(ClientIDHeader)
+	verifyReq.DeviceID = c.GetHeader(DeviceIDHeader)
+	verifyReq.IPAddress = c.ClientIP()
+	verifyReq.UserAgent = c.Request.UserAgent()
+	location := h.Locator.Lookup(verifyReq.IPAddress)
+	verifyReq.Country = location.Country
+	verifyReq.City = location.City
+
+	// Reject the request if the source is temporarily blocked by the anomaly detection subsystem
+	detector := h.Detector
+	if detector.IsBlocked(c.ClientIP(), "") {
+		httputil.TooManyRequests(c, "Login blocked", "Too many suspicious login attempts, please try again later")
+		return
+	}
+
+	loginResp, err := h.Service.VerifyMFA(c.Request.Context(), verifyReq)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidMFACode), errors.Is(err, service.ErrInvalidMFAToken):
+			// Record the failed attempt for anomaly detection, the codes are guessed like passwords
+			detector.Record(anomaly.Event{
+				Type:    anomaly.EventFailedLogin,
+				IP:      c.ClientIP(),
+				Country: location.Country,
+				ASN:     location.ASN,
+			})
+			httputil.Unauthorized(c, "Failed to verify code", err.Error())
+		case errors.Is(err, service.ErrSessionLimitReached):
+			httputil.ConflictMap(c, "Session limit reached", []map[string]string{{
+				"code":    SessionLimitReachedCode,
+				"message": "The maximum number of active sessions is reached, log out of another session and try again",
+			}})
+		case errors.Is(err, service.ErrAdminLoginBlocked):
+			httputil.Forbidden(c, "Login blocked", "Admin login is not allowed from your location")
+		case errors.Is(err, service.ErrLoginRejected):
+			httputil.Forbidden(c, "Login rejected", err.Error())
+		default:
+			respondError(c, "Failed to verify code", err)
+		}
 		return
 	}
 
@@ -71,6 +320,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 // RefreshToken handles token refresh requests.
 // It validates the request, checks the refresh token, and returns a new JWT token if successful.
+// If the previous access token is given, it must belong to the same user as the refresh token.
 // @Summary      Refresh token
 // @Description  Refresh token
 // @Tags         auth
@@ -79,38 +329,128 @@ func (h *AuthHandler) Login(c *gin.Context) {
 // @Param        request  body      entity.RefreshTokenRequest  true  "Refresh token request"
 // @Success      200  {object}  model.HttpResponse for successful token refresh
 // @Failure      400  {object}  model.HttpResponse for bad request
-// @Failure      401  {object}  model.HttpResponse for unauthorized
+// @Failure      401  {object}  model.HttpResponse for an invalid or expired refresh token
+// @Failure      403  {object}  model.HttpResponse for a locked or unverified account
 // @Router       /auth/refresh-token [post]
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	// Bind the request body to the RefreshTokenRequest struct
 	// This struct contains the refresh token field
 	var refreshTokenReq entity.RefreshTokenRequest
-	if err := c.ShouldBindJSON(&refreshTokenReq); err != nil {
-		httputil.BadRequest(c, "Invalid request", err.Error())
+	if !bindJSONStrict(c, &refreshTokenReq, "Invalid request") {
 		return
 	}
+	refreshTokenReq.ClientID = c.GetHeader(ClientIDHeader)
+	refreshTokenReq.IPAddress = c.ClientIP()
 
 	// Call the service to refresh the token
-	refreshTokenResp, err := h.Service.RefreshToken(refreshTokenReq)
+	refreshTokenResp, err := h.Service.RefreshToken(c.Request.Context(), refreshTokenReq)
 
-	if err != nil {
-		// Check if the error is a validation error
-		var ve validator.ValidationErrors
-		if errors.As(err, &ve) {
-			httputil.BadRequestMap(c, "Failed to refresh token", validation.FormatValidationErrors(err))
-			return
-		}
+	// The refresh token was presented with the access token of another user, it may have been stolen
+	if errors.Is(err, service.ErrTokenSubjectMismatch) {
+		h.Detector.Record(anomaly.Event{
+			Type: anomaly.EventTokenError,
+			IP:   c.ClientIP(),
+		})
+		httputil.Unauthorized(c, "Invalid refresh token", "The access token and the refresh token do not belong to the same user")
+		return
+	}
 
+	if err != nil {
+		// The unknown, used, or revoked refresh tokens and the deleted users are not told apart
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			httputil.Unauthorized(c, "Invalid refresh token", "Refresh token is invalid")
 			return
 		}
 
-		// Handle other errors, such as database connection issues
-		// or query execution errors
-		httputil.Unauthorized(c, "Failed to refresh token", err.Error())
+		// The expired refresh tokens and the locked accounts are reported, the other errors are not
+		respondError(c, "Failed to refresh token", err)
 		return
 	}
 
 	httputil.Success(c, "Token refreshed successfully", refreshTokenResp)
 }
+
+// Logout handles logout requests.
+// It deletes the refresh token of the session and revokes the access token of the request until it expires.
+// The refresh token must belong to the user of the access token.
+// @Summary      Logout
+// @Description  Logout, revoking the refresh token and the access token of the session
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.LogoutRequest  true  "Logout request"
+// @Success      200  {object}  model.HttpResponse for successful logout
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      401  {object}  model.HttpResponse for unauthorized
+// @Router       /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	// Extract the user information of the access token from the request context
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	// Bind the request body to the LogoutRequest struct
+	// This struct contains the refresh token field
+	var logoutReq entity.LogoutRequest
+	if err := c.ShouldBindJSON(&logoutReq); err != nil {
+		httputil.BadRequest(c, "Invalid request", err.Error())
+		return
+	}
+	logoutReq.UserID = meta.UserID
+	logoutReq.TokenID = meta.TokenID
+	logoutReq.TokenExpiresAt = meta.TokenExpiresAt
+	logoutReq.IPAddress = c.ClientIP()
+
+	// Call the service to end the session
+	err := h.Service.Logout(c.Request.Context(), logoutReq)
+
+	// The refresh token belongs to another user, it may have been stolen
+	if errors.Is(err, service.ErrTokenSubjectMismatch) {
+		h.Detector.Record(anomaly.Event{
+			Type: anomaly.EventTokenError,
+			IP:   c.ClientIP(),
+		})
+		httputil.Unauthorized(c, "Invalid refresh token", "The access token and the refresh token do not belong to the same user")
+		return
+	}
+
+	if err != nil {
+		respondError(c, "Failed to logout", err)
+		return
+	}
+
+	httputil.Success(c, "Logged out successfully", nil)
+}
+
+// Register handles self-registration requests.
+// It creates an active user account with the ROLE_USER role, the user logs in afterwards.
+// @Summary      Register
+// @Description  Create a user account with the ROLE_USER role
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.RegisterRequest  true  "Registration request"
+// @Success      201  {object}  model.HttpResponse for successful registration
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      409  {object}  model.HttpResponse for username or email already taken
+// @Failure      429  {object}  model.HttpResponse for too many requests
+// @Router       /auth/register [post]
+func (h *AuthHandler) Register(c *gin.Context) {
+	// Bind the request body to the RegisterRequest struct
+	var registerReq entity.RegisterRequest
+	if err := c.ShouldBindJSON(&registerReq); err != nil {
+		httputil.BadRequest(c, "Invalid request", err.Error())
+		return
+	}
+
+	registerResp, err := h.Service.Register(c.Request.Context(), registerReq)
+	if err != nil {
+		// The validation errors and the usernames or emails already taken are reported to the client
+		respondError(c, "Failed to register", err)
+		return
+	}
+
+	httputil.Created(c, "User registered successfully", registerResp)
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	bindingutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/binding-util"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// bindJSONStrict binds the JSON body of the request into obj, rejecting the unknown fields.
+// It responds with 400 and returns false if the body cannot be bound, reporting the offending field when there is one.
+func bindJSONStrict(c *gin.Context, obj interface{}, message string) bool {
+	err := bindingutil.BindJSONStrict(c, obj)
+	if err == nil {
+		return true
+	}
+
+	if fields := bindingutil.FormatBindingErrors(err); fields != nil {
+		httputil.BadRequestMap(c, message, fields)
+		return false
+	}
+
+	httputil.BadRequest(c, message, err.Error())
+	return false
+}
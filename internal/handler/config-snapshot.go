@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// This struct defines the ConfigSnapshotHandler which handles HTTP requests related to the runtime configuration.
+// It contains a service field of type ConfigSnapshotService which is used to take the snapshots.
+type ConfigSnapshotHandler struct {
+	Service service.ConfigSnapshotService
+}
+
+// NewConfigSnapshotHandler creates a new instance of ConfigSnapshotHandler.
+// It initializes the ConfigSnapshotHandler struct with the provided ConfigSnapshotService.
+func NewConfigSnapshotHandler(configSnapshotService service.ConfigSnapshotService) *ConfigSnapshotHandler {
+	return &ConfigSnapshotHandler{Service: configSnapshotService}
+}
+
+// GetConfigSnapshot retrieves the effective configuration of the instance serving the request.
+// @Summary      Get config snapshot
+// @Description  Get the sanitized runtime configuration, the enabled features, the rate limits and the quotas, and the signing keys in use
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  model.HttpResponse for successful retrieval
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/config-snapshot [get]
+func (h *ConfigSnapshotHandler) GetConfigSnapshot(c *gin.Context) {
+	snapshot, err := h.Service.GetConfigSnapshot()
+	if err != nil {
+		respondError(c, "Failed to retrieve config snapshot", err)
+		return
+	}
+
+	httputil.Success(c, "Config snapshot retrieved successfully", snapshot)
+}
@@ -1,62 +1,90 @@
 package handler
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"gopkg.in/go-playground/validator.v9"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
 	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/localeformat"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
 	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
-	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
 )
 
+const (
+	// NDJSONContentType is the content type of the newline-delimited JSON streams.
+	NDJSONContentType = "application/x-ndjson"
+
+	// ExportLimitHeader is the header returning the maximum number of consumers of an extraction, if capped.
+	ExportLimitHeader = "X-Export-Limit"
+
+	// streamFlushInterval is the number of lines written between two flushes of a stream.
+	streamFlushInterval = 100
+)
+
+// errExportLimitReached stops a stream once the extraction reached its cap.
+var errExportLimitReached = errors.New("export limit reached")
+
 // This struct defines the ConsumerHandler which handles HTTP requests related to consumers.
-// It contains a service field of type ConsumerService which is used to interact with the consumer data layer.
+// It contains a service field of type ConsumerService which is used to interact with the consumer data layer,
+// and the pagination settings capping the pages and the exports.
 type ConsumerHandler struct {
 	Service service.ConsumerService
+	Paging  pagination.Config
 }
 
 // NewConsumerHandler creates a new instance of ConsumerHandler.
-// It initializes the ConsumerHandler struct with the provided ConsumerService.
-func NewConsumerHandler(consumerService service.ConsumerService) *ConsumerHandler {
-	return &ConsumerHandler{Service: consumerService}
+// It initializes the ConsumerHandler struct with the provided ConsumerService and pagination settings.
+func NewConsumerHandler(consumerService service.ConsumerService, paging pagination.Config) *ConsumerHandler {
+	return &ConsumerHandler{Service: consumerService, Paging: paging}
 }
 
 // GetAllConsumers retrieves all consumers from the database and returns them as JSON.
 // @Summary      Get all consumers
-// @Description  Get all consumers from the database
+// @Description  Get the consumers visible to the roles of the caller, by default the users do not see the suspended consumers.
+// @Description  The deleted consumers are left out, unless an administrator sets includeDeleted
 // @Tags         consumers
 // @Accept       json
 // @Produce      json
-// @Param        page   query     string  false "Page number (default is 1)"
-// @Param        limit  query     string  false "Number of transactions per page (default is 10)"
+// @Param        page            query     string  false "Page number (default is 1)"
+// @Param        limit           query     string  false "Number of consumers per page (default is 10, at most PAGINATION_MAX_LIMIT)"
+// @Param        includeDeleted  query     bool    false "Include the deleted consumers, for the administrators only (default is false)"
 // @Success      200  {array}   model.HttpResponse for successful retrieval
 // @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      403  {object}  model.HttpResponse for includeDeleted set by a caller who is not an administrator
 // @Failure      404  {object}  model.HttpResponse for not found
 // @Failure      500  {object}  model.HttpResponse for internal server error
 // @Router       /consumers [get]
 func (h *ConsumerHandler) GetAllConsumers(c *gin.Context) {
-	pageStr := c.DefaultQuery("page", "1")
-	limitStr := c.DefaultQuery("limit", "10")
+	params, perr := pagination.Parse(c, h.Paging)
+	if perr != nil {
+		httputil.BadRequest(c, perr.Message, perr.Detail)
+		return
+	}
 
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		httputil.BadRequest(c, "Invalid page number", "Page must be a positive integer")
+	includeDeleted, ok := parseIncludeDeleted(c)
+	if !ok {
 		return
 	}
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 1 {
-		httputil.BadRequest(c, "Invalid limit", "Limit must be a positive integer")
+
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
 		return
 	}
 
-	consumers, err := h.Service.GetAllConsumers(page, limit)
+	consumers, err := h.Service.GetAllConsumers(c.Request.Context(), meta.Roles, params.Page, params.Limit, includeDeleted)
 	if err != nil {
-		httputil.InternalServerError(c, "Failed to retrieve consumers", err.Error())
+		respondError(c, "Failed to retrieve consumers", err)
 		return
 	}
 
@@ -65,7 +93,102 @@ func (h *ConsumerHandler) GetAllConsumers(c *gin.Context) {
 		return
 	}
 
-	httputil.Success(c, "All consumers retrieved successfully", consumers)
+	httputil.Success(c, "All consumers retrieved successfully", newConsumerResponses(c, consumers))
+}
+
+// parseIncludeDeleted parses the includeDeleted query parameter of the listings, false if it is not set.
+// It responds with 400 and returns false if the parameter is not a boolean.
+func parseIncludeDeleted(c *gin.Context) (bool, bool) {
+	v := c.Query("includeDeleted")
+	if v == "" {
+		return false, true
+	}
+
+	includeDeleted, err := strconv.ParseBool(v)
+	if err != nil {
+		httputil.BadRequest(c, "Invalid includeDeleted", "includeDeleted must be true or false")
+		return false, false
+	}
+	return includeDeleted, true
+}
+
+// StreamConsumers streams the consumers visible to the roles of the caller as newline-delimited JSON, one consumer per line.
+// The consumers are written as they are read from the database, so the full extractions neither load the whole table
+// in memory nor wait for the last consumer before sending the first one.
+// An error occurring once the stream started cannot change the status code anymore: it is written as a last line
+// holding an "error" field, so the clients can tell a truncated extraction from a complete one.
+// The extractions are capped by EXPORT_MAX_ROWS or the cap of the roles of the caller, if any: the cap is returned
+// in the X-Export-Limit header, and a capped extraction ends with an "error" line holding the cap as well.
+// @Summary      Stream consumers
+// @Description  Stream the consumers visible to the roles of the caller as newline-delimited JSON, for full extractions
+// @Tags         consumers
+// @Produce      application/x-ndjson
+// @Param        includeDeleted  query     bool    false "Include the deleted consumers, for the administrators only (default is false)"
+// @Success      200  {object}  entity.ConsumerResponse for each line of the stream
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      403  {object}  model.HttpResponse for includeDeleted set by a caller who is not an administrator
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /consumers/stream [get]
+func (h *ConsumerHandler) StreamConsumers(c *gin.Context) {
+	includeDeleted, ok := parseIncludeDeleted(c)
+	if !ok {
+		return
+	}
+
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	limit := pagination.ExportLimit(c, h.Paging)
+	if limit > 0 {
+		c.Header(ExportLimitHeader, strconv.Itoa(limit))
+	}
+
+	// The locale is negotiated once, before the first line is written
+	render := consumerRenderer(c)
+	streamed := 0
+	encoder := json.NewEncoder(c.Writer)
+	err := h.Service.StreamConsumers(c.Request.Context(), meta.Roles, includeDeleted, func(consumer entity.Consumer) error {
+		if limit > 0 && streamed >= limit {
+			return errExportLimitReached
+		}
+		if streamed == 0 {
+			c.Header("Content-Type", NDJSONContentType)
+			c.Status(http.StatusOK)
+		}
+
+		if err := encoder.Encode(render(consumer)); err != nil {
+			return err
+		}
+
+		streamed++
+		if streamed%streamFlushInterval == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+
+	if err != nil && streamed == 0 {
+		respondError(c, "Failed to stream consumers", err)
+		return
+	}
+	if errors.Is(err, errExportLimitReached) {
+		logger.InfoContext(c.Request.Context(), "Consumer stream capped", logrus.Fields{"streamed": streamed, "limit": limit})
+		_ = encoder.Encode(gin.H{"error": fmt.Sprintf("The extraction is limited to %d consumers", limit), "limit": limit})
+	} else if err != nil {
+		logger.ErrorContext(c.Request.Context(), "Consumer stream interrupted", logrus.Fields{"streamed": streamed, "error": err.Error()})
+		_ = encoder.Encode(gin.H{"error": "Failed to stream consumers"})
+	}
+
+	// An empty extraction is an empty stream
+	if streamed == 0 {
+		c.Header("Content-Type", NDJSONContentType)
+		c.Status(http.StatusOK)
+		c.Writer.WriteHeaderNow()
+	}
+	c.Writer.Flush()
 }
 
 // GetConsumerByID retrieves a consumer by its ID from the database and returns it as JSON.
@@ -89,7 +212,7 @@ func (h *ConsumerHandler) GetConsumerByID(c *gin.Context) {
 	}
 
 	// Retrieve the consumer by ID from the service
-	consumer, err := h.Service.GetConsumerByID(id)
+	consumer, err := h.Service.GetConsumerByID(c.Request.Context(), id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			httputil.NotFound(c, "Consumer not found", "No consumer found with the given ID")
@@ -98,44 +221,50 @@ func (h *ConsumerHandler) GetConsumerByID(c *gin.Context) {
 
 		// If the error is not a record not found error, return a generic internal server error
 		// This is to avoid exposing internal details of the error
-		httputil.InternalServerError(c, "Failed to retrieve consumer", err.Error())
+		respondError(c, "Failed to retrieve consumer", err)
 		return
 	}
 
-	httputil.Success(c, "Consumer retrieved successfully", consumer)
+	httputil.Success(c, "Consumer retrieved successfully", newConsumerResponse(c, consumer))
 }
 
 // GetActiveConsumers retrieves all active consumers from the database and returns them as JSON.
 // @Summary      Get active consumers
 // @Description  Get all active consumers from the database
+// @Description  The deleted consumers are left out, unless an administrator sets includeDeleted
 // @Tags         consumers
 // @Accept       json
 // @Produce      json
-// @Param        page   query     string  false "Page number (default is 1)"
-// @Param        limit  query     string  false "Number of transactions per page (default is 10)"
+// @Param        page            query     string  false "Page number (default is 1)"
+// @Param        limit           query     string  false "Number of consumers per page (default is 10, at most PAGINATION_MAX_LIMIT)"
+// @Param        includeDeleted  query     bool    false "Include the deleted consumers, for the administrators only (default is false)"
 // @Success      200  {array}   model.HttpResponse for successful retrieval
 // @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      403  {object}  model.HttpResponse for includeDeleted set by a caller who is not an administrator
 // @Failure      404  {object}  model.HttpResponse for not found
 // @Failure      500  {object}  model.HttpResponse for internal server error
 // @Router       /consumers/active [get]
 func (h *ConsumerHandler) GetActiveConsumers(c *gin.Context) {
-	pageStr := c.DefaultQuery("page", "1")
-	limitStr := c.DefaultQuery("limit", "10")
+	params, perr := pagination.Parse(c, h.Paging)
+	if perr != nil {
+		httputil.BadRequest(c, perr.Message, perr.Detail)
+		return
+	}
 
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		httputil.BadRequest(c, "Invalid page number", "Page must be a positive integer")
+	includeDeleted, ok := parseIncludeDeleted(c)
+	if !ok {
 		return
 	}
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 1 {
-		httputil.BadRequest(c, "Invalid limit", "Limit must be a positive integer")
+
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
 		return
 	}
 
-	activeConsumers, err := h.Service.GetActiveConsumers(page, limit)
+	activeConsumers, err := h.Service.GetActiveConsumers(c.Request.Context(), meta.Roles, params.Page, params.Limit, includeDeleted)
 	if err != nil {
-		httputil.InternalServerError(c, "Failed to retrieve active consumers", err.Error())
+		respondError(c, "Failed to retrieve active consumers", err)
 		return
 	}
 
@@ -144,40 +273,46 @@ func (h *ConsumerHandler) GetActiveConsumers(c *gin.Context) {
 		return
 	}
 
-	httputil.Success(c, "Active consumers retrieved successfully", activeConsumers)
+	httputil.Success(c, "Active consumers retrieved successfully", newConsumerResponses(c, activeConsumers))
 }
 
 // GetInactiveConsumers retrieves all inactive consumers from the database and returns them as JSON.
 // @Summary      Get inactive consumers
 // @Description  Get all inactive consumers from the database
+// @Description  The deleted consumers are left out, unless an administrator sets includeDeleted
 // @Tags         consumers
 // @Accept       json
 // @Produce      json
-// @Param        page   query     string  false "Page number (default is 1)"
-// @Param        limit  query     string  false "Number of transactions per page (default is 10)"
+// @Param        page            query     string  false "Page number (default is 1)"
+// @Param        limit           query     string  false "Number of consumers per page (default is 10, at most PAGINATION_MAX_LIMIT)"
+// @Param        includeDeleted  query     bool    false "Include the deleted consumers, for the administrators only (default is false)"
 // @Success      200  {array}   model.HttpResponse for successful retrieval
 // @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      403  {object}  model.HttpResponse for includeDeleted set by a caller who is not an administrator
 // @Failure      404  {object}  model.HttpResponse for not found
 // @Failure      500  {object}  model.HttpResponse for internal server error
 // @Router       /consumers/inactive [get]
 func (h *ConsumerHandler) GetInactiveConsumers(c *gin.Context) {
-	pageStr := c.DefaultQuery("page", "1")
-	limitStr := c.DefaultQuery("limit", "10")
+	params, perr := pagination.Parse(c, h.Paging)
+	if perr != nil {
+		httputil.BadRequest(c, perr.Message, perr.Detail)
+		return
+	}
 
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		httputil.BadRequest(c, "Invalid page number", "Page must be a positive integer")
+	includeDeleted, ok := parseIncludeDeleted(c)
+	if !ok {
 		return
 	}
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 1 {
-		httputil.BadRequest(c, "Invalid limit", "Limit must be a positive integer")
+
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
 		return
 	}
 
-	inactiveConsumers, err := h.Service.GetInactiveConsumers(page, limit)
+	inactiveConsumers, err := h.Service.GetInactiveConsumers(c.Request.Context(), meta.Roles, params.Page, params.Limit, includeDeleted)
 	if err != nil {
-		httputil.InternalServerError(c, "Failed to retrieve inactive consumers", err.Error())
+		respondError(c, "Failed to retrieve inactive consumers", err)
 		return
 	}
 
@@ -186,40 +321,46 @@ func (h *ConsumerHandler) GetInactiveConsumers(c *gin.Context) {
 		return
 	}
 
-	httputil.Success(c, "Inactive consumers retrieved successfully", inactiveConsumers)
+	httputil.Success(c, "Inactive consumers retrieved successfully", newConsumerResponses(c, inactiveConsumers))
 }
 
 // GetSuspendedConsumers retrieves all suspended consumers from the database and returns them as JSON.
 // @Summary      Get suspended consumers
 // @Description  Get all suspended consumers from the database
+// @Description  The deleted consumers are left out, unless an administrator sets includeDeleted
 // @Tags         consumers
 // @Accept       json
 // @Produce      json
-// @Param        page   query     string  false "Page number (default is 1)"
-// @Param        limit  query     string  false "Number of transactions per page (default is 10)"
+// @Param        page            query     string  false "Page number (default is 1)"
+// @Param        limit           query     string  false "Number of consumers per page (default is 10, at most PAGINATION_MAX_LIMIT)"
+// @Param        includeDeleted  query     bool    false "Include the deleted consumers, for the administrators only (default is false)"
 // @Success      200  {array}   model.HttpResponse for successful retrieval
 // @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      403  {object}  model.HttpResponse for includeDeleted set by a caller who is not an administrator
 // @Failure      404  {object}  model.HttpResponse for not found
 // @Failure      500  {object}  model.HttpResponse for internal server error
 // @Router       /consumers/suspended [get]
 func (h *ConsumerHandler) GetSuspendedConsumers(c *gin.Context) {
-	pageStr := c.DefaultQuery("page", "1")
-	limitStr := c.DefaultQuery("limit", "10")
+	params, perr := pagination.Parse(c, h.Paging)
+	if perr != nil {
+		httputil.BadRequest(c, perr.Message, perr.Detail)
+		return
+	}
 
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		httputil.BadRequest(c, "Invalid page number", "Page must be a positive integer")
+	includeDeleted, ok := parseIncludeDeleted(c)
+	if !ok {
 		return
 	}
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 1 {
-		httputil.BadRequest(c, "Invalid limit", "Limit must be a positive integer")
+
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
 		return
 	}
 
-	suspendedConsumers, err := h.Service.GetSuspendedConsumers(page, limit)
+	suspendedConsumers, err := h.Service.GetSuspendedConsumers(c.Request.Context(), meta.Roles, params.Page, params.Limit, includeDeleted)
 	if err != nil {
-		httputil.InternalServerError(c, "Failed to retrieve suspended consumers", err.Error())
+		respondError(c, "Failed to retrieve suspended consumers", err)
 		return
 	}
 
@@ -228,7 +369,7 @@ func (h *ConsumerHandler) GetSuspendedConsumers(c *gin.Context) {
 		return
 	}
 
-	httputil.Success(c, "Suspended consumers retrieved successfully", suspendedConsumers)
+	httputil.Success(c, "Suspended consumers retrieved successfully", newConsumerResponses(c, suspendedConsumers))
 }
 
 // CreateConsumer creates a new consumer in the database and returns it as JSON.
@@ -237,37 +378,107 @@ func (h *ConsumerHandler) GetSuspendedConsumers(c *gin.Context) {
 // @Tags         consumers
 // @Accept       json
 // @Produce      json
-// @Param        consumer  body      Consumer  true  "Consumer object"
+// @Param        consumer  body      entity.ConsumerCreateRequest  true  "Consumer to create"
 // @Success      201  {object}  model.HttpResponse for successful creation
 // @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      409  {object}  model.HttpResponse for a duplicate username, email, or phone
 // @Failure      500  {object}  model.HttpResponse for internal server error
 // @Router       /consumers [post]
 func (h *ConsumerHandler) CreateConsumer(c *gin.Context) {
-	// Bind the JSON request body to the Consumer struct
-	// This will automatically validate the request body against the struct tags
-	var consumer entity.Consumer
-	if err := c.ShouldBindJSON(&consumer); err != nil {
-		httputil.BadRequest(c, "Invalid request body", err.Error())
+	// Bind the JSON request body to the ConsumerCreateRequest struct, rejecting the fields it does not have
+	var req entity.ConsumerCreateRequest
+	if !bindJSONStrict(c, &req, "Invalid request body") {
+		return
+	}
+
+	// Create the consumer using the service, which validates it
+	createdConsumer, err := h.Service.CreateConsumer(c.Request.Context(), req.ToConsumer())
+	if err != nil {
+		// The validation errors, the broken rules, and the duplicate usernames, emails, or phones are reported to the client
+		respondError(c, "Failed to create consumer", err)
+		return
+	}
+
+	httputil.Created(c, "Consumer created successfully", newConsumerResponse(c, createdConsumer))
+}
+
+// CheckConsumerAvailability reports whether a username, an email, or a phone number is already used by a consumer,
+// so that the onboarding forms can be validated before submitting the consumer.
+// @Summary      Check consumer availability
+// @Description  Check whether a username, an email, or a phone number is still available for a new consumer
+// @Tags         consumers
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.ConsumerAvailabilityRequest  true  "Values to check"
+// @Success      200  {object}  model.HttpResponse for successful check
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      429  {object}  model.HttpResponse for too many requests
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /consumers/check-availability [post]
+func (h *ConsumerHandler) CheckConsumerAvailability(c *gin.Context) {
+	var req entity.ConsumerAvailabilityRequest
+	if !bindJSONStrict(c, &req, "Invalid request body") {
 		return
 	}
 
-	// Create the consumer using the service
-	createdConsumer, err := h.Service.CreateConsumer(consumer)
+	availability, err := h.Service.CheckConsumerAvailability(c.Request.Context(), req)
 	if err != nil {
-		// Check if the error is a validation error
-		var ve validator.ValidationErrors
-		if errors.As(err, &ve) {
-			httputil.BadRequestMap(c, "Failed to create consumer", validation.FormatValidationErrors(err))
+		respondError(c, "Failed to check consumer availability", err)
+		return
+	}
+
+	httputil.Success(c, "Consumer availability checked successfully", availability)
+}
+
+// UpdateConsumer updates the fields of a consumer given by the JSON body, e.g. its email or its address, by its ID
+// and returns the updated consumer as JSON. The requests with a status query parameter update the status of the consumer instead,
+// as UpdateConsumerStatus does, so that the clients changing the statuses keep working.
+// @Summary      Update consumer
+// @Description  Update any of the fullname, email, phone, address, or birthDate of a consumer by its ID, the fields not given are left unchanged
+// @Tags         consumers
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string                        true  "Consumer ID"
+// @Param        consumer  body      entity.ConsumerUpdateRequest  true  "Fields to update"
+// @Success      200  {object}  model.HttpResponse for successful update
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for not found
+// @Failure      409  {object}  model.HttpResponse for an email or a phone of another consumer
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /consumers/{id} [patch]
+func (h *ConsumerHandler) UpdateConsumer(c *gin.Context) {
+	if _, ok := c.GetQuery("status"); ok {
+		h.UpdateConsumerStatus(c)
+		return
+	}
+
+	// Get the ID from the URL parameters
+	id := c.Param("id")
+	if id == "" {
+		httputil.BadRequest(c, "Invalid ID", "ID cannot be empty")
+		return
+	}
+
+	// Bind the JSON request body to the ConsumerUpdateRequest struct, rejecting the fields it does not have, e.g. the username
+	var req entity.ConsumerUpdateRequest
+	if !bindJSONStrict(c, &req, "Invalid request body") {
+		return
+	}
+
+	// Update the consumer using the service, which validates it
+	updatedConsumer, err := h.Service.UpdateConsumer(c.Request.Context(), id, req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "Consumer not found", "No consumer found with the given ID")
 			return
 		}
 
-		// If the error is not a validation error, return a generic internal server error
-		// This is to avoid exposing internal details of the error
-		httputil.InternalServerError(c, "Failed to create consumer", err.Error())
+		// The validation errors, the broken rules, and the emails or phones of other consumers are reported to the client
+		respondError(c, "Failed to update consumer", err)
 		return
 	}
 
-	httputil.Created(c, "Consumer created successfully", createdConsumer)
+	httputil.Success(c, "Consumer updated successfully", newConsumerResponse(c, updatedConsumer))
 }
 
 // UpdateConsumerStatus updates the status of a consumer by its ID and returns the updated consumer as JSON.
@@ -301,7 +512,7 @@ func (h *ConsumerHandler) UpdateConsumerStatus(c *gin.Context) {
 	}
 
 	// Update the consumer status using the service
-	updatedConsumer, err := h.Service.UpdateConsumerStatus(id, status)
+	updatedConsumer, err := h.Service.UpdateConsumerStatus(c.Request.Context(), id, status)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			httputil.NotFound(c, "Consumer not found", "No consumer found with the given ID")
@@ -310,9 +521,121 @@ func (h *ConsumerHandler) UpdateConsumerStatus(c *gin.Context) {
 
 		// If the error is not a record not found error, return a generic internal server error
 		// This is to avoid exposing internal details of the error
-		httputil.InternalServerError(c, "Failed to update consumer status", err.Error())
+		respondError(c, "Failed to update consumer status", err)
+		return
+	}
+
+	httputil.Success(c, "Consumer status updated successfully", newConsumerResponse(c, updatedConsumer))
+}
+
+// DeleteConsumer soft-deletes a consumer by its ID.
+// @Summary      Delete consumer
+// @Description  Soft-delete a consumer by its ID, it is left out of the lookups and the listings until it is restored
+// @Tags         consumers
+// @Produce      json
+// @Param        id   path      string  true  "Consumer ID"
+// @Success      200  {object}  model.HttpResponse for successful deletion
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for not found or already deleted
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /consumers/{id} [delete]
+func (h *ConsumerHandler) DeleteConsumer(c *gin.Context) {
+	// Get the ID from the URL parameters
+	id := c.Param("id")
+	if id == "" {
+		httputil.BadRequest(c, "Invalid ID", "ID cannot be empty")
 		return
 	}
 
-	httputil.Success(c, "Consumer status updated successfully", updatedConsumer)
+	if err := h.Service.DeleteConsumer(c.Request.Context(), id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "Consumer not found", "No consumer found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to delete consumer", err)
+		return
+	}
+
+	httputil.Success(c, "Consumer deleted successfully", nil)
+}
+
+// RestoreConsumer restores a soft-deleted consumer by its ID and returns the restored consumer as JSON.
+// @Summary      Restore consumer
+// @Description  Restore a soft-deleted consumer by its ID, it is found by the lookups and the listings again
+// @Tags         consumers
+// @Produce      json
+// @Param        id   path      string  true  "Consumer ID"
+// @Success      200  {object}  model.HttpResponse for successful restore
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for not found
+// @Failure      409  {object}  model.HttpResponse for a consumer which is not deleted
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /consumers/{id}/restore [post]
+func (h *ConsumerHandler) RestoreConsumer(c *gin.Context) {
+	// Get the ID from the URL parameters
+	id := c.Param("id")
+	if id == "" {
+		httputil.BadRequest(c, "Invalid ID", "ID cannot be empty")
+		return
+	}
+
+	restoredConsumer, err := h.Service.RestoreConsumer(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "Consumer not found", "No consumer found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to restore consumer", err)
+		return
+	}
+
+	httputil.Success(c, "Consumer restored successfully", newConsumerResponse(c, restoredConsumer))
+}
+
+// formatConsumerRuleViolations formats the rules of the deployment broken by a consumer like the validation errors.
+func formatConsumerRuleViolations(err *service.ConsumerRuleError) []map[string]string {
+	errs := make([]map[string]string, 0, len(err.Violations))
+	for _, v := range err.Violations {
+		errs = append(errs, map[string]string{"field": v.Field, "message": v.Message})
+	}
+
+	return errs
+}
+
+// consumerRenderer returns the function rendering the consumers of the response: in the locale negotiated with the Accept-Language
+// of the request when the locale formatting is enabled, which is set as the Content-Language of the response, as they are stored otherwise.
+func consumerRenderer(c *gin.Context) func(entity.Consumer) entity.ConsumerResponse {
+	if !localeformat.Enabled() {
+		return entity.NewConsumerResponse
+	}
+
+	// The responses of the same URL depend on the Accept-Language of the request, for the caches
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	locale, ok := localeformat.Negotiate(c.GetHeader("Accept-Language"))
+	if !ok {
+		return entity.NewConsumerResponse
+	}
+
+	c.Header("Content-Language", locale.String())
+	return func(consumer entity.Consumer) entity.ConsumerResponse {
+		return entity.NewConsumerResponse(consumer).Localized(locale)
+	}
+}
+
+// newConsumerResponse returns the representation of the consumer, rendered in the locale of the request if any.
+func newConsumerResponse(c *gin.Context, consumer entity.Consumer) entity.ConsumerResponse {
+	return consumerRenderer(c)(consumer)
+}
+
+// newConsumerResponses returns the representations of the consumers, rendered in the locale of the request if any.
+func newConsumerResponses(c *gin.Context, consumers []entity.Consumer) []entity.ConsumerResponse {
+	render := consumerRenderer(c)
+	resp := make([]entity.ConsumerResponse, 0, len(consumers))
+	for _, consumer := range consumers {
+		resp = append(resp, render(consumer))
+	}
+
+	return resp
 }
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/go-playground/validator.v9"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// unavailableErrorDetail is the detail of the errors of a database unavailable, or failing transiently after the retries.
+const unavailableErrorDetail = "The service is temporarily unavailable, please try again later"
+
+// unexpectedErrorDetail is the detail of the errors not known to the handlers, whose text is logged instead of being returned.
+const unexpectedErrorDetail = "An unexpected error occurred, please try again later"
+
+// serviceError maps an error of the services to the status of its response and the detail returned to the client.
+// An empty detail returns the text of the error, which the services compose for the client.
+type serviceError struct {
+	err    error
+	status int
+	detail string
+}
+
+// serviceErrors are the errors of the services known to the handlers, checked in order with errors.Is.
+var serviceErrors = []serviceError{
+	{err: service.ErrInvalidCredentials, status: http.StatusUnauthorized, detail: "Username or password is incorrect"},
+	{err: service.ErrUserLocked, status: http.StatusForbidden, detail: "The user account is locked"},
+	{err: service.ErrUserNotVerified, status: http.StatusForbidden, detail: "The user account is pending verification"},
+	{err: service.ErrRefreshTokenExpired, status: http.StatusUnauthorized, detail: "Refresh token is expired"},
+	{err: service.ErrDuplicateUsername, status: http.StatusConflict, detail: "A consumer with this username already exists"},
+	{err: service.ErrDuplicateEmail, status: http.StatusConflict, detail: "A consumer with this email already exists"},
+	{err: service.ErrDuplicatePhone, status: http.StatusConflict, detail: "A consumer with this phone already exists"},
+	{err: service.ErrEmptyConsumerUpdate, status: http.StatusBadRequest},
+	{err: service.ErrDeletedConsumersForbidden, status: http.StatusForbidden},
+	{err: service.ErrConsumerNotDeleted, status: http.StatusConflict},
+	{err: service.ErrUserAlreadyExists, status: http.StatusConflict},
+	{err: service.ErrUnknownRole, status: http.StatusBadRequest},
+	{err: service.ErrCannotDeleteSelf, status: http.StatusBadRequest},
+	{err: service.ErrNoRolesLeft, status: http.StatusBadRequest},
+	{err: service.ErrNotServiceAccount, status: http.StatusBadRequest},
+	{err: service.ErrApiKeyRevoked, status: http.StatusConflict},
+	{err: service.ErrOAuthClientRevoked, status: http.StatusConflict},
+	{err: service.ErrInvalidMFACode, status: http.StatusBadRequest},
+	{err: service.ErrMFANotEnrolled, status: http.StatusConflict},
+	{err: service.ErrMFANotEnabled, status: http.StatusConflict},
+	{err: service.ErrMFAAlreadyEnabled, status: http.StatusConflict},
+	{err: service.ErrBuiltInRole, status: http.StatusBadRequest},
+	{err: service.ErrUnknownPermission, status: http.StatusBadRequest},
+	{err: service.ErrRoleAlreadyExists, status: http.StatusConflict},
+	{err: service.ErrRoleInUse, status: http.StatusConflict},
+	{err: service.ErrInvalidRateLimitClient, status: http.StatusBadRequest},
+	{err: gorm.ErrDuplicatedKey, status: http.StatusConflict, detail: "The resource already exists"},
+	{err: gorm.ErrRecordNotFound, status: http.StatusNotFound, detail: "The requested resource was not found"},
+	{err: database.ErrCircuitOpen, status: http.StatusServiceUnavailable, detail: unavailableErrorDetail},
+}
+
+// respondError responds with the error of a service: the validation errors and the broken consumer rules with their fields,
+// the known errors of the services with their status, the transient database errors with 503, so that the clients retry later,
+// and the other errors with 500, without their text, which is logged.
+// The handlers check the errors needing a specific response, e.g. a user not found, before calling it.
+func respondError(c *gin.Context, message string, err error) {
+	// Check if the error is a validation error
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		httputil.BadRequestMap(c, message, validation.FormatValidationErrors(err))
+		return
+	}
+
+	// The rules of the deployment broken by a consumer are reported like the validation errors
+	var re *service.ConsumerRuleError
+	if errors.As(err, &re) {
+		httputil.BadRequestMap(c, message, formatConsumerRuleViolations(re))
+		return
+	}
+
+	for _, se := range serviceErrors {
+		if !errors.Is(err, se.err) {
+			continue
+		}
+
+		detail := se.detail
+		if detail == "" {
+			detail = err.Error()
+		}
+		writeError(c, se.status, message, detail)
+		return
+	}
+
+	// The database is unavailable, or its transient errors outlasted the retries
+	if repository.IsTransientError(err) {
+		logger.WarnContext(c.Request.Context(), message, logrus.Fields{"error": err.Error()})
+		httputil.ServiceUnavailable(c, message, unavailableErrorDetail)
+		return
+	}
+
+	logger.ErrorContext(c.Request.Context(), message, logrus.Fields{"error": err.Error()})
+	httputil.InternalServerError(c, message, unexpectedErrorDetail)
+}
+
+// writeError responds with the given status, message, and detail.
+func writeError(c *gin.Context, status int, message string, detail string) {
+	switch status {
+	case http.StatusBadRequest:
+		httputil.BadRequest(c, message, detail)
+	case http.StatusUnauthorized:
+		httputil.Unauthorized(c, message, detail)
+	case http.StatusForbidden:
+		httputil.Forbidden(c, message, detail)
+	case http.StatusNotFound:
+		httputil.NotFound(c, message, detail)
+	case http.StatusConflict:
+		httputil.Conflict(c, message, detail)
+	case http.StatusServiceUnavailable:
+		httputil.ServiceUnavailable(c, message, detail)
+	default:
+		httputil.InternalServerError(c, message, detail)
+	}
+}
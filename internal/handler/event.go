@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/eventbus"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+const (
+	// eventWriteTimeout is the time a connection has to accept an event or a ping before it is closed.
+	eventWriteTimeout = 10 * time.Second
+
+	// eventPingInterval is the interval of the pings keeping the idle connections open through the proxies.
+	eventPingInterval = 30 * time.Second
+
+	// eventPongTimeout is the time a connection has to answer a ping before it is closed.
+	eventPongTimeout = 2 * eventPingInterval
+)
+
+// This struct defines the EventHandler which pushes the realtime events to the connected admin UIs over WebSocket.
+// It contains the event bus the events are published to, and the policy selecting the topics of each role.
+type EventHandler struct {
+	Bus      *eventbus.Bus
+	Policy   eventbus.Policy
+	upgrader websocket.Upgrader
+}
+
+// NewEventHandler creates a new instance of EventHandler.
+// It initializes the EventHandler struct with the provided event bus and policy.
+func NewEventHandler(bus *eventbus.Bus, policy eventbus.Policy) *EventHandler {
+	return &EventHandler{
+		Bus:    bus,
+		Policy: policy,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{authorization.WebSocketTokenProtocol},
+
+			// The origin of the request was already checked against the allowed origins by the CORS middleware
+			CheckOrigin: func(_ *http.Request) bool { return true },
+		},
+	}
+}
+
+// StreamEvents upgrades the request to a WebSocket connection and pushes the events of the topics of the roles
+// of the user as JSON text messages, until the client disconnects or the access token expires.
+// The messages sent by the client are ignored.
+// @Summary      Stream events
+// @Description  Push the realtime events of the topics of the roles of the user over WebSocket
+// @Tags         events
+// @Success      101  {object}  eventbus.Event for each message of the connection
+// @Failure      400  {object}  model.HttpResponse for a request that is not a WebSocket upgrade
+// @Failure      403  {object}  model.HttpResponse for roles without topics
+// @Router       /ws [get]
+func (h *EventHandler) StreamEvents(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	topics := h.Policy.Topics(meta.Roles)
+	if len(topics) == 0 {
+		httputil.Forbidden(c, "Access denied", "The roles of the user do not receive any event")
+		return
+	}
+
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		httputil.BadRequest(c, "WebSocket upgrade required", "The request must upgrade the connection to WebSocket")
+		return
+	}
+
+	// Subscribe before the upgrade, so that no event is missed once the client is connected
+	sub := h.Bus.Subscribe(topics, eventbus.DefaultSubscriberBuffer)
+	defer sub.Close()
+
+	// The upgrader responds to the failed upgrades on its own
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	logger.InfoContext(c.Request.Context(), "Event stream connected", logrus.Fields{"user_id": meta.UserID, "topics": topics})
+	reason := serveEvents(conn, sub, meta.TokenExpiresAt)
+	logger.InfoContext(c.Request.Context(), "Event stream disconnected", logrus.Fields{"user_id": meta.UserID, "reason": reason, "dropped": sub.Dropped()})
+}
+
+// serveEvents writes the events of the subscription to the connection, and pings it, until the client disconnects,
+// the access token expires, or the subscription is closed on shutdown. It returns the reason of the disconnection.
+func serveEvents(conn *websocket.Conn, sub *eventbus.Subscription, tokenExpiresAt time.Time) string {
+	// Read the messages of the client, so that the pongs and the close messages are handled
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+
+		_ = conn.SetReadDeadline(time.Now().Add(eventPongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(eventPongTimeout))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// The connection does not outlive its access token, the client reconnects with a new one
+	var expired <-chan time.Time
+	if !tokenExpiresAt.IsZero() {
+		timer := time.NewTimer(time.Until(tokenExpiresAt))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	ping := time.NewTicker(eventPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case e, ok := <-sub.Events():
+			if !ok {
+				closeConnection(conn, websocket.CloseGoingAway, "Server shutting down")
+				return "shutdown"
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+			if err := conn.WriteJSON(e); err != nil {
+				return "write failed"
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventWriteTimeout)); err != nil {
+				return "ping failed"
+			}
+		case <-expired:
+			closeConnection(conn, websocket.ClosePolicyViolation, "Token expired")
+			return "token expired"
+		case <-disconnected:
+			return "client disconnected"
+		}
+	}
+}
+
+// closeConnection sends a close message with the given code and reason to the client.
+func closeConnection(conn *websocket.Conn, code int, reason string) {
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(eventWriteTimeout))
+}
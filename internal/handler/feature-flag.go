@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// This struct defines the FeatureFlagHandler which handles HTTP requests related to the features disabled at runtime.
+// It contains a service field of type FeatureFlagService which is used to read and switch the flags.
+type FeatureFlagHandler struct {
+	Service service.FeatureFlagService
+}
+
+// NewFeatureFlagHandler creates a new instance of FeatureFlagHandler.
+// It initializes the FeatureFlagHandler struct with the provided FeatureFlagService.
+func NewFeatureFlagHandler(featureFlagService service.FeatureFlagService) *FeatureFlagHandler {
+	return &FeatureFlagHandler{Service: featureFlagService}
+}
+
+// GetFeatureFlags retrieves the flags of all the features.
+// @Summary      Get feature flags
+// @Description  Get the features that can be disabled, and whether they are disabled at runtime or by the environment
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  model.HttpResponse for successful retrieval
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/feature-flags [get]
+func (h *FeatureFlagHandler) GetFeatureFlags(c *gin.Context) {
+	flags, err := h.Service.GetFeatureFlags(c.Request.Context())
+	if err != nil {
+		respondError(c, "Failed to retrieve feature flags", err)
+		return
+	}
+
+	httputil.Success(c, "Feature flags retrieved successfully", flags)
+}
+
+// UpdateFeatureFlag disables or enables a feature at runtime.
+// @Summary      Update feature flag
+// @Description  Disable or enable the feature, the requests of a disabled feature are rejected with 503
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        name     path      string                     true  "Feature name"
+// @Param        request  body      entity.FeatureFlagRequest  true  "Feature flag"
+// @Success      200  {object}  model.HttpResponse for successful update
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for unknown feature
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/feature-flags/{name} [put]
+func (h *FeatureFlagHandler) UpdateFeatureFlag(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	var req entity.FeatureFlagRequest
+	if !bindJSONStrict(c, &req, "Invalid request body") {
+		return
+	}
+
+	flag, err := h.Service.UpdateFeatureFlag(c.Request.Context(), meta.UserID, c.Param("name"), req)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownFeature) {
+			httputil.NotFound(c, "Feature not found", err.Error())
+			return
+		}
+
+		// Check if the error is a validation error
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			httputil.BadRequestMap(c, "Failed to update feature flag", validation.FormatValidationErrors(err))
+			return
+		}
+
+		respondError(c, "Failed to update feature flag", err)
+		return
+	}
+
+	httputil.Success(c, "Feature flag updated successfully", flag)
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// This struct defines the MFAHandler which handles HTTP requests related to the two-factor authentication of the authenticated user.
+// It contains a service field of type MFAService which is used to enroll, enable, and disable two-factor authentication.
+type MFAHandler struct {
+	Service service.MFAService
+}
+
+// NewMFAHandler creates a new instance of MFAHandler.
+// It initializes the MFAHandler struct with the provided MFAService.
+func NewMFAHandler(mfaService service.MFAService) *MFAHandler {
+	return &MFAHandler{Service: mfaService}
+}
+
+// GetMFAStatus returns whether two-factor authentication is enabled for the authenticated user.
+// @Summary      Get two-factor authentication status
+// @Description  Get whether two-factor authentication is enabled for the authenticated user
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  model.HttpResponse for successful retrieval
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users/me/mfa [get]
+func (h *MFAHandler) GetMFAStatus(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	status, err := h.Service.GetStatus(c.Request.Context(), meta.UserID)
+	if err != nil {
+		respondError(c, "Failed to retrieve two-factor authentication status", err)
+		return
+	}
+
+	httputil.Success(c, "Two-factor authentication status retrieved successfully", status)
+}
+
+// EnrollMFA generates the secret of a new authenticator app for the authenticated user.
+// @Summary      Enroll two-factor authentication
+// @Description  Generate the secret and the provisioning URI of an authenticator app, confirmed with a code by the activation
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  model.HttpResponse for successful enrollment
+// @Failure      409  {object}  model.HttpResponse for two-factor authentication already enabled
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users/me/mfa/enroll [post]
+func (h *MFAHandler) EnrollMFA(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	enrollment, err := h.Service.Enroll(c.Request.Context(), meta.UserID)
+	if err != nil {
+		respondError(c, "Failed to enroll two-factor authentication", err)
+		return
+	}
+
+	httputil.Success(c, "Two-factor authentication enrolled, confirm it with a code of the authenticator app", enrollment)
+}
+
+// ActivateMFA enables two-factor authentication for the authenticated user with a code of the enrolled authenticator app.
+// @Summary      Activate two-factor authentication
+// @Description  Enable two-factor authentication with a code of the enrolled authenticator app
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.MFACodeRequest  true  "Code of the authenticator app"
+// @Success      200  {object}  model.HttpResponse for successful activation
+// @Failure      400  {object}  model.HttpResponse for bad request or invalid code
+// @Failure      409  {object}  model.HttpResponse for two-factor authentication not enrolled or already enabled
+// @Failure      429  {object}  model.HttpResponse for too many requests
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users/me/mfa/activate [post]
+func (h *MFAHandler) ActivateMFA(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	var codeReq entity.MFACodeRequest
+	if err := c.ShouldBindJSON(&codeReq); err != nil {
+		httputil.BadRequest(c, "Invalid request", err.Error())
+		return
+	}
+	codeReq.IPAddress = c.ClientIP()
+
+	status, err := h.Service.Activate(c.Request.Context(), meta.UserID, codeReq)
+	if err != nil {
+		respondError(c, "Failed to activate two-factor authentication", err)
+		return
+	}
+
+	httputil.Success(c, "Two-factor authentication enabled successfully", status)
+}
+
+// DisableMFA disables two-factor authentication for the authenticated user with a code of the authenticator app.
+// @Summary      Disable two-factor authentication
+// @Description  Disable two-factor authentication with a code of the authenticator app, the user is notified by email
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.MFACodeRequest  true  "Code of the authenticator app"
+// @Success      200  {object}  model.HttpResponse for successful deactivation
+// @Failure      400  {object}  model.HttpResponse for bad request or invalid code
+// @Failure      409  {object}  model.HttpResponse for two-factor authentication not enabled
+// @Failure      429  {object}  model.HttpResponse for too many requests
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users/me/mfa/disable [post]
+func (h *MFAHandler) DisableMFA(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	var codeReq entity.MFACodeRequest
+	if err := c.ShouldBindJSON(&codeReq); err != nil {
+		httputil.BadRequest(c, "Invalid request", err.Error())
+		return
+	}
+	codeReq.IPAddress = c.ClientIP()
+
+	if err := h.Service.Disable(c.Request.Context(), meta.UserID, codeReq); err != nil {
+		respondError(c, "Failed to disable two-factor authentication", err)
+		return
+	}
+
+	httputil.Success(c, "Two-factor authentication disabled successfully", nil)
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// This struct defines the NotificationHandler which handles HTTP requests related to the notification preferences.
+// It contains a service field of type NotificationService which is used to interact with the notification data layer.
+type NotificationHandler struct {
+	Service service.NotificationService
+}
+
+// NewNotificationHandler creates a new instance of NotificationHandler.
+// It initializes the NotificationHandler struct with the provided NotificationService.
+func NewNotificationHandler(notificationService service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{Service: notificationService}
+}
+
+// GetNotificationPreference retrieves the notification preferences of the authenticated user.
+// @Summary      Get notification preferences
+// @Description  Get the security notifications the authenticated user receives
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  model.HttpResponse for successful retrieval
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users/me/notification-preferences [get]
+func (h *NotificationHandler) GetNotificationPreference(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	pref, err := h.Service.GetNotificationPreference(c.Request.Context(), meta.UserID)
+	if err != nil {
+		respondError(c, "Failed to retrieve notification preferences", err)
+		return
+	}
+
+	httputil.Success(c, "Notification preferences retrieved successfully", pref)
+}
+
+// UpdateNotificationPreference replaces the notification preferences of the authenticated user.
+// @Summary      Update notification preferences
+// @Description  Turn the security notifications of the authenticated user on or off
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.NotificationPreferenceRequest  true  "Notification preferences"
+// @Success      200  {object}  model.HttpResponse for successful update
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users/me/notification-preferences [put]
+func (h *NotificationHandler) UpdateNotificationPreference(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	var req entity.NotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	pref, err := h.Service.UpdateNotificationPreference(c.Request.Context(), meta.UserID, req)
+	if err != nil {
+		// Check if the error is a validation error
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			httputil.BadRequestMap(c, "Failed to update notification preferences", validation.FormatValidationErrors(err))
+			return
+		}
+
+		respondError(c, "Failed to update notification preferences", err)
+		return
+	}
+
+	httputil.Success(c, "Notification preferences updated successfully", pref)
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/anomaly"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// OAuth2 error codes of the token endpoint (RFC 6749, section 5.2)
+const (
+	OAuthErrorInvalidRequest       = "invalid_request"
+	OAuthErrorInvalidClient        = "invalid_client"
+	OAuthErrorInvalidScope         = "invalid_scope"
+	OAuthErrorUnsupportedGrantType = "unsupported_grant_type"
+	OAuthErrorServerError          = "server_error"
+)
+
+// This struct defines the OAuthClientHandler which handles the token requests of the OAuth2 clients of the service accounts,
+// and the HTTP requests of the administrators managing them.
+// It contains a service field of type OAuthClientService which is used to manage the clients and issue their tokens.
+type OAuthClientHandler struct {
+	Service service.OAuthClientService
+}
+
+// NewOAuthClientHandler creates a new instance of OAuthClientHandler.
+// It initializes the OAuthClientHandler struct with the provided OAuthClientService.
+func NewOAuthClientHandler(oauthClientService service.OAuthClientService) *OAuthClientHandler {
+	return &OAuthClientHandler{Service: oauthClientService}
+}
+
+// Token handles the token requests with the client_credentials grant.
+// The client ID and secret are read from the HTTP Basic authentication of the request, or from the form.
+// The responses are not wrapped in the response envelope, they follow RFC 6749 as expected by the OAuth2 libraries.
+// @Summary      OAuth2 token
+// @Description  Exchange the client ID and secret of an OAuth2 client of a service account for an access token (client_credentials grant)
+// @Tags         auth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type     formData  string  true   "client_credentials"
+// @Param        client_id      formData  string  false  "Client ID, unless sent with the HTTP Basic authentication"
+// @Param        client_secret  formData  string  false  "Client secret, unless sent with the HTTP Basic authentication"
+// @Param        scope          formData  string  false  "Space-delimited list of the requested scopes"
+// @Success      200  {object}  entity.ClientCredentialsResponse for successful token request
+// @Failure      400  {object}  entity.OAuthErrorResponse for invalid request, unsupported grant type, or invalid scope
+// @Failure      401  {object}  entity.OAuthErrorResponse for invalid client
+// @Failure      429  {object}  entity.OAuthErrorResponse for blocked client
+// @Router       /auth/token [post]
+func (h *OAuthClientHandler) Token(c *gin.Context) {
+	// The tokens must not be cached (RFC 6749, section 5.1)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req entity.ClientCredentialsRequest
+	if err := c.ShouldBind(&req); err != nil {
+		oauthError(c, http.StatusBadRequest, OAuthErrorInvalidRequest, "The request body is not a valid form")
+		return
+	}
+
+	// The HTTP Basic authentication takes precedence, its client ID and secret are form-encoded (RFC 6749, section 2.3.1)
+	usedBasicAuth := false
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		usedBasicAuth = true
+		req.ClientID, _ = url.QueryUnescape(clientID)
+		req.ClientSecret, _ = url.QueryUnescape(clientSecret)
+	}
+
+	// Reject the request if the source or the client is temporarily blocked by the anomaly detection subsystem
+	detector := anomaly.GetDetector()
+	if detector.IsBlocked(c.ClientIP(), req.ClientID) {
+		oauthError(c, http.StatusTooManyRequests, OAuthErrorInvalidClient, "Too many failed token requests, please try again later")
+		return
+	}
+
+	resp, err := h.Service.IssueClientCredentialsToken(c.Request.Context(), req)
+	switch {
+	case err == nil:
+		detector.Reset(req.ClientID)
+		c.JSON(http.StatusOK, resp)
+	case errors.Is(err, service.ErrInvalidTokenRequest):
+		oauthError(c, http.StatusBadRequest, OAuthErrorInvalidRequest, err.Error())
+	case errors.Is(err, service.ErrUnsupportedGrantType):
+		oauthError(c, http.StatusBadRequest, OAuthErrorUnsupportedGrantType, err.Error())
+	case errors.Is(err, service.ErrInvalidScope):
+		oauthError(c, http.StatusBadRequest, OAuthErrorInvalidScope, err.Error())
+	case errors.Is(err, service.ErrInvalidClient):
+		// Record the failed attempt for anomaly detection, like the failed logins
+		detector.Record(anomaly.Event{
+			Type:    anomaly.EventFailedLogin,
+			IP:      c.ClientIP(),
+			Account: req.ClientID,
+		})
+		if usedBasicAuth {
+			c.Header("WWW-Authenticate", `Basic realm="token"`)
+		}
+		oauthError(c, http.StatusUnauthorized, OAuthErrorInvalidClient, err.Error())
+	default:
+		logger.ErrorContext(c.Request.Context(), "Failed to issue the token of an OAuth client: "+err.Error(), nil)
+		oauthError(c, http.StatusInternalServerError, OAuthErrorServerError, "Unable to issue the token")
+	}
+}
+
+// GetOAuthClients retrieves the OAuth clients of a user.
+// @Summary      Get the OAuth clients of a user
+// @Description  Get the OAuth2 clients of a service account, the revoked ones included. The secrets are never returned
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  model.HttpResponse for successful retrieval
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for user not found
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/users/{id}/oauth-clients [get]
+func (h *OAuthClientHandler) GetOAuthClients(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		httputil.BadRequest(c, "Invalid ID", "ID must be a positive integer")
+		return
+	}
+
+	clients, err := h.Service.GetOAuthClients(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "User not found", "No user found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to retrieve OAuth clients", err)
+		return
+	}
+
+	if clients == nil {
+		clients = []entity.OAuthClient{}
+	}
+	httputil.Success(c, "OAuth clients retrieved successfully", clients)
+}
+
+// CreateOAuthClient creates a new OAuth client for a service account.
+// @Summary      Create an OAuth client
+// @Description  Create a new OAuth2 client for a service account. The secret is returned once, it cannot be retrieved afterward
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                     true  "User ID"
+// @Param        request  body      entity.OAuthClientRequest  true  "OAuth client request"
+// @Success      201  {object}  model.HttpResponse for successful creation
+// @Failure      400  {object}  model.HttpResponse for bad request or user which is not a service account
+// @Failure      404  {object}  model.HttpResponse for user not found
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/users/{id}/oauth-clients [post]
+func (h *OAuthClientHandler) CreateOAuthClient(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		httputil.BadRequest(c, "Invalid ID", "ID must be a positive integer")
+		return
+	}
+
+	var req entity.OAuthClientRequest
+	if !bindJSONStrict(c, &req, "Invalid request body") {
+		return
+	}
+
+	issued, err := h.Service.CreateOAuthClient(c.Request.Context(), id, req, meta.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "User not found", "No user found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to create OAuth client", err)
+		return
+	}
+
+	httputil.Created(c, "OAuth client created successfully", issued)
+}
+
+// RevokeOAuthClient revokes an OAuth client.
+// @Summary      Revoke an OAuth client
+// @Description  Revoke an OAuth2 client, it cannot request tokens anymore. The tokens already issued remain valid until they expire
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "OAuth client ID"
+// @Success      200  {object}  model.HttpResponse for successful revocation
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for OAuth client not found
+// @Failure      409  {object}  model.HttpResponse for OAuth client already revoked
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/oauth-clients/{id} [delete]
+func (h *OAuthClientHandler) RevokeOAuthClient(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		httputil.BadRequest(c, "Invalid ID", "ID must be a positive integer")
+		return
+	}
+
+	if err := h.Service.RevokeOAuthClient(c.Request.Context(), id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "OAuth client not found", "No OAuth client found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to revoke OAuth client", err)
+		return
+	}
+
+	httputil.Success(c, "OAuth client revoked successfully", nil)
+}
+
+// oauthError responds with an OAuth2 error response, with the given status, error code, and description.
+func oauthError(c *gin.Context, status int, code string, description string) {
+	c.JSON(status, entity.OAuthErrorResponse{Error: code, ErrorDescription: description})
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/anomaly"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/basepath"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/geoip"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/oidc"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// OIDCLoginCookie is the cookie keeping the state of a login with an OpenID Connect provider between the login route and the callback.
+// It is only sent to the routes of the provider, and only readable by the application.
+const OIDCLoginCookie = "oidc_login"
+
+// This struct defines the OIDCHandler which handles the logins with the OpenID Connect providers.
+// It contains a service field of type OIDCService which is used to run the authorization code flow,
+// a locator used to look up the location of the clients, and whether the login cookie is only sent on HTTPS.
+type OIDCHandler struct {
+	Service      service.OIDCService
+	Locator      geoip.Locator
+	SecureCookie bool
+}
+
+// NewOIDCHandler creates a new instance of OIDCHandler.
+// It initializes the OIDCHandler struct with the provided OIDCService, the GeoIP locator of the process,
+// and whether the server is served over HTTPS, so that the login cookie is only sent on HTTPS.
+func NewOIDCHandler(oidcService service.OIDCService, secureCookie bool) *OIDCHandler {
+	return &OIDCHandler{Service: oidcService, Locator: geoip.GetLocator(), SecureCookie: secureCookie}
+}
+
+// Login starts a login with an OpenID Connect provider, redirecting the user to the provider.
+// @Summary      OIDC login
+// @Description  Redirect the user to the OpenID Connect provider (e.g. google, microsoft) to log in with their account
+// @Tags         auth
+// @Param        provider  path  string  true  "Provider name"
+// @Success      302  "Redirection to the provider"
+// @Failure      404  {object}  model.HttpResponse for unknown provider
+// @Failure      503  {object}  model.HttpResponse for unavailable provider
+// @Router       /auth/oidc/{provider}/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	provider := c.Param("provider")
+
+	authorization, err := h.Service.StartLogin(c.Request.Context(), provider)
+	if err != nil {
+		handleOIDCError(c, "Failed to start OIDC login", err)
+		return
+	}
+
+	value, err := json.Marshal(authorization.LoginState)
+	if err != nil {
+		respondError(c, "Failed to start OIDC login", err)
+		return
+	}
+	h.setOIDCLoginCookie(c, provider, base64.RawURLEncoding.EncodeToString(value), int(service.OIDCLoginStateLifetime.Seconds()))
+
+	c.Redirect(http.StatusFound, authorization.URL)
+}
+
+// Callback completes a login with an OpenID Connect provider, once the provider redirected the user back.
+// It provisions the user on their first login, and returns a JWT token like the login with a password.
+// @Summary      OIDC callback
+// @Description  Complete the login with the OpenID Connect provider, and issue the access and refresh tokens of the application. The user is created with the default role on their first login
+// @Tags         auth
+// @Produce      json
+// @Param        provider           path   string  true   "Provider name"
+// @Param        code               query  string  false  "Authorization code"
+// @Param        state              query  string  false  "State of the authorization request"
+// @Param        error              query  string  false  "Error of the provider"
+// @Param        error_description  query  string  false  "Description of the error of the provider"
+// @Success      200  {object}  model.HttpResponse for successful login
+// @Failure      400  {object}  model.HttpResponse for callback not matching a login in progress
+// @Failure      401  {object}  model.HttpResponse for denied authorization or invalid ID token
+// @Failure      403  {object}  model.HttpResponse for unverified email admin login from a blocked country, or login rejected by a login hook
+// @Failure      404  {object}  model.HttpResponse for unknown provider
+// @Failure      409  {object}  model.HttpResponse for email used by another user or session limit reached
+// @Failure      429  {object}  model.HttpResponse for blocked client
+// @Failure      503  {object}  model.HttpResponse for unavailable provider
+// @Router       /auth/oidc/{provider}/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	provider := c.Param("provider")
+
+	var req entity.OIDCCallbackRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request", err.Error())
+		return
+	}
+	req.ClientID = c.GetHeader(ClientIDHeader)
+	req.DeviceID = c.GetHeader(DeviceIDHeader)
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+	location := h.Locator.Lookup(req.IPAddress)
+	req.Country = location.Country
+	req.City = location.City
+
+	// The state of the login is used once, a missing or malformed cookie does not match any login
+	var loginState entity.OIDCLoginState
+	if cookie, err := c.Cookie(OIDCLoginCookie); err == nil {
+		if value, err := base64.RawURLEncoding.DecodeString(cookie); err == nil {
+			_ = json.Unmarshal(value, &loginState)
+		}
+	}
+	h.setOIDCLoginCookie(c, provider, "", -1)
+
+	// Reject the request if the source is temporarily blocked by the anomaly detection subsystem
+	detector := anomaly.GetDetector()
+	if detector.IsBlocked(c.ClientIP(), "") {
+		httputil.TooManyRequests(c, "Login blocked", "Too many suspicious login attempts, please try again later")
+		return
+	}
+
+	loginResp, err := h.Service.CompleteLogin(c.Request.Context(), provider, req, loginState)
+	if err != nil {
+		// The ID tokens failing the verification are recorded like the failed logins
+		if errors.Is(err, oidc.ErrInvalidIDToken) {
+			detector.Record(anomaly.Event{
+				Type:    anomaly.EventFailedLogin,
+				IP:      c.ClientIP(),
+				Country: location.Country,
+				ASN:     location.ASN,
+			})
+		}
+
+		handleOIDCError(c, "Failed to login with OIDC provider", err)
+		return
+	}
+
+	// The user completes the login with a code of their authenticator app
+	if loginResp.MFARequired {
+		httputil.Success(c, "Two-factor authentication required", loginResp)
+		return
+	}
+
+	httputil.Success(c, "Login successful", loginResp)
+}
+
+// setOIDCLoginCookie sets the cookie keeping the state of the login with the provider, or removes it with a negative max age.
+// The cookie is sent back on the redirection of the provider to the callback (SameSite=Lax), and only on HTTPS when SecureCookie is set.
+func (h *OIDCHandler) setOIDCLoginCookie(c *gin.Context, provider string, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     OIDCLoginCookie,
+		Value:    value,
+		Path:     basepath.Join("/auth/oidc/" + provider),
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// handleOIDCError responds with the error of a login with an OpenID Connect provider, with the given message.
+func handleOIDCError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnknownOIDCProvider):
+		httputil.NotFound(c, message, err.Error())
+	case errors.Is(err, service.ErrInvalidOIDCCallback):
+		httputil.BadRequest(c, message, err.Error())
+	case errors.Is(err, service.ErrOIDCAuthorizationDenied), errors.Is(err, oidc.ErrInvalidIDToken), errors.Is(err, oidc.ErrTokenExchange):
+		httputil.Unauthorized(c, message, err.Error())
+	case errors.Is(err, service.ErrOIDCEmailNotVerified), errors.Is(err, service.ErrAdminLoginBlocked), errors.Is(err, service.ErrLoginRejected):
+		httputil.Forbidden(c, message, err.Error())
+	case errors.Is(err, service.ErrOIDCAccountExists), errors.Is(err, service.ErrUserAlreadyExists):
+		httputil.Conflict(c, message, err.Error())
+	case errors.Is(err, service.ErrSessionLimitReached):
+		httputil.ConflictMap(c, "Session limit reached", []map[string]string{{
+			"code":    SessionLimitReachedCode,
+			"message": "The maximum number of active sessions is reached, log out of another session and try again",
+		}})
+	case errors.Is(err, oidc.ErrProviderUnavailable):
+		logger.WarnContext(c.Request.Context(), "OIDC provider is unavailable", logrus.Fields{"provider": c.Param("provider"), "error": err.Error()})
+		httputil.ServiceUnavailable(c, message, "The identity provider cannot be reached, please try again later")
+	default:
+		respondError(c, message, err)
+	}
+}
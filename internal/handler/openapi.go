@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/diagnostics"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// OpenAPIContentType is the content type of the OpenAPI document.
+const OpenAPIContentType = "application/yaml"
+
+// OpenAPIArchiveContentType is the content type of the archive of the OpenAPI document.
+const OpenAPIArchiveContentType = "application/zip"
+
+// openAPIArchiveName is the prefix of the file name of the archive, followed by the version of the API and of the service.
+const openAPIArchiveName = "go-jwt-auth-demo-openapi"
+
+// OpenAPIManifest describes the OpenAPI document of the archive: the API and the build of the service serving it,
+// and the SHA-256 checksum of openapi.yaml, so that the integrating teams can tell which contract their clients are generated from.
+type OpenAPIManifest struct {
+	Title          string `json:"title"`
+	APIVersion     string `json:"apiVersion"`
+	ServiceVersion string `json:"serviceVersion"`
+	Commit         string `json:"commit"`
+	ServerURL      string `json:"serverUrl"`
+	SHA256         string `json:"sha256"`
+}
+
+// defaultServers is the servers section of the OpenAPI document, describing the API mounted at the root.
+var defaultServers = []byte("servers:\n  - url: /\n")
+
+// This struct defines the OpenAPIHandler which serves the OpenAPI document of the API, e.g. to the Swagger UI.
+// It contains the document, whose server URL is the base path of the service.
+// The archive of the document is built once, as it does not change while the service is running.
+type OpenAPIHandler struct {
+	Document []byte
+
+	archive     []byte
+	archiveName string
+	archiveETag string
+}
+
+// NewOpenAPIHandler creates a new instance of OpenAPIHandler.
+// It initializes the OpenAPIHandler struct with the given document, mounted under the given base path.
+func NewOpenAPIHandler(document []byte, basePath string) *OpenAPIHandler {
+	if basePath != "" {
+		document = bytes.Replace(document, defaultServers, []byte("servers:\n  - url: "+basePath+"\n"), 1)
+	}
+
+	h := &OpenAPIHandler{Document: document}
+	if err := h.buildArchive(basePath); err != nil {
+		logger.Error(fmt.Sprintf("Failed to build the archive of the OpenAPI document: %v", err), nil)
+	}
+
+	return h
+}
+
+// GetOpenAPI serves the OpenAPI document of the API.
+// @Summary      OpenAPI document
+// @Description  Get the OpenAPI document of the API, its server URL is the base path of the service
+// @Tags         health
+// @Produce      application/yaml
+// @Success      200  {string}  string "OpenAPI document"
+// @Router       /openapi.yaml [get]
+func (h *OpenAPIHandler) GetOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, OpenAPIContentType, h.Document)
+}
+
+// GetOpenAPIArchive serves the versioned archive of the OpenAPI document, for the client generators of the integrating teams.
+// @Summary      OpenAPI archive
+// @Description  Get a zip archive of the OpenAPI document in YAML and JSON, with a manifest of the versions of the API and of the service
+// @Tags         health
+// @Produce      application/zip
+// @Success      200  {file}    file "OpenAPI archive"
+// @Success      304  {string}  string "Not modified"
+// @Failure      500  {object}  httputil.HttpResponse
+// @Router       /openapi.zip [get]
+func (h *OpenAPIHandler) GetOpenAPIArchive(c *gin.Context) {
+	if h.archive == nil {
+		httputil.InternalServerError(c, "Failed to get the OpenAPI archive", "The OpenAPI document could not be archived")
+		return
+	}
+
+	c.Header("ETag", h.archiveETag)
+	if c.GetHeader("If-None-Match") == h.archiveETag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", h.archiveName))
+	c.Data(http.StatusOK, OpenAPIArchiveContentType, h.archive)
+}
+
+// buildArchive builds the zip archive of the document: openapi.yaml as it is served, openapi.json, and manifest.json.
+// The entries have no modification time, so that the archive, and its ETag, only change with the document and the build.
+func (h *OpenAPIHandler) buildArchive(basePath string) error {
+	doc, err := openapi3.NewLoader().LoadFromData(h.Document)
+	if err != nil {
+		return fmt.Errorf("failed to load the document: %w", err)
+	}
+
+	documentJSON, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to convert the document to JSON: %w", err)
+	}
+
+	checksum := sha256.Sum256(h.Document)
+	manifest := OpenAPIManifest{
+		Title:          doc.Info.Title,
+		APIVersion:     doc.Info.Version,
+		ServiceVersion: diagnostics.Version,
+		Commit:         diagnostics.BuildCommit(),
+		ServerURL:      "/",
+		SHA256:         hex.EncodeToString(checksum[:]),
+	}
+	if basePath != "" {
+		manifest.ServerURL = basePath
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the manifest: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, entry := range []struct {
+		name    string
+		content []byte
+	}{
+		{"openapi.yaml", h.Document},
+		{"openapi.json", documentJSON},
+		{"manifest.json", manifestJSON},
+	} {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: entry.name, Method: zip.Deflate})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", entry.name, err)
+		}
+		if _, err := f.Write(entry.content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", entry.name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to close the archive: %w", err)
+	}
+
+	archiveChecksum := sha256.Sum256(buf.Bytes())
+	h.archive = buf.Bytes()
+	h.archiveName = fmt.Sprintf("%s-%s-%s.zip", openAPIArchiveName, manifest.APIVersion, manifest.ServiceVersion)
+	h.archiveETag = `"` + hex.EncodeToString(archiveChecksum[:16]) + `"`
+
+	return nil
+}
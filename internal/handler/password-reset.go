@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// This struct defines the PasswordResetHandler which handles HTTP requests related to the reset of forgotten passwords.
+// It contains a service field of type PasswordResetService which is used to send the reset links and reset the passwords.
+type PasswordResetHandler struct {
+	Service service.PasswordResetService
+}
+
+// NewPasswordResetHandler creates a new instance of PasswordResetHandler.
+// It initializes the PasswordResetHandler struct with the provided PasswordResetService.
+func NewPasswordResetHandler(passwordResetService service.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{Service: passwordResetService}
+}
+
+// ForgotPassword sends a password reset link to the email of the request, if it belongs to an active user.
+// The response is the same whether the email is registered or not. The email is written in the language of Accept-Language.
+// @Summary      Forgot password
+// @Description  Send a password reset link to the email of a user
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.ForgotPasswordRequest  true  "Forgot password request"
+// @Param        Accept-Language  header  string  false  "Preferred languages of the email, e.g. id-ID,id;q=0.9"
+// @Success      200  {object}  model.HttpResponse for a request accepted
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      429  {object}  model.HttpResponse for too many requests
+// @Router       /auth/forgot-password [post]
+func (h *PasswordResetHandler) ForgotPassword(c *gin.Context) {
+	var forgotReq entity.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&forgotReq); err != nil {
+		httputil.BadRequest(c, "Invalid request", err.Error())
+		return
+	}
+	forgotReq.Locale = c.GetHeader("Accept-Language")
+
+	if err := h.Service.RequestPasswordReset(c.Request.Context(), forgotReq); err != nil {
+		// Check if the error is a validation error
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			httputil.BadRequestMap(c, "Failed to request password reset", validation.FormatValidationErrors(err))
+			return
+		}
+
+		respondError(c, "Failed to request password reset", err)
+		return
+	}
+
+	httputil.Success(c, "If the email is registered, a password reset link has been sent to it", nil)
+}
+
+// ResetPassword sets a new password with the token of a password reset link.
+// @Summary      Reset password
+// @Description  Set a new password with the token of a password reset link, the sessions of the user are ended
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.ResetPasswordRequest  true  "Reset password request"
+// @Success      200  {object}  model.HttpResponse for successful reset
+// @Failure      400  {object}  model.HttpResponse for bad request or invalid token
+// @Router       /auth/reset-password [post]
+func (h *PasswordResetHandler) ResetPassword(c *gin.Context) {
+	var resetReq entity.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&resetReq); err != nil {
+		httputil.BadRequest(c, "Invalid request", err.Error())
+		return
+	}
+	resetReq.IPAddress = c.ClientIP()
+
+	if err := h.Service.ResetPassword(c.Request.Context(), resetReq); err != nil {
+		// Check if the error is a validation error
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			httputil.BadRequestMap(c, "Failed to reset password", validation.FormatValidationErrors(err))
+			return
+		}
+
+		if errors.Is(err, service.ErrInvalidPasswordResetToken) {
+			httputil.BadRequest(c, "Failed to reset password", err.Error())
+			return
+		}
+
+		respondError(c, "Failed to reset password", err)
+		return
+	}
+
+	httputil.Success(c, "Password reset successfully", nil)
+}
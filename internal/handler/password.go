@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// This struct defines the PasswordHandler which handles HTTP requests related to the password of the authenticated user.
+// It contains a service field of type PasswordService which is used to change the passwords.
+type PasswordHandler struct {
+	Service service.PasswordService
+}
+
+// NewPasswordHandler creates a new instance of PasswordHandler.
+// It initializes the PasswordHandler struct with the provided PasswordService.
+func NewPasswordHandler(passwordService service.PasswordService) *PasswordHandler {
+	return &PasswordHandler{Service: passwordService}
+}
+
+// ChangePassword changes the password of the authenticated user, whose other sessions are ended.
+// @Summary      Change password
+// @Description  Change the password of the authenticated user with their current password, the refresh tokens of the user are revoked
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.ChangePasswordRequest  true  "Change password request"
+// @Success      200  {object}  model.HttpResponse for successful change
+// @Failure      400  {object}  model.HttpResponse for bad request or incorrect current password
+// @Failure      429  {object}  model.HttpResponse for too many requests
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users/me/password [put]
+func (h *PasswordHandler) ChangePassword(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	var changeReq entity.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&changeReq); err != nil {
+		httputil.BadRequest(c, "Invalid request", err.Error())
+		return
+	}
+	changeReq.IPAddress = c.ClientIP()
+
+	changed, err := h.Service.ChangePassword(c.Request.Context(), meta.UserID, changeReq)
+	if err != nil {
+		// Check if the error is a validation error
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			httputil.BadRequestMap(c, "Failed to change password", validation.FormatValidationErrors(err))
+			return
+		}
+
+		if errors.Is(err, service.ErrIncorrectPassword) {
+			httputil.BadRequest(c, "Failed to change password", err.Error())
+			return
+		}
+
+		respondError(c, "Failed to change password", err)
+		return
+	}
+
+	httputil.Success(c, "Password changed successfully", changed)
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/quota"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// This struct defines the QuotaHandler which reports the usage of the quotas of the authenticated client.
+// It contains the quota tracker enforcing the budgets of the v1 routes.
+type QuotaHandler struct {
+	Tracker *quota.Tracker
+}
+
+// NewQuotaHandler creates a new instance of QuotaHandler.
+// It initializes the QuotaHandler struct with the provided quota tracker.
+func NewQuotaHandler(tracker *quota.Tracker) *QuotaHandler {
+	return &QuotaHandler{Tracker: tracker}
+}
+
+// GetQuota retrieves the usage of the daily and monthly budgets of the client of the access token.
+// @Summary      Get quota usage
+// @Description  Get the units spent and left in the daily and monthly budgets of the client of the access token
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  model.HttpResponse for successful retrieval
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /me/quota [get]
+func (h *QuotaHandler) GetQuota(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	httputil.Success(c, "Quota usage retrieved successfully", h.Tracker.Usage(quota.ClientKey(meta.ClientID, meta.UserID)))
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// This struct defines the RateLimitOverrideHandler which handles HTTP requests related to the rate limits of the clients.
+// It contains a service field of type RateLimitOverrideService which is used to read and change the overrides.
+type RateLimitOverrideHandler struct {
+	Service service.RateLimitOverrideService
+}
+
+// NewRateLimitOverrideHandler creates a new instance of RateLimitOverrideHandler.
+// It initializes the RateLimitOverrideHandler struct with the provided RateLimitOverrideService.
+func NewRateLimitOverrideHandler(rateLimitOverrideService service.RateLimitOverrideService) *RateLimitOverrideHandler {
+	return &RateLimitOverrideHandler{Service: rateLimitOverrideService}
+}
+
+// GetRateLimitOverrides retrieves the rate limit overrides of all the clients.
+// @Summary      Get rate limit overrides
+// @Description  Get the clients whose rate limits are scaled, and their factors
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  model.HttpResponse for successful retrieval
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/rate-limit-overrides [get]
+func (h *RateLimitOverrideHandler) GetRateLimitOverrides(c *gin.Context) {
+	overrides, err := h.Service.GetRateLimitOverrides(c.Request.Context())
+	if err != nil {
+		respondError(c, "Failed to retrieve rate limit overrides", err)
+		return
+	}
+
+	httputil.Success(c, "Rate limit overrides retrieved successfully", overrides)
+}
+
+// UpdateRateLimitOverride creates or replaces the rate limit override of a client.
+// @Summary      Update rate limit override
+// @Description  Scale every rate limit of the client by the factor, e.g. 10 for ten times the default limits, or 0.1 to throttle the client
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        client   path      string                           true  "Client ID, or user:<id> for the tokens issued without client"
+// @Param        request  body      entity.RateLimitOverrideRequest  true  "Rate limit override"
+// @Success      200  {object}  model.HttpResponse for successful update
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/rate-limit-overrides/{client} [put]
+func (h *RateLimitOverrideHandler) UpdateRateLimitOverride(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	var req entity.RateLimitOverrideRequest
+	if !bindJSONStrict(c, &req, "Invalid request body") {
+		return
+	}
+
+	override, err := h.Service.SaveRateLimitOverride(c.Request.Context(), meta.UserID, c.Param("client"), req)
+	if err != nil {
+		respondError(c, "Failed to update rate limit override", err)
+		return
+	}
+
+	httputil.Success(c, "Rate limit override updated successfully", override)
+}
+
+// DeleteRateLimitOverride deletes the rate limit override of a client.
+// @Summary      Delete rate limit override
+// @Description  Delete the rate limit override of the client, which is allowed the default limits again
+// @Tags         admin
+// @Produce      json
+// @Param        client  path      string  true  "Client ID, or user:<id> for the tokens issued without client"
+// @Success      200  {object}  model.HttpResponse for successful deletion
+// @Failure      404  {object}  model.HttpResponse for client without override
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/rate-limit-overrides/{client} [delete]
+func (h *RateLimitOverrideHandler) DeleteRateLimitOverride(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	if err := h.Service.DeleteRateLimitOverride(c.Request.Context(), meta.UserID, c.Param("client")); err != nil {
+		if errors.Is(err, service.ErrRateLimitOverrideNotFound) {
+			httputil.NotFound(c, "Rate limit override not found", err.Error())
+			return
+		}
+
+		respondError(c, "Failed to delete rate limit override", err)
+		return
+	}
+
+	httputil.Success(c, "Rate limit override deleted successfully", nil)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/readiness"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// This struct defines the ReadinessHandler which reports whether the service is ready to accept traffic.
+// It contains the readiness gate completed by the warm-up of the service.
+type ReadinessHandler struct {
+	Gate *readiness.Gate
+}
+
+// NewReadinessHandler creates a new instance of ReadinessHandler.
+// It initializes the ReadinessHandler struct with the provided readiness gate.
+func NewReadinessHandler(gate *readiness.Gate) *ReadinessHandler {
+	return &ReadinessHandler{Gate: gate}
+}
+
+// Readyz reports whether the warm-up of the service completed, and the service is not shutting down.
+// @Summary      Readiness probe
+// @Description  Report whether the service is ready to accept traffic, with the outcome of the warm-up steps
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  model.HttpResponse for a ready service
+// @Failure      503  {object}  model.HttpResponse for a service still warming up or shutting down
+// @Router       /readyz [get]
+func (h *ReadinessHandler) Readyz(c *gin.Context) {
+	// The load balancers stop routing to a service shutting down
+	if h.Gate.IsDraining() {
+		httputil.ServiceUnavailable(c, "Service is not ready", "The service is shutting down")
+		return
+	}
+
+	steps := h.Gate.Steps()
+	if h.Gate.IsReady() {
+		httputil.Success(c, "Service is ready", steps)
+		return
+	}
+
+	var pending []string
+	for _, step := range steps {
+		if step.Optional || step.Status == readiness.StepOK {
+			continue
+		}
+
+		if step.Error != "" {
+			pending = append(pending, fmt.Sprintf("%s (%s)", step.Name, step.Error))
+		} else {
+			pending = append(pending, step.Name)
+		}
+	}
+
+	detail := "The service is warming up"
+	if len(pending) > 0 {
+		detail = fmt.Sprintf("The service is warming up, waiting for: %s", strings.Join(pending, ", "))
+	}
+	httputil.ServiceUnavailable(c, "Service is not ready", detail)
+}
@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// This struct defines the RoleHandler which handles HTTP requests related to the administration of the roles.
+// It contains a service field of type RoleService which is used to list, create, update, and delete the roles.
+type RoleHandler struct {
+	Service service.RoleService
+}
+
+// NewRoleHandler creates a new instance of RoleHandler.
+// It initializes the RoleHandler struct with the provided RoleService.
+func NewRoleHandler(roleService service.RoleService) *RoleHandler {
+	return &RoleHandler{Service: roleService}
+}
+
+// GetAllRoles retrieves all the roles and returns them as JSON.
+// @Summary      Get all roles
+// @Description  Get the built-in and the custom roles ordered by ID
+// @Tags         roles
+// @Produce      json
+// @Success      200  {array}   model.HttpResponse for successful retrieval
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /roles [get]
+func (h *RoleHandler) GetAllRoles(c *gin.Context) {
+	roles, err := h.Service.GetRoles(c.Request.Context())
+	if err != nil {
+		respondError(c, "Failed to retrieve roles", err)
+		return
+	}
+
+	httputil.Success(c, "All roles retrieved successfully", roles)
+}
+
+// CreateRole creates a custom role and returns it as JSON.
+// @Summary      Create role
+// @Description  Create a custom role named ROLE_ followed by uppercase letters, digits, and underscores
+// @Tags         roles
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.RoleRequest  true  "Role to create"
+// @Success      201  {object}  model.HttpResponse for successful creation
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      409  {object}  model.HttpResponse for role name already taken
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /roles [post]
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var req entity.RoleRequest
+	if !bindJSONStrict(c, &req, "Invalid request body") {
+		return
+	}
+
+	role, err := h.Service.CreateRole(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "Role not found", "No role found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to create role", err)
+		return
+	}
+
+	httputil.Created(c, "Role created successfully", role)
+}
+
+// UpdateRole renames a custom role or replaces the description of a role, and returns the updated role as JSON.
+// @Summary      Update role
+// @Description  Rename a custom role and replace its description, the built-in roles can only get a new description
+// @Tags         roles
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string              true  "Role ID"
+// @Param        request  body      entity.RoleRequest  true  "New name and description of the role"
+// @Success      200  {object}  model.HttpResponse for successful update
+// @Failure      400  {object}  model.HttpResponse for bad request or built-in role renamed
+// @Failure      404  {object}  model.HttpResponse for role not found
+// @Failure      409  {object}  model.HttpResponse for role name already taken
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /roles/{id} [put]
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	id, ok := parseRoleID(c)
+	if !ok {
+		return
+	}
+
+	var req entity.RoleRequest
+	if !bindJSONStrict(c, &req, "Invalid request body") {
+		return
+	}
+
+	role, err := h.Service.UpdateRole(c.Request.Context(), id, req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "Role not found", "No role found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to update role", err)
+		return
+	}
+
+	httputil.Success(c, "Role updated successfully", role)
+}
+
+// DeleteRole deletes a custom role that is not assigned to any user.
+// @Summary      Delete role
+// @Description  Delete a custom role, the built-in roles and the roles assigned to users cannot be deleted
+// @Tags         roles
+// @Produce      json
+// @Param        id   path      string  true  "Role ID"
+// @Success      200  {object}  model.HttpResponse for successful deletion
+// @Failure      400  {object}  model.HttpResponse for bad request or built-in role
+// @Failure      404  {object}  model.HttpResponse for role not found
+// @Failure      409  {object}  model.HttpResponse for role assigned to users
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /roles/{id} [delete]
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	id, ok := parseRoleID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeleteRole(c.Request.Context(), id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "Role not found", "No role found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to delete role", err)
+		return
+	}
+
+	httputil.Success(c, "Role deleted successfully", nil)
+}
+
+// GetAllPermissions retrieves all the permissions that can be granted to the roles and returns them as JSON.
+// @Summary      Get all permissions
+// @Description  Get the permissions that can be granted to the roles, carried by the access tokens in the scopes claim
+// @Tags         roles
+// @Produce      json
+// @Success      200  {array}   model.HttpResponse for successful retrieval
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /permissions [get]
+func (h *RoleHandler) GetAllPermissions(c *gin.Context) {
+	permissions, err := h.Service.GetPermissions(c.Request.Context())
+	if err != nil {
+		respondError(c, "Failed to retrieve permissions", err)
+		return
+	}
+
+	httputil.Success(c, "All permissions retrieved successfully", permissions)
+}
+
+// GetRolePermissions retrieves the permissions granted to a role and returns them as JSON.
+// @Summary      Get role permissions
+// @Description  Get the permissions granted to a role
+// @Tags         roles
+// @Produce      json
+// @Param        id   path      string  true  "Role ID"
+// @Success      200  {array}   model.HttpResponse for successful retrieval
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for role not found
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /roles/{id}/permissions [get]
+func (h *RoleHandler) GetRolePermissions(c *gin.Context) {
+	id, ok := parseRoleID(c)
+	if !ok {
+		return
+	}
+
+	permissions, err := h.Service.GetRolePermissions(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "Role not found", "No role found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to retrieve role permissions", err)
+		return
+	}
+
+	httputil.Success(c, "Role permissions retrieved successfully", permissions)
+}
+
+// SetRolePermissions replaces the permissions granted to a role and returns them as JSON.
+// @Summary      Set role permissions
+// @Description  Replace the permissions granted to a role, the next access tokens of its users carry them in the scopes claim
+// @Tags         roles
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                         true  "Role ID"
+// @Param        request  body      entity.RolePermissionsRequest  true  "Permissions of the role"
+// @Success      200  {object}  model.HttpResponse for successful update
+// @Failure      400  {object}  model.HttpResponse for bad request or unknown permission
+// @Failure      404  {object}  model.HttpResponse for role not found
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /roles/{id}/permissions [put]
+func (h *RoleHandler) SetRolePermissions(c *gin.Context) {
+	id, ok := parseRoleID(c)
+	if !ok {
+		return
+	}
+
+	var req entity.RolePermissionsRequest
+	if !bindJSONStrict(c, &req, "Invalid request body") {
+		return
+	}
+
+	permissions, err := h.Service.SetRolePermissions(c.Request.Context(), id, req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "Role not found", "No role found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to update role permissions", err)
+		return
+	}
+
+	httputil.Success(c, "Role permissions updated successfully", permissions)
+}
+
+// parseRoleID parses the ID of the role from the URL parameter, and responds with 400 if it is not a positive integer.
+func parseRoleID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id < 1 {
+		httputil.BadRequest(c, "Invalid ID", "ID must be a positive integer")
+		return 0, false
+	}
+
+	return uint(id), true
+}
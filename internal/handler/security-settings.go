@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// This struct defines the SecuritySettingsHandler which handles HTTP requests related to the CORS and security headers changed at runtime.
+// It contains a service field of type SecuritySettingsService which is used to read and replace the settings.
+type SecuritySettingsHandler struct {
+	Service service.SecuritySettingsService
+}
+
+// NewSecuritySettingsHandler creates a new instance of SecuritySettingsHandler.
+// It initializes the SecuritySettingsHandler struct with the provided SecuritySettingsService.
+func NewSecuritySettingsHandler(securitySettingsService service.SecuritySettingsService) *SecuritySettingsHandler {
+	return &SecuritySettingsHandler{Service: securitySettingsService}
+}
+
+// GetSecuritySettings retrieves the CORS and security headers changed at runtime.
+// @Summary      Get security settings
+// @Description  Get the allowed origins and headers added to the ones of the environment, and the overridden security headers
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  model.HttpResponse for successful retrieval
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/security-settings [get]
+func (h *SecuritySettingsHandler) GetSecuritySettings(c *gin.Context) {
+	settings, err := h.Service.GetSecuritySettings(c.Request.Context())
+	if err != nil {
+		respondError(c, "Failed to retrieve security settings", err)
+		return
+	}
+
+	httputil.Success(c, "Security settings retrieved successfully", settings)
+}
+
+// UpdateSecuritySettings replaces the CORS and security headers changed at runtime.
+// @Summary      Update security settings
+// @Description  Replace the allowed origins and headers added to the ones of the environment, and the overridden security headers
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.SecuritySettingsRequest  true  "Security settings"
+// @Success      200  {object}  model.HttpResponse for successful update
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/security-settings [put]
+func (h *SecuritySettingsHandler) UpdateSecuritySettings(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	var req entity.SecuritySettingsRequest
+	if !bindJSONStrict(c, &req, "Invalid request body") {
+		return
+	}
+
+	settings, err := h.Service.UpdateSecuritySettings(c.Request.Context(), meta.UserID, req)
+	if err != nil {
+		// Check if the error is a validation error
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			httputil.BadRequestMap(c, "Failed to update security settings", validation.FormatValidationErrors(err))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidSecuritySettings) {
+			httputil.BadRequest(c, "Failed to update security settings", err.Error())
+			return
+		}
+
+		respondError(c, "Failed to update security settings", err)
+		return
+	}
+
+	httputil.Success(c, "Security settings updated successfully", settings)
+}
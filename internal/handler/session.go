@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// This struct defines the SessionHandler which handles HTTP requests related to the sessions of the authenticated user.
+// It contains a service field of type RefreshTokenService which is used to interact with the refresh tokens of the sessions.
+type SessionHandler struct {
+	Service service.RefreshTokenService
+}
+
+// NewSessionHandler creates a new instance of SessionHandler.
+// It initializes the SessionHandler struct with the provided RefreshTokenService.
+func NewSessionHandler(refreshTokenService service.RefreshTokenService) *SessionHandler {
+	return &SessionHandler{Service: refreshTokenService}
+}
+
+// GetSessions retrieves the active sessions of the authenticated user.
+// @Summary      Get sessions
+// @Description  Get the active sessions of the authenticated user, one per device it is logged in on
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  model.HttpResponse for successful retrieval
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users/me/sessions [get]
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	sessions, err := h.Service.GetSessions(c.Request.Context(), meta.UserID)
+	if err != nil {
+		respondError(c, "Failed to retrieve sessions", err)
+		return
+	}
+
+	httputil.Success(c, "Sessions retrieved successfully", sessions)
+}
+
+// RemoveSession ends a session of the authenticated user, its refresh token can no longer be used.
+// @Summary      Remove session
+// @Description  End a session of the authenticated user, e.g. the session of a lost device
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        sessionId  path      string  true  "Session ID"
+// @Success      200  {object}  model.HttpResponse for successful removal
+// @Failure      404  {object}  model.HttpResponse for session not found
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users/me/sessions/{sessionId} [delete]
+func (h *SessionHandler) RemoveSession(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	if err := h.Service.RemoveSession(c.Request.Context(), meta.UserID, c.Param("sessionId")); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "Session not found", "No active session found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to remove session", err)
+		return
+	}
+
+	httputil.Success(c, "Session removed successfully", nil)
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/basepath"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/signedurl"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// This struct defines the SignedURLHandler which issues the signed download URLs.
+// It contains the signer of the URLs, which are then verified by the SignedURL middleware.
+type SignedURLHandler struct {
+	Signer *signedurl.Signer
+}
+
+// NewSignedURLHandler creates a new instance of SignedURLHandler.
+// It initializes the SignedURLHandler struct with the provided signer.
+func NewSignedURLHandler(signer *signedurl.Signer) *SignedURLHandler {
+	return &SignedURLHandler{Signer: signer}
+}
+
+// Issue returns the handler issuing a signed URL of the given download route to the authenticated user.
+// The URL carries the base path of the service, and the identity of the user, whose roles are checked again on the download.
+// @Summary      Issue a signed download URL
+// @Description  Issue a time-limited URL downloading without the access token, for the browsers and the download managers
+// @Tags         consumers
+// @Produce      json
+// @Success      201  {object}  model.HttpResponse for successful issuance
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /consumers/stream/signed-url [post]
+func (h *SignedURLHandler) Issue(path string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+		if !ok {
+			httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+			return
+		}
+
+		url, expiresAt := h.Signer.SignForUser(path, meta)
+		httputil.Created(c, "Signed URL issued successfully", entity.SignedURLResponse{URL: basepath.Join(url), ExpiresAt: expiresAt})
+	}
+}
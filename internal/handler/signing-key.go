@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	jwtutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
+)
+
+// JWKSCacheControl is the Cache-Control header of the key set, so that the validating services do not fetch it on every token.
+const JWKSCacheControl = "public, max-age=300"
+
+// This struct defines the SigningKeyHandler which publishes and rotates the keys signing the RS256 tokens.
+// It contains a service field of type SigningKeyService which is used to load the key set and to rotate the key.
+type SigningKeyHandler struct {
+	Service service.SigningKeyService
+}
+
+// NewSigningKeyHandler creates a new instance of SigningKeyHandler.
+// It initializes the SigningKeyHandler struct with the provided SigningKeyService.
+func NewSigningKeyHandler(signingKeyService service.SigningKeyService) *SigningKeyHandler {
+	return &SigningKeyHandler{Service: signingKeyService}
+}
+
+// GetJWKS serves the public keys in the JSON Web Key Set format.
+// The key set is served as it is, not wrapped in the response envelope, as expected by the JWT libraries.
+// @Summary      JSON Web Key Set
+// @Description  Get the public keys verifying the access tokens, selected by the kid header of the tokens
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  jwtutil.JSONWebKeySet for successful retrieval
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /.well-known/jwks.json [get]
+func (h *SigningKeyHandler) GetJWKS(c *gin.Context) {
+	keySet, err := h.Service.GetKeySet()
+	if err != nil {
+		respondError(c, "Failed to load the public keys", err)
+		return
+	}
+
+	c.Header("Cache-Control", JWKSCacheControl)
+	c.JSON(http.StatusOK, keySet)
+}
+
+// RotateSigningKey creates a new key signing the access tokens, without restarting the service.
+// @Summary      Rotate signing key
+// @Description  Create a new key signing the access tokens, the previous key keeps verifying its tokens until they expire
+// @Tags         admin
+// @Produce      json
+// @Success      201  {object}  model.HttpResponse for successful rotation
+// @Failure      409  {object}  model.HttpResponse for key rotation disabled
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/signing-keys/rotate [post]
+func (h *SigningKeyHandler) RotateSigningKey(c *gin.Context) {
+	rotation, err := h.Service.RotateKey()
+	if err != nil {
+		if errors.Is(err, jwtutil.ErrKeyRotationDisabled) {
+			httputil.Conflict(c, "Failed to rotate signing key", err.Error())
+			return
+		}
+
+		respondError(c, "Failed to rotate signing key", err)
+		return
+	}
+
+	httputil.Created(c, "Signing key rotated successfully", rotation)
+}
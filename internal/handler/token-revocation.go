@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// This struct defines the TokenRevocationHandler which handles HTTP requests related to the revocation of the tokens.
+// It contains a service field of type TokenRevocationService which is used to revoke the tokens of the users.
+type TokenRevocationHandler struct {
+	Service service.TokenRevocationService
+}
+
+// NewTokenRevocationHandler creates a new instance of TokenRevocationHandler.
+// It initializes the TokenRevocationHandler struct with the provided TokenRevocationService.
+func NewTokenRevocationHandler(tokenRevocationService service.TokenRevocationService) *TokenRevocationHandler {
+	return &TokenRevocationHandler{Service: tokenRevocationService}
+}
+
+// RevokeUserTokens revokes all the access and refresh tokens of a user.
+// @Summary      Revoke the tokens of a user
+// @Description  Revoke all the access and refresh tokens of a user, e.g. when the account is compromised
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  model.HttpResponse for successful revocation
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for user not found
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/users/{id}/revoke-tokens [post]
+func (h *TokenRevocationHandler) RevokeUserTokens(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		httputil.BadRequest(c, "Invalid ID", "ID must be a positive integer")
+		return
+	}
+
+	revoked, err := h.Service.RevokeUserTokens(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "User not found", "No user found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to revoke tokens", err)
+		return
+	}
+
+	httputil.Success(c, "Tokens revoked successfully", revoked)
+}
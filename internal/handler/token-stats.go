@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// defaultTokenStatsDays is the number of days covered by the token statistics when no date range is given.
+const defaultTokenStatsDays = 30
+
+// This struct defines the TokenStatsHandler which handles HTTP requests related to the token usage analytics.
+// It contains a service field of type TokenUsageService which is used to interact with the token usage data layer.
+type TokenStatsHandler struct {
+	Service service.TokenUsageService
+}
+
+// NewTokenStatsHandler creates a new instance of TokenStatsHandler.
+// It initializes the TokenStatsHandler struct with the provided TokenUsageService.
+func NewTokenStatsHandler(tokenUsageService service.TokenUsageService) *TokenStatsHandler {
+	return &TokenStatsHandler{Service: tokenUsageService}
+}
+
+// GetTokenStats retrieves the number of tokens issued, refreshed, and revoked per day, user, and client.
+// @Summary      Get token usage statistics
+// @Description  Get the token usage per day, user, and client over a date range (default is the last 30 days)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        from    query     string  false "First day of the range (YYYY-MM-DD)"
+// @Param        to      query     string  false "Last day of the range (YYYY-MM-DD, default is today)"
+// @Param        userId  query     string  false "Only count the tokens of this user"
+// @Param        client  query     string  false "Only count the tokens of this client"
+// @Success      200  {object}  model.HttpResponse for successful retrieval
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/token-stats [get]
+func (h *TokenStatsHandler) GetTokenStats(c *gin.Context) {
+	filter, err := parseTokenStatsFilter(c, time.Now().UTC())
+	if err != nil {
+		httputil.BadRequest(c, "Invalid token statistics filter", err.Error())
+		return
+	}
+	if err := filter.Validate(); err != nil {
+		httputil.BadRequest(c, "Invalid date range", err.Error())
+		return
+	}
+
+	stats, err := h.Service.GetTokenStats(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, "Failed to retrieve token statistics", err)
+		return
+	}
+
+	httputil.Success(c, "Token statistics retrieved successfully", stats)
+}
+
+// parseTokenStatsFilter parses the from, to, userId, and client query parameters.
+// The range defaults to the 30 days ending today, or ending on the given to day.
+func parseTokenStatsFilter(c *gin.Context, now time.Time) (entity.TokenStatsFilter, error) {
+	filter := entity.TokenStatsFilter{
+		To:     now.Truncate(24 * time.Hour),
+		Client: c.Query("client"),
+	}
+
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return entity.TokenStatsFilter{}, fmt.Errorf("to must be a date in the YYYY-MM-DD format")
+		}
+		filter.To = t
+	}
+
+	filter.From = filter.To.AddDate(0, 0, -(defaultTokenStatsDays - 1))
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return entity.TokenStatsFilter{}, fmt.Errorf("from must be a date in the YYYY-MM-DD format")
+		}
+		filter.From = t
+	}
+
+	if userID := c.Query("userId"); userID != "" {
+		id, err := strconv.ParseInt(userID, 10, 64)
+		if err != nil || id < 1 {
+			return entity.TokenStatsFilter{}, fmt.Errorf("userId must be a positive integer")
+		}
+		filter.UserID = id
+	}
+
+	return filter, nil
+}
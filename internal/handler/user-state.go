@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/go-playground/validator.v9"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// This struct defines the UserStateHandler which handles HTTP requests related to the state of the user accounts.
+// It contains a service field of type UserStateService which is used to change the state of the users.
+type UserStateHandler struct {
+	Service service.UserStateService
+}
+
+// NewUserStateHandler creates a new instance of UserStateHandler.
+// It initializes the UserStateHandler struct with the provided UserStateService.
+func NewUserStateHandler(userStateService service.UserStateService) *UserStateHandler {
+	return &UserStateHandler{Service: userStateService}
+}
+
+// ChangeUserState changes the state of a user account.
+// @Summary      Change the state of a user
+// @Description  Activate, disable, or suspend a user account. Suspending a user also revokes all their tokens
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                   true  "User ID"
+// @Param        request  body      entity.UserStateRequest  true  "User state request"
+// @Success      200  {object}  model.HttpResponse for successful state change
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for user not found
+// @Failure      409  {object}  model.HttpResponse for invalid state transition
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/users/{id}/state [put]
+func (h *UserStateHandler) ChangeUserState(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		httputil.BadRequest(c, "Invalid ID", "ID must be a positive integer")
+		return
+	}
+
+	var req entity.UserStateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request", err.Error())
+		return
+	}
+
+	change, err := h.Service.ChangeUserState(c.Request.Context(), id, req)
+	if err != nil {
+		handleUserStateError(c, err)
+		return
+	}
+
+	httputil.Success(c, "User state changed successfully", change)
+}
+
+// handleUserStateError responds with the error of the change of state of a user account.
+func handleUserStateError(c *gin.Context, err error) {
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		httputil.BadRequestMap(c, "Failed to change user state", validation.FormatValidationErrors(err))
+		return
+	}
+
+	if errors.Is(err, service.ErrUserStateReasonRequired) {
+		httputil.BadRequest(c, "Failed to change user state", err.Error())
+		return
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		httputil.NotFound(c, "User not found", "No user found with the given ID")
+		return
+	}
+
+	if errors.Is(err, service.ErrInvalidUserStateTransition) {
+		httputil.Conflict(c, "Failed to change user state", err.Error())
+		return
+	}
+
+	respondError(c, "Failed to change user state", err)
+}
@@ -0,0 +1,305 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+)
+
+// This struct defines the UserHandler which handles HTTP requests related to the administration of the user accounts.
+// It contains a service field of type UserService which is used to list, create, update, and delete the users,
+// and the UserStateService which is used to enable, disable, and lock them, and the pagination settings capping the pages.
+type UserHandler struct {
+	Service service.UserService
+	States  service.UserStateService
+	Paging  pagination.Config
+}
+
+// NewUserHandler creates a new instance of UserHandler.
+// It initializes the UserHandler struct with the provided UserService, UserStateService, and pagination settings.
+func NewUserHandler(userService service.UserService, userStateService service.UserStateService, paging pagination.Config) *UserHandler {
+	return &UserHandler{Service: userService, States: userStateService, Paging: paging}
+}
+
+// GetAllUsers retrieves a page of the users and returns them as JSON.
+// @Summary      Get all users
+// @Description  Get a page of the users ordered by ID, the deleted users are left out
+// @Tags         users
+// @Produce      json
+// @Param        page   query     string  false "Page number (default is 1)"
+// @Param        limit  query     string  false "Number of users per page (default is 10, at most PAGINATION_MAX_LIMIT)"
+// @Success      200  {array}   model.HttpResponse for successful retrieval
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for not found
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users [get]
+func (h *UserHandler) GetAllUsers(c *gin.Context) {
+	params, perr := pagination.Parse(c, h.Paging)
+	if perr != nil {
+		httputil.BadRequest(c, perr.Message, perr.Detail)
+		return
+	}
+
+	users, err := h.Service.GetUsers(c.Request.Context(), params.Page, params.Limit)
+	if err != nil {
+		respondError(c, "Failed to retrieve users", err)
+		return
+	}
+
+	if len(users) == 0 {
+		httputil.NotFound(c, "No users found", "No users available in the database")
+		return
+	}
+
+	resp := make([]entity.UserResponse, 0, len(users))
+	for _, user := range users {
+		resp = append(resp, entity.NewUserResponse(user))
+	}
+
+	httputil.Success(c, "All users retrieved successfully", resp)
+}
+
+// GetUserByID retrieves a user by its ID and returns it as JSON.
+// @Summary      Get user by ID
+// @Description  Get a user by its ID, the deleted users are not found
+// @Tags         users
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  model.HttpResponse for successful retrieval
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for not found
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users/{id} [get]
+func (h *UserHandler) GetUserByID(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.Service.GetUserByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "User not found", "No user found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to retrieve user", err)
+		return
+	}
+
+	httputil.Success(c, "User retrieved successfully", entity.NewUserResponse(user))
+}
+
+// CreateUser creates an active user account and returns it as JSON.
+// @Summary      Create user
+// @Description  Create an active user account with the given roles (ROLE_USER by default)
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      entity.CreateUserRequest  true  "User to create"
+// @Success      201  {object}  model.HttpResponse for successful creation
+// @Failure      400  {object}  model.HttpResponse for bad request or unknown role
+// @Failure      409  {object}  model.HttpResponse for username or email already taken
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users [post]
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	var req entity.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	user, err := h.Service.CreateUserByAdmin(c.Request.Context(), req, meta.UserID)
+	if err != nil {
+		handleUserError(c, "Failed to create user", err)
+		return
+	}
+
+	httputil.Created(c, "User created successfully", entity.NewUserResponse(user))
+}
+
+// UpdateUser replaces the profile and the roles of a user and returns the updated user as JSON.
+// @Summary      Update user
+// @Description  Replace the email, the names, the user type, and the roles of a user
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                    true  "User ID"
+// @Param        request  body      entity.UpdateUserRequest  true  "New profile and roles of the user"
+// @Success      200  {object}  model.HttpResponse for successful update
+// @Failure      400  {object}  model.HttpResponse for bad request or unknown role
+// @Failure      404  {object}  model.HttpResponse for user not found
+// @Failure      409  {object}  model.HttpResponse for email already taken
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users/{id} [put]
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	var req entity.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	user, err := h.Service.UpdateUser(c.Request.Context(), id, req, meta.UserID)
+	if err != nil {
+		handleUserError(c, "Failed to update user", err)
+		return
+	}
+
+	httputil.Success(c, "User updated successfully", entity.NewUserResponse(user))
+}
+
+// UpdateUserStatus enables, disables, or locks a user account.
+// @Summary      Enable, disable, or lock user
+// @Description  Enable, disable, or lock a user account. Locking suspends the account and revokes all the tokens of the user
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                    true  "User ID"
+// @Param        request  body      entity.UserActionRequest  true  "Action and reason"
+// @Success      200  {object}  model.HttpResponse for successful state change
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for user not found
+// @Failure      409  {object}  model.HttpResponse for invalid state transition
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users/{id} [patch]
+func (h *UserHandler) UpdateUserStatus(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req entity.UserActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+	if err := req.Validate(); err != nil {
+		handleUserStateError(c, err)
+		return
+	}
+
+	change, err := h.States.ChangeUserState(c.Request.Context(), id, req.StateRequest())
+	if err != nil {
+		handleUserStateError(c, err)
+		return
+	}
+
+	httputil.Success(c, "User state changed successfully", change)
+}
+
+// DeleteUser soft-deletes a user account and revokes all the tokens of the user.
+// @Summary      Delete user
+// @Description  Soft-delete a user account and revoke all the tokens of the user, the administrators cannot delete their own account
+// @Tags         users
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  model.HttpResponse for successful deletion
+// @Failure      400  {object}  model.HttpResponse for bad request or own account
+// @Failure      404  {object}  model.HttpResponse for user not found
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users/{id} [delete]
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	if err := h.Service.DeleteUser(c.Request.Context(), id, meta.UserID); err != nil {
+		handleUserError(c, "Failed to delete user", err)
+		return
+	}
+
+	httputil.Success(c, "User deleted successfully", nil)
+}
+
+// ChangeUserRoles assigns and removes roles of a user and returns the updated user as JSON.
+// @Summary      Assign and remove user roles
+// @Description  Assign and remove roles of a user, the roles not named in the request are kept. A user must keep at least one role
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                   true  "User ID"
+// @Param        request  body      entity.UserRolesRequest  true  "Roles to assign and to remove"
+// @Success      200  {object}  model.HttpResponse for successful change
+// @Failure      400  {object}  model.HttpResponse for bad request, unknown role, or no role left
+// @Failure      404  {object}  model.HttpResponse for user not found
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /users/{id}/roles [post]
+func (h *UserHandler) ChangeUserRoles(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	if !ok {
+		httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+		return
+	}
+
+	var req entity.UserRolesRequest
+	if !bindJSONStrict(c, &req, "Invalid request body") {
+		return
+	}
+
+	user, err := h.Service.ChangeUserRoles(c.Request.Context(), id, req, meta.UserID)
+	if err != nil {
+		handleUserError(c, "Failed to change user roles", err)
+		return
+	}
+
+	httputil.Success(c, "User roles changed successfully", entity.NewUserResponse(user))
+}
+
+// parseUserID parses the ID of the user from the URL parameter, and responds with 400 if it is not a positive integer.
+func parseUserID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		httputil.BadRequest(c, "Invalid ID", "ID must be a positive integer")
+		return 0, false
+	}
+
+	return id, true
+}
+
+// handleUserError responds with the error of the creation, the update, or the deletion of a user account.
+func handleUserError(c *gin.Context, message string, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		httputil.NotFound(c, "User not found", "No user found with the given ID")
+		return
+	}
+
+	// The validation errors, the unknown roles, and the usernames or emails already taken are reported to the client
+	respondError(c, message, err)
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+)
+
+// This struct defines the WebhookHandler which handles HTTP requests related to the deliveries of the consumer webhooks.
+// It contains a service field of type WebhookService which is used to list, inspect, and redeliver the deliveries,
+// and the pagination settings capping the pages.
+type WebhookHandler struct {
+	Service service.WebhookService
+	Paging  pagination.Config
+}
+
+// NewWebhookHandler creates a new instance of WebhookHandler.
+// It initializes the WebhookHandler struct with the provided WebhookService and pagination settings.
+func NewWebhookHandler(webhookService service.WebhookService, paging pagination.Config) *WebhookHandler {
+	return &WebhookHandler{Service: webhookService, Paging: paging}
+}
+
+// GetFailedWebhookDeliveries retrieves a page of the failed and dead webhook deliveries and returns them as JSON.
+// @Summary      Get failed webhook deliveries
+// @Description  Get a page of the webhook deliveries waiting for a retry (FAILED) or dead-lettered (DEAD), the most recent first
+// @Tags         admin
+// @Produce      json
+// @Param        status query     string  false "FAILED or DEAD (default is both)"
+// @Param        page   query     string  false "Page number (default is 1)"
+// @Param        limit  query     string  false "Number of deliveries per page (default is 10, at most PAGINATION_MAX_LIMIT)"
+// @Success      200  {array}   model.HttpResponse for successful retrieval
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/webhook-deliveries [get]
+func (h *WebhookHandler) GetFailedWebhookDeliveries(c *gin.Context) {
+	params, perr := pagination.Parse(c, h.Paging)
+	if perr != nil {
+		httputil.BadRequest(c, perr.Message, perr.Detail)
+		return
+	}
+
+	deliveries, err := h.Service.GetFailedDeliveries(c.Request.Context(), strings.ToUpper(c.Query("status")), params.Page, params.Limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidWebhookStatus) {
+			httputil.BadRequest(c, "Invalid status", err.Error())
+			return
+		}
+
+		respondError(c, "Failed to retrieve webhook deliveries", err)
+		return
+	}
+
+	httputil.Success(c, "Failed webhook deliveries retrieved successfully", deliveries)
+}
+
+// GetWebhookDelivery retrieves a webhook delivery by its ID, with its payload and the last response, and returns it as JSON.
+// @Summary      Get webhook delivery
+// @Description  Get a webhook delivery with its payload and the status, the body, or the error of its last attempt
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "Delivery ID"
+// @Success      200  {object}  model.HttpResponse for successful retrieval
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for not found
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/webhook-deliveries/{id} [get]
+func (h *WebhookHandler) GetWebhookDelivery(c *gin.Context) {
+	id, ok := parseDeliveryID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.Service.GetDelivery(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.NotFound(c, "Webhook delivery not found", "No webhook delivery found with the given ID")
+			return
+		}
+
+		respondError(c, "Failed to retrieve webhook delivery", err)
+		return
+	}
+
+	httputil.Success(c, "Webhook delivery retrieved successfully", delivery)
+}
+
+// RedeliverWebhook attempts a failed or dead webhook delivery right away, and returns it with the outcome of the attempt as JSON.
+// @Summary      Redeliver webhook
+// @Description  Attempt a failed or dead webhook delivery right away. If it fails again, it is retried with the backoff before it is dead-lettered again
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "Delivery ID"
+// @Success      200  {object}  model.HttpResponse for the attempt, which may have failed again
+// @Failure      400  {object}  model.HttpResponse for bad request
+// @Failure      404  {object}  model.HttpResponse for not found
+// @Failure      409  {object}  model.HttpResponse for a delivery that did not fail
+// @Failure      500  {object}  model.HttpResponse for internal server error
+// @Router       /admin/webhook-deliveries/{id}/redeliver [post]
+func (h *WebhookHandler) RedeliverWebhook(c *gin.Context) {
+	id, ok := parseDeliveryID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.Service.Redeliver(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			httputil.NotFound(c, "Webhook delivery not found", "No webhook delivery found with the given ID")
+		case errors.Is(err, service.ErrWebhookNotRedeliverable):
+			httputil.Conflict(c, "Failed to redeliver webhook", err.Error())
+		default:
+			respondError(c, "Failed to redeliver webhook", err)
+		}
+		return
+	}
+
+	httputil.Success(c, "Webhook delivery attempted", delivery)
+}
+
+// parseDeliveryID parses the ID of the webhook delivery from the URL parameter, and responds with 400 if it is not a positive integer.
+func parseDeliveryID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		httputil.BadRequest(c, "Invalid ID", "ID must be a positive integer")
+		return 0, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=api-key.go -destination=../../tests/mocks/api-key-repository.go -package=mocks
+
+// Interface for API key repository
+// This interface defines the methods that the API key repository should implement
+type ApiKeyRepository interface {
+	CreateApiKey(tx *gorm.DB, key entity.ApiKey) (entity.ApiKey, error)
+	GetApiKeyByID(tx *gorm.DB, id int64) (entity.ApiKey, error)
+	GetApiKeyByHash(tx *gorm.DB, keyHash string) (entity.ApiKey, error)
+	GetApiKeysByUserID(tx *gorm.DB, userID int64) ([]entity.ApiKey, error)
+	RevokeApiKey(tx *gorm.DB, id int64, revokedAt time.Time) (bool, error)
+}
+
+// This struct defines the ApiKeyRepository that contains methods for interacting with the database
+// It implements the ApiKeyRepository interface and provides methods for API key-related operations
+type apiKeyRepository struct{}
+
+// NewApiKeyRepository creates a new instance of ApiKeyRepository.
+// It initializes the apiKeyRepository struct and returns it.
+func NewApiKeyRepository() ApiKeyRepository {
+	return &apiKeyRepository{}
+}
+
+// CreateApiKey inserts a new API key into the database.
+func (r *apiKeyRepository) CreateApiKey(tx *gorm.DB, key entity.ApiKey) (entity.ApiKey, error) {
+	if err := tx.Create(&key).Error; err != nil {
+		return entity.ApiKey{}, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetApiKeyByID retrieves an API key by its ID from the database.
+func (r *apiKeyRepository) GetApiKeyByID(tx *gorm.DB, id int64) (entity.ApiKey, error) {
+	var key entity.ApiKey
+	if err := tx.First(&key, "id = ?", id).Error; err != nil {
+		return entity.ApiKey{}, err
+	}
+
+	return key, nil
+}
+
+// GetApiKeyByHash retrieves an API key by the hash of the key from the database.
+func (r *apiKeyRepository) GetApiKeyByHash(tx *gorm.DB, keyHash string) (entity.ApiKey, error) {
+	var key entity.ApiKey
+	if err := tx.First(&key, "key_hash = ?", keyHash).Error; err != nil {
+		return entity.ApiKey{}, err
+	}
+
+	return key, nil
+}
+
+// GetApiKeysByUserID retrieves the API keys of the user from the database, oldest first, the revoked ones included.
+func (r *apiKeyRepository) GetApiKeysByUserID(tx *gorm.DB, userID int64) ([]entity.ApiKey, error) {
+	var keys []entity.ApiKey
+	if err := tx.Order("id ASC").Find(&keys, "user_id = ?", userID).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve API keys of user %d: %w", userID, err)
+	}
+
+	return keys, nil
+}
+
+// RevokeApiKey records the time the API key was revoked, if it was not revoked yet.
+// It returns false if the key does not exist or was already revoked, e.g. by a concurrent request.
+func (r *apiKeyRepository) RevokeApiKey(tx *gorm.DB, id int64, revokedAt time.Time) (bool, error) {
+	result := tx.Model(&entity.ApiKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to revoke API key %d: %w", id, result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=audit-event.go -destination=../../tests/mocks/audit-event-repository.go -package=mocks
+
+// Interface for audit event repository
+// This interface defines the methods that the audit event repository should implement
+type AuditEventRepository interface {
+	CreateAuditEvent(tx *gorm.DB, event entity.AuditEvent) (entity.AuditEvent, error)
+	GetAuditEvents(tx *gorm.DB, filter entity.AuditEventFilter, page int, limit int) ([]entity.AuditEvent, error)
+}
+
+// This struct defines the AuditEventRepository that contains methods for interacting with the database
+// It implements the AuditEventRepository interface and provides methods for audit event-related operations
+type auditEventRepository struct{}
+
+// NewAuditEventRepository creates a new instance of AuditEventRepository.
+// It initializes the auditEventRepository struct and returns it.
+func NewAuditEventRepository() AuditEventRepository {
+	return &auditEventRepository{}
+}
+
+// CreateAuditEvent inserts a new audit event into the database.
+// An event whose event ID is already recorded is not inserted again, so that writing an event again is safe.
+func (r *auditEventRepository) CreateAuditEvent(tx *gorm.DB, event entity.AuditEvent) (entity.AuditEvent, error) {
+	if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "event_id"}}, DoNothing: true}).Create(&event).Error; err != nil {
+		return entity.AuditEvent{}, fmt.Errorf("failed to create audit event: %w", err)
+	}
+
+	return event, nil
+}
+
+// GetAuditEvents retrieves a page of the audit events meeting the criteria of the filter, the most recent first.
+func (r *auditEventRepository) GetAuditEvents(tx *gorm.DB, filter entity.AuditEventFilter, page int, limit int) ([]entity.AuditEvent, error) {
+	query := tx.Model(&entity.AuditEvent{})
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var events []entity.AuditEvent
+	err := query.Order("id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
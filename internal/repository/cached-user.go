@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+)
+
+/**
+ * The cached user repository puts a short-TTL cache in front of GetUserByUsername,
+ * which is hit on every login, to protect the database during login storms and credential stuffing.
+ * Found users are cached (positive entries), and so are unknown usernames (negative entries),
+ * so repeated attempts with the same username do not reach the database until the entry expires.
+ * The entries of a user are invalidated when it is updated, its roles change, or its tokens are revoked through the repository,
+ * once the transaction of the change is committed (see database.AfterCommit): invalidated before, the entry could be filled again
+ * by a login reading the user before the commit. A lookup started before an invalidation does not fill the cache either.
+ * Last login updates do not invalidate them, so a cached user may carry an older last login time.
+ * The hit rate is published with expvar as "user_lookup_cache".
+ */
+
+// defaultUserCacheSize is the maximum number of usernames whose lookup result is cached.
+const defaultUserCacheSize = 10000
+
+// UserCacheStats holds the counters of the user lookup cache.
+type UserCacheStats struct {
+	Hits          uint64  `json:"hits"`
+	NegativeHits  uint64  `json:"negativeHits"`
+	Misses        uint64  `json:"misses"`
+	Invalidations uint64  `json:"invalidations"`
+	HitRate       float64 `json:"hitRate"`
+}
+
+// userCacheCounters holds the counters of all cached user repositories of the process.
+var userCacheCounters struct {
+	hits          atomic.Uint64
+	negativeHits  atomic.Uint64
+	misses        atomic.Uint64
+	invalidations atomic.Uint64
+}
+
+func init() {
+	expvar.Publish("user_lookup_cache", expvar.Func(func() interface{} {
+		return GetUserCacheStats()
+	}))
+}
+
+// GetUserCacheStats returns the counters of the user lookup cache.
+// The hit rate is the share of lookups answered from the cache, negative hits included.
+func GetUserCacheStats() UserCacheStats {
+	stats := UserCacheStats{
+		Hits:          userCacheCounters.hits.Load(),
+		NegativeHits:  userCacheCounters.negativeHits.Load(),
+		Misses:        userCacheCounters.misses.Load(),
+		Invalidations: userCacheCounters.invalidations.Load(),
+	}
+
+	if total := stats.Hits + stats.NegativeHits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits+stats.NegativeHits) / float64(total)
+	}
+
+	return stats
+}
+
+// userCacheEntry holds the cached lookup result of a username.
+// A negative entry records that no user has the username.
+type userCacheEntry struct {
+	user      entity.User
+	negative  bool
+	expiresAt time.Time
+}
+
+// This struct defines the cached UserRepository that wraps another UserRepository
+// It implements the UserRepository interface; only GetUserByUsername is cached
+type cachedUserRepository struct {
+	UserRepository
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	clock   clock.Clock
+	entries map[string]userCacheEntry
+
+	// generation is incremented by every invalidation, a lookup caches its result only if none happened since it started
+	generation uint64
+}
+
+// NewCachedUserRepository creates a new instance of UserRepository that caches the username lookups
+// of the given repository for the given TTL. It returns the repository itself if the TTL is not positive.
+func NewCachedUserRepository(repo UserRepository, ttl time.Duration, clk clock.Clock) UserRepository {
+	if ttl <= 0 {
+		return repo
+	}
+
+	return &cachedUserRepository{
+		UserRepository: repo,
+		ttl:            ttl,
+		maxSize:        defaultUserCacheSize,
+		clock:          clk,
+		entries:        make(map[string]userCacheEntry),
+	}
+}
+
+// GetUserByUsername retrieves a user by their username from the cache, or from the wrapped repository.
+// Only the "record not found" error is cached; other errors are returned without caching.
+func (r *cachedUserRepository) GetUserByUsername(ctx context.Context, tx *gorm.DB, username string) (entity.User, error) {
+	key := strings.ToLower(username)
+
+	if entry, ok := r.get(key); ok {
+		if entry.negative {
+			userCacheCounters.negativeHits.Add(1)
+			return entity.User{}, gorm.ErrRecordNotFound
+		}
+		userCacheCounters.hits.Add(1)
+		return cloneUser(entry.user), nil
+	}
+	userCacheCounters.misses.Add(1)
+	generation := r.currentGeneration()
+
+	user, err := r.UserRepository.GetUserByUsername(ctx, tx, username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		r.set(key, userCacheEntry{negative: true}, generation)
+		return entity.User{}, err
+	}
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	r.set(key, userCacheEntry{user: cloneUser(user)}, generation)
+
+	return user, nil
+}
+
+// CreateUser creates the user in the wrapped repository and invalidates the cached entry of its username,
+// which may be a negative entry left by a login attempt before the user existed.
+func (r *cachedUserRepository) CreateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error) {
+	created, err := r.UserRepository.CreateUser(ctx, tx, user)
+	r.invalidateAfterCommit(tx, created.ID, user.Username)
+
+	return created, err
+}
+
+// UpdateUser updates the user in the wrapped repository and invalidates its cached entries.
+func (r *cachedUserRepository) UpdateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error) {
+	updated, err := r.UserRepository.UpdateUser(ctx, tx, user)
+
+	// Invalidate even on failure, since the update may have been partially applied
+	r.invalidateAfterCommit(tx, user.ID, user.Username)
+
+	return updated, err
+}
+
+// UpdatePassword sets the password hash of the user in the wrapped repository and invalidates its cached entries,
+// so that the next logins check the new password.
+func (r *cachedUserRepository) UpdatePassword(ctx context.Context, tx *gorm.DB, id int64, password string, credentialsExpiresAt *time.Time) error {
+	err := r.UserRepository.UpdatePassword(ctx, tx, id, password, credentialsExpiresAt)
+	r.invalidateAfterCommit(tx, id, "")
+
+	return err
+}
+
+// UpdateUserState sets the state of the user in the wrapped repository and invalidates its cached entries,
+// so that the next logins see the new state.
+func (r *cachedUserRepository) UpdateUserState(ctx context.Context, tx *gorm.DB, id int64, state entity.UserState, reason *string, changedAt time.Time) error {
+	err := r.UserRepository.UpdateUserState(ctx, tx, id, state, reason, changedAt)
+	r.invalidateAfterCommit(tx, id, "")
+
+	return err
+}
+
+// SoftDeleteUser marks the user as deleted in the wrapped repository and invalidates its cached entries,
+// so that the user cannot log in anymore.
+func (r *cachedUserRepository) SoftDeleteUser(ctx context.Context, tx *gorm.DB, id int64, deletedBy int64, deletedAt time.Time) error {
+	err := r.UserRepository.SoftDeleteUser(ctx, tx, id, deletedBy, deletedAt)
+	r.invalidateAfterCommit(tx, id, "")
+
+	return err
+}
+
+// IncrementTokenVersion bumps the token version of the user in the wrapped repository and invalidates its cached entries,
+// so that the next logins issue tokens with the new version.
+func (r *cachedUserRepository) IncrementTokenVersion(ctx context.Context, tx *gorm.DB, id int64) (int64, error) {
+	version, err := r.UserRepository.IncrementTokenVersion(ctx, tx, id)
+	r.invalidateAfterCommit(tx, id, "")
+
+	return version, err
+}
+
+// ReplaceUserRoles replaces the roles of the user in the wrapped repository and invalidates its cached entries.
+func (r *cachedUserRepository) ReplaceUserRoles(ctx context.Context, tx *gorm.DB, userID int64, roles []entity.Role) error {
+	err := r.UserRepository.ReplaceUserRoles(ctx, tx, userID, roles)
+	r.invalidateAfterCommit(tx, userID, "")
+
+	return err
+}
+
+// WarmRoleCache pre-fills the role cache of the wrapped repository, if it has one.
+func (r *cachedUserRepository) WarmRoleCache(tx *gorm.DB, limit int) (int, error) {
+	if warmer, ok := r.UserRepository.(RoleCacheWarmer); ok {
+		return warmer.WarmRoleCache(tx, limit)
+	}
+
+	return 0, nil
+}
+
+// InvalidateRoleCache removes all the cached users, which carry their roles,
+// and the role cache of the wrapped repository, if it has one.
+func (r *cachedUserRepository) InvalidateRoleCache() {
+	r.mu.Lock()
+	r.entries = make(map[string]userCacheEntry)
+	r.generation++
+	r.mu.Unlock()
+
+	if invalidator, ok := r.UserRepository.(RoleCacheInvalidator); ok {
+		invalidator.InvalidateRoleCache()
+	}
+}
+
+// get returns the cached entry of the username, if present and not expired.
+func (r *cachedUserRepository) get(key string) (userCacheEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[key]
+	if !ok {
+		return userCacheEntry{}, false
+	}
+	if !r.clock.Now().Before(entry.expiresAt) {
+		delete(r.entries, key)
+		return userCacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+// currentGeneration returns the number of invalidations so far.
+func (r *cachedUserRepository) currentGeneration() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.generation
+}
+
+// set caches the entry of the username, read by a lookup started at the given generation.
+// The entry is not cached if an invalidation happened since, the lookup may have read the user before the change.
+// When the cache is full, the expired entries are evicted first; if it is still full, the entry is not cached.
+func (r *cachedUserRepository) set(key string, entry userCacheEntry, generation uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if generation != r.generation {
+		return
+	}
+
+	now := r.clock.Now()
+	if _, exists := r.entries[key]; !exists && len(r.entries) >= r.maxSize {
+		for k, e := range r.entries {
+			if !now.Before(e.expiresAt) {
+				delete(r.entries, k)
+			}
+		}
+		if len(r.entries) >= r.maxSize {
+			return
+		}
+	}
+
+	entry.expiresAt = now.Add(r.ttl)
+	r.entries[key] = entry
+}
+
+// invalidateAfterCommit invalidates the cached entries of the user once the transaction of tx is committed.
+func (r *cachedUserRepository) invalidateAfterCommit(tx *gorm.DB, userID int64, username string) {
+	database.AfterCommit(tx, func() { r.invalidate(userID, username) })
+}
+
+// invalidate removes the cached entries of the user with the given ID,
+// and the entry of the given username (e.g. a negative entry for a new username).
+func (r *cachedUserRepository) invalidate(userID int64, username string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, entry := range r.entries {
+		if !entry.negative && entry.user.ID == userID {
+			delete(r.entries, key)
+		}
+	}
+	if username != "" {
+		delete(r.entries, strings.ToLower(username))
+	}
+	r.generation++
+
+	userCacheCounters.invalidations.Add(1)
+}
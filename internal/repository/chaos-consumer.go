@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/chaos"
+)
+
+// This struct defines the chaos ConsumerRepository that wraps another ConsumerRepository
+// It implements the ConsumerRepository interface; each call runs the fault of the injector before the wrapped repository
+type chaosConsumerRepository struct {
+	ConsumerRepository
+	injector *chaos.Injector
+}
+
+// NewChaosConsumerRepository creates a new instance of ConsumerRepository injecting the faults of the given injector into the calls to the given repository,
+// for the resilience tests. It returns the repository itself if the injector is nil, i.e. the injection is disabled.
+func NewChaosConsumerRepository(repo ConsumerRepository, injector *chaos.Injector) ConsumerRepository {
+	if injector == nil {
+		return repo
+	}
+
+	return &chaosConsumerRepository{ConsumerRepository: repo, injector: injector}
+}
+
+// GetAllConsumers retrieves the consumers from the wrapped repository, after the fault of the injector.
+func (r *chaosConsumerRepository) GetAllConsumers(ctx context.Context, tx *gorm.DB, page int, limit int) ([]entity.Consumer, error) {
+	if err := r.injector.Inject(ctx, "GetAllConsumers"); err != nil {
+		return nil, err
+	}
+
+	return r.ConsumerRepository.GetAllConsumers(ctx, tx, page, limit)
+}
+
+// GetConsumerByID retrieves a consumer by its ID from the wrapped repository, after the fault of the injector.
+func (r *chaosConsumerRepository) GetConsumerByID(ctx context.Context, tx *gorm.DB, id string) (entity.Consumer, error) {
+	if err := r.injector.Inject(ctx, "GetConsumerByID"); err != nil {
+		return entity.Consumer{}, err
+	}
+
+	return r.ConsumerRepository.GetConsumerByID(ctx, tx, id)
+}
+
+// GetConsumerByUsername retrieves a consumer by its username from the wrapped repository, after the fault of the injector.
+func (r *chaosConsumerRepository) GetConsumerByUsername(ctx context.Context, tx *gorm.DB, username string) (entity.Consumer, error) {
+	if err := r.injector.Inject(ctx, "GetConsumerByUsername"); err != nil {
+		return entity.Consumer{}, err
+	}
+
+	return r.ConsumerRepository.GetConsumerByUsername(ctx, tx, username)
+}
+
+// GetConsumerByEmail retrieves a consumer by its email from the wrapped repository, after the fault of the injector.
+func (r *chaosConsumerRepository) GetConsumerByEmail(ctx context.Context, tx *gorm.DB, email string) (entity.Consumer, error) {
+	if err := r.injector.Inject(ctx, "GetConsumerByEmail"); err != nil {
+		return entity.Consumer{}, err
+	}
+
+	return r.ConsumerRepository.GetConsumerByEmail(ctx, tx, email)
+}
+
+// GetConsumerByPhone retrieves a consumer by its phone from the wrapped repository, after the fault of the injector.
+func (r *chaosConsumerRepository) GetConsumerByPhone(ctx context.Context, tx *gorm.DB, phone string) (entity.Consumer, error) {
+	if err := r.injector.Inject(ctx, "GetConsumerByPhone"); err != nil {
+		return entity.Consumer{}, err
+	}
+
+	return r.ConsumerRepository.GetConsumerByPhone(ctx, tx, phone)
+}
+
+// GetConsumersByStatus retrieves the consumers with the given status from the wrapped repository, after the fault of the injector.
+func (r *chaosConsumerRepository) GetConsumersByStatus(ctx context.Context, tx *gorm.DB, status string, page int, limit int) ([]entity.Consumer, error) {
+	if err := r.injector.Inject(ctx, "GetConsumersByStatus"); err != nil {
+		return nil, err
+	}
+
+	return r.ConsumerRepository.GetConsumersByStatus(ctx, tx, status, page, limit)
+}
+
+// GetConsumersExcludingStatuses retrieves the consumers without the given statuses from the wrapped repository, after the fault of the injector.
+func (r *chaosConsumerRepository) GetConsumersExcludingStatuses(ctx context.Context, tx *gorm.DB, statuses []string, page int, limit int) ([]entity.Consumer, error) {
+	if err := r.injector.Inject(ctx, "GetConsumersExcludingStatuses"); err != nil {
+		return nil, err
+	}
+
+	return r.ConsumerRepository.GetConsumersExcludingStatuses(ctx, tx, statuses, page, limit)
+}
+
+// StreamConsumers streams the consumers without the given statuses from the wrapped repository, after the fault of the injector.
+func (r *chaosConsumerRepository) StreamConsumers(ctx context.Context, tx *gorm.DB, excludedStatuses []string, fn func(entity.Consumer) error) error {
+	if err := r.injector.Inject(ctx, "StreamConsumers"); err != nil {
+		return err
+	}
+
+	return r.ConsumerRepository.StreamConsumers(ctx, tx, excludedStatuses, fn)
+}
+
+// CreateConsumer creates the consumer in the wrapped repository, after the fault of the injector.
+func (r *chaosConsumerRepository) CreateConsumer(ctx context.Context, tx *gorm.DB, d entity.Consumer) (entity.Consumer, error) {
+	if err := r.injector.Inject(ctx, "CreateConsumer"); err != nil {
+		return entity.Consumer{}, err
+	}
+
+	return r.ConsumerRepository.CreateConsumer(ctx, tx, d)
+}
+
+// UpdateConsumer updates the consumer in the wrapped repository, after the fault of the injector.
+func (r *chaosConsumerRepository) UpdateConsumer(ctx context.Context, tx *gorm.DB, d entity.Consumer) (entity.Consumer, error) {
+	if err := r.injector.Inject(ctx, "UpdateConsumer"); err != nil {
+		return entity.Consumer{}, err
+	}
+
+	return r.ConsumerRepository.UpdateConsumer(ctx, tx, d)
+}
+
+// SoftDeleteConsumer soft deletes the consumer in the wrapped repository, after the fault of the injector.
+func (r *chaosConsumerRepository) SoftDeleteConsumer(ctx context.Context, tx *gorm.DB, id string, deletedAt time.Time) error {
+	if err := r.injector.Inject(ctx, "SoftDeleteConsumer"); err != nil {
+		return err
+	}
+
+	return r.ConsumerRepository.SoftDeleteConsumer(ctx, tx, id, deletedAt)
+}
+
+// RestoreConsumer restores the consumer in the wrapped repository, after the fault of the injector.
+func (r *chaosConsumerRepository) RestoreConsumer(ctx context.Context, tx *gorm.DB, id string) error {
+	if err := r.injector.Inject(ctx, "RestoreConsumer"); err != nil {
+		return err
+	}
+
+	return r.ConsumerRepository.RestoreConsumer(ctx, tx, id)
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/chaos"
+)
+
+// This struct defines the chaos UserRepository that wraps another UserRepository
+// It implements the UserRepository interface; each call runs the fault of the injector before the wrapped repository
+type chaosUserRepository struct {
+	UserRepository
+	injector *chaos.Injector
+}
+
+// NewChaosUserRepository creates a new instance of UserRepository injecting the faults of the given injector into the calls to the given repository,
+// for the resilience tests. It returns the repository itself if the injector is nil, i.e. the injection is disabled.
+func NewChaosUserRepository(repo UserRepository, injector *chaos.Injector) UserRepository {
+	if injector == nil {
+		return repo
+	}
+
+	return &chaosUserRepository{UserRepository: repo, injector: injector}
+}
+
+// GetUsers retrieves the users from the wrapped repository, after the fault of the injector.
+func (r *chaosUserRepository) GetUsers(ctx context.Context, tx *gorm.DB, page int, limit int) ([]entity.User, error) {
+	if err := r.injector.Inject(ctx, "GetUsers"); err != nil {
+		return nil, err
+	}
+
+	return r.UserRepository.GetUsers(ctx, tx, page, limit)
+}
+
+// GetUserByID retrieves a user by its ID from the wrapped repository, after the fault of the injector.
+func (r *chaosUserRepository) GetUserByID(ctx context.Context, tx *gorm.DB, id int64) (entity.User, error) {
+	if err := r.injector.Inject(ctx, "GetUserByID"); err != nil {
+		return entity.User{}, err
+	}
+
+	return r.UserRepository.GetUserByID(ctx, tx, id)
+}
+
+// GetUserByUsername retrieves a user by its username from the wrapped repository, after the fault of the injector.
+func (r *chaosUserRepository) GetUserByUsername(ctx context.Context, tx *gorm.DB, username string) (entity.User, error) {
+	if err := r.injector.Inject(ctx, "GetUserByUsername"); err != nil {
+		return entity.User{}, err
+	}
+
+	return r.UserRepository.GetUserByUsername(ctx, tx, username)
+}
+
+// GetUserByEmail retrieves a user by its email from the wrapped repository, after the fault of the injector.
+func (r *chaosUserRepository) GetUserByEmail(ctx context.Context, tx *gorm.DB, email string) (entity.User, error) {
+	if err := r.injector.Inject(ctx, "GetUserByEmail"); err != nil {
+		return entity.User{}, err
+	}
+
+	return r.UserRepository.GetUserByEmail(ctx, tx, email)
+}
+
+// CreateUser creates the user in the wrapped repository, after the fault of the injector.
+func (r *chaosUserRepository) CreateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error) {
+	if err := r.injector.Inject(ctx, "CreateUser"); err != nil {
+		return entity.User{}, err
+	}
+
+	return r.UserRepository.CreateUser(ctx, tx, user)
+}
+
+// UpdateUser updates the user in the wrapped repository, after the fault of the injector.
+func (r *chaosUserRepository) UpdateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error) {
+	if err := r.injector.Inject(ctx, "UpdateUser"); err != nil {
+		return entity.User{}, err
+	}
+
+	return r.UserRepository.UpdateUser(ctx, tx, user)
+}
+
+// UpdateLastLogin updates the last login time of a user in the wrapped repository, after the fault of the injector.
+func (r *chaosUserRepository) UpdateLastLogin(ctx context.Context, tx *gorm.DB, id int64, lastLogin time.Time) error {
+	if err := r.injector.Inject(ctx, "UpdateLastLogin"); err != nil {
+		return err
+	}
+
+	return r.UserRepository.UpdateLastLogin(ctx, tx, id, lastLogin)
+}
+
+// UpdatePassword updates the password of a user in the wrapped repository, after the fault of the injector.
+func (r *chaosUserRepository) UpdatePassword(ctx context.Context, tx *gorm.DB, id int64, password string, credentialsExpiresAt *time.Time) error {
+	if err := r.injector.Inject(ctx, "UpdatePassword"); err != nil {
+		return err
+	}
+
+	return r.UserRepository.UpdatePassword(ctx, tx, id, password, credentialsExpiresAt)
+}
+
+// UpdateUserState updates the state of a user in the wrapped repository, after the fault of the injector.
+func (r *chaosUserRepository) UpdateUserState(ctx context.Context, tx *gorm.DB, id int64, state entity.UserState, reason *string, changedAt time.Time) error {
+	if err := r.injector.Inject(ctx, "UpdateUserState"); err != nil {
+		return err
+	}
+
+	return r.UserRepository.UpdateUserState(ctx, tx, id, state, reason, changedAt)
+}
+
+// SoftDeleteUser soft deletes a user in the wrapped repository, after the fault of the injector.
+func (r *chaosUserRepository) SoftDeleteUser(ctx context.Context, tx *gorm.DB, id int64, deletedBy int64, deletedAt time.Time) error {
+	if err := r.injector.Inject(ctx, "SoftDeleteUser"); err != nil {
+		return err
+	}
+
+	return r.UserRepository.SoftDeleteUser(ctx, tx, id, deletedBy, deletedAt)
+}
+
+// IncrementTokenVersion increments the token version of a user in the wrapped repository, after the fault of the injector.
+func (r *chaosUserRepository) IncrementTokenVersion(ctx context.Context, tx *gorm.DB, id int64) (int64, error) {
+	if err := r.injector.Inject(ctx, "IncrementTokenVersion"); err != nil {
+		return 0, err
+	}
+
+	return r.UserRepository.IncrementTokenVersion(ctx, tx, id)
+}
+
+// GetRevokedTokenVersions retrieves the token versions of the users from the wrapped repository, after the fault of the injector.
+func (r *chaosUserRepository) GetRevokedTokenVersions(ctx context.Context, tx *gorm.DB) (map[int64]int64, error) {
+	if err := r.injector.Inject(ctx, "GetRevokedTokenVersions"); err != nil {
+		return nil, err
+	}
+
+	return r.UserRepository.GetRevokedTokenVersions(ctx, tx)
+}
+
+// ReplaceUserRoles replaces the roles of a user in the wrapped repository, after the fault of the injector.
+func (r *chaosUserRepository) ReplaceUserRoles(ctx context.Context, tx *gorm.DB, userID int64, roles []entity.Role) error {
+	if err := r.injector.Inject(ctx, "ReplaceUserRoles"); err != nil {
+		return err
+	}
+
+	return r.UserRepository.ReplaceUserRoles(ctx, tx, userID, roles)
+}
+
+// WarmRoleCache pre-fills the role cache of the wrapped repository, if it has one.
+func (r *chaosUserRepository) WarmRoleCache(tx *gorm.DB, limit int) (int, error) {
+	if warmer, ok := r.UserRepository.(RoleCacheWarmer); ok {
+		return warmer.WarmRoleCache(tx, limit)
+	}
+
+	return 0, nil
+}
+
+// InvalidateRoleCache removes the role cache of the wrapped repository, if it has one.
+func (r *chaosUserRepository) InvalidateRoleCache() {
+	if invalidator, ok := r.UserRepository.(RoleCacheInvalidator); ok {
+		invalidator.InvalidateRoleCache()
+	}
+}
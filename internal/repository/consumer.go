@@ -1,24 +1,35 @@
 package repository
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm" // Import GORM for ORM functionalities
 
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
 )
 
+//go:generate go tool mockgen -source=consumer.go -destination=../../tests/mocks/consumer-repository.go -package=mocks
+
 // Interface for consumer repository
 // This interface defines the methods that the consumer repository should implement
+// The soft-deleted consumers are left out of the lookups by ID and of the listings, unless the given tx is unscoped (tx.Unscoped()),
+// and are always found by the lookups by username, email, and phone, which are unique over all the consumers
 type ConsumerRepository interface {
-	GetAllConsumers(tx *gorm.DB, page int, limit int) ([]entity.Consumer, error)
-	GetConsumerByID(tx *gorm.DB, id string) (entity.Consumer, error)
-	GetConsumerByUsername(tx *gorm.DB, username string) (entity.Consumer, error)
-	GetConsumerByEmail(tx *gorm.DB, email string) (entity.Consumer, error)
-	GetConsumerByPhone(tx *gorm.DB, phone string) (entity.Consumer, error)
-	GetConsumersByStatus(tx *gorm.DB, status string, page int, limit int) ([]entity.Consumer, error)
-	CreateConsumer(tx *gorm.DB, d entity.Consumer) (entity.Consumer, error)
-	UpdateConsumer(tx *gorm.DB, d entity.Consumer) (entity.Consumer, error)
+	GetAllConsumers(ctx context.Context, tx *gorm.DB, page int, limit int) ([]entity.Consumer, error)
+	GetConsumerByID(ctx context.Context, tx *gorm.DB, id string) (entity.Consumer, error)
+	GetConsumerByUsername(ctx context.Context, tx *gorm.DB, username string) (entity.Consumer, error)
+	GetConsumerByEmail(ctx context.Context, tx *gorm.DB, email string) (entity.Consumer, error)
+	GetConsumerByPhone(ctx context.Context, tx *gorm.DB, phone string) (entity.Consumer, error)
+	GetConsumersByStatus(ctx context.Context, tx *gorm.DB, status string, page int, limit int) ([]entity.Consumer, error)
+	GetConsumersExcludingStatuses(ctx context.Context, tx *gorm.DB, statuses []string, page int, limit int) ([]entity.Consumer, error)
+	StreamConsumers(ctx context.Context, tx *gorm.DB, excludedStatuses []string, fn func(entity.Consumer) error) error
+	CreateConsumer(ctx context.Context, tx *gorm.DB, d entity.Consumer) (entity.Consumer, error)
+	UpdateConsumer(ctx context.Context, tx *gorm.DB, d entity.Consumer) (entity.Consumer, error)
+	SoftDeleteConsumer(ctx context.Context, tx *gorm.DB, id string, deletedAt time.Time) error
+	RestoreConsumer(ctx context.Context, tx *gorm.DB, id string) error
 }
 
 // This struct defines the consumerRepository that implements the ConsumerRepository interface.
@@ -31,10 +42,10 @@ func NewConsumerRepository() ConsumerRepository {
 	return &consumerRepository{}
 }
 
-// GetAllConsumers retrieves all consumers from the database.
-func (r *consumerRepository) GetAllConsumers(tx *gorm.DB, page int, limit int) ([]entity.Consumer, error) {
+// GetAllConsumers retrieves all consumers from the database, from a read replica if any.
+func (r *consumerRepository) GetAllConsumers(ctx context.Context, tx *gorm.DB, page int, limit int) ([]entity.Consumer, error) {
 	var consumers []entity.Consumer
-	err := tx.Order("created_at ASC").
+	err := database.ReadReplicas(tx).WithContext(ctx).Order("created_at ASC").
 		Offset((page - 1) * limit).
 		Limit(limit).
 		Find(&consumers).Error
@@ -47,9 +58,9 @@ func (r *consumerRepository) GetAllConsumers(tx *gorm.DB, page int, limit int) (
 }
 
 // It returns a single consumer by its ID from the database.
-func (r *consumerRepository) GetConsumerByID(tx *gorm.DB, id string) (entity.Consumer, error) {
+func (r *consumerRepository) GetConsumerByID(ctx context.Context, tx *gorm.DB, id string) (entity.Consumer, error) {
 	var consumer entity.Consumer
-	err := tx.First(&consumer, "id = ?", id).Error
+	err := tx.WithContext(ctx).First(&consumer, "id = ?", id).Error
 
 	if err != nil {
 		return entity.Consumer{}, err
@@ -58,10 +69,10 @@ func (r *consumerRepository) GetConsumerByID(tx *gorm.DB, id string) (entity.Con
 	return consumer, nil
 }
 
-// GetConsumerByEmail retrieves a consumer by their email from the database.
-func (r *consumerRepository) GetConsumerByUsername(tx *gorm.DB, username string) (entity.Consumer, error) {
+// GetConsumerByUsername retrieves a consumer by their username (case-insensitive) from the database, deleted or not.
+func (r *consumerRepository) GetConsumerByUsername(ctx context.Context, tx *gorm.DB, username string) (entity.Consumer, error) {
 	var consumer entity.Consumer
-	err := tx.First(&consumer, "lower(username) = lower(?)", username).Error
+	err := tx.WithContext(ctx).Unscoped().First(&consumer, "lower(username) = lower(?)", username).Error
 
 	if err != nil {
 		return entity.Consumer{}, err
@@ -70,10 +81,10 @@ func (r *consumerRepository) GetConsumerByUsername(tx *gorm.DB, username string)
 	return consumer, nil
 }
 
-// GetConsumerByEmail retrieves a consumer by their email from the database.
-func (r *consumerRepository) GetConsumerByEmail(tx *gorm.DB, email string) (entity.Consumer, error) {
+// GetConsumerByEmail retrieves a consumer by their email (case-insensitive) from the database, deleted or not, using its blind index.
+func (r *consumerRepository) GetConsumerByEmail(ctx context.Context, tx *gorm.DB, email string) (entity.Consumer, error) {
 	var consumer entity.Consumer
-	err := tx.First(&consumer, "lower(email) = lower(?)", email).Error
+	err := tx.WithContext(ctx).Unscoped().First(&consumer, "email_index = ?", entity.ConsumerEmailIndex(email)).Error
 
 	if err != nil {
 		return entity.Consumer{}, err
@@ -82,10 +93,11 @@ func (r *consumerRepository) GetConsumerByEmail(tx *gorm.DB, email string) (enti
 	return consumer, nil
 }
 
-// GetConsumerByPhone retrieves a consumer by their phone number from the database.
-func (r *consumerRepository) GetConsumerByPhone(tx *gorm.DB, phone string) (entity.Consumer, error) {
+// GetConsumerByPhone retrieves a consumer by their phone number from the database, deleted or not, using its blind index
+// since the phone numbers are encrypted.
+func (r *consumerRepository) GetConsumerByPhone(ctx context.Context, tx *gorm.DB, phone string) (entity.Consumer, error) {
 	var consumer entity.Consumer
-	err := tx.First(&consumer, "phone = ?", phone).Error
+	err := tx.WithContext(ctx).Unscoped().First(&consumer, "phone_index = ?", entity.ConsumerPhoneIndex(phone)).Error
 
 	if err != nil {
 		return entity.Consumer{}, err
@@ -94,10 +106,10 @@ func (r *consumerRepository) GetConsumerByPhone(tx *gorm.DB, phone string) (enti
 	return consumer, nil
 }
 
-// GetActiveConsumers retrieves all active consumers from the database.
-func (r *consumerRepository) GetConsumersByStatus(tx *gorm.DB, status string, page int, limit int) ([]entity.Consumer, error) {
+// GetActiveConsumers retrieves all active consumers from the database, from a read replica if any.
+func (r *consumerRepository) GetConsumersByStatus(ctx context.Context, tx *gorm.DB, status string, page int, limit int) ([]entity.Consumer, error) {
 	var consumers []entity.Consumer
-	err := tx.Where("status = ?", status).
+	err := database.ReadReplicas(tx).WithContext(ctx).Where("status = ?", status).
 		Order("created_at ASC").
 		Offset((page - 1) * limit).
 		Limit(limit).
@@ -111,10 +123,61 @@ func (r *consumerRepository) GetConsumersByStatus(tx *gorm.DB, status string, pa
 	return consumers, nil
 }
 
+// GetConsumersExcludingStatuses retrieves a page of consumers whose status is none of the given statuses from the database,
+// from a read replica if any.
+func (r *consumerRepository) GetConsumersExcludingStatuses(ctx context.Context, tx *gorm.DB, statuses []string, page int, limit int) ([]entity.Consumer, error) {
+	var consumers []entity.Consumer
+	err := database.ReadReplicas(tx).WithContext(ctx).Where("status NOT IN ?", statuses).
+		Order("created_at ASC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&consumers).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return consumers, nil
+}
+
+// StreamConsumers calls fn with each consumer whose status is none of the given statuses, in creation order.
+// The consumers are read one at a time from a database cursor, of a read replica if any, so the whole table is never held in memory.
+// It stops at the first error returned by fn, and returns it.
+func (r *consumerRepository) StreamConsumers(ctx context.Context, tx *gorm.DB, excludedStatuses []string, fn func(entity.Consumer) error) error {
+	query := database.ReadReplicas(tx).WithContext(ctx).Model(&entity.Consumer{}).Order("created_at ASC")
+	if len(excludedStatuses) > 0 {
+		query = query.Where("status NOT IN ?", excludedStatuses)
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		return fmt.Errorf("failed to stream consumers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var consumer entity.Consumer
+		if err := tx.WithContext(ctx).ScanRows(rows, &consumer); err != nil {
+			return fmt.Errorf("failed to scan consumer: %w", err)
+		}
+
+		if err := fn(consumer); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to stream consumers: %w", err)
+	}
+
+	return nil
+}
+
 // CreateConsumer creates a new consumer in the database and returns the created consumer.
-func (r *consumerRepository) CreateConsumer(tx *gorm.DB, t entity.Consumer) (entity.Consumer, error) {
+func (r *consumerRepository) CreateConsumer(ctx context.Context, tx *gorm.DB, t entity.Consumer) (entity.Consumer, error) {
 	// Insert new consumer
-	if err := tx.Create(&t).Error; err != nil {
+	if err := tx.WithContext(ctx).Create(&t).Error; err != nil {
 		return entity.Consumer{}, fmt.Errorf("failed to create consumer: %w", err)
 	}
 
@@ -123,11 +186,41 @@ func (r *consumerRepository) CreateConsumer(tx *gorm.DB, t entity.Consumer) (ent
 
 // UpdateConsumer updates an existing consumer in the database and returns the updated consumer.
 // This method is used to modify an existing consumer's details.
-func (r *consumerRepository) UpdateConsumer(tx *gorm.DB, t entity.Consumer) (entity.Consumer, error) {
+func (r *consumerRepository) UpdateConsumer(ctx context.Context, tx *gorm.DB, t entity.Consumer) (entity.Consumer, error) {
 	// Save the updated consumer
-	if err := tx.Save(&t).Error; err != nil {
+	if err := tx.WithContext(ctx).Save(&t).Error; err != nil {
 		return entity.Consumer{}, fmt.Errorf("failed to update consumer: %w", err)
 	}
 
 	return t, nil
 }
+
+// SoftDeleteConsumer marks the consumer as deleted at the given time, without removing its row.
+// It returns gorm.ErrRecordNotFound if the consumer does not exist or is already deleted.
+func (r *consumerRepository) SoftDeleteConsumer(ctx context.Context, tx *gorm.DB, id string, deletedAt time.Time) error {
+	result := tx.WithContext(ctx).Model(&entity.Consumer{}).Where("id = ?", id).UpdateColumn("deleted_at", deletedAt)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete consumer %s: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to delete consumer %s: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	return nil
+}
+
+// RestoreConsumer clears the deletion time of the soft-deleted consumer, which is found by the queries again.
+// It returns gorm.ErrRecordNotFound if the consumer does not exist or is not deleted.
+func (r *consumerRepository) RestoreConsumer(ctx context.Context, tx *gorm.DB, id string) error {
+	result := tx.WithContext(ctx).Unscoped().Model(&entity.Consumer{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		UpdateColumn("deleted_at", nil)
+	if result.Error != nil {
+		return fmt.Errorf("failed to restore consumer %s: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to restore consumer %s: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	return nil
+}
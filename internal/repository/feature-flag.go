@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=feature-flag.go -destination=../../tests/mocks/feature-flag-repository.go -package=mocks
+
+// Interface for feature flag repository
+// This interface defines the methods that the feature flag repository should implement
+type FeatureFlagRepository interface {
+	GetFeatureFlags(tx *gorm.DB) ([]entity.FeatureFlag, error)
+	SaveFeatureFlag(tx *gorm.DB, flag entity.FeatureFlag) (entity.FeatureFlag, error)
+}
+
+// This struct defines the FeatureFlagRepository that contains methods for interacting with the database
+// It implements the FeatureFlagRepository interface and provides methods for feature flag-related operations
+type featureFlagRepository struct{}
+
+// NewFeatureFlagRepository creates a new instance of FeatureFlagRepository.
+// It initializes the featureFlagRepository struct and returns it.
+func NewFeatureFlagRepository() FeatureFlagRepository {
+	return &featureFlagRepository{}
+}
+
+// GetFeatureFlags retrieves the feature flags saved in the database, ordered by name.
+func (r *featureFlagRepository) GetFeatureFlags(tx *gorm.DB) ([]entity.FeatureFlag, error) {
+	var flags []entity.FeatureFlag
+	if err := tx.Order("name").Find(&flags).Error; err != nil {
+		return nil, err
+	}
+
+	return flags, nil
+}
+
+// SaveFeatureFlag creates or replaces the feature flag in the database.
+func (r *featureFlagRepository) SaveFeatureFlag(tx *gorm.DB, flag entity.FeatureFlag) (entity.FeatureFlag, error) {
+	if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&flag).Error; err != nil {
+		return entity.FeatureFlag{}, fmt.Errorf("failed to save feature flag: %w", err)
+	}
+
+	return flag, nil
+}
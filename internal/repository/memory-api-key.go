@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory ApiKeyRepository backed by a MemoryStore
+// It implements the ApiKeyRepository interface; the tx argument is ignored
+type memoryApiKeyRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryApiKeyRepository creates a new instance of ApiKeyRepository backed by the given store.
+func NewMemoryApiKeyRepository(store *MemoryStore) ApiKeyRepository {
+	return &memoryApiKeyRepository{store: store}
+}
+
+// CreateApiKey adds a new API key to the store.
+// The hash of the key must be unique, and the user must exist.
+func (r *memoryApiKeyRepository) CreateApiKey(tx *gorm.DB, key entity.ApiKey) (entity.ApiKey, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[key.UserID]; !ok {
+		return entity.ApiKey{}, fmt.Errorf("failed to create API key: user with ID %d does not exist", key.UserID)
+	}
+	for _, existing := range r.store.apiKeys {
+		if existing.KeyHash == key.KeyHash {
+			return entity.ApiKey{}, fmt.Errorf("failed to create API key: %w", gorm.ErrDuplicatedKey)
+		}
+	}
+
+	key.ID = r.store.nextApiKeyID
+	r.store.nextApiKeyID++
+	r.store.apiKeys[key.ID] = cloneApiKey(key)
+
+	return key, nil
+}
+
+// GetApiKeyByID retrieves an API key by its ID from the store.
+func (r *memoryApiKeyRepository) GetApiKeyByID(tx *gorm.DB, id int64) (entity.ApiKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	key, ok := r.store.apiKeys[id]
+	if !ok {
+		return entity.ApiKey{}, gorm.ErrRecordNotFound
+	}
+
+	return cloneApiKey(key), nil
+}
+
+// GetApiKeyByHash retrieves an API key by the hash of the key from the store.
+func (r *memoryApiKeyRepository) GetApiKeyByHash(tx *gorm.DB, keyHash string) (entity.ApiKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, key := range r.store.apiKeys {
+		if key.KeyHash == keyHash {
+			return cloneApiKey(key), nil
+		}
+	}
+
+	return entity.ApiKey{}, gorm.ErrRecordNotFound
+}
+
+// GetApiKeysByUserID retrieves the API keys of the user from the store, oldest first, the revoked ones included.
+func (r *memoryApiKeyRepository) GetApiKeysByUserID(tx *gorm.DB, userID int64) ([]entity.ApiKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var keys []entity.ApiKey
+	for _, key := range r.store.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, cloneApiKey(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+
+	return keys, nil
+}
+
+// RevokeApiKey records the time the API key was revoked, if it was not revoked yet.
+func (r *memoryApiKeyRepository) RevokeApiKey(tx *gorm.DB, id int64, revokedAt time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key, ok := r.store.apiKeys[id]
+	if !ok || key.RevokedAt != nil {
+		return false, nil
+	}
+
+	key.RevokedAt = &revokedAt
+	r.store.apiKeys[id] = key
+
+	return true, nil
+}
+
+// cloneApiKey returns a copy of the API key which does not share its pointers.
+func cloneApiKey(key entity.ApiKey) entity.ApiKey {
+	key.ExpiresAt = clonePtr(key.ExpiresAt)
+	key.RevokedAt = clonePtr(key.RevokedAt)
+	return key
+}
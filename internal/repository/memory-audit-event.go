@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"sort"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory AuditEventRepository backed by a MemoryStore
+// It implements the AuditEventRepository interface; the tx argument is ignored
+type memoryAuditEventRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryAuditEventRepository creates a new instance of AuditEventRepository backed by the given store.
+func NewMemoryAuditEventRepository(store *MemoryStore) AuditEventRepository {
+	return &memoryAuditEventRepository{store: store}
+}
+
+// CreateAuditEvent adds a new audit event to the store and returns it with its assigned ID.
+// An event whose event ID is already recorded is not added again.
+func (r *memoryAuditEventRepository) CreateAuditEvent(tx *gorm.DB, event entity.AuditEvent) (entity.AuditEvent, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.auditEvents {
+		if existing.EventID == event.EventID {
+			return cloneAuditEvent(existing), nil
+		}
+	}
+
+	event.ID = r.store.nextAuditEventID
+	r.store.nextAuditEventID++
+	event.ActorID = clonePtr(event.ActorID)
+	r.store.auditEvents[event.ID] = event
+
+	return cloneAuditEvent(event), nil
+}
+
+// GetAuditEvents retrieves a page of the audit events meeting the criteria of the filter from the store, the most recent first.
+func (r *memoryAuditEventRepository) GetAuditEvents(tx *gorm.DB, filter entity.AuditEventFilter, page int, limit int) ([]entity.AuditEvent, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var matching []entity.AuditEvent
+	for _, event := range r.store.auditEvents {
+		if filter.Matches(event) {
+			matching = append(matching, event)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool { return matching[i].ID > matching[j].ID })
+
+	start, end := paginate(len(matching), page, limit)
+	events := make([]entity.AuditEvent, 0, end-start)
+	for _, event := range matching[start:end] {
+		events = append(events, cloneAuditEvent(event))
+	}
+
+	return events, nil
+}
+
+// cloneAuditEvent returns a copy of the audit event that does not share its actor ID with the store.
+func cloneAuditEvent(event entity.AuditEvent) entity.AuditEvent {
+	event.ActorID = clonePtr(event.ActorID)
+	return event
+}
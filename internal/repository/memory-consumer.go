@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory ConsumerRepository backed by a MemoryStore
+// It implements the ConsumerRepository interface; the tx argument only includes the soft-deleted consumers when it is unscoped,
+// like the soft delete scope of GORM, and the ctx argument only stops the streams
+type memoryConsumerRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryConsumerRepository creates a new instance of ConsumerRepository backed by the given store.
+func NewMemoryConsumerRepository(store *MemoryStore) ConsumerRepository {
+	return &memoryConsumerRepository{store: store}
+}
+
+// GetAllConsumers retrieves a page of consumers ordered by creation time from the store.
+func (r *memoryConsumerRepository) GetAllConsumers(ctx context.Context, tx *gorm.DB, page int, limit int) ([]entity.Consumer, error) {
+	return r.find(tx, func(entity.Consumer) bool { return true }, page, limit), nil
+}
+
+// GetConsumerByID retrieves a consumer by its ID from the store.
+func (r *memoryConsumerRepository) GetConsumerByID(ctx context.Context, tx *gorm.DB, id string) (entity.Consumer, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	consumer, ok := r.store.consumers[id]
+	if !ok || (consumer.IsDeleted() && !isUnscoped(tx)) {
+		return entity.Consumer{}, gorm.ErrRecordNotFound
+	}
+
+	return cloneConsumer(consumer), nil
+}
+
+// GetConsumerByUsername retrieves a consumer by their username (case-insensitive) from the store, deleted or not.
+func (r *memoryConsumerRepository) GetConsumerByUsername(ctx context.Context, tx *gorm.DB, username string) (entity.Consumer, error) {
+	return r.first(func(c entity.Consumer) bool { return strings.EqualFold(c.Username, username) })
+}
+
+// GetConsumerByEmail retrieves a consumer by their email (case-insensitive) from the store, deleted or not.
+func (r *memoryConsumerRepository) GetConsumerByEmail(ctx context.Context, tx *gorm.DB, email string) (entity.Consumer, error) {
+	return r.first(func(c entity.Consumer) bool { return strings.EqualFold(c.Email, email) })
+}
+
+// GetConsumerByPhone retrieves a consumer by their phone number from the store, deleted or not.
+func (r *memoryConsumerRepository) GetConsumerByPhone(ctx context.Context, tx *gorm.DB, phone string) (entity.Consumer, error) {
+	return r.first(func(c entity.Consumer) bool { return c.Phone == phone })
+}
+
+// GetConsumersByStatus retrieves a page of consumers with the given status from the store.
+func (r *memoryConsumerRepository) GetConsumersByStatus(ctx context.Context, tx *gorm.DB, status string, page int, limit int) ([]entity.Consumer, error) {
+	return r.find(tx, func(c entity.Consumer) bool { return c.Status == status }, page, limit), nil
+}
+
+// GetConsumersExcludingStatuses retrieves a page of consumers whose status is none of the given statuses from the store.
+func (r *memoryConsumerRepository) GetConsumersExcludingStatuses(ctx context.Context, tx *gorm.DB, statuses []string, page int, limit int) ([]entity.Consumer, error) {
+	return r.find(tx, func(c entity.Consumer) bool {
+		for _, status := range statuses {
+			if c.Status == status {
+				return false
+			}
+		}
+		return true
+	}, page, limit), nil
+}
+
+// StreamConsumers calls fn with each consumer whose status is none of the given statuses, in creation order.
+// The consumers are copied from the store first (a limit of 0 returns all of them), so that fn runs without holding the lock.
+// It stops at the first error returned by fn, and returns it, or at the error of the context once it is done, like the database cursor.
+func (r *memoryConsumerRepository) StreamConsumers(ctx context.Context, tx *gorm.DB, excludedStatuses []string, fn func(entity.Consumer) error) error {
+	consumers, err := r.GetConsumersExcludingStatuses(ctx, tx, excludedStatuses, 1, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, consumer := range consumers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(consumer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreateConsumer creates a new consumer in the store and returns the created consumer.
+// The ID is generated if empty, and the status defaults to inactive like the database default.
+func (r *memoryConsumerRepository) CreateConsumer(ctx context.Context, tx *gorm.DB, c entity.Consumer) (entity.Consumer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	if _, exists := r.store.consumers[c.ID]; exists {
+		return entity.Consumer{}, fmt.Errorf("failed to create consumer: %w", gorm.ErrDuplicatedKey)
+	}
+	if err := r.checkUniqueConsumer(c); err != nil {
+		return entity.Consumer{}, fmt.Errorf("failed to create consumer: %w", err)
+	}
+
+	if c.Status == "" {
+		c.Status = entity.ConsumerStatusInactive
+	}
+	now := time.Now()
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = now
+	}
+	c.UpdatedAt = now
+
+	r.store.consumers[c.ID] = cloneConsumer(c)
+	r.store.consumerIDs = append(r.store.consumerIDs, c.ID)
+
+	return c, nil
+}
+
+// UpdateConsumer updates an existing consumer in the store and returns the updated consumer.
+func (r *memoryConsumerRepository) UpdateConsumer(ctx context.Context, tx *gorm.DB, c entity.Consumer) (entity.Consumer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.consumers[c.ID]
+	if !ok || (existing.IsDeleted() && !isUnscoped(tx)) {
+		return entity.Consumer{}, fmt.Errorf("failed to update consumer: %w", gorm.ErrRecordNotFound)
+	}
+	if err := r.checkUniqueConsumer(c); err != nil {
+		return entity.Consumer{}, fmt.Errorf("failed to update consumer: %w", err)
+	}
+
+	c.CreatedAt = existing.CreatedAt
+	c.UpdatedAt = time.Now()
+	r.store.consumers[c.ID] = cloneConsumer(c)
+
+	return c, nil
+}
+
+// SoftDeleteConsumer marks the consumer as deleted at the given time in the store.
+// It returns gorm.ErrRecordNotFound if the consumer does not exist or is already deleted.
+func (r *memoryConsumerRepository) SoftDeleteConsumer(ctx context.Context, tx *gorm.DB, id string, deletedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	consumer, ok := r.store.consumers[id]
+	if !ok || consumer.IsDeleted() {
+		return fmt.Errorf("failed to delete consumer %s: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	consumer.DeletedAt = gorm.DeletedAt{Time: deletedAt, Valid: true}
+	r.store.consumers[id] = consumer
+
+	return nil
+}
+
+// RestoreConsumer clears the deletion time of the soft-deleted consumer in the store.
+// It returns gorm.ErrRecordNotFound if the consumer does not exist or is not deleted.
+func (r *memoryConsumerRepository) RestoreConsumer(ctx context.Context, tx *gorm.DB, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	consumer, ok := r.store.consumers[id]
+	if !ok || !consumer.IsDeleted() {
+		return fmt.Errorf("failed to restore consumer %s: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	consumer.DeletedAt = gorm.DeletedAt{}
+	r.store.consumers[id] = consumer
+
+	return nil
+}
+
+// checkUniqueConsumer checks the unique constraints of the consumers table against the other consumers.
+// The caller must hold the lock.
+func (r *memoryConsumerRepository) checkUniqueConsumer(c entity.Consumer) error {
+	for id, existing := range r.store.consumers {
+		if id == c.ID {
+			continue
+		}
+		if strings.EqualFold(existing.Username, c.Username) {
+			return fmt.Errorf("consumer with username %s already exists: %w", c.Username, gorm.ErrDuplicatedKey)
+		}
+		if strings.EqualFold(existing.Email, c.Email) {
+			return fmt.Errorf("consumer with email %s already exists: %w", c.Email, gorm.ErrDuplicatedKey)
+		}
+		if existing.Phone == c.Phone {
+			return fmt.Errorf("consumer with phone %s already exists: %w", c.Phone, gorm.ErrDuplicatedKey)
+		}
+	}
+
+	return nil
+}
+
+// first returns the first consumer matching the predicate, in creation order.
+func (r *memoryConsumerRepository) first(match func(entity.Consumer) bool) (entity.Consumer, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, id := range r.store.consumerIDs {
+		if c := r.store.consumers[id]; match(c) {
+			return cloneConsumer(c), nil
+		}
+	}
+
+	return entity.Consumer{}, gorm.ErrRecordNotFound
+}
+
+// find returns a page of the consumers matching the predicate, in creation order.
+// The soft-deleted consumers are left out, unless the given tx is unscoped.
+func (r *memoryConsumerRepository) find(tx *gorm.DB, match func(entity.Consumer) bool, page int, limit int) []entity.Consumer {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	unscoped := isUnscoped(tx)
+	var matched []entity.Consumer
+	for _, id := range r.store.consumerIDs {
+		if c := r.store.consumers[id]; (unscoped || !c.IsDeleted()) && match(c) {
+			matched = append(matched, cloneConsumer(c))
+		}
+	}
+
+	start, end := paginate(len(matched), page, limit)
+	return matched[start:end]
+}
+
+// isUnscoped reports whether the given tx is unscoped, i.e. includes the soft-deleted records, like with tx.Unscoped().
+func isUnscoped(tx *gorm.DB) bool {
+	return tx != nil && tx.Statement != nil && tx.Statement.Unscoped
+}
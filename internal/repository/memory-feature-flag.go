@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"sort"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory FeatureFlagRepository backed by a MemoryStore
+// It implements the FeatureFlagRepository interface; the tx argument is ignored
+type memoryFeatureFlagRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryFeatureFlagRepository creates a new instance of FeatureFlagRepository backed by the given store.
+func NewMemoryFeatureFlagRepository(store *MemoryStore) FeatureFlagRepository {
+	return &memoryFeatureFlagRepository{store: store}
+}
+
+// GetFeatureFlags retrieves the feature flags saved in the store, ordered by name.
+func (r *memoryFeatureFlagRepository) GetFeatureFlags(tx *gorm.DB) ([]entity.FeatureFlag, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	flags := make([]entity.FeatureFlag, 0, len(r.store.featureFlags))
+	for _, flag := range r.store.featureFlags {
+		flags = append(flags, cloneFeatureFlag(flag))
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+
+	return flags, nil
+}
+
+// SaveFeatureFlag creates or replaces the feature flag in the store.
+func (r *memoryFeatureFlagRepository) SaveFeatureFlag(tx *gorm.DB, flag entity.FeatureFlag) (entity.FeatureFlag, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.featureFlags[flag.Name] = cloneFeatureFlag(flag)
+
+	return cloneFeatureFlag(flag), nil
+}
+
+// cloneFeatureFlag returns a deep copy of the feature flag.
+func cloneFeatureFlag(f entity.FeatureFlag) entity.FeatureFlag {
+	f.Reason = clonePtr(f.Reason)
+	f.UpdatedBy = clonePtr(f.UpdatedBy)
+	f.UpdatedAt = clonePtr(f.UpdatedAt)
+	return f
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory MFARepository backed by a MemoryStore
+// It implements the MFARepository interface; the tx argument is ignored
+type memoryMFARepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryMFARepository creates a new instance of MFARepository backed by the given store.
+func NewMemoryMFARepository(store *MemoryStore) MFARepository {
+	return &memoryMFARepository{store: store}
+}
+
+// GetUserMFA retrieves the two-factor authentication settings of a user from the store.
+func (r *memoryMFARepository) GetUserMFA(tx *gorm.DB, userID int64) (entity.UserMFA, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	mfa, ok := r.store.userMFA[userID]
+	if !ok {
+		return entity.UserMFA{}, gorm.ErrRecordNotFound
+	}
+
+	mfa.EnabledAt = clonePtr(mfa.EnabledAt)
+	return mfa, nil
+}
+
+// SaveUserMFA adds the two-factor authentication settings of a user to the store, or replaces the existing ones.
+func (r *memoryMFARepository) SaveUserMFA(tx *gorm.DB, mfa entity.UserMFA) (entity.UserMFA, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	mfa.EnabledAt = clonePtr(mfa.EnabledAt)
+	r.store.userMFA[mfa.UserID] = mfa
+
+	return mfa, nil
+}
+
+// MarkMFAStepUsed records the time step of the code accepted for the user, if it is after the last one.
+func (r *memoryMFARepository) MarkMFAStepUsed(tx *gorm.DB, userID int64, step int64) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	mfa, ok := r.store.userMFA[userID]
+	if !ok || mfa.LastUsedStep >= step {
+		return false, nil
+	}
+
+	mfa.LastUsedStep = step
+	r.store.userMFA[userID] = mfa
+
+	return true, nil
+}
+
+// RemoveUserMFA removes the two-factor authentication settings of a user from the store.
+func (r *memoryMFARepository) RemoveUserMFA(tx *gorm.DB, userID int64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.userMFA[userID]; !ok {
+		return 0, nil
+	}
+
+	delete(r.store.userMFA, userID)
+	return 1, nil
+}
+
+// CreateMFAChallenge adds a new MFA challenge to the store.
+// The hash of the token must be unique.
+func (r *memoryMFARepository) CreateMFAChallenge(tx *gorm.DB, challenge entity.MFAChallenge) (entity.MFAChallenge, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.mfaChallenges {
+		if existing.TokenHash == challenge.TokenHash {
+			return entity.MFAChallenge{}, fmt.Errorf("failed to create MFA challenge: %w", gorm.ErrDuplicatedKey)
+		}
+	}
+
+	challenge.ID = r.store.nextMFAChallengeID
+	r.store.nextMFAChallengeID++
+	challenge.UsedAt = clonePtr(challenge.UsedAt)
+	r.store.mfaChallenges[challenge.ID] = challenge
+
+	return challenge, nil
+}
+
+// GetMFAChallengeByHash retrieves an MFA challenge by the hash of its token from the store.
+func (r *memoryMFARepository) GetMFAChallengeByHash(tx *gorm.DB, tokenHash string) (entity.MFAChallenge, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, challenge := range r.store.mfaChallenges {
+		if challenge.TokenHash == tokenHash {
+			challenge.UsedAt = clonePtr(challenge.UsedAt)
+			return challenge, nil
+		}
+	}
+
+	return entity.MFAChallenge{}, gorm.ErrRecordNotFound
+}
+
+// UpdateMFAChallenge updates the attempts and the use time of an MFA challenge in the store.
+func (r *memoryMFARepository) UpdateMFAChallenge(tx *gorm.DB, challenge entity.MFAChallenge) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.mfaChallenges[challenge.ID]
+	if !ok {
+		return fmt.Errorf("failed to update MFA challenge %d: %w", challenge.ID, gorm.ErrRecordNotFound)
+	}
+
+	existing.Attempts = challenge.Attempts
+	existing.UsedAt = clonePtr(challenge.UsedAt)
+	r.store.mfaChallenges[challenge.ID] = existing
+
+	return nil
+}
+
+// RemoveMFAChallenges removes the MFA challenges of a user from the store.
+func (r *memoryMFARepository) RemoveMFAChallenges(tx *gorm.DB, userID int64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var removed int64
+	for id, challenge := range r.store.mfaChallenges {
+		if challenge.UserID == userID {
+			delete(r.store.mfaChallenges, id)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory NotificationRepository backed by a MemoryStore
+// It implements the NotificationRepository interface; the tx argument is ignored
+type memoryNotificationRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryNotificationRepository creates a new instance of NotificationRepository backed by the given store.
+func NewMemoryNotificationRepository(store *MemoryStore) NotificationRepository {
+	return &memoryNotificationRepository{store: store}
+}
+
+// GetNotificationPreference retrieves the notification preferences of the user from the store.
+func (r *memoryNotificationRepository) GetNotificationPreference(tx *gorm.DB, userID int64) (entity.NotificationPreference, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	pref, ok := r.store.preferences[userID]
+	if !ok {
+		return entity.NotificationPreference{}, gorm.ErrRecordNotFound
+	}
+
+	return pref, nil
+}
+
+// SaveNotificationPreference creates or replaces the notification preferences of the user in the store.
+// The user must exist.
+func (r *memoryNotificationRepository) SaveNotificationPreference(tx *gorm.DB, pref entity.NotificationPreference) (entity.NotificationPreference, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[pref.UserID]; !ok {
+		return entity.NotificationPreference{}, fmt.Errorf("failed to save notification preferences: user with ID %d does not exist", pref.UserID)
+	}
+
+	r.store.preferences[pref.UserID] = pref
+
+	return pref, nil
+}
+
+// CountUserDevices counts the devices the user has logged in from in the store.
+func (r *memoryNotificationRepository) CountUserDevices(tx *gorm.DB, userID int64) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var count int64
+	for _, device := range r.store.userDevices {
+		if device.UserID == userID {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// TouchUserDevice records a login of the user from the device in the store.
+// It returns true if the device is new.
+func (r *memoryNotificationRepository) TouchUserDevice(tx *gorm.DB, device entity.UserDevice) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := fmt.Sprintf("%d|%s", device.UserID, device.Fingerprint)
+	if existing, ok := r.store.userDevices[key]; ok {
+		existing.LastSeenAt = device.LastSeenAt
+		existing.LastIP = device.LastIP
+		existing.LastCountry = device.LastCountry
+		existing.LastCity = device.LastCity
+		r.store.userDevices[key] = existing
+		return false, nil
+	}
+
+	r.store.userDevices[key] = device
+
+	return true, nil
+}
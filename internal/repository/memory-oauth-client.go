@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory OAuthClientRepository backed by a MemoryStore
+// It implements the OAuthClientRepository interface; the tx argument is ignored
+type memoryOAuthClientRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryOAuthClientRepository creates a new instance of OAuthClientRepository backed by the given store.
+func NewMemoryOAuthClientRepository(store *MemoryStore) OAuthClientRepository {
+	return &memoryOAuthClientRepository{store: store}
+}
+
+// CreateOAuthClient adds a new OAuth client to the store.
+// The client ID must be unique, and the user must exist.
+func (r *memoryOAuthClientRepository) CreateOAuthClient(tx *gorm.DB, client entity.OAuthClient) (entity.OAuthClient, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[client.UserID]; !ok {
+		return entity.OAuthClient{}, fmt.Errorf("failed to create OAuth client: user with ID %d does not exist", client.UserID)
+	}
+	for _, existing := range r.store.oauthClients {
+		if existing.ClientID == client.ClientID {
+			return entity.OAuthClient{}, fmt.Errorf("failed to create OAuth client: %w", gorm.ErrDuplicatedKey)
+		}
+	}
+
+	client.ID = r.store.nextOAuthClientID
+	r.store.nextOAuthClientID++
+	r.store.oauthClients[client.ID] = cloneOAuthClient(client)
+
+	return client, nil
+}
+
+// GetOAuthClientByID retrieves an OAuth client by its ID from the store.
+func (r *memoryOAuthClientRepository) GetOAuthClientByID(tx *gorm.DB, id int64) (entity.OAuthClient, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	client, ok := r.store.oauthClients[id]
+	if !ok {
+		return entity.OAuthClient{}, gorm.ErrRecordNotFound
+	}
+
+	return cloneOAuthClient(client), nil
+}
+
+// GetOAuthClientByClientID retrieves an OAuth client by its client ID from the store.
+func (r *memoryOAuthClientRepository) GetOAuthClientByClientID(tx *gorm.DB, clientID string) (entity.OAuthClient, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, client := range r.store.oauthClients {
+		if client.ClientID == clientID {
+			return cloneOAuthClient(client), nil
+		}
+	}
+
+	return entity.OAuthClient{}, gorm.ErrRecordNotFound
+}
+
+// GetOAuthClientsByUserID retrieves the OAuth clients of the user from the store, oldest first, the revoked ones included.
+func (r *memoryOAuthClientRepository) GetOAuthClientsByUserID(tx *gorm.DB, userID int64) ([]entity.OAuthClient, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var clients []entity.OAuthClient
+	for _, client := range r.store.oauthClients {
+		if client.UserID == userID {
+			clients = append(clients, cloneOAuthClient(client))
+		}
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].ID < clients[j].ID
+	})
+
+	return clients, nil
+}
+
+// RevokeOAuthClient records the time the OAuth client was revoked, if it was not revoked yet.
+func (r *memoryOAuthClientRepository) RevokeOAuthClient(tx *gorm.DB, id int64, revokedAt time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	client, ok := r.store.oauthClients[id]
+	if !ok || client.RevokedAt != nil {
+		return false, nil
+	}
+
+	client.RevokedAt = &revokedAt
+	r.store.oauthClients[id] = client
+
+	return true, nil
+}
+
+// cloneOAuthClient returns a copy of the OAuth client which does not share its pointers.
+func cloneOAuthClient(client entity.OAuthClient) entity.OAuthClient {
+	client.RevokedAt = clonePtr(client.RevokedAt)
+	return client
+}
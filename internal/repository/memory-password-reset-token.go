@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory PasswordResetTokenRepository backed by a MemoryStore
+// It implements the PasswordResetTokenRepository interface; the tx argument is ignored
+type memoryPasswordResetTokenRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryPasswordResetTokenRepository creates a new instance of PasswordResetTokenRepository backed by the given store.
+func NewMemoryPasswordResetTokenRepository(store *MemoryStore) PasswordResetTokenRepository {
+	return &memoryPasswordResetTokenRepository{store: store}
+}
+
+// CreatePasswordResetToken adds a new password reset token to the store.
+// The hash of the token must be unique.
+func (r *memoryPasswordResetTokenRepository) CreatePasswordResetToken(tx *gorm.DB, token entity.PasswordResetToken) (entity.PasswordResetToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.resetTokens {
+		if existing.TokenHash == token.TokenHash {
+			return entity.PasswordResetToken{}, fmt.Errorf("failed to create password reset token: %w", gorm.ErrDuplicatedKey)
+		}
+	}
+
+	token.ID = r.store.nextResetTokenID
+	r.store.nextResetTokenID++
+	r.store.resetTokens[token.ID] = token
+
+	return token, nil
+}
+
+// GetPasswordResetTokenByHash retrieves a password reset token by the hash of the token from the store.
+func (r *memoryPasswordResetTokenRepository) GetPasswordResetTokenByHash(tx *gorm.DB, tokenHash string) (entity.PasswordResetToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, token := range r.store.resetTokens {
+		if token.TokenHash == tokenHash {
+			token.UsedAt = clonePtr(token.UsedAt)
+			return token, nil
+		}
+	}
+
+	return entity.PasswordResetToken{}, gorm.ErrRecordNotFound
+}
+
+// MarkPasswordResetTokenUsed records the time the token was used, if it was not used yet.
+func (r *memoryPasswordResetTokenRepository) MarkPasswordResetTokenUsed(tx *gorm.DB, id int64, usedAt time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token, ok := r.store.resetTokens[id]
+	if !ok || token.UsedAt != nil {
+		return false, nil
+	}
+
+	token.UsedAt = &usedAt
+	r.store.resetTokens[id] = token
+
+	return true, nil
+}
+
+// RemoveUnusedPasswordResetTokens removes the password reset tokens of the user which were not used from the store.
+func (r *memoryPasswordResetTokenRepository) RemoveUnusedPasswordResetTokens(tx *gorm.DB, userID int64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var removed int64
+	for id, token := range r.store.resetTokens {
+		if token.UserID == userID && token.UsedAt == nil {
+			delete(r.store.resetTokens, id)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory PermissionRepository backed by a MemoryStore
+// It implements the PermissionRepository interface; the tx argument is ignored
+type memoryPermissionRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryPermissionRepository creates a new instance of PermissionRepository backed by the given store.
+func NewMemoryPermissionRepository(store *MemoryStore) PermissionRepository {
+	return &memoryPermissionRepository{store: store}
+}
+
+// GetPermissions retrieves all the permissions from the store, ordered by ID.
+func (r *memoryPermissionRepository) GetPermissions(tx *gorm.DB) ([]entity.Permission, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	permissions := make([]entity.Permission, 0, len(r.store.permissions))
+	for _, p := range r.store.permissions {
+		permissions = append(permissions, clonePermission(p))
+	}
+	sortPermissions(permissions)
+
+	return permissions, nil
+}
+
+// GetPermissionsByNames retrieves the permissions with the given names from the store, ordered by ID.
+// The names without a permission are skipped.
+func (r *memoryPermissionRepository) GetPermissionsByNames(tx *gorm.DB, names []string) ([]entity.Permission, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	permissions := []entity.Permission{}
+	for _, name := range names {
+		if id, ok := r.store.permissionID(name); ok && !containsPermission(permissions, id) {
+			permissions = append(permissions, clonePermission(r.store.permissions[id]))
+		}
+	}
+	sortPermissions(permissions)
+
+	return permissions, nil
+}
+
+// GetRolePermissions retrieves the permissions granted to the role with the given ID, ordered by ID.
+func (r *memoryPermissionRepository) GetRolePermissions(tx *gorm.DB, roleID uint) ([]entity.Permission, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	permissions := []entity.Permission{}
+	for _, id := range r.store.rolePerms[roleID] {
+		if p, ok := r.store.permissions[id]; ok {
+			permissions = append(permissions, clonePermission(p))
+		}
+	}
+	sortPermissions(permissions)
+
+	return permissions, nil
+}
+
+// ReplaceRolePermissions replaces the permissions granted to the role with the given ID.
+// Like the foreign keys of the role_permissions table, the role and the permissions must exist.
+func (r *memoryPermissionRepository) ReplaceRolePermissions(tx *gorm.DB, roleID uint, permissionIDs []uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.roles[roleID]; !ok {
+		return fmt.Errorf("failed to grant permissions to role %d: %w", roleID, gorm.ErrForeignKeyViolated)
+	}
+	ids := make([]uint, 0, len(permissionIDs))
+	for _, id := range permissionIDs {
+		if _, ok := r.store.permissions[id]; !ok {
+			return fmt.Errorf("failed to grant permission %d to role %d: %w", id, roleID, gorm.ErrForeignKeyViolated)
+		}
+		if !containsID(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	r.store.rolePerms[roleID] = ids
+
+	return nil
+}
+
+// GetPermissionNamesByRoleNames retrieves the distinct names of the permissions granted to any of the roles
+// with the given names, sorted by name.
+func (r *memoryPermissionRepository) GetPermissionNamesByRoleNames(tx *gorm.DB, roleNames []string) ([]string, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(roleNames))
+	for _, name := range roleNames {
+		wanted[name] = true
+	}
+
+	seen := make(map[string]bool)
+	names := []string{}
+	for roleID, role := range r.store.roles {
+		if !wanted[role.Name] {
+			continue
+		}
+		for _, id := range r.store.rolePerms[roleID] {
+			if p, ok := r.store.permissions[id]; ok && !seen[p.Name] {
+				seen[p.Name] = true
+				names = append(names, p.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// sortPermissions sorts the permissions by ID, like the PostgreSQL repository.
+func sortPermissions(permissions []entity.Permission) {
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i].ID < permissions[j].ID })
+}
+
+// containsPermission reports whether the permissions contain the permission with the given ID.
+func containsPermission(permissions []entity.Permission, id uint) bool {
+	for _, p := range permissions {
+		if p.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"sort"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory RateLimitOverrideRepository backed by a MemoryStore
+// It implements the RateLimitOverrideRepository interface; the tx argument is ignored
+type memoryRateLimitOverrideRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryRateLimitOverrideRepository creates a new instance of RateLimitOverrideRepository backed by the given store.
+func NewMemoryRateLimitOverrideRepository(store *MemoryStore) RateLimitOverrideRepository {
+	return &memoryRateLimitOverrideRepository{store: store}
+}
+
+// GetRateLimitOverrides retrieves the rate limit overrides saved in the store, ordered by client.
+func (r *memoryRateLimitOverrideRepository) GetRateLimitOverrides(tx *gorm.DB) ([]entity.RateLimitOverride, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	overrides := make([]entity.RateLimitOverride, 0, len(r.store.rateLimits))
+	for _, override := range r.store.rateLimits {
+		overrides = append(overrides, cloneRateLimitOverride(override))
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Client < overrides[j].Client })
+
+	return overrides, nil
+}
+
+// SaveRateLimitOverride creates or replaces the rate limit override of the client in the store.
+func (r *memoryRateLimitOverrideRepository) SaveRateLimitOverride(tx *gorm.DB, override entity.RateLimitOverride) (entity.RateLimitOverride, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.rateLimits[override.Client] = cloneRateLimitOverride(override)
+
+	return cloneRateLimitOverride(override), nil
+}
+
+// DeleteRateLimitOverride deletes the rate limit override of the client from the store.
+// It returns gorm.ErrRecordNotFound if the client has no override.
+func (r *memoryRateLimitOverrideRepository) DeleteRateLimitOverride(tx *gorm.DB, client string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.rateLimits[client]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.store.rateLimits, client)
+
+	return nil
+}
+
+// cloneRateLimitOverride returns a deep copy of the rate limit override.
+func cloneRateLimitOverride(o entity.RateLimitOverride) entity.RateLimitOverride {
+	o.Reason = clonePtr(o.Reason)
+	o.UpdatedBy = clonePtr(o.UpdatedBy)
+	o.UpdatedAt = clonePtr(o.UpdatedAt)
+	return o
+}
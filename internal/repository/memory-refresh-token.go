@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory RefreshTokenRepository backed by a MemoryStore
+// It implements the RefreshTokenRepository interface; the tx argument is ignored
+type memoryRefreshTokenRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryRefreshTokenRepository creates a new instance of RefreshTokenRepository backed by the given store.
+func NewMemoryRefreshTokenRepository(store *MemoryStore) RefreshTokenRepository {
+	return &memoryRefreshTokenRepository{store: store}
+}
+
+// GetRefreshTokenByUserID retrieves the most recent refresh token of the user from the store.
+func (r *memoryRefreshTokenRepository) GetRefreshTokenByUserID(tx *gorm.DB, userID int64) (entity.RefreshToken, error) {
+	tokens := r.userTokens(userID)
+	if len(tokens) == 0 {
+		return entity.RefreshToken{}, gorm.ErrRecordNotFound
+	}
+
+	return tokens[len(tokens)-1], nil
+}
+
+// GetRefreshTokenByToken retrieves a refresh token by its token string from the store.
+func (r *memoryRefreshTokenRepository) GetRefreshTokenByToken(tx *gorm.DB, token string) (entity.RefreshToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	refreshToken, ok := r.store.refreshTokens[token]
+	if !ok {
+		return entity.RefreshToken{}, gorm.ErrRecordNotFound
+	}
+
+	return refreshToken, nil
+}
+
+// GetRefreshTokensByUserID retrieves the refresh tokens of the user from the store, oldest first.
+func (r *memoryRefreshTokenRepository) GetRefreshTokensByUserID(tx *gorm.DB, userID int64) ([]entity.RefreshToken, error) {
+	return r.userTokens(userID), nil
+}
+
+// LockUserSessions does nothing, the in-memory driver is meant for development and tests.
+func (r *memoryRefreshTokenRepository) LockUserSessions(tx *gorm.DB, userID int64) error {
+	return nil
+}
+
+// CreateRefreshToken creates a new refresh token in the store.
+// The token must be unique, and the user must exist.
+func (r *memoryRefreshTokenRepository) CreateRefreshToken(tx *gorm.DB, token entity.RefreshToken) (entity.RefreshToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[token.UserID]; !ok {
+		return entity.RefreshToken{}, fmt.Errorf("failed to create refresh token: user with ID %d does not exist", token.UserID)
+	}
+	if _, exists := r.store.refreshTokens[token.Token]; exists {
+		return entity.RefreshToken{}, fmt.Errorf("failed to create refresh token: %w", gorm.ErrDuplicatedKey)
+	}
+	token.User = nil
+	r.store.refreshTokens[token.Token] = token
+
+	return token, nil
+}
+
+// RemoveRefreshToken removes a refresh token by its token string from the store.
+// It returns false if the token does not exist.
+func (r *memoryRefreshTokenRepository) RemoveRefreshToken(tx *gorm.DB, token string) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.refreshTokens[token]; !ok {
+		return false, nil
+	}
+	delete(r.store.refreshTokens, token)
+
+	return true, nil
+}
+
+// RemoveRefreshTokenBySessionID removes the refresh token of the given session of the user from the store.
+// It returns false if the user has no such session.
+func (r *memoryRefreshTokenRepository) RemoveRefreshTokenBySessionID(tx *gorm.DB, userID int64, sessionID string) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	removed := false
+	for key, t := range r.store.refreshTokens {
+		if t.UserID == userID && t.SessionID == sessionID {
+			delete(r.store.refreshTokens, key)
+			removed = true
+		}
+	}
+
+	return removed, nil
+}
+
+// RemoveRefreshTokenByUserID removes all the refresh tokens of the user from the store.
+func (r *memoryRefreshTokenRepository) RemoveRefreshTokenByUserID(tx *gorm.DB, userID int64) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for key, t := range r.store.refreshTokens {
+		if t.UserID == userID {
+			delete(r.store.refreshTokens, key)
+		}
+	}
+
+	return true, nil
+}
+
+// userTokens returns the refresh tokens of the user in the store, oldest first.
+func (r *memoryRefreshTokenRepository) userTokens(userID int64) []entity.RefreshToken {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var tokens []entity.RefreshToken
+	for _, token := range r.store.refreshTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+
+	return tokens
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory RevokedTokenRepository backed by a MemoryStore
+// It implements the RevokedTokenRepository interface; the tx argument is ignored
+type memoryRevokedTokenRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryRevokedTokenRepository creates a new instance of RevokedTokenRepository backed by the given store.
+func NewMemoryRevokedTokenRepository(store *MemoryStore) RevokedTokenRepository {
+	return &memoryRevokedTokenRepository{store: store}
+}
+
+// CreateRevokedToken records the revoked access token in the store.
+// Revoking a token already revoked is not an error.
+func (r *memoryRevokedTokenRepository) CreateRevokedToken(tx *gorm.DB, token entity.RevokedToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.revokedTokens[token.TokenID]; !exists {
+		r.store.revokedTokens[token.TokenID] = token
+	}
+
+	return nil
+}
+
+// GetRevokedTokens retrieves the revoked access tokens not expired at the given time from the store.
+func (r *memoryRevokedTokenRepository) GetRevokedTokens(tx *gorm.DB, now time.Time) ([]entity.RevokedToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var tokens []entity.RevokedToken
+	for _, token := range r.store.revokedTokens {
+		if token.ExpiresAt.After(now) {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens, nil
+}
+
+// RemoveExpiredRevokedTokens removes the revoked access tokens expired at the given time from the store.
+func (r *memoryRevokedTokenRepository) RemoveExpiredRevokedTokens(tx *gorm.DB, now time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var removed int64
+	for id, token := range r.store.revokedTokens {
+		if !token.ExpiresAt.After(now) {
+			delete(r.store.revokedTokens, id)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
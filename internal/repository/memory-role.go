@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory RoleRepository backed by a MemoryStore
+// It implements the RoleRepository interface; the tx argument is ignored
+type memoryRoleRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryRoleRepository creates a new instance of RoleRepository backed by the given store.
+func NewMemoryRoleRepository(store *MemoryStore) RoleRepository {
+	return &memoryRoleRepository{store: store}
+}
+
+// GetRoleByID retrieves a role by its ID from the store.
+func (r *memoryRoleRepository) GetRoleByID(tx *gorm.DB, id uint) (entity.Role, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	role, ok := r.store.roles[id]
+	if !ok {
+		return entity.Role{}, gorm.ErrRecordNotFound
+	}
+
+	return cloneRole(role), nil
+}
+
+// GetRoleByName retrieves a role by its name (case-insensitive) from the store.
+func (r *memoryRoleRepository) GetRoleByName(tx *gorm.DB, name string) (entity.Role, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, role := range r.store.roles {
+		if strings.EqualFold(role.Name, name) {
+			return cloneRole(role), nil
+		}
+	}
+
+	return entity.Role{}, gorm.ErrRecordNotFound
+}
+
+// GetRoles retrieves all the roles from the store, ordered by ID.
+func (r *memoryRoleRepository) GetRoles(tx *gorm.DB) ([]entity.Role, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	roles := make([]entity.Role, 0, len(r.store.roles))
+	for _, role := range r.store.roles {
+		roles = append(roles, cloneRole(role))
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].ID < roles[j].ID })
+
+	return roles, nil
+}
+
+// CreateRole creates a new role in the store. The role name must be unique (case-insensitive).
+func (r *memoryRoleRepository) CreateRole(tx *gorm.DB, role entity.Role) (entity.Role, error) {
+	role.ID = 0
+	created, err := r.store.AddRole(cloneRole(role))
+	if err != nil {
+		return entity.Role{}, fmt.Errorf("failed to create role: %w", err)
+	}
+
+	return cloneRole(created), nil
+}
+
+// UpdateRole updates the name and the description of an existing role in the store.
+// The role name must remain unique (case-insensitive).
+func (r *memoryRoleRepository) UpdateRole(tx *gorm.DB, role entity.Role) (entity.Role, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.roles[role.ID]; !ok {
+		return entity.Role{}, fmt.Errorf("failed to update role %d: %w", role.ID, gorm.ErrRecordNotFound)
+	}
+	for id, existing := range r.store.roles {
+		if id != role.ID && strings.EqualFold(existing.Name, role.Name) {
+			return entity.Role{}, fmt.Errorf("failed to update role %d: role %s already exists: %w", role.ID, role.Name, gorm.ErrDuplicatedKey)
+		}
+	}
+
+	r.store.roles[role.ID] = cloneRole(role)
+
+	return cloneRole(role), nil
+}
+
+// DeleteRole deletes the role with the given ID from the store.
+// Like the foreign key of the user_roles table, it refuses to delete a role still assigned to users,
+// and like the cascade of the role_permissions table, it drops the permissions of the role.
+func (r *memoryRoleRepository) DeleteRole(tx *gorm.DB, id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.roles[id]; !ok {
+		return fmt.Errorf("failed to delete role %d: %w", id, gorm.ErrRecordNotFound)
+	}
+	if r.store.countRoleUsers(id) > 0 {
+		return fmt.Errorf("failed to delete role %d: %w", id, gorm.ErrForeignKeyViolated)
+	}
+
+	delete(r.store.roles, id)
+	delete(r.store.rolePerms, id)
+
+	return nil
+}
+
+// CountRoleUsers counts the users the role is assigned to, including the soft-deleted users.
+func (r *memoryRoleRepository) CountRoleUsers(tx *gorm.DB, id uint) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.countRoleUsers(id), nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"maps"
+	"slices"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory SecuritySettingsRepository backed by a MemoryStore
+// It implements the SecuritySettingsRepository interface; the tx argument is ignored
+type memorySecuritySettingsRepository struct {
+	store *MemoryStore
+}
+
+// NewMemorySecuritySettingsRepository creates a new instance of SecuritySettingsRepository backed by the given store.
+func NewMemorySecuritySettingsRepository(store *MemoryStore) SecuritySettingsRepository {
+	return &memorySecuritySettingsRepository{store: store}
+}
+
+// GetSecuritySettings retrieves the security settings from the store.
+// It returns gorm.ErrRecordNotFound if they were never saved.
+func (r *memorySecuritySettingsRepository) GetSecuritySettings(tx *gorm.DB) (entity.SecuritySettings, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if r.store.securitySettings == nil {
+		return entity.SecuritySettings{}, gorm.ErrRecordNotFound
+	}
+
+	return cloneSecuritySettings(*r.store.securitySettings), nil
+}
+
+// SaveSecuritySettings creates or replaces the security settings in the store.
+func (r *memorySecuritySettingsRepository) SaveSecuritySettings(tx *gorm.DB, settings entity.SecuritySettings) (entity.SecuritySettings, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	settings.ID = entity.SecuritySettingsID
+	stored := cloneSecuritySettings(settings)
+	r.store.securitySettings = &stored
+
+	return cloneSecuritySettings(settings), nil
+}
+
+// cloneSecuritySettings returns a deep copy of the security settings.
+func cloneSecuritySettings(s entity.SecuritySettings) entity.SecuritySettings {
+	s.AllowedOrigins = slices.Clone(s.AllowedOrigins)
+	s.AllowedHeaders = slices.Clone(s.AllowedHeaders)
+	s.SecurityHeaders = maps.Clone(s.SecurityHeaders)
+	s.UpdatedBy = clonePtr(s.UpdatedBy)
+	s.UpdatedAt = clonePtr(s.UpdatedAt)
+	return s
+}
@@ -0,0 +1,462 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+)
+
+/**
+ * MemoryStore holds the data of the in-memory repositories used when DB_DRIVER=memory.
+ * It is shared by the in-memory user, role, permission, refresh token, revoked token, password reset token, MFA, consumer,
+ * token usage, notification, webhook delivery, security settings, feature flag, API key, OAuth client, user identity, and audit event repositories, so that users can be resolved with their roles like the PostgreSQL repositories do.
+ * All access is guarded by a single RWMutex and the unique constraints of the tables are enforced.
+ * Entities are copied on the way in and out, so callers never share pointers with the store.
+ */
+
+// seedPasswordHash is the bcrypt hash of "P@ssw0rd" used by the seed users (same as import.sql)
+const seedPasswordHash = "$2a$10$eP5Sddi7Q5Jv6seppeF93.XsWGY8r4PnsqprWGb5AxsZ9TpwULIGa"
+
+// MemoryStore is a thread-safe in-memory store for all entities.
+type MemoryStore struct {
+	mu            sync.RWMutex
+	users         map[int64]entity.User
+	roles         map[uint]entity.Role
+	userRoles     map[int64][]uint
+	permissions   map[uint]entity.Permission
+	rolePerms     map[uint][]uint
+	refreshTokens map[string]entity.RefreshToken
+	consumers     map[string]entity.Consumer
+	consumerIDs   []string // consumer IDs in insertion (created_at) order
+	tokenUsage    map[string]entity.TokenUsage
+	userDevices   map[string]entity.UserDevice
+	preferences   map[int64]entity.NotificationPreference
+	revokedTokens map[string]entity.RevokedToken
+	resetTokens   map[int64]entity.PasswordResetToken
+	userMFA       map[int64]entity.UserMFA
+	mfaChallenges map[int64]entity.MFAChallenge
+	webhooks      map[int64]entity.WebhookDelivery
+	featureFlags  map[string]entity.FeatureFlag
+	apiKeys       map[int64]entity.ApiKey
+	oauthClients  map[int64]entity.OAuthClient
+	identities    map[int64]entity.UserIdentity
+	rateLimits    map[string]entity.RateLimitOverride
+	auditEvents   map[int64]entity.AuditEvent
+	nextUserID    int64
+	nextRoleID    uint
+
+	nextPermissionID   uint
+	nextResetTokenID   int64
+	nextMFAChallengeID int64
+	nextWebhookID      int64
+	nextApiKeyID       int64
+	nextOAuthClientID  int64
+	nextIdentityID     int64
+	nextAuditEventID   int64
+
+	securitySettings *entity.SecuritySettings // nil until the settings are saved
+}
+
+// NewMemoryStore creates a new empty instance of MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:         make(map[int64]entity.User),
+		roles:         make(map[uint]entity.Role),
+		userRoles:     make(map[int64][]uint),
+		permissions:   make(map[uint]entity.Permission),
+		rolePerms:     make(map[uint][]uint),
+		refreshTokens: make(map[string]entity.RefreshToken),
+		consumers:     make(map[string]entity.Consumer),
+		tokenUsage:    make(map[string]entity.TokenUsage),
+		userDevices:   make(map[string]entity.UserDevice),
+		preferences:   make(map[int64]entity.NotificationPreference),
+		revokedTokens: make(map[string]entity.RevokedToken),
+		resetTokens:   make(map[int64]entity.PasswordResetToken),
+		userMFA:       make(map[int64]entity.UserMFA),
+		mfaChallenges: make(map[int64]entity.MFAChallenge),
+		webhooks:      make(map[int64]entity.WebhookDelivery),
+		featureFlags:  make(map[string]entity.FeatureFlag),
+		apiKeys:       make(map[int64]entity.ApiKey),
+		oauthClients:  make(map[int64]entity.OAuthClient),
+		identities:    make(map[int64]entity.UserIdentity),
+		rateLimits:    make(map[string]entity.RateLimitOverride),
+		auditEvents:   make(map[int64]entity.AuditEvent),
+		nextUserID:    1,
+		nextRoleID:    1,
+
+		nextPermissionID:   1,
+		nextResetTokenID:   1,
+		nextMFAChallengeID: 1,
+		nextWebhookID:      1,
+		nextApiKeyID:       1,
+		nextOAuthClientID:  1,
+		nextIdentityID:     1,
+		nextAuditEventID:   1,
+	}
+}
+
+// AddRole adds a role to the store and returns it with its assigned ID.
+// The role name must be unique (case-insensitive).
+func (s *MemoryStore) AddRole(role entity.Role) (entity.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.roles {
+		if strings.EqualFold(r.Name, role.Name) {
+			return entity.Role{}, fmt.Errorf("role %s already exists: %w", role.Name, gorm.ErrDuplicatedKey)
+		}
+	}
+
+	if role.ID == 0 {
+		role.ID = s.nextRoleID
+	}
+	if _, exists := s.roles[role.ID]; exists {
+		return entity.Role{}, fmt.Errorf("role with ID %d already exists: %w", role.ID, gorm.ErrDuplicatedKey)
+	}
+	if role.ID >= s.nextRoleID {
+		s.nextRoleID = role.ID + 1
+	}
+
+	s.roles[role.ID] = role
+	return role, nil
+}
+
+// AddPermission adds a permission to the store and returns it with its assigned ID.
+// The permission name must be unique.
+func (s *MemoryStore) AddPermission(permission entity.Permission) (entity.Permission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.permissions {
+		if p.Name == permission.Name {
+			return entity.Permission{}, fmt.Errorf("permission %s already exists: %w", permission.Name, gorm.ErrDuplicatedKey)
+		}
+	}
+
+	permission.ID = s.nextPermissionID
+	s.nextPermissionID++
+	s.permissions[permission.ID] = clonePermission(permission)
+
+	return clonePermission(permission), nil
+}
+
+// GrantPermissions grants the permissions with the given names to the role with the given ID,
+// in addition to the permissions it already has.
+func (s *MemoryStore) GrantPermissions(roleID uint, names ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.roles[roleID]; !ok {
+		return fmt.Errorf("role with ID %d does not exist", roleID)
+	}
+
+	for _, name := range names {
+		id, ok := s.permissionID(name)
+		if !ok {
+			return fmt.Errorf("permission %s does not exist", name)
+		}
+		if !containsID(s.rolePerms[roleID], id) {
+			s.rolePerms[roleID] = append(s.rolePerms[roleID], id)
+		}
+	}
+
+	return nil
+}
+
+// AddUser adds a user to the store and returns it with its assigned ID.
+// The username and email must be unique (case-insensitive), and the roles of the user
+// must already exist in the store; they are assigned to the user by ID.
+func (s *MemoryStore) AddUser(user entity.User) (entity.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user.ID == 0 {
+		user.ID = s.nextUserID
+	}
+	if _, exists := s.users[user.ID]; exists {
+		return entity.User{}, fmt.Errorf("user with ID %d already exists: %w", user.ID, gorm.ErrDuplicatedKey)
+	}
+	if err := s.checkUniqueUser(user); err != nil {
+		return entity.User{}, err
+	}
+
+	roleIDs := make([]uint, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		if _, exists := s.roles[role.ID]; !exists {
+			return entity.User{}, fmt.Errorf("role with ID %d does not exist", role.ID)
+		}
+		roleIDs = append(roleIDs, role.ID)
+	}
+
+	if user.ID >= s.nextUserID {
+		s.nextUserID = user.ID + 1
+	}
+
+	user.Roles = nil
+	s.users[user.ID] = cloneUser(user)
+	s.userRoles[user.ID] = roleIDs
+
+	return s.userWithRoles(s.users[user.ID]), nil
+}
+
+// Seed loads the same initial data as the import.sql seed file: the ROLE_USER, ROLE_MODERATOR and ROLE_ADMIN roles
+// with their default permissions, the admin and userone users, and sample consumers.
+func (s *MemoryStore) Seed() error {
+	roles := make(map[string]entity.Role)
+	for _, name := range []string{"ROLE_USER", "ROLE_MODERATOR", "ROLE_ADMIN"} {
+		role, err := s.AddRole(entity.Role{Name: name})
+		if err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", name, err)
+		}
+		roles[name] = role
+	}
+
+	permissions := []struct {
+		name, description string
+	}{
+		{entity.PermissionConsumersRead, "Read the consumers"},
+		{entity.PermissionConsumersWrite, "Create and update the consumers"},
+		{entity.PermissionUsersRead, "Read the users"},
+		{entity.PermissionUsersWrite, "Create, update, and delete the users"},
+		{entity.PermissionRolesRead, "Read the roles and their permissions"},
+		{entity.PermissionRolesWrite, "Create, update, and delete the roles and grant their permissions"},
+	}
+	for _, p := range permissions {
+		description := p.description
+		if _, err := s.AddPermission(entity.Permission{Name: p.name, Description: &description}); err != nil {
+			return fmt.Errorf("failed to seed permission %s: %w", p.name, err)
+		}
+	}
+	for name, permissions := range entity.DefaultRolePermissions {
+		if err := s.GrantPermissions(roles[name].ID, permissions...); err != nil {
+			return fmt.Errorf("failed to seed permissions of role %s: %w", name, err)
+		}
+	}
+
+	users := []struct {
+		username  string
+		firstname string
+		lastname  string
+		role      string
+	}{
+		{"admin", "Admin", "Admin", "ROLE_ADMIN"},
+		{"userone", "User", "One", "ROLE_USER"},
+	}
+	for _, u := range users {
+		deleted := false
+		lastname := u.lastname
+		lastLogin := time.Now()
+		_, err := s.AddUser(entity.User{
+			Username:  u.username,
+			Password:  seedPasswordHash,
+			Email:     u.username + "@mygmail.com",
+			Firstname: u.firstname,
+			Lastname:  &lastname,
+			State:     entity.UserStateActive,
+			IsDeleted: &deleted,
+			UserType:  "USER_ACCOUNT",
+			LastLogin: &lastLogin,
+			Roles:     []entity.Role{roles[u.role]},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to seed user %s: %w", u.username, err)
+		}
+	}
+
+	consumers := []struct {
+		fullname, username, phone, address, birthDate, status string
+	}{
+		{"John Doe", "johndoe", "6281234567890", "Jl. Merdeka No. 123, Jakarta", "1990-05-10", entity.ConsumerStatusActive},
+		{"Jane Smith", "janesmith", "6289876543210", "Jl. Sudirman No. 45, Bandung", "1988-11-23", entity.ConsumerStatusInactive},
+		{"Ahmad Yusuf", "ahmadyusuf", "6281122334455", "Jl. Diponegoro No. 21, Surabaya", "1992-03-15", entity.ConsumerStatusActive},
+		{"Maria Clara", "mariaclara", "6289988776655", "Jl. Gajah Mada No. 10, Yogyakarta", "1995-07-01", entity.ConsumerStatusSuspended},
+		{"Budi Santoso", "budisantoso", "6285566778899", "Jl. Cihampelas No. 7, Bandung", "1985-02-28", entity.ConsumerStatusActive},
+		{"Citra Lestari", "citralestari", "6286655443322", "Jl. Malioboro No. 4, Yogyakarta", "1991-12-12", entity.ConsumerStatusInactive},
+		{"Kevin Pratama", "kevinpratama", "6281346798200", "Jl. Asia Afrika No. 33, Jakarta", "1993-09-30", entity.ConsumerStatusActive},
+		{"Lina Hartati", "linahartati", "6287723456789", "Jl. Braga No. 55, Bandung", "1994-04-18", entity.ConsumerStatusSuspended},
+		{"Fajar Nugroho", "fajarnugroho", "6289001122334", "Jl. Ahmad Yani No. 9, Semarang", "1987-08-22", entity.ConsumerStatusActive},
+		{"Sinta Dewi", "sintadewi", "6283234567890", "Jl. Riau No. 14, Medan", "1996-06-06", entity.ConsumerStatusActive},
+	}
+	repo := NewMemoryConsumerRepository(s)
+	for _, c := range consumers {
+		birthDate, err := time.Parse("2006-01-02", c.birthDate)
+		if err != nil {
+			return fmt.Errorf("failed to parse birth date of consumer %s: %w", c.username, err)
+		}
+
+		_, err = repo.CreateConsumer(context.Background(), nil, entity.Consumer{
+			Fullname:  c.fullname,
+			Username:  c.username,
+			Email:     strings.ReplaceAll(strings.ToLower(c.fullname), " ", ".") + "@example.com",
+			Phone:     c.phone,
+			Address:   c.address,
+			BirthDate: &customtype.Date{Time: birthDate},
+			Status:    c.status,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to seed consumer %s: %w", c.username, err)
+		}
+	}
+
+	return nil
+}
+
+// checkUniqueUser checks the unique constraints of the users table against the other users.
+// The caller must hold the lock.
+func (s *MemoryStore) checkUniqueUser(user entity.User) error {
+	for id, u := range s.users {
+		if id == user.ID {
+			continue
+		}
+		if strings.EqualFold(u.Username, user.Username) {
+			return fmt.Errorf("user with username %s already exists: %w", user.Username, gorm.ErrDuplicatedKey)
+		}
+		if strings.EqualFold(u.Email, user.Email) {
+			return fmt.Errorf("user with email %s already exists: %w", user.Email, gorm.ErrDuplicatedKey)
+		}
+	}
+
+	return nil
+}
+
+// countRoleUsers counts the users the role is assigned to, including the soft-deleted users.
+// The caller must hold the lock.
+func (s *MemoryStore) countRoleUsers(roleID uint) int64 {
+	var count int64
+	for _, roleIDs := range s.userRoles {
+		for _, id := range roleIDs {
+			if id == roleID {
+				count++
+				break
+			}
+		}
+	}
+
+	return count
+}
+
+// permissionID returns the ID of the permission with the given name.
+// The caller must hold the lock.
+func (s *MemoryStore) permissionID(name string) (uint, bool) {
+	for id, p := range s.permissions {
+		if p.Name == name {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
+
+// containsID reports whether the IDs contain the given ID.
+func containsID(ids []uint, id uint) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// userWithRoles returns a copy of the user with its roles resolved, like Preload("Roles").
+// The caller must hold the lock.
+func (s *MemoryStore) userWithRoles(user entity.User) entity.User {
+	user = cloneUser(user)
+
+	roleIDs := s.userRoles[user.ID]
+	user.Roles = make([]entity.Role, 0, len(roleIDs))
+	for _, id := range roleIDs {
+		if role, ok := s.roles[id]; ok {
+			user.Roles = append(user.Roles, cloneRole(role))
+		}
+	}
+	sort.Slice(user.Roles, func(i, j int) bool { return user.Roles[i].ID < user.Roles[j].ID })
+
+	return user
+}
+
+// cloneUser returns a deep copy of the user, so that the store never shares pointers with callers.
+func cloneUser(u entity.User) entity.User {
+	u.Lastname = clonePtr(u.Lastname)
+	u.StateReason = clonePtr(u.StateReason)
+	u.StateChangedAt = clonePtr(u.StateChangedAt)
+	u.IsDeleted = clonePtr(u.IsDeleted)
+	u.AccountExpirationDate = clonePtr(u.AccountExpirationDate)
+	u.CredentialsExpirationDate = clonePtr(u.CredentialsExpirationDate)
+	u.LastLogin = clonePtr(u.LastLogin)
+	u.CreatedBy = clonePtr(u.CreatedBy)
+	u.CreatedAt = clonePtr(u.CreatedAt)
+	u.UpdatedBy = clonePtr(u.UpdatedBy)
+	u.UpdatedAt = clonePtr(u.UpdatedAt)
+	u.DeletedBy = clonePtr(u.DeletedBy)
+	u.DeletedAt = clonePtr(u.DeletedAt)
+	if u.Roles != nil {
+		roles := make([]entity.Role, len(u.Roles))
+		for i, role := range u.Roles {
+			roles[i] = cloneRole(role)
+		}
+		u.Roles = roles
+	}
+
+	return u
+}
+
+// cloneRole returns a deep copy of the role.
+func cloneRole(r entity.Role) entity.Role {
+	r.Description = clonePtr(r.Description)
+	return r
+}
+
+// clonePermission returns a deep copy of the permission.
+func clonePermission(p entity.Permission) entity.Permission {
+	p.Description = clonePtr(p.Description)
+	return p
+}
+
+// cloneConsumer returns a deep copy of the consumer.
+func cloneConsumer(c entity.Consumer) entity.Consumer {
+	c.BirthDate = clonePtr(c.BirthDate)
+	return c
+}
+
+// cloneWebhookDelivery returns a deep copy of the webhook delivery.
+func cloneWebhookDelivery(d entity.WebhookDelivery) entity.WebhookDelivery {
+	d.NextAttemptAt = clonePtr(d.NextAttemptAt)
+	d.LastAttemptAt = clonePtr(d.LastAttemptAt)
+	return d
+}
+
+// clonePtr returns a pointer to a copy of the value, or nil if the pointer is nil.
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// paginate returns the slice bounds of the given page, in the same way as Offset/Limit.
+func paginate(total int, page int, limit int) (int, int) {
+	start := (page - 1) * limit
+	if start < 0 {
+		start = 0
+	}
+	if start > total {
+		start = total
+	}
+
+	end := total
+	if limit > 0 && start+limit < total {
+		end = start + limit
+	}
+
+	return start, end
+}
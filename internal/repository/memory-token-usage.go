@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory TokenUsageRepository backed by a MemoryStore
+// It implements the TokenUsageRepository interface; the tx argument is ignored
+type memoryTokenUsageRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryTokenUsageRepository creates a new instance of TokenUsageRepository backed by the given store.
+func NewMemoryTokenUsageRepository(store *MemoryStore) TokenUsageRepository {
+	return &memoryTokenUsageRepository{store: store}
+}
+
+// IncrementTokenUsage adds the counters of the given usage to the row of its day, user, and client in the store.
+func (r *memoryTokenUsageRepository) IncrementTokenUsage(tx *gorm.DB, usage entity.TokenUsage) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := tokenUsageKey(usage)
+	row, ok := r.store.tokenUsage[key]
+	if !ok {
+		row = entity.TokenUsage{Day: usage.Day, UserID: usage.UserID, Client: usage.Client}
+	}
+	row.Issued += usage.Issued
+	row.Refreshed += usage.Refreshed
+	row.Revoked += usage.Revoked
+	r.store.tokenUsage[key] = row
+
+	return nil
+}
+
+// GetTokenUsage retrieves the token usage rows matching the filter from the store,
+// ordered by day, user ID, and client.
+func (r *memoryTokenUsageRepository) GetTokenUsage(tx *gorm.DB, filter entity.TokenStatsFilter) ([]entity.TokenUsage, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	from, to := filter.From.Format("2006-01-02"), filter.To.Format("2006-01-02")
+	usage := []entity.TokenUsage{}
+	for _, row := range r.store.tokenUsage {
+		day := row.Day.Format("2006-01-02")
+		if day < from || day > to {
+			continue
+		}
+		if filter.UserID != 0 && row.UserID != filter.UserID {
+			continue
+		}
+		if filter.Client != "" && row.Client != filter.Client {
+			continue
+		}
+		usage = append(usage, row)
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		if !usage[i].Day.Equal(usage[j].Day.Time) {
+			return usage[i].Day.Before(usage[j].Day.Time)
+		}
+		if usage[i].UserID != usage[j].UserID {
+			return usage[i].UserID < usage[j].UserID
+		}
+		return usage[i].Client < usage[j].Client
+	})
+
+	return usage, nil
+}
+
+// tokenUsageKey returns the key of the token usage row of the day, user, and client.
+func tokenUsageKey(usage entity.TokenUsage) string {
+	return fmt.Sprintf("%s|%d|%s", usage.Day.Format("2006-01-02"), usage.UserID, usage.Client)
+}
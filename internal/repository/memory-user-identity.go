@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory UserIdentityRepository backed by a MemoryStore
+// It implements the UserIdentityRepository interface; the tx argument is ignored
+type memoryUserIdentityRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryUserIdentityRepository creates a new instance of UserIdentityRepository backed by the given store.
+func NewMemoryUserIdentityRepository(store *MemoryStore) UserIdentityRepository {
+	return &memoryUserIdentityRepository{store: store}
+}
+
+// CreateUserIdentity adds a new user identity to the store.
+// The provider and the subject must be unique together, and the user must exist.
+func (r *memoryUserIdentityRepository) CreateUserIdentity(tx *gorm.DB, identity entity.UserIdentity) (entity.UserIdentity, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[identity.UserID]; !ok {
+		return entity.UserIdentity{}, fmt.Errorf("failed to create user identity: user with ID %d does not exist", identity.UserID)
+	}
+	for _, existing := range r.store.identities {
+		if existing.Provider == identity.Provider && existing.Subject == identity.Subject {
+			return entity.UserIdentity{}, fmt.Errorf("failed to create user identity: %w", gorm.ErrDuplicatedKey)
+		}
+	}
+
+	identity.ID = r.store.nextIdentityID
+	r.store.nextIdentityID++
+	r.store.identities[identity.ID] = cloneUserIdentity(identity)
+
+	return identity, nil
+}
+
+// GetUserIdentity retrieves the user identity of the subject at the provider from the store.
+func (r *memoryUserIdentityRepository) GetUserIdentity(tx *gorm.DB, provider string, subject string) (entity.UserIdentity, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, identity := range r.store.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return cloneUserIdentity(identity), nil
+		}
+	}
+
+	return entity.UserIdentity{}, gorm.ErrRecordNotFound
+}
+
+// UpdateLastLogin records the time of the last login of the user with the identity.
+func (r *memoryUserIdentityRepository) UpdateLastLogin(tx *gorm.DB, id int64, lastLoginAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	identity, ok := r.store.identities[id]
+	if !ok {
+		return nil
+	}
+
+	identity.LastLoginAt = &lastLoginAt
+	r.store.identities[id] = identity
+
+	return nil
+}
+
+// cloneUserIdentity returns a copy of the user identity which does not share its pointers.
+func cloneUserIdentity(identity entity.UserIdentity) entity.UserIdentity {
+	identity.LastLoginAt = clonePtr(identity.LastLoginAt)
+	return identity
+}
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory UserRepository backed by a MemoryStore
+// It implements the UserRepository interface; the ctx and tx arguments are ignored
+type memoryUserRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryUserRepository creates a new instance of UserRepository backed by the given store.
+func NewMemoryUserRepository(store *MemoryStore) UserRepository {
+	return &memoryUserRepository{store: store}
+}
+
+// GetUsers retrieves a page of the users ordered by ID from the store, together with their roles.
+// The soft-deleted users are left out.
+func (r *memoryUserRepository) GetUsers(ctx context.Context, tx *gorm.DB, page int, limit int) ([]entity.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	ids := make([]int64, 0, len(r.store.users))
+	for id, user := range r.store.users {
+		if !isSoftDeleted(user) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	start, end := paginate(len(ids), page, limit)
+	users := make([]entity.User, 0, end-start)
+	for _, id := range ids[start:end] {
+		users = append(users, r.store.userWithRoles(r.store.users[id]))
+	}
+
+	return users, nil
+}
+
+// GetUserByID retrieves a user by its ID from the store.
+func (r *memoryUserRepository) GetUserByID(ctx context.Context, tx *gorm.DB, id int64) (entity.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, ok := r.store.users[id]
+	if !ok || isSoftDeleted(user) {
+		return entity.User{}, gorm.ErrRecordNotFound
+	}
+
+	return r.store.userWithRoles(user), nil
+}
+
+// GetUserByUsername retrieves a user by their username (case-insensitive) from the store.
+func (r *memoryUserRepository) GetUserByUsername(ctx context.Context, tx *gorm.DB, username string) (entity.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, user := range r.store.users {
+		if strings.EqualFold(user.Username, username) && !isSoftDeleted(user) {
+			return r.store.userWithRoles(user), nil
+		}
+	}
+
+	return entity.User{}, gorm.ErrRecordNotFound
+}
+
+// GetUserByEmail retrieves a user by their email (case-insensitive) from the store.
+func (r *memoryUserRepository) GetUserByEmail(ctx context.Context, tx *gorm.DB, email string) (entity.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, user := range r.store.users {
+		if strings.EqualFold(user.Email, email) && !isSoftDeleted(user) {
+			return r.store.userWithRoles(user), nil
+		}
+	}
+
+	return entity.User{}, gorm.ErrRecordNotFound
+}
+
+// CreateUser creates a new user in the store, together with the assignment of its roles.
+// The username and email must be unique, and the roles must exist.
+func (r *memoryUserRepository) CreateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error) {
+	user.ID = 0
+	created, err := r.store.AddUser(user)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return created, nil
+}
+
+// UpdateUser updates an existing user in the store and returns the updated user.
+// The roles of the user are not changed.
+func (r *memoryUserRepository) UpdateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[user.ID]; !ok {
+		return entity.User{}, fmt.Errorf("failed to update user: %w", gorm.ErrRecordNotFound)
+	}
+	if err := r.store.checkUniqueUser(user); err != nil {
+		return entity.User{}, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	stored := cloneUser(user)
+	stored.Roles = nil
+	r.store.users[user.ID] = stored
+
+	return r.store.userWithRoles(stored), nil
+}
+
+// UpdateLastLogin sets the last login time of the user in the store.
+func (r *memoryUserRepository) UpdateLastLogin(ctx context.Context, tx *gorm.DB, id int64, lastLogin time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return fmt.Errorf("failed to update last login of user %d: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	user.LastLogin = &lastLogin
+	r.store.users[id] = user
+
+	return nil
+}
+
+// UpdatePassword sets the password hash of the user and the expiration date of the credentials in the store.
+func (r *memoryUserRepository) UpdatePassword(ctx context.Context, tx *gorm.DB, id int64, password string, credentialsExpiresAt *time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return fmt.Errorf("failed to update password of user %d: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	user.Password = password
+	user.CredentialsExpirationDate = clonePtr(credentialsExpiresAt)
+	r.store.users[id] = user
+
+	return nil
+}
+
+// UpdateUserState sets the state of the user in the store, along with the reason and the time of the change.
+func (r *memoryUserRepository) UpdateUserState(ctx context.Context, tx *gorm.DB, id int64, state entity.UserState, reason *string, changedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return fmt.Errorf("failed to update state of user %d: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	user.State = state
+	user.StateReason = clonePtr(reason)
+	user.StateChangedAt = &changedAt
+	r.store.users[id] = user
+
+	return nil
+}
+
+// SoftDeleteUser marks the user as deleted in the store, the lookups no longer find it.
+func (r *memoryUserRepository) SoftDeleteUser(ctx context.Context, tx *gorm.DB, id int64, deletedBy int64, deletedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok || isSoftDeleted(user) {
+		return fmt.Errorf("failed to delete user %d: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	deleted := true
+	user.IsDeleted = &deleted
+	user.DeletedBy = &deletedBy
+	user.DeletedAt = &gorm.DeletedAt{Time: deletedAt, Valid: true}
+	r.store.users[id] = user
+
+	return nil
+}
+
+// IncrementTokenVersion bumps the token version of the user in the store and returns the new version.
+func (r *memoryUserRepository) IncrementTokenVersion(ctx context.Context, tx *gorm.DB, id int64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return 0, fmt.Errorf("failed to increment token version of user %d: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	user.TokenVersion++
+	r.store.users[id] = user
+
+	return user.TokenVersion, nil
+}
+
+// GetRevokedTokenVersions retrieves the token version of the users of the store whose tokens were revoked at least once.
+func (r *memoryUserRepository) GetRevokedTokenVersions(ctx context.Context, tx *gorm.DB) (map[int64]int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	versions := make(map[int64]int64)
+	for id, user := range r.store.users {
+		if user.TokenVersion > 0 {
+			versions[id] = user.TokenVersion
+		}
+	}
+
+	return versions, nil
+}
+
+// ReplaceUserRoles replaces the roles assigned to the user with the given roles.
+// The user and the roles must exist in the store.
+func (r *memoryUserRepository) ReplaceUserRoles(ctx context.Context, tx *gorm.DB, userID int64, roles []entity.Role) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[userID]; !ok {
+		return fmt.Errorf("failed to replace roles of user %d: %w", userID, gorm.ErrRecordNotFound)
+	}
+
+	roleIDs := make([]uint, 0, len(roles))
+	for _, role := range roles {
+		if _, ok := r.store.roles[role.ID]; !ok {
+			return fmt.Errorf("failed to replace roles of user %d: role with ID %d does not exist", userID, role.ID)
+		}
+		roleIDs = append(roleIDs, role.ID)
+	}
+	r.store.userRoles[userID] = roleIDs
+
+	return nil
+}
+
+// isSoftDeleted reports whether the user was soft-deleted, like the soft delete scope of GORM.
+func isSoftDeleted(user entity.User) bool {
+	return user.DeletedAt != nil && user.DeletedAt.Valid
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the in-memory WebhookDeliveryRepository backed by a MemoryStore
+// It implements the WebhookDeliveryRepository interface; the tx argument is ignored
+type memoryWebhookDeliveryRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryWebhookDeliveryRepository creates a new instance of WebhookDeliveryRepository backed by the given store.
+func NewMemoryWebhookDeliveryRepository(store *MemoryStore) WebhookDeliveryRepository {
+	return &memoryWebhookDeliveryRepository{store: store}
+}
+
+// CreateWebhookDelivery adds a new webhook delivery to the store and returns it with its assigned ID.
+func (r *memoryWebhookDeliveryRepository) CreateWebhookDelivery(tx *gorm.DB, delivery entity.WebhookDelivery) (entity.WebhookDelivery, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delivery.ID = r.store.nextWebhookID
+	r.store.nextWebhookID++
+	delivery = cloneWebhookDelivery(delivery)
+	r.store.webhooks[delivery.ID] = delivery
+
+	return cloneWebhookDelivery(delivery), nil
+}
+
+// GetWebhookDeliveryByID retrieves a webhook delivery by its ID from the store.
+func (r *memoryWebhookDeliveryRepository) GetWebhookDeliveryByID(tx *gorm.DB, id int64) (entity.WebhookDelivery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	delivery, ok := r.store.webhooks[id]
+	if !ok {
+		return entity.WebhookDelivery{}, gorm.ErrRecordNotFound
+	}
+
+	return cloneWebhookDelivery(delivery), nil
+}
+
+// GetWebhookDeliveriesByStatus retrieves a page of the webhook deliveries with one of the given statuses from the store, the most recent first.
+func (r *memoryWebhookDeliveryRepository) GetWebhookDeliveriesByStatus(tx *gorm.DB, statuses []string, page int, limit int) ([]entity.WebhookDelivery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var matching []entity.WebhookDelivery
+	for _, delivery := range r.store.webhooks {
+		for _, status := range statuses {
+			if delivery.Status == status {
+				matching = append(matching, delivery)
+				break
+			}
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool { return matching[i].ID > matching[j].ID })
+
+	start, end := paginate(len(matching), page, limit)
+	deliveries := make([]entity.WebhookDelivery, 0, end-start)
+	for _, delivery := range matching[start:end] {
+		deliveries = append(deliveries, cloneWebhookDelivery(delivery))
+	}
+
+	return deliveries, nil
+}
+
+// GetDueWebhookDeliveries retrieves the pending and failed webhook deliveries whose next attempt is due at the given time
+// from the store, the most overdue first.
+func (r *memoryWebhookDeliveryRepository) GetDueWebhookDeliveries(tx *gorm.DB, now time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var due []entity.WebhookDelivery
+	for _, delivery := range r.store.webhooks {
+		if delivery.Status != entity.WebhookDeliveryPending && delivery.Status != entity.WebhookDeliveryFailed {
+			continue
+		}
+		if delivery.NextAttemptAt == nil || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		due = append(due, cloneWebhookDelivery(delivery))
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(*due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	return due, nil
+}
+
+// UpdateWebhookDelivery saves the status and the outcome of the last attempt of a webhook delivery in the store.
+func (r *memoryWebhookDeliveryRepository) UpdateWebhookDelivery(tx *gorm.DB, delivery entity.WebhookDelivery) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.webhooks[delivery.ID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	existing.Status = delivery.Status
+	existing.Attempts = delivery.Attempts
+	existing.NextAttemptAt = clonePtr(delivery.NextAttemptAt)
+	existing.LastAttemptAt = clonePtr(delivery.LastAttemptAt)
+	existing.ResponseStatus = delivery.ResponseStatus
+	existing.ResponseBody = delivery.ResponseBody
+	existing.LastError = delivery.LastError
+	existing.UpdatedAt = delivery.UpdatedAt
+	r.store.webhooks[delivery.ID] = existing
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=mfa.go -destination=../../tests/mocks/mfa-repository.go -package=mocks
+
+// Interface for MFA repository
+// This interface defines the methods that the MFA repository should implement
+type MFARepository interface {
+	GetUserMFA(tx *gorm.DB, userID int64) (entity.UserMFA, error)
+	SaveUserMFA(tx *gorm.DB, mfa entity.UserMFA) (entity.UserMFA, error)
+	MarkMFAStepUsed(tx *gorm.DB, userID int64, step int64) (bool, error)
+	RemoveUserMFA(tx *gorm.DB, userID int64) (int64, error)
+	CreateMFAChallenge(tx *gorm.DB, challenge entity.MFAChallenge) (entity.MFAChallenge, error)
+	GetMFAChallengeByHash(tx *gorm.DB, tokenHash string) (entity.MFAChallenge, error)
+	UpdateMFAChallenge(tx *gorm.DB, challenge entity.MFAChallenge) error
+	RemoveMFAChallenges(tx *gorm.DB, userID int64) (int64, error)
+}
+
+// This struct defines the MFARepository that contains methods for interacting with the database
+// It implements the MFARepository interface and provides methods for two-factor authentication-related operations
+type mfaRepository struct{}
+
+// NewMFARepository creates a new instance of MFARepository.
+// It initializes the mfaRepository struct and returns it.
+func NewMFARepository() MFARepository {
+	return &mfaRepository{}
+}
+
+// GetUserMFA retrieves the two-factor authentication settings of a user from the database.
+// The row is locked until the end of the transaction, so that the settings are not changed concurrently.
+func (r *mfaRepository) GetUserMFA(tx *gorm.DB, userID int64) (entity.UserMFA, error) {
+	var mfa entity.UserMFA
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&mfa).Error
+	if err != nil {
+		return entity.UserMFA{}, err
+	}
+
+	return mfa, nil
+}
+
+// SaveUserMFA inserts the two-factor authentication settings of a user, or replaces the existing ones.
+func (r *mfaRepository) SaveUserMFA(tx *gorm.DB, mfa entity.UserMFA) (entity.UserMFA, error) {
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "enabled_at", "last_used_step", "created_at"}),
+	}).Create(&mfa).Error
+	if err != nil {
+		return entity.UserMFA{}, fmt.Errorf("failed to save the MFA settings of user %d: %w", mfa.UserID, err)
+	}
+
+	return mfa, nil
+}
+
+// MarkMFAStepUsed records the time step of the code accepted for the user, if it is after the last one.
+// It reports whether the step was recorded, false meaning the code was already used.
+func (r *mfaRepository) MarkMFAStepUsed(tx *gorm.DB, userID int64, step int64) (bool, error) {
+	result := tx.Model(&entity.UserMFA{}).Where("user_id = ? AND last_used_step < ?", userID, step).UpdateColumn("last_used_step", step)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to record the MFA code of user %d: %w", userID, result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// RemoveUserMFA removes the two-factor authentication settings of a user. It returns the number of removed rows.
+func (r *mfaRepository) RemoveUserMFA(tx *gorm.DB, userID int64) (int64, error) {
+	result := tx.Where("user_id = ?", userID).Delete(&entity.UserMFA{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to remove the MFA settings of user %d: %w", userID, result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// CreateMFAChallenge inserts a new MFA challenge into the database.
+func (r *mfaRepository) CreateMFAChallenge(tx *gorm.DB, challenge entity.MFAChallenge) (entity.MFAChallenge, error) {
+	if err := tx.Create(&challenge).Error; err != nil {
+		return entity.MFAChallenge{}, fmt.Errorf("failed to create MFA challenge: %w", err)
+	}
+
+	return challenge, nil
+}
+
+// GetMFAChallengeByHash retrieves an MFA challenge by the hash of its token from the database.
+// The row is locked until the end of the transaction, so that the attempts are counted once.
+func (r *mfaRepository) GetMFAChallengeByHash(tx *gorm.DB, tokenHash string) (entity.MFAChallenge, error) {
+	var challenge entity.MFAChallenge
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", tokenHash).First(&challenge).Error
+	if err != nil {
+		return entity.MFAChallenge{}, err
+	}
+
+	return challenge, nil
+}
+
+// UpdateMFAChallenge updates the attempts and the use time of an MFA challenge.
+func (r *mfaRepository) UpdateMFAChallenge(tx *gorm.DB, challenge entity.MFAChallenge) error {
+	err := tx.Model(&entity.MFAChallenge{}).Where("id = ?", challenge.ID).
+		UpdateColumns(map[string]interface{}{"attempts": challenge.Attempts, "used_at": challenge.UsedAt}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update MFA challenge %d: %w", challenge.ID, err)
+	}
+
+	return nil
+}
+
+// RemoveMFAChallenges removes the MFA challenges of a user. It returns the number of removed challenges.
+func (r *mfaRepository) RemoveMFAChallenges(tx *gorm.DB, userID int64) (int64, error) {
+	result := tx.Where("user_id = ?", userID).Delete(&entity.MFAChallenge{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to remove the MFA challenges of user %d: %w", userID, result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=notification.go -destination=../../tests/mocks/notification-repository.go -package=mocks
+
+// Interface for notification repository
+// This interface defines the methods that the notification repository should implement
+type NotificationRepository interface {
+	GetNotificationPreference(tx *gorm.DB, userID int64) (entity.NotificationPreference, error)
+	SaveNotificationPreference(tx *gorm.DB, pref entity.NotificationPreference) (entity.NotificationPreference, error)
+	CountUserDevices(tx *gorm.DB, userID int64) (int64, error)
+	TouchUserDevice(tx *gorm.DB, device entity.UserDevice) (bool, error)
+}
+
+// This struct defines the NotificationRepository that contains methods for interacting with the database
+// It implements the NotificationRepository interface and provides methods for notification-related operations
+type notificationRepository struct{}
+
+// NewNotificationRepository creates a new instance of NotificationRepository.
+// It initializes the notificationRepository struct and returns it.
+func NewNotificationRepository() NotificationRepository {
+	return &notificationRepository{}
+}
+
+// GetNotificationPreference retrieves the notification preferences of the user from the database.
+func (r *notificationRepository) GetNotificationPreference(tx *gorm.DB, userID int64) (entity.NotificationPreference, error) {
+	var pref entity.NotificationPreference
+	if err := tx.First(&pref, "user_id = ?", userID).Error; err != nil {
+		return entity.NotificationPreference{}, err
+	}
+
+	return pref, nil
+}
+
+// SaveNotificationPreference creates or replaces the notification preferences of the user in the database.
+func (r *notificationRepository) SaveNotificationPreference(tx *gorm.DB, pref entity.NotificationPreference) (entity.NotificationPreference, error) {
+	if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&pref).Error; err != nil {
+		return entity.NotificationPreference{}, fmt.Errorf("failed to save notification preferences of user %d: %w", pref.UserID, err)
+	}
+
+	return pref, nil
+}
+
+// CountUserDevices counts the devices the user has logged in from.
+func (r *notificationRepository) CountUserDevices(tx *gorm.DB, userID int64) (int64, error) {
+	var count int64
+	if err := tx.Model(&entity.UserDevice{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// TouchUserDevice records a login of the user from the device.
+// It updates the last seen time and IP of a known device, or creates the device and returns true if it is new.
+func (r *notificationRepository) TouchUserDevice(tx *gorm.DB, device entity.UserDevice) (bool, error) {
+	result := tx.Model(&entity.UserDevice{}).
+		Where("user_id = ? AND fingerprint = ?", device.UserID, device.Fingerprint).
+		Updates(map[string]interface{}{
+			"last_seen_at": device.LastSeenAt,
+			"last_ip":      device.LastIP,
+			"last_country": device.LastCountry,
+			"last_city":    device.LastCity,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update device of user %d: %w", device.UserID, result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return false, nil
+	}
+
+	// A concurrent login from the same new device may have created it in the meantime
+	result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&device)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to create device of user %d: %w", device.UserID, result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=oauth-client.go -destination=../../tests/mocks/oauth-client-repository.go -package=mocks
+
+// Interface for OAuth client repository
+// This interface defines the methods that the OAuth client repository should implement
+type OAuthClientRepository interface {
+	CreateOAuthClient(tx *gorm.DB, client entity.OAuthClient) (entity.OAuthClient, error)
+	GetOAuthClientByID(tx *gorm.DB, id int64) (entity.OAuthClient, error)
+	GetOAuthClientByClientID(tx *gorm.DB, clientID string) (entity.OAuthClient, error)
+	GetOAuthClientsByUserID(tx *gorm.DB, userID int64) ([]entity.OAuthClient, error)
+	RevokeOAuthClient(tx *gorm.DB, id int64, revokedAt time.Time) (bool, error)
+}
+
+// This struct defines the OAuthClientRepository that contains methods for interacting with the database
+// It implements the OAuthClientRepository interface and provides methods for OAuth client-related operations
+type oauthClientRepository struct{}
+
+// NewOAuthClientRepository creates a new instance of OAuthClientRepository.
+// It initializes the oauthClientRepository struct and returns it.
+func NewOAuthClientRepository() OAuthClientRepository {
+	return &oauthClientRepository{}
+}
+
+// CreateOAuthClient inserts a new OAuth client into the database.
+func (r *oauthClientRepository) CreateOAuthClient(tx *gorm.DB, client entity.OAuthClient) (entity.OAuthClient, error) {
+	if err := tx.Create(&client).Error; err != nil {
+		return entity.OAuthClient{}, fmt.Errorf("failed to create OAuth client: %w", err)
+	}
+
+	return client, nil
+}
+
+// GetOAuthClientByID retrieves an OAuth client by its ID from the database.
+func (r *oauthClientRepository) GetOAuthClientByID(tx *gorm.DB, id int64) (entity.OAuthClient, error) {
+	var client entity.OAuthClient
+	if err := tx.First(&client, "id = ?", id).Error; err != nil {
+		return entity.OAuthClient{}, err
+	}
+
+	return client, nil
+}
+
+// GetOAuthClientByClientID retrieves an OAuth client by its client ID from the database.
+func (r *oauthClientRepository) GetOAuthClientByClientID(tx *gorm.DB, clientID string) (entity.OAuthClient, error) {
+	var client entity.OAuthClient
+	if err := tx.First(&client, "client_id = ?", clientID).Error; err != nil {
+		return entity.OAuthClient{}, err
+	}
+
+	return client, nil
+}
+
+// GetOAuthClientsByUserID retrieves the OAuth clients of the user from the database, oldest first, the revoked ones included.
+func (r *oauthClientRepository) GetOAuthClientsByUserID(tx *gorm.DB, userID int64) ([]entity.OAuthClient, error) {
+	var clients []entity.OAuthClient
+	if err := tx.Order("id ASC").Find(&clients, "user_id = ?", userID).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve OAuth clients of user %d: %w", userID, err)
+	}
+
+	return clients, nil
+}
+
+// RevokeOAuthClient records the time the OAuth client was revoked, if it was not revoked yet.
+// It returns false if the client does not exist or was already revoked, e.g. by a concurrent request.
+func (r *oauthClientRepository) RevokeOAuthClient(tx *gorm.DB, id int64, revokedAt time.Time) (bool, error) {
+	result := tx.Model(&entity.OAuthClient{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to revoke OAuth client %d: %w", id, result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
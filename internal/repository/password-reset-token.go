@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=password-reset-token.go -destination=../../tests/mocks/password-reset-token-repository.go -package=mocks
+
+// Interface for password reset token repository
+// This interface defines the methods that the password reset token repository should implement
+type PasswordResetTokenRepository interface {
+	CreatePasswordResetToken(tx *gorm.DB, token entity.PasswordResetToken) (entity.PasswordResetToken, error)
+	GetPasswordResetTokenByHash(tx *gorm.DB, tokenHash string) (entity.PasswordResetToken, error)
+	MarkPasswordResetTokenUsed(tx *gorm.DB, id int64, usedAt time.Time) (bool, error)
+	RemoveUnusedPasswordResetTokens(tx *gorm.DB, userID int64) (int64, error)
+}
+
+// This struct defines the PasswordResetTokenRepository that contains methods for interacting with the database
+// It implements the PasswordResetTokenRepository interface and provides methods for password reset token-related operations
+type passwordResetTokenRepository struct{}
+
+// NewPasswordResetTokenRepository creates a new instance of PasswordResetTokenRepository.
+// It initializes the passwordResetTokenRepository struct and returns it.
+func NewPasswordResetTokenRepository() PasswordResetTokenRepository {
+	return &passwordResetTokenRepository{}
+}
+
+// CreatePasswordResetToken inserts a new password reset token into the database.
+func (r *passwordResetTokenRepository) CreatePasswordResetToken(tx *gorm.DB, token entity.PasswordResetToken) (entity.PasswordResetToken, error) {
+	if err := tx.Create(&token).Error; err != nil {
+		return entity.PasswordResetToken{}, fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	return token, nil
+}
+
+// GetPasswordResetTokenByHash retrieves a password reset token by the hash of the token from the database.
+// The row is locked until the end of the transaction, so that the token cannot be used twice concurrently.
+func (r *passwordResetTokenRepository) GetPasswordResetTokenByHash(tx *gorm.DB, tokenHash string) (entity.PasswordResetToken, error) {
+	var token entity.PasswordResetToken
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return entity.PasswordResetToken{}, err
+	}
+
+	return token, nil
+}
+
+// MarkPasswordResetTokenUsed records the time the token was used, if it was not used yet.
+// It reports whether the token was marked, false meaning it was already used.
+func (r *passwordResetTokenRepository) MarkPasswordResetTokenUsed(tx *gorm.DB, id int64, usedAt time.Time) (bool, error) {
+	result := tx.Model(&entity.PasswordResetToken{}).Where("id = ? AND used_at IS NULL", id).UpdateColumn("used_at", usedAt)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to mark password reset token %d as used: %w", id, result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// RemoveUnusedPasswordResetTokens removes the password reset tokens of the user which were not used,
+// so that only the latest reset link of the user works. It returns the number of removed tokens.
+func (r *passwordResetTokenRepository) RemoveUnusedPasswordResetTokens(tx *gorm.DB, userID int64) (int64, error) {
+	result := tx.Where("user_id = ? AND used_at IS NULL", userID).Delete(&entity.PasswordResetToken{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to remove password reset tokens of user %d: %w", userID, result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=permission.go -destination=../../tests/mocks/permission-repository.go -package=mocks
+
+// Interface for permission repository
+// This interface defines the methods that the permission repository should implement
+type PermissionRepository interface {
+	GetPermissions(tx *gorm.DB) ([]entity.Permission, error)
+	GetPermissionsByNames(tx *gorm.DB, names []string) ([]entity.Permission, error)
+	GetRolePermissions(tx *gorm.DB, roleID uint) ([]entity.Permission, error)
+	ReplaceRolePermissions(tx *gorm.DB, roleID uint, permissionIDs []uint) error
+	GetPermissionNamesByRoleNames(tx *gorm.DB, roleNames []string) ([]string, error)
+}
+
+// This struct defines the PermissionRepository that contains methods for interacting with the database
+type permissionRepository struct{}
+
+// NewPermissionRepository creates a new instance of PermissionRepository.
+// It initializes the permissionRepository struct and returns it.
+func NewPermissionRepository() PermissionRepository {
+	return &permissionRepository{}
+}
+
+// GetPermissions retrieves all the permissions from the database, ordered by ID.
+func (r *permissionRepository) GetPermissions(tx *gorm.DB) ([]entity.Permission, error) {
+	var permissions []entity.Permission
+	if err := tx.Order("id ASC").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// GetPermissionsByNames retrieves the permissions with the given names from the database, ordered by ID.
+// The names without a permission are skipped.
+func (r *permissionRepository) GetPermissionsByNames(tx *gorm.DB, names []string) ([]entity.Permission, error) {
+	var permissions []entity.Permission
+	if len(names) == 0 {
+		return permissions, nil
+	}
+
+	if err := tx.Where("name IN ?", names).Order("id ASC").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// GetRolePermissions retrieves the permissions granted to the role with the given ID, ordered by ID.
+func (r *permissionRepository) GetRolePermissions(tx *gorm.DB, roleID uint) ([]entity.Permission, error) {
+	var permissions []entity.Permission
+	err := tx.Model(&entity.Permission{}).
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Where("role_permissions.role_id = ?", roleID).
+		Order("permissions.id ASC").
+		Find(&permissions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions of role %d: %w", roleID, err)
+	}
+
+	return permissions, nil
+}
+
+// ReplaceRolePermissions replaces the permissions granted to the role with the given ID.
+func (r *permissionRepository) ReplaceRolePermissions(tx *gorm.DB, roleID uint, permissionIDs []uint) error {
+	if err := tx.Where("role_id = ?", roleID).Delete(&entity.RolePermission{}).Error; err != nil {
+		return fmt.Errorf("failed to remove permissions of role %d: %w", roleID, err)
+	}
+
+	if len(permissionIDs) == 0 {
+		return nil
+	}
+
+	rolePermissions := make([]entity.RolePermission, 0, len(permissionIDs))
+	for _, id := range permissionIDs {
+		rolePermissions = append(rolePermissions, entity.RolePermission{RoleID: roleID, PermissionID: id})
+	}
+	if err := tx.Create(&rolePermissions).Error; err != nil {
+		return fmt.Errorf("failed to grant permissions to role %d: %w", roleID, err)
+	}
+
+	return nil
+}
+
+// GetPermissionNamesByRoleNames retrieves the distinct names of the permissions granted to any of the roles
+// with the given names, sorted by name.
+func (r *permissionRepository) GetPermissionNamesByRoleNames(tx *gorm.DB, roleNames []string) ([]string, error) {
+	names := []string{}
+	if len(roleNames) == 0 {
+		return names, nil
+	}
+
+	err := tx.Model(&entity.Permission{}).
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name IN ?", roleNames).
+		Order("permissions.name ASC").
+		Pluck("permissions.name", &names).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions of roles: %w", err)
+	}
+
+	return names, nil
+}
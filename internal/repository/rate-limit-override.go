@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=rate-limit-override.go -destination=../../tests/mocks/rate-limit-override-repository.go -package=mocks
+
+// Interface for rate limit override repository
+// This interface defines the methods that the rate limit override repository should implement
+type RateLimitOverrideRepository interface {
+	GetRateLimitOverrides(tx *gorm.DB) ([]entity.RateLimitOverride, error)
+	SaveRateLimitOverride(tx *gorm.DB, override entity.RateLimitOverride) (entity.RateLimitOverride, error)
+	DeleteRateLimitOverride(tx *gorm.DB, client string) error
+}
+
+// This struct defines the RateLimitOverrideRepository that contains methods for interacting with the database
+// It implements the RateLimitOverrideRepository interface and provides methods for rate limit override-related operations
+type rateLimitOverrideRepository struct{}
+
+// NewRateLimitOverrideRepository creates a new instance of RateLimitOverrideRepository.
+// It initializes the rateLimitOverrideRepository struct and returns it.
+func NewRateLimitOverrideRepository() RateLimitOverrideRepository {
+	return &rateLimitOverrideRepository{}
+}
+
+// GetRateLimitOverrides retrieves the rate limit overrides saved in the database, ordered by client.
+func (r *rateLimitOverrideRepository) GetRateLimitOverrides(tx *gorm.DB) ([]entity.RateLimitOverride, error) {
+	var overrides []entity.RateLimitOverride
+	if err := tx.Order("client").Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+// SaveRateLimitOverride creates or replaces the rate limit override of the client in the database.
+func (r *rateLimitOverrideRepository) SaveRateLimitOverride(tx *gorm.DB, override entity.RateLimitOverride) (entity.RateLimitOverride, error) {
+	if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&override).Error; err != nil {
+		return entity.RateLimitOverride{}, fmt.Errorf("failed to save rate limit override: %w", err)
+	}
+
+	return override, nil
+}
+
+// DeleteRateLimitOverride deletes the rate limit override of the client from the database.
+// It returns gorm.ErrRecordNotFound if the client has no override.
+func (r *rateLimitOverrideRepository) DeleteRateLimitOverride(tx *gorm.DB, client string) error {
+	result := tx.Where("client = ?", client).Delete(&entity.RateLimitOverride{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete rate limit override: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -8,12 +8,21 @@ import (
 	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
 )
 
+//go:generate go tool mockgen -source=refresh-token.go -destination=../../tests/mocks/refresh-token-repository.go -package=mocks
+
+// sessionLockClass is the first key of the PostgreSQL advisory locks of the sessions of the users, the second being the user ID.
+const sessionLockClass int32 = 4491
+
 // Interface for refresh token repository
 // This interface defines the methods that the refresh token repository should implement
 type RefreshTokenRepository interface {
 	GetRefreshTokenByUserID(tx *gorm.DB, userID int64) (entity.RefreshToken, error)
 	GetRefreshTokenByToken(tx *gorm.DB, token string) (entity.RefreshToken, error)
+	GetRefreshTokensByUserID(tx *gorm.DB, userID int64) ([]entity.RefreshToken, error)
+	LockUserSessions(tx *gorm.DB, userID int64) error
 	CreateRefreshToken(tx *gorm.DB, token entity.RefreshToken) (entity.RefreshToken, error)
+	RemoveRefreshToken(tx *gorm.DB, token string) (bool, error)
+	RemoveRefreshTokenBySessionID(tx *gorm.DB, userID int64, sessionID string) (bool, error)
 	RemoveRefreshTokenByUserID(tx *gorm.DB, userID int64) (bool, error)
 }
 
@@ -27,11 +36,11 @@ func NewRefreshTokenRepository() RefreshTokenRepository {
 	return &refreshTokenRepository{}
 }
 
-// GetRefreshTokenByUserID retrieves a refresh token by its user ID from the database.
+// GetRefreshTokenByUserID retrieves the most recent refresh token of the user from the database.
 func (r *refreshTokenRepository) GetRefreshTokenByUserID(tx *gorm.DB, userID int64) (entity.RefreshToken, error) {
-	// Select the refresh token with the given user ID from the database
+	// Select the latest refresh token with the given user ID from the database
 	var refreshToken entity.RefreshToken
-	err := tx.First(&refreshToken, "user_id = ?", userID).Error
+	err := tx.Order("created_at DESC").First(&refreshToken, "user_id = ?", userID).Error
 	if err != nil {
 		return entity.RefreshToken{}, err
 	}
@@ -51,6 +60,29 @@ func (r *refreshTokenRepository) GetRefreshTokenByToken(tx *gorm.DB, token strin
 	return refreshToken, nil
 }
 
+// GetRefreshTokensByUserID retrieves the refresh tokens of the user from the database, oldest first.
+func (r *refreshTokenRepository) GetRefreshTokensByUserID(tx *gorm.DB, userID int64) ([]entity.RefreshToken, error) {
+	var refreshTokens []entity.RefreshToken
+	if err := tx.Order("created_at ASC").Find(&refreshTokens, "user_id = ?", userID).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve refresh tokens of user %d: %w", userID, err)
+	}
+
+	return refreshTokens, nil
+}
+
+// LockUserSessions takes the PostgreSQL advisory lock of the sessions of the user until the end of the transaction tx,
+// so that the sessions of a user are counted and created by one transaction at a time, even while the user has none.
+// The lock is keyed by the user ID rather than taken on the row of the user, which is not in the database
+// when the users are read from an external user store.
+func (r *refreshTokenRepository) LockUserSessions(tx *gorm.DB, userID int64) error {
+	// The users whose IDs share their lower 32 bits share a lock, which only serializes their logins
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", sessionLockClass, int32(userID)).Error; err != nil {
+		return fmt.Errorf("failed to lock the sessions of user %d: %w", userID, err)
+	}
+
+	return nil
+}
+
 // CreateRefreshToken creates a new refresh token in the database.
 func (r *refreshTokenRepository) CreateRefreshToken(tx *gorm.DB, token entity.RefreshToken) (entity.RefreshToken, error) {
 	// Create a new refresh token in the database
@@ -61,9 +93,31 @@ func (r *refreshTokenRepository) CreateRefreshToken(tx *gorm.DB, token entity.Re
 	return token, nil
 }
 
-// RemoveRefreshTokenByUserID removes a refresh token by its user ID from the database.
+// RemoveRefreshToken removes a refresh token by its token string from the database.
+// It returns false if the token does not exist, e.g. when it was already removed by a concurrent request.
+func (r *refreshTokenRepository) RemoveRefreshToken(tx *gorm.DB, token string) (bool, error) {
+	result := tx.Where("token = ?", token).Delete(&entity.RefreshToken{})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to remove refresh token: %w", result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// RemoveRefreshTokenBySessionID removes the refresh token of the given session of the user from the database.
+// It returns false if the user has no such session.
+func (r *refreshTokenRepository) RemoveRefreshTokenBySessionID(tx *gorm.DB, userID int64, sessionID string) (bool, error) {
+	result := tx.Where("user_id = ? AND session_id = ?", userID, sessionID).Delete(&entity.RefreshToken{})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to remove the session %s of user %d: %w", sessionID, userID, result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// RemoveRefreshTokenByUserID removes all the refresh tokens of the user from the database.
 func (r *refreshTokenRepository) RemoveRefreshTokenByUserID(tx *gorm.DB, userID int64) (bool, error) {
-	// Delete the refresh token with the given user ID from the database
+	// Delete the refresh tokens with the given user ID from the database
 	if err := tx.Where("user_id = ?", userID).Delete(&entity.RefreshToken{}).Error; err != nil {
 		return false, fmt.Errorf("failed to remove refresh token by user ID %d: %w", userID, err)
 	}
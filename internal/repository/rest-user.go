@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+/**
+ * The REST user store reads and writes the users with an identity service, e.g. a thin API in front of
+ * an existing user database. The users are exchanged as the JSON of entity.User, with their token version
+ * in tokenVersion and their bcrypt password hash in password. The service serves, under USER_STORE_URL:
+ *   - GET    /users?page=&limit=          the page of the users, as an array
+ *   - GET    /users/{id}                  the user, or 404
+ *   - GET    /users/by-username/{username} the user with the username (case-insensitive), or 404
+ *   - GET    /users/by-email/{email}      the user with the email (case-insensitive), or 404
+ *   - POST   /users                       creates the user and returns it with its ID, or 409 if the username or the email is taken
+ *   - PUT    /users/{id}                  replaces the profile of the user and returns it, or 409 if the email is taken
+ *   - PATCH  /users/{id}                  sets the given fields of the user, e.g. lastLogin or state, and responds with 204
+ *   - POST   /users/{id}/token-version    increments the token version of the user and returns {"tokenVersion": n}
+ *   - GET    /users/token-versions        the token versions above 0, as an object of user IDs to versions
+ *   - PUT    /users/{id}/roles            replaces the roles of the user with the given array of roles, and responds with 204
+ */
+
+// restContentType is the media type of the requests and responses of the REST user store.
+const restContentType = "application/json"
+
+// restUser is a user as exchanged with the REST user store, with its token version.
+type restUser struct {
+	entity.User
+	TokenVersion int64 `json:"tokenVersion"`
+}
+
+// This struct defines the UserStore backed by a REST identity service
+// It implements the UserStore interface
+type restUserStore struct {
+	client userStoreClient
+}
+
+// newRESTUserStore creates a new instance of UserStore reading and writing the users with the identity service of the client.
+func newRESTUserStore(client userStoreClient) UserStore {
+	return &restUserStore{client: client}
+}
+
+// GetUsers retrieves a page of the users from the identity service.
+func (s *restUserStore) GetUsers(ctx context.Context, page int, limit int) ([]entity.User, error) {
+	var users []restUser
+	query := url.Values{"page": {strconv.Itoa(page)}, "limit": {strconv.Itoa(limit)}}
+	if err := s.client.do(ctx, http.MethodGet, "/users?"+query.Encode(), restContentType, nil, &users); err != nil {
+		return nil, err
+	}
+
+	result := make([]entity.User, 0, len(users))
+	for _, u := range users {
+		result = append(result, u.entity())
+	}
+
+	return result, nil
+}
+
+// GetUserByID retrieves a user by its ID from the identity service.
+func (s *restUserStore) GetUserByID(ctx context.Context, id int64) (entity.User, error) {
+	return s.get(ctx, restUserPath(id))
+}
+
+// GetUserByUsername retrieves a user by its username from the identity service.
+func (s *restUserStore) GetUserByUsername(ctx context.Context, username string) (entity.User, error) {
+	return s.get(ctx, "/users/by-username/"+url.PathEscape(username))
+}
+
+// GetUserByEmail retrieves a user by its email from the identity service.
+func (s *restUserStore) GetUserByEmail(ctx context.Context, email string) (entity.User, error) {
+	return s.get(ctx, "/users/by-email/"+url.PathEscape(email))
+}
+
+// CreateUser creates a user with the identity service, which assigns its ID.
+func (s *restUserStore) CreateUser(ctx context.Context, user entity.User) (entity.User, error) {
+	var created restUser
+	if err := s.client.do(ctx, http.MethodPost, "/users", restContentType, restUser{User: user, TokenVersion: user.TokenVersion}, &created); err != nil {
+		return entity.User{}, err
+	}
+
+	return created.entity(), nil
+}
+
+// UpdateUser replaces the profile of a user with the identity service.
+func (s *restUserStore) UpdateUser(ctx context.Context, user entity.User) (entity.User, error) {
+	var updated restUser
+	if err := s.client.do(ctx, http.MethodPut, restUserPath(user.ID), restContentType, restUser{User: user, TokenVersion: user.TokenVersion}, &updated); err != nil {
+		return entity.User{}, err
+	}
+
+	return updated.entity(), nil
+}
+
+// UpdateLastLogin sets the last login time of a user with the identity service.
+func (s *restUserStore) UpdateLastLogin(ctx context.Context, id int64, lastLogin time.Time) error {
+	return s.patch(ctx, id, map[string]interface{}{"lastLogin": lastLogin})
+}
+
+// UpdatePassword sets the password hash of a user and the expiration date of its credentials with the identity service.
+func (s *restUserStore) UpdatePassword(ctx context.Context, id int64, password string, credentialsExpiresAt *time.Time) error {
+	return s.patch(ctx, id, map[string]interface{}{"password": password, "credentialsExpirationDate": credentialsExpiresAt})
+}
+
+// UpdateUserState sets the state of a user with the identity service.
+func (s *restUserStore) UpdateUserState(ctx context.Context, id int64, state entity.UserState, reason *string, changedAt time.Time) error {
+	return s.patch(ctx, id, map[string]interface{}{"state": state, "stateReason": reason, "stateChangedAt": changedAt})
+}
+
+// SoftDeleteUser marks a user as deleted with the identity service.
+func (s *restUserStore) SoftDeleteUser(ctx context.Context, id int64, deletedBy int64, deletedAt time.Time) error {
+	return s.patch(ctx, id, map[string]interface{}{"isDeleted": true, "deletedBy": deletedBy, "deletedAt": deletedAt})
+}
+
+// IncrementTokenVersion increments the token version of a user with the identity service, and returns the new version.
+func (s *restUserStore) IncrementTokenVersion(ctx context.Context, id int64) (int64, error) {
+	var resp struct {
+		TokenVersion int64 `json:"tokenVersion"`
+	}
+	if err := s.client.do(ctx, http.MethodPost, restUserPath(id)+"/token-version", restContentType, nil, &resp); err != nil {
+		return 0, err
+	}
+
+	return resp.TokenVersion, nil
+}
+
+// GetRevokedTokenVersions retrieves the token versions of the users whose tokens were revoked from the identity service.
+func (s *restUserStore) GetRevokedTokenVersions(ctx context.Context) (map[int64]int64, error) {
+	var resp map[string]int64
+	if err := s.client.do(ctx, http.MethodGet, "/users/token-versions", restContentType, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	versions := make(map[int64]int64, len(resp))
+	for key, version := range resp {
+		id, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid user ID %q in the token versions", ErrUserStoreUnavailable, key)
+		}
+		versions[id] = version
+	}
+
+	return versions, nil
+}
+
+// ReplaceUserRoles replaces the roles of a user with the identity service.
+func (s *restUserStore) ReplaceUserRoles(ctx context.Context, userID int64, roles []entity.Role) error {
+	if roles == nil {
+		roles = []entity.Role{}
+	}
+
+	return s.client.do(ctx, http.MethodPut, restUserPath(userID)+"/roles", restContentType, roles, nil)
+}
+
+// get retrieves the user at the path from the identity service.
+func (s *restUserStore) get(ctx context.Context, path string) (entity.User, error) {
+	var user restUser
+	if err := s.client.do(ctx, http.MethodGet, path, restContentType, nil, &user); err != nil {
+		return entity.User{}, err
+	}
+
+	return user.entity(), nil
+}
+
+// patch sets the given fields of a user with the identity service.
+func (s *restUserStore) patch(ctx context.Context, id int64, fields map[string]interface{}) error {
+	return s.client.do(ctx, http.MethodPatch, restUserPath(id), restContentType, fields, nil)
+}
+
+// restUserPath returns the path of the user with the given ID.
+func restUserPath(id int64) string {
+	return "/users/" + strconv.FormatInt(id, 10)
+}
+
+// entity returns the user with its token version.
+func (u restUser) entity() entity.User {
+	user := u.User
+	user.TokenVersion = u.TokenVersion
+	return user
+}
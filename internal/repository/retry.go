@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+)
+
+/**
+ * The retrying repositories retry the idempotent reads failing with a transient database error, instead of failing
+ * the request with a 500: the serialization failures and deadlocks, which PostgreSQL resolves by aborting one of the
+ * conflicting statements, and the connections lost or refused, e.g. while the database fails over or restarts.
+ * The reads are retried up to DB_READ_RETRIES times, after a delay doubling after every attempt, with full jitter,
+ * so that the requests failing together do not retry together. The writes are never retried, since a write whose
+ * connection was lost may have been applied; neither are the reads of a transaction, which is aborted by the error.
+ * The reads failing with ErrCircuitOpen of the database package, rejected while the database is unavailable, are not retried,
+ * and the transient errors still failing the reads after their retries are answered with 503 by the handlers.
+ * The retries are exported as db_read_retries_total{operation} on /metrics.
+ */
+
+// SQLSTATE codes of the transient PostgreSQL errors answered by the database, the others being those of a database unavailable.
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// IsTransientError reports whether the error is a transient database error, after which the same read may succeed:
+// a serialization failure, a deadlock, or a connection lost, refused, or rejected by a database shutting down.
+// The cancellations and the deadlines of the requests are not transient, neither are the reads rejected by the open circuit breaker.
+func IsTransientError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected) {
+		return true
+	}
+
+	return database.IsUnavailableError(err)
+}
+
+// retryRead runs the read, and runs it again while it fails with a transient error, up to the retries of the policy.
+// The read is run once when it is part of a transaction, which the error has aborted, or when the context is done.
+// The delay before a retry is cut short when the context is done, returning the last error of the read.
+func retryRead[T any](ctx context.Context, policy policyconfig.RetryPolicy, tx *gorm.DB, operation string, read func() (T, error)) (T, error) {
+	result, err := read()
+	if err == nil || inTransaction(tx) {
+		return result, err
+	}
+
+	for retry := 1; retry <= policy.Retries && IsTransientError(err); retry++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if policy.Sleep != nil {
+			policy.Sleep(policy.RetryDelay(retry))
+		} else {
+			select {
+			case <-ctx.Done():
+				return result, err
+			case <-time.After(policy.RetryDelay(retry)):
+			}
+		}
+		metrics.DatabaseReadRetry(operation)
+		result, err = read()
+	}
+
+	return result, err
+}
+
+// inTransaction reports whether the statements of tx are run in a transaction.
+func inTransaction(tx *gorm.DB) bool {
+	if tx == nil || tx.Statement == nil {
+		return false
+	}
+
+	_, ok := tx.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the retrying ConsumerRepository that wraps another ConsumerRepository
+// It implements the ConsumerRepository interface; only the reads are retried, the writes are passed through.
+// StreamConsumers is not retried either, since the consumers already streamed would be streamed again.
+type retryingConsumerRepository struct {
+	ConsumerRepository
+	policy policyconfig.RetryPolicy
+}
+
+// NewRetryingConsumerRepository creates a new instance of ConsumerRepository that retries the reads of the given repository
+// failing with a transient database error, as configured by the policy. It returns the repository itself if the retries are disabled.
+func NewRetryingConsumerRepository(repo ConsumerRepository, policy policyconfig.RetryPolicy) ConsumerRepository {
+	if !policy.Enabled() {
+		return repo
+	}
+
+	return &retryingConsumerRepository{ConsumerRepository: repo, policy: policy}
+}
+
+// GetAllConsumers retrieves the consumers from the wrapped repository, retrying the transient errors.
+func (r *retryingConsumerRepository) GetAllConsumers(ctx context.Context, tx *gorm.DB, page int, limit int) ([]entity.Consumer, error) {
+	return retryRead(ctx, r.policy, tx, "GetAllConsumers", func() ([]entity.Consumer, error) {
+		return r.ConsumerRepository.GetAllConsumers(ctx, tx, page, limit)
+	})
+}
+
+// GetConsumerByID retrieves a consumer by its ID from the wrapped repository, retrying the transient errors.
+func (r *retryingConsumerRepository) GetConsumerByID(ctx context.Context, tx *gorm.DB, id string) (entity.Consumer, error) {
+	return retryRead(ctx, r.policy, tx, "GetConsumerByID", func() (entity.Consumer, error) {
+		return r.ConsumerRepository.GetConsumerByID(ctx, tx, id)
+	})
+}
+
+// GetConsumerByUsername retrieves a consumer by its username from the wrapped repository, retrying the transient errors.
+func (r *retryingConsumerRepository) GetConsumerByUsername(ctx context.Context, tx *gorm.DB, username string) (entity.Consumer, error) {
+	return retryRead(ctx, r.policy, tx, "GetConsumerByUsername", func() (entity.Consumer, error) {
+		return r.ConsumerRepository.GetConsumerByUsername(ctx, tx, username)
+	})
+}
+
+// GetConsumerByEmail retrieves a consumer by its email from the wrapped repository, retrying the transient errors.
+func (r *retryingConsumerRepository) GetConsumerByEmail(ctx context.Context, tx *gorm.DB, email string) (entity.Consumer, error) {
+	return retryRead(ctx, r.policy, tx, "GetConsumerByEmail", func() (entity.Consumer, error) {
+		return r.ConsumerRepository.GetConsumerByEmail(ctx, tx, email)
+	})
+}
+
+// GetConsumerByPhone retrieves a consumer by its phone from the wrapped repository, retrying the transient errors.
+func (r *retryingConsumerRepository) GetConsumerByPhone(ctx context.Context, tx *gorm.DB, phone string) (entity.Consumer, error) {
+	return retryRead(ctx, r.policy, tx, "GetConsumerByPhone", func() (entity.Consumer, error) {
+		return r.ConsumerRepository.GetConsumerByPhone(ctx, tx, phone)
+	})
+}
+
+// GetConsumersByStatus retrieves the consumers with the given status from the wrapped repository, retrying the transient errors.
+func (r *retryingConsumerRepository) GetConsumersByStatus(ctx context.Context, tx *gorm.DB, status string, page int, limit int) ([]entity.Consumer, error) {
+	return retryRead(ctx, r.policy, tx, "GetConsumersByStatus", func() ([]entity.Consumer, error) {
+		return r.ConsumerRepository.GetConsumersByStatus(ctx, tx, status, page, limit)
+	})
+}
+
+// GetConsumersExcludingStatuses retrieves the consumers without the given statuses from the wrapped repository, retrying the transient errors.
+func (r *retryingConsumerRepository) GetConsumersExcludingStatuses(ctx context.Context, tx *gorm.DB, statuses []string, page int, limit int) ([]entity.Consumer, error) {
+	return retryRead(ctx, r.policy, tx, "GetConsumersExcludingStatuses", func() ([]entity.Consumer, error) {
+		return r.ConsumerRepository.GetConsumersExcludingStatuses(ctx, tx, statuses, page, limit)
+	})
+}
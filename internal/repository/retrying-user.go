@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// This struct defines the retrying UserRepository that wraps another UserRepository
+// It implements the UserRepository interface; only the reads are retried, the writes are passed through
+type retryingUserRepository struct {
+	UserRepository
+	policy policyconfig.RetryPolicy
+}
+
+// NewRetryingUserRepository creates a new instance of UserRepository that retries the reads of the given repository
+// failing with a transient database error, as configured by the policy. It returns the repository itself if the retries are disabled.
+func NewRetryingUserRepository(repo UserRepository, policy policyconfig.RetryPolicy) UserRepository {
+	if !policy.Enabled() {
+		return repo
+	}
+
+	return &retryingUserRepository{UserRepository: repo, policy: policy}
+}
+
+// GetUsers retrieves the users from the wrapped repository, retrying the transient errors.
+func (r *retryingUserRepository) GetUsers(ctx context.Context, tx *gorm.DB, page int, limit int) ([]entity.User, error) {
+	return retryRead(ctx, r.policy, tx, "GetUsers", func() ([]entity.User, error) {
+		return r.UserRepository.GetUsers(ctx, tx, page, limit)
+	})
+}
+
+// GetUserByID retrieves a user by its ID from the wrapped repository, retrying the transient errors.
+func (r *retryingUserRepository) GetUserByID(ctx context.Context, tx *gorm.DB, id int64) (entity.User, error) {
+	return retryRead(ctx, r.policy, tx, "GetUserByID", func() (entity.User, error) {
+		return r.UserRepository.GetUserByID(ctx, tx, id)
+	})
+}
+
+// GetUserByUsername retrieves a user by its username from the wrapped repository, retrying the transient errors.
+func (r *retryingUserRepository) GetUserByUsername(ctx context.Context, tx *gorm.DB, username string) (entity.User, error) {
+	return retryRead(ctx, r.policy, tx, "GetUserByUsername", func() (entity.User, error) {
+		return r.UserRepository.GetUserByUsername(ctx, tx, username)
+	})
+}
+
+// GetUserByEmail retrieves a user by its email from the wrapped repository, retrying the transient errors.
+func (r *retryingUserRepository) GetUserByEmail(ctx context.Context, tx *gorm.DB, email string) (entity.User, error) {
+	return retryRead(ctx, r.policy, tx, "GetUserByEmail", func() (entity.User, error) {
+		return r.UserRepository.GetUserByEmail(ctx, tx, email)
+	})
+}
+
+// GetRevokedTokenVersions retrieves the token versions of the users from the wrapped repository, retrying the transient errors.
+func (r *retryingUserRepository) GetRevokedTokenVersions(ctx context.Context, tx *gorm.DB) (map[int64]int64, error) {
+	return retryRead(ctx, r.policy, tx, "GetRevokedTokenVersions", func() (map[int64]int64, error) {
+		return r.UserRepository.GetRevokedTokenVersions(ctx, tx)
+	})
+}
+
+// WarmRoleCache pre-fills the role cache of the wrapped repository, if it has one.
+func (r *retryingUserRepository) WarmRoleCache(tx *gorm.DB, limit int) (int, error) {
+	if warmer, ok := r.UserRepository.(RoleCacheWarmer); ok {
+		return warmer.WarmRoleCache(tx, limit)
+	}
+
+	return 0, nil
+}
+
+// InvalidateRoleCache removes the role cache of the wrapped repository, if it has one.
+func (r *retryingUserRepository) InvalidateRoleCache() {
+	if invalidator, ok := r.UserRepository.(RoleCacheInvalidator); ok {
+		invalidator.InvalidateRoleCache()
+	}
+}
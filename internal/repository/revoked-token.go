@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=revoked-token.go -destination=../../tests/mocks/revoked-token-repository.go -package=mocks
+
+// Interface for revoked token repository
+// This interface defines the methods that the revoked token repository should implement
+type RevokedTokenRepository interface {
+	CreateRevokedToken(tx *gorm.DB, token entity.RevokedToken) error
+	GetRevokedTokens(tx *gorm.DB, now time.Time) ([]entity.RevokedToken, error)
+	RemoveExpiredRevokedTokens(tx *gorm.DB, now time.Time) (int64, error)
+}
+
+// This struct defines the RevokedTokenRepository that contains methods for interacting with the database
+// It implements the RevokedTokenRepository interface and provides methods for revoked token-related operations
+type revokedTokenRepository struct{}
+
+// NewRevokedTokenRepository creates a new instance of RevokedTokenRepository.
+// It initializes the revokedTokenRepository struct and returns it.
+func NewRevokedTokenRepository() RevokedTokenRepository {
+	return &revokedTokenRepository{}
+}
+
+// CreateRevokedToken records the revoked access token in the database.
+// Revoking a token already revoked is not an error.
+func (r *revokedTokenRepository) CreateRevokedToken(tx *gorm.DB, token entity.RevokedToken) error {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&token).Error; err != nil {
+		return fmt.Errorf("failed to revoke token %s: %w", token.TokenID, err)
+	}
+
+	return nil
+}
+
+// GetRevokedTokens retrieves the revoked access tokens not expired at the given time from the database.
+func (r *revokedTokenRepository) GetRevokedTokens(tx *gorm.DB, now time.Time) ([]entity.RevokedToken, error) {
+	var tokens []entity.RevokedToken
+	if err := tx.Where("expires_at > ?", now).Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve revoked tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// RemoveExpiredRevokedTokens removes the revoked access tokens expired at the given time from the database,
+// they are rejected by the JWT validation anyway. It returns the number of removed tokens.
+func (r *revokedTokenRepository) RemoveExpiredRevokedTokens(tx *gorm.DB, now time.Time) (int64, error) {
+	result := tx.Where("expires_at <= ?", now).Delete(&entity.RevokedToken{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to remove expired revoked tokens: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
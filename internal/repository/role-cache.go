@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+)
+
+/**
+ * RoleCache is a small TTL cache of the role sets of users, keyed by user ID.
+ * The roles of a user rarely change, so the user repository can skip loading them
+ * on every login and refresh. Entries expire after the TTL and are invalidated
+ * when the role assignments of a user change through the repository.
+ * The cache is local to the process: with several instances, a role change made by
+ * one instance is seen by the others once their entries expire.
+ */
+
+// defaultRoleCacheSize is the maximum number of users whose roles are cached.
+const defaultRoleCacheSize = 10000
+
+// roleCacheEntry holds the cached roles of a user and their expiration time.
+type roleCacheEntry struct {
+	roles     []entity.Role
+	expiresAt time.Time
+}
+
+// RoleCache is a thread-safe TTL cache of role sets keyed by user ID.
+// A nil *RoleCache is valid and caches nothing.
+type RoleCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	clock   clock.Clock
+	entries map[int64]roleCacheEntry
+}
+
+// NewRoleCache creates a new instance of RoleCache whose entries expire after the given TTL.
+// It returns nil (no caching) if the TTL is not positive.
+func NewRoleCache(ttl time.Duration, clk clock.Clock) *RoleCache {
+	if ttl <= 0 {
+		return nil
+	}
+
+	return &RoleCache{
+		ttl:     ttl,
+		maxSize: defaultRoleCacheSize,
+		clock:   clk,
+		entries: make(map[int64]roleCacheEntry),
+	}
+}
+
+// Get returns a copy of the cached roles of the user, if present and not expired.
+func (c *RoleCache) Get(userID int64) ([]entity.Role, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok {
+		return nil, false
+	}
+	if !c.clock.Now().Before(entry.expiresAt) {
+		delete(c.entries, userID)
+		return nil, false
+	}
+
+	return append([]entity.Role{}, entry.roles...), true
+}
+
+// Set caches a copy of the roles of the user.
+// When the cache is full, the expired entries are evicted first; if it is still full, the roles are not cached.
+func (c *RoleCache) Set(userID int64, roles []entity.Role) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	if _, exists := c.entries[userID]; !exists && len(c.entries) >= c.maxSize {
+		for id, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+		if len(c.entries) >= c.maxSize {
+			return
+		}
+	}
+
+	c.entries[userID] = roleCacheEntry{
+		roles:     append([]entity.Role{}, roles...),
+		expiresAt: now.Add(c.ttl),
+	}
+}
+
+// Invalidate removes the cached roles of the user.
+func (c *RoleCache) Invalidate(userID int64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, userID)
+}
+
+// InvalidateAll removes all cached roles, e.g. after a role itself has changed.
+func (c *RoleCache) InvalidateAll() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[int64]roleCacheEntry)
+}
+
+// Len returns the number of cached entries, including the expired ones not evicted yet.
+func (c *RoleCache) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
@@ -1,16 +1,25 @@
 package repository
 
 import (
+	"fmt"
+
 	"gorm.io/gorm"
 
 	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
 )
 
+//go:generate go tool mockgen -source=role.go -destination=../../tests/mocks/role-repository.go -package=mocks
+
 // Interface for role repository
 // This interface defines the methods that the role repository should implement
 type RoleRepository interface {
 	GetRoleByID(tx *gorm.DB, id uint) (entity.Role, error)
 	GetRoleByName(tx *gorm.DB, name string) (entity.Role, error)
+	GetRoles(tx *gorm.DB) ([]entity.Role, error)
+	CreateRole(tx *gorm.DB, role entity.Role) (entity.Role, error)
+	UpdateRole(tx *gorm.DB, role entity.Role) (entity.Role, error)
+	DeleteRole(tx *gorm.DB, id uint) error
+	CountRoleUsers(tx *gorm.DB, id uint) (int64, error)
 }
 
 // This struct defines the RoleRepository that contains methods for interacting with the database
@@ -47,3 +56,65 @@ func (r *roleRepository) GetRoleByName(tx *gorm.DB, name string) (entity.Role, e
 
 	return role, nil
 }
+
+// GetRoles retrieves all the roles from the database, ordered by ID.
+func (r *roleRepository) GetRoles(tx *gorm.DB) ([]entity.Role, error) {
+	var roles []entity.Role
+	if err := tx.Order("id ASC").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// CreateRole creates a new role in the database.
+func (r *roleRepository) CreateRole(tx *gorm.DB, role entity.Role) (entity.Role, error) {
+	if err := tx.Create(&role).Error; err != nil {
+		return entity.Role{}, fmt.Errorf("failed to create role: %w", err)
+	}
+
+	return role, nil
+}
+
+// UpdateRole updates the name and the description of an existing role in the database.
+func (r *roleRepository) UpdateRole(tx *gorm.DB, role entity.Role) (entity.Role, error) {
+	result := tx.Model(&entity.Role{}).Where("id = ?", role.ID).UpdateColumns(map[string]interface{}{
+		"name":        role.Name,
+		"description": role.Description,
+	})
+	if result.Error != nil {
+		return entity.Role{}, fmt.Errorf("failed to update role %d: %w", role.ID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return entity.Role{}, fmt.Errorf("failed to update role %d: %w", role.ID, gorm.ErrRecordNotFound)
+	}
+
+	return role, nil
+}
+
+// DeleteRole deletes the role with the given ID, and the permissions granted to it, from the database.
+func (r *roleRepository) DeleteRole(tx *gorm.DB, id uint) error {
+	if err := tx.Where("role_id = ?", id).Delete(&entity.RolePermission{}).Error; err != nil {
+		return fmt.Errorf("failed to remove permissions of role %d: %w", id, err)
+	}
+
+	result := tx.Delete(&entity.Role{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete role %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to delete role %d: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	return nil
+}
+
+// CountRoleUsers counts the users the role is assigned to, including the soft-deleted users.
+func (r *roleRepository) CountRoleUsers(tx *gorm.DB, id uint) (int64, error) {
+	var count int64
+	if err := tx.Model(&entity.UserRole{}).Where("role_id = ?", id).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count users of role %d: %w", id, err)
+	}
+
+	return count, nil
+}
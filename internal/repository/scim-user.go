@@ -0,0 +1,387 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+/**
+ * The SCIM user store reads and writes the users with a SCIM 2.0 server (RFC 7643, RFC 7644) at USER_STORE_URL,
+ * e.g. the provisioning API of an existing directory. The users are the /Users resources:
+ *   - id is the ID of the user and must be numeric, e.g. the primary key of the user database behind the server.
+ *   - userName, name.givenName, name.familyName, the primary email, userType, and roles (by name) are mapped to the user.
+ *   - active is true for the ACTIVE users, and the deleted users are removed with DELETE.
+ *   - the token version, the last login time, the state, and the expiration dates are kept in the extension
+ *     scimExtensionSchema. Without it, the sessions cannot be revoked by incrementing the token version.
+ * SCIM never returns the passwords, so the users of a SCIM store log in with an OpenID Connect provider,
+ * and the password changes and resets fail with ErrUserStoreUnsupported.
+ */
+
+// SCIM schemas and media type.
+const (
+	scimUserSchema         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimPatchOpSchema      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	scimExtensionSchema    = "urn:yoanesber:params:scim:schemas:extension:jwt-auth:2.0:User"
+	scimContentType        = "application/scim+json"
+	scimTokenVersionsCount = 100
+)
+
+// scimUser is a /Users resource of a SCIM server.
+type scimUser struct {
+	Schemas   []string       `json:"schemas"`
+	ID        string         `json:"id,omitempty"`
+	UserName  string         `json:"userName"`
+	Name      scimName       `json:"name"`
+	Emails    []scimValue    `json:"emails,omitempty"`
+	Active    bool           `json:"active"`
+	UserType  string         `json:"userType,omitempty"`
+	Roles     []scimValue    `json:"roles,omitempty"`
+	Meta      *scimMeta      `json:"meta,omitempty"`
+	Extension *scimExtension `json:"urn:yoanesber:params:scim:schemas:extension:jwt-auth:2.0:User,omitempty"`
+}
+
+// scimName is the name of a SCIM user.
+type scimName struct {
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// scimValue is a value of a multi-valued attribute, e.g. an email or a role.
+type scimValue struct {
+	Value   string `json:"value"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// scimMeta holds the creation and modification times of a SCIM resource.
+type scimMeta struct {
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+}
+
+// scimExtension holds the attributes of the user which are not part of the core schema.
+type scimExtension struct {
+	State                     entity.UserState `json:"state,omitempty"`
+	StateReason               *string          `json:"stateReason,omitempty"`
+	StateChangedAt            *time.Time       `json:"stateChangedAt,omitempty"`
+	TokenVersion              int64            `json:"tokenVersion"`
+	LastLogin                 *time.Time       `json:"lastLogin,omitempty"`
+	AccountExpirationDate     *time.Time       `json:"accountExpirationDate,omitempty"`
+	CredentialsExpirationDate *time.Time       `json:"credentialsExpirationDate,omitempty"`
+}
+
+// scimListResponse is a page of the resources returned by a query.
+type scimListResponse struct {
+	TotalResults int        `json:"totalResults"`
+	Resources    []scimUser `json:"Resources"`
+}
+
+// scimPatch is a PATCH request changing the attributes of a resource.
+type scimPatch struct {
+	Schemas    []string        `json:"schemas"`
+	Operations []scimOperation `json:"Operations"`
+}
+
+// scimOperation is an operation of a PATCH request.
+type scimOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// This struct defines the UserStore backed by a SCIM 2.0 server
+// It implements the UserStore interface
+type scimUserStore struct {
+	client userStoreClient
+}
+
+// newSCIMUserStore creates a new instance of UserStore reading and writing the users with the SCIM server of the client.
+func newSCIMUserStore(client userStoreClient) UserStore {
+	return &scimUserStore{client: client}
+}
+
+// GetUsers retrieves a page of the users from the SCIM server.
+func (s *scimUserStore) GetUsers(ctx context.Context, page int, limit int) ([]entity.User, error) {
+	query := url.Values{"startIndex": {strconv.Itoa((page-1)*limit + 1)}, "count": {strconv.Itoa(limit)}}
+	list, err := s.list(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]entity.User, 0, len(list.Resources))
+	for _, resource := range list.Resources {
+		user, err := resource.entity()
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// GetUserByID retrieves a user by its ID from the SCIM server.
+func (s *scimUserStore) GetUserByID(ctx context.Context, id int64) (entity.User, error) {
+	var resource scimUser
+	if err := s.client.do(ctx, http.MethodGet, scimUserPath(id), scimContentType, nil, &resource); err != nil {
+		return entity.User{}, err
+	}
+
+	return resource.entity()
+}
+
+// GetUserByUsername retrieves a user by its username from the SCIM server.
+func (s *scimUserStore) GetUserByUsername(ctx context.Context, username string) (entity.User, error) {
+	return s.find(ctx, "userName", username)
+}
+
+// GetUserByEmail retrieves a user by its email from the SCIM server.
+func (s *scimUserStore) GetUserByEmail(ctx context.Context, email string) (entity.User, error) {
+	return s.find(ctx, "emails.value", email)
+}
+
+// CreateUser creates a user with the SCIM server, which assigns its ID. The password is not sent.
+func (s *scimUserStore) CreateUser(ctx context.Context, user entity.User) (entity.User, error) {
+	var created scimUser
+	if err := s.client.do(ctx, http.MethodPost, "/Users", scimContentType, newSCIMUser(user), &created); err != nil {
+		return entity.User{}, err
+	}
+
+	return created.entity()
+}
+
+// UpdateUser replaces a user with the SCIM server. The password is not sent.
+func (s *scimUserStore) UpdateUser(ctx context.Context, user entity.User) (entity.User, error) {
+	var updated scimUser
+	if err := s.client.do(ctx, http.MethodPut, scimUserPath(user.ID), scimContentType, newSCIMUser(user), &updated); err != nil {
+		return entity.User{}, err
+	}
+
+	return updated.entity()
+}
+
+// UpdateLastLogin sets the last login time of a user with the SCIM server.
+func (s *scimUserStore) UpdateLastLogin(ctx context.Context, id int64, lastLogin time.Time) error {
+	return s.patch(ctx, id, scimOperation{Op: "replace", Path: scimExtensionPath("lastLogin"), Value: lastLogin})
+}
+
+// UpdatePassword is not supported, the SCIM servers hash the passwords themselves and never return them.
+func (s *scimUserStore) UpdatePassword(ctx context.Context, id int64, password string, credentialsExpiresAt *time.Time) error {
+	return fmt.Errorf("%w: the passwords of a SCIM store cannot be changed", ErrUserStoreUnsupported)
+}
+
+// UpdateUserState sets the state of a user with the SCIM server, only the ACTIVE users are active.
+func (s *scimUserStore) UpdateUserState(ctx context.Context, id int64, state entity.UserState, reason *string, changedAt time.Time) error {
+	return s.patch(ctx, id,
+		scimOperation{Op: "replace", Path: "active", Value: state == entity.UserStateActive},
+		scimOperation{Op: "replace", Path: scimExtensionPath("state"), Value: state},
+		scimOperation{Op: "replace", Path: scimExtensionPath("stateReason"), Value: reason},
+		scimOperation{Op: "replace", Path: scimExtensionPath("stateChangedAt"), Value: changedAt},
+	)
+}
+
+// SoftDeleteUser deletes a user from the SCIM server, which has no soft deletion.
+func (s *scimUserStore) SoftDeleteUser(ctx context.Context, id int64, deletedBy int64, deletedAt time.Time) error {
+	return s.client.do(ctx, http.MethodDelete, scimUserPath(id), scimContentType, nil, nil)
+}
+
+// IncrementTokenVersion increments the token version of a user with the SCIM server, and returns the new version.
+// SCIM has no atomic increment: the version is read then replaced, so concurrent revocations of a user may share a version.
+func (s *scimUserStore) IncrementTokenVersion(ctx context.Context, id int64) (int64, error) {
+	var resource scimUser
+	if err := s.client.do(ctx, http.MethodGet, scimUserPath(id), scimContentType, nil, &resource); err != nil {
+		return 0, err
+	}
+
+	var version int64 = 1
+	if resource.Extension != nil {
+		version = resource.Extension.TokenVersion + 1
+	}
+	if err := s.patch(ctx, id, scimOperation{Op: "replace", Path: scimExtensionPath("tokenVersion"), Value: version}); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// GetRevokedTokenVersions retrieves the token versions above 0 from the SCIM server, reading every page of the users.
+func (s *scimUserStore) GetRevokedTokenVersions(ctx context.Context) (map[int64]int64, error) {
+	versions := make(map[int64]int64)
+	for startIndex := 1; ; startIndex += scimTokenVersionsCount {
+		list, err := s.list(ctx, url.Values{
+			"filter":     {scimExtensionPath("tokenVersion") + " gt 0"},
+			"attributes": {"id," + scimExtensionPath("tokenVersion")},
+			"startIndex": {strconv.Itoa(startIndex)},
+			"count":      {strconv.Itoa(scimTokenVersionsCount)},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, resource := range list.Resources {
+			id, err := strconv.ParseInt(resource.ID, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: the SCIM id %q is not numeric", ErrUserStoreUnavailable, resource.ID)
+			}
+			if resource.Extension != nil && resource.Extension.TokenVersion > 0 {
+				versions[id] = resource.Extension.TokenVersion
+			}
+		}
+
+		if len(list.Resources) == 0 || startIndex+len(list.Resources) > list.TotalResults {
+			return versions, nil
+		}
+	}
+}
+
+// ReplaceUserRoles replaces the roles of a user with the SCIM server, by their names.
+func (s *scimUserStore) ReplaceUserRoles(ctx context.Context, userID int64, roles []entity.Role) error {
+	return s.patch(ctx, userID, scimOperation{Op: "replace", Path: "roles", Value: scimRoles(roles)})
+}
+
+// find retrieves the user whose attribute equals the value from the SCIM server.
+func (s *scimUserStore) find(ctx context.Context, attribute string, value string) (entity.User, error) {
+	list, err := s.list(ctx, url.Values{"filter": {attribute + " eq " + scimString(value)}, "count": {"1"}})
+	if err != nil {
+		return entity.User{}, err
+	}
+	if len(list.Resources) == 0 {
+		return entity.User{}, gorm.ErrRecordNotFound
+	}
+
+	return list.Resources[0].entity()
+}
+
+// list queries the users of the SCIM server.
+func (s *scimUserStore) list(ctx context.Context, query url.Values) (scimListResponse, error) {
+	var list scimListResponse
+	if err := s.client.do(ctx, http.MethodGet, "/Users?"+query.Encode(), scimContentType, nil, &list); err != nil {
+		return scimListResponse{}, err
+	}
+
+	return list, nil
+}
+
+// patch applies the operations to a user with the SCIM server.
+func (s *scimUserStore) patch(ctx context.Context, id int64, ops ...scimOperation) error {
+	return s.client.do(ctx, http.MethodPatch, scimUserPath(id), scimContentType, scimPatch{Schemas: []string{scimPatchOpSchema}, Operations: ops}, nil)
+}
+
+// newSCIMUser returns the SCIM resource of the user, without its password.
+func newSCIMUser(user entity.User) scimUser {
+	resource := scimUser{
+		Schemas:  []string{scimUserSchema, scimExtensionSchema},
+		UserName: user.Username,
+		Name:     scimName{GivenName: user.Firstname},
+		Active:   user.State == entity.UserStateActive,
+		UserType: user.UserType,
+		Roles:    scimRoles(user.Roles),
+		Extension: &scimExtension{
+			State:                     user.State,
+			StateReason:               user.StateReason,
+			StateChangedAt:            user.StateChangedAt,
+			TokenVersion:              user.TokenVersion,
+			LastLogin:                 user.LastLogin,
+			AccountExpirationDate:     user.AccountExpirationDate,
+			CredentialsExpirationDate: user.CredentialsExpirationDate,
+		},
+	}
+	if user.ID != 0 {
+		resource.ID = strconv.FormatInt(user.ID, 10)
+	}
+	if user.Lastname != nil {
+		resource.Name.FamilyName = *user.Lastname
+	}
+	if user.Email != "" {
+		resource.Emails = []scimValue{{Value: user.Email, Primary: true}}
+	}
+
+	return resource
+}
+
+// entity returns the user of the SCIM resource, whose id must be numeric.
+func (r scimUser) entity() (entity.User, error) {
+	id, err := strconv.ParseInt(r.ID, 10, 64)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("%w: the SCIM id %q is not numeric", ErrUserStoreUnavailable, r.ID)
+	}
+
+	deleted := false
+	user := entity.User{
+		ID:        id,
+		Username:  r.UserName,
+		Firstname: r.Name.GivenName,
+		UserType:  r.UserType,
+		IsDeleted: &deleted,
+		State:     entity.UserStateDisabled,
+	}
+	if r.Active {
+		user.State = entity.UserStateActive
+	}
+	if user.UserType == "" {
+		user.UserType = entity.UserTypeUserAccount
+	}
+	if r.Name.FamilyName != "" {
+		lastname := r.Name.FamilyName
+		user.Lastname = &lastname
+	}
+	for i, email := range r.Emails {
+		if i == 0 || email.Primary {
+			user.Email = email.Value
+		}
+	}
+	for _, role := range r.Roles {
+		user.Roles = append(user.Roles, entity.Role{Name: role.Value})
+	}
+	if r.Meta != nil {
+		user.CreatedAt = r.Meta.Created
+		user.UpdatedAt = r.Meta.LastModified
+	}
+	if ext := r.Extension; ext != nil {
+		// The state of the extension tells the inactive users apart, e.g. SUSPENDED from DISABLED
+		if ext.State != "" && (ext.State == entity.UserStateActive) == r.Active {
+			user.State = ext.State
+		}
+		user.StateReason = ext.StateReason
+		user.StateChangedAt = ext.StateChangedAt
+		user.TokenVersion = ext.TokenVersion
+		user.LastLogin = ext.LastLogin
+		user.AccountExpirationDate = ext.AccountExpirationDate
+		user.CredentialsExpirationDate = ext.CredentialsExpirationDate
+	}
+
+	return user, nil
+}
+
+// scimRoles returns the values of the roles attribute, the names of the roles.
+func scimRoles(roles []entity.Role) []scimValue {
+	values := make([]scimValue, 0, len(roles))
+	for _, role := range roles {
+		values = append(values, scimValue{Value: role.Name})
+	}
+
+	return values
+}
+
+// scimUserPath returns the path of the user with the given ID.
+func scimUserPath(id int64) string {
+	return "/Users/" + strconv.FormatInt(id, 10)
+}
+
+// scimExtensionPath returns the path of an attribute of the extension.
+func scimExtensionPath(attribute string) string {
+	return scimExtensionSchema + ":" + attribute
+}
+
+// scimString returns the value as a string of a SCIM filter, quoted and escaped.
+func scimString(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
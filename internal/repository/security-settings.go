@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=security-settings.go -destination=../../tests/mocks/security-settings-repository.go -package=mocks
+
+// Interface for security settings repository
+// This interface defines the methods that the security settings repository should implement
+type SecuritySettingsRepository interface {
+	GetSecuritySettings(tx *gorm.DB) (entity.SecuritySettings, error)
+	SaveSecuritySettings(tx *gorm.DB, settings entity.SecuritySettings) (entity.SecuritySettings, error)
+}
+
+// This struct defines the SecuritySettingsRepository that contains methods for interacting with the database
+// It implements the SecuritySettingsRepository interface and provides methods for security settings-related operations
+type securitySettingsRepository struct{}
+
+// NewSecuritySettingsRepository creates a new instance of SecuritySettingsRepository.
+// It initializes the securitySettingsRepository struct and returns it.
+func NewSecuritySettingsRepository() SecuritySettingsRepository {
+	return &securitySettingsRepository{}
+}
+
+// GetSecuritySettings retrieves the security settings from the database.
+// It returns gorm.ErrRecordNotFound if they were never saved.
+func (r *securitySettingsRepository) GetSecuritySettings(tx *gorm.DB) (entity.SecuritySettings, error) {
+	var settings entity.SecuritySettings
+	if err := tx.First(&settings, "id = ?", entity.SecuritySettingsID).Error; err != nil {
+		return entity.SecuritySettings{}, err
+	}
+
+	return settings, nil
+}
+
+// SaveSecuritySettings creates or replaces the security settings in the database.
+func (r *securitySettingsRepository) SaveSecuritySettings(tx *gorm.DB, settings entity.SecuritySettings) (entity.SecuritySettings, error) {
+	settings.ID = entity.SecuritySettingsID
+	if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&settings).Error; err != nil {
+		return entity.SecuritySettings{}, fmt.Errorf("failed to save security settings: %w", err)
+	}
+
+	return settings, nil
+}
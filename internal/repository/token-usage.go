@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=token-usage.go -destination=../../tests/mocks/token-usage-repository.go -package=mocks
+
+// Interface for token usage repository
+// This interface defines the methods that the token usage repository should implement
+type TokenUsageRepository interface {
+	IncrementTokenUsage(tx *gorm.DB, usage entity.TokenUsage) error
+	GetTokenUsage(tx *gorm.DB, filter entity.TokenStatsFilter) ([]entity.TokenUsage, error)
+}
+
+// This struct defines the TokenUsageRepository that contains methods for interacting with the database
+// It implements the TokenUsageRepository interface and provides methods for token usage-related operations
+type tokenUsageRepository struct{}
+
+// NewTokenUsageRepository creates a new instance of TokenUsageRepository.
+// It initializes the tokenUsageRepository struct and returns it.
+func NewTokenUsageRepository() TokenUsageRepository {
+	return &tokenUsageRepository{}
+}
+
+// IncrementTokenUsage adds the counters of the given usage to the row of its day, user, and client.
+// The row is created if it does not exist yet, with a single upsert statement.
+func (r *tokenUsageRepository) IncrementTokenUsage(tx *gorm.DB, usage entity.TokenUsage) error {
+	err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "day"}, {Name: "user_id"}, {Name: "client"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"issued":    gorm.Expr("token_usage.issued + EXCLUDED.issued"),
+			"refreshed": gorm.Expr("token_usage.refreshed + EXCLUDED.refreshed"),
+			"revoked":   gorm.Expr("token_usage.revoked + EXCLUDED.revoked"),
+		}),
+	}).Create(&usage).Error
+	if err != nil {
+		return fmt.Errorf("failed to increment token usage of user %d: %w", usage.UserID, err)
+	}
+
+	return nil
+}
+
+// GetTokenUsage retrieves the token usage rows matching the filter, ordered by day, user ID, and client.
+func (r *tokenUsageRepository) GetTokenUsage(tx *gorm.DB, filter entity.TokenStatsFilter) ([]entity.TokenUsage, error) {
+	query := tx.Where("day BETWEEN ? AND ?", filter.From.Format("2006-01-02"), filter.To.Format("2006-01-02"))
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Client != "" {
+		query = query.Where("client = ?", filter.Client)
+	}
+
+	var usage []entity.TokenUsage
+	if err := query.Order("day, user_id, client").Find(&usage).Error; err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=user-identity.go -destination=../../tests/mocks/user-identity-repository.go -package=mocks
+
+// Interface for user identity repository
+// This interface defines the methods that the user identity repository should implement
+type UserIdentityRepository interface {
+	CreateUserIdentity(tx *gorm.DB, identity entity.UserIdentity) (entity.UserIdentity, error)
+	GetUserIdentity(tx *gorm.DB, provider string, subject string) (entity.UserIdentity, error)
+	UpdateLastLogin(tx *gorm.DB, id int64, lastLoginAt time.Time) error
+}
+
+// This struct defines the UserIdentityRepository that contains methods for interacting with the database
+// It implements the UserIdentityRepository interface and provides methods for user identity-related operations
+type userIdentityRepository struct{}
+
+// NewUserIdentityRepository creates a new instance of UserIdentityRepository.
+// It initializes the userIdentityRepository struct and returns it.
+func NewUserIdentityRepository() UserIdentityRepository {
+	return &userIdentityRepository{}
+}
+
+// CreateUserIdentity inserts a new user identity into the database.
+func (r *userIdentityRepository) CreateUserIdentity(tx *gorm.DB, identity entity.UserIdentity) (entity.UserIdentity, error) {
+	if err := tx.Create(&identity).Error; err != nil {
+		return entity.UserIdentity{}, fmt.Errorf("failed to create user identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+// GetUserIdentity retrieves the user identity of the subject at the provider from the database.
+func (r *userIdentityRepository) GetUserIdentity(tx *gorm.DB, provider string, subject string) (entity.UserIdentity, error) {
+	var identity entity.UserIdentity
+	if err := tx.First(&identity, "provider = ? AND subject = ?", provider, subject).Error; err != nil {
+		return entity.UserIdentity{}, err
+	}
+
+	return identity, nil
+}
+
+// UpdateLastLogin records the time of the last login of the user with the identity.
+func (r *userIdentityRepository) UpdateLastLogin(tx *gorm.DB, id int64, lastLoginAt time.Time) error {
+	if err := tx.Model(&entity.UserIdentity{}).Where("id = ?", id).Update("last_login_at", lastLoginAt).Error; err != nil {
+		return fmt.Errorf("failed to update the last login of user identity %d: %w", id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/secrets"
+)
+
+//go:generate go tool mockgen -source=user-store.go -destination=../../tests/mocks/user-store.go -package=mocks
+
+/**
+ * The user store is the source of the users, so that the service can front an existing user database
+ * without migrating it into its own schema. The users are read from the database of the service by default,
+ * or from an external identity source set by USER_STORE:
+ *   - rest: an identity service exposing the users as JSON, see restUserStore for the endpoints it must serve.
+ *   - scim: a SCIM 2.0 server (RFC 7643, RFC 7644), see scimUserStore for the attributes it must support.
+ * The external stores have no transactions: their changes are not rolled back with the ones of the database
+ * of the service, which still holds the sessions, the roles, and the other data of the users.
+ * A store returns gorm.ErrRecordNotFound for an unknown user and gorm.ErrDuplicatedKey for a taken username
+ * or email, like the database, so the services handle their errors the same way.
+ */
+
+// maxUserStoreResponseBody is the maximum size of a response of an external user store.
+const maxUserStoreResponseBody = 4 << 20
+
+var (
+	// ErrUserStoreUnavailable is returned when an external user store cannot be reached or responds with an error.
+	ErrUserStoreUnavailable = errors.New("user store unavailable")
+
+	// ErrUserStoreUnsupported is returned when an external user store does not support an operation,
+	// e.g. the password changes with a SCIM server, which does not return the passwords.
+	ErrUserStoreUnsupported = errors.New("operation not supported by the user store")
+)
+
+// Interface for user store
+// This interface defines the methods that the sources of the users should implement, without transactions
+type UserStore interface {
+	GetUsers(ctx context.Context, page int, limit int) ([]entity.User, error)
+	GetUserByID(ctx context.Context, id int64) (entity.User, error)
+	GetUserByUsername(ctx context.Context, username string) (entity.User, error)
+	GetUserByEmail(ctx context.Context, email string) (entity.User, error)
+	CreateUser(ctx context.Context, user entity.User) (entity.User, error)
+	UpdateUser(ctx context.Context, user entity.User) (entity.User, error)
+	UpdateLastLogin(ctx context.Context, id int64, lastLogin time.Time) error
+	UpdatePassword(ctx context.Context, id int64, password string, credentialsExpiresAt *time.Time) error
+	UpdateUserState(ctx context.Context, id int64, state entity.UserState, reason *string, changedAt time.Time) error
+	SoftDeleteUser(ctx context.Context, id int64, deletedBy int64, deletedAt time.Time) error
+	IncrementTokenVersion(ctx context.Context, id int64) (int64, error)
+	GetRevokedTokenVersions(ctx context.Context) (map[int64]int64, error)
+	ReplaceUserRoles(ctx context.Context, userID int64, roles []entity.Role) error
+}
+
+// NewUserStore creates the external user store of the settings. Its requests are authenticated with
+// the bearer token of the USER_STORE_TOKEN secret, if configured, read from the given secrets provider.
+// A nil client is replaced by a client with the timeout of the settings.
+func NewUserStore(cfg policyconfig.UserStoreConfig, provider secrets.Provider, client *http.Client) (UserStore, error) {
+	if err := cfg.Check(); err != nil {
+		return nil, err
+	}
+
+	token, err := provider.Get("USER_STORE_TOKEN")
+	if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		return nil, err
+	}
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	c := userStoreClient{baseURL: cfg.URL, token: token, client: client}
+
+	switch cfg.Kind {
+	case policyconfig.UserStoreREST:
+		return newRESTUserStore(c), nil
+	case policyconfig.UserStoreSCIM:
+		return newSCIMUserStore(c), nil
+	}
+
+	return nil, fmt.Errorf("user store %q is not external", cfg.Kind)
+}
+
+// userStoreClient sends the JSON requests of an external user store.
+type userStoreClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// do sends a request with the JSON body, if any, and decodes the JSON response into out, if not nil.
+// The request is canceled when the context is done. The responses 404 and 409 are returned as gorm.ErrRecordNotFound
+// and gorm.ErrDuplicatedKey, and the other failed responses as ErrUserStoreUnavailable.
+func (c userStoreClient) do(ctx context.Context, method string, path string, contentType string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode the request to the user store: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUserStoreUnavailable, err)
+	}
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUserStoreUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return gorm.ErrRecordNotFound
+	case resp.StatusCode == http.StatusConflict:
+		return gorm.ErrDuplicatedKey
+	case resp.StatusCode >= http.StatusBadRequest:
+		return fmt.Errorf("%w: %s %s responded with %d", ErrUserStoreUnavailable, method, path, resp.StatusCode)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxUserStoreResponseBody)).Decode(out); err != nil {
+		return fmt.Errorf("%w: invalid response to %s %s: %v", ErrUserStoreUnavailable, method, path, err)
+	}
+
+	return nil
+}
+
+// This struct defines the UserRepository backed by a UserStore
+// It implements the UserRepository interface; the tx argument is ignored, the ctx argument is passed to the store
+type userStoreRepository struct {
+	store UserStore
+}
+
+// NewUserStoreRepository creates a new instance of UserRepository reading and writing the users with the given store.
+func NewUserStoreRepository(store UserStore) UserRepository {
+	return &userStoreRepository{store: store}
+}
+
+// GetUsers retrieves a page of the users from the store.
+func (r *userStoreRepository) GetUsers(ctx context.Context, tx *gorm.DB, page int, limit int) ([]entity.User, error) {
+	return r.store.GetUsers(ctx, page, limit)
+}
+
+// GetUserByID retrieves a user by its ID from the store.
+func (r *userStoreRepository) GetUserByID(ctx context.Context, tx *gorm.DB, id int64) (entity.User, error) {
+	return r.store.GetUserByID(ctx, id)
+}
+
+// GetUserByUsername retrieves a user by its username from the store.
+func (r *userStoreRepository) GetUserByUsername(ctx context.Context, tx *gorm.DB, username string) (entity.User, error) {
+	return r.store.GetUserByUsername(ctx, username)
+}
+
+// GetUserByEmail retrieves a user by its email from the store.
+func (r *userStoreRepository) GetUserByEmail(ctx context.Context, tx *gorm.DB, email string) (entity.User, error) {
+	return r.store.GetUserByEmail(ctx, email)
+}
+
+// CreateUser creates a user in the store.
+func (r *userStoreRepository) CreateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error) {
+	return r.store.CreateUser(ctx, user)
+}
+
+// UpdateUser updates a user in the store.
+func (r *userStoreRepository) UpdateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error) {
+	return r.store.UpdateUser(ctx, user)
+}
+
+// UpdateLastLogin updates the last login time of a user in the store.
+func (r *userStoreRepository) UpdateLastLogin(ctx context.Context, tx *gorm.DB, id int64, lastLogin time.Time) error {
+	return r.store.UpdateLastLogin(ctx, id, lastLogin)
+}
+
+// UpdatePassword updates the password of a user in the store.
+func (r *userStoreRepository) UpdatePassword(ctx context.Context, tx *gorm.DB, id int64, password string, credentialsExpiresAt *time.Time) error {
+	return r.store.UpdatePassword(ctx, id, password, credentialsExpiresAt)
+}
+
+// UpdateUserState updates the state of a user in the store.
+func (r *userStoreRepository) UpdateUserState(ctx context.Context, tx *gorm.DB, id int64, state entity.UserState, reason *string, changedAt time.Time) error {
+	return r.store.UpdateUserState(ctx, id, state, reason, changedAt)
+}
+
+// SoftDeleteUser deletes a user from the store.
+func (r *userStoreRepository) SoftDeleteUser(ctx context.Context, tx *gorm.DB, id int64, deletedBy int64, deletedAt time.Time) error {
+	return r.store.SoftDeleteUser(ctx, id, deletedBy, deletedAt)
+}
+
+// IncrementTokenVersion increments the token version of a user in the store.
+func (r *userStoreRepository) IncrementTokenVersion(ctx context.Context, tx *gorm.DB, id int64) (int64, error) {
+	return r.store.IncrementTokenVersion(ctx, id)
+}
+
+// GetRevokedTokenVersions retrieves the token versions of the users from the store.
+func (r *userStoreRepository) GetRevokedTokenVersions(ctx context.Context, tx *gorm.DB) (map[int64]int64, error) {
+	return r.store.GetRevokedTokenVersions(ctx)
+}
+
+// ReplaceUserRoles replaces the roles of a user in the store.
+func (r *userStoreRepository) ReplaceUserRoles(ctx context.Context, tx *gorm.DB, userID int64, roles []entity.Role) error {
+	return r.store.ReplaceUserRoles(ctx, userID, roles)
+}
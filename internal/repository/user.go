@@ -1,77 +1,393 @@
 package repository
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
 )
 
+//go:generate go tool mockgen -source=user.go -destination=../../tests/mocks/user-repository.go -package=mocks
+
 // Interface for user repository
 // This interface defines the methods that the user repository should implement
 type UserRepository interface {
-	GetUserByID(tx *gorm.DB, id int64) (entity.User, error)
-	GetUserByUsername(tx *gorm.DB, username string) (entity.User, error)
-	GetUserByEmail(tx *gorm.DB, email string) (entity.User, error)
-	UpdateUser(tx *gorm.DB, user entity.User) (entity.User, error)
+	GetUsers(ctx context.Context, tx *gorm.DB, page int, limit int) ([]entity.User, error)
+	GetUserByID(ctx context.Context, tx *gorm.DB, id int64) (entity.User, error)
+	GetUserByUsername(ctx context.Context, tx *gorm.DB, username string) (entity.User, error)
+	GetUserByEmail(ctx context.Context, tx *gorm.DB, email string) (entity.User, error)
+	CreateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error)
+	UpdateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error)
+	UpdateLastLogin(ctx context.Context, tx *gorm.DB, id int64, lastLogin time.Time) error
+	UpdatePassword(ctx context.Context, tx *gorm.DB, id int64, password string, credentialsExpiresAt *time.Time) error
+	UpdateUserState(ctx context.Context, tx *gorm.DB, id int64, state entity.UserState, reason *string, changedAt time.Time) error
+	SoftDeleteUser(ctx context.Context, tx *gorm.DB, id int64, deletedBy int64, deletedAt time.Time) error
+	IncrementTokenVersion(ctx context.Context, tx *gorm.DB, id int64) (int64, error)
+	GetRevokedTokenVersions(ctx context.Context, tx *gorm.DB) (map[int64]int64, error)
+	ReplaceUserRoles(ctx context.Context, tx *gorm.DB, userID int64, roles []entity.Role) error
+}
+
+// RoleCacheWarmer is implemented by the user repositories able to pre-fill their role cache,
+// e.g. during the warm-up of the service, so that the first logins do not load the roles from the database.
+type RoleCacheWarmer interface {
+	WarmRoleCache(tx *gorm.DB, limit int) (int, error)
+}
+
+// RoleCacheInvalidator is implemented by the user repositories caching the roles of the users,
+// so that the cached roles can be dropped once a role itself has been renamed.
+type RoleCacheInvalidator interface {
+	InvalidateRoleCache()
 }
 
 // This struct defines the UserRepository that contains methods for interacting with the database
 // It implements the UserRepository interface and provides methods for user-related operations
-type userRepository struct{}
+// By default, the roles of a user are loaded with Preload("Roles"), which issues extra queries per lookup.
+// The joinRoles option loads the user and its roles with a single joined query instead,
+// and the roleCache option skips loading the roles while they are cached.
+type userRepository struct {
+	joinRoles bool
+	roleCache *RoleCache
+}
+
+// UserRepositoryOption configures the user repository.
+type UserRepositoryOption func(*userRepository)
+
+// WithJoinedRoles loads the user and its roles with a single joined query instead of Preload("Roles").
+func WithJoinedRoles() UserRepositoryOption {
+	return func(r *userRepository) {
+		r.joinRoles = true
+	}
+}
+
+// WithRoleCache caches the roles of the users in the given cache.
+// The cached roles of a user are invalidated when they are changed through the repository.
+func WithRoleCache(cache *RoleCache) UserRepositoryOption {
+	return func(r *userRepository) {
+		r.roleCache = cache
+	}
+}
+
+// userRoleRow is a row of the joined users and roles query, one per role of the user.
+type userRoleRow struct {
+	entity.User
+	RoleID          *uint
+	RoleName        *string
+	RoleDescription *string
+}
 
 // NewUserRepository creates a new instance of UserRepository.
-// It initializes the userRepository struct and returns it.
-func NewUserRepository() UserRepository {
-	return &userRepository{}
+// It initializes the userRepository struct with the given options and returns it.
+func NewUserRepository(opts ...UserRepositoryOption) UserRepository {
+	r := &userRepository{}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
 }
 
-// GetUserByID retrieves a user by its ID from the database.
-func (r *userRepository) GetUserByID(tx *gorm.DB, id int64) (entity.User, error) {
-	// Select the user with the given ID from the database
-	var user entity.User
-	err := tx.Preload("Roles").First(&user, "id = ?", id).Error
+// GetUsers retrieves a page of the users ordered by ID from the database, together with their roles.
+// The soft-deleted users are left out.
+func (r *userRepository) GetUsers(ctx context.Context, tx *gorm.DB, page int, limit int) ([]entity.User, error) {
+	var users []entity.User
+	err := tx.WithContext(ctx).Preload("Roles").
+		Where("is_deleted = ?", false).
+		Order("id ASC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&users).Error
 
 	if err != nil {
-		return entity.User{}, err
+		return nil, err
 	}
 
-	return user, nil
+	return users, nil
+}
+
+// GetUserByID retrieves a user by its ID from the database.
+func (r *userRepository) GetUserByID(ctx context.Context, tx *gorm.DB, id int64) (entity.User, error) {
+	// Select the user with the given ID from the database
+	return r.findUser(tx.WithContext(ctx), "users.id = ?", id)
 }
 
 // GetUserByUsername retrieves a user by their username from the database.
-func (r *userRepository) GetUserByUsername(tx *gorm.DB, username string) (entity.User, error) {
+func (r *userRepository) GetUserByUsername(ctx context.Context, tx *gorm.DB, username string) (entity.User, error) {
 	// Select the user with the given username from the database
-	var user entity.User
-	err := tx.Preload("Roles").First(&user, "lower(username) = lower(?)", username).Error
+	return r.findUser(tx.WithContext(ctx), "lower(users.username) = lower(?)", username)
+}
 
-	if err != nil {
-		return entity.User{}, err
+// GetUserByEmail retrieves a user by their email from the database.
+func (r *userRepository) GetUserByEmail(ctx context.Context, tx *gorm.DB, email string) (entity.User, error) {
+	// Select the user with the given email from the database
+	return r.findUser(tx.WithContext(ctx), "lower(users.email) = lower(?)", email)
+}
+
+// CreateUser creates a new user in the database, together with the assignment of its roles.
+// The roles must already exist.
+func (r *userRepository) CreateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error) {
+	if err := tx.WithContext(ctx).Omit("Roles.*").Create(&user).Error; err != nil {
+		return entity.User{}, fmt.Errorf("failed to create user: %w", err)
 	}
 
 	return user, nil
 }
 
-// GetUserByEmail retrieves a user by their email from the database.
-func (r *userRepository) GetUserByEmail(tx *gorm.DB, email string) (entity.User, error) {
-	// Select the user with the given email from the database
+// findUser retrieves the first user matching the condition, together with its roles.
+// The condition must qualify its columns with the users table, since it is also used in joined queries.
+func (r *userRepository) findUser(tx *gorm.DB, query string, arg interface{}) (entity.User, error) {
+	if r.roleCache == nil {
+		if r.joinRoles {
+			return r.findUserWithJoinedRoles(tx, query, arg)
+		}
+
+		var user entity.User
+		if err := tx.Preload("Roles").First(&user, query, arg).Error; err != nil {
+			return entity.User{}, err
+		}
+
+		return user, nil
+	}
+
+	// Select the user only, and load its roles unless they are cached
 	var user entity.User
-	err := tx.Preload("Roles").First(&user, "lower(email) = lower(?)", email).Error
+	if err := tx.First(&user, query, arg).Error; err != nil {
+		return entity.User{}, err
+	}
 
+	roles, ok := r.roleCache.Get(user.ID)
+	if !ok {
+		var err error
+		if roles, err = r.getRolesByUserID(tx, user.ID); err != nil {
+			return entity.User{}, err
+		}
+		r.roleCache.Set(user.ID, roles)
+	}
+	user.Roles = roles
+
+	return user, nil
+}
+
+// findUserWithJoinedRoles retrieves the first user matching the condition and its roles with a single query.
+// The query returns one row per role of the user (or a single row without role if the user has none).
+func (r *userRepository) findUserWithJoinedRoles(tx *gorm.DB, query string, arg interface{}) (entity.User, error) {
+	var rows []userRoleRow
+	err := tx.Model(&entity.User{}).
+		Select("users.*, roles.id AS role_id, roles.name AS role_name, roles.description AS role_description").
+		Joins("LEFT JOIN user_roles ON user_roles.user_id = users.id").
+		Joins("LEFT JOIN roles ON roles.id = user_roles.role_id").
+		Where(query, arg).
+		Order("users.id, roles.id").
+		Scan(&rows).Error
 	if err != nil {
 		return entity.User{}, err
 	}
+	if len(rows) == 0 {
+		return entity.User{}, gorm.ErrRecordNotFound
+	}
+
+	// Keep the first matching user, as First would, and collect its roles
+	user := rows[0].User
+	user.Roles = make([]entity.Role, 0, len(rows))
+	for _, row := range rows {
+		if row.ID != user.ID {
+			break
+		}
+		if row.RoleID != nil && row.RoleName != nil {
+			user.Roles = append(user.Roles, entity.Role{ID: *row.RoleID, Name: *row.RoleName, Description: row.RoleDescription})
+		}
+	}
 
 	return user, nil
 }
 
+// InvalidateRoleCache removes the cached roles of all the users, if the role cache is enabled.
+func (r *userRepository) InvalidateRoleCache() {
+	r.roleCache.InvalidateAll()
+}
+
+// getRolesByUserID retrieves the roles assigned to the user with a single joined query.
+func (r *userRepository) getRolesByUserID(tx *gorm.DB, userID int64) ([]entity.Role, error) {
+	var roles []entity.Role
+	err := tx.Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.id").
+		Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// WarmRoleCache caches the roles of the users who logged in most recently, up to the given number of users.
+// It returns the number of users whose roles were cached, 0 if the role cache is disabled.
+func (r *userRepository) WarmRoleCache(tx *gorm.DB, limit int) (int, error) {
+	if r.roleCache == nil || limit <= 0 {
+		return 0, nil
+	}
+
+	var userIDs []int64
+	err := tx.Model(&entity.User{}).
+		Order("last_login DESC NULLS LAST").
+		Limit(limit).
+		Pluck("id", &userIDs).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve the users to warm the role cache: %w", err)
+	}
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	var rows []struct {
+		UserID int64
+		entity.Role
+	}
+	err = tx.Table("roles").
+		Select("user_roles.user_id, roles.id, roles.name, roles.description").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id IN ?", userIDs).
+		Order("roles.id").
+		Scan(&rows).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve the roles to warm the role cache: %w", err)
+	}
+
+	roles := make(map[int64][]entity.Role, len(userIDs))
+	for _, row := range rows {
+		roles[row.UserID] = append(roles[row.UserID], row.Role)
+	}
+	for _, id := range userIDs {
+		r.roleCache.Set(id, roles[id])
+	}
+
+	return len(userIDs), nil
+}
+
 // UpdateUser updates an existing user in the database and returns the updated user.
-func (r *userRepository) UpdateUser(tx *gorm.DB, user entity.User) (entity.User, error) {
+func (r *userRepository) UpdateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error) {
 	// Update the user in the database
-	if err := tx.Save(&user).Error; err != nil {
+	if err := tx.WithContext(ctx).Save(&user).Error; err != nil {
 		return entity.User{}, fmt.Errorf("failed to update user: %w", err)
 	}
 
+	// Saving the user also saves the role assignments it carries
+	if user.Roles != nil {
+		r.roleCache.Invalidate(user.ID)
+	}
+
 	return user, nil
 }
+
+// UpdateLastLogin sets the last login time of the user with a targeted update of the last_login column.
+func (r *userRepository) UpdateLastLogin(ctx context.Context, tx *gorm.DB, id int64, lastLogin time.Time) error {
+	result := tx.WithContext(ctx).Model(&entity.User{}).Where("id = ?", id).UpdateColumn("last_login", lastLogin)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update last login of user %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to update last login of user %d: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	return nil
+}
+
+// UpdatePassword sets the password hash of the user and the expiration date of the credentials (nil if they never expire),
+// with a targeted update of their columns.
+func (r *userRepository) UpdatePassword(ctx context.Context, tx *gorm.DB, id int64, password string, credentialsExpiresAt *time.Time) error {
+	result := tx.WithContext(ctx).Model(&entity.User{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"password":                    password,
+		"credentials_expiration_date": credentialsExpiresAt,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update password of user %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to update password of user %d: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	return nil
+}
+
+// UpdateUserState sets the state of the user, along with the reason and the time of the change.
+func (r *userRepository) UpdateUserState(ctx context.Context, tx *gorm.DB, id int64, state entity.UserState, reason *string, changedAt time.Time) error {
+	result := tx.WithContext(ctx).Model(&entity.User{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"state":            state,
+		"state_reason":     reason,
+		"state_changed_at": changedAt,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update state of user %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to update state of user %d: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	return nil
+}
+
+// SoftDeleteUser marks the user as deleted by the given user, without removing its row.
+// The soft-deleted users are no longer found by the lookups, so they cannot log in anymore.
+func (r *userRepository) SoftDeleteUser(ctx context.Context, tx *gorm.DB, id int64, deletedBy int64, deletedAt time.Time) error {
+	result := tx.WithContext(ctx).Model(&entity.User{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"is_deleted": true,
+		"deleted_by": deletedBy,
+		"deleted_at": deletedAt,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete user %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to delete user %d: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	return nil
+}
+
+// IncrementTokenVersion bumps the token version of the user with a targeted update of the token_version column,
+// which invalidates the access tokens issued before. It returns the new token version.
+func (r *userRepository) IncrementTokenVersion(ctx context.Context, tx *gorm.DB, id int64) (int64, error) {
+	var user entity.User
+	result := tx.WithContext(ctx).Model(&user).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "token_version"}}}).
+		Where("id = ?", id).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1"))
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to increment token version of user %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return 0, fmt.Errorf("failed to increment token version of user %d: %w", id, gorm.ErrRecordNotFound)
+	}
+
+	return user.TokenVersion, nil
+}
+
+// GetRevokedTokenVersions retrieves the token version of the users whose tokens were revoked at least once.
+// Soft-deleted users are included, since their tokens remain revoked.
+func (r *userRepository) GetRevokedTokenVersions(ctx context.Context, tx *gorm.DB) (map[int64]int64, error) {
+	var users []entity.User
+	if err := tx.WithContext(ctx).Unscoped().Select("id", "token_version").Where("token_version > 0").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve revoked token versions: %w", err)
+	}
+
+	versions := make(map[int64]int64, len(users))
+	for _, user := range users {
+		versions[user.ID] = user.TokenVersion
+	}
+
+	return versions, nil
+}
+
+// ReplaceUserRoles replaces the roles assigned to the user with the given roles.
+func (r *userRepository) ReplaceUserRoles(ctx context.Context, tx *gorm.DB, userID int64, roles []entity.Role) error {
+	if err := tx.WithContext(ctx).Model(&entity.User{ID: userID}).Association("Roles").Replace(roles); err != nil {
+		return fmt.Errorf("failed to replace roles of user %d: %w", userID, err)
+	}
+
+	r.roleCache.Invalidate(userID)
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=webhook.go -destination=../../tests/mocks/webhook-repository.go -package=mocks
+
+// Interface for webhook delivery repository
+// This interface defines the methods that the webhook delivery repository should implement
+type WebhookDeliveryRepository interface {
+	CreateWebhookDelivery(tx *gorm.DB, delivery entity.WebhookDelivery) (entity.WebhookDelivery, error)
+	GetWebhookDeliveryByID(tx *gorm.DB, id int64) (entity.WebhookDelivery, error)
+	GetWebhookDeliveriesByStatus(tx *gorm.DB, statuses []string, page int, limit int) ([]entity.WebhookDelivery, error)
+	GetDueWebhookDeliveries(tx *gorm.DB, now time.Time, limit int) ([]entity.WebhookDelivery, error)
+	UpdateWebhookDelivery(tx *gorm.DB, delivery entity.WebhookDelivery) error
+}
+
+// This struct defines the WebhookDeliveryRepository that contains methods for interacting with the database
+// It implements the WebhookDeliveryRepository interface and provides methods for webhook delivery-related operations
+type webhookDeliveryRepository struct{}
+
+// NewWebhookDeliveryRepository creates a new instance of WebhookDeliveryRepository.
+// It initializes the webhookDeliveryRepository struct and returns it.
+func NewWebhookDeliveryRepository() WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{}
+}
+
+// CreateWebhookDelivery inserts a new webhook delivery into the database.
+func (r *webhookDeliveryRepository) CreateWebhookDelivery(tx *gorm.DB, delivery entity.WebhookDelivery) (entity.WebhookDelivery, error) {
+	if err := tx.Create(&delivery).Error; err != nil {
+		return entity.WebhookDelivery{}, fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// GetWebhookDeliveryByID retrieves a webhook delivery by its ID from the database.
+func (r *webhookDeliveryRepository) GetWebhookDeliveryByID(tx *gorm.DB, id int64) (entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	if err := tx.First(&delivery, "id = ?", id).Error; err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+// GetWebhookDeliveriesByStatus retrieves a page of the webhook deliveries with one of the given statuses, the most recent first.
+func (r *webhookDeliveryRepository) GetWebhookDeliveriesByStatus(tx *gorm.DB, statuses []string, page int, limit int) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	err := tx.Where("status IN ?", statuses).
+		Order("id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// GetDueWebhookDeliveries retrieves the pending and failed webhook deliveries whose next attempt is due at the given time,
+// the most overdue first. The rows are locked until the end of the transaction, and the rows locked by another instance
+// are skipped, so that a delivery is not attempted twice at the same time.
+func (r *webhookDeliveryRepository) GetDueWebhookDeliveries(tx *gorm.DB, now time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status IN ? AND next_attempt_at <= ?", []string{entity.WebhookDeliveryPending, entity.WebhookDeliveryFailed}, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery saves the status and the outcome of the last attempt of a webhook delivery in the database.
+func (r *webhookDeliveryRepository) UpdateWebhookDelivery(tx *gorm.DB, delivery entity.WebhookDelivery) error {
+	result := tx.Model(&entity.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"last_attempt_at": delivery.LastAttemptAt,
+		"response_status": delivery.ResponseStatus,
+		"response_body":   delivery.ResponseBody,
+		"last_error":      delivery.LastError,
+		"updated_at":      delivery.UpdatedAt,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update webhook delivery %d: %w", delivery.ID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+)
+
+//go:generate go tool mockgen -source=api-key.go -destination=../../tests/mocks/api-key-service.go -package=mocks
+
+/**
+ * The service accounts (user type SERVICE_ACCOUNT) cannot log in interactively, they authenticate their requests
+ * with an API key sent in the X-API-Key header instead. The administrators create, rotate, and revoke the keys.
+ * The keys are random, only their SHA-256 hash is stored, and they are returned once, when they are created or rotated.
+ * Every request checks the key and the state of its account, so a revoked key or a disabled or suspended account
+ * is rejected at once, by every instance.
+ */
+
+const (
+	// ApiKeyPrefix starts every API key, so that a leaked key can be recognized, e.g. by the secret scanners.
+	ApiKeyPrefix = "sk_"
+
+	// apiKeyBytes is the number of random bytes of the API keys.
+	apiKeyBytes = 32
+
+	// apiKeyDisplayLength is the number of characters of the beginning of the keys kept to tell them apart.
+	apiKeyDisplayLength = len(ApiKeyPrefix) + 8
+)
+
+var (
+	// ErrNotServiceAccount is returned when an API key is created for a user which is not a service account.
+	ErrNotServiceAccount = errors.New("API keys can only be issued to service accounts")
+
+	// ErrApiKeyRevoked is returned when a revoked API key is rotated or revoked.
+	ErrApiKeyRevoked = errors.New("API key is already revoked")
+)
+
+// Interface for API key service
+// This interface defines the methods that the API key service should implement
+type ApiKeyService interface {
+	GetApiKeys(ctx context.Context, userID int64) ([]entity.ApiKey, error)
+	CreateApiKey(ctx context.Context, userID int64, req entity.ApiKeyRequest, createdBy int64) (entity.IssuedApiKey, error)
+	RotateApiKey(ctx context.Context, id int64, rotatedBy int64) (entity.IssuedApiKey, error)
+	RevokeApiKey(ctx context.Context, id int64) error
+	Authenticate(ctx context.Context, key string) (metacontext.UserInformationMeta, error)
+}
+
+// This struct defines the ApiKeyService that contains the API key and user repositories,
+// the resolver of the scopes granted to the service accounts, if any, and a clock used to get the current time
+// It implements the ApiKeyService interface, and the authorization.ApiKeyAuthenticator checked by the API key middleware
+type apiKeyService struct {
+	store    database.DataStore
+	repo     repository.ApiKeyRepository
+	userRepo repository.UserRepository
+	scopes   ScopeResolver
+	clock    clock.Clock
+}
+
+// NewApiKeyService creates a new instance of ApiKeyService with the given dependencies.
+// The requests authenticated with an API key carry the permissions granted to the roles of their service account,
+// as resolved by the given resolver; they carry no scopes when it is nil.
+func NewApiKeyService(store database.DataStore, repo repository.ApiKeyRepository, userRepo repository.UserRepository, scopes ScopeResolver, clk clock.Clock) ApiKeyService {
+	return &apiKeyService{
+		store:    store,
+		repo:     repo,
+		userRepo: userRepo,
+		scopes:   scopes,
+		clock:    clk,
+	}
+}
+
+// GetApiKeys retrieves the API keys of the user, the revoked and expired ones included.
+func (s *apiKeyService) GetApiKeys(ctx context.Context, userID int64) ([]entity.ApiKey, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	if _, err := s.userRepo.GetUserByID(context.Background(), db, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetApiKeysByUserID(db, userID)
+}
+
+// CreateApiKey creates a new API key for the service account, and returns it with the key itself.
+func (s *apiKeyService) CreateApiKey(ctx context.Context, userID int64, req entity.ApiKeyRequest, createdBy int64) (entity.IssuedApiKey, error) {
+	// Validate the API key request
+	if err := req.Validate(); err != nil {
+		return entity.IssuedApiKey{}, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.IssuedApiKey{}, fmt.Errorf("database connection is nil")
+	}
+
+	user, err := s.userRepo.GetUserByID(context.Background(), db, userID)
+	if err != nil {
+		return entity.IssuedApiKey{}, err
+	}
+	if user.UserType != entity.UserTypeServiceAccount {
+		return entity.IssuedApiKey{}, ErrNotServiceAccount
+	}
+
+	now := s.clock.Now()
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := now.AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	return s.issue(db, entity.ApiKey{UserID: userID, Name: req.Name, ExpiresAt: expiresAt, CreatedBy: createdBy, CreatedAt: now})
+}
+
+// RotateApiKey replaces the API key with a new one, with the same name and lifetime, and revokes it at once.
+func (s *apiKeyService) RotateApiKey(ctx context.Context, id int64, rotatedBy int64) (entity.IssuedApiKey, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.IssuedApiKey{}, fmt.Errorf("database connection is nil")
+	}
+
+	now := s.clock.Now()
+	var issued entity.IssuedApiKey
+	err := db.Transaction(func(tx *gorm.DB) error {
+		previous, err := s.repo.GetApiKeyByID(tx, id)
+		if err != nil {
+			return err
+		}
+
+		// The key is revoked first, so that it is rotated once when concurrent rotations race
+		revoked, err := s.repo.RevokeApiKey(tx, id, now)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return ErrApiKeyRevoked
+		}
+
+		var expiresAt *time.Time
+		if previous.ExpiresAt != nil {
+			t := now.Add(previous.ExpiresAt.Sub(previous.CreatedAt))
+			expiresAt = &t
+		}
+
+		issued, err = s.issue(tx, entity.ApiKey{UserID: previous.UserID, Name: previous.Name, ExpiresAt: expiresAt, CreatedBy: rotatedBy, CreatedAt: now})
+		return err
+	})
+	if err != nil {
+		return entity.IssuedApiKey{}, err
+	}
+
+	return issued, nil
+}
+
+// RevokeApiKey revokes the API key, the requests using it are rejected at once.
+func (s *apiKeyService) RevokeApiKey(ctx context.Context, id int64) error {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	if _, err := s.repo.GetApiKeyByID(db, id); err != nil {
+		return err
+	}
+
+	revoked, err := s.repo.RevokeApiKey(db, id, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrApiKeyRevoked
+	}
+
+	return nil
+}
+
+// Authenticate checks the API key and returns the user information of its service account.
+// It returns authorization.ErrInvalidApiKey when the key is unknown, expired, or revoked,
+// or when its account is not an active service account anymore.
+func (s *apiKeyService) Authenticate(ctx context.Context, key string) (metacontext.UserInformationMeta, error) {
+	if !strings.HasPrefix(key, ApiKeyPrefix) {
+		return metacontext.UserInformationMeta{}, authorization.ErrInvalidApiKey
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return metacontext.UserInformationMeta{}, fmt.Errorf("database connection is nil")
+	}
+
+	apiKey, err := s.repo.GetApiKeyByHash(db, HashApiKey(key))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return metacontext.UserInformationMeta{}, authorization.ErrInvalidApiKey
+	}
+	if err != nil {
+		return metacontext.UserInformationMeta{}, fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	if !apiKey.IsUsable(s.clock.Now()) {
+		return metacontext.UserInformationMeta{}, authorization.ErrInvalidApiKey
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, db, apiKey.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return metacontext.UserInformationMeta{}, authorization.ErrInvalidApiKey
+	}
+	if err != nil {
+		return metacontext.UserInformationMeta{}, fmt.Errorf("failed to retrieve user by ID: %w", err)
+	}
+	if user.UserType != entity.UserTypeServiceAccount || checkUserStatus(user) != nil {
+		return metacontext.UserInformationMeta{}, authorization.ErrInvalidApiKey
+	}
+
+	roles := ExtractRoleNames(user.Roles)
+	var scopes []string
+	if s.scopes != nil {
+		if scopes, err = s.scopes.GetScopes(roles); err != nil {
+			return metacontext.UserInformationMeta{}, fmt.Errorf("failed to resolve the scopes of the API key: %w", err)
+		}
+	}
+
+	return metacontext.UserInformationMeta{
+		UserID:   user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Roles:    roles,
+		Scopes:   scopes,
+	}, nil
+}
+
+// issue generates a new key and creates the API key with its hash, then returns it with the key itself.
+func (s *apiKeyService) issue(tx *gorm.DB, apiKey entity.ApiKey) (entity.IssuedApiKey, error) {
+	key, err := generateApiKey()
+	if err != nil {
+		return entity.IssuedApiKey{}, err
+	}
+
+	apiKey.Prefix = key[:apiKeyDisplayLength]
+	apiKey.KeyHash = HashApiKey(key)
+	created, err := s.repo.CreateApiKey(tx, apiKey)
+	if err != nil {
+		return entity.IssuedApiKey{}, err
+	}
+
+	return entity.IssuedApiKey{ApiKey: created, Key: key}, nil
+}
+
+// generateApiKey returns a new random API key, starting with ApiKeyPrefix.
+func generateApiKey() (string, error) {
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	return ApiKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashApiKey returns the SHA-256 hash of an API key, as stored in the database.
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
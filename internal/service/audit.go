@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+//go:generate go tool mockgen -source=audit.go -destination=../../tests/mocks/audit-service.go -package=mocks
+
+/**
+ * The audit log records the security-relevant actions in the audit_events table: the logins, the logouts, and the token refreshes
+ * recorded by the auth service, the changes of the roles, of the status of the consumers, and of the admin routes recorded by the
+ * Audit middleware of the routes. Each event carries its actor, its target, the client IP, and the ID of the request, so that
+ * an action found in the audit log can be followed in the logs of the request. The administrators search the audit log with
+ * GET /api/v1/audit-events. The events are written by a background worker, so that the audited requests do not wait for the database,
+ * and through the write buffer, if enabled, so that they are not lost during a failover.
+ */
+
+// DefaultAuditQueueSize is the maximum number of audit events waiting to be written.
+const DefaultAuditQueueSize = 1000
+
+// ErrInvalidAuditAction is returned when the audit log is searched by an unknown action.
+var ErrInvalidAuditAction = errors.New("invalid audit action")
+
+// AuditActions are the actions of the audit events, in the order they are documented.
+var AuditActions = []string{
+	entity.AuditActionLogin, entity.AuditActionLogout, entity.AuditActionTokenRefresh,
+	entity.AuditActionRoleChange, entity.AuditActionConsumerStatusChange, entity.AuditActionConsumerUpdate,
+	entity.AuditActionConsumerDelete, entity.AuditActionConsumerRestore, entity.AuditActionAdmin,
+}
+
+// Interface for audit service
+// This interface defines the methods that the audit service should implement
+type AuditService interface {
+	RecordAuditEvent(event entity.AuditEvent) error
+	GetAuditEvents(ctx context.Context, filter entity.AuditEventFilter, page int, limit int) ([]entity.AuditEvent, error)
+}
+
+// This struct defines the AuditService that contains a repository field of type AuditEventRepository
+// It implements the AuditService interface and provides methods for audit event-related operations
+type auditService struct {
+	store database.DataStore
+	repo  repository.AuditEventRepository
+}
+
+// NewAuditService creates a new instance of AuditService with the given data store and repository.
+// It initializes the auditService struct and returns it.
+func NewAuditService(store database.DataStore, repo repository.AuditEventRepository) AuditService {
+	return &auditService{store: store, repo: repo}
+}
+
+// RecordAuditEvent writes the audit event to the audit log.
+func (s *auditService) RecordAuditEvent(event entity.AuditEvent) error {
+	db := s.store.DB(context.Background())
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	_, err := s.repo.CreateAuditEvent(db, event)
+	return err
+}
+
+// GetAuditEvents retrieves a page of the audit events meeting the criteria of the filter, the most recent first.
+// It returns ErrInvalidAuditAction if the action of the filter is unknown.
+func (s *auditService) GetAuditEvents(ctx context.Context, filter entity.AuditEventFilter, page int, limit int) ([]entity.AuditEvent, error) {
+	if filter.Action != "" && !slices.Contains(AuditActions, filter.Action) {
+		return nil, ErrInvalidAuditAction
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	return s.repo.GetAuditEvents(db, filter, page, limit)
+}
+
+// Interface for audit recorder
+// This interface defines the methods used to record the audit events of the requests
+type AuditRecorder interface {
+	Record(ctx context.Context, event entity.AuditEvent)
+	Close()
+}
+
+// This struct defines the AuditRecorder that writes the audit events in the background
+// It implements the AuditRecorder interface
+type asyncAuditRecorder struct {
+	service AuditService
+	buffer  *WriteBuffer
+	clock   clock.Clock
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan entity.AuditEvent
+	done    chan struct{}
+}
+
+// NewAsyncAuditRecorder creates a new instance of AuditRecorder that writes the events with the given audit service,
+// and starts its background worker. The events failing with a transient database error are retried by the write buffer, if not nil.
+// A non-positive queue size falls back to the default.
+func NewAsyncAuditRecorder(auditSvc AuditService, buffer *WriteBuffer, queueSize int, clk clock.Clock) AuditRecorder {
+	if queueSize <= 0 {
+		queueSize = DefaultAuditQueueSize
+	}
+
+	r := &asyncAuditRecorder{
+		service: auditSvc,
+		buffer:  buffer,
+		clock:   clk,
+		queue:   make(chan entity.AuditEvent, queueSize),
+		done:    make(chan struct{}),
+	}
+	go r.run()
+
+	return r
+}
+
+// Record queues the event without waiting for it to be written. The event is given its event ID, and the time of the event,
+// the ID of the request, and the actor, when the event has none, are taken from the clock and the context of the request.
+// When the queue is full or the recorder is closed, the event is dropped and an error is logged.
+func (r *asyncAuditRecorder) Record(ctx context.Context, event entity.AuditEvent) {
+	event.EventID = uuid.NewString()
+	event.CreatedAt = r.clock.Now()
+	if event.RequestID == "" {
+		event.RequestID = metacontext.ExtractRequestID(ctx)
+	}
+	if meta, ok := metacontext.ExtractUserInformationMeta(ctx); ok {
+		if event.ActorID == nil {
+			event.ActorID = &meta.UserID
+		}
+		if event.ActorUsername == "" && *event.ActorID == meta.UserID {
+			event.ActorUsername = meta.Username
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.closed {
+		select {
+		case r.queue <- event:
+			return
+		default:
+		}
+	}
+
+	logger.ErrorContext(ctx, "Dropped an audit event, the audit queue is full or closed", auditFields(event))
+}
+
+// Close stops the background worker after writing the queued events.
+// It is safe to call Close more than once.
+func (r *asyncAuditRecorder) Close() {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mu.Unlock()
+
+	<-r.done
+}
+
+// run writes the queued events until the recorder is closed.
+// Failures are logged and not retried, unless they are transient database errors and the events are buffered.
+// Writing an event again is safe: an event ID already recorded is not inserted twice.
+func (r *asyncAuditRecorder) run() {
+	defer close(r.done)
+
+	for event := range r.queue {
+		fields := auditFields(event)
+		if err := r.buffer.Write("audit_event", fields, func() error {
+			return r.service.RecordAuditEvent(event)
+		}); err != nil {
+			logger.Error(fmt.Sprintf("Failed to write the audit event: %v", err), fields)
+		}
+	}
+}
+
+// auditFields returns the fields describing the audit event in the logs.
+func auditFields(event entity.AuditEvent) logrus.Fields {
+	return logrus.Fields{
+		"event_id":    event.EventID,
+		"action":      event.Action,
+		"target_type": event.TargetType,
+		"target_id":   event.TargetID,
+		"request_id":  event.RequestID,
+	}
+}
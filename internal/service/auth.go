@@ -1,375 +1,699 @@
 package service
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"os"
 	"strconv"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
 
-	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	jwtconfig "github.com/yoanesber/go-jwt-auth-demo/config/jwt-config"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
-	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
 	jwtutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
 )
 
-var (
-	once              sync.Once
-	JWTSecret         string
-	TokenType         string
-	SigningMethod     string
-	JWTAudience       string
-	JWTIssuer         string
-	JWTExpirationHour string
-	AccessTokenTTL    time.Duration
-)
-
-// LoadEnv loads environment variables
-func LoadEnv() {
-	once.Do(func() {
-		JWTSecret = os.Getenv("JWT_SECRET")
-		TokenType = os.Getenv("TOKEN_TYPE")
-		SigningMethod = os.Getenv("JWT_ALGORITHM")
-		JWTAudience = os.Getenv("JWT_AUDIENCE")
-		JWTIssuer = os.Getenv("JWT_ISSUER")
-		JWTExpirationHour = os.Getenv("JWT_EXPIRATION_HOUR")
-
-		// Load access and refresh token TTL from environment variables
-		access, _ := strconv.Atoi(os.Getenv("ACCESS_TOKEN_TTL_MINUTES"))
-		AccessTokenTTL = time.Duration(access) * time.Minute
-	})
-}
+//go:generate go tool mockgen -source=auth.go -destination=../../tests/mocks/auth-service.go -package=mocks
 
 // Interface for auth service
 // This interface defines the methods that the auth service should implement
 type AuthService interface {
-	Login(loginReq entity.LoginRequest) (entity.LoginResponse, error)
-	RefreshToken(refreshTokenReq entity.RefreshTokenRequest) (entity.RefreshTokenResponse, error)
+	Login(ctx context.Context, loginReq entity.LoginRequest) (entity.LoginResponse, error)
+	RefreshToken(ctx context.Context, refreshTokenReq entity.RefreshTokenRequest) (entity.RefreshTokenResponse, error)
+	Logout(ctx context.Context, logoutReq entity.LogoutRequest) error
+	Register(ctx context.Context, registerReq entity.RegisterRequest) (entity.RegisterResponse, error)
+	VerifyMFA(ctx context.Context, verifyReq entity.VerifyMFARequest) (entity.LoginResponse, error)
+	LoginExternal(ctx context.Context, user entity.User, loginReq entity.LoginRequest) (entity.LoginResponse, error)
 }
 
-// This struct defines the AuthService that contains a user repository and a role repository
+// ErrAdminLoginBlocked is returned when an administrator logs in from a country where the admin logins are blocked.
+var ErrAdminLoginBlocked = errors.New("admin login is not allowed from this location")
+
+// ErrTokenSubjectMismatch is returned when the access token given along with a refresh token is invalid
+// or belongs to another user than the refresh token.
+var ErrTokenSubjectMismatch = errors.New("access token and refresh token do not belong to the same user")
+
+var (
+	// ErrInvalidCredentials is returned when the username of a login is unknown or its password does not match,
+	// which are not told apart so that the usernames cannot be guessed.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrUserLocked is returned when the account authenticating is deleted, disabled, or suspended.
+	ErrUserLocked = errors.New("user account is locked")
+
+	// ErrUserNotVerified is returned when the account authenticating is pending verification.
+	ErrUserNotVerified = errors.New("user account is pending verification")
+
+	// ErrRefreshTokenExpired is returned when the refresh token presented is expired.
+	ErrRefreshTokenExpired = errors.New("refresh token is expired")
+
+	// ErrStepUpRequired is returned when the password of a login requiring a step-up authentication is valid,
+	// but the user has no two-factor authentication to complete it with.
+	ErrStepUpRequired = errors.New("step-up authentication required")
+)
+
+// This struct defines the AuthService that contains the user and refresh token services,
+// the token issuer used to sign access tokens, the recorders of the last login times and of the token usage,
+// the notifier of the security events, the token revocation service ending the sessions on logout, a clock used to get the current time,
+// the countries the administrators cannot log in from, the MFA service completing the logins of the users with two-factor authentication,
+// the hooks extending the login flow, and the recorder of the audit log
 // It implements the AuthService interface and provides methods for authentication-related operations
-type authService struct{}
+type authService struct {
+	userService         UserService
+	refreshTokenService RefreshTokenService
+	tokenIssuer         TokenIssuer
+	lastLogin           LastLoginRecorder
+	tokenUsage          TokenUsageRecorder
+	notifier            SecurityNotifier
+	tokenRevocation     TokenRevocationService
+	clock               clock.Clock
+	blockedAdminCountry map[string]bool
+	mfa                 MFAService
+	loginHooks          []LoginHook
+	audit               AuditRecorder
+	logins              singleflight.Group
+}
+
+// AuthServiceOption configures the auth service.
+type AuthServiceOption func(*authService)
 
-// NewAuthService creates a new instance of AuthService with the given user and role repositories.
-// It initializes the authService struct and returns it.
-func NewAuthService() AuthService {
-	return &authService{}
+// WithBlockedAdminCountries rejects the logins of the administrators from the given countries,
+// identified by their ISO 3166-1 alpha-2 codes. The logins from an unknown location are not rejected.
+func WithBlockedAdminCountries(countries []string) AuthServiceOption {
+	return func(s *authService) {
+		for _, c := range countries {
+			s.blockedAdminCountry[strings.ToUpper(c)] = true
+		}
+	}
 }
 
-// Login authenticates a user with the given username and password.
-// It retrieves the token for the user if the authentication is successful.
-func (s *authService) Login(loginReq entity.LoginRequest) (entity.LoginResponse, error) {
-	// Load environment variables
-	LoadEnv()
+// WithMFA requires a code of the authenticator app to complete the logins of the users with two-factor authentication.
+// Without it, the users log in with their password only.
+func WithMFA(mfa MFAService) AuthServiceOption {
+	return func(s *authService) {
+		s.mfa = mfa
+	}
+}
+
+// WithAudit records the logins, the logouts, and the token refreshes in the audit log with the given recorder.
+func WithAudit(recorder AuditRecorder) AuthServiceOption {
+	return func(s *authService) {
+		s.audit = recorder
+	}
+}
 
-	// Get the database connection from the context
-	db := database.GetPostgres()
-	if db == nil {
-		return entity.LoginResponse{}, fmt.Errorf("database connection is nil")
+// NewAuthService creates a new instance of AuthService with the given dependencies.
+// It initializes the authService struct with the given options and returns it.
+func NewAuthService(userSvc UserService, refreshSvc RefreshTokenService, tokenIssuer TokenIssuer, lastLogin LastLoginRecorder, tokenUsage TokenUsageRecorder, notifier SecurityNotifier, tokenRevocation TokenRevocationService, clk clock.Clock, opts ...AuthServiceOption) AuthService {
+	s := &authService{
+		userService:         userSvc,
+		refreshTokenService: refreshSvc,
+		tokenIssuer:         tokenIssuer,
+		lastLogin:           lastLogin,
+		tokenUsage:          tokenUsage,
+		notifier:            notifier,
+		tokenRevocation:     tokenRevocation,
+		clock:               clk,
+		blockedAdminCountry: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
 
+	return s
+}
+
+// Login authenticates a user with the given username and password.
+// It retrieves the token for the user if the authentication is successful.
+func (s *authService) Login(ctx context.Context, loginReq entity.LoginRequest) (resp entity.LoginResponse, err error) {
+	defer func() { recordLogin(resp, err) }()
+
 	// Validate the authentication parameters using the validation
 	if err := loginReq.Validate(); err != nil {
 		return entity.LoginResponse{}, err
 	}
 
-	var tokenStr string
-	var refreshTokenStr string
-	var expirationDateStr string
-	err := db.Transaction(func(tx *gorm.DB) error {
-		// Check if the user exists
-		userRepo := repository.NewUserRepository()
-		userService := NewUserService(userRepo)
-		existingUser, err := userService.GetUserByUsername(loginReq.Username)
-		if err != nil {
-			return err
-		}
+	// Concurrent identical logins share the result of a single credential verification and token issuance,
+	// which is not canceled with the request that started it, so that the others do not fail with its cancellation
+	loginCtx := context.WithoutCancel(ctx)
+	results := s.logins.DoChan(loginKey(loginReq), func() (interface{}, error) {
+		return s.login(loginCtx, loginReq)
+	})
 
-		// Check some conditions for the user
-		if existingUser.Equals(&entity.User{}) {
-			return fmt.Errorf("user with username %s not found", loginReq.Username)
-		}
-		if !*existingUser.IsEnabled {
-			return fmt.Errorf("user with username %s is not enabled", loginReq.Username)
-		}
-		if !*existingUser.IsAccountNonExpired {
-			return fmt.Errorf("user account is expired")
-		}
-		if !*existingUser.IsAccountNonLocked {
-			return fmt.Errorf("user account is locked")
-		}
-		if !*existingUser.IsCredentialsNonExpired {
-			return fmt.Errorf("user credentials are expired")
-		}
-		if *existingUser.IsDeleted {
-			return fmt.Errorf("user with username %s is deleted", loginReq.Username)
+	select {
+	case <-ctx.Done():
+		return entity.LoginResponse{}, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return entity.LoginResponse{}, result.Err
 		}
 
-		// Compare the provided password with the stored hashed password
-		if err := bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(loginReq.Password)); err != nil {
-			return fmt.Errorf("invalid credentials for user %s", loginReq.Username)
-		}
+		return result.Val.(entity.LoginResponse), nil
+	}
+}
 
-		// Generate an access token for the user
-		tokenStr, err = GenerateJWTToken(existingUser)
-		if err != nil {
-			return fmt.Errorf("failed to generate JWT token: %w", err)
-		}
+// recordLogin counts the login on /metrics with its result. The logins abandoned by their client are not counted.
+func recordLogin(resp entity.LoginResponse, err error) {
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return
+	case err != nil:
+		metrics.Login(metrics.LoginResultFailed)
+	case resp.MFARequired:
+		metrics.Login(metrics.LoginResultMFARequired)
+	default:
+		metrics.Login(metrics.LoginResultSucceeded)
+	}
+}
 
-		// Parse the JWT token
-		jwtToken, err := ParseJWTToken(tokenStr)
-		if err != nil {
-			return fmt.Errorf("failed to parse JWT token: %w", err)
-		}
+// loginKey returns the key used to deduplicate concurrent identical logins.
+// It includes a hash of the password, so that a login with another password never shares the result,
+// the client, so that the tokens issued to each client are counted, the device, which starts its own session, the country,
+// since the logins of the administrators may be rejected from some countries, and whether a step-up authentication is required.
+// It also includes the IP address and a hash of the User-Agent of the caller, which are recorded with the session,
+// checked by the anomaly detection, and sent in the login notification: only the retries of the same caller share a login.
+func loginKey(loginReq entity.LoginRequest) string {
+	hash := sha256.Sum256([]byte(loginReq.Password))
+	userAgent := sha256.Sum256([]byte(loginReq.UserAgent))
+	return strings.ToLower(loginReq.Username) + ":" + hex.EncodeToString(hash[:]) + ":" + loginReq.ClientID + ":" + loginReq.DeviceID + ":" + loginReq.Country +
+		":" + strconv.FormatBool(loginReq.StepUp) + ":" + loginReq.IPAddress + ":" + hex.EncodeToString(userAgent[:])
+}
 
-		// Get the expiration date from the token
-		expirationDateStr, err = GetExpirationDateFromToken(jwtToken)
-		if err != nil {
-			return fmt.Errorf("failed to get expiration date from token: %w", err)
-		}
+// login verifies the credentials of the user and issues the access and refresh tokens.
+func (s *authService) login(ctx context.Context, loginReq entity.LoginRequest) (entity.LoginResponse, error) {
+	// The login hooks may reject the login before the credentials are verified
+	if err := s.beforeLogin(loginReq); err != nil {
+		return entity.LoginResponse{}, err
+	}
+
+	// Check if the user exists
+	existingUser, err := s.userService.GetUserByUsername(ctx, loginReq.Username)
+	if err != nil {
+		return entity.LoginResponse{}, err
+	}
+
+	// Check some conditions for the user
+	if existingUser.Equals(&entity.User{}) {
+		return entity.LoginResponse{}, fmt.Errorf("%w: user with username %s not found", ErrInvalidCredentials, loginReq.Username)
+	}
+	if err := checkUserStatus(existingUser); err != nil {
+		return entity.LoginResponse{}, err
+	}
+
+	// Compare the provided password with the stored hashed password
+	if err := bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(loginReq.Password)); err != nil {
+		return entity.LoginResponse{}, fmt.Errorf("%w for user %s", ErrInvalidCredentials, loginReq.Username)
+	}
 
-		// Generate a refresh token for the user
-		refreshTokenRepo := repository.NewRefreshTokenRepository()
-		refreshTokenService := NewRefreshTokenService(refreshTokenRepo)
-		jwtRefreshToken, err := refreshTokenService.CreateRefreshToken(existingUser.ID)
+	return s.authenticated(ctx, existingUser, loginReq)
+}
+
+// LoginExternal logs in a user authenticated by an external identity provider, e.g. an OpenID Connect provider,
+// so no password is checked. The account must be active, and the login goes on like a login with a password:
+// the administrators are rejected from the blocked countries, and the users with two-factor authentication
+// complete the login with a code of their authenticator app.
+func (s *authService) LoginExternal(ctx context.Context, user entity.User, loginReq entity.LoginRequest) (resp entity.LoginResponse, err error) {
+	defer func() { recordLogin(resp, err) }()
+
+	if err := s.beforeLogin(loginReq); err != nil {
+		return entity.LoginResponse{}, err
+	}
+	if err := checkUserStatus(user); err != nil {
+		return entity.LoginResponse{}, err
+	}
+
+	return s.authenticated(ctx, user, loginReq)
+}
+
+// authenticated goes on with the login of the user once it is authenticated: it rejects the administrators
+// from the blocked countries, starts the MFA challenge of the users with two-factor authentication,
+// which also completes a step-up authentication, and issues the tokens of the other users.
+// A login requiring a step-up authentication of a user without two-factor authentication fails with ErrStepUpRequired.
+func (s *authService) authenticated(ctx context.Context, existingUser entity.User, loginReq entity.LoginRequest) (entity.LoginResponse, error) {
+	// Reject the logins of the administrators from the blocked countries
+	if s.blockedAdminCountry[loginReq.Country] && hasRole(existingUser, "ROLE_ADMIN") {
+		return entity.LoginResponse{}, ErrAdminLoginBlocked
+	}
+
+	// The users with two-factor authentication complete the login with a code of their authenticator app
+	if s.mfa != nil {
+		enabled, err := s.mfa.IsEnabled(ctx, existingUser.ID)
 		if err != nil {
-			return fmt.Errorf("failed to create refresh token: %w", err)
+			return entity.LoginResponse{}, err
 		}
-		if jwtRefreshToken.Equals(&entity.RefreshToken{}) {
-			return fmt.Errorf("failed to create refresh token")
+		if enabled {
+			challenge, err := s.mfa.StartChallenge(ctx, existingUser.ID)
+			if err != nil {
+				return entity.LoginResponse{}, err
+			}
+
+			return entity.LoginResponse{
+				MFARequired:            true,
+				MFAToken:               challenge.Token,
+				MFATokenExpirationDate: challenge.ExpiresAt.Format(time.RFC3339),
+			}, nil
 		}
+	}
+	if loginReq.StepUp {
+		return entity.LoginResponse{}, ErrStepUpRequired
+	}
 
-		refreshTokenStr = jwtRefreshToken.Token
+	return s.completeLogin(ctx, existingUser, loginReq)
+}
 
-		// Update the last login time for the user
-		_, err = userService.UpdateLastLogin(existingUser.ID, time.Now())
-		if err != nil {
-			return fmt.Errorf("failed to update last login time: %w", err)
-		}
+// VerifyMFA completes the login of a user with two-factor authentication, with the MFA token returned by Login
+// and a code of the authenticator app of the user. It issues the access and refresh tokens like Login.
+func (s *authService) VerifyMFA(ctx context.Context, verifyReq entity.VerifyMFARequest) (resp entity.LoginResponse, err error) {
+	defer func() { recordLogin(resp, err) }()
 
-		return nil
+	// Validate the request using the validator
+	if err := verifyReq.Validate(); err != nil {
+		return entity.LoginResponse{}, err
+	}
+	if s.mfa == nil {
+		return entity.LoginResponse{}, ErrInvalidMFAToken
+	}
+
+	userID, err := s.mfa.VerifyChallenge(ctx, verifyReq.MFAToken, verifyReq.Code)
+	if err != nil {
+		return entity.LoginResponse{}, err
+	}
+
+	// The account may have changed since the password was checked
+	user, err := s.userService.GetUserByID(ctx, userID)
+	if err != nil {
+		return entity.LoginResponse{}, err
+	}
+	if err := checkUserStatus(user); err != nil {
+		return entity.LoginResponse{}, err
+	}
+	if s.blockedAdminCountry[verifyReq.Country] && hasRole(user, "ROLE_ADMIN") {
+		return entity.LoginResponse{}, ErrAdminLoginBlocked
+	}
+
+	return s.completeLogin(ctx, user, entity.LoginRequest{
+		Username:  user.Username,
+		ClientID:  verifyReq.ClientID,
+		DeviceID:  verifyReq.DeviceID,
+		IPAddress: verifyReq.IPAddress,
+		UserAgent: verifyReq.UserAgent,
+		Country:   verifyReq.Country,
+		City:      verifyReq.City,
 	})
+}
+
+// completeLogin issues the access and refresh tokens of the authenticated user, in a new session on the device of the login.
+func (s *authService) completeLogin(ctx context.Context, existingUser entity.User, loginReq entity.LoginRequest) (entity.LoginResponse, error) {
+	// The login hooks may reject the login of the authenticated user, or add claims to its access token
+	existingUser, err := s.afterLogin(existingUser, loginReq)
+	if err != nil {
+		return entity.LoginResponse{}, err
+	}
 
+	// Issue the access and refresh tokens for the user, in a new session
+	device := entity.SessionDevice{
+		DeviceID:  loginReq.DeviceID,
+		ClientID:  loginReq.ClientID,
+		UserAgent: loginReq.UserAgent,
+		IPAddress: loginReq.IPAddress,
+	}
+	accessToken, refreshToken, err := s.issueTokens(ctx, existingUser, device, nil)
 	if err != nil {
 		return entity.LoginResponse{}, err
 	}
+	s.tokenUsage.Record(TokenEventIssued, existingUser.ID, loginReq.ClientID, s.clock.Now())
+
+	// Report the login, the user is notified if it comes from a new device
+	s.notifier.Notify(entity.SecurityEvent{
+		Type:      entity.SecurityEventLogin,
+		UserID:    existingUser.ID,
+		Username:  existingUser.Username,
+		Email:     existingUser.Email,
+		IPAddress: loginReq.IPAddress,
+		UserAgent: loginReq.UserAgent,
+		Country:   loginReq.Country,
+		City:      loginReq.City,
+		ClientID:  loginReq.ClientID,
+		At:        s.clock.Now(),
+	})
+	s.recordAudit(ctx, entity.AuditEvent{
+		Action:        entity.AuditActionLogin,
+		ActorID:       &existingUser.ID,
+		ActorUsername: existingUser.Username,
+		TargetType:    entity.AuditTargetUser,
+		TargetID:      strconv.FormatInt(existingUser.ID, 10),
+		Details:       loginReq.ClientID,
+		IPAddress:     loginReq.IPAddress,
+	})
 
 	return entity.LoginResponse{
-		AccessToken:    tokenStr,
-		RefreshToken:   refreshTokenStr,
-		ExpirationDate: expirationDateStr,
-		TokenType:      TokenType,
+		AccessToken:    accessToken.AccessToken,
+		RefreshToken:   refreshToken.Token,
+		ExpirationDate: accessToken.ExpiresAt.Format(time.RFC3339),
+		TokenType:      s.tokenIssuer.TokenType(),
 	}, nil
 }
 
 // RefreshToken refreshes the access token using the provided refresh token.
 // It retrieves the new access token and refresh token for the user.
-func (s *authService) RefreshToken(refreshTokenReq entity.RefreshTokenRequest) (entity.RefreshTokenResponse, error) {
-	// Load environment variables
-	LoadEnv()
-
-	// Get the database connection from the context
-	db := database.GetPostgres()
-	if db == nil {
-		return entity.RefreshTokenResponse{}, fmt.Errorf("database connection is nil")
-	}
+func (s *authService) RefreshToken(ctx context.Context, refreshTokenReq entity.RefreshTokenRequest) (resp entity.RefreshTokenResponse, err error) {
+	defer func() {
+		if err != nil {
+			metrics.TokenRefresh(metrics.RefreshResultFailed)
+			return
+		}
+		metrics.TokenRefresh(metrics.RefreshResultSucceeded)
+	}()
 
 	// Validate the refresh token request
 	if err := refreshTokenReq.Validate(); err != nil {
 		return entity.RefreshTokenResponse{}, err
 	}
 
-	var accessTokenStr string
-	var refreshTokenStr string
-	var expirationDateStr string
-	err := db.Transaction(func(tx *gorm.DB) error {
-		// Check if the refresh token exists
-		refreshTokenRepo := repository.NewRefreshTokenRepository()
-		refreshTokenService := NewRefreshTokenService(refreshTokenRepo)
-		existingRefreshToken, err := refreshTokenService.GetRefreshTokenByToken(refreshTokenReq.RefreshToken)
-		if err != nil {
-			return err
-		}
-		if existingRefreshToken.Equals(&entity.RefreshToken{}) {
-			return fmt.Errorf("refresh token not found")
-		}
-
-		// If found, check if the refresh token is expired
-		ok, _ := refreshTokenService.VerifyExpirationDate(existingRefreshToken.ExpiryDate)
-		if !ok {
-			return fmt.Errorf("refresh token is expired")
-		}
+	// Check if the refresh token exists
+	existingRefreshToken, err := s.refreshTokenService.GetRefreshTokenByToken(ctx, refreshTokenReq.RefreshToken)
+	if err != nil {
+		return entity.RefreshTokenResponse{}, err
+	}
+	if existingRefreshToken.Equals(&entity.RefreshToken{}) {
+		return entity.RefreshTokenResponse{}, fmt.Errorf("refresh token not found: %w", gorm.ErrRecordNotFound)
+	}
 
-		// Get user details using the user ID from the refresh token
-		userRepo := repository.NewUserRepository()
-		userService := NewUserService(userRepo)
-		userDetails, err := userService.GetUserByID(existingRefreshToken.UserID)
-		if err != nil {
-			return err
-		}
-		if userDetails.Equals(&entity.User{}) {
-			return fmt.Errorf("user with ID %d not found", existingRefreshToken.UserID)
+	// If an access token is given along with the refresh token, both must belong to the same user,
+	// so that a stolen refresh token cannot be used in the identity context of another user
+	if refreshTokenReq.AccessToken != "" {
+		userID, err := s.tokenIssuer.ParseUserID(refreshTokenReq.AccessToken)
+		if err != nil || userID != existingRefreshToken.UserID {
+			logger.WarnContext(ctx, "Refresh token presented with an invalid access token or the access token of another user", logrus.Fields{
+				"refresh_token_user_id": existingRefreshToken.UserID,
+				"access_token_user_id":  userID,
+			})
+			return entity.RefreshTokenResponse{}, ErrTokenSubjectMismatch
 		}
+	}
 
-		// Generate an access token for the user
-		accessTokenStr, err = GenerateJWTToken(userDetails)
-		if err != nil {
-			return fmt.Errorf("failed to generate JWT token: %w", err)
-		}
+	// If found, check if the refresh token is expired
+	ok, _ := s.refreshTokenService.VerifyExpirationDate(existingRefreshToken.ExpiryDate)
+	if !ok {
+		return entity.RefreshTokenResponse{}, ErrRefreshTokenExpired
+	}
 
-		// Parse the JWT token
-		jwtToken, err := ParseJWTToken(accessTokenStr)
-		if err != nil {
-			return fmt.Errorf("failed to parse JWT token: %w", err)
-		}
+	// Get user details using the user ID from the refresh token
+	userDetails, err := s.userService.GetUserByID(ctx, existingRefreshToken.UserID)
+	if err != nil {
+		return entity.RefreshTokenResponse{}, err
+	}
+	if userDetails.Equals(&entity.User{}) {
+		return entity.RefreshTokenResponse{}, fmt.Errorf("user with ID %d not found: %w", existingRefreshToken.UserID, gorm.ErrRecordNotFound)
+	}
+	if err := checkUserStatus(userDetails); err != nil {
+		return entity.RefreshTokenResponse{}, err
+	}
 
-		// Get the expiration date from the token
-		expirationDateStr, err = GetExpirationDateFromToken(jwtToken)
-		if err != nil {
-			return fmt.Errorf("failed to get expiration date from token: %w", err)
-		}
+	// Issue the new access token and rotate the refresh token of the session
+	accessToken, refreshToken, err := s.issueTokens(ctx, userDetails, entity.SessionDevice{ClientID: refreshTokenReq.ClientID}, &existingRefreshToken)
+	if err != nil {
+		return entity.RefreshTokenResponse{}, err
+	}
+	s.tokenUsage.Record(TokenEventRefreshed, userDetails.ID, refreshTokenReq.ClientID, s.clock.Now())
+	s.recordAudit(ctx, entity.AuditEvent{
+		Action:        entity.AuditActionTokenRefresh,
+		ActorID:       &userDetails.ID,
+		ActorUsername: userDetails.Username,
+		TargetType:    entity.AuditTargetUser,
+		TargetID:      strconv.FormatInt(userDetails.ID, 10),
+		Details:       refreshTokenReq.ClientID,
+		IPAddress:     refreshTokenReq.IPAddress,
+	})
 
-		// Regenerate a refresh token for the user
-		jwtRefreshToken, err := refreshTokenService.CreateRefreshToken(userDetails.ID)
-		if err != nil {
-			return fmt.Errorf("failed to create refresh token: %w", err)
-		}
-		if jwtRefreshToken.Equals(&entity.RefreshToken{}) {
-			return fmt.Errorf("failed to create refresh token")
-		}
+	return entity.RefreshTokenResponse{
+		AccessToken:    accessToken.AccessToken,
+		RefreshToken:   refreshToken.Token,
+		ExpirationDate: accessToken.ExpiresAt.Format(time.RFC3339),
+		TokenType:      s.tokenIssuer.TokenType(),
+	}, nil
+}
 
-		refreshTokenStr = jwtRefreshToken.Token
+// Logout ends the session of the user: the refresh token is deleted, so it cannot be used to refresh the tokens,
+// and the access token of the request is revoked until it expires.
+func (s *authService) Logout(ctx context.Context, logoutReq entity.LogoutRequest) error {
+	// Validate the logout request
+	if err := logoutReq.Validate(); err != nil {
+		return err
+	}
 
-		// Update the last login time for the user
-		_, err = userService.UpdateLastLogin(userDetails.ID, time.Now())
-		if err != nil {
-			return fmt.Errorf("failed to update last login time: %w", err)
-		}
+	if err := s.tokenRevocation.RevokeSession(ctx, logoutReq); err != nil {
+		return err
+	}
 
-		return nil
+	s.recordAudit(ctx, entity.AuditEvent{
+		Action:     entity.AuditActionLogout,
+		ActorID:    &logoutReq.UserID,
+		TargetType: entity.AuditTargetUser,
+		TargetID:   strconv.FormatInt(logoutReq.UserID, 10),
+		IPAddress:  logoutReq.IPAddress,
 	})
+	return nil
+}
+
+// recordAudit records the event in the audit log, if the auth service is audited.
+func (s *authService) recordAudit(ctx context.Context, event entity.AuditEvent) {
+	if s.audit != nil {
+		s.audit.Record(ctx, event)
+	}
+}
 
+// Register creates the account of a user registering themselves, with the default role, and returns it without its password.
+// The user logs in afterwards, no token is issued on registration.
+func (s *authService) Register(ctx context.Context, registerReq entity.RegisterRequest) (entity.RegisterResponse, error) {
+	user, err := s.userService.CreateUser(ctx, registerReq)
 	if err != nil {
-		return entity.RefreshTokenResponse{}, err
+		return entity.RegisterResponse{}, err
 	}
 
-	return entity.RefreshTokenResponse{
-		AccessToken:    accessTokenStr,
-		RefreshToken:   refreshTokenStr,
-		ExpirationDate: expirationDateStr,
-		TokenType:      TokenType,
+	roles := make([]string, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		roles = append(roles, role.Name)
+	}
+
+	return entity.RegisterResponse{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Firstname: user.Firstname,
+		Lastname:  user.Lastname,
+		State:     user.State,
+		Roles:     roles,
+		CreatedAt: user.CreatedAt,
 	}, nil
 }
 
-// GenerateJWTToken determines the function to use for generating a JWT token based on the signing method.
-// It checks the signing method from the environment variable and calls the appropriate function.
-func GenerateJWTToken(user entity.User) (string, error) {
-	// Load environment variables
-	// LoadEnv()
+// hasRole reports whether the user has the role with the given name.
+func hasRole(user entity.User, name string) bool {
+	for _, role := range user.Roles {
+		if role.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
 
-	// Check the signing method from the environment variable
-	if SigningMethod == jwt.SigningMethodHS256.Alg() {
-		return GenerateJWTTokenWithHS256(user)
-	} else if SigningMethod == jwt.SigningMethodRS256.Alg() {
-		return GenerateJWTTokenWithRS256(user)
+// checkUserStatus checks that the user account can be used to authenticate.
+// Only the active accounts can log in or refresh their tokens.
+func checkUserStatus(user entity.User) error {
+	if user.IsDeleted != nil && *user.IsDeleted {
+		return fmt.Errorf("%w: user with username %s is deleted", ErrUserLocked, user.Username)
 	}
 
-	return "", fmt.Errorf("unsupported signing method: %s", SigningMethod)
+	switch user.State {
+	case entity.UserStateActive:
+		return nil
+	case entity.UserStateDisabled:
+		return fmt.Errorf("%w: user with username %s is disabled", ErrUserLocked, user.Username)
+	case entity.UserStateSuspended:
+		return fmt.Errorf("%w: user account is suspended", ErrUserLocked)
+	case entity.UserStatePendingVerification:
+		return ErrUserNotVerified
+	default:
+		return fmt.Errorf("%w: user account is in an unknown state %q", ErrUserLocked, user.State)
+	}
 }
 
-// GenerateJWTTokenWithHS256 generates a JWT token using the HS256 signing method.
-// It creates the claims for the token and signs it with the secret key from the environment variable.
-func GenerateJWTTokenWithHS256(user entity.User) (string, error) {
-	// Load environment variables
-	// LoadEnv()
+// issueTokens issues an access token and a refresh token for the user logging in with the client of the given device,
+// then records the last login time of the user, which is written asynchronously.
+// The refresh token replaces the given one, or starts a new session on the device if none is given.
+func (s *authService) issueTokens(ctx context.Context, user entity.User, device entity.SessionDevice, replaced *entity.RefreshToken) (IssuedToken, entity.RefreshToken, error) {
+	// Generate an access token for the user
+	now := s.clock.Now()
+	accessToken, err := s.tokenIssuer.IssueToken(user, device.ClientID, now)
+	if err != nil {
+		return IssuedToken{}, entity.RefreshToken{}, err
+	}
 
-	// Set the now time
-	// This is used to set the issued at (iat) and expiration (exp) claims
-	now := time.Now().Unix()
+	// Generate a refresh token for the user
+	var refreshToken entity.RefreshToken
+	if replaced != nil {
+		refreshToken, err = s.refreshTokenService.RotateRefreshToken(ctx, *replaced)
+	} else {
+		refreshToken, err = s.refreshTokenService.CreateRefreshToken(ctx, user.ID, device)
+	}
+	if err != nil {
+		return IssuedToken{}, entity.RefreshToken{}, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	if refreshToken.Equals(&entity.RefreshToken{}) {
+		return IssuedToken{}, entity.RefreshToken{}, fmt.Errorf("failed to create refresh token")
+	}
 
-	// Create the claims for the JWT token
-	claims := jwt.MapClaims{
-		"sub":      user.Username,
-		"aud":      JWTAudience,
-		"iss":      JWTIssuer,
-		"iat":      now,
-		"exp":      GetJWTExpiration(now),
-		"email":    user.Email,
-		"userid":   user.ID,
-		"username": user.Username,
-		"roles":    ExtractRoleNames(user.Roles),
+	// Record the last login time for the user, the login does not wait for it to be written
+	s.lastLogin.Record(user.ID, now)
+
+	return accessToken, refreshToken, nil
+}
+
+// GenerateJWTToken determines the function to use for generating a JWT token based on the signing method.
+// It checks the signing method of the given settings and calls the appropriate function.
+// The given time is used as the issued at time of the token, and the client is used to resolve its lifetime.
+func GenerateJWTToken(cfg jwtconfig.JWTConfig, user entity.User, clientID string, issuedAt time.Time) (string, error) {
+	// Check the signing method of the settings
+	switch cfg.SigningMethod {
+	case jwt.SigningMethodHS256.Alg():
+		return GenerateJWTTokenWithHS256(cfg, user, clientID, issuedAt)
+	case jwt.SigningMethodRS256.Alg():
+		return GenerateJWTTokenWithRS256(cfg, user, clientID, issuedAt)
+	case jwt.SigningMethodES256.Alg():
+		return GenerateJWTTokenWithES256(cfg, user, clientID, issuedAt)
+	case jwt.SigningMethodEdDSA.Alg():
+		return GenerateJWTTokenWithEdDSA(cfg, user, clientID, issuedAt)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(JWTSecret))
+	return "", fmt.Errorf("unsupported signing method: %s", cfg.SigningMethod)
+}
+
+// GenerateJWTTokenWithHS256 generates a JWT token using the HS256 signing method.
+// It creates the claims for the token and signs it with the secret key of the settings.
+func GenerateJWTTokenWithHS256(cfg jwtconfig.JWTConfig, user entity.User, clientID string, issuedAt time.Time) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, newAccessTokenClaims(cfg, user, clientID, issuedAt))
+	return token.SignedString([]byte(cfg.Secret))
 }
 
 // GenerateJWTTokenWithRS256 generates a JWT token using the RS256 signing method.
-// It creates the claims for the token and signs it with the private key loaded from the file.
-func GenerateJWTTokenWithRS256(user entity.User) (string, error) {
-	// Load environment variables
-	// LoadEnv()
+// It creates the claims for the token and signs it with the current key of the key set.
+func GenerateJWTTokenWithRS256(cfg jwtconfig.JWTConfig, user entity.User, clientID string, issuedAt time.Time) (string, error) {
+	// Load the current signing key
+	signingKey, err := cfg.Keys.LoadSigningKey()
+	if err != nil {
+		return "", err
+	}
 
-	// Load the private key from the file
-	privateKey, err := jwtutil.LoadPrivateKey()
+	// The kid header identifies the public key verifying the token in the published key set
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, newAccessTokenClaims(cfg, user, clientID, issuedAt))
+	token.Header["kid"] = signingKey.ID
+	return token.SignedString(signingKey.PrivateKey)
+}
+
+// GenerateJWTTokenWithES256 generates a JWT token using the ES256 signing method.
+// It creates the claims for the token and signs it with the ECDSA P-256 private key of JWT_PRIVATE_KEY_PATH.
+func GenerateJWTTokenWithES256(cfg jwtconfig.JWTConfig, user entity.User, clientID string, issuedAt time.Time) (string, error) {
+	privateKey, err := cfg.Keys.LoadECPrivateKey()
 	if err != nil {
 		return "", err
 	}
 
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, newAccessTokenClaims(cfg, user, clientID, issuedAt))
+	return token.SignedString(privateKey)
+}
+
+// GenerateJWTTokenWithEdDSA generates a JWT token using the EdDSA signing method.
+// It creates the claims for the token and signs it with the Ed25519 private key of JWT_PRIVATE_KEY_PATH.
+func GenerateJWTTokenWithEdDSA(cfg jwtconfig.JWTConfig, user entity.User, clientID string, issuedAt time.Time) (string, error) {
+	privateKey, err := cfg.Keys.LoadEdPrivateKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, newAccessTokenClaims(cfg, user, clientID, issuedAt))
+	return token.SignedString(privateKey)
+}
+
+// newAccessTokenClaims creates the claims of an access token issued to the user for the client at the given time.
+func newAccessTokenClaims(cfg jwtconfig.JWTConfig, user entity.User, clientID string, issuedAt time.Time) jwt.MapClaims {
 	// Set the now time
 	// This is used to set the issued at (iat) and expiration (exp) claims
-	now := time.Now().Unix()
+	now := issuedAt.Unix()
 
 	// Create the claims for the JWT token
 	claims := jwt.MapClaims{
-		"sub":      user.Username,
-		"aud":      JWTAudience,
-		"iss":      JWTIssuer,
-		"iat":      now,
-		"exp":      GetJWTExpiration(now),
-		"email":    user.Email,
-		"userid":   user.ID,
-		"username": user.Username,
-		"roles":    ExtractRoleNames(user.Roles),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	return token.SignedString(privateKey)
+		"sub":          user.Username,
+		"aud":          cfg.Audience,
+		"iss":          cfg.Issuer,
+		"iat":          now,
+		"exp":          GetJWTExpiration(cfg, user, clientID, now),
+		"email":        user.Email,
+		"userid":       user.ID,
+		"username":     user.Username,
+		"roles":        ExtractRoleNames(user.Roles),
+		"tokenversion": user.TokenVersion,
+		"jti":          uuid.New().String(),
+	}
+	if clientID != "" {
+		claims["client"] = clientID
+	}
+	if len(user.Scopes) > 0 {
+		claims["scopes"] = user.Scopes
+	}
+
+	// The additional claims of the login hooks never replace the claims above, even the optional ones
+	for name, value := range user.Claims {
+		if _, ok := claims[name]; !ok && name != "client" && name != "scopes" {
+			claims[name] = value
+		}
+	}
+
+	return claims
 }
 
 // ParseJWTToken determines the function to use for parsing a JWT token based on the signing method.
-// It checks the signing method from the environment variable and calls the appropriate function.
-func ParseJWTToken(tokenStr string) (*jwt.Token, error) {
-	// Load environment variables
-	// LoadEnv()
-
-	// Check the signing method from the environment variable
-	if SigningMethod == jwt.SigningMethodHS256.Alg() {
-		return ParseJWTTokenWithHS256(tokenStr)
-	} else if SigningMethod == jwt.SigningMethodRS256.Alg() {
-		return ParseJWTTokenWithRS256(tokenStr)
+// It checks the signing method of the given settings and calls the appropriate function.
+// The given time is used as the current time when validating the time based claims,
+// and the aud and iss claims are validated against the audience and the issuer of the settings.
+func ParseJWTToken(cfg jwtconfig.JWTConfig, tokenStr string, now time.Time) (*jwt.Token, error) {
+	// Check the signing method of the settings
+	switch cfg.SigningMethod {
+	case jwt.SigningMethodHS256.Alg():
+		return ParseJWTTokenWithHS256(cfg, tokenStr, now)
+	case jwt.SigningMethodRS256.Alg():
+		return ParseJWTTokenWithRS256(cfg, tokenStr, now)
+	case jwt.SigningMethodES256.Alg():
+		return ParseJWTTokenWithES256(cfg, tokenStr, now)
+	case jwt.SigningMethodEdDSA.Alg():
+		return ParseJWTTokenWithEdDSA(cfg, tokenStr, now)
 	}
 
-	return nil, fmt.Errorf("unsupported signing method: %s", SigningMethod)
+	return nil, fmt.Errorf("unsupported signing method: %s", cfg.SigningMethod)
 }
 
 // ParseJWTTokenWithHS256 parses a JWT token using the HS256 signing method.
 // It validates the token and returns the parsed token object.
-func ParseJWTTokenWithHS256(tokenStr string) (*jwt.Token, error) {
-	// Load environment variables
-	// LoadEnv()
-
+func ParseJWTTokenWithHS256(cfg jwtconfig.JWTConfig, tokenStr string, now time.Time) (*jwt.Token, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(JWTSecret), nil
-	})
+		return []byte(cfg.Secret), nil
+	}, append(cfg.AudienceIssuerOptions(), jwt.WithValidMethods([]string{cfg.SigningMethod}), jwt.WithTimeFunc(func() time.Time { return now }))...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse JWT token: %v", err)
 	}
@@ -377,40 +701,109 @@ func ParseJWTTokenWithHS256(tokenStr string) (*jwt.Token, error) {
 }
 
 // ParseJWTTokenWithRS256 parses a JWT token using the RS256 signing method.
-// It validates the token and returns the parsed token object.
-func ParseJWTTokenWithRS256(tokenStr string) (*jwt.Token, error) {
-	// Load the public key from the file
-	publicKey, err := jwtutil.LoadPublicKey()
+// It validates the token with the public key selected by its kid header, among the keys of the key set
+// still verifying valid tokens at the given time, and returns the parsed token object.
+func ParseJWTTokenWithRS256(cfg jwtconfig.JWTConfig, tokenStr string, now time.Time) (*jwt.Token, error) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		publicKey, err := cfg.Keys.LoadPublicKeyByID(jwtutil.TokenKeyID(token), cfg.TTL.Max(), now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load public key: %v", err)
+		}
+		return publicKey, nil
+	}, append(cfg.AudienceIssuerOptions(), jwt.WithValidMethods([]string{cfg.SigningMethod}), jwt.WithTimeFunc(func() time.Time { return now }))...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load public key: %v", err)
+		return nil, fmt.Errorf("failed to parse JWT token: %v", err)
 	}
+	return token, nil
+}
 
+// ParseJWTTokenWithES256 parses a JWT token using the ES256 signing method.
+// It validates the token with the ECDSA P-256 public key of JWT_PUBLIC_KEY_PATH and returns the parsed token object.
+func ParseJWTTokenWithES256(cfg jwtconfig.JWTConfig, tokenStr string, now time.Time) (*jwt.Token, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
+
+		publicKey, err := cfg.Keys.LoadECPublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load public key: %v", err)
+		}
 		return publicKey, nil
-	})
+	}, append(cfg.AudienceIssuerOptions(), jwt.WithValidMethods([]string{cfg.SigningMethod}), jwt.WithTimeFunc(func() time.Time { return now }))...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse JWT token: %v", err)
 	}
 	return token, nil
 }
 
-// GetRefreshTokenExpiration calculates the expiration time for the refresh token.
-func GetJWTExpiration(now int64) int64 {
-	// Load environment variables
-	// LoadEnv()
+// ParseJWTTokenWithEdDSA parses a JWT token using the EdDSA signing method.
+// It validates the token with the Ed25519 public key of JWT_PUBLIC_KEY_PATH and returns the parsed token object.
+func ParseJWTTokenWithEdDSA(cfg jwtconfig.JWTConfig, tokenStr string, now time.Time) (*jwt.Token, error) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
 
-	expHour, err := strconv.Atoi(JWTExpirationHour)
+		publicKey, err := cfg.Keys.LoadEdPublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load public key: %v", err)
+		}
+		return publicKey, nil
+	}, append(cfg.AudienceIssuerOptions(), jwt.WithValidMethods([]string{cfg.SigningMethod}), jwt.WithTimeFunc(func() time.Time { return now }))...)
 	if err != nil {
-		return now + int64(time.Hour.Seconds()*24)
+		return nil, fmt.Errorf("failed to parse JWT token: %v", err)
 	}
-	if expHour <= 0 {
-		expHour = 24
+	return token, nil
+}
+
+// ParseExpiredJWTToken parses a JWT token signed with the signing method of the given settings,
+// without validating its time based claims, e.g. to read the claims of an access token that has expired.
+// The signature of the token is still verified.
+func ParseExpiredJWTToken(cfg jwtconfig.JWTConfig, tokenStr string) (*jwt.Token, error) {
+	switch cfg.SigningMethod {
+	case jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg(), jwt.SigningMethodEdDSA.Alg():
+	default:
+		return nil, fmt.Errorf("unsupported signing method: %s", cfg.SigningMethod)
 	}
 
-	return now + int64(time.Duration(expHour)*time.Hour/time.Second)
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != cfg.SigningMethod {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		switch cfg.SigningMethod {
+		case jwt.SigningMethodHS256.Alg():
+			return []byte(cfg.Secret), nil
+		case jwt.SigningMethodES256.Alg():
+			return cfg.Keys.LoadECPublicKey()
+		case jwt.SigningMethodEdDSA.Alg():
+			return cfg.Keys.LoadEdPublicKey()
+		}
+
+		// The token is expired, so it is verified with any key of the key set, retired or not
+		keySet, err := cfg.Keys.LoadKeySet()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load public key: %v", err)
+		}
+		return keySet.PublicKey(jwtutil.TokenKeyID(token))
+	}, jwt.WithValidMethods([]string{cfg.SigningMethod}), jwt.WithoutClaimsValidation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT token: %v", err)
+	}
+	return token, nil
+}
+
+// GetJWTExpiration calculates the expiration time of an access token issued to the user for the client at the given time.
+// The lifetime of the token is resolved by the TTL policy of the settings from the type and the roles of the user and the client.
+func GetJWTExpiration(cfg jwtconfig.JWTConfig, user entity.User, clientID string, now int64) int64 {
+	ttl := cfg.TTL.Resolve(user.UserType, ExtractRoleNames(user.Roles), clientID)
+
+	return now + int64(ttl/time.Second)
 }
 
 // ExtractRoleNames extracts the role names from a slice of roles.
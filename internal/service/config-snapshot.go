@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	jwtconfig "github.com/yoanesber/go-jwt-auth-demo/config/jwt-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/diagnostics"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/quota"
+)
+
+//go:generate go tool mockgen -source=config-snapshot.go -destination=../../tests/mocks/config-snapshot-service.go -package=mocks
+
+// Interface for config snapshot service
+// This interface defines the methods used to report the effective runtime configuration of the instance
+type ConfigSnapshotService interface {
+	GetConfigSnapshot() (entity.ConfigSnapshot, error)
+}
+
+// This struct defines the ConfigSnapshotService that contains the sources of the reported configuration:
+// the features enabled at startup, the rate limits of the routes, the quota policy, the services holding the runtime flags,
+// the rate limit overrides, and the signing keys, the JWT settings, and a clock used to get the current time
+// It implements the ConfigSnapshotService interface
+type configSnapshotService struct {
+	features           func() map[string]bool
+	rateLimits         []entity.RateLimitSetting
+	quotas             quota.Policy
+	featureFlags       FeatureFlagService
+	rateLimitOverrides RateLimitOverrideService
+	signingKeys        SigningKeyService
+	jwtConfig          jwtconfig.JWTConfig
+	clock              clock.Clock
+}
+
+// NewConfigSnapshotService creates a new instance of ConfigSnapshotService with the given sources.
+// The features are read on each snapshot, so that the ones enabled once the dependencies are initialized are reported.
+func NewConfigSnapshotService(features func() map[string]bool, rateLimits []entity.RateLimitSetting, quotas quota.Policy, featureFlags FeatureFlagService,
+	rateLimitOverrides RateLimitOverrideService, signingKeys SigningKeyService, jwtConfig jwtconfig.JWTConfig, clk clock.Clock) ConfigSnapshotService {
+	return &configSnapshotService{
+		features:           features,
+		rateLimits:         rateLimits,
+		quotas:             quotas,
+		featureFlags:       featureFlags,
+		rateLimitOverrides: rateLimitOverrides,
+		signingKeys:        signingKeys,
+		jwtConfig:          jwtConfig,
+		clock:              clk,
+	}
+}
+
+// GetConfigSnapshot returns the effective configuration of the instance: its build, its sanitized environment,
+// the features enabled at startup and the ones disabled at runtime, the rate limits and the quotas, the IDs of the signing keys in use,
+// and the statistics of its connection pools.
+func (s *configSnapshotService) GetConfigSnapshot() (entity.ConfigSnapshot, error) {
+	info := diagnostics.CollectStartupInfo(s.features())
+
+	flags, err := s.featureFlags.GetFeatureFlags(context.Background())
+	if err != nil {
+		return entity.ConfigSnapshot{}, fmt.Errorf("failed to get the feature flags: %w", err)
+	}
+
+	overrides, err := s.rateLimitOverrides.GetRateLimitOverrides(context.Background())
+	if err != nil {
+		return entity.ConfigSnapshot{}, fmt.Errorf("failed to get the rate limit overrides: %w", err)
+	}
+
+	keys, err := s.keys()
+	if err != nil {
+		return entity.ConfigSnapshot{}, fmt.Errorf("failed to get the signing keys: %w", err)
+	}
+
+	quotas := entity.ConfigSnapshotQuotas{
+		Default: entity.QuotaBudget{Daily: s.quotas.Default.Daily, Monthly: s.quotas.Default.Monthly},
+		Clients: make(map[string]entity.QuotaBudget, len(s.quotas.Clients)),
+		Costs:   s.quotas.Costs,
+	}
+	for client, budget := range s.quotas.Clients {
+		quotas.Clients[client] = entity.QuotaBudget{Daily: budget.Daily, Monthly: budget.Monthly}
+	}
+
+	return entity.ConfigSnapshot{
+		Version:       info.Version,
+		Commit:        info.Commit,
+		GoVersion:     info.GoVersion,
+		Config:        info.Config,
+		Features:      info.Features,
+		FeatureFlags:  flags,
+		RateLimits:    entity.ConfigSnapshotLimits{Routes: s.rateLimits, Overrides: overrides},
+		Quotas:        quotas,
+		SigningKeys:   keys,
+		GeneratedAt:   s.clock.Now(),
+		DatabasePools: diagnostics.DatabasePoolStats(),
+	}, nil
+}
+
+// keys returns the IDs of the keys of the RS256 tokens: the current key, and every key still verifying tokens, sorted.
+// The other algorithms have no key ID.
+func (s *configSnapshotService) keys() (entity.ConfigSnapshotKeys, error) {
+	keys := entity.ConfigSnapshotKeys{Algorithm: s.jwtConfig.SigningMethod, KeyIDs: []string{}}
+	if s.jwtConfig.SigningMethod != jwt.SigningMethodRS256.Alg() {
+		return keys, nil
+	}
+
+	keySet, err := s.jwtConfig.Keys.LoadKeySet()
+	if err != nil {
+		return entity.ConfigSnapshotKeys{}, err
+	}
+	keys.CurrentKeyID = keySet.Current.ID
+
+	published, err := s.signingKeys.GetKeySet()
+	if err != nil {
+		return entity.ConfigSnapshotKeys{}, err
+	}
+	for _, key := range published.Keys {
+		keys.KeyIDs = append(keys.KeyIDs, key.Kid)
+	}
+	sort.Strings(keys.KeyIDs)
+
+	return keys, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+)
+
+/**
+ * The consumer cache is a read-through cache in front of the consumer lookups by ID,
+ * which downstream services repeat for their hot consumers.
+ * Concurrent lookups of the same consumer share a single database query, and the consumers found
+ * are cached for a short TTL. Unknown IDs and errors are not cached. The entry of a consumer is
+ * invalidated when the consumer is updated, and a lookup started before the update never caches its result.
+ * The shared query is not canceled with the request that started it, so that the other requests waiting for it
+ * do not fail with its cancellation; each request stops waiting once its own context is done.
+ * The hits and misses are exported on /metrics as consumer_cache_requests_total.
+ */
+
+// defaultConsumerCacheSize is the maximum number of consumers cached.
+const defaultConsumerCacheSize = 10000
+
+// consumerCacheEntry holds a cached consumer and the time it expires.
+type consumerCacheEntry struct {
+	consumer  entity.Consumer
+	expiresAt time.Time
+}
+
+// consumerCache caches the consumers by ID.
+type consumerCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxSize    int
+	clock      clock.Clock
+	entries    map[string]consumerCacheEntry
+	generation uint64
+	lookups    singleflight.Group
+}
+
+// newConsumerCache creates a new consumer cache keeping the consumers for the given TTL.
+func newConsumerCache(ttl time.Duration, clk clock.Clock) *consumerCache {
+	return &consumerCache{
+		ttl:     ttl,
+		maxSize: defaultConsumerCacheSize,
+		clock:   clk,
+		entries: make(map[string]consumerCacheEntry),
+	}
+}
+
+// getOrLoad returns the cached consumer with the given ID, or loads it with the given function.
+// Concurrent loads of the same consumer are coalesced into a single call, which is not canceled with the context;
+// the wait for it returns the error of the context once the context is done.
+func (c *consumerCache) getOrLoad(ctx context.Context, id string, load func(ctx context.Context) (entity.Consumer, error)) (entity.Consumer, error) {
+	if consumer, ok := c.get(id); ok {
+		metrics.ConsumerCacheLookup(true)
+		return consumer, nil
+	}
+	metrics.ConsumerCacheLookup(false)
+
+	lookupCtx := context.WithoutCancel(ctx)
+	results := c.lookups.DoChan(id, func() (interface{}, error) {
+		generation := c.currentGeneration()
+		consumer, err := load(lookupCtx)
+		if err != nil {
+			return entity.Consumer{}, err
+		}
+
+		c.set(id, consumer, generation)
+		return consumer, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return entity.Consumer{}, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return entity.Consumer{}, result.Err
+		}
+
+		return cloneConsumer(result.Val.(entity.Consumer)), nil
+	}
+}
+
+// get returns the cached consumer with the given ID, if present and not expired.
+func (c *consumerCache) get(id string) (entity.Consumer, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[id]
+	if !ok {
+		return entity.Consumer{}, false
+	}
+	if !c.clock.Now().Before(entry.expiresAt) {
+		delete(c.entries, id)
+		return entity.Consumer{}, false
+	}
+
+	return cloneConsumer(entry.consumer), true
+}
+
+// set caches the consumer loaded at the given generation, unless a consumer was invalidated since.
+// When the cache is full, the expired entries are evicted first; if it is still full, the consumer is not cached.
+func (c *consumerCache) set(id string, consumer entity.Consumer, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	now := c.clock.Now()
+	if _, exists := c.entries[id]; !exists && len(c.entries) >= c.maxSize {
+		for k, e := range c.entries {
+			if !now.Before(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= c.maxSize {
+			return
+		}
+	}
+
+	c.entries[id] = consumerCacheEntry{consumer: cloneConsumer(consumer), expiresAt: now.Add(c.ttl)}
+}
+
+// currentGeneration returns the number of invalidations so far.
+func (c *consumerCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+// invalidate removes the cached consumer with the given ID,
+// and prevents the lookups in flight from caching the consumer they loaded before the update.
+func (c *consumerCache) invalidate(id string) {
+	c.mu.Lock()
+	delete(c.entries, id)
+	c.generation++
+	c.mu.Unlock()
+
+	c.lookups.Forget(id)
+}
+
+// cloneConsumer returns a copy of the consumer that does not share its birth date with the original.
+func cloneConsumer(consumer entity.Consumer) entity.Consumer {
+	if consumer.BirthDate != nil {
+		birthDate := *consumer.BirthDate
+		consumer.BirthDate = &birthDate
+	}
+
+	return consumer
+}
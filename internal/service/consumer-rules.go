@@ -0,0 +1,85 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// ConsumerRuleViolation is a rule of the deployment broken by a consumer, on the given field of its payload.
+type ConsumerRuleViolation struct {
+	Field   string
+	Message string
+}
+
+// ConsumerRuleError is returned when a consumer breaks the rules of the deployment, with every rule it breaks.
+type ConsumerRuleError struct {
+	Violations []ConsumerRuleViolation
+}
+
+// Error returns the messages of the violations.
+func (e *ConsumerRuleError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+
+	return "consumer breaks the rules of the deployment: " + strings.Join(messages, "; ")
+}
+
+// checkConsumerRules returns a *ConsumerRuleError listing the rules of the deployment broken by the consumer, or nil if it breaks none.
+func checkConsumerRules(r policyconfig.ConsumerRules, c entity.Consumer) error {
+	var violations []ConsumerRuleViolation
+
+	var missing []string
+	for _, key := range r.RequiredMetadataKeys {
+		if strings.TrimSpace(c.Metadata[key]) == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		violations = append(violations, ConsumerRuleViolation{
+			Field:   "metadata",
+			Message: fmt.Sprintf("metadata must have the keys: %s", strings.Join(missing, ", ")),
+		})
+	}
+
+	if r.UsernamePattern != nil && !r.UsernamePattern.MatchString(c.Username) {
+		violations = append(violations, ConsumerRuleViolation{
+			Field:   "username",
+			Message: "username does not match the pattern of the usernames",
+		})
+	}
+
+	if domain := emailDomain(c.Email); domain != "" {
+		for _, blocked := range r.BlockedEmailDomains {
+			if domain == blocked || strings.HasSuffix(domain, "."+blocked) {
+				violations = append(violations, ConsumerRuleViolation{
+					Field:   "email",
+					Message: fmt.Sprintf("email addresses of the domain %s are not accepted", blocked),
+				})
+				break
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &ConsumerRuleError{Violations: violations}
+	}
+
+	return nil
+}
+
+// emailDomain returns the domain of the email address in lower case, or an empty string if it has none.
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimSuffix(email[at+1:], "."))
+}
@@ -1,51 +1,151 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
 	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/eventbus"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+)
+
+//go:generate go tool mockgen -source=consumer.go -destination=../../tests/mocks/consumer-service.go -package=mocks
+
+var (
+	// ErrDuplicateUsername is returned when a consumer is created with the username of another consumer.
+	ErrDuplicateUsername = errors.New("consumer username already exists")
+
+	// ErrDuplicateEmail is returned when a consumer is created, or updated, with the email of another consumer.
+	ErrDuplicateEmail = errors.New("consumer email already exists")
+
+	// ErrDuplicatePhone is returned when a consumer is created, or updated, with the phone of another consumer.
+	ErrDuplicatePhone = errors.New("consumer phone already exists")
+
+	// ErrEmptyConsumerUpdate is returned when a consumer is updated without any of its fields.
+	ErrEmptyConsumerUpdate = errors.New("at least one of fullname, email, phone, address, or birthDate must be given")
+
+	// ErrDeletedConsumersForbidden is returned when a caller who is not an administrator lists the deleted consumers.
+	ErrDeletedConsumersForbidden = errors.New("only the administrators can list the deleted consumers")
+
+	// ErrConsumerNotDeleted is returned when a consumer which is not deleted is restored.
+	ErrConsumerNotDeleted = errors.New("the consumer is not deleted")
 )
 
 // Interface for consumer service
 // This interface defines the methods that the consumer service should implement
 type ConsumerService interface {
-	GetAllConsumers(page int, limit int) ([]entity.Consumer, error)
-	GetConsumerByID(id string) (entity.Consumer, error)
-	GetActiveConsumers(page int, limit int) ([]entity.Consumer, error)
-	GetInactiveConsumers(page int, limit int) ([]entity.Consumer, error)
-	GetSuspendedConsumers(page int, limit int) ([]entity.Consumer, error)
-	CreateConsumer(c entity.Consumer) (entity.Consumer, error)
-	UpdateConsumerStatus(id string, status string) (entity.Consumer, error)
+	GetAllConsumers(ctx context.Context, roles []string, page int, limit int, includeDeleted bool) ([]entity.Consumer, error)
+	StreamConsumers(ctx context.Context, roles []string, includeDeleted bool, fn func(entity.Consumer) error) error
+	GetConsumerByID(ctx context.Context, id string) (entity.Consumer, error)
+	GetActiveConsumers(ctx context.Context, roles []string, page int, limit int, includeDeleted bool) ([]entity.Consumer, error)
+	GetInactiveConsumers(ctx context.Context, roles []string, page int, limit int, includeDeleted bool) ([]entity.Consumer, error)
+	GetSuspendedConsumers(ctx context.Context, roles []string, page int, limit int, includeDeleted bool) ([]entity.Consumer, error)
+	CreateConsumer(ctx context.Context, c entity.Consumer) (entity.Consumer, error)
+	CheckConsumerAvailability(ctx context.Context, req entity.ConsumerAvailabilityRequest) (entity.ConsumerAvailability, error)
+	UpdateConsumer(ctx context.Context, id string, req entity.ConsumerUpdateRequest) (entity.Consumer, error)
+	UpdateConsumerStatus(ctx context.Context, id string, status string) (entity.Consumer, error)
+	DeleteConsumer(ctx context.Context, id string) error
+	RestoreConsumer(ctx context.Context, id string) (entity.Consumer, error)
 }
 
-// This struct defines the ConsumerService that contains a repository field of type ConsumerRepository
+// This struct defines the ConsumerService that contains a repository field of type ConsumerRepository,
+// the policy deciding which consumers each role sees in the consumer listing,
+// the optional cache of the consumer lookups by ID, the optional webhook the consumer events are sent to,
+// the optional event bus pushing them to the connected admin UIs, the rules of the deployment the new consumers are checked against,
+// and the clock setting the deletion time of the consumers
 // It implements the ConsumerService interface and provides methods for consumer-related operations
 type consumerService struct {
-	repo repository.ConsumerRepository
+	store    database.DataStore
+	repo     repository.ConsumerRepository
+	policy   policyconfig.ConsumerListingPolicy
+	cache    *consumerCache
+	webhooks WebhookService
+	events   eventbus.Publisher
+	rules    policyconfig.ConsumerRules
+	clock    clock.Clock
+}
+
+// ConsumerServiceOption configures the consumer service.
+type ConsumerServiceOption func(*consumerService)
+
+// WithConsumerCache caches the consumers looked up by ID for the given TTL, using the given clock.
+// The cache is disabled if the TTL is not positive.
+func WithConsumerCache(ttl time.Duration, clk clock.Clock) ConsumerServiceOption {
+	return func(s *consumerService) {
+		if ttl > 0 {
+			s.cache = newConsumerCache(ttl, clk)
+		}
+	}
 }
 
-// NewConsumerService creates a new instance of ConsumerService with the given repository.
-// This function initializes the consumerService struct and returns it.
-func NewConsumerService(repo repository.ConsumerRepository) ConsumerService {
-	return &consumerService{repo: repo}
+// WithConsumerWebhooks sends the consumer events, a consumer created or a status changed, with the given webhook service.
+func WithConsumerWebhooks(webhooks WebhookService) ConsumerServiceOption {
+	return func(s *consumerService) {
+		s.webhooks = webhooks
+	}
 }
 
-// GetAllConsumers retrieves all consumers from the database.
-func (s *consumerService) GetAllConsumers(page int, limit int) ([]entity.Consumer, error) {
-	db := database.GetPostgres()
-	if db == nil {
-		return nil, fmt.Errorf("database connection is nil")
+// WithConsumerEvents publishes the consumer events, a consumer created or suspended, with the given publisher.
+func WithConsumerEvents(events eventbus.Publisher) ConsumerServiceOption {
+	return func(s *consumerService) {
+		s.events = events
+	}
+}
+
+// WithConsumerRules checks the consumers against the given rules of the deployment when they are created.
+func WithConsumerRules(rules policyconfig.ConsumerRules) ConsumerServiceOption {
+	return func(s *consumerService) {
+		s.rules = rules
+	}
+}
+
+// WithConsumerClock sets the deletion time of the consumers with the given clock, instead of the system clock.
+func WithConsumerClock(clk clock.Clock) ConsumerServiceOption {
+	return func(s *consumerService) {
+		s.clock = clk
+	}
+}
+
+// NewConsumerService creates a new instance of ConsumerService with the given data store, repository, and consumer listing policy.
+// This function initializes the consumerService struct with the given options and returns it.
+func NewConsumerService(store database.DataStore, repo repository.ConsumerRepository, policy policyconfig.ConsumerListingPolicy, opts ...ConsumerServiceOption) ConsumerService {
+	s := &consumerService{store: store, repo: repo, policy: policy, clock: clock.New()}
+	for _, opt := range opts {
+		opt(s)
 	}
 
-	// Retrieve all consumers from the repository
-	consumers, err := s.repo.GetAllConsumers(db, page, limit)
+	return s
+}
+
+// GetAllConsumers retrieves the consumers visible to a caller with the given roles from the database.
+// The statuses excluded for the roles by the consumer listing policy are left out, and so are the deleted consumers,
+// unless includeDeleted is set by an administrator; it fails with ErrDeletedConsumersForbidden for the other callers.
+func (s *consumerService) GetAllConsumers(ctx context.Context, roles []string, page int, limit int, includeDeleted bool) ([]entity.Consumer, error) {
+	db, err := s.listingDB(ctx, roles, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve the consumers from the repository, leaving out the statuses excluded for the roles
+	var consumers []entity.Consumer
+	if excluded := s.policy.Excluded(roles); len(excluded) > 0 {
+		consumers, err = s.repo.GetConsumersExcludingStatuses(ctx, db, excluded, page, limit)
+	} else {
+		consumers, err = s.repo.GetAllConsumers(ctx, db, page, limit)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -53,15 +153,53 @@ func (s *consumerService) GetAllConsumers(page int, limit int) ([]entity.Consume
 	return consumers, nil
 }
 
-// GetConsumerByID retrieves a consumer by its ID from the database.
-func (s *consumerService) GetConsumerByID(id string) (entity.Consumer, error) {
-	db := database.GetPostgres()
+// StreamConsumers calls fn with each consumer visible to a caller with the given roles, in creation order,
+// reading them one at a time from the database. The statuses excluded for the roles by the consumer listing policy are left out,
+// and so are the deleted consumers, unless includeDeleted is set by an administrator, like GetAllConsumers.
+// The query is canceled when the context is done, e.g. when the client disconnects.
+func (s *consumerService) StreamConsumers(ctx context.Context, roles []string, includeDeleted bool, fn func(entity.Consumer) error) error {
+	db, err := s.listingDB(ctx, roles, includeDeleted)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.StreamConsumers(ctx, db, s.policy.Excluded(roles), fn)
+}
+
+// listingDB returns the connection of the listings of a caller with the given roles, unscoped to include the deleted consumers
+// if includeDeleted is set. It fails with ErrDeletedConsumersForbidden if includeDeleted is set by a caller who is not an administrator.
+func (s *consumerService) listingDB(ctx context.Context, roles []string, includeDeleted bool) (*gorm.DB, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	if includeDeleted {
+		if !slices.Contains(roles, entity.RoleAdmin) {
+			return nil, ErrDeletedConsumersForbidden
+		}
+		db = db.Unscoped()
+	}
+
+	return db, nil
+}
+
+// GetConsumerByID retrieves a consumer by its ID from the cache, if enabled, or from the database.
+func (s *consumerService) GetConsumerByID(ctx context.Context, id string) (entity.Consumer, error) {
+	db := s.store.DB(ctx)
 	if db == nil {
 		return entity.Consumer{}, fmt.Errorf("database connection is nil")
 	}
 
 	// Retrieve the consumer by ID from the repository
-	consumer, err := s.repo.GetConsumerByID(db, id)
+	load := func(ctx context.Context) (entity.Consumer, error) {
+		return s.repo.GetConsumerByID(ctx, db, id)
+	}
+	if s.cache != nil {
+		return s.cache.getOrLoad(ctx, id, load)
+	}
+
+	consumer, err := load(ctx)
 	if err != nil {
 		return entity.Consumer{}, err
 	}
@@ -70,14 +208,15 @@ func (s *consumerService) GetConsumerByID(id string) (entity.Consumer, error) {
 }
 
 // GetActiveConsumers retrieves all active consumers from the database.
-func (s *consumerService) GetActiveConsumers(page int, limit int) ([]entity.Consumer, error) {
-	db := database.GetPostgres()
-	if db == nil {
-		return nil, fmt.Errorf("database connection is nil")
+// The deleted consumers are left out, unless includeDeleted is set by an administrator, like GetAllConsumers.
+func (s *consumerService) GetActiveConsumers(ctx context.Context, roles []string, page int, limit int, includeDeleted bool) ([]entity.Consumer, error) {
+	db, err := s.listingDB(ctx, roles, includeDeleted)
+	if err != nil {
+		return nil, err
 	}
 
 	// Retrieve all active consumers from the repository
-	activeConsumers, err := s.repo.GetConsumersByStatus(db, entity.ConsumerStatusActive, page, limit)
+	activeConsumers, err := s.repo.GetConsumersByStatus(ctx, db, entity.ConsumerStatusActive, page, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -86,14 +225,15 @@ func (s *consumerService) GetActiveConsumers(page int, limit int) ([]entity.Cons
 }
 
 // GetInactiveConsumers retrieves all inactive consumers from the database.
-func (s *consumerService) GetInactiveConsumers(page int, limit int) ([]entity.Consumer, error) {
-	db := database.GetPostgres()
-	if db == nil {
-		return nil, fmt.Errorf("database connection is nil")
+// The deleted consumers are left out, unless includeDeleted is set by an administrator, like GetAllConsumers.
+func (s *consumerService) GetInactiveConsumers(ctx context.Context, roles []string, page int, limit int, includeDeleted bool) ([]entity.Consumer, error) {
+	db, err := s.listingDB(ctx, roles, includeDeleted)
+	if err != nil {
+		return nil, err
 	}
 
 	// Retrieve all inactive consumers from the repository
-	inactiveConsumers, err := s.repo.GetConsumersByStatus(db, "inactive", page, limit)
+	inactiveConsumers, err := s.repo.GetConsumersByStatus(ctx, db, "inactive", page, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -102,14 +242,15 @@ func (s *consumerService) GetInactiveConsumers(page int, limit int) ([]entity.Co
 }
 
 // GetSuspendedConsumers retrieves all suspended consumers from the database.
-func (s *consumerService) GetSuspendedConsumers(page int, limit int) ([]entity.Consumer, error) {
-	db := database.GetPostgres()
-	if db == nil {
-		return nil, fmt.Errorf("database connection is nil")
+// The deleted consumers are left out, unless includeDeleted is set by an administrator, like GetAllConsumers.
+func (s *consumerService) GetSuspendedConsumers(ctx context.Context, roles []string, page int, limit int, includeDeleted bool) ([]entity.Consumer, error) {
+	db, err := s.listingDB(ctx, roles, includeDeleted)
+	if err != nil {
+		return nil, err
 	}
 
 	// Retrieve all suspended consumers from the repository
-	suspendedConsumers, err := s.repo.GetConsumersByStatus(db, "suspended", page, limit)
+	suspendedConsumers, err := s.repo.GetConsumersByStatus(ctx, db, "suspended", page, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -119,56 +260,60 @@ func (s *consumerService) GetSuspendedConsumers(page int, limit int) ([]entity.C
 
 // CreateConsumer creates a new consumer in the database.
 // It validates the consumer struct and checks if the ID already exists before creating a new consumer.
-func (s *consumerService) CreateConsumer(c entity.Consumer) (entity.Consumer, error) {
-	db := database.GetPostgres()
+func (s *consumerService) CreateConsumer(ctx context.Context, c entity.Consumer) (entity.Consumer, error) {
+	db := s.store.DB(ctx)
 	if db == nil {
 		return entity.Consumer{}, fmt.Errorf("database connection is nil")
 	}
 
-	// Validate the consumer struct using the validator
+	// Normalize the phone number before validating it
+	c.Phone = NormalizePhoneNumber(c.Phone)
+
+	// Validate the consumer struct using the validator, then against the rules of the deployment
 	if err := c.Validate(); err != nil {
 		return entity.Consumer{}, err
 	}
+	if err := checkConsumerRules(s.rules, c); err != nil {
+		return entity.Consumer{}, err
+	}
 
 	createdConsumer := entity.Consumer{}
 	err := db.Transaction(func(tx *gorm.DB) error {
 		// Check if the username already exists
-		existingConsumer, err := s.repo.GetConsumerByUsername(db, c.Username)
+		existingConsumer, err := s.repo.GetConsumerByUsername(ctx, db, c.Username)
 		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			return fmt.Errorf("failed to check existing consumer by username: %w", err)
 		}
 
 		// If the consumer already exists, return an error
 		if (err == nil) || !(existingConsumer.Equals(&entity.Consumer{})) {
-			return fmt.Errorf("consumer with username %s already exists", c.Username)
+			return ErrDuplicateUsername
 		}
 
 		// Check if the email already exists
-		existingConsumer, err = s.repo.GetConsumerByEmail(db, c.Email)
+		existingConsumer, err = s.repo.GetConsumerByEmail(ctx, db, c.Email)
 		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			return fmt.Errorf("failed to check existing consumer by email: %w", err)
 		}
 
 		// If the consumer already exists, return an error
 		if (err == nil) || !(existingConsumer.Equals(&entity.Consumer{})) {
-			return fmt.Errorf("consumer with email %s already exists", c.Email)
+			return ErrDuplicateEmail
 		}
 
 		// Check if the phone already exists
-		normalizedPhone := NormalizePhoneNumber(c.Phone)
-		c.Phone = normalizedPhone
-		existingConsumer, err = s.repo.GetConsumerByPhone(db, normalizedPhone)
+		existingConsumer, err = s.repo.GetConsumerByPhone(ctx, db, c.Phone)
 		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			return fmt.Errorf("failed to check existing consumer by phone: %w", err)
 		}
 
 		// If the consumer already exists, return an error
 		if (err == nil) || !(existingConsumer.Equals(&entity.Consumer{})) {
-			return fmt.Errorf("consumer with phone %s already exists", c.Phone)
+			return ErrDuplicatePhone
 		}
 
 		c.Status = "inactive" // Set default status to inactive
-		createdConsumer, err = s.repo.CreateConsumer(tx, c)
+		createdConsumer, err = s.repo.CreateConsumer(ctx, tx, c)
 		if err != nil {
 			return err
 		}
@@ -180,9 +325,58 @@ func (s *consumerService) CreateConsumer(c entity.Consumer) (entity.Consumer, er
 		return entity.Consumer{}, err
 	}
 
+	metrics.ConsumerCreated()
+	s.sendEvent(ctx, entity.WebhookEventConsumerCreated, createdConsumer)
+	s.publishEvent(eventbus.TopicConsumerCreated, createdConsumer)
+
 	return createdConsumer, nil
 }
 
+// CheckConsumerAvailability reports whether the given username, email, and phone number are not used by any consumer yet,
+// with the same rules as the creation of a consumer. The phone number is normalized before it is checked.
+// The answer is only a hint for the onboarding forms, the creation still checks them.
+func (s *consumerService) CheckConsumerAvailability(ctx context.Context, req entity.ConsumerAvailabilityRequest) (entity.ConsumerAvailability, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.ConsumerAvailability{}, fmt.Errorf("database connection is nil")
+	}
+
+	// Normalize the phone number before validating it
+	if req.Phone != "" {
+		req.Phone = NormalizePhoneNumber(req.Phone)
+	}
+
+	// Validate the request struct using the validator
+	if err := req.Validate(); err != nil {
+		return entity.ConsumerAvailability{}, err
+	}
+
+	var availability entity.ConsumerAvailability
+	checks := []struct {
+		value  string
+		lookup func(ctx context.Context, tx *gorm.DB, value string) (entity.Consumer, error)
+		result **entity.FieldAvailability
+		field  string
+	}{
+		{req.Username, s.repo.GetConsumerByUsername, &availability.Username, "username"},
+		{req.Email, s.repo.GetConsumerByEmail, &availability.Email, "email"},
+		{req.Phone, s.repo.GetConsumerByPhone, &availability.Phone, "phone"},
+	}
+	for _, check := range checks {
+		if check.value == "" {
+			continue
+		}
+
+		_, err := check.lookup(ctx, db, check.value)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.ConsumerAvailability{}, fmt.Errorf("failed to check existing consumer by %s: %w", check.field, err)
+		}
+		*check.result = &entity.FieldAvailability{Value: check.value, Available: err != nil}
+	}
+
+	return availability, nil
+}
+
 // NormalizePhoneNumber removes non-digit characters and ensures country code (e.g., starts with 62)
 func NormalizePhoneNumber(phone string) string {
 	// Remove all non-digit characters
@@ -197,24 +391,127 @@ func NormalizePhoneNumber(phone string) string {
 	return digitsOnly
 }
 
+// UpdateConsumer updates the fields of an existing consumer given by the request, and returns the updated consumer.
+// The updated consumer is validated like a new one, and the rules of the deployment are checked on the changed fields only,
+// so that the consumers created before a rule keep being updatable. A changed email or phone must not be used by another consumer.
+func (s *consumerService) UpdateConsumer(ctx context.Context, id string, req entity.ConsumerUpdateRequest) (entity.Consumer, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.Consumer{}, fmt.Errorf("database connection is nil")
+	}
+
+	if req.IsEmpty() {
+		return entity.Consumer{}, ErrEmptyConsumerUpdate
+	}
+
+	// Normalize the phone number before validating it
+	if req.Phone != nil {
+		phone := NormalizePhoneNumber(*req.Phone)
+		req.Phone = &phone
+	}
+
+	updatedConsumer := entity.Consumer{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Check if the consumer exists
+		existingConsumer, err := s.repo.GetConsumerByID(ctx, db, id)
+		if err != nil {
+			return err
+		}
+
+		// Validate the updated consumer struct using the validator, then the changed fields against the rules of the deployment
+		consumer := req.ApplyTo(existingConsumer)
+		if err := consumer.Validate(); err != nil {
+			return err
+		}
+		if err := s.checkChangedFields(consumer, req); err != nil {
+			return err
+		}
+
+		// Check if the changed email or phone is used by another consumer
+		if !strings.EqualFold(consumer.Email, existingConsumer.Email) {
+			other, err := s.repo.GetConsumerByEmail(ctx, db, consumer.Email)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("failed to check existing consumer by email: %w", err)
+			}
+			if err == nil && other.ID != id {
+				return ErrDuplicateEmail
+			}
+		}
+		if consumer.Phone != existingConsumer.Phone {
+			other, err := s.repo.GetConsumerByPhone(ctx, db, consumer.Phone)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("failed to check existing consumer by phone: %w", err)
+			}
+			if err == nil && other.ID != id {
+				return ErrDuplicatePhone
+			}
+		}
+
+		updatedConsumer, err = s.repo.UpdateConsumer(ctx, tx, consumer)
+		return err
+	})
+
+	// Invalidate even on failure, since the update may have been applied before the commit failed
+	if s.cache != nil {
+		s.cache.invalidate(id)
+	}
+
+	if err != nil {
+		return entity.Consumer{}, err
+	}
+
+	return updatedConsumer, nil
+}
+
+// checkChangedFields checks the consumer against the rules of the deployment, reporting only the broken rules of the fields
+// changed by the request.
+func (s *consumerService) checkChangedFields(c entity.Consumer, req entity.ConsumerUpdateRequest) error {
+	var re *ConsumerRuleError
+	if err := checkConsumerRules(s.rules, c); !errors.As(err, &re) {
+		return err
+	}
+
+	changed := map[string]bool{
+		"fullname":  req.Fullname != nil,
+		"email":     req.Email != nil,
+		"phone":     req.Phone != nil,
+		"address":   req.Address != nil,
+		"birthDate": req.BirthDate != nil,
+	}
+
+	var violations []ConsumerRuleViolation
+	for _, v := range re.Violations {
+		if changed[v.Field] {
+			violations = append(violations, v)
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return &ConsumerRuleError{Violations: violations}
+}
+
 // UpdateConsumerStatus updates the status of an existing consumer in the database.
 // It checks if the consumer exists and validates the status before updating it.
-func (s *consumerService) UpdateConsumerStatus(id string, status string) (entity.Consumer, error) {
-	db := database.GetPostgres()
+func (s *consumerService) UpdateConsumerStatus(ctx context.Context, id string, status string) (entity.Consumer, error) {
+	db := s.store.DB(ctx)
 	if db == nil {
 		return entity.Consumer{}, fmt.Errorf("database connection is nil")
 	}
 
 	updatedConsumer := entity.Consumer{}
+	var previousStatus string
 	err := db.Transaction(func(tx *gorm.DB) error {
 		// Check if the consumer exists
-		existingConsumer, err := s.repo.GetConsumerByID(db, id)
+		existingConsumer, err := s.repo.GetConsumerByID(ctx, db, id)
 		if err != nil {
 			return err
 		}
 
+		previousStatus = existingConsumer.Status
 		existingConsumer.Status = status
-		updatedConsumer, err = s.repo.UpdateConsumer(tx, existingConsumer)
+		updatedConsumer, err = s.repo.UpdateConsumer(ctx, tx, existingConsumer)
 		if err != nil {
 			return err
 		}
@@ -222,9 +519,110 @@ func (s *consumerService) UpdateConsumerStatus(id string, status string) (entity
 		return nil
 	})
 
+	// Invalidate even on failure, since the update may have been applied before the commit failed
+	if s.cache != nil {
+		s.cache.invalidate(id)
+	}
+
 	if err != nil {
 		return entity.Consumer{}, err
 	}
 
+	metrics.ConsumerStatusChanged(previousStatus, updatedConsumer.Status)
+	if previousStatus != updatedConsumer.Status {
+		s.sendEvent(ctx, entity.WebhookEventConsumerStatusChanged, entity.ConsumerStatusChange{Consumer: updatedConsumer, PreviousStatus: previousStatus})
+		if updatedConsumer.Status == entity.ConsumerStatusSuspended {
+			s.publishEvent(eventbus.TopicConsumerSuspended, entity.ConsumerStatusChange{Consumer: updatedConsumer, PreviousStatus: previousStatus})
+		}
+	}
+
 	return updatedConsumer, nil
 }
+
+// DeleteConsumer soft-deletes the consumer: it is left out of the lookups and the listings until it is restored,
+// and keeps its username, email, and phone number. A consumer already deleted is not found.
+func (s *consumerService) DeleteConsumer(ctx context.Context, id string) error {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	err := s.repo.SoftDeleteConsumer(ctx, db, id, s.clock.Now())
+
+	// Invalidate even on failure, since the deletion may have been applied before the error
+	if s.cache != nil {
+		s.cache.invalidate(id)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	logger.InfoContext(ctx, "Deleted consumer", logrus.Fields{"consumer_id": id})
+
+	return nil
+}
+
+// RestoreConsumer restores the soft-deleted consumer, and returns it.
+// It fails with ErrConsumerNotDeleted if the consumer is not deleted.
+func (s *consumerService) RestoreConsumer(ctx context.Context, id string) (entity.Consumer, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.Consumer{}, fmt.Errorf("database connection is nil")
+	}
+
+	restoredConsumer := entity.Consumer{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Check if the consumer exists, deleted or not
+		existingConsumer, err := s.repo.GetConsumerByID(ctx, tx.Unscoped(), id)
+		if err != nil {
+			return err
+		}
+		if !existingConsumer.IsDeleted() {
+			return ErrConsumerNotDeleted
+		}
+
+		if err := s.repo.RestoreConsumer(ctx, tx, id); err != nil {
+			return err
+		}
+
+		restoredConsumer, err = s.repo.GetConsumerByID(ctx, tx, id)
+		return err
+	})
+
+	// Invalidate even on failure, since the restore may have been applied before the commit failed
+	if s.cache != nil {
+		s.cache.invalidate(id)
+	}
+
+	if err != nil {
+		return entity.Consumer{}, err
+	}
+
+	logger.InfoContext(ctx, "Restored consumer", logrus.Fields{"consumer_id": id})
+
+	return restoredConsumer, nil
+}
+
+// sendEvent queues the consumer event for the webhook, if any, in the transaction of the request if the context carries one.
+// The change is already written, so a failure to queue the event is logged and does not fail the request.
+func (s *consumerService) sendEvent(ctx context.Context, event string, data interface{}) {
+	if s.webhooks == nil {
+		return
+	}
+
+	if err := s.webhooks.Enqueue(ctx, event, data); err != nil {
+		logger.Error(fmt.Sprintf("Failed to queue the webhook event: %v", err), logrus.Fields{
+			"event": event,
+		})
+	}
+}
+
+// publishEvent publishes the consumer event to the event bus, if any.
+func (s *consumerService) publishEvent(topic string, data interface{}) {
+	if s.events == nil {
+		return
+	}
+
+	s.events.Publish(topic, data)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/featureflag"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+//go:generate go tool mockgen -source=feature-flag.go -destination=../../tests/mocks/feature-flag-service.go -package=mocks
+
+// ErrUnknownFeature is returned when the feature of a flag is not one of featureflag.Features.
+var ErrUnknownFeature = errors.New("unknown feature")
+
+// Interface for feature flag service
+// This interface defines the methods that the feature flag service should implement
+type FeatureFlagService interface {
+	GetFeatureFlags(ctx context.Context) ([]entity.FeatureFlag, error)
+	UpdateFeatureFlag(ctx context.Context, userID int64, name string, req entity.FeatureFlagRequest) (entity.FeatureFlag, error)
+	LoadFlags() error
+	Flags() *featureflag.Cache
+}
+
+// This struct defines the FeatureFlagService that contains a repository field of type FeatureFlagRepository,
+// the cache of the disabled features checked by the feature flag middleware, and a clock used to get the current time
+// It implements the FeatureFlagService interface and provides methods for feature flag-related operations
+type featureFlagService struct {
+	store database.DataStore
+	repo  repository.FeatureFlagRepository
+	cache *featureflag.Cache
+	clock clock.Clock
+}
+
+// NewFeatureFlagService creates a new instance of FeatureFlagService with the given data store and repository.
+// The features of the environment are disabled for the lifetime of the process, and the ones disabled at runtime
+// are cached for the given TTL, see featureflag.NewCache.
+func NewFeatureFlagService(store database.DataStore, repo repository.FeatureFlagRepository, environment []string, ttl time.Duration, clk clock.Clock) FeatureFlagService {
+	s := &featureFlagService{store: store, repo: repo, clock: clk}
+	s.cache = featureflag.NewCache(environment, s.loadDisabled, ttl, clk)
+
+	return s
+}
+
+// GetFeatureFlags retrieves the flags of all the features, ordered by name.
+// The features never switched at runtime are enabled, unless they are disabled by the environment.
+func (s *featureFlagService) GetFeatureFlags(ctx context.Context) ([]entity.FeatureFlag, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	saved, err := s.repo.GetFeatureFlags(db)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]entity.FeatureFlag, len(saved))
+	for _, flag := range saved {
+		byName[flag.Name] = flag
+	}
+
+	flags := make([]entity.FeatureFlag, 0, len(featureflag.Features))
+	for _, name := range featureflag.Names() {
+		flag, ok := byName[name]
+		if !ok {
+			flag = entity.FeatureFlag{Name: name}
+		}
+		flags = append(flags, s.describe(flag))
+	}
+
+	return flags, nil
+}
+
+// UpdateFeatureFlag disables or enables the feature at runtime, and reloads the flags of the instance.
+// It fails with ErrUnknownFeature if the feature cannot be disabled. A feature disabled by the environment
+// stays disabled whatever its flag. The other instances apply the new flag once their cached ones are older than the TTL.
+func (s *featureFlagService) UpdateFeatureFlag(ctx context.Context, userID int64, name string, req entity.FeatureFlagRequest) (entity.FeatureFlag, error) {
+	if !featureflag.IsKnown(name) {
+		return entity.FeatureFlag{}, fmt.Errorf("%w: %s", ErrUnknownFeature, name)
+	}
+
+	// Validate the request using the validator
+	if err := req.Validate(); err != nil {
+		return entity.FeatureFlag{}, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.FeatureFlag{}, fmt.Errorf("database connection is nil")
+	}
+
+	now := s.clock.Now()
+	flag := entity.FeatureFlag{Name: name, Disabled: *req.Disabled, UpdatedBy: &userID, UpdatedAt: &now}
+	if flag.Disabled && req.Reason != nil && *req.Reason != "" {
+		flag.Reason = req.Reason
+	}
+
+	savedFlag, err := s.repo.SaveFeatureFlag(db, flag)
+	if err != nil {
+		return entity.FeatureFlag{}, err
+	}
+
+	s.cache.Invalidate()
+
+	logger.Info("Updated feature flag", logrus.Fields{
+		"user_id":  userID,
+		"feature":  name,
+		"disabled": savedFlag.Disabled,
+	})
+
+	return s.describe(savedFlag), nil
+}
+
+// LoadFlags loads the features disabled at runtime into the cache. It is run on Startup,
+// the middleware only applies the features disabled by the environment before.
+func (s *featureFlagService) LoadFlags() error {
+	return s.cache.Load()
+}
+
+// Flags returns the cache of the disabled features, checked by the feature flag middleware.
+func (s *featureFlagService) Flags() *featureflag.Cache {
+	return s.cache
+}
+
+// loadDisabled loads the features disabled at runtime from the database, with their reasons, for the cache.
+func (s *featureFlagService) loadDisabled() (map[string]string, error) {
+	db := s.store.DB(context.Background())
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	flags, err := s.repo.GetFeatureFlags(db)
+	if err != nil {
+		return nil, err
+	}
+
+	disabled := make(map[string]string)
+	for _, flag := range flags {
+		if flag.Disabled {
+			disabled[flag.Name] = ""
+			if flag.Reason != nil {
+				disabled[flag.Name] = *flag.Reason
+			}
+		}
+	}
+
+	return disabled, nil
+}
+
+// describe adds the description of the feature to its flag, and whether the environment disables it.
+func (s *featureFlagService) describe(flag entity.FeatureFlag) entity.FeatureFlag {
+	flag.Description = featureflag.Features[flag.Name]
+	flag.DisabledByEnvironment = s.cache.DisabledByEnvironment(flag.Name)
+	return flag
+}
@@ -0,0 +1,22 @@
+package service
+
+import "time"
+
+// runFlushLoop calls flush every interval until stop is closed, then calls it one last time
+// so that nothing pending is lost, and closes done. It is the worker of the asynchronous recorders.
+func runFlushLoop(interval time.Duration, stop <-chan struct{}, done chan<- struct{}, flush func()) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			flush()
+		case <-stop:
+			flush()
+			return
+		}
+	}
+}
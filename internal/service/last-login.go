@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+//go:generate go tool mockgen -source=last-login.go -destination=../../tests/mocks/last-login-recorder.go -package=mocks
+
+/**
+ * The last login time of a user is bookkeeping: a login must not wait for it, nor fail because of it.
+ * The asynchronous recorder collects the last login times in memory and writes them in batches,
+ * one targeted update per user, from a background worker. Several logins of the same user between
+ * two flushes result in a single write with the latest time. Pending times are flushed on Close,
+ * so they are not lost on a graceful shutdown. With a write buffer, the updates failing while the database
+ * fails over are retried instead of being dropped.
+ */
+
+const (
+	// DefaultLastLoginFlushInterval is how often the pending last login times are written.
+	DefaultLastLoginFlushInterval = time.Second
+
+	// DefaultLastLoginMaxPending is the maximum number of users whose last login time is pending.
+	DefaultLastLoginMaxPending = 10000
+)
+
+// Interface for last login recorder
+// This interface defines the methods used to record the last login time of the users
+type LastLoginRecorder interface {
+	Record(userID int64, lastLogin time.Time)
+	Close()
+}
+
+// This struct defines the LastLoginRecorder that writes the last login times in the background
+// It implements the LastLoginRecorder interface
+type asyncLastLoginRecorder struct {
+	userService   UserService
+	buffer        *WriteBuffer
+	flushInterval time.Duration
+	maxPending    int
+	mu            sync.Mutex
+	pending       map[int64]time.Time
+	stop          chan struct{}
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+// NewAsyncLastLoginRecorder creates a new instance of LastLoginRecorder that writes the last login times
+// with the given user service every flush interval, and starts its background worker.
+// The updates failing with a transient database error are retried by the write buffer, if not nil.
+// Non-positive values fall back to the defaults.
+func NewAsyncLastLoginRecorder(userSvc UserService, buffer *WriteBuffer, flushInterval time.Duration, maxPending int) LastLoginRecorder {
+	if flushInterval <= 0 {
+		flushInterval = DefaultLastLoginFlushInterval
+	}
+	if maxPending <= 0 {
+		maxPending = DefaultLastLoginMaxPending
+	}
+
+	r := &asyncLastLoginRecorder{
+		userService:   userSvc,
+		buffer:        buffer,
+		flushInterval: flushInterval,
+		maxPending:    maxPending,
+		pending:       make(map[int64]time.Time),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go runFlushLoop(r.flushInterval, r.stop, r.done, r.flush)
+
+	return r
+}
+
+// Record queues the last login time of the user without waiting for it to be written.
+// If the user already has a pending time, the latest one is kept.
+// When too many users are pending, the time is dropped and a warning is logged.
+func (r *asyncLastLoginRecorder) Record(userID int64, lastLogin time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current, ok := r.pending[userID]; ok {
+		if lastLogin.After(current) {
+			r.pending[userID] = lastLogin
+		}
+		return
+	}
+	if len(r.pending) >= r.maxPending {
+		logger.Warn("Dropped the last login time, too many pending updates", logrus.Fields{
+			"user_id":     userID,
+			"max_pending": r.maxPending,
+		})
+		return
+	}
+
+	r.pending[userID] = lastLogin
+}
+
+// Close stops the background worker after writing the pending last login times.
+// It is safe to call Close more than once.
+func (r *asyncLastLoginRecorder) Close() {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+}
+
+// flush writes the pending last login times, one update per user.
+// Failed updates are logged and not retried, a later login records a newer time anyway,
+// unless they failed with a transient database error and are buffered.
+func (r *asyncLastLoginRecorder) flush() {
+	r.mu.Lock()
+	batch := r.pending
+	r.pending = make(map[int64]time.Time, len(batch))
+	r.mu.Unlock()
+
+	for userID, lastLogin := range batch {
+		err := r.buffer.Write("last_login", logrus.Fields{"user_id": userID}, func() error {
+			_, err := r.userService.UpdateLastLogin(context.Background(), userID, lastLogin)
+			return err
+		})
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to update the last login time: %v", err), logrus.Fields{
+				"user_id": userID,
+			})
+		}
+	}
+}
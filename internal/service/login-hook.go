@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+//go:generate go tool mockgen -source=login-hook.go -destination=../../tests/mocks/login-hook.go -package=mocks
+
+/**
+ * The login hooks extend the login flow without changing the auth service, e.g. to enforce business checks
+ * of a deployment, add claims to the access tokens, or sync the logins with an external CRM.
+ * BeforeLogin is invoked before the credentials are verified, and AfterLogin once the user is authenticated,
+ * after the two-factor authentication if any, and before the tokens are issued.
+ * The hooks run in the order of their registration, and the first error rejects the login with ErrLoginRejected.
+ * The claims added by AfterLogin are carried by the access token of the login only, not by the refreshed ones.
+ */
+
+// ErrLoginRejected is returned when a login hook rejects a login.
+var ErrLoginRejected = errors.New("login rejected")
+
+// Interface for login hook
+// This interface defines the methods invoked by the auth service before and after the authentication of a user
+type LoginHook interface {
+	BeforeLogin(loginReq entity.LoginRequest) error
+	AfterLogin(user entity.User, loginReq entity.LoginRequest) (map[string]interface{}, error)
+}
+
+// LoginHookFuncs is an adapter allowing functions to be used as a LoginHook, either of them may be nil.
+// After returns the additional claims of the access token of the user, which never replace the claims set by the service.
+type LoginHookFuncs struct {
+	Before func(loginReq entity.LoginRequest) error
+	After  func(user entity.User, loginReq entity.LoginRequest) (map[string]interface{}, error)
+}
+
+// BeforeLogin calls the Before function, if any.
+func (h LoginHookFuncs) BeforeLogin(loginReq entity.LoginRequest) error {
+	if h.Before == nil {
+		return nil
+	}
+
+	return h.Before(loginReq)
+}
+
+// AfterLogin calls the After function, if any.
+func (h LoginHookFuncs) AfterLogin(user entity.User, loginReq entity.LoginRequest) (map[string]interface{}, error) {
+	if h.After == nil {
+		return nil, nil
+	}
+
+	return h.After(user, loginReq)
+}
+
+// WithLoginHooks invokes the given hooks on every login, see LoginHook.
+func WithLoginHooks(hooks ...LoginHook) AuthServiceOption {
+	return func(s *authService) {
+		s.loginHooks = append(s.loginHooks, hooks...)
+	}
+}
+
+// beforeLogin invokes the BeforeLogin method of the login hooks, and stops at the first error.
+func (s *authService) beforeLogin(loginReq entity.LoginRequest) error {
+	for _, hook := range s.loginHooks {
+		if err := hook.BeforeLogin(loginReq); err != nil {
+			return fmt.Errorf("%w: %w", ErrLoginRejected, err)
+		}
+	}
+
+	return nil
+}
+
+// afterLogin invokes the AfterLogin method of the login hooks, and returns the user with the claims they added.
+// The claims of a hook replace the ones of the same name added by the hooks before it.
+func (s *authService) afterLogin(user entity.User, loginReq entity.LoginRequest) (entity.User, error) {
+	for _, hook := range s.loginHooks {
+		claims, err := hook.AfterLogin(user, loginReq)
+		if err != nil {
+			return entity.User{}, fmt.Errorf("%w: %w", ErrLoginRejected, err)
+		}
+
+		for name, value := range claims {
+			if user.Claims == nil {
+				user.Claims = make(map[string]interface{}, len(claims))
+			}
+			user.Claims[name] = value
+		}
+	}
+
+	return user, nil
+}
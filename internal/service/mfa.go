@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/totp"
+)
+
+//go:generate go tool mockgen -source=mfa.go -destination=../../tests/mocks/mfa-service.go -package=mocks
+
+/**
+ * The users protect their account with two-factor authentication (TOTP) by enrolling an authenticator app:
+ * the enrollment returns a secret and its provisioning URI, and is enabled once the user confirms it with a code of the app.
+ * The login of a user with two-factor authentication then takes two steps: the password is checked first,
+ * and a random MFA token is returned instead of the tokens. The client completes the login with the MFA token
+ * and a code of the app. The MFA tokens expire after MFA_TOKEN_TTL_MINUTES (default 5), only their SHA-256 hash is stored,
+ * and they are used up by the first valid code or after MaxMFAAttempts invalid ones. Each code is accepted once.
+ * Disabling two-factor authentication also takes a code, and the user is notified of it.
+ */
+
+const (
+	// mfaTokenBytes is the number of random bytes of the MFA tokens.
+	mfaTokenBytes = 32
+
+	// MaxMFAAttempts is the number of invalid codes after which an MFA token is used up, and the user must log in again.
+	MaxMFAAttempts = 5
+)
+
+var (
+	// ErrMFAAlreadyEnabled is returned when a user enrolls or confirms two-factor authentication while it is enabled.
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+	// ErrMFANotEnrolled is returned when a user confirms two-factor authentication without enrolling first.
+	ErrMFANotEnrolled = errors.New("two-factor authentication is not enrolled")
+
+	// ErrMFANotEnabled is returned when a user disables two-factor authentication while it is not enabled.
+	ErrMFANotEnabled = errors.New("two-factor authentication is not enabled")
+
+	// ErrInvalidMFACode is returned when a code of the authenticator app is wrong, expired, or already used.
+	ErrInvalidMFACode = errors.New("invalid two-factor authentication code")
+
+	// ErrInvalidMFAToken is returned when an MFA token is unknown, expired, or used up.
+	ErrInvalidMFAToken = errors.New("invalid or expired MFA token")
+)
+
+// MFAChallenge is the second step of a login, started once the password of a user with two-factor authentication is checked.
+type MFAChallenge struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// Interface for MFA service
+// This interface defines the methods that the MFA service should implement
+type MFAService interface {
+	GetStatus(ctx context.Context, userID int64) (entity.MFAStatusResponse, error)
+	Enroll(ctx context.Context, userID int64) (entity.MFAEnrollResponse, error)
+	Activate(ctx context.Context, userID int64, req entity.MFACodeRequest) (entity.MFAStatusResponse, error)
+	Disable(ctx context.Context, userID int64, req entity.MFACodeRequest) error
+	IsEnabled(ctx context.Context, userID int64) (bool, error)
+	StartChallenge(ctx context.Context, userID int64) (MFAChallenge, error)
+	VerifyChallenge(ctx context.Context, token string, code string) (int64, error)
+}
+
+// This struct defines the MFAService that contains the MFA and user repositories, the notifier of the security events,
+// the two-factor authentication policy, and a clock used to get the current time
+// It implements the MFAService interface and provides methods for two-factor authentication-related operations
+type mfaService struct {
+	store    database.DataStore
+	repo     repository.MFARepository
+	userRepo repository.UserRepository
+	notifier SecurityNotifier
+	policy   policyconfig.MFAPolicy
+	clock    clock.Clock
+}
+
+// NewMFAService creates a new instance of MFAService with the given dependencies.
+// It initializes the mfaService struct and returns it.
+func NewMFAService(store database.DataStore, repo repository.MFARepository, userRepo repository.UserRepository, notifier SecurityNotifier, policy policyconfig.MFAPolicy, clk clock.Clock) MFAService {
+	if policy.TokenTTL <= 0 {
+		policy.TokenTTL = policyconfig.DefaultMFATokenTTL
+	}
+	if policy.Issuer == "" {
+		policy.Issuer = policyconfig.DefaultMFAIssuer
+	}
+
+	return &mfaService{
+		store:    store,
+		repo:     repo,
+		userRepo: userRepo,
+		notifier: notifier,
+		policy:   policy,
+		clock:    clk,
+	}
+}
+
+// GetStatus returns whether two-factor authentication is enabled for the user.
+func (s *mfaService) GetStatus(ctx context.Context, userID int64) (entity.MFAStatusResponse, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.MFAStatusResponse{}, fmt.Errorf("database connection is nil")
+	}
+
+	mfa, err := s.repo.GetUserMFA(db, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.MFAStatusResponse{}, nil
+	}
+	if err != nil {
+		return entity.MFAStatusResponse{}, fmt.Errorf("failed to retrieve the MFA settings of the user: %w", err)
+	}
+
+	return entity.MFAStatusResponse{Enabled: mfa.IsEnabled(), EnabledAt: mfa.EnabledAt}, nil
+}
+
+// Enroll generates a new secret for the authenticator app of the user, replacing a pending enrollment.
+// Two-factor authentication is enabled once the enrollment is confirmed with Activate.
+func (s *mfaService) Enroll(ctx context.Context, userID int64) (entity.MFAEnrollResponse, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.MFAEnrollResponse{}, fmt.Errorf("database connection is nil")
+	}
+
+	user, err := s.userRepo.GetUserByID(context.Background(), db, userID)
+	if err != nil {
+		return entity.MFAEnrollResponse{}, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return entity.MFAEnrollResponse{}, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		existing, err := s.repo.GetUserMFA(tx, userID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to retrieve the MFA settings of the user: %w", err)
+		}
+		if err == nil && existing.IsEnabled() {
+			return ErrMFAAlreadyEnabled
+		}
+
+		_, err = s.repo.SaveUserMFA(tx, entity.UserMFA{UserID: userID, Secret: secret, CreatedAt: s.clock.Now()})
+		return err
+	})
+	if err != nil {
+		return entity.MFAEnrollResponse{}, err
+	}
+
+	return entity.MFAEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.policy.Issuer, user.Username, secret),
+	}, nil
+}
+
+// Activate enables two-factor authentication for the user once the code of the enrolled authenticator app is checked.
+func (s *mfaService) Activate(ctx context.Context, userID int64, req entity.MFACodeRequest) (entity.MFAStatusResponse, error) {
+	// Validate the request using the validator
+	if err := req.Validate(); err != nil {
+		return entity.MFAStatusResponse{}, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.MFAStatusResponse{}, fmt.Errorf("database connection is nil")
+	}
+
+	now := s.clock.Now()
+	var mfa entity.UserMFA
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		mfa, err = s.repo.GetUserMFA(tx, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMFANotEnrolled
+		}
+		if err != nil {
+			return fmt.Errorf("failed to retrieve the MFA settings of the user: %w", err)
+		}
+		if mfa.IsEnabled() {
+			return ErrMFAAlreadyEnabled
+		}
+
+		step, ok := totp.Validate(mfa.Secret, req.Code, now)
+		if !ok {
+			return ErrInvalidMFACode
+		}
+
+		mfa.EnabledAt = &now
+		mfa.LastUsedStep = step
+		mfa, err = s.repo.SaveUserMFA(tx, mfa)
+		return err
+	})
+	if err != nil {
+		return entity.MFAStatusResponse{}, err
+	}
+
+	logger.Info("Enabled two-factor authentication for the user", logrus.Fields{"user_id": userID})
+
+	return entity.MFAStatusResponse{Enabled: true, EnabledAt: mfa.EnabledAt}, nil
+}
+
+// Disable disables two-factor authentication for the user once a code of the authenticator app is checked,
+// and ends the logins waiting for a code. The user is notified of the change.
+func (s *mfaService) Disable(ctx context.Context, userID int64, req entity.MFACodeRequest) error {
+	// Validate the request using the validator
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	user, err := s.userRepo.GetUserByID(context.Background(), db, userID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve user: %w", err)
+	}
+
+	now := s.clock.Now()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		mfa, err := s.repo.GetUserMFA(tx, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !mfa.IsEnabled()) {
+			return ErrMFANotEnabled
+		}
+		if err != nil {
+			return fmt.Errorf("failed to retrieve the MFA settings of the user: %w", err)
+		}
+
+		if err := s.useCode(tx, mfa, req.Code, now); err != nil {
+			return err
+		}
+
+		if _, err := s.repo.RemoveUserMFA(tx, userID); err != nil {
+			return err
+		}
+		_, err = s.repo.RemoveMFAChallenges(tx, userID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Disabled two-factor authentication for the user", logrus.Fields{"user_id": userID})
+
+	s.notifier.Notify(entity.SecurityEvent{
+		Type:      entity.SecurityEventMFADisabled,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		IPAddress: req.IPAddress,
+		At:        now,
+	})
+
+	return nil
+}
+
+// IsEnabled reports whether two-factor authentication is enabled for the user.
+func (s *mfaService) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	status, err := s.GetStatus(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return status.Enabled, nil
+}
+
+// StartChallenge starts the second step of the login of the user, and returns the MFA token completing it.
+func (s *mfaService) StartChallenge(ctx context.Context, userID int64) (MFAChallenge, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return MFAChallenge{}, fmt.Errorf("database connection is nil")
+	}
+
+	token, err := generateMFAToken()
+	if err != nil {
+		return MFAChallenge{}, err
+	}
+
+	now := s.clock.Now()
+	challenge, err := s.repo.CreateMFAChallenge(db, entity.MFAChallenge{
+		UserID:    userID,
+		TokenHash: HashMFAToken(token),
+		ExpiresAt: now.Add(s.policy.TokenTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return MFAChallenge{}, err
+	}
+
+	return MFAChallenge{Token: token, ExpiresAt: challenge.ExpiresAt}, nil
+}
+
+// VerifyChallenge checks the code of the authenticator app for the login of the given MFA token,
+// and returns the ID of the user logging in. The token is used up by a valid code, or after MaxMFAAttempts invalid ones.
+func (s *mfaService) VerifyChallenge(ctx context.Context, token string, code string) (int64, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return 0, fmt.Errorf("database connection is nil")
+	}
+
+	now := s.clock.Now()
+	var userID int64
+	var codeErr error
+	err := db.Transaction(func(tx *gorm.DB) error {
+		challenge, err := s.repo.GetMFAChallengeByHash(tx, HashMFAToken(token))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidMFAToken
+		}
+		if err != nil {
+			return fmt.Errorf("failed to retrieve MFA challenge: %w", err)
+		}
+		if !challenge.IsUsable(now) {
+			return ErrInvalidMFAToken
+		}
+
+		mfa, err := s.repo.GetUserMFA(tx, challenge.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !mfa.IsEnabled()) {
+			return ErrInvalidMFAToken
+		}
+		if err != nil {
+			return fmt.Errorf("failed to retrieve the MFA settings of the user: %w", err)
+		}
+
+		// The invalid attempts are recorded, so the transaction is committed with the error of the code
+		codeErr = s.useCode(tx, mfa, code, now)
+		if codeErr == nil {
+			challenge.UsedAt = &now
+		} else if errors.Is(codeErr, ErrInvalidMFACode) {
+			challenge.Attempts++
+			if challenge.Attempts >= MaxMFAAttempts {
+				challenge.UsedAt = &now
+			}
+		} else {
+			return codeErr
+		}
+
+		userID = challenge.UserID
+		return s.repo.UpdateMFAChallenge(tx, challenge)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if codeErr != nil {
+		return 0, codeErr
+	}
+
+	return userID, nil
+}
+
+// useCode checks the code against the secret of the user, and records its time step so that it cannot be used again.
+func (s *mfaService) useCode(tx *gorm.DB, mfa entity.UserMFA, code string, now time.Time) error {
+	step, ok := totp.Validate(mfa.Secret, code, now)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	// A code used concurrently, or already used, is only accepted once
+	marked, err := s.repo.MarkMFAStepUsed(tx, mfa.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// generateMFAToken returns a new random MFA token, encoded for URLs.
+func generateMFAToken() (string, error) {
+	b := make([]byte, mfaTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate MFA token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashMFAToken returns the SHA-256 hash of an MFA token, as stored in the database.
+func HashMFAToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/geoip"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/mailer"
+)
+
+//go:generate go tool mockgen -source=notification.go -destination=../../tests/mocks/notification-service.go -package=mocks
+
+/**
+ * The notification subsystem informs the users by email about the security-relevant events of their account:
+ * a login from a new device, a password change, and the deactivation of MFA. Each user can turn each
+ * notification off in their preferences, all of them are on by default. A device is identified by a
+ * fingerprint of its user agent and client; the first device of a user is recorded without notification.
+ * The events are handled by a background worker, so the login does not wait for the database or the mailer.
+ * The emails are rendered from the mail templates, in the default locale of the templates (MAIL_LOCALE).
+ */
+
+// DefaultNotificationQueueSize is the maximum number of security events waiting to be handled.
+const DefaultNotificationQueueSize = 1000
+
+// Interface for notification service
+// This interface defines the methods that the notification service should implement
+type NotificationService interface {
+	GetNotificationPreference(ctx context.Context, userID int64) (entity.NotificationPreference, error)
+	UpdateNotificationPreference(ctx context.Context, userID int64, req entity.NotificationPreferenceRequest) (entity.NotificationPreference, error)
+	HandleSecurityEvent(event entity.SecurityEvent) error
+}
+
+// This struct defines the NotificationService that contains a repository field of type NotificationRepository
+// and the mailer used to send the notifications
+// It implements the NotificationService interface and provides methods for notification-related operations
+type notificationService struct {
+	store     database.DataStore
+	repo      repository.NotificationRepository
+	mailer    mailer.Mailer
+	templates *mailer.Templates
+}
+
+// NewNotificationService creates a new instance of NotificationService with the given data store, repository, mailer,
+// and the templates of the emails. It initializes the notificationService struct and returns it.
+func NewNotificationService(store database.DataStore, repo repository.NotificationRepository, m mailer.Mailer, templates *mailer.Templates) NotificationService {
+	return &notificationService{store: store, repo: repo, mailer: m, templates: templates}
+}
+
+// GetNotificationPreference retrieves the notification preferences of the user from the database.
+// A user without preferences gets the default ones.
+func (s *notificationService) GetNotificationPreference(ctx context.Context, userID int64) (entity.NotificationPreference, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.NotificationPreference{}, fmt.Errorf("database connection is nil")
+	}
+
+	return s.getNotificationPreference(db, userID)
+}
+
+// UpdateNotificationPreference replaces the notification preferences of the user in the database.
+func (s *notificationService) UpdateNotificationPreference(ctx context.Context, userID int64, req entity.NotificationPreferenceRequest) (entity.NotificationPreference, error) {
+	if err := req.Validate(); err != nil {
+		return entity.NotificationPreference{}, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.NotificationPreference{}, fmt.Errorf("database connection is nil")
+	}
+
+	return s.repo.SaveNotificationPreference(db, entity.NotificationPreference{
+		UserID:          userID,
+		NewDeviceLogin:  *req.NewDeviceLogin,
+		PasswordChanged: *req.PasswordChanged,
+		MFADisabled:     *req.MFADisabled,
+		UpdatedAt:       time.Now(),
+	})
+}
+
+// HandleSecurityEvent sends the notification of the event to the user, if their preferences allow it.
+// A login only results in a notification when it comes from a new device of a user who already has devices.
+func (s *notificationService) HandleSecurityEvent(event entity.SecurityEvent) error {
+	db := s.store.DB(context.Background())
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	pref, err := s.getNotificationPreference(db, event.UserID)
+	if err != nil {
+		return err
+	}
+
+	var template string
+	data := mailer.SecurityEmail{Username: event.Username, At: event.At, IPAddress: event.IPAddress}
+	switch event.Type {
+	case entity.SecurityEventLogin:
+		isNew, err := s.recordDevice(db, event)
+		if err != nil || !isNew || !pref.NewDeviceLogin {
+			return err
+		}
+		template = mailer.TemplateNewDeviceLogin
+		data.Location = geoip.Location{Country: event.Country, City: event.City}.String()
+		data.Device = event.UserAgent
+	case entity.SecurityEventPasswordChanged:
+		if !pref.PasswordChanged {
+			return nil
+		}
+		template = mailer.TemplatePasswordChanged
+	case entity.SecurityEventMFADisabled:
+		if !pref.MFADisabled {
+			return nil
+		}
+		template = mailer.TemplateMFADisabled
+	default:
+		return fmt.Errorf("unsupported security event type: %s", event.Type)
+	}
+
+	if event.Email == "" {
+		return nil
+	}
+	msg, err := s.templates.Render(template, "", data)
+	if err != nil {
+		return err
+	}
+	msg.To = event.Email
+
+	return s.mailer.Send(msg)
+}
+
+// getNotificationPreference retrieves the notification preferences of the user, or the default ones.
+func (s *notificationService) getNotificationPreference(tx *gorm.DB, userID int64) (entity.NotificationPreference, error) {
+	pref, err := s.repo.GetNotificationPreference(tx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.DefaultNotificationPreference(userID), nil
+	}
+	if err != nil {
+		return entity.NotificationPreference{}, err
+	}
+
+	return pref, nil
+}
+
+// recordDevice records the device of the login and reports whether it is a new device of a user
+// who already had other devices. The first device of a user is not reported as new.
+func (s *notificationService) recordDevice(tx *gorm.DB, event entity.SecurityEvent) (bool, error) {
+	known, err := s.repo.CountUserDevices(tx, event.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	created, err := s.repo.TouchUserDevice(tx, entity.UserDevice{
+		UserID:      event.UserID,
+		Fingerprint: DeviceFingerprint(event.UserAgent, event.ClientID),
+		UserAgent:   event.UserAgent,
+		LastIP:      event.IPAddress,
+		LastCountry: event.Country,
+		LastCity:    event.City,
+		FirstSeenAt: event.At,
+		LastSeenAt:  event.At,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return created && known > 0, nil
+}
+
+// DeviceFingerprint returns the fingerprint identifying the device of a login.
+func DeviceFingerprint(userAgent string, clientID string) string {
+	hash := sha256.Sum256([]byte(userAgent + "|" + NormalizeClient(clientID)))
+	return hex.EncodeToString(hash[:])
+}
+
+// Interface for security notifier
+// This interface defines the methods used to report the security events of the users
+type SecurityNotifier interface {
+	Notify(event entity.SecurityEvent)
+	Close()
+}
+
+// This struct defines the SecurityNotifier that handles the security events in the background
+// It implements the SecurityNotifier interface
+type asyncSecurityNotifier struct {
+	service NotificationService
+	buffer  *WriteBuffer
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan entity.SecurityEvent
+	done    chan struct{}
+}
+
+// NewAsyncSecurityNotifier creates a new instance of SecurityNotifier that handles the events
+// with the given notification service, and starts its background worker.
+// The events failing with a transient database error are retried by the write buffer, if not nil.
+// A non-positive queue size falls back to the default.
+func NewAsyncSecurityNotifier(notificationSvc NotificationService, buffer *WriteBuffer, queueSize int) SecurityNotifier {
+	if queueSize <= 0 {
+		queueSize = DefaultNotificationQueueSize
+	}
+
+	n := &asyncSecurityNotifier{
+		service: notificationSvc,
+		buffer:  buffer,
+		queue:   make(chan entity.SecurityEvent, queueSize),
+		done:    make(chan struct{}),
+	}
+	go n.run()
+
+	return n
+}
+
+// Notify queues the event without waiting for it to be handled.
+// When the queue is full or the notifier is closed, the event is dropped and a warning is logged.
+func (n *asyncSecurityNotifier) Notify(event entity.SecurityEvent) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if !n.closed {
+		select {
+		case n.queue <- event:
+			return
+		default:
+		}
+	}
+
+	logger.Warn("Dropped a security event, the notification queue is full or closed", logrus.Fields{
+		"event":   event.Type,
+		"user_id": event.UserID,
+	})
+}
+
+// Close stops the background worker after handling the queued events.
+// It is safe to call Close more than once.
+func (n *asyncSecurityNotifier) Close() {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+
+	<-n.done
+}
+
+// run handles the queued events until the notifier is closed.
+// Failures are logged and not retried, unless they are transient database errors and the events are buffered.
+// Handling an event again is safe: a device already recorded is not reported as new a second time.
+func (n *asyncSecurityNotifier) run() {
+	defer close(n.done)
+
+	for event := range n.queue {
+		fields := logrus.Fields{"event": event.Type, "user_id": event.UserID}
+		if err := n.buffer.Write("security_event", fields, func() error {
+			return n.service.HandleSecurityEvent(event)
+		}); err != nil {
+			logger.Error(fmt.Sprintf("Failed to handle the security event: %v", err), fields)
+		}
+	}
+}
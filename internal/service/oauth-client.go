@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+)
+
+//go:generate go tool mockgen -source=oauth-client.go -destination=../../tests/mocks/oauth-client-service.go -package=mocks
+
+/**
+ * The machine clients of the service accounts integrate with the OAuth2 client_credentials grant (RFC 6749, section 4.4):
+ * they exchange the client ID and secret created by the administrators for an access token with POST /auth/token,
+ * instead of logging in with a username and password. Like the API keys, the secrets are random, only their SHA-256 hash
+ * is stored, and they are returned once, when the client is created.
+ * The token carries the client ID in its client claim, and the scopes requested by the client, which must be granted
+ * to the roles of its service account; it carries every scope of the account when none is requested.
+ * No refresh token is issued, the client requests a new token when it expires.
+ */
+
+const (
+	// ClientSecretPrefix starts every client secret, so that a leaked secret can be recognized, e.g. by the secret scanners.
+	ClientSecretPrefix = "cs_"
+)
+
+var (
+	// ErrInvalidClient is returned when the client is unknown or revoked, when its secret does not match,
+	// or when its service account cannot authenticate anymore.
+	ErrInvalidClient = errors.New("invalid client credentials")
+
+	// ErrInvalidScope is returned when a token request asks for a scope which is not granted to the service account of the client.
+	ErrInvalidScope = errors.New("requested scope is not granted to the client")
+
+	// ErrUnsupportedGrantType is returned when a token request asks for another grant than client_credentials.
+	ErrUnsupportedGrantType = errors.New("only the client_credentials grant is supported")
+
+	// ErrOAuthClientRevoked is returned when a revoked OAuth client is revoked.
+	ErrOAuthClientRevoked = errors.New("OAuth client is already revoked")
+
+	// ErrInvalidTokenRequest is returned when a token request misses the grant type, the client ID, or the client secret.
+	ErrInvalidTokenRequest = errors.New("grant_type, client_id, and client_secret are required")
+)
+
+// Interface for OAuth client service
+// This interface defines the methods that the OAuth client service should implement
+type OAuthClientService interface {
+	GetOAuthClients(ctx context.Context, userID int64) ([]entity.OAuthClient, error)
+	CreateOAuthClient(ctx context.Context, userID int64, req entity.OAuthClientRequest, createdBy int64) (entity.IssuedOAuthClient, error)
+	RevokeOAuthClient(ctx context.Context, id int64) error
+	IssueClientCredentialsToken(ctx context.Context, req entity.ClientCredentialsRequest) (entity.ClientCredentialsResponse, error)
+}
+
+// This struct defines the OAuthClientService that contains the OAuth client and user repositories, the token issuer
+// signing the access tokens, the resolver of the scopes granted to the service accounts, the recorder of the token usage,
+// and a clock used to get the current time
+// It implements the OAuthClientService interface
+type oauthClientService struct {
+	store       database.DataStore
+	repo        repository.OAuthClientRepository
+	userRepo    repository.UserRepository
+	tokenIssuer TokenIssuer
+	scopes      ScopeResolver
+	tokenUsage  TokenUsageRecorder
+	clock       clock.Clock
+}
+
+// NewOAuthClientService creates a new instance of OAuthClientService with the given dependencies.
+func NewOAuthClientService(store database.DataStore, repo repository.OAuthClientRepository, userRepo repository.UserRepository, tokenIssuer TokenIssuer, scopes ScopeResolver, tokenUsage TokenUsageRecorder, clk clock.Clock) OAuthClientService {
+	return &oauthClientService{
+		store:       store,
+		repo:        repo,
+		userRepo:    userRepo,
+		tokenIssuer: tokenIssuer,
+		scopes:      scopes,
+		tokenUsage:  tokenUsage,
+		clock:       clk,
+	}
+}
+
+// GetOAuthClients retrieves the OAuth clients of the user, the revoked ones included.
+func (s *oauthClientService) GetOAuthClients(ctx context.Context, userID int64) ([]entity.OAuthClient, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	if _, err := s.userRepo.GetUserByID(context.Background(), db, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetOAuthClientsByUserID(db, userID)
+}
+
+// CreateOAuthClient creates a new OAuth client for the service account, and returns it with its secret.
+func (s *oauthClientService) CreateOAuthClient(ctx context.Context, userID int64, req entity.OAuthClientRequest, createdBy int64) (entity.IssuedOAuthClient, error) {
+	// Validate the OAuth client request
+	if err := req.Validate(); err != nil {
+		return entity.IssuedOAuthClient{}, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.IssuedOAuthClient{}, fmt.Errorf("database connection is nil")
+	}
+
+	user, err := s.userRepo.GetUserByID(context.Background(), db, userID)
+	if err != nil {
+		return entity.IssuedOAuthClient{}, err
+	}
+	if user.UserType != entity.UserTypeServiceAccount {
+		return entity.IssuedOAuthClient{}, ErrNotServiceAccount
+	}
+
+	secret, err := generateClientSecret()
+	if err != nil {
+		return entity.IssuedOAuthClient{}, err
+	}
+
+	client, err := s.repo.CreateOAuthClient(db, entity.OAuthClient{
+		UserID:     userID,
+		Name:       req.Name,
+		ClientID:   uuid.New().String(),
+		SecretHash: HashApiKey(secret),
+		CreatedBy:  createdBy,
+		CreatedAt:  s.clock.Now(),
+	})
+	if err != nil {
+		return entity.IssuedOAuthClient{}, err
+	}
+
+	return entity.IssuedOAuthClient{OAuthClient: client, ClientSecret: secret}, nil
+}
+
+// RevokeOAuthClient revokes the OAuth client, it cannot request tokens anymore.
+// The tokens already issued to the client remain valid until they expire.
+func (s *oauthClientService) RevokeOAuthClient(ctx context.Context, id int64) error {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	if _, err := s.repo.GetOAuthClientByID(db, id); err != nil {
+		return err
+	}
+
+	revoked, err := s.repo.RevokeOAuthClient(db, id, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrOAuthClientRevoked
+	}
+
+	return nil
+}
+
+// IssueClientCredentialsToken authenticates the client with its ID and secret, and issues an access token
+// for its service account, carrying the requested scopes.
+func (s *oauthClientService) IssueClientCredentialsToken(ctx context.Context, req entity.ClientCredentialsRequest) (entity.ClientCredentialsResponse, error) {
+	if req.GrantType == "" || req.ClientID == "" || req.ClientSecret == "" {
+		return entity.ClientCredentialsResponse{}, ErrInvalidTokenRequest
+	}
+	if req.GrantType != entity.GrantTypeClientCredentials {
+		return entity.ClientCredentialsResponse{}, ErrUnsupportedGrantType
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.ClientCredentialsResponse{}, fmt.Errorf("database connection is nil")
+	}
+
+	client, err := s.repo.GetOAuthClientByClientID(db, req.ClientID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.ClientCredentialsResponse{}, ErrInvalidClient
+	}
+	if err != nil {
+		return entity.ClientCredentialsResponse{}, fmt.Errorf("failed to retrieve OAuth client: %w", err)
+	}
+	if client.RevokedAt != nil || subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(HashApiKey(req.ClientSecret))) != 1 {
+		return entity.ClientCredentialsResponse{}, ErrInvalidClient
+	}
+
+	user, err := s.userRepo.GetUserByID(context.Background(), db, client.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.ClientCredentialsResponse{}, ErrInvalidClient
+	}
+	if err != nil {
+		return entity.ClientCredentialsResponse{}, fmt.Errorf("failed to retrieve user by ID: %w", err)
+	}
+	if user.UserType != entity.UserTypeServiceAccount || checkUserStatus(user) != nil {
+		return entity.ClientCredentialsResponse{}, ErrInvalidClient
+	}
+
+	// The token carries the requested scopes, every scope granted to the account when none is requested
+	granted, err := s.scopes.GetScopes(ExtractRoleNames(user.Roles))
+	if err != nil {
+		return entity.ClientCredentialsResponse{}, fmt.Errorf("failed to resolve scopes: %w", err)
+	}
+	scopes := granted
+	if requested := strings.Fields(req.Scope); len(requested) > 0 {
+		if !containsAll(granted, requested) {
+			return entity.ClientCredentialsResponse{}, ErrInvalidScope
+		}
+		scopes = requested
+	}
+
+	now := s.clock.Now()
+	token, err := s.tokenIssuer.IssueScopedToken(user, client.ClientID, scopes, now)
+	if err != nil {
+		return entity.ClientCredentialsResponse{}, err
+	}
+	s.tokenUsage.Record(TokenEventIssued, user.ID, client.ClientID, now)
+
+	return entity.ClientCredentialsResponse{
+		AccessToken: token.AccessToken,
+		TokenType:   s.tokenIssuer.TokenType(),
+		ExpiresIn:   int64(token.ExpiresAt.Sub(now).Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// containsAll reports whether every value of want is in have.
+func containsAll(have []string, want []string) bool {
+	set := make(map[string]bool, len(have))
+	for _, v := range have {
+		set[v] = true
+	}
+	for _, v := range want {
+		if !set[v] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// generateClientSecret returns a new random client secret, starting with ClientSecretPrefix.
+func generateClientSecret() (string, error) {
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate client secret: %w", err)
+	}
+
+	return ClientSecretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
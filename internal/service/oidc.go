@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/oidc"
+)
+
+//go:generate go tool mockgen -source=oidc.go -destination=../../tests/mocks/oidc-service.go -package=mocks
+
+/**
+ * The users log in with an account of an OpenID Connect provider (e.g. Google, Microsoft) with the authorization code flow:
+ * the login route redirects them to the provider, which redirects them back to the callback route with an authorization code,
+ * exchanged for an ID token identifying their account. The account is linked to a local user, provisioned with the default
+ * role on the first login, and the login goes on like a login with a password: the application issues its own access
+ * and refresh tokens, once the users with two-factor authentication entered a code of their authenticator app.
+ * A local user is never linked to an account of a provider by its email, so an account of a provider using the email
+ * of an existing user is rejected; the provisioned users have no usable password until they reset it.
+ */
+
+const (
+	// OIDCLoginStateLifetime is the time the user has to log in with the provider and come back to the callback.
+	OIDCLoginStateLifetime = 10 * time.Minute
+
+	// oidcRandomBytes is the number of random bytes of the states, the nonces, and the code verifiers.
+	oidcRandomBytes = 32
+
+	// oidcUsernameMaxBase is the maximum length of a provisioned username before its numeric suffix.
+	oidcUsernameMaxBase = 15
+
+	// oidcUsernameAttempts is the number of usernames tried before the provisioning of a user fails.
+	oidcUsernameAttempts = 5
+)
+
+var (
+	// ErrUnknownOIDCProvider is returned when a login uses a provider which is not configured by OIDC_PROVIDERS.
+	ErrUnknownOIDCProvider = errors.New("unknown OIDC provider")
+
+	// ErrInvalidOIDCCallback is returned when the callback of the provider misses its authorization code, or when its state
+	// does not match the login started by the browser, e.g. because the login expired or was started in another browser.
+	ErrInvalidOIDCCallback = errors.New("OIDC callback does not match a login in progress")
+
+	// ErrOIDCAuthorizationDenied is returned when the provider redirects the user back with an error, e.g. after a refused consent.
+	ErrOIDCAuthorizationDenied = errors.New("OIDC provider denied the authorization")
+
+	// ErrOIDCEmailNotVerified is returned when the account of the provider has no email, or an email it did not verify.
+	ErrOIDCEmailNotVerified = errors.New("OIDC account has no verified email")
+
+	// ErrOIDCAccountExists is returned when the email of the account of the provider is used by a local user not linked to it.
+	ErrOIDCAccountExists = errors.New("email of the OIDC account is already used by another user")
+)
+
+// Interface for OIDC provider
+// This interface defines the authorization code flow of an OpenID Connect provider, implemented by oidc.Provider
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state string, nonce string, codeVerifier string) (string, error)
+	Authenticate(ctx context.Context, code string, codeVerifier string, nonce string) (oidc.Claims, error)
+}
+
+// Interface for OIDC service
+// This interface defines the methods that the OIDC service should implement
+type OIDCService interface {
+	Providers() []string
+	StartLogin(ctx context.Context, provider string) (entity.OIDCAuthorization, error)
+	CompleteLogin(ctx context.Context, provider string, req entity.OIDCCallbackRequest, loginState entity.OIDCLoginState) (entity.LoginResponse, error)
+}
+
+// This struct defines the OIDCService that contains the configured providers by name, the repositories of the identities,
+// the users, and the roles, the auth service issuing the tokens, and a clock used to get the current time
+// It implements the OIDCService interface
+type oidcService struct {
+	store        database.DataStore
+	providers    map[string]OIDCProvider
+	identityRepo repository.UserIdentityRepository
+	userRepo     repository.UserRepository
+	roleRepo     repository.RoleRepository
+	auth         AuthService
+	clock        clock.Clock
+}
+
+// NewOIDCService creates a new instance of OIDCService with the given providers, by name, and dependencies.
+func NewOIDCService(store database.DataStore, providers map[string]OIDCProvider, identityRepo repository.UserIdentityRepository, userRepo repository.UserRepository, roleRepo repository.RoleRepository, auth AuthService, clk clock.Clock) OIDCService {
+	return &oidcService{
+		store:        store,
+		providers:    providers,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		roleRepo:     roleRepo,
+		auth:         auth,
+		clock:        clk,
+	}
+}
+
+// Providers returns the names of the configured providers, sorted.
+func (s *oidcService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// StartLogin starts a login with the provider: it returns the URL of the provider the user is redirected to,
+// and the state of the login, which the browser keeps until the callback.
+func (s *oidcService) StartLogin(ctx context.Context, provider string) (entity.OIDCAuthorization, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return entity.OIDCAuthorization{}, ErrUnknownOIDCProvider
+	}
+
+	loginState := entity.OIDCLoginState{Provider: provider, ExpiresAt: s.clock.Now().Add(OIDCLoginStateLifetime)}
+	for _, value := range []*string{&loginState.State, &loginState.Nonce, &loginState.CodeVerifier} {
+		random, err := oidc.RandomString(oidcRandomBytes)
+		if err != nil {
+			return entity.OIDCAuthorization{}, fmt.Errorf("failed to generate the OIDC login state: %w", err)
+		}
+		*value = random
+	}
+
+	url, err := p.AuthCodeURL(ctx, loginState.State, loginState.Nonce, loginState.CodeVerifier)
+	if err != nil {
+		return entity.OIDCAuthorization{}, err
+	}
+
+	return entity.OIDCAuthorization{URL: url, LoginState: loginState}, nil
+}
+
+// CompleteLogin completes the login with the provider once it redirected the user back to the callback:
+// it checks the callback against the state of the login, exchanges the authorization code for the ID token of the user,
+// resolves the local user linked to the account of the provider, provisioning it on the first login, and logs it in.
+func (s *oidcService) CompleteLogin(ctx context.Context, provider string, req entity.OIDCCallbackRequest, loginState entity.OIDCLoginState) (entity.LoginResponse, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return entity.LoginResponse{}, ErrUnknownOIDCProvider
+	}
+	if req.Error != "" {
+		return entity.LoginResponse{}, fmt.Errorf("%w: %s", ErrOIDCAuthorizationDenied, req.Error)
+	}
+	if req.Code == "" || loginState.Provider != provider || loginState.State == "" ||
+		subtle.ConstantTimeCompare([]byte(req.State), []byte(loginState.State)) != 1 || !s.clock.Now().Before(loginState.ExpiresAt) {
+		return entity.LoginResponse{}, ErrInvalidOIDCCallback
+	}
+
+	claims, err := p.Authenticate(ctx, req.Code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return entity.LoginResponse{}, err
+	}
+
+	user, err := s.resolveUser(ctx, provider, claims)
+	if err != nil {
+		return entity.LoginResponse{}, err
+	}
+
+	return s.auth.LoginExternal(ctx, user, entity.LoginRequest{
+		Username:  user.Username,
+		ClientID:  req.ClientID,
+		DeviceID:  req.DeviceID,
+		IPAddress: req.IPAddress,
+		UserAgent: req.UserAgent,
+		Country:   req.Country,
+		City:      req.City,
+	})
+}
+
+// resolveUser returns the local user linked to the account of the provider, provisioning it on the first login.
+func (s *oidcService) resolveUser(ctx context.Context, provider string, claims oidc.Claims) (entity.User, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.User{}, fmt.Errorf("database connection is nil")
+	}
+
+	identity, err := s.identityRepo.GetUserIdentity(db, provider, claims.Subject)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.provisionUser(ctx, db, provider, claims)
+	}
+	if err != nil {
+		return entity.User{}, fmt.Errorf("failed to retrieve user identity: %w", err)
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, db, identity.UserID)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("failed to retrieve the user of identity %d: %w", identity.ID, err)
+	}
+	if err := s.identityRepo.UpdateLastLogin(db, identity.ID, s.clock.Now()); err != nil {
+		logger.WarnContext(ctx, "Failed to record the last login of a user identity", logrus.Fields{"identity_id": identity.ID, "error": err.Error()})
+	}
+
+	return user, nil
+}
+
+// provisionUser creates the local user of the account of the provider, with the default role and an unusable password,
+// and links the account to it.
+func (s *oidcService) provisionUser(ctx context.Context, db *gorm.DB, provider string, claims oidc.Claims) (entity.User, error) {
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || (claims.EmailVerified != nil && !*claims.EmailVerified) {
+		return entity.User{}, ErrOIDCEmailNotVerified
+	}
+
+	// Nobody knows the password, the user logs in with the provider or sets a password with a reset
+	secret, err := oidc.RandomString(oidcRandomBytes)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("failed to generate password: %w", err)
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	now := s.clock.Now()
+	var createdUser entity.User
+	err = database.Transaction(db, func(tx *gorm.DB) error {
+		if _, err := s.userRepo.GetUserByEmail(ctx, tx, email); err == nil {
+			return ErrOIDCAccountExists
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to check existing user by email: %w", err)
+		}
+
+		username, err := s.availableUsername(ctx, tx, claims)
+		if err != nil {
+			return err
+		}
+
+		role, err := s.roleRepo.GetRoleByName(tx, DefaultUserRole)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve role %s: %w", DefaultUserRole, err)
+		}
+
+		var lastname *string
+		if claims.FamilyName != "" {
+			l := truncateRunes(claims.FamilyName, 20)
+			lastname = &l
+		}
+
+		createdUser, err = s.userRepo.CreateUser(ctx, tx, entity.User{
+			Username:  username,
+			Password:  string(hashedPassword),
+			Email:     email,
+			Firstname: oidcFirstname(claims, username),
+			Lastname:  lastname,
+			State:     entity.UserStateActive,
+			UserType:  entity.UserTypeUserAccount,
+			Roles:     []entity.Role{role},
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = s.identityRepo.CreateUserIdentity(tx, entity.UserIdentity{
+			UserID:      createdUser.ID,
+			Provider:    provider,
+			Subject:     claims.Subject,
+			Email:       email,
+			LastLoginAt: &now,
+			CreatedAt:   now,
+		})
+		return err
+	})
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	logger.InfoContext(ctx, "Provisioned user from OIDC provider", logrus.Fields{"user_id": createdUser.ID, "provider": provider})
+
+	return createdUser, nil
+}
+
+// availableUsername returns a username not used yet, derived from the preferred username or the email of the account,
+// with a random numeric suffix when it is already used.
+func (s *oidcService) availableUsername(ctx context.Context, tx *gorm.DB, claims oidc.Claims) (string, error) {
+	base := oidcUsernameBase(claims)
+
+	candidate := base
+	for attempt := 0; attempt < oidcUsernameAttempts; attempt++ {
+		if attempt > 0 {
+			candidate = fmt.Sprintf("%s%04d", base, rand.IntN(10000))
+		}
+
+		_, err := s.userRepo.GetUserByUsername(ctx, tx, candidate)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to check existing user by username: %w", err)
+		}
+	}
+
+	return "", fmt.Errorf("%w: no username available for %s", ErrUserAlreadyExists, base)
+}
+
+// oidcUsernameBase returns the username derived from the preferred username of the account, or from its email,
+// keeping the lowercase letters, the digits, the dots, the dashes, and the underscores of its local part.
+func oidcUsernameBase(claims oidc.Claims) string {
+	for _, source := range []string{claims.PreferredUsername, claims.Email} {
+		local, _, _ := strings.Cut(source, "@")
+
+		var b strings.Builder
+		for _, r := range strings.ToLower(local) {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '-' || r == '_' {
+				b.WriteRune(r)
+			}
+		}
+		if username := truncateRunes(b.String(), oidcUsernameMaxBase); len(username) >= 3 {
+			return username
+		}
+	}
+
+	return "user"
+}
+
+// oidcFirstname returns the first name of the provisioned user: the given name of the account,
+// the first word of its name, or the username.
+func oidcFirstname(claims oidc.Claims, username string) string {
+	firstname := strings.TrimSpace(claims.GivenName)
+	if firstname == "" {
+		if fields := strings.FieldsFunc(claims.Name, unicode.IsSpace); len(fields) > 0 {
+			firstname = fields[0]
+		}
+	}
+	if firstname == "" {
+		firstname = username
+	}
+
+	return truncateRunes(firstname, 20)
+}
+
+// truncateRunes returns the string cut to the given number of characters.
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) > max {
+		return string(runes[:max])
+	}
+
+	return s
+}
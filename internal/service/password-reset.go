@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/mailer"
+)
+
+//go:generate go tool mockgen -source=password-reset.go -destination=../../tests/mocks/password-reset-service.go -package=mocks
+
+/**
+ * A user who forgot their password requests a reset link by email, and sets a new password with the token of the link.
+ * The tokens are random, only their SHA-256 hash is stored, and each token can be used once before it expires
+ * (PASSWORD_RESET_TOKEN_TTL_MINUTES, default 30). Requesting a new link invalidates the previous ones.
+ * Resetting the password revokes all the tokens of the user, so the sessions opened with the old password are ended.
+ */
+
+const (
+	// passwordResetTokenBytes is the number of random bytes of the password reset tokens.
+	passwordResetTokenBytes = 32
+)
+
+// ErrInvalidPasswordResetToken is returned when a password reset token is unknown, expired, or already used.
+var ErrInvalidPasswordResetToken = errors.New("invalid or expired password reset token")
+
+// Interface for password reset service
+// This interface defines the methods that the password reset service should implement
+type PasswordResetService interface {
+	RequestPasswordReset(ctx context.Context, req entity.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, req entity.ResetPasswordRequest) error
+}
+
+// This struct defines the PasswordResetService that contains the password reset token and user repositories,
+// the token revocation service ending the sessions of the user, the notifier of the security events,
+// the mailer and the templates of the reset emails, the password reset policy, and a clock used to get the current time
+// It implements the PasswordResetService interface and provides methods for password reset-related operations
+type passwordResetService struct {
+	store      database.DataStore
+	repo       repository.PasswordResetTokenRepository
+	userRepo   repository.UserRepository
+	revocation TokenRevocationService
+	notifier   SecurityNotifier
+	mailer     mailer.Mailer
+	templates  *mailer.Templates
+	policy     policyconfig.PasswordResetPolicy
+	clock      clock.Clock
+}
+
+// NewPasswordResetService creates a new instance of PasswordResetService with the given dependencies.
+// It initializes the passwordResetService struct and returns it.
+func NewPasswordResetService(store database.DataStore, repo repository.PasswordResetTokenRepository, userRepo repository.UserRepository, revocation TokenRevocationService, notifier SecurityNotifier, m mailer.Mailer, templates *mailer.Templates, policy policyconfig.PasswordResetPolicy, clk clock.Clock) PasswordResetService {
+	if policy.TokenTTL <= 0 {
+		policy.TokenTTL = policyconfig.DefaultPasswordResetTokenTTL
+	}
+
+	return &passwordResetService{
+		store:      store,
+		repo:       repo,
+		userRepo:   userRepo,
+		revocation: revocation,
+		notifier:   notifier,
+		mailer:     m,
+		templates:  templates,
+		policy:     policy,
+		clock:      clk,
+	}
+}
+
+// RequestPasswordReset emails a password reset link to the user with the given email.
+// Nothing is sent when no active user has the email, and no error is returned either,
+// so that the endpoint cannot be used to find out which emails are registered.
+func (s *passwordResetService) RequestPasswordReset(ctx context.Context, req entity.ForgotPasswordRequest) error {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	// Validate the request using the validator
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByEmail(context.Background(), db, req.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to retrieve user by email: %w", err)
+	}
+	if err := checkUserStatus(user); err != nil {
+		return nil
+	}
+
+	token, err := generatePasswordResetToken()
+	if err != nil {
+		return err
+	}
+
+	// Only the latest link of the user works
+	now := s.clock.Now()
+	var resetToken entity.PasswordResetToken
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.repo.RemoveUnusedPasswordResetTokens(tx, user.ID); err != nil {
+			return err
+		}
+
+		resetToken, err = s.repo.CreatePasswordResetToken(tx, entity.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: HashPasswordResetToken(token),
+			ExpiresAt: now.Add(s.policy.TokenTTL),
+			CreatedAt: now,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// The token is lost if the email cannot be sent, the user requests another link.
+	// The error is not returned, it would tell that the email is registered
+	msg, err := s.templates.Render(mailer.TemplatePasswordReset, req.Locale, mailer.PasswordResetEmail{
+		Username:  user.Username,
+		Link:      s.resetLink(token),
+		ExpiresAt: resetToken.ExpiresAt,
+	})
+	if err == nil {
+		msg.To = user.Email
+		err = s.mailer.Send(msg)
+	}
+	if err != nil {
+		logger.Error("Failed to send the password reset email", logrus.Fields{"user_id": user.ID, "error": err.Error()})
+	}
+
+	return nil
+}
+
+// ResetPassword sets the new password of the user of the password reset token, and uses the token up.
+// All the tokens of the user are revoked afterwards, and the user is notified of the change.
+func (s *passwordResetService) ResetPassword(ctx context.Context, req entity.ResetPasswordRequest) error {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	// Validate the request using the validator
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	// Hash the new password before the token is used, so that a hashing failure does not waste it
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	now := s.clock.Now()
+	var user entity.User
+	err = database.Transaction(db, func(tx *gorm.DB) error {
+		resetToken, err := s.repo.GetPasswordResetTokenByHash(tx, HashPasswordResetToken(req.Token))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidPasswordResetToken
+		}
+		if err != nil {
+			return fmt.Errorf("failed to retrieve password reset token: %w", err)
+		}
+		if !resetToken.IsUsable(now) {
+			return ErrInvalidPasswordResetToken
+		}
+
+		// A token used concurrently is only accepted once
+		marked, err := s.repo.MarkPasswordResetTokenUsed(tx, resetToken.ID, now)
+		if err != nil {
+			return err
+		}
+		if !marked {
+			return ErrInvalidPasswordResetToken
+		}
+
+		if user, err = s.userRepo.GetUserByID(context.Background(), tx, resetToken.UserID); err != nil {
+			return fmt.Errorf("failed to retrieve user: %w", err)
+		}
+		if err := checkUserStatus(user); err != nil {
+			return ErrInvalidPasswordResetToken
+		}
+
+		if err := s.userRepo.UpdatePassword(context.Background(), tx, user.ID, string(hashedPassword), s.policy.Credentials.CredentialsExpiration(now)); err != nil {
+			return err
+		}
+
+		_, err = s.repo.RemoveUnusedPasswordResetTokens(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// End the sessions opened with the old password
+	if _, err := s.revocation.RevokeUserTokens(ctx, user.ID); err != nil {
+		return fmt.Errorf("password was reset but the tokens of the user could not be revoked: %w", err)
+	}
+
+	s.notifier.Notify(entity.SecurityEvent{
+		Type:      entity.SecurityEventPasswordChanged,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		IPAddress: req.IPAddress,
+		At:        now,
+	})
+
+	return nil
+}
+
+// resetLink returns the link of the reset page of the frontend carrying the given token.
+func (s *passwordResetService) resetLink(token string) string {
+	separator := "?"
+	if strings.Contains(s.policy.ResetURL, "?") {
+		separator = "&"
+	}
+
+	return s.policy.ResetURL + separator + "token=" + url.QueryEscape(token)
+}
+
+// generatePasswordResetToken returns a new random password reset token, encoded for URLs.
+func generatePasswordResetToken() (string, error) {
+	b := make([]byte, passwordResetTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate password reset token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashPasswordResetToken returns the SHA-256 hash of a password reset token, as stored in the database.
+func HashPasswordResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
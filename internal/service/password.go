@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+//go:generate go tool mockgen -source=password.go -destination=../../tests/mocks/password-service.go -package=mocks
+
+/**
+ * The authenticated users change their password by giving their current password and a new one.
+ * The new password must contain uppercase and lowercase letters, a digit, and a special character,
+ * and differ from the current one. A changed (or reset) password expires after CREDENTIALS_TTL_DAYS
+ * (default 90, 0 never expires), which is recorded in the CredentialsExpirationDate of the user.
+ * All the refresh tokens of the user are removed, so the other sessions end when their access tokens expire.
+ */
+
+// ErrIncorrectPassword is returned when the current password given to change the password is wrong.
+var ErrIncorrectPassword = errors.New("current password is incorrect")
+
+// Interface for password service
+// This interface defines the methods that the password service should implement
+type PasswordService interface {
+	ChangePassword(ctx context.Context, userID int64, req entity.ChangePasswordRequest) (entity.ChangePasswordResponse, error)
+}
+
+// This struct defines the PasswordService that contains the user and refresh token repositories,
+// the notifier of the security events, the password policy, and a clock used to get the current time
+// It implements the PasswordService interface and provides methods for password-related operations
+type passwordService struct {
+	store            database.DataStore
+	userRepo         repository.UserRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	notifier         SecurityNotifier
+	policy           policyconfig.PasswordPolicy
+	clock            clock.Clock
+}
+
+// NewPasswordService creates a new instance of PasswordService with the given dependencies.
+// It initializes the passwordService struct and returns it.
+func NewPasswordService(store database.DataStore, userRepo repository.UserRepository, refreshTokenRepo repository.RefreshTokenRepository, notifier SecurityNotifier, policy policyconfig.PasswordPolicy, clk clock.Clock) PasswordService {
+	return &passwordService{
+		store:            store,
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		notifier:         notifier,
+		policy:           policy,
+		clock:            clk,
+	}
+}
+
+// ChangePassword sets the new password of the user after checking the current one, and sets the expiration date of the credentials.
+// All the refresh tokens of the user are removed in the same transaction, and the user is notified of the change.
+func (s *passwordService) ChangePassword(ctx context.Context, userID int64, req entity.ChangePasswordRequest) (entity.ChangePasswordResponse, error) {
+	// Validate the request using the validator
+	if err := req.Validate(); err != nil {
+		return entity.ChangePasswordResponse{}, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.ChangePasswordResponse{}, fmt.Errorf("database connection is nil")
+	}
+
+	user, err := s.userRepo.GetUserByID(context.Background(), db, userID)
+	if err != nil {
+		return entity.ChangePasswordResponse{}, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	if err := checkUserStatus(user); err != nil {
+		return entity.ChangePasswordResponse{}, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		return entity.ChangePasswordResponse{}, ErrIncorrectPassword
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return entity.ChangePasswordResponse{}, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	now := s.clock.Now()
+	expiresAt := s.policy.CredentialsExpiration(now)
+	err = database.Transaction(db, func(tx *gorm.DB) error {
+		if err := s.userRepo.UpdatePassword(context.Background(), tx, user.ID, string(hashedPassword), expiresAt); err != nil {
+			return err
+		}
+
+		// End the sessions opened with the old password
+		if _, err := s.refreshTokenRepo.RemoveRefreshTokenByUserID(tx, user.ID); err != nil {
+			return fmt.Errorf("failed to remove the refresh tokens of the user: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return entity.ChangePasswordResponse{}, err
+	}
+
+	logger.Info("Changed the password of the user", logrus.Fields{"user_id": user.ID})
+
+	s.notifier.Notify(entity.SecurityEvent{
+		Type:      entity.SecurityEventPasswordChanged,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		IPAddress: req.IPAddress,
+		At:        now,
+	})
+
+	return entity.ChangePasswordResponse{
+		UserID:                    user.ID,
+		CredentialsExpirationDate: expiresAt,
+		ChangedAt:                 now,
+	}, nil
+}
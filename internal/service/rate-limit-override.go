@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/ratelimit"
+)
+
+//go:generate go tool mockgen -source=rate-limit-override.go -destination=../../tests/mocks/rate-limit-override-service.go -package=mocks
+
+// maxRateLimitClientLength is the length of the client column of the rate limit overrides.
+const maxRateLimitClientLength = 100
+
+var (
+	// ErrInvalidRateLimitClient is returned when the client of a rate limit override is empty or too long.
+	ErrInvalidRateLimitClient = errors.New("invalid client")
+
+	// ErrRateLimitOverrideNotFound is returned when the client has no rate limit override.
+	ErrRateLimitOverrideNotFound = errors.New("rate limit override not found")
+)
+
+// Interface for rate limit override service
+// This interface defines the methods that the rate limit override service should implement
+type RateLimitOverrideService interface {
+	GetRateLimitOverrides(ctx context.Context) ([]entity.RateLimitOverride, error)
+	SaveRateLimitOverride(ctx context.Context, userID int64, client string, req entity.RateLimitOverrideRequest) (entity.RateLimitOverride, error)
+	DeleteRateLimitOverride(ctx context.Context, userID int64, client string) error
+	LoadOverrides() error
+	Overrides() *ratelimit.Overrides
+}
+
+// This struct defines the RateLimitOverrideService that contains a repository field of type RateLimitOverrideRepository,
+// the cache of the factors applied by the rate limit middleware, and a clock used to get the current time
+// It implements the RateLimitOverrideService interface and provides methods for rate limit override-related operations
+type rateLimitOverrideService struct {
+	store database.DataStore
+	repo  repository.RateLimitOverrideRepository
+	cache *ratelimit.Overrides
+	clock clock.Clock
+}
+
+// NewRateLimitOverrideService creates a new instance of RateLimitOverrideService with the given data store and repository.
+// The overrides are cached for the given TTL, see ratelimit.NewOverrides.
+func NewRateLimitOverrideService(store database.DataStore, repo repository.RateLimitOverrideRepository, ttl time.Duration, clk clock.Clock) RateLimitOverrideService {
+	s := &rateLimitOverrideService{store: store, repo: repo, clock: clk}
+	s.cache = ratelimit.NewOverrides(s.loadFactors, ttl, clk)
+
+	return s
+}
+
+// GetRateLimitOverrides retrieves the rate limit overrides of all the clients, ordered by client.
+func (s *rateLimitOverrideService) GetRateLimitOverrides(ctx context.Context) ([]entity.RateLimitOverride, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	return s.repo.GetRateLimitOverrides(db)
+}
+
+// SaveRateLimitOverride creates or replaces the rate limit override of the client, and reloads the overrides of the instance.
+// The other instances apply the new override once their cached ones are older than the TTL.
+func (s *rateLimitOverrideService) SaveRateLimitOverride(ctx context.Context, userID int64, client string, req entity.RateLimitOverrideRequest) (entity.RateLimitOverride, error) {
+	if err := validateRateLimitClient(client); err != nil {
+		return entity.RateLimitOverride{}, err
+	}
+
+	// Validate the request using the validator
+	if err := req.Validate(); err != nil {
+		return entity.RateLimitOverride{}, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.RateLimitOverride{}, fmt.Errorf("database connection is nil")
+	}
+
+	now := s.clock.Now()
+	override := entity.RateLimitOverride{Client: client, Factor: *req.Factor, UpdatedBy: &userID, UpdatedAt: &now}
+	if req.Reason != nil && *req.Reason != "" {
+		override.Reason = req.Reason
+	}
+
+	savedOverride, err := s.repo.SaveRateLimitOverride(db, override)
+	if err != nil {
+		return entity.RateLimitOverride{}, err
+	}
+
+	s.cache.Invalidate()
+
+	logger.Info("Saved rate limit override", logrus.Fields{
+		"user_id": userID,
+		"client":  client,
+		"factor":  savedOverride.Factor,
+	})
+
+	return savedOverride, nil
+}
+
+// DeleteRateLimitOverride deletes the rate limit override of the client, which is allowed the default limits again,
+// and reloads the overrides of the instance. It fails with ErrRateLimitOverrideNotFound if the client has no override.
+func (s *rateLimitOverrideService) DeleteRateLimitOverride(ctx context.Context, userID int64, client string) error {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	if err := s.repo.DeleteRateLimitOverride(db, client); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %s", ErrRateLimitOverrideNotFound, client)
+		}
+		return err
+	}
+
+	s.cache.Invalidate()
+
+	logger.Info("Deleted rate limit override", logrus.Fields{
+		"user_id": userID,
+		"client":  client,
+	})
+
+	return nil
+}
+
+// LoadOverrides loads the rate limit overrides into the cache. It is run on Startup,
+// the middleware applies the default limits to every client before.
+func (s *rateLimitOverrideService) LoadOverrides() error {
+	return s.cache.Load()
+}
+
+// Overrides returns the cache of the rate limit overrides, applied by the rate limit middleware.
+func (s *rateLimitOverrideService) Overrides() *ratelimit.Overrides {
+	return s.cache
+}
+
+// loadFactors loads the factors of the clients with an override from the database, for the cache.
+func (s *rateLimitOverrideService) loadFactors() (map[string]float64, error) {
+	db := s.store.DB(context.Background())
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	overrides, err := s.repo.GetRateLimitOverrides(db)
+	if err != nil {
+		return nil, err
+	}
+
+	factors := make(map[string]float64, len(overrides))
+	for _, override := range overrides {
+		factors[override.Client] = override.Factor
+	}
+
+	return factors, nil
+}
+
+// validateRateLimitClient checks that the client of a rate limit override is not empty, and fits its column.
+func validateRateLimitClient(client string) error {
+	if strings.TrimSpace(client) == "" || len(client) > maxRateLimitClientLength {
+		return fmt.Errorf("%w: the client must be between 1 and %d characters", ErrInvalidRateLimitClient, maxRateLimitClientLength)
+	}
+
+	return nil
+}
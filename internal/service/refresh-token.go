@@ -1,44 +1,74 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"os"
-	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
 	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
 )
 
+//go:generate go tool mockgen -source=refresh-token.go -destination=../../tests/mocks/refresh-token-service.go -package=mocks
+
+/**
+ * Every refresh token is an active session of its user, so a user may be logged in on several devices at once.
+ * A login from a device identified by a device ID replaces the previous session of that device.
+ * The number of active sessions per user is capped with MAX_SESSIONS_PER_USER (default 5).
+ * SESSION_LIMIT_MODE selects what happens when a login exceeds it: EVICT_OLDEST (default) removes
+ * the oldest sessions, REJECT fails the login with ErrSessionLimitReached.
+ * Refreshing a token rotates it within its session, so it never counts against the limit.
+ * The refresh tokens expire after JWT_REFRESH_TOKEN_EXPIRATION_HOUR (default 24).
+ */
+
+// ErrSessionLimitReached is returned when a login is rejected because the user has too many active sessions.
+var ErrSessionLimitReached = errors.New("session limit reached")
+
 // Interface for refresh token service
 // This interface defines the methods that the refresh token service should implement
 type RefreshTokenService interface {
-	GetRefreshTokenByUserID(userID int64) (entity.RefreshToken, error)
-	GetRefreshTokenByToken(token string) (entity.RefreshToken, error)
+	GetRefreshTokenByUserID(ctx context.Context, userID int64) (entity.RefreshToken, error)
+	GetRefreshTokenByToken(ctx context.Context, token string) (entity.RefreshToken, error)
 	VerifyExpirationDate(exp time.Time) (bool, error)
-	CreateRefreshToken(userID int64) (entity.RefreshToken, error)
+	CreateRefreshToken(ctx context.Context, userID int64, device entity.SessionDevice) (entity.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, refreshToken entity.RefreshToken) (entity.RefreshToken, error)
+	GetSessions(ctx context.Context, userID int64) ([]entity.Session, error)
+	RemoveSession(ctx context.Context, userID int64, sessionID string) error
 }
 
-// This struct defines the RefreshTokenService that contains a repository field of type RefreshTokenRepository
+// This struct defines the RefreshTokenService that contains a repository field of type RefreshTokenRepository,
+// the policy limiting the active sessions of the users, and a clock used to get the current time
 // It implements the RefreshTokenService interface and provides methods for refresh token-related operations
 type refreshTokenService struct {
-	repo repository.RefreshTokenRepository
+	store  database.DataStore
+	repo   repository.RefreshTokenRepository
+	policy policyconfig.SessionPolicy
+	clock  clock.Clock
 }
 
-// NewRefreshTokenService creates a new instance of RefreshTokenService with the given repository.
+// NewRefreshTokenService creates a new instance of RefreshTokenService with the given data store, repository, session policy, and clock.
 // It initializes the refreshTokenService struct and returns it.
-func NewRefreshTokenService(repo repository.RefreshTokenRepository) RefreshTokenService {
-	return &refreshTokenService{repo: repo}
+func NewRefreshTokenService(store database.DataStore, repo repository.RefreshTokenRepository, policy policyconfig.SessionPolicy, clk clock.Clock) RefreshTokenService {
+	if policy.MaxSessions <= 0 {
+		policy.MaxSessions = policyconfig.DefaultMaxSessions
+	}
+	if policy.RefreshTokenTTL <= 0 {
+		policy.RefreshTokenTTL = policyconfig.DefaultRefreshTokenTTL
+	}
+
+	return &refreshTokenService{store: store, repo: repo, policy: policy, clock: clk}
 }
 
 // GetRefreshTokenByUserID retrieves a refresh token by its user ID from the database.
-func (s *refreshTokenService) GetRefreshTokenByUserID(userID int64) (entity.RefreshToken, error) {
-	db := database.GetPostgres()
+func (s *refreshTokenService) GetRefreshTokenByUserID(ctx context.Context, userID int64) (entity.RefreshToken, error) {
+	db := s.store.DB(ctx)
 	if db == nil {
 		return entity.RefreshToken{}, fmt.Errorf("database connection is nil")
 	}
@@ -53,8 +83,8 @@ func (s *refreshTokenService) GetRefreshTokenByUserID(userID int64) (entity.Refr
 }
 
 // GetRefreshTokenByToken retrieves a refresh token by its token string from the database.
-func (s *refreshTokenService) GetRefreshTokenByToken(token string) (entity.RefreshToken, error) {
-	db := database.GetPostgres()
+func (s *refreshTokenService) GetRefreshTokenByToken(ctx context.Context, token string) (entity.RefreshToken, error) {
+	db := s.store.DB(ctx)
 	if db == nil {
 		return entity.RefreshToken{}, fmt.Errorf("database connection is nil")
 	}
@@ -76,52 +106,107 @@ func (s *refreshTokenService) VerifyExpirationDate(exp time.Time) (bool, error)
 	}
 
 	// Check if the expiration date is in the past
-	if time.Now().After(exp) {
+	if s.clock.Now().After(exp) {
 		return false, nil
 	}
 
 	return true, nil
 }
 
-// CreateRefreshToken creates a new refresh token for the user in the database.
-// If a refresh token already exists for the user, it will be removed before creating a new one,
-// ensuring that only one refresh token exists for each user at a time.
-func (s *refreshTokenService) CreateRefreshToken(userID int64) (entity.RefreshToken, error) {
-	db := database.GetPostgres()
+// CreateRefreshToken creates a new refresh token for the user in the database, which starts a new session on the given device.
+// The expired refresh tokens of the user and the previous session of the device are removed first.
+// If the user already has the maximum number of active sessions, the oldest ones are removed
+// or the creation fails with ErrSessionLimitReached, depending on the session policy.
+func (s *refreshTokenService) CreateRefreshToken(ctx context.Context, userID int64, device entity.SessionDevice) (entity.RefreshToken, error) {
+	db := s.store.DB(ctx)
 	if db == nil {
 		return entity.RefreshToken{}, fmt.Errorf("database connection is nil")
 	}
 
 	createdRefreshToken := entity.RefreshToken{}
 	err := db.Transaction(func(tx *gorm.DB) error {
-		// Check if the refresh token already exists for the user
-		existingRefreshToken, err := s.repo.GetRefreshTokenByUserID(tx, userID)
-		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		// Lock the sessions of the user before counting them, no other session of the user is created until the end of the transaction
+		if err := s.repo.LockUserSessions(tx, userID); err != nil {
 			return err
 		}
 
-		// If the refresh token already exists, remove it
-		if !existingRefreshToken.Equals(&entity.RefreshToken{}) {
-			if _, err := s.repo.RemoveRefreshTokenByUserID(tx, userID); err != nil {
-				return err
+		existingRefreshTokens, err := s.repo.GetRefreshTokensByUserID(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		// Remove the expired sessions and the previous session of the device, they do not count against the limit
+		now := s.clock.Now()
+		active := make([]entity.RefreshToken, 0, len(existingRefreshTokens))
+		for _, token := range existingRefreshTokens {
+			if !now.Before(token.ExpiryDate) || (device.DeviceID != "" && token.DeviceID == device.DeviceID) {
+				if _, err := s.repo.RemoveRefreshToken(tx, token.Token); err != nil {
+					return err
+				}
+				continue
 			}
+			active = append(active, token)
 		}
 
-		// Create a new refresh token
-		tokenStr := uuid.New().String()
-		refreshToken := entity.RefreshToken{
-			Token:      tokenStr,
-			UserID:     userID,
-			ExpiryDate: GetRefreshTokenExpiration(time.Now()),
+		// Enforce the session limit, the sessions are sorted from the oldest
+		if excess := len(active) - s.policy.MaxSessions + 1; excess > 0 {
+			if s.policy.Mode == policyconfig.SessionLimitModeReject {
+				return fmt.Errorf("%w: user %d already has %d active sessions", ErrSessionLimitReached, userID, len(active))
+			}
+			for _, token := range active[:excess] {
+				if _, err := s.repo.RemoveRefreshToken(tx, token.Token); err != nil {
+					return err
+				}
+			}
 		}
 
-		// Create the refresh token in the database
+		refreshToken := s.newRefreshToken(userID, now)
+		refreshToken.SessionID = uuid.New().String()
+		refreshToken.DeviceID = device.DeviceID
+		refreshToken.ClientID = device.ClientID
+		refreshToken.UserAgent = device.UserAgent
+		refreshToken.IPAddress = device.IPAddress
+		refreshToken.SessionStartedAt = now
+
 		createdRefreshToken, err = s.repo.CreateRefreshToken(tx, refreshToken)
+		return err
+	})
+
+	if err != nil {
+		return entity.RefreshToken{}, err
+	}
+
+	return createdRefreshToken, nil
+}
+
+// RotateRefreshToken replaces the given refresh token of the user with a new one, in the same session on the same device.
+// It fails with gorm.ErrRecordNotFound if the token was already removed, e.g. by a concurrent refresh or an eviction.
+func (s *refreshTokenService) RotateRefreshToken(ctx context.Context, refreshToken entity.RefreshToken) (entity.RefreshToken, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.RefreshToken{}, fmt.Errorf("database connection is nil")
+	}
+
+	createdRefreshToken := entity.RefreshToken{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		removed, err := s.repo.RemoveRefreshToken(tx, refreshToken.Token)
 		if err != nil {
 			return err
 		}
+		if !removed {
+			return fmt.Errorf("refresh token was already used or revoked: %w", gorm.ErrRecordNotFound)
+		}
+
+		rotated := s.newRefreshToken(refreshToken.UserID, s.clock.Now())
+		rotated.SessionID = refreshToken.SessionID
+		rotated.DeviceID = refreshToken.DeviceID
+		rotated.ClientID = refreshToken.ClientID
+		rotated.UserAgent = refreshToken.UserAgent
+		rotated.IPAddress = refreshToken.IPAddress
+		rotated.SessionStartedAt = refreshToken.SessionStartedAt
 
-		return nil
+		createdRefreshToken, err = s.repo.CreateRefreshToken(tx, rotated)
+		return err
 	})
 
 	if err != nil {
@@ -131,16 +216,55 @@ func (s *refreshTokenService) CreateRefreshToken(userID int64) (entity.RefreshTo
 	return createdRefreshToken, nil
 }
 
-// GetRefreshTokenExpiration calculates the expiration date for the refresh token.
-// It retrieves the expiration hour from an environment variable and adds it to the current time.
-func GetRefreshTokenExpiration(now time.Time) time.Time {
-	expHour, err := strconv.Atoi(os.Getenv("JWT_REFRESH_TOKEN_EXPIRATION_HOUR"))
+// GetSessions retrieves the active sessions of the user, oldest first. The expired sessions are not listed.
+func (s *refreshTokenService) GetSessions(ctx context.Context, userID int64) ([]entity.Session, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	refreshTokens, err := s.repo.GetRefreshTokensByUserID(db, userID)
 	if err != nil {
-		return now.Add(24 * time.Hour) // Default to 24 hours if the environment variable is not set or invalid
+		return nil, err
 	}
-	if expHour <= 0 {
-		expHour = 24
+
+	now := s.clock.Now()
+	sessions := make([]entity.Session, 0, len(refreshTokens))
+	for _, token := range refreshTokens {
+		if !now.Before(token.ExpiryDate) {
+			continue
+		}
+		sessions = append(sessions, token.ToSession())
 	}
 
-	return now.Add(time.Hour * time.Duration(expHour))
+	return sessions, nil
+}
+
+// RemoveSession ends the given session of the user by removing its refresh token.
+// It fails with gorm.ErrRecordNotFound if the user has no such session.
+func (s *refreshTokenService) RemoveSession(ctx context.Context, userID int64, sessionID string) error {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	removed, err := s.repo.RemoveRefreshTokenBySessionID(db, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("session %s of user %d not found: %w", sessionID, userID, gorm.ErrRecordNotFound)
+	}
+
+	return nil
+}
+
+// newRefreshToken returns a new refresh token for the user, created at the given time.
+func (s *refreshTokenService) newRefreshToken(userID int64, now time.Time) entity.RefreshToken {
+	return entity.RefreshToken{
+		Token:      uuid.New().String(),
+		UserID:     userID,
+		ExpiryDate: now.Add(s.policy.RefreshTokenTTL),
+		CreatedAt:  now,
+	}
 }
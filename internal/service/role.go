@@ -1,35 +1,85 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
 	"github.com/yoanesber/go-jwt-auth-demo/config/database"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
 )
 
+//go:generate go tool mockgen -source=role.go -destination=../../tests/mocks/role-service.go -package=mocks
+
 // Interface for role service
 // This interface defines the methods that the role service should implement
 type RoleService interface {
-	GetRoleByID(id uint) (entity.Role, error)
-	GetRoleByName(name string) (entity.Role, error)
+	GetRoleByID(ctx context.Context, id uint) (entity.Role, error)
+	GetRoleByName(ctx context.Context, name string) (entity.Role, error)
+	GetRoles(ctx context.Context) ([]entity.Role, error)
+	CreateRole(ctx context.Context, req entity.RoleRequest) (entity.Role, error)
+	UpdateRole(ctx context.Context, id uint, req entity.RoleRequest) (entity.Role, error)
+	DeleteRole(ctx context.Context, id uint) error
+	GetPermissions(ctx context.Context) ([]entity.Permission, error)
+	GetRolePermissions(ctx context.Context, id uint) ([]entity.Permission, error)
+	SetRolePermissions(ctx context.Context, id uint, req entity.RolePermissionsRequest) ([]entity.Permission, error)
+	GetScopes(roleNames []string) ([]string, error)
 }
 
-// This struct defines the RoleService that contains a repository field of type RoleRepository
+var (
+	// ErrRoleAlreadyExists is returned when a role is created or renamed with the name of another role.
+	ErrRoleAlreadyExists = errors.New("role already exists")
+
+	// ErrBuiltInRole is returned when a built-in role is renamed or deleted.
+	ErrBuiltInRole = errors.New("built-in roles cannot be renamed or deleted")
+
+	// ErrRoleInUse is returned when a role still assigned to users is deleted.
+	ErrRoleInUse = errors.New("role is assigned to users")
+
+	// ErrUnknownPermission is returned when a role is granted a permission that does not exist.
+	ErrUnknownPermission = errors.New("unknown permission")
+)
+
+// This struct defines the RoleService that contains a repository field of type RoleRepository,
+// the repository of the permissions granted to the roles, and the invalidator of the cached roles of the users, used when a role is renamed
 // It implements the RoleService interface and provides methods for role-related operations
 type roleService struct {
-	repo repository.RoleRepository
+	store          database.DataStore
+	repo           repository.RoleRepository
+	permissionRepo repository.PermissionRepository
+	invalidator    repository.RoleCacheInvalidator
 }
 
-// NewRoleService creates a new instance of RoleService with the given repository.
+// RoleServiceOption configures the role service.
+type RoleServiceOption func(*roleService)
+
+// WithRoleCacheInvalidator drops the cached roles of the users with the given invalidator when a role is renamed,
+// so that the next logins issue tokens with the new name.
+func WithRoleCacheInvalidator(invalidator repository.RoleCacheInvalidator) RoleServiceOption {
+	return func(s *roleService) {
+		s.invalidator = invalidator
+	}
+}
+
+// NewRoleService creates a new instance of RoleService with the given data store and repositories.
 // It initializes the roleService struct and returns it.
-func NewRoleService(repo repository.RoleRepository) RoleService {
-	return &roleService{repo: repo}
+func NewRoleService(store database.DataStore, repo repository.RoleRepository, permissionRepo repository.PermissionRepository, opts ...RoleServiceOption) RoleService {
+	s := &roleService{store: store, repo: repo, permissionRepo: permissionRepo}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 // GetRoleByID retrieves a role by its ID from the database.
-func (s *roleService) GetRoleByID(id uint) (entity.Role, error) {
-	db := database.GetPostgres()
+func (s *roleService) GetRoleByID(ctx context.Context, id uint) (entity.Role, error) {
+	db := s.store.DB(ctx)
 	if db == nil {
 		return entity.Role{}, fmt.Errorf("database connection is nil")
 	}
@@ -44,8 +94,8 @@ func (s *roleService) GetRoleByID(id uint) (entity.Role, error) {
 }
 
 // GetRoleByName retrieves a role by its name from the database.
-func (s *roleService) GetRoleByName(name string) (entity.Role, error) {
-	db := database.GetPostgres()
+func (s *roleService) GetRoleByName(ctx context.Context, name string) (entity.Role, error) {
+	db := s.store.DB(ctx)
 	if db == nil {
 		return entity.Role{}, fmt.Errorf("database connection is nil")
 	}
@@ -58,3 +108,252 @@ func (s *roleService) GetRoleByName(name string) (entity.Role, error) {
 
 	return role, nil
 }
+
+// GetRoles retrieves all the roles, the built-in and the custom ones, ordered by ID.
+func (s *roleService) GetRoles(ctx context.Context) ([]entity.Role, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	roles, err := s.repo.GetRoles(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// CreateRole creates a custom role. It fails with ErrRoleAlreadyExists if the name is taken,
+// the names being compared case-insensitively.
+func (s *roleService) CreateRole(ctx context.Context, req entity.RoleRequest) (entity.Role, error) {
+	// Validate the request using the validator
+	if err := req.Validate(); err != nil {
+		return entity.Role{}, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.Role{}, fmt.Errorf("database connection is nil")
+	}
+
+	createdRole := entity.Role{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := s.checkNameAvailable(tx, req.Name, 0); err != nil {
+			return err
+		}
+
+		var err error
+		createdRole, err = s.repo.CreateRole(tx, entity.Role{Name: req.Name, Description: req.Description})
+		return err
+	})
+	if err != nil {
+		return entity.Role{}, err
+	}
+
+	logger.Info("Created role", logrus.Fields{"role_id": createdRole.ID, "role_name": createdRole.Name})
+
+	return createdRole, nil
+}
+
+// UpdateRole renames a custom role and replaces its description; the built-in roles can only get a new description.
+// It fails with ErrBuiltInRole if a built-in role is renamed, and with ErrRoleAlreadyExists if the name is taken.
+// The users keep the renamed role, their cached roles are dropped so that their next tokens carry the new name.
+func (s *roleService) UpdateRole(ctx context.Context, id uint, req entity.RoleRequest) (entity.Role, error) {
+	// Validate the request using the validator
+	if err := req.Validate(); err != nil {
+		return entity.Role{}, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.Role{}, fmt.Errorf("database connection is nil")
+	}
+
+	renamed := false
+	updatedRole := entity.Role{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		role, err := s.repo.GetRoleByID(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if role.Name != req.Name {
+			if entity.IsBuiltInRole(role.Name) {
+				return fmt.Errorf("%w: %s", ErrBuiltInRole, role.Name)
+			}
+			if err := s.checkNameAvailable(tx, req.Name, id); err != nil {
+				return err
+			}
+			renamed = true
+		}
+
+		role.Name = req.Name
+		role.Description = req.Description
+		updatedRole, err = s.repo.UpdateRole(tx, role)
+		return err
+	})
+	if err != nil {
+		return entity.Role{}, err
+	}
+
+	if renamed && s.invalidator != nil {
+		s.invalidator.InvalidateRoleCache()
+	}
+
+	logger.Info("Updated role", logrus.Fields{"role_id": id, "role_name": updatedRole.Name})
+
+	return updatedRole, nil
+}
+
+// DeleteRole deletes a custom role. It fails with ErrBuiltInRole for the built-in roles,
+// and with ErrRoleInUse if the role is still assigned to users, including the deleted ones.
+func (s *roleService) DeleteRole(ctx context.Context, id uint) error {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		role, err := s.repo.GetRoleByID(tx, id)
+		if err != nil {
+			return err
+		}
+		if entity.IsBuiltInRole(role.Name) {
+			return fmt.Errorf("%w: %s", ErrBuiltInRole, role.Name)
+		}
+
+		users, err := s.repo.CountRoleUsers(tx, id)
+		if err != nil {
+			return err
+		}
+		if users > 0 {
+			return fmt.Errorf("%w: %s is assigned to %d users", ErrRoleInUse, role.Name, users)
+		}
+
+		return s.repo.DeleteRole(tx, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Deleted role", logrus.Fields{"role_id": id})
+
+	return nil
+}
+
+// GetPermissions retrieves all the permissions that can be granted to the roles, ordered by ID.
+func (s *roleService) GetPermissions(ctx context.Context) ([]entity.Permission, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	permissions, err := s.permissionRepo.GetPermissions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// GetRolePermissions retrieves the permissions granted to the role with the given ID, ordered by ID.
+// It fails with gorm.ErrRecordNotFound if the role does not exist.
+func (s *roleService) GetRolePermissions(ctx context.Context, id uint) ([]entity.Permission, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	if _, err := s.repo.GetRoleByID(db, id); err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.permissionRepo.GetRolePermissions(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// SetRolePermissions replaces the permissions granted to the role with the given ID, and returns them ordered by ID.
+// It fails with ErrUnknownPermission if one of the permissions does not exist.
+// The tokens already issued keep their scopes, the next ones carry the new permissions.
+func (s *roleService) SetRolePermissions(ctx context.Context, id uint, req entity.RolePermissionsRequest) ([]entity.Permission, error) {
+	// Validate the request using the validator
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	var permissions []entity.Permission
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.repo.GetRoleByID(tx, id); err != nil {
+			return err
+		}
+
+		var err error
+		permissions, err = s.permissionRepo.GetPermissionsByNames(tx, req.Permissions)
+		if err != nil {
+			return err
+		}
+
+		found := make(map[string]bool, len(permissions))
+		ids := make([]uint, 0, len(permissions))
+		for _, p := range permissions {
+			found[p.Name] = true
+			ids = append(ids, p.ID)
+		}
+		for _, name := range req.Permissions {
+			if !found[name] {
+				return fmt.Errorf("%w: %s", ErrUnknownPermission, name)
+			}
+		}
+
+		return s.permissionRepo.ReplaceRolePermissions(tx, id, ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Replaced role permissions", logrus.Fields{"role_id": id, "permissions": req.Permissions})
+
+	return permissions, nil
+}
+
+// GetScopes retrieves the names of the permissions granted to any of the roles with the given names, sorted by name.
+// It is used to set the scopes claim of the access tokens.
+func (s *roleService) GetScopes(roleNames []string) ([]string, error) {
+	db := s.store.DB(context.Background())
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	scopes, err := s.permissionRepo.GetPermissionNamesByRoleNames(db, roleNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return scopes, nil
+}
+
+// checkNameAvailable fails with ErrRoleAlreadyExists if the name is used by a role other than the given one.
+func (s *roleService) checkNameAvailable(tx *gorm.DB, name string, roleID uint) error {
+	existing, err := s.repo.GetRoleByName(tx, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check existing role by name: %w", err)
+	}
+	if existing.ID != roleID {
+		return fmt.Errorf("%w: %s", ErrRoleAlreadyExists, name)
+	}
+
+	return nil
+}
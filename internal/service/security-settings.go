@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/headers"
+)
+
+//go:generate go tool mockgen -source=security-settings.go -destination=../../tests/mocks/security-settings-service.go -package=mocks
+
+// ErrInvalidSecuritySettings is returned when an origin or a security header of the settings is not accepted.
+var ErrInvalidSecuritySettings = errors.New("invalid security settings")
+
+// Interface for security settings service
+// This interface defines the methods that the security settings service should implement
+type SecuritySettingsService interface {
+	GetSecuritySettings(ctx context.Context) (entity.SecuritySettings, error)
+	UpdateSecuritySettings(ctx context.Context, userID int64, req entity.SecuritySettingsRequest) (entity.SecuritySettings, error)
+	LoadOverrides() error
+	Overrides() *headers.OverridesCache
+}
+
+// This struct defines the SecuritySettingsService that contains a repository field of type SecuritySettingsRepository,
+// the cache of the overrides applied by the CORS and security headers middlewares, and a clock used to get the current time
+// It implements the SecuritySettingsService interface and provides methods for security settings-related operations
+type securitySettingsService struct {
+	store database.DataStore
+	repo  repository.SecuritySettingsRepository
+	cache *headers.OverridesCache
+	clock clock.Clock
+}
+
+// NewSecuritySettingsService creates a new instance of SecuritySettingsService with the given data store and repository.
+// The overrides of the settings are cached for the given TTL, see headers.NewOverridesCache.
+func NewSecuritySettingsService(store database.DataStore, repo repository.SecuritySettingsRepository, ttl time.Duration, clk clock.Clock) SecuritySettingsService {
+	s := &securitySettingsService{store: store, repo: repo, clock: clk}
+	s.cache = headers.NewOverridesCache(s.loadOverrides, ttl, clk)
+
+	return s
+}
+
+// GetSecuritySettings retrieves the security settings from the database.
+// A database without settings gets empty ones.
+func (s *securitySettingsService) GetSecuritySettings(ctx context.Context) (entity.SecuritySettings, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.SecuritySettings{}, fmt.Errorf("database connection is nil")
+	}
+
+	return s.getSecuritySettings(db)
+}
+
+// UpdateSecuritySettings replaces the security settings in the database, and reloads the overrides of the instance.
+// It fails with ErrInvalidSecuritySettings if an origin is not an HTTP or HTTPS origin, or a security header cannot be overridden.
+// The other instances apply the new settings once their cached ones are older than the TTL.
+func (s *securitySettingsService) UpdateSecuritySettings(ctx context.Context, userID int64, req entity.SecuritySettingsRequest) (entity.SecuritySettings, error) {
+	// Validate the request using the validator
+	if err := req.Validate(); err != nil {
+		return entity.SecuritySettings{}, err
+	}
+
+	settings, err := s.normalizeSecuritySettings(req)
+	if err != nil {
+		return entity.SecuritySettings{}, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.SecuritySettings{}, fmt.Errorf("database connection is nil")
+	}
+
+	now := s.clock.Now()
+	settings.UpdatedBy = &userID
+	settings.UpdatedAt = &now
+	savedSettings, err := s.repo.SaveSecuritySettings(db, settings)
+	if err != nil {
+		return entity.SecuritySettings{}, err
+	}
+
+	s.cache.Invalidate()
+
+	logger.Info("Updated security settings", logrus.Fields{
+		"user_id":          userID,
+		"allowed_origins":  savedSettings.AllowedOrigins,
+		"allowed_headers":  savedSettings.AllowedHeaders,
+		"security_headers": len(savedSettings.SecurityHeaders),
+	})
+
+	return savedSettings, nil
+}
+
+// LoadOverrides loads the overrides of the security settings into the cache. It is run on Startup,
+// the middlewares apply no overrides before.
+func (s *securitySettingsService) LoadOverrides() error {
+	return s.cache.Load()
+}
+
+// Overrides returns the cache of the overrides, applied by the CORS and security headers middlewares.
+func (s *securitySettingsService) Overrides() *headers.OverridesCache {
+	return s.cache
+}
+
+// loadOverrides loads the overrides of the security settings from the database, for the cache.
+func (s *securitySettingsService) loadOverrides() (headers.Overrides, error) {
+	settings, err := s.GetSecuritySettings(context.Background())
+	if err != nil {
+		return headers.Overrides{}, err
+	}
+
+	return headers.Overrides{
+		AllowedOrigins:  settings.AllowedOrigins,
+		AllowedHeaders:  settings.AllowedHeaders,
+		SecurityHeaders: settings.SecurityHeaders,
+	}, nil
+}
+
+// getSecuritySettings retrieves the security settings with the given transaction, or empty ones if there are none.
+func (s *securitySettingsService) getSecuritySettings(tx *gorm.DB) (entity.SecuritySettings, error) {
+	settings, err := s.repo.GetSecuritySettings(tx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.SecuritySettings{AllowedOrigins: []string{}, AllowedHeaders: []string{}, SecurityHeaders: map[string]string{}}, nil
+	}
+	if err != nil {
+		return entity.SecuritySettings{}, err
+	}
+
+	return settings, nil
+}
+
+// normalizeSecuritySettings checks the origins and the security headers of the request, and returns the settings
+// with the header names in their canonical form, without duplicates.
+func (s *securitySettingsService) normalizeSecuritySettings(req entity.SecuritySettingsRequest) (entity.SecuritySettings, error) {
+	settings := entity.SecuritySettings{AllowedOrigins: []string{}, AllowedHeaders: []string{}, SecurityHeaders: map[string]string{}}
+
+	seen := make(map[string]bool)
+	for _, origin := range req.AllowedOrigins {
+		if err := headers.ValidateOrigin(origin); err != nil {
+			return entity.SecuritySettings{}, fmt.Errorf("%w: %v", ErrInvalidSecuritySettings, err)
+		}
+		if !seen[origin] {
+			seen[origin] = true
+			settings.AllowedOrigins = append(settings.AllowedOrigins, origin)
+		}
+	}
+
+	for _, name := range req.AllowedHeaders {
+		name = http.CanonicalHeaderKey(name)
+		if !seen[name] {
+			seen[name] = true
+			settings.AllowedHeaders = append(settings.AllowedHeaders, name)
+		}
+	}
+
+	for name, value := range req.SecurityHeaders {
+		if !headers.IsOverridableSecurityHeader(name) {
+			return entity.SecuritySettings{}, fmt.Errorf("%w: %s cannot be overridden", ErrInvalidSecuritySettings, name)
+		}
+		settings.SecurityHeaders[http.CanonicalHeaderKey(name)] = value
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	jwtconfig "github.com/yoanesber/go-jwt-auth-demo/config/jwt-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	jwtutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
+)
+
+//go:generate go tool mockgen -source=signing-key.go -destination=../../tests/mocks/signing-key-service.go -package=mocks
+
+// Interface for signing key service
+// This interface defines the methods used to publish and rotate the keys signing the RS256 access tokens
+type SigningKeyService interface {
+	GetKeySet() (jwtutil.JSONWebKeySet, error)
+	RotateKey() (entity.SigningKeyRotation, error)
+	EnsureKey() error
+}
+
+// This struct defines the SigningKeyService that contains the JWT settings, whose longest token lifetime
+// is the time the retired keys keep verifying tokens, the interval of the automatic rotations,
+// and a clock used to get the current time
+// It implements the SigningKeyService interface and provides methods for signing key-related operations
+type signingKeyService struct {
+	config           jwtconfig.JWTConfig
+	rotationInterval time.Duration
+	clock            clock.Clock
+}
+
+// NewSigningKeyService creates a new instance of SigningKeyService with the given JWT settings, rotation interval, and clock.
+// A zero rotation interval disables the rotation at startup.
+func NewSigningKeyService(cfg jwtconfig.JWTConfig, rotationInterval time.Duration, clk clock.Clock) SigningKeyService {
+	return &signingKeyService{config: cfg, rotationInterval: rotationInterval, clock: clk}
+}
+
+// GetKeySet returns the public keys verifying the tokens: the current key and the retired keys that may still verify valid tokens.
+func (s *signingKeyService) GetKeySet() (jwtutil.JSONWebKeySet, error) {
+	return s.config.Keys.LoadJSONWebKeySet(s.config.TTL.Max(), s.clock.Now())
+}
+
+// RotateKey creates a new signing key, which signs the new tokens from now on.
+// The previous key keeps verifying the tokens it signed for the longest lifetime of the access tokens.
+// It returns jwtutil.ErrKeyRotationDisabled if the keys are not read from a key set directory.
+func (s *signingKeyService) RotateKey() (entity.SigningKeyRotation, error) {
+	now := s.clock.Now()
+
+	previous, err := s.config.Keys.LoadKeySet()
+	if err != nil && !errors.Is(err, jwtutil.ErrNoSigningKey) {
+		return entity.SigningKeyRotation{}, err
+	}
+
+	key, err := s.config.Keys.RotateKey(s.config.TTL.Max(), now)
+	if err != nil {
+		return entity.SigningKeyRotation{}, err
+	}
+
+	rotation := entity.SigningKeyRotation{KeyID: key.ID, CreatedAt: key.CreatedAt}
+	if previous.Current.ID != "" {
+		expiresAt := now.Add(s.config.TTL.Max())
+		rotation.PreviousKeyID = previous.Current.ID
+		rotation.PreviousKeyExpiresAt = &expiresAt
+	}
+
+	logger.Info("JWT signing key rotated", logrus.Fields{"kid": rotation.KeyID, "previous_kid": rotation.PreviousKeyID})
+	return rotation, nil
+}
+
+// EnsureKey creates the first signing key of an empty key set directory, and rotates the current key
+// if it is older than the rotation interval. It does nothing if the keys are not read from a key set directory.
+func (s *signingKeyService) EnsureKey() error {
+	if s.config.Keys.KeySetDir == "" {
+		return nil
+	}
+
+	keySet, err := s.config.Keys.LoadKeySet()
+	switch {
+	case errors.Is(err, jwtutil.ErrNoSigningKey):
+		// The directory has no key yet, the first one is created
+	case err != nil:
+		return fmt.Errorf("failed to load the JWT key set: %w", err)
+	case s.rotationInterval <= 0 || s.clock.Now().Sub(keySet.Current.CreatedAt) < s.rotationInterval:
+		return nil
+	}
+
+	_, err = s.RotateKey()
+	return err
+}
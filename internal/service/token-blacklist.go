@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/cache"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+)
+
+//go:generate go tool mockgen -source=token-blacklist.go -destination=../../tests/mocks/token-blacklist-service.go -package=mocks
+
+// DefaultTokenBlacklistTimeout is the time Redis has to answer the check of a token, so that a slow Redis
+// does not delay every request. The requests are then served with the denylist of the process only.
+const DefaultTokenBlacklistTimeout = 100 * time.Millisecond
+
+// Interface for token blacklist service
+// This interface defines the methods that the token blacklist service should implement
+type TokenBlacklistService interface {
+	BlacklistToken(tokenID string, expiresAt time.Time) error
+	BlacklistUserTokens(userID int64, version int64) error
+	IsRevoked(ctx context.Context, userID int64, tokenVersion int64, tokenID string) (bool, error)
+}
+
+// This struct defines the TokenBlacklistService that shares the revocations of the access tokens between the instances in Redis,
+// the prefix of its keys, the longest lifetime of the access tokens, and a clock used to get the current time
+// It implements the TokenBlacklistService interface, and the revocation.Blacklist checked by the JWT validation middleware
+type tokenBlacklistService struct {
+	prefix      string
+	maxTokenTTL time.Duration
+	clock       clock.Clock
+}
+
+// NewTokenBlacklistService creates a new instance of TokenBlacklistService storing its keys with the given prefix
+// in the shared Redis client. The token versions of the users are kept for the longest lifetime of the access tokens,
+// the tokens issued before a revocation being all expired by then.
+func NewTokenBlacklistService(prefix string, maxTokenTTL time.Duration, clk clock.Clock) TokenBlacklistService {
+	return &tokenBlacklistService{prefix: prefix, maxTokenTTL: maxTokenTTL, clock: clk}
+}
+
+// BlacklistToken rejects the access token with the given identifier until it expires at the given time.
+// An expired token is not stored, it is rejected by the JWT validation anyway.
+func (s *tokenBlacklistService) BlacklistToken(tokenID string, expiresAt time.Time) error {
+	client := cache.GetRedis()
+	if client == nil {
+		return fmt.Errorf("redis connection is nil")
+	}
+
+	ttl := expiresAt.Sub(s.clock.Now())
+	if tokenID == "" || ttl <= 0 {
+		return nil
+	}
+
+	return client.Set(context.Background(), s.tokenKey(tokenID), 1, ttl).Err()
+}
+
+// BlacklistUserTokens rejects the access tokens of the user carrying a version older than the given one.
+// A version older than the one already stored is ignored, so that concurrent revocations keep the newest version.
+func (s *tokenBlacklistService) BlacklistUserTokens(userID int64, version int64) error {
+	client := cache.GetRedis()
+	if client == nil {
+		return fmt.Errorf("redis connection is nil")
+	}
+
+	return client.Eval(context.Background(), setMaxVersionScript, []string{s.userKey(userID)}, version, s.maxTokenTTL.Milliseconds()).Err()
+}
+
+// IsRevoked reports whether the access token was revoked by any instance, on its own or with all the tokens of its user.
+// Both revocations are checked with a single round trip to Redis, bounded by DefaultTokenBlacklistTimeout.
+func (s *tokenBlacklistService) IsRevoked(ctx context.Context, userID int64, tokenVersion int64, tokenID string) (bool, error) {
+	client := cache.GetRedis()
+	if client == nil {
+		return false, fmt.Errorf("redis connection is nil")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultTokenBlacklistTimeout)
+	defer cancel()
+
+	// Tokens without an identifier were issued before the logout and cannot be revoked on their own
+	keys := []string{s.userKey(userID)}
+	if tokenID != "" {
+		keys = append(keys, s.tokenKey(tokenID))
+	}
+
+	values, err := client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if len(values) > 1 && values[1] != nil {
+		return true, nil
+	}
+	if version, ok := values[0].(string); ok {
+		revokedVersion, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return false, errors.New("invalid token version in the blacklist")
+		}
+		return tokenVersion < revokedVersion, nil
+	}
+
+	return false, nil
+}
+
+// tokenKey returns the key of a revoked access token.
+func (s *tokenBlacklistService) tokenKey(tokenID string) string {
+	return s.prefix + "revoked:token:" + tokenID
+}
+
+// userKey returns the key of the current token version of a user whose tokens were revoked.
+func (s *tokenBlacklistService) userKey(userID int64) string {
+	return s.prefix + "revoked:user:" + strconv.FormatInt(userID, 10)
+}
+
+// setMaxVersionScript stores the version of KEYS[1] if it is newer than the stored one, with the TTL of ARGV[2] in milliseconds.
+var setMaxVersionScript = `
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local version = tonumber(ARGV[1])
+if version > current then
+	redis.call('SET', KEYS[1], version, 'PX', ARGV[2])
+end
+return 1
+`
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	jwtconfig "github.com/yoanesber/go-jwt-auth-demo/config/jwt-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	jwtutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
+)
+
+//go:generate go tool mockgen -source=token-issuer.go -destination=../../tests/mocks/token-issuer.go -package=mocks
+
+// Interface for token issuer
+// This interface defines the methods used to issue access tokens for authenticated users
+type TokenIssuer interface {
+	IssueToken(user entity.User, clientID string, issuedAt time.Time) (IssuedToken, error)
+	IssueScopedToken(user entity.User, clientID string, scopes []string, issuedAt time.Time) (IssuedToken, error)
+	TokenType() string
+	ParseUserID(accessToken string) (int64, error)
+}
+
+// IssuedToken represents an access token issued for a user along with its expiration date.
+type IssuedToken struct {
+	AccessToken string
+	ExpiresAt   time.Time
+}
+
+// Interface for scope resolver
+// This interface defines the method used to resolve the permissions granted to the roles of a user
+type ScopeResolver interface {
+	GetScopes(roleNames []string) ([]string, error)
+}
+
+// This struct defines the TokenIssuer that signs JWT tokens using the configured signing method,
+// and the resolver of the scopes carried by the tokens, if any
+// It implements the TokenIssuer interface
+type jwtTokenIssuer struct {
+	config jwtconfig.JWTConfig
+	scopes ScopeResolver
+}
+
+// JWTTokenIssuerOption configures the JWT token issuer.
+type JWTTokenIssuerOption func(*jwtTokenIssuer)
+
+// WithScopeResolver sets the scopes claim of the tokens to the permissions granted to the roles of the user,
+// as resolved by the given resolver, so that the routes can require them with authorization.RequireScope.
+func WithScopeResolver(resolver ScopeResolver) JWTTokenIssuerOption {
+	return func(i *jwtTokenIssuer) {
+		i.scopes = resolver
+	}
+}
+
+// NewJWTTokenIssuer creates a new instance of TokenIssuer that issues JWT tokens with the given settings.
+func NewJWTTokenIssuer(cfg jwtconfig.JWTConfig, opts ...JWTTokenIssuerOption) TokenIssuer {
+	i := &jwtTokenIssuer{config: cfg}
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+}
+
+// IssueToken generates a JWT token for the user logging in with the given client and reads back its expiration date.
+// The lifetime of the token is resolved by the TTL policy of the settings.
+func (i *jwtTokenIssuer) IssueToken(user entity.User, clientID string, issuedAt time.Time) (IssuedToken, error) {
+	// Resolve the permissions granted to the roles of the user
+	if i.scopes != nil {
+		scopes, err := i.scopes.GetScopes(ExtractRoleNames(user.Roles))
+		if err != nil {
+			return IssuedToken{}, fmt.Errorf("failed to resolve scopes: %w", err)
+		}
+		user.Scopes = scopes
+	}
+
+	return i.issue(user, clientID, issuedAt)
+}
+
+// IssueScopedToken generates a JWT token for the user and the given client, carrying the given scopes
+// instead of the permissions granted to the roles of the user, e.g. the scopes requested by a client of a service account.
+func (i *jwtTokenIssuer) IssueScopedToken(user entity.User, clientID string, scopes []string, issuedAt time.Time) (IssuedToken, error) {
+	user.Scopes = scopes
+
+	return i.issue(user, clientID, issuedAt)
+}
+
+// issue generates a JWT token for the user with its scopes, and reads back its expiration date.
+func (i *jwtTokenIssuer) issue(user entity.User, clientID string, issuedAt time.Time) (IssuedToken, error) {
+	// Generate an access token for the user
+	tokenStr, err := GenerateJWTToken(i.config, user, clientID, issuedAt)
+	if err != nil {
+		return IssuedToken{}, fmt.Errorf("failed to generate JWT token: %w", err)
+	}
+
+	// Parse the JWT token
+	jwtToken, err := ParseJWTToken(i.config, tokenStr, issuedAt)
+	if err != nil {
+		return IssuedToken{}, fmt.Errorf("failed to parse JWT token: %w", err)
+	}
+
+	// Get the expiration date from the token
+	exp, err := jwtToken.Claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return IssuedToken{}, fmt.Errorf("failed to get expiration date from token")
+	}
+
+	return IssuedToken{AccessToken: tokenStr, ExpiresAt: exp.Time}, nil
+}
+
+// ParseUserID verifies the signature of an access token issued by the issuer and returns the ID of its user.
+// The token is not rejected when it is expired, so that it can be presented along with the refresh token.
+func (i *jwtTokenIssuer) ParseUserID(accessToken string) (int64, error) {
+	jwtToken, err := ParseExpiredJWTToken(i.config, accessToken)
+	if err != nil {
+		return 0, err
+	}
+
+	claims, ok := jwtToken.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, fmt.Errorf("failed to extract claims from token")
+	}
+
+	userID, err := jwtutil.GetInt64Claim(claims, "userid")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user ID from token: %w", err)
+	}
+
+	return userID, nil
+}
+
+// TokenType returns the type of the issued tokens, e.g. "Bearer".
+func (i *jwtTokenIssuer) TokenType() string {
+	return i.config.TokenType
+}
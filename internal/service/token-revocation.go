@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/revocation"
+)
+
+//go:generate go tool mockgen -source=token-revocation.go -destination=../../tests/mocks/token-revocation-service.go -package=mocks
+
+// Interface for token revocation service
+// This interface defines the methods that the token revocation service should implement
+type TokenRevocationService interface {
+	RevokeUserTokens(ctx context.Context, userID int64) (entity.TokenRevocation, error)
+	RevokeSession(ctx context.Context, req entity.LogoutRequest) error
+	LoadRevocations() error
+}
+
+// This struct defines the TokenRevocationService that contains the user, refresh token, and revoked token repositories,
+// the denylist checked by the JWT validation middleware, the blacklist sharing the revocations with the other instances,
+// the recorder of the token usage, and a clock used to get the current time
+// It implements the TokenRevocationService interface and provides methods for token revocation-related operations
+type tokenRevocationService struct {
+	store            database.DataStore
+	userRepo         repository.UserRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	revokedTokenRepo repository.RevokedTokenRepository
+	denylist         *revocation.Denylist
+	blacklist        TokenBlacklistService
+	tokenUsage       TokenUsageRecorder
+	clock            clock.Clock
+}
+
+// TokenRevocationServiceOption configures the token revocation service.
+type TokenRevocationServiceOption func(*tokenRevocationService)
+
+// WithTokenBlacklist shares the revocations with the other instances through the given blacklist,
+// so that they reject the revoked tokens at once instead of after their restart.
+func WithTokenBlacklist(blacklist TokenBlacklistService) TokenRevocationServiceOption {
+	return func(s *tokenRevocationService) {
+		s.blacklist = blacklist
+	}
+}
+
+// NewTokenRevocationService creates a new instance of TokenRevocationService with the given dependencies.
+// It initializes the tokenRevocationService struct and returns it.
+func NewTokenRevocationService(store database.DataStore, userRepo repository.UserRepository, refreshTokenRepo repository.RefreshTokenRepository, revokedTokenRepo repository.RevokedTokenRepository, denylist *revocation.Denylist, tokenUsage TokenUsageRecorder, clk clock.Clock, opts ...TokenRevocationServiceOption) TokenRevocationService {
+	s := &tokenRevocationService{
+		store:            store,
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		revokedTokenRepo: revokedTokenRepo,
+		denylist:         denylist,
+		tokenUsage:       tokenUsage,
+		clock:            clk,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// RevokeUserTokens revokes all the tokens of the user, e.g. when the account is compromised.
+// The token version of the user is bumped and the refresh tokens are deleted in a single transaction,
+// then the access tokens issued before are rejected by the denylist, and by the blacklist of the other instances if any.
+func (s *tokenRevocationService) RevokeUserTokens(ctx context.Context, userID int64) (entity.TokenRevocation, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.TokenRevocation{}, fmt.Errorf("database connection is nil")
+	}
+
+	var version int64
+	err := database.Transaction(db, func(tx *gorm.DB) error {
+		var err error
+		if version, err = s.userRepo.IncrementTokenVersion(context.Background(), tx, userID); err != nil {
+			return err
+		}
+
+		_, err = s.refreshTokenRepo.RemoveRefreshTokenByUserID(tx, userID)
+		return err
+	})
+	if err != nil {
+		return entity.TokenRevocation{}, err
+	}
+
+	now := s.clock.Now()
+	s.denylist.Revoke(userID, version)
+	if s.blacklist != nil {
+		if err := s.blacklist.BlacklistUserTokens(userID, version); err != nil {
+			logger.Error(fmt.Sprintf("Failed to share the revocation of the tokens of the user, the other instances apply it on restart: %v", err), logrus.Fields{
+				"user_id": userID,
+			})
+		}
+	}
+	s.tokenUsage.Record(TokenEventRevoked, userID, "", now)
+
+	logger.Info("Revoked all the tokens of the user", logrus.Fields{
+		"user_id":       userID,
+		"token_version": version,
+	})
+
+	return entity.TokenRevocation{UserID: userID, TokenVersion: version, RevokedAt: now}, nil
+}
+
+// RevokeSession ends a single session of the user, e.g. on logout.
+// The refresh token is deleted and the access token is recorded as revoked in a single transaction,
+// then the access token is rejected by the denylist, and by the blacklist of the other instances if any, until it expires.
+// A refresh token already deleted is ignored, so that logging out twice succeeds,
+// but a refresh token of another user is rejected with ErrTokenSubjectMismatch.
+func (s *tokenRevocationService) RevokeSession(ctx context.Context, req entity.LogoutRequest) error {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		refreshToken, err := s.refreshTokenRepo.GetRefreshTokenByToken(tx, req.RefreshToken)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			if refreshToken.UserID != req.UserID {
+				return ErrTokenSubjectMismatch
+			}
+			if _, err := s.refreshTokenRepo.RemoveRefreshToken(tx, req.RefreshToken); err != nil {
+				return err
+			}
+		}
+
+		// The access tokens issued before the logout have no identifier and expire on their own
+		if req.TokenID == "" {
+			return nil
+		}
+
+		return s.revokedTokenRepo.CreateRevokedToken(tx, entity.RevokedToken{
+			TokenID:   req.TokenID,
+			UserID:    req.UserID,
+			ExpiresAt: req.TokenExpiresAt,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	now := s.clock.Now()
+	if req.TokenID != "" {
+		s.denylist.RevokeToken(req.TokenID, req.TokenExpiresAt)
+		if s.blacklist != nil {
+			if err := s.blacklist.BlacklistToken(req.TokenID, req.TokenExpiresAt); err != nil {
+				logger.Error(fmt.Sprintf("Failed to share the revocation of the access token, the other instances apply it on restart: %v", err), logrus.Fields{
+					"user_id":  req.UserID,
+					"token_id": req.TokenID,
+				})
+			}
+		}
+	}
+	s.denylist.PruneTokens(now)
+	s.tokenUsage.Record(TokenEventRevoked, req.UserID, "", now)
+
+	logger.Info("Revoked the session of the user", logrus.Fields{
+		"user_id":  req.UserID,
+		"token_id": req.TokenID,
+	})
+
+	return nil
+}
+
+// LoadRevocations loads the token versions of the users whose tokens were revoked,
+// and the access tokens revoked on their own and not expired yet, into the denylist.
+// The revoked access tokens already expired are removed from the database.
+// It is called at startup, so that the revocations survive a restart.
+func (s *tokenRevocationService) LoadRevocations() error {
+	db := s.store.DB(context.Background())
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	versions, err := s.userRepo.GetRevokedTokenVersions(context.Background(), db)
+	if err != nil {
+		return err
+	}
+	s.denylist.Load(versions)
+
+	now := s.clock.Now()
+	revokedTokens, err := s.revokedTokenRepo.GetRevokedTokens(db, now)
+	if err != nil {
+		return err
+	}
+
+	tokens := make(map[string]time.Time, len(revokedTokens))
+	for _, t := range revokedTokens {
+		tokens[t.TokenID] = t.ExpiresAt
+	}
+	s.denylist.LoadTokens(tokens)
+
+	if _, err := s.revokedTokenRepo.RemoveExpiredRevokedTokens(db, now); err != nil {
+		return err
+	}
+
+	return nil
+}
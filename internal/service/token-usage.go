@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+//go:generate go tool mockgen -source=token-usage.go -destination=../../tests/mocks/token-usage-service.go -package=mocks
+
+/**
+ * Token usage analytics count the tokens issued, refreshed, and revoked per user, per client, and per day
+ * in the token_usage table, for capacity planning and abuse detection. The auth service records the events
+ * with the asynchronous recorder, which aggregates them in memory and adds the counters to the table
+ * in batches, so a login only pays for a map update. The client is the X-Client-ID header of the request.
+ */
+
+const (
+	// DefaultTokenUsageFlushInterval is how often the pending token usage counters are written.
+	DefaultTokenUsageFlushInterval = 5 * time.Second
+
+	// DefaultTokenUsageMaxPending is the maximum number of pending token usage rows.
+	DefaultTokenUsageMaxPending = 10000
+
+	// maxClientLength is the maximum length of a client identifier, longer ones are truncated.
+	maxClientLength = 100
+)
+
+// TokenEvent is the type of a token event counted by the token usage analytics.
+type TokenEvent string
+
+const (
+	TokenEventIssued    TokenEvent = "ISSUED"
+	TokenEventRefreshed TokenEvent = "REFRESHED"
+	TokenEventRevoked   TokenEvent = "REVOKED"
+)
+
+// Interface for token usage service
+// This interface defines the methods that the token usage service should implement
+type TokenUsageService interface {
+	IncrementTokenUsage(usage []entity.TokenUsage) error
+	GetTokenStats(ctx context.Context, filter entity.TokenStatsFilter) (entity.TokenStats, error)
+}
+
+// This struct defines the TokenUsageService that contains a repository field of type TokenUsageRepository
+// It implements the TokenUsageService interface and provides methods for token usage-related operations
+type tokenUsageService struct {
+	store database.DataStore
+	repo  repository.TokenUsageRepository
+}
+
+// NewTokenUsageService creates a new instance of TokenUsageService with the given data store and repository.
+// It initializes the tokenUsageService struct and returns it.
+func NewTokenUsageService(store database.DataStore, repo repository.TokenUsageRepository) TokenUsageService {
+	return &tokenUsageService{store: store, repo: repo}
+}
+
+// IncrementTokenUsage adds the counters of the given rows to the database in a single transaction.
+func (s *tokenUsageService) IncrementTokenUsage(usage []entity.TokenUsage) error {
+	db := s.store.DB(context.Background())
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, u := range usage {
+			if err := s.repo.IncrementTokenUsage(tx, u); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetTokenStats retrieves the token usage rows matching the filter and computes their totals.
+func (s *tokenUsageService) GetTokenStats(ctx context.Context, filter entity.TokenStatsFilter) (entity.TokenStats, error) {
+	if err := filter.Validate(); err != nil {
+		return entity.TokenStats{}, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.TokenStats{}, fmt.Errorf("database connection is nil")
+	}
+
+	usage, err := s.repo.GetTokenUsage(db, filter)
+	if err != nil {
+		return entity.TokenStats{}, err
+	}
+	if usage == nil {
+		usage = []entity.TokenUsage{}
+	}
+
+	stats := entity.TokenStats{
+		From:  customtype.Date{Time: filter.From},
+		To:    customtype.Date{Time: filter.To},
+		Usage: usage,
+	}
+	for _, u := range usage {
+		stats.Totals.Issued += u.Issued
+		stats.Totals.Refreshed += u.Refreshed
+		stats.Totals.Revoked += u.Revoked
+	}
+
+	return stats, nil
+}
+
+// Interface for token usage recorder
+// This interface defines the methods used to record the token events of the users
+type TokenUsageRecorder interface {
+	Record(event TokenEvent, userID int64, client string, at time.Time)
+	Close()
+}
+
+// This struct defines the TokenUsageRecorder that aggregates the token events in memory
+// and writes the counters in the background
+// It implements the TokenUsageRecorder interface
+type asyncTokenUsageRecorder struct {
+	usageService  TokenUsageService
+	flushInterval time.Duration
+	maxPending    int
+	mu            sync.Mutex
+	pending       map[string]entity.TokenUsage
+	stop          chan struct{}
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+// NewAsyncTokenUsageRecorder creates a new instance of TokenUsageRecorder that writes the counters
+// with the given token usage service every flush interval, and starts its background worker.
+// Non-positive values fall back to the defaults.
+func NewAsyncTokenUsageRecorder(usageSvc TokenUsageService, flushInterval time.Duration, maxPending int) TokenUsageRecorder {
+	if flushInterval <= 0 {
+		flushInterval = DefaultTokenUsageFlushInterval
+	}
+	if maxPending <= 0 {
+		maxPending = DefaultTokenUsageMaxPending
+	}
+
+	r := &asyncTokenUsageRecorder{
+		usageService:  usageSvc,
+		flushInterval: flushInterval,
+		maxPending:    maxPending,
+		pending:       make(map[string]entity.TokenUsage),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go runFlushLoop(r.flushInterval, r.stop, r.done, r.flush)
+
+	return r
+}
+
+// Record counts the token event of the user and client on the UTC day of the given time.
+// When too many rows are pending, the event is dropped and a warning is logged.
+func (r *asyncTokenUsageRecorder) Record(event TokenEvent, userID int64, client string, at time.Time) {
+	day := at.UTC().Truncate(24 * time.Hour)
+	usage := entity.TokenUsage{Day: customtype.Date{Time: day}, UserID: userID, Client: NormalizeClient(client)}
+	key := fmt.Sprintf("%s|%d|%s", day.Format("2006-01-02"), userID, usage.Client)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.pending[key]; ok {
+		usage = existing
+	} else if len(r.pending) >= r.maxPending {
+		logger.Warn("Dropped a token usage event, too many pending rows", logrus.Fields{
+			"event":       event,
+			"user_id":     userID,
+			"max_pending": r.maxPending,
+		})
+		return
+	}
+
+	switch event {
+	case TokenEventIssued:
+		usage.Issued++
+	case TokenEventRefreshed:
+		usage.Refreshed++
+	case TokenEventRevoked:
+		usage.Revoked++
+	}
+	r.pending[key] = usage
+}
+
+// Close stops the background worker after writing the pending counters.
+// It is safe to call Close more than once.
+func (r *asyncTokenUsageRecorder) Close() {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+}
+
+// flush writes the pending counters in a single transaction.
+// Failed writes are logged and the counters are dropped, the analytics are best effort.
+func (r *asyncTokenUsageRecorder) flush() {
+	r.mu.Lock()
+	batch := make([]entity.TokenUsage, 0, len(r.pending))
+	for _, usage := range r.pending {
+		batch = append(batch, usage)
+	}
+	r.pending = make(map[string]entity.TokenUsage)
+	r.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+	if err := r.usageService.IncrementTokenUsage(batch); err != nil {
+		logger.Error(fmt.Sprintf("Failed to write the token usage counters: %v", err), logrus.Fields{
+			"rows": len(batch),
+		})
+	}
+}
+
+// NormalizeClient returns the client identifier counted by the token usage analytics.
+// Blank identifiers are counted as the unknown client, and long ones are truncated.
+func NormalizeClient(client string) string {
+	client = strings.TrimSpace(client)
+	if client == "" {
+		return entity.UnknownClient
+	}
+	if len(client) > maxClientLength {
+		client = client[:maxClientLength]
+	}
+
+	return client
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/tracing"
+)
+
+// This struct defines the traced AuthService that wraps another AuthService
+// It implements the AuthService interface; each call is run in a span named after the method, e.g. AuthService.Login
+type tracedAuthService struct {
+	AuthService
+}
+
+// NewTracedAuthService creates a new instance of AuthService running the calls of the given service in spans,
+// so the traces of the requests show the time spent in the service around its SQL queries.
+func NewTracedAuthService(s AuthService) AuthService {
+	return &tracedAuthService{AuthService: s}
+}
+
+// Login logs the user in with the wrapped service, in a span.
+func (s *tracedAuthService) Login(ctx context.Context, loginReq entity.LoginRequest) (entity.LoginResponse, error) {
+	return tracing.RunValue(ctx, "AuthService.Login", func(ctx context.Context) (entity.LoginResponse, error) {
+		return s.AuthService.Login(ctx, loginReq)
+	})
+}
+
+// RefreshToken refreshes the tokens with the wrapped service, in a span.
+func (s *tracedAuthService) RefreshToken(ctx context.Context, refreshTokenReq entity.RefreshTokenRequest) (entity.RefreshTokenResponse, error) {
+	return tracing.RunValue(ctx, "AuthService.RefreshToken", func(ctx context.Context) (entity.RefreshTokenResponse, error) {
+		return s.AuthService.RefreshToken(ctx, refreshTokenReq)
+	})
+}
+
+// Logout ends the session with the wrapped service, in a span.
+func (s *tracedAuthService) Logout(ctx context.Context, logoutReq entity.LogoutRequest) error {
+	return tracing.Run(ctx, "AuthService.Logout", func(ctx context.Context) error {
+		return s.AuthService.Logout(ctx, logoutReq)
+	})
+}
+
+// Register registers the user with the wrapped service, in a span.
+func (s *tracedAuthService) Register(ctx context.Context, registerReq entity.RegisterRequest) (entity.RegisterResponse, error) {
+	return tracing.RunValue(ctx, "AuthService.Register", func(ctx context.Context) (entity.RegisterResponse, error) {
+		return s.AuthService.Register(ctx, registerReq)
+	})
+}
+
+// VerifyMFA completes the login with a code of the authenticator app with the wrapped service, in a span.
+func (s *tracedAuthService) VerifyMFA(ctx context.Context, verifyReq entity.VerifyMFARequest) (entity.LoginResponse, error) {
+	return tracing.RunValue(ctx, "AuthService.VerifyMFA", func(ctx context.Context) (entity.LoginResponse, error) {
+		return s.AuthService.VerifyMFA(ctx, verifyReq)
+	})
+}
+
+// LoginExternal logs in the user authenticated by an identity provider with the wrapped service, in a span.
+func (s *tracedAuthService) LoginExternal(ctx context.Context, user entity.User, loginReq entity.LoginRequest) (entity.LoginResponse, error) {
+	return tracing.RunValue(ctx, "AuthService.LoginExternal", func(ctx context.Context) (entity.LoginResponse, error) {
+		return s.AuthService.LoginExternal(ctx, user, loginReq)
+	})
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/tracing"
+)
+
+// This struct defines the traced ConsumerService that wraps another ConsumerService
+// It implements the ConsumerService interface; each call is run in a span named after the method, e.g. ConsumerService.GetAllConsumers
+type tracedConsumerService struct {
+	ConsumerService
+}
+
+// NewTracedConsumerService creates a new instance of ConsumerService running the calls of the given service in spans,
+// so the traces of the requests show the time spent in the service around its SQL queries.
+func NewTracedConsumerService(s ConsumerService) ConsumerService {
+	return &tracedConsumerService{ConsumerService: s}
+}
+
+// GetAllConsumers retrieves a page of the consumers from the wrapped service, in a span.
+func (s *tracedConsumerService) GetAllConsumers(ctx context.Context, roles []string, page int, limit int, includeDeleted bool) ([]entity.Consumer, error) {
+	return tracing.RunValue(ctx, "ConsumerService.GetAllConsumers", func(ctx context.Context) ([]entity.Consumer, error) {
+		return s.ConsumerService.GetAllConsumers(ctx, roles, page, limit, includeDeleted)
+	})
+}
+
+// StreamConsumers streams the consumers from the wrapped service, in a span.
+func (s *tracedConsumerService) StreamConsumers(ctx context.Context, roles []string, includeDeleted bool, fn func(entity.Consumer) error) error {
+	return tracing.Run(ctx, "ConsumerService.StreamConsumers", func(ctx context.Context) error {
+		return s.ConsumerService.StreamConsumers(ctx, roles, includeDeleted, fn)
+	})
+}
+
+// GetConsumerByID retrieves a consumer by its ID from the wrapped service, in a span.
+func (s *tracedConsumerService) GetConsumerByID(ctx context.Context, id string) (entity.Consumer, error) {
+	return tracing.RunValue(ctx, "ConsumerService.GetConsumerByID", func(ctx context.Context) (entity.Consumer, error) {
+		return s.ConsumerService.GetConsumerByID(ctx, id)
+	})
+}
+
+// GetActiveConsumers retrieves a page of the active consumers from the wrapped service, in a span.
+func (s *tracedConsumerService) GetActiveConsumers(ctx context.Context, roles []string, page int, limit int, includeDeleted bool) ([]entity.Consumer, error) {
+	return tracing.RunValue(ctx, "ConsumerService.GetActiveConsumers", func(ctx context.Context) ([]entity.Consumer, error) {
+		return s.ConsumerService.GetActiveConsumers(ctx, roles, page, limit, includeDeleted)
+	})
+}
+
+// GetInactiveConsumers retrieves a page of the inactive consumers from the wrapped service, in a span.
+func (s *tracedConsumerService) GetInactiveConsumers(ctx context.Context, roles []string, page int, limit int, includeDeleted bool) ([]entity.Consumer, error) {
+	return tracing.RunValue(ctx, "ConsumerService.GetInactiveConsumers", func(ctx context.Context) ([]entity.Consumer, error) {
+		return s.ConsumerService.GetInactiveConsumers(ctx, roles, page, limit, includeDeleted)
+	})
+}
+
+// GetSuspendedConsumers retrieves a page of the suspended consumers from the wrapped service, in a span.
+func (s *tracedConsumerService) GetSuspendedConsumers(ctx context.Context, roles []string, page int, limit int, includeDeleted bool) ([]entity.Consumer, error) {
+	return tracing.RunValue(ctx, "ConsumerService.GetSuspendedConsumers", func(ctx context.Context) ([]entity.Consumer, error) {
+		return s.ConsumerService.GetSuspendedConsumers(ctx, roles, page, limit, includeDeleted)
+	})
+}
+
+// CreateConsumer creates a consumer with the wrapped service, in a span.
+func (s *tracedConsumerService) CreateConsumer(ctx context.Context, c entity.Consumer) (entity.Consumer, error) {
+	return tracing.RunValue(ctx, "ConsumerService.CreateConsumer", func(ctx context.Context) (entity.Consumer, error) {
+		return s.ConsumerService.CreateConsumer(ctx, c)
+	})
+}
+
+// CheckConsumerAvailability checks whether the identifiers of a consumer are available with the wrapped service, in a span.
+func (s *tracedConsumerService) CheckConsumerAvailability(ctx context.Context, req entity.ConsumerAvailabilityRequest) (entity.ConsumerAvailability, error) {
+	return tracing.RunValue(ctx, "ConsumerService.CheckConsumerAvailability", func(ctx context.Context) (entity.ConsumerAvailability, error) {
+		return s.ConsumerService.CheckConsumerAvailability(ctx, req)
+	})
+}
+
+// UpdateConsumer updates the fields of a consumer with the wrapped service, in a span.
+func (s *tracedConsumerService) UpdateConsumer(ctx context.Context, id string, req entity.ConsumerUpdateRequest) (entity.Consumer, error) {
+	return tracing.RunValue(ctx, "ConsumerService.UpdateConsumer", func(ctx context.Context) (entity.Consumer, error) {
+		return s.ConsumerService.UpdateConsumer(ctx, id, req)
+	})
+}
+
+// UpdateConsumerStatus updates the status of a consumer with the wrapped service, in a span.
+func (s *tracedConsumerService) UpdateConsumerStatus(ctx context.Context, id string, status string) (entity.Consumer, error) {
+	return tracing.RunValue(ctx, "ConsumerService.UpdateConsumerStatus", func(ctx context.Context) (entity.Consumer, error) {
+		return s.ConsumerService.UpdateConsumerStatus(ctx, id, status)
+	})
+}
+
+// DeleteConsumer soft-deletes a consumer with the wrapped service, in a span.
+func (s *tracedConsumerService) DeleteConsumer(ctx context.Context, id string) error {
+	return tracing.Run(ctx, "ConsumerService.DeleteConsumer", func(ctx context.Context) error {
+		return s.ConsumerService.DeleteConsumer(ctx, id)
+	})
+}
+
+// RestoreConsumer restores a soft-deleted consumer with the wrapped service, in a span.
+func (s *tracedConsumerService) RestoreConsumer(ctx context.Context, id string) (entity.Consumer, error) {
+	return tracing.RunValue(ctx, "ConsumerService.RestoreConsumer", func(ctx context.Context) (entity.Consumer, error) {
+		return s.ConsumerService.RestoreConsumer(ctx, id)
+	})
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/tracing"
+)
+
+// This struct defines the traced UserService that wraps another UserService
+// It implements the UserService interface; each call is run in a span named after the method, e.g. UserService.GetUsers
+type tracedUserService struct {
+	UserService
+}
+
+// NewTracedUserService creates a new instance of UserService running the calls of the given service in spans,
+// so the traces of the requests show the time spent in the service around its SQL queries.
+func NewTracedUserService(s UserService) UserService {
+	return &tracedUserService{UserService: s}
+}
+
+// GetUsers retrieves a page of the users from the wrapped service, in a span.
+func (s *tracedUserService) GetUsers(ctx context.Context, page int, limit int) ([]entity.User, error) {
+	return tracing.RunValue(ctx, "UserService.GetUsers", func(ctx context.Context) ([]entity.User, error) {
+		return s.UserService.GetUsers(ctx, page, limit)
+	})
+}
+
+// GetUserByID retrieves a user by its ID from the wrapped service, in a span.
+func (s *tracedUserService) GetUserByID(ctx context.Context, id int64) (entity.User, error) {
+	return tracing.RunValue(ctx, "UserService.GetUserByID", func(ctx context.Context) (entity.User, error) {
+		return s.UserService.GetUserByID(ctx, id)
+	})
+}
+
+// GetUserByUsername retrieves a user by its username from the wrapped service, in a span.
+func (s *tracedUserService) GetUserByUsername(ctx context.Context, username string) (entity.User, error) {
+	return tracing.RunValue(ctx, "UserService.GetUserByUsername", func(ctx context.Context) (entity.User, error) {
+		return s.UserService.GetUserByUsername(ctx, username)
+	})
+}
+
+// GetUserByEmail retrieves a user by its email from the wrapped service, in a span.
+func (s *tracedUserService) GetUserByEmail(ctx context.Context, email string) (entity.User, error) {
+	return tracing.RunValue(ctx, "UserService.GetUserByEmail", func(ctx context.Context) (entity.User, error) {
+		return s.UserService.GetUserByEmail(ctx, email)
+	})
+}
+
+// CreateUser creates a registered user with the wrapped service, in a span.
+func (s *tracedUserService) CreateUser(ctx context.Context, req entity.RegisterRequest) (entity.User, error) {
+	return tracing.RunValue(ctx, "UserService.CreateUser", func(ctx context.Context) (entity.User, error) {
+		return s.UserService.CreateUser(ctx, req)
+	})
+}
+
+// CreateUserByAdmin creates a user for an administrator with the wrapped service, in a span.
+func (s *tracedUserService) CreateUserByAdmin(ctx context.Context, req entity.CreateUserRequest, createdBy int64) (entity.User, error) {
+	return tracing.RunValue(ctx, "UserService.CreateUserByAdmin", func(ctx context.Context) (entity.User, error) {
+		return s.UserService.CreateUserByAdmin(ctx, req, createdBy)
+	})
+}
+
+// UpdateUser updates a user with the wrapped service, in a span.
+func (s *tracedUserService) UpdateUser(ctx context.Context, id int64, req entity.UpdateUserRequest, updatedBy int64) (entity.User, error) {
+	return tracing.RunValue(ctx, "UserService.UpdateUser", func(ctx context.Context) (entity.User, error) {
+		return s.UserService.UpdateUser(ctx, id, req, updatedBy)
+	})
+}
+
+// DeleteUser deletes a user with the wrapped service, in a span.
+func (s *tracedUserService) DeleteUser(ctx context.Context, id int64, deletedBy int64) error {
+	return tracing.Run(ctx, "UserService.DeleteUser", func(ctx context.Context) error {
+		return s.UserService.DeleteUser(ctx, id, deletedBy)
+	})
+}
+
+// ChangeUserRoles replaces the roles of a user with the wrapped service, in a span.
+func (s *tracedUserService) ChangeUserRoles(ctx context.Context, id int64, req entity.UserRolesRequest, updatedBy int64) (entity.User, error) {
+	return tracing.RunValue(ctx, "UserService.ChangeUserRoles", func(ctx context.Context) (entity.User, error) {
+		return s.UserService.ChangeUserRoles(ctx, id, req, updatedBy)
+	})
+}
+
+// UpdateLastLogin updates the last login time of a user with the wrapped service, in a span.
+func (s *tracedUserService) UpdateLastLogin(ctx context.Context, id int64, lastLogin time.Time) (bool, error) {
+	return tracing.RunValue(ctx, "UserService.UpdateLastLogin", func(ctx context.Context) (bool, error) {
+		return s.UserService.UpdateLastLogin(ctx, id, lastLogin)
+	})
+}
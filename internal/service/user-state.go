@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+)
+
+//go:generate go tool mockgen -source=user-state.go -destination=../../tests/mocks/user-state-service.go -package=mocks
+
+var (
+	// ErrInvalidUserStateTransition is returned when a user account cannot change from its current state to the requested one.
+	ErrInvalidUserStateTransition = errors.New("invalid user state transition")
+
+	// ErrUserStateReasonRequired is returned when a user account is disabled or suspended without a reason.
+	ErrUserStateReasonRequired = errors.New("a reason is required to disable or suspend a user")
+)
+
+// Interface for user state service
+// This interface defines the methods that the user state service should implement
+type UserStateService interface {
+	ChangeUserState(ctx context.Context, userID int64, req entity.UserStateRequest) (entity.UserStateChange, error)
+}
+
+// This struct defines the UserStateService that contains the user repository,
+// the token revocation service used to revoke the tokens of the suspended users, and a clock used to get the current time
+// It implements the UserStateService interface and provides methods for user state-related operations
+type userStateService struct {
+	store           database.DataStore
+	userRepo        repository.UserRepository
+	tokenRevocation TokenRevocationService
+	clock           clock.Clock
+}
+
+// NewUserStateService creates a new instance of UserStateService with the given dependencies.
+// It initializes the userStateService struct and returns it.
+func NewUserStateService(store database.DataStore, userRepo repository.UserRepository, tokenRevocation TokenRevocationService, clk clock.Clock) UserStateService {
+	return &userStateService{
+		store:           store,
+		userRepo:        userRepo,
+		tokenRevocation: tokenRevocation,
+		clock:           clk,
+	}
+}
+
+// ChangeUserState changes the state of the user account, if the state machine allows it.
+// Disabling a user only prevents new logins and token refreshes, while suspending a user also revokes all their tokens.
+func (s *userStateService) ChangeUserState(ctx context.Context, userID int64, req entity.UserStateRequest) (entity.UserStateChange, error) {
+	// Validate the user state request
+	if err := req.Validate(); err != nil {
+		return entity.UserStateChange{}, err
+	}
+
+	var reason *string
+	if r := strings.TrimSpace(req.Reason); r != "" {
+		reason = &r
+	}
+	if req.State.RequiresReason() && reason == nil {
+		return entity.UserStateChange{}, ErrUserStateReasonRequired
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.UserStateChange{}, fmt.Errorf("database connection is nil")
+	}
+
+	now := s.clock.Now()
+	var previous entity.UserState
+	err := database.Transaction(db, func(tx *gorm.DB) error {
+		user, err := s.userRepo.GetUserByID(context.Background(), tx, userID)
+		if err != nil {
+			return err
+		}
+
+		previous = user.State
+		if !previous.CanTransitionTo(req.State) {
+			return fmt.Errorf("%w: from %s to %s", ErrInvalidUserStateTransition, previous, req.State)
+		}
+
+		return s.userRepo.UpdateUserState(context.Background(), tx, userID, req.State, reason, now)
+	})
+	if err != nil {
+		return entity.UserStateChange{}, err
+	}
+
+	metrics.UserStateChanged(string(previous), string(req.State))
+
+	// A suspension is a hard stop: the tokens already issued are rejected right away
+	if req.State == entity.UserStateSuspended {
+		if _, err := s.tokenRevocation.RevokeUserTokens(ctx, userID); err != nil {
+			return entity.UserStateChange{}, fmt.Errorf("user suspended but failed to revoke the tokens: %w", err)
+		}
+	}
+
+	logger.Info("Changed the state of the user", logrus.Fields{
+		"user_id":        userID,
+		"previous_state": previous,
+		"state":          req.State,
+		"reason":         req.Reason,
+	})
+
+	return entity.UserStateChange{
+		UserID:        userID,
+		PreviousState: previous,
+		State:         req.State,
+		Reason:        reason,
+		ChangedAt:     now,
+	}, nil
+}
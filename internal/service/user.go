@@ -1,45 +1,104 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
 	"github.com/yoanesber/go-jwt-auth-demo/config/database"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
-	"gorm.io/gorm"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
 )
 
+//go:generate go tool mockgen -source=user.go -destination=../../tests/mocks/user-service.go -package=mocks
+
 // Interface for user service
 // This interface defines the methods that the user service should implement
 type UserService interface {
-	GetUserByID(id int64) (entity.User, error)
-	GetUserByUsername(username string) (entity.User, error)
-	GetUserByEmail(email string) (entity.User, error)
-	UpdateLastLogin(id int64, lastLogin time.Time) (bool, error)
+	GetUsers(ctx context.Context, page int, limit int) ([]entity.User, error)
+	GetUserByID(ctx context.Context, id int64) (entity.User, error)
+	GetUserByUsername(ctx context.Context, username string) (entity.User, error)
+	GetUserByEmail(ctx context.Context, email string) (entity.User, error)
+	CreateUser(ctx context.Context, req entity.RegisterRequest) (entity.User, error)
+	CreateUserByAdmin(ctx context.Context, req entity.CreateUserRequest, createdBy int64) (entity.User, error)
+	UpdateUser(ctx context.Context, id int64, req entity.UpdateUserRequest, updatedBy int64) (entity.User, error)
+	DeleteUser(ctx context.Context, id int64, deletedBy int64) error
+	ChangeUserRoles(ctx context.Context, id int64, req entity.UserRolesRequest, updatedBy int64) (entity.User, error)
+	UpdateLastLogin(ctx context.Context, id int64, lastLogin time.Time) (bool, error)
 }
 
-// This struct defines the UserService that contains a repository field of type UserRepository
+// DefaultUserRole is the role assigned to the users registering themselves.
+const DefaultUserRole = "ROLE_USER"
+
+var (
+	// ErrUserAlreadyExists is returned when a user is created with the username or the email of another user.
+	ErrUserAlreadyExists = errors.New("user already exists")
+
+	// ErrUnknownRole is returned when a user is given a role that does not exist.
+	ErrUnknownRole = errors.New("unknown role")
+
+	// ErrCannotDeleteSelf is returned when an administrator deletes their own account.
+	ErrCannotDeleteSelf = errors.New("administrators cannot delete their own account")
+
+	// ErrNoRolesLeft is returned when all the roles of a user are removed.
+	ErrNoRolesLeft = errors.New("a user must keep at least one role")
+)
+
+// This struct defines the UserService that contains a repository field of type UserRepository,
+// the role repository used to assign the roles of the users, the token revocation service
+// used to revoke the tokens of the deleted users, and a clock used to get the current time
 // It implements the UserService interface and provides methods for user-related operations
 type userService struct {
-	repo repository.UserRepository
+	store           database.DataStore
+	repo            repository.UserRepository
+	roleRepo        repository.RoleRepository
+	tokenRevocation TokenRevocationService
+	clock           clock.Clock
 }
 
-// NewUserService creates a new instance of UserService with the given repository.
+// NewUserService creates a new instance of UserService with the given dependencies.
 // It initializes the userService struct and returns it.
-func NewUserService(repo repository.UserRepository) UserService {
-	return &userService{repo: repo}
+func NewUserService(store database.DataStore, repo repository.UserRepository, roleRepo repository.RoleRepository, tokenRevocation TokenRevocationService, clk clock.Clock) UserService {
+	return &userService{
+		store:           store,
+		repo:            repo,
+		roleRepo:        roleRepo,
+		tokenRevocation: tokenRevocation,
+		clock:           clk,
+	}
+}
+
+// GetUsers retrieves a page of the users, the soft-deleted users are left out.
+func (s *userService) GetUsers(ctx context.Context, page int, limit int) ([]entity.User, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	users, err := s.repo.GetUsers(ctx, db, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
 }
 
 // GetUserByID retrieves a user by its ID from the database.
-func (s *userService) GetUserByID(id int64) (entity.User, error) {
-	db := database.GetPostgres()
+func (s *userService) GetUserByID(ctx context.Context, id int64) (entity.User, error) {
+	db := s.store.DB(ctx)
 	if db == nil {
 		return entity.User{}, fmt.Errorf("database connection is nil")
 	}
 
 	// Retrieve the user by ID from the repository
-	user, err := s.repo.GetUserByID(db, id)
+	user, err := s.repo.GetUserByID(ctx, db, id)
 	if err != nil {
 		return entity.User{}, err
 	}
@@ -48,14 +107,14 @@ func (s *userService) GetUserByID(id int64) (entity.User, error) {
 }
 
 // GetUserByUsername retrieves a user by their username from the database.
-func (s *userService) GetUserByUsername(username string) (entity.User, error) {
-	db := database.GetPostgres()
+func (s *userService) GetUserByUsername(ctx context.Context, username string) (entity.User, error) {
+	db := s.store.DB(ctx)
 	if db == nil {
 		return entity.User{}, fmt.Errorf("database connection is nil")
 	}
 
 	// Retrieve the user by username from the repository
-	user, err := s.repo.GetUserByUsername(db, username)
+	user, err := s.repo.GetUserByUsername(ctx, db, username)
 	if err != nil {
 		return entity.User{}, err
 	}
@@ -64,14 +123,14 @@ func (s *userService) GetUserByUsername(username string) (entity.User, error) {
 }
 
 // GetUserByEmail retrieves a user by their email from the database.
-func (s *userService) GetUserByEmail(email string) (entity.User, error) {
-	db := database.GetPostgres()
+func (s *userService) GetUserByEmail(ctx context.Context, email string) (entity.User, error) {
+	db := s.store.DB(ctx)
 	if db == nil {
 		return entity.User{}, fmt.Errorf("database connection is nil")
 	}
 
 	// Retrieve the user by email from the repository
-	user, err := s.repo.GetUserByEmail(db, email)
+	user, err := s.repo.GetUserByEmail(ctx, db, email)
 	if err != nil {
 		return entity.User{}, err
 	}
@@ -79,36 +138,332 @@ func (s *userService) GetUserByEmail(email string) (entity.User, error) {
 	return user, nil
 }
 
-// UpdateLastLogin updates the last login time of a user in the database.
-func (s *userService) UpdateLastLogin(id int64, lastLogin time.Time) (bool, error) {
-	db := database.GetPostgres()
+// CreateUser creates an active user account from the registration request, with the default ROLE_USER role.
+// The password is hashed with bcrypt. It fails with ErrUserAlreadyExists if the username or the email is taken,
+// both being compared case-insensitively.
+func (s *userService) CreateUser(ctx context.Context, req entity.RegisterRequest) (entity.User, error) {
+	db := s.store.DB(ctx)
 	if db == nil {
-		return false, fmt.Errorf("database connection is nil")
+		return entity.User{}, fmt.Errorf("database connection is nil")
+	}
+
+	// Validate the registration request using the validator
+	if err := req.Validate(); err != nil {
+		return entity.User{}, err
+	}
+
+	// Hash the password before it is stored
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	createdUser := entity.User{}
+	err = database.Transaction(db, func(tx *gorm.DB) error {
+		// Check if the username or the email already exists
+		if _, err := s.repo.GetUserByUsername(ctx, tx, req.Username); err == nil {
+			return fmt.Errorf("%w: username %s is already taken", ErrUserAlreadyExists, req.Username)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to check existing user by username: %w", err)
+		}
+		if _, err := s.repo.GetUserByEmail(ctx, tx, req.Email); err == nil {
+			return fmt.Errorf("%w: email %s is already taken", ErrUserAlreadyExists, req.Email)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to check existing user by email: %w", err)
+		}
+
+		// Assign the default role to the user
+		role, err := s.roleRepo.GetRoleByName(tx, DefaultUserRole)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve role %s: %w", DefaultUserRole, err)
+		}
+
+		createdUser, err = s.repo.CreateUser(ctx, tx, entity.User{
+			Username:  req.Username,
+			Password:  string(hashedPassword),
+			Email:     req.Email,
+			Firstname: req.Firstname,
+			Lastname:  req.Lastname,
+			State:     entity.UserStateActive,
+			UserType:  entity.UserTypeUserAccount,
+			Roles:     []entity.Role{role},
+		})
+		return err
+	})
+
+	if err != nil {
+		return entity.User{}, err
 	}
 
-	err := db.Transaction(func(tx *gorm.DB) error {
-		// Check if the user exists
-		existingUser, err := s.repo.GetUserByID(db, id)
+	return createdUser, nil
+}
+
+// CreateUserByAdmin creates an active user account on behalf of an administrator, with the given roles (ROLE_USER by default)
+// and user type (USER_ACCOUNT by default). It fails with ErrUserAlreadyExists if the username or the email is taken,
+// and with ErrUnknownRole if a role does not exist.
+func (s *userService) CreateUserByAdmin(ctx context.Context, req entity.CreateUserRequest, createdBy int64) (entity.User, error) {
+	// Validate the request using the validator
+	if err := req.Validate(); err != nil {
+		return entity.User{}, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.User{}, fmt.Errorf("database connection is nil")
+	}
+
+	// Hash the password before it is stored
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	userType := req.UserType
+	if userType == "" {
+		userType = entity.UserTypeUserAccount
+	}
+	roleNames := req.Roles
+	if len(roleNames) == 0 {
+		roleNames = []string{DefaultUserRole}
+	}
+
+	createdUser := entity.User{}
+	err = database.Transaction(db, func(tx *gorm.DB) error {
+		// Check if the username or the email already exists
+		if _, err := s.repo.GetUserByUsername(ctx, tx, req.Username); err == nil {
+			return fmt.Errorf("%w: username %s is already taken", ErrUserAlreadyExists, req.Username)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to check existing user by username: %w", err)
+		}
+		if err := s.checkEmailAvailable(ctx, tx, req.Email, 0); err != nil {
+			return err
+		}
+
+		roles, err := s.resolveRoles(tx, roleNames)
 		if err != nil {
 			return err
 		}
 
-		// Check if the existing user is empty
-		if (existingUser.Equals(&entity.User{})) {
-			return fmt.Errorf("user with ID %d not found", id)
+		createdUser, err = s.repo.CreateUser(ctx, tx, entity.User{
+			Username:  req.Username,
+			Password:  string(hashedPassword),
+			Email:     req.Email,
+			Firstname: req.Firstname,
+			Lastname:  req.Lastname,
+			State:     entity.UserStateActive,
+			UserType:  userType,
+			CreatedBy: &createdBy,
+			Roles:     roles,
+		})
+		return err
+	})
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	logger.InfoContext(ctx, "Created user account", logrus.Fields{"user_id": createdUser.ID, "created_by": createdBy})
+
+	return createdUser, nil
+}
+
+// UpdateUser replaces the profile and the roles of the user on behalf of an administrator.
+// The username, the password, and the state of the user are left unchanged.
+// It fails with ErrUserAlreadyExists if the email is used by another user, and with ErrUnknownRole if a role does not exist.
+func (s *userService) UpdateUser(ctx context.Context, id int64, req entity.UpdateUserRequest, updatedBy int64) (entity.User, error) {
+	// Validate the request using the validator
+	if err := req.Validate(); err != nil {
+		return entity.User{}, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.User{}, fmt.Errorf("database connection is nil")
+	}
+
+	updatedUser := entity.User{}
+	err := database.Transaction(db, func(tx *gorm.DB) error {
+		user, err := s.repo.GetUserByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.checkEmailAvailable(ctx, tx, req.Email, id); err != nil {
+			return err
 		}
 
-		// Update the last login time
-		*existingUser.LastLogin = lastLogin
-		_, err = s.repo.UpdateUser(tx, existingUser)
+		roles, err := s.resolveRoles(tx, req.Roles)
 		if err != nil {
 			return err
 		}
 
-		return nil
+		user.Email = req.Email
+		user.Firstname = req.Firstname
+		user.Lastname = req.Lastname
+		user.UserType = req.UserType
+		user.UpdatedBy = &updatedBy
+
+		// The roles are replaced separately, saving them with the user would only add the new ones
+		user.Roles = nil
+		if _, err := s.repo.UpdateUser(ctx, tx, user); err != nil {
+			return err
+		}
+		if err := s.repo.ReplaceUserRoles(ctx, tx, id, roles); err != nil {
+			return err
+		}
+
+		updatedUser, err = s.repo.GetUserByID(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	logger.InfoContext(ctx, "Updated user account", logrus.Fields{"user_id": id, "updated_by": updatedBy})
+
+	return updatedUser, nil
+}
+
+// DeleteUser soft-deletes the user on behalf of an administrator, who cannot delete their own account.
+// All the tokens of the user are revoked first, so that the sessions of the user end right away.
+func (s *userService) DeleteUser(ctx context.Context, id int64, deletedBy int64) error {
+	if id == deletedBy {
+		return ErrCannotDeleteSelf
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	if _, err := s.repo.GetUserByID(ctx, db, id); err != nil {
+		return err
+	}
+
+	// The token version of a deleted user cannot be bumped anymore, so the tokens are revoked before
+	if _, err := s.tokenRevocation.RevokeUserTokens(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke the tokens of user %d: %w", id, err)
+	}
+
+	if err := s.repo.SoftDeleteUser(ctx, db, id, deletedBy, s.clock.Now()); err != nil {
+		return err
+	}
+
+	logger.InfoContext(ctx, "Deleted user account", logrus.Fields{"user_id": id, "deleted_by": deletedBy})
+
+	return nil
+}
+
+// ChangeUserRoles assigns and removes roles of the user on behalf of an administrator, the other roles are kept.
+// Assigning a role the user has, or removing one it does not have, changes nothing; a role both assigned and removed is removed.
+// It fails with ErrUnknownRole if a role does not exist, and with ErrNoRolesLeft if the user would be left without any role.
+func (s *userService) ChangeUserRoles(ctx context.Context, id int64, req entity.UserRolesRequest, updatedBy int64) (entity.User, error) {
+	// Validate the request using the validator
+	if err := req.Validate(); err != nil {
+		return entity.User{}, err
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.User{}, fmt.Errorf("database connection is nil")
+	}
+
+	updatedUser := entity.User{}
+	err := database.Transaction(db, func(tx *gorm.DB) error {
+		user, err := s.repo.GetUserByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		added, err := s.resolveRoles(tx, req.Add)
+		if err != nil {
+			return err
+		}
+		removed, err := s.resolveRoles(tx, req.Remove)
+		if err != nil {
+			return err
+		}
+
+		removedIDs := make(map[uint]bool, len(removed))
+		for _, role := range removed {
+			removedIDs[role.ID] = true
+		}
+
+		// Keep the current roles not removed, then append the added ones the user does not have yet
+		seen := make(map[uint]bool, len(user.Roles)+len(added))
+		roles := make([]entity.Role, 0, len(user.Roles)+len(added))
+		for _, role := range append(user.Roles, added...) {
+			if removedIDs[role.ID] || seen[role.ID] {
+				continue
+			}
+			seen[role.ID] = true
+			roles = append(roles, role)
+		}
+		if len(roles) == 0 {
+			return ErrNoRolesLeft
+		}
+
+		if err := s.repo.ReplaceUserRoles(ctx, tx, id, roles); err != nil {
+			return err
+		}
+
+		updatedUser, err = s.repo.GetUserByID(ctx, tx, id)
+		return err
 	})
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	logger.InfoContext(ctx, "Changed user roles", logrus.Fields{"user_id": id, "added": req.Add, "removed": req.Remove, "updated_by": updatedBy})
 
+	return updatedUser, nil
+}
+
+// checkEmailAvailable fails with ErrUserAlreadyExists if the email is used by a user other than the given one.
+func (s *userService) checkEmailAvailable(ctx context.Context, tx *gorm.DB, email string, userID int64) error {
+	existing, err := s.repo.GetUserByEmail(ctx, tx, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
 	if err != nil {
+		return fmt.Errorf("failed to check existing user by email: %w", err)
+	}
+	if existing.ID != userID {
+		return fmt.Errorf("%w: email %s is already taken", ErrUserAlreadyExists, email)
+	}
+
+	return nil
+}
+
+// resolveRoles retrieves the roles with the given names, a role named twice is assigned once.
+func (s *userService) resolveRoles(tx *gorm.DB, names []string) ([]entity.Role, error) {
+	seen := make(map[uint]bool, len(names))
+	roles := make([]entity.Role, 0, len(names))
+	for _, name := range names {
+		role, err := s.roleRepo.GetRoleByName(tx, name)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRole, name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve role %s: %w", name, err)
+		}
+
+		if !seen[role.ID] {
+			seen[role.ID] = true
+			roles = append(roles, role)
+		}
+	}
+
+	return roles, nil
+}
+
+// UpdateLastLogin updates the last login time of a user in the database.
+// Only the last_login column is written, the rest of the user is left untouched.
+func (s *userService) UpdateLastLogin(ctx context.Context, id int64, lastLogin time.Time) (bool, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return false, fmt.Errorf("database connection is nil")
+	}
+
+	if err := s.repo.UpdateLastLogin(ctx, db, id, lastLogin); err != nil {
 		return false, err
 	}
 
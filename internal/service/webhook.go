@@ -0,0 +1,421 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/alerting"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+)
+
+//go:generate go tool mockgen -source=webhook.go -destination=../../tests/mocks/webhook-service.go -package=mocks
+
+/**
+ * The webhook subsystem sends the consumer events (a consumer created, a status changed) to CONSUMER_WEBHOOK_URL.
+ * Every event is stored as a delivery before it is sent, so that it survives a failure of the receiver or a restart.
+ * A background dispatcher attempts the due deliveries; a failed attempt (an error or a non-2xx response) is retried
+ * with an exponential backoff, and the delivery is dead-lettered after CONSUMER_WEBHOOK_MAX_ATTEMPTS attempts.
+ * The administrators list the failed and dead deliveries, inspect their payload and the last response, and
+ * redeliver them manually, which starts a new round of attempts.
+ */
+
+const (
+	// DefaultWebhookPollInterval is how often the dispatcher looks for the due deliveries.
+	DefaultWebhookPollInterval = 5 * time.Second
+
+	// WebhookEventHeader and WebhookDeliveryHeader are the request headers carrying the type of the event
+	// and the ID of the delivery, so that the receivers can ignore the deliveries they already handled.
+	WebhookEventHeader    = "X-Webhook-Event"
+	WebhookDeliveryHeader = "X-Webhook-Delivery"
+
+	// webhookBatchSize is the maximum number of deliveries attempted by a round of the dispatcher.
+	webhookBatchSize = 50
+
+	// maxWebhookResponseBody is the number of bytes of the responses kept for the inspection of the deliveries.
+	maxWebhookResponseBody = 4096
+)
+
+var (
+	// ErrWebhookNotRedeliverable is returned when a delivery that did not fail is redelivered.
+	ErrWebhookNotRedeliverable = errors.New("only the failed and dead webhook deliveries can be redelivered")
+
+	// ErrInvalidWebhookStatus is returned when the failed deliveries are listed with another status than FAILED or DEAD.
+	ErrInvalidWebhookStatus = errors.New("invalid webhook delivery status, use FAILED or DEAD")
+)
+
+// Interface for webhook service
+// This interface defines the methods that the webhook service should implement
+type WebhookService interface {
+	Enqueue(ctx context.Context, event string, data interface{}) error
+	DeliverDue() (int, error)
+	GetFailedDeliveries(ctx context.Context, status string, page int, limit int) ([]entity.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, id int64) (entity.WebhookDelivery, error)
+	Redeliver(ctx context.Context, id int64) (entity.WebhookDelivery, error)
+}
+
+// This struct defines the WebhookService that contains a repository field of type WebhookDeliveryRepository,
+// the policy of the deliveries, the HTTP client sending them, and the notifier alerting the operators of the dead deliveries
+// It implements the WebhookService interface and provides methods for webhook-related operations
+type webhookService struct {
+	store    database.DataStore
+	repo     repository.WebhookDeliveryRepository
+	policy   policyconfig.WebhookPolicy
+	client   *http.Client
+	clock    clock.Clock
+	notifier alerting.Notifier
+}
+
+// WebhookServiceOption configures the webhook service.
+type WebhookServiceOption func(*webhookService)
+
+// WithWebhookAlerts raises a webhook_dead alert with the given notifier when a delivery is dead-lettered,
+// so that the operators redeliver it once the receiver is fixed.
+func WithWebhookAlerts(notifier alerting.Notifier) WebhookServiceOption {
+	return func(s *webhookService) {
+		s.notifier = notifier
+	}
+}
+
+// NewWebhookService creates a new instance of WebhookService with the given data store, repository, policy, HTTP client, and clock.
+// A nil client falls back to a client with the timeout of the policy.
+func NewWebhookService(store database.DataStore, repo repository.WebhookDeliveryRepository, policy policyconfig.WebhookPolicy, client *http.Client, clk clock.Clock, opts ...WebhookServiceOption) WebhookService {
+	if client == nil {
+		client = &http.Client{Timeout: policy.Timeout}
+	}
+
+	s := &webhookService{store: store, repo: repo, policy: policy, client: client, clock: clk}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Enqueue stores the delivery of a consumer event, to be sent by the dispatcher as soon as possible.
+// It does nothing when the webhooks are disabled.
+func (s *webhookService) Enqueue(ctx context.Context, event string, data interface{}) error {
+	if !s.policy.Enabled() {
+		return nil
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	now := s.clock.Now()
+	payload, err := json.Marshal(entity.WebhookEvent{Event: event, OccurredAt: now, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to encode the webhook event: %w", err)
+	}
+
+	_, err = s.repo.CreateWebhookDelivery(db, entity.WebhookDelivery{
+		Event:         event,
+		URL:           s.policy.URL,
+		Payload:       string(payload),
+		Status:        entity.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+
+	return err
+}
+
+// DeliverDue attempts the deliveries whose next attempt is due, and returns the number of deliveries attempted.
+// The deliveries are claimed before they are sent, so that the other instances do not attempt them at the same time;
+// a delivery whose attempt was interrupted is attempted again once its claim expires.
+func (s *webhookService) DeliverDue() (int, error) {
+	db := s.store.DB(context.Background())
+	if db == nil {
+		return 0, fmt.Errorf("database connection is nil")
+	}
+
+	now := s.clock.Now()
+	claimedUntil := now.Add(2 * s.policy.Timeout)
+
+	var due []entity.WebhookDelivery
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		due, err = s.repo.GetDueWebhookDeliveries(tx, now, webhookBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for i := range due {
+			due[i].NextAttemptAt = &claimedUntil
+			due[i].UpdatedAt = now
+			if err := s.repo.UpdateWebhookDelivery(tx, due[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim the due webhook deliveries: %w", err)
+	}
+
+	for _, delivery := range due {
+		if _, err := s.attempt(db, delivery); err != nil {
+			logger.Error(fmt.Sprintf("Failed to save the webhook delivery attempt: %v", err), logrus.Fields{
+				"delivery_id": delivery.ID,
+			})
+		}
+	}
+
+	return len(due), nil
+}
+
+// GetFailedDeliveries retrieves a page of the deliveries with the given status, FAILED or DEAD, the most recent first.
+// Both are listed when the status is empty.
+func (s *webhookService) GetFailedDeliveries(ctx context.Context, status string, page int, limit int) ([]entity.WebhookDelivery, error) {
+	var statuses []string
+	switch status {
+	case "":
+		statuses = []string{entity.WebhookDeliveryFailed, entity.WebhookDeliveryDead}
+	case entity.WebhookDeliveryFailed, entity.WebhookDeliveryDead:
+		statuses = []string{status}
+	default:
+		return nil, ErrInvalidWebhookStatus
+	}
+
+	db := s.store.DB(ctx)
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	return s.repo.GetWebhookDeliveriesByStatus(db, statuses, page, limit)
+}
+
+// GetDelivery retrieves a delivery by its ID, with its payload and the outcome of its last attempt.
+func (s *webhookService) GetDelivery(ctx context.Context, id int64) (entity.WebhookDelivery, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.WebhookDelivery{}, fmt.Errorf("database connection is nil")
+	}
+
+	return s.repo.GetWebhookDeliveryByID(db, id)
+}
+
+// Redeliver attempts a failed or dead delivery right away, and returns it with the outcome of the attempt.
+// The count of attempts is reset, so that a delivery failing again is retried with the backoff before it is dead-lettered again.
+func (s *webhookService) Redeliver(ctx context.Context, id int64) (entity.WebhookDelivery, error) {
+	db := s.store.DB(ctx)
+	if db == nil {
+		return entity.WebhookDelivery{}, fmt.Errorf("database connection is nil")
+	}
+
+	delivery, err := s.repo.GetWebhookDeliveryByID(db, id)
+	if err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+	if delivery.Status != entity.WebhookDeliveryFailed && delivery.Status != entity.WebhookDeliveryDead {
+		return entity.WebhookDelivery{}, ErrWebhookNotRedeliverable
+	}
+
+	delivery.Attempts = 0
+	delivery, err = s.attempt(db, delivery)
+	if err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+
+	logger.Info("Webhook delivery redelivered", logrus.Fields{
+		"delivery_id": delivery.ID,
+		"event":       delivery.Event,
+		"status":      delivery.Status,
+	})
+
+	return delivery, nil
+}
+
+// attempt sends the delivery, and saves the outcome of the attempt: the delivery succeeds on a 2xx response,
+// otherwise it is retried after the backoff, or dead-lettered once it has no attempts left.
+func (s *webhookService) attempt(tx *gorm.DB, delivery entity.WebhookDelivery) (entity.WebhookDelivery, error) {
+	status, body, err := s.send(delivery)
+
+	at := s.clock.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &at
+	delivery.UpdatedAt = at
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+	delivery.LastError = ""
+
+	switch {
+	case err == nil && status >= 200 && status < 300:
+		delivery.Status = entity.WebhookDeliverySucceeded
+		delivery.NextAttemptAt = nil
+		metrics.WebhookAttempted(metrics.WebhookResultSucceeded)
+	case delivery.Attempts >= s.policy.MaxAttempts:
+		delivery.Status = entity.WebhookDeliveryDead
+		delivery.NextAttemptAt = nil
+		delivery.LastError = webhookError(status, err)
+		metrics.WebhookAttempted(metrics.WebhookResultDead)
+
+		logger.Warn("Webhook delivery dead-lettered, all its attempts failed", logrus.Fields{
+			"delivery_id": delivery.ID,
+			"event":       delivery.Event,
+			"attempts":    delivery.Attempts,
+			"error":       delivery.LastError,
+		})
+	default:
+		next := at.Add(s.policy.RetryDelay(delivery.Attempts))
+		delivery.Status = entity.WebhookDeliveryFailed
+		delivery.NextAttemptAt = &next
+		delivery.LastError = webhookError(status, err)
+		metrics.WebhookAttempted(metrics.WebhookResultFailed)
+	}
+
+	if err := s.repo.UpdateWebhookDelivery(tx, delivery); err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+
+	if delivery.Status == entity.WebhookDeliveryDead && s.notifier != nil {
+		s.notifier.Notify(alerting.Alert{
+			Event:   alerting.EventWebhookDead,
+			Key:     strconv.FormatInt(delivery.ID, 10),
+			Title:   "Webhook delivery dead-lettered",
+			Message: fmt.Sprintf("All the %d attempts of the %s delivery failed, redeliver it once the receiver is fixed.", delivery.Attempts, delivery.Event),
+			Fields: map[string]string{
+				"delivery_id": strconv.FormatInt(delivery.ID, 10),
+				"event":       delivery.Event,
+				"error":       delivery.LastError,
+			},
+			Timestamp: at,
+		})
+	}
+
+	return delivery, nil
+}
+
+// send posts the payload of the delivery to its URL, and returns the status and the beginning of the body of the response.
+func (s *webhookService) send(delivery entity.WebhookDelivery) (int, string, error) {
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBody))
+	if err != nil {
+		return resp.StatusCode, string(body), err
+	}
+
+	return resp.StatusCode, string(body), nil
+}
+
+// webhookError describes a failed attempt, the error of the request or the unexpected status of the response.
+func webhookError(status int, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+
+	return fmt.Sprintf("unexpected response status %d", status)
+}
+
+// Interface for webhook dispatcher
+// This interface defines the methods of the background worker attempting the due webhook deliveries
+type WebhookDispatcher interface {
+	Close()
+}
+
+// This struct defines the WebhookDispatcher that attempts the due deliveries in the background
+// It implements the WebhookDispatcher interface
+type webhookDispatcher struct {
+	service   WebhookService
+	interval  time.Duration
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewWebhookDispatcher creates a new instance of WebhookDispatcher that attempts the due deliveries
+// with the given webhook service every interval, and starts its background worker.
+// A non-positive interval falls back to the default.
+func NewWebhookDispatcher(webhookSvc WebhookService, interval time.Duration) WebhookDispatcher {
+	if interval <= 0 {
+		interval = DefaultWebhookPollInterval
+	}
+
+	d := &webhookDispatcher{
+		service:  webhookSvc,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go d.run()
+
+	return d
+}
+
+// Close stops the background worker, after the round of deliveries in progress.
+// The deliveries left are attempted after the restart, since they are stored.
+// It is safe to call Close more than once.
+func (d *webhookDispatcher) Close() {
+	d.closeOnce.Do(func() {
+		close(d.stop)
+	})
+	<-d.done
+}
+
+// run attempts the due deliveries every interval until the dispatcher is closed.
+// A full round is followed by another one right away, so that a backlog is drained without waiting.
+func (d *webhookDispatcher) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				n, err := d.service.DeliverDue()
+				if err != nil {
+					logger.Error(fmt.Sprintf("Failed to attempt the due webhook deliveries: %v", err), nil)
+				}
+				if err != nil || n < webhookBatchSize || d.stopped() {
+					break
+				}
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// stopped reports whether the dispatcher is being closed.
+func (d *webhookDispatcher) stopped() bool {
+	select {
+	case <-d.stop:
+		return true
+	default:
+		return false
+	}
+}
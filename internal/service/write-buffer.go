@@ -0,0 +1,208 @@
+package service
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+)
+
+/**
+ * The write buffer keeps the low-risk writes of the background workers, the last login times, the devices of the
+ * security events, and the audit events, failing with a transient database error, e.g. while PostgreSQL fails over
+ * or restarts, and retries them until they succeed, instead of dropping them. While writes are pending, the new ones
+ * are queued behind them, so that they are applied in order, e.g. an older last login time never overwrites a newer one.
+ *
+ * The buffer is bounded: it holds up to DB_WRITE_BUFFER_SIZE writes, each retried for up to DB_WRITE_BUFFER_TIMEOUT_SECOND,
+ * so that a database down for longer does not grow the memory of the process; the writes dropped are logged.
+ * Only the idempotent writes are buffered, since a write whose connection was lost may have been applied:
+ * the token usage counters, which would be counted twice, are not. The buffer is off unless DB_WRITE_BUFFER is TRUE.
+ */
+
+// bufferedWrite is a write waiting to be retried, with the fields describing it in the logs and the time it was buffered.
+type bufferedWrite struct {
+	name       string
+	fields     logrus.Fields
+	write      func() error
+	bufferedAt time.Time
+}
+
+// WriteBuffer retries the writes failing with a transient database error in the background.
+// A nil buffer runs the writes once, so that the workers do not check whether the buffer is enabled.
+type WriteBuffer struct {
+	policy    policyconfig.WriteBufferPolicy
+	clock     clock.Clock
+	mu        sync.Mutex
+	pending   []bufferedWrite
+	closed    bool
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewWriteBuffer creates a new write buffer with the given policy and clock, and starts its background worker.
+// It returns nil if the policy is disabled. Non-positive values fall back to the defaults.
+func NewWriteBuffer(policy policyconfig.WriteBufferPolicy, clk clock.Clock) *WriteBuffer {
+	if !policy.Enabled {
+		return nil
+	}
+	if policy.Size <= 0 {
+		policy.Size = policyconfig.DefaultWriteBufferSize
+	}
+	if policy.Timeout <= 0 {
+		policy.Timeout = policyconfig.DefaultWriteBufferTimeout
+	}
+	if policy.RetryInterval <= 0 {
+		policy.RetryInterval = policyconfig.DefaultWriteBufferRetryInterval
+	}
+
+	b := &WriteBuffer{
+		policy: policy,
+		clock:  clk,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go runFlushLoop(policy.RetryInterval, b.stop, b.done, b.retry)
+
+	return b
+}
+
+// Write runs the write named after what it writes, e.g. last_login, or queues it behind the pending writes if any.
+// A write failing with a transient database error is queued to be retried, and nil is returned.
+// The other errors are returned for the caller to log, as well as the transient ones when the buffer is full or closed.
+func (b *WriteBuffer) Write(name string, fields logrus.Fields, write func() error) error {
+	if b == nil {
+		return write()
+	}
+
+	// The writes are applied in order, a new write waits for the pending ones
+	b.mu.Lock()
+	queued := len(b.pending) > 0 && b.enqueue(name, fields, write)
+	b.mu.Unlock()
+	if queued {
+		return nil
+	}
+
+	err := write()
+	if !repository.IsTransientError(err) {
+		return err
+	}
+
+	b.mu.Lock()
+	queued = b.enqueue(name, fields, write)
+	b.mu.Unlock()
+	if !queued {
+		return fmt.Errorf("%w (the write buffer is full)", err)
+	}
+
+	logger.Warn(fmt.Sprintf("Buffered a write failing with a transient database error: %v", err), withWrite(fields, name))
+	return nil
+}
+
+// enqueue adds the write to the pending writes, and reports whether it was added: the buffer may be full or closed.
+// The caller must hold the lock.
+func (b *WriteBuffer) enqueue(name string, fields logrus.Fields, write func() error) bool {
+	if b.closed || len(b.pending) >= b.policy.Size {
+		return false
+	}
+
+	b.pending = append(b.pending, bufferedWrite{name: name, fields: fields, write: write, bufferedAt: b.clock.Now()})
+	metrics.DatabaseBufferedWrite(metrics.BufferedWriteResultBuffered)
+	return true
+}
+
+// Pending returns the number of writes waiting to be retried.
+func (b *WriteBuffer) Pending() int {
+	if b == nil {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending)
+}
+
+// Close stops the background worker after retrying the pending writes one last time, the writes still failing are dropped.
+// It must be called after the workers writing with the buffer are closed. It is safe to call Close more than once.
+func (b *WriteBuffer) Close() {
+	if b == nil {
+		return
+	}
+
+	b.closeOnce.Do(func() {
+		close(b.stop)
+	})
+	<-b.done
+
+	b.mu.Lock()
+	b.closed = true
+	dropped := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	for _, w := range dropped {
+		drop(w, "the database is still unavailable on shutdown")
+	}
+}
+
+// retry runs the pending writes in their order, until one fails with a transient error again:
+// the database is still unavailable, the next writes wait for the next retry.
+// The writes buffered for longer than the timeout, or failing with another error, are dropped.
+func (b *WriteBuffer) retry() {
+	for {
+		b.mu.Lock()
+		if len(b.pending) == 0 {
+			b.mu.Unlock()
+			return
+		}
+		w := b.pending[0]
+		b.mu.Unlock()
+
+		var err error
+		expired := b.clock.Now().Sub(w.bufferedAt) >= b.policy.Timeout
+		if !expired {
+			if err = w.write(); repository.IsTransientError(err) {
+				return
+			}
+		}
+
+		// Only the worker removes the writes, the first one is still the one just run
+		b.mu.Lock()
+		b.pending = b.pending[1:]
+		b.mu.Unlock()
+
+		switch {
+		case expired:
+			drop(w, fmt.Sprintf("the database was unavailable for more than %s", b.policy.Timeout))
+		case err != nil:
+			drop(w, err.Error())
+		default:
+			metrics.DatabaseBufferedWrite(metrics.BufferedWriteResultWritten)
+			logger.Info("Wrote a buffered write", withWrite(w.fields, w.name))
+		}
+	}
+}
+
+// drop logs the write dropped from the buffer for the given reason.
+func drop(w bufferedWrite, reason string) {
+	metrics.DatabaseBufferedWrite(metrics.BufferedWriteResultDropped)
+	logger.Error(fmt.Sprintf("Dropped a buffered write: %s", reason), withWrite(w.fields, w.name))
+}
+
+// withWrite returns a copy of the fields of a write with its name.
+func withWrite(fields logrus.Fields, name string) logrus.Fields {
+	f := maps.Clone(fields)
+	if f == nil {
+		f = logrus.Fields{}
+	}
+	f["write"] = name
+	return f
+}
@@ -0,0 +1,72 @@
+// Consumer listing and lookup scenario, exercising the JWT validation middleware on every request.
+// Thresholds mirror the SLOs documented in loadtest/README.md.
+import http from 'k6/http';
+import { check } from 'k6';
+
+const BASE_URL = __ENV.BASE_URL || 'http://localhost:1000';
+const ORIGIN = __ENV.ORIGIN || 'http://localhost:3000';
+const USERNAME = __ENV.USERNAME || 'admin';
+const PASSWORD = __ENV.PASSWORD || 'P@ssw0rd';
+
+export const options = {
+  scenarios: {
+    list: {
+      executor: 'constant-arrival-rate',
+      exec: 'list',
+      rate: 200,
+      timeUnit: '1s',
+      duration: '1m',
+      preAllocatedVUs: 50,
+      maxVUs: 200,
+    },
+    get: {
+      executor: 'constant-arrival-rate',
+      exec: 'get',
+      rate: 200,
+      timeUnit: '1s',
+      duration: '1m',
+      preAllocatedVUs: 50,
+      maxVUs: 200,
+    },
+  },
+  thresholds: {
+    'http_req_duration{endpoint:list}': ['p(95)<50', 'p(99)<100'],
+    'http_req_duration{endpoint:get}': ['p(95)<30', 'p(99)<75'],
+    'http_req_failed{endpoint:list}': ['rate<0.001'],
+    'http_req_failed{endpoint:get}': ['rate<0.001'],
+  },
+};
+
+// setup logs in once and picks a consumer ID to look up
+export function setup() {
+  const headers = { 'Content-Type': 'application/json', Origin: ORIGIN };
+  const login = http.post(`${BASE_URL}/auth/login`, JSON.stringify({ username: USERNAME, password: PASSWORD }), { headers });
+  const token = login.json('data.accessToken');
+
+  const list = http.get(`${BASE_URL}/api/v1/consumers?page=1&limit=1`, {
+    headers: { ...headers, Authorization: `Bearer ${token}` },
+  });
+  const consumers = list.json('data') || [];
+
+  return { token, consumerId: consumers.length > 0 ? consumers[0].id : null };
+}
+
+function headers(data, endpoint) {
+  return {
+    headers: { Origin: ORIGIN, Authorization: `Bearer ${data.token}` },
+    tags: { endpoint },
+  };
+}
+
+export function list(data) {
+  const res = http.get(`${BASE_URL}/api/v1/consumers?page=1&limit=10`, headers(data, 'list'));
+  check(res, { 'list status is 200': (r) => r.status === 200 });
+}
+
+export function get(data) {
+  if (!data.consumerId) {
+    return;
+  }
+  const res = http.get(`${BASE_URL}/api/v1/consumers/${data.consumerId}`, headers(data, 'get'));
+  check(res, { 'get status is 200': (r) => r.status === 200 });
+}
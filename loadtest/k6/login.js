@@ -0,0 +1,73 @@
+// Login and refresh token scenario.
+// Thresholds mirror the SLOs documented in loadtest/README.md.
+import http from 'k6/http';
+import { check } from 'k6';
+
+const BASE_URL = __ENV.BASE_URL || 'http://localhost:1000';
+const ORIGIN = __ENV.ORIGIN || 'http://localhost:3000';
+const USERNAME = __ENV.USERNAME || 'admin';
+const PASSWORD = __ENV.PASSWORD || 'P@ssw0rd';
+
+const params = {
+  headers: { 'Content-Type': 'application/json', Origin: ORIGIN },
+};
+
+export const options = {
+  scenarios: {
+    login: {
+      executor: 'constant-arrival-rate',
+      exec: 'login',
+      rate: 20,
+      timeUnit: '1s',
+      duration: '1m',
+      preAllocatedVUs: 20,
+      maxVUs: 50,
+    },
+    refresh: {
+      executor: 'constant-arrival-rate',
+      exec: 'refresh',
+      rate: 50,
+      timeUnit: '1s',
+      duration: '1m',
+      preAllocatedVUs: 20,
+      maxVUs: 100,
+    },
+  },
+  thresholds: {
+    'http_req_duration{endpoint:login}': ['p(95)<300', 'p(99)<500'],
+    'http_req_duration{endpoint:refresh}': ['p(95)<100', 'p(99)<200'],
+    'http_req_failed{endpoint:login}': ['rate<0.001'],
+    'http_req_failed{endpoint:refresh}': ['rate<0.001'],
+  },
+};
+
+function doLogin() {
+  const res = http.post(`${BASE_URL}/auth/login`, JSON.stringify({ username: USERNAME, password: PASSWORD }), {
+    ...params,
+    tags: { endpoint: 'login' },
+  });
+  check(res, { 'login status is 200': (r) => r.status === 200 });
+  return res;
+}
+
+export function login() {
+  doLogin();
+}
+
+export function refresh() {
+  // Each refresh rotates the refresh token, so keep the latest one per VU
+  if (!refresh.token) {
+    const res = http.post(`${BASE_URL}/auth/login`, JSON.stringify({ username: USERNAME, password: PASSWORD }), params);
+    refresh.token = res.json('data.refreshToken');
+  }
+
+  const res = http.post(`${BASE_URL}/auth/refresh-token`, JSON.stringify({ refreshToken: refresh.token }), {
+    ...params,
+    tags: { endpoint: 'refresh' },
+  });
+  if (check(res, { 'refresh status is 200': (r) => r.status === 200 })) {
+    refresh.token = res.json('data.refreshToken');
+  } else {
+    refresh.token = null;
+  }
+}
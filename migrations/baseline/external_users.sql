@@ -0,0 +1,4 @@
+-- Description: applied after the baseline when the users are in an external user store (USER_STORE=rest or scim),
+-- whose users are not in the users table: the sessions and the roles of the users do not reference it.
+ALTER TABLE user_roles DROP CONSTRAINT fk_user_roles_user, DROP CONSTRAINT fk_user_roles_role;
+ALTER TABLE refresh_token DROP CONSTRAINT fk_refresh_token_user;
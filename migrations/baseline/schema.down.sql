@@ -0,0 +1,6 @@
+-- Description: reverts the baseline, dropping the tables of the original schema and their data.
+DROP TABLE IF EXISTS user_roles CASCADE;
+DROP TABLE IF EXISTS consumers CASCADE;
+DROP TABLE IF EXISTS refresh_token CASCADE;
+DROP TABLE IF EXISTS users CASCADE;
+DROP TABLE IF EXISTS roles CASCADE;
@@ -0,0 +1,76 @@
+-- Description: the baseline of the schema, version 1 of the migrations: the tables of the original schema, as they were
+-- created by the application before the versioned migrations, so that the databases created since the first release
+-- are baselined as they are and upgraded by the next versions. The tables are created in the schema of the search path
+-- of the connection, DB_SCHEMA. Do not edit this file: a change of the schema is a new version in the versions directory.
+
+CREATE TABLE roles (
+    id bigserial,
+    name varchar(20) NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT chk_roles_name CHECK (name IN ('ROLE_USER','ROLE_MODERATOR','ROLE_ADMIN'))
+);
+
+CREATE TABLE users (
+    id bigserial,
+    username varchar(20) NOT NULL,
+    password varchar(150) NOT NULL,
+    email varchar(100) NOT NULL,
+    firstname varchar(20) NOT NULL,
+    lastname varchar(20),
+    is_enabled boolean NOT NULL DEFAULT false,
+    is_account_non_expired boolean NOT NULL DEFAULT false,
+    is_account_non_locked boolean NOT NULL DEFAULT false,
+    is_credentials_non_expired boolean NOT NULL DEFAULT false,
+    is_deleted boolean NOT NULL DEFAULT false,
+    account_expiration_date timestamptz,
+    credentials_expiration_date timestamptz,
+    user_type varchar(20) NOT NULL,
+    last_login timestamptz,
+    created_by bigint,
+    created_at timestamptz DEFAULT now(),
+    updated_by bigint,
+    updated_at timestamptz DEFAULT now(),
+    deleted_by bigint,
+    deleted_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT uni_users_username UNIQUE (username),
+    CONSTRAINT uni_users_email UNIQUE (email),
+    CONSTRAINT chk_users_user_type CHECK (user_type IN ('SERVICE_ACCOUNT','USER_ACCOUNT'))
+);
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE user_roles (
+    user_id bigint,
+    role_id bigint,
+    PRIMARY KEY (user_id,role_id),
+    CONSTRAINT fk_user_roles_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL ON UPDATE RESTRICT,
+    CONSTRAINT fk_user_roles_role FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE SET NULL ON UPDATE RESTRICT
+);
+
+CREATE TABLE refresh_token (
+    token text NOT NULL,
+    user_id bigint NOT NULL,
+    expiry_date timestamptz NOT NULL,
+    PRIMARY KEY (token,user_id),
+    CONSTRAINT fk_refresh_token_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL ON UPDATE CASCADE,
+    CONSTRAINT uni_refresh_token_token UNIQUE (token),
+    CONSTRAINT uni_refresh_token_user_id UNIQUE (user_id)
+);
+
+CREATE TABLE consumers (
+    id uuid DEFAULT gen_random_uuid(),
+    fullname varchar(100) NOT NULL,
+    username varchar(50) NOT NULL,
+    email varchar(100) NOT NULL,
+    phone varchar(20) NOT NULL,
+    address text NOT NULL,
+    birth_date date,
+    status varchar(20) NOT NULL DEFAULT 'inactive',
+    created_at timestamptz DEFAULT now(),
+    updated_at timestamptz DEFAULT now(),
+    PRIMARY KEY (id),
+    CONSTRAINT uni_consumers_username UNIQUE (username),
+    CONSTRAINT uni_consumers_email UNIQUE (email),
+    CONSTRAINT uni_consumers_phone UNIQUE (phone),
+    CONSTRAINT chk_consumers_status CHECK (status IN ('active','inactive','suspended'))
+);
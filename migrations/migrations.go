@@ -0,0 +1,21 @@
+package migrations
+
+import "embed"
+
+/**
+ * migrations package embeds the versioned migrations of the database schema, applied in order by the migrator
+ * of the database package with DB_MIGRATE=TRUE or the migrate command, so that the binary carries its own schema.
+ * A version is a pair of files in the versions directory, NNNN_name.up.sql and NNNN_name.down.sql, the down file
+ * reverting the up file. Version 1 is the baseline, the schema frozen in the baseline directory, the files start at version 2.
+ */
+
+// Versions contains the up and down files of the versioned migrations.
+//
+//go:embed versions/*.sql
+var Versions embed.FS
+
+// Baseline contains the files of the baseline: the tables of the schema, the script reverting them,
+// and the script removing the references to the users table when the users are in an external user store.
+//
+//go:embed baseline/*.sql
+var Baseline embed.FS
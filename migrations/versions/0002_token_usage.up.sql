@@ -0,0 +1,10 @@
+-- Description: create the token_usage table counting the access tokens issued, refreshed and revoked per day, user and client.
+CREATE TABLE token_usage (
+    day date,
+    user_id bigint,
+    client varchar(100),
+    issued bigint NOT NULL DEFAULT 0,
+    refreshed bigint NOT NULL DEFAULT 0,
+    revoked bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (day,user_id,client)
+);
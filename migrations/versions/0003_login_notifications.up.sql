@@ -0,0 +1,22 @@
+-- Description: create the notification_preferences table holding the notifications chosen by the users,
+-- and the user_devices table holding the devices the users logged in from, to notify the logins from a new device.
+CREATE TABLE notification_preferences (
+    user_id bigserial,
+    new_device_login boolean NOT NULL,
+    password_changed boolean NOT NULL,
+    mfa_disabled boolean NOT NULL,
+    updated_at timestamptz,
+    PRIMARY KEY (user_id)
+);
+
+CREATE TABLE user_devices (
+    user_id bigint,
+    fingerprint varchar(64),
+    user_agent text,
+    last_ip varchar(45),
+    last_country varchar(2),
+    last_city varchar(100),
+    first_seen_at timestamptz NOT NULL,
+    last_seen_at timestamptz NOT NULL,
+    PRIMARY KEY (user_id,fingerprint)
+);
@@ -0,0 +1,2 @@
+-- Description: add the token version of the users, carried by their access tokens and incremented to revoke all of them at once.
+ALTER TABLE users ADD COLUMN token_version bigint NOT NULL DEFAULT 0;
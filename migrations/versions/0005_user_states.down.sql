@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN is_enabled boolean NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN is_account_non_expired boolean NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN is_account_non_locked boolean NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN is_credentials_non_expired boolean NOT NULL DEFAULT false;
+
+-- The flags a state was migrated from are restored as they were, the other states set the flags they stand for
+UPDATE users SET
+    is_enabled = state IN ('ACTIVE','SUSPENDED') OR (state = 'DISABLED' AND state_reason IS DISTINCT FROM 'Migrated: account not enabled'),
+    is_account_non_expired = state_reason IS DISTINCT FROM 'Migrated: account expired',
+    is_account_non_locked = state <> 'SUSPENDED',
+    is_credentials_non_expired = state_reason IS DISTINCT FROM 'Migrated: credentials expired';
+
+ALTER TABLE users DROP CONSTRAINT chk_users_state;
+ALTER TABLE users DROP COLUMN state;
+ALTER TABLE users DROP COLUMN state_reason;
+ALTER TABLE users DROP COLUMN state_changed_at;
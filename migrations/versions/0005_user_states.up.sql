@@ -0,0 +1,26 @@
+-- Description: replace the is_enabled, is_account_non_expired, is_account_non_locked and is_credentials_non_expired flags
+-- of the users with a single state. A locked account is suspended, an account that is not enabled or is expired is disabled,
+-- the others are active; the reason of the state tells which flag it was migrated from.
+ALTER TABLE users ADD COLUMN state varchar(20) NOT NULL DEFAULT 'PENDING_VERIFICATION';
+ALTER TABLE users ADD COLUMN state_reason varchar(255);
+ALTER TABLE users ADD COLUMN state_changed_at timestamptz;
+
+UPDATE users SET
+    state = CASE
+        WHEN NOT is_account_non_locked THEN 'SUSPENDED'
+        WHEN NOT is_enabled OR NOT is_account_non_expired OR NOT is_credentials_non_expired THEN 'DISABLED'
+        ELSE 'ACTIVE'
+    END,
+    state_reason = CASE
+        WHEN NOT is_account_non_locked THEN 'Migrated: account locked'
+        WHEN NOT is_enabled THEN 'Migrated: account not enabled'
+        WHEN NOT is_account_non_expired THEN 'Migrated: account expired'
+        WHEN NOT is_credentials_non_expired THEN 'Migrated: credentials expired'
+    END,
+    state_changed_at = now();
+
+ALTER TABLE users ADD CONSTRAINT chk_users_state CHECK (state IN ('ACTIVE','PENDING_VERIFICATION','DISABLED','SUSPENDED'));
+ALTER TABLE users DROP COLUMN is_enabled;
+ALTER TABLE users DROP COLUMN is_account_non_expired;
+ALTER TABLE users DROP COLUMN is_account_non_locked;
+ALTER TABLE users DROP COLUMN is_credentials_non_expired;
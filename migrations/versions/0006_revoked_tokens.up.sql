@@ -0,0 +1,11 @@
+-- Description: create the revoked_tokens table holding the access tokens revoked on logout.
+-- The entries can be removed once the tokens expire.
+CREATE TABLE revoked_tokens (
+    token_id varchar(64) NOT NULL,
+    user_id bigint NOT NULL,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (token_id)
+);
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+CREATE INDEX idx_revoked_tokens_user_id ON revoked_tokens (user_id);
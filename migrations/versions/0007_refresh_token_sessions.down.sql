@@ -0,0 +1,20 @@
+DROP INDEX IF EXISTS idx_refresh_token_session_id;
+DROP INDEX IF EXISTS idx_refresh_token_user_id;
+
+-- A user has a single refresh token again, the most recent one
+DELETE FROM refresh_token r WHERE EXISTS (
+    SELECT 1 FROM refresh_token n WHERE n.user_id = r.user_id AND (n.created_at, n.token) > (r.created_at, r.token)
+);
+
+ALTER TABLE refresh_token DROP COLUMN session_id;
+ALTER TABLE refresh_token DROP COLUMN device_id;
+ALTER TABLE refresh_token DROP COLUMN client_id;
+ALTER TABLE refresh_token DROP COLUMN user_agent;
+ALTER TABLE refresh_token DROP COLUMN ip_address;
+ALTER TABLE refresh_token DROP COLUMN session_started_at;
+ALTER TABLE refresh_token DROP COLUMN created_at;
+
+ALTER TABLE refresh_token DROP CONSTRAINT refresh_token_pkey;
+ALTER TABLE refresh_token ADD PRIMARY KEY (token, user_id);
+ALTER TABLE refresh_token ADD CONSTRAINT uni_refresh_token_token UNIQUE (token);
+ALTER TABLE refresh_token ADD CONSTRAINT uni_refresh_token_user_id UNIQUE (user_id);
@@ -0,0 +1,20 @@
+-- Description: allow several refresh tokens per user, one per session of a device, and record the device and the start of the sessions.
+-- Each existing refresh token becomes its own session, started when the migration runs.
+ALTER TABLE refresh_token DROP CONSTRAINT uni_refresh_token_user_id;
+ALTER TABLE refresh_token DROP CONSTRAINT uni_refresh_token_token;
+ALTER TABLE refresh_token DROP CONSTRAINT refresh_token_pkey;
+ALTER TABLE refresh_token ADD PRIMARY KEY (token);
+
+ALTER TABLE refresh_token ADD COLUMN session_id varchar(36);
+ALTER TABLE refresh_token ADD COLUMN device_id varchar(64);
+ALTER TABLE refresh_token ADD COLUMN client_id varchar(100);
+ALTER TABLE refresh_token ADD COLUMN user_agent varchar(255);
+ALTER TABLE refresh_token ADD COLUMN ip_address varchar(45);
+ALTER TABLE refresh_token ADD COLUMN session_started_at timestamptz NOT NULL DEFAULT now();
+ALTER TABLE refresh_token ADD COLUMN created_at timestamptz NOT NULL DEFAULT now();
+
+UPDATE refresh_token SET session_id = gen_random_uuid()::text;
+ALTER TABLE refresh_token ALTER COLUMN session_id SET NOT NULL;
+
+CREATE INDEX idx_refresh_token_user_id ON refresh_token (user_id);
+CREATE INDEX idx_refresh_token_session_id ON refresh_token (session_id);
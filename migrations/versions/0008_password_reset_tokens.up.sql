@@ -0,0 +1,13 @@
+-- Description: create the password_reset_tokens table holding the hashes of the password reset tokens.
+-- The used and expired tokens can be removed at any time.
+CREATE TABLE password_reset_tokens (
+    id bigserial,
+    user_id bigint NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (id),
+    CONSTRAINT uni_password_reset_tokens_token_hash UNIQUE (token_hash)
+);
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
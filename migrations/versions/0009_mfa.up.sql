@@ -0,0 +1,23 @@
+-- Description: create the user_mfa table holding the two-factor authentication secrets of the users, and the mfa_challenges
+-- table holding the hashes of the tokens of the logins waiting for a code. The used and expired challenges can be removed at any time.
+CREATE TABLE user_mfa (
+    user_id bigint,
+    secret varchar(64) NOT NULL,
+    enabled_at timestamptz,
+    last_used_step bigint NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id)
+);
+
+CREATE TABLE mfa_challenges (
+    id bigserial,
+    user_id bigint NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    attempts bigint NOT NULL DEFAULT 0,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (id),
+    CONSTRAINT uni_mfa_challenges_token_hash UNIQUE (token_hash)
+);
+CREATE INDEX idx_mfa_challenges_user_id ON mfa_challenges (user_id);
@@ -0,0 +1,19 @@
+-- Description: create the webhook_deliveries table holding the consumer events sent to the webhook, retried until delivered or dead.
+CREATE TABLE webhook_deliveries (
+    id bigserial,
+    event varchar(50) NOT NULL,
+    url text NOT NULL,
+    payload text NOT NULL,
+    status varchar(20) NOT NULL,
+    attempts bigint NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz,
+    last_attempt_at timestamptz,
+    response_status bigint NOT NULL DEFAULT 0,
+    response_body text,
+    last_error text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+CREATE INDEX idx_webhook_deliveries_status ON webhook_deliveries (status);
@@ -0,0 +1,8 @@
+-- Only the built-in roles are kept, the custom roles are removed from their users
+DELETE FROM user_roles WHERE role_id IN (SELECT id FROM roles WHERE name NOT IN ('ROLE_USER','ROLE_MODERATOR','ROLE_ADMIN'));
+DELETE FROM roles WHERE name NOT IN ('ROLE_USER','ROLE_MODERATOR','ROLE_ADMIN');
+
+DROP INDEX IF EXISTS idx_roles_name;
+ALTER TABLE roles DROP COLUMN description;
+ALTER TABLE roles ALTER COLUMN name TYPE varchar(20);
+ALTER TABLE roles ADD CONSTRAINT chk_roles_name CHECK (name IN ('ROLE_USER','ROLE_MODERATOR','ROLE_ADMIN'));
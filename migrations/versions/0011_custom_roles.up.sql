@@ -0,0 +1,5 @@
+-- Description: allow the custom roles, named freely and described, in addition to the built-in roles.
+ALTER TABLE roles DROP CONSTRAINT chk_roles_name;
+ALTER TABLE roles ALTER COLUMN name TYPE varchar(50);
+ALTER TABLE roles ADD COLUMN description varchar(255);
+CREATE UNIQUE INDEX idx_roles_name ON roles (name);
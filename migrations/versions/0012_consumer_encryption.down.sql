@@ -0,0 +1,8 @@
+-- The consumers are decrypted by the data step of the version, run before this file
+DROP INDEX IF EXISTS idx_consumers_email_index;
+DROP INDEX IF EXISTS idx_consumers_phone_index;
+ALTER TABLE consumers DROP COLUMN email_index;
+ALTER TABLE consumers DROP COLUMN phone_index;
+ALTER TABLE consumers ALTER COLUMN birth_date TYPE date USING birth_date::date;
+ALTER TABLE consumers ALTER COLUMN phone TYPE varchar(20);
+ALTER TABLE consumers ADD CONSTRAINT uni_consumers_phone UNIQUE (phone);
@@ -0,0 +1,10 @@
+-- Description: store the phone number, the address and the birth date of the consumers encrypted, and add the blind indexes
+-- of the email and the phone number, looked up instead of the encrypted values. The consumers are encrypted and indexed
+-- by the data step of the version, run after this file.
+ALTER TABLE consumers DROP CONSTRAINT uni_consumers_phone;
+ALTER TABLE consumers ALTER COLUMN phone TYPE text;
+ALTER TABLE consumers ALTER COLUMN birth_date TYPE text USING to_char(birth_date, 'YYYY-MM-DD');
+ALTER TABLE consumers ADD COLUMN email_index varchar(64);
+ALTER TABLE consumers ADD COLUMN phone_index varchar(64);
+CREATE UNIQUE INDEX idx_consumers_phone_index ON consumers (phone_index);
+CREATE UNIQUE INDEX idx_consumers_email_index ON consumers (email_index);
@@ -0,0 +1,11 @@
+-- Description: create the security_settings table holding the CORS origins and headers and the security headers
+-- changed at runtime by the administrators.
+CREATE TABLE security_settings (
+    id bigserial,
+    allowed_origins text NOT NULL,
+    allowed_headers text NOT NULL,
+    security_headers text NOT NULL,
+    updated_by bigint,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
@@ -0,0 +1,29 @@
+-- Description: create the permissions table and the role_permissions table granting them to the roles,
+-- and grant the default permissions to the built-in roles. The permissions are carried by the access tokens in the scopes claim.
+CREATE TABLE permissions (
+    id bigserial,
+    name varchar(100) NOT NULL,
+    description varchar(255),
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_permissions_name ON permissions (name);
+
+CREATE TABLE role_permissions (
+    role_id bigint NOT NULL,
+    permission_id bigint NOT NULL,
+    PRIMARY KEY (role_id,permission_id)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('consumers:read', 'Read the consumers'),
+    ('consumers:write', 'Create and update the consumers'),
+    ('users:read', 'Read the users'),
+    ('users:write', 'Create, update, and delete the users'),
+    ('roles:read', 'Read the roles and their permissions'),
+    ('roles:write', 'Create, update, and delete the roles and grant their permissions');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON
+    (r.name = 'ROLE_USER' AND p.name IN ('consumers:read')) OR
+    (r.name = 'ROLE_MODERATOR' AND p.name IN ('consumers:read', 'users:read')) OR
+    (r.name = 'ROLE_ADMIN');
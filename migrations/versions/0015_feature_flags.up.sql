@@ -0,0 +1,9 @@
+-- Description: create the feature_flags table holding the features disabled at runtime by the administrators.
+CREATE TABLE feature_flags (
+    name varchar(50),
+    disabled boolean NOT NULL DEFAULT false,
+    reason varchar(255),
+    updated_by bigint,
+    updated_at timestamptz,
+    PRIMARY KEY (name)
+);
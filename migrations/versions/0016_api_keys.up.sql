@@ -0,0 +1,16 @@
+-- Description: create the api_keys table holding the hashes of the API keys of the service accounts.
+-- The revoked and expired keys can be removed at any time.
+CREATE TABLE api_keys (
+    id bigserial,
+    user_id bigint NOT NULL,
+    name varchar(100) NOT NULL,
+    prefix varchar(16) NOT NULL,
+    key_hash varchar(64) NOT NULL,
+    expires_at timestamptz,
+    revoked_at timestamptz,
+    created_by bigint NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (id),
+    CONSTRAINT uni_api_keys_key_hash UNIQUE (key_hash)
+);
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
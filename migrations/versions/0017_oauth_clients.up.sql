@@ -0,0 +1,15 @@
+-- Description: create the oauth_clients table holding the client IDs and the hashes of the secrets of the OAuth2 clients
+-- of the service accounts. The revoked clients can be removed at any time.
+CREATE TABLE oauth_clients (
+    id bigserial,
+    user_id bigint NOT NULL,
+    name varchar(100) NOT NULL,
+    client_id varchar(64) NOT NULL,
+    secret_hash varchar(64) NOT NULL,
+    revoked_at timestamptz,
+    created_by bigint NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (id),
+    CONSTRAINT uni_oauth_clients_client_id UNIQUE (client_id)
+);
+CREATE INDEX idx_oauth_clients_user_id ON oauth_clients (user_id);
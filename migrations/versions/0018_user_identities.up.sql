@@ -0,0 +1,14 @@
+-- Description: create the user_identities table linking the accounts of the users at the OpenID Connect providers
+-- (e.g. Google, Microsoft) to their local user.
+CREATE TABLE user_identities (
+    id bigserial,
+    user_id bigint NOT NULL,
+    provider varchar(20) NOT NULL,
+    subject varchar(255) NOT NULL,
+    email varchar(100) NOT NULL,
+    last_login_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_user_identities_provider_subject ON user_identities (provider,subject);
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
@@ -0,0 +1,10 @@
+-- Description: create the rate_limit_overrides table holding the factors scaling the rate limits of the clients,
+-- set at runtime by the administrators.
+CREATE TABLE rate_limit_overrides (
+    client varchar(100),
+    factor decimal NOT NULL,
+    reason varchar(255),
+    updated_by bigint,
+    updated_at timestamptz,
+    PRIMARY KEY (client)
+);
@@ -0,0 +1 @@
+ALTER TABLE consumers DROP COLUMN IF EXISTS metadata;
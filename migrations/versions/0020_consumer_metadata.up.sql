@@ -0,0 +1,2 @@
+-- Description: add the metadata of the consumers, the free-form attributes set by the deployment (e.g. the tenant or the region of the consumer).
+ALTER TABLE consumers ADD COLUMN metadata jsonb;
@@ -0,0 +1,20 @@
+-- Description: create the audit_events table holding the audit log of the security-relevant actions.
+-- The events are only inserted by the service, never updated nor deleted.
+CREATE TABLE audit_events (
+    id bigserial,
+    event_id varchar(36) NOT NULL,
+    action varchar(50) NOT NULL,
+    actor_id bigint,
+    actor_username varchar(50),
+    target_type varchar(50),
+    target_id varchar(255),
+    details text,
+    ip_address varchar(45),
+    request_id varchar(128),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at);
+CREATE INDEX idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX idx_audit_events_action ON audit_events (action);
+CREATE UNIQUE INDEX idx_audit_events_event_id ON audit_events (event_id);
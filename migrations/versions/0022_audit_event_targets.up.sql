@@ -0,0 +1,2 @@
+-- Description: index the audit events by target, for the searches of the audit log by target type and ID.
+CREATE INDEX idx_audit_events_target ON audit_events (target_type, target_id);
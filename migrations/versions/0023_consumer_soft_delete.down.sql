@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_consumers_deleted_at;
+ALTER TABLE consumers DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,3 @@
+-- Description: add the deletion time of the soft-deleted consumers, left out of the queries until they are restored.
+ALTER TABLE consumers ADD COLUMN deleted_at timestamptz;
+CREATE INDEX idx_consumers_deleted_at ON consumers (deleted_at);
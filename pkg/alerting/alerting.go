@@ -0,0 +1,221 @@
+package alerting
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+/**
+ * alerting package sends the operational alerts (an authentication anomaly, a dead-lettered webhook delivery,
+ * a TLS certificate about to expire) to the operators through pluggable channels: the log, email, Slack, and SMS.
+ * ALERT_ROUTES selects the channels of every event, so that e.g. the expiring certificates page the on-call
+ * by SMS while the anomalies only go to Slack. The events without a route are written to the log.
+ *
+ * The alerts are sent by a background worker, so that a slow channel never delays a request, and the same alert
+ * (the same event and key) is sent at most once per ALERT_DEDUP_MINUTES, so that an ongoing attack does not
+ * flood the channels.
+ */
+
+// The events an alert is raised for.
+const (
+	EventAnomaly           = "anomaly"
+	EventWebhookDead       = "webhook_dead"
+	EventCertificateExpiry = "certificate_expiry"
+)
+
+// Events lists the events an alert is raised for.
+var Events = []string{EventAnomaly, EventWebhookDead, EventCertificateExpiry}
+
+const (
+	// DefaultQueueSize is the number of alerts waiting to be sent before the new ones are dropped.
+	DefaultQueueSize = 100
+
+	// DefaultDedupWindow is the time during which the same alert is not sent again.
+	DefaultDedupWindow = 15 * time.Minute
+)
+
+// Alert is an operational alert. Key identifies what the alert is about, e.g. the subject of an anomaly,
+// the alerts with the same event and key being deduplicated; an empty key is never deduplicated.
+type Alert struct {
+	Event     string
+	Key       string
+	Title     string
+	Message   string
+	Fields    map[string]string
+	Timestamp time.Time
+}
+
+// Text returns the alert as plain text, the title followed by the message and the fields sorted by name.
+func (a Alert) Text() string {
+	var b strings.Builder
+	b.WriteString(a.Title)
+	if a.Message != "" {
+		b.WriteString("\n" + a.Message)
+	}
+
+	names := make([]string, 0, len(a.Fields))
+	for name := range a.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n%s: %s", name, a.Fields[name])
+	}
+
+	return b.String()
+}
+
+// Interface for alert channel
+// This interface defines the methods that the channels sending the alerts should implement
+type Channel interface {
+	Name() string
+	Send(a Alert) error
+}
+
+// Interface for notifier
+// This interface defines the methods that the notifiers raising the alerts should implement
+type Notifier interface {
+	Notify(a Alert)
+}
+
+// Dispatcher is the Notifier sending the alerts to the channels routed to their event, with a background worker.
+// A nil *Dispatcher is valid and drops the alerts.
+type Dispatcher struct {
+	mu          sync.RWMutex
+	routes      map[string][]Channel
+	fallback    []Channel
+	dedupWindow time.Duration
+	clock       clock.Clock
+	sent        map[string]time.Time
+	queue       chan Alert
+	done        chan struct{}
+	closed      bool
+}
+
+// NewDispatcher creates a new instance of Dispatcher sending the alerts of every event to its routed channels,
+// the events without a route going to the fallback channels, and starts its background worker.
+// The same alert is not sent again during the dedup window; a non-positive window disables the deduplication.
+// A non-positive queue size falls back to the default.
+func NewDispatcher(routes map[string][]Channel, fallback []Channel, dedupWindow time.Duration, queueSize int, clk clock.Clock) *Dispatcher {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+
+	d := &Dispatcher{
+		routes:      routes,
+		fallback:    fallback,
+		dedupWindow: dedupWindow,
+		clock:       clk,
+		sent:        make(map[string]time.Time),
+		queue:       make(chan Alert, queueSize),
+		done:        make(chan struct{}),
+	}
+	go d.run()
+
+	return d
+}
+
+// Notify queues the alert without waiting for it to be sent.
+// When the queue is full or the dispatcher is closed, the alert is dropped and a warning is logged.
+func (d *Dispatcher) Notify(a Alert) {
+	if d == nil {
+		return
+	}
+	if a.Timestamp.IsZero() {
+		a.Timestamp = d.clock.Now()
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if !d.closed {
+		select {
+		case d.queue <- a:
+			return
+		default:
+		}
+	}
+
+	logger.Warn("Dropped an alert, the alert queue is full or closed", logrus.Fields{
+		"event": a.Event,
+		"key":   a.Key,
+	})
+}
+
+// Close stops the background worker after sending the queued alerts.
+// It is safe to call Close more than once.
+func (d *Dispatcher) Close() {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	<-d.done
+}
+
+// Channels returns the channels the alerts of the event are sent to.
+func (d *Dispatcher) Channels(event string) []Channel {
+	if channels, ok := d.routes[event]; ok {
+		return channels
+	}
+	return d.fallback
+}
+
+// run sends the queued alerts until the dispatcher is closed.
+// A failed channel is logged and not retried, the other channels of the alert are still sent.
+func (d *Dispatcher) run() {
+	defer close(d.done)
+
+	for a := range d.queue {
+		if d.duplicate(a) {
+			continue
+		}
+
+		for _, ch := range d.Channels(a.Event) {
+			if err := ch.Send(a); err != nil {
+				logger.Error(fmt.Sprintf("Failed to send the alert: %v", err), logrus.Fields{
+					"event":   a.Event,
+					"key":     a.Key,
+					"channel": ch.Name(),
+				})
+			}
+		}
+	}
+}
+
+// duplicate reports whether the same alert was sent during the dedup window, and records the alert otherwise.
+// It is only called by the worker.
+func (d *Dispatcher) duplicate(a Alert) bool {
+	if a.Key == "" || d.dedupWindow <= 0 {
+		return false
+	}
+
+	now := d.clock.Now()
+	for key, at := range d.sent {
+		if !now.Before(at.Add(d.dedupWindow)) {
+			delete(d.sent, key)
+		}
+	}
+
+	key := a.Event + ":" + a.Key
+	if _, ok := d.sent[key]; ok {
+		return true
+	}
+	d.sent[key] = now
+
+	return false
+}
@@ -0,0 +1,150 @@
+package alerting
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+const (
+	// DefaultCertificateExpiryWarning is how long before the expiry of the TLS certificate the alerts are raised.
+	DefaultCertificateExpiryWarning = 14 * 24 * time.Hour
+
+	// DefaultCertificateCheckInterval is how often the expiry of the TLS certificate is checked.
+	DefaultCertificateCheckInterval = 24 * time.Hour
+)
+
+// LoadCertificateExpiryWarning reads how long before the expiry of the TLS certificate the alerts are raised
+// from ALERT_CERT_EXPIRY_DAYS. Missing or invalid values fall back to the default.
+func LoadCertificateExpiryWarning() time.Duration {
+	if n, err := strconv.Atoi(env.Getenv("ALERT_CERT_EXPIRY_DAYS")); err == nil && n > 0 {
+		return time.Duration(n) * 24 * time.Hour
+	}
+
+	return DefaultCertificateExpiryWarning
+}
+
+// CertificateExpiry reads the PEM certificate file, and returns the expiry of its first certificate, the leaf one.
+func CertificateExpiry(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read the certificate: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return time.Time{}, fmt.Errorf("%s does not hold a PEM certificate", path)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse the certificate: %w", err)
+	}
+
+	return cert.NotAfter, nil
+}
+
+// CertificateMonitor raises a certificate_expiry alert when the TLS certificate of the server expires
+// within the warning, checking it right away and then every interval.
+type CertificateMonitor struct {
+	notifier  Notifier
+	path      string
+	warning   time.Duration
+	interval  time.Duration
+	clock     clock.Clock
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewCertificateMonitor creates a new instance of CertificateMonitor checking the certificate file
+// with the given warning, and starts its background worker.
+// A non-positive interval falls back to the default.
+func NewCertificateMonitor(notifier Notifier, path string, warning time.Duration, interval time.Duration, clk clock.Clock) *CertificateMonitor {
+	if interval <= 0 {
+		interval = DefaultCertificateCheckInterval
+	}
+
+	m := &CertificateMonitor{
+		notifier: notifier,
+		path:     path,
+		warning:  warning,
+		interval: interval,
+		clock:    clk,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go m.run()
+
+	return m
+}
+
+// Check checks the certificate once, and raises an alert if it expires within the warning, or has expired.
+// It returns whether an alert was raised. The certificate being read at every check, a renewed one is picked up.
+func (m *CertificateMonitor) Check() (bool, error) {
+	notAfter, err := CertificateExpiry(m.path)
+	if err != nil {
+		return false, err
+	}
+
+	left := notAfter.Sub(m.clock.Now())
+	if left > m.warning {
+		return false, nil
+	}
+
+	message := fmt.Sprintf("The TLS certificate of the server expires in %d day(s), renew it before %s.", int(left.Hours()/24), notAfter.UTC().Format(time.RFC3339))
+	if left <= 0 {
+		message = fmt.Sprintf("The TLS certificate of the server expired on %s, renew it now.", notAfter.UTC().Format(time.RFC3339))
+	}
+
+	m.notifier.Notify(Alert{
+		Event:   EventCertificateExpiry,
+		Key:     m.path,
+		Title:   "TLS certificate about to expire",
+		Message: message,
+		Fields: map[string]string{
+			"certificate": m.path,
+			"not_after":   notAfter.UTC().Format(time.RFC3339),
+		},
+	})
+
+	return true, nil
+}
+
+// Close stops the background worker. It is safe to call Close more than once.
+func (m *CertificateMonitor) Close() {
+	m.closeOnce.Do(func() {
+		close(m.stop)
+	})
+	<-m.done
+}
+
+// run checks the certificate right away, then every interval until the monitor is closed.
+func (m *CertificateMonitor) run() {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.Check(); err != nil {
+			logger.Error(fmt.Sprintf("Failed to check the expiry of the TLS certificate: %v", err), logrus.Fields{"certificate": m.path})
+		}
+
+		select {
+		case <-ticker.C:
+		case <-m.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package alerting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/mailer"
+)
+
+// The channels an event can be routed to.
+const (
+	ChannelLog   = "log"
+	ChannelEmail = "email"
+	ChannelSlack = "slack"
+	ChannelSMS   = "sms"
+)
+
+// DefaultTimeout is the time Slack and the SMS provider have to answer an alert.
+const DefaultTimeout = 10 * time.Second
+
+// maxSMSLength is the number of characters of an SMS, longer alerts being truncated.
+const maxSMSLength = 320
+
+// This struct defines the Channel that writes the alerts to the warning log
+type logChannel struct{}
+
+// NewLogChannel creates a new instance of Channel that writes the alerts to the warning log.
+func NewLogChannel() Channel {
+	return &logChannel{}
+}
+
+// Name returns the name of the channel.
+func (c *logChannel) Name() string {
+	return ChannelLog
+}
+
+// Send writes the alert to the warning log.
+func (c *logChannel) Send(a Alert) error {
+	fields := logrus.Fields{"event": a.Event, "alert_message": a.Message}
+	for name, value := range a.Fields {
+		fields[name] = value
+	}
+	logger.Warn(fmt.Sprintf("Alert: %s", a.Title), fields)
+
+	return nil
+}
+
+// This struct defines the Channel that emails the alerts to the operators with a mailer
+type emailChannel struct {
+	mailer     mailer.Mailer
+	recipients []string
+}
+
+// NewEmailChannel creates a new instance of Channel that emails the alerts to the given recipients with the mailer.
+func NewEmailChannel(m mailer.Mailer, recipients []string) Channel {
+	return &emailChannel{mailer: m, recipients: recipients}
+}
+
+// Name returns the name of the channel.
+func (c *emailChannel) Name() string {
+	return ChannelEmail
+}
+
+// Send emails the alert to every recipient, and returns the first error.
+func (c *emailChannel) Send(a Alert) error {
+	var firstErr error
+	for _, to := range c.recipients {
+		err := c.mailer.Send(mailer.Message{
+			To:      to,
+			Subject: "[Alert] " + a.Title,
+			Body:    a.Text() + "\n\nRaised at " + a.Timestamp.UTC().Format(time.RFC3339),
+		})
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to email the alert to %s: %w", to, err)
+		}
+	}
+
+	return firstErr
+}
+
+// This struct defines the Channel that posts the alerts to a Slack incoming webhook
+type slackChannel struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackChannel creates a new instance of Channel that posts the alerts to the given Slack incoming webhook.
+// A nil client falls back to a client with the default timeout.
+func NewSlackChannel(webhookURL string, client *http.Client) Channel {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	return &slackChannel{webhookURL: webhookURL, client: client}
+}
+
+// Name returns the name of the channel.
+func (c *slackChannel) Name() string {
+	return ChannelSlack
+}
+
+// Send posts the alert as the text of a Slack message.
+func (c *slackChannel) Send(a Alert) error {
+	body, err := json.Marshal(map[string]string{"text": ":rotating_light: *" + a.Title + "*" + strings.TrimPrefix(a.Text(), a.Title)})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Post(c.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post the alert to Slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponse("Slack", resp)
+}
+
+// SMSConfig holds the account of the SMS provider, an API compatible with the Messages API of Twilio,
+// and the phone numbers the alerts are sent from and to.
+type SMSConfig struct {
+	APIURL     string
+	AccountSID string
+	AuthToken  string
+	From       string
+	To         []string
+}
+
+// This struct defines the Channel that sends the alerts by SMS
+type smsChannel struct {
+	config SMSConfig
+	client *http.Client
+}
+
+// NewSMSChannel creates a new instance of Channel that sends the alerts by SMS with the given provider account.
+// A nil client falls back to a client with the default timeout.
+func NewSMSChannel(cfg SMSConfig, client *http.Client) Channel {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	return &smsChannel{config: cfg, client: client}
+}
+
+// Name returns the name of the channel.
+func (c *smsChannel) Name() string {
+	return ChannelSMS
+}
+
+// Send sends the alert by SMS to every phone number, and returns the first error.
+// An SMS being short, it holds the title and the message of the alert, without its fields.
+func (c *smsChannel) Send(a Alert) error {
+	text := a.Title
+	if a.Message != "" {
+		text += ": " + a.Message
+	}
+	if len(text) > maxSMSLength {
+		text = text[:maxSMSLength-3] + "..."
+	}
+
+	endpoint := strings.TrimSuffix(c.config.APIURL, "/") + "/2010-04-01/Accounts/" + url.PathEscape(c.config.AccountSID) + "/Messages.json"
+
+	var firstErr error
+	for _, to := range c.config.To {
+		if err := c.send(endpoint, to, text); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// send sends a single SMS.
+func (c *smsChannel) send(endpoint string, to string, text string) error {
+	form := url.Values{"From": {c.config.From}, "To": {to}, "Body": {text}}
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(c.config.AccountSID, c.config.AuthToken)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send the alert by SMS to %s: %w", to, err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponse("The SMS provider", resp)
+}
+
+// checkResponse returns an error with the beginning of the body if the response is not a 2xx response.
+func checkResponse(receiver string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("%s responded with status %d: %s", receiver, resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,150 @@
+package alerting
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/mailer"
+)
+
+// DefaultSMSAPIURL is the API the SMS are sent with, when ALERT_SMS_API_URL is not set.
+const DefaultSMSAPIURL = "https://api.twilio.com"
+
+// Config holds the routes of the events and the settings of the channels.
+type Config struct {
+	Routes          map[string][]string
+	DedupWindow     time.Duration
+	EmailTo         []string
+	SlackWebhookURL string
+	SMS             SMSConfig
+}
+
+// LoadConfig reads the alerting configuration from the environment variables.
+// It returns an error if ALERT_ROUTES is invalid, the configuration validation reports it at boot.
+func LoadConfig() (Config, error) {
+	routes, err := ParseRoutes(env.Getenv("ALERT_ROUTES"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg := Config{
+		Routes:          routes,
+		DedupWindow:     DefaultDedupWindow,
+		EmailTo:         splitList(env.Getenv("ALERT_EMAIL_TO")),
+		SlackWebhookURL: env.Getenv("ALERT_SLACK_WEBHOOK_URL"),
+		SMS: SMSConfig{
+			APIURL:     env.Getenv("ALERT_SMS_API_URL"),
+			AccountSID: env.Getenv("ALERT_SMS_ACCOUNT_SID"),
+			AuthToken:  env.Getenv("ALERT_SMS_AUTH_TOKEN"),
+			From:       env.Getenv("ALERT_SMS_FROM"),
+			To:         splitList(env.Getenv("ALERT_SMS_TO")),
+		},
+	}
+	if cfg.SMS.APIURL == "" {
+		cfg.SMS.APIURL = DefaultSMSAPIURL
+	}
+	if n, err := strconv.Atoi(env.Getenv("ALERT_DEDUP_MINUTES")); err == nil && n >= 0 {
+		cfg.DedupWindow = time.Duration(n) * time.Minute
+	}
+
+	return cfg, nil
+}
+
+// ParseRoutes parses a comma separated list of event=channels pairs, the channels being separated by "|",
+// e.g. "anomaly=slack,certificate_expiry=email|sms,webhook_dead=". An event routed to no channel is not sent.
+// It returns an error if a pair is malformed, or an event or a channel is unknown.
+func ParseRoutes(value string) (map[string][]string, error) {
+	routes := make(map[string][]string)
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		event, channels, ok := strings.Cut(pair, "=")
+		event = strings.ToLower(strings.TrimSpace(event))
+		if !ok || event == "" {
+			return nil, fmt.Errorf("invalid alert route %q, expected event=channels", pair)
+		}
+		if !slices.Contains(Events, event) {
+			return nil, fmt.Errorf("invalid alert route %q, unknown event %q", pair, event)
+		}
+
+		routed := []string{}
+		for _, channel := range strings.Split(channels, "|") {
+			switch channel = strings.ToLower(strings.TrimSpace(channel)); channel {
+			case "":
+				continue
+			case ChannelLog, ChannelEmail, ChannelSlack, ChannelSMS:
+				if !slices.Contains(routed, channel) {
+					routed = append(routed, channel)
+				}
+			default:
+				return nil, fmt.Errorf("invalid alert route %q, unknown channel %q", pair, channel)
+			}
+		}
+		routes[event] = routed
+	}
+
+	return routes, nil
+}
+
+// Validate checks that every routed channel is configured: the recipients of the emails,
+// the Slack webhook, and the account and phone numbers of the SMS.
+func (c Config) Validate() error {
+	for _, event := range Events {
+		for _, channel := range c.Routes[event] {
+			switch {
+			case channel == ChannelEmail && len(c.EmailTo) == 0:
+				return fmt.Errorf("%s alerts are routed to email, but ALERT_EMAIL_TO is not set", event)
+			case channel == ChannelSlack && c.SlackWebhookURL == "":
+				return fmt.Errorf("%s alerts are routed to slack, but ALERT_SLACK_WEBHOOK_URL is not set", event)
+			case channel == ChannelSMS && (c.SMS.AccountSID == "" || c.SMS.AuthToken == "" || c.SMS.From == "" || len(c.SMS.To) == 0):
+				return fmt.Errorf("%s alerts are routed to sms, but ALERT_SMS_ACCOUNT_SID, ALERT_SMS_AUTH_TOKEN, ALERT_SMS_FROM, or ALERT_SMS_TO is not set", event)
+			}
+		}
+	}
+
+	return nil
+}
+
+// New creates the dispatcher of the alerts with the given configuration, the emails being sent with the mailer.
+// The events without a route are written to the log.
+// It returns an error if a routed channel is not configured.
+func New(cfg Config, m mailer.Mailer, clk clock.Clock) (*Dispatcher, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	channels := map[string]Channel{
+		ChannelLog:   NewLogChannel(),
+		ChannelEmail: NewEmailChannel(m, cfg.EmailTo),
+		ChannelSlack: NewSlackChannel(cfg.SlackWebhookURL, nil),
+		ChannelSMS:   NewSMSChannel(cfg.SMS, nil),
+	}
+
+	routes := make(map[string][]Channel)
+	for event, names := range cfg.Routes {
+		routes[event] = []Channel{}
+		for _, name := range names {
+			routes[event] = append(routes[event], channels[name])
+		}
+	}
+
+	return NewDispatcher(routes, []Channel{channels[ChannelLog]}, cfg.DedupWindow, DefaultQueueSize, clk), nil
+}
+
+// splitList splits a comma separated list, ignoring the empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,587 @@
+package anomaly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/alerting"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/eventbus"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+/**
+ * anomaly package provides a behavioral anomaly detection subsystem for authentication traffic.
+ * It tracks per-IP, per-account and per-ASN sliding-window counters (failed logins, token errors)
+ * and remembers the last known geo/ASN information of each account to detect location changes.
+ * When a rule is triggered, the configured actions are applied: log, step-up auth requirement,
+ * temporary block, tarpit (the logins of the source are delayed), CAPTCHA challenge (the logins of the source
+ * require a solved CAPTCHA), webhook alert, and operational alert sent to the channels routed to the anomaly event.
+ * Every anomaly is also published to the event bus, if any, pushing it to the connected admin UIs,
+ * with the security events of the logins (see SecurityEvent).
+ * The counters of the sources gone quiet and the expired restrictions are swept once per window, and the number of
+ * counters is capped (ANOMALY_MAX_TRACKED_KEYS), the least recently seen being dropped first, so that a credential
+ * stuffing run spreading over many IPs and usernames cannot grow the memory of the detector without bound.
+ */
+
+// DefaultMaxTrackedKeys is the default number of sliding windows tracked by the detector.
+const DefaultMaxTrackedKeys = 100000
+
+// EventType represents the type of an authentication event observed by the detector.
+type EventType string
+
+// Action represents an action taken by the detector when an anomaly is detected.
+type Action string
+
+const (
+	EventFailedLogin  EventType = "failed_login"
+	EventTokenError   EventType = "token_error"
+	EventLoginSuccess EventType = "login_success"
+
+	ActionLog     Action = "log"
+	ActionStepUp  Action = "step_up"
+	ActionBlock   Action = "block"
+	ActionTarpit  Action = "tarpit"
+	ActionCaptcha Action = "captcha"
+	ActionWebhook Action = "webhook"
+	ActionAlert   Action = "alert"
+)
+
+// Event represents a single authentication event.
+// Country and ASN are optional and only used for geo/ASN change detection.
+type Event struct {
+	Type      EventType `json:"type"`
+	IP        string    `json:"ip"`
+	Account   string    `json:"account,omitempty"`
+	Country   string    `json:"country,omitempty"`
+	ASN       string    `json:"asn,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Alert represents an anomaly detected by the detector.
+// It is logged and sent to the webhook (if configured) as JSON.
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Subject   string    `json:"subject"`
+	Count     int       `json:"count,omitempty"`
+	Event     Event     `json:"event"`
+	Actions   []Action  `json:"actions"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Config holds the thresholds and actions used by the detector.
+// The failed logins of an ASN have their own threshold, an autonomous system gathering the clients of many networks.
+type Config struct {
+	Enabled                 bool
+	Window                  time.Duration
+	FailedLoginThreshold    int
+	FailedLoginASNThreshold int
+	TokenErrorThreshold     int
+	DetectGeoChange         bool
+	BlockDuration           time.Duration
+	StepUpDuration          time.Duration
+	TarpitDuration          time.Duration
+	TarpitDelay             time.Duration
+	CaptchaDuration         time.Duration
+	Actions                 []Action
+	WebhookURL              string
+
+	// MaxTrackedKeys caps the number of sliding windows, one per IP, account and ASN seen in the window
+	MaxTrackedKeys int
+}
+
+// Source identifies where a login comes from: the client IP, the account it targets, and the ASN of the client IP if known.
+type Source struct {
+	IP      string
+	Account string
+	ASN     string
+}
+
+// subjects returns the subjects of the rules matching the source.
+func (s Source) subjects() []string {
+	subjects := []string{"ip:" + s.IP}
+	if s.Account != "" {
+		subjects = append(subjects, "account:"+strings.ToLower(s.Account))
+	}
+	if s.ASN != "" {
+		subjects = append(subjects, "asn:"+s.ASN)
+	}
+	return subjects
+}
+
+// Verdict holds the restrictions applied to the logins of a source by the actions of the anomalies it triggered.
+type Verdict struct {
+	Blocked bool
+	Tarpit  time.Duration
+	Captcha bool
+}
+
+// geoInfo holds the last known location of an account.
+type geoInfo struct {
+	Country string
+	ASN     string
+}
+
+// Detector tracks authentication events and applies actions when anomalies are detected.
+type Detector struct {
+	mu        sync.Mutex
+	config    Config
+	clock     clock.Clock
+	windows   map[string][]time.Time
+	lastSweep time.Time
+	blocked   map[string]time.Time
+	stepUp    map[string]time.Time
+	tarpit    map[string]time.Time
+	captcha   map[string]time.Time
+	geo       map[string]geoInfo
+	client    *http.Client
+	notifier  alerting.Notifier
+	events    eventbus.Publisher
+}
+
+var (
+	once     sync.Once
+	detector *Detector
+)
+
+// LoadConfig loads the anomaly detection configuration from environment variables.
+// Missing or invalid values fall back to sensible defaults.
+func LoadConfig() Config {
+	cfg := Config{
+		Enabled:                 env.Getenv("ANOMALY_DETECTION_ENABLED") == "TRUE",
+		Window:                  getEnvMinutes("ANOMALY_WINDOW_MINUTES", 15),
+		FailedLoginThreshold:    getEnvInt("ANOMALY_FAILED_LOGIN_THRESHOLD", 5),
+		FailedLoginASNThreshold: getEnvInt("ANOMALY_FAILED_LOGIN_ASN_THRESHOLD", 50),
+		TokenErrorThreshold:     getEnvInt("ANOMALY_TOKEN_ERROR_THRESHOLD", 20),
+		DetectGeoChange:         env.Getenv("ANOMALY_DETECT_GEO_CHANGE") == "TRUE",
+		BlockDuration:           getEnvMinutes("ANOMALY_BLOCK_MINUTES", 15),
+		StepUpDuration:          getEnvMinutes("ANOMALY_STEP_UP_MINUTES", 30),
+		TarpitDuration:          getEnvMinutes("ANOMALY_TARPIT_MINUTES", 15),
+		TarpitDelay:             time.Duration(getEnvInt("ANOMALY_TARPIT_DELAY_MS", 3000)) * time.Millisecond,
+		CaptchaDuration:         getEnvMinutes("ANOMALY_CAPTCHA_MINUTES", 30),
+		WebhookURL:              env.Getenv("ANOMALY_WEBHOOK_URL"),
+		MaxTrackedKeys:          getEnvInt("ANOMALY_MAX_TRACKED_KEYS", DefaultMaxTrackedKeys),
+	}
+
+	// Parse the comma separated list of actions, e.g. "log,block,webhook,alert"
+	actions := env.Getenv("ANOMALY_ACTIONS")
+	if actions == "" {
+		actions = string(ActionLog)
+	}
+	for _, a := range strings.Split(actions, ",") {
+		switch action := Action(strings.ToLower(strings.TrimSpace(a))); action {
+		case ActionLog, ActionStepUp, ActionBlock, ActionTarpit, ActionCaptcha, ActionWebhook, ActionAlert:
+			cfg.Actions = append(cfg.Actions, action)
+		}
+	}
+
+	return cfg
+}
+
+// Init initializes the detector singleton using the configuration from environment variables and the system time.
+func Init() {
+	InitWithConfig(LoadConfig(), clock.New())
+}
+
+// InitWithConfig initializes the detector singleton with the given configuration and clock.
+func InitWithConfig(cfg Config, clk clock.Clock) {
+	once.Do(func() {
+		detector = NewDetector(cfg, clk)
+	})
+}
+
+// GetDetector returns the initialized detector instance.
+func GetDetector() *Detector {
+	if detector == nil {
+		Init()
+	}
+	return detector
+}
+
+// NewDetector creates a new instance of Detector with the given configuration,
+// and the clock giving the time of the events without a timestamp and of the checks of the restrictions.
+func NewDetector(cfg Config, clk clock.Clock) *Detector {
+	if cfg.MaxTrackedKeys <= 0 {
+		cfg.MaxTrackedKeys = DefaultMaxTrackedKeys
+	}
+
+	return &Detector{
+		config:  cfg,
+		clock:   clk,
+		windows: make(map[string][]time.Time),
+		blocked: make(map[string]time.Time),
+		stepUp:  make(map[string]time.Time),
+		tarpit:  make(map[string]time.Time),
+		captcha: make(map[string]time.Time),
+		geo:     make(map[string]geoInfo),
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// SetNotifier sets the notifier raising the alerts of the alert action.
+// The alert action does nothing until a notifier is set.
+func (d *Detector) SetNotifier(n alerting.Notifier) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notifier = n
+}
+
+// SetEventPublisher sets the publisher the anomalies are published to, with the anomaly.detected topic,
+// and the security events, with the security.event topic. They are not published until a publisher is set.
+func (d *Detector) SetEventPublisher(p eventbus.Publisher) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = p
+}
+
+// Record registers an authentication event and evaluates the detection rules.
+// It returns the alerts triggered by the event, if any.
+func (d *Detector) Record(e Event) []Alert {
+	if d == nil || !d.config.Enabled {
+		return nil
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = d.clock.Now()
+	}
+
+	d.mu.Lock()
+	var alerts []Alert
+	switch e.Type {
+	case EventFailedLogin:
+		if e.IP != "" {
+			if n := d.hit("failed_login:ip:"+e.IP, e.Timestamp); n >= d.config.FailedLoginThreshold {
+				alerts = append(alerts, d.newAlert("failed_login_per_ip", "ip:"+e.IP, n, e))
+			}
+		}
+		if e.Account != "" {
+			if n := d.hit("failed_login:account:"+strings.ToLower(e.Account), e.Timestamp); n >= d.config.FailedLoginThreshold {
+				alerts = append(alerts, d.newAlert("failed_login_per_account", "account:"+strings.ToLower(e.Account), n, e))
+			}
+		}
+		if e.ASN != "" {
+			if n := d.hit("failed_login:asn:"+e.ASN, e.Timestamp); n >= d.config.FailedLoginASNThreshold {
+				alerts = append(alerts, d.newAlert("failed_login_per_asn", "asn:"+e.ASN, n, e))
+			}
+		}
+	case EventTokenError:
+		if e.IP != "" {
+			if n := d.hit("token_error:ip:"+e.IP, e.Timestamp); n >= d.config.TokenErrorThreshold {
+				alerts = append(alerts, d.newAlert("token_error_per_ip", "ip:"+e.IP, n, e))
+			}
+		}
+	case EventLoginSuccess:
+		if d.config.DetectGeoChange && e.Account != "" && (e.Country != "" || e.ASN != "") {
+			account := strings.ToLower(e.Account)
+			last, seen := d.geo[account]
+			if seen && (last.Country != e.Country || last.ASN != e.ASN) {
+				alerts = append(alerts, d.newAlert("geo_change", "account:"+account, 0, e))
+			}
+			d.geo[account] = geoInfo{Country: e.Country, ASN: e.ASN}
+		}
+	}
+
+	for _, a := range alerts {
+		d.apply(a)
+	}
+	d.mu.Unlock()
+
+	// Notify outside of the lock since it may perform network calls
+	if e.Type == EventFailedLogin {
+		d.Emit(context.Background(), SecurityEvent{
+			Type:      SecurityEventLoginFailed,
+			IP:        e.IP,
+			Account:   e.Account,
+			Country:   e.Country,
+			ASN:       e.ASN,
+			Timestamp: e.Timestamp,
+		})
+	}
+	for _, a := range alerts {
+		d.notify(a)
+	}
+
+	return alerts
+}
+
+// IsBlocked reports whether the given IP or account is temporarily blocked.
+func (d *Detector) IsBlocked(ip string, account string) bool {
+	return d.Check(Source{IP: ip, Account: account}).Blocked
+}
+
+// Check returns the restrictions applied to the logins of the source: whether its IP, account or ASN is temporarily blocked,
+// the delay of its logins if it is tarpitted, and whether its logins require a solved CAPTCHA.
+func (d *Detector) Check(src Source) Verdict {
+	if d == nil || !d.config.Enabled {
+		return Verdict{}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.clock.Now()
+	var v Verdict
+	for _, key := range src.subjects() {
+		v.Blocked = v.Blocked || active(d.blocked, key, now)
+		if active(d.tarpit, key, now) {
+			v.Tarpit = d.config.TarpitDelay
+		}
+		v.Captcha = v.Captcha || active(d.captcha, key, now)
+	}
+	return v
+}
+
+// active reports whether the restriction of the key is active, removing it once it has expired.
+// The caller must hold the lock.
+func active(restrictions map[string]time.Time, key string, now time.Time) bool {
+	until, ok := restrictions[key]
+	if !ok {
+		return false
+	}
+	if now.Before(until) {
+		return true
+	}
+	delete(restrictions, key)
+	return false
+}
+
+// RequiresStepUp reports whether the given account must complete a step-up authentication.
+func (d *Detector) RequiresStepUp(account string) bool {
+	if d == nil || !d.config.Enabled || account == "" {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return active(d.stepUp, "account:"+strings.ToLower(account), d.clock.Now())
+}
+
+// Reset clears the counters of the given account, e.g. after a successful login.
+func (d *Detector) Reset(account string) {
+	if d == nil || account == "" {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.windows, "failed_login:account:"+strings.ToLower(account))
+}
+
+// Tracked returns the number of sliding windows tracked by the detector.
+func (d *Detector) Tracked() int {
+	if d == nil {
+		return 0
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.windows)
+}
+
+// hit adds a timestamp to the sliding window identified by key and returns the number of hits in the window.
+// The windows are swept once per window, and the least recently seen are dropped when a new one would exceed the cap.
+// The caller must hold the lock.
+func (d *Detector) hit(key string, ts time.Time) int {
+	cutoff := ts.Add(-d.config.Window)
+	if !ts.Before(d.lastSweep.Add(d.config.Window)) {
+		d.sweep(ts)
+	}
+
+	hits, tracked := d.windows[key]
+	if !tracked && len(d.windows) >= d.config.MaxTrackedKeys {
+		d.sweep(ts)
+		d.evict()
+	}
+
+	// Drop the timestamps that fall outside of the window
+	i := 0
+	for i < len(hits) && hits[i].Before(cutoff) {
+		i++
+	}
+	hits = append(hits[i:], ts)
+	d.windows[key] = hits
+
+	return len(hits)
+}
+
+// sweep removes the windows without a timestamp in the window ending at now, and the expired restrictions.
+// The caller must hold the lock.
+func (d *Detector) sweep(now time.Time) {
+	cutoff := now.Add(-d.config.Window)
+	for key, hits := range d.windows {
+		if len(hits) == 0 || hits[len(hits)-1].Before(cutoff) {
+			delete(d.windows, key)
+		}
+	}
+	for _, restrictions := range []map[string]time.Time{d.blocked, d.stepUp, d.tarpit, d.captcha} {
+		for key := range restrictions {
+			active(restrictions, key, now)
+		}
+	}
+	d.lastSweep = now
+}
+
+// evict drops the least recently seen windows, a tenth of the cap at once so that the next new keys do not evict again,
+// when the windows still reach the cap once swept. The caller must hold the lock.
+func (d *Detector) evict() {
+	if len(d.windows) < d.config.MaxTrackedKeys {
+		return
+	}
+
+	type seen struct {
+		key  string
+		last time.Time
+	}
+	windows := make([]seen, 0, len(d.windows))
+	for key, hits := range d.windows {
+		windows = append(windows, seen{key: key, last: hits[len(hits)-1]})
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].last.Before(windows[j].last) })
+
+	n := len(d.windows) - d.config.MaxTrackedKeys + max(d.config.MaxTrackedKeys/10, 1)
+	for _, w := range windows[:min(n, len(windows))] {
+		delete(d.windows, w.key)
+	}
+}
+
+// newAlert creates a new alert with the configured actions.
+func (d *Detector) newAlert(rule string, subject string, count int, e Event) Alert {
+	return Alert{
+		Rule:      rule,
+		Subject:   subject,
+		Count:     count,
+		Event:     e,
+		Actions:   d.config.Actions,
+		Timestamp: e.Timestamp,
+	}
+}
+
+// apply applies the stateful actions (block, step-up, tarpit, and CAPTCHA challenge) of an alert.
+// The caller must hold the lock.
+func (d *Detector) apply(a Alert) {
+	for _, action := range a.Actions {
+		switch action {
+		case ActionBlock:
+			d.blocked[a.Subject] = a.Timestamp.Add(d.config.BlockDuration)
+		case ActionTarpit:
+			d.tarpit[a.Subject] = a.Timestamp.Add(d.config.TarpitDuration)
+		case ActionCaptcha:
+			d.captcha[a.Subject] = a.Timestamp.Add(d.config.CaptchaDuration)
+		case ActionStepUp:
+			if a.Event.Account != "" {
+				d.stepUp["account:"+strings.ToLower(a.Event.Account)] = a.Timestamp.Add(d.config.StepUpDuration)
+			}
+		}
+	}
+}
+
+// notify applies the notification actions (log, webhook, and alert) of an alert, and publishes it to the event bus.
+func (d *Detector) notify(a Alert) {
+	d.mu.Lock()
+	notifier := d.notifier
+	events := d.events
+	d.mu.Unlock()
+
+	if events != nil {
+		events.Publish(eventbus.TopicAnomalyDetected, a)
+	}
+
+	for _, action := range a.Actions {
+		switch action {
+		case ActionLog:
+			logger.Warn("Authentication anomaly detected", logrus.Fields{
+				"rule":    a.Rule,
+				"subject": a.Subject,
+				"count":   a.Count,
+				"ip":      a.Event.IP,
+				"account": a.Event.Account,
+				"country": a.Event.Country,
+				"asn":     a.Event.ASN,
+				"actions": a.Actions,
+			})
+		case ActionWebhook:
+			if d.config.WebhookURL != "" {
+				go d.sendWebhook(a)
+			}
+		case ActionAlert:
+			if notifier != nil {
+				notifier.Notify(newOperationalAlert(a))
+			}
+		}
+	}
+}
+
+// newOperationalAlert converts the anomaly into an alert of the anomaly event, deduplicated per rule and subject.
+func newOperationalAlert(a Alert) alerting.Alert {
+	fields := map[string]string{"rule": a.Rule, "subject": a.Subject}
+	if a.Count > 0 {
+		fields["count"] = strconv.Itoa(a.Count)
+	}
+	for name, value := range map[string]string{"ip": a.Event.IP, "account": a.Event.Account, "country": a.Event.Country, "asn": a.Event.ASN} {
+		if value != "" {
+			fields[name] = value
+		}
+	}
+
+	return alerting.Alert{
+		Event:     alerting.EventAnomaly,
+		Key:       a.Rule + ":" + a.Subject,
+		Title:     "Authentication anomaly detected",
+		Message:   fmt.Sprintf("The %s rule was triggered by %s.", a.Rule, a.Subject),
+		Fields:    fields,
+		Timestamp: a.Timestamp,
+	}
+}
+
+// sendWebhook posts the alert as JSON to the configured webhook URL.
+func (d *Detector) sendWebhook(a Alert) {
+	body, err := json.Marshal(a)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to marshal anomaly alert: %v", err), nil)
+		return
+	}
+
+	resp, err := d.client.Post(d.config.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to send anomaly alert webhook: %v", err), nil)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		logger.Error(fmt.Sprintf("Anomaly alert webhook responded with status %d", resp.StatusCode), nil)
+	}
+}
+
+// getEnvInt reads a positive integer from the environment, falling back to def.
+func getEnvInt(key string, def int) int {
+	v, err := strconv.Atoi(env.Getenv(key))
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
+
+// getEnvMinutes reads a positive number of minutes from the environment, falling back to def.
+func getEnvMinutes(key string, def int) time.Duration {
+	return time.Duration(getEnvInt(key, def)) * time.Minute
+}
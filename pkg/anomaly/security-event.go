@@ -0,0 +1,62 @@
+package anomaly
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/eventbus"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+// SecurityEventType represents the type of a security event of the logins.
+type SecurityEventType string
+
+const (
+	SecurityEventLoginFailed    SecurityEventType = "login_failed"
+	SecurityEventLoginBlocked   SecurityEventType = "login_blocked"
+	SecurityEventLoginTarpitted SecurityEventType = "login_tarpitted"
+	SecurityEventCaptchaMissing SecurityEventType = "captcha_missing"
+	SecurityEventCaptchaFailed  SecurityEventType = "captcha_failed"
+	SecurityEventCaptchaPassed  SecurityEventType = "captcha_passed"
+)
+
+// SecurityEvent is a structured record of what happened to a login: a failed attempt, or a restriction applied to its source.
+// The security events are written to the log with the security_event field, and published to the event bus with the
+// security.event topic, so that the SIEM and the admin UIs follow the attacks as they happen.
+type SecurityEvent struct {
+	Type      SecurityEventType `json:"type"`
+	IP        string            `json:"ip"`
+	Account   string            `json:"account,omitempty"`
+	Country   string            `json:"country,omitempty"`
+	ASN       string            `json:"asn,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// Emit writes the security event to the log and publishes it to the event bus, if any.
+// The failed logins are emitted by Record, the restrictions by the handlers applying them.
+func (d *Detector) Emit(ctx context.Context, e SecurityEvent) {
+	if d == nil || !d.config.Enabled {
+		return
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+
+	logger.WarnContext(ctx, "Security event", logrus.Fields{
+		"security_event": e.Type,
+		"ip":             e.IP,
+		"account":        e.Account,
+		"country":        e.Country,
+		"asn":            e.ASN,
+	})
+
+	d.mu.Lock()
+	events := d.events
+	d.mu.Unlock()
+
+	if events != nil {
+		events.Publish(eventbus.TopicSecurityEvent, e)
+	}
+}
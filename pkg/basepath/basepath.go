@@ -0,0 +1,110 @@
+package basepath
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+)
+
+/**
+ * basepath package lets the service be mounted under a path prefix, e.g. behind an API gateway routing /auth-svc/* to it.
+ * BASE_PATH is the public prefix of the routes. The requests are accepted with or without it, so it does not matter
+ * whether the gateway strips the prefix before forwarding, and the readiness probes and metrics scrapes reaching
+ * the instance directly keep working. The paths reported to the clients (the path of the responses, the OpenAPI document)
+ * always carry the prefix.
+ */
+
+var (
+	mu     sync.RWMutex
+	prefix string
+)
+
+// Load reads the base path from the BASE_PATH environment variable.
+// It returns an error if the base path is not a valid path prefix.
+func Load() (string, error) {
+	return Normalize(env.Getenv("BASE_PATH"))
+}
+
+// Normalize returns the base path with a leading slash and no trailing slash, or an empty string for the root.
+// It returns an error if the base path holds a query, a fragment, or a wildcard of the router.
+func Normalize(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if strings.ContainsAny(path, "?#*: ") {
+		return "", fmt.Errorf("BASE_PATH %q must be a plain path such as /auth-svc", path)
+	}
+
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", nil
+	}
+	if strings.Contains(path, "//") {
+		return "", fmt.Errorf("BASE_PATH %q must not contain empty segments", path)
+	}
+
+	return "/" + path, nil
+}
+
+// Set sets the base path of the service. It must be normalized.
+func Set(path string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	prefix = path
+}
+
+// Get returns the base path of the service, or an empty string if it is mounted at the root.
+func Get() string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return prefix
+}
+
+// Join returns the public path of the given route path, prefixed with the base path.
+func Join(path string) string {
+	return Get() + path
+}
+
+// Handler removes the base path from the path of the requests before they reach the given handler, whose routes are at the root.
+// The requests without the base path, e.g. forwarded by a gateway stripping it, are passed as is.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := Get()
+		if base == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		path, ok := strip(r.URL.Path, base)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = path
+		if r.URL.RawPath != "" {
+			if rawPath, ok := strip(r.URL.RawPath, base); ok {
+				r2.URL.RawPath = rawPath
+			} else {
+				r2.URL.RawPath = ""
+			}
+		}
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// strip removes the base path from the given path, if it starts with it.
+func strip(path string, base string) (string, bool) {
+	if path == base {
+		return "/", true
+	}
+	if strings.HasPrefix(path, base+"/") {
+		return path[len(base):], true
+	}
+
+	return path, false
+}
@@ -0,0 +1,135 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+)
+
+/**
+ * captcha package verifies the CAPTCHA tokens solved by the clients challenged by the anomaly detection subsystem.
+ * The tokens are checked with the siteverify API shared by Cloudflare Turnstile, hCaptcha and Google reCAPTCHA:
+ * the secret of the site and the token are posted as a form to CAPTCHA_VERIFY_URL, which answers whether
+ * the token is valid. Without a verify URL and a secret, the tokens cannot be verified and the challenged clients cannot log in.
+ */
+
+// DefaultTimeout is the time the CAPTCHA provider has to verify a token.
+const DefaultTimeout = 5 * time.Second
+
+// ErrNotConfigured is returned when a token is verified without a CAPTCHA provider.
+var ErrNotConfigured = errors.New("no CAPTCHA provider is configured")
+
+// Verifier verifies the CAPTCHA tokens solved by the clients.
+type Verifier interface {
+	// Verify reports whether the token is valid for the client IP.
+	// An error means the provider could not verify the token.
+	Verify(ctx context.Context, token string, remoteIP string) (bool, error)
+}
+
+// Config holds the siteverify endpoint of the CAPTCHA provider and the secret of the site.
+type Config struct {
+	VerifyURL string
+	Secret    string
+}
+
+// Enabled reports whether a CAPTCHA provider is configured.
+func (c Config) Enabled() bool {
+	return c.VerifyURL != "" && c.Secret != ""
+}
+
+// siteVerifier verifies the tokens with the siteverify API of the provider.
+type siteVerifier struct {
+	config Config
+	client *http.Client
+}
+
+// noopVerifier is used when no CAPTCHA provider is configured, the tokens cannot be verified.
+type noopVerifier struct{}
+
+var (
+	once     sync.Once
+	verifier Verifier
+)
+
+// LoadConfig loads the CAPTCHA provider from the environment variables CAPTCHA_VERIFY_URL and CAPTCHA_SECRET.
+func LoadConfig() Config {
+	return Config{
+		VerifyURL: strings.TrimSpace(env.Getenv("CAPTCHA_VERIFY_URL")),
+		Secret:    env.Getenv("CAPTCHA_SECRET"),
+	}
+}
+
+// Init initializes the verifier singleton using the configuration from environment variables.
+func Init() {
+	InitWithConfig(LoadConfig())
+}
+
+// InitWithConfig initializes the verifier singleton with the given configuration.
+func InitWithConfig(cfg Config) {
+	once.Do(func() {
+		verifier = NewVerifier(cfg)
+	})
+}
+
+// GetVerifier returns the initialized verifier instance.
+func GetVerifier() Verifier {
+	if verifier == nil {
+		Init()
+	}
+	return verifier
+}
+
+// NewVerifier creates a new verifier of the tokens with the given provider, or one failing to verify them if none is configured.
+func NewVerifier(cfg Config) Verifier {
+	if !cfg.Enabled() {
+		return noopVerifier{}
+	}
+
+	return &siteVerifier{config: cfg, client: &http.Client{Timeout: DefaultTimeout}}
+}
+
+// Verify posts the secret, the token and the client IP to the siteverify endpoint and returns its answer.
+func (v *siteVerifier) Verify(ctx context.Context, token string, remoteIP string) (bool, error) {
+	form := url.Values{"secret": {v.config.Secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.config.VerifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify the CAPTCHA token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("the CAPTCHA provider responded with status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("failed to decode the CAPTCHA verification: %w", err)
+	}
+
+	return result.Success, nil
+}
+
+// Verify returns ErrNotConfigured.
+func (noopVerifier) Verify(ctx context.Context, token string, remoteIP string) (bool, error) {
+	return false, ErrNotConfigured
+}
@@ -0,0 +1,193 @@
+package certreload
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+/**
+ * certreload package serves the TLS certificate of the server from memory and reloads it when its files change,
+ * so that a rotated certificate is served to the new connections without restarting the listener.
+ * The files are checked every TLS_RELOAD_INTERVAL_SECOND, and reloaded at once on SIGHUP. A certificate that
+ * fails to load (e.g. the key is written after the certificate) is logged and the previous one is kept,
+ * the next check loading it once both files match. The expiry of the certificate is watched by the alerting package.
+ */
+
+// DefaultInterval is how often the certificate files are checked for a change, when TLS_RELOAD_INTERVAL_SECOND is not set or invalid.
+const DefaultInterval = 30 * time.Second
+
+// LoadInterval reads how often the certificate files are checked for a change from TLS_RELOAD_INTERVAL_SECOND.
+// 0 disables the checks, the certificate being then reloaded only on SIGHUP.
+func LoadInterval() time.Duration {
+	if n, err := strconv.Atoi(env.Getenv("TLS_RELOAD_INTERVAL_SECOND")); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second
+	}
+
+	return DefaultInterval
+}
+
+// Reloader holds the certificate of the server loaded from its certificate and key files.
+// Its GetCertificate method is used as the GetCertificate function of the tls.Config of the server.
+type Reloader struct {
+	certPath string
+	keyPath  string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates a new instance of Reloader with the given certificate and key files, and loads them.
+// It returns an error if the files cannot be loaded, the server cannot start without a certificate.
+func New(certPath string, keyPath string) (*Reloader, error) {
+	r := &Reloader{certPath: certPath, keyPath: keyPath}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, for every TLS handshake.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// TLSConfig returns a TLS configuration serving the current certificate.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{GetCertificate: r.GetCertificate}
+}
+
+// Reload loads the certificate and key files, and serves the new certificate to the new connections.
+// On error, the previous certificate is kept.
+func (r *Reloader) Reload() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load the TLS certificate: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse the TLS certificate: %w", err)
+	}
+	cert.Leaf = leaf
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTimes = modTimes
+	r.mu.Unlock()
+
+	logger.Info("Loaded the TLS certificate", logrus.Fields{
+		"certificate": r.certPath,
+		"subject":     leaf.Subject.String(),
+		"not_after":   leaf.NotAfter.UTC().Format(time.RFC3339),
+	})
+
+	return nil
+}
+
+// Certificate returns the certificate currently served.
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert
+}
+
+// Changed reports whether a file was modified since the certificate was loaded.
+func (r *Reloader) Changed() (bool, error) {
+	modTimes, err := r.stat()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return modTimes != r.modTimes, nil
+}
+
+// Watch starts a background worker reloading the certificate every interval when its files changed.
+// A non-positive interval does nothing, the certificate being reloaded only with Reload.
+func (r *Reloader) Watch(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run(interval)
+}
+
+// Close stops the background worker started by Watch. It is safe to call Close more than once.
+func (r *Reloader) Close() {
+	if r.stop == nil {
+		return
+	}
+
+	r.closeOnce.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+}
+
+// run reloads the certificate every interval when its files changed, until the reloader is closed.
+func (r *Reloader) run(interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			changed, err := r.Changed()
+			if err == nil && changed {
+				err = r.Reload()
+			}
+			if err != nil {
+				logger.Error(fmt.Sprintf("Failed to reload the TLS certificate, keeping the previous one: %v", err), logrus.Fields{
+					"certificate": r.certPath,
+				})
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// stat returns the modification times of the certificate and key files.
+func (r *Reloader) stat() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, path := range []string{r.certPath, r.keyPath} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, fmt.Errorf("failed to read the TLS certificate: %w", err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+
+	return modTimes, nil
+}
@@ -0,0 +1,306 @@
+package chaos
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+/**
+ * chaos package injects faults into the service for the resilience tests: latency, errors, and dropped connections,
+ * so that the retries of the reads, the write buffer, and the timeouts can be tested end to end, against a running instance.
+ * The faults are injected by the InjectFaults middleware, before the handlers, and by the chaos repositories,
+ * before the calls to the database. They are set per request with the X-Chaos-* headers, or for every call to the
+ * database with the CHAOS_DB_* environment variables:
+ *   - X-Chaos-Delay: a duration, e.g. 250ms, the request waits before its handler runs.
+ *   - X-Chaos-Status: a status, 429 or 5xx, the request is rejected with instead of running its handler.
+ *   - X-Chaos-DB-Fault: error or drop, the calls to the database fail with an error, or a dropped connection,
+ *     which is transient: the reads are retried, and the buffered writes are buffered.
+ *   - X-Chaos-DB-Delay: a duration, the calls to the database wait before they run, until the request is canceled.
+ *   - X-Chaos-DB-Count: the number of calls to the database faulted, the next ones succeed, e.g. 1 for a retry to succeed.
+ * The injection is for the test environments only: it is disabled unless CHAOS_ENABLED is TRUE, which is rejected
+ * when ENV is PRODUCTION, and the headers are ignored while it is disabled.
+ */
+
+// Kinds of faults of the calls to the database.
+const (
+	FaultError = "error"
+	FaultDrop  = "drop"
+)
+
+// Headers setting the faults of a request.
+const (
+	HeaderDelay   = "X-Chaos-Delay"
+	HeaderStatus  = "X-Chaos-Status"
+	HeaderDBFault = "X-Chaos-DB-Fault"
+	HeaderDBDelay = "X-Chaos-DB-Delay"
+	HeaderDBCount = "X-Chaos-DB-Count"
+)
+
+// maxDelay is the longest delay a request can ask for, so that a test cannot hold a connection forever.
+const maxDelay = time.Minute
+
+var (
+	// ErrInjected is the error of the calls to the database failing with an injected error, which is not transient.
+	ErrInjected = errors.New("chaos: injected database error")
+
+	// ErrDropped is the error of the calls to the database failing with an injected dropped connection, which is transient.
+	ErrDropped = fmt.Errorf("chaos: injected dropped connection: %w", driver.ErrBadConn)
+)
+
+// Config holds the settings of the fault injection: whether it is enabled, and the faults of every call to the database.
+type Config struct {
+	Enabled     bool
+	DBDelay     time.Duration
+	DBErrorRate float64
+	DBDropRate  float64
+}
+
+// LoadConfig reads the settings of the fault injection from the environment variables: CHAOS_ENABLED,
+// CHAOS_DB_DELAY_MS, the delay of every call to the database, and CHAOS_DB_ERROR_RATE and CHAOS_DB_DROP_RATE,
+// the shares of the calls failing with an error and with a dropped connection, between 0 and 1.
+// Invalid values are kept as -1, for Validate to report them.
+func LoadConfig() Config {
+	cfg := Config{Enabled: env.Getenv("CHAOS_ENABLED") == "TRUE"}
+
+	if v := strings.TrimSpace(env.Getenv("CHAOS_DB_DELAY_MS")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.DBDelay = time.Duration(n) * time.Millisecond
+		} else {
+			cfg.DBDelay = -1
+		}
+	}
+	cfg.DBErrorRate = rate("CHAOS_DB_ERROR_RATE")
+	cfg.DBDropRate = rate("CHAOS_DB_DROP_RATE")
+
+	return cfg
+}
+
+// rate returns the share of the environment variable, 0 if it is missing, or -1 if it is invalid.
+func rate(key string) float64 {
+	v := strings.TrimSpace(env.Getenv(key))
+	if v == "" {
+		return 0
+	}
+
+	r, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return -1
+	}
+	return r
+}
+
+// Validate checks the settings of the fault injection, and that it is not enabled in the given environment if it is production.
+func (c Config) Validate(env string) error {
+	if !c.Enabled {
+		return nil
+	}
+	if strings.EqualFold(env, "PRODUCTION") {
+		return fmt.Errorf("CHAOS_ENABLED must not be TRUE when ENV is PRODUCTION")
+	}
+	if c.DBDelay < 0 {
+		return fmt.Errorf("CHAOS_DB_DELAY_MS must be a non-negative number of milliseconds")
+	}
+	if c.DBErrorRate < 0 || c.DBErrorRate > 1 || c.DBDropRate < 0 || c.DBDropRate > 1 {
+		return fmt.Errorf("CHAOS_DB_ERROR_RATE and CHAOS_DB_DROP_RATE must be numbers between 0 and 1")
+	}
+	if c.DBErrorRate+c.DBDropRate > 1 {
+		return fmt.Errorf("CHAOS_DB_ERROR_RATE and CHAOS_DB_DROP_RATE must not add up to more than 1")
+	}
+
+	return nil
+}
+
+// Fault is the fault of the calls to the database of a request, set by its headers.
+type Fault struct {
+	Kind  string
+	Delay time.Duration
+
+	// remaining is the number of calls still faulted, negative for all of them
+	remaining atomic.Int64
+}
+
+// NewFault returns the fault of the given kind, empty for none, and delay, of the first count calls to the database,
+// or of all of them if count is not positive.
+func NewFault(kind string, delay time.Duration, count int) *Fault {
+	f := &Fault{Kind: kind, Delay: delay}
+	if count > 0 {
+		f.remaining.Store(int64(count))
+	} else {
+		f.remaining.Store(-1)
+	}
+	return f
+}
+
+// take reports whether the next call to the database is faulted, and counts it.
+func (f *Fault) take() bool {
+	for {
+		n := f.remaining.Load()
+		if n < 0 {
+			return true
+		}
+		if n == 0 {
+			return false
+		}
+		if f.remaining.CompareAndSwap(n, n-1) {
+			return true
+		}
+	}
+}
+
+// RequestFaults holds the faults of a request, parsed from its headers.
+type RequestFaults struct {
+	Delay  time.Duration
+	Status int
+	DB     *Fault
+}
+
+// ParseHeaders parses the faults of a request from its X-Chaos-* headers.
+// It returns an error if a header is invalid, and empty faults if none is set.
+func ParseHeaders(h http.Header) (RequestFaults, error) {
+	var faults RequestFaults
+
+	delay, err := parseDelay(h, HeaderDelay)
+	if err != nil {
+		return RequestFaults{}, err
+	}
+	faults.Delay = delay
+
+	if v := h.Get(HeaderStatus); v != "" {
+		status, err := strconv.Atoi(v)
+		if err != nil || (status != http.StatusTooManyRequests && (status < 500 || status > 599)) {
+			return RequestFaults{}, fmt.Errorf("%s must be 429 or a 5xx status, got %q", HeaderStatus, v)
+		}
+		faults.Status = status
+	}
+
+	kind := h.Get(HeaderDBFault)
+	if kind != "" && kind != FaultError && kind != FaultDrop {
+		return RequestFaults{}, fmt.Errorf("%s must be %s or %s, got %q", HeaderDBFault, FaultError, FaultDrop, kind)
+	}
+
+	dbDelay, err := parseDelay(h, HeaderDBDelay)
+	if err != nil {
+		return RequestFaults{}, err
+	}
+
+	count := 0
+	if v := h.Get(HeaderDBCount); v != "" {
+		if count, err = strconv.Atoi(v); err != nil || count <= 0 {
+			return RequestFaults{}, fmt.Errorf("%s must be a positive number, got %q", HeaderDBCount, v)
+		}
+	}
+
+	if kind != "" || dbDelay > 0 {
+		faults.DB = NewFault(kind, dbDelay, count)
+	}
+
+	return faults, nil
+}
+
+// parseDelay parses the duration of the header, 0 if it is missing.
+func parseDelay(h http.Header, key string) (time.Duration, error) {
+	v := h.Get(key)
+	if v == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 || d > maxDelay {
+		return 0, fmt.Errorf("%s must be a duration between 0 and %s, e.g. 250ms, got %q", key, maxDelay, v)
+	}
+	return d, nil
+}
+
+// faultKey is the key of the fault of a request in its context.
+type faultKey struct{}
+
+// WithFault returns a copy of the context carrying the fault of the calls to the database.
+func WithFault(ctx context.Context, fault *Fault) context.Context {
+	return context.WithValue(ctx, faultKey{}, fault)
+}
+
+// FaultFromContext returns the fault of the calls to the database carried by the context, nil if none.
+func FaultFromContext(ctx context.Context) *Fault {
+	fault, _ := ctx.Value(faultKey{}).(*Fault)
+	return fault
+}
+
+// Injector injects the faults into the calls to the database.
+// A nil injector injects nothing, so that the chaos repositories do not check whether the injection is enabled.
+type Injector struct {
+	cfg    Config
+	random func() float64
+}
+
+// New creates a new injector with the given settings. It returns nil if the injection is disabled.
+func New(cfg Config) *Injector {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	return &Injector{cfg: cfg, random: rand.Float64}
+}
+
+// Inject runs the fault of the call to the database named after the operation, e.g. GetUserByID: the fault of the request
+// carried by the context, or else the faults set by the environment variables. It waits for the delay of the fault,
+// then returns ErrInjected or ErrDropped, or nil to run the call. It returns the error of the context if it is done while waiting.
+func (i *Injector) Inject(ctx context.Context, operation string) error {
+	if i == nil {
+		return nil
+	}
+
+	kind, delay := "", i.cfg.DBDelay
+	if fault := FaultFromContext(ctx); fault != nil {
+		if !fault.take() {
+			return nil
+		}
+		kind, delay = fault.Kind, fault.Delay
+	} else if r := i.random(); r < i.cfg.DBDropRate {
+		kind = FaultDrop
+	} else if r < i.cfg.DBDropRate+i.cfg.DBErrorRate {
+		kind = FaultError
+	}
+
+	if delay > 0 {
+		if err := Sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+
+	switch kind {
+	case FaultError:
+		logger.WarnContext(ctx, "Injected a database error", logrus.Fields{"operation": operation})
+		return ErrInjected
+	case FaultDrop:
+		logger.WarnContext(ctx, "Injected a dropped database connection", logrus.Fields{"operation": operation})
+		return ErrDropped
+	}
+
+	return nil
+}
+
+// Sleep waits for the delay, or until the context is done, whose error it returns then.
+func Sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,24 @@
+package metacontext
+
+import "context"
+
+// This struct defines the RequestIDKeyType struct
+//
+//	It is used as a key for storing and retrieving the request ID from the context
+type RequestIDKeyType struct{}
+
+// Define a key for storing the request ID in the context
+var requestIDKey = RequestIDKeyType{}
+
+// InjectRequestID injects the ID of the request into the context.
+// This function is used by the RequestID middleware, so that the logs and the responses of the request carry its ID
+func InjectRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// ExtractRequestID retrieves the ID of the request from the context.
+// It returns an empty string if the context does not carry a request ID, e.g. outside of a request
+func ExtractRequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
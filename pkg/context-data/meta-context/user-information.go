@@ -2,6 +2,7 @@ package metacontext
 
 import (
 	"context"
+	"time"
 )
 
 // This struct defines the UserInformationMeta struct
@@ -12,6 +13,16 @@ type UserInformationMeta struct {
 	Username string
 	Email    string
 	Roles    []string
+
+	// Scopes are the permissions granted to the roles of the user when the access token was issued
+	Scopes []string
+
+	// ClientID identifies the client application the access token was issued to, if any
+	ClientID string
+
+	// TokenID and TokenExpiresAt identify the access token of the request, e.g. to revoke it on logout
+	TokenID        string
+	TokenExpiresAt time.Time
 }
 
 // This struct defines the UserInformationMetaKeyType struct
@@ -0,0 +1,96 @@
+package diagnostics
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+// PoolStats is a snapshot of the statistics of a connection pool: the connections open, in use, and idle,
+// and the waits for a connection, which grow when the pool is too small for the load.
+type PoolStats struct {
+	MaxOpenConnections int   `json:"maxOpenConnections"`
+	OpenConnections    int   `json:"openConnections"`
+	InUse              int   `json:"inUse"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"waitCount"`
+	WaitDurationMs     int64 `json:"waitDurationMs"`
+	MaxIdleClosed      int64 `json:"maxIdleClosed"`
+	MaxIdleTimeClosed  int64 `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed  int64 `json:"maxLifetimeClosed"`
+}
+
+var (
+	poolsMu sync.RWMutex
+	pools   = make(map[string]*sql.DB)
+)
+
+// RegisterDatabasePool reports the connection pool of the database with the given name in the diagnostics,
+// replacing the pool registered with the same name, if any.
+func RegisterDatabasePool(name string, db *sql.DB) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+	pools[name] = db
+}
+
+// UnregisterDatabasePool stops reporting the connection pool of the database with the given name, e.g. once it is closed.
+func UnregisterDatabasePool(name string) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+	delete(pools, name)
+}
+
+// DatabasePoolStats returns the statistics of the registered connection pools, by database name.
+func DatabasePoolStats() map[string]PoolStats {
+	poolsMu.RLock()
+	defer poolsMu.RUnlock()
+
+	stats := make(map[string]PoolStats, len(pools))
+	for name, db := range pools {
+		stats[name] = NewPoolStats(db.Stats())
+	}
+	return stats
+}
+
+// NewPoolStats returns the snapshot of the given statistics of a connection pool.
+func NewPoolStats(s sql.DBStats) PoolStats {
+	return PoolStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDurationMs:     s.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
+
+// LogDatabasePoolStats logs the statistics of the registered connection pools, one event per database.
+func LogDatabasePoolStats(stage string) {
+	stats := DatabasePoolStats()
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		s := stats[name]
+		logger.Info("Database pool stats snapshot", log.Fields{
+			"stage":              stage,
+			"database":           name,
+			"MaxOpenConnections": s.MaxOpenConnections,
+			"OpenConnections":    s.OpenConnections,
+			"InUse":              s.InUse,
+			"Idle":               s.Idle,
+			"WaitCount":          s.WaitCount,
+			"WaitDurationMS":     s.WaitDurationMs,
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package diagnostics
+
+import (
+	"net/url"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+// Version and Commit identify the build. They are set at build time, e.g.
+// go build -ldflags "-X github.com/yoanesber/go-jwt-auth-demo/pkg/diagnostics.Version=1.2.0 -X github.com/yoanesber/go-jwt-auth-demo/pkg/diagnostics.Commit=abc123"
+// Without them, the commit is read from the VCS information embedded by the Go toolchain, if any.
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// ConfigKeys are the environment variables reported in the startup event.
+var ConfigKeys = []string{
+	"CONFIG_FILE", "ENV", "API_VERSION", "PORT", "BASE_PATH", "IS_SSL", "SSL_KEYS", "SSL_CERT", "TLS_RELOAD_INTERVAL_SECOND", "FRONTEND_URL", "FRONTEND_URL_PRODUCTION",
+	"TRUSTED_PROXIES", "REMOTE_IP_HEADERS", "METRICS_ENABLED", "SLO_AVAILABILITY_TARGET", "SLO_LATENCY_TARGET", "SLO_LATENCY_THRESHOLD_MS", "SLO_LATENCY_THRESHOLDS_MS_BY_GROUP", "OPENAPI_REQUEST_VALIDATION", "SHUTDOWN_DRAIN_SECOND", "SHUTDOWN_TIMEOUT_SECOND",
+	"DB_DRIVER", "DB_HOST", "DB_PORT", "DB_USER", "DB_PASS", "DB_NAME", "DB_SCHEMA", "DB_SSL_MODE", "DB_TIMEZONE", "DB_REPLICA_DSN",
+	"DB_MIGRATE", "DB_SEED", "DB_SEED_FILE", "DB_JOIN_ROLES", "DB_LOG", "ROLE_CACHE_TTL_SECOND", "USER_CACHE_TTL_SECOND",
+	"DB_READ_RETRIES", "DB_READ_RETRY_DELAY_MS", "DB_CIRCUIT_BREAKER_THRESHOLD", "DB_CIRCUIT_BREAKER_OPEN_SECOND", "DB_WRITE_BUFFER", "DB_WRITE_BUFFER_SIZE", "DB_WRITE_BUFFER_TIMEOUT_SECOND", "DB_WRITE_BUFFER_RETRY_INTERVAL_MS", "DB_MAX_OPEN_CONNS", "DB_MAX_IDLE_CONNS", "DB_CONN_MAX_LIFETIME_SECOND", "DB_CONN_MAX_IDLE_TIME_SECOND", "USER_STORE", "USER_STORE_URL", "USER_STORE_TIMEOUT_SECOND",
+	"MAX_SESSIONS_PER_USER", "SESSION_LIMIT_MODE", "CONSUMER_LISTING_EXCLUDED_STATUSES", "CONSUMER_REQUIRED_METADATA_KEYS", "CONSUMER_USERNAME_PATTERN", "CONSUMER_BLOCKED_EMAIL_DOMAINS", "CONSUMER_CACHE_TTL_SECOND", "SECURITY_SETTINGS_CACHE_TTL_SECOND", "DISABLED_FEATURES", "FEATURE_FLAGS_CACHE_TTL_SECOND", "CHECK_AVAILABILITY_RATE_LIMIT", "REGISTER_RATE_LIMIT", "FORGOT_PASSWORD_RATE_LIMIT", "CHANGE_PASSWORD_RATE_LIMIT", "MFA_RATE_LIMIT", "AUTH_RATE_LIMIT_IP_BURST", "AUTH_RATE_LIMIT_IP_PER_MINUTE", "AUTH_RATE_LIMIT_USERNAME_BURST", "AUTH_RATE_LIMIT_USERNAME_PER_MINUTE", "AUTH_RATE_LIMIT_BACKEND", "RATE_LIMIT_OVERRIDES_CACHE_TTL_SECOND", "PAGINATION_MAX_LIMIT", "PAGINATION_LIMIT_MODE", "PAGINATION_LIMITS_BY_ROLE", "EXPORT_MAX_ROWS",
+	"QUOTA_DAILY_LIMIT", "QUOTA_MONTHLY_LIMIT", "QUOTA_LIMITS_BY_CLIENT", "QUOTA_ENDPOINT_COSTS",
+	"JWT_ALGORITHM", "JWT_SECRET", "JWT_PRIVATE_KEY_PATH", "JWT_PUBLIC_KEY_PATH", "JWT_KEYSET_DIR", "JWT_KEY_ROTATION_DAYS", "JWT_ISSUER", "JWT_AUDIENCE", "TOKEN_TYPE",
+	"JWT_ACCESS_TOKEN_TTL_MINUTES", "JWT_ACCESS_TOKEN_TTL_BY_USER_TYPE", "JWT_ACCESS_TOKEN_TTL_BY_ROLE", "JWT_ACCESS_TOKEN_TTL_BY_CLIENT",
+	"JWT_REFRESH_TOKEN_EXPIRATION_HOUR", "PASSWORD_RESET_TOKEN_TTL_MINUTES", "PASSWORD_RESET_URL", "CREDENTIALS_TTL_DAYS",
+	"MFA_ISSUER", "MFA_TOKEN_TTL_MINUTES", "PII_ENCRYPTION_KEY", "PII_ENCRYPTION_KEY_FILE",
+	"DATA_MASKING_ENABLED", "DATA_MASKING_FIELDS", "LOCALE_FORMATTING_ENABLED", "LOCALE_FORMATTING_LOCALES", "CHAOS_ENABLED", "CHAOS_DB_DELAY_MS", "CHAOS_DB_ERROR_RATE", "CHAOS_DB_DROP_RATE",
+	"CONSUMER_WEBHOOK_URL", "CONSUMER_WEBHOOK_MAX_ATTEMPTS", "CONSUMER_WEBHOOK_BACKOFF_SECOND", "CONSUMER_WEBHOOK_MAX_BACKOFF_SECOND", "CONSUMER_WEBHOOK_TIMEOUT_SECOND",
+	"ANOMALY_DETECTION_ENABLED", "ANOMALY_ACTIONS", "ANOMALY_WEBHOOK_URL", "ANOMALY_FAILED_LOGIN_ASN_THRESHOLD",
+	"ANOMALY_TARPIT_MINUTES", "ANOMALY_TARPIT_DELAY_MS", "ANOMALY_CAPTCHA_MINUTES", "ANOMALY_MAX_TRACKED_KEYS", "CAPTCHA_VERIFY_URL", "CAPTCHA_SECRET",
+	"GEOIP_DB_PATH", "GEOIP_ASN_DB_PATH", "GEOIP_BLOCKED_ADMIN_COUNTRIES",
+	"TRACING_ENABLED", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_SERVICE_NAME", "TRACING_SAMPLE_RATIO",
+	"SIGNED_URL_SECRET", "SIGNED_URL_SECRET_FILE", "SIGNED_URL_TTL_SECOND",
+	"OIDC_PROVIDERS", "OIDC_GOOGLE_CLIENT_ID", "OIDC_GOOGLE_CLIENT_SECRET", "OIDC_GOOGLE_REDIRECT_URL",
+	"OIDC_MICROSOFT_ISSUER_URL", "OIDC_MICROSOFT_CLIENT_ID", "OIDC_MICROSOFT_CLIENT_SECRET", "OIDC_MICROSOFT_REDIRECT_URL",
+	"MAILER_DRIVER", "SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD", "MAIL_FROM", "MAIL_TEMPLATES_DIR", "MAIL_LOCALE",
+	"ALERT_ROUTES", "ALERT_DEDUP_MINUTES", "ALERT_CERT_EXPIRY_DAYS", "ALERT_EMAIL_TO", "ALERT_SLACK_WEBHOOK_URL",
+	"ALERT_SMS_API_URL", "ALERT_SMS_ACCOUNT_SID", "ALERT_SMS_AUTH_TOKEN", "ALERT_SMS_FROM", "ALERT_SMS_TO",
+	"REDIS_URL", "REDIS_KEY_PREFIX", "EVENTS_ROLE_TOPICS",
+}
+
+// dependencies are the modules whose versions are reported in the startup event.
+var dependencies = []string{
+	"github.com/gin-gonic/gin",
+	"gorm.io/gorm",
+	"gorm.io/driver/postgres",
+	"github.com/golang-jwt/jwt/v5",
+	"github.com/prometheus/client_golang",
+	"go.opentelemetry.io/otel",
+}
+
+// sensitiveKeys are the parts of the names of the environment variables whose values are never logged.
+// The Slack webhook URLs embed the credentials of the webhook, and the connection strings the password of the database.
+var sensitiveKeys = []string{"SECRET", "PASS", "PRIVATE_KEY", "ENCRYPTION_KEY", "AUTH_TOKEN", "SLACK_WEBHOOK_URL", "DSN"}
+
+// StartupInfo describes the running service for the fleet-wide inventory.
+type StartupInfo struct {
+	Version      string
+	Commit       string
+	GoVersion    string
+	Dependencies map[string]string
+	Features     map[string]bool
+	Config       map[string]string
+}
+
+// CollectStartupInfo returns the build information of the service, its sanitized configuration, and the given enabled features.
+func CollectStartupInfo(features map[string]bool) StartupInfo {
+	info := StartupInfo{
+		Version:      Version,
+		Commit:       BuildCommit(),
+		GoVersion:    runtime.Version(),
+		Dependencies: make(map[string]string),
+		Features:     features,
+		Config:       SanitizedConfig(ConfigKeys),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range build.Deps {
+			for _, path := range dependencies {
+				if dep.Path == path {
+					info.Dependencies[path] = dep.Version
+				}
+			}
+		}
+	}
+
+	return info
+}
+
+// BuildCommit returns the commit of the build: Commit if set at build time, else the VCS revision embedded by the Go toolchain,
+// else "unknown".
+func BuildCommit() string {
+	if Commit != "" {
+		return Commit
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" && setting.Value != "" {
+				return setting.Value
+			}
+		}
+	}
+
+	return "unknown"
+}
+
+// SanitizedConfig returns the values of the given environment variables that are set.
+// The secrets are masked, and the credentials and query strings are removed from the URLs.
+func SanitizedConfig(keys []string) map[string]string {
+	config := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, ok := env.LookupEnv(key)
+		if !ok {
+			continue
+		}
+
+		config[key] = sanitize(key, value)
+	}
+
+	return config
+}
+
+// sanitize masks the value of a sensitive environment variable, and removes the credentials and query string of a URL.
+func sanitize(key string, value string) string {
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) && !strings.HasSuffix(key, "_PATH") {
+			if value == "" {
+				return ""
+			}
+			return "********"
+		}
+	}
+
+	if u, err := url.Parse(value); err == nil && u.Scheme != "" && u.Host != "" {
+		u.User = nil
+		u.RawQuery = ""
+		u.Fragment = ""
+		return u.String()
+	}
+
+	return value
+}
+
+// LogServiceStarted logs the single "Service started" event describing the service, with its memory usage.
+func LogServiceStarted(info StartupInfo) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	logger.Info("Service started", log.Fields{
+		"version":       info.Version,
+		"commit":        info.Commit,
+		"go_version":    info.GoVersion,
+		"dependencies":  info.Dependencies,
+		"features":      info.Features,
+		"config":        info.Config,
+		"heap_alloc_kb": m.HeapAlloc / 1024,
+		"num_goroutine": runtime.NumGoroutine(),
+	})
+}
@@ -0,0 +1,163 @@
+package eventbus
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+)
+
+/**
+ * eventbus package provides the internal bus the realtime events are published to, e.g. a consumer created
+ * or suspended, an authentication anomaly detected, and a security event of the logins (a failed login, a CAPTCHA
+ * challenge, ...), and the connected admin UIs subscribe to through /api/v1/ws.
+ * Every subscriber receives the events of its topics only: the topics of a WebSocket connection are the ones
+ * EVENTS_ROLE_TOPICS grants to the roles of its user (see Policy).
+ *
+ * The events are published without blocking: a subscriber whose buffer is full misses the new events,
+ * so that a slow connection never delays a request. The bus only lives in the process, each instance
+ * pushes the events of the requests it serves.
+ */
+
+// The topics the events are published to.
+const (
+	TopicConsumerCreated   = "consumer.created"
+	TopicConsumerSuspended = "consumer.suspended"
+	TopicAnomalyDetected   = "anomaly.detected"
+	TopicSecurityEvent     = "security.event"
+)
+
+// Topics lists the topics the events are published to.
+var Topics = []string{TopicConsumerCreated, TopicConsumerSuspended, TopicAnomalyDetected, TopicSecurityEvent}
+
+// DefaultSubscriberBuffer is the number of events waiting to be read by a subscriber before the new ones are dropped.
+const DefaultSubscriberBuffer = 64
+
+// Event is an event published to a topic, sent as JSON to the subscribers.
+type Event struct {
+	Topic      string      `json:"topic"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Data       interface{} `json:"data"`
+}
+
+// Interface for event publisher
+// This interface defines the method used by the services to publish their events
+type Publisher interface {
+	Publish(topic string, data interface{})
+}
+
+// Bus delivers the published events to the subscribers of their topic.
+// A nil bus drops the events, so that the publishers do not check whether the bus is enabled.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+	clock       clock.Clock
+}
+
+// NewBus creates a new instance of Bus timestamping the events with the given clock.
+func NewBus(clk clock.Clock) *Bus {
+	return &Bus{subscribers: make(map[*Subscription]struct{}), clock: clk}
+}
+
+// Publish sends the event to the subscribers of the topic, without waiting for them.
+// The subscribers whose buffer is full miss the event.
+func (b *Bus) Publish(topic string, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	e := Event{Topic: topic, OccurredAt: b.clock.Now(), Data: data}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for s := range b.subscribers {
+		if !s.topics[topic] {
+			continue
+		}
+
+		select {
+		case s.events <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribe subscribes to the events of the given topics, buffering up to the given number of events.
+// The subscription of a closed bus is closed at once.
+func (b *Bus) Subscribe(topics []string, buffer int) *Subscription {
+	s := &Subscription{bus: b, topics: make(map[string]bool, len(topics)), events: make(chan Event, buffer)}
+	for _, topic := range topics {
+		s.topics[topic] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		s.closeOnce.Do(func() { close(s.events) })
+		return s
+	}
+	b.subscribers[s] = struct{}{}
+
+	return s
+}
+
+// Subscribers returns the number of subscriptions of the bus.
+func (b *Bus) Subscribers() int {
+	if b == nil {
+		return 0
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.subscribers)
+}
+
+// Close closes all the subscriptions, e.g. on shutdown, and drops the events published afterwards.
+func (b *Bus) Close() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for s := range b.subscribers {
+		delete(b.subscribers, s)
+		s.closeOnce.Do(func() { close(s.events) })
+	}
+}
+
+// Subscription receives the events of its topics until it is closed.
+type Subscription struct {
+	bus       *Bus
+	topics    map[string]bool
+	events    chan Event
+	dropped   atomic.Int64
+	closeOnce sync.Once
+}
+
+// Events returns the channel of the events, closed when the subscription or the bus is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of events missed because the buffer was full.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes from the bus and closes the channel of the events.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	delete(s.bus.subscribers, s)
+	s.closeOnce.Do(func() { close(s.events) })
+}
@@ -0,0 +1,76 @@
+package eventbus
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+)
+
+// DefaultRoleTopics is the value of EVENTS_ROLE_TOPICS when it is not set: the administrators receive every event.
+const DefaultRoleTopics = "ROLE_ADMIN=consumer.created|consumer.suspended|anomaly.detected|security.event"
+
+// Policy holds the topics each role receives. A user receives the topics of all their roles,
+// and the roles not listed do not receive any event.
+type Policy map[string][]string
+
+// LoadPolicy loads the topics of the roles from EVENTS_ROLE_TOPICS, or DefaultRoleTopics when it is not set.
+// It returns an error if the value is invalid.
+func LoadPolicy() (Policy, error) {
+	value, ok := env.LookupEnv("EVENTS_ROLE_TOPICS")
+	if !ok {
+		value = DefaultRoleTopics
+	}
+
+	return ParsePolicy(value)
+}
+
+// ParsePolicy parses a comma separated list of role=topics pairs, the topics being separated by "|",
+// e.g. "ROLE_ADMIN=consumer.created|anomaly.detected,ROLE_MODERATOR=consumer.suspended".
+// It returns an error if a pair is malformed or a topic is unknown.
+func ParsePolicy(value string) (Policy, error) {
+	policy := make(Policy)
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		role, topics, ok := strings.Cut(pair, "=")
+		role = strings.TrimSpace(role)
+		if !ok || role == "" {
+			return nil, fmt.Errorf("invalid role topics %q, expected role=topics", pair)
+		}
+
+		for _, topic := range strings.Split(topics, "|") {
+			topic = strings.ToLower(strings.TrimSpace(topic))
+			if topic == "" {
+				continue
+			}
+			if !slices.Contains(Topics, topic) {
+				return nil, fmt.Errorf("invalid role topics %q, unknown topic %q", pair, topic)
+			}
+			if !slices.Contains(policy[role], topic) {
+				policy[role] = append(policy[role], topic)
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+// Topics returns the topics received by a user with the given roles, sorted by name.
+func (p Policy) Topics(roles []string) []string {
+	var topics []string
+	for _, role := range roles {
+		for _, topic := range p[role] {
+			if !slices.Contains(topics, topic) {
+				topics = append(topics, topic)
+			}
+		}
+	}
+	sort.Strings(topics)
+
+	return topics
+}
@@ -0,0 +1,199 @@
+package featureflag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+/**
+ * featureflag package provides the switches disabling individual routes or groups of routes, e.g. the consumer writes
+ * during a migration of their table, so that a partial degradation does not require a full maintenance of the service.
+ * The requests of a disabled feature are rejected with 503 and the FEATURE_DISABLED error code.
+ * The features are disabled by DISABLED_FEATURES for the lifetime of the process, or at runtime by the administrators:
+ * the runtime switches are stored in the database and cached by every instance, which reloads them once they are
+ * older than the TTL, and at once after a change it made itself.
+ */
+
+// Features switched by the flags, named "<resource>.<operation>".
+const (
+	ConsumerReads  = "consumers.read"
+	ConsumerWrites = "consumers.write"
+	ConsumerStream = "consumers.stream"
+	UserWrites     = "users.write"
+	Registration   = "auth.register"
+	PasswordResets = "auth.password-reset"
+	OIDCLogin      = "auth.oidc"
+	RealtimeEvents = "events.stream"
+)
+
+// DisabledCode is the error code of the responses to the requests of a disabled feature.
+const DisabledCode = "FEATURE_DISABLED"
+
+// Features describes the features that can be disabled, by name.
+var Features = map[string]string{
+	ConsumerReads:  "The listing and the lookups of the consumers",
+	ConsumerWrites: "The creation, the status changes, and the availability checks of the consumers",
+	ConsumerStream: "The stream of the consumers for the full extractions",
+	UserWrites:     "The creation, the changes, and the deletion of the user accounts by the administrators",
+	Registration:   "The self-registration of the users",
+	PasswordResets: "The requests and the completions of the password resets",
+	OIDCLogin:      "The logins with the OpenID Connect providers",
+	RealtimeEvents: "The WebSocket connections pushing the realtime events",
+}
+
+// Names returns the names of the features, sorted.
+func Names() []string {
+	names := make([]string, 0, len(Features))
+	for name := range Features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// IsKnown reports whether the feature can be disabled.
+func IsKnown(name string) bool {
+	_, ok := Features[name]
+	return ok
+}
+
+// ParseDisabled parses a comma separated list of features, e.g. "consumers.write,auth.register".
+// It fails on the unknown features, so that a typo does not leave a feature enabled.
+func ParseDisabled(value string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !IsKnown(name) {
+			return nil, fmt.Errorf("unknown feature %q, expected one of %s", name, strings.Join(Names(), ", "))
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// LoadDisabled reads the features disabled for the lifetime of the process from DISABLED_FEATURES.
+func LoadDisabled() ([]string, error) {
+	return ParseDisabled(env.Getenv("DISABLED_FEATURES"))
+}
+
+// Loader loads the features disabled at runtime and the reasons given for them, e.g. from the database.
+type Loader func() (map[string]string, error)
+
+// Cache is a thread-safe TTL cache of the disabled features.
+// The features of the environment are disabled at once, the ones of the loader once it is loaded with Load,
+// so that the requests served before the database is initialized never query it.
+// A nil *Cache is valid and disables no feature.
+type Cache struct {
+	mu          sync.Mutex
+	load        Loader
+	ttl         time.Duration
+	clock       clock.Clock
+	environment map[string]bool
+	current     map[string]string
+	loaded      bool
+	stale       bool
+	loadedAt    time.Time
+}
+
+// NewCache creates a new instance of Cache disabling the features of the environment, and the ones of the loader.
+// The features of the loader are reloaded once they are older than the TTL; with a TTL that is not positive,
+// they are only reloaded after Invalidate.
+func NewCache(environment []string, load Loader, ttl time.Duration, clk clock.Clock) *Cache {
+	c := &Cache{load: load, ttl: ttl, clock: clk, environment: make(map[string]bool)}
+	for _, name := range environment {
+		c.environment[name] = true
+	}
+
+	return c
+}
+
+// Load loads the features disabled at runtime at once, and returns the error of the loader.
+func (c *Cache) Load() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.reload()
+}
+
+// Disabled reports whether the feature is disabled, and the reason given for it if any.
+// If the reload of the stale features fails, the previous ones are kept and retried after the TTL.
+func (c *Cache) Disabled(name string) (bool, string) {
+	if c == nil {
+		return false, ""
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.environment[name] {
+		return true, ""
+	}
+	if !c.loaded {
+		return false, ""
+	}
+	if c.stale || (c.ttl > 0 && !c.clock.Now().Before(c.loadedAt.Add(c.ttl))) {
+		if err := c.reload(); err != nil {
+			logger.Warn("Failed to reload the feature flags, keeping the previous ones", logrus.Fields{"error": err.Error()})
+		}
+	}
+
+	reason, disabled := c.current[name]
+	return disabled, reason
+}
+
+// DisabledByEnvironment reports whether the feature is disabled by DISABLED_FEATURES, and cannot be enabled at runtime.
+func (c *Cache) DisabledByEnvironment(name string) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.environment[name]
+}
+
+// Invalidate marks the features disabled at runtime stale, so that the next request reloads them.
+func (c *Cache) Invalidate() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stale = true
+}
+
+// reload loads the features disabled at runtime with the loader. The caller must hold the lock.
+func (c *Cache) reload() error {
+	c.loadedAt = c.clock.Now()
+	c.stale = false
+
+	disabled, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	c.current = disabled
+	c.loaded = true
+	return nil
+}
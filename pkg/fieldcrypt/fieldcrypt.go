@@ -0,0 +1,198 @@
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/secrets"
+)
+
+/**
+ * fieldcrypt package encrypts the personal data stored in the database (PII) at the application level.
+ * The values are encrypted with AES-256-GCM under a key read from the secrets provider, so a dump of
+ * the database or a backup does not disclose them. The columns are encrypted by the "encrypted" GORM
+ * serializer, and the equality lookups go through blind indexes: keyed HMAC-SHA256 digests of the values,
+ * stored next to them, which can be compared without decrypting anything.
+ *
+ * The key is held by the package because the GORM serializers are registered globally. Without a key,
+ * the values are stored in plain text, and the values stored in plain text are still read once a key is set,
+ * so that the existing rows can be encrypted afterward.
+ */
+
+// KeySecretName is the name of the secret holding the key, a base64-encoded 32-byte key.
+const KeySecretName = "PII_ENCRYPTION_KEY"
+
+// KeySize is the size of the key in bytes, for AES-256.
+const KeySize = 32
+
+// encryptedPrefix marks the encrypted values, followed by the base64-encoded nonce and ciphertext.
+const encryptedPrefix = "enc:v1:"
+
+// ErrKeyNotConfigured is returned when an encrypted value is read without a key.
+var ErrKeyNotConfigured = errors.New("PII encryption key is not configured")
+
+// Cipher encrypts the values and computes their blind indexes, with two keys derived from the configured key.
+type Cipher struct {
+	aead     cipher.AEAD
+	indexKey []byte
+}
+
+var current atomic.Pointer[Cipher]
+
+// NewCipher creates a cipher from a 32-byte key.
+// The encryption key and the blind index key are derived from it, so that the indexes do not leak the encryption key.
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("PII encryption key must be %d bytes, got %d", KeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(deriveKey(key, "pii-encryption"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the PII cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the PII cipher: %w", err)
+	}
+
+	return &Cipher{aead: aead, indexKey: deriveKey(key, "pii-blind-index")}, nil
+}
+
+// ParseKey decodes a base64-encoded 32-byte key.
+func ParseKey(value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("PII encryption key must be base64-encoded: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("PII encryption key must be %d bytes, got %d", KeySize, len(key))
+	}
+
+	return key, nil
+}
+
+// LoadKey reads the key from the given secrets provider.
+// It returns a nil key without error if the secret is not configured, the encryption is then disabled.
+func LoadKey(provider secrets.Provider) ([]byte, error) {
+	value, err := provider.Get(KeySecretName)
+	if errors.Is(err, secrets.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseKey(value)
+}
+
+// Init configures the cipher with the key of the default secrets provider.
+// It returns false if the key is invalid; without a key, the encryption is disabled and it returns true.
+func Init() bool {
+	key, err := LoadKey(secrets.Default())
+	if err != nil {
+		logger.Error("Failed to load the PII encryption key", logrus.Fields{"error": err.Error()})
+		return false
+	}
+	if key == nil {
+		logger.Warn(fmt.Sprintf("%s is not set, the personal data of the consumers is stored in plain text", KeySecretName), nil)
+		Configure(nil)
+		return true
+	}
+
+	c, err := NewCipher(key)
+	if err != nil {
+		logger.Error("Failed to create the PII cipher", logrus.Fields{"error": err.Error()})
+		return false
+	}
+	Configure(c)
+
+	return true
+}
+
+// Configure sets the cipher used by the serializer and the blind indexes, nil disables the encryption.
+func Configure(c *Cipher) {
+	current.Store(c)
+}
+
+// Enabled reports whether a cipher is configured.
+func Enabled() bool {
+	return current.Load() != nil
+}
+
+// Encrypt encrypts the plaintext with a random nonce, and returns it as a prefixed base64 string.
+func (c *Cipher) Encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, plaintext, nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value returned by Encrypt. It fails if the value was tampered with or encrypted under another key.
+func (c *Cipher) Decrypt(value string) ([]byte, error) {
+	encoded, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return nil, errors.New("value is not encrypted")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted value: %w", err)
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return nil, errors.New("encrypted value is too short")
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// BlindIndex returns the keyed digest of the value, as a hex string, for the equality lookups.
+func (c *Cipher) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, c.indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsEncrypted reports whether the stored value was encrypted by a cipher.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// BlindIndex returns the blind index of the value with the configured cipher.
+// Without a cipher, it returns the unkeyed SHA-256 digest of the value, so that the lookups keep working
+// while the encryption is disabled; the indexes must then be recomputed once a key is set.
+func BlindIndex(value string) string {
+	if c := current.Load(); c != nil {
+		return c.BlindIndex(value)
+	}
+
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// deriveKey derives a subkey of the key for the given purpose.
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,93 @@
+package fieldcrypt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName is the name of the GORM serializer encrypting the columns, e.g. `gorm:"serializer:encrypted"`.
+const SerializerName = "encrypted"
+
+func init() {
+	schema.RegisterSerializer(SerializerName, Serializer{})
+}
+
+// Serializer is the GORM serializer storing the fields encrypted with the configured cipher.
+// The fields are encoded as JSON before they are encrypted, so that any type can be encrypted; without a cipher,
+// the strings are stored as they are and the other types as JSON. A nil field is stored as NULL.
+type Serializer struct{}
+
+// Scan decrypts the stored value into the field. The values stored in plain text are read as they are.
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType)
+
+	if dbValue != nil {
+		var stored string
+		switch v := dbValue.(type) {
+		case []byte:
+			stored = string(v)
+		case string:
+			stored = v
+		default:
+			return fmt.Errorf("failed to decrypt %s: unsupported value type %T", field.Name, dbValue)
+		}
+
+		if err := decode(stored, fieldValue.Interface()); err != nil {
+			return fmt.Errorf("failed to read %s: %w", field.Name, err)
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+// Value encrypts the field with the configured cipher, or returns it in plain text without a cipher.
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	if v := reflect.ValueOf(fieldValue); !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(fieldValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", field.Name, err)
+	}
+
+	c := current.Load()
+	if c == nil {
+		var s string
+		if json.Unmarshal(data, &s) == nil {
+			return s, nil
+		}
+		return string(data), nil
+	}
+
+	return c.Encrypt(data)
+}
+
+// decode decodes a stored value into dst: the decrypted JSON encoding of the value, or the plain text value,
+// read as a JSON string first (e.g. a string or a date), then as JSON (e.g. a number).
+func decode(stored string, dst interface{}) error {
+	if !IsEncrypted(stored) {
+		quoted, _ := json.Marshal(stored)
+		if err := json.Unmarshal(quoted, dst); err == nil {
+			return nil
+		}
+		return json.Unmarshal([]byte(stored), dst)
+	}
+
+	c := current.Load()
+	if c == nil {
+		return ErrKeyNotConfigured
+	}
+
+	data, err := c.Decrypt(stored)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,180 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+/**
+ * geoip package provides the lookup of the location of the client IP addresses.
+ * The locations are read from a MaxMind GeoIP2 or GeoLite2 City database, and their autonomous systems
+ * from a GeoLite2 ASN database, whose paths are configurable. They annotate the login audit entries and
+ * the anomaly detection events, and the logins of the administrators can be rejected from configured countries.
+ * Without a database, every location is unknown.
+ */
+
+// Location represents the location of an IP address.
+// Country is the ISO 3166-1 alpha-2 code of the country, e.g. "ID", City its English name,
+// and ASN the number of the autonomous system of the network, e.g. "AS64500".
+// They are empty when they are unknown.
+type Location struct {
+	Country string `json:"country,omitempty"`
+	City    string `json:"city,omitempty"`
+	ASN     string `json:"asn,omitempty"`
+}
+
+// Locator looks up the location of an IP address.
+type Locator interface {
+	Lookup(ip string) Location
+}
+
+// Config holds the paths of the GeoIP databases and the countries the administrators cannot log in from.
+type Config struct {
+	DBPath                string
+	ASNDBPath             string
+	BlockedAdminCountries []string
+}
+
+// maxMindLocator looks up the locations in a MaxMind City database and the autonomous systems in a MaxMind ASN database,
+// either of them being optional.
+type maxMindLocator struct {
+	city *geoip2.Reader
+	asn  *geoip2.Reader
+}
+
+// noopLocator is used when no GeoIP database is configured, every location is unknown.
+type noopLocator struct{}
+
+var (
+	once    sync.Once
+	locator Locator
+)
+
+// LoadConfig loads the GeoIP configuration from environment variables.
+// BlockedAdminCountries is read from a comma separated list of country codes, e.g. "KP,IR".
+func LoadConfig() Config {
+	cfg := Config{DBPath: env.Getenv("GEOIP_DB_PATH"), ASNDBPath: env.Getenv("GEOIP_ASN_DB_PATH")}
+
+	for _, c := range strings.Split(env.Getenv("GEOIP_BLOCKED_ADMIN_COUNTRIES"), ",") {
+		if c = strings.ToUpper(strings.TrimSpace(c)); c != "" {
+			cfg.BlockedAdminCountries = append(cfg.BlockedAdminCountries, c)
+		}
+	}
+
+	return cfg
+}
+
+// Init initializes the locator singleton using the configuration from environment variables.
+// If a database cannot be opened, the error is logged and every location is unknown.
+func Init() {
+	InitWithConfig(LoadConfig())
+}
+
+// InitWithConfig initializes the locator singleton with the given configuration, like Init.
+func InitWithConfig(cfg Config) {
+	once.Do(func() {
+		if cfg.DBPath == "" && cfg.ASNDBPath == "" {
+			locator = noopLocator{}
+			return
+		}
+
+		l, err := OpenDatabases(cfg.DBPath, cfg.ASNDBPath)
+		if err != nil {
+			logger.Error("Failed to open the GeoIP database, locations are unknown", logrus.Fields{
+				"path":     cfg.DBPath,
+				"asn_path": cfg.ASNDBPath,
+				"error":    err.Error(),
+			})
+			locator = noopLocator{}
+			return
+		}
+		locator = l
+	})
+}
+
+// GetLocator returns the initialized locator instance.
+func GetLocator() Locator {
+	if locator == nil {
+		Init()
+	}
+	return locator
+}
+
+// Open opens the MaxMind City database at the given path.
+func Open(path string) (Locator, error) {
+	return OpenDatabases(path, "")
+}
+
+// OpenDatabases opens the MaxMind City database and the MaxMind ASN database at the given paths, an empty path being skipped.
+func OpenDatabases(cityPath string, asnPath string) (Locator, error) {
+	l := &maxMindLocator{}
+	if cityPath != "" {
+		reader, err := geoip2.Open(cityPath)
+		if err != nil {
+			return nil, err
+		}
+		l.city = reader
+	}
+
+	if asnPath != "" {
+		reader, err := geoip2.Open(asnPath)
+		if err != nil {
+			if l.city != nil {
+				l.city.Close()
+			}
+			return nil, err
+		}
+		l.asn = reader
+	}
+
+	return l, nil
+}
+
+// Lookup returns the location of the IP address, or an empty location if it is not found.
+func (l *maxMindLocator) Lookup(ip string) Location {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return Location{}
+	}
+
+	var location Location
+	if l.city != nil {
+		if record, err := l.city.City(parsed); err == nil {
+			location.Country = record.Country.IsoCode
+			location.City = record.City.Names["en"]
+		}
+	}
+
+	if l.asn != nil {
+		if record, err := l.asn.ASN(parsed); err == nil && record.AutonomousSystemNumber != 0 {
+			location.ASN = fmt.Sprintf("AS%d", record.AutonomousSystemNumber)
+		}
+	}
+
+	return location
+}
+
+// Lookup returns an empty location.
+func (noopLocator) Lookup(ip string) Location {
+	return Location{}
+}
+
+// String returns the location in a human readable form, e.g. "Jakarta, ID".
+func (l Location) String() string {
+	switch {
+	case l.Country == "":
+		return "Unknown"
+	case l.City == "":
+		return l.Country
+	default:
+		return l.City + ", " + l.Country
+	}
+}
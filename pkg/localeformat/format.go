@@ -0,0 +1,156 @@
+package localeformat
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Locale is a locale the consumers are formatted for, e.g. id-ID.
+type Locale struct {
+	tag    language.Tag
+	region string
+}
+
+// newLocale returns the locale of the given tag.
+func newLocale(tag language.Tag) Locale {
+	region, _ := tag.Region()
+	return Locale{tag: tag, region: region.String()}
+}
+
+// String returns the BCP 47 tag of the locale, e.g. id-ID, set as the Content-Language of the formatted responses.
+func (l Locale) String() string {
+	return l.tag.String()
+}
+
+// phonePattern renders the national significant numbers starting with its prefix and of its length,
+// filling the X of its templates with their digits in order.
+type phonePattern struct {
+	prefix        string
+	length        int
+	national      string
+	international string
+}
+
+// phonePlan holds the phone numbers of a country calling code: the regions using them and the patterns rendering them.
+type phonePlan struct {
+	callingCode string
+	regions     []string
+	patterns    []phonePattern
+}
+
+// phonePlans are the numbering plans of the countries of the supported locales, the other numbers are left in E.164.
+// The patterns of a plan are checked in order, the first matching one renders the number.
+var phonePlans = []phonePlan{
+	{callingCode: "1", regions: []string{"US", "CA"}, patterns: []phonePattern{
+		{length: 10, national: "(XXX) XXX-XXXX", international: "+1 XXX-XXX-XXXX"},
+	}},
+	{callingCode: "44", regions: []string{"GB"}, patterns: []phonePattern{
+		{prefix: "20", length: 10, national: "0XX XXXX XXXX", international: "+44 XX XXXX XXXX"},
+		{length: 10, national: "0XXXX XXXXXX", international: "+44 XXXX XXXXXX"},
+	}},
+	{callingCode: "60", regions: []string{"MY"}, patterns: []phonePattern{
+		{prefix: "1", length: 10, national: "0XX-XXXX XXXX", international: "+60 XX-XXXX XXXX"},
+		{prefix: "1", length: 9, national: "0XX-XXX XXXX", international: "+60 XX-XXX XXXX"},
+		{prefix: "3", length: 9, national: "0X-XXXX XXXX", international: "+60 X-XXXX XXXX"},
+	}},
+	{callingCode: "61", regions: []string{"AU"}, patterns: []phonePattern{
+		{prefix: "4", length: 9, national: "0XXX XXX XXX", international: "+61 XXX XXX XXX"},
+		{length: 9, national: "(0X) XXXX XXXX", international: "+61 X XXXX XXXX"},
+	}},
+	{callingCode: "62", regions: []string{"ID"}, patterns: []phonePattern{
+		{prefix: "8", length: 9, national: "0XXX-XXX-XXX", international: "+62 XXX-XXX-XXX"},
+		{prefix: "8", length: 10, national: "0XXX-XXX-XXXX", international: "+62 XXX-XXX-XXXX"},
+		{prefix: "8", length: 11, national: "0XXX-XXXX-XXXX", international: "+62 XXX-XXXX-XXXX"},
+		{prefix: "8", length: 12, national: "0XXX-XXXX-XXXXX", international: "+62 XXX-XXXX-XXXXX"},
+		{prefix: "21", length: 10, national: "(0XX) XXXX-XXXX", international: "+62 XX XXXX-XXXX"},
+	}},
+	{callingCode: "65", regions: []string{"SG"}, patterns: []phonePattern{
+		{length: 8, national: "XXXX XXXX", international: "+65 XXXX XXXX"},
+	}},
+	{callingCode: "81", regions: []string{"JP"}, patterns: []phonePattern{
+		{prefix: "70", length: 10, national: "0XX-XXXX-XXXX", international: "+81 XX-XXXX-XXXX"},
+		{prefix: "80", length: 10, national: "0XX-XXXX-XXXX", international: "+81 XX-XXXX-XXXX"},
+		{prefix: "90", length: 10, national: "0XX-XXXX-XXXX", international: "+81 XX-XXXX-XXXX"},
+		{prefix: "3", length: 9, national: "0X-XXXX-XXXX", international: "+81 X-XXXX-XXXX"},
+	}},
+}
+
+// bigEndianLanguages are the languages writing the addresses from the largest part to the smallest, e.g. the prefecture first.
+var bigEndianLanguages = map[string]bool{"ja": true}
+
+// Phone renders a phone number stored in E.164, with or without its leading '+', in the national format of the locale
+// when it belongs to the country of the locale, and in the international format otherwise.
+// The numbers of the other countries, or not matching the plan of their country, are returned in E.164.
+func (l Locale) Phone(e164 string) string {
+	digits := strings.TrimPrefix(strings.TrimSpace(e164), "+")
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return e164
+	}
+
+	for _, plan := range phonePlans {
+		nsn, ok := strings.CutPrefix(digits, plan.callingCode)
+		if !ok {
+			continue
+		}
+
+		for _, p := range plan.patterns {
+			if len(nsn) != p.length || !strings.HasPrefix(nsn, p.prefix) {
+				continue
+			}
+
+			if contains(plan.regions, l.region) {
+				return fill(p.national, nsn)
+			}
+			return fill(p.international, nsn)
+		}
+		break
+	}
+
+	return "+" + digits
+}
+
+// Address renders an address stored as comma-separated parts from the smallest to the largest, e.g. "Jl. Merdeka No. 123, Jakarta",
+// in the order of the locale, with its parts separated by a comma and a space.
+func (l Locale) Address(address string) string {
+	var parts []string
+	for _, part := range strings.Split(address, ",") {
+		if part = strings.Join(strings.Fields(part), " "); part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	base, _ := l.tag.Base()
+	if bigEndianLanguages[base.String()] {
+		for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+			parts[i], parts[j] = parts[j], parts[i]
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// fill replaces the X of the template with the digits in order.
+func fill(template string, digits string) string {
+	var b strings.Builder
+	next := 0
+	for _, r := range template {
+		if r == 'X' {
+			b.WriteByte(digits[next])
+			next++
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// contains reports whether the values contain the given value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package localeformat
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/text/language"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+/**
+ * localeformat package renders the phone numbers and the addresses of the consumers in the conventions of the locale
+ * negotiated with the Accept-Language header of the request, for the consumer-facing frontends of several markets.
+ * The phone numbers, stored in E.164, are rendered in the national format of the locale when they belong to its country,
+ * e.g. "0812-3456-7890" for id-ID, and in the international format otherwise, e.g. "+62 812-3456-7890" for en-US.
+ * The addresses, stored as free text with comma-separated parts from the smallest to the largest, are rendered
+ * in the order of the locale, e.g. from the largest to the smallest for ja-JP.
+ *
+ * The formatting is opt-in with LOCALE_FORMATTING_ENABLED=TRUE, so that the clients storing the values as they are returned
+ * keep getting the E.164 phone numbers. Like the data masking, the settings are held by the package.
+ * The stored data is never modified, only its rendering.
+ */
+
+// SupportedLocales lists the locales the consumers can be formatted for.
+var SupportedLocales = []string{"en-US", "en-GB", "en-AU", "en-SG", "id-ID", "ms-MY", "ja-JP"}
+
+// Config holds the formatting settings: whether the formatting is enabled and the locales negotiated.
+type Config struct {
+	Enabled bool
+	Locales []language.Tag
+	matcher language.Matcher
+}
+
+var current atomic.Pointer[Config]
+
+// LoadConfig reads the formatting settings from the environment variables.
+// LOCALE_FORMATTING_ENABLED=TRUE enables the formatting for the locales of LOCALE_FORMATTING_LOCALES, a comma separated list
+// of the supported locales, all of them by default. It returns an error if a locale is not supported.
+func LoadConfig() (Config, error) {
+	cfg := Config{Enabled: env.Getenv("LOCALE_FORMATTING_ENABLED") == "TRUE"}
+
+	names := SupportedLocales
+	if v := strings.TrimSpace(env.Getenv("LOCALE_FORMATTING_LOCALES")); v != "" {
+		names = strings.Split(v, ",")
+	}
+
+	for _, name := range names {
+		tag, err := language.Parse(strings.TrimSpace(name))
+		if err != nil || !isSupported(tag) {
+			return cfg, fmt.Errorf("LOCALE_FORMATTING_LOCALES contains an unsupported locale %q, expected any of %s", strings.TrimSpace(name), strings.Join(SupportedLocales, ", "))
+		}
+		cfg.Locales = append(cfg.Locales, tag)
+	}
+	cfg.matcher = language.NewMatcher(cfg.Locales)
+
+	return cfg, nil
+}
+
+// Init configures the formatting with the settings of the environment variables.
+// It returns false if the settings are invalid.
+func Init() bool {
+	cfg, err := LoadConfig()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Invalid locale formatting configuration: %v", err), nil)
+		return false
+	}
+
+	Configure(cfg)
+	return true
+}
+
+// Configure sets the formatting settings.
+func Configure(cfg Config) {
+	if cfg.matcher == nil {
+		cfg.matcher = language.NewMatcher(cfg.Locales)
+	}
+	current.Store(&cfg)
+}
+
+// Enabled reports whether the formatting is enabled.
+func Enabled() bool {
+	cfg := current.Load()
+	return cfg != nil && cfg.Enabled
+}
+
+// Negotiate returns the locale of the given Accept-Language value, e.g. "id-ID,id;q=0.9,en;q=0.8", among the configured locales.
+// It returns false when the formatting is disabled, or when none of the languages of the value matches a configured locale,
+// in which case the consumers are not formatted.
+func Negotiate(acceptLanguage string) (Locale, bool) {
+	cfg := current.Load()
+	if cfg == nil || !cfg.Enabled || len(cfg.Locales) == 0 || strings.TrimSpace(acceptLanguage) == "" {
+		return Locale{}, false
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return Locale{}, false
+	}
+
+	_, index, confidence := cfg.matcher.Match(tags...)
+	if confidence == language.No {
+		return Locale{}, false
+	}
+
+	return newLocale(cfg.Locales[index]), true
+}
+
+// isSupported reports whether the given locale is one of SupportedLocales.
+func isSupported(tag language.Tag) bool {
+	for _, name := range SupportedLocales {
+		if tag == language.MustParse(name) {
+			return true
+		}
+	}
+	return false
+}
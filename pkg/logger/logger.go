@@ -1,12 +1,15 @@
 package logger
 
 import (
+	"context"
 	"io"
 	"os"
 	"sync"
 
 	"github.com/sirupsen/logrus"
 	"gopkg.in/natefinch/lumberjack.v2"
+
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
 )
 
 /**
@@ -15,6 +18,7 @@ import (
  * Each logger is configured with a specific log file, maximum size, number of backups, and age.
  * The loggers are initialized only once using sync.Once to ensure thread safety.
  * The package provides functions to log messages at different levels (Info, Warn, Error, Fatal, Panic, Trace, Debug).
+ * The Context variants add the ID of the request of the given context to the fields, to correlate the logs of a request.
  * The log files are stored in the "logs" directory, and each logger has its own file with specific naming conventions.
  */
 
@@ -324,6 +328,39 @@ func Debug(msg string, fields logrus.Fields) {
 	}
 }
 
+// Log functions for the given context, adding the ID of its request to the fields as request_id, if it carries one
+func InfoContext(ctx context.Context, msg string, fields logrus.Fields) {
+	Info(msg, withRequestID(ctx, fields))
+}
+
+func WarnContext(ctx context.Context, msg string, fields logrus.Fields) {
+	Warn(msg, withRequestID(ctx, fields))
+}
+
+func ErrorContext(ctx context.Context, msg string, fields logrus.Fields) {
+	Error(msg, withRequestID(ctx, fields))
+}
+
+func DebugContext(ctx context.Context, msg string, fields logrus.Fields) {
+	Debug(msg, withRequestID(ctx, fields))
+}
+
+// withRequestID returns a copy of the fields with the ID of the request of the context, or the fields unchanged if it carries none.
+func withRequestID(ctx context.Context, fields logrus.Fields) logrus.Fields {
+	requestID := metacontext.ExtractRequestID(ctx)
+	if requestID == "" {
+		return fields
+	}
+
+	withID := make(logrus.Fields, len(fields)+1)
+	for k, v := range fields {
+		withID[k] = v
+	}
+	withID["request_id"] = requestID
+
+	return withID
+}
+
 func Exit() {
 	// Exit all loggers gracefully
 	RequestLogger.Exit(0)
@@ -0,0 +1,189 @@
+package mailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+/**
+ * mailer package sends the emails of the application through a pluggable Mailer.
+ * MAILER_DRIVER selects the implementation: log (default) writes the emails to the info log,
+ * which is enough for development, smtp sends them with the SMTP_* settings, and none discards them.
+ */
+
+const (
+	DriverLog  = "log"
+	DriverSMTP = "smtp"
+	DriverNone = "none"
+)
+
+// Message is an email with a plain text body, and an HTML alternative if HTML is set.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	HTML    string
+}
+
+// Interface for mailer
+// This interface defines the methods that the mailers should implement
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Config holds the driver of the mailer, and the settings of the SMTP server with the smtp driver.
+type Config struct {
+	Driver string
+	SMTP   SMTPConfig
+}
+
+// LoadConfig reads the settings of the mailer from MAILER_DRIVER and the SMTP_* environment variables.
+func LoadConfig() Config {
+	return Config{
+		Driver: strings.ToLower(env.Getenv("MAILER_DRIVER")),
+		SMTP: SMTPConfig{
+			Host:     env.Getenv("SMTP_HOST"),
+			Port:     env.Getenv("SMTP_PORT"),
+			Username: env.Getenv("SMTP_USERNAME"),
+			Password: env.Getenv("SMTP_PASSWORD"),
+			From:     env.Getenv("MAIL_FROM"),
+		},
+	}
+}
+
+// New creates the mailer of the driver of the settings.
+// It returns an error if the driver is not supported or the SMTP settings are incomplete.
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Driver {
+	case "", DriverLog:
+		return NewLogMailer(), nil
+	case DriverNone:
+		return NewNoopMailer(), nil
+	case DriverSMTP:
+		return NewSMTPMailer(cfg.SMTP)
+	default:
+		return nil, fmt.Errorf("MAILER_DRIVER %q is not supported, use log, smtp, or none", cfg.Driver)
+	}
+}
+
+// This struct defines the Mailer that writes the emails to the log instead of sending them
+type logMailer struct{}
+
+// NewLogMailer creates a new instance of Mailer that writes the emails to the info log.
+func NewLogMailer() Mailer {
+	return &logMailer{}
+}
+
+// Send writes the email to the info log.
+func (m *logMailer) Send(msg Message) error {
+	logger.Info(fmt.Sprintf("Email to %s: %s", msg.To, msg.Subject), logrus.Fields{
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+		"html":    msg.HTML != "",
+	})
+
+	return nil
+}
+
+// This struct defines the Mailer that discards the emails
+type noopMailer struct{}
+
+// NewNoopMailer creates a new instance of Mailer that discards the emails.
+func NewNoopMailer() Mailer {
+	return &noopMailer{}
+}
+
+// Send discards the email.
+func (m *noopMailer) Send(msg Message) error {
+	return nil
+}
+
+// SMTPConfig holds the settings of the SMTP server used to send the emails.
+// The username and password are optional, the server is used without authentication if they are empty.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// This struct defines the Mailer that sends the emails with an SMTP server
+type smtpMailer struct {
+	config SMTPConfig
+	auth   smtp.Auth
+}
+
+// NewSMTPMailer creates a new instance of Mailer that sends the emails with the given SMTP server.
+// It returns an error if the host, port, or sender is missing.
+func NewSMTPMailer(cfg SMTPConfig) (Mailer, error) {
+	if cfg.Host == "" || cfg.Port == "" || cfg.From == "" {
+		return nil, fmt.Errorf("SMTP_HOST, SMTP_PORT, and MAIL_FROM must be set to send emails with SMTP")
+	}
+
+	m := &smtpMailer{config: cfg}
+	if cfg.Username != "" {
+		m.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return m, nil
+}
+
+// Send sends the email with the SMTP server.
+func (m *smtpMailer) Send(msg Message) error {
+	// Reject header injection through the recipient or the subject
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("invalid email header")
+	}
+
+	body, err := m.compose(msg)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.config.Host, m.config.Port)
+	if err := smtp.SendMail(addr, m.auth, m.config.From, []string{msg.To}, body); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", msg.To, err)
+	}
+
+	return nil
+}
+
+// compose returns the email with its headers, the subject being encoded for its non-ASCII characters.
+// An email with HTML is sent as multipart/alternative, the plain text first, so the clients show the HTML if they can.
+func (m *smtpMailer) compose(msg Message) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("From: " + m.config.From + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("UTF-8", msg.Subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n" + msg.Body)
+		return []byte(b.String()), nil
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("failed to generate the MIME boundary: %w", err)
+	}
+	boundary := hex.EncodeToString(random)
+
+	b.WriteString("Content-Type: multipart/alternative; boundary=\"" + boundary + "\"\r\n\r\n")
+	b.WriteString("--" + boundary + "\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n" + msg.Body + "\r\n")
+	b.WriteString("--" + boundary + "\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n" + msg.HTML + "\r\n")
+	b.WriteString("--" + boundary + "--\r\n")
+
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,303 @@
+package mailer
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+)
+
+/**
+ * The templates render the emails of the application in plain text and HTML, in the locale of the recipient.
+ * The default templates are embedded in the binary, under templates/<locale>/. A template is made of <name>.txt,
+ * a text/template defining the "subject" and rendering the plain text body, and of the optional <name>.html,
+ * an html/template rendering the HTML body, whose values are escaped. Both can format a time with datetime.
+ * MAIL_TEMPLATES_DIR brands the emails without a rebuild: its files, laid out like the embedded ones, replace
+ * the embedded files with the same path, and may add locales. The locale of an email is picked from a locale
+ * or an Accept-Language value, then its language without region, then MAIL_LOCALE (en by default); a template
+ * missing from a locale falls back to the same template of MAIL_LOCALE, then of en.
+ */
+
+// DefaultLocale is the locale of the emails when MAIL_LOCALE is not set.
+const DefaultLocale = "en"
+
+// Names of the email templates.
+const (
+	TemplatePasswordReset   = "password-reset"
+	TemplateNewDeviceLogin  = "new-device-login"
+	TemplatePasswordChanged = "password-changed"
+	TemplateMFADisabled     = "mfa-disabled"
+)
+
+// Extensions of the files of a template.
+const (
+	textTemplateExt = ".txt"
+	htmlTemplateExt = ".html"
+)
+
+// ErrUnknownTemplate is returned when an email is rendered with a template that does not exist.
+var ErrUnknownTemplate = errors.New("unknown email template")
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// PasswordResetEmail holds the data of the password reset email.
+type PasswordResetEmail struct {
+	Username  string
+	Link      string
+	ExpiresAt time.Time
+}
+
+// SecurityEmail holds the data of the security notification emails: a new device login, a password change,
+// and the deactivation of two-factor authentication. The location and the device are only set for the logins.
+type SecurityEmail struct {
+	Username  string
+	At        time.Time
+	IPAddress string
+	Location  string
+	Device    string
+}
+
+// templateFuncs are the functions available to the templates.
+var templateFuncs = map[string]interface{}{
+	"datetime": func(t time.Time) string {
+		return t.UTC().Format(time.RFC1123)
+	},
+}
+
+// mailTemplate is a parsed template, its HTML body is optional.
+type mailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Templates renders the emails from the embedded templates and their overrides.
+type Templates struct {
+	locale    string
+	templates map[string]map[string]mailTemplate
+}
+
+// LoadTemplates parses the embedded templates, overridden by the ones of MAIL_TEMPLATES_DIR, if set,
+// with MAIL_LOCALE as the default locale.
+func LoadTemplates() (*Templates, error) {
+	return NewTemplates(env.Getenv("MAIL_TEMPLATES_DIR"), env.Getenv("MAIL_LOCALE"))
+}
+
+// NewTemplates parses the embedded templates, overridden by the ones of the given directory, if not empty.
+// It returns an error if a template does not parse, has no subject or no plain text body,
+// or if the default locale has no templates.
+func NewTemplates(dir string, locale string) (*Templates, error) {
+	locale = normalizeLocale(locale)
+	if locale == "" {
+		locale = DefaultLocale
+	}
+
+	embedded, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	files, err := readTemplateFiles(embedded, nil)
+	if err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if files, err = readTemplateFiles(os.DirFS(dir), files); err != nil {
+			return nil, fmt.Errorf("MAIL_TEMPLATES_DIR: %w", err)
+		}
+	}
+
+	t := &Templates{locale: locale, templates: make(map[string]map[string]mailTemplate)}
+	for file, content := range files {
+		if path.Ext(file) != textTemplateExt {
+			continue
+		}
+
+		dirName, fileName := path.Split(file)
+		name := strings.TrimSuffix(fileName, textTemplateExt)
+		tmpl, err := parseTemplate(file, content, files[path.Join(dirName, name+htmlTemplateExt)])
+		if err != nil {
+			return nil, err
+		}
+
+		loc := path.Clean(dirName)
+		if t.templates[loc] == nil {
+			t.templates[loc] = make(map[string]mailTemplate)
+		}
+		t.templates[loc][name] = tmpl
+	}
+
+	for file := range files {
+		if path.Ext(file) == htmlTemplateExt {
+			if _, ok := files[strings.TrimSuffix(file, htmlTemplateExt)+textTemplateExt]; !ok {
+				return nil, fmt.Errorf("email template %s has no plain text body %s", file, path.Base(strings.TrimSuffix(file, htmlTemplateExt)+textTemplateExt))
+			}
+		}
+	}
+	if _, ok := t.templates[locale]; !ok {
+		return nil, fmt.Errorf("MAIL_LOCALE %q has no email templates", locale)
+	}
+
+	return t, nil
+}
+
+// Locales returns the locales with templates, sorted.
+func (t *Templates) Locales() []string {
+	locales := make([]string, 0, len(t.templates))
+	for locale := range t.templates {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	return locales
+}
+
+// Render renders the email of the template with the given data, in the locale picked from the given locale
+// or Accept-Language value. The recipient of the returned message is left empty.
+func (t *Templates) Render(name string, locale string, data interface{}) (Message, error) {
+	tmpl, ok := t.lookup(name, locale)
+	if !ok {
+		return Message{}, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+
+	var subject, body strings.Builder
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render the subject of email %s: %w", name, err)
+	}
+	if err := tmpl.text.Execute(&body, data); err != nil {
+		return Message{}, fmt.Errorf("failed to render email %s: %w", name, err)
+	}
+
+	msg := Message{Subject: strings.TrimSpace(subject.String()), Body: body.String()}
+	if tmpl.html != nil {
+		var html strings.Builder
+		if err := tmpl.html.Execute(&html, data); err != nil {
+			return Message{}, fmt.Errorf("failed to render the HTML of email %s: %w", name, err)
+		}
+		msg.HTML = html.String()
+	}
+
+	return msg, nil
+}
+
+// lookup returns the template with the given name in the first locale having it: the locales picked from the given
+// locale or Accept-Language value, then the default locale, then DefaultLocale.
+func (t *Templates) lookup(name string, locale string) (mailTemplate, bool) {
+	for _, candidate := range append(preferredLocales(locale), t.locale, DefaultLocale) {
+		if tmpl, ok := t.templates[candidate][name]; ok {
+			return tmpl, true
+		}
+	}
+
+	return mailTemplate{}, false
+}
+
+// readTemplateFiles adds the template files of the file system, <locale>/<name>.txt and <locale>/<name>.html,
+// to the given files, replacing the files with the same path. The other files are ignored.
+func readTemplateFiles(fsys fs.FS, files map[string]string) (map[string]string, error) {
+	if files == nil {
+		files = make(map[string]string)
+	}
+
+	err := fs.WalkDir(fsys, ".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.Count(file, "/") != 1 {
+			return nil
+		}
+		if ext := path.Ext(file); ext != textTemplateExt && ext != htmlTemplateExt {
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		files[normalizeLocale(path.Dir(file))+"/"+path.Base(file)] = string(content)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// parseTemplate parses the plain text template, which must define the subject, and the HTML template, if not empty.
+func parseTemplate(file string, text string, html string) (mailTemplate, error) {
+	var tmpl mailTemplate
+	var err error
+
+	tmpl.text, err = texttemplate.New(path.Base(file)).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return mailTemplate{}, fmt.Errorf("invalid email template %s: %w", file, err)
+	}
+	if tmpl.text.Lookup("subject") == nil {
+		return mailTemplate{}, fmt.Errorf("email template %s does not define its subject", file)
+	}
+
+	if html != "" {
+		htmlFile := strings.TrimSuffix(file, textTemplateExt) + htmlTemplateExt
+		tmpl.html, err = htmltemplate.New(path.Base(htmlFile)).Funcs(templateFuncs).Option("missingkey=error").Parse(html)
+		if err != nil {
+			return mailTemplate{}, fmt.Errorf("invalid email template %s: %w", htmlFile, err)
+		}
+	}
+
+	return tmpl, nil
+}
+
+// preferredLocales returns the locales of a locale or an Accept-Language value, e.g. "id-ID,id;q=0.9,en;q=0.8",
+// by decreasing preference, each followed by its language without region.
+func preferredLocales(value string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(value, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = normalizeLocale(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{locale: tag, q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	locales := make([]string, 0, 2*len(tags))
+	for _, tag := range tags {
+		locales = append(locales, tag.locale)
+		if language, _, ok := strings.Cut(tag.locale, "-"); ok {
+			locales = append(locales, language)
+		}
+	}
+
+	return locales
+}
+
+// normalizeLocale returns the locale in lowercase with dashes, e.g. "pt-br" for "pt_BR".
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
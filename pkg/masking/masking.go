@@ -0,0 +1,151 @@
+package masking
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+/**
+ * masking package redacts the personal data (PII) of the consumers in the API responses and the exports
+ * (the consumer stream and the webhook payloads), for the staging and development environments running
+ * against a copy of the production data. It is enforced where the consumers are encoded as JSON,
+ * so no handler can leak the data by forgetting to mask it.
+ *
+ * The settings are held by the package because the JSON encoding cannot be given any dependency.
+ * The stored data is never modified, only its encoding.
+ */
+
+// The fields of the consumers that can be masked, named after their JSON keys.
+const (
+	FieldFullname  = "fullname"
+	FieldUsername  = "username"
+	FieldEmail     = "email"
+	FieldPhone     = "phone"
+	FieldAddress   = "address"
+	FieldBirthDate = "birthDate"
+)
+
+// Fields lists the fields that can be masked.
+var Fields = []string{FieldFullname, FieldUsername, FieldEmail, FieldPhone, FieldAddress, FieldBirthDate}
+
+// DefaultFields lists the fields masked when DATA_MASKING_FIELDS is not set, the username is kept to tell the consumers apart.
+var DefaultFields = []string{FieldFullname, FieldEmail, FieldPhone, FieldAddress, FieldBirthDate}
+
+// Redacted replaces the values that are masked entirely.
+const Redacted = "[REDACTED]"
+
+// Config holds the masking settings: whether the masking is enabled and the fields it masks.
+type Config struct {
+	Enabled bool
+	Fields  map[string]bool
+}
+
+var current atomic.Pointer[Config]
+
+// LoadConfig reads the masking settings from the environment variables.
+// DATA_MASKING_ENABLED=TRUE enables the masking of the fields in DATA_MASKING_FIELDS, a comma separated list of field names.
+// It returns an error if a field cannot be masked.
+func LoadConfig() (Config, error) {
+	cfg := Config{Enabled: env.Getenv("DATA_MASKING_ENABLED") == "TRUE", Fields: make(map[string]bool)}
+
+	names := DefaultFields
+	if v := strings.TrimSpace(env.Getenv("DATA_MASKING_FIELDS")); v != "" {
+		names = strings.Split(v, ",")
+	}
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if !isField(name) {
+			return cfg, fmt.Errorf("DATA_MASKING_FIELDS contains an unknown field %q, expected any of %s", name, strings.Join(Fields, ", "))
+		}
+		cfg.Fields[name] = true
+	}
+
+	return cfg, nil
+}
+
+// Init configures the masking with the settings of the environment variables.
+// It returns false if the settings are invalid.
+func Init() bool {
+	cfg, err := LoadConfig()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Invalid data masking configuration: %v", err), nil)
+		return false
+	}
+
+	if cfg.Enabled {
+		logger.Warn("Data masking is enabled, the personal data of the consumers is redacted in the responses and the exports", nil)
+	}
+	Configure(cfg)
+
+	return true
+}
+
+// Configure sets the masking settings.
+func Configure(cfg Config) {
+	current.Store(&cfg)
+}
+
+// Enabled reports whether the masking is enabled.
+func Enabled() bool {
+	cfg := current.Load()
+	return cfg != nil && cfg.Enabled
+}
+
+// Masks reports whether the given field is masked.
+func Masks(field string) bool {
+	cfg := current.Load()
+	return cfg != nil && cfg.Enabled && cfg.Fields[field]
+}
+
+// Name masks a name, keeping the first letter of each word, e.g. "John Doe" becomes "J*** D**".
+func Name(value string) string {
+	words := strings.Fields(value)
+	for i, w := range words {
+		r := []rune(w)
+		words[i] = string(r[0]) + strings.Repeat("*", len(r)-1)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// Email masks the local part of an email, keeping its first letter and the domain, e.g. "john@example.com" becomes "j***@example.com".
+func Email(value string) string {
+	local, domain, ok := strings.Cut(value, "@")
+	if !ok {
+		return Name(value)
+	}
+
+	return Name(local) + "@" + domain
+}
+
+// Phone masks a phone number, keeping its last 4 digits, e.g. "+6281234567890" becomes "+*********7890".
+func Phone(value string) string {
+	r := []rune(value)
+	keep := 4
+	if len(r) <= keep {
+		return strings.Repeat("*", len(r))
+	}
+
+	prefix := ""
+	if r[0] == '+' {
+		prefix, r = "+", r[1:]
+	}
+
+	return prefix + strings.Repeat("*", len(r)-keep) + string(r[len(r)-keep:])
+}
+
+// isField reports whether the field can be masked.
+func isField(name string) bool {
+	for _, f := range Fields {
+		if f == name {
+			return true
+		}
+	}
+
+	return false
+}
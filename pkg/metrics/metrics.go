@@ -0,0 +1,290 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+)
+
+/**
+ * metrics package exports the metrics of the application in the OpenMetrics (and Prometheus) text format on /metrics.
+ * Besides the Go runtime and process metrics, it exports business counters, such as the consumers created and
+ * the status transitions of the consumers and the users, so that product dashboards can be built from the same
+ * endpoint as the operational ones, and the database queries by table and operation, so that e.g. the slow consumer
+ * searches can be told apart from the user lookups of the logins, the retries of the reads failing with a transient database error,
+ * and the writes buffered while the database is unavailable.
+ * The HTTP requests are counted and timed by route (the pattern of the route, not the requested path), method, and status,
+ * the connection pool of the database is exported as the go_sql_* gauges, and the logins and the token refreshes by result.
+ * The requests also feed the burn rates of the availability and latency objectives of their route group, see slo.go.
+ * The counters are process-wide and reset when the application restarts.
+ */
+
+// Results of the consumer cache lookups.
+const (
+	CacheResultHit  = "hit"
+	CacheResultMiss = "miss"
+)
+
+// Results of the logins. A login of a user with two-factor authentication is counted as mfa_required,
+// then as succeeded or failed once the code is verified.
+const (
+	LoginResultSucceeded   = "succeeded"
+	LoginResultFailed      = "failed"
+	LoginResultMFARequired = "mfa_required"
+)
+
+// Results of the token refreshes.
+const (
+	RefreshResultSucceeded = "succeeded"
+	RefreshResultFailed    = "failed"
+)
+
+// Results of the writes buffered after a transient database error: buffered when queued, then written or dropped.
+const (
+	BufferedWriteResultBuffered = "buffered"
+	BufferedWriteResultWritten  = "written"
+	BufferedWriteResultDropped  = "dropped"
+)
+
+// UnmatchedRoute is the route label of the requests matching no route, so that the scans of unknown paths
+// do not create a series per path.
+const UnmatchedRoute = "unmatched"
+
+// Results of the attempts of the webhook deliveries.
+const (
+	WebhookResultSucceeded = "succeeded"
+	WebhookResultFailed    = "failed"
+	WebhookResultDead      = "dead"
+)
+
+var (
+	// Registry holds the metrics exported on /metrics.
+	Registry = prometheus.NewRegistry()
+
+	consumersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "consumers_created_total",
+		Help: "Number of consumers created.",
+	})
+
+	consumerStatusTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "consumer_status_transitions_total",
+		Help: "Number of status changes of the consumers, by previous and new status.",
+	}, []string{"from", "to"})
+
+	userStateTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "user_state_transitions_total",
+		Help: "Number of state changes of the user accounts, by previous and new state.",
+	}, []string{"from", "to"})
+
+	consumerCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "consumer_cache_requests_total",
+		Help: "Number of consumer lookups by ID answered by the consumer cache, by result (hit or miss).",
+	}, []string{"result"})
+
+	webhookAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_delivery_attempts_total",
+		Help: "Number of attempts of the webhook deliveries, by result (succeeded, failed and retried later, or dead-lettered).",
+	}, []string{"result"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of the database queries, by table and operation (create, query, update, delete, row, or raw).",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"table", "operation"})
+
+	dbQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Number of failed database queries, by table and operation. The lookups finding no record are not counted.",
+	}, []string{"table", "operation"})
+
+	dbReadRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_read_retries_total",
+		Help: "Number of retries of the repository reads failing with a transient database error, by operation.",
+	}, []string{"operation"})
+
+	dbCircuitState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_circuit_breaker_state",
+		Help: "State of the circuit breaker of the database: 1 for the current state (closed, open, or half_open), 0 for the others.",
+	}, []string{"state"})
+
+	dbBufferedWrites = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_buffered_writes_total",
+		Help: "Number of writes buffered after a transient database error, by result (buffered, then written or dropped).",
+	}, []string{"result"})
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests handled, by route, method, and status.",
+	}, []string{"route", "method", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of the HTTP requests, by route, method, and status.",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"route", "method", "status"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_logins_total",
+		Help: "Number of logins, by result (succeeded, failed, or mfa_required).",
+	}, []string{"result"})
+
+	tokenRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_token_refreshes_total",
+		Help: "Number of token refreshes, by result (succeeded or failed).",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		consumersCreated,
+		consumerStatusTransitions,
+		userStateTransitions,
+		consumerCacheRequests,
+		webhookAttempts,
+		dbQueryDuration,
+		dbQueryErrors,
+		dbReadRetries,
+		dbCircuitState,
+		dbBufferedWrites,
+		httpRequests,
+		httpRequestDuration,
+		logins,
+		tokenRefreshes,
+		newSLOCollector(),
+	)
+
+	// Export the cache, webhook, login, and refresh results from the start, so the dashboards do not show missing series as gaps
+	consumerCacheRequests.WithLabelValues(CacheResultHit)
+	consumerCacheRequests.WithLabelValues(CacheResultMiss)
+	webhookAttempts.WithLabelValues(WebhookResultSucceeded)
+	webhookAttempts.WithLabelValues(WebhookResultFailed)
+	webhookAttempts.WithLabelValues(WebhookResultDead)
+	logins.WithLabelValues(LoginResultSucceeded)
+	logins.WithLabelValues(LoginResultFailed)
+	logins.WithLabelValues(LoginResultMFARequired)
+	tokenRefreshes.WithLabelValues(RefreshResultSucceeded)
+	tokenRefreshes.WithLabelValues(RefreshResultFailed)
+}
+
+// Enabled reports whether the /metrics endpoint is exposed. It is enabled unless METRICS_ENABLED is FALSE.
+func Enabled() bool {
+	return !strings.EqualFold(env.Getenv("METRICS_ENABLED"), "FALSE")
+}
+
+// Handler returns the HTTP handler exporting the metrics of the registry.
+// The OpenMetrics format is served to the scrapers asking for it, the Prometheus text format otherwise.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
+
+// ConsumerCreated counts a created consumer.
+func ConsumerCreated() {
+	consumersCreated.Inc()
+}
+
+// ConsumerStatusChanged counts a status change of a consumer. Updates keeping the same status are not counted.
+func ConsumerStatusChanged(from, to string) {
+	if from == to {
+		return
+	}
+	consumerStatusTransitions.WithLabelValues(from, to).Inc()
+}
+
+// UserStateChanged counts a state change of a user account.
+func UserStateChanged(from, to string) {
+	if from == to {
+		return
+	}
+	userStateTransitions.WithLabelValues(from, to).Inc()
+}
+
+// ConsumerCacheLookup counts a consumer lookup by ID answered by the consumer cache (hit) or not (miss).
+func ConsumerCacheLookup(hit bool) {
+	if hit {
+		consumerCacheRequests.WithLabelValues(CacheResultHit).Inc()
+		return
+	}
+	consumerCacheRequests.WithLabelValues(CacheResultMiss).Inc()
+}
+
+// WebhookAttempted counts an attempt of a webhook delivery with the given result,
+// WebhookResultSucceeded, WebhookResultFailed, or WebhookResultDead.
+func WebhookAttempted(result string) {
+	webhookAttempts.WithLabelValues(result).Inc()
+}
+
+// DatabaseQuery records a database query on the given table with the given operation, its duration, and whether it failed.
+// The number of queries is the count of the duration histogram.
+func DatabaseQuery(table, operation string, duration time.Duration, failed bool) {
+	dbQueryDuration.WithLabelValues(table, operation).Observe(duration.Seconds())
+	if failed {
+		dbQueryErrors.WithLabelValues(table, operation).Inc()
+	}
+}
+
+// DatabaseReadRetry records a retry of the repository read with the given operation, e.g. GetUserByUsername,
+// after a transient database error.
+func DatabaseReadRetry(operation string) {
+	dbReadRetries.WithLabelValues(operation).Inc()
+}
+
+// circuitStates are the states of the circuit breaker of the database.
+var circuitStates = []string{"closed", "open", "half_open"}
+
+// DatabaseCircuitState records the current state of the circuit breaker of the database: closed, open, or half_open.
+func DatabaseCircuitState(state string) {
+	for _, s := range circuitStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		dbCircuitState.WithLabelValues(s).Set(value)
+	}
+}
+
+// DatabaseBufferedWrite records a write of the write buffer with the given result: buffered, written, or dropped.
+func DatabaseBufferedWrite(result string) {
+	dbBufferedWrites.WithLabelValues(result).Inc()
+}
+
+// RegisterDatabase exports the statistics of the connection pool of the database with the given name,
+// e.g. the open, in use, and idle connections, and the waits for a connection. A database already registered is kept.
+func RegisterDatabase(name string, db *sql.DB) error {
+	err := Registry.Register(collectors.NewDBStatsCollector(db, name))
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		return nil
+	}
+
+	return err
+}
+
+// HTTPRequest records an HTTP request handled on the given route, its method, status, and duration.
+// The route is the pattern of the route, e.g. /api/v1/consumers/:id, or UnmatchedRoute.
+func HTTPRequest(route, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(route, method, code).Inc()
+	httpRequestDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+	currentSLO().record(route, status, duration)
+}
+
+// Login counts a login with the given result, LoginResultSucceeded, LoginResultFailed, or LoginResultMFARequired.
+func Login(result string) {
+	logins.WithLabelValues(result).Inc()
+}
+
+// TokenRefresh counts a token refresh with the given result, RefreshResultSucceeded or RefreshResultFailed.
+func TokenRefresh(result string) {
+	tokenRefreshes.WithLabelValues(result).Inc()
+}
@@ -0,0 +1,367 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+)
+
+/**
+ * The SLO metrics export the burn rates of the availability and latency objectives of each route group,
+ * over the windows of the standard multi-window burn rate alerts, so that the alerts are written against
+ * slo_burn_rate directly, without recording rules, e.g. slo_burn_rate{window="1h"} > 14.4 and slo_burn_rate{window="5m"} > 14.4.
+ * The availability counts the requests answered with a 5xx status as bad, the latency the requests slower than
+ * the threshold of their group. A burn rate of 1 spends the error budget of the objective exactly over its period,
+ * 14.4 spends 2% of a 30-day budget in an hour. The requests are counted per minute, in memory, for up to 3 days:
+ * the windows are computed over the requests of the instance since it started, and reset when it restarts.
+ */
+
+// Route groups of the SLO metrics.
+const (
+	SLOGroupAuth      = "auth"
+	SLOGroupConsumers = "consumers"
+	SLOGroupUsers     = "users"
+	SLOGroupAdmin     = "admin"
+	SLOGroupOther     = "other"
+)
+
+// Objectives of the SLO metrics.
+const (
+	SLOAvailability = "availability"
+	SLOLatency      = "latency"
+)
+
+// Default objectives, when the environment variables are not set.
+const (
+	DefaultSLOAvailabilityTarget = 0.999
+	DefaultSLOLatencyTarget      = 0.99
+	DefaultSLOLatencyThreshold   = 300 * time.Millisecond
+)
+
+// SLOGroups are the route groups of the SLO metrics, in order.
+var SLOGroups = []string{SLOGroupAuth, SLOGroupConsumers, SLOGroupUsers, SLOGroupAdmin, SLOGroupOther}
+
+// DefaultSLOLatencyThresholds are the latency thresholds of the groups whose targets differ from the default one,
+// as set by the load tests: the logins verify a bcrypt hash, the consumer reads are served from the cache.
+var DefaultSLOLatencyThresholds = map[string]time.Duration{
+	SLOGroupAuth:      500 * time.Millisecond,
+	SLOGroupConsumers: 100 * time.Millisecond,
+}
+
+// sloWindow is a window of the burn rates, labeled with its name.
+type sloWindow struct {
+	name     string
+	duration time.Duration
+}
+
+// sloWindows are the windows of the burn rates: the long and short windows of the page and ticket alerts.
+var sloWindows = []sloWindow{
+	{"5m", 5 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"2h", 2 * time.Hour},
+	{"6h", 6 * time.Hour},
+	{"1d", 24 * time.Hour},
+	{"3d", 72 * time.Hour},
+}
+
+// sloBuckets is the number of minutes the requests are counted for, the longest window.
+const sloBuckets = 72 * 60
+
+// sloExcludedRoutes are the routes of the probes and the scrapes, which are not part of the objectives.
+var sloExcludedRoutes = map[string]bool{"/metrics": true, "/readyz": true, UnmatchedRoute: true}
+
+// SLOConfig holds the objectives of the route groups: the ratio of the requests to answer without a 5xx status,
+// and the ratio of the requests to answer within the latency threshold of their group.
+type SLOConfig struct {
+	AvailabilityTarget float64
+	LatencyTarget      float64
+	LatencyThreshold   time.Duration
+	LatencyThresholds  map[string]time.Duration
+}
+
+// LoadSLOConfig reads the objectives from the environment variables SLO_AVAILABILITY_TARGET, SLO_LATENCY_TARGET,
+// SLO_LATENCY_THRESHOLD_MS and SLO_LATENCY_THRESHOLDS_MS_BY_GROUP, e.g. auth=500,consumers=100. The invalid settings are
+// reported by Validate, the ones not set keep their defaults.
+func LoadSLOConfig() SLOConfig {
+	cfg := SLOConfig{
+		AvailabilityTarget: DefaultSLOAvailabilityTarget,
+		LatencyTarget:      DefaultSLOLatencyTarget,
+		LatencyThreshold:   DefaultSLOLatencyThreshold,
+		LatencyThresholds:  make(map[string]time.Duration, len(DefaultSLOLatencyThresholds)),
+	}
+	for group, threshold := range DefaultSLOLatencyThresholds {
+		cfg.LatencyThresholds[group] = threshold
+	}
+
+	if v := env.Getenv("SLO_AVAILABILITY_TARGET"); v != "" {
+		cfg.AvailabilityTarget = parseRatio(v)
+	}
+	if v := env.Getenv("SLO_LATENCY_TARGET"); v != "" {
+		cfg.LatencyTarget = parseRatio(v)
+	}
+	if v := env.Getenv("SLO_LATENCY_THRESHOLD_MS"); v != "" {
+		cfg.LatencyThreshold = -1
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.LatencyThreshold = time.Duration(n) * time.Millisecond
+		}
+	}
+
+	// The invalid thresholds are reported by the validation of the configuration
+	if thresholds, err := ParseSLOLatencyThresholds(env.Getenv("SLO_LATENCY_THRESHOLDS_MS_BY_GROUP")); err == nil {
+		for group, threshold := range thresholds {
+			cfg.LatencyThresholds[group] = threshold
+		}
+	}
+
+	return cfg
+}
+
+// parseRatio returns the ratio of the setting, -1 if it is not a number.
+func parseRatio(v string) float64 {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return -1
+	}
+	return f
+}
+
+// ParseSLOLatencyThresholds parses the latency thresholds of the route groups, in milliseconds, e.g. auth=500,consumers=100.
+func ParseSLOLatencyThresholds(v string) (map[string]time.Duration, error) {
+	thresholds := make(map[string]time.Duration)
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		group, ms, ok := strings.Cut(entry, "=")
+		group = strings.TrimSpace(group)
+		if !ok || !isSLOGroup(group) {
+			return nil, fmt.Errorf("invalid entry %q, expected <group>=<milliseconds> with a group among %s", entry, strings.Join(SLOGroups, ", "))
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(ms))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid threshold %q of group %s, expected a positive number of milliseconds", ms, group)
+		}
+		thresholds[group] = time.Duration(n) * time.Millisecond
+	}
+
+	return thresholds, nil
+}
+
+// Validate returns an error if a target is not a ratio between 0 and 1 excluded, or a threshold is not positive.
+func (c SLOConfig) Validate() error {
+	if c.AvailabilityTarget <= 0 || c.AvailabilityTarget >= 1 {
+		return fmt.Errorf("SLO_AVAILABILITY_TARGET must be between 0 and 1 excluded, e.g. 0.999")
+	}
+	if c.LatencyTarget <= 0 || c.LatencyTarget >= 1 {
+		return fmt.Errorf("SLO_LATENCY_TARGET must be between 0 and 1 excluded, e.g. 0.99")
+	}
+	if c.LatencyThreshold <= 0 {
+		return fmt.Errorf("SLO_LATENCY_THRESHOLD_MS must be a positive integer")
+	}
+
+	return nil
+}
+
+// Threshold returns the latency threshold of the given route group.
+func (c SLOConfig) Threshold(group string) time.Duration {
+	if threshold, ok := c.LatencyThresholds[group]; ok {
+		return threshold
+	}
+	return c.LatencyThreshold
+}
+
+// isSLOGroup reports whether the given name is a route group of the SLO metrics.
+func isSLOGroup(name string) bool {
+	for _, group := range SLOGroups {
+		if group == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SLOGroup returns the route group of the given route pattern, e.g. consumers for /api/v1/consumers/:id.
+func SLOGroup(route string) string {
+	switch {
+	case strings.HasPrefix(route, "/auth/"):
+		return SLOGroupAuth
+	case strings.HasPrefix(route, "/api/v1/consumers"):
+		return SLOGroupConsumers
+	case strings.HasPrefix(route, "/api/v1/users"), strings.HasPrefix(route, "/api/v1/roles"):
+		return SLOGroupUsers
+	case strings.HasPrefix(route, "/api/v1/admin"):
+		return SLOGroupAdmin
+	default:
+		return SLOGroupOther
+	}
+}
+
+// sloCounts are the requests of a minute: all of them, the ones answered with a 5xx status, and the slow ones.
+type sloCounts struct {
+	minute int64
+	total  int64
+	errors int64
+	slow   int64
+}
+
+// sloSeries holds the requests of a route group per minute, in a ring of the minutes of the longest window.
+type sloSeries struct {
+	mu      sync.Mutex
+	buckets [sloBuckets]sloCounts
+}
+
+// add counts a request of the given minute.
+func (s *sloSeries) add(minute int64, failed, slow bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := &s.buckets[minute%sloBuckets]
+	if b.minute != minute {
+		*b = sloCounts{minute: minute}
+	}
+	b.total++
+	if failed {
+		b.errors++
+	}
+	if slow {
+		b.slow++
+	}
+}
+
+// windows returns the requests of each window ending with the given minute, in the order of sloWindows.
+func (s *sloSeries) windows(now int64) []sloCounts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]sloCounts, len(sloWindows))
+	var sum sloCounts
+	next := 0
+	for age := int64(0); age < sloBuckets && age <= now && next < len(sloWindows); age++ {
+		if b := s.buckets[(now-age)%sloBuckets]; b.minute == now-age {
+			sum.total += b.total
+			sum.errors += b.errors
+			sum.slow += b.slow
+		}
+
+		// The window is complete once its minutes are summed
+		for next < len(sloWindows) && age+1 == int64(sloWindows[next].duration/time.Minute) {
+			result[next] = sum
+			next++
+		}
+	}
+
+	return result
+}
+
+// sloTracker counts the requests of the route groups against their objectives.
+type sloTracker struct {
+	cfg    SLOConfig
+	clock  clock.Clock
+	series map[string]*sloSeries
+}
+
+// newSLOTracker creates a tracker of the given objectives, reading the time of the requests from the given clock.
+func newSLOTracker(cfg SLOConfig, clk clock.Clock) *sloTracker {
+	t := &sloTracker{cfg: cfg, clock: clk, series: make(map[string]*sloSeries, len(SLOGroups))}
+	for _, group := range SLOGroups {
+		t.series[group] = &sloSeries{}
+	}
+	return t
+}
+
+// record counts a request of the given route, answered with the given status in the given duration.
+func (t *sloTracker) record(route string, status int, duration time.Duration) {
+	if sloExcludedRoutes[route] {
+		return
+	}
+
+	group := SLOGroup(route)
+	t.series[group].add(t.clock.Now().Unix()/60, status >= 500, duration > t.cfg.Threshold(group))
+}
+
+var (
+	sloMu      sync.RWMutex
+	sloCurrent = newSLOTracker(LoadSLOConfig(), clock.New())
+)
+
+// ConfigureSLO sets the objectives of the route groups and the clock timing the requests, and resets the counted requests.
+func ConfigureSLO(cfg SLOConfig, clk clock.Clock) {
+	sloMu.Lock()
+	defer sloMu.Unlock()
+	sloCurrent = newSLOTracker(cfg, clk)
+}
+
+// currentSLO returns the tracker of the objectives.
+func currentSLO() *sloTracker {
+	sloMu.RLock()
+	defer sloMu.RUnlock()
+	return sloCurrent
+}
+
+// sloCollector exports the objectives of the route groups and their burn rates over each window.
+type sloCollector struct {
+	burnRate  *prometheus.Desc
+	requests  *prometheus.Desc
+	objective *prometheus.Desc
+}
+
+// newSLOCollector creates the collector of the SLO metrics.
+func newSLOCollector() *sloCollector {
+	return &sloCollector{
+		burnRate: prometheus.NewDesc("slo_burn_rate",
+			"Rate at which the error budget of the objective of the route group is spent over the window, 1 spending it exactly over the period of the objective.",
+			[]string{"group", "slo", "window"}, nil),
+		requests: prometheus.NewDesc("slo_window_requests",
+			"Number of requests of the route group over the window, e.g. to ignore the burn rates of too few requests.",
+			[]string{"group", "window"}, nil),
+		objective: prometheus.NewDesc("slo_objective",
+			"Target ratio of the good requests of the objective of the route group.",
+			[]string{"group", "slo"}, nil),
+	}
+}
+
+// Describe sends the descriptors of the SLO metrics.
+func (c *sloCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.burnRate
+	ch <- c.requests
+	ch <- c.objective
+}
+
+// Collect sends the objectives, and the burn rates and the requests of each route group over each window.
+func (c *sloCollector) Collect(ch chan<- prometheus.Metric) {
+	t := currentSLO()
+	now := t.clock.Now().Unix() / 60
+
+	for _, group := range SLOGroups {
+		ch <- prometheus.MustNewConstMetric(c.objective, prometheus.GaugeValue, t.cfg.AvailabilityTarget, group, SLOAvailability)
+		ch <- prometheus.MustNewConstMetric(c.objective, prometheus.GaugeValue, t.cfg.LatencyTarget, group, SLOLatency)
+
+		for i, counts := range t.series[group].windows(now) {
+			window := sloWindows[i].name
+			ch <- prometheus.MustNewConstMetric(c.requests, prometheus.GaugeValue, float64(counts.total), group, window)
+			ch <- prometheus.MustNewConstMetric(c.burnRate, prometheus.GaugeValue,
+				burnRate(counts.errors, counts.total, t.cfg.AvailabilityTarget), group, SLOAvailability, window)
+			ch <- prometheus.MustNewConstMetric(c.burnRate, prometheus.GaugeValue,
+				burnRate(counts.slow, counts.total, t.cfg.LatencyTarget), group, SLOLatency, window)
+		}
+	}
+}
+
+// burnRate returns the ratio of the bad requests divided by the error budget of the target, 0 without requests.
+func burnRate(bad, total int64, target float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return (float64(bad) / float64(total)) / (1 - target)
+}
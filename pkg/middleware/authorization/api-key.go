@@ -0,0 +1,81 @@
+package authorization
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+/**
+* ApiKeyValidation is a middleware function that authenticates the requests of the service accounts with their API key,
+* sent in the X-API-Key header, for the non-interactive clients which cannot log in (e.g. the batch jobs).
+* The key is checked by the ApiKeyAuthenticator, which returns the user information of its service account.
+* Like JwtValidation, the user information is injected into the request context, so that the authorization middleware
+* (e.g. RoleBasedAccessControl and RequireScope) and the handlers serve both kinds of requests the same way.
+* If the key is missing, unknown, expired, or revoked, it returns an unauthorized error response.
+ */
+
+// ApiKeyHeader is the header carrying the API key of the request.
+const ApiKeyHeader = "X-API-Key"
+
+// ErrInvalidApiKey is returned by the ApiKeyAuthenticator when the key is unknown, expired, or revoked,
+// or when its service account cannot authenticate anymore.
+var ErrInvalidApiKey = errors.New("invalid, expired, or revoked API key")
+
+// Interface for API key authenticator
+// This interface defines the check of the API keys, returning the user information of their service account
+type ApiKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (metacontext.UserInformationMeta, error)
+}
+
+// ApiKeyValidation returns the middleware authenticating the requests with the API key of their X-API-Key header.
+func ApiKeyValidation(keys ApiKeyAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(ApiKeyHeader)
+		if key == "" {
+			httputil.Unauthorized(c, "No API key provided", fmt.Sprintf("%s header is missing", ApiKeyHeader))
+			c.Abort()
+			return
+		}
+
+		meta, err := keys.Authenticate(c.Request.Context(), key)
+		if errors.Is(err, ErrInvalidApiKey) {
+			recordTokenError(c)
+			httputil.Unauthorized(c, "Invalid API key", err.Error())
+			c.Abort()
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), fmt.Sprintf("Failed to authenticate the API key: %v", err), nil)
+			httputil.InternalServerError(c, "Failed to authenticate API key", "Unable to check the API key")
+			c.Abort()
+			return
+		}
+
+		// Inject user information into the request context
+		c.Request = c.Request.WithContext(metacontext.InjectUserInformationMeta(c.Request.Context(), meta))
+
+		c.Next()
+	}
+}
+
+// ApiKeyOrJwtValidation returns the middleware authenticating the requests with ApiKeyValidation when they carry
+// an X-API-Key header, and with the given JWT validation middleware otherwise.
+func ApiKeyOrJwtValidation(keys ApiKeyAuthenticator, jwtValidation gin.HandlerFunc) gin.HandlerFunc {
+	apiKeyValidation := ApiKeyValidation(keys)
+
+	return func(c *gin.Context) {
+		if c.GetHeader(ApiKeyHeader) != "" {
+			apiKeyValidation(c)
+			return
+		}
+
+		jwtValidation(c)
+	}
+}
@@ -1,14 +1,21 @@
 package authorization
 
 import (
+	"errors"
 	"fmt"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 
+	jwtconfig "github.com/yoanesber/go-jwt-auth-demo/config/jwt-config"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/anomaly"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
 	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/revocation"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/tracing"
 	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
 	jwtutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
 )
@@ -16,108 +23,193 @@ import (
 /**
 * JwtValidation is a middleware function that validates JWT tokens in the request header.
 * It checks if the token is present, has the correct format, and is valid.
+* The tokens must be signed with the signing method of the settings: HS256, RS256, ES256, or EdDSA.
+* The RS256 tokens are verified with the public key selected by their kid header, as published by /.well-known/jwks.json.
+* Tokens revoked before their expiration (see the revocation package), all at once or on their own by jti, are rejected,
+* including the ones revoked by the other instances when a blacklist is shared (see the cache package).
 * If the token is valid, it extracts user information from the token claims and injects it into the request context.
 * If the token is invalid or missing, it returns an unauthorized error response.
+* The validation is traced as the jwt.validate span of the request.
  */
-var (
-	TokenType string
-	JWTSecret string
-)
 
-// LoadEnv loads environment variables
-func LoadEnv() {
-	TokenType = os.Getenv("TOKEN_TYPE")
-	JWTSecret = os.Getenv("JWT_SECRET")
-}
+// errInvalidToken is the error of the span of a rejected token, the reason is in the response.
+var errInvalidToken = errors.New("missing, invalid, or revoked token")
 
+// JwtValidation returns the JWT validation middleware using the system clock
+// and the JWT settings read from the environment variables.
 func JwtValidation() gin.HandlerFunc {
-	// Load environment variables
-	LoadEnv()
+	return JwtValidationWithConfig(jwtconfig.Load(), clock.New())
+}
+
+// JwtValidationWithClock returns the JWT validation middleware using the given clock
+// to validate the time based claims (exp, iat, nbf) of the token,
+// and the JWT settings read from the environment variables.
+func JwtValidationWithClock(clk clock.Clock) gin.HandlerFunc {
+	return JwtValidationWithConfig(jwtconfig.Load(), clk)
+}
 
+// JwtValidationWithConfig returns the JWT validation middleware using the given JWT settings and clock.
+func JwtValidationWithConfig(cfg jwtconfig.JWTConfig, clk clock.Clock) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get the token from the request header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			httputil.Unauthorized(c, "No token provided", "Authorization header is missing")
-			c.Abort()
+		// The validation has its own span in the trace of the request, the handlers are not part of it
+		_, span := tracing.Start(c.Request.Context(), "jwt.validate")
+		validateJWT(c, cfg, clk)
+		if c.IsAborted() {
+			tracing.End(span, errInvalidToken)
 			return
 		}
+		tracing.End(span, nil)
 
-		// Check if the token starts with TokenType
-		tokenPrefix := TokenType + " "
-		if !strings.HasPrefix(authHeader, tokenPrefix) {
-			httputil.Unauthorized(c, "Invalid token format", fmt.Sprintf("Token must start with '%s'", tokenPrefix))
-			c.Abort()
-			return
-		}
+		c.Next()
+	}
+}
 
-		// Extract the token string
-		tokenStr := strings.TrimPrefix(authHeader, tokenPrefix)
-		if tokenStr == "" {
-			httputil.Unauthorized(c, "Invalid token format", "Token string is empty")
-			c.Abort()
-			return
+// validateJWT validates the token of the request and injects the information of its user into the request context.
+// The request is aborted with 401 if the token is missing, invalid, or revoked.
+func validateJWT(c *gin.Context, cfg jwtconfig.JWTConfig, clk clock.Clock) {
+	// Get the token from the request header
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		httputil.Unauthorized(c, "No token provided", "Authorization header is missing")
+		c.Abort()
+		return
+	}
+
+	// Check if the token starts with TokenType
+	tokenPrefix := cfg.TokenType + " "
+	if !strings.HasPrefix(authHeader, tokenPrefix) {
+		httputil.Unauthorized(c, "Invalid token format", fmt.Sprintf("Token must start with '%s'", tokenPrefix))
+		c.Abort()
+		return
+	}
+
+	// Extract the token string
+	tokenStr := strings.TrimPrefix(authHeader, tokenPrefix)
+	if tokenStr == "" {
+		httputil.Unauthorized(c, "Invalid token format", "Token string is empty")
+		c.Abort()
+		return
+	}
+
+	// Parse the token and validate it
+	// Only the tokens signed with the signing method of the settings are accepted, so that a token cannot be verified
+	// with a key of another kind, e.g. an HS256 token signed with the published public key
+	// The tokens minted for another audience or by another issuer (JWT_AUDIENCE and JWT_ISSUER) are rejected as well
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		// For HS256 signing method, return the secret key for validation
+		case *jwt.SigningMethodHMAC:
+			return []byte(cfg.Secret), nil
+
+		// For RS256 signing method
+		// Load the public key selected by the kid header of the token, among the keys of the key set
+		// still verifying valid tokens. The tokens issued without a kid are verified with the current public key
+		case *jwt.SigningMethodRSA:
+			return cfg.Keys.LoadPublicKeyByID(jwtutil.TokenKeyID(token), cfg.TTL.Max(), clk.Now())
+
+		// For ES256 signing method, return the ECDSA P-256 public key for validation
+		case *jwt.SigningMethodECDSA:
+			return cfg.Keys.LoadECPublicKey()
+
+		// For EdDSA signing method, return the Ed25519 public key for validation
+		case *jwt.SigningMethodEd25519:
+			return cfg.Keys.LoadEdPublicKey()
 		}
 
-		// Parse the token and validate it
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			// For HS256 signing method
-			if token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
-				// Validate the token signing method
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-				}
-
-				// Return the secret key for validation
-				return []byte(JWTSecret), nil
-			}
-
-			// For RS256 signing method
-			// Load the public key from the environment variable
-			publicKey, err := jwtutil.LoadPublicKey()
-			if err != nil {
-				return nil, err
-			}
-
-			// Validate the token signing method
-			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-
-			// Return the public key for validation
-			return publicKey, nil
-		})
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}, append(cfg.AudienceIssuerOptions(), jwt.WithValidMethods([]string{cfg.SigningMethod}), jwt.WithTimeFunc(func() time.Time { return clk.Now() }))...)
 
+	if err != nil {
+		recordTokenError(c)
+		httputil.Unauthorized(c, "Invalid token", err.Error())
+		c.Abort()
+		return
+	}
+
+	// Check if the token is valid
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		recordTokenError(c)
+		httputil.Unauthorized(c, "Invalid token", "Token is not valid")
+		c.Abort()
+		return
+	}
+
+	// Get the username from the claims
+	// A token without a username cannot be bound to a user, so it is rejected
+	username, err := jwtutil.GetStringClaim(claims, "username")
+	if err != nil || username == "" {
+		recordTokenError(c)
+		httputil.Unauthorized(c, "Invalid token", "Token is missing the username claim")
+		c.Abort()
+		return
+	}
+
+	// Get the user ID and email from the claims
+	// Convert the user ID to int64
+	userID, _ := jwtutil.GetInt64Claim(claims, "userid")
+	email, _ := jwtutil.GetStringClaim(claims, "email")
+	clientID, _ := jwtutil.GetStringClaim(claims, "client")
+
+	// Reject the token if the tokens of the user were revoked after it was issued
+	// Tokens without a version were issued before the first revocation of the user
+	tokenVersion, _ := jwtutil.GetInt64Claim(claims, "tokenversion")
+	if revocation.GetDenylist().IsRevoked(userID, tokenVersion) {
+		httputil.Unauthorized(c, "Invalid token", "Token has been revoked")
+		c.Abort()
+		return
+	}
+
+	// Reject the token if it was revoked on its own, e.g. on logout
+	// Tokens without an identifier were issued before the logout and cannot be revoked on their own
+	tokenID, _ := jwtutil.GetStringClaim(claims, "jti")
+	if tokenID != "" && revocation.GetDenylist().IsTokenRevoked(tokenID) {
+		httputil.Unauthorized(c, "Invalid token", "Token has been revoked")
+		c.Abort()
+		return
+	}
+
+	// Reject the token if it was revoked by another instance, shared with the blacklist (e.g. in Redis)
+	// When the blacklist cannot be reached, the token is accepted with the checks of the denylist of the process only
+	if blacklist := revocation.GetBlacklist(); blacklist != nil {
+		revoked, err := blacklist.IsRevoked(c.Request.Context(), userID, tokenVersion, tokenID)
 		if err != nil {
-			httputil.Unauthorized(c, "Invalid token", err.Error())
-			c.Abort()
-			return
+			logger.WarnContext(c.Request.Context(), fmt.Sprintf("Failed to check the token blacklist, using the denylist of the process: %v", err), nil)
 		}
-
-		// Check if the token is valid
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || !token.Valid {
-			httputil.Unauthorized(c, "Invalid token", "Token is not valid")
+		if revoked {
+			httputil.Unauthorized(c, "Invalid token", "Token has been revoked")
 			c.Abort()
 			return
 		}
+	}
 
-		// Get the user ID from the claims
-		// Convert the user ID to int64
-		userID, _ := jwtutil.GetInt64Claim(claims, "userid")
+	var tokenExpiresAt time.Time
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		tokenExpiresAt = exp.Time
+	}
 
-		// Inject user information into the request context
-		meta := metacontext.UserInformationMeta{
-			UserID:   userID,
-			Username: claims["username"].(string),
-			Email:    claims["email"].(string),
-			Roles:    jwtutil.GetStringSliceClaim(claims, "roles"),
-		}
-		ctx := metacontext.InjectUserInformationMeta(c.Request.Context(), meta)
+	// Inject user information into the request context
+	meta := metacontext.UserInformationMeta{
+		UserID:   userID,
+		Username: username,
+		Email:    email,
+		Roles:    jwtutil.GetStringSliceClaim(claims, "roles"),
+		Scopes:   jwtutil.GetStringSliceClaim(claims, "scopes"),
+		ClientID: clientID,
+
+		TokenID:        tokenID,
+		TokenExpiresAt: tokenExpiresAt,
+	}
+	ctx := metacontext.InjectUserInformationMeta(c.Request.Context(), meta)
 
-		// Set the new request context with user information
-		c.Request = c.Request.WithContext(ctx)
+	// Set the new request context with user information
+	c.Request = c.Request.WithContext(ctx)
+}
 
-		c.Next()
-	}
+// recordTokenError reports an invalid token to the anomaly detection subsystem.
+func recordTokenError(c *gin.Context) {
+	anomaly.GetDetector().Record(anomaly.Event{
+		Type: anomaly.EventTokenError,
+		IP:   c.ClientIP(),
+	})
 }
@@ -0,0 +1,59 @@
+package authorization
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+/**
+* RequireScope is a middleware function that checks if the access token carries the required scopes to access a resource.
+* The scopes are the permissions granted to the roles of the user (e.g. "consumers:write"), set in the scopes claim when the token is issued,
+* so it is a finer-grained alternative to RoleBasedAccessControl: the access of a role can be changed without changing the routes.
+* Unlike RoleBasedAccessControl, the token must carry all the required scopes, not only one of them.
+* If the token is missing any of the required scopes, it returns a forbidden response and aborts the request.
+ */
+func RequireScope(requiredScopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// If no required scopes are provided, allow access
+		if len(requiredScopes) == 0 {
+			c.Next()
+			return
+		}
+
+		// Extract user metadata from the context
+		meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+		if !ok {
+			httputil.InternalServerError(c, "Failed to extract metadata", "Unable to extract user metadata from context")
+			c.Abort()
+			return
+		}
+
+		// Get the scopes of the token from the metadata
+		// The tokens issued before the permissions were granted, or without them, do not have any scopes
+		if len(meta.Scopes) == 0 {
+			httputil.Forbidden(c, "No scopes found", "Token does not have any scopes")
+			c.Abort()
+			return
+		}
+
+		granted := make(map[string]bool, len(meta.Scopes))
+		for _, scope := range meta.Scopes {
+			granted[scope] = true
+		}
+
+		// Check if the token has all the required scopes
+		for _, scope := range requiredScopes {
+			if !granted[scope] {
+				httputil.Forbidden(c, "Access denied", fmt.Sprintf("Token does not have the required scope %s", scope))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package authorization
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/signedurl"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+/**
+* SignedURL is a middleware function that authenticates the downloads with a signed URL instead of an access token,
+* so that a browser or a download manager can fetch them without the Authorization header.
+* It checks the signature of the path and of the query of the request, and that the URL has not expired.
+* If the URL is valid, it injects the information of the user it was signed for into the request context,
+* so that the role checks and the handlers see the same user as with the access token the URL was issued with.
+* If the URL is not signed, was altered, or has expired, it returns an unauthorized error response.
+ */
+func SignedURL(signer *signedurl.Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		meta, err := signer.VerifyUser(c.Request.URL.Path, c.Request.URL.Query())
+		if err != nil {
+			detail := "The signature of the URL is invalid"
+			switch {
+			case errors.Is(err, signedurl.ErrMissingSignature):
+				detail = "The URL is not signed"
+			case errors.Is(err, signedurl.ErrExpired):
+				detail = "The URL has expired, please request a new one"
+			}
+
+			httputil.Unauthorized(c, "Invalid signed URL", detail)
+			c.Abort()
+			return
+		}
+
+		ctx := metacontext.InjectUserInformationMeta(c.Request.Context(), meta)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package authorization
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+/**
+* WebSocketToken is a middleware function that lets the browsers authenticate the WebSocket connections,
+* since they cannot set the Authorization header of the upgrade request. The access token is taken from:
+*   - the Sec-WebSocket-Protocol header, as the subprotocol following WebSocketTokenProtocol,
+*     e.g. new WebSocket(url, ["bearer", accessToken]);
+*   - or else the WebSocketTokenCookie cookie.
+* It sets the Authorization header of the request with the token, so that it is then validated by JwtValidation.
+* A request already carrying an Authorization header is left unchanged.
+ */
+
+const (
+	// WebSocketTokenProtocol is the subprotocol followed by the access token in the Sec-WebSocket-Protocol header.
+	// It is the subprotocol selected by the server.
+	WebSocketTokenProtocol = "bearer"
+
+	// WebSocketTokenCookie is the cookie the access token is read from when it is not sent as a subprotocol.
+	WebSocketTokenCookie = "access_token"
+)
+
+// WebSocketToken returns the middleware setting the Authorization header of the WebSocket upgrade requests
+// with the access token of the subprotocols or of the cookie, prefixed with the given token type (e.g. "Bearer").
+func WebSocketToken(tokenType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := webSocketToken(c.Request); token != "" {
+				c.Request.Header.Set("Authorization", tokenType+" "+token)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// webSocketToken returns the access token of the subprotocols, or else of the cookie, or an empty string if there is none.
+func webSocketToken(r *http.Request) string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			protocols = append(protocols, strings.TrimSpace(protocol))
+		}
+	}
+	for i := 0; i+1 < len(protocols); i++ {
+		if protocols[i] == WebSocketTokenProtocol {
+			return protocols[i+1]
+		}
+	}
+
+	if cookie, err := r.Cookie(WebSocketTokenCookie); err == nil {
+		return cookie.Value
+	}
+
+	return ""
+}
@@ -12,18 +12,34 @@ import (
 /**
  * ContentType is a middleware function that checks the Content-Type header of incoming requests.
  * It ensures that the Content-Type is set to `application/json` for POST, PUT, and PATCH requests.
+ * The given routes accept `application/x-www-form-urlencoded` instead, e.g. the OAuth2 token endpoint,
+ * whose requests are form-encoded by the OAuth2 libraries.
  * If the Content-Type is not set correctly, it returns a 415 Unsupported Media Type error and aborts the request.
  * This middleware is useful for enforcing the expected content type for API requests.
  */
 
-func ContentType() gin.HandlerFunc {
+// FormContentType is the content type of the form-encoded request bodies.
+const FormContentType = "application/x-www-form-urlencoded"
+
+func ContentType(formRoutes ...string) gin.HandlerFunc {
+	forms := make(map[string]bool, len(formRoutes))
+	for _, route := range formRoutes {
+		forms[route] = true
+	}
+
 	return func(c *gin.Context) {
 		method := c.Request.Method
 		contentType := c.GetHeader("Content-Type")
 
 		// Only enforce for methods that require a body
 		if method == http.MethodPost || method == http.MethodPut {
-			if !strings.HasPrefix(contentType, "application/json") {
+			if forms[c.FullPath()] {
+				if !strings.HasPrefix(contentType, FormContentType) {
+					httputil.UnsupportedMediaType(c, "Unsupported Media Type", "Content-Type must be `"+FormContentType+"`")
+					c.Abort()
+					return
+				}
+			} else if !strings.HasPrefix(contentType, "application/json") {
 				httputil.UnsupportedMediaType(c, "Unsupported Media Type", "Content-Type must be `application/json`")
 				c.Abort()
 				return
@@ -2,12 +2,13 @@ package headers
 
 import (
 	"net/url"
-	"os"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
 	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
 )
 
@@ -18,16 +19,47 @@ import (
 * when they are hosted on different origins (domains, protocols, or ports).
  */
 
-func CorsHeaders() gin.HandlerFunc {
-	env := os.Getenv("NODE_ENV")
+// defaultAllowedHeaders are the request headers the frontends are always allowed to send.
+const defaultAllowedHeaders = "X-Requested-With, Content-Type, Origin, Authorization, Accept, Client-Security-Token, Accept-Encoding, x-access-token, X-Captcha-Token"
+
+// CorsConfig holds the origins of the frontends allowed to send cross-origin requests.
+// It is passed by value and never modified after it is loaded.
+type CorsConfig struct {
+	AllowedOrigins []string
+}
 
-	var allowedOrigins []string
-	if env == "production" {
-		allowedOrigins = strings.Split(os.Getenv("FRONTEND_URL_PRODUCTION"), ",")
-	} else {
-		allowedOrigins = strings.Split(os.Getenv("FRONTEND_URL"), ",")
+// LoadCorsConfig reads the allowed origins from the comma separated FRONTEND_URL_PRODUCTION when NODE_ENV is production,
+// and from FRONTEND_URL otherwise.
+func LoadCorsConfig() CorsConfig {
+	key := "FRONTEND_URL"
+	if env.Getenv("NODE_ENV") == "production" {
+		key = "FRONTEND_URL_PRODUCTION"
 	}
 
+	var cfg CorsConfig
+	for _, origin := range strings.Split(env.Getenv(key), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.AllowedOrigins = append(cfg.AllowedOrigins, origin)
+		}
+	}
+
+	return cfg
+}
+
+func CorsHeaders() gin.HandlerFunc {
+	return CorsHeadersWithOverrides(nil)
+}
+
+// CorsHeadersWithOverrides is CorsHeaders with the origins and the allowed headers of the overrides
+// added to the ones of the environment, so that the administrators can allow a new frontend at runtime.
+func CorsHeadersWithOverrides(overrides *OverridesCache) gin.HandlerFunc {
+	return CorsHeadersWithConfig(LoadCorsConfig(), overrides)
+}
+
+// CorsHeadersWithConfig is CorsHeadersWithOverrides with the allowed origins of the given settings instead of the ones of the environment.
+func CorsHeadersWithConfig(cfg CorsConfig, overrides *OverridesCache) gin.HandlerFunc {
+	allowedOrigins := cfg.AllowedOrigins
+
 	// Set CORS headers for allowed origins
 	return func(c *gin.Context) {
 		origin := c.Request.Header.Get("Origin")
@@ -51,16 +83,17 @@ func CorsHeaders() gin.HandlerFunc {
 			return
 		}
 
-		// Check if the origin is in the allowed origins list
+		// Check if the origin is in the allowed origins list, the ones of the environment and the overridden ones
 		// If the origin is allowed, set CORS headers
-		for _, allowed := range allowedOrigins {
+		current := overrides.Get()
+		for _, allowed := range slices.Concat(allowedOrigins, current.AllowedOrigins) {
 			allowedOriginTrimmed := strings.TrimSpace(allowed)
 			if origin == allowedOriginTrimmed || allowedOriginTrimmed == "*" {
 				maxAge := 24 * time.Hour
 				c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
 				c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-				c.Writer.Header().Set("Access-Control-Allow-Headers", "X-Requested-With, Content-Type, Origin, Authorization, Accept, Client-Security-Token, Accept-Encoding, x-access-token")
-				c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length")
+				c.Writer.Header().Set("Access-Control-Allow-Headers", strings.Join(append([]string{defaultAllowedHeaders}, current.AllowedHeaders...), ", "))
+				c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length, "+RequestIDHeader)
 				c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 				c.Writer.Header().Set("Access-Control-Max-Age", maxAge.String())
 
@@ -0,0 +1,155 @@
+package headers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+/**
+ * The CORS and security headers can be changed at runtime by the administrators, so that adding the origin
+ * of a new frontend does not require a redeploy. The overrides are stored in the database and cached by every
+ * instance: an instance reloads them once they are older than the TTL, and at once after a change it made itself.
+ * The origins and the allowed headers of the overrides extend the ones of the environment, they never remove them.
+ */
+
+// OverridableSecurityHeaders lists the security headers the overrides can replace or remove.
+// The headers protecting the transport (HSTS) and the content type sniffing cannot be overridden.
+var OverridableSecurityHeaders = []string{
+	"Content-Security-Policy",
+	"Referrer-Policy",
+	"Permissions-Policy",
+	"Cross-Origin-Opener-Policy",
+	"Cross-Origin-Embedder-Policy",
+	"Cross-Origin-Resource-Policy",
+	"X-Frame-Options",
+	"X-Dns-Prefetch-Control",
+	"X-Permitted-Cross-Domain-Policies",
+}
+
+// IsOverridableSecurityHeader reports whether the header is one of OverridableSecurityHeaders, the name being case-insensitive.
+func IsOverridableSecurityHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	for _, h := range OverridableSecurityHeaders {
+		if h == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateOrigin checks that the origin is an HTTP or HTTPS origin, a scheme and a host with an optional port,
+// the form the browsers send in the Origin header.
+func ValidateOrigin(origin string) error {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid URL", origin)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%s must use HTTP or HTTPS scheme", origin)
+	}
+	if u.Host == "" || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%s must be a scheme and a host, without path", origin)
+	}
+	return nil
+}
+
+// Overrides holds the CORS and security headers changed at runtime.
+// An empty value of a security header removes the header from the responses.
+type Overrides struct {
+	AllowedOrigins  []string
+	AllowedHeaders  []string
+	SecurityHeaders map[string]string
+}
+
+// OverridesLoader loads the current overrides, e.g. from the database.
+type OverridesLoader func() (Overrides, error)
+
+// OverridesCache is a thread-safe TTL cache of the overrides.
+// It serves no overrides until it is loaded with Load, so that the requests served before the database
+// is initialized never query it. A nil *OverridesCache is valid and serves no overrides.
+type OverridesCache struct {
+	mu       sync.Mutex
+	load     OverridesLoader
+	ttl      time.Duration
+	clock    clock.Clock
+	current  Overrides
+	loaded   bool
+	stale    bool
+	loadedAt time.Time
+}
+
+// NewOverridesCache creates a new instance of OverridesCache loading the overrides with the given loader.
+// The overrides are reloaded once they are older than the TTL; with a TTL that is not positive,
+// they are only reloaded after Invalidate.
+func NewOverridesCache(load OverridesLoader, ttl time.Duration, clk clock.Clock) *OverridesCache {
+	return &OverridesCache{load: load, ttl: ttl, clock: clk}
+}
+
+// Load loads the overrides at once, and returns the error of the loader.
+func (c *OverridesCache) Load() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.reload()
+}
+
+// Get returns the current overrides, reloading them if they are stale.
+// If the reload fails, the previous overrides are kept and retried after the TTL.
+func (c *OverridesCache) Get() Overrides {
+	if c == nil {
+		return Overrides{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.loaded {
+		return Overrides{}
+	}
+	if c.stale || (c.ttl > 0 && !c.clock.Now().Before(c.loadedAt.Add(c.ttl))) {
+		if err := c.reload(); err != nil {
+			logger.Warn("Failed to reload the CORS and security header overrides, keeping the previous ones", logrus.Fields{"error": err.Error()})
+		}
+	}
+
+	return c.current
+}
+
+// Invalidate marks the overrides stale, so that the next request reloads them.
+func (c *OverridesCache) Invalidate() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stale = true
+}
+
+// reload loads the overrides with the loader. The caller must hold the lock.
+func (c *OverridesCache) reload() error {
+	c.loadedAt = c.clock.Now()
+	c.stale = false
+
+	overrides, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	c.current = overrides
+	c.loaded = true
+	return nil
+}
@@ -0,0 +1,55 @@
+package headers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+)
+
+/**
+ * RequestID is a middleware function that gives each request an ID, to correlate its logs, its response, and the reports of the clients.
+ * It keeps the ID sent by the caller in the X-Request-Id header, e.g. by a gateway or another service, so that the ID follows
+ * the request across the services, and generates a new one if the caller sent none or an invalid one.
+ * The ID is stored in the request context, where the logger and the error responses read it, and returned in the X-Request-Id header.
+ * It should run before the other middleware, so that the requests they reject carry an ID as well.
+ */
+
+// RequestIDHeader is the header carrying the ID of the request, on the request and on the response.
+const RequestIDHeader = "X-Request-Id"
+
+// MaxRequestIDLength is the maximum length of the request IDs accepted from the callers.
+const MaxRequestIDLength = 128
+
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Request = c.Request.WithContext(metacontext.InjectRequestID(c.Request.Context(), requestID))
+		c.Writer.Header().Set(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// validRequestID reports whether the request ID sent by the caller can be kept: not empty, not longer than MaxRequestIDLength,
+// and made of letters, digits, and the characters - _ . : only, so that it cannot forge the log lines or the headers.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > MaxRequestIDLength {
+		return false
+	}
+
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
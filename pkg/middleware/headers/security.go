@@ -1,11 +1,10 @@
 package headers
 
 import (
-	"os"
-
 	"github.com/gin-gonic/gin"
 	"github.com/unrolled/secure"
 
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
 	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
 )
 
@@ -17,7 +16,13 @@ import (
  */
 
 func SecurityHeaders() gin.HandlerFunc {
-	isSSLRedirect := os.Getenv("IS_SSL") == "TRUE"
+	return SecurityHeadersWithOverrides(nil)
+}
+
+// SecurityHeadersWithOverrides is SecurityHeaders with the security headers of the overrides replacing the default ones,
+// an empty value removing the header. Only the OverridableSecurityHeaders can be overridden.
+func SecurityHeadersWithOverrides(overrides *OverridesCache) gin.HandlerFunc {
+	isSSLRedirect := env.Getenv("IS_SSL") == "TRUE"
 
 	secureMiddleware := secure.New(secure.Options{
 		// Protects against reflected XSS attacks in older browsers
@@ -94,6 +99,17 @@ func SecurityHeaders() gin.HandlerFunc {
 			return
 		}
 
+		for name, value := range overrides.Get().SecurityHeaders {
+			if !IsOverridableSecurityHeader(name) {
+				continue
+			}
+			if value == "" {
+				c.Writer.Header().Del(name)
+			} else {
+				c.Writer.Header().Set(name, value)
+			}
+		}
+
 		c.Next()
 	}
 }
@@ -49,7 +49,7 @@ func RequestLogger() gin.HandlerFunc {
 			"path":           c.Request.URL.Path,
 			"query":          c.Request.URL.Query(),
 			"referer":        c.Request.Referer(),
-			"request_id":     c.Writer.Header().Get("X-Request-Id"),
+			"request_id":     metacontext.ExtractRequestID(c.Request.Context()),
 			"status":         c.Writer.Status(),
 			"user_agent":     c.Request.UserAgent(),
 			"username":       meta.Username,
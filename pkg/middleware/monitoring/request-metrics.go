@@ -0,0 +1,31 @@
+package monitoring
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+)
+
+/**
+* RequestMetrics is a middleware function that records the HTTP requests on /metrics.
+* Each request is counted and timed once it is processed, labeled by the pattern of its route, e.g. /api/v1/consumers/:id,
+* so the IDs in the paths do not create a series per request, its method, and its status.
+* The requests matching no route are labeled with metrics.UnmatchedRoute.
+ */
+func RequestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		// Process the request first, the route and the status are only known once it is handled
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = metrics.UnmatchedRoute
+		}
+
+		metrics.HTTPRequest(route, c.Request.Method, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,26 @@
+package request_filter
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/anomaly"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+/**
+ * BlockSuspiciousSources is a middleware function that rejects requests coming from sources
+ * temporarily blocked by the anomaly detection subsystem.
+ * If the client IP is blocked, it returns a 429 Too Many Requests response and aborts the request.
+ * Account-level blocks are enforced by the handlers that know the account (e.g. login).
+ */
+func BlockSuspiciousSources() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if anomaly.GetDetector().IsBlocked(c.ClientIP(), "") {
+			httputil.TooManyRequests(c, "Request blocked", "Too many suspicious requests from this source, please try again later")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package request_filter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/ratelimit"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// maxSubjectBodySize is the size of the request bodies read to find their subject, the larger bodies have no subject.
+const maxSubjectBodySize = 64 << 10
+
+// SubjectFunc returns the subject of a request limited by AuthRateLimit, or an empty string if it has none.
+type SubjectFunc func(c *gin.Context) string
+
+/**
+ * AuthRateLimit is a middleware function that limits the requests to an authentication route with the token buckets of the limiter:
+ * one per client IP, and one per subject of the request returned by the given function, e.g. the username of a login.
+ * It runs before the body is bound by the handler, the body read to find the subject is given back to the handler.
+ * If a bucket is empty, it returns a 429 Too Many Requests response with a Retry-After header and aborts the request.
+ */
+func AuthRateLimit(limiter *ratelimit.AuthLimiter, subject SubjectFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ok, retryAfter := limiter.Allow(c.Request.Context(), c.ClientIP(), subject(c)); !ok {
+			c.Header("Retry-After", strconv.Itoa(max(int(math.Ceil(retryAfter.Seconds())), 1)))
+			httputil.TooManyRequests(c, "Too many requests", "Too many authentication attempts, please try again later")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// LoginSubject returns the username of the login request, so that the logins of an account are limited whatever their client IP.
+func LoginSubject(c *gin.Context) string {
+	var body struct {
+		Username string `json:"username"`
+	}
+	if !peekJSON(c, &body) {
+		return ""
+	}
+
+	return body.Username
+}
+
+// RefreshTokenSubject returns the hash of the refresh token of the request, so that the refreshes of a token are limited
+// whatever their client IP. The refresh requests carry no username, the user of a refresh token is only known once it is looked up.
+func RefreshTokenSubject(c *gin.Context) string {
+	var body struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if !peekJSON(c, &body) || body.RefreshToken == "" {
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(body.RefreshToken))
+	return "refresh-token:" + hex.EncodeToString(hash[:16])
+}
+
+// peekJSON decodes the JSON body of the request into v, and gives the body back to the request for the handler.
+// It reports whether the body was decoded; a body larger than maxSubjectBodySize is not decoded.
+func peekJSON(c *gin.Context, v any) bool {
+	if c.Request.Body == nil {
+		return false
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxSubjectBodySize+1))
+	c.Request.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(data), c.Request.Body), Closer: c.Request.Body}
+	if err != nil || len(data) > maxSubjectBodySize {
+		return false
+	}
+
+	return json.Unmarshal(data, v) == nil
+}
+
+// readCloser reads the body given back to the request, and closes the original body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,55 @@
+package request_filter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/basepath"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/chaos"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+/**
+ * InjectFaults is a middleware function that injects the faults set by the X-Chaos-* headers of a request, see the chaos package.
+ * It delays the request, or rejects it with the status of X-Chaos-Status, before the handler runs, and passes the fault
+ * of the calls to the database to the chaos repositories with the context of the request.
+ * Invalid headers are rejected with a 400 Bad Request response. It must only be used when the injection is enabled.
+ */
+func InjectFaults() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		faults, err := chaos.ParseHeaders(c.Request.Header)
+		if err != nil {
+			httputil.BadRequest(c, "Invalid chaos headers", err.Error())
+			c.Abort()
+			return
+		}
+
+		if faults.Delay > 0 {
+			if err := chaos.Sleep(c.Request.Context(), faults.Delay); err != nil {
+				c.Abort()
+				return
+			}
+		}
+
+		if faults.Status != 0 {
+			c.AbortWithStatusJSON(faults.Status, httputil.HttpResponse{
+				Message:   "Injected fault",
+				Error:     fmt.Sprintf("The request was rejected by the %s header", chaos.HeaderStatus),
+				Path:      basepath.Join(c.Request.URL.Path),
+				Status:    faults.Status,
+				Timestamp: time.Now(),
+				RequestID: metacontext.ExtractRequestID(c.Request.Context()),
+			})
+			return
+		}
+
+		if faults.DB != nil {
+			c.Request = c.Request.WithContext(chaos.WithFault(c.Request.Context(), faults.DB))
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,33 @@
+package request_filter
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/featureflag"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+/**
+ * RequireFeature is a middleware function that rejects the requests of a disabled feature, see the featureflag package.
+ * It returns a 503 Service Unavailable response with the FEATURE_DISABLED error code, the feature, and the reason
+ * given by the administrator if any, and aborts the request. The other routes keep being served.
+ */
+func RequireFeature(flags *featureflag.Cache, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		disabled, reason := flags.Disabled(name)
+		if !disabled {
+			c.Next()
+			return
+		}
+
+		if reason == "" {
+			reason = "The feature is temporarily disabled, please try again later"
+		}
+		httputil.ServiceUnavailableMap(c, "Feature disabled", []map[string]string{{
+			"code":    featureflag.DisabledCode,
+			"feature": name,
+			"message": reason,
+		}})
+		c.Abort()
+	}
+}
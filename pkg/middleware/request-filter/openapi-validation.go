@@ -0,0 +1,155 @@
+package request_filter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+/**
+ * ValidateRequests is a middleware function that validates the requests against the OpenAPI document before the handlers run.
+ * The path and query parameters and the request body of the documented operations are checked against their schemas,
+ * and the request bodies must not hold properties that are not documented (unless their schema sets additionalProperties).
+ * The invalid requests get a 400 Bad Request response listing the field and the reason of each error,
+ * like the validation errors of the handlers. The routes missing from the document are passed as is.
+ * The security requirements are not checked, the authentication is left to the JWT validation middleware.
+ */
+func ValidateRequests(spec []byte) (gin.HandlerFunc, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the OpenAPI document: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("the OpenAPI document is invalid: %w", err)
+	}
+
+	// Reject the unknown fields of the request bodies, which would otherwise be silently ignored by the JSON binding
+	visited := make(map[*openapi3.Schema]bool)
+	for _, item := range doc.Paths.Map() {
+		for _, op := range item.Operations() {
+			if op.RequestBody == nil || op.RequestBody.Value == nil {
+				continue
+			}
+			for _, mediaType := range op.RequestBody.Value.Content {
+				disallowUnknownProperties(mediaType.Schema, visited)
+			}
+		}
+	}
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the router of the OpenAPI document: %w", err)
+	}
+
+	options := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(c *gin.Context) {
+		route, pathParams, err := router.FindRoute(c.Request)
+		if err != nil {
+			// The undocumented routes and methods are handled by the router
+			c.Next()
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			httputil.BadRequestMap(c, "Invalid request", formatRequestErrors(err))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}, nil
+}
+
+// disallowUnknownProperties sets additionalProperties to false on the object schemas of the request bodies and their nested objects.
+// The schemas setting additionalProperties, or composed with allOf, oneOf or anyOf, are left as is.
+func disallowUnknownProperties(ref *openapi3.SchemaRef, visited map[*openapi3.Schema]bool) {
+	if ref == nil || ref.Value == nil || visited[ref.Value] {
+		return
+	}
+	schema := ref.Value
+	visited[schema] = true
+
+	if len(schema.AllOf) > 0 || len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		return
+	}
+
+	for _, property := range schema.Properties {
+		disallowUnknownProperties(property, visited)
+	}
+	disallowUnknownProperties(schema.Items, visited)
+
+	isObject := schema.Type.Is(openapi3.TypeObject) || len(schema.Properties) > 0
+	if isObject && schema.AdditionalProperties.Has == nil && schema.AdditionalProperties.Schema == nil {
+		disallowed := false
+		schema.AdditionalProperties.Has = &disallowed
+	}
+}
+
+// formatRequestErrors formats the errors of the request validation into a slice of maps,
+// each holding the field (the parameter name, or the path of the field in the body) and the reason of the error.
+func formatRequestErrors(err error) []map[string]string {
+	var issues []map[string]string
+
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		for _, err := range e {
+			issues = append(issues, formatRequestErrors(err)...)
+		}
+	case *openapi3filter.RequestError:
+		switch inner := e.Err.(type) {
+		case openapi3.MultiError, *openapi3.SchemaError:
+			if e.Parameter == nil {
+				return formatRequestErrors(inner)
+			}
+		}
+
+		field := "body"
+		if e.Parameter != nil {
+			field = e.Parameter.Name
+		}
+		issues = append(issues, map[string]string{"field": field, "message": reason(e)})
+	case *openapi3.SchemaError:
+		field := "body"
+		if path := e.JSONPointer(); len(path) > 0 {
+			field = strings.Join(path, ".")
+		}
+		issues = append(issues, map[string]string{"field": field, "message": e.Reason})
+	default:
+		issues = append(issues, map[string]string{"field": "request", "message": err.Error()})
+	}
+
+	return issues
+}
+
+// reason returns the reason of a request error, followed by its underlying error if any.
+func reason(err *openapi3filter.RequestError) string {
+	if schemaErr, ok := err.Err.(*openapi3.SchemaError); ok {
+		return schemaErr.Reason
+	}
+
+	switch {
+	case err.Err == nil:
+		return err.Reason
+	case err.Reason == "":
+		return err.Err.Error()
+	default:
+		return err.Reason + ": " + err.Err.Error()
+	}
+}
@@ -0,0 +1,46 @@
+package request_filter
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/quota"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+/**
+ * EnforceQuota is a middleware function that spends the cost of each request from the budgets of its client.
+ * It must run after the JWT validation, the callers are identified by the client of their token, or by user ID.
+ * The remaining units of the daily budget are reported in the X-Quota-Limit and X-Quota-Remaining headers.
+ * If the cost exceeds one of the budgets, it returns a 429 Too Many Requests response with a Retry-After header
+ * until the budget is reset and aborts the request.
+ */
+func EnforceQuota(tracker *quota.Tracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context())
+		if !ok {
+			c.Next()
+			return
+		}
+
+		cost := tracker.Policy().Cost(c.Request.Method, c.FullPath())
+		usage, allowed := tracker.Consume(quota.ClientKey(meta.ClientID, meta.UserID), cost)
+		if !usage.Daily.Unlimited {
+			c.Header("X-Quota-Limit", strconv.FormatInt(usage.Daily.Limit, 10))
+			c.Header("X-Quota-Remaining", strconv.FormatInt(usage.Daily.Remaining, 10))
+		}
+
+		if !allowed {
+			retryAfter := usage.RetryAfter(cost, tracker.Now())
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			httputil.TooManyRequests(c, "Quota exceeded", "The usage quota of the client is exhausted, please try again after it is reset")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
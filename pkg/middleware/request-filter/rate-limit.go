@@ -0,0 +1,48 @@
+package request_filter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/quota"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/ratelimit"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+/**
+ * RateLimit is a middleware function that limits the requests of each caller with the given limiter.
+ * The authenticated callers are limited by user ID, the others by client IP.
+ * If the limit is exceeded, it returns a 429 Too Many Requests response with a Retry-After header and aborts the request.
+ */
+func RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return RateLimitWithOverrides(limiter, nil)
+}
+
+/**
+ * RateLimitWithOverrides is like RateLimit, the limit of the authenticated callers being scaled by the override of their client,
+ * identified like for the quotas by the client of their token or by user ID. The client of the unauthenticated callers
+ * is not verified, so they are always limited by the limit of the limiter.
+ */
+func RateLimitWithOverrides(limiter *ratelimit.Limiter, overrides *ratelimit.Overrides) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		limit := limiter.Limit()
+		if meta, ok := metacontext.ExtractUserInformationMeta(c.Request.Context()); ok {
+			key = fmt.Sprintf("user:%d", meta.UserID)
+			limit = ratelimit.Scale(limit, overrides.Factor(quota.ClientKey(meta.ClientID, meta.UserID)))
+		}
+
+		if ok, retryAfter := limiter.AllowLimit(key, limit); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			httputil.TooManyRequests(c, "Too many requests", "Rate limit exceeded, please try again later")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
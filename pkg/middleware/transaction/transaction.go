@@ -0,0 +1,136 @@
+package transaction
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+/**
+ * Transactional is an opt-in middleware function that runs each request in a database transaction.
+ * The transaction is stored in the context of the request, where the services get it from their DataStore and the handlers
+ * with database.GetPostgresContext, so the transactions they open are nested in it as savepoints.
+ * The transaction is committed if the response status is below 400 and no error was attached to the context,
+ * and rolled back otherwise, or if the handler panics. The functions registered with database.AfterCommit run after the commit.
+ * The response is buffered until the transaction ends, so a failed commit is reported as a 500 Internal Server Error
+ * instead of the response of the handler. It must not be applied to the streaming endpoints.
+ */
+func Transactional() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := database.GetPostgres()
+		if db == nil {
+			httputil.InternalServerError(c, "Internal server error", "database connection is nil")
+			c.Abort()
+			return
+		}
+
+		tx := database.Begin(db.WithContext(c.Request.Context()))
+		if tx.Error != nil {
+			logger.ErrorContext(c.Request.Context(), "Failed to begin the request transaction", logrus.Fields{"path": c.FullPath(), "error": tx.Error.Error()})
+			httputil.InternalServerError(c, "Internal server error", "failed to begin the transaction")
+			c.Abort()
+			return
+		}
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Request = c.Request.WithContext(database.WithTransaction(c.Request.Context(), tx))
+
+		committed := false
+		defer func() {
+			if committed {
+				return
+			}
+
+			// The handler panicked: the transaction is rolled back and the panic is left to the recovery middleware
+			c.Writer = writer.ResponseWriter
+			tx.Rollback()
+		}()
+
+		c.Next()
+
+		c.Writer = writer.ResponseWriter
+		committed = true
+
+		if writer.status >= http.StatusBadRequest || len(c.Errors) > 0 {
+			if err := tx.Rollback().Error; err != nil {
+				logger.ErrorContext(c.Request.Context(), "Failed to roll back the request transaction", logrus.Fields{"path": c.FullPath(), "error": err.Error()})
+			}
+			writer.flush()
+			return
+		}
+
+		if err := database.Commit(tx); err != nil {
+			logger.ErrorContext(c.Request.Context(), "Failed to commit the request transaction", logrus.Fields{"path": c.FullPath(), "error": err.Error()})
+			httputil.InternalServerError(c, "Internal server error", "failed to commit the transaction")
+			return
+		}
+		writer.flush()
+	}
+}
+
+// bufferedWriter holds the status and body of the response until the transaction of the request ends.
+// The headers are written to the underlying writer directly.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+// Flush is a no-op, the response is sent when the transaction ends.
+func (w *bufferedWriter) Flush() {}
+
+// flush sends the buffered response to the underlying writer.
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+
+	if _, err := w.ResponseWriter.Write(w.body.Bytes()); err != nil {
+		logger.Error("Failed to write the response", logrus.Fields{"error": err.Error()})
+	}
+}
@@ -0,0 +1,117 @@
+package oidc
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+)
+
+// Default issuers of the well-known providers, used when OIDC_<NAME>_ISSUER_URL is not set.
+var DefaultIssuers = map[string]string{
+	"google": "https://accounts.google.com",
+}
+
+// DefaultScopes are the scopes requested when OIDC_<NAME>_SCOPES is not set, enough to identify and provision the user.
+var DefaultScopes = []string{"openid", "email", "profile"}
+
+// providerNamePattern restricts the provider names, which are part of the routes and of the environment variables.
+var providerNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]{0,19}$`)
+
+// Config holds the settings of an OpenID Connect provider: its issuer, whose discovery document gives the endpoints,
+// the client registered for the application, and the URL of the callback route the provider redirects the users to.
+type Config struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// LoadConfigs reads the settings of the providers listed by OIDC_PROVIDERS, e.g. "google,microsoft".
+// The settings of a provider are read from OIDC_<NAME>_ISSUER_URL, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET,
+// OIDC_<NAME>_REDIRECT_URL, and OIDC_<NAME>_SCOPES (space-separated). It returns an error if a setting is missing or invalid.
+func LoadConfigs() ([]Config, error) {
+	var configs []Config
+	seen := make(map[string]bool)
+
+	for _, name := range strings.Split(env.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		cfg, err := loadConfig(name)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, cfg)
+	}
+
+	return configs, nil
+}
+
+// loadConfig reads the settings of the provider with the given name.
+func loadConfig(name string) (Config, error) {
+	if !providerNamePattern.MatchString(name) {
+		return Config{}, fmt.Errorf("OIDC provider name %q must be lowercase letters and digits, starting with a letter", name)
+	}
+
+	prefix := "OIDC_" + strings.ToUpper(name) + "_"
+	cfg := Config{
+		Name:         name,
+		IssuerURL:    strings.TrimSuffix(env.Getenv(prefix+"ISSUER_URL"), "/"),
+		ClientID:     env.Getenv(prefix + "CLIENT_ID"),
+		ClientSecret: env.Getenv(prefix + "CLIENT_SECRET"),
+		RedirectURL:  env.Getenv(prefix + "REDIRECT_URL"),
+		Scopes:       strings.Fields(env.Getenv(prefix + "SCOPES")),
+	}
+	if cfg.IssuerURL == "" {
+		cfg.IssuerURL = DefaultIssuers[name]
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = append([]string{}, DefaultScopes...)
+	}
+
+	settings := []struct {
+		key   string
+		value string
+		url   bool
+	}{
+		{"ISSUER_URL", cfg.IssuerURL, true},
+		{"CLIENT_ID", cfg.ClientID, false},
+		{"CLIENT_SECRET", cfg.ClientSecret, false},
+		{"REDIRECT_URL", cfg.RedirectURL, true},
+	}
+	for _, setting := range settings {
+		if setting.value == "" {
+			return Config{}, fmt.Errorf("%s%s is not set", prefix, setting.key)
+		}
+		if !setting.url {
+			continue
+		}
+		if u, err := url.Parse(setting.value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Config{}, fmt.Errorf("%s%s must be an absolute http or https URL, got %q", prefix, setting.key, setting.value)
+		}
+	}
+	if !containsScope(cfg.Scopes, "openid") {
+		cfg.Scopes = append([]string{"openid"}, cfg.Scopes...)
+	}
+
+	return cfg, nil
+}
+
+// containsScope reports whether the scopes contain the given one.
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
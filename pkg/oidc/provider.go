@@ -0,0 +1,448 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+)
+
+/**
+ * oidc package implements the relying party of the OpenID Connect authorization code flow (OpenID Connect Core 1.0),
+ * so that the users log in with an account of their identity provider, e.g. Google or Microsoft Entra ID.
+ * The endpoints of a provider are read from the discovery document of its issuer, and the signing keys of its ID tokens
+ * from its JSON Web Key Set, which is fetched again when a token is signed with an unknown key, e.g. after a rotation.
+ * The authorization requests are protected with PKCE (RFC 7636), and the ID tokens are checked for their signature,
+ * issuer, audience, expiry, and nonce before their claims are trusted.
+ */
+
+const (
+	// DefaultHTTPTimeout is the time the provider has to answer a request of the discovery, key set, or token endpoint.
+	DefaultHTTPTimeout = 10 * time.Second
+
+	// keySetRefreshInterval is the minimum delay between two fetches of the key set, so that the tokens signed
+	// with unknown keys cannot make the application hammer the provider.
+	keySetRefreshInterval = time.Minute
+
+	// clockSkew is the leeway given to the time based claims of the ID tokens.
+	clockSkew = time.Minute
+
+	// maxResponseBody is the maximum number of bytes read from a response of the provider.
+	maxResponseBody = 1 << 20
+)
+
+var (
+	// ErrInvalidIDToken is returned when the ID token of the provider is missing, invalid, expired,
+	// issued to another client, or carries another nonce than the authorization request.
+	ErrInvalidIDToken = errors.New("invalid OIDC ID token")
+
+	// ErrTokenExchange is returned when the provider rejects the authorization code, e.g. because it was already used.
+	ErrTokenExchange = errors.New("OIDC authorization code exchange failed")
+
+	// ErrProviderUnavailable is returned when the discovery document, the key set, or the token endpoint of the provider cannot be reached.
+	ErrProviderUnavailable = errors.New("OIDC provider is unavailable")
+)
+
+// Claims holds the claims of a verified ID token identifying the user.
+// EmailVerified is nil when the provider does not say whether the email is verified, e.g. Microsoft Entra ID.
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     *bool
+	Name              string
+	GivenName         string
+	FamilyName        string
+	PreferredUsername string
+}
+
+// idTokenClaims holds the claims of an ID token, as parsed from its payload.
+// email_verified is a boolean, but some providers send it as a string.
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	AuthorizedParty   string      `json:"azp"`
+	Nonce             string      `json:"nonce"`
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"`
+	Name              string      `json:"name"`
+	GivenName         string      `json:"given_name"`
+	FamilyName        string      `json:"family_name"`
+	PreferredUsername string      `json:"preferred_username"`
+}
+
+// metadata holds the endpoints of the provider read from its discovery document.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// jsonWebKey is a public key of the key set of the provider, RSA or elliptic curve (RFC 7517, RFC 7518).
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// tokenResponse holds the response of the token endpoint, or its error (RFC 6749, section 5).
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Provider is the relying party of an OpenID Connect provider.
+// The discovery document is read once, and the key set is cached until a token is signed with an unknown key.
+// It is safe for concurrent use.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+	clock  clock.Clock
+
+	mu            sync.Mutex
+	metadata      *metadata
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+// NewProvider creates the relying party of the provider with the given settings,
+// sending its requests with the given HTTP client (an HTTP client with DefaultHTTPTimeout if nil).
+func NewProvider(cfg Config, client *http.Client, clk clock.Clock) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+
+	return &Provider{cfg: cfg, client: client, clock: clk}
+}
+
+// Name returns the name of the provider, e.g. google.
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthCodeURL returns the URL of the authorization endpoint the user is redirected to, to log in with the provider.
+// The state and the nonce are echoed back by the provider, in the callback and in the ID token,
+// and the code verifier must be sent along with the authorization code in Authenticate.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, codeVerifier string) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(md.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return md.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Authenticate exchanges the authorization code of the callback for the tokens of the user,
+// and returns the claims of the ID token once it is verified, carrying the given nonce.
+func (p *Provider) Authenticate(ctx context.Context, code string, codeVerifier string, nonce string) (Claims, error) {
+	rawIDToken, err := p.exchange(ctx, code, codeVerifier)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	return p.Verify(ctx, rawIDToken, nonce)
+}
+
+// Verify verifies the ID token issued by the provider and returns its claims.
+// The token must be signed with a key of the provider (RS256 or ES256), issued by its issuer to the client,
+// not expired, and carry the given nonce.
+func (p *Provider) Verify(ctx context.Context, rawIDToken string, nonce string) (Claims, error) {
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithIssuer(p.cfg.IssuerURL),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew),
+		jwt.WithTimeFunc(p.clock.Now),
+	)
+	if errors.Is(err, ErrProviderUnavailable) {
+		return Claims{}, err
+	}
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	// The authorized party must be the client when the token is issued to several audiences (OpenID Connect Core 1.0, section 3.1.3.7)
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID {
+		return Claims{}, fmt.Errorf("%w: token is authorized for another party", ErrInvalidIDToken)
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return Claims{}, fmt.Errorf("%w: nonce does not match the authorization request", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: sub claim is missing", ErrInvalidIDToken)
+	}
+
+	return Claims{
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     parseVerified(claims.EmailVerified),
+		Name:              claims.Name,
+		GivenName:         claims.GivenName,
+		FamilyName:        claims.FamilyName,
+		PreferredUsername: claims.PreferredUsername,
+	}, nil
+}
+
+// exchange sends the authorization code and the code verifier to the token endpoint, and returns the ID token.
+// The client authenticates with its secret in the body of the request (client_secret_post).
+func (p *Provider) exchange(ctx context.Context, code string, codeVerifier string) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"client_secret": {p.cfg.ClientSecret},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	var body tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBody)).Decode(&body); err != nil {
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return "", fmt.Errorf("%w: token endpoint responded with %d", ErrProviderUnavailable, resp.StatusCode)
+		}
+		return "", fmt.Errorf("%w: invalid response of the token endpoint: %v", ErrTokenExchange, err)
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return "", fmt.Errorf("%w: token endpoint responded with %d", ErrProviderUnavailable, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("%w: %s %s", ErrTokenExchange, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", fmt.Errorf("%w: id_token is missing from the response", ErrInvalidIDToken)
+	}
+
+	return body.IDToken, nil
+}
+
+// discover returns the endpoints of the provider, reading its discovery document the first time.
+// The issuer of the document must be the configured one (OpenID Connect Discovery 1.0, section 4.3).
+func (p *Provider) discover(ctx context.Context) (metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return *p.metadata, nil
+	}
+
+	var md metadata
+	if err := p.getJSON(ctx, p.cfg.IssuerURL+"/.well-known/openid-configuration", &md); err != nil {
+		return metadata{}, err
+	}
+	if strings.TrimSuffix(md.Issuer, "/") != p.cfg.IssuerURL {
+		return metadata{}, fmt.Errorf("%w: discovery document is issued by %q instead of %q", ErrProviderUnavailable, md.Issuer, p.cfg.IssuerURL)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return metadata{}, fmt.Errorf("%w: discovery document misses an endpoint", ErrProviderUnavailable)
+	}
+
+	p.metadata = &md
+	return md, nil
+}
+
+// publicKey returns the key of the provider with the given kid, fetching the key set again if the key is unknown,
+// at most once every keySetRefreshInterval. Without kid, the key set must hold a single key.
+func (p *Provider) publicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if p.keys != nil && p.clock.Now().Sub(p.keysFetchedAt) < keySetRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var keySet struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, md.JWKSURI, &keySet); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(keySet.Keys))
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	p.keys = keys
+	p.keysFetchedAt = p.clock.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey returns the cached key with the given kid, or the only cached key when the token has no kid.
+// The caller must hold the lock.
+func (p *Provider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// getJSON reads the JSON document at the target URL into v.
+func (p *Provider) getJSON(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s responded with %d", ErrProviderUnavailable, target, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBody)).Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid document at %s: %v", ErrProviderUnavailable, target, err)
+	}
+
+	return nil
+}
+
+// publicKey returns the RSA or P-256 public key of the JSON Web Key.
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// parseVerified returns the value of the email_verified claim, sent as a boolean or as a string, or nil if it is missing.
+func parseVerified(v interface{}) *bool {
+	var verified bool
+	switch value := v.(type) {
+	case bool:
+		verified = value
+	case string:
+		verified = strings.EqualFold(value, "true")
+	default:
+		return nil
+	}
+
+	return &verified
+}
+
+// RandomString returns a random URL-safe string of the given number of random bytes,
+// used for the states, the nonces, and the code verifiers of the authorization requests.
+func RandomString(bytes int) (string, error) {
+	b := make([]byte, bytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge returns the S256 code challenge of the code verifier (RFC 7636, section 4.2).
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,314 @@
+package quota
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+)
+
+/**
+ * quota package provides cost-based usage quotas per client.
+ * Each client has a daily and a monthly budget of units, and each request spends the cost of its route
+ * (1 by default, more for the expensive routes such as the exports). A request is rejected when its cost exceeds
+ * the units left in one of the budgets, until the budget is reset at midnight UTC or on the first day of the month.
+ * The usage is kept in memory per instance, it is not shared between replicas and is lost on restart.
+ */
+
+// DefaultCost is the cost of the routes without a configured cost.
+const DefaultCost int64 = 1
+
+// DefaultCosts are the costs of the expensive routes, overridden by QUOTA_ENDPOINT_COSTS.
+// The quota usage endpoint is free, so that a client can always check its budgets.
+var DefaultCosts = map[string]int64{
+	"GET /api/v1/consumers/stream":    10,
+	"GET /api/v1/downloads/consumers": 10,
+	"GET /api/v1/me/quota":            0,
+}
+
+// Budget is the number of units a client may spend per day and per month, 0 meaning unlimited.
+type Budget struct {
+	Daily   int64
+	Monthly int64
+}
+
+// Policy holds the budgets of the clients and the costs of the routes.
+type Policy struct {
+	Default Budget
+	Clients map[string]Budget
+	Costs   map[string]int64
+}
+
+// LoadPolicy reads the quota policy from the environment variables.
+// QUOTA_DAILY_LIMIT and QUOTA_MONTHLY_LIMIT are the default budgets (0 or unset means unlimited),
+// QUOTA_LIMITS_BY_CLIENT overrides them per client, e.g. "web=10000/200000,partner=1000/20000",
+// and QUOTA_ENDPOINT_COSTS overrides the costs of the routes, e.g. "GET /api/v1/consumers/stream=20".
+// Invalid values are ignored, the configuration validation reports them at boot.
+func LoadPolicy() Policy {
+	policy := Policy{Clients: make(map[string]Budget), Costs: make(map[string]int64)}
+	policy.Default.Daily, _ = strconv.ParseInt(env.Getenv("QUOTA_DAILY_LIMIT"), 10, 64)
+	policy.Default.Monthly, _ = strconv.ParseInt(env.Getenv("QUOTA_MONTHLY_LIMIT"), 10, 64)
+
+	if clients, err := ParseBudgets(env.Getenv("QUOTA_LIMITS_BY_CLIENT")); err == nil {
+		policy.Clients = clients
+	}
+
+	for route, cost := range DefaultCosts {
+		policy.Costs[route] = cost
+	}
+	if costs, err := ParseCosts(env.Getenv("QUOTA_ENDPOINT_COSTS")); err == nil {
+		for route, cost := range costs {
+			policy.Costs[route] = cost
+		}
+	}
+
+	return policy
+}
+
+// ParseBudgets parses a comma separated list of client=daily/monthly pairs, 0 meaning unlimited.
+// It returns an error if a pair is malformed or one of its budgets is not a non-negative integer.
+func ParseBudgets(value string) (map[string]Budget, error) {
+	budgets := make(map[string]Budget)
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		client, limits, ok := strings.Cut(pair, "=")
+		client = strings.TrimSpace(client)
+		daily, monthly, ok2 := strings.Cut(limits, "/")
+		if !ok || !ok2 || client == "" {
+			return nil, fmt.Errorf("invalid quota %q, expected client=daily/monthly", pair)
+		}
+
+		d, err := strconv.ParseInt(strings.TrimSpace(daily), 10, 64)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid quota %q, the daily budget must be a non-negative integer", pair)
+		}
+		m, err := strconv.ParseInt(strings.TrimSpace(monthly), 10, 64)
+		if err != nil || m < 0 {
+			return nil, fmt.Errorf("invalid quota %q, the monthly budget must be a non-negative integer", pair)
+		}
+		budgets[client] = Budget{Daily: d, Monthly: m}
+	}
+
+	return budgets, nil
+}
+
+// ParseCosts parses a comma separated list of "METHOD /route=cost" pairs, the routes being the patterns of the router.
+// It returns an error if a pair is malformed or its cost is not a non-negative integer.
+func ParseCosts(value string) (map[string]int64, error) {
+	costs := make(map[string]int64)
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		route, cost, ok := strings.Cut(pair, "=")
+		method, path, ok2 := strings.Cut(strings.TrimSpace(route), " ")
+		path = strings.TrimSpace(path)
+		if !ok || !ok2 || method == "" || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid endpoint cost %q, expected METHOD /route=cost", pair)
+		}
+
+		n, err := strconv.ParseInt(strings.TrimSpace(cost), 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid endpoint cost %q, the cost must be a non-negative integer", pair)
+		}
+		costs[strings.ToUpper(method)+" "+path] = n
+	}
+
+	return costs, nil
+}
+
+// Budget returns the budget of the given client, or the default budget if it has none.
+func (p Policy) Budget(client string) Budget {
+	if budget, ok := p.Clients[client]; ok {
+		return budget
+	}
+	return p.Default
+}
+
+// Cost returns the cost of a request to the given route pattern, e.g. "/api/v1/consumers/:id".
+func (p Policy) Cost(method string, route string) int64 {
+	if cost, ok := p.Costs[method+" "+route]; ok {
+		return cost
+	}
+	return DefaultCost
+}
+
+// Period represents the usage of a budget in its current period, the limit and remaining units are 0 when unlimited.
+type Period struct {
+	Limit     int64     `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	Unlimited bool      `json:"unlimited,omitempty"`
+	ResetsAt  time.Time `json:"resetsAt"`
+}
+
+// Usage represents the usage of the budgets of a client.
+type Usage struct {
+	Client  string `json:"client"`
+	Daily   Period `json:"daily"`
+	Monthly Period `json:"monthly"`
+}
+
+// RetryAfter returns the time to wait before the exhausted budgets of the usage are reset.
+func (u Usage) RetryAfter(cost int64, now time.Time) time.Duration {
+	var wait time.Duration
+	if !u.Monthly.Unlimited && u.Monthly.Remaining < cost {
+		wait = u.Monthly.ResetsAt.Sub(now)
+	} else if !u.Daily.Unlimited && u.Daily.Remaining < cost {
+		wait = u.Daily.ResetsAt.Sub(now)
+	}
+	return wait
+}
+
+// counter holds the units spent by a client in the current day and month.
+type counter struct {
+	day     time.Time
+	daily   int64
+	month   time.Time
+	monthly int64
+}
+
+// Tracker keeps the units spent by the clients and enforces their budgets.
+type Tracker struct {
+	mu       sync.Mutex
+	policy   Policy
+	clock    clock.Clock
+	counters map[string]*counter
+	lastDay  time.Time
+}
+
+// NewTracker creates a new tracker enforcing the given policy.
+func NewTracker(policy Policy, clk clock.Clock) *Tracker {
+	return &Tracker{
+		policy:   policy,
+		clock:    clk,
+		counters: make(map[string]*counter),
+		lastDay:  startOfDay(clk.Now()),
+	}
+}
+
+// Policy returns the policy enforced by the tracker.
+func (t *Tracker) Policy() Policy {
+	return t.policy
+}
+
+// Now returns the current time of the clock of the tracker.
+func (t *Tracker) Now() time.Time {
+	return t.clock.Now()
+}
+
+// Consume spends the given cost from the budgets of the client and reports whether the request is allowed.
+// A rejected request spends nothing. The usage of the client after the request is returned with it.
+func (t *Tracker) Consume(client string, cost int64) (Usage, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clock.Now()
+	t.sweep(now)
+
+	c := t.counter(client, now)
+	budget := t.policy.Budget(client)
+	if (budget.Daily > 0 && c.daily+cost > budget.Daily) || (budget.Monthly > 0 && c.monthly+cost > budget.Monthly) {
+		return t.usage(client, c, budget), false
+	}
+
+	c.daily += cost
+	c.monthly += cost
+	return t.usage(client, c, budget), true
+}
+
+// Usage returns the usage of the budgets of the client.
+func (t *Tracker) Usage(client string) Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clock.Now()
+	return t.usage(client, t.counter(client, now), t.policy.Budget(client))
+}
+
+// counter returns the counter of the client, reset if its day or month is over.
+func (t *Tracker) counter(client string, now time.Time) *counter {
+	day, month := startOfDay(now), startOfMonth(now)
+
+	c, ok := t.counters[client]
+	if !ok {
+		c = &counter{day: day, month: month}
+		t.counters[client] = c
+	}
+	if !c.day.Equal(day) {
+		c.day, c.daily = day, 0
+	}
+	if !c.month.Equal(month) {
+		c.month, c.monthly = month, 0
+	}
+
+	return c
+}
+
+// usage returns the usage of the given counter of the client against its budget.
+func (t *Tracker) usage(client string, c *counter, budget Budget) Usage {
+	return Usage{
+		Client:  client,
+		Daily:   period(budget.Daily, c.daily, c.day.AddDate(0, 0, 1)),
+		Monthly: period(budget.Monthly, c.monthly, c.month.AddDate(0, 1, 0)),
+	}
+}
+
+// sweep removes the counters of the clients without request in the current month, at most once per day.
+func (t *Tracker) sweep(now time.Time) {
+	day := startOfDay(now)
+	if day.Equal(t.lastDay) {
+		return
+	}
+	t.lastDay = day
+
+	month := startOfMonth(now)
+	for client, c := range t.counters {
+		if !c.month.Equal(month) {
+			delete(t.counters, client)
+		}
+	}
+}
+
+// period returns the usage of a budget with the given limit, 0 meaning unlimited.
+func period(limit int64, used int64, resetsAt time.Time) Period {
+	p := Period{Limit: limit, Used: used, ResetsAt: resetsAt}
+	if limit <= 0 {
+		p.Limit = 0
+		p.Unlimited = true
+		return p
+	}
+
+	if used < limit {
+		p.Remaining = limit - used
+	}
+	return p
+}
+
+// startOfDay returns midnight UTC of the day of the given time.
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// startOfMonth returns midnight UTC of the first day of the month of the given time.
+func startOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// ClientKey returns the key of the budgets of a caller: its client, or its user when the token was issued without client.
+func ClientKey(clientID string, userID int64) string {
+	if clientID != "" {
+		return clientID
+	}
+	return fmt.Sprintf("user:%d", userID)
+}
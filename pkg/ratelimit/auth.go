@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+)
+
+// Backends of the token buckets of the authentication routes.
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// Default token buckets of the authentication routes.
+var (
+	DefaultAuthIPPolicy      = BucketPolicy{Burst: 20, PerMinute: 10}
+	DefaultAuthSubjectPolicy = BucketPolicy{Burst: 5, PerMinute: 2}
+)
+
+// AuthConfig holds the token buckets limiting the logins and the token refreshes: one per client IP, against the attacks
+// spraying many accounts from one address, and one per subject (the username of a login), against the attacks guessing
+// the password of one account from many addresses. A bucket that is not refilled does not limit the requests.
+// The buckets are kept per instance, or shared by the instances in Redis.
+type AuthConfig struct {
+	IP      BucketPolicy
+	Subject BucketPolicy
+	Backend string
+}
+
+// LoadAuthConfig reads the token buckets of the authentication routes from the environment variables:
+// AUTH_RATE_LIMIT_IP_BURST and AUTH_RATE_LIMIT_IP_PER_MINUTE for the client IPs, AUTH_RATE_LIMIT_USERNAME_BURST
+// and AUTH_RATE_LIMIT_USERNAME_PER_MINUTE for the usernames, 0 per minute disabling a bucket, and AUTH_RATE_LIMIT_BACKEND,
+// memory (the default) or redis. Invalid values fall back to their defaults, the configuration validation reports them at boot.
+func LoadAuthConfig() AuthConfig {
+	cfg := AuthConfig{
+		IP: BucketPolicy{
+			Burst:     nonNegativeInt("AUTH_RATE_LIMIT_IP_BURST", DefaultAuthIPPolicy.Burst),
+			PerMinute: nonNegativeInt("AUTH_RATE_LIMIT_IP_PER_MINUTE", DefaultAuthIPPolicy.PerMinute),
+		},
+		Subject: BucketPolicy{
+			Burst:     nonNegativeInt("AUTH_RATE_LIMIT_USERNAME_BURST", DefaultAuthSubjectPolicy.Burst),
+			PerMinute: nonNegativeInt("AUTH_RATE_LIMIT_USERNAME_PER_MINUTE", DefaultAuthSubjectPolicy.PerMinute),
+		},
+		Backend: BackendMemory,
+	}
+
+	if strings.EqualFold(strings.TrimSpace(env.Getenv("AUTH_RATE_LIMIT_BACKEND")), BackendRedis) {
+		cfg.Backend = BackendRedis
+	}
+
+	return cfg
+}
+
+// nonNegativeInt returns the non-negative integer of the environment variable, or the default value if it is missing or invalid.
+func nonNegativeInt(key string, def int) int {
+	n, err := strconv.Atoi(env.Getenv(key))
+	if err != nil || n < 0 {
+		return def
+	}
+
+	return n
+}
+
+// NewBucketStore creates the store of the token buckets of the backend of the settings,
+// the Redis keys being prefixed with the given prefix of the keys of the service.
+func (c AuthConfig) NewBucketStore(keyPrefix string, clk clock.Clock) BucketStore {
+	if c.Backend == BackendRedis {
+		return NewRedisBuckets(keyPrefix+"ratelimit:auth:", clk)
+	}
+
+	return NewMemoryBuckets(clk)
+}
+
+// AuthLimiter limits the requests to the authentication routes with the token buckets of the client IPs and of the subjects.
+type AuthLimiter struct {
+	config AuthConfig
+	store  BucketStore
+}
+
+// NewAuthLimiter creates a new limiter with the given settings, keeping its buckets in the given store.
+func NewAuthLimiter(cfg AuthConfig, store BucketStore) *AuthLimiter {
+	return &AuthLimiter{config: cfg, store: store}
+}
+
+// Allow takes a token from the bucket of the client IP, then from the bucket of the subject if any, e.g. the username of a login,
+// and reports whether the request is allowed. The subjects are case-insensitive, like the usernames.
+// A request rejected by the bucket of the client IP takes no token from the bucket of the subject.
+func (l *AuthLimiter) Allow(ctx context.Context, ip string, subject string) (bool, time.Duration) {
+	if l.config.IP.Enabled() {
+		if ok, retryAfter := l.store.Take(ctx, "ip:"+ip, l.config.IP); !ok {
+			return false, retryAfter
+		}
+	}
+
+	if subject = strings.ToLower(strings.TrimSpace(subject)); subject != "" && l.config.Subject.Enabled() {
+		if ok, retryAfter := l.store.Take(ctx, fmt.Sprintf("subject:%s", subject), l.config.Subject); !ok {
+			return false, retryAfter
+		}
+	}
+
+	return true, 0
+}
@@ -0,0 +1,175 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/cache"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+// BucketPolicy is a token bucket holding up to Burst tokens, refilled with PerMinute tokens per minute.
+// Each request takes a token, a burst of requests is allowed up to the tokens left, then PerMinute requests per minute.
+type BucketPolicy struct {
+	Burst     int
+	PerMinute int
+}
+
+// Enabled reports whether the bucket limits the requests, that is when it is refilled.
+func (p BucketPolicy) Enabled() bool {
+	return p.PerMinute > 0
+}
+
+// capacity returns the number of tokens of a full bucket, at least 1.
+func (p BucketPolicy) capacity() float64 {
+	return float64(max(p.Burst, 1))
+}
+
+// perMillisecond returns the number of tokens added to the bucket per millisecond.
+func (p BucketPolicy) perMillisecond() float64 {
+	return float64(p.PerMinute) / float64(time.Minute.Milliseconds())
+}
+
+// BucketStore holds the token buckets of the keys.
+type BucketStore interface {
+	// Take takes a token from the bucket of the key and reports whether the request is allowed.
+	// A rejected request takes no token, and the time to wait before the next token is returned with it.
+	Take(ctx context.Context, key string, policy BucketPolicy) (bool, time.Duration)
+}
+
+// bucket is the state of a token bucket: its tokens left when it was updated last.
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	policy    BucketPolicy
+}
+
+// refill adds the tokens earned since the bucket was updated last, up to its capacity.
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updatedAt); elapsed > 0 {
+		b.tokens = math.Min(b.policy.capacity(), b.tokens+float64(elapsed.Milliseconds())*b.policy.perMillisecond())
+		b.updatedAt = now
+	}
+}
+
+// MemoryBuckets holds the token buckets in memory, per instance.
+type MemoryBuckets struct {
+	mu        sync.Mutex
+	clock     clock.Clock
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryBuckets creates a new in-memory store of token buckets.
+func NewMemoryBuckets(clk clock.Clock) *MemoryBuckets {
+	return &MemoryBuckets{clock: clk, buckets: make(map[string]*bucket), lastSweep: clk.Now()}
+}
+
+// Take takes a token from the bucket of the key, see BucketStore.
+func (m *MemoryBuckets) Take(_ context.Context, key string, policy BucketPolicy) (bool, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok || b.policy != policy {
+		b = &bucket{tokens: policy.capacity(), updatedAt: now, policy: policy}
+		m.buckets[key] = b
+	}
+	b.refill(now)
+
+	if b.tokens < 1 {
+		return false, time.Duration(math.Ceil((1-b.tokens)/policy.perMillisecond())) * time.Millisecond
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// sweep removes the buckets refilled to their capacity, which are the same as new ones, at most once per minute.
+func (m *MemoryBuckets) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if b.refill(now); b.tokens >= b.policy.capacity() {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+// takeScript takes a token from the bucket of KEYS[1], stored as a hash of its tokens and its update time in milliseconds.
+// ARGV are the capacity of the bucket, the tokens added per millisecond, and the current time in milliseconds.
+// It returns whether the request is allowed, and the milliseconds to wait before the next token.
+// The bucket expires once it is refilled to its capacity, when it is the same as a new one.
+const takeScript = `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * rate)
+	ts = now
+end
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate) + 1)
+return {allowed, wait}
+`
+
+// DefaultRedisBucketsTimeout is the time Redis has to answer a request for a token, so that a slow Redis does not delay the logins.
+const DefaultRedisBucketsTimeout = 100 * time.Millisecond
+
+// RedisBuckets holds the token buckets in the shared Redis client, so that the instances share the limits.
+// While Redis is not initialized or fails, the tokens are taken from the buckets of the process instead.
+type RedisBuckets struct {
+	prefix   string
+	clock    clock.Clock
+	fallback *MemoryBuckets
+}
+
+// NewRedisBuckets creates a new store of token buckets in Redis, whose keys are stored with the given prefix.
+func NewRedisBuckets(prefix string, clk clock.Clock) *RedisBuckets {
+	return &RedisBuckets{prefix: prefix, clock: clk, fallback: NewMemoryBuckets(clk)}
+}
+
+// Take takes a token from the bucket of the key, see BucketStore.
+func (r *RedisBuckets) Take(ctx context.Context, key string, policy BucketPolicy) (bool, time.Duration) {
+	client := cache.GetRedis()
+	if client == nil {
+		return r.fallback.Take(ctx, key, policy)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultRedisBucketsTimeout)
+	defer cancel()
+
+	result, err := client.Eval(ctx, takeScript, []string{r.prefix + key}, policy.capacity(), policy.perMillisecond(), r.clock.Now().UnixMilli()).Int64Slice()
+	if err == nil && len(result) != 2 {
+		err = fmt.Errorf("unexpected reply %v", result)
+	}
+	if err != nil {
+		logger.WarnContext(ctx, fmt.Sprintf("Failed to take a rate limit token from Redis, using the buckets of the process: %v", err), logrus.Fields{"key": key})
+		return r.fallback.Take(ctx, key, policy)
+	}
+
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond
+}
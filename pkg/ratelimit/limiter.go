@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+)
+
+/**
+ * ratelimit package provides an in-memory sliding-window rate limiter keyed by caller, e.g. a user ID or an IP.
+ * A key is allowed the given number of requests within any window, the following ones are rejected until
+ * the oldest request leaves the window. The limits are per instance, they are not shared between replicas.
+ * The limits of individual clients are scaled by the overrides stored by the administrators, see Overrides.
+ */
+
+// Limiter allows a number of requests per key within a sliding window.
+type Limiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	clock     clock.Clock
+	hits      map[string][]time.Time
+	lastSweep time.Time
+}
+
+// NewLimiter creates a new limiter allowing the given number of requests per key within the window.
+func NewLimiter(limit int, window time.Duration, clk clock.Clock) *Limiter {
+	return &Limiter{
+		limit:     limit,
+		window:    window,
+		clock:     clk,
+		hits:      make(map[string][]time.Time),
+		lastSweep: clk.Now(),
+	}
+}
+
+// Limit returns the number of requests allowed per key within the window.
+func (l *Limiter) Limit() int {
+	return l.limit
+}
+
+// Allow records a request of the key and reports whether it is allowed.
+// A rejected request is not recorded, and the time to wait before the next allowed request is returned with it.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	return l.AllowLimit(key, l.limit)
+}
+
+// AllowLimit is like Allow, with the given number of requests allowed for the key instead of the limit of the limiter,
+// e.g. the limit scaled by the override of the client of the key. A key is always allowed one request per window.
+func (l *Limiter) AllowLimit(key string, limit int) (bool, time.Duration) {
+	limit = max(limit, 1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	l.sweep(now)
+
+	hits := prune(l.hits[key], now.Add(-l.window))
+	if len(hits) >= limit {
+		l.hits[key] = hits
+		return false, hits[0].Add(l.window).Sub(now)
+	}
+
+	l.hits[key] = append(hits, now)
+	return true, 0
+}
+
+// sweep removes the keys without request in the window, at most once per window.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+
+	cutoff := now.Add(-l.window)
+	for key, hits := range l.hits {
+		if hits = prune(hits, cutoff); len(hits) == 0 {
+			delete(l.hits, key)
+		} else {
+			l.hits[key] = hits
+		}
+	}
+}
+
+// prune removes the requests made before the cutoff, the requests are sorted from the oldest.
+func prune(hits []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	return hits[i:]
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+// Scale returns the limit multiplied by the factor of an override, rounded down, and at least 1.
+func Scale(limit int, factor float64) int {
+	return max(int(math.Floor(float64(limit)*factor)), 1)
+}
+
+// OverridesLoader loads the factors of the clients with an override, e.g. from the database.
+type OverridesLoader func() (map[string]float64, error)
+
+// Overrides is a thread-safe TTL cache of the factors scaling the limits of individual clients,
+// e.g. 10 for a client allowed ten times the default limits, or 0.1 for a throttled partner.
+// The factors are applied once they are loaded with Load, so that the requests served before the database
+// is initialized never query it. A nil *Overrides is valid and scales no limit.
+type Overrides struct {
+	mu       sync.Mutex
+	load     OverridesLoader
+	ttl      time.Duration
+	clock    clock.Clock
+	current  map[string]float64
+	loaded   bool
+	stale    bool
+	loadedAt time.Time
+}
+
+// NewOverrides creates a new instance of Overrides with the factors of the loader.
+// The factors are reloaded once they are older than the TTL; with a TTL that is not positive,
+// they are only reloaded after Invalidate.
+func NewOverrides(load OverridesLoader, ttl time.Duration, clk clock.Clock) *Overrides {
+	return &Overrides{load: load, ttl: ttl, clock: clk}
+}
+
+// Load loads the factors at once, and returns the error of the loader.
+func (o *Overrides) Load() error {
+	if o == nil {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.reload()
+}
+
+// Factor returns the factor of the client, or 1 if it has no override.
+// If the reload of the stale factors fails, the previous ones are kept and retried after the TTL.
+func (o *Overrides) Factor(client string) float64 {
+	if o == nil {
+		return 1
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.loaded {
+		return 1
+	}
+	if o.stale || (o.ttl > 0 && !o.clock.Now().Before(o.loadedAt.Add(o.ttl))) {
+		if err := o.reload(); err != nil {
+			logger.Warn("Failed to reload the rate limit overrides, keeping the previous ones", logrus.Fields{"error": err.Error()})
+		}
+	}
+
+	if factor, ok := o.current[client]; ok {
+		return factor
+	}
+	return 1
+}
+
+// Invalidate marks the factors stale, so that the next request reloads them.
+func (o *Overrides) Invalidate() {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.stale = true
+}
+
+// reload loads the factors with the loader. The caller must hold the lock.
+func (o *Overrides) reload() error {
+	o.loadedAt = o.clock.Now()
+	o.stale = false
+
+	factors, err := o.load()
+	if err != nil {
+		return err
+	}
+
+	o.current = factors
+	o.loaded = true
+	return nil
+}
@@ -0,0 +1,78 @@
+package readiness
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+/**
+ * On shutdown, the service first drains: /readyz reports not ready, so the load balancers stop sending new requests,
+ * while the requests already routed to the instance are still served during the drain period (SHUTDOWN_DRAIN_SECOND, default 10).
+ * The HTTP server is then shut down, waiting up to SHUTDOWN_TIMEOUT_SECOND (default 20) for the in-flight requests to complete.
+ * The drain period should be longer than the interval at which the load balancers probe /readyz.
+ */
+
+const (
+	// DefaultDrainPeriod is the time the load balancers have to stop routing to the instance when SHUTDOWN_DRAIN_SECOND is missing or invalid.
+	DefaultDrainPeriod = 10 * time.Second
+
+	// DefaultShutdownTimeout is the time the in-flight requests have to complete when SHUTDOWN_TIMEOUT_SECOND is missing or invalid.
+	DefaultShutdownTimeout = 20 * time.Second
+)
+
+// ShutdownPolicy holds the drain period of the service and the time the in-flight requests have to complete on shutdown.
+type ShutdownPolicy struct {
+	DrainPeriod time.Duration
+	Timeout     time.Duration
+}
+
+// LoadShutdownPolicy reads the shutdown policy from the environment variables.
+// SHUTDOWN_DRAIN_SECOND=0 disables the drain period, missing or invalid values fall back to the defaults.
+func LoadShutdownPolicy() ShutdownPolicy {
+	policy := ShutdownPolicy{DrainPeriod: DefaultDrainPeriod, Timeout: DefaultShutdownTimeout}
+
+	if n, err := strconv.Atoi(env.Getenv("SHUTDOWN_DRAIN_SECOND")); err == nil && n >= 0 {
+		policy.DrainPeriod = time.Duration(n) * time.Second
+	}
+	if n, err := strconv.Atoi(env.Getenv("SHUTDOWN_TIMEOUT_SECOND")); err == nil && n > 0 {
+		policy.Timeout = time.Duration(n) * time.Second
+	}
+
+	return policy
+}
+
+// Drain marks the service not ready for good, then waits for the given period so that the load balancers
+// notice it on /readyz and stop routing to the instance. It returns early if the context is canceled.
+func (g *Gate) Drain(ctx context.Context, period time.Duration) {
+	g.mu.Lock()
+	g.draining = true
+	g.mu.Unlock()
+
+	logger.Info("Draining, the service reports not ready", logrus.Fields{"drain_period": period.String()})
+
+	if period <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(period)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// IsDraining reports whether the service is draining for its shutdown.
+func (g *Gate) IsDraining() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.draining
+}
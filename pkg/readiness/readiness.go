@@ -0,0 +1,156 @@
+package readiness
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+)
+
+/**
+ * readiness package gates the readiness of the service on a warm-up phase.
+ * The warm-up steps (loading the JWT keys, priming the validator, pinging the database, ...) run once
+ * the server is listening, and /readyz reports ready only when all the required steps succeeded, so the
+ * load balancers do not send traffic to a cold instance. A failed required step is retried until it succeeds,
+ * while a failed optional step is only logged.
+ */
+
+// DefaultRetryInterval is the time between two attempts of a failed required step.
+const DefaultRetryInterval = 5 * time.Second
+
+// Statuses of the warm-up steps.
+const (
+	StepPending = "pending"
+	StepOK      = "ok"
+	StepFailed  = "failed"
+)
+
+// Step is a warm-up step. The service is not ready until all its required steps succeeded.
+type Step struct {
+	Name     string
+	Optional bool
+	Run      func(ctx context.Context) error
+}
+
+// StepStatus represents the outcome of a warm-up step, as reported by /readyz.
+type StepStatus struct {
+	Name     string `json:"name"`
+	Optional bool   `json:"optional,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// Gate holds the readiness of the service and the outcome of its warm-up steps.
+type Gate struct {
+	mu            sync.RWMutex
+	ready         bool
+	draining      bool
+	steps         []StepStatus
+	retryInterval time.Duration
+}
+
+var (
+	once sync.Once
+	gate *Gate
+)
+
+// GetGate returns the readiness gate of the process.
+func GetGate() *Gate {
+	once.Do(func() {
+		gate = NewGate(DefaultRetryInterval)
+	})
+	return gate
+}
+
+// NewGate creates a new gate, not ready until WarmUp completes, retrying the failed required steps after the given interval.
+func NewGate(retryInterval time.Duration) *Gate {
+	if retryInterval <= 0 {
+		retryInterval = DefaultRetryInterval
+	}
+
+	return &Gate{retryInterval: retryInterval}
+}
+
+// WarmUp runs the steps in order and marks the gate ready once all the required ones succeeded.
+// A failed required step is retried until it succeeds or the context is canceled, in which case the context error is returned.
+func (g *Gate) WarmUp(ctx context.Context, steps []Step) error {
+	g.mu.Lock()
+	g.ready = false
+	g.steps = make([]StepStatus, len(steps))
+	for i, step := range steps {
+		g.steps[i] = StepStatus{Name: step.Name, Optional: step.Optional, Status: StepPending}
+	}
+	g.mu.Unlock()
+
+	started := time.Now()
+	for i, step := range steps {
+		for {
+			err := g.run(ctx, i, step)
+			if err == nil || step.Optional {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(g.retryInterval):
+			}
+		}
+	}
+
+	// A service draining for its shutdown stays not ready
+	g.mu.Lock()
+	g.ready = !g.draining
+	g.mu.Unlock()
+
+	logger.Info("Warm-up completed, the service is ready", logrus.Fields{
+		"duration": time.Since(started).String(),
+	})
+
+	return nil
+}
+
+// run runs a step and records its outcome.
+func (g *Gate) run(ctx context.Context, i int, step Step) error {
+	started := time.Now()
+	err := step.Run(ctx)
+
+	status := StepStatus{Name: step.Name, Optional: step.Optional, Status: StepOK, Duration: time.Since(started).String()}
+	if err != nil {
+		status.Status = StepFailed
+		status.Error = err.Error()
+
+		fields := logrus.Fields{"step": step.Name, "error": err.Error()}
+		if step.Optional {
+			logger.Warn("Optional warm-up step failed", fields)
+		} else {
+			logger.Error("Warm-up step failed, retrying", fields)
+		}
+	}
+
+	g.mu.Lock()
+	g.steps[i] = status
+	g.mu.Unlock()
+
+	return err
+}
+
+// IsReady reports whether the warm-up completed and the service is not draining.
+func (g *Gate) IsReady() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.ready && !g.draining
+}
+
+// Steps returns a copy of the outcome of the warm-up steps.
+func (g *Gate) Steps() []StepStatus {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return append([]StepStatus{}, g.steps...)
+}
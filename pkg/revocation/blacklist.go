@@ -0,0 +1,39 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+)
+
+/**
+ * The denylist only lives in the process, so the other instances keep accepting a revoked token until they restart.
+ * A Blacklist shares the revocations between the instances, e.g. in Redis: the JWT validation middleware checks it
+ * after the denylist, so that a token revoked by any instance is rejected by all of them.
+ */
+
+// Interface for blacklist
+// This interface defines the check of the revocations shared by the instances
+type Blacklist interface {
+	IsRevoked(ctx context.Context, userID int64, tokenVersion int64, tokenID string) (bool, error)
+}
+
+var (
+	blacklistMu sync.RWMutex
+	blacklist   Blacklist
+)
+
+// SetBlacklist sets the blacklist checked by the JWT validation middleware, nil to check the denylist only.
+func SetBlacklist(b Blacklist) {
+	blacklistMu.Lock()
+	defer blacklistMu.Unlock()
+
+	blacklist = b
+}
+
+// GetBlacklist returns the blacklist checked by the JWT validation middleware, or nil if there is none.
+func GetBlacklist() Blacklist {
+	blacklistMu.RLock()
+	defer blacklistMu.RUnlock()
+
+	return blacklist
+}
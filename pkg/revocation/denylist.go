@@ -0,0 +1,105 @@
+package revocation
+
+import (
+	"sync"
+	"time"
+)
+
+/**
+ * revocation package provides the denylist of the access tokens revoked before their expiration.
+ * Every user has a token version, which is embedded in the access tokens issued to them and bumped
+ * when all their tokens are revoked (e.g. by an administrator responding to a compromised account).
+ * The denylist holds the current token version of the users whose tokens were revoked, and the JWT
+ * validation middleware rejects the tokens carrying an older version. It also holds the identifiers (jti)
+ * of the single access tokens revoked on logout, until they expire. It only lives in the process:
+ * it is loaded from the database at startup, and the other instances learn of a revocation on restart.
+ */
+
+// Denylist holds the current token version of the users whose tokens were revoked,
+// and the expiration time of the single access tokens revoked by their identifier.
+// It is safe for concurrent use.
+type Denylist struct {
+	mu       sync.RWMutex
+	versions map[int64]int64
+	tokens   map[string]time.Time
+}
+
+var (
+	once     sync.Once
+	denylist *Denylist
+)
+
+// GetDenylist returns the denylist of the process, creating it on first use.
+func GetDenylist() *Denylist {
+	once.Do(func() {
+		denylist = NewDenylist()
+	})
+	return denylist
+}
+
+// NewDenylist creates a new empty denylist.
+func NewDenylist() *Denylist {
+	return &Denylist{versions: make(map[int64]int64), tokens: make(map[string]time.Time)}
+}
+
+// Revoke rejects the tokens of the user with a version older than the given one.
+// A version older than the one already recorded is ignored.
+func (d *Denylist) Revoke(userID int64, version int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if version > d.versions[userID] {
+		d.versions[userID] = version
+	}
+}
+
+// Load records the current token versions of the users, e.g. as read from the database at startup.
+func (d *Denylist) Load(versions map[int64]int64) {
+	for userID, version := range versions {
+		d.Revoke(userID, version)
+	}
+}
+
+// IsRevoked reports whether a token of the user carrying the given version was revoked.
+func (d *Denylist) IsRevoked(userID int64, tokenVersion int64) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return tokenVersion < d.versions[userID]
+}
+
+// RevokeToken rejects the access token with the given identifier until it expires at the given time.
+func (d *Denylist) RevokeToken(tokenID string, expiresAt time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.tokens[tokenID] = expiresAt
+}
+
+// LoadTokens records the revoked access tokens by identifier with their expiration time, e.g. as read from the database at startup.
+func (d *Denylist) LoadTokens(tokens map[string]time.Time) {
+	for tokenID, expiresAt := range tokens {
+		d.RevokeToken(tokenID, expiresAt)
+	}
+}
+
+// IsTokenRevoked reports whether the access token with the given identifier was revoked.
+func (d *Denylist) IsTokenRevoked(tokenID string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	_, revoked := d.tokens[tokenID]
+	return revoked
+}
+
+// PruneTokens forgets the revoked access tokens expired at the given time, they are rejected by the JWT validation anyway.
+func (d *Denylist) PruneTokens(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for tokenID, expiresAt := range d.tokens {
+		if !expiresAt.After(now) {
+			delete(d.tokens, tokenID)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+)
+
+/**
+ * secrets package resolves the secrets of the application, such as the encryption keys, by name.
+ * The default provider reads a secret from the environment variable of the same name, or from the file
+ * named by the <NAME>_FILE environment variable, as mounted by the Docker and Kubernetes secrets.
+ * Other providers (e.g. a vault) can implement Provider and be installed with SetDefault.
+ */
+
+// ErrNotFound is returned when a secret is not configured.
+var ErrNotFound = errors.New("secret not found")
+
+// Provider resolves the secrets by name.
+type Provider interface {
+	Get(name string) (string, error)
+}
+
+// envProvider reads the secrets from the environment variables, or from the files they point to.
+type envProvider struct{}
+
+var defaultProvider Provider = envProvider{}
+
+// NewEnvProvider creates a provider reading the secret NAME from the NAME environment variable,
+// or from the file named by NAME_FILE when NAME is not set. The content of the file is trimmed.
+func NewEnvProvider() Provider {
+	return envProvider{}
+}
+
+// Default returns the provider used by the application.
+func Default() Provider {
+	return defaultProvider
+}
+
+// SetDefault replaces the provider used by the application, it must be called before the secrets are read.
+func SetDefault(p Provider) {
+	defaultProvider = p
+}
+
+// Get returns the secret with the given name, or ErrNotFound if it is not configured.
+func (envProvider) Get(name string) (string, error) {
+	if value := env.Getenv(name); value != "" {
+		return value, nil
+	}
+
+	path := env.Getenv(name + "_FILE")
+	if path == "" {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s from %s: %w", name, path, err)
+	}
+
+	value := strings.TrimSpace(string(content))
+	if value == "" {
+		return "", fmt.Errorf("%w: %s is empty", ErrNotFound, path)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,207 @@
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/secrets"
+)
+
+/**
+ * signedurl package signs time-limited download URLs, so that the large downloads (e.g. the consumer exports)
+ * can be handed to a browser or a download manager without the access token of the user.
+ * A signed URL carries its expiry and the user it was issued to in its query, and an HMAC-SHA256 signature of its path
+ * and of the rest of its query, keyed with SIGNED_URL_SECRET: changing any of them, or using it after its expiry,
+ * makes it invalid. The instances sharing the secret accept the URLs signed by each other.
+ * A signed URL cannot be revoked before it expires, its lifetime (SIGNED_URL_TTL_SECOND) should be kept short.
+ */
+
+// SecretName is the name of the secret keying the signatures, see the secrets package.
+const SecretName = "SIGNED_URL_SECRET"
+
+// Settings of the signed URLs.
+const (
+	// MinSecretLength is the minimum length of the secret, in bytes.
+	MinSecretLength = 32
+
+	// DefaultTTL is the lifetime of the signed URLs when SIGNED_URL_TTL_SECOND is not set.
+	DefaultTTL = 5 * time.Minute
+
+	// MaxTTL is the longest lifetime of the signed URLs.
+	MaxTTL = 24 * time.Hour
+)
+
+// Query parameters of the signed URLs.
+const (
+	ParamExpires   = "expires"
+	ParamSignature = "signature"
+	ParamUserID    = "uid"
+	ParamUsername  = "sub"
+	ParamRoles     = "roles"
+	ParamClientID  = "client"
+)
+
+var (
+	// ErrMissingSignature is returned when the URL is not signed.
+	ErrMissingSignature = errors.New("the URL is not signed")
+
+	// ErrInvalidSignature is returned when the signature does not match the path and the query of the URL.
+	ErrInvalidSignature = errors.New("the signature of the URL is invalid")
+
+	// ErrExpired is returned when the URL is used after its expiry.
+	ErrExpired = errors.New("the URL has expired")
+)
+
+// Config holds the settings of the signed URLs. The signed URLs are disabled without a secret.
+type Config struct {
+	Secret []byte
+	TTL    time.Duration
+}
+
+// Enabled reports whether the signed URLs are enabled, that is when a secret is configured.
+func (c Config) Enabled() bool {
+	return len(c.Secret) > 0
+}
+
+// LoadConfig reads the secret from the given secrets provider, and the lifetime of the signed URLs from SIGNED_URL_TTL_SECOND.
+// It returns an error if the secret is shorter than MinSecretLength, or the lifetime is not between 1 second and MaxTTL.
+func LoadConfig(provider secrets.Provider) (Config, error) {
+	cfg := Config{TTL: DefaultTTL}
+	if v := strings.TrimSpace(env.Getenv("SIGNED_URL_TTL_SECOND")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || time.Duration(n)*time.Second > MaxTTL {
+			return Config{}, fmt.Errorf("SIGNED_URL_TTL_SECOND must be a number of seconds between 1 and %d, got %q", int(MaxTTL.Seconds()), v)
+		}
+		cfg.TTL = time.Duration(n) * time.Second
+	}
+
+	secret, err := provider.Get(SecretName)
+	if errors.Is(err, secrets.ErrNotFound) {
+		return cfg, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+	if len(secret) < MinSecretLength {
+		return Config{}, fmt.Errorf("%s must be at least %d bytes long", SecretName, MinSecretLength)
+	}
+	cfg.Secret = []byte(secret)
+
+	return cfg, nil
+}
+
+// Signer signs and verifies the URLs.
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+	clk    clock.Clock
+}
+
+// NewSigner creates a signer with the given settings, using the clock to set and check the expiry of the URLs.
+// It returns nil if the signed URLs are disabled.
+func NewSigner(cfg Config, clk clock.Clock) *Signer {
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	return &Signer{secret: cfg.Secret, ttl: cfg.TTL, clk: clk}
+}
+
+// Sign returns the signed URL of the path with the given query parameters, and the time it expires at.
+// The URL is relative, without the base path of the service.
+func (s *Signer) Sign(path string, params url.Values) (string, time.Time) {
+	expiresAt := s.clk.Now().Add(s.ttl).Truncate(time.Second)
+
+	query := url.Values{}
+	for key, values := range params {
+		query[key] = append([]string(nil), values...)
+	}
+	query.Del(ParamSignature)
+	query.Set(ParamExpires, strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set(ParamSignature, s.signature(path, query))
+
+	return path + "?" + query.Encode(), expiresAt
+}
+
+// Verify checks that the query of the request of the path carries a valid signature, and that the URL has not expired.
+func (s *Signer) Verify(path string, query url.Values) error {
+	signatures := query[ParamSignature]
+	if len(signatures) == 0 {
+		return ErrMissingSignature
+	}
+
+	signed := url.Values{}
+	for key, values := range query {
+		if key != ParamSignature {
+			signed[key] = values
+		}
+	}
+	if len(signatures) > 1 || !hmac.Equal([]byte(signatures[0]), []byte(s.signature(path, signed))) {
+		return ErrInvalidSignature
+	}
+
+	expires, err := strconv.ParseInt(query.Get(ParamExpires), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !s.clk.Now().Before(time.Unix(expires, 0)) {
+		return ErrExpired
+	}
+
+	return nil
+}
+
+// SignForUser returns the signed URL of the path for the user of the given information, like Sign.
+// The user ID, username, roles, and client ID of the user are carried by the URL, see VerifyUser.
+func (s *Signer) SignForUser(path string, meta metacontext.UserInformationMeta) (string, time.Time) {
+	params := url.Values{}
+	params.Set(ParamUserID, strconv.FormatInt(meta.UserID, 10))
+	params.Set(ParamUsername, meta.Username)
+	params.Set(ParamRoles, strings.Join(meta.Roles, ","))
+	if meta.ClientID != "" {
+		params.Set(ParamClientID, meta.ClientID)
+	}
+
+	return s.Sign(path, params)
+}
+
+// VerifyUser verifies the URL like Verify, and returns the information of the user it was signed for by SignForUser.
+func (s *Signer) VerifyUser(path string, query url.Values) (metacontext.UserInformationMeta, error) {
+	if err := s.Verify(path, query); err != nil {
+		return metacontext.UserInformationMeta{}, err
+	}
+
+	userID, err := strconv.ParseInt(query.Get(ParamUserID), 10, 64)
+	if err != nil {
+		return metacontext.UserInformationMeta{}, ErrInvalidSignature
+	}
+
+	meta := metacontext.UserInformationMeta{
+		UserID:   userID,
+		Username: query.Get(ParamUsername),
+		ClientID: query.Get(ParamClientID),
+	}
+	if roles := query.Get(ParamRoles); roles != "" {
+		meta.Roles = strings.Split(roles, ",")
+	}
+
+	return meta, nil
+}
+
+// signature returns the base64url-encoded HMAC-SHA256 of the path and the query, whose parameters are sorted by key.
+func (s *Signer) signature(path string, query url.Values) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(path + "?" + query.Encode()))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
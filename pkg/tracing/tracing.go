@@ -0,0 +1,167 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+)
+
+/**
+ * tracing package traces the requests with OpenTelemetry: each request produces a trace whose spans cover
+ * the middleware (e.g. the JWT validation), the services, and the SQL queries, exported with OTLP over HTTP
+ * to a collector (e.g. the OpenTelemetry Collector, Jaeger, or Tempo).
+ * The tracing is disabled unless TRACING_ENABLED is TRUE, the spans are then no-ops. The W3C trace context
+ * of the incoming requests is continued, so the traces of the gateways and the callers include the spans of the service.
+ */
+
+// Default settings of the tracing, when the environment variables are missing or invalid.
+const (
+	// DefaultEndpoint is the OTLP/HTTP endpoint of the collector the spans are exported to.
+	DefaultEndpoint = "http://localhost:4318"
+
+	// DefaultServiceName is the name of the service on the spans.
+	DefaultServiceName = "go-jwt-auth-demo"
+
+	// DefaultSampleRatio is the share of the traces recorded, the traces started by a caller follow its decision.
+	DefaultSampleRatio = 1.0
+)
+
+// instrumentationName is the name of the tracer of the application.
+const instrumentationName = "github.com/yoanesber/go-jwt-auth-demo"
+
+// Config holds the settings of the tracing.
+type Config struct {
+	Enabled     bool
+	Endpoint    string
+	ServiceName string
+	SampleRatio float64
+}
+
+// Enabled reports whether the requests are traced. It is disabled unless TRACING_ENABLED is TRUE.
+func Enabled() bool {
+	return strings.EqualFold(env.Getenv("TRACING_ENABLED"), "TRUE")
+}
+
+// LoadConfig loads the tracing configuration from environment variables: TRACING_ENABLED,
+// OTEL_EXPORTER_OTLP_ENDPOINT, the base URL of the collector, OTEL_SERVICE_NAME, and TRACING_SAMPLE_RATIO, between 0 and 1.
+func LoadConfig() Config {
+	cfg := Config{
+		Enabled:     Enabled(),
+		Endpoint:    strings.TrimSpace(env.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")),
+		ServiceName: strings.TrimSpace(env.Getenv("OTEL_SERVICE_NAME")),
+		SampleRatio: DefaultSampleRatio,
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = DefaultEndpoint
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = DefaultServiceName
+	}
+	if v := strings.TrimSpace(env.Getenv("TRACING_SAMPLE_RATIO")); v != "" {
+		if ratio, err := strconv.ParseFloat(v, 64); err == nil {
+			cfg.SampleRatio = ratio
+		} else {
+			cfg.SampleRatio = -1
+		}
+	}
+
+	return cfg
+}
+
+// Validate returns an error if the endpoint is not an absolute http(s) URL or the sample ratio is not between 0 and 1.
+func (c Config) Validate() error {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT must be the absolute http(s) URL of the collector, e.g. %s", DefaultEndpoint)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("TRACING_SAMPLE_RATIO must be a number between 0 and 1")
+	}
+
+	return nil
+}
+
+// Init sets up the tracer provider exporting the spans to the collector of the configuration, if the tracing is enabled,
+// and the propagation of the W3C trace context. It returns the function flushing the pending spans and stopping the export,
+// to be called on shutdown. The spans are exported in batches, a collector that cannot be reached does not fail the requests.
+func Init(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(strings.TrimSuffix(cfg.Endpoint, "/")+"/v1/traces"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the service of the spans: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of the application, from the global tracer provider set by Init.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts a span with the given name and attributes, child of the span of the context, if any.
+// The returned context carries the span, it is ended with End.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends the span, recording the error, if any. The cancellations of the callers are not recorded as errors.
+func End(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, context.Canceled) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Run runs the function in a span with the given name, ended with the error of the function.
+func Run(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	ctx, span := Start(ctx, name)
+	err := fn(ctx)
+	End(span, err)
+
+	return err
+}
+
+// RunValue runs the function in a span with the given name, like Run, and returns its result.
+func RunValue[T any](ctx context.Context, name string, fn func(ctx context.Context) (T, error)) (T, error) {
+	ctx, span := Start(ctx, name)
+	v, err := fn(ctx)
+	End(span, err)
+
+	return v, err
+}
@@ -0,0 +1,79 @@
+package binding_util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+/**
+ * binding_util package binds the JSON bodies of the requests strictly: unlike ShouldBindJSON,
+ * the fields unknown to the payload are rejected instead of being silently dropped, so that a typo
+ * such as "phonee" fails loudly. The errors report the offending field, see FormatBindingErrors.
+ */
+
+// unknownFieldPrefix is the prefix of the errors of the JSON decoder for the unknown fields.
+const unknownFieldPrefix = "json: unknown field "
+
+// UnknownFieldError is returned when the JSON body holds a field unknown to the payload.
+type UnknownFieldError struct {
+	Field string
+}
+
+// Error returns the name of the unknown field.
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field %q", e.Field)
+}
+
+// BindJSONStrict decodes the JSON body of the request into obj like ShouldBindJSON, and rejects the unknown fields
+// with an UnknownFieldError. The payload is then validated by the validator of gin, as with ShouldBindJSON.
+func BindJSONStrict(c *gin.Context, obj interface{}) error {
+	if c.Request == nil || c.Request.Body == nil {
+		return errors.New("invalid request")
+	}
+
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		if field, ok := strings.CutPrefix(err.Error(), unknownFieldPrefix); ok {
+			if unquoted, uerr := strconv.Unquote(field); uerr == nil {
+				field = unquoted
+			}
+			return &UnknownFieldError{Field: field}
+		}
+		return err
+	}
+
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// FormatBindingErrors formats the error of BindJSONStrict into a slice of maps, like the validation errors,
+// when it is caused by a field of the payload: an unknown field, or a value of the wrong type.
+// It returns nil for the other errors, e.g. a malformed body.
+func FormatBindingErrors(err error) []map[string]string {
+	var unknown *UnknownFieldError
+	if errors.As(err, &unknown) {
+		return []map[string]string{{
+			"field":   unknown.Field,
+			"message": fmt.Sprintf("%s is not a known field", unknown.Field),
+		}}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []map[string]string{{
+			"field":   typeErr.Field,
+			"message": fmt.Sprintf("%s must be of type %s", typeErr.Field, typeErr.Type.String()),
+		}}
+	}
+
+	return nil
+}
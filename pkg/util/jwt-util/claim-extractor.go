@@ -20,6 +20,18 @@ func GetInt64Claim(claims jwt.MapClaims, key string) (int64, error) {
 
 // GetStringClaim retrieves a string claim from the JWT claims.
 // It checks if the claim exists and is of type string.
+func GetStringClaim(claims jwt.MapClaims, key string) (string, error) {
+	if val, ok := claims[key]; ok {
+		if str, ok := val.(string); ok {
+			return str, nil
+		}
+		return "", fmt.Errorf("claim %s is not a string", key)
+	}
+	return "", fmt.Errorf("claim %s not found", key)
+}
+
+// GetStringSliceClaim retrieves a string slice claim from the JWT claims.
+// It checks if the claim exists and is a slice, skipping the elements that are not strings.
 func GetStringSliceClaim(claims jwt.MapClaims, key string) []string {
 	if val, ok := claims[key]; ok {
 		if slice, ok := val.([]interface{}); ok {
@@ -0,0 +1,88 @@
+package jwt_util
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrUnknownKeyID is returned when a token is signed with a key that is not published, e.g. a key retired for too long.
+var ErrUnknownKeyID = errors.New("unknown key ID")
+
+// JSONWebKey is an RSA public key in the JSON Web Key format (RFC 7517).
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JSONWebKeySet is the set of public keys published to the services validating the tokens.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// NewJSONWebKey returns the JSON Web Key of an RSA public key verifying the RS256 signatures.
+func NewJSONWebKey(publicKey *rsa.PublicKey) JSONWebKey {
+	return JSONWebKey{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: jwt.SigningMethodRS256.Alg(),
+		Kid: KeyID(publicKey),
+		N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+	}
+}
+
+// KeyID returns the identifier of an RSA public key, set as the kid header of the tokens it verifies.
+// It is the JWK thumbprint of the key (RFC 7638), so it changes whenever the key is replaced.
+func KeyID(publicKey *rsa.PublicKey) string {
+	// The members are required to be in lexicographic order, which is the order of the fields of the struct
+	thumbprint, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+	})
+
+	sum := sha256.Sum256(thumbprint)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// LoadJSONWebKeySet returns the public keys verifying the tokens at the given time, see KeySet.
+func (f KeyFiles) LoadJSONWebKeySet(maxTokenTTL time.Duration, now time.Time) (JSONWebKeySet, error) {
+	keySet, err := f.LoadKeySet()
+	if err != nil {
+		return JSONWebKeySet{}, err
+	}
+
+	return keySet.Active(maxTokenTTL, now).JSONWebKeySet(), nil
+}
+
+// LoadPublicKeyByID returns the public key with the given kid, if it verifies the tokens at the given time.
+// The tokens issued without a kid, before the keys were identified, are verified with the current public key.
+func (f KeyFiles) LoadPublicKeyByID(kid string, maxTokenTTL time.Duration, now time.Time) (*rsa.PublicKey, error) {
+	keySet, err := f.LoadKeySet()
+	if err != nil {
+		return nil, err
+	}
+
+	return keySet.Active(maxTokenTTL, now).PublicKey(kid)
+}
+
+// TokenKeyID returns the kid header of the token, or an empty string if it has none.
+func TokenKeyID(token *jwt.Token) string {
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
@@ -1,39 +1,178 @@
 package jwt_util
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// LoadPublicKey loads the public key from the specified path in the environment variable.
+// cachedKey holds a parsed key and the modification time and size of its file when it was parsed.
+type cachedKey struct {
+	modTime time.Time
+	size    int64
+	key     interface{}
+}
+
+// keyCache holds the parsed keys by kind and path, so that they are not read and parsed on every request.
+// A key is parsed again when its file changes, so the keys can be replaced without restarting the application.
+var (
+	keyCacheMu sync.RWMutex
+	keyCache   = make(map[string]cachedKey)
+)
+
+// KeyFiles holds the paths of the key files of the tokens signed with a key pair, read from the settings when the application is wired:
+// the private key signing the tokens and the public key verifying them, or the directory of the key set, see KeySet.
+type KeyFiles struct {
+	PublicKeyPath  string
+	PrivateKeyPath string
+	KeySetDir      string
+}
+
+// LoadPublicKey loads the RSA public key from the path in JWT_PUBLIC_KEY_PATH.
 // It returns the parsed RSA public key or an error if the file cannot be read or parsed.
-func LoadPublicKey() (*rsa.PublicKey, error) {
-	jwtPublicKeyPath := os.Getenv("JWT_PUBLIC_KEY_PATH")
-	if jwtPublicKeyPath == "" {
-		return nil, fmt.Errorf("JWT_PUBLIC_KEY_PATH environment variable is not set")
+func (f KeyFiles) LoadPublicKey() (*rsa.PublicKey, error) {
+	if f.PublicKeyPath == "" {
+		return nil, fmt.Errorf("JWT_PUBLIC_KEY_PATH is not set")
 	}
 
-	keyData, err := os.ReadFile(jwtPublicKeyPath)
+	key, err := loadKey("public", f.PublicKeyPath, func(data []byte) (interface{}, error) {
+		return jwt.ParseRSAPublicKeyFromPEM(data)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return jwt.ParseRSAPublicKeyFromPEM(keyData)
+	return key.(*rsa.PublicKey), nil
 }
 
-// LoadPrivateKey loads the private key from the specified path in the environment variable.
+// LoadPrivateKey loads the RSA private key from the path in JWT_PRIVATE_KEY_PATH.
 // It returns the parsed RSA private key or an error if the file cannot be read or parsed.
-func LoadPrivateKey() (*rsa.PrivateKey, error) {
-	jwtPrivateKeyPath := os.Getenv("JWT_PRIVATE_KEY_PATH")
-	if jwtPrivateKeyPath == "" {
-		return nil, fmt.Errorf("JWT_PRIVATE_KEY_PATH environment variable is not set")
+func (f KeyFiles) LoadPrivateKey() (*rsa.PrivateKey, error) {
+	if f.PrivateKeyPath == "" {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY_PATH is not set")
+	}
+
+	key, err := loadKey("private", f.PrivateKeyPath, func(data []byte) (interface{}, error) {
+		return jwt.ParseRSAPrivateKeyFromPEM(data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key.(*rsa.PrivateKey), nil
+}
+
+// LoadECPublicKey loads the ECDSA public key verifying the ES256 tokens from the path in JWT_PUBLIC_KEY_PATH.
+// It returns an error if the file cannot be read or parsed, or if the key is not on the P-256 curve required by ES256.
+func (f KeyFiles) LoadECPublicKey() (*ecdsa.PublicKey, error) {
+	if f.PublicKeyPath == "" {
+		return nil, fmt.Errorf("JWT_PUBLIC_KEY_PATH is not set")
+	}
+
+	key, err := loadKey("ec-public", f.PublicKeyPath, func(data []byte) (interface{}, error) {
+		publicKey, err := jwt.ParseECPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return publicKey, checkP256(publicKey.Curve)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key.(*ecdsa.PublicKey), nil
+}
+
+// LoadECPrivateKey loads the ECDSA private key signing the ES256 tokens from the path in JWT_PRIVATE_KEY_PATH.
+// It returns an error if the file cannot be read or parsed, or if the key is not on the P-256 curve required by ES256.
+func (f KeyFiles) LoadECPrivateKey() (*ecdsa.PrivateKey, error) {
+	if f.PrivateKeyPath == "" {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY_PATH is not set")
+	}
+
+	key, err := loadKey("ec-private", f.PrivateKeyPath, func(data []byte) (interface{}, error) {
+		privateKey, err := jwt.ParseECPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return privateKey, checkP256(privateKey.Curve)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key.(*ecdsa.PrivateKey), nil
+}
+
+// LoadEdPublicKey loads the Ed25519 public key verifying the EdDSA tokens from the path in JWT_PUBLIC_KEY_PATH.
+// It returns an error if the file cannot be read or parsed.
+func (f KeyFiles) LoadEdPublicKey() (ed25519.PublicKey, error) {
+	if f.PublicKeyPath == "" {
+		return nil, fmt.Errorf("JWT_PUBLIC_KEY_PATH is not set")
 	}
 
-	keyData, err := os.ReadFile(jwtPrivateKeyPath)
+	key, err := loadKey("ed-public", f.PublicKeyPath, func(data []byte) (interface{}, error) {
+		return jwt.ParseEdPublicKeyFromPEM(data)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return jwt.ParseRSAPrivateKeyFromPEM(keyData)
+	return key.(ed25519.PublicKey), nil
+}
+
+// LoadEdPrivateKey loads the Ed25519 private key signing the EdDSA tokens from the path in JWT_PRIVATE_KEY_PATH.
+// It returns an error if the file cannot be read or parsed.
+func (f KeyFiles) LoadEdPrivateKey() (ed25519.PrivateKey, error) {
+	if f.PrivateKeyPath == "" {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY_PATH is not set")
+	}
+
+	key, err := loadKey("ed-private", f.PrivateKeyPath, func(data []byte) (interface{}, error) {
+		return jwt.ParseEdPrivateKeyFromPEM(data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key.(ed25519.PrivateKey), nil
+}
+
+// checkP256 returns an error if the curve is not P-256, the only curve of the ES256 signatures.
+func checkP256(curve elliptic.Curve) error {
+	if curve != elliptic.P256() {
+		return fmt.Errorf("the ECDSA key must be on the P-256 curve, got %s", curve.Params().Name)
+	}
+	return nil
+}
+
+// loadKey returns the cached key of the given kind of the file at the given path, parsing it again if the file changed since it was cached.
+func loadKey(kind string, path string, parse func([]byte) (interface{}, error)) (interface{}, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keyCacheMu.RLock()
+	cached, ok := keyCache[kind+":"+path]
+	keyCacheMu.RUnlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.key, nil
+	}
+
+	keyData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parse(keyData)
+	if err != nil {
+		return nil, err
+	}
+
+	keyCacheMu.Lock()
+	keyCache[kind+":"+path] = cachedKey{modTime: info.ModTime(), size: info.Size(), key: key}
+	keyCacheMu.Unlock()
+
+	return key, nil
 }
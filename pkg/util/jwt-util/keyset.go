@@ -0,0 +1,292 @@
+package jwt_util
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+/**
+ * The key set holds the RSA key signing the new tokens and the previous keys, still verifying the tokens they signed.
+ * With JWT_KEYSET_DIR, the keys are the RSA private keys of the directory, named key-<unix time>.pem after their creation.
+ * The newest key signs the tokens; a previous key is retired when the next one is created, and verifies the tokens
+ * until the longest lifetime of the access tokens has elapsed since, when none of its tokens can still be valid.
+ * The directory is read again when it changes, so the keys can be rotated without restarting the application,
+ * and the instances sharing the directory pick up the new key on their own.
+ *
+ * Without JWT_KEYSET_DIR, the key set is the single key pair of JWT_PRIVATE_KEY_PATH and JWT_PUBLIC_KEY_PATH,
+ * which cannot be rotated by the application.
+ */
+
+// DefaultRSAKeyBits is the size of the RSA keys created by a rotation.
+const DefaultRSAKeyBits = 2048
+
+var (
+	// ErrKeyRotationDisabled is returned when a key is rotated without JWT_KEYSET_DIR.
+	ErrKeyRotationDisabled = errors.New("key rotation requires JWT_KEYSET_DIR")
+
+	// ErrNoSigningKey is returned when the key set directory does not contain any key.
+	ErrNoSigningKey = errors.New("no signing key in JWT_KEYSET_DIR")
+)
+
+// keyFileName matches the names of the key files of the key set directory.
+var keyFileName = regexp.MustCompile(`^key-(\d+)\.pem$`)
+
+// SigningKey is a key of the key set, identified by its kid.
+// The private key is only known for the keys of the key set directory, the single public key cannot sign.
+type SigningKey struct {
+	ID         string
+	PrivateKey *rsa.PrivateKey
+	PublicKey  *rsa.PublicKey
+	CreatedAt  time.Time
+	RetiredAt  time.Time
+	path       string
+}
+
+// KeySet holds the current key, signing the new tokens, and the previous keys, ordered from the newest to the oldest.
+type KeySet struct {
+	Current  SigningKey
+	Previous []SigningKey
+}
+
+// keySetCache holds the keys of the key set directory and the modification time of the directory when it was read.
+var (
+	keySetMu    sync.Mutex
+	keySetCache struct {
+		dir     string
+		modTime time.Time
+		keys    []SigningKey
+	}
+)
+
+// LoadKeySet returns all the keys of the key set, including the previous keys that no longer verify any valid token.
+func (f KeyFiles) LoadKeySet() (KeySet, error) {
+	dir := f.KeySetDir
+	if dir == "" {
+		publicKey, err := f.LoadPublicKey()
+		if err != nil {
+			return KeySet{}, err
+		}
+
+		return KeySet{Current: SigningKey{ID: KeyID(publicKey), PublicKey: publicKey}}, nil
+	}
+
+	keys, err := readKeySetDir(dir)
+	if err != nil {
+		return KeySet{}, err
+	}
+	if len(keys) == 0 {
+		return KeySet{}, ErrNoSigningKey
+	}
+
+	return KeySet{Current: keys[0], Previous: keys[1:]}, nil
+}
+
+// LoadSigningKey returns the current key of the key set, with its private key.
+func (f KeyFiles) LoadSigningKey() (SigningKey, error) {
+	if f.KeySetDir == "" {
+		privateKey, err := f.LoadPrivateKey()
+		if err != nil {
+			return SigningKey{}, err
+		}
+
+		return SigningKey{ID: KeyID(&privateKey.PublicKey), PrivateKey: privateKey, PublicKey: &privateKey.PublicKey}, nil
+	}
+
+	keySet, err := f.LoadKeySet()
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	return keySet.Current, nil
+}
+
+// RotateKey creates a new key in the key set directory, which signs the new tokens from now on.
+// The current key is retired and keeps verifying its tokens for maxTokenTTL, the keys retired for longer are deleted.
+func (f KeyFiles) RotateKey(maxTokenTTL time.Duration, now time.Time) (SigningKey, error) {
+	dir := f.KeySetDir
+	if dir == "" {
+		return SigningKey{}, ErrKeyRotationDisabled
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, DefaultRSAKeyBits)
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("failed to generate RSA key: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return SigningKey{}, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	// The key is written to a temporary file first, so that it is never read half written
+	path := filepath.Join(dir, fmt.Sprintf("key-%d.pem", now.Unix()))
+	if _, err := os.Stat(path); err == nil {
+		return SigningKey{}, fmt.Errorf("a key was already created at %s", now.UTC().Format(time.RFC3339))
+	}
+
+	tmp, err := os.CreateTemp(dir, ".key-*.tmp")
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("failed to write the key: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	err = pem.Encode(tmp, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("failed to write the key: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return SigningKey{}, fmt.Errorf("failed to write the key: %w", err)
+	}
+	invalidateKeySetCache()
+
+	keySet, err := f.LoadKeySet()
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	// Delete the keys that no longer verify any valid token
+	active := keySet.Active(maxTokenTTL, now)
+	for _, key := range keySet.Previous {
+		if _, ok := active.Key(key.ID); !ok {
+			if err := os.Remove(key.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return SigningKey{}, fmt.Errorf("failed to delete the retired key %s: %w", key.ID, err)
+			}
+		}
+	}
+	invalidateKeySetCache()
+
+	return keySet.Current, nil
+}
+
+// Active returns the key set without the previous keys retired for longer than maxTokenTTL at the given time.
+func (s KeySet) Active(maxTokenTTL time.Duration, now time.Time) KeySet {
+	active := KeySet{Current: s.Current}
+	for _, key := range s.Previous {
+		if now.Before(key.RetiredAt.Add(maxTokenTTL)) {
+			active.Previous = append(active.Previous, key)
+		}
+	}
+
+	return active
+}
+
+// Key returns the key with the given kid.
+// The tokens issued without a kid, before the keys were identified, are verified with the current key.
+func (s KeySet) Key(kid string) (SigningKey, bool) {
+	if kid == "" || kid == s.Current.ID {
+		return s.Current, true
+	}
+
+	for _, key := range s.Previous {
+		if key.ID == kid {
+			return key, true
+		}
+	}
+
+	return SigningKey{}, false
+}
+
+// PublicKey returns the public key with the given kid, or ErrUnknownKeyID if the key set does not contain it.
+func (s KeySet) PublicKey(kid string) (*rsa.PublicKey, error) {
+	key, ok := s.Key(kid)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyID, kid)
+	}
+
+	return key.PublicKey, nil
+}
+
+// JSONWebKeySet returns the public keys of the key set in the JSON Web Key Set format, the current key first.
+func (s KeySet) JSONWebKeySet() JSONWebKeySet {
+	keySet := JSONWebKeySet{Keys: []JSONWebKey{NewJSONWebKey(s.Current.PublicKey)}}
+	for _, key := range s.Previous {
+		keySet.Keys = append(keySet.Keys, NewJSONWebKey(key.PublicKey))
+	}
+
+	return keySet
+}
+
+// invalidateKeySetCache drops the cached keys, so that the directory is read again even if its modification time
+// did not change, e.g. on the file systems with a coarse time resolution.
+func invalidateKeySetCache() {
+	keySetMu.Lock()
+	keySetCache.dir = ""
+	keySetMu.Unlock()
+}
+
+// readKeySetDir returns the keys of the directory from the newest to the oldest, read again if the directory changed.
+func readKeySetDir(dir string) ([]SigningKey, error) {
+	info, err := os.Stat(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keySetMu.Lock()
+	defer keySetMu.Unlock()
+
+	if keySetCache.dir == dir && keySetCache.modTime.Equal(info.ModTime()) {
+		return keySetCache.keys, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []SigningKey
+	for _, entry := range entries {
+		match := keyFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		created, _ := strconv.ParseInt(match[1], 10, 64)
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s does not contain a valid RSA private key: %w", path, err)
+		}
+
+		keys = append(keys, SigningKey{
+			ID:         KeyID(&privateKey.PublicKey),
+			PrivateKey: privateKey,
+			PublicKey:  &privateKey.PublicKey,
+			CreatedAt:  time.Unix(created, 0),
+			path:       path,
+		})
+	}
+
+	// A key is retired when the next one is created
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	for i := 1; i < len(keys); i++ {
+		keys[i].RetiredAt = keys[i-1].CreatedAt
+	}
+
+	keySetCache.dir = dir
+	keySetCache.modTime = info.ModTime()
+	keySetCache.keys = keys
+
+	return keys, nil
+}
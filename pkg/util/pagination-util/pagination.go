@@ -0,0 +1,190 @@
+package pagination_util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+)
+
+/**
+ * pagination_util package parses the page and limit query parameters of the list endpoints.
+ * The maximum page size is configured with PAGINATION_MAX_LIMIT (default 100), so that clients
+ * cannot pull entire tables with a huge limit. PAGINATION_LIMIT_MODE selects what happens
+ * when the limit exceeds the maximum: REJECT (default) fails with an error, CLAMP lowers it to the maximum.
+ *
+ * The package also caps the rows of the exports (EXPORT_MAX_ROWS, e.g. the consumer stream), unlimited by default.
+ * PAGINATION_LIMITS_BY_ROLE overrides both caps per role, e.g. "ROLE_USER=20/1000", so that the clients with ROLE_USER cannot run the heavy queries reserved for the administrators.
+ * A caller with several roles gets the highest cap of its roles, the roles without override having the default caps.
+ */
+
+const (
+	DefaultPage     = 1
+	DefaultLimit    = 10
+	DefaultMaxLimit = 100
+
+	ModeReject = "REJECT"
+	ModeClamp  = "CLAMP"
+)
+
+// Config holds the pagination settings, and the cap of the exports, 0 meaning unlimited.
+type Config struct {
+	MaxLimit      int
+	Mode          string
+	MaxExportRows int
+
+	// Roles overrides the caps per role
+	Roles map[string]Limits
+}
+
+// Limits holds the caps of a role: the maximum page size, and the maximum rows of an export, 0 meaning unlimited.
+type Limits struct {
+	MaxLimit      int
+	MaxExportRows int
+}
+
+// Params holds the parsed page and limit.
+type Params struct {
+	Page  int
+	Limit int
+}
+
+// Error is returned when the page or limit is invalid.
+// Message and Detail are meant to be returned to the client as is.
+type Error struct {
+	Message string
+	Detail  string
+}
+
+// Error returns the detail of the error.
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+// LoadConfig loads the pagination settings from the environment, once at startup; the handlers are given the settings.
+// Missing or invalid values fall back to the defaults.
+func LoadConfig() Config {
+	cfg := Config{MaxLimit: DefaultMaxLimit, Mode: ModeReject}
+
+	if n, err := strconv.Atoi(env.Getenv("PAGINATION_MAX_LIMIT")); err == nil && n > 0 {
+		cfg.MaxLimit = n
+	}
+	if strings.EqualFold(env.Getenv("PAGINATION_LIMIT_MODE"), ModeClamp) {
+		cfg.Mode = ModeClamp
+	}
+	if n, err := strconv.Atoi(env.Getenv("EXPORT_MAX_ROWS")); err == nil && n > 0 {
+		cfg.MaxExportRows = n
+	}
+	if roles, err := ParseRoleLimits(env.Getenv("PAGINATION_LIMITS_BY_ROLE")); err == nil && len(roles) > 0 {
+		cfg.Roles = roles
+	}
+
+	return cfg
+}
+
+// ParseRoleLimits parses a comma separated list of role=page/export caps, e.g. "ROLE_USER=20/1000".
+// It returns an error if a pair is malformed, its page size is not a positive integer, or its export cap is negative.
+func ParseRoleLimits(value string) (map[string]Limits, error) {
+	roles := make(map[string]Limits)
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		role, caps, ok := strings.Cut(pair, "=")
+		role = strings.TrimSpace(role)
+		values := strings.Split(caps, "/")
+		if !ok || role == "" || len(values) != 2 {
+			return nil, fmt.Errorf("invalid role limits %q, expected role=page/export", pair)
+		}
+
+		var n [2]int
+		for i, v := range values {
+			var err error
+			if n[i], err = strconv.Atoi(strings.TrimSpace(v)); err != nil || n[i] < 0 || (i == 0 && n[i] == 0) {
+				return nil, fmt.Errorf("invalid role limits %q, the page size must be a positive integer and the export cap a non-negative integer", pair)
+			}
+		}
+		roles[role] = Limits{MaxLimit: n[0], MaxExportRows: n[1]}
+	}
+
+	return roles, nil
+}
+
+// ForRoles returns the caps of a caller with the given roles: the highest cap of its roles,
+// the roles without override, and the anonymous callers, having the default caps.
+func (cfg Config) ForRoles(roles []string) Limits {
+	defaults := Limits{MaxLimit: cfg.MaxLimit, MaxExportRows: cfg.MaxExportRows}
+	if len(cfg.Roles) == 0 || len(roles) == 0 {
+		return defaults
+	}
+
+	var limits Limits
+	for i, role := range roles {
+		l, ok := cfg.Roles[role]
+		if !ok {
+			l = defaults
+		}
+		if i == 0 {
+			limits = l
+			continue
+		}
+
+		limits.MaxLimit = max(limits.MaxLimit, l.MaxLimit)
+		limits.MaxExportRows = maxCap(limits.MaxExportRows, l.MaxExportRows)
+	}
+
+	return limits
+}
+
+// maxCap returns the highest of two caps, 0 meaning unlimited.
+func maxCap(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return max(a, b)
+}
+
+// callerLimits returns the caps of the caller of the request, by the roles of its access token.
+func callerLimits(c *gin.Context, cfg Config) Limits {
+	meta, _ := metacontext.ExtractUserInformationMeta(c.Request.Context())
+	return cfg.ForRoles(meta.Roles)
+}
+
+// Parse parses the page and limit query parameters of the request with the given settings.
+// The page defaults to 1 and the limit to 10 (or the maximum, if lower), the maximum being the one of the roles of the caller.
+func Parse(c *gin.Context, cfg Config) (Params, *Error) {
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = DefaultMaxLimit
+	}
+	cfg.MaxLimit = callerLimits(c, cfg).MaxLimit
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", strconv.Itoa(DefaultPage)))
+	if err != nil || page < 1 {
+		return Params{}, &Error{Message: "Invalid page number", Detail: "Page must be a positive integer"}
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(min(DefaultLimit, cfg.MaxLimit))))
+	if err != nil || limit < 1 {
+		return Params{}, &Error{Message: "Invalid limit", Detail: "Limit must be a positive integer"}
+	}
+
+	if limit > cfg.MaxLimit {
+		if cfg.Mode != ModeClamp {
+			return Params{}, &Error{Message: "Invalid limit", Detail: fmt.Sprintf("Limit must not exceed %d", cfg.MaxLimit)}
+		}
+		limit = cfg.MaxLimit
+	}
+
+	return Params{Page: page, Limit: limit}, nil
+}
+
+// ExportLimit returns the maximum number of rows of an export for the caller of the request, with the given settings.
+// It returns 0 if the exports of the caller are unlimited.
+func ExportLimit(c *gin.Context, cfg Config) int {
+	return callerLimits(c, cfg).MaxExportRows
+}
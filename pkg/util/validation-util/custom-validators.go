@@ -0,0 +1,119 @@
+package validation_util
+
+import (
+	"regexp"
+	"time"
+	"unicode"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+)
+
+var (
+	// e164Regex matches phone numbers in E.164 format, with or without the leading '+'
+	e164Regex = regexp.MustCompile(`^\+?[1-9]\d{7,14}$`)
+
+	// roleNameRegex matches role names such as ROLE_ADMIN or ROLE_BILLING_2
+	roleNameRegex = regexp.MustCompile(`^ROLE_[A-Z0-9]+(_[A-Z0-9]+)*$`)
+
+	// headerNameRegex matches HTTP header names such as X-Request-Id (the token characters of RFC 9110)
+	headerNameRegex = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+	// consumerStatuses lists the allowed values of the consumer status
+	consumerStatuses = map[string]bool{
+		"active":    true,
+		"inactive":  true,
+		"suspended": true,
+	}
+)
+
+const (
+	// Minimum length of a password accepted by the password complexity rule
+	minPasswordLength = 8
+)
+
+// registerCustomValidations registers the custom validation tags on the given validator.
+// The dates are checked against the current time of the given clock.
+func registerCustomValidations(v *validator.Validate, clk clock.Clock) error {
+	validations := map[string]validator.Func{
+		"e164":                validateE164,
+		"consumer_status":     validateConsumerStatus,
+		"notfuture":           notFutureValidator(clk),
+		"password_complexity": validatePasswordComplexity,
+		"role_name":           validateRoleName,
+		"header_name":         validateHeaderName,
+	}
+
+	for tag, fn := range validations {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateE164 checks that the field is a phone number in E.164 format.
+func validateE164(fl validator.FieldLevel) bool {
+	return e164Regex.MatchString(fl.Field().String())
+}
+
+// validateConsumerStatus checks that the field is one of the allowed consumer statuses.
+func validateConsumerStatus(fl validator.FieldLevel) bool {
+	return consumerStatuses[fl.Field().String()]
+}
+
+// validateRoleName checks that the field is a role name: ROLE_ followed by uppercase letters and digits separated by underscores.
+func validateRoleName(fl validator.FieldLevel) bool {
+	return roleNameRegex.MatchString(fl.Field().String())
+}
+
+// validateHeaderName checks that the field is a valid HTTP header name.
+func validateHeaderName(fl validator.FieldLevel) bool {
+	return headerNameRegex.MatchString(fl.Field().String())
+}
+
+// notFutureValidator returns the validation checking that the date field is not after the current time of the clock.
+// It supports both time.Time and customtype.Date fields.
+func notFutureValidator(clk clock.Clock) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		var t time.Time
+		switch v := fl.Field().Interface().(type) {
+		case time.Time:
+			t = v
+		case customtype.Date:
+			t = v.Time
+		default:
+			return false
+		}
+
+		return !t.After(clk.Now())
+	}
+}
+
+// validatePasswordComplexity checks that the password contains at least one uppercase letter,
+// one lowercase letter, one digit, and one special character.
+func validatePasswordComplexity(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+	if len(password) < minPasswordLength {
+		return false
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSpecial = true
+		}
+	}
+
+	return hasUpper && hasLower && hasDigit && hasSpecial
+}
@@ -2,6 +2,8 @@ package validation_util
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 
 	"gopkg.in/go-playground/validator.v9"
 )
@@ -18,12 +20,34 @@ func FormatValidationErrors(err error) []map[string]string {
 			switch fe.Tag() {
 			case "required":
 				message = fmt.Sprintf("%s is required", fe.Field())
+			case "required_without_all":
+				message = fmt.Sprintf("%s is required when none of the other fields is given", fe.Field())
 			case "email":
 				message = fmt.Sprintf("%s must be a valid email address", fe.Field())
 			case "min":
-				message = fmt.Sprintf("%s must be at least %s characters", fe.Field(), fe.Param())
+				message = fmt.Sprintf("%s must be at least %s %s", fe.Field(), fe.Param(), lengthUnit(fe))
 			case "max":
-				message = fmt.Sprintf("%s must be at most %s characters", fe.Field(), fe.Param())
+				message = fmt.Sprintf("%s must be at most %s %s", fe.Field(), fe.Param(), lengthUnit(fe))
+			case "len":
+				message = fmt.Sprintf("%s must be exactly %s characters", fe.Field(), fe.Param())
+			case "numeric":
+				message = fmt.Sprintf("%s must contain only digits", fe.Field())
+			case "e164":
+				message = fmt.Sprintf("%s must be a valid phone number in E.164 format", fe.Field())
+			case "oneof":
+				message = fmt.Sprintf("%s must be one of: %s", fe.Field(), strings.Join(strings.Fields(fe.Param()), ", "))
+			case "consumer_status":
+				message = fmt.Sprintf("%s must be one of: active, inactive, suspended", fe.Field())
+			case "notfuture":
+				message = fmt.Sprintf("%s must not be in the future", fe.Field())
+			case "nefield":
+				message = fmt.Sprintf("%s must be different from %s", fe.Field(), lowerFirst(fe.Param()))
+			case "password_complexity":
+				message = fmt.Sprintf("%s must contain uppercase and lowercase letters, a digit, and a special character", fe.Field())
+			case "role_name":
+				message = fmt.Sprintf("%s must start with ROLE_ followed by uppercase letters, digits, and underscores", fe.Field())
+			case "header_name":
+				message = fmt.Sprintf("%s must be a valid HTTP header name", fe.Field())
 			default:
 				message = fmt.Sprintf("%s is not valid", fe.Field())
 			}
@@ -37,3 +61,19 @@ func FormatValidationErrors(err error) []map[string]string {
 
 	return errors
 }
+
+// lengthUnit returns what the length of the field counts, the items of the lists or the characters of the strings.
+func lengthUnit(fe validator.FieldError) string {
+	if fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map {
+		return "items"
+	}
+	return "characters"
+}
+
+// lowerFirst returns the name of a struct field with its first letter in lower case, like the JSON names of the payloads.
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
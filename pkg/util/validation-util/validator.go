@@ -6,6 +6,9 @@ import (
 	"sync"
 
 	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
 )
 
 var (
@@ -13,8 +16,13 @@ var (
 	validate *validator.Validate
 )
 
-// Init initializes the validator and registers custom validations.
+// Init initializes the validator and registers custom validations, checking the dates against the system time.
 func Init() bool {
+	return InitWithClock(clock.New())
+}
+
+// InitWithClock initializes the validator and registers custom validations, checking the dates against the given clock.
+func InitWithClock(clk clock.Clock) bool {
 	isSuccess := true
 	once.Do(func() {
 		validate = validator.New()
@@ -28,6 +36,19 @@ func Init() bool {
 			}
 			return strings.Split(tag, ",")[0]
 		})
+
+		// Validate the dates as their time, the validator otherwise descending into the struct without checking its tags
+		validate.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+			if d, ok := field.Interface().(customtype.Date); ok {
+				return d.Time
+			}
+			return nil
+		}, customtype.Date{})
+
+		// Register custom validation tags (e.g. e164, consumer_status, notfuture, password_complexity)
+		if err := registerCustomValidations(validate, clk); err != nil {
+			isSuccess = false
+		}
 	})
 
 	return isSuccess
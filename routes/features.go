@@ -0,0 +1,65 @@
+package routes
+
+import (
+	"time"
+
+	appconfig "github.com/yoanesber/go-jwt-auth-demo/config/app-config"
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/fieldcrypt"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/localeformat"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/masking"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+)
+
+// EnabledFeatures returns the optional features of the service and whether they are enabled with the given settings,
+// reported by the startup event and the config snapshot of the admin routes.
+// Some of them are only enabled once the dependencies are initialized, e.g. the metrics and the Redis token blacklist.
+func EnabledFeatures(cfg appconfig.Config) map[string]bool {
+	return map[string]bool{
+		"tls":                        cfg.Server.SSL,
+		"metrics":                    metrics.Enabled(),
+		"memory_database":            cfg.Database.IsMemory(),
+		"db_write_buffer":            cfg.Database.WriteBuffer.Enabled,
+		"db_read_replicas":           len(cfg.Database.ReplicaDSNs) > 0,
+		"db_circuit_breaker":         !cfg.Database.IsMemory() && cfg.Database.CircuitBreaker.Enabled(),
+		"geoip":                      cfg.GeoIP.DBPath != "" || cfg.GeoIP.ASNDBPath != "",
+		"anomaly_detection":          cfg.Anomaly.Enabled,
+		"captcha":                    cfg.Captcha.Enabled(),
+		"openapi_request_validation": cfg.Server.RequestValidation,
+		"consumer_webhooks":          cfg.Webhooks.Enabled(),
+		"alert_routes":               len(cfg.Alerts.Routes) > 0,
+		"redis_token_blacklist":      cfg.Redis.Enabled(),
+		"event_role_topics":          env.Getenv("EVENTS_ROLE_TOPICS") != "",
+		"disabled_features":          len(cfg.DisabledFeatures) > 0,
+		"pii_encryption":             fieldcrypt.Enabled(),
+		"data_masking":               masking.Enabled(),
+		"locale_formatting":          localeformat.Enabled(),
+		"tracing":                    cfg.Tracing.Enabled,
+		"signed_urls":                cfg.SignedURLs.Enabled(),
+		"chaos":                      cfg.Chaos.Enabled,
+	}
+}
+
+// rateLimitSettings returns the rate limits of the routes with the given settings, by the environment variable setting them.
+// They must be kept in line with the limiters of the routes.
+func rateLimitSettings(cfg appconfig.Config) []entity.RateLimitSetting {
+	settings := []entity.RateLimitSetting{
+		{Name: "CHECK_AVAILABILITY_RATE_LIMIT", Routes: []string{"POST /api/v1/consumers/check-availability"}, Limit: cfg.RateLimits.CheckAvailability, Window: time.Minute.String()},
+		{Name: "REGISTER_RATE_LIMIT", Routes: []string{"POST /auth/register"}, Limit: cfg.RateLimits.Register, Window: time.Hour.String()},
+		{Name: "FORGOT_PASSWORD_RATE_LIMIT", Routes: []string{"POST /auth/forgot-password"}, Limit: cfg.RateLimits.ForgotPassword, Window: time.Hour.String()},
+		{Name: "CHANGE_PASSWORD_RATE_LIMIT", Routes: []string{"PUT /api/v1/me/password"}, Limit: cfg.RateLimits.ChangePassword, Window: time.Hour.String()},
+		{Name: "MFA_RATE_LIMIT", Routes: []string{"POST /auth/mfa/verify", "POST /api/v1/me/mfa/activate", "POST /api/v1/me/mfa/disable"}, Limit: cfg.RateLimits.MFA, Window: time.Hour.String()},
+	}
+
+	// The token buckets of the logins and the token refreshes, a bucket that is not refilled is disabled
+	auth, authRoutes := cfg.RateLimits.Auth, []string{"POST /auth/login", "POST /auth/refresh-token"}
+	if auth.IP.Enabled() {
+		settings = append(settings, entity.RateLimitSetting{Name: "AUTH_RATE_LIMIT_IP_PER_MINUTE", Routes: authRoutes, Limit: auth.IP.PerMinute, Window: time.Minute.String(), Burst: auth.IP.Burst})
+	}
+	if auth.Subject.Enabled() {
+		settings = append(settings, entity.RateLimitSetting{Name: "AUTH_RATE_LIMIT_USERNAME_PER_MINUTE", Routes: authRoutes, Limit: auth.Subject.PerMinute, Window: time.Minute.String(), Burst: auth.Subject.Burst})
+	}
+
+	return settings
+}
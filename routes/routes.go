@@ -1,52 +1,516 @@
 package routes
 
 import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
+	appconfig "github.com/yoanesber/go-jwt-auth-demo/config/app-config"
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/docs"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
 	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/alerting"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/anomaly"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/basepath"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/chaos"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/eventbus"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/featureflag"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/mailer"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
 	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
 	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/headers"
 	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/logging"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/monitoring"
 	request_filter "github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/request-filter"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/oidc"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/quota"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/ratelimit"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/readiness"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/revocation"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/secrets"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/signedurl"
 	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
 )
 
-// SetupRouter initializes the router and sets up the routes for the application.
+// oauthTokenRoute is the OAuth2 token endpoint, whose requests are form-encoded instead of JSON.
+const oauthTokenRoute = "/auth/token"
+
+// startupHooks holds the functions completing the setup of the routes once the database is initialized,
+// such as loading the revoked tokens. They are run by Startup.
+// shutdownHooks holds the functions releasing the resources created by SetupRouter,
+// such as the background workers that must flush their pending writes. They are run by Shutdown.
+// loginHooks holds the hooks extending the login flow, invoked by the auth service of the routes created after their registration.
+var (
+	hooksMu       sync.Mutex
+	startupHooks  []func() error
+	shutdownHooks []func()
+	loginHooks    []service.LoginHook
+)
+
+// RegisterLoginHook registers a hook invoked before and after the authentication of the users, see service.LoginHook.
+// It must be called before SetupRouter, e.g. in main by a fork enforcing its own checks on the logins.
+func RegisterLoginHook(hook service.LoginHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	loginHooks = append(loginHooks, hook)
+}
+
+// registeredLoginHooks returns the login hooks registered with RegisterLoginHook.
+func registeredLoginHooks() []service.LoginHook {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	return append([]service.LoginHook(nil), loginHooks...)
+}
+
+// onStartup registers a function to run on Startup.
+func onStartup(fn func() error) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	startupHooks = append(startupHooks, fn)
+}
+
+// Startup completes the setup of the routes created by SetupRouter, in the order of their creation.
+// It must be called after the database connection is initialized, and stops at the first error.
+func Startup() error {
+	hooksMu.Lock()
+	hooks := startupHooks
+	startupHooks = nil
+	hooksMu.Unlock()
+
+	for _, hook := range hooks {
+		if err := hook(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// onShutdown registers a function to run on Shutdown.
+func onShutdown(fn func()) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	shutdownHooks = append(shutdownHooks, fn)
+}
+
+// Shutdown releases the resources created by SetupRouter, in the reverse order of their creation.
+// It must be called before the database connection is closed, so that pending writes can be flushed.
+func Shutdown() {
+	hooksMu.Lock()
+	hooks := shutdownHooks
+	shutdownHooks = nil
+	hooksMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// repositories holds the repositories shared by the routes.
+type repositories struct {
+	user          repository.UserRepository
+	role          repository.RoleRepository
+	permission    repository.PermissionRepository
+	refreshToken  repository.RefreshTokenRepository
+	revokedToken  repository.RevokedTokenRepository
+	passwordReset repository.PasswordResetTokenRepository
+	mfa           repository.MFARepository
+	consumer      repository.ConsumerRepository
+	tokenUsage    repository.TokenUsageRepository
+	notification  repository.NotificationRepository
+	webhook       repository.WebhookDeliveryRepository
+	security      repository.SecuritySettingsRepository
+	featureFlag   repository.FeatureFlagRepository
+	apiKey        repository.ApiKeyRepository
+	oauthClient   repository.OAuthClientRepository
+	userIdentity  repository.UserIdentityRepository
+	rateLimit     repository.RateLimitOverrideRepository
+	audit         repository.AuditEventRepository
+}
+
+// newRepositories creates the repositories for the configured database driver.
+// With DB_DRIVER=memory, the in-memory repositories are used and seeded when DB_SEED is TRUE.
+// With PostgreSQL, the reads of the users and the consumers failing with a transient error are retried, as set by DB_READ_RETRIES.
+// With CHAOS_ENABLED=TRUE, the faults of the chaos package are injected into the calls of the users and the consumers to the database.
+func newRepositories(cfg appconfig.Config) repositories {
+	injector := chaos.New(cfg.Chaos)
+	if !cfg.Database.IsMemory() {
+		retryPolicy := cfg.Database.ReadRetry
+		return repositories{
+			user:          newUserRepository(cfg),
+			role:          repository.NewRoleRepository(),
+			permission:    repository.NewPermissionRepository(),
+			refreshToken:  repository.NewRefreshTokenRepository(),
+			revokedToken:  repository.NewRevokedTokenRepository(),
+			passwordReset: repository.NewPasswordResetTokenRepository(),
+			mfa:           repository.NewMFARepository(),
+			consumer:      repository.NewRetryingConsumerRepository(repository.NewChaosConsumerRepository(repository.NewConsumerRepository(), injector), retryPolicy),
+			tokenUsage:    repository.NewTokenUsageRepository(),
+			notification:  repository.NewNotificationRepository(),
+			webhook:       repository.NewWebhookDeliveryRepository(),
+			security:      repository.NewSecuritySettingsRepository(),
+			featureFlag:   repository.NewFeatureFlagRepository(),
+			apiKey:        repository.NewApiKeyRepository(),
+			oauthClient:   repository.NewOAuthClientRepository(),
+			userIdentity:  repository.NewUserIdentityRepository(),
+			rateLimit:     repository.NewRateLimitOverrideRepository(),
+			audit:         repository.NewAuditEventRepository(),
+		}
+	}
+
+	store := repository.NewMemoryStore()
+	if cfg.Database.Seed {
+		if err := store.Seed(); err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to seed the in-memory store: %v", err), nil)
+		}
+	}
+
+	return repositories{
+		user:          repository.NewChaosUserRepository(repository.NewMemoryUserRepository(store), injector),
+		role:          repository.NewMemoryRoleRepository(store),
+		permission:    repository.NewMemoryPermissionRepository(store),
+		refreshToken:  repository.NewMemoryRefreshTokenRepository(store),
+		revokedToken:  repository.NewMemoryRevokedTokenRepository(store),
+		passwordReset: repository.NewMemoryPasswordResetTokenRepository(store),
+		mfa:           repository.NewMemoryMFARepository(store),
+		consumer:      repository.NewChaosConsumerRepository(repository.NewMemoryConsumerRepository(store), injector),
+		tokenUsage:    repository.NewMemoryTokenUsageRepository(store),
+		notification:  repository.NewMemoryNotificationRepository(store),
+		webhook:       repository.NewMemoryWebhookDeliveryRepository(store),
+		security:      repository.NewMemorySecuritySettingsRepository(store),
+		featureFlag:   repository.NewMemoryFeatureFlagRepository(store),
+		apiKey:        repository.NewMemoryApiKeyRepository(store),
+		oauthClient:   repository.NewMemoryOAuthClientRepository(store),
+		userIdentity:  repository.NewMemoryUserIdentityRepository(store),
+		rateLimit:     repository.NewMemoryRateLimitOverrideRepository(store),
+		audit:         repository.NewMemoryAuditEventRepository(store),
+	}
+}
+
+// newUserRepository creates the PostgreSQL user repository, retrying its reads as configured by DB_READ_RETRIES.
+// USER_CACHE_TTL_SECOND caches the username lookups of the logins for the given number of seconds
+// (0 or unset disables the cache); the lookups missing the cache are retried.
+func newUserRepository(cfg appconfig.Config) repository.UserRepository {
+	repo := repository.NewChaosUserRepository(repository.NewUserRepository(userRepositoryOptions(cfg)...), chaos.New(cfg.Chaos))
+	repo = repository.NewRetryingUserRepository(repo, cfg.Database.ReadRetry)
+
+	return repository.NewCachedUserRepository(repo, cfg.Caches.UserTTL, clock.New())
+}
+
+// newUserStoreRepository creates the user repository of the external user store set by USER_STORE,
+// caching the username lookups of the logins like the database with USER_CACHE_TTL_SECOND.
+func newUserStoreRepository(cfg appconfig.Config) repository.UserRepository {
+	store, err := repository.NewUserStore(cfg.UserStore, secrets.Default(), nil)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to create the user store: %v", err), nil)
+	}
+
+	return repository.NewCachedUserRepository(repository.NewUserStoreRepository(store), cfg.Caches.UserTTL, clock.New())
+}
+
+// userRepositoryOptions returns the options of the PostgreSQL user repository.
+// DB_JOIN_ROLES=TRUE loads the roles of a user with a joined query instead of Preload("Roles"),
+// and ROLE_CACHE_TTL_SECOND caches the roles of the users for the given number of seconds (0 or unset disables it).
+func userRepositoryOptions(cfg appconfig.Config) []repository.UserRepositoryOption {
+	var opts []repository.UserRepositoryOption
+	if cfg.Database.JoinRoles {
+		opts = append(opts, repository.WithJoinedRoles())
+	}
+
+	if cache := repository.NewRoleCache(cfg.Caches.RoleTTL, clock.New()); cache != nil {
+		opts = append(opts, repository.WithRoleCache(cache))
+	}
+
+	return opts
+}
+
+// SetupRouter initializes the router and sets up the routes for the application, with the settings of the environment.
 func SetupRouter() *gin.Engine {
+	cfg, err := appconfig.FromEnv()
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Invalid configuration: %v", err), nil)
+	}
+
+	return SetupRouterWithConfig(cfg)
+}
+
+// SetupRouterWithConfig initializes the router and sets up the routes for the application, with the given settings.
+func SetupRouterWithConfig(cfg appconfig.Config) *gin.Engine {
 	// Create a new Gin router instance
 	r := gin.Default()
 
+	// Resolve the real client IP only from the forwarding headers set by the trusted proxies
+	if err := cfg.Server.Proxy.Apply(r); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to set the trusted proxies: %v", err), nil)
+	}
+
+	// The routes are at the root, BASE_PATH is the public prefix they are mounted under behind a gateway
+	// The prefix is removed from the requests by basepath.Handler, and added to the paths reported to the clients
+	basePath := cfg.Server.BasePath
+	basepath.Set(basePath)
+
+	// Create the repositories for the configured database driver, and the user store
+	repos := newRepositories(cfg)
+	if cfg.UserStore.IsExternal() {
+		repos.user = newUserStoreRepository(cfg)
+	}
+
+	// The services get the connection from the data store, which resolves it once the database is initialized
+	store := database.DefaultStore()
+
+	// The JWT settings are shared by the token issuer and the JWT validation middleware
+	clk := clock.New()
+	jwtConfig := cfg.JWT
+
+	// The administrators add allowed origins and headers and override the security headers with the admin routes,
+	// the overrides are loaded on Startup and reloaded every SECURITY_SETTINGS_CACHE_TTL_SECOND
+	securitySettingsService := service.NewSecuritySettingsService(store, repos.security, cfg.Caches.SecuritySettingsTTL, clk)
+	onStartup(securitySettingsService.LoadOverrides)
+
+	// The routes of a feature are rejected with 503 while it is disabled by DISABLED_FEATURES or by the administrators with the admin routes,
+	// the runtime flags are loaded on Startup and reloaded every FEATURE_FLAGS_CACHE_TTL_SECOND
+	featureFlagService := service.NewFeatureFlagService(store, repos.featureFlag, cfg.DisabledFeatures, cfg.Caches.FeatureFlagsTTL, clk)
+	onStartup(featureFlagService.LoadFlags)
+	feature := func(name string) gin.HandlerFunc {
+		return request_filter.RequireFeature(featureFlagService.Flags(), name)
+	}
+
+	// The rate limits of the authenticated clients are scaled by the overrides set by the administrators with the admin routes,
+	// e.g. ten times the default limits for a partner or a tenth for a noisy client; the overrides are loaded on Startup
+	// and reloaded every RATE_LIMIT_OVERRIDES_CACHE_TTL_SECOND
+	rateLimitOverrideService := service.NewRateLimitOverrideService(store, repos.rateLimit, cfg.Caches.RateLimitOverridesTTL, clk)
+	onStartup(rateLimitOverrideService.LoadOverrides)
+	rateLimit := func(limiter *ratelimit.Limiter) gin.HandlerFunc {
+		return request_filter.RateLimitWithOverrides(limiter, rateLimitOverrideService.Overrides())
+	}
+
+	// TRACING_ENABLED=TRUE traces each request from its first middleware, continuing the trace context of the caller;
+	// the probes and the scrapes are not traced. The services and the SQL queries add their spans to the trace
+	if cfg.Tracing.Enabled {
+		r.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(func(req *http.Request) bool {
+			return req.URL.Path != "/metrics" && req.URL.Path != "/readyz"
+		})))
+	}
+
+	// Give each request an ID, kept from the X-Request-Id header of the caller or generated, before the other middleware,
+	// so that the logs and the error responses of every request, rejected or not, carry the same ID
+	r.Use(headers.RequestID())
+
+	// Count and time the requests on /metrics by route, method, and status, before any other middleware,
+	// so the requests rejected by the filters are recorded as well, and count them against the objectives of their route group
+	if metrics.Enabled() {
+		metrics.ConfigureSLO(cfg.SLO, clk)
+		r.Use(monitoring.RequestMetrics())
+	}
+
 	// Set up middleware for the router
-	// Middleware is used to handle cross-cutting concerns such as logging, security, and request ID generation
+	// Middleware is used to handle cross-cutting concerns such as logging and security
 	r.Use(
-		headers.SecurityHeaders(),
-		headers.CorsHeaders(),
-		headers.ContentType(),
+		headers.SecurityHeadersWithOverrides(securitySettingsService.Overrides()),
+		headers.CorsHeadersWithConfig(cfg.CORS, securitySettingsService.Overrides()),
+		headers.ContentType(oauthTokenRoute),
 		request_filter.DetectParameterPollution(),
+		request_filter.BlockSuspiciousSources(),
 		logging.RequestLogger(),
 		gzip.Gzip(gzip.DefaultCompression),
 	)
 
+	// OPENAPI_REQUEST_VALIDATION=TRUE validates the parameters and bodies of the requests against the OpenAPI document before the handlers run,
+	// so that the type mismatches and the unknown fields are rejected with the same 400 response on every route
+	if cfg.Server.RequestValidation {
+		validateRequests, err := request_filter.ValidateRequests(docs.OpenAPI)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to set up the OpenAPI request validation: %v", err), nil)
+		}
+		r.Use(validateRequests)
+	}
+
+	// CHAOS_ENABLED=TRUE injects the faults set by the X-Chaos-* headers of the requests, for the resilience tests:
+	// the requests are delayed or rejected before their handlers, and their calls to the database fail or wait
+	if cfg.Chaos.Enabled {
+		r.Use(request_filter.InjectFaults())
+	}
+
+	// The token usage is recorded by the auth and token revocation services and reported by the admin routes
+	tokenUsageService := service.NewTokenUsageService(store, repos.tokenUsage)
+	tokenUsageRecorder := service.NewAsyncTokenUsageRecorder(tokenUsageService, service.DefaultTokenUsageFlushInterval, service.DefaultTokenUsageMaxPending)
+	onShutdown(tokenUsageRecorder.Close)
+
+	// The revoked tokens are rejected by the JWT validation middleware, the denylist is loaded on Startup
+	// With REDIS_URL, the revocations are shared with the other instances through the blacklist, checked by the middleware as well
+	var revocationOpts []service.TokenRevocationServiceOption
+	if cfg.Redis.Enabled() {
+		tokenBlacklistService := service.NewTokenBlacklistService(cfg.Redis.KeyPrefix, jwtConfig.TTL.Max(), clk)
+		revocation.SetBlacklist(tokenBlacklistService)
+		revocationOpts = append(revocationOpts, service.WithTokenBlacklist(tokenBlacklistService))
+	}
+	tokenRevocationService := service.NewTokenRevocationService(store, repos.user, repos.refreshToken, repos.revokedToken, revocation.GetDenylist(), tokenUsageRecorder, clk, revocationOpts...)
+	onStartup(tokenRevocationService.LoadRevocations)
+
+	// The security events are reported by the auth and password services, the users manage their notifications with the v1 routes
+	m, err := mailer.New(cfg.Mailer)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Invalid mailer configuration: %v", err), nil)
+	}
+	templates, err := mailer.LoadTemplates()
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Invalid mail templates: %v", err), nil)
+	}
+	notificationService := service.NewNotificationService(store, repos.notification, m, templates)
+
+	// With DB_WRITE_BUFFER, the last login times, the security events, and the audit events failing while the database fails over are retried
+	// in the background, instead of being dropped. The buffer is closed after the workers writing with it
+	writeBuffer := service.NewWriteBuffer(cfg.Database.WriteBuffer, clk)
+	onShutdown(writeBuffer.Close)
+
+	// The logins, the logouts, the token refreshes, and the changes made by the administrators are recorded in the audit log
+	auditService := service.NewAuditService(store, repos.audit)
+	auditRecorder := service.NewAsyncAuditRecorder(auditService, writeBuffer, service.DefaultAuditQueueSize, clk)
+	onShutdown(auditRecorder.Close)
+	audits := handler.NewAuditHandler(auditService, auditRecorder, cfg.Pagination)
+	notifier := service.NewAsyncSecurityNotifier(notificationService, writeBuffer, service.DefaultNotificationQueueSize)
+	onShutdown(notifier.Close)
+
+	// The users with two-factor authentication complete their logins with a code of their authenticator app,
+	// they enroll and disable it with the v1 routes. The codes are rate limited, so that they cannot be guessed
+	// MFA_RATE_LIMIT is the number of codes checked per user (or client IP for the logins) and hour
+	mfaService := service.NewMFAService(store, repos.mfa, repos.user, notifier, cfg.MFA, clk)
+	mfaLimiter := ratelimit.NewLimiter(cfg.RateLimits.MFA, time.Hour, clk)
+
+	// The operational alerts (the anomalies, the dead webhook deliveries, the expiring TLS certificate) are sent
+	// to the channels routed to their event with ALERT_ROUTES, the events without a route are written to the log
+	alerts, err := alerting.New(cfg.Alerts.Config, m, clk)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Invalid alerting configuration: %v", err), nil)
+	}
+	onShutdown(alerts.Close)
+	anomaly.GetDetector().SetNotifier(alerts)
+
+	// The realtime events (the consumers created and suspended, the anomalies) are published to the event bus,
+	// and pushed to the admin UIs connected to /api/v1/ws receiving the topics granted to their roles with EVENTS_ROLE_TOPICS
+	events := eventbus.NewBus(clk)
+	onShutdown(events.Close)
+	anomaly.GetDetector().SetEventPublisher(events)
+	if cfg.Server.SSL {
+		certificateMonitor := alerting.NewCertificateMonitor(alerts, cfg.Server.SSLCert, cfg.Alerts.CertificateExpiryWarning, alerting.DefaultCertificateCheckInterval, clk)
+		onShutdown(certificateMonitor.Close)
+	}
+
+	// Every refresh token is a session of its user, the users list and end their sessions with the v1 routes
+	refreshTokenService := service.NewRefreshTokenService(store, repos.refreshToken, cfg.Sessions, clk)
+
+	// The consumer events are sent to CONSUMER_WEBHOOK_URL by a background dispatcher, retrying the failed deliveries,
+	// the administrators inspect and redeliver the failed ones with the admin routes
+	webhookService := service.NewWebhookService(store, repos.webhook, cfg.Webhooks, nil, clk, service.WithWebhookAlerts(alerts))
+	if cfg.Webhooks.Enabled() {
+		dispatcher := service.NewWebhookDispatcher(webhookService, service.DefaultWebhookPollInterval)
+		onShutdown(dispatcher.Close)
+	}
+
+	// The users log in with the auth routes, the administrators manage their accounts with the v1 routes
+	userService := service.NewUserService(store, repos.user, repos.role, tokenRevocationService, clk)
+	if cfg.Tracing.Enabled {
+		userService = service.NewTracedUserService(userService)
+	}
+	userStateService := service.NewUserStateService(store, repos.user, tokenRevocationService, clk)
+
+	// The administrators manage the custom roles and their permissions with the v1 routes, the cached roles of the users are dropped when a role is renamed
+	var roleOpts []service.RoleServiceOption
+	if invalidator, ok := repos.user.(repository.RoleCacheInvalidator); ok {
+		roleOpts = append(roleOpts, service.WithRoleCacheInvalidator(invalidator))
+	}
+	roleService := service.NewRoleService(store, repos.role, repos.permission, roleOpts...)
+
+	// The service accounts authenticate the v1 routes with the API keys created by the administrators with the admin routes,
+	// sent in the X-API-Key header instead of an access token; the requests carry the roles and scopes of their account
+	apiKeyService := service.NewApiKeyService(store, repos.apiKey, repos.user, roleService, clk)
+
+	// The access tokens carry the permissions granted to the roles of their user as scopes
+	// The machine clients of the service accounts exchange the client ID and secret created by the administrators with the admin routes
+	// for an access token with the client_credentials grant, carrying the scopes they request
+	tokenIssuer := service.NewJWTTokenIssuer(jwtConfig, service.WithScopeResolver(roleService))
+	oauthClientService := service.NewOAuthClientService(store, repos.oauthClient, repos.user, tokenIssuer, roleService, tokenUsageRecorder, clk)
+
+	// The RS256 tokens are signed with the current key of the key set, the public keys are published for the downstream services
+	// With JWT_KEYSET_DIR, the first key is created on Startup, and the key is rotated every JWT_KEY_ROTATION_DAYS or by the administrators
+	signingKeyService := service.NewSigningKeyService(jwtConfig, cfg.KeyRotation, clk)
+	signsWithRSA := jwtConfig.SigningMethod == jwt.SigningMethodRS256.Alg()
+	if signsWithRSA {
+		onStartup(signingKeyService.EnsureKey)
+	}
+	signingKeys := handler.NewSigningKeyHandler(signingKeyService)
+
 	// Set up the authentication routes
 	// These routes handle user login and authentication
 	authGroup := r.Group("/auth")
 	{
 		// Routes for authentication
 		// These routes handle user login
-		s := service.NewAuthService()
+		lastLoginRecorder := service.NewAsyncLastLoginRecorder(userService, writeBuffer, service.DefaultLastLoginFlushInterval, service.DefaultLastLoginMaxPending)
+		onShutdown(lastLoginRecorder.Close)
+		s := service.NewAuthService(userService, refreshTokenService, tokenIssuer, lastLoginRecorder, tokenUsageRecorder, notifier, tokenRevocationService, clk,
+			service.WithBlockedAdminCountries(cfg.GeoIP.BlockedAdminCountries), service.WithMFA(mfaService), service.WithLoginHooks(registeredLoginHooks()...), service.WithAudit(auditRecorder))
+		if cfg.Tracing.Enabled {
+			s = service.NewTracedAuthService(s)
+		}
 		h := handler.NewAuthHandler(s)
 
 		// Define the routes for authentication
 		// These routes handle user login
-		authGroup.POST("/login", h.Login)
-		authGroup.POST("/refresh-token", h.RefreshToken)
+		// The logins and the token refreshes are limited with a token bucket per client IP and another per username (per refresh token
+		// for the refreshes), against the password guessing and the credential stuffing; with AUTH_RATE_LIMIT_BACKEND=redis,
+		// the buckets are shared by the instances in Redis
+		authLimiter := ratelimit.NewAuthLimiter(cfg.RateLimits.Auth, cfg.RateLimits.Auth.NewBucketStore(cfg.Redis.KeyPrefix, clk))
+		authGroup.POST("/login", request_filter.AuthRateLimit(authLimiter, request_filter.LoginSubject), h.Login)
+		authGroup.POST("/refresh-token", request_filter.AuthRateLimit(authLimiter, request_filter.RefreshTokenSubject), h.RefreshToken)
+		authGroup.POST("/mfa/verify", rateLimit(mfaLimiter), h.VerifyMFA)
+		authGroup.POST("/token", handler.NewOAuthClientHandler(oauthClientService).Token)
+
+		// The self-registration is rate limited per client IP, so that accounts cannot be created in bulk
+		// REGISTER_RATE_LIMIT is the number of registrations allowed per client IP and hour
+		authGroup.POST("/register", feature(featureflag.Registration), rateLimit(ratelimit.NewLimiter(cfg.RateLimits.Register, time.Hour, clk)), h.Register)
+
+		// The users who forgot their password request a reset link by email, then set a new password with its token
+		// FORGOT_PASSWORD_RATE_LIMIT is the number of reset links requested per client IP and hour
+		passwordResets := handler.NewPasswordResetHandler(service.NewPasswordResetService(store, repos.passwordReset, repos.user, tokenRevocationService, notifier, m, templates, cfg.PasswordReset, clk))
+		authGroup.POST("/forgot-password", feature(featureflag.PasswordResets), rateLimit(ratelimit.NewLimiter(cfg.RateLimits.ForgotPassword, time.Hour, clk)), passwordResets.ForgotPassword)
+		authGroup.POST("/reset-password", feature(featureflag.PasswordResets), passwordResets.ResetPassword)
+
+		// The logout ends the session of the access token of the request
+		authGroup.POST("/logout", authorization.JwtValidationWithConfig(jwtConfig, clk), h.Logout)
+
+		// The users log in with an account of the OpenID Connect providers of OIDC_PROVIDERS (e.g. Google, Microsoft),
+		// they are provisioned with the default role on their first login
+		oidcProviders := make(map[string]service.OIDCProvider, len(cfg.OIDC))
+		for _, provider := range cfg.OIDC {
+			oidcProviders[provider.Name] = oidc.NewProvider(provider, nil, clk)
+		}
+		oidcHandler := handler.NewOIDCHandler(service.NewOIDCService(store, oidcProviders, repos.userIdentity, repos.user, repos.role, s, clk), cfg.Server.SSL)
+		authGroup.GET("/oidc/:provider/login", feature(featureflag.OIDCLogin), oidcHandler.Login)
+		authGroup.GET("/oidc/:provider/callback", feature(featureflag.OIDCLogin), oidcHandler.Callback)
 	}
 
 	// Set up the API version 1 routes
-	v1 := r.Group("/api/v1", authorization.JwtValidation())
+	// The requests are authenticated with an access token, or with the API key of a service account
+	// Every request spends its cost from the daily and monthly budgets of its client (see the quota package)
+	quotaTracker := quota.NewTracker(cfg.Quota, clk)
+	v1 := r.Group("/api/v1", authorization.ApiKeyOrJwtValidation(apiKeyService, authorization.JwtValidationWithConfig(jwtConfig, clk)), request_filter.EnforceQuota(quotaTracker))
 	{
 		// Routes for consumer management
 		// These routes handle CRUD operations for consumers
@@ -54,28 +518,215 @@ func SetupRouter() *gin.Engine {
 		{
 			// Initialize the transaction repository and service
 			// This is where the actual implementation of the repository and service would be used
-			r := repository.NewConsumerRepository()
-			s := service.NewConsumerService(r)
+			// CONSUMER_CACHE_TTL_SECOND caches the consumers looked up by ID for the given number of seconds
+			// (0 or unset disables the cache)
+			// The new consumers are checked against the rules of the deployment, e.g. CONSUMER_REQUIRED_METADATA_KEYS
+			s := service.NewConsumerService(store, repos.consumer, cfg.Consumers.Listing,
+				service.WithConsumerCache(cfg.Caches.ConsumerTTL, clk), service.WithConsumerWebhooks(webhookService),
+				service.WithConsumerEvents(events), service.WithConsumerRules(cfg.Consumers.Rules), service.WithConsumerClock(clk))
+			if cfg.Tracing.Enabled {
+				s = service.NewTracedConsumerService(s)
+			}
 
 			// Initialize the transaction handler with the service
 			// This handler handles the HTTP requests and responses for transaction-related operations
-			h := handler.NewConsumerHandler(s)
+			h := handler.NewConsumerHandler(s, cfg.Pagination)
 
 			// Define the routes for transaction management
 			// These routes handle CRUD operations for transactions
 			// The GET methods are accessible to both admin and user roles
-			consumerGroup.GET("", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.GetAllConsumers)
-			consumerGroup.GET("/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.GetConsumerByID)
-			consumerGroup.GET("/active", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.GetActiveConsumers)
-			consumerGroup.GET("/inactive", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.GetInactiveConsumers)
-			consumerGroup.GET("/suspended", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.GetSuspendedConsumers)
+			consumerGroup.GET("", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), feature(featureflag.ConsumerReads), h.GetAllConsumers)
+			consumerGroup.GET("/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), feature(featureflag.ConsumerReads), h.GetConsumerByID)
+			consumerGroup.GET("/active", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), feature(featureflag.ConsumerReads), h.GetActiveConsumers)
+			consumerGroup.GET("/inactive", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), feature(featureflag.ConsumerReads), h.GetInactiveConsumers)
+			consumerGroup.GET("/suspended", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), feature(featureflag.ConsumerReads), h.GetSuspendedConsumers)
+			consumerGroup.GET("/stream", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), feature(featureflag.ConsumerStream), h.StreamConsumers)
 
 			// The POST and PUT methods are restricted to admin users only
-			consumerGroup.POST("", authorization.RoleBasedAccessControl("ROLE_ADMIN"), h.CreateConsumer)
-			consumerGroup.PATCH("/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), h.UpdateConsumerStatus)
+			consumerGroup.POST("", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.ConsumerWrites), h.CreateConsumer)
+
+			// The availability check of the onboarding forms is rate limited per user, so it cannot be used to enumerate the consumers
+			// CHECK_AVAILABILITY_RATE_LIMIT is the number of checks allowed per user and minute
+			consumerGroup.POST("/check-availability", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.ConsumerWrites),
+				rateLimit(ratelimit.NewLimiter(cfg.RateLimits.CheckAvailability, time.Minute, clk)), h.CheckConsumerAvailability)
+
+			// The PATCH requests with a status query parameter change the status of the consumer, the others update its fields
+			consumerGroup.PATCH("/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.ConsumerWrites),
+				auditConsumerChange(audits), h.UpdateConsumer)
+
+			// The consumers are soft-deleted, and restored by the administrators
+			consumerGroup.DELETE("/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.ConsumerWrites),
+				audits.Audit(entity.AuditActionConsumerDelete, entity.AuditTargetConsumer), h.DeleteConsumer)
+			consumerGroup.POST("/:id/restore", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.ConsumerWrites),
+				audits.Audit(entity.AuditActionConsumerRestore, entity.AuditTargetConsumer), h.RestoreConsumer)
+
+			// With SIGNED_URL_SECRET, the consumer stream is also downloaded with a signed URL instead of the access token,
+			// e.g. by a download manager; the URL expires after SIGNED_URL_TTL_SECOND and is bound to the roles of the user it was issued to
+			if signer := signedurl.NewSigner(cfg.SignedURLs, clk); signer != nil {
+				const downloadPath = "/api/v1/downloads/consumers"
+				consumerGroup.POST("/stream/signed-url", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), feature(featureflag.ConsumerStream),
+					handler.NewSignedURLHandler(signer).Issue(downloadPath))
+				r.GET(downloadPath, authorization.SignedURL(signer), request_filter.EnforceQuota(quotaTracker),
+					authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), feature(featureflag.ConsumerStream), h.StreamConsumers)
+			}
+		}
+
+		// Routes for user management, restricted to admin users
+		// These routes handle CRUD operations for users, the deleted users are soft-deleted and their tokens revoked
+		// The changes of the users are recorded in the audit log, the changes of their roles as role changes
+		userGroup := v1.Group("/users", authorization.RoleBasedAccessControl("ROLE_ADMIN"), audits.Audit(entity.AuditActionAdmin, entity.AuditTargetRoute))
+		{
+			users := handler.NewUserHandler(userService, userStateService, cfg.Pagination)
+			userGroup.GET("", users.GetAllUsers)
+			userGroup.GET("/:id", users.GetUserByID)
+			userGroup.POST("", feature(featureflag.UserWrites), users.CreateUser)
+			userGroup.PUT("/:id", feature(featureflag.UserWrites), users.UpdateUser)
+			userGroup.PATCH("/:id", feature(featureflag.UserWrites), users.UpdateUserStatus)
+			userGroup.DELETE("/:id", feature(featureflag.UserWrites), users.DeleteUser)
+			userGroup.POST("/:id/roles", feature(featureflag.UserWrites), audits.Audit(entity.AuditActionRoleChange, entity.AuditTargetUser), users.ChangeUserRoles)
+		}
+
+		// Routes for role management, restricted to admin users
+		// The built-in roles cannot be renamed or deleted, the custom roles only once they are not assigned anymore
+		// The permissions granted to the roles are carried by the next access tokens of their users in the scopes claim
+		// The changes of the roles and of their permissions are recorded in the audit log
+		roleGroup := v1.Group("/roles", authorization.RoleBasedAccessControl("ROLE_ADMIN"), audits.Audit(entity.AuditActionRoleChange, entity.AuditTargetRole))
+		roles := handler.NewRoleHandler(roleService)
+		{
+			roleGroup.GET("", roles.GetAllRoles)
+			roleGroup.POST("", roles.CreateRole)
+			roleGroup.PUT("/:id", roles.UpdateRole)
+			roleGroup.DELETE("/:id", roles.DeleteRole)
+			roleGroup.GET("/:id/permissions", roles.GetRolePermissions)
+			roleGroup.PUT("/:id/permissions", roles.SetRolePermissions)
+		}
+		v1.GET("/permissions", authorization.RoleBasedAccessControl("ROLE_ADMIN"), roles.GetAllPermissions)
+
+		// Routes for the notification preferences, the sessions, the password, and the two-factor authentication of the authenticated user
+		// Every authenticated user can manage their own preferences, sessions, and credentials
+		meGroup := v1.Group("/users/me")
+		{
+			h := handler.NewNotificationHandler(notificationService)
+			meGroup.GET("/notification-preferences", h.GetNotificationPreference)
+			meGroup.PUT("/notification-preferences", h.UpdateNotificationPreference)
+
+			sessions := handler.NewSessionHandler(refreshTokenService)
+			meGroup.GET("/sessions", sessions.GetSessions)
+			meGroup.DELETE("/sessions/:sessionId", sessions.RemoveSession)
+
+			// The password changes are rate limited per user, so that a stolen access token cannot be used to guess the current password
+			// CHANGE_PASSWORD_RATE_LIMIT is the number of password changes attempted per user and hour
+			passwords := handler.NewPasswordHandler(service.NewPasswordService(store, repos.user, repos.refreshToken, notifier, cfg.Credentials, clk))
+			meGroup.PUT("/password", rateLimit(ratelimit.NewLimiter(cfg.RateLimits.ChangePassword, time.Hour, clk)), passwords.ChangePassword)
+
+			mfa := handler.NewMFAHandler(mfaService)
+			meGroup.GET("/mfa", mfa.GetMFAStatus)
+			meGroup.POST("/mfa/enroll", mfa.EnrollMFA)
+			meGroup.POST("/mfa/activate", rateLimit(mfaLimiter), mfa.ActivateMFA)
+			meGroup.POST("/mfa/disable", rateLimit(mfaLimiter), mfa.DisableMFA)
+		}
+
+		// Route for the usage of the quotas of the client of the authenticated user
+		v1.GET("/me/quota", handler.NewQuotaHandler(quotaTracker).GetQuota)
+
+		// Route for searching the audit log, restricted to admin users
+		v1.GET("/audit-events", authorization.RoleBasedAccessControl("ROLE_ADMIN"), audits.GetAuditEvents)
+
+		// Routes for administration, restricted to admin users
+		// These routes report the token usage for capacity planning and abuse detection,
+		// revoke the tokens of compromised accounts, change the state of the user accounts, manage the API keys and the OAuth clients of the service accounts, redeliver the failed webhooks,
+		// change the CORS and security headers, the feature flags, and the rate limits of the clients, rotate the key signing the tokens,
+		// and report the effective configuration of the instance
+		// The changes made with these routes are recorded in the audit log
+		adminGroup := v1.Group("/admin", authorization.RoleBasedAccessControl("ROLE_ADMIN"), audits.Audit(entity.AuditActionAdmin, entity.AuditTargetRoute))
+		{
+			stats := handler.NewTokenStatsHandler(tokenUsageService)
+			adminGroup.GET("/token-stats", stats.GetTokenStats)
+
+			revocations := handler.NewTokenRevocationHandler(tokenRevocationService)
+			adminGroup.POST("/users/:id/revoke-tokens", revocations.RevokeUserTokens)
+
+			states := handler.NewUserStateHandler(userStateService)
+			adminGroup.PUT("/users/:id/state", states.ChangeUserState)
+
+			apiKeys := handler.NewApiKeyHandler(apiKeyService)
+			adminGroup.GET("/users/:id/api-keys", apiKeys.GetApiKeys)
+			adminGroup.POST("/users/:id/api-keys", apiKeys.CreateApiKey)
+			adminGroup.POST("/api-keys/:id/rotate", apiKeys.RotateApiKey)
+			adminGroup.DELETE("/api-keys/:id", apiKeys.RevokeApiKey)
+
+			oauthClients := handler.NewOAuthClientHandler(oauthClientService)
+			adminGroup.GET("/users/:id/oauth-clients", oauthClients.GetOAuthClients)
+			adminGroup.POST("/users/:id/oauth-clients", oauthClients.CreateOAuthClient)
+			adminGroup.DELETE("/oauth-clients/:id", oauthClients.RevokeOAuthClient)
+
+			webhooks := handler.NewWebhookHandler(webhookService, cfg.Pagination)
+			adminGroup.GET("/webhook-deliveries", webhooks.GetFailedWebhookDeliveries)
+			adminGroup.GET("/webhook-deliveries/:id", webhooks.GetWebhookDelivery)
+			adminGroup.POST("/webhook-deliveries/:id/redeliver", webhooks.RedeliverWebhook)
+
+			securitySettings := handler.NewSecuritySettingsHandler(securitySettingsService)
+			adminGroup.GET("/security-settings", securitySettings.GetSecuritySettings)
+			adminGroup.PUT("/security-settings", securitySettings.UpdateSecuritySettings)
+
+			// The administrators disable and enable the features at runtime, these routes are never disabled
+			featureFlags := handler.NewFeatureFlagHandler(featureFlagService)
+			adminGroup.GET("/feature-flags", featureFlags.GetFeatureFlags)
+			adminGroup.PUT("/feature-flags/:name", featureFlags.UpdateFeatureFlag)
+
+			// The administrators scale the rate limits of individual clients
+			rateLimitOverrides := handler.NewRateLimitOverrideHandler(rateLimitOverrideService)
+			adminGroup.GET("/rate-limit-overrides", rateLimitOverrides.GetRateLimitOverrides)
+			adminGroup.PUT("/rate-limit-overrides/:client", rateLimitOverrides.UpdateRateLimitOverride)
+			adminGroup.DELETE("/rate-limit-overrides/:client", rateLimitOverrides.DeleteRateLimitOverride)
+
+			// Route for rotating the key signing the RS256 tokens
+			if signsWithRSA {
+				adminGroup.POST("/signing-keys/rotate", signingKeys.RotateSigningKey)
+			}
+
+			// The support engineers verify the effective configuration of the instance, the secrets are masked
+			configSnapshotService := service.NewConfigSnapshotService(func() map[string]bool { return EnabledFeatures(cfg) }, rateLimitSettings(cfg), cfg.Quota,
+				featureFlagService, rateLimitOverrideService, signingKeyService, jwtConfig, clk)
+			adminGroup.GET("/config-snapshot", handler.NewConfigSnapshotHandler(configSnapshotService).GetConfigSnapshot)
 		}
 	}
 
+	// Set up the route of the realtime events, pushed over WebSocket
+	// The browsers cannot set the Authorization header of the upgrade, the token is also read from the subprotocols or a cookie
+	r.GET("/api/v1/ws", authorization.WebSocketToken(jwtConfig.TokenType), authorization.JwtValidationWithConfig(jwtConfig, clk),
+		request_filter.EnforceQuota(quotaTracker), feature(featureflag.RealtimeEvents), handler.NewEventHandler(events, cfg.Events).StreamEvents)
+
+	// Set up the debug routes, restricted to admin users
+	// These routes expose the runtime metrics published with expvar (e.g. the user lookup cache hit rate)
+	debugGroup := r.Group("/debug", authorization.JwtValidationWithConfig(jwtConfig, clk), authorization.RoleBasedAccessControl("ROLE_ADMIN"))
+	{
+		debugGroup.GET("/vars", gin.WrapH(expvar.Handler()))
+	}
+
+	// Set up the readiness route, probed by the load balancers and the orchestrators
+	// It reports ready once the warm-up steps registered here are completed by WarmUp
+	registerWarmUpSteps(jwtConfig, cfg.Caches.RoleWarmUpUsers, store, repos)
+	r.GET("/readyz", handler.NewReadinessHandler(readiness.GetGate()).Readyz)
+
+	// Set up the routes of the OpenAPI document, loaded by the Swagger UI and the client generators,
+	// and of its versioned archive, downloaded by the integrating teams to generate their clients from the running contract
+	openAPIHandler := handler.NewOpenAPIHandler(docs.OpenAPI, basePath)
+	r.GET("/openapi.yaml", openAPIHandler.GetOpenAPI)
+	r.GET("/openapi.zip", openAPIHandler.GetOpenAPIArchive)
+
+	// Set up the route of the public keys, fetched by the downstream services validating the tokens on their own
+	// The keys are only published when the tokens are signed with RS256, the HS256 secret cannot be shared
+	if signsWithRSA {
+		r.GET("/.well-known/jwks.json", signingKeys.GetJWKS)
+	}
+
+	// Set up the metrics route, scraped by Prometheus compatible collectors
+	// It exports the runtime metrics and the business counters (e.g. the consumers created) in the OpenMetrics format
+	if metrics.Enabled() {
+		r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
+
 	// NoRoute handler for undefined routes
 	// This handler will be called when no other route matches the request
 	r.NoRoute(func(c *gin.Context) {
@@ -90,3 +741,18 @@ func SetupRouter() *gin.Engine {
 
 	return r
 }
+
+// auditConsumerChange returns the middleware recording the changes of the consumers in the audit log:
+// a status change for the requests with a status query parameter, and an update for the others.
+func auditConsumerChange(audits *handler.AuditHandler) gin.HandlerFunc {
+	statusChange := audits.Audit(entity.AuditActionConsumerStatusChange, entity.AuditTargetConsumer)
+	update := audits.Audit(entity.AuditActionConsumerUpdate, entity.AuditTargetConsumer)
+
+	return func(c *gin.Context) {
+		if _, ok := c.GetQuery("status"); ok {
+			statusChange(c)
+			return
+		}
+		update(c)
+	}
+}
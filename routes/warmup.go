@@ -0,0 +1,138 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	jwtconfig "github.com/yoanesber/go-jwt-auth-demo/config/jwt-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/readiness"
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+// warmUpPingTimeout is the time the database has to answer the ping of the warm-up.
+const warmUpPingTimeout = 5 * time.Second
+
+// warmUpSteps holds the steps run by WarmUp before the service reports ready on /readyz.
+var warmUpSteps []readiness.Step
+
+// onWarmUp registers a step to run on WarmUp.
+func onWarmUp(step readiness.Step) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	warmUpSteps = append(warmUpSteps, step)
+}
+
+// WarmUp runs the warm-up steps of the routes created by SetupRouter, in the order of their registration,
+// and marks the service ready once they succeeded. The failed required steps are retried until the context is canceled.
+// It must be called after Startup, while the server is accepting connections so that /readyz can be probed.
+func WarmUp(ctx context.Context) error {
+	hooksMu.Lock()
+	steps := warmUpSteps
+	warmUpSteps = nil
+	hooksMu.Unlock()
+
+	return readiness.GetGate().WarmUp(ctx, steps)
+}
+
+// registerWarmUpSteps registers the steps avoiding the latency of the first requests:
+// loading the JWT keys, priming the validator, pinging the database, and optionally pre-filling the role cache
+// with the roles of the given number of users who logged in most recently (0 disables it).
+func registerWarmUpSteps(jwtConfig jwtconfig.JWTConfig, roleCacheUsers int, store database.DataStore, repos repositories) {
+	onWarmUp(readiness.Step{Name: "jwt_keys", Run: func(ctx context.Context) error {
+		return warmJWTKeys(jwtConfig)
+	}})
+
+	onWarmUp(readiness.Step{Name: "validator", Run: func(ctx context.Context) error {
+		return warmValidator()
+	}})
+
+	onWarmUp(readiness.Step{Name: "database", Run: func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, warmUpPingTimeout)
+		defer cancel()
+
+		return database.Ping(ctx)
+	}})
+
+	if warmer, ok := repos.user.(repository.RoleCacheWarmer); ok && roleCacheUsers > 0 {
+		onWarmUp(readiness.Step{Name: "role_cache", Optional: true, Run: func(ctx context.Context) error {
+			n, err := warmer.WarmRoleCache(store.DB(ctx), roleCacheUsers)
+			if err != nil {
+				return err
+			}
+
+			logger.Info("Role cache warmed up", logrus.Fields{"users": n})
+			return nil
+		}})
+	}
+}
+
+// warmJWTKeys loads and caches the keys signing and verifying the tokens, if they are signed with a key pair.
+func warmJWTKeys(jwtConfig jwtconfig.JWTConfig) error {
+	switch jwtConfig.SigningMethod {
+	case jwt.SigningMethodES256.Alg():
+		if _, err := jwtConfig.Keys.LoadECPrivateKey(); err != nil {
+			return fmt.Errorf("failed to load the JWT private key: %w", err)
+		}
+		if _, err := jwtConfig.Keys.LoadECPublicKey(); err != nil {
+			return fmt.Errorf("failed to load the JWT public key: %w", err)
+		}
+	case jwt.SigningMethodEdDSA.Alg():
+		if _, err := jwtConfig.Keys.LoadEdPrivateKey(); err != nil {
+			return fmt.Errorf("failed to load the JWT private key: %w", err)
+		}
+		if _, err := jwtConfig.Keys.LoadEdPublicKey(); err != nil {
+			return fmt.Errorf("failed to load the JWT public key: %w", err)
+		}
+	case jwt.SigningMethodRS256.Alg():
+		if _, err := jwtConfig.Keys.LoadSigningKey(); err != nil {
+			return fmt.Errorf("failed to load the JWT private key: %w", err)
+		}
+		if _, err := jwtConfig.Keys.LoadKeySet(); err != nil {
+			return fmt.Errorf("failed to load the JWT public keys: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// warmValidator validates a sample of each request payload, so that the validator caches the rules of their structs.
+// The validation errors of the empty payloads are expected and ignored.
+func warmValidator() error {
+	v := validation.GetValidator()
+	if v == nil {
+		return fmt.Errorf("validator is not initialized")
+	}
+
+	for _, payload := range []interface{}{
+		&entity.LoginRequest{},
+		&entity.RefreshTokenRequest{},
+		&entity.LogoutRequest{},
+		&entity.RegisterRequest{},
+		&entity.ForgotPasswordRequest{},
+		&entity.ResetPasswordRequest{},
+		&entity.ChangePasswordRequest{},
+		&entity.MFACodeRequest{},
+		&entity.VerifyMFARequest{},
+		&entity.Consumer{},
+		&entity.ConsumerAvailabilityRequest{},
+		&entity.UserStateRequest{},
+		&entity.CreateUserRequest{},
+		&entity.UpdateUserRequest{},
+		&entity.UserActionRequest{},
+		&entity.RoleRequest{},
+		&entity.UserRolesRequest{},
+	} {
+		_ = v.Struct(payload)
+	}
+
+	return nil
+}
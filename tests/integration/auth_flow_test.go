@@ -0,0 +1,141 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// doJSON performs a request against the application router and decodes the response.
+func doJSON(t *testing.T, method string, path string, token string, body interface{}) (int, httputil.HttpResponse) {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(payload)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, path, reader)
+	require.NoError(t, err)
+	req.Header.Set("Origin", testOrigin)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp httputil.HttpResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+
+	return w.Code, resp
+}
+
+// dataField returns a string field from the data object of the response.
+func dataField(t *testing.T, resp httputil.HttpResponse, key string) string {
+	t.Helper()
+
+	data, ok := resp.Data.(map[string]interface{})
+	require.True(t, ok, "response data is not an object")
+
+	value, _ := data[key].(string)
+	return value
+}
+
+func TestLogin_InvalidPassword(t *testing.T) {
+	status, resp := doJSON(t, "POST", "/auth/login", "", map[string]string{
+		"username": "admin",
+		"password": "wrong-password",
+	})
+
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.NotNil(t, resp.Error)
+}
+
+func TestLoginRefreshAndConsumerCRUD(t *testing.T) {
+	// Login with the seeded admin user
+	status, resp := doJSON(t, "POST", "/auth/login", "", map[string]string{
+		"username": "admin",
+		"password": "P@ssw0rd",
+	})
+	require.Equal(t, http.StatusOK, status, resp.Error)
+	accessToken := dataField(t, resp, "accessToken")
+	refreshToken := dataField(t, resp, "refreshToken")
+	require.NotEmpty(t, accessToken)
+	require.NotEmpty(t, refreshToken)
+
+	// Refresh the access token
+	status, resp = doJSON(t, "POST", "/auth/refresh-token", "", map[string]string{
+		"refreshToken": refreshToken,
+	})
+	require.Equal(t, http.StatusOK, status, resp.Error)
+	accessToken = dataField(t, resp, "accessToken")
+	assert.NotEqual(t, refreshToken, dataField(t, resp, "refreshToken"))
+
+	// The old refresh token must not be usable anymore
+	status, _ = doJSON(t, "POST", "/auth/refresh-token", "", map[string]string{
+		"refreshToken": refreshToken,
+	})
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// Create a consumer
+	status, resp = doJSON(t, "POST", "/api/v1/consumers", accessToken, map[string]string{
+		"fullname":  "Integration Consumer",
+		"username":  "integrationconsumer",
+		"email":     "integration.consumer@example.com",
+		"phone":     "081299998888",
+		"address":   "Jl. Integrasi No. 1, Jakarta",
+		"birthDate": "1990-01-01",
+	})
+	require.Equal(t, http.StatusCreated, status, resp.Error)
+	id := dataField(t, resp, "id")
+	require.NotEmpty(t, id)
+	assert.Equal(t, "6281299998888", dataField(t, resp, "phone"))
+	assert.Equal(t, "inactive", dataField(t, resp, "status"))
+
+	// Creating the same consumer again must fail
+	status, _ = doJSON(t, "POST", "/api/v1/consumers", accessToken, map[string]string{
+		"fullname":  "Integration Consumer",
+		"username":  "integrationconsumer",
+		"email":     "integration.consumer@example.com",
+		"phone":     "081299998888",
+		"address":   "Jl. Integrasi No. 1, Jakarta",
+		"birthDate": "1990-01-01",
+	})
+	assert.Equal(t, http.StatusConflict, status)
+
+	// Read the consumer back
+	status, resp = doJSON(t, "GET", "/api/v1/consumers/"+id, accessToken, nil)
+	require.Equal(t, http.StatusOK, status, resp.Error)
+	assert.Equal(t, "integrationconsumer", dataField(t, resp, "username"))
+
+	// Activate the consumer
+	status, resp = doJSON(t, "PATCH", "/api/v1/consumers/"+id+"?status=active", accessToken, nil)
+	require.Equal(t, http.StatusOK, status, resp.Error)
+	assert.Equal(t, "active", dataField(t, resp, "status"))
+
+	// The consumer is now listed among the active consumers
+	status, resp = doJSON(t, "GET", "/api/v1/consumers/active?limit=100", accessToken, nil)
+	require.Equal(t, http.StatusOK, status, resp.Error)
+	found := false
+	for _, item := range resp.Data.([]interface{}) {
+		if item.(map[string]interface{})["id"] == id {
+			found = true
+		}
+	}
+	assert.True(t, found, "created consumer is not listed among active consumers")
+}
@@ -0,0 +1,90 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+)
+
+func TestCreateConsumer_UniqueViolationIsDuplicatedKey(t *testing.T) {
+	repo := repository.NewConsumerRepository()
+	consumer := entity.Consumer{
+		Fullname: "Duplicate Consumer",
+		Username: "duplicateconsumer",
+		Email:    "duplicate.consumer@example.com",
+		Phone:    "6281277776666",
+		Address:  "Jl. Duplikat No. 1, Jakarta",
+		Status:   "inactive",
+	}
+
+	_, err := repo.CreateConsumer(context.Background(), database.GetPostgres(), consumer)
+	require.NoError(t, err)
+
+	// The unique index rejects the second row, reported as gorm.ErrDuplicatedKey
+	_, err = repo.CreateConsumer(context.Background(), database.GetPostgres(), consumer)
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+}
+
+func TestCreateConsumer_ConcurrentDuplicatesConflict(t *testing.T) {
+	status, resp := doJSON(t, "POST", "/auth/login", "", map[string]string{
+		"username": "admin",
+		"password": "P@ssw0rd",
+	})
+	require.Equal(t, http.StatusOK, status, resp.Error)
+	accessToken := dataField(t, resp, "accessToken")
+
+	payload, err := json.Marshal(map[string]string{
+		"fullname":  "Concurrent Consumer",
+		"username":  "concurrentconsumer",
+		"email":     "concurrent.consumer@example.com",
+		"phone":     "081277775555",
+		"address":   "Jl. Konkuren No. 1, Jakarta",
+		"birthDate": "1990-01-01",
+	})
+	require.NoError(t, err)
+
+	// The requests race past the existence checks of the service, the losers hit the unique index
+	const requests = 8
+	statuses := make([]int, requests)
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/consumers", bytes.NewReader(payload))
+			req.Header.Set("Origin", testOrigin)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			statuses[i] = w.Code
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, status := range statuses {
+		if status == http.StatusCreated {
+			created++
+			continue
+		}
+		assert.Equal(t, http.StatusConflict, status, fmt.Sprint(statuses))
+	}
+	assert.Equal(t, 1, created, fmt.Sprint(statuses))
+}
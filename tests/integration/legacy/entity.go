@@ -0,0 +1,92 @@
+package legacy
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * legacy package holds the entities of the first releases, whose tables were created with AutoMigrate before the versioned
+ * migrations, so that the integration tests create a database as those releases did and check that the migrations upgrade it.
+ * The entities must not change: they are the original schema, the baseline of the migrations.
+ */
+
+// Role is the role entity of the first releases.
+type Role struct {
+	ID   uint   `gorm:"primaryKey;autoIncrement"`
+	Name string `gorm:"type:varchar(20);not null;check:name IN ('ROLE_USER','ROLE_MODERATOR','ROLE_ADMIN')"`
+}
+
+// TableName returns the table of the roles.
+func (Role) TableName() string {
+	return "roles"
+}
+
+// User is the user entity of the first releases, with the status flags replaced by the state of the users since.
+type User struct {
+	ID                        int64      `gorm:"primaryKey;autoIncrement"`
+	Username                  string     `gorm:"type:varchar(20);not null;unique"`
+	Password                  string     `gorm:"type:varchar(150);not null"`
+	Email                     string     `gorm:"type:varchar(100);not null;unique"`
+	Firstname                 string     `gorm:"type:varchar(20);not null"`
+	Lastname                  *string    `gorm:"type:varchar(20)"`
+	IsEnabled                 *bool      `gorm:"not null;default:false"`
+	IsAccountNonExpired       *bool      `gorm:"not null;default:false"`
+	IsAccountNonLocked        *bool      `gorm:"not null;default:false"`
+	IsCredentialsNonExpired   *bool      `gorm:"not null;default:false"`
+	IsDeleted                 *bool      `gorm:"not null;default:false"`
+	AccountExpirationDate     *time.Time `gorm:"type:timestamptz"`
+	CredentialsExpirationDate *time.Time `gorm:"type:timestamptz"`
+	UserType                  string     `gorm:"type:varchar(20);not null;check:user_type IN ('SERVICE_ACCOUNT','USER_ACCOUNT')"`
+	LastLogin                 *time.Time
+	CreatedBy                 *int64
+	CreatedAt                 *time.Time `gorm:"type:timestamptz;autoCreateTime;default:now()"`
+	UpdatedBy                 *int64
+	UpdatedAt                 *time.Time `gorm:"type:timestamptz;autoUpdateTime;default:now()"`
+	DeletedBy                 *int64
+	DeletedAt                 *gorm.DeletedAt `gorm:"type:timestamptz;index"`
+	Roles                     []Role          `gorm:"many2many:user_roles;constraint:OnUpdate:RESTRICT,OnDelete:SET NULL"`
+}
+
+// TableName returns the table of the users.
+func (User) TableName() string {
+	return "users"
+}
+
+// RefreshToken is the refresh token entity of the first releases, a single one per user.
+type RefreshToken struct {
+	Token      string    `gorm:"column:token;type:text;primaryKey;unique;not null"`
+	UserID     int64     `gorm:"column:user_id;primaryKey;unique;not null"`
+	User       *User     `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	ExpiryDate time.Time `gorm:"column:expiry_date;type:timestamptz;not null"`
+}
+
+// TableName returns the table of the refresh tokens.
+func (RefreshToken) TableName() string {
+	return "refresh_token"
+}
+
+// Consumer is the consumer entity of the first releases, stored in plain text.
+type Consumer struct {
+	ID        string     `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Fullname  string     `gorm:"type:varchar(100);not null"`
+	Username  string     `gorm:"type:varchar(50);unique;not null"`
+	Email     string     `gorm:"type:varchar(100);unique;not null"`
+	Phone     string     `gorm:"type:varchar(20);unique;not null"`
+	Address   string     `gorm:"type:text;not null"`
+	BirthDate *time.Time `gorm:"type:date"`
+	Status    string     `gorm:"type:varchar(20);not null;default:'inactive';check:status IN ('active','inactive','suspended')"`
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamptz;autoCreateTime;default:now()"`
+	UpdatedAt time.Time  `gorm:"column:updated_at;type:timestamptz;autoUpdateTime;default:now()"`
+}
+
+// TableName returns the table of the consumers.
+func (Consumer) TableName() string {
+	return "consumers"
+}
+
+// Entities returns the entities in the order the first releases migrated them.
+func Entities() []interface{} {
+	return []interface{}{&Role{}, &User{}, &RefreshToken{}, &Consumer{}}
+}
@@ -0,0 +1,178 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/routes"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+/**
+ * The integration test suite spins up a real PostgreSQL container (and a Redis container when
+ * INTEGRATION_REDIS=TRUE) using testcontainers, runs the migrations and the seed file,
+ * and exercises the HTTP API end-to-end against real SQL.
+ * It only runs with the `integration` build tag so the unit tests stay fast:
+ *
+ *	go test -tags integration ./tests/integration/...
+ *
+ * With E2E_SHARED_DB=TRUE no PostgreSQL container is started. The suite connects to the database
+ * configured with the DB_* variables instead, boots against a uniquely named schema, and drops it
+ * afterward, so that parallel CI runs can share a single database instance.
+ */
+
+const (
+	postgresImage = "postgres:16-alpine"
+	redisImage    = "redis:7-alpine"
+	testOrigin    = "http://localhost:3000"
+	testSecret    = "integration-secret-that-is-at-least-256-bits-long"
+)
+
+// router is the application router shared by all integration tests.
+var router *gin.Engine
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+// run starts the containers, configures the environment, runs the tests, and cleans up.
+func run(m *testing.M) int {
+	ctx := context.Background()
+
+	seedFile, err := filepath.Abs(filepath.Join("..", "..", "import.sql"))
+	if err != nil {
+		fmt.Printf("failed to resolve seed file: %v\n", err)
+		return 1
+	}
+
+	setEnv(map[string]string{
+		"ENV":                               "INTEGRATION",
+		"FRONTEND_URL":                      testOrigin,
+		"DB_DRIVER":                         database.DriverPostgres,
+		"DB_MIGRATE":                        "TRUE",
+		"DB_SEED":                           "TRUE",
+		"DB_SEED_FILE":                      seedFile,
+		"DB_LOG":                            "SILENT",
+		"JWT_SECRET":                        testSecret,
+		"JWT_ALGORITHM":                     "HS256",
+		"JWT_ACCESS_TOKEN_TTL_MINUTES":      "60",
+		"JWT_REFRESH_TOKEN_EXPIRATION_HOUR": "24",
+		"JWT_AUDIENCE":                      "integration_audience",
+		"JWT_ISSUER":                        "integration_issuer",
+		"TOKEN_TYPE":                        "Bearer",
+	})
+
+	sharedDB := os.Getenv("E2E_SHARED_DB") == "TRUE"
+	if sharedDB {
+		// Use the configured database with a schema of its own for this run
+		schema := testsupport.EphemeralSchemaName("e2e")
+		setEnv(map[string]string{"DB_SCHEMA": schema})
+		fmt.Printf("running end-to-end tests in ephemeral schema %s\n", schema)
+	} else {
+		pg, err := postgres.Run(ctx, postgresImage,
+			postgres.WithDatabase("golang_demo"),
+			postgres.WithUsername("postgres"),
+			postgres.WithPassword("postgres"),
+			postgres.BasicWaitStrategies(),
+		)
+		defer func() {
+			if err := testcontainers.TerminateContainer(pg); err != nil {
+				fmt.Printf("failed to terminate postgres container: %v\n", err)
+			}
+		}()
+		if err != nil {
+			fmt.Printf("failed to start postgres container: %v\n", err)
+			return 1
+		}
+
+		host, err := pg.Host(ctx)
+		if err != nil {
+			fmt.Printf("failed to get postgres host: %v\n", err)
+			return 1
+		}
+		port, err := pg.MappedPort(ctx, "5432/tcp")
+		if err != nil {
+			fmt.Printf("failed to get postgres port: %v\n", err)
+			return 1
+		}
+
+		setEnv(map[string]string{
+			"DB_HOST":     host,
+			"DB_PORT":     port.Port(),
+			"DB_USER":     "postgres",
+			"DB_PASS":     "postgres",
+			"DB_NAME":     "golang_demo",
+			"DB_SCHEMA":   "public",
+			"DB_SSL_MODE": "disable",
+			"DB_TIMEZONE": "UTC",
+		})
+	}
+
+	// Start Redis only when configured, for the features that rely on it
+	if os.Getenv("INTEGRATION_REDIS") == "TRUE" {
+		redis, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+			ContainerRequest: testcontainers.ContainerRequest{
+				Image:        redisImage,
+				ExposedPorts: []string{"6379/tcp"},
+				WaitingFor:   wait.ForListeningPort("6379/tcp").WithStartupTimeout(30 * time.Second),
+			},
+			Started: true,
+		})
+		defer func() {
+			if err := testcontainers.TerminateContainer(redis); err != nil {
+				fmt.Printf("failed to terminate redis container: %v\n", err)
+			}
+		}()
+		if err != nil {
+			fmt.Printf("failed to start redis container: %v\n", err)
+			return 1
+		}
+
+		endpoint, err := redis.Endpoint(ctx, "")
+		if err != nil {
+			fmt.Printf("failed to get redis endpoint: %v\n", err)
+			return 1
+		}
+		setEnv(map[string]string{"REDIS_ADDR": endpoint})
+	}
+
+	// Connect to the database, run the migrations, and import the seed data
+	if !database.InitPostgres() {
+		fmt.Println("failed to initialize postgres")
+		return 1
+	}
+	defer database.ClosePostgres()
+
+	// Drop the ephemeral schema before the connection is closed, even if the tests failed
+	if sharedDB {
+		defer func() {
+			if err := database.DropPostgresSchema(); err != nil {
+				fmt.Printf("failed to drop ephemeral schema: %v\n", err)
+			}
+		}()
+	}
+
+	gin.SetMode(gin.TestMode)
+	router = routes.SetupRouter()
+
+	return m.Run()
+}
+
+// setEnv sets the given environment variables for the test process.
+func setEnv(vars map[string]string) {
+	for k, v := range vars {
+		os.Setenv(k, v)
+	}
+}
@@ -0,0 +1,188 @@
+//go:build integration
+
+package integration
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormpostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/integration/legacy"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// openLegacySchema creates a schema of its own with the tables of the first releases, created with AutoMigrate as they did,
+// and returns a connection using it with the settings of the migrations. The schema is dropped at the end of the test.
+func openLegacySchema(t *testing.T) (*gorm.DB, database.Config) {
+	t.Helper()
+
+	cfg := database.Config{Schema: testsupport.EphemeralSchemaName("legacy")}
+	require.NoError(t, database.GetPostgres().Exec(fmt.Sprintf("CREATE SCHEMA %s", cfg.Schema)).Error)
+	t.Cleanup(func() {
+		database.GetPostgres().Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", cfg.Schema))
+	})
+
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=UTC search_path=%s",
+		os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_USER"), os.Getenv("DB_PASS"), os.Getenv("DB_NAME"), os.Getenv("DB_SSL_MODE"), cfg.Schema)
+	conn, err := gorm.Open(gormpostgres.Open(dsn), &gorm.Config{Logger: gormLogger.Default.LogMode(gormLogger.Silent)})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	require.NoError(t, conn.AutoMigrate(legacy.Entities()...))
+	return conn, cfg
+}
+
+// legacyUser returns a user of the first releases with the given status flags.
+func legacyUser(username string, enabled, nonExpired, nonLocked, credentialsNonExpired bool, role legacy.Role) legacy.User {
+	no := false
+	return legacy.User{
+		Username: username, Password: "hash", Email: username + "@example.com", Firstname: username, UserType: "USER_ACCOUNT",
+		IsEnabled: &enabled, IsAccountNonExpired: &nonExpired, IsAccountNonLocked: &nonLocked, IsCredentialsNonExpired: &credentialsNonExpired,
+		IsDeleted: &no, Roles: []legacy.Role{role},
+	}
+}
+
+func TestMigrateUp_UpgradesTheOriginalSchema(t *testing.T) {
+	conn, cfg := openLegacySchema(t)
+
+	// The data of a database of the first releases
+	roles := []legacy.Role{{Name: "ROLE_USER"}, {Name: "ROLE_MODERATOR"}, {Name: "ROLE_ADMIN"}}
+	require.NoError(t, conn.Create(&roles).Error)
+	user := legacyUser("active", true, true, true, true, roles[0])
+	require.NoError(t, conn.Create(&user).Error)
+	require.NoError(t, conn.Create(&legacy.RefreshToken{Token: "legacy-refresh-token", UserID: user.ID, ExpiryDate: time.Now().Add(time.Hour)}).Error)
+	birthDate := time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, conn.Create(&legacy.Consumer{
+		Fullname: "Legacy Consumer", Username: "legacyconsumer", Email: "legacy.consumer@example.com",
+		Phone: "6281200001111", Address: "Jl. Lama No. 1, Jakarta", BirthDate: &birthDate, Status: "active",
+	}).Error)
+
+	// The database is baselined as it is and upgraded by every version
+	all, err := database.Migrations(cfg)
+	require.NoError(t, err)
+	applied, err := database.MigrateUp(conn, cfg, 0)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(all))
+
+	// The built-in roles are granted their default permissions
+	var granted int64
+	require.NoError(t, conn.Table("role_permissions").Joins("JOIN roles ON roles.id = role_permissions.role_id").Where("roles.name = ?", "ROLE_ADMIN").Count(&granted).Error)
+	assert.Equal(t, int64(6), granted)
+
+	// The refresh token becomes a session, and the consumer is indexed and read back
+	var token entity.RefreshToken
+	require.NoError(t, conn.First(&token, "token = ?", "legacy-refresh-token").Error)
+	assert.NotEmpty(t, token.SessionID)
+	assert.Equal(t, user.ID, token.UserID)
+
+	var consumer entity.Consumer
+	require.NoError(t, conn.First(&consumer, "username = ?", "legacyconsumer").Error)
+	assert.Equal(t, "6281200001111", consumer.Phone)
+	assert.Equal(t, entity.ConsumerPhoneIndex("6281200001111"), consumer.PhoneIndex)
+	assert.Equal(t, entity.ConsumerEmailIndex("legacy.consumer@example.com"), consumer.EmailIndex)
+	require.NotNil(t, consumer.BirthDate)
+	assert.Equal(t, "1990-05-10", consumer.BirthDate.String())
+
+	// Reverting every version returns the database to the original schema
+	reverted, err := database.MigrateDown(conn, cfg, len(all)-1)
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(all)-1)
+
+	var originalConsumer legacy.Consumer
+	require.NoError(t, conn.First(&originalConsumer, "username = ?", "legacyconsumer").Error)
+	assert.Equal(t, "6281200001111", originalConsumer.Phone)
+	require.NotNil(t, originalConsumer.BirthDate)
+	assert.Equal(t, "1990-05-10", originalConsumer.BirthDate.Format("2006-01-02"))
+}
+
+func TestMigrateUp_RejectsADatabaseNotMatchingTheBaseline(t *testing.T) {
+	conn, cfg := openLegacySchema(t)
+	require.NoError(t, conn.Exec("ALTER TABLE users DROP COLUMN is_enabled").Error)
+	require.NoError(t, conn.Exec("ALTER TABLE users ADD COLUMN state varchar(20)").Error)
+
+	// The columns are compared, not only the tables, and nothing is recorded
+	applied, err := database.MigrateUp(conn, cfg, 0)
+	require.Error(t, err)
+	assert.Empty(t, applied)
+	assert.Contains(t, err.Error(), "table users lacks the columns is_enabled")
+	assert.Contains(t, err.Error(), "table users has the columns state not in the baseline")
+
+	states, err := database.MigrationStatus(conn, cfg)
+	require.NoError(t, err)
+	for _, s := range states {
+		assert.Nil(t, s.AppliedAt, s.Name)
+	}
+}
+
+func TestMigrateUp_MapsTheFlagsOfTheUsersToTheirStates(t *testing.T) {
+	conn, cfg := openLegacySchema(t)
+
+	role := legacy.Role{Name: "ROLE_USER"}
+	require.NoError(t, conn.Create(&role).Error)
+	users := []legacy.User{
+		legacyUser("active", true, true, true, true, role),
+		legacyUser("locked", true, true, false, true, role),
+		legacyUser("lockeddisabled", false, true, false, true, role),
+		legacyUser("notenabled", false, true, true, true, role),
+		legacyUser("expired", true, false, true, true, role),
+		legacyUser("credsexpired", true, true, true, false, role),
+	}
+	require.NoError(t, conn.Create(&users).Error)
+
+	// A locked account is suspended, an account not enabled or expired is disabled, with the flag it was migrated from
+	all, err := database.Migrations(cfg)
+	require.NoError(t, err)
+	_, err = database.MigrateUp(conn, cfg, 0)
+	require.NoError(t, err)
+
+	var migrated []entity.User
+	require.NoError(t, conn.Order("id").Find(&migrated).Error)
+	states := map[string][2]string{}
+	for _, u := range migrated {
+		reason := ""
+		if u.StateReason != nil {
+			reason = *u.StateReason
+		}
+		states[u.Username] = [2]string{string(u.State), reason}
+	}
+	assert.Equal(t, map[string][2]string{
+		"active":         {string(entity.UserStateActive), ""},
+		"locked":         {string(entity.UserStateSuspended), "Migrated: account locked"},
+		"lockeddisabled": {string(entity.UserStateSuspended), "Migrated: account locked"},
+		"notenabled":     {string(entity.UserStateDisabled), "Migrated: account not enabled"},
+		"expired":        {string(entity.UserStateDisabled), "Migrated: account expired"},
+		"credsexpired":   {string(entity.UserStateDisabled), "Migrated: credentials expired"},
+	}, states)
+
+	// Reverting down to the version before the states restores the flags, except the ones hidden by the lock
+	_, err = database.MigrateDown(conn, cfg, len(all)-4)
+	require.NoError(t, err)
+
+	var original []legacy.User
+	require.NoError(t, conn.Order("id").Find(&original).Error)
+	flags := map[string][4]bool{}
+	for _, u := range original {
+		flags[u.Username] = [4]bool{*u.IsEnabled, *u.IsAccountNonExpired, *u.IsAccountNonLocked, *u.IsCredentialsNonExpired}
+	}
+	assert.Equal(t, map[string][4]bool{
+		"active":         {true, true, true, true},
+		"locked":         {true, true, false, true},
+		"lockeddisabled": {true, true, false, true},
+		"notenabled":     {false, true, true, true},
+		"expired":        {true, false, true, true},
+		"credsexpired":   {true, true, true, false},
+	}, flags)
+}
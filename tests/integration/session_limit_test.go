@@ -0,0 +1,68 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+)
+
+func TestCreateRefreshToken_ConcurrentLoginsRespectTheSessionLimit(t *testing.T) {
+	var user entity.User
+	require.NoError(t, database.GetPostgres().Select("id").Take(&user, "username = ?", "admin").Error)
+	require.NoError(t, database.GetPostgres().Where("user_id = ?", user.ID).Delete(&entity.RefreshToken{}).Error)
+	t.Cleanup(func() {
+		database.GetPostgres().Where("user_id = ?", user.ID).Delete(&entity.RefreshToken{})
+	})
+
+	policy := policyconfig.SessionPolicy{MaxSessions: 1, Mode: policyconfig.SessionLimitModeReject, RefreshTokenTTL: time.Hour}
+	s := service.NewRefreshTokenService(database.DefaultStore(), repository.NewRefreshTokenRepository(), policy, clock.New())
+
+	// The user has no session yet: the logins are serialized by the lock of the user, not by the rows of the sessions
+	const logins = 8
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		created  int
+		rejected int
+	)
+	for i := 0; i < logins; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.CreateRefreshToken(context.Background(), user.ID, entity.SessionDevice{})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(err, service.ErrSessionLimitReached):
+				rejected++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, created)
+	assert.Equal(t, logins-1, rejected)
+
+	var sessions int64
+	require.NoError(t, database.GetPostgres().Model(&entity.RefreshToken{}).Where("user_id = ?", user.ID).Count(&sessions).Error)
+	assert.Equal(t, int64(1), sessions)
+}
@@ -0,0 +1,80 @@
+//go:build integration
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/transaction"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// newTransactionalRouter creates a router whose handler creates the role named by the request through the role service,
+// in the transaction of the request, and then responds with the given status.
+func newTransactionalRouter(t *testing.T, status int) *gin.Engine {
+	t.Helper()
+
+	roles := service.NewRoleService(database.DefaultStore(), repository.NewRoleRepository(), repository.NewPermissionRepository())
+	r := gin.New()
+	r.POST("/roles/:name", transaction.Transactional(), func(c *gin.Context) {
+		role, err := roles.CreateRole(c.Request.Context(), entity.RoleRequest{Name: c.Param("name")})
+		require.NoError(t, err)
+
+		// A later step of the handler decides the response
+		switch status {
+		case http.StatusCreated:
+			httputil.Created(c, "Role created successfully", role)
+		case http.StatusConflict:
+			httputil.Conflict(c, "Conflict", "a later step failed")
+		default:
+			httputil.InternalServerError(c, "Internal server error", "a later step failed")
+		}
+	})
+
+	return r
+}
+
+// roleExists reports whether the role is in the database, read outside of any transaction.
+func roleExists(t *testing.T, name string) bool {
+	t.Helper()
+
+	var count int64
+	require.NoError(t, database.GetPostgres().Model(&entity.Role{}).Where("name = ?", name).Count(&count).Error)
+	return count > 0
+}
+
+func TestTransactional_ErrorResponseRollsBackTheWritesOfTheService(t *testing.T) {
+	for _, status := range []int{http.StatusConflict, http.StatusInternalServerError} {
+		name := fmt.Sprintf("ROLE_ROLLED_BACK_%d", status)
+		w := httptest.NewRecorder()
+		newTransactionalRouter(t, status).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/roles/"+name, nil))
+
+		// The role was written by the service in the transaction of the request, rolled back with the response
+		assert.Equal(t, status, w.Code)
+		assert.False(t, roleExists(t, name), "role %s must be rolled back", name)
+	}
+}
+
+func TestTransactional_SuccessfulResponseCommitsTheWritesOfTheService(t *testing.T) {
+	name := "ROLE_COMMITTED"
+	t.Cleanup(func() {
+		database.GetPostgres().Where("name = ?", name).Delete(&entity.Role{})
+	})
+
+	w := httptest.NewRecorder()
+	newTransactionalRouter(t, http.StatusCreated).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/roles/"+name, nil))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.True(t, roleExists(t, name))
+}
@@ -0,0 +1,118 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: api-key.go
+//
+// Generated by this command:
+//
+//	mockgen -source=api-key.go -destination=../../tests/mocks/api-key-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockApiKeyRepository is a mock of ApiKeyRepository interface.
+type MockApiKeyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockApiKeyRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockApiKeyRepositoryMockRecorder is the mock recorder for MockApiKeyRepository.
+type MockApiKeyRepositoryMockRecorder struct {
+	mock *MockApiKeyRepository
+}
+
+// NewMockApiKeyRepository creates a new mock instance.
+func NewMockApiKeyRepository(ctrl *gomock.Controller) *MockApiKeyRepository {
+	mock := &MockApiKeyRepository{ctrl: ctrl}
+	mock.recorder = &MockApiKeyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockApiKeyRepository) EXPECT() *MockApiKeyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateApiKey mocks base method.
+func (m *MockApiKeyRepository) CreateApiKey(tx *gorm.DB, key entity.ApiKey) (entity.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateApiKey", tx, key)
+	ret0, _ := ret[0].(entity.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateApiKey indicates an expected call of CreateApiKey.
+func (mr *MockApiKeyRepositoryMockRecorder) CreateApiKey(tx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateApiKey", reflect.TypeOf((*MockApiKeyRepository)(nil).CreateApiKey), tx, key)
+}
+
+// GetApiKeyByHash mocks base method.
+func (m *MockApiKeyRepository) GetApiKeyByHash(tx *gorm.DB, keyHash string) (entity.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApiKeyByHash", tx, keyHash)
+	ret0, _ := ret[0].(entity.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApiKeyByHash indicates an expected call of GetApiKeyByHash.
+func (mr *MockApiKeyRepositoryMockRecorder) GetApiKeyByHash(tx, keyHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApiKeyByHash", reflect.TypeOf((*MockApiKeyRepository)(nil).GetApiKeyByHash), tx, keyHash)
+}
+
+// GetApiKeyByID mocks base method.
+func (m *MockApiKeyRepository) GetApiKeyByID(tx *gorm.DB, id int64) (entity.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApiKeyByID", tx, id)
+	ret0, _ := ret[0].(entity.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApiKeyByID indicates an expected call of GetApiKeyByID.
+func (mr *MockApiKeyRepositoryMockRecorder) GetApiKeyByID(tx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApiKeyByID", reflect.TypeOf((*MockApiKeyRepository)(nil).GetApiKeyByID), tx, id)
+}
+
+// GetApiKeysByUserID mocks base method.
+func (m *MockApiKeyRepository) GetApiKeysByUserID(tx *gorm.DB, userID int64) ([]entity.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApiKeysByUserID", tx, userID)
+	ret0, _ := ret[0].([]entity.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApiKeysByUserID indicates an expected call of GetApiKeysByUserID.
+func (mr *MockApiKeyRepositoryMockRecorder) GetApiKeysByUserID(tx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApiKeysByUserID", reflect.TypeOf((*MockApiKeyRepository)(nil).GetApiKeysByUserID), tx, userID)
+}
+
+// RevokeApiKey mocks base method.
+func (m *MockApiKeyRepository) RevokeApiKey(tx *gorm.DB, id int64, revokedAt time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeApiKey", tx, id, revokedAt)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeApiKey indicates an expected call of RevokeApiKey.
+func (mr *MockApiKeyRepositoryMockRecorder) RevokeApiKey(tx, id, revokedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeApiKey", reflect.TypeOf((*MockApiKeyRepository)(nil).RevokeApiKey), tx, id, revokedAt)
+}
@@ -0,0 +1,117 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: api-key.go
+//
+// Generated by this command:
+//
+//	mockgen -source=api-key.go -destination=../../tests/mocks/api-key-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockApiKeyService is a mock of ApiKeyService interface.
+type MockApiKeyService struct {
+	ctrl     *gomock.Controller
+	recorder *MockApiKeyServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockApiKeyServiceMockRecorder is the mock recorder for MockApiKeyService.
+type MockApiKeyServiceMockRecorder struct {
+	mock *MockApiKeyService
+}
+
+// NewMockApiKeyService creates a new mock instance.
+func NewMockApiKeyService(ctrl *gomock.Controller) *MockApiKeyService {
+	mock := &MockApiKeyService{ctrl: ctrl}
+	mock.recorder = &MockApiKeyServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockApiKeyService) EXPECT() *MockApiKeyServiceMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockApiKeyService) Authenticate(ctx context.Context, key string) (metacontext.UserInformationMeta, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, key)
+	ret0, _ := ret[0].(metacontext.UserInformationMeta)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockApiKeyServiceMockRecorder) Authenticate(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockApiKeyService)(nil).Authenticate), ctx, key)
+}
+
+// CreateApiKey mocks base method.
+func (m *MockApiKeyService) CreateApiKey(ctx context.Context, userID int64, req entity.ApiKeyRequest, createdBy int64) (entity.IssuedApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateApiKey", ctx, userID, req, createdBy)
+	ret0, _ := ret[0].(entity.IssuedApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateApiKey indicates an expected call of CreateApiKey.
+func (mr *MockApiKeyServiceMockRecorder) CreateApiKey(ctx, userID, req, createdBy any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateApiKey", reflect.TypeOf((*MockApiKeyService)(nil).CreateApiKey), ctx, userID, req, createdBy)
+}
+
+// GetApiKeys mocks base method.
+func (m *MockApiKeyService) GetApiKeys(ctx context.Context, userID int64) ([]entity.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApiKeys", ctx, userID)
+	ret0, _ := ret[0].([]entity.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApiKeys indicates an expected call of GetApiKeys.
+func (mr *MockApiKeyServiceMockRecorder) GetApiKeys(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApiKeys", reflect.TypeOf((*MockApiKeyService)(nil).GetApiKeys), ctx, userID)
+}
+
+// RevokeApiKey mocks base method.
+func (m *MockApiKeyService) RevokeApiKey(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeApiKey", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeApiKey indicates an expected call of RevokeApiKey.
+func (mr *MockApiKeyServiceMockRecorder) RevokeApiKey(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeApiKey", reflect.TypeOf((*MockApiKeyService)(nil).RevokeApiKey), ctx, id)
+}
+
+// RotateApiKey mocks base method.
+func (m *MockApiKeyService) RotateApiKey(ctx context.Context, id, rotatedBy int64) (entity.IssuedApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateApiKey", ctx, id, rotatedBy)
+	ret0, _ := ret[0].(entity.IssuedApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateApiKey indicates an expected call of RotateApiKey.
+func (mr *MockApiKeyServiceMockRecorder) RotateApiKey(ctx, id, rotatedBy any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateApiKey", reflect.TypeOf((*MockApiKeyService)(nil).RotateApiKey), ctx, id, rotatedBy)
+}
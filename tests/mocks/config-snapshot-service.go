@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: config-snapshot.go
+//
+// Generated by this command:
+//
+//	mockgen -source=config-snapshot.go -destination=../../tests/mocks/config-snapshot-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockConfigSnapshotService is a mock of ConfigSnapshotService interface.
+type MockConfigSnapshotService struct {
+	ctrl     *gomock.Controller
+	recorder *MockConfigSnapshotServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockConfigSnapshotServiceMockRecorder is the mock recorder for MockConfigSnapshotService.
+type MockConfigSnapshotServiceMockRecorder struct {
+	mock *MockConfigSnapshotService
+}
+
+// NewMockConfigSnapshotService creates a new mock instance.
+func NewMockConfigSnapshotService(ctrl *gomock.Controller) *MockConfigSnapshotService {
+	mock := &MockConfigSnapshotService{ctrl: ctrl}
+	mock.recorder = &MockConfigSnapshotServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConfigSnapshotService) EXPECT() *MockConfigSnapshotServiceMockRecorder {
+	return m.recorder
+}
+
+// GetConfigSnapshot mocks base method.
+func (m *MockConfigSnapshotService) GetConfigSnapshot() (entity.ConfigSnapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConfigSnapshot")
+	ret0, _ := ret[0].(entity.ConfigSnapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConfigSnapshot indicates an expected call of GetConfigSnapshot.
+func (mr *MockConfigSnapshotServiceMockRecorder) GetConfigSnapshot() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConfigSnapshot", reflect.TypeOf((*MockConfigSnapshotService)(nil).GetConfigSnapshot))
+}
@@ -0,0 +1,221 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: consumer.go
+//
+// Generated by this command:
+//
+//	mockgen -source=consumer.go -destination=../../tests/mocks/consumer-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockConsumerRepository is a mock of ConsumerRepository interface.
+type MockConsumerRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockConsumerRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockConsumerRepositoryMockRecorder is the mock recorder for MockConsumerRepository.
+type MockConsumerRepositoryMockRecorder struct {
+	mock *MockConsumerRepository
+}
+
+// NewMockConsumerRepository creates a new mock instance.
+func NewMockConsumerRepository(ctrl *gomock.Controller) *MockConsumerRepository {
+	mock := &MockConsumerRepository{ctrl: ctrl}
+	mock.recorder = &MockConsumerRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConsumerRepository) EXPECT() *MockConsumerRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateConsumer mocks base method.
+func (m *MockConsumerRepository) CreateConsumer(ctx context.Context, tx *gorm.DB, d entity.Consumer) (entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateConsumer", ctx, tx, d)
+	ret0, _ := ret[0].(entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateConsumer indicates an expected call of CreateConsumer.
+func (mr *MockConsumerRepositoryMockRecorder) CreateConsumer(ctx, tx, d any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateConsumer", reflect.TypeOf((*MockConsumerRepository)(nil).CreateConsumer), ctx, tx, d)
+}
+
+// GetAllConsumers mocks base method.
+func (m *MockConsumerRepository) GetAllConsumers(ctx context.Context, tx *gorm.DB, page, limit int) ([]entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllConsumers", ctx, tx, page, limit)
+	ret0, _ := ret[0].([]entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllConsumers indicates an expected call of GetAllConsumers.
+func (mr *MockConsumerRepositoryMockRecorder) GetAllConsumers(ctx, tx, page, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllConsumers", reflect.TypeOf((*MockConsumerRepository)(nil).GetAllConsumers), ctx, tx, page, limit)
+}
+
+// GetConsumerByEmail mocks base method.
+func (m *MockConsumerRepository) GetConsumerByEmail(ctx context.Context, tx *gorm.DB, email string) (entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsumerByEmail", ctx, tx, email)
+	ret0, _ := ret[0].(entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsumerByEmail indicates an expected call of GetConsumerByEmail.
+func (mr *MockConsumerRepositoryMockRecorder) GetConsumerByEmail(ctx, tx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsumerByEmail", reflect.TypeOf((*MockConsumerRepository)(nil).GetConsumerByEmail), ctx, tx, email)
+}
+
+// GetConsumerByID mocks base method.
+func (m *MockConsumerRepository) GetConsumerByID(ctx context.Context, tx *gorm.DB, id string) (entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsumerByID", ctx, tx, id)
+	ret0, _ := ret[0].(entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsumerByID indicates an expected call of GetConsumerByID.
+func (mr *MockConsumerRepositoryMockRecorder) GetConsumerByID(ctx, tx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsumerByID", reflect.TypeOf((*MockConsumerRepository)(nil).GetConsumerByID), ctx, tx, id)
+}
+
+// GetConsumerByPhone mocks base method.
+func (m *MockConsumerRepository) GetConsumerByPhone(ctx context.Context, tx *gorm.DB, phone string) (entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsumerByPhone", ctx, tx, phone)
+	ret0, _ := ret[0].(entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsumerByPhone indicates an expected call of GetConsumerByPhone.
+func (mr *MockConsumerRepositoryMockRecorder) GetConsumerByPhone(ctx, tx, phone any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsumerByPhone", reflect.TypeOf((*MockConsumerRepository)(nil).GetConsumerByPhone), ctx, tx, phone)
+}
+
+// GetConsumerByUsername mocks base method.
+func (m *MockConsumerRepository) GetConsumerByUsername(ctx context.Context, tx *gorm.DB, username string) (entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsumerByUsername", ctx, tx, username)
+	ret0, _ := ret[0].(entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsumerByUsername indicates an expected call of GetConsumerByUsername.
+func (mr *MockConsumerRepositoryMockRecorder) GetConsumerByUsername(ctx, tx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsumerByUsername", reflect.TypeOf((*MockConsumerRepository)(nil).GetConsumerByUsername), ctx, tx, username)
+}
+
+// GetConsumersByStatus mocks base method.
+func (m *MockConsumerRepository) GetConsumersByStatus(ctx context.Context, tx *gorm.DB, status string, page, limit int) ([]entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsumersByStatus", ctx, tx, status, page, limit)
+	ret0, _ := ret[0].([]entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsumersByStatus indicates an expected call of GetConsumersByStatus.
+func (mr *MockConsumerRepositoryMockRecorder) GetConsumersByStatus(ctx, tx, status, page, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsumersByStatus", reflect.TypeOf((*MockConsumerRepository)(nil).GetConsumersByStatus), ctx, tx, status, page, limit)
+}
+
+// GetConsumersExcludingStatuses mocks base method.
+func (m *MockConsumerRepository) GetConsumersExcludingStatuses(ctx context.Context, tx *gorm.DB, statuses []string, page, limit int) ([]entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsumersExcludingStatuses", ctx, tx, statuses, page, limit)
+	ret0, _ := ret[0].([]entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsumersExcludingStatuses indicates an expected call of GetConsumersExcludingStatuses.
+func (mr *MockConsumerRepositoryMockRecorder) GetConsumersExcludingStatuses(ctx, tx, statuses, page, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsumersExcludingStatuses", reflect.TypeOf((*MockConsumerRepository)(nil).GetConsumersExcludingStatuses), ctx, tx, statuses, page, limit)
+}
+
+// RestoreConsumer mocks base method.
+func (m *MockConsumerRepository) RestoreConsumer(ctx context.Context, tx *gorm.DB, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreConsumer", ctx, tx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreConsumer indicates an expected call of RestoreConsumer.
+func (mr *MockConsumerRepositoryMockRecorder) RestoreConsumer(ctx, tx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreConsumer", reflect.TypeOf((*MockConsumerRepository)(nil).RestoreConsumer), ctx, tx, id)
+}
+
+// SoftDeleteConsumer mocks base method.
+func (m *MockConsumerRepository) SoftDeleteConsumer(ctx context.Context, tx *gorm.DB, id string, deletedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SoftDeleteConsumer", ctx, tx, id, deletedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SoftDeleteConsumer indicates an expected call of SoftDeleteConsumer.
+func (mr *MockConsumerRepositoryMockRecorder) SoftDeleteConsumer(ctx, tx, id, deletedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SoftDeleteConsumer", reflect.TypeOf((*MockConsumerRepository)(nil).SoftDeleteConsumer), ctx, tx, id, deletedAt)
+}
+
+// StreamConsumers mocks base method.
+func (m *MockConsumerRepository) StreamConsumers(ctx context.Context, tx *gorm.DB, excludedStatuses []string, fn func(entity.Consumer) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamConsumers", ctx, tx, excludedStatuses, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamConsumers indicates an expected call of StreamConsumers.
+func (mr *MockConsumerRepositoryMockRecorder) StreamConsumers(ctx, tx, excludedStatuses, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamConsumers", reflect.TypeOf((*MockConsumerRepository)(nil).StreamConsumers), ctx, tx, excludedStatuses, fn)
+}
+
+// UpdateConsumer mocks base method.
+func (m *MockConsumerRepository) UpdateConsumer(ctx context.Context, tx *gorm.DB, d entity.Consumer) (entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateConsumer", ctx, tx, d)
+	ret0, _ := ret[0].(entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateConsumer indicates an expected call of UpdateConsumer.
+func (mr *MockConsumerRepositoryMockRecorder) UpdateConsumer(ctx, tx, d any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateConsumer", reflect.TypeOf((*MockConsumerRepository)(nil).UpdateConsumer), ctx, tx, d)
+}
@@ -0,0 +1,220 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: consumer.go
+//
+// Generated by this command:
+//
+//	mockgen -source=consumer.go -destination=../../tests/mocks/consumer-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockConsumerService is a mock of ConsumerService interface.
+type MockConsumerService struct {
+	ctrl     *gomock.Controller
+	recorder *MockConsumerServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockConsumerServiceMockRecorder is the mock recorder for MockConsumerService.
+type MockConsumerServiceMockRecorder struct {
+	mock *MockConsumerService
+}
+
+// NewMockConsumerService creates a new mock instance.
+func NewMockConsumerService(ctrl *gomock.Controller) *MockConsumerService {
+	mock := &MockConsumerService{ctrl: ctrl}
+	mock.recorder = &MockConsumerServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConsumerService) EXPECT() *MockConsumerServiceMockRecorder {
+	return m.recorder
+}
+
+// CheckConsumerAvailability mocks base method.
+func (m *MockConsumerService) CheckConsumerAvailability(ctx context.Context, req entity.ConsumerAvailabilityRequest) (entity.ConsumerAvailability, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckConsumerAvailability", ctx, req)
+	ret0, _ := ret[0].(entity.ConsumerAvailability)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckConsumerAvailability indicates an expected call of CheckConsumerAvailability.
+func (mr *MockConsumerServiceMockRecorder) CheckConsumerAvailability(ctx, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckConsumerAvailability", reflect.TypeOf((*MockConsumerService)(nil).CheckConsumerAvailability), ctx, req)
+}
+
+// CreateConsumer mocks base method.
+func (m *MockConsumerService) CreateConsumer(ctx context.Context, c entity.Consumer) (entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateConsumer", ctx, c)
+	ret0, _ := ret[0].(entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateConsumer indicates an expected call of CreateConsumer.
+func (mr *MockConsumerServiceMockRecorder) CreateConsumer(ctx, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateConsumer", reflect.TypeOf((*MockConsumerService)(nil).CreateConsumer), ctx, c)
+}
+
+// DeleteConsumer mocks base method.
+func (m *MockConsumerService) DeleteConsumer(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteConsumer", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteConsumer indicates an expected call of DeleteConsumer.
+func (mr *MockConsumerServiceMockRecorder) DeleteConsumer(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteConsumer", reflect.TypeOf((*MockConsumerService)(nil).DeleteConsumer), ctx, id)
+}
+
+// GetActiveConsumers mocks base method.
+func (m *MockConsumerService) GetActiveConsumers(ctx context.Context, roles []string, page, limit int, includeDeleted bool) ([]entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveConsumers", ctx, roles, page, limit, includeDeleted)
+	ret0, _ := ret[0].([]entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveConsumers indicates an expected call of GetActiveConsumers.
+func (mr *MockConsumerServiceMockRecorder) GetActiveConsumers(ctx, roles, page, limit, includeDeleted any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveConsumers", reflect.TypeOf((*MockConsumerService)(nil).GetActiveConsumers), ctx, roles, page, limit, includeDeleted)
+}
+
+// GetAllConsumers mocks base method.
+func (m *MockConsumerService) GetAllConsumers(ctx context.Context, roles []string, page, limit int, includeDeleted bool) ([]entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllConsumers", ctx, roles, page, limit, includeDeleted)
+	ret0, _ := ret[0].([]entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllConsumers indicates an expected call of GetAllConsumers.
+func (mr *MockConsumerServiceMockRecorder) GetAllConsumers(ctx, roles, page, limit, includeDeleted any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllConsumers", reflect.TypeOf((*MockConsumerService)(nil).GetAllConsumers), ctx, roles, page, limit, includeDeleted)
+}
+
+// GetConsumerByID mocks base method.
+func (m *MockConsumerService) GetConsumerByID(ctx context.Context, id string) (entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsumerByID", ctx, id)
+	ret0, _ := ret[0].(entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsumerByID indicates an expected call of GetConsumerByID.
+func (mr *MockConsumerServiceMockRecorder) GetConsumerByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsumerByID", reflect.TypeOf((*MockConsumerService)(nil).GetConsumerByID), ctx, id)
+}
+
+// GetInactiveConsumers mocks base method.
+func (m *MockConsumerService) GetInactiveConsumers(ctx context.Context, roles []string, page, limit int, includeDeleted bool) ([]entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInactiveConsumers", ctx, roles, page, limit, includeDeleted)
+	ret0, _ := ret[0].([]entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInactiveConsumers indicates an expected call of GetInactiveConsumers.
+func (mr *MockConsumerServiceMockRecorder) GetInactiveConsumers(ctx, roles, page, limit, includeDeleted any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInactiveConsumers", reflect.TypeOf((*MockConsumerService)(nil).GetInactiveConsumers), ctx, roles, page, limit, includeDeleted)
+}
+
+// GetSuspendedConsumers mocks base method.
+func (m *MockConsumerService) GetSuspendedConsumers(ctx context.Context, roles []string, page, limit int, includeDeleted bool) ([]entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSuspendedConsumers", ctx, roles, page, limit, includeDeleted)
+	ret0, _ := ret[0].([]entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSuspendedConsumers indicates an expected call of GetSuspendedConsumers.
+func (mr *MockConsumerServiceMockRecorder) GetSuspendedConsumers(ctx, roles, page, limit, includeDeleted any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSuspendedConsumers", reflect.TypeOf((*MockConsumerService)(nil).GetSuspendedConsumers), ctx, roles, page, limit, includeDeleted)
+}
+
+// RestoreConsumer mocks base method.
+func (m *MockConsumerService) RestoreConsumer(ctx context.Context, id string) (entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreConsumer", ctx, id)
+	ret0, _ := ret[0].(entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreConsumer indicates an expected call of RestoreConsumer.
+func (mr *MockConsumerServiceMockRecorder) RestoreConsumer(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreConsumer", reflect.TypeOf((*MockConsumerService)(nil).RestoreConsumer), ctx, id)
+}
+
+// StreamConsumers mocks base method.
+func (m *MockConsumerService) StreamConsumers(ctx context.Context, roles []string, includeDeleted bool, fn func(entity.Consumer) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamConsumers", ctx, roles, includeDeleted, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamConsumers indicates an expected call of StreamConsumers.
+func (mr *MockConsumerServiceMockRecorder) StreamConsumers(ctx, roles, includeDeleted, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamConsumers", reflect.TypeOf((*MockConsumerService)(nil).StreamConsumers), ctx, roles, includeDeleted, fn)
+}
+
+// UpdateConsumer mocks base method.
+func (m *MockConsumerService) UpdateConsumer(ctx context.Context, id string, req entity.ConsumerUpdateRequest) (entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateConsumer", ctx, id, req)
+	ret0, _ := ret[0].(entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateConsumer indicates an expected call of UpdateConsumer.
+func (mr *MockConsumerServiceMockRecorder) UpdateConsumer(ctx, id, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateConsumer", reflect.TypeOf((*MockConsumerService)(nil).UpdateConsumer), ctx, id, req)
+}
+
+// UpdateConsumerStatus mocks base method.
+func (m *MockConsumerService) UpdateConsumerStatus(ctx context.Context, id, status string) (entity.Consumer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateConsumerStatus", ctx, id, status)
+	ret0, _ := ret[0].(entity.Consumer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateConsumerStatus indicates an expected call of UpdateConsumerStatus.
+func (mr *MockConsumerServiceMockRecorder) UpdateConsumerStatus(ctx, id, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateConsumerStatus", reflect.TypeOf((*MockConsumerService)(nil).UpdateConsumerStatus), ctx, id, status)
+}
@@ -0,0 +1,72 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: feature-flag.go
+//
+// Generated by this command:
+//
+//	mockgen -source=feature-flag.go -destination=../../tests/mocks/feature-flag-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockFeatureFlagRepository is a mock of FeatureFlagRepository interface.
+type MockFeatureFlagRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockFeatureFlagRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockFeatureFlagRepositoryMockRecorder is the mock recorder for MockFeatureFlagRepository.
+type MockFeatureFlagRepositoryMockRecorder struct {
+	mock *MockFeatureFlagRepository
+}
+
+// NewMockFeatureFlagRepository creates a new mock instance.
+func NewMockFeatureFlagRepository(ctrl *gomock.Controller) *MockFeatureFlagRepository {
+	mock := &MockFeatureFlagRepository{ctrl: ctrl}
+	mock.recorder = &MockFeatureFlagRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFeatureFlagRepository) EXPECT() *MockFeatureFlagRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetFeatureFlags mocks base method.
+func (m *MockFeatureFlagRepository) GetFeatureFlags(tx *gorm.DB) ([]entity.FeatureFlag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFeatureFlags", tx)
+	ret0, _ := ret[0].([]entity.FeatureFlag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFeatureFlags indicates an expected call of GetFeatureFlags.
+func (mr *MockFeatureFlagRepositoryMockRecorder) GetFeatureFlags(tx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFeatureFlags", reflect.TypeOf((*MockFeatureFlagRepository)(nil).GetFeatureFlags), tx)
+}
+
+// SaveFeatureFlag mocks base method.
+func (m *MockFeatureFlagRepository) SaveFeatureFlag(tx *gorm.DB, flag entity.FeatureFlag) (entity.FeatureFlag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveFeatureFlag", tx, flag)
+	ret0, _ := ret[0].(entity.FeatureFlag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveFeatureFlag indicates an expected call of SaveFeatureFlag.
+func (mr *MockFeatureFlagRepositoryMockRecorder) SaveFeatureFlag(tx, flag any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveFeatureFlag", reflect.TypeOf((*MockFeatureFlagRepository)(nil).SaveFeatureFlag), tx, flag)
+}
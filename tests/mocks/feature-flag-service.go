@@ -0,0 +1,101 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: feature-flag.go
+//
+// Generated by this command:
+//
+//	mockgen -source=feature-flag.go -destination=../../tests/mocks/feature-flag-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	featureflag "github.com/yoanesber/go-jwt-auth-demo/pkg/featureflag"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockFeatureFlagService is a mock of FeatureFlagService interface.
+type MockFeatureFlagService struct {
+	ctrl     *gomock.Controller
+	recorder *MockFeatureFlagServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockFeatureFlagServiceMockRecorder is the mock recorder for MockFeatureFlagService.
+type MockFeatureFlagServiceMockRecorder struct {
+	mock *MockFeatureFlagService
+}
+
+// NewMockFeatureFlagService creates a new mock instance.
+func NewMockFeatureFlagService(ctrl *gomock.Controller) *MockFeatureFlagService {
+	mock := &MockFeatureFlagService{ctrl: ctrl}
+	mock.recorder = &MockFeatureFlagServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFeatureFlagService) EXPECT() *MockFeatureFlagServiceMockRecorder {
+	return m.recorder
+}
+
+// Flags mocks base method.
+func (m *MockFeatureFlagService) Flags() *featureflag.Cache {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flags")
+	ret0, _ := ret[0].(*featureflag.Cache)
+	return ret0
+}
+
+// Flags indicates an expected call of Flags.
+func (mr *MockFeatureFlagServiceMockRecorder) Flags() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flags", reflect.TypeOf((*MockFeatureFlagService)(nil).Flags))
+}
+
+// GetFeatureFlags mocks base method.
+func (m *MockFeatureFlagService) GetFeatureFlags(ctx context.Context) ([]entity.FeatureFlag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFeatureFlags", ctx)
+	ret0, _ := ret[0].([]entity.FeatureFlag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFeatureFlags indicates an expected call of GetFeatureFlags.
+func (mr *MockFeatureFlagServiceMockRecorder) GetFeatureFlags(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFeatureFlags", reflect.TypeOf((*MockFeatureFlagService)(nil).GetFeatureFlags), ctx)
+}
+
+// LoadFlags mocks base method.
+func (m *MockFeatureFlagService) LoadFlags() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadFlags")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LoadFlags indicates an expected call of LoadFlags.
+func (mr *MockFeatureFlagServiceMockRecorder) LoadFlags() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadFlags", reflect.TypeOf((*MockFeatureFlagService)(nil).LoadFlags))
+}
+
+// UpdateFeatureFlag mocks base method.
+func (m *MockFeatureFlagService) UpdateFeatureFlag(ctx context.Context, userID int64, name string, req entity.FeatureFlagRequest) (entity.FeatureFlag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateFeatureFlag", ctx, userID, name, req)
+	ret0, _ := ret[0].(entity.FeatureFlag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateFeatureFlag indicates an expected call of UpdateFeatureFlag.
+func (mr *MockFeatureFlagServiceMockRecorder) UpdateFeatureFlag(ctx, userID, name, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFeatureFlag", reflect.TypeOf((*MockFeatureFlagService)(nil).UpdateFeatureFlag), ctx, userID, name, req)
+}
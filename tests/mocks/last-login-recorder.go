@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: last-login.go
+//
+// Generated by this command:
+//
+//	mockgen -source=last-login.go -destination=../../tests/mocks/last-login-recorder.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLastLoginRecorder is a mock of LastLoginRecorder interface.
+type MockLastLoginRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockLastLoginRecorderMockRecorder
+	isgomock struct{}
+}
+
+// MockLastLoginRecorderMockRecorder is the mock recorder for MockLastLoginRecorder.
+type MockLastLoginRecorderMockRecorder struct {
+	mock *MockLastLoginRecorder
+}
+
+// NewMockLastLoginRecorder creates a new mock instance.
+func NewMockLastLoginRecorder(ctrl *gomock.Controller) *MockLastLoginRecorder {
+	mock := &MockLastLoginRecorder{ctrl: ctrl}
+	mock.recorder = &MockLastLoginRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLastLoginRecorder) EXPECT() *MockLastLoginRecorderMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockLastLoginRecorder) Close() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Close")
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockLastLoginRecorderMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockLastLoginRecorder)(nil).Close))
+}
+
+// Record mocks base method.
+func (m *MockLastLoginRecorder) Record(userID int64, lastLogin time.Time) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Record", userID, lastLogin)
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockLastLoginRecorderMockRecorder) Record(userID, lastLogin any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockLastLoginRecorder)(nil).Record), userID, lastLogin)
+}
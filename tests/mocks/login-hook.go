@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: login-hook.go
+//
+// Generated by this command:
+//
+//	mockgen -source=login-hook.go -destination=../../tests/mocks/login-hook.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLoginHook is a mock of LoginHook interface.
+type MockLoginHook struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginHookMockRecorder
+	isgomock struct{}
+}
+
+// MockLoginHookMockRecorder is the mock recorder for MockLoginHook.
+type MockLoginHookMockRecorder struct {
+	mock *MockLoginHook
+}
+
+// NewMockLoginHook creates a new mock instance.
+func NewMockLoginHook(ctrl *gomock.Controller) *MockLoginHook {
+	mock := &MockLoginHook{ctrl: ctrl}
+	mock.recorder = &MockLoginHookMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginHook) EXPECT() *MockLoginHookMockRecorder {
+	return m.recorder
+}
+
+// AfterLogin mocks base method.
+func (m *MockLoginHook) AfterLogin(user entity.User, loginReq entity.LoginRequest) (map[string]any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AfterLogin", user, loginReq)
+	ret0, _ := ret[0].(map[string]any)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AfterLogin indicates an expected call of AfterLogin.
+func (mr *MockLoginHookMockRecorder) AfterLogin(user, loginReq any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AfterLogin", reflect.TypeOf((*MockLoginHook)(nil).AfterLogin), user, loginReq)
+}
+
+// BeforeLogin mocks base method.
+func (m *MockLoginHook) BeforeLogin(loginReq entity.LoginRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeforeLogin", loginReq)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BeforeLogin indicates an expected call of BeforeLogin.
+func (mr *MockLoginHookMockRecorder) BeforeLogin(loginReq any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeforeLogin", reflect.TypeOf((*MockLoginHook)(nil).BeforeLogin), loginReq)
+}
@@ -0,0 +1,161 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: mfa.go
+//
+// Generated by this command:
+//
+//	mockgen -source=mfa.go -destination=../../tests/mocks/mfa-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockMFARepository is a mock of MFARepository interface.
+type MockMFARepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockMFARepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockMFARepositoryMockRecorder is the mock recorder for MockMFARepository.
+type MockMFARepositoryMockRecorder struct {
+	mock *MockMFARepository
+}
+
+// NewMockMFARepository creates a new mock instance.
+func NewMockMFARepository(ctrl *gomock.Controller) *MockMFARepository {
+	mock := &MockMFARepository{ctrl: ctrl}
+	mock.recorder = &MockMFARepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMFARepository) EXPECT() *MockMFARepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateMFAChallenge mocks base method.
+func (m *MockMFARepository) CreateMFAChallenge(tx *gorm.DB, challenge entity.MFAChallenge) (entity.MFAChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateMFAChallenge", tx, challenge)
+	ret0, _ := ret[0].(entity.MFAChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateMFAChallenge indicates an expected call of CreateMFAChallenge.
+func (mr *MockMFARepositoryMockRecorder) CreateMFAChallenge(tx, challenge any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMFAChallenge", reflect.TypeOf((*MockMFARepository)(nil).CreateMFAChallenge), tx, challenge)
+}
+
+// GetMFAChallengeByHash mocks base method.
+func (m *MockMFARepository) GetMFAChallengeByHash(tx *gorm.DB, tokenHash string) (entity.MFAChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMFAChallengeByHash", tx, tokenHash)
+	ret0, _ := ret[0].(entity.MFAChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMFAChallengeByHash indicates an expected call of GetMFAChallengeByHash.
+func (mr *MockMFARepositoryMockRecorder) GetMFAChallengeByHash(tx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMFAChallengeByHash", reflect.TypeOf((*MockMFARepository)(nil).GetMFAChallengeByHash), tx, tokenHash)
+}
+
+// GetUserMFA mocks base method.
+func (m *MockMFARepository) GetUserMFA(tx *gorm.DB, userID int64) (entity.UserMFA, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserMFA", tx, userID)
+	ret0, _ := ret[0].(entity.UserMFA)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserMFA indicates an expected call of GetUserMFA.
+func (mr *MockMFARepositoryMockRecorder) GetUserMFA(tx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserMFA", reflect.TypeOf((*MockMFARepository)(nil).GetUserMFA), tx, userID)
+}
+
+// MarkMFAStepUsed mocks base method.
+func (m *MockMFARepository) MarkMFAStepUsed(tx *gorm.DB, userID, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkMFAStepUsed", tx, userID, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkMFAStepUsed indicates an expected call of MarkMFAStepUsed.
+func (mr *MockMFARepositoryMockRecorder) MarkMFAStepUsed(tx, userID, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkMFAStepUsed", reflect.TypeOf((*MockMFARepository)(nil).MarkMFAStepUsed), tx, userID, step)
+}
+
+// RemoveMFAChallenges mocks base method.
+func (m *MockMFARepository) RemoveMFAChallenges(tx *gorm.DB, userID int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveMFAChallenges", tx, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveMFAChallenges indicates an expected call of RemoveMFAChallenges.
+func (mr *MockMFARepositoryMockRecorder) RemoveMFAChallenges(tx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveMFAChallenges", reflect.TypeOf((*MockMFARepository)(nil).RemoveMFAChallenges), tx, userID)
+}
+
+// RemoveUserMFA mocks base method.
+func (m *MockMFARepository) RemoveUserMFA(tx *gorm.DB, userID int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveUserMFA", tx, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveUserMFA indicates an expected call of RemoveUserMFA.
+func (mr *MockMFARepositoryMockRecorder) RemoveUserMFA(tx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUserMFA", reflect.TypeOf((*MockMFARepository)(nil).RemoveUserMFA), tx, userID)
+}
+
+// SaveUserMFA mocks base method.
+func (m *MockMFARepository) SaveUserMFA(tx *gorm.DB, mfa entity.UserMFA) (entity.UserMFA, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveUserMFA", tx, mfa)
+	ret0, _ := ret[0].(entity.UserMFA)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveUserMFA indicates an expected call of SaveUserMFA.
+func (mr *MockMFARepositoryMockRecorder) SaveUserMFA(tx, mfa any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveUserMFA", reflect.TypeOf((*MockMFARepository)(nil).SaveUserMFA), tx, mfa)
+}
+
+// UpdateMFAChallenge mocks base method.
+func (m *MockMFARepository) UpdateMFAChallenge(tx *gorm.DB, challenge entity.MFAChallenge) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMFAChallenge", tx, challenge)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateMFAChallenge indicates an expected call of UpdateMFAChallenge.
+func (mr *MockMFARepositoryMockRecorder) UpdateMFAChallenge(tx, challenge any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMFAChallenge", reflect.TypeOf((*MockMFARepository)(nil).UpdateMFAChallenge), tx, challenge)
+}
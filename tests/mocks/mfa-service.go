@@ -0,0 +1,147 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: mfa.go
+//
+// Generated by this command:
+//
+//	mockgen -source=mfa.go -destination=../../tests/mocks/mfa-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	service "github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockMFAService is a mock of MFAService interface.
+type MockMFAService struct {
+	ctrl     *gomock.Controller
+	recorder *MockMFAServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockMFAServiceMockRecorder is the mock recorder for MockMFAService.
+type MockMFAServiceMockRecorder struct {
+	mock *MockMFAService
+}
+
+// NewMockMFAService creates a new mock instance.
+func NewMockMFAService(ctrl *gomock.Controller) *MockMFAService {
+	mock := &MockMFAService{ctrl: ctrl}
+	mock.recorder = &MockMFAServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMFAService) EXPECT() *MockMFAServiceMockRecorder {
+	return m.recorder
+}
+
+// Activate mocks base method.
+func (m *MockMFAService) Activate(ctx context.Context, userID int64, req entity.MFACodeRequest) (entity.MFAStatusResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Activate", ctx, userID, req)
+	ret0, _ := ret[0].(entity.MFAStatusResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Activate indicates an expected call of Activate.
+func (mr *MockMFAServiceMockRecorder) Activate(ctx, userID, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Activate", reflect.TypeOf((*MockMFAService)(nil).Activate), ctx, userID, req)
+}
+
+// Disable mocks base method.
+func (m *MockMFAService) Disable(ctx context.Context, userID int64, req entity.MFACodeRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disable", ctx, userID, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Disable indicates an expected call of Disable.
+func (mr *MockMFAServiceMockRecorder) Disable(ctx, userID, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disable", reflect.TypeOf((*MockMFAService)(nil).Disable), ctx, userID, req)
+}
+
+// Enroll mocks base method.
+func (m *MockMFAService) Enroll(ctx context.Context, userID int64) (entity.MFAEnrollResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enroll", ctx, userID)
+	ret0, _ := ret[0].(entity.MFAEnrollResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Enroll indicates an expected call of Enroll.
+func (mr *MockMFAServiceMockRecorder) Enroll(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enroll", reflect.TypeOf((*MockMFAService)(nil).Enroll), ctx, userID)
+}
+
+// GetStatus mocks base method.
+func (m *MockMFAService) GetStatus(ctx context.Context, userID int64) (entity.MFAStatusResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatus", ctx, userID)
+	ret0, _ := ret[0].(entity.MFAStatusResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatus indicates an expected call of GetStatus.
+func (mr *MockMFAServiceMockRecorder) GetStatus(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatus", reflect.TypeOf((*MockMFAService)(nil).GetStatus), ctx, userID)
+}
+
+// IsEnabled mocks base method.
+func (m *MockMFAService) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsEnabled", ctx, userID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsEnabled indicates an expected call of IsEnabled.
+func (mr *MockMFAServiceMockRecorder) IsEnabled(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsEnabled", reflect.TypeOf((*MockMFAService)(nil).IsEnabled), ctx, userID)
+}
+
+// StartChallenge mocks base method.
+func (m *MockMFAService) StartChallenge(ctx context.Context, userID int64) (service.MFAChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartChallenge", ctx, userID)
+	ret0, _ := ret[0].(service.MFAChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartChallenge indicates an expected call of StartChallenge.
+func (mr *MockMFAServiceMockRecorder) StartChallenge(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartChallenge", reflect.TypeOf((*MockMFAService)(nil).StartChallenge), ctx, userID)
+}
+
+// VerifyChallenge mocks base method.
+func (m *MockMFAService) VerifyChallenge(ctx context.Context, token, code string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyChallenge", ctx, token, code)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyChallenge indicates an expected call of VerifyChallenge.
+func (mr *MockMFAServiceMockRecorder) VerifyChallenge(ctx, token, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyChallenge", reflect.TypeOf((*MockMFAService)(nil).VerifyChallenge), ctx, token, code)
+}
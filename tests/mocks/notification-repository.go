@@ -0,0 +1,102 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: notification.go
+//
+// Generated by this command:
+//
+//	mockgen -source=notification.go -destination=../../tests/mocks/notification-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockNotificationRepository is a mock of NotificationRepository interface.
+type MockNotificationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotificationRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockNotificationRepositoryMockRecorder is the mock recorder for MockNotificationRepository.
+type MockNotificationRepositoryMockRecorder struct {
+	mock *MockNotificationRepository
+}
+
+// NewMockNotificationRepository creates a new mock instance.
+func NewMockNotificationRepository(ctrl *gomock.Controller) *MockNotificationRepository {
+	mock := &MockNotificationRepository{ctrl: ctrl}
+	mock.recorder = &MockNotificationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotificationRepository) EXPECT() *MockNotificationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CountUserDevices mocks base method.
+func (m *MockNotificationRepository) CountUserDevices(tx *gorm.DB, userID int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUserDevices", tx, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUserDevices indicates an expected call of CountUserDevices.
+func (mr *MockNotificationRepositoryMockRecorder) CountUserDevices(tx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUserDevices", reflect.TypeOf((*MockNotificationRepository)(nil).CountUserDevices), tx, userID)
+}
+
+// GetNotificationPreference mocks base method.
+func (m *MockNotificationRepository) GetNotificationPreference(tx *gorm.DB, userID int64) (entity.NotificationPreference, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNotificationPreference", tx, userID)
+	ret0, _ := ret[0].(entity.NotificationPreference)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNotificationPreference indicates an expected call of GetNotificationPreference.
+func (mr *MockNotificationRepositoryMockRecorder) GetNotificationPreference(tx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotificationPreference", reflect.TypeOf((*MockNotificationRepository)(nil).GetNotificationPreference), tx, userID)
+}
+
+// SaveNotificationPreference mocks base method.
+func (m *MockNotificationRepository) SaveNotificationPreference(tx *gorm.DB, pref entity.NotificationPreference) (entity.NotificationPreference, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveNotificationPreference", tx, pref)
+	ret0, _ := ret[0].(entity.NotificationPreference)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveNotificationPreference indicates an expected call of SaveNotificationPreference.
+func (mr *MockNotificationRepositoryMockRecorder) SaveNotificationPreference(tx, pref any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveNotificationPreference", reflect.TypeOf((*MockNotificationRepository)(nil).SaveNotificationPreference), tx, pref)
+}
+
+// TouchUserDevice mocks base method.
+func (m *MockNotificationRepository) TouchUserDevice(tx *gorm.DB, device entity.UserDevice) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchUserDevice", tx, device)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TouchUserDevice indicates an expected call of TouchUserDevice.
+func (mr *MockNotificationRepositoryMockRecorder) TouchUserDevice(tx, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchUserDevice", reflect.TypeOf((*MockNotificationRepository)(nil).TouchUserDevice), tx, device)
+}
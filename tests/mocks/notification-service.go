@@ -0,0 +1,134 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: notification.go
+//
+// Generated by this command:
+//
+//	mockgen -source=notification.go -destination=../../tests/mocks/notification-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockNotificationService is a mock of NotificationService interface.
+type MockNotificationService struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotificationServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockNotificationServiceMockRecorder is the mock recorder for MockNotificationService.
+type MockNotificationServiceMockRecorder struct {
+	mock *MockNotificationService
+}
+
+// NewMockNotificationService creates a new mock instance.
+func NewMockNotificationService(ctrl *gomock.Controller) *MockNotificationService {
+	mock := &MockNotificationService{ctrl: ctrl}
+	mock.recorder = &MockNotificationServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotificationService) EXPECT() *MockNotificationServiceMockRecorder {
+	return m.recorder
+}
+
+// GetNotificationPreference mocks base method.
+func (m *MockNotificationService) GetNotificationPreference(ctx context.Context, userID int64) (entity.NotificationPreference, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNotificationPreference", ctx, userID)
+	ret0, _ := ret[0].(entity.NotificationPreference)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNotificationPreference indicates an expected call of GetNotificationPreference.
+func (mr *MockNotificationServiceMockRecorder) GetNotificationPreference(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotificationPreference", reflect.TypeOf((*MockNotificationService)(nil).GetNotificationPreference), ctx, userID)
+}
+
+// HandleSecurityEvent mocks base method.
+func (m *MockNotificationService) HandleSecurityEvent(event entity.SecurityEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleSecurityEvent", event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandleSecurityEvent indicates an expected call of HandleSecurityEvent.
+func (mr *MockNotificationServiceMockRecorder) HandleSecurityEvent(event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleSecurityEvent", reflect.TypeOf((*MockNotificationService)(nil).HandleSecurityEvent), event)
+}
+
+// UpdateNotificationPreference mocks base method.
+func (m *MockNotificationService) UpdateNotificationPreference(ctx context.Context, userID int64, req entity.NotificationPreferenceRequest) (entity.NotificationPreference, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateNotificationPreference", ctx, userID, req)
+	ret0, _ := ret[0].(entity.NotificationPreference)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateNotificationPreference indicates an expected call of UpdateNotificationPreference.
+func (mr *MockNotificationServiceMockRecorder) UpdateNotificationPreference(ctx, userID, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateNotificationPreference", reflect.TypeOf((*MockNotificationService)(nil).UpdateNotificationPreference), ctx, userID, req)
+}
+
+// MockSecurityNotifier is a mock of SecurityNotifier interface.
+type MockSecurityNotifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockSecurityNotifierMockRecorder
+	isgomock struct{}
+}
+
+// MockSecurityNotifierMockRecorder is the mock recorder for MockSecurityNotifier.
+type MockSecurityNotifierMockRecorder struct {
+	mock *MockSecurityNotifier
+}
+
+// NewMockSecurityNotifier creates a new mock instance.
+func NewMockSecurityNotifier(ctrl *gomock.Controller) *MockSecurityNotifier {
+	mock := &MockSecurityNotifier{ctrl: ctrl}
+	mock.recorder = &MockSecurityNotifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSecurityNotifier) EXPECT() *MockSecurityNotifierMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockSecurityNotifier) Close() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Close")
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockSecurityNotifierMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockSecurityNotifier)(nil).Close))
+}
+
+// Notify mocks base method.
+func (m *MockSecurityNotifier) Notify(event entity.SecurityEvent) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Notify", event)
+}
+
+// Notify indicates an expected call of Notify.
+func (mr *MockSecurityNotifierMockRecorder) Notify(event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Notify", reflect.TypeOf((*MockSecurityNotifier)(nil).Notify), event)
+}
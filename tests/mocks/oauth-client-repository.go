@@ -0,0 +1,118 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: oauth-client.go
+//
+// Generated by this command:
+//
+//	mockgen -source=oauth-client.go -destination=../../tests/mocks/oauth-client-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockOAuthClientRepository is a mock of OAuthClientRepository interface.
+type MockOAuthClientRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuthClientRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockOAuthClientRepositoryMockRecorder is the mock recorder for MockOAuthClientRepository.
+type MockOAuthClientRepositoryMockRecorder struct {
+	mock *MockOAuthClientRepository
+}
+
+// NewMockOAuthClientRepository creates a new mock instance.
+func NewMockOAuthClientRepository(ctrl *gomock.Controller) *MockOAuthClientRepository {
+	mock := &MockOAuthClientRepository{ctrl: ctrl}
+	mock.recorder = &MockOAuthClientRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuthClientRepository) EXPECT() *MockOAuthClientRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateOAuthClient mocks base method.
+func (m *MockOAuthClientRepository) CreateOAuthClient(tx *gorm.DB, client entity.OAuthClient) (entity.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOAuthClient", tx, client)
+	ret0, _ := ret[0].(entity.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOAuthClient indicates an expected call of CreateOAuthClient.
+func (mr *MockOAuthClientRepositoryMockRecorder) CreateOAuthClient(tx, client any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOAuthClient", reflect.TypeOf((*MockOAuthClientRepository)(nil).CreateOAuthClient), tx, client)
+}
+
+// GetOAuthClientByClientID mocks base method.
+func (m *MockOAuthClientRepository) GetOAuthClientByClientID(tx *gorm.DB, clientID string) (entity.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthClientByClientID", tx, clientID)
+	ret0, _ := ret[0].(entity.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthClientByClientID indicates an expected call of GetOAuthClientByClientID.
+func (mr *MockOAuthClientRepositoryMockRecorder) GetOAuthClientByClientID(tx, clientID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthClientByClientID", reflect.TypeOf((*MockOAuthClientRepository)(nil).GetOAuthClientByClientID), tx, clientID)
+}
+
+// GetOAuthClientByID mocks base method.
+func (m *MockOAuthClientRepository) GetOAuthClientByID(tx *gorm.DB, id int64) (entity.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthClientByID", tx, id)
+	ret0, _ := ret[0].(entity.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthClientByID indicates an expected call of GetOAuthClientByID.
+func (mr *MockOAuthClientRepositoryMockRecorder) GetOAuthClientByID(tx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthClientByID", reflect.TypeOf((*MockOAuthClientRepository)(nil).GetOAuthClientByID), tx, id)
+}
+
+// GetOAuthClientsByUserID mocks base method.
+func (m *MockOAuthClientRepository) GetOAuthClientsByUserID(tx *gorm.DB, userID int64) ([]entity.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthClientsByUserID", tx, userID)
+	ret0, _ := ret[0].([]entity.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthClientsByUserID indicates an expected call of GetOAuthClientsByUserID.
+func (mr *MockOAuthClientRepositoryMockRecorder) GetOAuthClientsByUserID(tx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthClientsByUserID", reflect.TypeOf((*MockOAuthClientRepository)(nil).GetOAuthClientsByUserID), tx, userID)
+}
+
+// RevokeOAuthClient mocks base method.
+func (m *MockOAuthClientRepository) RevokeOAuthClient(tx *gorm.DB, id int64, revokedAt time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOAuthClient", tx, id, revokedAt)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeOAuthClient indicates an expected call of RevokeOAuthClient.
+func (mr *MockOAuthClientRepositoryMockRecorder) RevokeOAuthClient(tx, id, revokedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOAuthClient", reflect.TypeOf((*MockOAuthClientRepository)(nil).RevokeOAuthClient), tx, id, revokedAt)
+}
@@ -0,0 +1,101 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: oauth-client.go
+//
+// Generated by this command:
+//
+//	mockgen -source=oauth-client.go -destination=../../tests/mocks/oauth-client-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOAuthClientService is a mock of OAuthClientService interface.
+type MockOAuthClientService struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuthClientServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockOAuthClientServiceMockRecorder is the mock recorder for MockOAuthClientService.
+type MockOAuthClientServiceMockRecorder struct {
+	mock *MockOAuthClientService
+}
+
+// NewMockOAuthClientService creates a new mock instance.
+func NewMockOAuthClientService(ctrl *gomock.Controller) *MockOAuthClientService {
+	mock := &MockOAuthClientService{ctrl: ctrl}
+	mock.recorder = &MockOAuthClientServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuthClientService) EXPECT() *MockOAuthClientServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateOAuthClient mocks base method.
+func (m *MockOAuthClientService) CreateOAuthClient(ctx context.Context, userID int64, req entity.OAuthClientRequest, createdBy int64) (entity.IssuedOAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOAuthClient", ctx, userID, req, createdBy)
+	ret0, _ := ret[0].(entity.IssuedOAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOAuthClient indicates an expected call of CreateOAuthClient.
+func (mr *MockOAuthClientServiceMockRecorder) CreateOAuthClient(ctx, userID, req, createdBy any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOAuthClient", reflect.TypeOf((*MockOAuthClientService)(nil).CreateOAuthClient), ctx, userID, req, createdBy)
+}
+
+// GetOAuthClients mocks base method.
+func (m *MockOAuthClientService) GetOAuthClients(ctx context.Context, userID int64) ([]entity.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOAuthClients", ctx, userID)
+	ret0, _ := ret[0].([]entity.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOAuthClients indicates an expected call of GetOAuthClients.
+func (mr *MockOAuthClientServiceMockRecorder) GetOAuthClients(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOAuthClients", reflect.TypeOf((*MockOAuthClientService)(nil).GetOAuthClients), ctx, userID)
+}
+
+// IssueClientCredentialsToken mocks base method.
+func (m *MockOAuthClientService) IssueClientCredentialsToken(ctx context.Context, req entity.ClientCredentialsRequest) (entity.ClientCredentialsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueClientCredentialsToken", ctx, req)
+	ret0, _ := ret[0].(entity.ClientCredentialsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueClientCredentialsToken indicates an expected call of IssueClientCredentialsToken.
+func (mr *MockOAuthClientServiceMockRecorder) IssueClientCredentialsToken(ctx, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueClientCredentialsToken", reflect.TypeOf((*MockOAuthClientService)(nil).IssueClientCredentialsToken), ctx, req)
+}
+
+// RevokeOAuthClient mocks base method.
+func (m *MockOAuthClientService) RevokeOAuthClient(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOAuthClient", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeOAuthClient indicates an expected call of RevokeOAuthClient.
+func (mr *MockOAuthClientServiceMockRecorder) RevokeOAuthClient(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOAuthClient", reflect.TypeOf((*MockOAuthClientService)(nil).RevokeOAuthClient), ctx, id)
+}
@@ -0,0 +1,103 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: password-reset-token.go
+//
+// Generated by this command:
+//
+//	mockgen -source=password-reset-token.go -destination=../../tests/mocks/password-reset-token-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockPasswordResetTokenRepository is a mock of PasswordResetTokenRepository interface.
+type MockPasswordResetTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordResetTokenRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockPasswordResetTokenRepositoryMockRecorder is the mock recorder for MockPasswordResetTokenRepository.
+type MockPasswordResetTokenRepositoryMockRecorder struct {
+	mock *MockPasswordResetTokenRepository
+}
+
+// NewMockPasswordResetTokenRepository creates a new mock instance.
+func NewMockPasswordResetTokenRepository(ctrl *gomock.Controller) *MockPasswordResetTokenRepository {
+	mock := &MockPasswordResetTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockPasswordResetTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPasswordResetTokenRepository) EXPECT() *MockPasswordResetTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreatePasswordResetToken mocks base method.
+func (m *MockPasswordResetTokenRepository) CreatePasswordResetToken(tx *gorm.DB, token entity.PasswordResetToken) (entity.PasswordResetToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePasswordResetToken", tx, token)
+	ret0, _ := ret[0].(entity.PasswordResetToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePasswordResetToken indicates an expected call of CreatePasswordResetToken.
+func (mr *MockPasswordResetTokenRepositoryMockRecorder) CreatePasswordResetToken(tx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePasswordResetToken", reflect.TypeOf((*MockPasswordResetTokenRepository)(nil).CreatePasswordResetToken), tx, token)
+}
+
+// GetPasswordResetTokenByHash mocks base method.
+func (m *MockPasswordResetTokenRepository) GetPasswordResetTokenByHash(tx *gorm.DB, tokenHash string) (entity.PasswordResetToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasswordResetTokenByHash", tx, tokenHash)
+	ret0, _ := ret[0].(entity.PasswordResetToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasswordResetTokenByHash indicates an expected call of GetPasswordResetTokenByHash.
+func (mr *MockPasswordResetTokenRepositoryMockRecorder) GetPasswordResetTokenByHash(tx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasswordResetTokenByHash", reflect.TypeOf((*MockPasswordResetTokenRepository)(nil).GetPasswordResetTokenByHash), tx, tokenHash)
+}
+
+// MarkPasswordResetTokenUsed mocks base method.
+func (m *MockPasswordResetTokenRepository) MarkPasswordResetTokenUsed(tx *gorm.DB, id int64, usedAt time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkPasswordResetTokenUsed", tx, id, usedAt)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkPasswordResetTokenUsed indicates an expected call of MarkPasswordResetTokenUsed.
+func (mr *MockPasswordResetTokenRepositoryMockRecorder) MarkPasswordResetTokenUsed(tx, id, usedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkPasswordResetTokenUsed", reflect.TypeOf((*MockPasswordResetTokenRepository)(nil).MarkPasswordResetTokenUsed), tx, id, usedAt)
+}
+
+// RemoveUnusedPasswordResetTokens mocks base method.
+func (m *MockPasswordResetTokenRepository) RemoveUnusedPasswordResetTokens(tx *gorm.DB, userID int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveUnusedPasswordResetTokens", tx, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveUnusedPasswordResetTokens indicates an expected call of RemoveUnusedPasswordResetTokens.
+func (mr *MockPasswordResetTokenRepositoryMockRecorder) RemoveUnusedPasswordResetTokens(tx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUnusedPasswordResetTokens", reflect.TypeOf((*MockPasswordResetTokenRepository)(nil).RemoveUnusedPasswordResetTokens), tx, userID)
+}
@@ -0,0 +1,57 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: password.go
+//
+// Generated by this command:
+//
+//	mockgen -source=password.go -destination=../../tests/mocks/password-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPasswordService is a mock of PasswordService interface.
+type MockPasswordService struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockPasswordServiceMockRecorder is the mock recorder for MockPasswordService.
+type MockPasswordServiceMockRecorder struct {
+	mock *MockPasswordService
+}
+
+// NewMockPasswordService creates a new mock instance.
+func NewMockPasswordService(ctrl *gomock.Controller) *MockPasswordService {
+	mock := &MockPasswordService{ctrl: ctrl}
+	mock.recorder = &MockPasswordServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPasswordService) EXPECT() *MockPasswordServiceMockRecorder {
+	return m.recorder
+}
+
+// ChangePassword mocks base method.
+func (m *MockPasswordService) ChangePassword(ctx context.Context, userID int64, req entity.ChangePasswordRequest) (entity.ChangePasswordResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, userID, req)
+	ret0, _ := ret[0].(entity.ChangePasswordResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockPasswordServiceMockRecorder) ChangePassword(ctx, userID, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockPasswordService)(nil).ChangePassword), ctx, userID, req)
+}
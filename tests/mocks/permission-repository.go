@@ -0,0 +1,116 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: permission.go
+//
+// Generated by this command:
+//
+//	mockgen -source=permission.go -destination=../../tests/mocks/permission-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockPermissionRepository is a mock of PermissionRepository interface.
+type MockPermissionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPermissionRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockPermissionRepositoryMockRecorder is the mock recorder for MockPermissionRepository.
+type MockPermissionRepositoryMockRecorder struct {
+	mock *MockPermissionRepository
+}
+
+// NewMockPermissionRepository creates a new mock instance.
+func NewMockPermissionRepository(ctrl *gomock.Controller) *MockPermissionRepository {
+	mock := &MockPermissionRepository{ctrl: ctrl}
+	mock.recorder = &MockPermissionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPermissionRepository) EXPECT() *MockPermissionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetPermissionNamesByRoleNames mocks base method.
+func (m *MockPermissionRepository) GetPermissionNamesByRoleNames(tx *gorm.DB, roleNames []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPermissionNamesByRoleNames", tx, roleNames)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPermissionNamesByRoleNames indicates an expected call of GetPermissionNamesByRoleNames.
+func (mr *MockPermissionRepositoryMockRecorder) GetPermissionNamesByRoleNames(tx, roleNames any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPermissionNamesByRoleNames", reflect.TypeOf((*MockPermissionRepository)(nil).GetPermissionNamesByRoleNames), tx, roleNames)
+}
+
+// GetPermissions mocks base method.
+func (m *MockPermissionRepository) GetPermissions(tx *gorm.DB) ([]entity.Permission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPermissions", tx)
+	ret0, _ := ret[0].([]entity.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPermissions indicates an expected call of GetPermissions.
+func (mr *MockPermissionRepositoryMockRecorder) GetPermissions(tx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPermissions", reflect.TypeOf((*MockPermissionRepository)(nil).GetPermissions), tx)
+}
+
+// GetPermissionsByNames mocks base method.
+func (m *MockPermissionRepository) GetPermissionsByNames(tx *gorm.DB, names []string) ([]entity.Permission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPermissionsByNames", tx, names)
+	ret0, _ := ret[0].([]entity.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPermissionsByNames indicates an expected call of GetPermissionsByNames.
+func (mr *MockPermissionRepositoryMockRecorder) GetPermissionsByNames(tx, names any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPermissionsByNames", reflect.TypeOf((*MockPermissionRepository)(nil).GetPermissionsByNames), tx, names)
+}
+
+// GetRolePermissions mocks base method.
+func (m *MockPermissionRepository) GetRolePermissions(tx *gorm.DB, roleID uint) ([]entity.Permission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRolePermissions", tx, roleID)
+	ret0, _ := ret[0].([]entity.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRolePermissions indicates an expected call of GetRolePermissions.
+func (mr *MockPermissionRepositoryMockRecorder) GetRolePermissions(tx, roleID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRolePermissions", reflect.TypeOf((*MockPermissionRepository)(nil).GetRolePermissions), tx, roleID)
+}
+
+// ReplaceRolePermissions mocks base method.
+func (m *MockPermissionRepository) ReplaceRolePermissions(tx *gorm.DB, roleID uint, permissionIDs []uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceRolePermissions", tx, roleID, permissionIDs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceRolePermissions indicates an expected call of ReplaceRolePermissions.
+func (mr *MockPermissionRepositoryMockRecorder) ReplaceRolePermissions(tx, roleID, permissionIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceRolePermissions", reflect.TypeOf((*MockPermissionRepository)(nil).ReplaceRolePermissions), tx, roleID, permissionIDs)
+}
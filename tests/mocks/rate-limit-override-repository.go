@@ -0,0 +1,86 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: rate-limit-override.go
+//
+// Generated by this command:
+//
+//	mockgen -source=rate-limit-override.go -destination=../../tests/mocks/rate-limit-override-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockRateLimitOverrideRepository is a mock of RateLimitOverrideRepository interface.
+type MockRateLimitOverrideRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateLimitOverrideRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockRateLimitOverrideRepositoryMockRecorder is the mock recorder for MockRateLimitOverrideRepository.
+type MockRateLimitOverrideRepositoryMockRecorder struct {
+	mock *MockRateLimitOverrideRepository
+}
+
+// NewMockRateLimitOverrideRepository creates a new mock instance.
+func NewMockRateLimitOverrideRepository(ctrl *gomock.Controller) *MockRateLimitOverrideRepository {
+	mock := &MockRateLimitOverrideRepository{ctrl: ctrl}
+	mock.recorder = &MockRateLimitOverrideRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateLimitOverrideRepository) EXPECT() *MockRateLimitOverrideRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteRateLimitOverride mocks base method.
+func (m *MockRateLimitOverrideRepository) DeleteRateLimitOverride(tx *gorm.DB, client string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRateLimitOverride", tx, client)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRateLimitOverride indicates an expected call of DeleteRateLimitOverride.
+func (mr *MockRateLimitOverrideRepositoryMockRecorder) DeleteRateLimitOverride(tx, client any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRateLimitOverride", reflect.TypeOf((*MockRateLimitOverrideRepository)(nil).DeleteRateLimitOverride), tx, client)
+}
+
+// GetRateLimitOverrides mocks base method.
+func (m *MockRateLimitOverrideRepository) GetRateLimitOverrides(tx *gorm.DB) ([]entity.RateLimitOverride, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRateLimitOverrides", tx)
+	ret0, _ := ret[0].([]entity.RateLimitOverride)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRateLimitOverrides indicates an expected call of GetRateLimitOverrides.
+func (mr *MockRateLimitOverrideRepositoryMockRecorder) GetRateLimitOverrides(tx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRateLimitOverrides", reflect.TypeOf((*MockRateLimitOverrideRepository)(nil).GetRateLimitOverrides), tx)
+}
+
+// SaveRateLimitOverride mocks base method.
+func (m *MockRateLimitOverrideRepository) SaveRateLimitOverride(tx *gorm.DB, override entity.RateLimitOverride) (entity.RateLimitOverride, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRateLimitOverride", tx, override)
+	ret0, _ := ret[0].(entity.RateLimitOverride)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveRateLimitOverride indicates an expected call of SaveRateLimitOverride.
+func (mr *MockRateLimitOverrideRepositoryMockRecorder) SaveRateLimitOverride(tx, override any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRateLimitOverride", reflect.TypeOf((*MockRateLimitOverrideRepository)(nil).SaveRateLimitOverride), tx, override)
+}
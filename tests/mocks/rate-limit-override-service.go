@@ -0,0 +1,115 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: rate-limit-override.go
+//
+// Generated by this command:
+//
+//	mockgen -source=rate-limit-override.go -destination=../../tests/mocks/rate-limit-override-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	ratelimit "github.com/yoanesber/go-jwt-auth-demo/pkg/ratelimit"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRateLimitOverrideService is a mock of RateLimitOverrideService interface.
+type MockRateLimitOverrideService struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateLimitOverrideServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockRateLimitOverrideServiceMockRecorder is the mock recorder for MockRateLimitOverrideService.
+type MockRateLimitOverrideServiceMockRecorder struct {
+	mock *MockRateLimitOverrideService
+}
+
+// NewMockRateLimitOverrideService creates a new mock instance.
+func NewMockRateLimitOverrideService(ctrl *gomock.Controller) *MockRateLimitOverrideService {
+	mock := &MockRateLimitOverrideService{ctrl: ctrl}
+	mock.recorder = &MockRateLimitOverrideServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateLimitOverrideService) EXPECT() *MockRateLimitOverrideServiceMockRecorder {
+	return m.recorder
+}
+
+// DeleteRateLimitOverride mocks base method.
+func (m *MockRateLimitOverrideService) DeleteRateLimitOverride(ctx context.Context, userID int64, client string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRateLimitOverride", ctx, userID, client)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRateLimitOverride indicates an expected call of DeleteRateLimitOverride.
+func (mr *MockRateLimitOverrideServiceMockRecorder) DeleteRateLimitOverride(ctx, userID, client any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRateLimitOverride", reflect.TypeOf((*MockRateLimitOverrideService)(nil).DeleteRateLimitOverride), ctx, userID, client)
+}
+
+// GetRateLimitOverrides mocks base method.
+func (m *MockRateLimitOverrideService) GetRateLimitOverrides(ctx context.Context) ([]entity.RateLimitOverride, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRateLimitOverrides", ctx)
+	ret0, _ := ret[0].([]entity.RateLimitOverride)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRateLimitOverrides indicates an expected call of GetRateLimitOverrides.
+func (mr *MockRateLimitOverrideServiceMockRecorder) GetRateLimitOverrides(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRateLimitOverrides", reflect.TypeOf((*MockRateLimitOverrideService)(nil).GetRateLimitOverrides), ctx)
+}
+
+// LoadOverrides mocks base method.
+func (m *MockRateLimitOverrideService) LoadOverrides() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadOverrides")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LoadOverrides indicates an expected call of LoadOverrides.
+func (mr *MockRateLimitOverrideServiceMockRecorder) LoadOverrides() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadOverrides", reflect.TypeOf((*MockRateLimitOverrideService)(nil).LoadOverrides))
+}
+
+// Overrides mocks base method.
+func (m *MockRateLimitOverrideService) Overrides() *ratelimit.Overrides {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Overrides")
+	ret0, _ := ret[0].(*ratelimit.Overrides)
+	return ret0
+}
+
+// Overrides indicates an expected call of Overrides.
+func (mr *MockRateLimitOverrideServiceMockRecorder) Overrides() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Overrides", reflect.TypeOf((*MockRateLimitOverrideService)(nil).Overrides))
+}
+
+// SaveRateLimitOverride mocks base method.
+func (m *MockRateLimitOverrideService) SaveRateLimitOverride(ctx context.Context, userID int64, client string, req entity.RateLimitOverrideRequest) (entity.RateLimitOverride, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRateLimitOverride", ctx, userID, client, req)
+	ret0, _ := ret[0].(entity.RateLimitOverride)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveRateLimitOverride indicates an expected call of SaveRateLimitOverride.
+func (mr *MockRateLimitOverrideServiceMockRecorder) SaveRateLimitOverride(ctx, userID, client, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRateLimitOverride", reflect.TypeOf((*MockRateLimitOverrideService)(nil).SaveRateLimitOverride), ctx, userID, client, req)
+}
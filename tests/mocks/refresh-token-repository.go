@@ -0,0 +1,161 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: refresh-token.go
+//
+// Generated by this command:
+//
+//	mockgen -source=refresh-token.go -destination=../../tests/mocks/refresh-token-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockRefreshTokenRepository is a mock of RefreshTokenRepository interface.
+type MockRefreshTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRefreshTokenRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockRefreshTokenRepositoryMockRecorder is the mock recorder for MockRefreshTokenRepository.
+type MockRefreshTokenRepositoryMockRecorder struct {
+	mock *MockRefreshTokenRepository
+}
+
+// NewMockRefreshTokenRepository creates a new mock instance.
+func NewMockRefreshTokenRepository(ctrl *gomock.Controller) *MockRefreshTokenRepository {
+	mock := &MockRefreshTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockRefreshTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRefreshTokenRepository) EXPECT() *MockRefreshTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateRefreshToken mocks base method.
+func (m *MockRefreshTokenRepository) CreateRefreshToken(tx *gorm.DB, token entity.RefreshToken) (entity.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRefreshToken", tx, token)
+	ret0, _ := ret[0].(entity.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRefreshToken indicates an expected call of CreateRefreshToken.
+func (mr *MockRefreshTokenRepositoryMockRecorder) CreateRefreshToken(tx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRefreshToken", reflect.TypeOf((*MockRefreshTokenRepository)(nil).CreateRefreshToken), tx, token)
+}
+
+// GetRefreshTokenByToken mocks base method.
+func (m *MockRefreshTokenRepository) GetRefreshTokenByToken(tx *gorm.DB, token string) (entity.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefreshTokenByToken", tx, token)
+	ret0, _ := ret[0].(entity.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshTokenByToken indicates an expected call of GetRefreshTokenByToken.
+func (mr *MockRefreshTokenRepositoryMockRecorder) GetRefreshTokenByToken(tx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshTokenByToken", reflect.TypeOf((*MockRefreshTokenRepository)(nil).GetRefreshTokenByToken), tx, token)
+}
+
+// GetRefreshTokenByUserID mocks base method.
+func (m *MockRefreshTokenRepository) GetRefreshTokenByUserID(tx *gorm.DB, userID int64) (entity.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefreshTokenByUserID", tx, userID)
+	ret0, _ := ret[0].(entity.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshTokenByUserID indicates an expected call of GetRefreshTokenByUserID.
+func (mr *MockRefreshTokenRepositoryMockRecorder) GetRefreshTokenByUserID(tx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshTokenByUserID", reflect.TypeOf((*MockRefreshTokenRepository)(nil).GetRefreshTokenByUserID), tx, userID)
+}
+
+// GetRefreshTokensByUserID mocks base method.
+func (m *MockRefreshTokenRepository) GetRefreshTokensByUserID(tx *gorm.DB, userID int64) ([]entity.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefreshTokensByUserID", tx, userID)
+	ret0, _ := ret[0].([]entity.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshTokensByUserID indicates an expected call of GetRefreshTokensByUserID.
+func (mr *MockRefreshTokenRepositoryMockRecorder) GetRefreshTokensByUserID(tx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshTokensByUserID", reflect.TypeOf((*MockRefreshTokenRepository)(nil).GetRefreshTokensByUserID), tx, userID)
+}
+
+// LockUserSessions mocks base method.
+func (m *MockRefreshTokenRepository) LockUserSessions(tx *gorm.DB, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockUserSessions", tx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockUserSessions indicates an expected call of LockUserSessions.
+func (mr *MockRefreshTokenRepositoryMockRecorder) LockUserSessions(tx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockUserSessions", reflect.TypeOf((*MockRefreshTokenRepository)(nil).LockUserSessions), tx, userID)
+}
+
+// RemoveRefreshToken mocks base method.
+func (m *MockRefreshTokenRepository) RemoveRefreshToken(tx *gorm.DB, token string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveRefreshToken", tx, token)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveRefreshToken indicates an expected call of RemoveRefreshToken.
+func (mr *MockRefreshTokenRepositoryMockRecorder) RemoveRefreshToken(tx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRefreshToken", reflect.TypeOf((*MockRefreshTokenRepository)(nil).RemoveRefreshToken), tx, token)
+}
+
+// RemoveRefreshTokenBySessionID mocks base method.
+func (m *MockRefreshTokenRepository) RemoveRefreshTokenBySessionID(tx *gorm.DB, userID int64, sessionID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveRefreshTokenBySessionID", tx, userID, sessionID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveRefreshTokenBySessionID indicates an expected call of RemoveRefreshTokenBySessionID.
+func (mr *MockRefreshTokenRepositoryMockRecorder) RemoveRefreshTokenBySessionID(tx, userID, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRefreshTokenBySessionID", reflect.TypeOf((*MockRefreshTokenRepository)(nil).RemoveRefreshTokenBySessionID), tx, userID, sessionID)
+}
+
+// RemoveRefreshTokenByUserID mocks base method.
+func (m *MockRefreshTokenRepository) RemoveRefreshTokenByUserID(tx *gorm.DB, userID int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveRefreshTokenByUserID", tx, userID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveRefreshTokenByUserID indicates an expected call of RemoveRefreshTokenByUserID.
+func (mr *MockRefreshTokenRepositoryMockRecorder) RemoveRefreshTokenByUserID(tx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRefreshTokenByUserID", reflect.TypeOf((*MockRefreshTokenRepository)(nil).RemoveRefreshTokenByUserID), tx, userID)
+}
@@ -0,0 +1,147 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: refresh-token.go
+//
+// Generated by this command:
+//
+//	mockgen -source=refresh-token.go -destination=../../tests/mocks/refresh-token-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRefreshTokenService is a mock of RefreshTokenService interface.
+type MockRefreshTokenService struct {
+	ctrl     *gomock.Controller
+	recorder *MockRefreshTokenServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockRefreshTokenServiceMockRecorder is the mock recorder for MockRefreshTokenService.
+type MockRefreshTokenServiceMockRecorder struct {
+	mock *MockRefreshTokenService
+}
+
+// NewMockRefreshTokenService creates a new mock instance.
+func NewMockRefreshTokenService(ctrl *gomock.Controller) *MockRefreshTokenService {
+	mock := &MockRefreshTokenService{ctrl: ctrl}
+	mock.recorder = &MockRefreshTokenServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRefreshTokenService) EXPECT() *MockRefreshTokenServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateRefreshToken mocks base method.
+func (m *MockRefreshTokenService) CreateRefreshToken(ctx context.Context, userID int64, device entity.SessionDevice) (entity.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRefreshToken", ctx, userID, device)
+	ret0, _ := ret[0].(entity.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRefreshToken indicates an expected call of CreateRefreshToken.
+func (mr *MockRefreshTokenServiceMockRecorder) CreateRefreshToken(ctx, userID, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRefreshToken", reflect.TypeOf((*MockRefreshTokenService)(nil).CreateRefreshToken), ctx, userID, device)
+}
+
+// GetRefreshTokenByToken mocks base method.
+func (m *MockRefreshTokenService) GetRefreshTokenByToken(ctx context.Context, token string) (entity.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefreshTokenByToken", ctx, token)
+	ret0, _ := ret[0].(entity.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshTokenByToken indicates an expected call of GetRefreshTokenByToken.
+func (mr *MockRefreshTokenServiceMockRecorder) GetRefreshTokenByToken(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshTokenByToken", reflect.TypeOf((*MockRefreshTokenService)(nil).GetRefreshTokenByToken), ctx, token)
+}
+
+// GetRefreshTokenByUserID mocks base method.
+func (m *MockRefreshTokenService) GetRefreshTokenByUserID(ctx context.Context, userID int64) (entity.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefreshTokenByUserID", ctx, userID)
+	ret0, _ := ret[0].(entity.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshTokenByUserID indicates an expected call of GetRefreshTokenByUserID.
+func (mr *MockRefreshTokenServiceMockRecorder) GetRefreshTokenByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshTokenByUserID", reflect.TypeOf((*MockRefreshTokenService)(nil).GetRefreshTokenByUserID), ctx, userID)
+}
+
+// GetSessions mocks base method.
+func (m *MockRefreshTokenService) GetSessions(ctx context.Context, userID int64) ([]entity.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", ctx, userID)
+	ret0, _ := ret[0].([]entity.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions.
+func (mr *MockRefreshTokenServiceMockRecorder) GetSessions(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockRefreshTokenService)(nil).GetSessions), ctx, userID)
+}
+
+// RemoveSession mocks base method.
+func (m *MockRefreshTokenService) RemoveSession(ctx context.Context, userID int64, sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveSession", ctx, userID, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveSession indicates an expected call of RemoveSession.
+func (mr *MockRefreshTokenServiceMockRecorder) RemoveSession(ctx, userID, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveSession", reflect.TypeOf((*MockRefreshTokenService)(nil).RemoveSession), ctx, userID, sessionID)
+}
+
+// RotateRefreshToken mocks base method.
+func (m *MockRefreshTokenService) RotateRefreshToken(ctx context.Context, refreshToken entity.RefreshToken) (entity.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateRefreshToken", ctx, refreshToken)
+	ret0, _ := ret[0].(entity.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateRefreshToken indicates an expected call of RotateRefreshToken.
+func (mr *MockRefreshTokenServiceMockRecorder) RotateRefreshToken(ctx, refreshToken any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockRefreshTokenService)(nil).RotateRefreshToken), ctx, refreshToken)
+}
+
+// VerifyExpirationDate mocks base method.
+func (m *MockRefreshTokenService) VerifyExpirationDate(exp time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyExpirationDate", exp)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyExpirationDate indicates an expected call of VerifyExpirationDate.
+func (mr *MockRefreshTokenServiceMockRecorder) VerifyExpirationDate(exp any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyExpirationDate", reflect.TypeOf((*MockRefreshTokenService)(nil).VerifyExpirationDate), exp)
+}
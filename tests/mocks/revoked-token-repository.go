@@ -0,0 +1,87 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: revoked-token.go
+//
+// Generated by this command:
+//
+//	mockgen -source=revoked-token.go -destination=../../tests/mocks/revoked-token-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockRevokedTokenRepository is a mock of RevokedTokenRepository interface.
+type MockRevokedTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRevokedTokenRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockRevokedTokenRepositoryMockRecorder is the mock recorder for MockRevokedTokenRepository.
+type MockRevokedTokenRepositoryMockRecorder struct {
+	mock *MockRevokedTokenRepository
+}
+
+// NewMockRevokedTokenRepository creates a new mock instance.
+func NewMockRevokedTokenRepository(ctrl *gomock.Controller) *MockRevokedTokenRepository {
+	mock := &MockRevokedTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockRevokedTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRevokedTokenRepository) EXPECT() *MockRevokedTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateRevokedToken mocks base method.
+func (m *MockRevokedTokenRepository) CreateRevokedToken(tx *gorm.DB, token entity.RevokedToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRevokedToken", tx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRevokedToken indicates an expected call of CreateRevokedToken.
+func (mr *MockRevokedTokenRepositoryMockRecorder) CreateRevokedToken(tx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRevokedToken", reflect.TypeOf((*MockRevokedTokenRepository)(nil).CreateRevokedToken), tx, token)
+}
+
+// GetRevokedTokens mocks base method.
+func (m *MockRevokedTokenRepository) GetRevokedTokens(tx *gorm.DB, now time.Time) ([]entity.RevokedToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRevokedTokens", tx, now)
+	ret0, _ := ret[0].([]entity.RevokedToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRevokedTokens indicates an expected call of GetRevokedTokens.
+func (mr *MockRevokedTokenRepositoryMockRecorder) GetRevokedTokens(tx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevokedTokens", reflect.TypeOf((*MockRevokedTokenRepository)(nil).GetRevokedTokens), tx, now)
+}
+
+// RemoveExpiredRevokedTokens mocks base method.
+func (m *MockRevokedTokenRepository) RemoveExpiredRevokedTokens(tx *gorm.DB, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveExpiredRevokedTokens", tx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveExpiredRevokedTokens indicates an expected call of RemoveExpiredRevokedTokens.
+func (mr *MockRevokedTokenRepositoryMockRecorder) RemoveExpiredRevokedTokens(tx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveExpiredRevokedTokens", reflect.TypeOf((*MockRevokedTokenRepository)(nil).RemoveExpiredRevokedTokens), tx, now)
+}
@@ -0,0 +1,72 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: security-settings.go
+//
+// Generated by this command:
+//
+//	mockgen -source=security-settings.go -destination=../../tests/mocks/security-settings-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockSecuritySettingsRepository is a mock of SecuritySettingsRepository interface.
+type MockSecuritySettingsRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSecuritySettingsRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockSecuritySettingsRepositoryMockRecorder is the mock recorder for MockSecuritySettingsRepository.
+type MockSecuritySettingsRepositoryMockRecorder struct {
+	mock *MockSecuritySettingsRepository
+}
+
+// NewMockSecuritySettingsRepository creates a new mock instance.
+func NewMockSecuritySettingsRepository(ctrl *gomock.Controller) *MockSecuritySettingsRepository {
+	mock := &MockSecuritySettingsRepository{ctrl: ctrl}
+	mock.recorder = &MockSecuritySettingsRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSecuritySettingsRepository) EXPECT() *MockSecuritySettingsRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetSecuritySettings mocks base method.
+func (m *MockSecuritySettingsRepository) GetSecuritySettings(tx *gorm.DB) (entity.SecuritySettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSecuritySettings", tx)
+	ret0, _ := ret[0].(entity.SecuritySettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSecuritySettings indicates an expected call of GetSecuritySettings.
+func (mr *MockSecuritySettingsRepositoryMockRecorder) GetSecuritySettings(tx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecuritySettings", reflect.TypeOf((*MockSecuritySettingsRepository)(nil).GetSecuritySettings), tx)
+}
+
+// SaveSecuritySettings mocks base method.
+func (m *MockSecuritySettingsRepository) SaveSecuritySettings(tx *gorm.DB, settings entity.SecuritySettings) (entity.SecuritySettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSecuritySettings", tx, settings)
+	ret0, _ := ret[0].(entity.SecuritySettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveSecuritySettings indicates an expected call of SaveSecuritySettings.
+func (mr *MockSecuritySettingsRepositoryMockRecorder) SaveSecuritySettings(tx, settings any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSecuritySettings", reflect.TypeOf((*MockSecuritySettingsRepository)(nil).SaveSecuritySettings), tx, settings)
+}
@@ -0,0 +1,101 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: security-settings.go
+//
+// Generated by this command:
+//
+//	mockgen -source=security-settings.go -destination=../../tests/mocks/security-settings-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	headers "github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/headers"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSecuritySettingsService is a mock of SecuritySettingsService interface.
+type MockSecuritySettingsService struct {
+	ctrl     *gomock.Controller
+	recorder *MockSecuritySettingsServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockSecuritySettingsServiceMockRecorder is the mock recorder for MockSecuritySettingsService.
+type MockSecuritySettingsServiceMockRecorder struct {
+	mock *MockSecuritySettingsService
+}
+
+// NewMockSecuritySettingsService creates a new mock instance.
+func NewMockSecuritySettingsService(ctrl *gomock.Controller) *MockSecuritySettingsService {
+	mock := &MockSecuritySettingsService{ctrl: ctrl}
+	mock.recorder = &MockSecuritySettingsServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSecuritySettingsService) EXPECT() *MockSecuritySettingsServiceMockRecorder {
+	return m.recorder
+}
+
+// GetSecuritySettings mocks base method.
+func (m *MockSecuritySettingsService) GetSecuritySettings(ctx context.Context) (entity.SecuritySettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSecuritySettings", ctx)
+	ret0, _ := ret[0].(entity.SecuritySettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSecuritySettings indicates an expected call of GetSecuritySettings.
+func (mr *MockSecuritySettingsServiceMockRecorder) GetSecuritySettings(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecuritySettings", reflect.TypeOf((*MockSecuritySettingsService)(nil).GetSecuritySettings), ctx)
+}
+
+// LoadOverrides mocks base method.
+func (m *MockSecuritySettingsService) LoadOverrides() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadOverrides")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LoadOverrides indicates an expected call of LoadOverrides.
+func (mr *MockSecuritySettingsServiceMockRecorder) LoadOverrides() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadOverrides", reflect.TypeOf((*MockSecuritySettingsService)(nil).LoadOverrides))
+}
+
+// Overrides mocks base method.
+func (m *MockSecuritySettingsService) Overrides() *headers.OverridesCache {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Overrides")
+	ret0, _ := ret[0].(*headers.OverridesCache)
+	return ret0
+}
+
+// Overrides indicates an expected call of Overrides.
+func (mr *MockSecuritySettingsServiceMockRecorder) Overrides() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Overrides", reflect.TypeOf((*MockSecuritySettingsService)(nil).Overrides))
+}
+
+// UpdateSecuritySettings mocks base method.
+func (m *MockSecuritySettingsService) UpdateSecuritySettings(ctx context.Context, userID int64, req entity.SecuritySettingsRequest) (entity.SecuritySettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSecuritySettings", ctx, userID, req)
+	ret0, _ := ret[0].(entity.SecuritySettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateSecuritySettings indicates an expected call of UpdateSecuritySettings.
+func (mr *MockSecuritySettingsServiceMockRecorder) UpdateSecuritySettings(ctx, userID, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSecuritySettings", reflect.TypeOf((*MockSecuritySettingsService)(nil).UpdateSecuritySettings), ctx, userID, req)
+}
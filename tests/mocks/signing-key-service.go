@@ -0,0 +1,86 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: signing-key.go
+//
+// Generated by this command:
+//
+//	mockgen -source=signing-key.go -destination=../../tests/mocks/signing-key-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	jwt_util "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSigningKeyService is a mock of SigningKeyService interface.
+type MockSigningKeyService struct {
+	ctrl     *gomock.Controller
+	recorder *MockSigningKeyServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockSigningKeyServiceMockRecorder is the mock recorder for MockSigningKeyService.
+type MockSigningKeyServiceMockRecorder struct {
+	mock *MockSigningKeyService
+}
+
+// NewMockSigningKeyService creates a new mock instance.
+func NewMockSigningKeyService(ctrl *gomock.Controller) *MockSigningKeyService {
+	mock := &MockSigningKeyService{ctrl: ctrl}
+	mock.recorder = &MockSigningKeyServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSigningKeyService) EXPECT() *MockSigningKeyServiceMockRecorder {
+	return m.recorder
+}
+
+// EnsureKey mocks base method.
+func (m *MockSigningKeyService) EnsureKey() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureKey")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureKey indicates an expected call of EnsureKey.
+func (mr *MockSigningKeyServiceMockRecorder) EnsureKey() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureKey", reflect.TypeOf((*MockSigningKeyService)(nil).EnsureKey))
+}
+
+// GetKeySet mocks base method.
+func (m *MockSigningKeyService) GetKeySet() (jwt_util.JSONWebKeySet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetKeySet")
+	ret0, _ := ret[0].(jwt_util.JSONWebKeySet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetKeySet indicates an expected call of GetKeySet.
+func (mr *MockSigningKeyServiceMockRecorder) GetKeySet() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKeySet", reflect.TypeOf((*MockSigningKeyService)(nil).GetKeySet))
+}
+
+// RotateKey mocks base method.
+func (m *MockSigningKeyService) RotateKey() (entity.SigningKeyRotation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateKey")
+	ret0, _ := ret[0].(entity.SigningKeyRotation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateKey indicates an expected call of RotateKey.
+func (mr *MockSigningKeyServiceMockRecorder) RotateKey() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateKey", reflect.TypeOf((*MockSigningKeyService)(nil).RotateKey))
+}
@@ -0,0 +1,85 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: token-blacklist.go
+//
+// Generated by this command:
+//
+//	mockgen -source=token-blacklist.go -destination=../../tests/mocks/token-blacklist-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTokenBlacklistService is a mock of TokenBlacklistService interface.
+type MockTokenBlacklistService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenBlacklistServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockTokenBlacklistServiceMockRecorder is the mock recorder for MockTokenBlacklistService.
+type MockTokenBlacklistServiceMockRecorder struct {
+	mock *MockTokenBlacklistService
+}
+
+// NewMockTokenBlacklistService creates a new mock instance.
+func NewMockTokenBlacklistService(ctrl *gomock.Controller) *MockTokenBlacklistService {
+	mock := &MockTokenBlacklistService{ctrl: ctrl}
+	mock.recorder = &MockTokenBlacklistServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenBlacklistService) EXPECT() *MockTokenBlacklistServiceMockRecorder {
+	return m.recorder
+}
+
+// BlacklistToken mocks base method.
+func (m *MockTokenBlacklistService) BlacklistToken(tokenID string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlacklistToken", tokenID, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BlacklistToken indicates an expected call of BlacklistToken.
+func (mr *MockTokenBlacklistServiceMockRecorder) BlacklistToken(tokenID, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlacklistToken", reflect.TypeOf((*MockTokenBlacklistService)(nil).BlacklistToken), tokenID, expiresAt)
+}
+
+// BlacklistUserTokens mocks base method.
+func (m *MockTokenBlacklistService) BlacklistUserTokens(userID, version int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlacklistUserTokens", userID, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BlacklistUserTokens indicates an expected call of BlacklistUserTokens.
+func (mr *MockTokenBlacklistServiceMockRecorder) BlacklistUserTokens(userID, version any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlacklistUserTokens", reflect.TypeOf((*MockTokenBlacklistService)(nil).BlacklistUserTokens), userID, version)
+}
+
+// IsRevoked mocks base method.
+func (m *MockTokenBlacklistService) IsRevoked(ctx context.Context, userID, tokenVersion int64, tokenID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsRevoked", ctx, userID, tokenVersion, tokenID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsRevoked indicates an expected call of IsRevoked.
+func (mr *MockTokenBlacklistServiceMockRecorder) IsRevoked(ctx, userID, tokenVersion, tokenID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsRevoked", reflect.TypeOf((*MockTokenBlacklistService)(nil).IsRevoked), ctx, userID, tokenVersion, tokenID)
+}
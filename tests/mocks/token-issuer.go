@@ -0,0 +1,141 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: token-issuer.go
+//
+// Generated by this command:
+//
+//	mockgen -source=token-issuer.go -destination=../../tests/mocks/token-issuer.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	service "github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTokenIssuer is a mock of TokenIssuer interface.
+type MockTokenIssuer struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenIssuerMockRecorder
+	isgomock struct{}
+}
+
+// MockTokenIssuerMockRecorder is the mock recorder for MockTokenIssuer.
+type MockTokenIssuerMockRecorder struct {
+	mock *MockTokenIssuer
+}
+
+// NewMockTokenIssuer creates a new mock instance.
+func NewMockTokenIssuer(ctrl *gomock.Controller) *MockTokenIssuer {
+	mock := &MockTokenIssuer{ctrl: ctrl}
+	mock.recorder = &MockTokenIssuerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenIssuer) EXPECT() *MockTokenIssuerMockRecorder {
+	return m.recorder
+}
+
+// IssueScopedToken mocks base method.
+func (m *MockTokenIssuer) IssueScopedToken(user entity.User, clientID string, scopes []string, issuedAt time.Time) (service.IssuedToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueScopedToken", user, clientID, scopes, issuedAt)
+	ret0, _ := ret[0].(service.IssuedToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueScopedToken indicates an expected call of IssueScopedToken.
+func (mr *MockTokenIssuerMockRecorder) IssueScopedToken(user, clientID, scopes, issuedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueScopedToken", reflect.TypeOf((*MockTokenIssuer)(nil).IssueScopedToken), user, clientID, scopes, issuedAt)
+}
+
+// IssueToken mocks base method.
+func (m *MockTokenIssuer) IssueToken(user entity.User, clientID string, issuedAt time.Time) (service.IssuedToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueToken", user, clientID, issuedAt)
+	ret0, _ := ret[0].(service.IssuedToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueToken indicates an expected call of IssueToken.
+func (mr *MockTokenIssuerMockRecorder) IssueToken(user, clientID, issuedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueToken", reflect.TypeOf((*MockTokenIssuer)(nil).IssueToken), user, clientID, issuedAt)
+}
+
+// ParseUserID mocks base method.
+func (m *MockTokenIssuer) ParseUserID(accessToken string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseUserID", accessToken)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseUserID indicates an expected call of ParseUserID.
+func (mr *MockTokenIssuerMockRecorder) ParseUserID(accessToken any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseUserID", reflect.TypeOf((*MockTokenIssuer)(nil).ParseUserID), accessToken)
+}
+
+// TokenType mocks base method.
+func (m *MockTokenIssuer) TokenType() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TokenType")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// TokenType indicates an expected call of TokenType.
+func (mr *MockTokenIssuerMockRecorder) TokenType() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TokenType", reflect.TypeOf((*MockTokenIssuer)(nil).TokenType))
+}
+
+// MockScopeResolver is a mock of ScopeResolver interface.
+type MockScopeResolver struct {
+	ctrl     *gomock.Controller
+	recorder *MockScopeResolverMockRecorder
+	isgomock struct{}
+}
+
+// MockScopeResolverMockRecorder is the mock recorder for MockScopeResolver.
+type MockScopeResolverMockRecorder struct {
+	mock *MockScopeResolver
+}
+
+// NewMockScopeResolver creates a new mock instance.
+func NewMockScopeResolver(ctrl *gomock.Controller) *MockScopeResolver {
+	mock := &MockScopeResolver{ctrl: ctrl}
+	mock.recorder = &MockScopeResolverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockScopeResolver) EXPECT() *MockScopeResolverMockRecorder {
+	return m.recorder
+}
+
+// GetScopes mocks base method.
+func (m *MockScopeResolver) GetScopes(roleNames []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScopes", roleNames)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScopes indicates an expected call of GetScopes.
+func (mr *MockScopeResolverMockRecorder) GetScopes(roleNames any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScopes", reflect.TypeOf((*MockScopeResolver)(nil).GetScopes), roleNames)
+}
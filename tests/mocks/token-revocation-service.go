@@ -0,0 +1,85 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: token-revocation.go
+//
+// Generated by this command:
+//
+//	mockgen -source=token-revocation.go -destination=../../tests/mocks/token-revocation-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTokenRevocationService is a mock of TokenRevocationService interface.
+type MockTokenRevocationService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenRevocationServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockTokenRevocationServiceMockRecorder is the mock recorder for MockTokenRevocationService.
+type MockTokenRevocationServiceMockRecorder struct {
+	mock *MockTokenRevocationService
+}
+
+// NewMockTokenRevocationService creates a new mock instance.
+func NewMockTokenRevocationService(ctrl *gomock.Controller) *MockTokenRevocationService {
+	mock := &MockTokenRevocationService{ctrl: ctrl}
+	mock.recorder = &MockTokenRevocationServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenRevocationService) EXPECT() *MockTokenRevocationServiceMockRecorder {
+	return m.recorder
+}
+
+// LoadRevocations mocks base method.
+func (m *MockTokenRevocationService) LoadRevocations() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadRevocations")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LoadRevocations indicates an expected call of LoadRevocations.
+func (mr *MockTokenRevocationServiceMockRecorder) LoadRevocations() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadRevocations", reflect.TypeOf((*MockTokenRevocationService)(nil).LoadRevocations))
+}
+
+// RevokeSession mocks base method.
+func (m *MockTokenRevocationService) RevokeSession(ctx context.Context, req entity.LogoutRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockTokenRevocationServiceMockRecorder) RevokeSession(ctx, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockTokenRevocationService)(nil).RevokeSession), ctx, req)
+}
+
+// RevokeUserTokens mocks base method.
+func (m *MockTokenRevocationService) RevokeUserTokens(ctx context.Context, userID int64) (entity.TokenRevocation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserTokens", ctx, userID)
+	ret0, _ := ret[0].(entity.TokenRevocation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeUserTokens indicates an expected call of RevokeUserTokens.
+func (mr *MockTokenRevocationServiceMockRecorder) RevokeUserTokens(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserTokens", reflect.TypeOf((*MockTokenRevocationService)(nil).RevokeUserTokens), ctx, userID)
+}
@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: token-usage.go
+//
+// Generated by this command:
+//
+//	mockgen -source=token-usage.go -destination=../../tests/mocks/token-usage-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockTokenUsageRepository is a mock of TokenUsageRepository interface.
+type MockTokenUsageRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenUsageRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockTokenUsageRepositoryMockRecorder is the mock recorder for MockTokenUsageRepository.
+type MockTokenUsageRepositoryMockRecorder struct {
+	mock *MockTokenUsageRepository
+}
+
+// NewMockTokenUsageRepository creates a new mock instance.
+func NewMockTokenUsageRepository(ctrl *gomock.Controller) *MockTokenUsageRepository {
+	mock := &MockTokenUsageRepository{ctrl: ctrl}
+	mock.recorder = &MockTokenUsageRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenUsageRepository) EXPECT() *MockTokenUsageRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetTokenUsage mocks base method.
+func (m *MockTokenUsageRepository) GetTokenUsage(tx *gorm.DB, filter entity.TokenStatsFilter) ([]entity.TokenUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTokenUsage", tx, filter)
+	ret0, _ := ret[0].([]entity.TokenUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTokenUsage indicates an expected call of GetTokenUsage.
+func (mr *MockTokenUsageRepositoryMockRecorder) GetTokenUsage(tx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTokenUsage", reflect.TypeOf((*MockTokenUsageRepository)(nil).GetTokenUsage), tx, filter)
+}
+
+// IncrementTokenUsage mocks base method.
+func (m *MockTokenUsageRepository) IncrementTokenUsage(tx *gorm.DB, usage entity.TokenUsage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementTokenUsage", tx, usage)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrementTokenUsage indicates an expected call of IncrementTokenUsage.
+func (mr *MockTokenUsageRepositoryMockRecorder) IncrementTokenUsage(tx, usage any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementTokenUsage", reflect.TypeOf((*MockTokenUsageRepository)(nil).IncrementTokenUsage), tx, usage)
+}
@@ -0,0 +1,121 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: token-usage.go
+//
+// Generated by this command:
+//
+//	mockgen -source=token-usage.go -destination=../../tests/mocks/token-usage-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	service "github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTokenUsageService is a mock of TokenUsageService interface.
+type MockTokenUsageService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenUsageServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockTokenUsageServiceMockRecorder is the mock recorder for MockTokenUsageService.
+type MockTokenUsageServiceMockRecorder struct {
+	mock *MockTokenUsageService
+}
+
+// NewMockTokenUsageService creates a new mock instance.
+func NewMockTokenUsageService(ctrl *gomock.Controller) *MockTokenUsageService {
+	mock := &MockTokenUsageService{ctrl: ctrl}
+	mock.recorder = &MockTokenUsageServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenUsageService) EXPECT() *MockTokenUsageServiceMockRecorder {
+	return m.recorder
+}
+
+// GetTokenStats mocks base method.
+func (m *MockTokenUsageService) GetTokenStats(ctx context.Context, filter entity.TokenStatsFilter) (entity.TokenStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTokenStats", ctx, filter)
+	ret0, _ := ret[0].(entity.TokenStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTokenStats indicates an expected call of GetTokenStats.
+func (mr *MockTokenUsageServiceMockRecorder) GetTokenStats(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTokenStats", reflect.TypeOf((*MockTokenUsageService)(nil).GetTokenStats), ctx, filter)
+}
+
+// IncrementTokenUsage mocks base method.
+func (m *MockTokenUsageService) IncrementTokenUsage(usage []entity.TokenUsage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementTokenUsage", usage)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrementTokenUsage indicates an expected call of IncrementTokenUsage.
+func (mr *MockTokenUsageServiceMockRecorder) IncrementTokenUsage(usage any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementTokenUsage", reflect.TypeOf((*MockTokenUsageService)(nil).IncrementTokenUsage), usage)
+}
+
+// MockTokenUsageRecorder is a mock of TokenUsageRecorder interface.
+type MockTokenUsageRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenUsageRecorderMockRecorder
+	isgomock struct{}
+}
+
+// MockTokenUsageRecorderMockRecorder is the mock recorder for MockTokenUsageRecorder.
+type MockTokenUsageRecorderMockRecorder struct {
+	mock *MockTokenUsageRecorder
+}
+
+// NewMockTokenUsageRecorder creates a new mock instance.
+func NewMockTokenUsageRecorder(ctrl *gomock.Controller) *MockTokenUsageRecorder {
+	mock := &MockTokenUsageRecorder{ctrl: ctrl}
+	mock.recorder = &MockTokenUsageRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenUsageRecorder) EXPECT() *MockTokenUsageRecorderMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockTokenUsageRecorder) Close() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Close")
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockTokenUsageRecorderMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockTokenUsageRecorder)(nil).Close))
+}
+
+// Record mocks base method.
+func (m *MockTokenUsageRecorder) Record(event service.TokenEvent, userID int64, client string, at time.Time) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Record", event, userID, client, at)
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockTokenUsageRecorderMockRecorder) Record(event, userID, client, at any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockTokenUsageRecorder)(nil).Record), event, userID, client, at)
+}
@@ -0,0 +1,309 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: user.go
+//
+// Generated by this command:
+//
+//	mockgen -source=user.go -destination=../../tests/mocks/user-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockUserRepository is a mock of UserRepository interface.
+type MockUserRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockUserRepositoryMockRecorder is the mock recorder for MockUserRepository.
+type MockUserRepositoryMockRecorder struct {
+	mock *MockUserRepository
+}
+
+// NewMockUserRepository creates a new mock instance.
+func NewMockUserRepository(ctrl *gomock.Controller) *MockUserRepository {
+	mock := &MockUserRepository{ctrl: ctrl}
+	mock.recorder = &MockUserRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateUser mocks base method.
+func (m *MockUserRepository) CreateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", ctx, tx, user)
+	ret0, _ := ret[0].(entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockUserRepositoryMockRecorder) CreateUser(ctx, tx, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserRepository)(nil).CreateUser), ctx, tx, user)
+}
+
+// GetRevokedTokenVersions mocks base method.
+func (m *MockUserRepository) GetRevokedTokenVersions(ctx context.Context, tx *gorm.DB) (map[int64]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRevokedTokenVersions", ctx, tx)
+	ret0, _ := ret[0].(map[int64]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRevokedTokenVersions indicates an expected call of GetRevokedTokenVersions.
+func (mr *MockUserRepositoryMockRecorder) GetRevokedTokenVersions(ctx, tx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevokedTokenVersions", reflect.TypeOf((*MockUserRepository)(nil).GetRevokedTokenVersions), ctx, tx)
+}
+
+// GetUserByEmail mocks base method.
+func (m *MockUserRepository) GetUserByEmail(ctx context.Context, tx *gorm.DB, email string) (entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByEmail", ctx, tx, email)
+	ret0, _ := ret[0].(entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByEmail indicates an expected call of GetUserByEmail.
+func (mr *MockUserRepositoryMockRecorder) GetUserByEmail(ctx, tx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockUserRepository)(nil).GetUserByEmail), ctx, tx, email)
+}
+
+// GetUserByID mocks base method.
+func (m *MockUserRepository) GetUserByID(ctx context.Context, tx *gorm.DB, id int64) (entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", ctx, tx, id)
+	ret0, _ := ret[0].(entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockUserRepositoryMockRecorder) GetUserByID(ctx, tx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockUserRepository)(nil).GetUserByID), ctx, tx, id)
+}
+
+// GetUserByUsername mocks base method.
+func (m *MockUserRepository) GetUserByUsername(ctx context.Context, tx *gorm.DB, username string) (entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByUsername", ctx, tx, username)
+	ret0, _ := ret[0].(entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByUsername indicates an expected call of GetUserByUsername.
+func (mr *MockUserRepositoryMockRecorder) GetUserByUsername(ctx, tx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUsername", reflect.TypeOf((*MockUserRepository)(nil).GetUserByUsername), ctx, tx, username)
+}
+
+// GetUsers mocks base method.
+func (m *MockUserRepository) GetUsers(ctx context.Context, tx *gorm.DB, page, limit int) ([]entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsers", ctx, tx, page, limit)
+	ret0, _ := ret[0].([]entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsers indicates an expected call of GetUsers.
+func (mr *MockUserRepositoryMockRecorder) GetUsers(ctx, tx, page, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockUserRepository)(nil).GetUsers), ctx, tx, page, limit)
+}
+
+// IncrementTokenVersion mocks base method.
+func (m *MockUserRepository) IncrementTokenVersion(ctx context.Context, tx *gorm.DB, id int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementTokenVersion", ctx, tx, id)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementTokenVersion indicates an expected call of IncrementTokenVersion.
+func (mr *MockUserRepositoryMockRecorder) IncrementTokenVersion(ctx, tx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementTokenVersion", reflect.TypeOf((*MockUserRepository)(nil).IncrementTokenVersion), ctx, tx, id)
+}
+
+// ReplaceUserRoles mocks base method.
+func (m *MockUserRepository) ReplaceUserRoles(ctx context.Context, tx *gorm.DB, userID int64, roles []entity.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceUserRoles", ctx, tx, userID, roles)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceUserRoles indicates an expected call of ReplaceUserRoles.
+func (mr *MockUserRepositoryMockRecorder) ReplaceUserRoles(ctx, tx, userID, roles any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceUserRoles", reflect.TypeOf((*MockUserRepository)(nil).ReplaceUserRoles), ctx, tx, userID, roles)
+}
+
+// SoftDeleteUser mocks base method.
+func (m *MockUserRepository) SoftDeleteUser(ctx context.Context, tx *gorm.DB, id, deletedBy int64, deletedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SoftDeleteUser", ctx, tx, id, deletedBy, deletedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SoftDeleteUser indicates an expected call of SoftDeleteUser.
+func (mr *MockUserRepositoryMockRecorder) SoftDeleteUser(ctx, tx, id, deletedBy, deletedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SoftDeleteUser", reflect.TypeOf((*MockUserRepository)(nil).SoftDeleteUser), ctx, tx, id, deletedBy, deletedAt)
+}
+
+// UpdateLastLogin mocks base method.
+func (m *MockUserRepository) UpdateLastLogin(ctx context.Context, tx *gorm.DB, id int64, lastLogin time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLastLogin", ctx, tx, id, lastLogin)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLastLogin indicates an expected call of UpdateLastLogin.
+func (mr *MockUserRepositoryMockRecorder) UpdateLastLogin(ctx, tx, id, lastLogin any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLastLogin", reflect.TypeOf((*MockUserRepository)(nil).UpdateLastLogin), ctx, tx, id, lastLogin)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, tx *gorm.DB, id int64, password string, credentialsExpiresAt *time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", ctx, tx, id, password, credentialsExpiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockUserRepositoryMockRecorder) UpdatePassword(ctx, tx, id, password, credentialsExpiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockUserRepository)(nil).UpdatePassword), ctx, tx, id, password, credentialsExpiresAt)
+}
+
+// UpdateUser mocks base method.
+func (m *MockUserRepository) UpdateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUser", ctx, tx, user)
+	ret0, _ := ret[0].(entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUser indicates an expected call of UpdateUser.
+func (mr *MockUserRepositoryMockRecorder) UpdateUser(ctx, tx, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockUserRepository)(nil).UpdateUser), ctx, tx, user)
+}
+
+// UpdateUserState mocks base method.
+func (m *MockUserRepository) UpdateUserState(ctx context.Context, tx *gorm.DB, id int64, state entity.UserState, reason *string, changedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserState", ctx, tx, id, state, reason, changedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserState indicates an expected call of UpdateUserState.
+func (mr *MockUserRepositoryMockRecorder) UpdateUserState(ctx, tx, id, state, reason, changedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserState", reflect.TypeOf((*MockUserRepository)(nil).UpdateUserState), ctx, tx, id, state, reason, changedAt)
+}
+
+// MockRoleCacheWarmer is a mock of RoleCacheWarmer interface.
+type MockRoleCacheWarmer struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoleCacheWarmerMockRecorder
+	isgomock struct{}
+}
+
+// MockRoleCacheWarmerMockRecorder is the mock recorder for MockRoleCacheWarmer.
+type MockRoleCacheWarmerMockRecorder struct {
+	mock *MockRoleCacheWarmer
+}
+
+// NewMockRoleCacheWarmer creates a new mock instance.
+func NewMockRoleCacheWarmer(ctrl *gomock.Controller) *MockRoleCacheWarmer {
+	mock := &MockRoleCacheWarmer{ctrl: ctrl}
+	mock.recorder = &MockRoleCacheWarmerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoleCacheWarmer) EXPECT() *MockRoleCacheWarmerMockRecorder {
+	return m.recorder
+}
+
+// WarmRoleCache mocks base method.
+func (m *MockRoleCacheWarmer) WarmRoleCache(tx *gorm.DB, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WarmRoleCache", tx, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WarmRoleCache indicates an expected call of WarmRoleCache.
+func (mr *MockRoleCacheWarmerMockRecorder) WarmRoleCache(tx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WarmRoleCache", reflect.TypeOf((*MockRoleCacheWarmer)(nil).WarmRoleCache), tx, limit)
+}
+
+// MockRoleCacheInvalidator is a mock of RoleCacheInvalidator interface.
+type MockRoleCacheInvalidator struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoleCacheInvalidatorMockRecorder
+	isgomock struct{}
+}
+
+// MockRoleCacheInvalidatorMockRecorder is the mock recorder for MockRoleCacheInvalidator.
+type MockRoleCacheInvalidatorMockRecorder struct {
+	mock *MockRoleCacheInvalidator
+}
+
+// NewMockRoleCacheInvalidator creates a new mock instance.
+func NewMockRoleCacheInvalidator(ctrl *gomock.Controller) *MockRoleCacheInvalidator {
+	mock := &MockRoleCacheInvalidator{ctrl: ctrl}
+	mock.recorder = &MockRoleCacheInvalidatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoleCacheInvalidator) EXPECT() *MockRoleCacheInvalidatorMockRecorder {
+	return m.recorder
+}
+
+// InvalidateRoleCache mocks base method.
+func (m *MockRoleCacheInvalidator) InvalidateRoleCache() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "InvalidateRoleCache")
+}
+
+// InvalidateRoleCache indicates an expected call of InvalidateRoleCache.
+func (mr *MockRoleCacheInvalidatorMockRecorder) InvalidateRoleCache() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateRoleCache", reflect.TypeOf((*MockRoleCacheInvalidator)(nil).InvalidateRoleCache))
+}
@@ -0,0 +1,57 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: user-state.go
+//
+// Generated by this command:
+//
+//	mockgen -source=user-state.go -destination=../../tests/mocks/user-state-service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserStateService is a mock of UserStateService interface.
+type MockUserStateService struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserStateServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockUserStateServiceMockRecorder is the mock recorder for MockUserStateService.
+type MockUserStateServiceMockRecorder struct {
+	mock *MockUserStateService
+}
+
+// NewMockUserStateService creates a new mock instance.
+func NewMockUserStateService(ctrl *gomock.Controller) *MockUserStateService {
+	mock := &MockUserStateService{ctrl: ctrl}
+	mock.recorder = &MockUserStateServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserStateService) EXPECT() *MockUserStateServiceMockRecorder {
+	return m.recorder
+}
+
+// ChangeUserState mocks base method.
+func (m *MockUserStateService) ChangeUserState(ctx context.Context, userID int64, req entity.UserStateRequest) (entity.UserStateChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeUserState", ctx, userID, req)
+	ret0, _ := ret[0].(entity.UserStateChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeUserState indicates an expected call of ChangeUserState.
+func (mr *MockUserStateServiceMockRecorder) ChangeUserState(ctx, userID, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeUserState", reflect.TypeOf((*MockUserStateService)(nil).ChangeUserState), ctx, userID, req)
+}
@@ -0,0 +1,117 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: webhook.go
+//
+// Generated by this command:
+//
+//	mockgen -source=webhook.go -destination=../../tests/mocks/webhook-repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	entity "github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+	gorm "gorm.io/gorm"
+)
+
+// MockWebhookDeliveryRepository is a mock of WebhookDeliveryRepository interface.
+type MockWebhookDeliveryRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookDeliveryRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockWebhookDeliveryRepositoryMockRecorder is the mock recorder for MockWebhookDeliveryRepository.
+type MockWebhookDeliveryRepositoryMockRecorder struct {
+	mock *MockWebhookDeliveryRepository
+}
+
+// NewMockWebhookDeliveryRepository creates a new mock instance.
+func NewMockWebhookDeliveryRepository(ctrl *gomock.Controller) *MockWebhookDeliveryRepository {
+	mock := &MockWebhookDeliveryRepository{ctrl: ctrl}
+	mock.recorder = &MockWebhookDeliveryRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookDeliveryRepository) EXPECT() *MockWebhookDeliveryRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateWebhookDelivery mocks base method.
+func (m *MockWebhookDeliveryRepository) CreateWebhookDelivery(tx *gorm.DB, delivery entity.WebhookDelivery) (entity.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhookDelivery", tx, delivery)
+	ret0, _ := ret[0].(entity.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhookDelivery indicates an expected call of CreateWebhookDelivery.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) CreateWebhookDelivery(tx, delivery any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhookDelivery", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).CreateWebhookDelivery), tx, delivery)
+}
+
+// GetDueWebhookDeliveries mocks base method.
+func (m *MockWebhookDeliveryRepository) GetDueWebhookDeliveries(tx *gorm.DB, now time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDueWebhookDeliveries", tx, now, limit)
+	ret0, _ := ret[0].([]entity.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDueWebhookDeliveries indicates an expected call of GetDueWebhookDeliveries.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) GetDueWebhookDeliveries(tx, now, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueWebhookDeliveries", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).GetDueWebhookDeliveries), tx, now, limit)
+}
+
+// GetWebhookDeliveriesByStatus mocks base method.
+func (m *MockWebhookDeliveryRepository) GetWebhookDeliveriesByStatus(tx *gorm.DB, statuses []string, page, limit int) ([]entity.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhookDeliveriesByStatus", tx, statuses, page, limit)
+	ret0, _ := ret[0].([]entity.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhookDeliveriesByStatus indicates an expected call of GetWebhookDeliveriesByStatus.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) GetWebhookDeliveriesByStatus(tx, statuses, page, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhookDeliveriesByStatus", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).GetWebhookDeliveriesByStatus), tx, statuses, page, limit)
+}
+
+// GetWebhookDeliveryByID mocks base method.
+func (m *MockWebhookDeliveryRepository) GetWebhookDeliveryByID(tx *gorm.DB, id int64) (entity.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhookDeliveryByID", tx, id)
+	ret0, _ := ret[0].(entity.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhookDeliveryByID indicates an expected call of GetWebhookDeliveryByID.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) GetWebhookDeliveryByID(tx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhookDeliveryByID", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).GetWebhookDeliveryByID), tx, id)
+}
+
+// UpdateWebhookDelivery mocks base method.
+func (m *MockWebhookDeliveryRepository) UpdateWebhookDelivery(tx *gorm.DB, delivery entity.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebhookDelivery", tx, delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWebhookDelivery indicates an expected call of UpdateWebhookDelivery.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) UpdateWebhookDelivery(tx, delivery any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebhookDelivery", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).UpdateWebhookDelivery), tx, delivery)
+}
@@ -0,0 +1,418 @@
+package test_alerting
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/alerting"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/anomaly"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/mailer"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// recordingChannel is a channel recording the alerts it sent, failing with err if set.
+type recordingChannel struct {
+	mu     sync.Mutex
+	name   string
+	err    error
+	alerts []alerting.Alert
+}
+
+func (c *recordingChannel) Name() string {
+	return c.name
+}
+
+func (c *recordingChannel) Send(a alerting.Alert) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.alerts = append(c.alerts, a)
+	return c.err
+}
+
+func (c *recordingChannel) sent() []alerting.Alert {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]alerting.Alert(nil), c.alerts...)
+}
+
+// recordingNotifier is a notifier recording the alerts raised, without sending them.
+type recordingNotifier struct {
+	mu     sync.Mutex
+	alerts []alerting.Alert
+}
+
+func (n *recordingNotifier) Notify(a alerting.Alert) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, a)
+}
+
+func (n *recordingNotifier) raised() []alerting.Alert {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]alerting.Alert(nil), n.alerts...)
+}
+
+// recordingMailer is a mailer recording the messages it sent.
+type recordingMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (m *recordingMailer) Send(msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+func newClock() *clock.FakeClock {
+	return clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := alerting.ParseRoutes(" anomaly = slack|EMAIL|slack , certificate_expiry=email|sms, webhook_dead= ")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		alerting.EventAnomaly:           {alerting.ChannelSlack, alerting.ChannelEmail},
+		alerting.EventCertificateExpiry: {alerting.ChannelEmail, alerting.ChannelSMS},
+		alerting.EventWebhookDead:       {},
+	}, routes)
+
+	routes, err = alerting.ParseRoutes("")
+	require.NoError(t, err)
+	assert.Empty(t, routes)
+
+	for _, value := range []string{"anomaly", "=slack", "login=slack", "anomaly=pager"} {
+		_, err := alerting.ParseRoutes(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestConfig_RoutedChannelsMustBeConfigured(t *testing.T) {
+	t.Setenv("ALERT_ROUTES", "anomaly=slack,certificate_expiry=sms")
+
+	cfg, err := alerting.LoadConfig()
+	require.NoError(t, err)
+	assert.ErrorContains(t, cfg.Validate(), "ALERT_SLACK_WEBHOOK_URL")
+	_, err = alerting.New(cfg, mailer.NewNoopMailer(), newClock())
+	assert.Error(t, err)
+
+	t.Setenv("ALERT_SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/T000/B000/XXXX")
+	cfg, err = alerting.LoadConfig()
+	require.NoError(t, err)
+	assert.ErrorContains(t, cfg.Validate(), "ALERT_SMS_")
+
+	t.Setenv("ALERT_SMS_ACCOUNT_SID", "AC123")
+	t.Setenv("ALERT_SMS_AUTH_TOKEN", "token")
+	t.Setenv("ALERT_SMS_FROM", "+15550000000")
+	t.Setenv("ALERT_SMS_TO", "+15551111111, +15552222222")
+	cfg, err = alerting.LoadConfig()
+	require.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, alerting.DefaultSMSAPIURL, cfg.SMS.APIURL)
+	assert.Equal(t, []string{"+15551111111", "+15552222222"}, cfg.SMS.To)
+
+	d, err := alerting.New(cfg, mailer.NewNoopMailer(), newClock())
+	require.NoError(t, err)
+	d.Close()
+}
+
+func TestDispatcher_RoutesAlertsPerEvent(t *testing.T) {
+	slack := &recordingChannel{name: alerting.ChannelSlack}
+	sms := &recordingChannel{name: alerting.ChannelSMS, err: errors.New("provider down")}
+	email := &recordingChannel{name: alerting.ChannelEmail}
+	fallback := &recordingChannel{name: alerting.ChannelLog}
+
+	d := alerting.NewDispatcher(map[string][]alerting.Channel{
+		alerting.EventAnomaly:           {slack},
+		alerting.EventCertificateExpiry: {sms, email},
+		alerting.EventWebhookDead:       {},
+	}, []alerting.Channel{fallback}, time.Minute, 0, newClock())
+
+	d.Notify(alerting.Alert{Event: alerting.EventAnomaly, Title: "anomaly"})
+	d.Notify(alerting.Alert{Event: alerting.EventCertificateExpiry, Title: "certificate"})
+	d.Notify(alerting.Alert{Event: alerting.EventWebhookDead, Title: "dead"})
+	d.Notify(alerting.Alert{Event: "unrouted", Title: "unrouted"})
+	d.Close()
+
+	require.Len(t, slack.sent(), 1)
+	assert.Equal(t, "anomaly", slack.sent()[0].Title)
+	assert.Equal(t, newClock().Now(), slack.sent()[0].Timestamp)
+
+	// A failed channel does not prevent the other channels of the event from sending the alert
+	require.Len(t, sms.sent(), 1)
+	require.Len(t, email.sent(), 1)
+	assert.Equal(t, "certificate", email.sent()[0].Title)
+
+	require.Len(t, fallback.sent(), 1)
+	assert.Equal(t, "unrouted", fallback.sent()[0].Title)
+
+	// A closed dispatcher drops the alerts, a nil one too
+	d.Notify(alerting.Alert{Event: alerting.EventAnomaly, Title: "late"})
+	assert.Len(t, slack.sent(), 1)
+	var nilDispatcher *alerting.Dispatcher
+	nilDispatcher.Notify(alerting.Alert{Event: alerting.EventAnomaly})
+	nilDispatcher.Close()
+}
+
+func TestDispatcher_DeduplicatesAlerts(t *testing.T) {
+	ch := &recordingChannel{name: alerting.ChannelLog}
+	clk := newClock()
+	d := alerting.NewDispatcher(nil, []alerting.Channel{ch}, 15*time.Minute, 0, clk)
+	defer d.Close()
+
+	waitFor := func(n int) {
+		require.Eventually(t, func() bool { return len(ch.sent()) == n }, time.Second, 5*time.Millisecond)
+	}
+
+	d.Notify(alerting.Alert{Event: alerting.EventAnomaly, Key: "failed_login_per_ip:ip:10.0.0.1"})
+	waitFor(1)
+
+	// The same alert is not sent again during the window, the alerts about another subject or without a key are
+	d.Notify(alerting.Alert{Event: alerting.EventAnomaly, Key: "failed_login_per_ip:ip:10.0.0.1"})
+	d.Notify(alerting.Alert{Event: alerting.EventAnomaly, Key: "failed_login_per_ip:ip:10.0.0.2"})
+	d.Notify(alerting.Alert{Event: alerting.EventAnomaly})
+	waitFor(3)
+
+	clk.Advance(15 * time.Minute)
+	d.Notify(alerting.Alert{Event: alerting.EventAnomaly, Key: "failed_login_per_ip:ip:10.0.0.1"})
+	waitFor(4)
+}
+
+func TestEmailChannel_EmailsEveryRecipient(t *testing.T) {
+	m := &recordingMailer{}
+	ch := alerting.NewEmailChannel(m, []string{"ops@example.com", "oncall@example.com"})
+
+	err := ch.Send(alerting.Alert{
+		Title:     "Webhook delivery dead-lettered",
+		Message:   "All the attempts failed.",
+		Fields:    map[string]string{"event": "consumer.created", "delivery_id": "7"},
+		Timestamp: newClock().Now(),
+	})
+	require.NoError(t, err)
+
+	require.Len(t, m.messages, 2)
+	assert.Equal(t, "ops@example.com", m.messages[0].To)
+	assert.Equal(t, "oncall@example.com", m.messages[1].To)
+	assert.Equal(t, "[Alert] Webhook delivery dead-lettered", m.messages[0].Subject)
+	assert.Equal(t, "Webhook delivery dead-lettered\nAll the attempts failed.\ndelivery_id: 7\nevent: consumer.created\n\nRaised at 2025-01-15T10:00:00Z", m.messages[0].Body)
+}
+
+func TestSlackChannel_PostsTheAlert(t *testing.T) {
+	var body map[string]string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("invalid_payload"))
+	}))
+	defer srv.Close()
+
+	ch := alerting.NewSlackChannel(srv.URL, nil)
+	require.NoError(t, ch.Send(alerting.Alert{Title: "Authentication anomaly detected", Message: "The geo_change rule was triggered."}))
+	assert.Equal(t, ":rotating_light: *Authentication anomaly detected*\nThe geo_change rule was triggered.", body["text"])
+
+	status = http.StatusBadRequest
+	err := ch.Send(alerting.Alert{Title: "Authentication anomaly detected"})
+	assert.ErrorContains(t, err, "status 400: invalid_payload")
+}
+
+func TestSMSChannel_SendsToEveryPhoneNumber(t *testing.T) {
+	var forms []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "token", pass)
+
+		data, _ := io.ReadAll(r.Body)
+		form, err := url.ParseQuery(string(data))
+		require.NoError(t, err)
+		forms = append(forms, form)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	ch := alerting.NewSMSChannel(alerting.SMSConfig{
+		APIURL:     srv.URL + "/",
+		AccountSID: "AC123",
+		AuthToken:  "token",
+		From:       "+15550000000",
+		To:         []string{"+15551111111", "+15552222222"},
+	}, nil)
+	require.NoError(t, ch.Send(alerting.Alert{
+		Title:   "TLS certificate about to expire",
+		Message: "Renew it.",
+		Fields:  map[string]string{"certificate": "/etc/ssl/server.crt"},
+	}))
+
+	require.Len(t, forms, 2)
+	assert.Equal(t, "+15550000000", forms[0].Get("From"))
+	assert.Equal(t, "+15551111111", forms[0].Get("To"))
+	assert.Equal(t, "+15552222222", forms[1].Get("To"))
+	assert.Equal(t, "TLS certificate about to expire: Renew it.", forms[0].Get("Body"))
+}
+
+func TestWebhookService_AlertsDeadDeliveries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	store := testsupport.UseMemoryDatabase(t)
+	clk := newClock()
+	notifier := &recordingNotifier{}
+	policy := policyconfig.WebhookPolicy{URL: srv.URL, MaxAttempts: 2, Backoff: time.Second, MaxBackoff: time.Second, Timeout: 5 * time.Second}
+	s := service.NewWebhookService(database.DefaultStore(), repository.NewMemoryWebhookDeliveryRepository(store), policy, nil, clk, service.WithWebhookAlerts(notifier))
+	require.NoError(t, s.Enqueue(context.Background(), entity.WebhookEventConsumerCreated, nil))
+
+	// A failed attempt with attempts left raises no alert
+	_, err := s.DeliverDue()
+	require.NoError(t, err)
+	assert.Empty(t, notifier.raised())
+
+	clk.Advance(time.Second)
+	_, err = s.DeliverDue()
+	require.NoError(t, err)
+
+	alerts := notifier.raised()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, alerting.EventWebhookDead, alerts[0].Event)
+	assert.Equal(t, "1", alerts[0].Key)
+	assert.Equal(t, "1", alerts[0].Fields["delivery_id"])
+	assert.Equal(t, entity.WebhookEventConsumerCreated, alerts[0].Fields["event"])
+	assert.Equal(t, "unexpected response status 503", alerts[0].Fields["error"])
+}
+
+func TestDetector_AlertAction(t *testing.T) {
+	notifier := &recordingNotifier{}
+	d := anomaly.NewDetector(anomaly.Config{
+		Enabled:              true,
+		Window:               time.Minute,
+		FailedLoginThreshold: 2,
+		Actions:              []anomaly.Action{anomaly.ActionAlert},
+	}, clock.New())
+
+	// The alert action does nothing until a notifier is set
+	d.Record(anomaly.Event{Type: anomaly.EventFailedLogin, IP: "10.0.0.1"})
+	d.Record(anomaly.Event{Type: anomaly.EventFailedLogin, IP: "10.0.0.1"})
+	assert.Empty(t, notifier.raised())
+
+	d.SetNotifier(notifier)
+	d.Record(anomaly.Event{Type: anomaly.EventFailedLogin, IP: "10.0.0.1"})
+
+	alerts := notifier.raised()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, alerting.EventAnomaly, alerts[0].Event)
+	assert.Equal(t, "failed_login_per_ip:ip:10.0.0.1", alerts[0].Key)
+	assert.Equal(t, "3", alerts[0].Fields["count"])
+	assert.Equal(t, "10.0.0.1", alerts[0].Fields["ip"])
+	assert.NotContains(t, alerts[0].Fields, "account")
+}
+
+func TestLoadConfig_ParsesAlertAction(t *testing.T) {
+	t.Setenv("ANOMALY_ACTIONS", "log, alert")
+	assert.Equal(t, []anomaly.Action{anomaly.ActionLog, anomaly.ActionAlert}, anomaly.LoadConfig().Actions)
+}
+
+// writeCertificate writes a self-signed PEM certificate expiring at notAfter, and returns its path.
+func writeCertificate(t *testing.T, notAfter time.Time) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "server.crt")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+
+	return path
+}
+
+func TestCertificateMonitor_AlertsBeforeExpiry(t *testing.T) {
+	clk := newClock()
+	notAfter := clk.Now().Add(20 * 24 * time.Hour).Truncate(time.Second)
+	path := writeCertificate(t, notAfter)
+
+	expiry, err := alerting.CertificateExpiry(path)
+	require.NoError(t, err)
+	assert.True(t, notAfter.Equal(expiry))
+
+	notifier := &recordingNotifier{}
+	m := alerting.NewCertificateMonitor(notifier, path, 14*24*time.Hour, time.Hour, clk)
+	m.Close()
+
+	// The certificate expires in 20 days, after the warning of 14 days
+	raised, err := m.Check()
+	require.NoError(t, err)
+	assert.False(t, raised)
+	assert.Empty(t, notifier.raised())
+
+	clk.Advance(10 * 24 * time.Hour)
+	raised, err = m.Check()
+	require.NoError(t, err)
+	assert.True(t, raised)
+
+	alerts := notifier.raised()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, alerting.EventCertificateExpiry, alerts[0].Event)
+	assert.Equal(t, path, alerts[0].Key)
+	assert.Contains(t, alerts[0].Message, "expires in 10 day(s)")
+
+	clk.Advance(11 * 24 * time.Hour)
+	_, err = m.Check()
+	require.NoError(t, err)
+	assert.Contains(t, notifier.raised()[1].Message, "expired on")
+}
+
+func TestCertificateExpiry_InvalidFile(t *testing.T) {
+	_, err := alerting.CertificateExpiry(filepath.Join(t.TempDir(), "missing.crt"))
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "server.crt")
+	require.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0o600))
+	_, err = alerting.CertificateExpiry(path)
+	assert.Error(t, err)
+}
+
+func TestLoadCertificateExpiryWarning(t *testing.T) {
+	assert.Equal(t, alerting.DefaultCertificateExpiryWarning, alerting.LoadCertificateExpiryWarning())
+
+	t.Setenv("ALERT_CERT_EXPIRY_DAYS", "30")
+	assert.Equal(t, 30*24*time.Hour, alerting.LoadCertificateExpiryWarning())
+}
@@ -0,0 +1,314 @@
+package test_anomaly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/anomaly"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/captcha"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/eventbus"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/geoip"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+)
+
+// fakeLocator returns the locations of a fixed table of IP addresses.
+type fakeLocator map[string]geoip.Location
+
+func (l fakeLocator) Lookup(ip string) geoip.Location {
+	return l[ip]
+}
+
+// fakeVerifier accepts the token "solved" only.
+type fakeVerifier struct{}
+
+func (fakeVerifier) Verify(ctx context.Context, token string, remoteIP string) (bool, error) {
+	return token == "solved", nil
+}
+
+// newDetector creates an enabled detector applying the given actions from the second failed login.
+func newDetector(actions ...anomaly.Action) *anomaly.Detector {
+	return anomaly.NewDetector(anomaly.Config{
+		Enabled:                 true,
+		Window:                  time.Minute,
+		FailedLoginThreshold:    2,
+		FailedLoginASNThreshold: 3,
+		BlockDuration:           time.Minute,
+		TarpitDuration:          time.Minute,
+		TarpitDelay:             50 * time.Millisecond,
+		CaptchaDuration:         time.Minute,
+		Actions:                 actions,
+	}, clock.New())
+}
+
+// newAuthHandler creates an auth handler with the given detector, locating every client in the AS64500 network.
+func newAuthHandler(t *testing.T, detector *anomaly.Detector) (*handler.AuthHandler, *mocks.MockAuthService) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockAuthService(ctrl)
+
+	h := handler.NewAuthHandler(s)
+	h.Detector = detector
+	h.Captcha = fakeVerifier{}
+	h.Locator = fakeLocator{
+		"203.0.113.1": {Country: "ID", ASN: "AS64500"},
+		"203.0.113.2": {Country: "ID", ASN: "AS64500"},
+		"203.0.113.3": {Country: "ID", ASN: "AS64500"},
+		"203.0.113.4": {Country: "ID", ASN: "AS64500"},
+	}
+
+	return h, s
+}
+
+// postLogin sends a login request of the username from the given IP address, with the CAPTCHA token if any.
+func postLogin(h *handler.AuthHandler, ip string, username string, captchaToken string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/auth/login", h.Login)
+
+	payload, _ := json.Marshal(entity.LoginRequest{Username: username, Password: "P@ssw0rd"})
+	req, _ := http.NewRequest("POST", "/auth/login", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if captchaToken != "" {
+		req.Header.Set(handler.CaptchaTokenHeader, captchaToken)
+	}
+	req.RemoteAddr = ip + ":40000"
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+// errorCode returns the code of the first error of the response.
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var body struct {
+		Error []map[string]string `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.NotEmpty(t, body.Error)
+
+	return body.Error[0]["code"]
+}
+
+// receive returns the next event of the subscription, failing the test if none is published.
+func receive(t *testing.T, sub *eventbus.Subscription) eventbus.Event {
+	t.Helper()
+
+	select {
+	case e := <-sub.Events():
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return eventbus.Event{}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("ANOMALY_ACTIONS", "log, Tarpit,captcha,unknown")
+	t.Setenv("ANOMALY_FAILED_LOGIN_ASN_THRESHOLD", "100")
+	t.Setenv("ANOMALY_TARPIT_DELAY_MS", "1500")
+
+	cfg := anomaly.LoadConfig()
+
+	assert.Equal(t, []anomaly.Action{anomaly.ActionLog, anomaly.ActionTarpit, anomaly.ActionCaptcha}, cfg.Actions)
+	assert.Equal(t, 100, cfg.FailedLoginASNThreshold)
+	assert.Equal(t, 1500*time.Millisecond, cfg.TarpitDelay)
+	assert.Equal(t, 15*time.Minute, cfg.TarpitDuration)
+	assert.Equal(t, 30*time.Minute, cfg.CaptchaDuration)
+}
+
+func TestDetector_FailedLoginsPerASN(t *testing.T) {
+	d := newDetector(anomaly.ActionTarpit)
+
+	// The failed logins of different IPs and accounts add up in their autonomous system
+	assert.Empty(t, d.Record(anomaly.Event{Type: anomaly.EventFailedLogin, IP: "203.0.113.1", Account: "alice", ASN: "AS64500"}))
+	assert.Empty(t, d.Record(anomaly.Event{Type: anomaly.EventFailedLogin, IP: "203.0.113.2", Account: "bob", ASN: "AS64500"}))
+	alerts := d.Record(anomaly.Event{Type: anomaly.EventFailedLogin, IP: "203.0.113.3", Account: "carol", ASN: "AS64500"})
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "failed_login_per_asn", alerts[0].Rule)
+	assert.Equal(t, "asn:AS64500", alerts[0].Subject)
+
+	// Every source of the autonomous system is tarpitted, the others are not
+	assert.Equal(t, anomaly.Verdict{Tarpit: 50 * time.Millisecond}, d.Check(anomaly.Source{IP: "203.0.113.9", Account: "dave", ASN: "AS64500"}))
+	assert.Equal(t, anomaly.Verdict{}, d.Check(anomaly.Source{IP: "198.51.100.1", Account: "dave", ASN: "AS64511"}))
+	assert.Equal(t, anomaly.Verdict{}, d.Check(anomaly.Source{IP: "203.0.113.9", Account: "dave"}))
+}
+
+func TestDetector_CheckAppliesTheActionsOfTheSubjects(t *testing.T) {
+	d := newDetector(anomaly.ActionBlock, anomaly.ActionCaptcha)
+
+	d.Record(anomaly.Event{Type: anomaly.EventFailedLogin, IP: "203.0.113.1"})
+	d.Record(anomaly.Event{Type: anomaly.EventFailedLogin, IP: "203.0.113.1"})
+
+	assert.Equal(t, anomaly.Verdict{Blocked: true, Captcha: true}, d.Check(anomaly.Source{IP: "203.0.113.1", Account: "alice"}))
+	assert.True(t, d.IsBlocked("203.0.113.1", ""))
+	assert.Equal(t, anomaly.Verdict{}, d.Check(anomaly.Source{IP: "203.0.113.2", Account: "alice"}))
+
+	// A disabled detector does not restrict any source
+	assert.Equal(t, anomaly.Verdict{}, anomaly.NewDetector(anomaly.Config{}, clock.New()).Check(anomaly.Source{IP: "203.0.113.1"}))
+}
+
+func TestDetector_EmitsTheSecurityEvents(t *testing.T) {
+	bus := eventbus.NewBus(clock.New())
+	sub := bus.Subscribe([]string{eventbus.TopicSecurityEvent}, 4)
+	d := newDetector(anomaly.ActionLog)
+	d.SetEventPublisher(bus)
+
+	d.Record(anomaly.Event{Type: anomaly.EventFailedLogin, IP: "203.0.113.1", Account: "alice", Country: "ID", ASN: "AS64500"})
+
+	event, ok := receive(t, sub).Data.(anomaly.SecurityEvent)
+	require.True(t, ok)
+	assert.Equal(t, anomaly.SecurityEventLoginFailed, event.Type)
+	assert.Equal(t, "203.0.113.1", event.IP)
+	assert.Equal(t, "alice", event.Account)
+	assert.Equal(t, "AS64500", event.ASN)
+	assert.False(t, event.Timestamp.IsZero())
+
+	// The successful logins are not security events
+	d.Record(anomaly.Event{Type: anomaly.EventLoginSuccess, IP: "203.0.113.1", Account: "alice"})
+	select {
+	case e := <-sub.Events():
+		t.Fatalf("unexpected event %v", e)
+	default:
+	}
+}
+
+func TestLogin_ChallengesTheSuspiciousSourcesWithACaptcha(t *testing.T) {
+	bus := eventbus.NewBus(clock.New())
+	sub := bus.Subscribe([]string{eventbus.TopicSecurityEvent}, 16)
+	detector := newDetector(anomaly.ActionCaptcha)
+	detector.SetEventPublisher(bus)
+	h, s := newAuthHandler(t, detector)
+
+	s.EXPECT().Login(gomock.Any(), gomock.Any()).Return(entity.LoginResponse{}, gorm.ErrRecordNotFound).Times(2)
+	assert.Equal(t, http.StatusUnauthorized, postLogin(h, "203.0.113.1", "alice", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, postLogin(h, "203.0.113.1", "alice", "").Code)
+	for range 2 {
+		assert.Equal(t, anomaly.SecurityEventLoginFailed, receive(t, sub).Data.(anomaly.SecurityEvent).Type)
+	}
+
+	// The source must solve a CAPTCHA, the credentials are not checked until then
+	w := postLogin(h, "203.0.113.1", "alice", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, handler.CaptchaRequiredCode, errorCode(t, w))
+	assert.Equal(t, anomaly.SecurityEventCaptchaMissing, receive(t, sub).Data.(anomaly.SecurityEvent).Type)
+
+	w = postLogin(h, "203.0.113.1", "alice", "guessed")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, handler.CaptchaInvalidCode, errorCode(t, w))
+	assert.Equal(t, anomaly.SecurityEventCaptchaFailed, receive(t, sub).Data.(anomaly.SecurityEvent).Type)
+
+	// The account is challenged from any source
+	w = postLogin(h, "203.0.113.2", "Alice", "")
+	assert.Equal(t, handler.CaptchaRequiredCode, errorCode(t, w))
+	receive(t, sub)
+
+	s.EXPECT().Login(gomock.Any(), gomock.Any()).Return(entity.LoginResponse{AccessToken: "access-token"}, nil)
+	assert.Equal(t, http.StatusOK, postLogin(h, "203.0.113.1", "alice", "solved").Code)
+	assert.Equal(t, anomaly.SecurityEventCaptchaPassed, receive(t, sub).Data.(anomaly.SecurityEvent).Type)
+}
+
+func TestLogin_StepsUpTheTargetedAccounts(t *testing.T) {
+	h, s := newAuthHandler(t, anomaly.NewDetector(anomaly.Config{
+		Enabled:                 true,
+		Window:                  time.Minute,
+		FailedLoginThreshold:    2,
+		FailedLoginASNThreshold: 10,
+		StepUpDuration:          time.Minute,
+		Actions:                 []anomaly.Action{anomaly.ActionStepUp},
+	}, clock.New()))
+	stepUp := func(want bool) gomock.Matcher {
+		return gomock.Cond(func(loginReq entity.LoginRequest) bool { return loginReq.StepUp == want })
+	}
+
+	s.EXPECT().Login(gomock.Any(), stepUp(false)).Return(entity.LoginResponse{}, gorm.ErrRecordNotFound).Times(2)
+	assert.Equal(t, http.StatusUnauthorized, postLogin(h, "203.0.113.1", "alice", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, postLogin(h, "203.0.113.2", "alice", "").Code)
+
+	// The valid password of a user without two-factor authentication is not enough, from any source
+	s.EXPECT().Login(gomock.Any(), stepUp(true)).Return(entity.LoginResponse{}, service.ErrStepUpRequired)
+	w := postLogin(h, "203.0.113.3", "Alice", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, handler.StepUpRequiredCode, errorCode(t, w))
+
+	// A user with two-factor authentication gets the MFA challenge
+	s.EXPECT().Login(gomock.Any(), stepUp(true)).Return(entity.LoginResponse{MFARequired: true, MFAToken: "mfa-token"}, nil)
+	assert.Equal(t, http.StatusOK, postLogin(h, "203.0.113.3", "alice", "").Code)
+
+	// An invalid CAPTCHA is rejected, a solved one completes the step-up
+	w = postLogin(h, "203.0.113.3", "alice", "guessed")
+	assert.Equal(t, handler.CaptchaInvalidCode, errorCode(t, w))
+
+	s.EXPECT().Login(gomock.Any(), stepUp(false)).Return(entity.LoginResponse{AccessToken: "access-token"}, nil)
+	assert.Equal(t, http.StatusOK, postLogin(h, "203.0.113.3", "alice", "solved").Code)
+
+	// The other accounts are not stepped up
+	s.EXPECT().Login(gomock.Any(), stepUp(false)).Return(entity.LoginResponse{AccessToken: "access-token"}, nil)
+	assert.Equal(t, http.StatusOK, postLogin(h, "203.0.113.3", "bob", "").Code)
+}
+
+func TestLogin_TarpitsTheSuspiciousNetworks(t *testing.T) {
+	h, s := newAuthHandler(t, newDetector(anomaly.ActionTarpit))
+
+	s.EXPECT().Login(gomock.Any(), gomock.Any()).Return(entity.LoginResponse{}, gorm.ErrRecordNotFound).Times(3)
+	for i, ip := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"} {
+		assert.Equal(t, http.StatusUnauthorized, postLogin(h, ip, []string{"alice", "bob", "carol"}[i], "").Code)
+	}
+
+	// The logins of the autonomous system are delayed, then checked
+	s.EXPECT().Login(gomock.Any(), gomock.Any()).Return(entity.LoginResponse{AccessToken: "access-token"}, nil)
+	start := time.Now()
+	assert.Equal(t, http.StatusOK, postLogin(h, "203.0.113.4", "dave", "").Code)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestSiteVerifier(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "site-secret", r.PostForm.Get("secret"))
+		assert.Equal(t, "203.0.113.1", r.PostForm.Get("remoteip"))
+
+		switch r.PostForm.Get("response") {
+		case "solved":
+			w.Write([]byte(`{"success": true}`))
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+		}
+	}))
+	defer srv.Close()
+
+	v := captcha.NewVerifier(captcha.Config{VerifyURL: srv.URL, Secret: "site-secret"})
+
+	ok, err := v.Verify(context.Background(), "solved", "203.0.113.1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = v.Verify(context.Background(), "guessed", "203.0.113.1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = v.Verify(context.Background(), "broken", "203.0.113.1")
+	assert.Error(t, err)
+
+	// Without a provider, the tokens cannot be verified
+	_, err = captcha.NewVerifier(captcha.Config{}).Verify(context.Background(), "solved", "203.0.113.1")
+	assert.ErrorIs(t, err, captcha.ErrNotConfigured)
+}
@@ -0,0 +1,165 @@
+package test_anomaly
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/anomaly"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+)
+
+var start = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newClockedDetector creates an enabled detector applying the given actions from the third failed login of a window
+// of ten minutes, with a fake clock.
+func newClockedDetector(maxTrackedKeys int, actions ...anomaly.Action) (*anomaly.Detector, *clock.FakeClock) {
+	clk := clock.NewFakeClock(start)
+	return anomaly.NewDetector(anomaly.Config{
+		Enabled:              true,
+		Window:               10 * time.Minute,
+		FailedLoginThreshold: 3,
+		TokenErrorThreshold:  2,
+		BlockDuration:        15 * time.Minute,
+		StepUpDuration:       30 * time.Minute,
+		MaxTrackedKeys:       maxTrackedKeys,
+		Actions:              actions,
+	}, clk), clk
+}
+
+// failedLogin records a failed login of the account from the IP at the current time of the clock.
+func failedLogin(d *anomaly.Detector, ip string, account string) []anomaly.Alert {
+	return d.Record(anomaly.Event{Type: anomaly.EventFailedLogin, IP: ip, Account: account})
+}
+
+// rules returns the rules of the alerts.
+func rules(alerts []anomaly.Alert) []string {
+	var names []string
+	for _, a := range alerts {
+		names = append(names, a.Rule)
+	}
+	return names
+}
+
+func TestDetector_Threshold(t *testing.T) {
+	d, _ := newClockedDetector(0, anomaly.ActionLog)
+
+	// The rules are triggered from the threshold on, per IP and per account
+	assert.Empty(t, failedLogin(d, "203.0.113.1", "alice"))
+	assert.Empty(t, failedLogin(d, "203.0.113.1", "Alice"))
+	alerts := failedLogin(d, "203.0.113.1", "ALICE")
+	assert.Equal(t, []string{"failed_login_per_ip", "failed_login_per_account"}, rules(alerts))
+	assert.Equal(t, 3, alerts[0].Count)
+	assert.Equal(t, "account:alice", alerts[1].Subject)
+	assert.Equal(t, start, alerts[0].Timestamp)
+	assert.Len(t, failedLogin(d, "203.0.113.1", "alice"), 2)
+
+	// Another IP guessing another account is counted apart
+	assert.Empty(t, failedLogin(d, "203.0.113.2", "bob"))
+
+	// The token errors have their own threshold
+	assert.Empty(t, d.Record(anomaly.Event{Type: anomaly.EventTokenError, IP: "203.0.113.3"}))
+	assert.Equal(t, []string{"token_error_per_ip"}, rules(d.Record(anomaly.Event{Type: anomaly.EventTokenError, IP: "203.0.113.3"})))
+
+	// A successful login resets the counter of the account, not the one of the IP
+	d.Reset("alice")
+	assert.Equal(t, []string{"failed_login_per_ip"}, rules(failedLogin(d, "203.0.113.1", "alice")))
+}
+
+func TestDetector_SlidingWindow(t *testing.T) {
+	d, clk := newClockedDetector(0, anomaly.ActionBlock)
+
+	failedLogin(d, "203.0.113.1", "")
+	clk.Advance(6 * time.Minute)
+	failedLogin(d, "203.0.113.1", "")
+
+	// The first failure leaves the window before the third one
+	clk.Advance(5 * time.Minute)
+	assert.Empty(t, failedLogin(d, "203.0.113.1", ""))
+	assert.False(t, d.IsBlocked("203.0.113.1", ""))
+
+	// The three failures of the last ten minutes trigger the rule
+	clk.Advance(time.Minute)
+	assert.NotEmpty(t, failedLogin(d, "203.0.113.1", ""))
+	assert.True(t, d.IsBlocked("203.0.113.1", ""))
+
+	// The block lasts its duration on the clock of the detector
+	clk.Advance(15*time.Minute - time.Second)
+	assert.True(t, d.IsBlocked("203.0.113.1", ""))
+	clk.Advance(time.Second)
+	assert.False(t, d.IsBlocked("203.0.113.1", ""))
+}
+
+func TestDetector_StepUp(t *testing.T) {
+	d, clk := newClockedDetector(0, anomaly.ActionStepUp)
+
+	for range 3 {
+		failedLogin(d, "203.0.113.1", "Alice")
+	}
+
+	// The step-up applies to the account, from any source, and not to the others
+	assert.True(t, d.RequiresStepUp("alice"))
+	assert.True(t, d.RequiresStepUp("ALICE"))
+	assert.False(t, d.RequiresStepUp("bob"))
+	assert.False(t, d.RequiresStepUp(""))
+	assert.False(t, d.IsBlocked("203.0.113.1", "alice"))
+
+	// The failures of an IP alone require no step-up, having no account
+	for range 3 {
+		failedLogin(d, "203.0.113.2", "")
+	}
+	assert.False(t, d.RequiresStepUp("bob"))
+
+	clk.Advance(30*time.Minute - time.Second)
+	assert.True(t, d.RequiresStepUp("alice"))
+	clk.Advance(time.Second)
+	assert.False(t, d.RequiresStepUp("alice"))
+}
+
+func TestDetector_SweepsTheQuietSources(t *testing.T) {
+	d, clk := newClockedDetector(0, anomaly.ActionLog)
+
+	for i := range 50 {
+		failedLogin(d, fmt.Sprintf("198.51.100.%d", i), fmt.Sprintf("user%d", i))
+	}
+	assert.Equal(t, 100, d.Tracked())
+
+	// Once the window elapsed, the next failure sweeps the counters of the sources gone quiet
+	clk.Advance(10*time.Minute + time.Second)
+	failedLogin(d, "203.0.113.1", "")
+	assert.Equal(t, 1, d.Tracked())
+
+	// A reset account is not tracked anymore
+	failedLogin(d, "203.0.113.1", "alice")
+	d.Reset("alice")
+	assert.Equal(t, 1, d.Tracked())
+}
+
+func TestDetector_CapsTheTrackedSources(t *testing.T) {
+	d, clk := newClockedDetector(20, anomaly.ActionBlock)
+
+	// A credential stuffing run with a new IP and username at every attempt
+	for i := range 1000 {
+		clk.Advance(time.Millisecond)
+		failedLogin(d, fmt.Sprintf("198.51.%d.%d", i/256, i%256), fmt.Sprintf("user%d", i))
+		require.LessOrEqual(t, d.Tracked(), 20)
+	}
+
+	// The least recently seen sources are dropped first, an IP failing again is still detected
+	failedLogin(d, "203.0.113.1", "")
+	failedLogin(d, "203.0.113.1", "")
+	assert.False(t, d.IsBlocked("203.0.113.1", ""))
+	assert.NotEmpty(t, failedLogin(d, "203.0.113.1", ""))
+	assert.True(t, d.IsBlocked("203.0.113.1", ""))
+}
+
+func TestDetector_Disabled(t *testing.T) {
+	d := anomaly.NewDetector(anomaly.Config{FailedLoginThreshold: 1, Actions: []anomaly.Action{anomaly.ActionStepUp}}, clock.New())
+
+	assert.Empty(t, failedLogin(d, "203.0.113.1", "alice"))
+	assert.False(t, d.RequiresStepUp("alice"))
+	assert.Zero(t, d.Tracked())
+}
@@ -0,0 +1,181 @@
+package test_api_key
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/go-playground/validator.v9"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newApiKeyService creates an API key service backed by a seeded in-memory store, with a service account
+// having the ROLE_USER role, and returns it with the store, the ID of the service account, and the clock of the service.
+func newApiKeyService(t *testing.T) (service.ApiKeyService, *repository.MemoryStore, int64, *clock.FakeClock) {
+	store := testsupport.UseMemoryDatabase(t)
+	require.NoError(t, store.Seed())
+
+	account, err := store.AddUser(entity.User{Username: "exporter", Email: "exporter@example.com", Firstname: "Exporter",
+		UserType: entity.UserTypeServiceAccount, State: entity.UserStateActive, Roles: []entity.Role{{ID: 1}}})
+	require.NoError(t, err)
+
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	roles := service.NewRoleService(database.DefaultStore(), repository.NewMemoryRoleRepository(store), repository.NewMemoryPermissionRepository(store))
+	s := service.NewApiKeyService(database.DefaultStore(), repository.NewMemoryApiKeyRepository(store), repository.NewMemoryUserRepository(store), roles, clk)
+
+	return s, store, account.ID, clk
+}
+
+// doWithApiKey performs a GET request against the router with the given API key in the X-API-Key header.
+func doWithApiKey(router http.Handler, path string, key string) int {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set(authorization.ApiKeyHeader, key)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w.Code
+}
+
+func TestApiKeyService_CreateApiKey(t *testing.T) {
+	s, _, accountID, clk := newApiKeyService(t)
+
+	issued, err := s.CreateApiKey(context.Background(), accountID, entity.ApiKeyRequest{Name: "Nightly export", ExpiresInDays: 30}, 1)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(issued.Key, service.ApiKeyPrefix))
+	assert.True(t, strings.HasPrefix(issued.Key, issued.Prefix))
+	assert.Equal(t, service.HashApiKey(issued.Key), issued.KeyHash)
+	assert.Equal(t, clk.Now().AddDate(0, 0, 30), *issued.ExpiresAt)
+	assert.Equal(t, int64(1), issued.CreatedBy)
+
+	// The key itself is never returned afterward
+	keys, err := s.GetApiKeys(context.Background(), accountID)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, issued.ID, keys[0].ID)
+	assert.NotContains(t, keys[0].KeyHash, issued.Key)
+
+	meta, err := s.Authenticate(context.Background(), issued.Key)
+	require.NoError(t, err)
+	assert.Equal(t, metacontext.UserInformationMeta{UserID: accountID, Username: "exporter", Email: "exporter@example.com",
+		Roles: []string{entity.RoleUser}, Scopes: []string{entity.PermissionConsumersRead}}, meta)
+}
+
+func TestApiKeyService_CreateApiKey_Invalid(t *testing.T) {
+	s, _, _, _ := newApiKeyService(t)
+
+	// The keys are only issued to the service accounts
+	_, err := s.CreateApiKey(context.Background(), 1, entity.ApiKeyRequest{Name: "Admin key"}, 1)
+	assert.ErrorIs(t, err, service.ErrNotServiceAccount)
+
+	_, err = s.CreateApiKey(context.Background(), 99, entity.ApiKeyRequest{Name: "Unknown"}, 1)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	_, err = s.CreateApiKey(context.Background(), 1, entity.ApiKeyRequest{}, 1)
+	var ve validator.ValidationErrors
+	assert.True(t, errors.As(err, &ve))
+}
+
+func TestApiKeyService_Authenticate_Rejected(t *testing.T) {
+	s, store, accountID, clk := newApiKeyService(t)
+	ctx := context.Background()
+
+	issued, err := s.CreateApiKey(context.Background(), accountID, entity.ApiKeyRequest{Name: "Nightly export", ExpiresInDays: 1}, 1)
+	require.NoError(t, err)
+
+	for _, key := range []string{"", "unknown", service.ApiKeyPrefix + "unknown"} {
+		_, err = s.Authenticate(ctx, key)
+		assert.ErrorIs(t, err, authorization.ErrInvalidApiKey, key)
+	}
+
+	// The keys of a disabled account are rejected until it is activated again
+	users := repository.NewMemoryUserRepository(store)
+	require.NoError(t, users.UpdateUserState(context.Background(), nil, accountID, entity.UserStateDisabled, nil, clk.Now()))
+	_, err = s.Authenticate(ctx, issued.Key)
+	assert.ErrorIs(t, err, authorization.ErrInvalidApiKey)
+	require.NoError(t, users.UpdateUserState(context.Background(), nil, accountID, entity.UserStateActive, nil, clk.Now()))
+	_, err = s.Authenticate(ctx, issued.Key)
+	require.NoError(t, err)
+
+	// The expired keys are rejected
+	clk.Advance(24 * time.Hour)
+	_, err = s.Authenticate(ctx, issued.Key)
+	assert.ErrorIs(t, err, authorization.ErrInvalidApiKey)
+}
+
+func TestApiKeyService_RotateAndRevoke(t *testing.T) {
+	s, _, accountID, clk := newApiKeyService(t)
+	ctx := context.Background()
+
+	issued, err := s.CreateApiKey(context.Background(), accountID, entity.ApiKeyRequest{Name: "Nightly export", ExpiresInDays: 30}, 1)
+	require.NoError(t, err)
+
+	// The new key has the same name and lifetime, the previous one is rejected at once
+	clk.Advance(time.Hour)
+	rotated, err := s.RotateApiKey(context.Background(), issued.ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "Nightly export", rotated.Name)
+	assert.Equal(t, clk.Now().AddDate(0, 0, 30), *rotated.ExpiresAt)
+	assert.Equal(t, int64(2), rotated.CreatedBy)
+	assert.NotEqual(t, issued.Key, rotated.Key)
+
+	_, err = s.Authenticate(ctx, issued.Key)
+	assert.ErrorIs(t, err, authorization.ErrInvalidApiKey)
+	_, err = s.Authenticate(ctx, rotated.Key)
+	require.NoError(t, err)
+
+	_, err = s.RotateApiKey(context.Background(), issued.ID, 2)
+	assert.ErrorIs(t, err, service.ErrApiKeyRevoked)
+
+	require.NoError(t, s.RevokeApiKey(context.Background(), rotated.ID))
+	_, err = s.Authenticate(ctx, rotated.Key)
+	assert.ErrorIs(t, err, authorization.ErrInvalidApiKey)
+	assert.ErrorIs(t, s.RevokeApiKey(context.Background(), rotated.ID), service.ErrApiKeyRevoked)
+	assert.ErrorIs(t, s.RevokeApiKey(context.Background(), 99), gorm.ErrRecordNotFound)
+
+	keys, err := s.GetApiKeys(context.Background(), accountID)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.NotNil(t, keys[0].RevokedAt)
+	assert.NotNil(t, keys[1].RevokedAt)
+}
+
+func TestApiKeyOrJwtValidation(t *testing.T) {
+	s, _, accountID, _ := newApiKeyService(t)
+	issued, err := s.CreateApiKey(context.Background(), accountID, entity.ApiKeyRequest{Name: "Nightly export"}, 1)
+	require.NoError(t, err)
+
+	testsupport.SetupJWTEnv(t)
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(authorization.ApiKeyOrJwtValidation(s, authorization.JwtValidationWithConfig(testsupport.NewJWTConfig(), clock.New())))
+	router.GET("/consumers", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), authorization.RequireScope(entity.PermissionConsumersRead),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/users", authorization.RoleBasedAccessControl("ROLE_ADMIN"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// The requests with an API key carry the roles and the scopes of the service account
+	assert.Equal(t, http.StatusOK, doWithApiKey(router, "/consumers", issued.Key))
+	assert.Equal(t, http.StatusForbidden, doWithApiKey(router, "/users", issued.Key))
+	assert.Equal(t, http.StatusUnauthorized, doWithApiKey(router, "/consumers", service.ApiKeyPrefix+"unknown"))
+
+	// The requests without an API key are authenticated with their access token
+	assert.Equal(t, http.StatusOK, testsupport.Do(router, "GET", "/users", testsupport.NewTokenBuilder().Build(t)).Code)
+	assert.Equal(t, http.StatusUnauthorized, testsupport.Do(router, "GET", "/users", "").Code)
+}
@@ -0,0 +1,168 @@
+package test_audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// fakeRecorder is an AuditRecorder keeping the recorded events in memory.
+type fakeRecorder struct {
+	mu     sync.Mutex
+	events []entity.AuditEvent
+}
+
+func (r *fakeRecorder) Record(ctx context.Context, event entity.AuditEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *fakeRecorder) Close() {}
+
+// newAuditService creates the audit service backed by an in-memory store.
+func newAuditService(t *testing.T) service.AuditService {
+	store := testsupport.UseMemoryDatabase(t)
+	return service.NewAuditService(database.DefaultStore(), repository.NewMemoryAuditEventRepository(store))
+}
+
+func TestAuditRecorder_FillsTheEventFromTheRequest(t *testing.T) {
+	s := newAuditService(t)
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	r := service.NewAsyncAuditRecorder(s, nil, 0, clk)
+
+	ctx := metacontext.InjectRequestID(context.Background(), "req-1")
+	ctx = metacontext.InjectUserInformationMeta(ctx, metacontext.UserInformationMeta{UserID: 1, Username: "admin"})
+	r.Record(ctx, entity.AuditEvent{Action: entity.AuditActionAdmin, TargetType: entity.AuditTargetRoute, TargetID: "PUT /api/v1/admin/security-settings"})
+	r.Close()
+	r.Close()
+
+	events, err := s.GetAuditEvents(context.Background(), entity.AuditEventFilter{}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.NotEmpty(t, events[0].EventID)
+	assert.Equal(t, "req-1", events[0].RequestID)
+	assert.Equal(t, int64(1), *events[0].ActorID)
+	assert.Equal(t, "admin", events[0].ActorUsername)
+	assert.True(t, clk.Now().Equal(events[0].CreatedAt))
+
+	// An event recorded after Close is dropped
+	r.Record(ctx, entity.AuditEvent{Action: entity.AuditActionAdmin})
+	events, err = s.GetAuditEvents(context.Background(), entity.AuditEventFilter{}, 1, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestAuditService_FiltersAndPaginates(t *testing.T) {
+	s := newAuditService(t)
+	at := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	alice, bob := int64(1), int64(2)
+
+	for i, e := range []entity.AuditEvent{
+		{Action: entity.AuditActionLogin, ActorID: &alice, TargetType: entity.AuditTargetUser, TargetID: "1"},
+		{Action: entity.AuditActionLogin, ActorID: &bob, TargetType: entity.AuditTargetUser, TargetID: "2"},
+		{Action: entity.AuditActionConsumerStatusChange, ActorID: &alice, TargetType: entity.AuditTargetConsumer, TargetID: "c-1"},
+		{Action: entity.AuditActionLogout, ActorID: &alice, TargetType: entity.AuditTargetUser, TargetID: "1"},
+	} {
+		e.EventID = string(rune('a' + i))
+		e.CreatedAt = at.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, s.RecordAuditEvent(e))
+	}
+
+	// Writing an event again does not duplicate it
+	require.NoError(t, s.RecordAuditEvent(entity.AuditEvent{EventID: "a", Action: entity.AuditActionLogin}))
+
+	ids := func(filter entity.AuditEventFilter, page int, limit int) []int64 {
+		events, err := s.GetAuditEvents(context.Background(), filter, page, limit)
+		require.NoError(t, err)
+
+		var ids []int64
+		for _, e := range events {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	from, to := at.Add(time.Hour), at.Add(3*time.Hour)
+	assert.Equal(t, []int64{4, 3, 2, 1}, ids(entity.AuditEventFilter{}, 1, 10))
+	assert.Equal(t, []int64{2, 1}, ids(entity.AuditEventFilter{Action: entity.AuditActionLogin}, 1, 10))
+	assert.Equal(t, []int64{4, 3, 1}, ids(entity.AuditEventFilter{ActorID: &alice}, 1, 10))
+	assert.Equal(t, []int64{3}, ids(entity.AuditEventFilter{TargetType: entity.AuditTargetConsumer, TargetID: "c-1"}, 1, 10))
+	assert.Equal(t, []int64{3, 2}, ids(entity.AuditEventFilter{From: &from, To: &to}, 1, 10))
+	assert.Equal(t, []int64{2, 1}, ids(entity.AuditEventFilter{}, 2, 2))
+
+	_, err := s.GetAuditEvents(context.Background(), entity.AuditEventFilter{Action: "unknown"}, 1, 10)
+	assert.ErrorIs(t, err, service.ErrInvalidAuditAction)
+}
+
+func TestAudit_RecordsTheSuccessfulChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := &fakeRecorder{}
+	audits := handler.NewAuditHandler(nil, recorder, pagination.LoadConfig())
+	status := func(code int) gin.HandlerFunc {
+		return func(c *gin.Context) { c.Status(code) }
+	}
+
+	r := gin.New()
+	admin := r.Group("/api/v1/admin", audits.Audit(entity.AuditActionAdmin, entity.AuditTargetRoute))
+	admin.GET("/feature-flags", status(http.StatusOK))
+	admin.PUT("/feature-flags/:name", status(http.StatusOK))
+	admin.DELETE("/rate-limit-overrides/:client", status(http.StatusNotFound))
+	admin.POST("/users/:id/roles", audits.Audit(entity.AuditActionRoleChange, entity.AuditTargetUser), status(http.StatusOK))
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/admin/feature-flags"},
+		{http.MethodPut, "/api/v1/admin/feature-flags/registration"},
+		{http.MethodDelete, "/api/v1/admin/rate-limit-overrides/acme"},
+		{http.MethodPost, "/api/v1/admin/users/3/roles"},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	// The reads and the failed requests are not recorded, and a request is recorded once
+	require.Len(t, recorder.events, 2)
+	assert.Equal(t, entity.AuditEvent{
+		Action:     entity.AuditActionAdmin,
+		TargetType: entity.AuditTargetRoute,
+		TargetID:   "PUT /api/v1/admin/feature-flags/:name",
+		Details:    "PUT /api/v1/admin/feature-flags/registration",
+		IPAddress:  "192.0.2.1",
+	}, recorder.events[0])
+	assert.Equal(t, entity.AuditActionRoleChange, recorder.events[1].Action)
+	assert.Equal(t, entity.AuditTargetUser, recorder.events[1].TargetType)
+	assert.Equal(t, "3", recorder.events[1].TargetID)
+}
+
+func TestGetAuditEvents_RejectsAnInvalidFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/v1/audit-events", handler.NewAuditHandler(newAuditService(t), nil, pagination.LoadConfig()).GetAuditEvents)
+
+	for _, query := range []string{"?actorId=abc", "?from=2025-01-15", "?to=tomorrow", "?action=unknown"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/audit-events"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/audit-events?actorId=1&from=2025-01-15T00:00:00Z", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,63 @@
+package test_auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+)
+
+// BenchmarkAuthService_Login measures the login hot path: bcrypt verification with the default cost
+// used by the seed data, plus minting and parsing the access token.
+// The user and refresh token services are mocked so the database is not part of the measurement.
+func BenchmarkAuthService_Login(b *testing.B) {
+	user := newActiveUser(b)
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.DefaultCost)
+	if err != nil {
+		b.Fatal(err)
+	}
+	user.Password = string(hash)
+
+	ctrl := gomock.NewController(b)
+	users := mocks.NewMockUserService(ctrl)
+	refresh := mocks.NewMockRefreshTokenService(ctrl)
+	users.EXPECT().GetUserByUsername(gomock.Any(), user.Username).Return(user, nil).AnyTimes()
+	lastLogin := mocks.NewMockLastLoginRecorder(ctrl)
+	lastLogin.EXPECT().Record(user.ID, gomock.Any()).AnyTimes()
+	tokenUsage := mocks.NewMockTokenUsageRecorder(ctrl)
+	tokenUsage.EXPECT().Record(service.TokenEventIssued, user.ID, gomock.Any(), gomock.Any()).AnyTimes()
+	notifier := mocks.NewMockSecurityNotifier(ctrl)
+	notifier.EXPECT().Notify(gomock.Any()).AnyTimes()
+	refresh.EXPECT().CreateRefreshToken(gomock.Any(), user.ID, gomock.Any()).Return(entity.RefreshToken{Token: "refresh-token", UserID: user.ID}, nil).AnyTimes()
+
+	s := service.NewAuthService(users, refresh, newJWTTokenIssuer(), lastLogin, tokenUsage, notifier, mocks.NewMockTokenRevocationService(ctrl), clock.New())
+	loginReq := entity.LoginRequest{Username: user.Username, Password: testPassword}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Login(context.Background(), loginReq); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkJWTTokenIssuer_IssueToken measures minting and parsing an access token without bcrypt.
+func BenchmarkJWTTokenIssuer_IssueToken(b *testing.B) {
+	issuer := newJWTTokenIssuer()
+	user := newActiveUser(b)
+	now := time.Now()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := issuer.IssueToken(user, "", now); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
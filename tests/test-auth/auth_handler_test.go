@@ -0,0 +1,237 @@
+package test_auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+)
+
+// setupAuthRouter sets up the Gin router with the auth routes backed by the given mocked service.
+func setupAuthRouter(s *mocks.MockAuthService) *gin.Engine {
+	h := handler.NewAuthHandler(s)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/auth/login", h.Login)
+	router.POST("/auth/refresh-token", h.RefreshToken)
+	router.POST("/auth/logout", injectUser(logoutUser), h.Logout)
+
+	return router
+}
+
+// logoutUser is the user of the access token of the logout requests.
+var logoutUser = metacontext.UserInformationMeta{UserID: 1, Username: "admin", TokenID: "token-id"}
+
+// injectUser injects the given user information into the request context, as the JWT validation middleware does.
+func injectUser(meta metacontext.UserInformationMeta) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(metacontext.InjectUserInformationMeta(c.Request.Context(), meta))
+		c.Next()
+	}
+}
+
+// postJSON sends a POST request with the given body to the router.
+func postJSON(router *gin.Engine, path string, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestLogin_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockAuthService(ctrl)
+	router := setupAuthRouter(s)
+
+	loginReq := entity.LoginRequest{Username: "admin", Password: "P@ssw0rd"}
+	s.EXPECT().Login(gomock.Any(), loginReq).Return(entity.LoginResponse{
+		AccessToken:  "access-token",
+		RefreshToken: "refresh-token",
+		TokenType:    "Bearer",
+	}, nil)
+
+	w := postJSON(router, "/auth/login", loginReq)
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var httpResponse httputil.HttpResponse
+	err := json.Unmarshal(w.Body.Bytes(), &httpResponse)
+	assert.NoError(t, err)
+	assert.Nil(t, httpResponse.Error)
+	assert.Equal(t, "access-token", httpResponse.Data.(map[string]interface{})["accessToken"])
+}
+
+func TestLogin_UserNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockAuthService(ctrl)
+	router := setupAuthRouter(s)
+
+	s.EXPECT().Login(gomock.Any(), gomock.Any()).Return(entity.LoginResponse{}, gorm.ErrRecordNotFound)
+
+	w := postJSON(router, "/auth/login", entity.LoginRequest{Username: "unknown", Password: "P@ssw0rd"})
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var httpResponse httputil.HttpResponse
+	err := json.Unmarshal(w.Body.Bytes(), &httpResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, "Invalid credentials", httpResponse.Message)
+}
+
+func TestLogin_LockedOrUnexpectedErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantError  string
+	}{
+		{"wrong password", fmt.Errorf("%w for user admin", service.ErrInvalidCredentials), http.StatusUnauthorized, "Username or password is incorrect"},
+		{"locked account", fmt.Errorf("%w: user with username admin is disabled", service.ErrUserLocked), http.StatusForbidden, "The user account is locked"},
+		{"unverified account", service.ErrUserNotVerified, http.StatusForbidden, "The user account is pending verification"},
+		{"unexpected error", errors.New("pq: connection refused to 10.0.0.5:5432"), http.StatusInternalServerError, "An unexpected error occurred, please try again later"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			s := mocks.NewMockAuthService(ctrl)
+			router := setupAuthRouter(s)
+
+			s.EXPECT().Login(gomock.Any(), gomock.Any()).Return(entity.LoginResponse{}, tt.err)
+
+			w := postJSON(router, "/auth/login", entity.LoginRequest{Username: "admin", Password: "P@ssw0rd"})
+
+			// The response tells the kind of the error, never its internal text
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			var httpResponse httputil.HttpResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &httpResponse))
+			assert.Equal(t, tt.wantError, httpResponse.Error)
+			assert.NotContains(t, w.Body.String(), "admin is disabled")
+			assert.NotContains(t, w.Body.String(), "10.0.0.5")
+		})
+	}
+}
+
+func TestLogin_SessionLimitReached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockAuthService(ctrl)
+	router := setupAuthRouter(s)
+
+	s.EXPECT().Login(gomock.Any(), gomock.Any()).Return(entity.LoginResponse{}, fmt.Errorf("failed to create refresh token: %w", service.ErrSessionLimitReached))
+
+	w := postJSON(router, "/auth/login", entity.LoginRequest{Username: "admin", Password: "P@ssw0rd"})
+
+	// The clients can tell the session limit from invalid credentials by the status and the error code
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var httpResponse httputil.HttpResponse
+	err := json.Unmarshal(w.Body.Bytes(), &httpResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, "Session limit reached", httpResponse.Message)
+	assert.Equal(t, handler.SessionLimitReachedCode, httpResponse.Error.([]interface{})[0].(map[string]interface{})["code"])
+}
+
+func TestLogin_InvalidBody(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockAuthService(ctrl)
+	router := setupAuthRouter(s)
+
+	// The service must not be called when the body cannot be bound
+	req, _ := http.NewRequest("POST", "/auth/login", bytes.NewBufferString("{invalid"))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRefreshToken_Expired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockAuthService(ctrl)
+	router := setupAuthRouter(s)
+
+	s.EXPECT().RefreshToken(gomock.Any(), entity.RefreshTokenRequest{RefreshToken: "expired"}).
+		Return(entity.RefreshTokenResponse{}, service.ErrRefreshTokenExpired)
+
+	w := postJSON(router, "/auth/refresh-token", entity.RefreshTokenRequest{RefreshToken: "expired"})
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var httpResponse httputil.HttpResponse
+	err := json.Unmarshal(w.Body.Bytes(), &httpResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, "Refresh token is expired", httpResponse.Error)
+}
+
+func TestRefreshToken_AccessTokenOfAnotherUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockAuthService(ctrl)
+	router := setupAuthRouter(s)
+
+	s.EXPECT().RefreshToken(gomock.Any(), entity.RefreshTokenRequest{RefreshToken: "refresh-token", AccessToken: "access-token"}).
+		Return(entity.RefreshTokenResponse{}, service.ErrTokenSubjectMismatch)
+
+	w := postJSON(router, "/auth/refresh-token", entity.RefreshTokenRequest{RefreshToken: "refresh-token", AccessToken: "access-token"})
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var httpResponse httputil.HttpResponse
+	err := json.Unmarshal(w.Body.Bytes(), &httpResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, "Invalid refresh token", httpResponse.Message)
+}
+
+func TestLogout_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockAuthService(ctrl)
+	router := setupAuthRouter(s)
+
+	// The user and the access token come from the request context, not from the payload
+	s.EXPECT().Logout(gomock.Any(), entity.LogoutRequest{RefreshToken: "refresh-token", UserID: 1, TokenID: "token-id"}).Return(nil)
+
+	w := postJSON(router, "/auth/logout", map[string]interface{}{"refreshToken": "refresh-token", "UserID": 2})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestLogout_RefreshTokenOfAnotherUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockAuthService(ctrl)
+	router := setupAuthRouter(s)
+
+	s.EXPECT().Logout(gomock.Any(), gomock.Any()).Return(service.ErrTokenSubjectMismatch)
+
+	w := postJSON(router, "/auth/logout", entity.LogoutRequest{RefreshToken: "refresh-token"})
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var httpResponse httputil.HttpResponse
+	err := json.Unmarshal(w.Body.Bytes(), &httpResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, "Invalid refresh token", httpResponse.Message)
+}
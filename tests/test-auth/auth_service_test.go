@@ -0,0 +1,599 @@
+package test_auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/go-playground/validator.v9"
+	"gorm.io/gorm"
+
+	jwtconfig "github.com/yoanesber/go-jwt-auth-demo/config/jwt-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+const (
+	testPassword        = "P@ssw0rd"
+	testExpirationHours = 2
+)
+
+// authServiceDeps holds the mocked dependencies of the auth service under test.
+type authServiceDeps struct {
+	users      *mocks.MockUserService
+	refresh    *mocks.MockRefreshTokenService
+	issuer     *mocks.MockTokenIssuer
+	lastLogin  *mocks.MockLastLoginRecorder
+	tokenUsage *mocks.MockTokenUsageRecorder
+	notifier   *mocks.MockSecurityNotifier
+	revocation *mocks.MockTokenRevocationService
+	clock      *clock.FakeClock
+}
+
+// newAuthService creates the auth service under test backed by mocked dependencies and a fake clock.
+func newAuthService(t *testing.T) (service.AuthService, authServiceDeps) {
+	ctrl := gomock.NewController(t)
+	deps := authServiceDeps{
+		users:      mocks.NewMockUserService(ctrl),
+		refresh:    mocks.NewMockRefreshTokenService(ctrl),
+		issuer:     mocks.NewMockTokenIssuer(ctrl),
+		lastLogin:  mocks.NewMockLastLoginRecorder(ctrl),
+		tokenUsage: mocks.NewMockTokenUsageRecorder(ctrl),
+		notifier:   mocks.NewMockSecurityNotifier(ctrl),
+		revocation: mocks.NewMockTokenRevocationService(ctrl),
+		clock:      clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)),
+	}
+
+	return service.NewAuthService(deps.users, deps.refresh, deps.issuer, deps.lastLogin, deps.tokenUsage, deps.notifier, deps.revocation, deps.clock), deps
+}
+
+// newJWTTokenIssuer creates the real JWT token issuer configured with HS256.
+func newJWTTokenIssuer() service.TokenIssuer {
+	cfg := testsupport.NewJWTConfig()
+	cfg.TTL = jwtconfig.TTLPolicy{Default: testExpirationHours * time.Hour}
+
+	return service.NewJWTTokenIssuer(cfg)
+}
+
+// newActiveUser creates a user that is allowed to log in with testPassword.
+func newActiveUser(t testing.TB) entity.User {
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	deleted := false
+	return entity.User{
+		ID:        1,
+		Username:  "admin",
+		Password:  string(hash),
+		Email:     "admin@example.com",
+		Firstname: "Admin",
+		State:     entity.UserStateActive,
+		IsDeleted: &deleted,
+		UserType:  "USER_ACCOUNT",
+		Roles:     []entity.Role{{ID: 1, Name: "ROLE_ADMIN"}},
+	}
+}
+
+// boolPtr returns a pointer to the given bool value.
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestAuthService_Login_Success(t *testing.T) {
+	s, deps := newAuthService(t)
+	user := newActiveUser(t)
+	now := deps.clock.Now()
+	expiresAt := now.Add(testExpirationHours * time.Hour)
+
+	deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(user, nil)
+	deps.issuer.EXPECT().IssueToken(user, "web", now).Return(service.IssuedToken{AccessToken: "access-token", ExpiresAt: expiresAt}, nil)
+	deps.issuer.EXPECT().TokenType().Return("Bearer")
+	deps.refresh.EXPECT().CreateRefreshToken(gomock.Any(), user.ID, gomock.Any()).Return(entity.RefreshToken{Token: "refresh-token", UserID: user.ID}, nil)
+	deps.lastLogin.EXPECT().Record(user.ID, now)
+	deps.tokenUsage.EXPECT().Record(service.TokenEventIssued, user.ID, "web", now)
+	deps.notifier.EXPECT().Notify(entity.SecurityEvent{
+		Type:      entity.SecurityEventLogin,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		IPAddress: "192.0.2.1",
+		UserAgent: "curl/8.0",
+		ClientID:  "web",
+		At:        now,
+	})
+
+	resp, err := s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: testPassword, ClientID: "web", IPAddress: "192.0.2.1", UserAgent: "curl/8.0"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "access-token", resp.AccessToken)
+	assert.Equal(t, "refresh-token", resp.RefreshToken)
+	assert.Equal(t, "Bearer", resp.TokenType)
+	assert.Equal(t, expiresAt.Format(time.RFC3339), resp.ExpirationDate)
+}
+
+func TestAuthService_Login_RejectedAccounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(u *entity.User)
+		wantIs  error
+		wantErr string
+	}{
+		{"disabled", func(u *entity.User) { u.State = entity.UserStateDisabled }, service.ErrUserLocked, "user account is locked: user with username admin is disabled"},
+		{"suspended", func(u *entity.User) { u.State = entity.UserStateSuspended }, service.ErrUserLocked, "user account is locked: user account is suspended"},
+		{"pending verification", func(u *entity.User) { u.State = entity.UserStatePendingVerification }, service.ErrUserNotVerified, "user account is pending verification"},
+		{"unknown state", func(u *entity.User) { u.State = "LOCKED" }, service.ErrUserLocked, `user account is locked: user account is in an unknown state "LOCKED"`},
+		{"deleted", func(u *entity.User) { u.IsDeleted = boolPtr(true) }, service.ErrUserLocked, "user account is locked: user with username admin is deleted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, deps := newAuthService(t)
+			user := newActiveUser(t)
+			tt.mutate(&user)
+
+			// No token must be issued for a rejected account
+			deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(user, nil)
+
+			_, err := s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: testPassword})
+
+			assert.ErrorIs(t, err, tt.wantIs)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestAuthService_Login_WrongPassword(t *testing.T) {
+	s, deps := newAuthService(t)
+	user := newActiveUser(t)
+
+	deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(user, nil)
+
+	_, err := s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: "Wr0ngP@ss"})
+
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	assert.EqualError(t, err, "invalid credentials for user admin")
+}
+
+func TestAuthService_Login_UserNotFound(t *testing.T) {
+	s, deps := newAuthService(t)
+
+	deps.users.EXPECT().GetUserByUsername(gomock.Any(), "unknown").Return(entity.User{}, gorm.ErrRecordNotFound)
+
+	_, err := s.Login(context.Background(), entity.LoginRequest{Username: "unknown", Password: testPassword})
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestAuthService_Login_InvalidRequest(t *testing.T) {
+	s, _ := newAuthService(t)
+
+	_, err := s.Login(context.Background(), entity.LoginRequest{Username: "", Password: ""})
+
+	assert.Error(t, err)
+}
+
+func TestAuthService_Login_TokenIssuerError(t *testing.T) {
+	s, deps := newAuthService(t)
+	user := newActiveUser(t)
+
+	deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(user, nil)
+	deps.issuer.EXPECT().IssueToken(user, "", deps.clock.Now()).Return(service.IssuedToken{}, errors.New("signing failed"))
+
+	_, err := s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: testPassword})
+
+	assert.EqualError(t, err, "signing failed")
+}
+
+func TestAuthService_Login_DeduplicatesConcurrentIdenticalLogins(t *testing.T) {
+	s, deps := newAuthService(t)
+	user := newActiveUser(t)
+	now := deps.clock.Now()
+	release := make(chan struct{})
+
+	// The first login blocks on the user lookup until the duplicates have joined it
+	deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").DoAndReturn(func(context.Context, string) (entity.User, error) {
+		<-release
+		return user, nil
+	}).Times(1)
+	deps.issuer.EXPECT().IssueToken(user, "", now).Return(service.IssuedToken{AccessToken: "access-token", ExpiresAt: now.Add(time.Hour)}, nil).Times(1)
+	deps.issuer.EXPECT().TokenType().Return("Bearer").Times(1)
+	deps.refresh.EXPECT().CreateRefreshToken(gomock.Any(), user.ID, gomock.Any()).Return(entity.RefreshToken{Token: "refresh-token", UserID: user.ID}, nil).Times(1)
+	deps.lastLogin.EXPECT().Record(user.ID, now).Times(1)
+	deps.tokenUsage.EXPECT().Record(service.TokenEventIssued, user.ID, "", now).Times(1)
+	deps.notifier.EXPECT().Notify(gomock.Any()).Times(1)
+
+	const n = 5
+	var wg sync.WaitGroup
+	results := make([]entity.LoginResponse, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// The username is matched case-insensitively, like the user lookup
+			username := []string{"admin", "ADMIN"}[i%2]
+			results[i], errs[i] = s.Login(context.Background(), entity.LoginRequest{Username: username, Password: testPassword})
+		}(i)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, results[0], results[i])
+	}
+}
+
+func TestAuthService_Login_DoesNotShareResultAcrossPasswords(t *testing.T) {
+	s, deps := newAuthService(t)
+	user := newActiveUser(t)
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	gomock.InOrder(
+		deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").DoAndReturn(func(context.Context, string) (entity.User, error) {
+			close(started)
+			<-release
+			return entity.User{}, gorm.ErrRecordNotFound
+		}),
+		deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(user, nil),
+	)
+
+	done := make(chan error)
+	go func() {
+		_, err := s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: testPassword})
+		done <- err
+	}()
+	<-started
+
+	// A login with another password runs on its own while the first one is still in flight
+	_, err := s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: "Wr0ngP@ss"})
+	assert.EqualError(t, err, "invalid credentials for user admin")
+
+	close(release)
+	assert.ErrorIs(t, <-done, gorm.ErrRecordNotFound)
+}
+
+func TestAuthService_Login_DoesNotShareResultAcrossCallers(t *testing.T) {
+	callers := map[string]entity.LoginRequest{
+		"IP address": {Username: "admin", Password: testPassword, IPAddress: "198.51.100.7", UserAgent: "curl/8.5.0"},
+		"User-Agent": {Username: "admin", Password: testPassword, IPAddress: "203.0.113.10", UserAgent: "Mozilla/5.0"},
+	}
+
+	for name, other := range callers {
+		t.Run(name, func(t *testing.T) {
+			s, deps := newAuthService(t)
+			release := make(chan struct{})
+			started := make(chan struct{})
+
+			// Each login is recorded with the IP address and the User-Agent of its caller, so they are verified on their own
+			gomock.InOrder(
+				deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").DoAndReturn(func(context.Context, string) (entity.User, error) {
+					close(started)
+					<-release
+					return entity.User{}, gorm.ErrRecordNotFound
+				}),
+				deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(entity.User{}, gorm.ErrRecordNotFound),
+			)
+
+			done := make(chan error)
+			go func() {
+				_, err := s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: testPassword, IPAddress: "203.0.113.10", UserAgent: "curl/8.5.0"})
+				done <- err
+			}()
+			<-started
+
+			_, err := s.Login(context.Background(), other)
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+			close(release)
+			assert.ErrorIs(t, <-done, gorm.ErrRecordNotFound)
+		})
+	}
+}
+
+func TestAuthService_RefreshToken_Success(t *testing.T) {
+	s, deps := newAuthService(t)
+	user := newActiveUser(t)
+	now := deps.clock.Now()
+	existing := entity.RefreshToken{Token: "old-refresh-token", UserID: user.ID, ExpiryDate: now.Add(time.Hour)}
+
+	deps.refresh.EXPECT().GetRefreshTokenByToken(gomock.Any(), "old-refresh-token").Return(existing, nil)
+	deps.refresh.EXPECT().VerifyExpirationDate(existing.ExpiryDate).Return(true, nil)
+	deps.users.EXPECT().GetUserByID(gomock.Any(), user.ID).Return(user, nil)
+	deps.issuer.EXPECT().IssueToken(user, "", now).Return(service.IssuedToken{AccessToken: "access-token", ExpiresAt: now.Add(time.Hour)}, nil)
+	deps.issuer.EXPECT().TokenType().Return("Bearer")
+	deps.refresh.EXPECT().RotateRefreshToken(gomock.Any(), existing).Return(entity.RefreshToken{Token: "new-refresh-token", UserID: user.ID}, nil)
+	deps.lastLogin.EXPECT().Record(user.ID, now)
+	deps.tokenUsage.EXPECT().Record(service.TokenEventRefreshed, user.ID, "", now)
+
+	resp, err := s.RefreshToken(context.Background(), entity.RefreshTokenRequest{RefreshToken: "old-refresh-token"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "access-token", resp.AccessToken)
+	assert.Equal(t, "new-refresh-token", resp.RefreshToken)
+}
+
+func TestAuthService_RefreshToken_Expired(t *testing.T) {
+	s, deps := newAuthService(t)
+	existing := entity.RefreshToken{Token: "old-refresh-token", UserID: 1, ExpiryDate: deps.clock.Now().Add(-time.Minute)}
+
+	deps.refresh.EXPECT().GetRefreshTokenByToken(gomock.Any(), "old-refresh-token").Return(existing, nil)
+	deps.refresh.EXPECT().VerifyExpirationDate(existing.ExpiryDate).Return(false, errors.New("refresh token is expired"))
+
+	_, err := s.RefreshToken(context.Background(), entity.RefreshTokenRequest{RefreshToken: "old-refresh-token"})
+
+	assert.ErrorIs(t, err, service.ErrRefreshTokenExpired)
+}
+
+func TestAuthService_Logout(t *testing.T) {
+	s, deps := newAuthService(t)
+	logoutReq := entity.LogoutRequest{RefreshToken: "refresh-token", UserID: 1, TokenID: "token-id"}
+
+	deps.revocation.EXPECT().RevokeSession(gomock.Any(), logoutReq).Return(nil)
+
+	assert.NoError(t, s.Logout(context.Background(), logoutReq))
+
+	// The refresh token is required
+	var ve validator.ValidationErrors
+	assert.ErrorAs(t, s.Logout(context.Background(), entity.LogoutRequest{UserID: 1}), &ve)
+}
+
+func TestAuthService_LogoutIsAudited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	revocation := mocks.NewMockTokenRevocationService(ctrl)
+	recorder := mocks.NewMockAuditRecorder(ctrl)
+	s := service.NewAuthService(nil, nil, nil, nil, nil, nil, revocation, clock.New(), service.WithAudit(recorder))
+	logoutReq := entity.LogoutRequest{RefreshToken: "refresh-token", UserID: 1, TokenID: "token-id", IPAddress: "192.0.2.1"}
+
+	gomock.InOrder(
+		revocation.EXPECT().RevokeSession(gomock.Any(), logoutReq).Return(nil),
+		recorder.EXPECT().Record(gomock.Any(), gomock.Cond(func(e entity.AuditEvent) bool {
+			return e.Action == entity.AuditActionLogout && *e.ActorID == 1 && e.TargetID == "1" && e.IPAddress == "192.0.2.1"
+		})),
+	)
+	assert.NoError(t, s.Logout(context.Background(), logoutReq))
+
+	// A failed logout is not audited
+	revocation.EXPECT().RevokeSession(gomock.Any(), gomock.Any()).Return(service.ErrTokenSubjectMismatch)
+	assert.ErrorIs(t, s.Logout(context.Background(), logoutReq), service.ErrTokenSubjectMismatch)
+}
+
+func TestJWTTokenIssuer_ExpiryMath(t *testing.T) {
+	issuer := newJWTTokenIssuer()
+	issuedAt := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	token, err := issuer.IssueToken(newActiveUser(t), "", issuedAt)
+
+	require.NoError(t, err)
+	assert.True(t, token.ExpiresAt.Equal(issuedAt.Add(testExpirationHours*time.Hour)))
+
+	// The token must be valid just before its expiry and rejected once it has passed
+	cfg := testsupport.NewJWTConfig()
+	_, err = service.ParseJWTToken(cfg, token.AccessToken, token.ExpiresAt.Add(-time.Second))
+	assert.NoError(t, err)
+	_, err = service.ParseJWTToken(cfg, token.AccessToken, token.ExpiresAt.Add(time.Second))
+	assert.ErrorContains(t, err, jwt.ErrTokenExpired.Error())
+}
+
+func TestJWTTokenIssuer_ClientClaim(t *testing.T) {
+	issuer := newJWTTokenIssuer()
+	issuedAt := time.Now()
+	cfg := testsupport.NewJWTConfig()
+
+	// The client of the login is carried by the token, so that the quotas of the client apply to its requests
+	token, err := issuer.IssueToken(newActiveUser(t), "web", issuedAt)
+	require.NoError(t, err)
+	parsed, err := service.ParseJWTToken(cfg, token.AccessToken, issuedAt)
+	require.NoError(t, err)
+	assert.Equal(t, "web", parsed.Claims.(jwt.MapClaims)["client"])
+
+	token, err = issuer.IssueToken(newActiveUser(t), "", issuedAt)
+	require.NoError(t, err)
+	parsed, err = service.ParseJWTToken(cfg, token.AccessToken, issuedAt)
+	require.NoError(t, err)
+	assert.NotContains(t, parsed.Claims.(jwt.MapClaims), "client")
+}
+
+func TestJWTTokenIssuer_ParseUserID(t *testing.T) {
+	issuer := newJWTTokenIssuer()
+	user := newActiveUser(t)
+
+	// An expired access token still identifies its user
+	token, err := issuer.IssueToken(user, "", time.Now().Add(-72*time.Hour))
+	require.NoError(t, err)
+
+	userID, err := issuer.ParseUserID(token.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, userID)
+
+	// A token signed with another secret is rejected
+	cfg := testsupport.NewJWTConfig()
+	cfg.Secret = "another-secret-at-least-256-bits-long-value"
+	forged, err := service.NewJWTTokenIssuer(cfg).IssueToken(user, "", time.Now())
+	require.NoError(t, err)
+
+	_, err = issuer.ParseUserID(forged.AccessToken)
+	assert.Error(t, err)
+}
+
+func TestGetJWTExpiration(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC).Unix()
+	cfg := jwtconfig.JWTConfig{TTL: jwtconfig.TTLPolicy{
+		Default:   24 * time.Hour,
+		UserTypes: map[string]time.Duration{"SERVICE_ACCOUNT": 720 * time.Hour},
+		Roles:     map[string]time.Duration{"ROLE_ADMIN": 30 * time.Minute, "ROLE_MODERATOR": 2 * time.Hour},
+		Clients:   map[string]time.Duration{"mobile-app": 168 * time.Hour},
+	}}
+
+	user := newActiveUser(t)
+	user.Roles = []entity.Role{{ID: 2, Name: "ROLE_USER"}}
+	serviceAccount := user
+	serviceAccount.UserType = "SERVICE_ACCOUNT"
+	admin := newActiveUser(t)
+	admin.Roles = []entity.Role{{ID: 2, Name: "ROLE_MODERATOR"}, {ID: 1, Name: "ROLE_ADMIN"}}
+
+	tests := []struct {
+		name     string
+		user     entity.User
+		clientID string
+		want     time.Duration
+	}{
+		{"default", user, "", 24 * time.Hour},
+		{"user type", serviceAccount, "", 720 * time.Hour},
+		{"shortest role", admin, "web", 30 * time.Minute},
+		{"client first", admin, "mobile-app", 168 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, now+int64(tt.want.Seconds()), service.GetJWTExpiration(cfg, tt.user, tt.clientID, now), tt.name)
+	}
+}
+
+func TestLoadTTLPolicy(t *testing.T) {
+	t.Setenv("JWT_ACCESS_TOKEN_TTL_MINUTES", "60")
+	t.Setenv("JWT_ACCESS_TOKEN_TTL_BY_USER_TYPE", "SERVICE_ACCOUNT=43200, USER_ACCOUNT=120")
+	t.Setenv("JWT_ACCESS_TOKEN_TTL_BY_ROLE", "ROLE_ADMIN=invalid")
+	t.Setenv("JWT_ACCESS_TOKEN_TTL_BY_CLIENT", "")
+
+	policy := jwtconfig.LoadTTLPolicy()
+
+	assert.Equal(t, time.Hour, policy.Default)
+	assert.Equal(t, map[string]time.Duration{"SERVICE_ACCOUNT": 720 * time.Hour, "USER_ACCOUNT": 2 * time.Hour}, policy.UserTypes)
+	assert.Empty(t, policy.Roles)
+	assert.Empty(t, policy.Clients)
+	assert.Equal(t, 720*time.Hour, policy.Max())
+
+	// A missing or invalid default falls back to 24 hours
+	t.Setenv("JWT_ACCESS_TOKEN_TTL_MINUTES", "-5")
+	assert.Equal(t, jwtconfig.DefaultAccessTokenTTL, jwtconfig.LoadTTLPolicy().Default)
+}
+
+func TestParseTTLOverrides_Invalid(t *testing.T) {
+	for _, value := range []string{"SERVICE_ACCOUNT", "=60", "ROLE_ADMIN=0", "ROLE_ADMIN=1h"} {
+		_, err := jwtconfig.ParseTTLOverrides(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestAuthService_Login_AdminBlockedCountry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserService(ctrl)
+	s := service.NewAuthService(users, mocks.NewMockRefreshTokenService(ctrl), mocks.NewMockTokenIssuer(ctrl),
+		mocks.NewMockLastLoginRecorder(ctrl), mocks.NewMockTokenUsageRecorder(ctrl), mocks.NewMockSecurityNotifier(ctrl),
+		mocks.NewMockTokenRevocationService(ctrl), clock.New(), service.WithBlockedAdminCountries([]string{"kp"}))
+
+	users.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(newActiveUser(t), nil)
+
+	// No tokens are issued to the admin logging in from a blocked country
+	_, err := s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: testPassword, Country: "KP"})
+
+	assert.ErrorIs(t, err, service.ErrAdminLoginBlocked)
+}
+
+func TestAuthService_Login_BlockedCountryOnlyAppliesToAdmins(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserService(ctrl)
+	refresh := mocks.NewMockRefreshTokenService(ctrl)
+	issuer := mocks.NewMockTokenIssuer(ctrl)
+	lastLogin := mocks.NewMockLastLoginRecorder(ctrl)
+	tokenUsage := mocks.NewMockTokenUsageRecorder(ctrl)
+	notifier := mocks.NewMockSecurityNotifier(ctrl)
+	s := service.NewAuthService(users, refresh, issuer, lastLogin, tokenUsage, notifier,
+		mocks.NewMockTokenRevocationService(ctrl), clock.New(), service.WithBlockedAdminCountries([]string{"KP"}))
+
+	user := newActiveUser(t)
+	user.Roles = []entity.Role{{ID: 2, Name: "ROLE_USER"}}
+	users.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(user, nil)
+	issuer.EXPECT().IssueToken(user, "", gomock.Any()).Return(service.IssuedToken{AccessToken: "access-token"}, nil)
+	issuer.EXPECT().TokenType().Return("Bearer")
+	refresh.EXPECT().CreateRefreshToken(gomock.Any(), user.ID, gomock.Any()).Return(entity.RefreshToken{Token: "refresh-token", UserID: user.ID}, nil)
+	lastLogin.EXPECT().Record(user.ID, gomock.Any())
+	tokenUsage.EXPECT().Record(service.TokenEventIssued, user.ID, "", gomock.Any())
+	notifier.EXPECT().Notify(gomock.Any()).Do(func(event entity.SecurityEvent) {
+		assert.Equal(t, "KP", event.Country)
+		assert.Equal(t, "Pyongyang", event.City)
+	})
+
+	resp, err := s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: testPassword, Country: "KP", City: "Pyongyang"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "access-token", resp.AccessToken)
+}
+
+func TestAuthService_RefreshToken_MatchingAccessToken(t *testing.T) {
+	s, deps := newAuthService(t)
+	user := newActiveUser(t)
+	now := deps.clock.Now()
+	existing := entity.RefreshToken{Token: "old-refresh-token", UserID: user.ID, ExpiryDate: now.Add(time.Hour)}
+
+	deps.refresh.EXPECT().GetRefreshTokenByToken(gomock.Any(), "old-refresh-token").Return(existing, nil)
+	deps.issuer.EXPECT().ParseUserID("expired-access-token").Return(user.ID, nil)
+	deps.refresh.EXPECT().VerifyExpirationDate(existing.ExpiryDate).Return(true, nil)
+	deps.users.EXPECT().GetUserByID(gomock.Any(), user.ID).Return(user, nil)
+	deps.issuer.EXPECT().IssueToken(user, "", now).Return(service.IssuedToken{AccessToken: "access-token", ExpiresAt: now.Add(time.Hour)}, nil)
+	deps.issuer.EXPECT().TokenType().Return("Bearer")
+	deps.refresh.EXPECT().RotateRefreshToken(gomock.Any(), existing).Return(entity.RefreshToken{Token: "new-refresh-token", UserID: user.ID}, nil)
+	deps.lastLogin.EXPECT().Record(user.ID, now)
+	deps.tokenUsage.EXPECT().Record(service.TokenEventRefreshed, user.ID, "", now)
+
+	resp, err := s.RefreshToken(context.Background(), entity.RefreshTokenRequest{RefreshToken: "old-refresh-token", AccessToken: "expired-access-token"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "new-refresh-token", resp.RefreshToken)
+}
+
+func TestAuthService_RefreshToken_AccessTokenOfAnotherUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID int64
+		err    error
+	}{
+		{"another user", 2, nil},
+		{"invalid access token", 0, errors.New("failed to parse JWT token")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, deps := newAuthService(t)
+			existing := entity.RefreshToken{Token: "stolen-refresh-token", UserID: 1, ExpiryDate: deps.clock.Now().Add(time.Hour)}
+
+			deps.refresh.EXPECT().GetRefreshTokenByToken(gomock.Any(), "stolen-refresh-token").Return(existing, nil)
+			deps.issuer.EXPECT().ParseUserID("access-token").Return(tt.userID, tt.err)
+
+			// No tokens are issued and the refresh token is not rotated
+			_, err := s.RefreshToken(context.Background(), entity.RefreshTokenRequest{RefreshToken: "stolen-refresh-token", AccessToken: "access-token"})
+
+			assert.ErrorIs(t, err, service.ErrTokenSubjectMismatch)
+		})
+	}
+}
+
+func TestAuthService_RefreshToken_DisabledUser(t *testing.T) {
+	s, deps := newAuthService(t)
+	user := newActiveUser(t)
+	user.State = entity.UserStateDisabled
+	existing := entity.RefreshToken{Token: "refresh-token", UserID: user.ID, ExpiryDate: deps.clock.Now().Add(time.Hour)}
+
+	deps.refresh.EXPECT().GetRefreshTokenByToken(gomock.Any(), "refresh-token").Return(existing, nil)
+	deps.refresh.EXPECT().VerifyExpirationDate(existing.ExpiryDate).Return(true, nil)
+	deps.users.EXPECT().GetUserByID(gomock.Any(), user.ID).Return(user, nil)
+
+	// A disabled user keeps the access tokens already issued, but cannot get new ones
+	_, err := s.RefreshToken(context.Background(), entity.RefreshTokenRequest{RefreshToken: "refresh-token"})
+
+	assert.ErrorIs(t, err, service.ErrUserLocked)
+	assert.EqualError(t, err, "user account is locked: user with username admin is disabled")
+}
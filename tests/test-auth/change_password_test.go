@@ -0,0 +1,131 @@
+package test_auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// passwordDeps holds the dependencies of the password service under test.
+type passwordDeps struct {
+	users    repository.UserRepository
+	sessions repository.RefreshTokenRepository
+	notifier *mocks.MockSecurityNotifier
+	clock    *clock.FakeClock
+	user     entity.User
+}
+
+// newPasswordService creates a password service with passwords expiring after 90 days,
+// backed by an in-memory store with an active user whose password is P@ssw0rd, logged in on two devices.
+func newPasswordService(t *testing.T) (service.PasswordService, passwordDeps) {
+	store := testsupport.UseMemoryDatabase(t)
+	hashed, err := bcrypt.GenerateFromPassword([]byte("P@ssw0rd"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user, err := store.AddUser(entity.User{Username: "alice", Email: "alice@example.com", Password: string(hashed), State: entity.UserStateActive})
+	require.NoError(t, err)
+
+	deps := passwordDeps{
+		users:    repository.NewMemoryUserRepository(store),
+		sessions: repository.NewMemoryRefreshTokenRepository(store),
+		notifier: mocks.NewMockSecurityNotifier(gomock.NewController(t)),
+		clock:    clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)),
+		user:     user,
+	}
+	for _, token := range []string{"laptop-token", "phone-token"} {
+		_, err := deps.sessions.CreateRefreshToken(nil, entity.RefreshToken{Token: token, SessionID: token, UserID: user.ID, ExpiryDate: deps.clock.Now().Add(time.Hour)})
+		require.NoError(t, err)
+	}
+
+	policy := policyconfig.PasswordPolicy{CredentialsTTL: 90 * 24 * time.Hour}
+	return service.NewPasswordService(database.DefaultStore(), deps.users, deps.sessions, deps.notifier, policy, deps.clock), deps
+}
+
+func TestChangePassword_UpdatesPasswordAndEndsSessions(t *testing.T) {
+	s, deps := newPasswordService(t)
+
+	deps.notifier.EXPECT().Notify(gomock.Cond(func(event entity.SecurityEvent) bool {
+		return event.Type == entity.SecurityEventPasswordChanged && event.UserID == deps.user.ID && event.IPAddress == "203.0.113.7"
+	}))
+
+	changed, err := s.ChangePassword(context.Background(), deps.user.ID, entity.ChangePasswordRequest{CurrentPassword: "P@ssw0rd", NewPassword: "N3w-P@ssw0rd", IPAddress: "203.0.113.7"})
+	require.NoError(t, err)
+
+	expiresAt := deps.clock.Now().Add(90 * 24 * time.Hour)
+	require.NotNil(t, changed.CredentialsExpirationDate)
+	assert.Equal(t, expiresAt, *changed.CredentialsExpirationDate)
+
+	user, err := deps.users.GetUserByID(context.Background(), nil, deps.user.ID)
+	require.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("N3w-P@ssw0rd")))
+	require.NotNil(t, user.CredentialsExpirationDate)
+	assert.Equal(t, expiresAt, *user.CredentialsExpirationDate)
+
+	// The sessions opened with the old password are ended
+	tokens, err := deps.sessions.GetRefreshTokensByUserID(nil, deps.user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+}
+
+func TestChangePassword_RejectsWrongCurrentPassword(t *testing.T) {
+	s, deps := newPasswordService(t)
+
+	_, err := s.ChangePassword(context.Background(), deps.user.ID, entity.ChangePasswordRequest{CurrentPassword: "Wr0ngP@ss", NewPassword: "N3w-P@ssw0rd"})
+	assert.ErrorIs(t, err, service.ErrIncorrectPassword)
+
+	// Nothing is changed
+	tokens, err := deps.sessions.GetRefreshTokensByUserID(nil, deps.user.ID)
+	require.NoError(t, err)
+	assert.Len(t, tokens, 2)
+}
+
+func TestChangePassword_EnforcesPasswordPolicy(t *testing.T) {
+	s, deps := newPasswordService(t)
+
+	for name, newPassword := range map[string]string{
+		"weak password":    "password",
+		"same as current":  "P@ssw0rd",
+		"too long":         "N3w-P@ssw0rd-N3w-P@ssw0rd",
+		"missing password": "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := s.ChangePassword(context.Background(), deps.user.ID, entity.ChangePasswordRequest{CurrentPassword: "P@ssw0rd", NewPassword: newPassword})
+
+			var ve validator.ValidationErrors
+			require.True(t, errors.As(err, &ve), "expected a validation error, got %v", err)
+			assert.Equal(t, "newPassword", ve[0].Field())
+		})
+	}
+}
+
+func TestPasswordPolicy_CredentialsExpiration(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	t.Setenv("CREDENTIALS_TTL_DAYS", "30")
+	expiresAt := policyconfig.LoadPasswordPolicy().CredentialsExpiration(now)
+	require.NotNil(t, expiresAt)
+	assert.Equal(t, now.AddDate(0, 0, 30), *expiresAt)
+
+	// 0 disables the expiration
+	t.Setenv("CREDENTIALS_TTL_DAYS", "0")
+	assert.Nil(t, policyconfig.LoadPasswordPolicy().CredentialsExpiration(now))
+
+	// Invalid values fall back to the default
+	t.Setenv("CREDENTIALS_TTL_DAYS", "-1")
+	assert.Equal(t, policyconfig.DefaultCredentialsTTL, policyconfig.LoadPasswordPolicy().CredentialsTTL)
+}
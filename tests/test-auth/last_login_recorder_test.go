@@ -0,0 +1,84 @@
+package test_auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+)
+
+func TestAsyncLastLoginRecorder_FlushesLatestTimePerUserOnClose(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserService(ctrl)
+	first := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	latest := first.Add(time.Minute)
+
+	// The flush interval is long enough that only Close writes the pending times
+	r := service.NewAsyncLastLoginRecorder(users, nil, time.Hour, 0)
+
+	users.EXPECT().UpdateLastLogin(gomock.Any(), int64(1), latest).Return(true, nil).Times(1)
+	users.EXPECT().UpdateLastLogin(gomock.Any(), int64(2), first).Return(true, nil).Times(1)
+
+	r.Record(1, first)
+	r.Record(1, latest)
+	r.Record(1, first) // an older time does not overwrite the latest one
+	r.Record(2, first)
+
+	r.Close()
+	r.Close()
+}
+
+func TestAsyncLastLoginRecorder_FlushesInTheBackground(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserService(ctrl)
+	lastLogin := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	written := make(chan struct{})
+
+	r := service.NewAsyncLastLoginRecorder(users, nil, 10*time.Millisecond, 0)
+	defer r.Close()
+
+	users.EXPECT().UpdateLastLogin(gomock.Any(), int64(1), lastLogin).DoAndReturn(func(context.Context, int64, time.Time) (bool, error) {
+		close(written)
+		return true, nil
+	}).Times(1)
+
+	r.Record(1, lastLogin)
+
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("the last login time was not written in the background")
+	}
+}
+
+func TestAsyncLastLoginRecorder_FailuresAndOverflowAreNotFatal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserService(ctrl)
+	lastLogin := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	r := service.NewAsyncLastLoginRecorder(users, nil, time.Hour, 2)
+
+	var mu sync.Mutex
+	var writtenIDs []int64
+	users.EXPECT().UpdateLastLogin(gomock.Any(), gomock.Any(), lastLogin).DoAndReturn(func(_ context.Context, id int64, _ time.Time) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		writtenIDs = append(writtenIDs, id)
+		return false, errors.New("database connection is nil")
+	}).Times(2)
+
+	// The third user is dropped, at most two users can be pending
+	r.Record(1, lastLogin)
+	r.Record(2, lastLogin)
+	r.Record(3, lastLogin)
+
+	r.Close()
+	assert.ElementsMatch(t, []int64{1, 2}, writtenIDs)
+}
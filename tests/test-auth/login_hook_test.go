@@ -0,0 +1,117 @@
+package test_auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newAuthServiceWithHooks creates the auth service under test backed by mocked dependencies and the given login hooks.
+func newAuthServiceWithHooks(t *testing.T, issuer service.TokenIssuer, hooks ...service.LoginHook) (service.AuthService, authServiceDeps) {
+	_, deps := newAuthService(t)
+	s := service.NewAuthService(deps.users, deps.refresh, issuer, deps.lastLogin, deps.tokenUsage, deps.notifier, deps.revocation, deps.clock,
+		service.WithLoginHooks(hooks...))
+
+	return s, deps
+}
+
+func TestLoginHook_BeforeLoginRejectsBeforeTheCredentials(t *testing.T) {
+	errClosed := errors.New("the store is closed")
+	var checked []string
+	s, _ := newAuthServiceWithHooks(t, newJWTTokenIssuer(), service.LoginHookFuncs{
+		Before: func(loginReq entity.LoginRequest) error {
+			checked = append(checked, loginReq.Username)
+			return errClosed
+		},
+	})
+
+	// The user is not even read
+	_, err := s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: testPassword})
+
+	assert.ErrorIs(t, err, service.ErrLoginRejected)
+	assert.ErrorIs(t, err, errClosed)
+	assert.Equal(t, []string{"admin"}, checked)
+}
+
+func TestLoginHook_AfterLoginRejectsTheAuthenticatedUser(t *testing.T) {
+	s, deps := newAuthServiceWithHooks(t, newJWTTokenIssuer(), service.LoginHookFuncs{
+		After: func(user entity.User, loginReq entity.LoginRequest) (map[string]interface{}, error) {
+			return nil, fmt.Errorf("user %d has no contract", user.ID)
+		},
+	})
+	deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(newActiveUser(t), nil)
+
+	// The credentials are valid, but no tokens are issued
+	_, err := s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: testPassword})
+
+	assert.ErrorIs(t, err, service.ErrLoginRejected)
+	assert.EqualError(t, err, "login rejected: user 1 has no contract")
+}
+
+func TestLoginHook_AfterLoginAddsClaims(t *testing.T) {
+	s, deps := newAuthServiceWithHooks(t, newJWTTokenIssuer(),
+		service.LoginHookFuncs{
+			After: func(user entity.User, loginReq entity.LoginRequest) (map[string]interface{}, error) {
+				return map[string]interface{}{"tenant": "acme", "crm_id": "c-1"}, nil
+			},
+		},
+		service.LoginHookFuncs{
+			After: func(user entity.User, loginReq entity.LoginRequest) (map[string]interface{}, error) {
+				// The claims of the hooks before are visible, and the claims of the service cannot be replaced
+				assert.Equal(t, "acme", user.Claims["tenant"])
+				return map[string]interface{}{"crm_id": "c-2", "sub": "root", "client": "forged"}, nil
+			},
+		},
+	)
+	user := newActiveUser(t)
+	now := deps.clock.Now()
+
+	deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(user, nil)
+	deps.refresh.EXPECT().CreateRefreshToken(gomock.Any(), user.ID, gomock.Any()).Return(entity.RefreshToken{Token: "refresh-token", UserID: user.ID}, nil)
+	deps.lastLogin.EXPECT().Record(user.ID, now)
+	deps.tokenUsage.EXPECT().Record(service.TokenEventIssued, user.ID, "", now)
+	deps.notifier.EXPECT().Notify(gomock.Any())
+
+	resp, err := s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: testPassword})
+	require.NoError(t, err)
+
+	parsed, err := service.ParseJWTToken(testsupport.NewJWTConfig(), resp.AccessToken, now.Add(time.Minute))
+	require.NoError(t, err)
+	claims := parsed.Claims.(jwt.MapClaims)
+	assert.Equal(t, "acme", claims["tenant"])
+	assert.Equal(t, "c-2", claims["crm_id"])
+	assert.Equal(t, "admin", claims["sub"])
+	assert.NotContains(t, claims, "client")
+}
+
+func TestLogin_RejectedByLoginHook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockAuthService(ctrl)
+	router := setupAuthRouter(s)
+
+	s.EXPECT().Login(gomock.Any(), gomock.Any()).Return(entity.LoginResponse{}, fmt.Errorf("%w: outside of the business hours", service.ErrLoginRejected))
+
+	w := postJSON(router, "/auth/login", entity.LoginRequest{Username: "admin", Password: "P@ssw0rd"})
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var httpResponse httputil.HttpResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &httpResponse))
+	assert.Equal(t, "Login rejected", httpResponse.Message)
+	assert.Equal(t, "login rejected: outside of the business hours", httpResponse.Error)
+}
@@ -0,0 +1,282 @@
+package test_auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/totp"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// mfaDeps holds the dependencies of the MFA service under test.
+type mfaDeps struct {
+	repo     repository.MFARepository
+	notifier *mocks.MockSecurityNotifier
+	clock    *clock.FakeClock
+	user     entity.User
+}
+
+// newMFAService creates an MFA service with a 5 minute token TTL,
+// backed by an in-memory store with an active user.
+func newMFAService(t *testing.T) (service.MFAService, mfaDeps) {
+	store := testsupport.UseMemoryDatabase(t)
+	user, err := store.AddUser(entity.User{Username: "alice", Email: "alice@example.com", State: entity.UserStateActive})
+	require.NoError(t, err)
+
+	deps := mfaDeps{
+		repo:     repository.NewMemoryMFARepository(store),
+		notifier: mocks.NewMockSecurityNotifier(gomock.NewController(t)),
+		clock:    clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)),
+		user:     user,
+	}
+	policy := policyconfig.MFAPolicy{Issuer: "Demo", TokenTTL: 5 * time.Minute}
+
+	return service.NewMFAService(database.DefaultStore(), deps.repo, repository.NewMemoryUserRepository(store), deps.notifier, policy, deps.clock), deps
+}
+
+// currentCode returns the code of the authenticator app at the time of the fake clock.
+func currentCode(t *testing.T, secret string, clk *clock.FakeClock) string {
+	code, err := totp.Code(secret, totp.Step(clk.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+// enableMFA enrolls and activates two-factor authentication for the user, and returns the secret.
+// The clock is moved to the next time step, so that the next code is not the one used by the activation.
+func enableMFA(t *testing.T, s service.MFAService, deps mfaDeps) string {
+	enrollment, err := s.Enroll(context.Background(), deps.user.ID)
+	require.NoError(t, err)
+
+	_, err = s.Activate(context.Background(), deps.user.ID, entity.MFACodeRequest{Code: currentCode(t, enrollment.Secret, deps.clock)})
+	require.NoError(t, err)
+
+	deps.clock.Advance(totp.Period)
+	return enrollment.Secret
+}
+
+func TestTOTP_MatchesRFC6238(t *testing.T) {
+	// Test vector of RFC 6238 for SHA-1, the secret is "12345678901234567890" in base32
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	at := time.Unix(59, 0)
+
+	code, err := totp.Code(secret, totp.Step(at))
+	require.NoError(t, err)
+	assert.Equal(t, "287082", code)
+
+	// The codes are accepted during the step before and after their own
+	step, ok := totp.Validate(secret, code, at.Add(totp.Period))
+	assert.True(t, ok)
+	assert.Equal(t, totp.Step(at), step)
+
+	_, ok = totp.Validate(secret, code, at.Add(2*totp.Period))
+	assert.False(t, ok)
+}
+
+func TestMFAService_EnrollAndActivate(t *testing.T) {
+	s, deps := newMFAService(t)
+
+	enrollment, err := s.Enroll(context.Background(), deps.user.ID)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.ProvisioningURI, "otpauth://totp/Demo:alice?")
+	assert.Contains(t, enrollment.ProvisioningURI, "secret="+enrollment.Secret)
+
+	// The enrollment is pending until it is confirmed with a code
+	status, err := s.GetStatus(context.Background(), deps.user.ID)
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+
+	_, err = s.Activate(context.Background(), deps.user.ID, entity.MFACodeRequest{Code: "000000"})
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+
+	status, err = s.Activate(context.Background(), deps.user.ID, entity.MFACodeRequest{Code: currentCode(t, enrollment.Secret, deps.clock)})
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+
+	_, err = s.Enroll(context.Background(), deps.user.ID)
+	assert.ErrorIs(t, err, service.ErrMFAAlreadyEnabled)
+}
+
+func TestMFAService_ActivateWithoutEnrollment(t *testing.T) {
+	s, deps := newMFAService(t)
+
+	_, err := s.Activate(context.Background(), deps.user.ID, entity.MFACodeRequest{Code: "123456"})
+	assert.ErrorIs(t, err, service.ErrMFANotEnrolled)
+}
+
+func TestMFAService_VerifyChallengeOnce(t *testing.T) {
+	s, deps := newMFAService(t)
+	secret := enableMFA(t, s, deps)
+
+	challenge, err := s.StartChallenge(context.Background(), deps.user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, deps.clock.Now().Add(5*time.Minute), challenge.ExpiresAt)
+
+	code := currentCode(t, secret, deps.clock)
+	userID, err := s.VerifyChallenge(context.Background(), challenge.Token, code)
+	require.NoError(t, err)
+	assert.Equal(t, deps.user.ID, userID)
+
+	// The token is used up, and the code cannot be used again with another token
+	_, err = s.VerifyChallenge(context.Background(), challenge.Token, code)
+	assert.ErrorIs(t, err, service.ErrInvalidMFAToken)
+
+	other, err := s.StartChallenge(context.Background(), deps.user.ID)
+	require.NoError(t, err)
+	_, err = s.VerifyChallenge(context.Background(), other.Token, code)
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+}
+
+func TestMFAService_VerifyChallengeLimitsAttempts(t *testing.T) {
+	s, deps := newMFAService(t)
+	secret := enableMFA(t, s, deps)
+
+	challenge, err := s.StartChallenge(context.Background(), deps.user.ID)
+	require.NoError(t, err)
+
+	for i := 0; i < service.MaxMFAAttempts; i++ {
+		_, err = s.VerifyChallenge(context.Background(), challenge.Token, "000000")
+		assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+	}
+
+	// The token is used up after too many invalid codes, even with a valid one
+	_, err = s.VerifyChallenge(context.Background(), challenge.Token, currentCode(t, secret, deps.clock))
+	assert.ErrorIs(t, err, service.ErrInvalidMFAToken)
+}
+
+func TestMFAService_VerifyChallengeExpires(t *testing.T) {
+	s, deps := newMFAService(t)
+	secret := enableMFA(t, s, deps)
+
+	challenge, err := s.StartChallenge(context.Background(), deps.user.ID)
+	require.NoError(t, err)
+
+	deps.clock.Advance(5 * time.Minute)
+	_, err = s.VerifyChallenge(context.Background(), challenge.Token, currentCode(t, secret, deps.clock))
+	assert.ErrorIs(t, err, service.ErrInvalidMFAToken)
+}
+
+func TestMFAService_Disable(t *testing.T) {
+	s, deps := newMFAService(t)
+	secret := enableMFA(t, s, deps)
+
+	challenge, err := s.StartChallenge(context.Background(), deps.user.ID)
+	require.NoError(t, err)
+
+	deps.notifier.EXPECT().Notify(gomock.Cond(func(event entity.SecurityEvent) bool {
+		return event.Type == entity.SecurityEventMFADisabled && event.UserID == deps.user.ID && event.IPAddress == "203.0.113.7"
+	}))
+
+	require.NoError(t, s.Disable(context.Background(), deps.user.ID, entity.MFACodeRequest{Code: currentCode(t, secret, deps.clock), IPAddress: "203.0.113.7"}))
+
+	enabled, err := s.IsEnabled(context.Background(), deps.user.ID)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+
+	// The logins waiting for a code are ended
+	deps.clock.Advance(totp.Period)
+	_, err = s.VerifyChallenge(context.Background(), challenge.Token, currentCode(t, secret, deps.clock))
+	assert.ErrorIs(t, err, service.ErrInvalidMFAToken)
+
+	err = s.Disable(context.Background(), deps.user.ID, entity.MFACodeRequest{Code: "123456"})
+	assert.ErrorIs(t, err, service.ErrMFANotEnabled)
+}
+
+func TestAuthService_Login_RequiresMFA(t *testing.T) {
+	_, deps := newAuthService(t)
+	mfa := mocks.NewMockMFAService(gomock.NewController(t))
+	s := service.NewAuthService(deps.users, deps.refresh, deps.issuer, deps.lastLogin, deps.tokenUsage, deps.notifier, deps.revocation, deps.clock, service.WithMFA(mfa))
+	user := newActiveUser(t)
+	expiresAt := deps.clock.Now().Add(5 * time.Minute)
+
+	// No token is issued until the code is checked
+	deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(user, nil)
+	mfa.EXPECT().IsEnabled(gomock.Any(), user.ID).Return(true, nil)
+	mfa.EXPECT().StartChallenge(gomock.Any(), user.ID).Return(service.MFAChallenge{Token: "mfa-token", ExpiresAt: expiresAt}, nil)
+
+	resp, err := s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: testPassword, ClientID: "web"})
+
+	require.NoError(t, err)
+	assert.True(t, resp.MFARequired)
+	assert.Equal(t, "mfa-token", resp.MFAToken)
+	assert.Equal(t, expiresAt.Format(time.RFC3339), resp.MFATokenExpirationDate)
+	assert.Empty(t, resp.AccessToken)
+}
+
+func TestAuthService_Login_StepUp(t *testing.T) {
+	_, deps := newAuthService(t)
+	mfa := mocks.NewMockMFAService(gomock.NewController(t))
+	s := service.NewAuthService(deps.users, deps.refresh, deps.issuer, deps.lastLogin, deps.tokenUsage, deps.notifier, deps.revocation, deps.clock, service.WithMFA(mfa))
+	user := newActiveUser(t)
+
+	// The step-up of a user with two-factor authentication is the MFA challenge
+	deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(user, nil)
+	mfa.EXPECT().IsEnabled(gomock.Any(), user.ID).Return(true, nil)
+	mfa.EXPECT().StartChallenge(gomock.Any(), user.ID).Return(service.MFAChallenge{Token: "mfa-token", ExpiresAt: deps.clock.Now().Add(5 * time.Minute)}, nil)
+
+	resp, err := s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: testPassword, StepUp: true})
+	require.NoError(t, err)
+	assert.True(t, resp.MFARequired)
+	assert.Empty(t, resp.AccessToken)
+
+	// No token is issued to a user without two-factor authentication
+	deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(user, nil)
+	mfa.EXPECT().IsEnabled(gomock.Any(), user.ID).Return(false, nil)
+
+	_, err = s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: testPassword, StepUp: true})
+	assert.ErrorIs(t, err, service.ErrStepUpRequired)
+
+	// The password is checked first
+	deps.users.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(user, nil)
+
+	_, err = s.Login(context.Background(), entity.LoginRequest{Username: "admin", Password: "Wr0ngP@ss", StepUp: true})
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+}
+
+func TestAuthService_VerifyMFA_IssuesTokens(t *testing.T) {
+	_, deps := newAuthService(t)
+	mfa := mocks.NewMockMFAService(gomock.NewController(t))
+	s := service.NewAuthService(deps.users, deps.refresh, deps.issuer, deps.lastLogin, deps.tokenUsage, deps.notifier, deps.revocation, deps.clock, service.WithMFA(mfa))
+	user := newActiveUser(t)
+	now := deps.clock.Now()
+
+	mfa.EXPECT().VerifyChallenge(gomock.Any(), "mfa-token", "123456").Return(user.ID, nil)
+	deps.users.EXPECT().GetUserByID(gomock.Any(), user.ID).Return(user, nil)
+	deps.issuer.EXPECT().IssueToken(user, "web", now).Return(service.IssuedToken{AccessToken: "access-token", ExpiresAt: now.Add(time.Hour)}, nil)
+	deps.issuer.EXPECT().TokenType().Return("Bearer")
+	deps.refresh.EXPECT().CreateRefreshToken(gomock.Any(), user.ID, gomock.Any()).Return(entity.RefreshToken{Token: "refresh-token", UserID: user.ID}, nil)
+	deps.lastLogin.EXPECT().Record(user.ID, now)
+	deps.tokenUsage.EXPECT().Record(service.TokenEventIssued, user.ID, "web", now)
+	deps.notifier.EXPECT().Notify(gomock.Cond(func(event entity.SecurityEvent) bool {
+		return event.Type == entity.SecurityEventLogin && event.UserID == user.ID
+	}))
+
+	resp, err := s.VerifyMFA(context.Background(), entity.VerifyMFARequest{MFAToken: "mfa-token", Code: "123456", ClientID: "web"})
+
+	require.NoError(t, err)
+	assert.False(t, resp.MFARequired)
+	assert.Equal(t, "access-token", resp.AccessToken)
+	assert.Equal(t, "refresh-token", resp.RefreshToken)
+}
+
+func TestAuthService_VerifyMFA_RejectsInvalidCode(t *testing.T) {
+	_, deps := newAuthService(t)
+	mfa := mocks.NewMockMFAService(gomock.NewController(t))
+	s := service.NewAuthService(deps.users, deps.refresh, deps.issuer, deps.lastLogin, deps.tokenUsage, deps.notifier, deps.revocation, deps.clock, service.WithMFA(mfa))
+
+	mfa.EXPECT().VerifyChallenge(gomock.Any(), "mfa-token", "000000").Return(int64(0), service.ErrInvalidMFACode)
+
+	_, err := s.VerifyMFA(context.Background(), entity.VerifyMFARequest{MFAToken: "mfa-token", Code: "000000"})
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+}
@@ -0,0 +1,179 @@
+package test_auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/mailer"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// resetLinkPattern extracts the token of the reset link of an email.
+var resetLinkPattern = regexp.MustCompile(`https://app\.example\.com/reset-password\?token=([A-Za-z0-9_-]+)`)
+
+// outbox is a mailer keeping the sent messages in memory.
+type outbox struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *outbox) Send(msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// lastToken returns the token of the reset link of the last email sent.
+func (m *outbox) lastToken(t *testing.T) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	require.NotEmpty(t, m.sent)
+	match := resetLinkPattern.FindStringSubmatch(m.sent[len(m.sent)-1].Body)
+	require.NotNil(t, match, m.sent[len(m.sent)-1].Body)
+	return match[1]
+}
+
+// passwordResetDeps holds the dependencies of the password reset service under test.
+type passwordResetDeps struct {
+	users      repository.UserRepository
+	tokens     repository.PasswordResetTokenRepository
+	revocation *mocks.MockTokenRevocationService
+	notifier   *mocks.MockSecurityNotifier
+	outbox     *outbox
+	clock      *clock.FakeClock
+	user       entity.User
+}
+
+// newPasswordResetService creates a password reset service with a 30 minute TTL,
+// backed by an in-memory store with an active user.
+func newPasswordResetService(t *testing.T) (service.PasswordResetService, passwordResetDeps) {
+	store := testsupport.UseMemoryDatabase(t)
+	user, err := store.AddUser(entity.User{Username: "alice", Email: "alice@example.com", State: entity.UserStateActive})
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	deps := passwordResetDeps{
+		users:      repository.NewMemoryUserRepository(store),
+		tokens:     repository.NewMemoryPasswordResetTokenRepository(store),
+		revocation: mocks.NewMockTokenRevocationService(ctrl),
+		notifier:   mocks.NewMockSecurityNotifier(ctrl),
+		outbox:     &outbox{},
+		clock:      clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)),
+		user:       user,
+	}
+	policy := policyconfig.PasswordResetPolicy{TokenTTL: 30 * time.Minute, ResetURL: "https://app.example.com/reset-password"}
+	templates, err := mailer.NewTemplates("", "")
+	require.NoError(t, err)
+
+	return service.NewPasswordResetService(database.DefaultStore(), deps.tokens, deps.users, deps.revocation, deps.notifier, deps.outbox, templates, policy, deps.clock), deps
+}
+
+func TestPasswordReset_ResetsPasswordOnce(t *testing.T) {
+	s, deps := newPasswordResetService(t)
+
+	require.NoError(t, s.RequestPasswordReset(context.Background(), entity.ForgotPasswordRequest{Email: "Alice@Example.com"}))
+	token := deps.outbox.lastToken(t)
+
+	// Only the hash of the token is stored
+	stored, err := deps.tokens.GetPasswordResetTokenByHash(nil, service.HashPasswordResetToken(token))
+	require.NoError(t, err)
+	assert.NotContains(t, stored.TokenHash, token)
+	assert.Equal(t, deps.clock.Now().Add(30*time.Minute), stored.ExpiresAt)
+
+	deps.revocation.EXPECT().RevokeUserTokens(gomock.Any(), deps.user.ID).Return(entity.TokenRevocation{UserID: deps.user.ID}, nil)
+	deps.notifier.EXPECT().Notify(gomock.Cond(func(event entity.SecurityEvent) bool {
+		return event.Type == entity.SecurityEventPasswordChanged && event.UserID == deps.user.ID && event.IPAddress == "203.0.113.7"
+	}))
+
+	require.NoError(t, s.ResetPassword(context.Background(), entity.ResetPasswordRequest{Token: token, NewPassword: "N3w-P@ssw0rd", IPAddress: "203.0.113.7"}))
+
+	user, err := deps.users.GetUserByID(context.Background(), nil, deps.user.ID)
+	require.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("N3w-P@ssw0rd")))
+
+	// The token is single-use
+	err = s.ResetPassword(context.Background(), entity.ResetPasswordRequest{Token: token, NewPassword: "An0ther-P@ss"})
+	assert.ErrorIs(t, err, service.ErrInvalidPasswordResetToken)
+}
+
+func TestPasswordReset_EmailsInTheLanguageOfTheRequest(t *testing.T) {
+	s, deps := newPasswordResetService(t)
+
+	require.NoError(t, s.RequestPasswordReset(context.Background(), entity.ForgotPasswordRequest{Email: "alice@example.com", Locale: "id-ID,id;q=0.9,en;q=0.8"}))
+	deps.outbox.lastToken(t)
+	msg := deps.outbox.sent[len(deps.outbox.sent)-1]
+	assert.Equal(t, "alice@example.com", msg.To)
+	assert.Equal(t, "Atur ulang kata sandi Anda", msg.Subject)
+	assert.Contains(t, msg.HTML, "https://app.example.com/reset-password?token=")
+
+	// A language without templates falls back to the default locale
+	require.NoError(t, s.RequestPasswordReset(context.Background(), entity.ForgotPasswordRequest{Email: "alice@example.com", Locale: "fr-FR"}))
+	assert.Equal(t, "Reset your password", deps.outbox.sent[len(deps.outbox.sent)-1].Subject)
+}
+
+func TestPasswordReset_RejectsExpiredAndReplacedTokens(t *testing.T) {
+	s, deps := newPasswordResetService(t)
+
+	require.NoError(t, s.RequestPasswordReset(context.Background(), entity.ForgotPasswordRequest{Email: "alice@example.com"}))
+	first := deps.outbox.lastToken(t)
+	require.NoError(t, s.RequestPasswordReset(context.Background(), entity.ForgotPasswordRequest{Email: "alice@example.com"}))
+	second := deps.outbox.lastToken(t)
+
+	// Requesting a new link invalidates the previous one
+	err := s.ResetPassword(context.Background(), entity.ResetPasswordRequest{Token: first, NewPassword: "N3w-P@ssw0rd"})
+	assert.ErrorIs(t, err, service.ErrInvalidPasswordResetToken)
+
+	deps.clock.Advance(30 * time.Minute)
+	err = s.ResetPassword(context.Background(), entity.ResetPasswordRequest{Token: second, NewPassword: "N3w-P@ssw0rd"})
+	assert.ErrorIs(t, err, service.ErrInvalidPasswordResetToken)
+
+	err = s.ResetPassword(context.Background(), entity.ResetPasswordRequest{Token: "unknown", NewPassword: "N3w-P@ssw0rd"})
+	assert.ErrorIs(t, err, service.ErrInvalidPasswordResetToken)
+}
+
+func TestPasswordReset_UnknownEmailSendsNothing(t *testing.T) {
+	s, deps := newPasswordResetService(t)
+
+	require.NoError(t, s.RequestPasswordReset(context.Background(), entity.ForgotPasswordRequest{Email: "nobody@example.com"}))
+	assert.Empty(t, deps.outbox.sent)
+}
+
+func TestPasswordResetHandler_InvalidToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockPasswordResetService(ctrl)
+	s.EXPECT().ResetPassword(gomock.Any(), gomock.Any()).Return(service.ErrInvalidPasswordResetToken)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/auth/reset-password", handler.NewPasswordResetHandler(s).ResetPassword)
+
+	req := httptest.NewRequest("POST", "/auth/reset-password", strings.NewReader(`{"token":"unknown","newPassword":"N3w-P@ssw0rd"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), service.ErrInvalidPasswordResetToken.Error())
+}
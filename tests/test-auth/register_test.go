@@ -0,0 +1,79 @@
+package test_auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newRegisterService creates a user service backed by an in-memory store with the default user role and an existing user.
+func newRegisterService(t *testing.T) (service.UserService, repository.UserRepository) {
+	store := testsupport.UseMemoryDatabase(t)
+	_, err := store.AddRole(entity.Role{Name: service.DefaultUserRole})
+	require.NoError(t, err)
+	_, err = store.AddUser(entity.User{Username: "alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+
+	repo := repository.NewMemoryUserRepository(store)
+	return service.NewUserService(database.DefaultStore(), repo, repository.NewMemoryRoleRepository(store), nil, clock.New()), repo
+}
+
+func TestCreateUser_RegistersActiveUserWithDefaultRole(t *testing.T) {
+	s, repo := newRegisterService(t)
+
+	created, err := s.CreateUser(context.Background(), entity.RegisterRequest{
+		Username:  "bob",
+		Password:  "P@ssw0rd",
+		Email:     "bob@example.com",
+		Firstname: "Bob",
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, created.ID)
+	assert.Equal(t, entity.UserStateActive, created.State)
+	assert.Equal(t, entity.UserTypeUserAccount, created.UserType)
+
+	stored, err := repo.GetUserByUsername(context.Background(), nil, "bob")
+	require.NoError(t, err)
+	require.Len(t, stored.Roles, 1)
+	assert.Equal(t, service.DefaultUserRole, stored.Roles[0].Name)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte("P@ssw0rd")))
+}
+
+func TestCreateUser_RejectsTakenUsernameOrEmail(t *testing.T) {
+	s, _ := newRegisterService(t)
+
+	for name, req := range map[string]entity.RegisterRequest{
+		"username": {Username: "ALICE", Password: "P@ssw0rd", Email: "other@example.com", Firstname: "Alice"},
+		"email":    {Username: "alice2", Password: "P@ssw0rd", Email: "Alice@Example.com", Firstname: "Alice"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := s.CreateUser(context.Background(), req)
+			assert.ErrorIs(t, err, service.ErrUserAlreadyExists)
+		})
+	}
+}
+
+func TestCreateUser_RejectsWeakPassword(t *testing.T) {
+	s, repo := newRegisterService(t)
+
+	_, err := s.CreateUser(context.Background(), entity.RegisterRequest{Username: "bob", Password: "password", Email: "bob@example.com", Firstname: "Bob"})
+
+	var validationErrors validator.ValidationErrors
+	require.True(t, errors.As(err, &validationErrors), err)
+	assert.Equal(t, "password_complexity", validationErrors[0].Tag())
+
+	_, err = repo.GetUserByUsername(context.Background(), nil, "bob")
+	assert.Error(t, err)
+}
@@ -0,0 +1,230 @@
+package test_auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newSessionService creates a refresh token service with the given session policy,
+// backed by an in-memory store with a single user.
+func newSessionService(t *testing.T, policy policyconfig.SessionPolicy) (service.RefreshTokenService, repository.RefreshTokenRepository, *clock.FakeClock, int64) {
+	store := testsupport.UseMemoryDatabase(t)
+	user, err := store.AddUser(entity.User{Username: "alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+
+	repo := repository.NewMemoryRefreshTokenRepository(store)
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+
+	return service.NewRefreshTokenService(database.DefaultStore(), repo, policy, clk), repo, clk, user.ID
+}
+
+// sessionTokens returns the refresh tokens of the user, oldest first.
+func sessionTokens(t *testing.T, repo repository.RefreshTokenRepository, userID int64) []string {
+	tokens, err := repo.GetRefreshTokensByUserID(nil, userID)
+	require.NoError(t, err)
+
+	var names []string
+	for _, token := range tokens {
+		names = append(names, token.Token)
+	}
+	return names
+}
+
+func TestCreateRefreshToken_EvictsOldestSession(t *testing.T) {
+	s, repo, clk, userID := newSessionService(t, policyconfig.SessionPolicy{MaxSessions: 2, Mode: policyconfig.SessionLimitModeEvictOldest})
+
+	first, err := s.CreateRefreshToken(context.Background(), userID, entity.SessionDevice{})
+	require.NoError(t, err)
+	clk.Advance(time.Minute)
+	second, err := s.CreateRefreshToken(context.Background(), userID, entity.SessionDevice{})
+	require.NoError(t, err)
+	clk.Advance(time.Minute)
+	third, err := s.CreateRefreshToken(context.Background(), userID, entity.SessionDevice{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{second.Token, third.Token}, sessionTokens(t, repo, userID))
+	_, err = s.GetRefreshTokenByToken(context.Background(), first.Token)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestCreateRefreshToken_RejectsOverLimit(t *testing.T) {
+	s, repo, clk, userID := newSessionService(t, policyconfig.SessionPolicy{MaxSessions: 1, Mode: policyconfig.SessionLimitModeReject})
+
+	first, err := s.CreateRefreshToken(context.Background(), userID, entity.SessionDevice{})
+	require.NoError(t, err)
+
+	_, err = s.CreateRefreshToken(context.Background(), userID, entity.SessionDevice{})
+	assert.ErrorIs(t, err, service.ErrSessionLimitReached)
+	assert.Equal(t, []string{first.Token}, sessionTokens(t, repo, userID))
+
+	// Expired sessions do not count against the limit and are removed
+	clk.Advance(first.ExpiryDate.Sub(clk.Now()))
+	second, err := s.CreateRefreshToken(context.Background(), userID, entity.SessionDevice{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{second.Token}, sessionTokens(t, repo, userID))
+}
+
+func TestCreateRefreshToken_LocksTheSessionsOfTheUserBeforeCountingThem(t *testing.T) {
+	testsupport.UseMemoryDatabase(t)
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRefreshTokenRepository(ctrl)
+	s := service.NewRefreshTokenService(database.DefaultStore(), repo, policyconfig.SessionPolicy{MaxSessions: 1, Mode: policyconfig.SessionLimitModeReject}, clock.New())
+
+	// A user without any session has no row to lock, the sessions of the user are locked instead
+	gomock.InOrder(
+		repo.EXPECT().LockUserSessions(gomock.Any(), int64(7)).Return(nil),
+		repo.EXPECT().GetRefreshTokensByUserID(gomock.Any(), int64(7)).Return(nil, nil),
+		repo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).DoAndReturn(func(_ *gorm.DB, token entity.RefreshToken) (entity.RefreshToken, error) {
+			return token, nil
+		}),
+	)
+
+	_, err := s.CreateRefreshToken(context.Background(), 7, entity.SessionDevice{})
+	require.NoError(t, err)
+}
+
+func TestRotateRefreshToken_KeepsOtherSessions(t *testing.T) {
+	s, repo, clk, userID := newSessionService(t, policyconfig.SessionPolicy{MaxSessions: 2, Mode: policyconfig.SessionLimitModeReject})
+
+	web, err := s.CreateRefreshToken(context.Background(), userID, entity.SessionDevice{})
+	require.NoError(t, err)
+	clk.Advance(time.Minute)
+	phone, err := s.CreateRefreshToken(context.Background(), userID, entity.SessionDevice{})
+	require.NoError(t, err)
+
+	// Rotating a token at the limit replaces it without touching the other session
+	clk.Advance(time.Minute)
+	rotated, err := s.RotateRefreshToken(context.Background(), web)
+	require.NoError(t, err)
+	assert.Equal(t, userID, rotated.UserID)
+	assert.Equal(t, web.SessionID, rotated.SessionID)
+	assert.Equal(t, web.SessionStartedAt, rotated.SessionStartedAt)
+	assert.Equal(t, []string{phone.Token, rotated.Token}, sessionTokens(t, repo, userID))
+
+	// A token cannot be rotated twice
+	_, err = s.RotateRefreshToken(context.Background(), web)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestLoadSessionPolicy(t *testing.T) {
+	t.Setenv("MAX_SESSIONS_PER_USER", "")
+	t.Setenv("SESSION_LIMIT_MODE", "")
+	t.Setenv("JWT_REFRESH_TOKEN_EXPIRATION_HOUR", "")
+	assert.Equal(t, policyconfig.SessionPolicy{
+		MaxSessions:     policyconfig.DefaultMaxSessions,
+		Mode:            policyconfig.SessionLimitModeEvictOldest,
+		RefreshTokenTTL: policyconfig.DefaultRefreshTokenTTL,
+	}, policyconfig.LoadSessionPolicy())
+
+	t.Setenv("MAX_SESSIONS_PER_USER", "3")
+	t.Setenv("SESSION_LIMIT_MODE", "reject")
+	t.Setenv("JWT_REFRESH_TOKEN_EXPIRATION_HOUR", "48")
+	assert.Equal(t, policyconfig.SessionPolicy{
+		MaxSessions:     3,
+		Mode:            policyconfig.SessionLimitModeReject,
+		RefreshTokenTTL: 48 * time.Hour,
+	}, policyconfig.LoadSessionPolicy())
+
+	t.Setenv("MAX_SESSIONS_PER_USER", "-1")
+	t.Setenv("JWT_REFRESH_TOKEN_EXPIRATION_HOUR", "-1")
+	assert.Equal(t, policyconfig.DefaultMaxSessions, policyconfig.LoadSessionPolicy().MaxSessions)
+	assert.Equal(t, policyconfig.DefaultRefreshTokenTTL, policyconfig.LoadSessionPolicy().RefreshTokenTTL)
+}
+
+func TestCreateRefreshToken_ReplacesSessionOfDevice(t *testing.T) {
+	s, repo, clk, userID := newSessionService(t, policyconfig.SessionPolicy{MaxSessions: 2, Mode: policyconfig.SessionLimitModeReject})
+
+	web, err := s.CreateRefreshToken(context.Background(), userID, entity.SessionDevice{DeviceID: "web", UserAgent: "Firefox", IPAddress: "10.0.0.1"})
+	require.NoError(t, err)
+	clk.Advance(time.Minute)
+	phone, err := s.CreateRefreshToken(context.Background(), userID, entity.SessionDevice{DeviceID: "phone", ClientID: "mobile-app"})
+	require.NoError(t, err)
+	assert.NotEqual(t, web.SessionID, phone.SessionID)
+	assert.Equal(t, "Firefox", web.UserAgent)
+
+	// A new login from a device replaces its session, even at the limit
+	clk.Advance(time.Minute)
+	phoneAgain, err := s.CreateRefreshToken(context.Background(), userID, entity.SessionDevice{DeviceID: "phone", ClientID: "mobile-app"})
+	require.NoError(t, err)
+	assert.NotEqual(t, phone.SessionID, phoneAgain.SessionID)
+	assert.Equal(t, []string{web.Token, phoneAgain.Token}, sessionTokens(t, repo, userID))
+}
+
+func TestGetSessions_And_RemoveSession(t *testing.T) {
+	s, _, clk, userID := newSessionService(t, policyconfig.SessionPolicy{MaxSessions: 3, Mode: policyconfig.SessionLimitModeReject})
+
+	web, err := s.CreateRefreshToken(context.Background(), userID, entity.SessionDevice{DeviceID: "web", ClientID: "web-app", UserAgent: "Firefox", IPAddress: "10.0.0.1"})
+	require.NoError(t, err)
+	clk.Advance(time.Minute)
+	phone, err := s.CreateRefreshToken(context.Background(), userID, entity.SessionDevice{DeviceID: "phone"})
+	require.NoError(t, err)
+
+	sessions, err := s.GetSessions(context.Background(), userID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, entity.Session{
+		SessionID:    web.SessionID,
+		DeviceID:     "web",
+		ClientID:     "web-app",
+		UserAgent:    "Firefox",
+		IPAddress:    "10.0.0.1",
+		StartedAt:    web.CreatedAt,
+		LastActiveAt: web.CreatedAt,
+		ExpiresAt:    web.ExpiryDate,
+	}, sessions[0])
+	assert.Equal(t, phone.SessionID, sessions[1].SessionID)
+
+	// The sessions of another user cannot be removed
+	assert.ErrorIs(t, s.RemoveSession(context.Background(), userID+1, web.SessionID), gorm.ErrRecordNotFound)
+
+	require.NoError(t, s.RemoveSession(context.Background(), userID, web.SessionID))
+	_, err = s.GetRefreshTokenByToken(context.Background(), web.Token)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, s.RemoveSession(context.Background(), userID, web.SessionID), gorm.ErrRecordNotFound)
+
+	// The expired sessions are not listed
+	clk.Advance(phone.ExpiryDate.Sub(clk.Now()))
+	sessions, err = s.GetSessions(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestSessions_Handler(t *testing.T) {
+	s, _, _, userID := newSessionService(t, policyconfig.SessionPolicy{MaxSessions: 2, Mode: policyconfig.SessionLimitModeReject})
+	web, err := s.CreateRefreshToken(context.Background(), userID, entity.SessionDevice{DeviceID: "web"})
+	require.NoError(t, err)
+
+	h := handler.NewSessionHandler(s)
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/users/me/sessions", h.GetSessions)
+	router.DELETE("/api/v1/users/me/sessions/:sessionId", h.RemoveSession)
+	token := testsupport.NewTokenBuilder().WithUser(userID, "alice", "alice@example.com").WithRoles("ROLE_USER").Build(t)
+
+	w := testsupport.Do(router, "GET", "/api/v1/users/me/sessions", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), web.SessionID)
+	assert.NotContains(t, w.Body.String(), web.Token)
+
+	w = testsupport.Do(router, "DELETE", "/api/v1/users/me/sessions/unknown", token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = testsupport.Do(router, "DELETE", "/api/v1/users/me/sessions/"+web.SessionID, token)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
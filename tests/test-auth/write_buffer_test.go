@@ -0,0 +1,137 @@
+package test_auth
+
+import (
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+)
+
+// writeLog records the writes run by the buffer, failing the first ones with the given errors.
+type writeLog struct {
+	mu     sync.Mutex
+	errs   []error
+	writes []string
+}
+
+func (l *writeLog) write(name string) func() error {
+	return func() error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		l.writes = append(l.writes, name)
+		if len(l.errs) == 0 {
+			return nil
+		}
+		err := l.errs[0]
+		l.errs = l.errs[1:]
+		return err
+	}
+}
+
+func (l *writeLog) runs() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.writes...)
+}
+
+func newWriteBuffer(size int, timeout time.Duration, clk clock.Clock) *service.WriteBuffer {
+	// The retry interval is long enough that only Close retries the pending writes
+	return service.NewWriteBuffer(policyconfig.WriteBufferPolicy{Enabled: true, Size: size, Timeout: timeout, RetryInterval: time.Hour}, clk)
+}
+
+func TestWriteBuffer_RetriesTransientFailuresInOrder(t *testing.T) {
+	b := newWriteBuffer(10, time.Minute, clock.New())
+	log := &writeLog{errs: []error{driver.ErrBadConn}}
+
+	assert.NoError(t, b.Write("first", nil, log.write("first")))
+	assert.NoError(t, b.Write("second", nil, log.write("second")))
+	assert.Equal(t, 2, b.Pending())
+	assert.Equal(t, []string{"first"}, log.runs(), "a new write waits for the pending ones")
+
+	b.Close()
+	b.Close()
+	assert.Equal(t, 0, b.Pending())
+	assert.Equal(t, []string{"first", "first", "second"}, log.runs())
+}
+
+func TestWriteBuffer_ReturnsOtherErrors(t *testing.T) {
+	b := newWriteBuffer(10, time.Minute, clock.New())
+	defer b.Close()
+	log := &writeLog{errs: []error{errors.New("constraint violation")}}
+
+	assert.EqualError(t, b.Write("write", nil, log.write("write")), "constraint violation")
+	assert.Equal(t, 0, b.Pending())
+}
+
+func TestWriteBuffer_ReturnsTheErrorWhenFull(t *testing.T) {
+	b := newWriteBuffer(1, time.Minute, clock.New())
+	defer b.Close()
+	log := &writeLog{errs: []error{driver.ErrBadConn, driver.ErrBadConn}}
+
+	assert.NoError(t, b.Write("first", nil, log.write("first")))
+	err := b.Write("second", nil, func() error { return driver.ErrBadConn })
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.Equal(t, 1, b.Pending())
+}
+
+func TestWriteBuffer_DropsExpiredWrites(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	b := newWriteBuffer(10, time.Minute, clk)
+	log := &writeLog{errs: []error{driver.ErrBadConn}}
+
+	assert.NoError(t, b.Write("write", nil, log.write("write")))
+	clk.Advance(time.Minute)
+
+	b.Close()
+	assert.Equal(t, []string{"write"}, log.runs(), "an expired write is not run again")
+	assert.Equal(t, 0, b.Pending())
+}
+
+func TestWriteBuffer_RetriesInTheBackground(t *testing.T) {
+	b := service.NewWriteBuffer(policyconfig.WriteBufferPolicy{Enabled: true, RetryInterval: 10 * time.Millisecond}, clock.New())
+	defer b.Close()
+	log := &writeLog{errs: []error{driver.ErrBadConn}}
+
+	assert.NoError(t, b.Write("write", nil, log.write("write")))
+	assert.Eventually(t, func() bool { return b.Pending() == 0 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"write", "write"}, log.runs())
+}
+
+func TestWriteBuffer_DisabledRunsTheWritesOnce(t *testing.T) {
+	b := service.NewWriteBuffer(policyconfig.WriteBufferPolicy{}, clock.New())
+	assert.Nil(t, b)
+
+	assert.ErrorIs(t, b.Write("write", nil, func() error { return driver.ErrBadConn }), driver.ErrBadConn)
+	assert.Equal(t, 0, b.Pending())
+	b.Close()
+}
+
+func TestAsyncLastLoginRecorder_BuffersTheFailedUpdates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserService(ctrl)
+	lastLogin := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	b := newWriteBuffer(10, time.Minute, clock.New())
+	r := service.NewAsyncLastLoginRecorder(users, b, time.Hour, 0)
+
+	gomock.InOrder(
+		users.EXPECT().UpdateLastLogin(gomock.Any(), int64(1), lastLogin).Return(false, driver.ErrBadConn),
+		users.EXPECT().UpdateLastLogin(gomock.Any(), int64(1), lastLogin).Return(true, nil),
+	)
+
+	r.Record(1, lastLogin)
+	r.Close()
+	assert.Equal(t, 1, b.Pending())
+
+	b.Close()
+	assert.Equal(t, 0, b.Pending())
+}
@@ -0,0 +1,86 @@
+package test_authorization
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	jwtutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+func TestAudienceIssuer_TokensOfOtherServicesRejected(t *testing.T) {
+	router := newSignedRouter(testsupport.NewJWTConfig(), clock.New())
+
+	cases := []struct {
+		name   string
+		token  *testsupport.TokenBuilder
+		status int
+	}{
+		{"same audience and issuer", testsupport.NewTokenBuilder(), http.StatusOK},
+		{"audience among others", testsupport.NewTokenBuilder().WithClaim("aud", []string{"billing", testsupport.DefaultAudience}), http.StatusOK},
+		{"other audience", testsupport.NewTokenBuilder().WithClaim("aud", "billing"), http.StatusUnauthorized},
+		{"other issuer", testsupport.NewTokenBuilder().WithClaim("iss", "https://billing.example.com"), http.StatusUnauthorized},
+		{"missing audience", testsupport.NewTokenBuilder().WithoutClaim("aud"), http.StatusUnauthorized},
+		{"missing issuer", testsupport.NewTokenBuilder().WithoutClaim("iss"), http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.status, testsupport.Do(router, "GET", "/protected", tc.token.Build(t)).Code)
+		})
+	}
+}
+
+func TestAudienceIssuer_NotCheckedWhenNotConfigured(t *testing.T) {
+	cfg := testsupport.NewJWTConfig()
+	cfg.Audience = ""
+	cfg.Issuer = ""
+	router := newSignedRouter(cfg, clock.New())
+
+	token := testsupport.NewTokenBuilder().WithClaim("aud", "billing").WithoutClaim("iss").Build(t)
+	assert.Equal(t, http.StatusOK, testsupport.Do(router, "GET", "/protected", token).Code)
+}
+
+func TestAudienceIssuer_IssuedTokens(t *testing.T) {
+	cfg := testsupport.NewJWTConfig()
+	now := time.Now()
+	user := entity.User{ID: 1, Username: "admin", Email: "admin@example.com", Roles: []entity.Role{{Name: "ROLE_ADMIN"}}}
+
+	tokenIDs := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		token, err := service.GenerateJWTToken(cfg, user, "", now)
+		require.NoError(t, err)
+		parsed, err := service.ParseJWTToken(cfg, token, now)
+		require.NoError(t, err)
+
+		claims := parsed.Claims.(jwt.MapClaims)
+		assert.Equal(t, testsupport.DefaultAudience, claims["aud"])
+		assert.Equal(t, testsupport.DefaultIssuer, claims["iss"])
+
+		// Every token has its own identifier, so that it can be revoked on its own
+		tokenID, err := jwtutil.GetStringClaim(claims, "jti")
+		require.NoError(t, err)
+		assert.NotEmpty(t, tokenID)
+		tokenIDs[tokenID] = true
+
+		assert.Equal(t, http.StatusOK, testsupport.Do(newSignedRouter(cfg, clock.New()), "GET", "/protected", token).Code)
+	}
+	assert.Len(t, tokenIDs, 2)
+
+	// A service with another audience rejects them
+	other := cfg
+	other.Audience = "billing"
+	token, err := service.GenerateJWTToken(cfg, user, "", now)
+	require.NoError(t, err)
+	_, err = service.ParseJWTToken(other, token, now)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, testsupport.Do(newSignedRouter(other, clock.New()), "GET", "/protected", token).Code)
+}
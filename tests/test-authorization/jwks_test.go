@@ -0,0 +1,121 @@
+package test_authorization
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jwtconfig "github.com/yoanesber/go-jwt-auth-demo/config/jwt-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	jwtutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newRS256Router creates a router with a single route protected by the JWT validation middleware verifying RS256 tokens.
+func newRS256Router() *gin.Engine {
+	cfg := testsupport.NewJWTConfig()
+	cfg.SigningMethod = "RS256"
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", authorization.JwtValidationWithConfig(cfg, clock.New()), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	return router
+}
+
+func TestGetJWKS(t *testing.T) {
+	key := testsupport.GenerateRSAKeyPair(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/.well-known/jwks.json", handler.NewSigningKeyHandler(service.NewSigningKeyService(testsupport.NewJWTConfig(), 0, clock.New())).GetJWKS)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, handler.JWKSCacheControl, w.Header().Get("Cache-Control"))
+
+	var keySet jwtutil.JSONWebKeySet
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &keySet))
+	require.Len(t, keySet.Keys, 1)
+
+	jwk := keySet.Keys[0]
+	assert.Equal(t, "RSA", jwk.Kty)
+	assert.Equal(t, "sig", jwk.Use)
+	assert.Equal(t, "RS256", jwk.Alg)
+	assert.Equal(t, jwtutil.KeyID(&key.PublicKey), jwk.Kid)
+
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	require.NoError(t, err)
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	require.NoError(t, err)
+	assert.Equal(t, 0, key.PublicKey.N.Cmp(new(big.Int).SetBytes(n)))
+	assert.Equal(t, int64(key.PublicKey.E), new(big.Int).SetBytes(e).Int64())
+}
+
+func TestKeyID(t *testing.T) {
+	first := testsupport.GenerateRSAKeyPair(t)
+	second := testsupport.GenerateRSAKeyPair(t)
+
+	assert.Equal(t, jwtutil.KeyID(&first.PublicKey), jwtutil.KeyID(&first.PublicKey))
+	assert.NotEqual(t, jwtutil.KeyID(&first.PublicKey), jwtutil.KeyID(&second.PublicKey))
+	assert.NotContains(t, jwtutil.KeyID(&first.PublicKey), "=")
+}
+
+func TestJwtValidation_SelectsKeyByKid(t *testing.T) {
+	key := testsupport.GenerateRSAKeyPair(t)
+	router := newRS256Router()
+	do := func(token string) int {
+		return testsupport.Do(router, "GET", "/protected", token).Code
+	}
+
+	assert.Equal(t, http.StatusOK, do(testsupport.NewTokenBuilder().WithRSAKey(key).WithKeyID(jwtutil.KeyID(&key.PublicKey)).Build(t)))
+
+	// The tokens issued before the keys were identified are verified with the current key
+	assert.Equal(t, http.StatusOK, do(testsupport.NewTokenBuilder().WithRSAKey(key).Build(t)))
+
+	// A token identifying a key that is not published is rejected, even with a valid signature
+	w := testsupport.Do(router, "GET", "/protected", testsupport.NewTokenBuilder().WithRSAKey(key).WithKeyID("replaced-key").Build(t))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), jwtutil.ErrUnknownKeyID.Error())
+}
+
+func TestGenerateJWTTokenWithRS256_SetsKid(t *testing.T) {
+	key := testsupport.GenerateRSAKeyPair(t)
+	cfg := testsupport.NewJWTConfig()
+	cfg.SigningMethod = "RS256"
+	user := entity.User{ID: 1, Username: "admin", Email: "admin@example.com", Roles: []entity.Role{{Name: "ROLE_ADMIN"}}}
+
+	tokenStr, err := service.GenerateJWTTokenWithRS256(cfg, user, "", time.Now())
+	require.NoError(t, err)
+
+	header, err := base64.RawURLEncoding.DecodeString(strings.Split(tokenStr, ".")[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(header), `"kid":"`+jwtutil.KeyID(&key.PublicKey)+`"`)
+
+	token, err := service.ParseJWTToken(cfg, tokenStr, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, jwtutil.KeyID(&key.PublicKey), token.Header["kid"])
+
+	// A token signed with a replaced key pair is not verified by the current key
+	testsupport.GenerateRSAKeyPair(t)
+	cfg.Keys = jwtconfig.LoadKeyFiles()
+	_, err = service.ParseJWTToken(cfg, tokenStr, time.Now())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), jwtutil.ErrUnknownKeyID.Error())
+}
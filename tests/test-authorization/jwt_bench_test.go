@@ -0,0 +1,49 @@
+package test_authorization
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// BenchmarkJwtValidation measures the JWT validation middleware for a valid HS256 token.
+func BenchmarkJwtValidation(b *testing.B) {
+	router := newProtectedRouter(b)
+	header := testsupport.DefaultTokenType + " " + testsupport.NewTokenBuilder().Build(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", header)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			b.Fatalf("unexpected status %d", w.Code)
+		}
+	}
+}
+
+// BenchmarkJwtValidation_RS256 measures the JWT validation middleware for a valid RS256 token,
+// which includes loading the public key on every request.
+func BenchmarkJwtValidation_RS256(b *testing.B) {
+	router := newProtectedRouter(b)
+	key := testsupport.GenerateRSAKeyPair(b)
+	header := testsupport.DefaultTokenType + " " + testsupport.NewTokenBuilder().WithRSAKey(key).Build(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", header)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			b.Fatalf("unexpected status %d", w.Code)
+		}
+	}
+}
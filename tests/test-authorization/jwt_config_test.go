@@ -0,0 +1,54 @@
+package test_authorization
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// TestJwtValidationWithConfig_Isolated checks that middleware built with different settings
+// do not share any state, so tests using them can run in parallel.
+func TestJwtValidationWithConfig_Isolated(t *testing.T) {
+	secrets := []string{
+		"first-secret-that-is-at-least-256-bits-long",
+		"second-secret-that-is-at-least-256-bits-long",
+	}
+
+	for i, secret := range secrets {
+		other := secrets[(i+1)%len(secrets)]
+
+		t.Run(secret, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := testsupport.NewJWTConfig()
+			cfg.Secret = secret
+			cfg.TokenType = "Token"
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.GET("/protected", authorization.JwtValidationWithConfig(cfg, clock.New()), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			do := func(tokenSecret string) int {
+				req := httptest.NewRequest("GET", "/protected", nil)
+				req.Header.Set("Authorization", "Token "+testsupport.NewTokenBuilder().WithSecret(tokenSecret).Build(t))
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				return w.Code
+			}
+
+			for n := 0; n < 50; n++ {
+				assert.Equal(t, http.StatusOK, do(secret))
+				assert.Equal(t, http.StatusUnauthorized, do(other))
+			}
+		})
+	}
+}
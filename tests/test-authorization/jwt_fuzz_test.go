@@ -0,0 +1,124 @@
+package test_authorization
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	jwtutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newProtectedRouter creates a router with a single route protected by the JWT validation middleware.
+// Since gin.New does not install the recovery middleware, a panic in the middleware fails the test.
+func newProtectedRouter(t testing.TB) *gin.Engine {
+	router := testsupport.NewRouter(t)
+	router.GET("/protected", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	return router
+}
+
+// seedTokens returns valid and malformed tokens used as the seed corpus of the fuzz targets.
+func seedTokens(t testing.TB) []string {
+	valid := testsupport.NewTokenBuilder().Build(t)
+	parts := strings.Split(valid, ".")
+
+	return []string{
+		valid,
+		valid[:len(valid)/2],
+		parts[0] + "." + parts[1],
+		parts[0] + "." + parts[1] + ".",
+		"..",
+		"not-a-token",
+		testsupport.NewTokenBuilder().Expired().Build(t),
+		testsupport.NewTokenBuilder().WithSecret("another-secret-that-is-long-enough-32b").Build(t),
+		testsupport.NewTokenBuilder().WithAlgorithm(jwt.SigningMethodNone).Build(t),
+		testsupport.NewTokenBuilder().WithAlgorithm(jwt.SigningMethodHS512).Build(t),
+		testsupport.NewTokenBuilder().WithoutClaim("username").Build(t),
+		testsupport.NewTokenBuilder().WithoutClaim("email").WithoutClaim("userid").WithoutClaim("roles").Build(t),
+		testsupport.NewTokenBuilder().WithClaim("username", 42).WithClaim("email", []string{"x"}).Build(t),
+		testsupport.NewTokenBuilder().WithClaim("roles", "ROLE_ADMIN").WithClaim("userid", "1").Build(t),
+		testsupport.NewTokenBuilder().WithClaim("exp", "tomorrow").Build(t),
+		// RS256 header with an HS256 signature
+		`eyJhbGciOiJSUzI1NiIsInR5cCI6IkpXVCJ9.` + parts[1] + "." + parts[2],
+	}
+}
+
+func TestJwtValidation_MissingUsernameClaim(t *testing.T) {
+	router := newProtectedRouter(t)
+	token := testsupport.NewTokenBuilder().WithoutClaim("username").Build(t)
+
+	w := testsupport.Do(router, "GET", "/protected", token)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestJwtValidation_InvalidClaimTypes(t *testing.T) {
+	router := newProtectedRouter(t)
+	token := testsupport.NewTokenBuilder().WithClaim("username", 42).WithClaim("email", 42).Build(t)
+
+	w := testsupport.Do(router, "GET", "/protected", token)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// FuzzJwtValidation feeds arbitrary Authorization headers into the JWT validation middleware.
+// The middleware must never panic and must either let the request through or respond with a clean 401.
+func FuzzJwtValidation(f *testing.F) {
+	router := newProtectedRouter(f)
+
+	for _, token := range seedTokens(f) {
+		f.Add(testsupport.DefaultTokenType + " " + token)
+	}
+	f.Add("")
+	f.Add(testsupport.DefaultTokenType + " ")
+	f.Add("Basic YWRtaW46UEBzc3cwcmQ=")
+
+	f.Fuzz(func(t *testing.T, header string) {
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", header)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK && w.Code != http.StatusUnauthorized {
+			t.Fatalf("unexpected status %d for header %q", w.Code, header)
+		}
+		if w.Code == http.StatusUnauthorized {
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("401 response is not valid JSON: %v", err)
+			}
+		}
+	})
+}
+
+// FuzzClaimExtractors feeds arbitrary JSON claims into the claim extractors, which must never panic.
+func FuzzClaimExtractors(f *testing.F) {
+	f.Add(`{"userid":1,"username":"admin","email":"admin@example.com","roles":["ROLE_ADMIN"]}`)
+	f.Add(`{"userid":"1","username":42,"email":null,"roles":"ROLE_ADMIN"}`)
+	f.Add(`{"roles":[1,null,{"a":"b"},"ROLE_USER"]}`)
+	f.Add(`{"userid":1e309}`)
+	f.Add(`{}`)
+
+	f.Fuzz(func(t *testing.T, payload string) {
+		var claims jwt.MapClaims
+		if err := json.Unmarshal([]byte(payload), &claims); err != nil {
+			t.Skip()
+		}
+
+		for _, key := range []string{"userid", "username", "email", "roles"} {
+			_, _ = jwtutil.GetInt64Claim(claims, key)
+			_, _ = jwtutil.GetStringClaim(claims, key)
+			_ = jwtutil.GetStringSliceClaim(claims, key)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package test_authorization
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	jwtutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// useKeySetDir points JWT_KEYSET_DIR to an empty directory for the duration of the test.
+func useKeySetDir(t *testing.T) string {
+	dir := filepath.Join(t.TempDir(), "keys")
+	t.Setenv("JWT_KEYSET_DIR", dir)
+	return dir
+}
+
+// keyFiles returns the names of the key files of the directory.
+func keyFiles(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := useKeySetDir(t)
+	clk := clock.NewFakeClock(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	cfg := testsupport.NewJWTConfig()
+	cfg.SigningMethod = "RS256"
+	keys := service.NewSigningKeyService(cfg, 0, clk)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", authorization.JwtValidationWithConfig(cfg, clk), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	user := entity.User{ID: 1, Username: "admin", Email: "admin@example.com", Roles: []entity.Role{{Name: "ROLE_ADMIN"}}}
+	issue := func() string {
+		token, err := service.GenerateJWTTokenWithRS256(cfg, user, "", clk.Now())
+		require.NoError(t, err)
+		return token
+	}
+
+	// The first key is created at startup
+	require.NoError(t, keys.EnsureKey())
+	first, err := cfg.Keys.LoadSigningKey()
+	require.NoError(t, err)
+	oldToken := issue()
+
+	clk.Advance(10 * time.Minute)
+	rotation, err := keys.RotateKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, rotation.KeyID)
+	assert.Equal(t, first.ID, rotation.PreviousKeyID)
+	assert.Equal(t, clk.Now().Add(time.Hour), *rotation.PreviousKeyExpiresAt)
+
+	// The new tokens are signed with the new key, the tokens of the previous key are still valid
+	newToken := issue()
+	parsed, err := service.ParseJWTToken(cfg, newToken, clk.Now())
+	require.NoError(t, err)
+	assert.Equal(t, rotation.KeyID, parsed.Header["kid"])
+	assert.Equal(t, http.StatusOK, testsupport.Do(router, "GET", "/protected", oldToken).Code)
+	assert.Equal(t, http.StatusOK, testsupport.Do(router, "GET", "/protected", newToken).Code)
+
+	keySet, err := keys.GetKeySet()
+	require.NoError(t, err)
+	require.Len(t, keySet.Keys, 2)
+	assert.Equal(t, rotation.KeyID, keySet.Keys[0].Kid)
+	assert.Equal(t, first.ID, keySet.Keys[1].Kid)
+
+	// Once the longest token lifetime has elapsed, the previous key is no longer published nor trusted
+	clk.Advance(time.Hour)
+	keySet, err = keys.GetKeySet()
+	require.NoError(t, err)
+	require.Len(t, keySet.Keys, 1)
+	_, err = cfg.Keys.LoadPublicKeyByID(first.ID, cfg.TTL.Max(), clk.Now())
+	assert.ErrorIs(t, err, jwtutil.ErrUnknownKeyID)
+
+	// The next rotation deletes it
+	assert.Len(t, keyFiles(t, dir), 2)
+	_, err = keys.RotateKey()
+	require.NoError(t, err)
+	assert.Len(t, keyFiles(t, dir), 2)
+}
+
+func TestEnsureKey_RotatesOldKey(t *testing.T) {
+	useKeySetDir(t)
+	clk := clock.NewFakeClock(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	cfg := testsupport.NewJWTConfig()
+	keys := service.NewSigningKeyService(cfg, 30*24*time.Hour, clk)
+
+	require.NoError(t, keys.EnsureKey())
+	first, err := cfg.Keys.LoadSigningKey()
+	require.NoError(t, err)
+
+	// The key is kept until it reaches the rotation interval
+	clk.Advance(29 * 24 * time.Hour)
+	require.NoError(t, keys.EnsureKey())
+	current, err := cfg.Keys.LoadSigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, current.ID)
+
+	clk.Advance(24 * time.Hour)
+	require.NoError(t, keys.EnsureKey())
+	current, err = cfg.Keys.LoadSigningKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, current.ID)
+}
+
+func TestRotateKey_WithoutKeySetDir(t *testing.T) {
+	key := testsupport.GenerateRSAKeyPair(t)
+	t.Setenv("JWT_KEYSET_DIR", "")
+	cfg := testsupport.NewJWTConfig()
+	keys := service.NewSigningKeyService(cfg, 0, clock.New())
+
+	_, err := keys.RotateKey()
+	assert.ErrorIs(t, err, jwtutil.ErrKeyRotationDisabled)
+
+	// The single key pair is used as it is
+	require.NoError(t, keys.EnsureKey())
+	current, err := cfg.Keys.LoadSigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, jwtutil.KeyID(&key.PublicKey), current.ID)
+}
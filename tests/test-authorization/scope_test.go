@@ -0,0 +1,92 @@
+package test_authorization
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	jwtutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+func TestRequireScope(t *testing.T) {
+	router := testsupport.NewRouter(t)
+	router.POST("/consumers", authorization.RequireScope(entity.PermissionConsumersWrite), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.DELETE("/consumers", authorization.RequireScope(entity.PermissionConsumersRead, entity.PermissionConsumersWrite), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		name   string
+		method string
+		scopes []string
+		status int
+	}{
+		{"granted scope", "POST", []string{entity.PermissionConsumersRead, entity.PermissionConsumersWrite}, http.StatusOK},
+		{"missing scope", "POST", []string{entity.PermissionConsumersRead}, http.StatusForbidden},
+		{"no scopes", "POST", nil, http.StatusForbidden},
+		{"all required scopes", "DELETE", []string{entity.PermissionConsumersRead, entity.PermissionConsumersWrite}, http.StatusOK},
+		{"one of the required scopes", "DELETE", []string{entity.PermissionConsumersWrite}, http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			token := testsupport.NewTokenBuilder()
+			if tc.scopes != nil {
+				token.WithClaim("scopes", tc.scopes)
+			}
+			assert.Equal(t, tc.status, testsupport.Do(router, tc.method, "/consumers", token.Build(t)).Code)
+		})
+	}
+}
+
+func TestRequireScope_IndependentOfTheRoles(t *testing.T) {
+	router := testsupport.NewRouter(t)
+	router.POST("/consumers", authorization.RequireScope(entity.PermissionConsumersWrite), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// A custom role granted the permission gets the access of the administrators on the route
+	token := testsupport.NewTokenBuilder().WithRoles("ROLE_BILLING").WithClaim("scopes", []string{entity.PermissionConsumersWrite}).Build(t)
+	assert.Equal(t, http.StatusOK, testsupport.Do(router, "POST", "/consumers", token).Code)
+}
+
+func TestJWTTokenIssuer_IssuesTheScopesOfTheRoles(t *testing.T) {
+	store := testsupport.UseMemoryDatabase(t)
+	require.NoError(t, store.Seed())
+	roles := service.NewRoleService(database.DefaultStore(), repository.NewMemoryRoleRepository(store), repository.NewMemoryPermissionRepository(store))
+	cfg := testsupport.NewJWTConfig()
+	issuer := service.NewJWTTokenIssuer(cfg, service.WithScopeResolver(roles))
+
+	scopesOf := func(roleNames ...string) []string {
+		user := entity.User{ID: 1, Username: "admin", Email: "admin@example.com"}
+		for _, name := range roleNames {
+			user.Roles = append(user.Roles, entity.Role{Name: name})
+		}
+
+		issued, err := issuer.IssueToken(user, "", time.Now())
+		require.NoError(t, err)
+		token, err := service.ParseJWTToken(cfg, issued.AccessToken, time.Now())
+		require.NoError(t, err)
+
+		return jwtutil.GetStringSliceClaim(token.Claims.(jwt.MapClaims), "scopes")
+	}
+
+	assert.Equal(t, []string{entity.PermissionConsumersRead}, scopesOf(entity.RoleUser))
+	assert.Equal(t, []string{entity.PermissionConsumersRead, entity.PermissionUsersRead}, scopesOf(entity.RoleUser, entity.RoleModerator))
+	assert.Len(t, scopesOf(entity.RoleAdmin), 6)
+	assert.Nil(t, scopesOf("ROLE_UNKNOWN"))
+
+	// The tokens of the issuers without a resolver do not carry scopes
+	issued, err := service.NewJWTTokenIssuer(cfg).IssueToken(entity.User{ID: 1, Username: "admin", Roles: []entity.Role{{Name: entity.RoleAdmin}}}, "", time.Now())
+	require.NoError(t, err)
+	token, err := service.ParseJWTToken(cfg, issued.AccessToken, time.Now())
+	require.NoError(t, err)
+	assert.NotContains(t, token.Claims.(jwt.MapClaims), "scopes")
+}
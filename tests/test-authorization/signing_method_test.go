@@ -0,0 +1,140 @@
+package test_authorization
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jwtconfig "github.com/yoanesber/go-jwt-auth-demo/config/jwt-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newSignedRouter creates a router with a route protected by the JWT middleware built with the given settings.
+func newSignedRouter(cfg jwtconfig.JWTConfig, clk clock.Clock) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", authorization.JwtValidationWithConfig(cfg, clk), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestSigningMethods_RoundTrip(t *testing.T) {
+	user := entity.User{ID: 1, Username: "admin", Email: "admin@example.com", Roles: []entity.Role{{Name: "ROLE_ADMIN"}}}
+
+	cases := []struct {
+		method   string
+		generate func(t *testing.T)
+	}{
+		{"ES256", func(t *testing.T) { testsupport.GenerateECKeyPair(t, elliptic.P256()) }},
+		{"EdDSA", func(t *testing.T) { testsupport.GenerateEd25519KeyPair(t) }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.method, func(t *testing.T) {
+			tc.generate(t)
+			clk := clock.NewFakeClock(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+			cfg := testsupport.NewJWTConfig()
+			cfg.SigningMethod = tc.method
+
+			// The tokens issued by the auth service are verified by the service and the middleware
+			token, err := service.GenerateJWTToken(cfg, user, "", clk.Now())
+			require.NoError(t, err)
+			parsed, err := service.ParseJWTToken(cfg, token, clk.Now())
+			require.NoError(t, err)
+			assert.Equal(t, tc.method, parsed.Method.Alg())
+			assert.Equal(t, http.StatusOK, testsupport.Do(newSignedRouter(cfg, clk), "GET", "/protected", token).Code)
+
+			// Once expired, the token is rejected, but its signature is still verified to read its claims
+			clk.Advance(2 * time.Hour)
+			_, err = service.ParseJWTToken(cfg, token, clk.Now())
+			assert.ErrorContains(t, err, "token is expired")
+			_, err = service.ParseExpiredJWTToken(cfg, token)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSigningMethods_RejectOtherMethods(t *testing.T) {
+	key := testsupport.GenerateECKeyPair(t, elliptic.P256())
+	cfg := testsupport.NewJWTConfig()
+	cfg.SigningMethod = "ES256"
+	router := newSignedRouter(cfg, clock.New())
+
+	assert.Equal(t, http.StatusOK, testsupport.Do(router, "GET", "/protected", testsupport.NewTokenBuilder().WithECKey(key).Build(t)).Code)
+
+	// The tokens signed with another method are rejected, even with a key the middleware knows
+	hs256 := testsupport.NewTokenBuilder().Build(t)
+	assert.Equal(t, http.StatusUnauthorized, testsupport.Do(router, "GET", "/protected", hs256).Code)
+	_, err := service.ParseJWTToken(cfg, hs256, time.Now())
+	assert.Error(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	eddsa := testsupport.NewTokenBuilder().WithEdKey(edKey).Build(t)
+	assert.Equal(t, http.StatusUnauthorized, testsupport.Do(router, "GET", "/protected", eddsa).Code)
+
+	// A token signed with another key is rejected
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	other := testsupport.NewTokenBuilder().WithECKey(otherKey).Build(t)
+	assert.Equal(t, http.StatusUnauthorized, testsupport.Do(router, "GET", "/protected", other).Code)
+}
+
+func TestKeyLoaders(t *testing.T) {
+	// ES256 requires the P-256 curve
+	testsupport.GenerateECKeyPair(t, elliptic.P384())
+	keys := jwtconfig.LoadKeyFiles()
+	_, err := keys.LoadECPrivateKey()
+	assert.ErrorContains(t, err, "P-256")
+	_, err = keys.LoadECPublicKey()
+	assert.ErrorContains(t, err, "P-256")
+
+	ecKey := testsupport.GenerateECKeyPair(t, elliptic.P256())
+	keys = jwtconfig.LoadKeyFiles()
+	publicKey, err := keys.LoadECPublicKey()
+	require.NoError(t, err)
+	assert.True(t, ecKey.PublicKey.Equal(publicKey))
+
+	// The keys of another kind are rejected
+	_, err = keys.LoadEdPublicKey()
+	assert.Error(t, err)
+	_, err = keys.LoadPublicKey()
+	assert.Error(t, err)
+
+	edKey := testsupport.GenerateEd25519KeyPair(t)
+	keys = jwtconfig.LoadKeyFiles()
+	privateKey, err := keys.LoadEdPrivateKey()
+	require.NoError(t, err)
+	assert.True(t, edKey.Equal(privateKey))
+	_, err = keys.LoadECPrivateKey()
+	assert.Error(t, err)
+}
+
+func TestParseJWTToken_RejectsOtherAlgorithmsOfTheSameFamily(t *testing.T) {
+	cfg := testsupport.NewJWTConfig()
+	now := time.Now()
+
+	// A token signed with the secret of the settings, but with HS384 instead of HS256, is rejected
+	hs384 := testsupport.NewTokenBuilder().WithAlgorithm(jwt.SigningMethodHS384).Build(t)
+	_, err := service.ParseJWTToken(cfg, hs384, now)
+	assert.ErrorContains(t, err, "signing method HS384 is invalid")
+	_, err = service.ParseExpiredJWTToken(cfg, hs384)
+	assert.ErrorContains(t, err, "signing method HS384 is invalid")
+
+	_, err = service.ParseJWTToken(cfg, testsupport.NewTokenBuilder().Build(t), now)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,113 @@
+package test_basepath
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/docs"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/basepath"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// useBasePath sets the base path of the service for the duration of the test.
+func useBasePath(t *testing.T, path string) {
+	t.Helper()
+
+	basepath.Set(path)
+	t.Cleanup(func() { basepath.Set("") })
+}
+
+// newRouter creates a router with its routes at the root, mounted under the base path.
+func newRouter() http.Handler {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/ping", func(c *gin.Context) {
+		httputil.Success(c, "pong", nil)
+	})
+	router.GET("/readyz", func(c *gin.Context) {
+		httputil.Success(c, "Service is ready", nil)
+	})
+
+	return basepath.Handler(router)
+}
+
+// get sends a GET request to the handler and decodes the response envelope.
+func get(t *testing.T, h http.Handler, path string) (int, httputil.HttpResponse) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var resp httputil.HttpResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w.Code, resp
+}
+
+func TestNormalize(t *testing.T) {
+	for input, want := range map[string]string{
+		"":             "",
+		"/":            "",
+		"auth-svc":     "/auth-svc",
+		"/auth-svc/":   "/auth-svc",
+		" /a/b ":       "/a/b",
+		"/api/auth/v2": "/api/auth/v2",
+	} {
+		got, err := basepath.Normalize(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"/auth-svc?x=1", "/auth-svc#top", "/:id", "/*any", "/a//b"} {
+		_, err := basepath.Normalize(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestHandler_AcceptsRequestsWithAndWithoutTheBasePath(t *testing.T) {
+	useBasePath(t, "/auth-svc")
+	h := newRouter()
+
+	// Forwarded with the prefix
+	status, resp := get(t, h, "/auth-svc/api/v1/ping")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "/auth-svc/api/v1/ping", resp.Path)
+
+	// Forwarded by a gateway stripping the prefix, or probed directly
+	status, resp = get(t, h, "/readyz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "/auth-svc/readyz", resp.Path)
+
+	// A path merely starting with the same characters is not under the base path
+	status, _ = get(t, h, "/auth-svcx/api/v1/ping")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestHandler_WithoutBasePath(t *testing.T) {
+	h := newRouter()
+
+	status, resp := get(t, h, "/api/v1/ping")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "/api/v1/ping", resp.Path)
+}
+
+func TestOpenAPIHandler_ServerURLIsTheBasePath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/openapi.yaml", handler.NewOpenAPIHandler(docs.OpenAPI, "/auth-svc").GetOpenAPI)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, handler.OpenAPIContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "servers:\n  - url: /auth-svc\n")
+	assert.NotContains(t, w.Body.String(), "servers:\n  - url: /\n")
+}
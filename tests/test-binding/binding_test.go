@@ -0,0 +1,62 @@
+package test_binding
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	bindingutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/binding-util"
+)
+
+// newContext creates a gin context for a request with the given JSON body.
+func newContext(body string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/consumers", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c
+}
+
+func TestBindJSONStrict(t *testing.T) {
+	var consumer entity.Consumer
+	err := bindingutil.BindJSONStrict(newContext(`{"fullname": "John Doe", "phone": "+6281234567890", "birthDate": "1990-05-17"}`), &consumer)
+
+	require.NoError(t, err)
+	assert.Equal(t, "John Doe", consumer.Fullname)
+	assert.Equal(t, "+6281234567890", consumer.Phone)
+}
+
+func TestBindJSONStrict_RejectsUnknownField(t *testing.T) {
+	var consumer entity.Consumer
+	err := bindingutil.BindJSONStrict(newContext(`{"fullname": "John Doe", "phonee": "+6281234567890"}`), &consumer)
+
+	var unknown *bindingutil.UnknownFieldError
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, "phonee", unknown.Field)
+	assert.Equal(t, []map[string]string{{"field": "phonee", "message": "phonee is not a known field"}}, bindingutil.FormatBindingErrors(err))
+}
+
+func TestBindJSONStrict_RejectsIgnoredField(t *testing.T) {
+	// The fields set by the server cannot be sent by the clients
+	var login entity.LoginRequest
+	err := bindingutil.BindJSONStrict(newContext(`{"username": "admin", "password": "P@ssw0rd", "IPAddress": "203.0.113.7"}`), &login)
+
+	var unknown *bindingutil.UnknownFieldError
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, "IPAddress", unknown.Field)
+}
+
+func TestFormatBindingErrors(t *testing.T) {
+	var login entity.LoginRequest
+	err := bindingutil.BindJSONStrict(newContext(`{"username": 42}`), &login)
+	assert.Equal(t, []map[string]string{{"field": "username", "message": "username must be of type string"}}, bindingutil.FormatBindingErrors(err))
+
+	// The malformed bodies are not caused by a field
+	err = bindingutil.BindJSONStrict(newContext(`not-an-object`), &login)
+	require.Error(t, err)
+	assert.Nil(t, bindingutil.FormatBindingErrors(err))
+}
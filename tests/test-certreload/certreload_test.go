@@ -0,0 +1,142 @@
+package test_certreload
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/certreload"
+)
+
+// writeKeyPair writes a self-signed certificate for the common name and its key to the given files,
+// with the given modification time so that the change is detected whatever the resolution of the file system.
+func writeKeyPair(t *testing.T, certPath string, keyPath string, commonName string, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	require.NoError(t, os.Chtimes(certPath, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyPath, modTime, modTime))
+}
+
+// newKeyPair writes a key pair for the common name in a temporary directory, and returns the paths of its files.
+func newKeyPair(t *testing.T, commonName string) (string, string) {
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	writeKeyPair(t, certPath, keyPath, commonName, time.Now().Add(-time.Hour))
+
+	return certPath, keyPath
+}
+
+// servedCommonName returns the common name of the certificate served by the TLS server.
+// The server name is sent, the certificate of httptest being served to the clients without one.
+func servedCommonName(t *testing.T, srv *httptest.Server) string {
+	conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{InsecureSkipVerify: true, ServerName: "example.com"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func TestReloader_ServesTheReloadedCertificate(t *testing.T) {
+	certPath, keyPath := newKeyPair(t, "old.example.com")
+	r, err := certreload.New(certPath, keyPath)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	srv.TLS = r.TLSConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	assert.Equal(t, "old.example.com", servedCommonName(t, srv))
+
+	changed, err := r.Changed()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// The listener keeps running, the new connections get the new certificate
+	writeKeyPair(t, certPath, keyPath, "new.example.com", time.Now())
+	changed, err = r.Changed()
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	require.NoError(t, r.Reload())
+	assert.Equal(t, "new.example.com", servedCommonName(t, srv))
+	assert.Equal(t, "new.example.com", r.Certificate().Leaf.Subject.CommonName)
+}
+
+func TestReloader_KeepsThePreviousCertificateOnError(t *testing.T) {
+	certPath, keyPath := newKeyPair(t, "old.example.com")
+	r, err := certreload.New(certPath, keyPath)
+	require.NoError(t, err)
+
+	// A certificate whose key is not written yet does not match the previous key
+	otherCert, _ := newKeyPair(t, "new.example.com")
+	data, err := os.ReadFile(otherCert)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certPath, data, 0o600))
+
+	assert.Error(t, r.Reload())
+	assert.Equal(t, "old.example.com", r.Certificate().Leaf.Subject.CommonName)
+
+	// The change is still pending, so that the next check retries it
+	changed, err := r.Changed()
+	require.NoError(t, err)
+	assert.True(t, changed)
+}
+
+func TestReloader_WatchReloadsTheChangedFiles(t *testing.T) {
+	certPath, keyPath := newKeyPair(t, "old.example.com")
+	r, err := certreload.New(certPath, keyPath)
+	require.NoError(t, err)
+
+	r.Watch(10 * time.Millisecond)
+	defer r.Close()
+
+	writeKeyPair(t, certPath, keyPath, "new.example.com", time.Now())
+	assert.Eventually(t, func() bool {
+		return r.Certificate().Leaf.Subject.CommonName == "new.example.com"
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestNew_InvalidFiles(t *testing.T) {
+	_, err := certreload.New(filepath.Join(t.TempDir(), "missing.crt"), filepath.Join(t.TempDir(), "missing.key"))
+	assert.Error(t, err)
+}
+
+func TestLoadInterval(t *testing.T) {
+	assert.Equal(t, certreload.DefaultInterval, certreload.LoadInterval())
+
+	t.Setenv("TLS_RELOAD_INTERVAL_SECOND", "0")
+	assert.Zero(t, certreload.LoadInterval())
+
+	t.Setenv("TLS_RELOAD_INTERVAL_SECOND", "invalid")
+	assert.Equal(t, certreload.DefaultInterval, certreload.LoadInterval())
+}
@@ -0,0 +1,135 @@
+package test_chaos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/chaos"
+	request_filter "github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/request-filter"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+)
+
+// newRouter returns a router injecting the faults of the headers, whose route reads a user through the chaos
+// and retrying repositories, as wired by the routes, and counts the calls reaching the backend.
+func newRouter(t *testing.T, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	backend := mocks.NewMockUserRepository(ctrl)
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "admin").DoAndReturn(func(context.Context, *gorm.DB, string) (entity.User, error) {
+		*calls++
+		return entity.User{ID: 1, Username: "admin"}, nil
+	}).AnyTimes()
+
+	repo := repository.NewChaosUserRepository(backend, chaos.New(chaos.Config{Enabled: true}))
+	repo = repository.NewRetryingUserRepository(repo, policyconfig.RetryPolicy{Retries: 2, Sleep: func(time.Duration) {}})
+
+	r := gin.New()
+	r.Use(request_filter.InjectFaults())
+	r.GET("/users/admin", func(c *gin.Context) {
+		if _, err := repo.GetUserByUsername(c.Request.Context(), nil, "admin"); err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func TestInjectFaults_DroppedConnectionsAreRetried(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+		calls   int
+	}{
+		{"no fault", nil, http.StatusOK, 1},
+		{"dropped once", map[string]string{chaos.HeaderDBFault: chaos.FaultDrop, chaos.HeaderDBCount: "1"}, http.StatusOK, 1},
+		{"dropped past the retries", map[string]string{chaos.HeaderDBFault: chaos.FaultDrop, chaos.HeaderDBCount: "3"}, http.StatusInternalServerError, 0},
+		{"error, not retried", map[string]string{chaos.HeaderDBFault: chaos.FaultError, chaos.HeaderDBCount: "1"}, http.StatusInternalServerError, 0},
+		{"rejected", map[string]string{chaos.HeaderStatus: "503"}, http.StatusServiceUnavailable, 0},
+		{"invalid", map[string]string{chaos.HeaderDBFault: "timeout"}, http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			r := newRouter(t, &calls)
+			req := httptest.NewRequest(http.MethodGet, "/users/admin", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+			assert.Equal(t, tt.calls, calls)
+		})
+	}
+}
+
+func TestInjectFaults_DelaysStopWithTheRequest(t *testing.T) {
+	calls := 0
+	r := newRouter(t, &calls)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// The call to the database waits past the deadline of the request, which fails instead of waiting for a minute
+	req := httptest.NewRequest(http.MethodGet, "/users/admin", nil).WithContext(ctx)
+	req.Header.Set(chaos.HeaderDBDelay, "1m")
+	start := time.Now()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Zero(t, calls)
+}
+
+func TestInjector_InjectsTheConfiguredFaults(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, chaos.New(chaos.Config{}).Inject(ctx, "GetUserByID"))
+	assert.ErrorIs(t, chaos.New(chaos.Config{Enabled: true, DBDropRate: 1}).Inject(ctx, "GetUserByID"), chaos.ErrDropped)
+	assert.ErrorIs(t, chaos.New(chaos.Config{Enabled: true, DBErrorRate: 1}).Inject(ctx, "GetUserByID"), chaos.ErrInjected)
+
+	// The dropped connections are transient, as the ones of the database, the injected errors are not
+	assert.True(t, repository.IsTransientError(chaos.ErrDropped))
+	assert.False(t, repository.IsTransientError(chaos.ErrInjected))
+
+	// The fault of the request takes precedence over the configured ones
+	ctx = chaos.WithFault(ctx, chaos.NewFault("", 0, 0))
+	assert.NoError(t, chaos.New(chaos.Config{Enabled: true, DBErrorRate: 1}).Inject(ctx, "GetUserByID"))
+
+	// A disabled injection leaves the repository as is
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserRepository(ctrl)
+	assert.Same(t, users, repository.NewChaosUserRepository(users, nil))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, chaos.Config{}.Validate("PRODUCTION"))
+	assert.NoError(t, chaos.Config{Enabled: true, DBErrorRate: 0.1, DBDropRate: 0.2}.Validate("DEVELOPMENT"))
+	assert.Error(t, chaos.Config{Enabled: true}.Validate("production"))
+	assert.Error(t, chaos.Config{Enabled: true, DBErrorRate: 1.5}.Validate("DEVELOPMENT"))
+	assert.Error(t, chaos.Config{Enabled: true, DBErrorRate: 0.6, DBDropRate: 0.6}.Validate("DEVELOPMENT"))
+	assert.Error(t, chaos.Config{Enabled: true, DBDelay: -1}.Validate("DEVELOPMENT"))
+
+	t.Setenv("CHAOS_ENABLED", "TRUE")
+	t.Setenv("CHAOS_DB_DELAY_MS", "25")
+	t.Setenv("CHAOS_DB_ERROR_RATE", "abc")
+	t.Setenv("CHAOS_DB_DROP_RATE", "0.5")
+	cfg := chaos.LoadConfig()
+	assert.Equal(t, 25*time.Millisecond, cfg.DBDelay)
+	assert.Equal(t, 0.5, cfg.DBDropRate)
+	assert.Error(t, cfg.Validate("DEVELOPMENT"))
+}
@@ -0,0 +1,116 @@
+package test_clock
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+var start = time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+func TestFakeClock_FrozenUntilSetOrAdvanced(t *testing.T) {
+	clk := clock.NewFakeClock(start)
+
+	assert.Equal(t, start, clk.Now())
+	time.Sleep(time.Millisecond)
+	assert.Equal(t, start, clk.Now())
+
+	clk.Advance(90 * time.Second)
+	assert.Equal(t, start.Add(90*time.Second), clk.Now())
+
+	clk.Set(start)
+	assert.Equal(t, start, clk.Now())
+
+	// The clock is advanced and read concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clk.Advance(time.Second)
+			_ = clk.Now()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, start.Add(10*time.Second), clk.Now())
+}
+
+func TestAccessToken_ExpiresWithTheClock(t *testing.T) {
+	clk := clock.NewFakeClock(start)
+	cfg := testsupport.NewJWTConfig()
+	user := entity.User{ID: 1, Username: "admin", Email: "admin@example.com", Roles: []entity.Role{{Name: "ROLE_ADMIN"}}}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", authorization.JwtValidationWithConfig(cfg, clk), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	token, err := service.GenerateJWTToken(cfg, user, "", clk.Now())
+	require.NoError(t, err)
+
+	// The token is valid until its expiry, excluded
+	clk.Advance(time.Hour - time.Second)
+	assert.Equal(t, http.StatusOK, testsupport.Do(router, "GET", "/protected", token).Code)
+	_, err = service.ParseJWTToken(cfg, token, clk.Now())
+	assert.NoError(t, err)
+
+	// and rejected from its expiry
+	clk.Advance(time.Second)
+	assert.Equal(t, http.StatusUnauthorized, testsupport.Do(router, "GET", "/protected", token).Code)
+	_, err = service.ParseJWTToken(cfg, token, clk.Now())
+	assert.ErrorContains(t, err, "token is expired")
+
+	// A token issued at the current time of the clock is valid again
+	token, err = service.GenerateJWTToken(cfg, user, "", clk.Now())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, testsupport.Do(router, "GET", "/protected", token).Code)
+}
+
+func TestRefreshToken_ExpiresWithTheClock(t *testing.T) {
+	store := testsupport.UseMemoryDatabase(t)
+	user, err := store.AddUser(entity.User{Username: "alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+
+	clk := clock.NewFakeClock(start)
+	s := service.NewRefreshTokenService(database.DefaultStore(), repository.NewMemoryRefreshTokenRepository(store),
+		policyconfig.SessionPolicy{RefreshTokenTTL: 24 * time.Hour}, clk)
+
+	token, err := s.CreateRefreshToken(context.Background(), user.ID, entity.SessionDevice{})
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(24*time.Hour), token.ExpiryDate)
+
+	// The refresh token is valid until its expiry date, included
+	clk.Advance(24 * time.Hour)
+	valid, err := s.VerifyExpirationDate(token.ExpiryDate)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	clk.Advance(time.Nanosecond)
+	valid, err = s.VerifyExpirationDate(token.ExpiryDate)
+	require.NoError(t, err)
+	assert.False(t, valid)
+
+	// The rotated token expires a full lifetime after the rotation
+	rotated, err := s.RotateRefreshToken(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, clk.Now().Add(24*time.Hour), rotated.ExpiryDate)
+
+	_, err = s.VerifyExpirationDate(time.Time{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package test_config_snapshot
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/featureflag"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/quota"
+	jwtutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newService creates a config snapshot service reading the given features and signing the tokens with the given algorithm,
+// with the feature flags and the rate limit overrides of the returned mocks.
+func newService(t *testing.T, features map[string]bool, algorithm string) (service.ConfigSnapshotService, *mocks.MockFeatureFlagService, *mocks.MockRateLimitOverrideService, *mocks.MockSigningKeyService) {
+	ctrl := gomock.NewController(t)
+	featureFlags := mocks.NewMockFeatureFlagService(ctrl)
+	rateLimitOverrides := mocks.NewMockRateLimitOverrideService(ctrl)
+	signingKeys := mocks.NewMockSigningKeyService(ctrl)
+
+	rateLimits := []entity.RateLimitSetting{{Name: "REGISTER_RATE_LIMIT", Routes: []string{"POST /auth/register"}, Limit: 3, Window: time.Hour.String()}}
+	quotas := quota.Policy{
+		Default: quota.Budget{Daily: 1000, Monthly: 20000},
+		Clients: map[string]quota.Budget{"partner": {Daily: 10000}},
+		Costs:   map[string]int64{"GET /api/v1/consumers/stream": 10},
+	}
+
+	jwtConfig := testsupport.NewJWTConfig()
+	jwtConfig.SigningMethod = algorithm
+	s := service.NewConfigSnapshotService(func() map[string]bool { return features }, rateLimits, quotas,
+		featureFlags, rateLimitOverrides, signingKeys, jwtConfig, clock.NewFakeClock(now))
+
+	return s, featureFlags, rateLimitOverrides, signingKeys
+}
+
+func TestGetConfigSnapshot(t *testing.T) {
+	t.Setenv("ENV", "PRODUCTION")
+	t.Setenv("JWT_SECRET", "s3cr3t")
+	t.Setenv("DB_PASS", "P@ssw0rd")
+
+	s, featureFlags, rateLimitOverrides, _ := newService(t, map[string]bool{"tls": true, "metrics": false}, "HS256")
+	flags := []entity.FeatureFlag{{Name: featureflag.ConsumerWrites, Disabled: true}}
+	featureFlags.EXPECT().GetFeatureFlags(gomock.Any()).Return(flags, nil)
+	overrides := []entity.RateLimitOverride{{Client: "partner", Factor: 10}}
+	rateLimitOverrides.EXPECT().GetRateLimitOverrides(gomock.Any()).Return(overrides, nil)
+
+	snapshot, err := s.GetConfigSnapshot()
+
+	require.NoError(t, err)
+	assert.Equal(t, "PRODUCTION", snapshot.Config["ENV"])
+	assert.Equal(t, "********", snapshot.Config["JWT_SECRET"], "the secrets should be masked")
+	assert.Equal(t, "********", snapshot.Config["DB_PASS"], "the secrets should be masked")
+	assert.Equal(t, map[string]bool{"tls": true, "metrics": false}, snapshot.Features)
+	assert.Equal(t, flags, snapshot.FeatureFlags)
+	assert.Equal(t, overrides, snapshot.RateLimits.Overrides)
+	assert.Equal(t, "REGISTER_RATE_LIMIT", snapshot.RateLimits.Routes[0].Name)
+	assert.Equal(t, entity.QuotaBudget{Daily: 1000, Monthly: 20000}, snapshot.Quotas.Default)
+	assert.Equal(t, map[string]entity.QuotaBudget{"partner": {Daily: 10000}}, snapshot.Quotas.Clients)
+	assert.Equal(t, int64(10), snapshot.Quotas.Costs["GET /api/v1/consumers/stream"])
+	assert.Equal(t, entity.ConfigSnapshotKeys{Algorithm: "HS256", KeyIDs: []string{}}, snapshot.SigningKeys, "the HS256 secret has no key ID")
+	assert.Equal(t, now, snapshot.GeneratedAt)
+}
+
+func TestGetConfigSnapshot_ReportsTheRS256KeyIDs(t *testing.T) {
+	key := testsupport.GenerateRSAKeyPair(t)
+	current := jwtutil.KeyID(&key.PublicKey)
+
+	s, featureFlags, rateLimitOverrides, signingKeys := newService(t, nil, "RS256")
+	featureFlags.EXPECT().GetFeatureFlags(gomock.Any()).Return(nil, nil)
+	rateLimitOverrides.EXPECT().GetRateLimitOverrides(gomock.Any()).Return(nil, nil)
+	signingKeys.EXPECT().GetKeySet().Return(jwtutil.JSONWebKeySet{Keys: []jwtutil.JSONWebKey{{Kid: current}, {Kid: "retired"}}}, nil)
+
+	snapshot, err := s.GetConfigSnapshot()
+
+	require.NoError(t, err)
+	assert.Equal(t, "RS256", snapshot.SigningKeys.Algorithm)
+	assert.Equal(t, current, snapshot.SigningKeys.CurrentKeyID)
+	assert.ElementsMatch(t, []string{"retired", current}, snapshot.SigningKeys.KeyIDs)
+	assert.True(t, sort.StringsAreSorted(snapshot.SigningKeys.KeyIDs))
+}
+
+func TestGetConfigSnapshot_FailsWithoutTheFeatureFlags(t *testing.T) {
+	s, featureFlags, _, _ := newService(t, nil, "HS256")
+	featureFlags.EXPECT().GetFeatureFlags(gomock.Any()).Return(nil, errors.New("connection refused"))
+
+	_, err := s.GetConfigSnapshot()
+
+	assert.ErrorContains(t, err, "connection refused")
+}
@@ -0,0 +1,181 @@
+package test_config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appconfig "github.com/yoanesber/go-jwt-auth-demo/config/app-config"
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/config/env"
+)
+
+func TestParseFile(t *testing.T) {
+	values, err := appconfig.ParseFile([]byte(`
+ENV: PRODUCTION
+PORT: 1000
+IS_SSL: true
+DB_MIGRATE: false
+BASE_PATH:
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"ENV":        "PRODUCTION",
+		"PORT":       "1000",
+		"IS_SSL":     "TRUE",
+		"DB_MIGRATE": "FALSE",
+		"BASE_PATH":  "",
+	}, values)
+}
+
+func TestParseFile_Invalid(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+	}{
+		{"lowercase key", "db_host: localhost"},
+		{"nested value", "DATABASE:\n  HOST: localhost"},
+		{"list value", "TRUSTED_PROXIES: [10.0.0.1, 10.0.0.2]"},
+		{"not a mapping", "- ENV"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := appconfig.ParseFile([]byte(tc.content))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadFile_EnvironmentTakesPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("MFA_ISSUER: From file\nREGISTER_RATE_LIMIT: 3\n"), 0o600))
+	t.Cleanup(func() { env.SetFile(nil) })
+
+	t.Setenv("MFA_ISSUER", "From environment")
+	t.Setenv("REGISTER_RATE_LIMIT", "")
+	require.NoError(t, os.Unsetenv("REGISTER_RATE_LIMIT"))
+
+	require.NoError(t, appconfig.LoadFile(path))
+	assert.Equal(t, "From environment", env.Getenv("MFA_ISSUER"))
+	assert.Equal(t, "3", env.Getenv("REGISTER_RATE_LIMIT"))
+
+	// The values of the file are not copied into the environment of the process
+	_, ok := os.LookupEnv("REGISTER_RATE_LIMIT")
+	assert.False(t, ok)
+
+	cfg, err := appconfig.FromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "From environment", cfg.MFA.Issuer)
+	assert.Equal(t, 3, cfg.RateLimits.Register)
+
+	assert.Error(t, appconfig.LoadFile(filepath.Join(t.TempDir(), "missing.yaml")))
+	assert.NoError(t, appconfig.LoadFile(""))
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("DB_DRIVER", database.DriverMemory)
+	t.Setenv("DB_SEED", "TRUE")
+	t.Setenv("IS_SSL", "TRUE")
+	t.Setenv("BASE_PATH", "/auth-svc/")
+	t.Setenv("NODE_ENV", "development")
+	t.Setenv("FRONTEND_URL", "http://localhost:3000, https://localhost:3000")
+	t.Setenv("USER_CACHE_TTL_SECOND", "30")
+	t.Setenv("CONSUMER_CACHE_TTL_SECOND", "invalid")
+	t.Setenv("REGISTER_RATE_LIMIT", "3")
+	t.Setenv("MFA_RATE_LIMIT", "0")
+	t.Setenv("MAX_SESSIONS_PER_USER", "2")
+	t.Setenv("JWT_REFRESH_TOKEN_EXPIRATION_HOUR", "48")
+	t.Setenv("JWT_PUBLIC_KEY_PATH", "/keys/public.pem")
+	t.Setenv("JWT_KEYSET_DIR", "/keys")
+	t.Setenv("WARMUP_ROLE_CACHE_USERS", "100")
+	t.Setenv("REDIS_URL", "redis://localhost:6379/0")
+	t.Setenv("REDIS_KEY_PREFIX", "auth-svc:")
+
+	cfg, err := appconfig.FromEnv()
+	require.NoError(t, err)
+
+	assert.True(t, cfg.Server.SSL)
+	assert.Equal(t, "/auth-svc", cfg.Server.BasePath)
+	assert.True(t, cfg.Database.IsMemory())
+	assert.True(t, cfg.Database.Seed)
+	assert.Equal(t, []string{"http://localhost:3000", "https://localhost:3000"}, cfg.CORS.AllowedOrigins)
+	assert.Equal(t, 30*time.Second, cfg.Caches.UserTTL)
+	assert.Zero(t, cfg.Caches.ConsumerTTL)
+	assert.Equal(t, 3, cfg.RateLimits.Register)
+	assert.Equal(t, appconfig.DefaultMFARateLimit, cfg.RateLimits.MFA)
+	assert.Equal(t, appconfig.DefaultCheckAvailabilityRateLimit, cfg.RateLimits.CheckAvailability)
+	assert.Equal(t, 2, cfg.Sessions.MaxSessions)
+	assert.Equal(t, 48*time.Hour, cfg.Sessions.RefreshTokenTTL)
+
+	// The settings read by the components are loaded once, and not read from the environment again
+	assert.Equal(t, "/keys/public.pem", cfg.JWT.Keys.PublicKeyPath)
+	assert.Equal(t, "/keys", cfg.JWT.Keys.KeySetDir)
+	assert.Equal(t, 100, cfg.Caches.RoleWarmUpUsers)
+	assert.True(t, cfg.Redis.Enabled())
+	assert.Equal(t, "auth-svc:", cfg.Redis.KeyPrefix)
+}
+
+func TestFromEnv_InvalidBasePath(t *testing.T) {
+	t.Setenv("BASE_PATH", "/auth-svc/*path")
+
+	_, err := appconfig.FromEnv()
+	assert.Error(t, err)
+}
+
+func TestFromEnv_DatabaseSettingsCheckedOnConnection(t *testing.T) {
+	t.Setenv("DB_DRIVER", database.DriverPostgres)
+	t.Setenv("DB_HOST", "")
+
+	cfg, err := appconfig.FromEnv()
+	require.NoError(t, err)
+	assert.Error(t, cfg.Database.Check())
+}
+
+func TestLoad_ReportsEveryProblem(t *testing.T) {
+	t.Setenv("DB_DRIVER", database.DriverMemory)
+	t.Setenv("ENV", "")
+	t.Setenv("PORT", "0")
+
+	_, err := appconfig.Load("", false)
+
+	var verr *appconfig.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Contains(t, verr.Problems, "ENV is not set")
+	assert.Contains(t, verr.Problems, `PORT must be a number between 1 and 65535, got "0"`)
+}
+
+func TestLoad_ReportsTheAuthRateLimitProblems(t *testing.T) {
+	t.Setenv("DB_DRIVER", database.DriverMemory)
+	t.Setenv("AUTH_RATE_LIMIT_IP_PER_MINUTE", "-1")
+	t.Setenv("AUTH_RATE_LIMIT_BACKEND", "redis")
+	t.Setenv("REDIS_URL", "")
+
+	_, err := appconfig.Load("", false)
+
+	var verr *appconfig.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Contains(t, verr.Problems, `AUTH_RATE_LIMIT_IP_PER_MINUTE must be a non-negative integer, got "-1"`)
+	assert.Contains(t, verr.Problems, "AUTH_RATE_LIMIT_BACKEND=redis requires REDIS_URL")
+}
+
+func TestLoad_ReportsTheCaptchaProblems(t *testing.T) {
+	t.Setenv("DB_DRIVER", database.DriverMemory)
+	t.Setenv("ANOMALY_DETECTION_ENABLED", "TRUE")
+	t.Setenv("ANOMALY_ACTIONS", "log,captcha")
+	t.Setenv("ANOMALY_TARPIT_DELAY_MS", "0")
+	t.Setenv("CAPTCHA_VERIFY_URL", "challenges.cloudflare.com/turnstile/v0/siteverify")
+	t.Setenv("CAPTCHA_SECRET", "")
+
+	_, err := appconfig.Load("", false)
+
+	var verr *appconfig.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Contains(t, verr.Problems, `ANOMALY_TARPIT_DELAY_MS must be a positive integer, got "0"`)
+	assert.Contains(t, verr.Problems, `CAPTCHA_VERIFY_URL must be an absolute http or https URL, got "challenges.cloudflare.com/turnstile/v0/siteverify"`)
+	assert.Contains(t, verr.Problems, "ANOMALY_ACTIONS=captcha requires CAPTCHA_VERIFY_URL and CAPTCHA_SECRET to be set")
+}
@@ -0,0 +1,69 @@
+package test_consumer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newAvailabilityService creates a consumer service backed by an in-memory store filled with the dummy consumers.
+func newAvailabilityService(t *testing.T) service.ConsumerService {
+	r := repository.NewMemoryConsumerRepository(testsupport.UseMemoryDatabase(t))
+	for _, c := range getDummyConsumers() {
+		_, err := r.CreateConsumer(context.Background(), nil, c)
+		require.NoError(t, err)
+	}
+
+	return service.NewConsumerService(database.DefaultStore(), r, policyconfig.LoadConsumerListingPolicy())
+}
+
+func TestCheckConsumerAvailability(t *testing.T) {
+	s := newAvailabilityService(t)
+
+	// The values are checked with the rules of the creation: case-insensitive usernames and emails, normalized phone numbers
+	availability, err := s.CheckConsumerAvailability(context.Background(), entity.ConsumerAvailabilityRequest{
+		Username: "DummyUser1",
+		Email:    "new-user@example.com",
+		Phone:    "0812-3456-7891",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, entity.ConsumerAvailability{
+		Username: &entity.FieldAvailability{Value: "DummyUser1", Available: false},
+		Email:    &entity.FieldAvailability{Value: "new-user@example.com", Available: true},
+		Phone:    &entity.FieldAvailability{Value: "6281234567891", Available: false},
+	}, availability)
+
+	// The values not given are not reported
+	availability, err = s.CheckConsumerAvailability(context.Background(), entity.ConsumerAvailabilityRequest{Email: "dummy-user-2@example.com"})
+	require.NoError(t, err)
+	assert.Nil(t, availability.Username)
+	assert.Nil(t, availability.Phone)
+	assert.Equal(t, &entity.FieldAvailability{Value: "dummy-user-2@example.com", Available: false}, availability.Email)
+}
+
+func TestCheckConsumerAvailability_InvalidRequest(t *testing.T) {
+	s := newAvailabilityService(t)
+
+	for name, req := range map[string]entity.ConsumerAvailabilityRequest{
+		"no value":      {},
+		"invalid email": {Email: "not-an-email"},
+		"invalid phone": {Phone: "12"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := s.CheckConsumerAvailability(context.Background(), req)
+
+			var ve validator.ValidationErrors
+			assert.ErrorAs(t, err, &ve)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package test_consumer
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"go.uber.org/mock/gomock"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// BenchmarkGetAllConsumers measures the consumer listing endpoint behind the JWT and RBAC middleware
+// for a full page of consumers. The service is mocked so the database is not part of the measurement.
+func BenchmarkGetAllConsumers(b *testing.B) {
+	page := make([]entity.Consumer, 0, 10)
+	for i := 0; i < 10; i++ {
+		c := getDummyConsumer()
+		c.ID = fmt.Sprintf("dummy-id-%d", i)
+		page = append(page, c)
+	}
+
+	ctrl := gomock.NewController(b)
+	s := mocks.NewMockConsumerService(ctrl)
+	s.EXPECT().GetAllConsumers(gomock.Any(), []string{"ROLE_USER"}, 1, 10, false).Return(page, nil).AnyTimes()
+	h := handler.NewConsumerHandler(s, pagination.LoadConfig())
+
+	router := testsupport.NewRouter(b)
+	router.GET("/api/v1/consumers", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.GetAllConsumers)
+	token := testsupport.NewTokenBuilder().WithRoles("ROLE_USER").Build(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := testsupport.Do(router, "GET", "/api/v1/consumers?page=1&limit=10", token)
+		if w.Code != http.StatusOK {
+			b.Fatalf("unexpected status %d", w.Code)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package test_consumer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newCachedConsumerService creates the consumer service under test with a cache of the given TTL, backed by a mocked repository.
+func newCachedConsumerService(t *testing.T, ttl time.Duration) (service.ConsumerService, *mocks.MockConsumerRepository, *clock.FakeClock) {
+	testsupport.UseMemoryDatabase(t)
+
+	repo := mocks.NewMockConsumerRepository(gomock.NewController(t))
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	s := service.NewConsumerService(database.DefaultStore(), repo, policyconfig.ConsumerListingPolicy{}, service.WithConsumerCache(ttl, clk))
+
+	return s, repo, clk
+}
+
+func TestConsumerCache_HitsUntilExpired(t *testing.T) {
+	s, repo, clk := newCachedConsumerService(t, time.Minute)
+	consumer := getDummyConsumer()
+
+	repo.EXPECT().GetConsumerByID(gomock.Any(), gomock.Any(), consumer.ID).Return(consumer, nil).Times(2)
+
+	for i := 0; i < 3; i++ {
+		got, err := s.GetConsumerByID(context.Background(), consumer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, consumer.ID, got.ID)
+	}
+
+	// The cached consumer is not shared with the callers
+	got, _ := s.GetConsumerByID(context.Background(), consumer.ID)
+	got.BirthDate.Time = time.Time{}
+	got, _ = s.GetConsumerByID(context.Background(), consumer.ID)
+	assert.Equal(t, consumer.BirthDate.Time, got.BirthDate.Time)
+
+	// The consumer is queried again once the entry expired
+	clk.Advance(time.Minute)
+	_, err := s.GetConsumerByID(context.Background(), consumer.ID)
+	require.NoError(t, err)
+}
+
+func TestConsumerCache_UnknownConsumersAreNotCached(t *testing.T) {
+	s, repo, _ := newCachedConsumerService(t, time.Minute)
+
+	repo.EXPECT().GetConsumerByID(gomock.Any(), gomock.Any(), "unknown").Return(entity.Consumer{}, gorm.ErrRecordNotFound).Times(2)
+
+	for i := 0; i < 2; i++ {
+		_, err := s.GetConsumerByID(context.Background(), "unknown")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	}
+}
+
+func TestConsumerCache_CoalescesConcurrentLookups(t *testing.T) {
+	s, repo, _ := newCachedConsumerService(t, time.Minute)
+	consumer := getDummyConsumer()
+
+	release := make(chan struct{})
+	repo.EXPECT().GetConsumerByID(gomock.Any(), gomock.Any(), consumer.ID).DoAndReturn(func(_ context.Context, tx *gorm.DB, id string) (entity.Consumer, error) {
+		<-release
+		return consumer, nil
+	}).Times(1)
+
+	// The lookups arriving while the query is in flight share its result, the later ones hit the cache
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := s.GetConsumerByID(context.Background(), consumer.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, consumer.ID, got.ID)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+}
+
+func TestConsumerCache_CanceledLookupDoesNotFailTheOthers(t *testing.T) {
+	s, repo, _ := newCachedConsumerService(t, time.Minute)
+	consumer := getDummyConsumer()
+
+	release := make(chan struct{})
+	repo.EXPECT().GetConsumerByID(gomock.Any(), gomock.Any(), consumer.ID).DoAndReturn(func(ctx context.Context, tx *gorm.DB, id string) (entity.Consumer, error) {
+		<-release
+		return consumer, ctx.Err()
+	}).Times(1)
+
+	// The first request gives up, while the shared query goes on for the second one
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error, 1)
+	go func() {
+		_, err := s.GetConsumerByID(ctx, consumer.ID)
+		canceled <- err
+	}()
+	found := make(chan error, 1)
+	go func() {
+		_, err := s.GetConsumerByID(context.Background(), consumer.ID)
+		found <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-canceled, context.Canceled)
+
+	close(release)
+	assert.NoError(t, <-found)
+
+	// The consumer found is cached
+	got, err := s.GetConsumerByID(context.Background(), consumer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, consumer.ID, got.ID)
+}
+
+func TestConsumerCache_InvalidatedOnStatusChange(t *testing.T) {
+	s, repo, _ := newCachedConsumerService(t, time.Minute)
+	consumer := getDummyConsumer()
+	suspended := consumer
+	suspended.Status = entity.ConsumerStatusSuspended
+
+	gomock.InOrder(
+		repo.EXPECT().GetConsumerByID(gomock.Any(), gomock.Any(), consumer.ID).Return(consumer, nil).Times(2),
+		repo.EXPECT().GetConsumerByID(gomock.Any(), gomock.Any(), consumer.ID).Return(suspended, nil),
+	)
+	repo.EXPECT().UpdateConsumer(gomock.Any(), gomock.Any(), gomock.Any()).Return(suspended, nil)
+
+	_, err := s.GetConsumerByID(context.Background(), consumer.ID)
+	require.NoError(t, err)
+
+	_, err = s.UpdateConsumerStatus(context.Background(), consumer.ID, entity.ConsumerStatusSuspended)
+	require.NoError(t, err)
+
+	got, err := s.GetConsumerByID(context.Background(), consumer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.ConsumerStatusSuspended, got.Status)
+}
+
+func TestConsumerCache_Disabled(t *testing.T) {
+	s, repo, _ := newCachedConsumerService(t, 0)
+	consumer := getDummyConsumer()
+
+	repo.EXPECT().GetConsumerByID(gomock.Any(), gomock.Any(), consumer.ID).Return(consumer, nil).Times(2)
+
+	for i := 0; i < 2; i++ {
+		_, err := s.GetConsumerByID(context.Background(), consumer.ID)
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,179 @@
+package test_consumer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+func TestGetConsumerByID_WithMockedService(t *testing.T) {
+	// Define a mocked service and the handler
+	// This allows testing the handler without needing a real database connection
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockConsumerService(ctrl)
+	h := handler.NewConsumerHandler(s, pagination.LoadConfig())
+
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/consumers/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.GetConsumerByID)
+
+	s.EXPECT().GetConsumerByID(gomock.Any(), "dummy-id").Return(getDummyConsumer(), nil)
+	s.EXPECT().GetConsumerByID(gomock.Any(), "unknown-id").Return(entity.Consumer{}, gorm.ErrRecordNotFound)
+
+	token := testsupport.NewTokenBuilder().WithRoles("ROLE_USER").Build(t)
+
+	// Existing consumer
+	w := testsupport.Do(router, "GET", "/api/v1/consumers/dummy-id", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var httpResponse httputil.HttpResponse
+	err := json.Unmarshal(w.Body.Bytes(), &httpResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, "dummy-id", httpResponse.Data.(map[string]interface{})["id"])
+
+	// Unknown consumer
+	w = testsupport.Do(router, "GET", "/api/v1/consumers/unknown-id", token)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetAllConsumers_LimitOverMaximum(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockConsumerService(ctrl)
+	reject := handler.NewConsumerHandler(s, pagination.Config{MaxLimit: 50, Mode: pagination.ModeReject})
+	clamp := handler.NewConsumerHandler(s, pagination.Config{MaxLimit: 50, Mode: pagination.ModeClamp})
+
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/consumers", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), reject.GetAllConsumers)
+	router.GET("/api/v1/clamped/consumers", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), clamp.GetAllConsumers)
+	token := testsupport.NewTokenBuilder().WithRoles("ROLE_USER").Build(t)
+
+	// By default, a limit over the maximum is rejected without reaching the service
+	w := testsupport.Do(router, "GET", "/api/v1/consumers?limit=100000", token)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Limit must not exceed 50")
+
+	// In clamp mode, the limit is lowered to the maximum
+	s.EXPECT().GetAllConsumers(gomock.Any(), []string{"ROLE_USER"}, 1, 50, false).Return([]entity.Consumer{getDummyConsumer()}, nil)
+	w = testsupport.Do(router, "GET", "/api/v1/clamped/consumers?limit=100000", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCreateConsumer_MapsTheRequestAndTheResponse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockConsumerService(ctrl)
+	h := handler.NewConsumerHandler(s, pagination.LoadConfig())
+
+	router := testsupport.NewRouter(t)
+	router.POST("/api/v1/consumers", authorization.RoleBasedAccessControl("ROLE_ADMIN"), h.CreateConsumer)
+	token := testsupport.NewTokenBuilder().WithRoles("ROLE_ADMIN").Build(t)
+
+	// The service receives the consumer mapped from the request, and the response has none of the internal columns
+	created := getDummyConsumer()
+	created.EmailIndex = "email-index"
+	created.PhoneIndex = "phone-index"
+	s.EXPECT().CreateConsumer(gomock.Any(), entity.Consumer{Fullname: "John Doe", Username: "johndoe", Email: "john.doe@example.com",
+		Phone: "+6281234567890", Address: "Jl. Merdeka No. 123, Jakarta"}).Return(created, nil)
+
+	body := `{"fullname":"John Doe","username":"johndoe","email":"john.doe@example.com","phone":"+6281234567890","address":"Jl. Merdeka No. 123, Jakarta"}`
+	w := postJSON(router, token, body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.ElementsMatch(t, []string{"id", "fullname", "username", "email", "phone", "address", "birthDate", "status", "createdAt", "updatedAt"}, keys(resp.Data))
+	assert.NotContains(t, w.Body.String(), "index")
+
+	// The fields the request does not have, e.g. the status or the ID, are rejected without reaching the service
+	w = postJSON(router, token, `{"fullname":"John Doe","status":"active"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "status")
+}
+
+func TestCreateConsumer_DuplicatePhone(t *testing.T) {
+	r := repository.NewMemoryConsumerRepository(testsupport.UseMemoryDatabase(t))
+	h := handler.NewConsumerHandler(service.NewConsumerService(database.DefaultStore(), r, policyconfig.LoadConsumerListingPolicy()), pagination.LoadConfig())
+
+	router := testsupport.NewRouter(t)
+	router.POST("/api/v1/consumers", authorization.RoleBasedAccessControl("ROLE_ADMIN"), h.CreateConsumer)
+	token := testsupport.NewTokenBuilder().WithRoles("ROLE_ADMIN").Build(t)
+
+	body := `{"fullname":"John Doe","username":"johndoe","email":"john.doe@example.com","phone":"+6281234567890","address":"Jl. Merdeka No. 123, Jakarta","birthDate":"1990-05-17"}`
+	w := postJSON(router, token, body)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// Another consumer with the same phone is a conflict, which does not echo the phone
+	body = `{"fullname":"Jane Doe","username":"janedoe","email":"jane.doe@example.com","phone":"+6281234567890","address":"Jl. Merdeka No. 123, Jakarta","birthDate":"1990-05-17"}`
+	w = postJSON(router, token, body)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var resp httputil.HttpResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "A consumer with this phone already exists", resp.Error)
+	assert.NotContains(t, w.Body.String(), "6281234567890")
+}
+
+// postJSON posts the body to /api/v1/consumers with the given token.
+func postJSON(router http.Handler, token string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/api/v1/consumers", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", testsupport.DefaultTokenType+" "+token)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+// keys returns the keys of the map.
+func keys(m map[string]interface{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+func TestGetConsumerByID_DatabaseUnavailable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockConsumerService(ctrl)
+	h := handler.NewConsumerHandler(s, pagination.LoadConfig())
+
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/consumers/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.GetConsumerByID)
+	token := testsupport.NewTokenBuilder().WithRoles("ROLE_USER").Build(t)
+
+	// The open circuit breaker, and the transient errors outlasting the retries, are answered with 503 without their text
+	s.EXPECT().GetConsumerByID(gomock.Any(), "dummy-id").Return(entity.Consumer{}, fmt.Errorf("failed to get consumer: %w", database.ErrCircuitOpen))
+	s.EXPECT().GetConsumerByID(gomock.Any(), "dummy-id").Return(entity.Consumer{}, &pgconn.PgError{Code: "40001", Message: "could not serialize access"})
+
+	for i := 0; i < 2; i++ {
+		w := testsupport.Do(router, "GET", "/api/v1/consumers/dummy-id", token)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		var resp httputil.HttpResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "The service is temporarily unavailable, please try again later", resp.Error)
+	}
+}
@@ -0,0 +1,93 @@
+package test_consumer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// listConsumerIDs lists the consumers as a caller with the given roles, using the given listing policy, and returns their IDs.
+func listConsumerIDs(t *testing.T, policy policyconfig.ConsumerListingPolicy, roles ...string) []string {
+	r := repository.NewMemoryConsumerRepository(testsupport.UseMemoryDatabase(t))
+	for _, c := range getDummyConsumers() {
+		if _, err := r.CreateConsumer(context.Background(), nil, c); err != nil {
+			t.Fatalf("failed to create dummy consumer: %v", err)
+		}
+	}
+
+	h := handler.NewConsumerHandler(service.NewConsumerService(database.DefaultStore(), r, policy), pagination.LoadConfig())
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/consumers", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.GetAllConsumers)
+
+	token := testsupport.NewTokenBuilder().WithRoles(roles...).Build(t)
+	w := testsupport.Do(router, "GET", "/api/v1/consumers?limit=100", token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	var ids []string
+	for _, c := range resp.Data {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func TestGetAllConsumers_DefaultListingPolicy(t *testing.T) {
+	policy := policyconfig.LoadConsumerListingPolicy()
+
+	// The users do not see the suspended consumers
+	ids := listConsumerIDs(t, policy, "ROLE_USER")
+	assert.Contains(t, ids, "dummy-id-1")
+	assert.Contains(t, ids, "dummy-id-2")
+	assert.NotContains(t, ids, "dummy-id-3")
+
+	// The administrators see all of them, even when they are also users
+	ids = listConsumerIDs(t, policy, "ROLE_USER", "ROLE_ADMIN")
+	assert.Contains(t, ids, "dummy-id-3")
+}
+
+func TestGetAllConsumers_ConfiguredListingPolicy(t *testing.T) {
+	t.Setenv("CONSUMER_LISTING_EXCLUDED_STATUSES", "ROLE_USER=suspended|inactive,ROLE_ADMIN=suspended")
+	policy := policyconfig.LoadConsumerListingPolicy()
+
+	ids := listConsumerIDs(t, policy, "ROLE_USER")
+	assert.Contains(t, ids, "dummy-id-1")
+	assert.NotContains(t, ids, "dummy-id-2")
+	assert.NotContains(t, ids, "dummy-id-3")
+
+	ids = listConsumerIDs(t, policy, "ROLE_ADMIN")
+	assert.Contains(t, ids, "dummy-id-2")
+	assert.NotContains(t, ids, "dummy-id-3")
+}
+
+func TestConsumerListingPolicy_Excluded(t *testing.T) {
+	policy, err := policyconfig.ParseConsumerListingPolicy("ROLE_USER=suspended|inactive, ROLE_AUDITOR=Suspended")
+	assert.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"suspended", "inactive"}, policy.Excluded([]string{"ROLE_USER"}))
+	assert.Equal(t, []string{"suspended"}, policy.Excluded([]string{"ROLE_USER", "ROLE_AUDITOR"}))
+	assert.Empty(t, policy.Excluded([]string{"ROLE_USER", "ROLE_ADMIN"}))
+	assert.Empty(t, policy.Excluded(nil))
+
+	_, err = policyconfig.ParseConsumerListingPolicy("ROLE_USER=deleted")
+	assert.Error(t, err)
+	_, err = policyconfig.ParseConsumerListingPolicy("suspended")
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package test_consumer
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newConsumer returns a valid consumer to create, with the given username, email, and metadata.
+func newConsumer(username string, email string, metadata entity.Metadata) entity.Consumer {
+	return entity.Consumer{
+		Fullname:  "John Doe",
+		Username:  username,
+		Email:     email,
+		Phone:     "+6281234567890",
+		Address:   "Jl. Merdeka No. 123, Jakarta",
+		BirthDate: &customtype.Date{Time: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)},
+		Metadata:  metadata,
+	}
+}
+
+func TestParseConsumerRules(t *testing.T) {
+	rules, err := policyconfig.ParseConsumerRules(" tenant, region ,", "[a-z][a-z0-9_.]*", "Mailinator.com, .example.org")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tenant", "region"}, rules.RequiredMetadataKeys)
+	assert.Equal(t, []string{"mailinator.com", "example.org"}, rules.BlockedEmailDomains)
+
+	// The pattern matches the whole username
+	assert.True(t, rules.UsernamePattern.MatchString("john.doe"))
+	assert.False(t, rules.UsernamePattern.MatchString("John.Doe"))
+	assert.False(t, rules.UsernamePattern.MatchString("john doe"))
+
+	rules, err = policyconfig.ParseConsumerRules("", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, policyconfig.ConsumerRules{}, rules)
+
+	_, err = policyconfig.ParseConsumerRules("", "[a-z", "")
+	assert.Error(t, err)
+	_, err = policyconfig.ParseConsumerRules("", "", "user@mailinator.com")
+	assert.Error(t, err)
+}
+
+func TestCreateConsumer_CheckedAgainstTheRules(t *testing.T) {
+	rules, err := policyconfig.ParseConsumerRules("tenant,region", "[a-z][a-z0-9_.]*", "mailinator.com")
+	require.NoError(t, err)
+	r := repository.NewMemoryConsumerRepository(testsupport.UseMemoryDatabase(t))
+	s := service.NewConsumerService(database.DefaultStore(), r, policyconfig.LoadConsumerListingPolicy(), service.WithConsumerRules(rules))
+
+	// Every broken rule is reported at once
+	_, err = s.CreateConsumer(context.Background(), newConsumer("John.Doe", "john@eu.mailinator.com", entity.Metadata{"tenant": "acme", "region": " "}))
+	var re *service.ConsumerRuleError
+	require.ErrorAs(t, err, &re)
+	assert.Equal(t, []service.ConsumerRuleViolation{
+		{Field: "metadata", Message: "metadata must have the keys: region"},
+		{Field: "username", Message: "username does not match the pattern of the usernames"},
+		{Field: "email", Message: "email addresses of the domain mailinator.com are not accepted"},
+	}, re.Violations)
+
+	created, err := s.CreateConsumer(context.Background(), newConsumer("john.doe", "john@example.com", entity.Metadata{"tenant": "acme", "region": "eu-west"}))
+	require.NoError(t, err)
+	assert.Equal(t, entity.Metadata{"tenant": "acme", "region": "eu-west"}, created.Metadata)
+
+	// The metadata is validated with the fields, before the rules
+	_, err = s.CreateConsumer(context.Background(), newConsumer("jane.doe", "jane@example.com", entity.Metadata{"tenant": "acme", "region": strings.Repeat("x", 256)}))
+	var ve validator.ValidationErrors
+	assert.ErrorAs(t, err, &ve)
+}
+
+func TestCreateConsumer_RuleViolationsAreBadRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockConsumerService(ctrl)
+	h := handler.NewConsumerHandler(s, pagination.LoadConfig())
+
+	router := testsupport.NewRouter(t)
+	router.POST("/api/v1/consumers", authorization.RoleBasedAccessControl("ROLE_ADMIN"), h.CreateConsumer)
+	token := testsupport.NewTokenBuilder().WithRoles("ROLE_ADMIN").Build(t)
+
+	s.EXPECT().CreateConsumer(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, c entity.Consumer) (entity.Consumer, error) {
+		assert.Equal(t, entity.Metadata{"tenant": "acme"}, c.Metadata)
+		return entity.Consumer{}, &service.ConsumerRuleError{Violations: []service.ConsumerRuleViolation{
+			{Field: "metadata", Message: "metadata must have the keys: region"},
+		}}
+	})
+
+	body := `{"fullname":"John Doe","username":"johndoe","email":"john.doe@example.com","phone":"+6281234567890","address":"Jl. Merdeka No. 123, Jakarta","metadata":{"tenant":"acme"}}`
+	w := postJSON(router, token, body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `{"field":"metadata","message":"metadata must have the keys: region"}`)
+}
@@ -0,0 +1,193 @@
+package test_consumer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newSoftDeleteService returns a consumer service over the dummy consumers, with a cache and the given clock.
+func newSoftDeleteService(t *testing.T, clk clock.Clock) service.ConsumerService {
+	r := repository.NewMemoryConsumerRepository(testsupport.UseMemoryDatabase(t))
+	for _, c := range getDummyConsumers() {
+		_, err := r.CreateConsumer(context.Background(), nil, c)
+		require.NoError(t, err)
+	}
+
+	return service.NewConsumerService(database.DefaultStore(), r, policyconfig.LoadConsumerListingPolicy(),
+		service.WithConsumerCache(time.Minute, clk), service.WithConsumerClock(clk))
+}
+
+// consumerIDs returns the IDs of the consumers.
+func consumerIDs(consumers []entity.Consumer) []string {
+	ids := make([]string, 0, len(consumers))
+	for _, c := range consumers {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func TestDeleteConsumer_HidesTheConsumerUntilRestored(t *testing.T) {
+	deletedAt := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+	s := newSoftDeleteService(t, clock.NewFakeClock(deletedAt))
+	ctx := context.Background()
+	admin := []string{entity.RoleAdmin}
+
+	// Cache the consumer before it is deleted
+	_, err := s.GetConsumerByID(ctx, "dummy-id-1")
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteConsumer(ctx, "dummy-id-1"))
+
+	// The deleted consumer is neither found, even if it was cached, nor listed, nor deleted again
+	_, err = s.GetConsumerByID(ctx, "dummy-id-1")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	consumers, err := s.GetAllConsumers(ctx, admin, 1, 10, false)
+	require.NoError(t, err)
+	assert.NotContains(t, consumerIDs(consumers), "dummy-id-1")
+	active, err := s.GetActiveConsumers(ctx, admin, 1, 10, false)
+	require.NoError(t, err)
+	assert.NotContains(t, consumerIDs(active), "dummy-id-1")
+	assert.ErrorIs(t, s.DeleteConsumer(ctx, "dummy-id-1"), gorm.ErrRecordNotFound)
+	_, err = s.UpdateConsumerStatus(ctx, "dummy-id-1", entity.ConsumerStatusSuspended)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// The administrators list it with includeDeleted, with its deletion time
+	consumers, err = s.GetAllConsumers(ctx, admin, 1, 10, true)
+	require.NoError(t, err)
+	require.Contains(t, consumerIDs(consumers), "dummy-id-1")
+	for _, c := range consumers {
+		assert.Equal(t, c.ID == "dummy-id-1", c.IsDeleted(), c.ID)
+		if c.IsDeleted() {
+			assert.True(t, c.DeletedAt.Time.Equal(deletedAt))
+		}
+	}
+
+	// It keeps its username, email, and phone
+	_, err = s.CreateConsumer(ctx, entity.Consumer{Fullname: "John Doe", Username: "DummyUser1", Email: "john.doe@example.com",
+		Phone: "+6281200000000", Address: "Jl. Merdeka No. 123, Jakarta", BirthDate: &customtype.Date{Time: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)}})
+	assert.ErrorIs(t, err, service.ErrDuplicateUsername)
+
+	// Once restored, it is found again, and cannot be restored twice
+	restored, err := s.RestoreConsumer(ctx, "dummy-id-1")
+	require.NoError(t, err)
+	assert.False(t, restored.IsDeleted())
+	assert.Equal(t, "dummyuser1", restored.Username)
+	_, err = s.GetConsumerByID(ctx, "dummy-id-1")
+	assert.NoError(t, err)
+	_, err = s.RestoreConsumer(ctx, "dummy-id-1")
+	assert.ErrorIs(t, err, service.ErrConsumerNotDeleted)
+	_, err = s.RestoreConsumer(ctx, "unknown-id")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestGetAllConsumers_IncludeDeletedForTheAdministratorsOnly(t *testing.T) {
+	s := newSoftDeleteService(t, clock.New())
+	h := handler.NewConsumerHandler(s, pagination.LoadConfig())
+	require.NoError(t, s.DeleteConsumer(context.Background(), "dummy-id-2"))
+
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/consumers", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.GetAllConsumers)
+	router.DELETE("/api/v1/consumers/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), h.DeleteConsumer)
+	router.POST("/api/v1/consumers/:id/restore", authorization.RoleBasedAccessControl("ROLE_ADMIN"), h.RestoreConsumer)
+	admin := testsupport.NewTokenBuilder().WithRoles("ROLE_ADMIN").Build(t)
+	user := testsupport.NewTokenBuilder().WithRoles("ROLE_USER").Build(t)
+
+	// Only the deleted consumers listed with includeDeleted have a deletion time
+	w := testsupport.Do(router, "GET", "/api/v1/consumers?includeDeleted=true", admin)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Data []entity.ConsumerResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 5)
+	for _, c := range resp.Data {
+		assert.Equal(t, c.ID == "dummy-id-2", c.DeletedAt != nil, c.ID)
+	}
+
+	w = testsupport.Do(router, "GET", "/api/v1/consumers", admin)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "dummy-id-2")
+	assert.NotContains(t, w.Body.String(), "deletedAt")
+
+	// The users cannot list the deleted consumers
+	w = testsupport.Do(router, "GET", "/api/v1/consumers?includeDeleted=true", user)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = testsupport.Do(router, "GET", "/api/v1/consumers?includeDeleted=yes", admin)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// A deleted consumer cannot be deleted again, nor a live one restored
+	w = testsupport.Do(router, "DELETE", "/api/v1/consumers/dummy-id-2", admin)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = testsupport.Do(router, "POST", "/api/v1/consumers/dummy-id-1/restore", admin)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = testsupport.Do(router, "POST", "/api/v1/consumers/dummy-id-2/restore", admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = testsupport.Do(router, "DELETE", "/api/v1/consumers/dummy-id-2", user)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = testsupport.Do(router, "DELETE", "/api/v1/consumers/dummy-id-2", admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestConsumerListings_IncludeDeletedForTheAdministratorsOnly(t *testing.T) {
+	s := newSoftDeleteService(t, clock.New())
+	h := handler.NewConsumerHandler(s, pagination.LoadConfig())
+	for _, id := range []string{"dummy-id-1", "dummy-id-2", "dummy-id-3"} {
+		require.NoError(t, s.DeleteConsumer(context.Background(), id))
+	}
+
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/consumers/active", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.GetActiveConsumers)
+	router.GET("/api/v1/consumers/inactive", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.GetInactiveConsumers)
+	router.GET("/api/v1/consumers/suspended", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.GetSuspendedConsumers)
+	router.GET("/api/v1/consumers/stream", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.StreamConsumers)
+	admin := testsupport.NewTokenBuilder().WithRoles("ROLE_ADMIN").Build(t)
+	user := testsupport.NewTokenBuilder().WithRoles("ROLE_USER").Build(t)
+
+	// The listings by status leave the deleted consumers out, unless an administrator includes them
+	for path, deleted := range map[string]string{"active": "dummy-id-1", "inactive": "dummy-id-2", "suspended": "dummy-id-3"} {
+		w := testsupport.Do(router, "GET", "/api/v1/consumers/"+path, admin)
+		assert.NotContains(t, w.Body.String(), deleted, path)
+
+		w = testsupport.Do(router, "GET", "/api/v1/consumers/"+path+"?includeDeleted=true", admin)
+		require.Equal(t, http.StatusOK, w.Code, path)
+		assert.Contains(t, w.Body.String(), deleted, path)
+		assert.Contains(t, w.Body.String(), "deletedAt", path)
+
+		w = testsupport.Do(router, "GET", "/api/v1/consumers/"+path+"?includeDeleted=true", user)
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+		w = testsupport.Do(router, "GET", "/api/v1/consumers/"+path+"?includeDeleted=yes", admin)
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+
+	// And so does the stream
+	w := testsupport.Do(router, "GET", "/api/v1/consumers/stream", admin)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "dummy-id-1")
+
+	w = testsupport.Do(router, "GET", "/api/v1/consumers/stream?includeDeleted=true", admin)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "dummy-id-1")
+
+	w = testsupport.Do(router, "GET", "/api/v1/consumers/stream?includeDeleted=true", user)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
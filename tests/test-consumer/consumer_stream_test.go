@@ -0,0 +1,133 @@
+package test_consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// streamLines splits the newline-delimited JSON stream into its decoded lines.
+func streamLines(t *testing.T, body string) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &decoded), line)
+		lines = append(lines, decoded)
+	}
+	return lines
+}
+
+func TestStreamConsumers_AppliesListingPolicy(t *testing.T) {
+	r := repository.NewMemoryConsumerRepository(testsupport.UseMemoryDatabase(t))
+	for _, c := range getDummyConsumers() {
+		_, err := r.CreateConsumer(context.Background(), nil, c)
+		require.NoError(t, err)
+	}
+
+	h := handler.NewConsumerHandler(service.NewConsumerService(database.DefaultStore(), r, policyconfig.LoadConsumerListingPolicy()), pagination.LoadConfig())
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/consumers/stream", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.StreamConsumers)
+
+	// The users do not see the suspended consumers, in the stream as in the listing
+	w := testsupport.Do(router, "GET", "/api/v1/consumers/stream", testsupport.NewTokenBuilder().WithRoles("ROLE_USER").Build(t))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, handler.NDJSONContentType, w.Header().Get("Content-Type"))
+
+	var ids []string
+	for _, line := range streamLines(t, w.Body.String()) {
+		ids = append(ids, line["id"].(string))
+	}
+	assert.Equal(t, "dummy-id-1", ids[0])
+	assert.NotContains(t, ids, "dummy-id-3")
+	assert.Len(t, ids, len(getDummyConsumers())-1)
+
+	w = testsupport.Do(router, "GET", "/api/v1/consumers/stream", testsupport.NewTokenBuilder().WithRoles("ROLE_ADMIN").Build(t))
+	assert.Len(t, streamLines(t, w.Body.String()), len(getDummyConsumers()))
+}
+
+func TestStreamConsumers_Empty(t *testing.T) {
+	r := repository.NewMemoryConsumerRepository(testsupport.UseMemoryDatabase(t))
+	h := handler.NewConsumerHandler(service.NewConsumerService(database.DefaultStore(), r, policyconfig.LoadConsumerListingPolicy()), pagination.LoadConfig())
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/consumers/stream", h.StreamConsumers)
+
+	w := testsupport.Do(router, "GET", "/api/v1/consumers/stream", testsupport.NewTokenBuilder().Build(t))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, handler.NDJSONContentType, w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Body.String())
+}
+
+func TestStreamConsumers_Interrupted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockConsumerService(ctrl)
+	h := handler.NewConsumerHandler(s, pagination.LoadConfig())
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/consumers/stream", h.StreamConsumers)
+
+	s.EXPECT().StreamConsumers(gomock.Any(), gomock.Any(), false, gomock.Any()).DoAndReturn(func(_ context.Context, _ []string, _ bool, fn func(entity.Consumer) error) error {
+		if err := fn(getDummyConsumer()); err != nil {
+			return err
+		}
+		return errors.New("connection reset")
+	})
+
+	// The status is already sent, the error is the last line of the stream
+	w := testsupport.Do(router, "GET", "/api/v1/consumers/stream", testsupport.NewTokenBuilder().Build(t))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	lines := streamLines(t, w.Body.String())
+	require.Len(t, lines, 2)
+	assert.Equal(t, "dummy-id", lines[0]["id"])
+	assert.Equal(t, "Failed to stream consumers", lines[1]["error"])
+}
+
+func TestStreamConsumers_CappedByTheRolesOfTheCaller(t *testing.T) {
+	t.Setenv("EXPORT_MAX_ROWS", "")
+	t.Setenv("PAGINATION_LIMITS_BY_ROLE", "ROLE_USER=10/1")
+	r := repository.NewMemoryConsumerRepository(testsupport.UseMemoryDatabase(t))
+	for _, c := range getDummyConsumers() {
+		_, err := r.CreateConsumer(context.Background(), nil, c)
+		require.NoError(t, err)
+	}
+
+	h := handler.NewConsumerHandler(service.NewConsumerService(database.DefaultStore(), r, policyconfig.LoadConsumerListingPolicy()), pagination.LoadConfig())
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/consumers/stream", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), h.StreamConsumers)
+
+	// The stream of a user stops at the cap of the role, with a last line telling it is capped
+	w := testsupport.Do(router, "GET", "/api/v1/consumers/stream", testsupport.NewTokenBuilder().WithRoles("ROLE_USER").Build(t))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get(handler.ExportLimitHeader))
+
+	lines := streamLines(t, w.Body.String())
+	require.Len(t, lines, 2)
+	assert.Equal(t, "dummy-id-1", lines[0]["id"])
+	assert.Equal(t, float64(1), lines[1]["limit"])
+
+	// The administrators are not capped, even with ROLE_USER
+	w = testsupport.Do(router, "GET", "/api/v1/consumers/stream", testsupport.NewTokenBuilder().WithRoles("ROLE_ADMIN", "ROLE_USER").Build(t))
+	assert.Empty(t, w.Header().Get(handler.ExportLimitHeader))
+	assert.Len(t, streamLines(t, w.Body.String()), len(getDummyConsumers()))
+}
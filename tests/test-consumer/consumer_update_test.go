@@ -0,0 +1,142 @@
+package test_consumer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/go-playground/validator.v9"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// ptr returns a pointer to the value.
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestUpdateConsumer_ChangesTheGivenFieldsOnly(t *testing.T) {
+	s := newAvailabilityService(t)
+	ctx := context.Background()
+
+	updated, err := s.UpdateConsumer(ctx, "dummy-id-1", entity.ConsumerUpdateRequest{
+		Address:   ptr("456 Other Street"),
+		Phone:     ptr("0812-0000-0001"),
+		BirthDate: &customtype.Date{Time: time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "456 Other Street", updated.Address)
+	assert.Equal(t, "6281200000001", updated.Phone)
+	assert.Equal(t, "1999-12-31", updated.BirthDate.Format("2006-01-02"))
+
+	// The other fields are left unchanged
+	stored, err := s.GetConsumerByID(ctx, "dummy-id-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Dummy Consumer 1", stored.Fullname)
+	assert.Equal(t, "dummyuser1", stored.Username)
+	assert.Equal(t, "dummy-user-1@example.com", stored.Email)
+	assert.Equal(t, "active", stored.Status)
+	assert.Equal(t, "6281200000001", stored.Phone)
+
+	// The unchanged email and phone of the consumer itself are not duplicates
+	_, err = s.UpdateConsumer(ctx, "dummy-id-1", entity.ConsumerUpdateRequest{Email: ptr("Dummy-User-1@example.com"), Phone: ptr("6281200000001")})
+	assert.NoError(t, err)
+}
+
+func TestUpdateConsumer_Rejected(t *testing.T) {
+	s := newAvailabilityService(t)
+	ctx := context.Background()
+
+	_, err := s.UpdateConsumer(ctx, "dummy-id-1", entity.ConsumerUpdateRequest{})
+	assert.ErrorIs(t, err, service.ErrEmptyConsumerUpdate)
+
+	_, err = s.UpdateConsumer(ctx, "unknown-id", entity.ConsumerUpdateRequest{Fullname: ptr("John Doe")})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// The email and the phone of another consumer
+	_, err = s.UpdateConsumer(ctx, "dummy-id-1", entity.ConsumerUpdateRequest{Email: ptr("dummy-user-2@example.com")})
+	assert.ErrorIs(t, err, service.ErrDuplicateEmail)
+	_, err = s.UpdateConsumer(ctx, "dummy-id-1", entity.ConsumerUpdateRequest{Phone: ptr("+62 812-3456-7892")})
+	assert.ErrorIs(t, err, service.ErrDuplicatePhone)
+
+	// The updated consumer is validated like a new one
+	for name, req := range map[string]entity.ConsumerUpdateRequest{
+		"empty fullname": {Fullname: ptr("")},
+		"invalid email":  {Email: ptr("not-an-email")},
+		"long fullname":  {Fullname: ptr(strings.Repeat("x", 101))},
+	} {
+		_, err = s.UpdateConsumer(ctx, "dummy-id-1", req)
+		var ve validator.ValidationErrors
+		assert.ErrorAs(t, err, &ve, name)
+	}
+}
+
+func TestUpdateConsumer_ChecksTheRulesOfTheChangedFields(t *testing.T) {
+	r := repository.NewMemoryConsumerRepository(testsupport.UseMemoryDatabase(t))
+	created, err := r.CreateConsumer(context.Background(), nil, newConsumer("John.Doe", "john@example.com", nil))
+	require.NoError(t, err)
+
+	rules, err := policyconfig.ParseConsumerRules("tenant", "[a-z][a-z0-9_.]*", "mailinator.com")
+	require.NoError(t, err)
+	s := service.NewConsumerService(database.DefaultStore(), r, policyconfig.LoadConsumerListingPolicy(), service.WithConsumerRules(rules))
+
+	// The consumer created before the rules breaks them, but only the changed email is checked
+	_, err = s.UpdateConsumer(context.Background(), created.ID, entity.ConsumerUpdateRequest{Email: ptr("john@mailinator.com")})
+	var re *service.ConsumerRuleError
+	require.ErrorAs(t, err, &re)
+	assert.Equal(t, []service.ConsumerRuleViolation{
+		{Field: "email", Message: "email addresses of the domain mailinator.com are not accepted"},
+	}, re.Violations)
+
+	_, err = s.UpdateConsumer(context.Background(), created.ID, entity.ConsumerUpdateRequest{Fullname: ptr("John Smith")})
+	assert.NoError(t, err)
+}
+
+func TestUpdateConsumer_Handler(t *testing.T) {
+	s := newAvailabilityService(t)
+	h := handler.NewConsumerHandler(s, pagination.LoadConfig())
+
+	router := testsupport.NewRouter(t)
+	router.PATCH("/api/v1/consumers/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), h.UpdateConsumer)
+	token := testsupport.NewTokenBuilder().WithRoles("ROLE_ADMIN").Build(t)
+
+	patch := func(target string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PATCH", target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", testsupport.DefaultTokenType+" "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := patch("/api/v1/consumers/dummy-id-1", `{"email":"john.doe@example.com"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"email":"john.doe@example.com"`)
+
+	// The username and the status are not updated with the body, and the updates need a field
+	assert.Equal(t, http.StatusBadRequest, patch("/api/v1/consumers/dummy-id-1", `{"username":"johndoe"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, patch("/api/v1/consumers/dummy-id-1", `{}`).Code)
+	assert.Equal(t, http.StatusConflict, patch("/api/v1/consumers/dummy-id-1", `{"email":"dummy-user-2@example.com"}`).Code)
+	assert.Equal(t, http.StatusNotFound, patch("/api/v1/consumers/unknown-id", `{"fullname":"John Doe"}`).Code)
+
+	// The status query parameter still changes the status, the body being ignored
+	w = patch("/api/v1/consumers/dummy-id-1?status=suspended", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"status":"suspended"`)
+	assert.Contains(t, w.Body.String(), `"email":"john.doe@example.com"`)
+}
@@ -0,0 +1,786 @@
+package test_contract
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/docs"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	_ "github.com/yoanesber/go-jwt-auth-demo/internal/repository" // publishes the user_lookup_cache metrics
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/eventbus"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/featureflag"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/headers"
+	request_filter "github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/request-filter"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/quota"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/ratelimit"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/readiness"
+	jwtutil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/jwt-util"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+	"github.com/yoanesber/go-jwt-auth-demo/routes"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// contractCase is a representative request replayed against the router.
+// Its response must be documented in the OpenAPI spec for the given status.
+type contractCase struct {
+	name   string
+	method string
+	path   string
+	token  string
+	body   interface{}
+	status int
+}
+
+// loadSpec loads and validates the embedded OpenAPI document and creates a router for it.
+func loadSpec(t *testing.T) (*openapi3.T, routers.Router) {
+	t.Helper()
+
+	doc, err := openapi3.NewLoader().LoadFromData(docs.OpenAPI)
+	require.NoError(t, err, "failed to load the OpenAPI document")
+	require.NoError(t, doc.Validate(context.Background()), "the OpenAPI document is invalid")
+
+	router, err := gorillamux.NewRouter(doc)
+	require.NoError(t, err)
+
+	return doc, router
+}
+
+func init() {
+	// The streams are validated line by line, as an array of their lines
+	openapi3filter.RegisterBodyDecoder(handler.NDJSONContentType, func(body io.Reader, _ http.Header, _ *openapi3.SchemaRef, _ openapi3filter.EncodingFn) (any, error) {
+		lines := []any{}
+		decoder := json.NewDecoder(body)
+		for decoder.More() {
+			var line any
+			if err := decoder.Decode(&line); err != nil {
+				return nil, err
+			}
+			lines = append(lines, line)
+		}
+		return lines, nil
+	})
+
+	// The archives are validated as binary strings, once they are read as zip archives
+	openapi3filter.RegisterBodyDecoder(handler.OpenAPIArchiveContentType, func(body io.Reader, _ http.Header, _ *openapi3.SchemaRef, _ openapi3filter.EncodingFn) (any, error) {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := zip.NewReader(bytes.NewReader(data), int64(len(data))); err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	})
+}
+
+// setupRouter mirrors the route table of routes.SetupRouter, backed by mocked services.
+func setupRouter(t *testing.T, authService *mocks.MockAuthService, consumerService *mocks.MockConsumerService, tokenUsageService *mocks.MockTokenUsageService, notificationService *mocks.MockNotificationService, tokenRevocationService *mocks.MockTokenRevocationService, userStateService *mocks.MockUserStateService, refreshTokenService *mocks.MockRefreshTokenService, passwordResetService *mocks.MockPasswordResetService, passwordService *mocks.MockPasswordService, mfaService *mocks.MockMFAService, userService *mocks.MockUserService, webhookService *mocks.MockWebhookService, roleService *mocks.MockRoleService, signingKeyService *mocks.MockSigningKeyService, securitySettingsService *mocks.MockSecuritySettingsService, featureFlagService *mocks.MockFeatureFlagService, apiKeyService *mocks.MockApiKeyService, oauthClientService *mocks.MockOAuthClientService, rateLimitOverrideService *mocks.MockRateLimitOverrideService, configSnapshotService *mocks.MockConfigSnapshotService, auditService *mocks.MockAuditService) *gin.Engine {
+	testsupport.SetupJWTEnv(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	// The realtime events are disabled, so that the responses of the disabled features are checked against the spec
+	flags := featureflag.NewCache([]string{featureflag.RealtimeEvents}, func() (map[string]string, error) { return nil, nil }, 0, clock.New())
+	feature := func(name string) gin.HandlerFunc {
+		return request_filter.RequireFeature(flags, name)
+	}
+
+	auth := handler.NewAuthHandler(authService)
+	r.POST("/auth/login", auth.Login)
+	r.POST("/auth/refresh-token", auth.RefreshToken)
+	r.POST("/auth/mfa/verify", request_filter.RateLimit(ratelimit.NewLimiter(2, time.Hour, clock.New())), auth.VerifyMFA)
+	r.POST("/auth/logout", authorization.JwtValidation(), auth.Logout)
+	r.POST("/auth/register", feature(featureflag.Registration), request_filter.RateLimit(ratelimit.NewLimiter(3, time.Hour, clock.New())), auth.Register)
+
+	oauthClients := handler.NewOAuthClientHandler(oauthClientService)
+	r.POST("/auth/token", oauthClients.Token)
+
+	passwordResets := handler.NewPasswordResetHandler(passwordResetService)
+	r.POST("/auth/forgot-password", feature(featureflag.PasswordResets), request_filter.RateLimit(ratelimit.NewLimiter(2, time.Hour, clock.New())), passwordResets.ForgotPassword)
+	r.POST("/auth/reset-password", feature(featureflag.PasswordResets), passwordResets.ResetPassword)
+
+	h := handler.NewConsumerHandler(consumerService, pagination.LoadConfig())
+	quotaTracker := quota.NewTracker(quota.Policy{Default: quota.Budget{Daily: 1000}}, clock.New())
+	v1 := r.Group("/api/v1", authorization.ApiKeyOrJwtValidation(apiKeyService, authorization.JwtValidation()), request_filter.EnforceQuota(quotaTracker))
+	v1.GET("/consumers", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), feature(featureflag.ConsumerReads), h.GetAllConsumers)
+	v1.GET("/consumers/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), feature(featureflag.ConsumerReads), h.GetConsumerByID)
+	v1.GET("/consumers/active", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), feature(featureflag.ConsumerReads), h.GetActiveConsumers)
+	v1.GET("/consumers/inactive", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), feature(featureflag.ConsumerReads), h.GetInactiveConsumers)
+	v1.GET("/consumers/suspended", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), feature(featureflag.ConsumerReads), h.GetSuspendedConsumers)
+	v1.GET("/consumers/stream", authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), feature(featureflag.ConsumerStream), h.StreamConsumers)
+	v1.POST("/consumers", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.ConsumerWrites), h.CreateConsumer)
+	v1.POST("/consumers/check-availability", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.ConsumerWrites),
+		request_filter.RateLimit(ratelimit.NewLimiter(2, time.Minute, clock.New())), h.CheckConsumerAvailability)
+	v1.PATCH("/consumers/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.ConsumerWrites), h.UpdateConsumer)
+	v1.DELETE("/consumers/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.ConsumerWrites), h.DeleteConsumer)
+	v1.POST("/consumers/:id/restore", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.ConsumerWrites), h.RestoreConsumer)
+
+	v1.GET("/me/quota", handler.NewQuotaHandler(quotaTracker).GetQuota)
+
+	users := handler.NewUserHandler(userService, userStateService, pagination.LoadConfig())
+	v1.GET("/users", authorization.RoleBasedAccessControl("ROLE_ADMIN"), users.GetAllUsers)
+	v1.GET("/users/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), users.GetUserByID)
+	v1.POST("/users", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.UserWrites), users.CreateUser)
+	v1.PUT("/users/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.UserWrites), users.UpdateUser)
+	v1.PATCH("/users/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.UserWrites), users.UpdateUserStatus)
+	v1.DELETE("/users/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.UserWrites), users.DeleteUser)
+	v1.POST("/users/:id/roles", authorization.RoleBasedAccessControl("ROLE_ADMIN"), feature(featureflag.UserWrites), users.ChangeUserRoles)
+
+	roles := handler.NewRoleHandler(roleService)
+	v1.GET("/roles", authorization.RoleBasedAccessControl("ROLE_ADMIN"), roles.GetAllRoles)
+	v1.POST("/roles", authorization.RoleBasedAccessControl("ROLE_ADMIN"), roles.CreateRole)
+	v1.PUT("/roles/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), roles.UpdateRole)
+	v1.DELETE("/roles/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), roles.DeleteRole)
+	v1.GET("/roles/:id/permissions", authorization.RoleBasedAccessControl("ROLE_ADMIN"), roles.GetRolePermissions)
+	v1.PUT("/roles/:id/permissions", authorization.RoleBasedAccessControl("ROLE_ADMIN"), roles.SetRolePermissions)
+	v1.GET("/permissions", authorization.RoleBasedAccessControl("ROLE_ADMIN"), roles.GetAllPermissions)
+
+	notifications := handler.NewNotificationHandler(notificationService)
+	v1.GET("/users/me/notification-preferences", notifications.GetNotificationPreference)
+	v1.PUT("/users/me/notification-preferences", notifications.UpdateNotificationPreference)
+
+	sessions := handler.NewSessionHandler(refreshTokenService)
+	v1.GET("/users/me/sessions", sessions.GetSessions)
+	v1.DELETE("/users/me/sessions/:sessionId", sessions.RemoveSession)
+
+	passwords := handler.NewPasswordHandler(passwordService)
+	v1.PUT("/users/me/password", request_filter.RateLimit(ratelimit.NewLimiter(2, time.Hour, clock.New())), passwords.ChangePassword)
+
+	mfa := handler.NewMFAHandler(mfaService)
+	mfaLimiter := ratelimit.NewLimiter(10, time.Hour, clock.New())
+	v1.GET("/users/me/mfa", mfa.GetMFAStatus)
+	v1.POST("/users/me/mfa/enroll", mfa.EnrollMFA)
+	v1.POST("/users/me/mfa/activate", request_filter.RateLimit(mfaLimiter), mfa.ActivateMFA)
+	v1.POST("/users/me/mfa/disable", request_filter.RateLimit(mfaLimiter), mfa.DisableMFA)
+
+	stats := handler.NewTokenStatsHandler(tokenUsageService)
+	v1.GET("/admin/token-stats", authorization.RoleBasedAccessControl("ROLE_ADMIN"), stats.GetTokenStats)
+
+	revocations := handler.NewTokenRevocationHandler(tokenRevocationService)
+	v1.POST("/admin/users/:id/revoke-tokens", authorization.RoleBasedAccessControl("ROLE_ADMIN"), revocations.RevokeUserTokens)
+
+	states := handler.NewUserStateHandler(userStateService)
+	v1.PUT("/admin/users/:id/state", authorization.RoleBasedAccessControl("ROLE_ADMIN"), states.ChangeUserState)
+
+	apiKeys := handler.NewApiKeyHandler(apiKeyService)
+	v1.GET("/admin/users/:id/api-keys", authorization.RoleBasedAccessControl("ROLE_ADMIN"), apiKeys.GetApiKeys)
+	v1.POST("/admin/users/:id/api-keys", authorization.RoleBasedAccessControl("ROLE_ADMIN"), apiKeys.CreateApiKey)
+	v1.POST("/admin/api-keys/:id/rotate", authorization.RoleBasedAccessControl("ROLE_ADMIN"), apiKeys.RotateApiKey)
+	v1.DELETE("/admin/api-keys/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), apiKeys.RevokeApiKey)
+	v1.GET("/admin/users/:id/oauth-clients", authorization.RoleBasedAccessControl("ROLE_ADMIN"), oauthClients.GetOAuthClients)
+	v1.POST("/admin/users/:id/oauth-clients", authorization.RoleBasedAccessControl("ROLE_ADMIN"), oauthClients.CreateOAuthClient)
+	v1.DELETE("/admin/oauth-clients/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), oauthClients.RevokeOAuthClient)
+
+	webhooks := handler.NewWebhookHandler(webhookService, pagination.LoadConfig())
+	v1.GET("/admin/webhook-deliveries", authorization.RoleBasedAccessControl("ROLE_ADMIN"), webhooks.GetFailedWebhookDeliveries)
+	v1.GET("/admin/webhook-deliveries/:id", authorization.RoleBasedAccessControl("ROLE_ADMIN"), webhooks.GetWebhookDelivery)
+	v1.POST("/admin/webhook-deliveries/:id/redeliver", authorization.RoleBasedAccessControl("ROLE_ADMIN"), webhooks.RedeliverWebhook)
+
+	securitySettings := handler.NewSecuritySettingsHandler(securitySettingsService)
+	v1.GET("/admin/security-settings", authorization.RoleBasedAccessControl("ROLE_ADMIN"), securitySettings.GetSecuritySettings)
+	v1.PUT("/admin/security-settings", authorization.RoleBasedAccessControl("ROLE_ADMIN"), securitySettings.UpdateSecuritySettings)
+
+	featureFlags := handler.NewFeatureFlagHandler(featureFlagService)
+	v1.GET("/admin/feature-flags", authorization.RoleBasedAccessControl("ROLE_ADMIN"), featureFlags.GetFeatureFlags)
+	v1.PUT("/admin/feature-flags/:name", authorization.RoleBasedAccessControl("ROLE_ADMIN"), featureFlags.UpdateFeatureFlag)
+
+	rateLimitOverrides := handler.NewRateLimitOverrideHandler(rateLimitOverrideService)
+	v1.GET("/admin/rate-limit-overrides", authorization.RoleBasedAccessControl("ROLE_ADMIN"), rateLimitOverrides.GetRateLimitOverrides)
+	v1.PUT("/admin/rate-limit-overrides/:client", authorization.RoleBasedAccessControl("ROLE_ADMIN"), rateLimitOverrides.UpdateRateLimitOverride)
+	v1.DELETE("/admin/rate-limit-overrides/:client", authorization.RoleBasedAccessControl("ROLE_ADMIN"), rateLimitOverrides.DeleteRateLimitOverride)
+
+	signingKeys := handler.NewSigningKeyHandler(signingKeyService)
+	v1.POST("/admin/signing-keys/rotate", authorization.RoleBasedAccessControl("ROLE_ADMIN"), signingKeys.RotateSigningKey)
+
+	v1.GET("/admin/config-snapshot", authorization.RoleBasedAccessControl("ROLE_ADMIN"), handler.NewConfigSnapshotHandler(configSnapshotService).GetConfigSnapshot)
+
+	v1.GET("/audit-events", authorization.RoleBasedAccessControl("ROLE_ADMIN"), handler.NewAuditHandler(auditService, nil, pagination.LoadConfig()).GetAuditEvents)
+
+	policy, err := eventbus.ParsePolicy(eventbus.DefaultRoleTopics)
+	require.NoError(t, err)
+	r.GET("/api/v1/ws", authorization.JwtValidation(), request_filter.EnforceQuota(quotaTracker), feature(featureflag.RealtimeEvents),
+		handler.NewEventHandler(eventbus.NewBus(clock.New()), policy).StreamEvents)
+
+	r.GET("/debug/vars", authorization.JwtValidation(), authorization.RoleBasedAccessControl("ROLE_ADMIN"), gin.WrapH(expvar.Handler()))
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	gate := readiness.NewGate(time.Millisecond)
+	require.NoError(t, gate.WarmUp(context.Background(), []readiness.Step{{Name: "database", Run: func(context.Context) error { return nil }}}))
+	r.GET("/readyz", handler.NewReadinessHandler(gate).Readyz)
+	openAPIHandler := handler.NewOpenAPIHandler(docs.OpenAPI, "")
+	r.GET("/openapi.yaml", openAPIHandler.GetOpenAPI)
+	r.GET("/openapi.zip", openAPIHandler.GetOpenAPIArchive)
+	r.GET("/.well-known/jwks.json", signingKeys.GetJWKS)
+
+	return r
+}
+
+// newConsumer creates a consumer as returned by the service.
+func newConsumer(id string, status string) entity.Consumer {
+	birthDate := customtype.Date{Time: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)}
+	return entity.Consumer{
+		ID:        id,
+		Fullname:  "John Doe",
+		Username:  "johndoe",
+		Email:     "john.doe@example.com",
+		Phone:     "6281234567890",
+		Address:   "Jl. Sudirman No. 1, Jakarta",
+		BirthDate: &birthDate,
+		Status:    status,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func TestResponsesMatchOpenAPISpec(t *testing.T) {
+	_, specRouter := loadSpec(t)
+
+	ctrl := gomock.NewController(t)
+	authService := mocks.NewMockAuthService(ctrl)
+	consumerService := mocks.NewMockConsumerService(ctrl)
+	tokenUsageService := mocks.NewMockTokenUsageService(ctrl)
+	notificationService := mocks.NewMockNotificationService(ctrl)
+	tokenRevocationService := mocks.NewMockTokenRevocationService(ctrl)
+	userStateService := mocks.NewMockUserStateService(ctrl)
+	refreshTokenService := mocks.NewMockRefreshTokenService(ctrl)
+	passwordResetService := mocks.NewMockPasswordResetService(ctrl)
+	passwordService := mocks.NewMockPasswordService(ctrl)
+	mfaService := mocks.NewMockMFAService(ctrl)
+	userService := mocks.NewMockUserService(ctrl)
+	webhookService := mocks.NewMockWebhookService(ctrl)
+	roleService := mocks.NewMockRoleService(ctrl)
+	signingKeyService := mocks.NewMockSigningKeyService(ctrl)
+	securitySettingsService := mocks.NewMockSecuritySettingsService(ctrl)
+	featureFlagService := mocks.NewMockFeatureFlagService(ctrl)
+	apiKeyService := mocks.NewMockApiKeyService(ctrl)
+	oauthClientService := mocks.NewMockOAuthClientService(ctrl)
+	rateLimitOverrideService := mocks.NewMockRateLimitOverrideService(ctrl)
+	configSnapshotService := mocks.NewMockConfigSnapshotService(ctrl)
+	auditService := mocks.NewMockAuditService(ctrl)
+	router := setupRouter(t, authService, consumerService, tokenUsageService, notificationService, tokenRevocationService, userStateService, refreshTokenService, passwordResetService, passwordService, mfaService, userService, webhookService, roleService, signingKeyService, securitySettingsService, featureFlagService, apiKeyService, oauthClientService, rateLimitOverrideService, configSnapshotService, auditService)
+
+	tokenResp := entity.LoginResponse{
+		AccessToken:    "access-token",
+		RefreshToken:   "refresh-token",
+		ExpirationDate: time.Now().Add(time.Hour).Format(time.RFC3339),
+		TokenType:      "Bearer",
+	}
+	authService.EXPECT().Login(gomock.Any(), entity.LoginRequest{Username: "admin", Password: "P@ssw0rd", IPAddress: "192.0.2.1"}).Return(tokenResp, nil)
+	authService.EXPECT().Login(gomock.Any(), entity.LoginRequest{Username: "admin", Password: "Wr0ngP@ss", IPAddress: "192.0.2.1"}).Return(entity.LoginResponse{}, gorm.ErrRecordNotFound)
+	authService.EXPECT().Login(gomock.Any(), entity.LoginRequest{Username: "userone", Password: "P@ssw0rd", IPAddress: "192.0.2.1"}).Return(entity.LoginResponse{}, service.ErrSessionLimitReached)
+	authService.EXPECT().Login(gomock.Any(), entity.LoginRequest{Username: "mfauser", Password: "P@ssw0rd", IPAddress: "192.0.2.1"}).
+		Return(entity.LoginResponse{MFARequired: true, MFAToken: "mfa-token", MFATokenExpirationDate: time.Now().Add(5 * time.Minute).Format(time.RFC3339)}, nil)
+	authService.EXPECT().VerifyMFA(gomock.Any(), gomock.Cond(func(req entity.VerifyMFARequest) bool { return req.Code == "123456" })).Return(tokenResp, nil)
+	authService.EXPECT().VerifyMFA(gomock.Any(), gomock.Cond(func(req entity.VerifyMFARequest) bool { return req.Code == "000000" })).Return(entity.LoginResponse{}, service.ErrInvalidMFACode)
+	authService.EXPECT().RefreshToken(gomock.Any(), entity.RefreshTokenRequest{RefreshToken: "refresh-token", IPAddress: "192.0.2.1"}).
+		Return(entity.RefreshTokenResponse{AccessToken: tokenResp.AccessToken, RefreshToken: tokenResp.RefreshToken, ExpirationDate: tokenResp.ExpirationDate, TokenType: tokenResp.TokenType}, nil)
+	authService.EXPECT().Logout(gomock.Any(), gomock.Cond(func(req entity.LogoutRequest) bool { return req.RefreshToken == "refresh-token" })).Return(nil)
+	authService.EXPECT().Logout(gomock.Any(), gomock.Cond(func(req entity.LogoutRequest) bool { return req.RefreshToken == "bob-refresh-token" })).Return(service.ErrTokenSubjectMismatch)
+	registered := time.Now()
+	authService.EXPECT().Register(gomock.Any(), gomock.Cond(func(req entity.RegisterRequest) bool { return req.Username == "janedoe" })).
+		Return(entity.RegisterResponse{ID: 3, Username: "janedoe", Email: "jane.doe@example.com", Firstname: "Jane", State: entity.UserStateActive, Roles: []string{"ROLE_USER"}, CreatedAt: &registered}, nil)
+	authService.EXPECT().Register(gomock.Any(), gomock.Cond(func(req entity.RegisterRequest) bool { return req.Username == "userone" })).
+		Return(entity.RegisterResponse{}, fmt.Errorf("%w: username userone is already taken", service.ErrUserAlreadyExists))
+	passwordResetService.EXPECT().RequestPasswordReset(gomock.Any(), entity.ForgotPasswordRequest{Email: "jane.doe@example.com"}).Return(nil)
+	passwordResetService.EXPECT().RequestPasswordReset(gomock.Any(), entity.ForgotPasswordRequest{Email: "not-an-email"}).
+		Return(validation.GetValidator().Struct(&entity.ForgotPasswordRequest{Email: "not-an-email"}))
+	passwordResetService.EXPECT().ResetPassword(gomock.Any(), gomock.Cond(func(req entity.ResetPasswordRequest) bool { return req.Token == "reset-token" })).Return(nil)
+	passwordResetService.EXPECT().ResetPassword(gomock.Any(), gomock.Cond(func(req entity.ResetPasswordRequest) bool { return req.Token == "used-token" })).
+		Return(service.ErrInvalidPasswordResetToken)
+
+	active := newConsumer("11111111-1111-1111-1111-111111111111", entity.ConsumerStatusActive)
+	consumerService.EXPECT().GetAllConsumers(gomock.Any(), gomock.Any(), 1, 10, false).Return([]entity.Consumer{active}, nil)
+	deleted := newConsumer("33333333-3333-3333-3333-333333333333", entity.ConsumerStatusInactive)
+	deleted.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	consumerService.EXPECT().GetAllConsumers(gomock.Any(), gomock.Any(), 1, 10, true).Return([]entity.Consumer{active, deleted}, nil)
+	consumerService.EXPECT().GetAllConsumers(gomock.Any(), gomock.Any(), 1, 10, true).Return(nil, service.ErrDeletedConsumersForbidden)
+	consumerService.EXPECT().GetActiveConsumers(gomock.Any(), gomock.Any(), 1, 10, false).Return([]entity.Consumer{active}, nil)
+	consumerService.EXPECT().GetInactiveConsumers(gomock.Any(), gomock.Any(), 1, 10, false).Return(nil, nil)
+	consumerService.EXPECT().GetSuspendedConsumers(gomock.Any(), gomock.Any(), 1, 10, false).Return(nil, gorm.ErrInvalidDB)
+	consumerService.EXPECT().GetConsumerByID(gomock.Any(), active.ID).Return(active, nil)
+	consumerService.EXPECT().StreamConsumers(gomock.Any(), gomock.Any(), false, gomock.Any()).DoAndReturn(func(_ context.Context, _ []string, _ bool, fn func(entity.Consumer) error) error {
+		for _, c := range []entity.Consumer{active, newConsumer("22222222-2222-2222-2222-222222222222", entity.ConsumerStatusInactive)} {
+			if err := fn(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	consumerService.EXPECT().StreamConsumers(gomock.Any(), gomock.Any(), false, gomock.Any()).Return(gorm.ErrInvalidDB)
+	consumerService.EXPECT().GetConsumerByID(gomock.Any(), "unknown").Return(entity.Consumer{}, gorm.ErrRecordNotFound)
+	consumerService.EXPECT().CreateConsumer(gomock.Any(), gomock.Any()).Return(newConsumer(active.ID, entity.ConsumerStatusInactive), nil)
+	consumerService.EXPECT().CheckConsumerAvailability(gomock.Any(), entity.ConsumerAvailabilityRequest{Username: "johndoe", Email: "new@example.com"}).
+		Return(entity.ConsumerAvailability{
+			Username: &entity.FieldAvailability{Value: "johndoe", Available: false},
+			Email:    &entity.FieldAvailability{Value: "new@example.com", Available: true},
+		}, nil)
+	consumerService.EXPECT().CheckConsumerAvailability(gomock.Any(), entity.ConsumerAvailabilityRequest{}).Return(entity.ConsumerAvailability{}, validation.GetValidator().Struct(&entity.ConsumerAvailabilityRequest{}))
+	consumerService.EXPECT().UpdateConsumerStatus(gomock.Any(), active.ID, entity.ConsumerStatusSuspended).Return(newConsumer(active.ID, entity.ConsumerStatusSuspended), nil)
+	consumerService.EXPECT().UpdateConsumer(gomock.Any(), active.ID, gomock.Any()).Return(newConsumer(active.ID, entity.ConsumerStatusActive), nil)
+	consumerService.EXPECT().UpdateConsumer(gomock.Any(), active.ID, gomock.Any()).Return(entity.Consumer{}, service.ErrDuplicateEmail)
+	consumerService.EXPECT().DeleteConsumer(gomock.Any(), active.ID).Return(nil)
+	consumerService.EXPECT().DeleteConsumer(gomock.Any(), "unknown").Return(fmt.Errorf("failed to delete consumer unknown: %w", gorm.ErrRecordNotFound))
+	consumerService.EXPECT().RestoreConsumer(gomock.Any(), deleted.ID).Return(newConsumer(deleted.ID, entity.ConsumerStatusInactive), nil)
+	consumerService.EXPECT().RestoreConsumer(gomock.Any(), active.ID).Return(entity.Consumer{}, service.ErrConsumerNotDeleted)
+
+	day := customtype.Date{Time: time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)}
+	tokenUsageService.EXPECT().GetTokenStats(gomock.Any(), gomock.Any()).Return(entity.TokenStats{
+		From:   day,
+		To:     day,
+		Totals: entity.TokenUsageTotals{Issued: 3, Refreshed: 1},
+		Usage:  []entity.TokenUsage{{Day: day, UserID: 1, Client: "web", Issued: 3, Refreshed: 1}},
+	}, nil)
+
+	pref := entity.DefaultNotificationPreference(2)
+	notificationService.EXPECT().GetNotificationPreference(gomock.Any(), int64(2)).Return(pref, nil)
+	pref.NewDeviceLogin = false
+	notificationService.EXPECT().UpdateNotificationPreference(gomock.Any(), int64(2), gomock.Any()).Return(pref, nil)
+
+	sessionID := "33333333-3333-3333-3333-333333333333"
+	refreshTokenService.EXPECT().GetSessions(gomock.Any(), int64(2)).Return([]entity.Session{{
+		SessionID:    sessionID,
+		DeviceID:     "phone",
+		ClientID:     "mobile-app",
+		UserAgent:    "okhttp/4.12.0",
+		IPAddress:    "192.0.2.1",
+		StartedAt:    time.Now().Add(-time.Hour),
+		LastActiveAt: time.Now(),
+		ExpiresAt:    time.Now().Add(24 * time.Hour),
+	}}, nil)
+	refreshTokenService.EXPECT().RemoveSession(gomock.Any(), int64(2), sessionID).Return(nil)
+	refreshTokenService.EXPECT().RemoveSession(gomock.Any(), int64(2), "unknown").Return(gorm.ErrRecordNotFound)
+
+	passwordExpiresAt := time.Now().Add(90 * 24 * time.Hour)
+	passwordService.EXPECT().ChangePassword(gomock.Any(), int64(2), gomock.Cond(func(req entity.ChangePasswordRequest) bool { return req.CurrentPassword == "P@ssw0rd" })).
+		Return(entity.ChangePasswordResponse{UserID: 2, CredentialsExpirationDate: &passwordExpiresAt, ChangedAt: time.Now()}, nil)
+	passwordService.EXPECT().ChangePassword(gomock.Any(), int64(2), gomock.Cond(func(req entity.ChangePasswordRequest) bool { return req.CurrentPassword == "Wr0ngP@ss" })).
+		Return(entity.ChangePasswordResponse{}, service.ErrIncorrectPassword)
+
+	tokenRevocationService.EXPECT().RevokeUserTokens(gomock.Any(), int64(2)).Return(entity.TokenRevocation{UserID: 2, TokenVersion: 1, RevokedAt: time.Now()}, nil)
+	tokenRevocationService.EXPECT().RevokeUserTokens(gomock.Any(), int64(99)).Return(entity.TokenRevocation{}, gorm.ErrRecordNotFound)
+
+	suspendReason := "Account compromised"
+	userStateService.EXPECT().ChangeUserState(gomock.Any(), int64(2), entity.UserStateRequest{State: entity.UserStateSuspended, Reason: suspendReason}).
+		Return(entity.UserStateChange{UserID: 2, PreviousState: entity.UserStateActive, State: entity.UserStateSuspended, Reason: &suspendReason, ChangedAt: time.Now()}, nil)
+	userStateService.EXPECT().ChangeUserState(gomock.Any(), int64(2), entity.UserStateRequest{State: entity.UserStateActive}).
+		Return(entity.UserStateChange{}, fmt.Errorf("%w: from ACTIVE to ACTIVE", service.ErrInvalidUserStateTransition))
+	userStateService.EXPECT().ChangeUserState(gomock.Any(), int64(99), gomock.Any()).Return(entity.UserStateChange{}, gorm.ErrRecordNotFound)
+
+	lockReason := "Left the company"
+	userStateService.EXPECT().ChangeUserState(gomock.Any(), int64(3), entity.UserStateRequest{State: entity.UserStateSuspended, Reason: lockReason}).
+		Return(entity.UserStateChange{UserID: 3, PreviousState: entity.UserStateActive, State: entity.UserStateSuspended, Reason: &lockReason, ChangedAt: time.Now()}, nil)
+
+	createdAt := time.Now()
+	managedUser := entity.User{ID: 3, Username: "usertwo", Email: "usertwo@example.com", Firstname: "User", State: entity.UserStateActive,
+		UserType: entity.UserTypeUserAccount, CreatedAt: &createdAt, Roles: []entity.Role{{ID: 1, Name: "ROLE_USER"}}}
+	userService.EXPECT().GetUsers(gomock.Any(), 1, 10).Return([]entity.User{managedUser}, nil)
+	userService.EXPECT().GetUserByID(gomock.Any(), int64(3)).Return(managedUser, nil)
+	userService.EXPECT().GetUserByID(gomock.Any(), int64(99)).Return(entity.User{}, gorm.ErrRecordNotFound)
+	userService.EXPECT().CreateUserByAdmin(gomock.Any(), gomock.Cond(func(req entity.CreateUserRequest) bool { return req.Username == "usertwo" }), int64(1)).Return(managedUser, nil)
+	userService.EXPECT().CreateUserByAdmin(gomock.Any(), gomock.Cond(func(req entity.CreateUserRequest) bool { return req.Username == "userone" }), int64(1)).
+		Return(entity.User{}, fmt.Errorf("%w: username userone is already taken", service.ErrUserAlreadyExists))
+	userService.EXPECT().UpdateUser(gomock.Any(), int64(3), gomock.Any(), int64(1)).Return(managedUser, nil)
+	userService.EXPECT().DeleteUser(gomock.Any(), int64(3), int64(1)).Return(nil)
+	userService.EXPECT().DeleteUser(gomock.Any(), int64(1), int64(1)).Return(service.ErrCannotDeleteSelf)
+	userService.EXPECT().ChangeUserRoles(gomock.Any(), int64(3), entity.UserRolesRequest{Add: []string{"ROLE_AUDITOR"}}, int64(1)).Return(managedUser, nil)
+	userService.EXPECT().ChangeUserRoles(gomock.Any(), int64(3), entity.UserRolesRequest{Remove: []string{"ROLE_USER"}}, int64(1)).Return(entity.User{}, service.ErrNoRolesLeft)
+
+	auditorDescription := "Read-only access to the audit reports"
+	auditor := entity.Role{ID: 4, Name: "ROLE_AUDITOR", Description: &auditorDescription}
+	roleService.EXPECT().GetRoles(gomock.Any()).Return([]entity.Role{{ID: 1, Name: "ROLE_USER"}, auditor}, nil)
+	roleService.EXPECT().CreateRole(gomock.Any(), entity.RoleRequest{Name: "ROLE_AUDITOR", Description: &auditorDescription}).Return(auditor, nil)
+	roleService.EXPECT().CreateRole(gomock.Any(), entity.RoleRequest{Name: "ROLE_USER"}).Return(entity.Role{}, fmt.Errorf("%w: ROLE_USER", service.ErrRoleAlreadyExists))
+	roleService.EXPECT().CreateRole(gomock.Any(), entity.RoleRequest{Name: "auditor"}).Return(entity.Role{}, validation.GetValidator().Struct(&entity.RoleRequest{Name: "auditor"}))
+	roleService.EXPECT().UpdateRole(gomock.Any(), uint(4), entity.RoleRequest{Name: "ROLE_AUDITOR", Description: &auditorDescription}).Return(auditor, nil)
+	roleService.EXPECT().UpdateRole(gomock.Any(), uint(1), entity.RoleRequest{Name: "ROLE_MEMBER"}).Return(entity.Role{}, fmt.Errorf("%w: ROLE_USER", service.ErrBuiltInRole))
+	roleService.EXPECT().DeleteRole(gomock.Any(), uint(4)).Return(nil)
+	roleService.EXPECT().DeleteRole(gomock.Any(), uint(5)).Return(fmt.Errorf("%w: ROLE_SUPPORT is assigned to 2 users", service.ErrRoleInUse))
+	roleService.EXPECT().DeleteRole(gomock.Any(), uint(99)).Return(gorm.ErrRecordNotFound)
+	consumersRead := entity.Permission{ID: 1, Name: entity.PermissionConsumersRead}
+	roleService.EXPECT().GetPermissions(gomock.Any()).Return([]entity.Permission{consumersRead}, nil)
+	roleService.EXPECT().GetRolePermissions(gomock.Any(), uint(1)).Return([]entity.Permission{consumersRead}, nil)
+	roleService.EXPECT().GetRolePermissions(gomock.Any(), uint(99)).Return(nil, gorm.ErrRecordNotFound)
+	roleService.EXPECT().SetRolePermissions(gomock.Any(), uint(1), entity.RolePermissionsRequest{Permissions: []string{entity.PermissionConsumersRead}}).Return([]entity.Permission{consumersRead}, nil)
+	roleService.EXPECT().SetRolePermissions(gomock.Any(), uint(1), entity.RolePermissionsRequest{Permissions: []string{"consumers:delete"}}).Return(nil, fmt.Errorf("%w: consumers:delete", service.ErrUnknownPermission))
+	rotatedAt := time.Now()
+	previousExpiresAt := rotatedAt.Add(time.Hour)
+	gomock.InOrder(
+		signingKeyService.EXPECT().RotateKey().Return(entity.SigningKeyRotation{KeyID: "new-kid", CreatedAt: rotatedAt, PreviousKeyID: "old-kid", PreviousKeyExpiresAt: &previousExpiresAt}, nil),
+		signingKeyService.EXPECT().RotateKey().Return(entity.SigningKeyRotation{}, jwtutil.ErrKeyRotationDisabled),
+	)
+	signingKeyService.EXPECT().GetKeySet().Return(jwtutil.JSONWebKeySet{Keys: []jwtutil.JSONWebKey{{Kty: "RSA", Use: "sig", Alg: "RS256", Kid: "new-kid", N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc", E: "AQAB"}}}, nil)
+
+	settingsUpdatedAt := time.Now()
+	adminID := int64(1)
+	securitySettingsService.EXPECT().GetSecuritySettings(gomock.Any()).Return(entity.SecuritySettings{AllowedOrigins: []string{}, AllowedHeaders: []string{}, SecurityHeaders: map[string]string{}}, nil)
+	securitySettingsService.EXPECT().UpdateSecuritySettings(gomock.Any(), int64(1), gomock.Cond(func(req entity.SecuritySettingsRequest) bool { return len(req.AllowedOrigins) == 1 })).
+		Return(entity.SecuritySettings{AllowedOrigins: []string{"https://admin.example.com"}, AllowedHeaders: []string{"X-Tenant-Id"},
+			SecurityHeaders: map[string]string{"Referrer-Policy": "strict-origin-when-cross-origin"}, UpdatedBy: &adminID, UpdatedAt: &settingsUpdatedAt}, nil)
+	securitySettingsService.EXPECT().UpdateSecuritySettings(gomock.Any(), int64(1), gomock.Cond(func(req entity.SecuritySettingsRequest) bool { return len(req.AllowedOrigins) == 0 })).
+		Return(entity.SecuritySettings{}, fmt.Errorf("%w: Strict-Transport-Security cannot be overridden", service.ErrInvalidSecuritySettings))
+
+	reason := "The consumers are being migrated until 14:00 UTC"
+	featureFlagService.EXPECT().GetFeatureFlags(gomock.Any()).Return([]entity.FeatureFlag{{Name: featureflag.ConsumerWrites, Description: featureflag.Features[featureflag.ConsumerWrites]}}, nil)
+	featureFlagService.EXPECT().UpdateFeatureFlag(gomock.Any(), int64(1), featureflag.ConsumerWrites, gomock.Cond(func(req entity.FeatureFlagRequest) bool { return req.Disabled == nil })).
+		Return(entity.FeatureFlag{}, validation.GetValidator().Struct(&entity.FeatureFlagRequest{Reason: &reason}))
+	featureFlagService.EXPECT().UpdateFeatureFlag(gomock.Any(), int64(1), featureflag.ConsumerWrites, gomock.Cond(func(req entity.FeatureFlagRequest) bool { return req.Disabled != nil })).
+		Return(entity.FeatureFlag{Name: featureflag.ConsumerWrites, Description: featureflag.Features[featureflag.ConsumerWrites], Disabled: true, Reason: &reason,
+			UpdatedBy: &adminID, UpdatedAt: &settingsUpdatedAt}, nil)
+	featureFlagService.EXPECT().UpdateFeatureFlag(gomock.Any(), int64(1), "consumers.delete", gomock.Any()).Return(entity.FeatureFlag{}, fmt.Errorf("%w: consumers.delete", service.ErrUnknownFeature))
+
+	factor := 10.0
+	partnerOverride := entity.RateLimitOverride{Client: "partner", Factor: factor, Reason: &reason, UpdatedBy: &adminID, UpdatedAt: &settingsUpdatedAt}
+	rateLimitOverrideService.EXPECT().GetRateLimitOverrides(gomock.Any()).Return([]entity.RateLimitOverride{partnerOverride}, nil)
+	rateLimitOverrideService.EXPECT().SaveRateLimitOverride(gomock.Any(), int64(1), "partner", gomock.Cond(func(req entity.RateLimitOverrideRequest) bool { return req.Factor != nil })).
+		Return(partnerOverride, nil)
+	rateLimitOverrideService.EXPECT().SaveRateLimitOverride(gomock.Any(), int64(1), "partner", gomock.Cond(func(req entity.RateLimitOverrideRequest) bool { return req.Factor == nil })).
+		Return(entity.RateLimitOverride{}, validation.GetValidator().Struct(&entity.RateLimitOverrideRequest{}))
+	rateLimitOverrideService.EXPECT().DeleteRateLimitOverride(gomock.Any(), int64(1), "partner").Return(nil)
+	rateLimitOverrideService.EXPECT().DeleteRateLimitOverride(gomock.Any(), int64(1), "unknown").Return(fmt.Errorf("%w: unknown", service.ErrRateLimitOverrideNotFound))
+	configSnapshotService.EXPECT().GetConfigSnapshot().Return(entity.ConfigSnapshot{
+		Version:      "dev",
+		Commit:       "unknown",
+		GoVersion:    "go1.24",
+		Config:       map[string]string{"ENV": "PRODUCTION", "JWT_SECRET": "********"},
+		Features:     map[string]bool{"tls": false, "metrics": true},
+		FeatureFlags: []entity.FeatureFlag{{Name: featureflag.ConsumerWrites, Description: featureflag.Features[featureflag.ConsumerWrites]}},
+		RateLimits: entity.ConfigSnapshotLimits{
+			Routes:    []entity.RateLimitSetting{{Name: "REGISTER_RATE_LIMIT", Routes: []string{"POST /auth/register"}, Limit: 3, Window: time.Hour.String()}},
+			Overrides: []entity.RateLimitOverride{{Client: "partner", Factor: 10}},
+		},
+		Quotas: entity.ConfigSnapshotQuotas{
+			Default: entity.QuotaBudget{Daily: 1000},
+			Clients: map[string]entity.QuotaBudget{"partner": {Daily: 10000, Monthly: 200000}},
+			Costs:   quota.DefaultCosts,
+		},
+		SigningKeys: entity.ConfigSnapshotKeys{Algorithm: "RS256", CurrentKeyID: "kid-2", KeyIDs: []string{"kid-1", "kid-2"}},
+		GeneratedAt: time.Now(),
+	}, nil)
+
+	keyExpiresAt := time.Now().AddDate(0, 0, 90)
+	apiKey := entity.ApiKey{ID: 3, UserID: 5, Name: "Nightly export", Prefix: "sk_Xq3v9LmA", ExpiresAt: &keyExpiresAt, CreatedBy: 1, CreatedAt: time.Now()}
+	apiKeyService.EXPECT().GetApiKeys(gomock.Any(), int64(5)).Return([]entity.ApiKey{apiKey}, nil)
+	apiKeyService.EXPECT().CreateApiKey(gomock.Any(), int64(5), entity.ApiKeyRequest{Name: "Nightly export", ExpiresInDays: 90}, int64(1)).
+		Return(entity.IssuedApiKey{ApiKey: apiKey, Key: "sk_Xq3v9LmA2cT7pR0wYbN4eJ8sKd1hGzUo6iVfQlE5aMx"}, nil)
+	apiKeyService.EXPECT().CreateApiKey(gomock.Any(), int64(2), entity.ApiKeyRequest{Name: "Nightly export"}, int64(1)).Return(entity.IssuedApiKey{}, service.ErrNotServiceAccount)
+	apiKeyService.EXPECT().RotateApiKey(gomock.Any(), int64(3), int64(1)).Return(entity.IssuedApiKey{ApiKey: apiKey, Key: "sk_Bv7kR2nWq9LmA2cT7pR0wYbN4eJ8sKd1hGzUo6iVfQl"}, nil)
+	apiKeyService.EXPECT().RevokeApiKey(gomock.Any(), int64(3)).Return(nil)
+	apiKeyService.EXPECT().RevokeApiKey(gomock.Any(), int64(4)).Return(service.ErrApiKeyRevoked)
+
+	oauthClient := entity.OAuthClient{ID: 3, UserID: 5, Name: "Billing integration", ClientID: "0f8fad5b-d9cb-469f-a165-70867728950e", CreatedBy: 1, CreatedAt: time.Now()}
+	oauthClientService.EXPECT().IssueClientCredentialsToken(gomock.Any(), entity.ClientCredentialsRequest{GrantType: "client_credentials", ClientID: oauthClient.ClientID, ClientSecret: "cs_secret", Scope: "consumers:read"}).
+		Return(entity.ClientCredentialsResponse{AccessToken: "access-token", TokenType: "Bearer", ExpiresIn: 3600, Scope: "consumers:read"}, nil)
+	oauthClientService.EXPECT().IssueClientCredentialsToken(gomock.Any(), entity.ClientCredentialsRequest{GrantType: "client_credentials", ClientID: oauthClient.ClientID, ClientSecret: "cs_wrong"}).
+		Return(entity.ClientCredentialsResponse{}, service.ErrInvalidClient)
+	oauthClientService.EXPECT().IssueClientCredentialsToken(gomock.Any(), entity.ClientCredentialsRequest{GrantType: "password", ClientID: oauthClient.ClientID, ClientSecret: "cs_secret"}).
+		Return(entity.ClientCredentialsResponse{}, service.ErrUnsupportedGrantType)
+	oauthClientService.EXPECT().GetOAuthClients(gomock.Any(), int64(5)).Return([]entity.OAuthClient{oauthClient}, nil)
+	oauthClientService.EXPECT().CreateOAuthClient(gomock.Any(), int64(5), entity.OAuthClientRequest{Name: "Billing integration"}, int64(1)).
+		Return(entity.IssuedOAuthClient{OAuthClient: oauthClient, ClientSecret: "cs_Xq3v9LmA2cT7pR0wYbN4eJ8sKd1hGzUo6iVfQlE5aMx"}, nil)
+	oauthClientService.EXPECT().RevokeOAuthClient(gomock.Any(), int64(3)).Return(nil)
+	oauthClientService.EXPECT().RevokeOAuthClient(gomock.Any(), int64(4)).Return(service.ErrOAuthClientRevoked)
+
+	attemptedAt := time.Now()
+	deadDelivery := entity.WebhookDelivery{ID: 7, Event: entity.WebhookEventConsumerCreated, URL: "https://hooks.example.com/consumers", Payload: `{"event":"consumer.created"}`,
+		Status: entity.WebhookDeliveryDead, Attempts: 5, LastAttemptAt: &attemptedAt, ResponseStatus: http.StatusServiceUnavailable, LastError: "unexpected response status 503",
+		CreatedAt: attemptedAt, UpdatedAt: attemptedAt}
+	redelivered := deadDelivery
+	redelivered.Status, redelivered.Attempts, redelivered.ResponseStatus, redelivered.LastError = entity.WebhookDeliverySucceeded, 1, http.StatusOK, ""
+	webhookService.EXPECT().GetFailedDeliveries(gomock.Any(), "DEAD", 1, 10).Return([]entity.WebhookDelivery{deadDelivery}, nil)
+	webhookService.EXPECT().GetFailedDeliveries(gomock.Any(), "PENDING", 1, 10).Return(nil, service.ErrInvalidWebhookStatus)
+
+	actorID := int64(1)
+	auditService.EXPECT().GetAuditEvents(gomock.Any(), entity.AuditEventFilter{Action: entity.AuditActionLogin}, 1, 10).Return([]entity.AuditEvent{{
+		ID: 1, EventID: "5f0c6b9e-2d4a-4c3b-9a51-0d2f1c7e8b6a", Action: entity.AuditActionLogin, ActorID: &actorID, ActorUsername: "admin",
+		TargetType: entity.AuditTargetUser, TargetID: "1", IPAddress: "192.0.2.1", RequestID: "req-1", CreatedAt: time.Now(),
+	}}, nil)
+	webhookService.EXPECT().GetDelivery(gomock.Any(), int64(7)).Return(deadDelivery, nil)
+	webhookService.EXPECT().GetDelivery(gomock.Any(), int64(99)).Return(entity.WebhookDelivery{}, gorm.ErrRecordNotFound)
+	webhookService.EXPECT().Redeliver(gomock.Any(), int64(7)).Return(redelivered, nil)
+	webhookService.EXPECT().Redeliver(gomock.Any(), int64(8)).Return(entity.WebhookDelivery{}, service.ErrWebhookNotRedeliverable)
+
+	enabledAt := time.Now()
+	mfaService.EXPECT().GetStatus(gomock.Any(), int64(2)).Return(entity.MFAStatusResponse{Enabled: true, EnabledAt: &enabledAt}, nil)
+	mfaService.EXPECT().Enroll(gomock.Any(), int64(2)).Return(entity.MFAEnrollResponse{Secret: "JBSWY3DPEHPK3PXP", ProvisioningURI: "otpauth://totp/Demo:userone?secret=JBSWY3DPEHPK3PXP&issuer=Demo"}, nil)
+	mfaService.EXPECT().Enroll(gomock.Any(), int64(2)).Return(entity.MFAEnrollResponse{}, service.ErrMFAAlreadyEnabled)
+	mfaService.EXPECT().Activate(gomock.Any(), int64(2), gomock.Cond(func(req entity.MFACodeRequest) bool { return req.Code == "123456" })).
+		Return(entity.MFAStatusResponse{Enabled: true, EnabledAt: &enabledAt}, nil)
+	mfaService.EXPECT().Activate(gomock.Any(), int64(2), gomock.Cond(func(req entity.MFACodeRequest) bool { return req.Code == "000000" })).
+		Return(entity.MFAStatusResponse{}, service.ErrInvalidMFACode)
+	mfaService.EXPECT().Disable(gomock.Any(), int64(2), gomock.Cond(func(req entity.MFACodeRequest) bool { return req.Code == "123456" })).Return(nil)
+	mfaService.EXPECT().Disable(gomock.Any(), int64(2), gomock.Cond(func(req entity.MFACodeRequest) bool { return req.Code == "654321" })).Return(service.ErrMFANotEnabled)
+
+	admin := testsupport.NewTokenBuilder().Build(t)
+	user := testsupport.NewTokenBuilder().WithUser(2, "userone", "userone@example.com").WithRoles("ROLE_USER").Build(t)
+	consumerBody := map[string]string{
+		"fullname":  "John Doe",
+		"username":  "johndoe",
+		"email":     "john.doe@example.com",
+		"phone":     "+6281234567890",
+		"address":   "Jl. Sudirman No. 1, Jakarta",
+		"birthDate": "1990-05-17",
+	}
+
+	cases := []contractCase{
+		{"login", "POST", "/auth/login", "", map[string]string{"username": "admin", "password": "P@ssw0rd"}, http.StatusOK},
+		{"login with wrong password", "POST", "/auth/login", "", map[string]string{"username": "admin", "password": "Wr0ngP@ss"}, http.StatusUnauthorized},
+		{"login over the session limit", "POST", "/auth/login", "", map[string]string{"username": "userone", "password": "P@ssw0rd"}, http.StatusConflict},
+		{"login with malformed body", "POST", "/auth/login", "", "not-an-object", http.StatusBadRequest},
+		{"login with unknown field", "POST", "/auth/login", "", map[string]string{"username": "admin", "pasword": "P@ssw0rd"}, http.StatusBadRequest},
+		{"login with two-factor authentication", "POST", "/auth/login", "", map[string]string{"username": "mfauser", "password": "P@ssw0rd"}, http.StatusOK},
+		{"verify MFA code", "POST", "/auth/mfa/verify", "", map[string]string{"mfaToken": "mfa-token", "code": "123456"}, http.StatusOK},
+		{"verify invalid MFA code", "POST", "/auth/mfa/verify", "", map[string]string{"mfaToken": "mfa-token", "code": "000000"}, http.StatusUnauthorized},
+		{"verify MFA code over the rate limit", "POST", "/auth/mfa/verify", "", map[string]string{"mfaToken": "mfa-token", "code": "123456"}, http.StatusTooManyRequests},
+		{"refresh token", "POST", "/auth/refresh-token", "", map[string]string{"refreshToken": "refresh-token"}, http.StatusOK},
+		{"refresh token with unknown field", "POST", "/auth/refresh-token", "", map[string]string{"refresh_token": "refresh-token"}, http.StatusBadRequest},
+		{"logout", "POST", "/auth/logout", user, map[string]string{"refreshToken": "refresh-token"}, http.StatusOK},
+		{"logout with the refresh token of another user", "POST", "/auth/logout", user, map[string]string{"refreshToken": "bob-refresh-token"}, http.StatusUnauthorized},
+		{"logout without refresh token", "POST", "/auth/logout", user, "not-an-object", http.StatusBadRequest},
+		{"logout without token", "POST", "/auth/logout", "", map[string]string{"refreshToken": "refresh-token"}, http.StatusUnauthorized},
+		{"register", "POST", "/auth/register", "", map[string]string{"username": "janedoe", "password": "P@ssw0rd", "email": "jane.doe@example.com", "firstName": "Jane"}, http.StatusCreated},
+		{"register with taken username", "POST", "/auth/register", "", map[string]string{"username": "userone", "password": "P@ssw0rd", "email": "jane.doe@example.com", "firstName": "Jane"}, http.StatusConflict},
+		{"register with malformed body", "POST", "/auth/register", "", "not-an-object", http.StatusBadRequest},
+		{"register over the rate limit", "POST", "/auth/register", "", map[string]string{"username": "janedoe", "password": "P@ssw0rd", "email": "jane.doe@example.com", "firstName": "Jane"}, http.StatusTooManyRequests},
+		{"forgot password", "POST", "/auth/forgot-password", "", map[string]string{"email": "jane.doe@example.com"}, http.StatusOK},
+		{"forgot password with invalid email", "POST", "/auth/forgot-password", "", map[string]string{"email": "not-an-email"}, http.StatusBadRequest},
+		{"forgot password over the rate limit", "POST", "/auth/forgot-password", "", map[string]string{"email": "jane.doe@example.com"}, http.StatusTooManyRequests},
+		{"reset password", "POST", "/auth/reset-password", "", map[string]string{"token": "reset-token", "newPassword": "N3w-P@ssw0rd"}, http.StatusOK},
+		{"reset password with used token", "POST", "/auth/reset-password", "", map[string]string{"token": "used-token", "newPassword": "N3w-P@ssw0rd"}, http.StatusBadRequest},
+		{"list consumers", "GET", "/api/v1/consumers", user, nil, http.StatusOK},
+		{"list consumers with invalid page", "GET", "/api/v1/consumers?page=0", user, nil, http.StatusBadRequest},
+		{"list consumers with limit over the maximum", "GET", "/api/v1/consumers?limit=100000", user, nil, http.StatusBadRequest},
+		{"list consumers without token", "GET", "/api/v1/consumers", "", nil, http.StatusUnauthorized},
+		{"list consumers including the deleted ones", "GET", "/api/v1/consumers?includeDeleted=true", admin, nil, http.StatusOK},
+		{"list consumers including the deleted ones as user", "GET", "/api/v1/consumers?includeDeleted=true", user, nil, http.StatusForbidden},
+		{"list consumers with invalid includeDeleted", "GET", "/api/v1/consumers?includeDeleted=maybe", admin, nil, http.StatusBadRequest},
+		{"list active consumers", "GET", "/api/v1/consumers/active", user, nil, http.StatusOK},
+		{"list inactive consumers when empty", "GET", "/api/v1/consumers/inactive", user, nil, http.StatusNotFound},
+		{"list suspended consumers on failure", "GET", "/api/v1/consumers/suspended", user, nil, http.StatusInternalServerError},
+		{"stream consumers", "GET", "/api/v1/consumers/stream", user, nil, http.StatusOK},
+		{"stream consumers on failure", "GET", "/api/v1/consumers/stream", admin, nil, http.StatusInternalServerError},
+		{"get consumer", "GET", "/api/v1/consumers/" + active.ID, user, nil, http.StatusOK},
+		{"get unknown consumer", "GET", "/api/v1/consumers/unknown", user, nil, http.StatusNotFound},
+		{"create consumer", "POST", "/api/v1/consumers", admin, consumerBody, http.StatusCreated},
+		{"create consumer with unknown field", "POST", "/api/v1/consumers", admin, map[string]string{"fullname": "John Doe", "phonee": "+6281234567890"}, http.StatusBadRequest},
+		{"create consumer as user", "POST", "/api/v1/consumers", user, consumerBody, http.StatusForbidden},
+		{"check consumer availability", "POST", "/api/v1/consumers/check-availability", admin, map[string]string{"username": "johndoe", "email": "new@example.com"}, http.StatusOK},
+		{"check consumer availability without value", "POST", "/api/v1/consumers/check-availability", admin, map[string]string{}, http.StatusBadRequest},
+		{"check consumer availability over the rate limit", "POST", "/api/v1/consumers/check-availability", admin, map[string]string{"username": "johndoe", "email": "new@example.com"}, http.StatusTooManyRequests},
+		{"check consumer availability as user", "POST", "/api/v1/consumers/check-availability", user, map[string]string{"username": "johndoe"}, http.StatusForbidden},
+		{"update consumer status", "PATCH", "/api/v1/consumers/" + active.ID + "?status=suspended", admin, nil, http.StatusOK},
+		{"update consumer with invalid status", "PATCH", "/api/v1/consumers/" + active.ID + "?status=unknown", admin, nil, http.StatusBadRequest},
+		{"update consumer", "PATCH", "/api/v1/consumers/" + active.ID, admin, map[string]string{"address": "Jl. Sudirman No. 1, Jakarta"}, http.StatusOK},
+		{"update consumer with the email of another", "PATCH", "/api/v1/consumers/" + active.ID, admin, map[string]string{"email": "jane.doe@example.com"}, http.StatusConflict},
+		{"update consumer username", "PATCH", "/api/v1/consumers/" + active.ID, admin, map[string]string{"username": "janedoe"}, http.StatusBadRequest},
+		{"delete consumer", "DELETE", "/api/v1/consumers/" + active.ID, admin, nil, http.StatusOK},
+		{"delete unknown consumer", "DELETE", "/api/v1/consumers/unknown", admin, nil, http.StatusNotFound},
+		{"delete consumer as user", "DELETE", "/api/v1/consumers/" + active.ID, user, nil, http.StatusForbidden},
+		{"restore consumer", "POST", "/api/v1/consumers/" + deleted.ID + "/restore", admin, nil, http.StatusOK},
+		{"restore consumer not deleted", "POST", "/api/v1/consumers/" + active.ID + "/restore", admin, nil, http.StatusConflict},
+		{"get quota usage", "GET", "/api/v1/me/quota", user, nil, http.StatusOK},
+		{"get notification preferences", "GET", "/api/v1/users/me/notification-preferences", user, nil, http.StatusOK},
+		{"update notification preferences", "PUT", "/api/v1/users/me/notification-preferences", user, map[string]bool{"newDeviceLogin": false, "passwordChanged": true, "mfaDisabled": true}, http.StatusOK},
+		{"update notification preferences with malformed body", "PUT", "/api/v1/users/me/notification-preferences", user, "not-an-object", http.StatusBadRequest},
+		{"list sessions", "GET", "/api/v1/users/me/sessions", user, nil, http.StatusOK},
+		{"remove session", "DELETE", "/api/v1/users/me/sessions/" + sessionID, user, nil, http.StatusOK},
+		{"remove unknown session", "DELETE", "/api/v1/users/me/sessions/unknown", user, nil, http.StatusNotFound},
+		{"change password", "PUT", "/api/v1/users/me/password", user, map[string]string{"currentPassword": "P@ssw0rd", "newPassword": "N3w-P@ssw0rd"}, http.StatusOK},
+		{"change password with wrong current password", "PUT", "/api/v1/users/me/password", user, map[string]string{"currentPassword": "Wr0ngP@ss", "newPassword": "N3w-P@ssw0rd"}, http.StatusBadRequest},
+		{"change password over the rate limit", "PUT", "/api/v1/users/me/password", user, map[string]string{"currentPassword": "P@ssw0rd", "newPassword": "N3w-P@ssw0rd"}, http.StatusTooManyRequests},
+		{"get MFA status", "GET", "/api/v1/users/me/mfa", user, nil, http.StatusOK},
+		{"enroll MFA", "POST", "/api/v1/users/me/mfa/enroll", user, nil, http.StatusOK},
+		{"enroll MFA already enabled", "POST", "/api/v1/users/me/mfa/enroll", user, nil, http.StatusConflict},
+		{"activate MFA", "POST", "/api/v1/users/me/mfa/activate", user, map[string]string{"code": "123456"}, http.StatusOK},
+		{"activate MFA with invalid code", "POST", "/api/v1/users/me/mfa/activate", user, map[string]string{"code": "000000"}, http.StatusBadRequest},
+		{"disable MFA", "POST", "/api/v1/users/me/mfa/disable", user, map[string]string{"code": "123456"}, http.StatusOK},
+		{"disable MFA not enabled", "POST", "/api/v1/users/me/mfa/disable", user, map[string]string{"code": "654321"}, http.StatusConflict},
+		{"token stats", "GET", "/api/v1/admin/token-stats?from=2025-01-15&to=2025-01-15", admin, nil, http.StatusOK},
+		{"token stats with inverted range", "GET", "/api/v1/admin/token-stats?from=2025-01-16&to=2025-01-15", admin, nil, http.StatusBadRequest},
+		{"token stats as user", "GET", "/api/v1/admin/token-stats", user, nil, http.StatusForbidden},
+		{"revoke user tokens", "POST", "/api/v1/admin/users/2/revoke-tokens", admin, nil, http.StatusOK},
+		{"revoke tokens of unknown user", "POST", "/api/v1/admin/users/99/revoke-tokens", admin, nil, http.StatusNotFound},
+		{"revoke tokens with invalid ID", "POST", "/api/v1/admin/users/abc/revoke-tokens", admin, nil, http.StatusBadRequest},
+		{"revoke user tokens as user", "POST", "/api/v1/admin/users/2/revoke-tokens", user, nil, http.StatusForbidden},
+		{"get api keys", "GET", "/api/v1/admin/users/5/api-keys", admin, nil, http.StatusOK},
+		{"create api key", "POST", "/api/v1/admin/users/5/api-keys", admin, map[string]interface{}{"name": "Nightly export", "expiresInDays": 90}, http.StatusCreated},
+		{"create api key for user account", "POST", "/api/v1/admin/users/2/api-keys", admin, map[string]string{"name": "Nightly export"}, http.StatusBadRequest},
+		{"create api key as user", "POST", "/api/v1/admin/users/5/api-keys", user, map[string]string{"name": "Nightly export"}, http.StatusForbidden},
+		{"rotate api key", "POST", "/api/v1/admin/api-keys/3/rotate", admin, nil, http.StatusOK},
+		{"revoke api key", "DELETE", "/api/v1/admin/api-keys/3", admin, nil, http.StatusOK},
+		{"revoke revoked api key", "DELETE", "/api/v1/admin/api-keys/4", admin, nil, http.StatusConflict},
+		{"client credentials token", "POST", "/auth/token", "", url.Values{"grant_type": {"client_credentials"}, "client_id": {oauthClient.ClientID}, "client_secret": {"cs_secret"}, "scope": {"consumers:read"}}, http.StatusOK},
+		{"client credentials token with wrong secret", "POST", "/auth/token", "", url.Values{"grant_type": {"client_credentials"}, "client_id": {oauthClient.ClientID}, "client_secret": {"cs_wrong"}}, http.StatusUnauthorized},
+		{"token with password grant", "POST", "/auth/token", "", url.Values{"grant_type": {"password"}, "client_id": {oauthClient.ClientID}, "client_secret": {"cs_secret"}}, http.StatusBadRequest},
+		{"get oauth clients", "GET", "/api/v1/admin/users/5/oauth-clients", admin, nil, http.StatusOK},
+		{"create oauth client", "POST", "/api/v1/admin/users/5/oauth-clients", admin, map[string]string{"name": "Billing integration"}, http.StatusCreated},
+		{"create oauth client as user", "POST", "/api/v1/admin/users/5/oauth-clients", user, map[string]string{"name": "Billing integration"}, http.StatusForbidden},
+		{"revoke oauth client", "DELETE", "/api/v1/admin/oauth-clients/3", admin, nil, http.StatusOK},
+		{"revoke revoked oauth client", "DELETE", "/api/v1/admin/oauth-clients/4", admin, nil, http.StatusConflict},
+		{"suspend user", "PUT", "/api/v1/admin/users/2/state", admin, map[string]string{"state": "SUSPENDED", "reason": "Account compromised"}, http.StatusOK},
+		{"activate active user", "PUT", "/api/v1/admin/users/2/state", admin, map[string]string{"state": "ACTIVE"}, http.StatusConflict},
+		{"change state of unknown user", "PUT", "/api/v1/admin/users/99/state", admin, map[string]string{"state": "DISABLED", "reason": "Left the company"}, http.StatusNotFound},
+		{"change user state as user", "PUT", "/api/v1/admin/users/2/state", user, map[string]string{"state": "ACTIVE"}, http.StatusForbidden},
+		{"list dead webhook deliveries", "GET", "/api/v1/admin/webhook-deliveries?status=dead", admin, nil, http.StatusOK},
+		{"list webhook deliveries with invalid status", "GET", "/api/v1/admin/webhook-deliveries?status=pending", admin, nil, http.StatusBadRequest},
+		{"list webhook deliveries as user", "GET", "/api/v1/admin/webhook-deliveries", user, nil, http.StatusForbidden},
+		{"list audit events", "GET", "/api/v1/audit-events?action=login", admin, nil, http.StatusOK},
+		{"list audit events with invalid time", "GET", "/api/v1/audit-events?from=yesterday", admin, nil, http.StatusBadRequest},
+		{"list audit events as user", "GET", "/api/v1/audit-events", user, nil, http.StatusForbidden},
+		{"get webhook delivery", "GET", "/api/v1/admin/webhook-deliveries/7", admin, nil, http.StatusOK},
+		{"get unknown webhook delivery", "GET", "/api/v1/admin/webhook-deliveries/99", admin, nil, http.StatusNotFound},
+		{"redeliver webhook", "POST", "/api/v1/admin/webhook-deliveries/7/redeliver", admin, nil, http.StatusOK},
+		{"redeliver succeeded webhook", "POST", "/api/v1/admin/webhook-deliveries/8/redeliver", admin, nil, http.StatusConflict},
+		{"get security settings", "GET", "/api/v1/admin/security-settings", admin, nil, http.StatusOK},
+		{"get security settings as user", "GET", "/api/v1/admin/security-settings", user, nil, http.StatusForbidden},
+		{"update security settings", "PUT", "/api/v1/admin/security-settings", admin, map[string]interface{}{"allowedOrigins": []string{"https://admin.example.com"},
+			"allowedHeaders": []string{"X-Tenant-ID"}, "securityHeaders": map[string]string{"Referrer-Policy": "strict-origin-when-cross-origin"}}, http.StatusOK},
+		{"update security settings with HSTS", "PUT", "/api/v1/admin/security-settings", admin, map[string]interface{}{"allowedOrigins": []string{},
+			"allowedHeaders": []string{}, "securityHeaders": map[string]string{"Strict-Transport-Security": "max-age=0"}}, http.StatusBadRequest},
+		{"update security settings with unknown field", "PUT", "/api/v1/admin/security-settings", admin, map[string]interface{}{"origins": []string{}}, http.StatusBadRequest},
+		{"get all users", "GET", "/api/v1/users", admin, nil, http.StatusOK},
+		{"get all users as user", "GET", "/api/v1/users", user, nil, http.StatusForbidden},
+		{"get user by ID", "GET", "/api/v1/users/3", admin, nil, http.StatusOK},
+		{"get unknown user", "GET", "/api/v1/users/99", admin, nil, http.StatusNotFound},
+		{"get user with invalid ID", "GET", "/api/v1/users/abc", admin, nil, http.StatusBadRequest},
+		{"create user", "POST", "/api/v1/users", admin, map[string]interface{}{"username": "usertwo", "password": "P@ssw0rd", "email": "usertwo@example.com", "firstName": "User", "roles": []string{"ROLE_USER"}}, http.StatusCreated},
+		{"create user with taken username", "POST", "/api/v1/users", admin, map[string]interface{}{"username": "userone", "password": "P@ssw0rd", "email": "userone@example.com", "firstName": "User"}, http.StatusConflict},
+		{"update user", "PUT", "/api/v1/users/3", admin, map[string]interface{}{"email": "usertwo@example.com", "firstName": "User", "userType": "USER_ACCOUNT", "roles": []string{"ROLE_USER"}}, http.StatusOK},
+		{"lock user", "PATCH", "/api/v1/users/3", admin, map[string]string{"action": "lock", "reason": "Left the company"}, http.StatusOK},
+		{"lock user without action", "PATCH", "/api/v1/users/3", admin, map[string]string{"reason": "Left the company"}, http.StatusBadRequest},
+		{"delete user", "DELETE", "/api/v1/users/3", admin, nil, http.StatusOK},
+		{"delete own account", "DELETE", "/api/v1/users/1", admin, nil, http.StatusBadRequest},
+		{"delete user as user", "DELETE", "/api/v1/users/3", user, nil, http.StatusForbidden},
+		{"assign user role", "POST", "/api/v1/users/3/roles", admin, map[string][]string{"add": {"ROLE_AUDITOR"}}, http.StatusOK},
+		{"remove last user role", "POST", "/api/v1/users/3/roles", admin, map[string][]string{"remove": {"ROLE_USER"}}, http.StatusBadRequest},
+		{"assign user role with unknown field", "POST", "/api/v1/users/3/roles", admin, map[string][]string{"roles": {"ROLE_AUDITOR"}}, http.StatusBadRequest},
+		{"assign user role as user", "POST", "/api/v1/users/3/roles", user, map[string][]string{"add": {"ROLE_AUDITOR"}}, http.StatusForbidden},
+		{"get all roles", "GET", "/api/v1/roles", admin, nil, http.StatusOK},
+		{"get all roles as user", "GET", "/api/v1/roles", user, nil, http.StatusForbidden},
+		{"create role", "POST", "/api/v1/roles", admin, map[string]string{"roleName": "ROLE_AUDITOR", "description": auditorDescription}, http.StatusCreated},
+		{"create role with taken name", "POST", "/api/v1/roles", admin, map[string]string{"roleName": "ROLE_USER"}, http.StatusConflict},
+		{"create role with invalid name", "POST", "/api/v1/roles", admin, map[string]string{"roleName": "auditor"}, http.StatusBadRequest},
+		{"update role", "PUT", "/api/v1/roles/4", admin, map[string]string{"roleName": "ROLE_AUDITOR", "description": auditorDescription}, http.StatusOK},
+		{"rename built-in role", "PUT", "/api/v1/roles/1", admin, map[string]string{"roleName": "ROLE_MEMBER"}, http.StatusBadRequest},
+		{"delete role", "DELETE", "/api/v1/roles/4", admin, nil, http.StatusOK},
+		{"delete role assigned to users", "DELETE", "/api/v1/roles/5", admin, nil, http.StatusConflict},
+		{"delete unknown role", "DELETE", "/api/v1/roles/99", admin, nil, http.StatusNotFound},
+		{"delete role with invalid ID", "DELETE", "/api/v1/roles/abc", admin, nil, http.StatusBadRequest},
+		{"get all permissions", "GET", "/api/v1/permissions", admin, nil, http.StatusOK},
+		{"get all permissions as user", "GET", "/api/v1/permissions", user, nil, http.StatusForbidden},
+		{"get role permissions", "GET", "/api/v1/roles/1/permissions", admin, nil, http.StatusOK},
+		{"get permissions of unknown role", "GET", "/api/v1/roles/99/permissions", admin, nil, http.StatusNotFound},
+		{"set role permissions", "PUT", "/api/v1/roles/1/permissions", admin, map[string][]string{"permissions": {entity.PermissionConsumersRead}}, http.StatusOK},
+		{"set unknown role permission", "PUT", "/api/v1/roles/1/permissions", admin, map[string][]string{"permissions": {"consumers:delete"}}, http.StatusBadRequest},
+		{"get feature flags", "GET", "/api/v1/admin/feature-flags", admin, nil, http.StatusOK},
+		{"get feature flags as user", "GET", "/api/v1/admin/feature-flags", user, nil, http.StatusForbidden},
+		{"disable feature", "PUT", "/api/v1/admin/feature-flags/consumers.write", admin, map[string]interface{}{"disabled": true, "reason": reason}, http.StatusOK},
+		{"disable unknown feature", "PUT", "/api/v1/admin/feature-flags/consumers.delete", admin, map[string]interface{}{"disabled": true}, http.StatusNotFound},
+		{"switch feature without state", "PUT", "/api/v1/admin/feature-flags/consumers.write", admin, map[string]interface{}{"reason": reason}, http.StatusBadRequest},
+		{"get rate limit overrides", "GET", "/api/v1/admin/rate-limit-overrides", admin, nil, http.StatusOK},
+		{"get rate limit overrides as user", "GET", "/api/v1/admin/rate-limit-overrides", user, nil, http.StatusForbidden},
+		{"override rate limits", "PUT", "/api/v1/admin/rate-limit-overrides/partner", admin, map[string]interface{}{"factor": factor, "reason": reason}, http.StatusOK},
+		{"override rate limits without factor", "PUT", "/api/v1/admin/rate-limit-overrides/partner", admin, map[string]interface{}{"reason": reason}, http.StatusBadRequest},
+		{"delete rate limit override", "DELETE", "/api/v1/admin/rate-limit-overrides/partner", admin, nil, http.StatusOK},
+		{"delete unknown rate limit override", "DELETE", "/api/v1/admin/rate-limit-overrides/unknown", admin, nil, http.StatusNotFound},
+		{"get config snapshot", "GET", "/api/v1/admin/config-snapshot", admin, nil, http.StatusOK},
+		{"get config snapshot as user", "GET", "/api/v1/admin/config-snapshot", user, nil, http.StatusForbidden},
+		{"stream events while disabled", "GET", "/api/v1/ws", admin, nil, http.StatusServiceUnavailable},
+		{"debug vars", "GET", "/debug/vars", admin, nil, http.StatusOK},
+		{"debug vars as user", "GET", "/debug/vars", user, nil, http.StatusForbidden},
+		{"metrics", "GET", "/metrics", "", nil, http.StatusOK},
+		{"readiness", "GET", "/readyz", "", nil, http.StatusOK},
+		{"openapi document", "GET", "/openapi.yaml", "", nil, http.StatusOK},
+		{"openapi archive", "GET", "/openapi.zip", "", nil, http.StatusOK},
+		{"rotate signing key", "POST", "/api/v1/admin/signing-keys/rotate", admin, nil, http.StatusCreated},
+		{"rotate signing key without key set", "POST", "/api/v1/admin/signing-keys/rotate", admin, nil, http.StatusConflict},
+		{"rotate signing key as user", "POST", "/api/v1/admin/signing-keys/rotate", user, nil, http.StatusForbidden},
+		{"json web key set", "GET", "/.well-known/jwks.json", "", nil, http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The form bodies are form-encoded, the other bodies are JSON
+			var payload []byte
+			contentType := "application/json"
+			if form, ok := tc.body.(url.Values); ok {
+				payload, contentType = []byte(form.Encode()), headers.FormContentType
+			} else if tc.body != nil {
+				payload, _ = json.Marshal(tc.body)
+			}
+
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewReader(payload))
+			req.Header.Set("Content-Type", contentType)
+			if tc.token != "" {
+				req.Header.Set("Authorization", testsupport.DefaultTokenType+" "+tc.token)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, tc.status, w.Code, w.Body.String())
+
+			// Find the documented operation and validate the response against it
+			route, pathParams, err := specRouter.FindRoute(req)
+			require.NoError(t, err, "%s %s is not documented", tc.method, tc.path)
+
+			input := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: &openapi3filter.RequestValidationInput{
+					Request:    req,
+					PathParams: pathParams,
+					Route:      route,
+				},
+				Status: w.Code,
+				Header: w.Header(),
+				Body:   io.NopCloser(bytes.NewReader(w.Body.Bytes())),
+				Options: &openapi3filter.Options{
+					IncludeResponseStatus: true,
+					MultiError:            true,
+				},
+			}
+			require.NoError(t, openapi3filter.ValidateResponse(context.Background(), input), w.Body.String())
+		})
+	}
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	doc, _ := loadSpec(t)
+	testsupport.SetupJWTEnv(t)
+
+	// Convert gin path parameters (:id) into OpenAPI path templates ({id})
+	param := regexp.MustCompile(`:([^/]+)`)
+	for _, route := range routes.SetupRouter().Routes() {
+		path := param.ReplaceAllString(route.Path, "{$1}")
+
+		item := doc.Paths.Find(path)
+		require.NotNil(t, item, "route %s %s is not documented", route.Method, path)
+		require.NotNil(t, item.GetOperation(strings.ToUpper(route.Method)), "route %s %s is not documented", route.Method, path)
+	}
+}
@@ -0,0 +1,64 @@
+package test_diagnostics
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/diagnostics"
+)
+
+// unreachableConnector is a database connector failing every connection, the pool statistics need none.
+type unreachableConnector struct{}
+
+func (unreachableConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("unreachable")
+}
+
+func (unreachableConnector) Driver() driver.Driver {
+	return nil
+}
+
+func TestDatabasePoolStats_ReportsTheRegisteredPools(t *testing.T) {
+	db := sql.OpenDB(unreachableConnector{})
+	t.Cleanup(func() { _ = db.Close() })
+	database.PoolConfig{MaxOpenConns: 7, MaxIdleConns: 3, ConnMaxLifetime: time.Minute, ConnMaxIdleTime: time.Second}.Apply(db)
+
+	diagnostics.RegisterDatabasePool("test", db)
+	stats := diagnostics.DatabasePoolStats()
+	assert.Equal(t, diagnostics.PoolStats{MaxOpenConnections: 7}, stats["test"])
+
+	// A closed pool is no longer reported
+	diagnostics.UnregisterDatabasePool("test")
+	assert.NotContains(t, diagnostics.DatabasePoolStats(), "test")
+}
+
+func TestReadPoolConfig(t *testing.T) {
+	t.Setenv("DB_MAX_OPEN_CONNS", "")
+	t.Setenv("DB_MAX_IDLE_CONNS", "")
+	t.Setenv("DB_CONN_MAX_LIFETIME_SECOND", "")
+	t.Setenv("DB_CONN_MAX_IDLE_TIME_SECOND", "abc")
+	assert.Equal(t, database.PoolConfig{
+		MaxOpenConns:    database.DefaultMaxOpenConns,
+		MaxIdleConns:    database.DefaultMaxIdleConns,
+		ConnMaxLifetime: database.DefaultConnMaxLifetime,
+		ConnMaxIdleTime: database.DefaultConnMaxIdleTime,
+	}, database.ReadPoolConfig())
+
+	// The idle connections are kept within the open ones
+	t.Setenv("DB_MAX_OPEN_CONNS", "5")
+	t.Setenv("DB_CONN_MAX_LIFETIME_SECOND", "600")
+	t.Setenv("DB_CONN_MAX_IDLE_TIME_SECOND", "60")
+	assert.Equal(t, database.PoolConfig{
+		MaxOpenConns:    5,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: 10 * time.Minute,
+		ConnMaxIdleTime: time.Minute,
+	}, database.ReadPoolConfig())
+}
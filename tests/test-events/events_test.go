@@ -0,0 +1,288 @@
+package test_events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/anomaly"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/eventbus"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// receive returns the next event of the subscription, failing the test if none arrives in time.
+func receive(t *testing.T, sub *eventbus.Subscription) eventbus.Event {
+	t.Helper()
+
+	select {
+	case e := <-sub.Events():
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return eventbus.Event{}
+	}
+}
+
+// newEventServer starts a server streaming the events of the bus on /ws, authenticated as in the routes.
+func newEventServer(t *testing.T, bus *eventbus.Bus) *httptest.Server {
+	testsupport.SetupJWTEnv(t)
+	gin.SetMode(gin.TestMode)
+
+	cfg := testsupport.NewJWTConfig()
+	policy, err := eventbus.ParsePolicy(eventbus.DefaultRoleTopics)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/ws", authorization.WebSocketToken(cfg.TokenType), authorization.JwtValidationWithConfig(cfg, clock.New()),
+		handler.NewEventHandler(bus, policy).StreamEvents)
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// dial opens a WebSocket connection to the event server with the given subprotocols and headers.
+func dial(srv *httptest.Server, protocols []string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	dialer := websocket.Dialer{Subprotocols: protocols, HandshakeTimeout: time.Second}
+	return dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", header)
+}
+
+// waitForSubscribers waits until the bus has the given number of subscribers.
+func waitForSubscribers(t *testing.T, bus *eventbus.Bus, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool { return bus.Subscribers() == n }, time.Second, 5*time.Millisecond)
+}
+
+func TestBus_DeliversTheSubscribedTopics(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	bus := eventbus.NewBus(clk)
+	consumers := bus.Subscribe([]string{eventbus.TopicConsumerCreated}, 4)
+	anomalies := bus.Subscribe([]string{eventbus.TopicAnomalyDetected}, 4)
+	assert.Equal(t, 2, bus.Subscribers())
+
+	bus.Publish(eventbus.TopicConsumerCreated, "consumer")
+	bus.Publish(eventbus.TopicAnomalyDetected, "anomaly")
+
+	assert.Equal(t, eventbus.Event{Topic: eventbus.TopicConsumerCreated, OccurredAt: clk.Now(), Data: "consumer"}, receive(t, consumers))
+	assert.Equal(t, "anomaly", receive(t, anomalies).Data)
+	assert.Empty(t, consumers.Events())
+	assert.Empty(t, anomalies.Events())
+
+	consumers.Close()
+	assert.Equal(t, 1, bus.Subscribers())
+	bus.Publish(eventbus.TopicConsumerCreated, "consumer")
+}
+
+func TestBus_DropsTheEventsOfTheSlowSubscribers(t *testing.T) {
+	bus := eventbus.NewBus(clock.New())
+	sub := bus.Subscribe([]string{eventbus.TopicConsumerCreated}, 1)
+
+	bus.Publish(eventbus.TopicConsumerCreated, 1)
+	bus.Publish(eventbus.TopicConsumerCreated, 2)
+
+	assert.Equal(t, 1, receive(t, sub).Data)
+	assert.Equal(t, int64(1), sub.Dropped())
+}
+
+func TestBus_CloseEndsTheSubscriptions(t *testing.T) {
+	bus := eventbus.NewBus(clock.New())
+	sub := bus.Subscribe([]string{eventbus.TopicConsumerCreated}, 1)
+
+	bus.Close()
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+	assert.Zero(t, bus.Subscribers())
+
+	// Publishing to a closed bus, or to no bus, does nothing
+	bus.Publish(eventbus.TopicConsumerCreated, 1)
+	sub.Close()
+	var nilBus *eventbus.Bus
+	nilBus.Publish(eventbus.TopicConsumerCreated, 1)
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := eventbus.ParsePolicy("ROLE_ADMIN=consumer.created|anomaly.detected, ROLE_MODERATOR=consumer.suspended")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{eventbus.TopicAnomalyDetected, eventbus.TopicConsumerCreated}, policy.Topics([]string{"ROLE_ADMIN"}))
+	assert.Equal(t, []string{eventbus.TopicAnomalyDetected, eventbus.TopicConsumerCreated, eventbus.TopicConsumerSuspended},
+		policy.Topics([]string{"ROLE_ADMIN", "ROLE_MODERATOR"}))
+	assert.Empty(t, policy.Topics([]string{"ROLE_USER"}))
+
+	for _, value := range []string{"ROLE_ADMIN", "ROLE_ADMIN=unknown.topic", "=consumer.created"} {
+		_, err := eventbus.ParsePolicy(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	policy, err := eventbus.LoadPolicy()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, eventbus.Topics, policy.Topics([]string{"ROLE_ADMIN"}))
+
+	t.Setenv("EVENTS_ROLE_TOPICS", "ROLE_USER=consumer.created")
+	policy, err = eventbus.LoadPolicy()
+	require.NoError(t, err)
+	assert.Empty(t, policy.Topics([]string{"ROLE_ADMIN"}))
+	assert.Equal(t, []string{eventbus.TopicConsumerCreated}, policy.Topics([]string{"ROLE_USER"}))
+}
+
+func TestStreamEvents_TokenInTheSubprotocol(t *testing.T) {
+	bus := eventbus.NewBus(clock.New())
+	srv := newEventServer(t, bus)
+	token := testsupport.NewTokenBuilder().WithRoles("ROLE_ADMIN").Build(t)
+
+	conn, resp, err := dial(srv, []string{authorization.WebSocketTokenProtocol, token}, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, authorization.WebSocketTokenProtocol, resp.Header.Get("Sec-WebSocket-Protocol"))
+
+	waitForSubscribers(t, bus, 1)
+	bus.Publish(eventbus.TopicConsumerCreated, map[string]string{"id": "consumer-id"})
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, message, err := conn.ReadMessage()
+	require.NoError(t, err)
+
+	var e struct {
+		Topic string            `json:"topic"`
+		Data  map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(message, &e))
+	assert.Equal(t, eventbus.TopicConsumerCreated, e.Topic)
+	assert.Equal(t, "consumer-id", e.Data["id"])
+
+	// The subscription ends with the connection
+	conn.Close()
+	waitForSubscribers(t, bus, 0)
+}
+
+func TestStreamEvents_TokenInTheCookie(t *testing.T) {
+	bus := eventbus.NewBus(clock.New())
+	srv := newEventServer(t, bus)
+	token := testsupport.NewTokenBuilder().WithRoles("ROLE_ADMIN").Build(t)
+
+	header := http.Header{}
+	header.Set("Cookie", authorization.WebSocketTokenCookie+"="+token)
+	conn, _, err := dial(srv, nil, header)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	waitForSubscribers(t, bus, 1)
+
+	// The bus closing on shutdown closes the connection
+	bus.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+}
+
+func TestStreamEvents_ClosedWhenTheTokenExpires(t *testing.T) {
+	bus := eventbus.NewBus(clock.New())
+	srv := newEventServer(t, bus)
+	token := testsupport.NewTokenBuilder().WithRoles("ROLE_ADMIN").WithExpiry(2 * time.Second).Build(t)
+
+	conn, _, err := dial(srv, []string{authorization.WebSocketTokenProtocol, token}, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), err)
+}
+
+func TestStreamEvents_Rejected(t *testing.T) {
+	bus := eventbus.NewBus(clock.New())
+	srv := newEventServer(t, bus)
+
+	// Without a token
+	_, resp, err := dial(srv, nil, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// The roles of the user do not receive any event
+	user := testsupport.NewTokenBuilder().WithRoles("ROLE_USER").Build(t)
+	_, resp, err = dial(srv, []string{authorization.WebSocketTokenProtocol, user}, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// A request that is not an upgrade
+	admin := testsupport.NewTokenBuilder().WithRoles("ROLE_ADMIN").Build(t)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/ws", nil)
+	req.Header.Set("Authorization", testsupport.DefaultTokenType+" "+admin)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Zero(t, bus.Subscribers())
+}
+
+func TestConsumerService_PublishesEvents(t *testing.T) {
+	store := testsupport.UseMemoryDatabase(t)
+	bus := eventbus.NewBus(clock.New())
+	sub := bus.Subscribe(eventbus.Topics, 4)
+	s := service.NewConsumerService(database.DefaultStore(), repository.NewMemoryConsumerRepository(store), policyconfig.ConsumerListingPolicy{}, service.WithConsumerEvents(bus))
+
+	created, err := s.CreateConsumer(context.Background(), entity.Consumer{
+		Fullname:  "Event Consumer",
+		Username:  "eventconsumer",
+		Email:     "event-consumer@example.com",
+		Phone:     "6281234567890",
+		Address:   "123 Event Street",
+		BirthDate: &customtype.Date{Time: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)},
+	})
+	require.NoError(t, err)
+	e := receive(t, sub)
+	assert.Equal(t, eventbus.TopicConsumerCreated, e.Topic)
+	assert.Equal(t, created.ID, e.Data.(entity.Consumer).ID)
+
+	// Only the suspensions are published
+	_, err = s.UpdateConsumerStatus(context.Background(), created.ID, entity.ConsumerStatusInactive)
+	require.NoError(t, err)
+	_, err = s.UpdateConsumerStatus(context.Background(), created.ID, entity.ConsumerStatusSuspended)
+	require.NoError(t, err)
+
+	e = receive(t, sub)
+	assert.Equal(t, eventbus.TopicConsumerSuspended, e.Topic)
+	assert.Equal(t, entity.ConsumerStatusChange{Consumer: e.Data.(entity.ConsumerStatusChange).Consumer, PreviousStatus: entity.ConsumerStatusInactive}, e.Data)
+	assert.Empty(t, sub.Events())
+}
+
+func TestDetector_PublishesAnomalies(t *testing.T) {
+	bus := eventbus.NewBus(clock.New())
+	sub := bus.Subscribe([]string{eventbus.TopicAnomalyDetected}, 4)
+	d := anomaly.NewDetector(anomaly.Config{
+		Enabled:              true,
+		Window:               time.Minute,
+		FailedLoginThreshold: 2,
+		Actions:              []anomaly.Action{anomaly.ActionBlock},
+	}, clock.New())
+	d.SetEventPublisher(bus)
+
+	d.Record(anomaly.Event{Type: anomaly.EventFailedLogin, IP: "10.0.0.1"})
+	assert.Empty(t, sub.Events())
+	d.Record(anomaly.Event{Type: anomaly.EventFailedLogin, IP: "10.0.0.1"})
+
+	alert, ok := receive(t, sub).Data.(anomaly.Alert)
+	require.True(t, ok)
+	assert.Equal(t, "ip:10.0.0.1", alert.Subject)
+}
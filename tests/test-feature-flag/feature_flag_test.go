@@ -0,0 +1,218 @@
+package test_feature_flag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/featureflag"
+	request_filter "github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/request-filter"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newFeatureFlagService creates a feature flag service backed by an in-memory store, with its flags loaded.
+func newFeatureFlagService(t *testing.T, environment []string, ttl time.Duration) (service.FeatureFlagService, repository.FeatureFlagRepository, *clock.FakeClock) {
+	store := testsupport.UseMemoryDatabase(t)
+	repo := repository.NewMemoryFeatureFlagRepository(store)
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+
+	s := service.NewFeatureFlagService(database.DefaultStore(), repo, environment, ttl, clk)
+	require.NoError(t, s.LoadFlags())
+
+	return s, repo, clk
+}
+
+// newRouter creates a router serving GET /consumers for the consumer reads, and POST /consumers for the consumer writes.
+func newRouter(flags *featureflag.Cache) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.GET("/consumers", request_filter.RequireFeature(flags, featureflag.ConsumerReads), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/consumers", request_filter.RequireFeature(flags, featureflag.ConsumerWrites), func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	return r
+}
+
+// do performs a request without body against the router.
+func do(router http.Handler, method string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, "/consumers", nil))
+	return w
+}
+
+// disable returns the request disabling a feature for the given reason.
+func disable(reason string) entity.FeatureFlagRequest {
+	disabled := true
+	return entity.FeatureFlagRequest{Disabled: &disabled, Reason: &reason}
+}
+
+func TestParseDisabled(t *testing.T) {
+	names, err := featureflag.ParseDisabled(" consumers.write, AUTH.REGISTER,,")
+	require.NoError(t, err)
+	assert.Equal(t, []string{featureflag.ConsumerWrites, featureflag.Registration}, names)
+
+	names, err = featureflag.ParseDisabled("")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	_, err = featureflag.ParseDisabled("consumers.write,consumers.delete")
+	assert.ErrorContains(t, err, `unknown feature "consumers.delete"`)
+}
+
+func TestCache(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	loaded := map[string]string{featureflag.ConsumerWrites: "Migration"}
+	loads := 0
+	c := featureflag.NewCache([]string{featureflag.Registration}, func() (map[string]string, error) {
+		loads++
+		return loaded, nil
+	}, time.Minute, clk)
+
+	// The features of the environment are disabled before the cache is loaded, the database is not queried
+	disabled, _ := c.Disabled(featureflag.Registration)
+	assert.True(t, disabled)
+	disabled, _ = c.Disabled(featureflag.ConsumerWrites)
+	assert.False(t, disabled)
+	assert.Zero(t, loads)
+
+	require.NoError(t, c.Load())
+	disabled, reason := c.Disabled(featureflag.ConsumerWrites)
+	assert.True(t, disabled)
+	assert.Equal(t, "Migration", reason)
+
+	// The flags are reloaded once they are older than the TTL, or after Invalidate
+	loaded = map[string]string{}
+	disabled, _ = c.Disabled(featureflag.ConsumerWrites)
+	assert.True(t, disabled)
+	clk.Advance(time.Minute)
+	disabled, _ = c.Disabled(featureflag.ConsumerWrites)
+	assert.False(t, disabled)
+
+	loaded = map[string]string{featureflag.ConsumerReads: ""}
+	c.Invalidate()
+	disabled, _ = c.Disabled(featureflag.ConsumerReads)
+	assert.True(t, disabled)
+	assert.Equal(t, 3, loads)
+}
+
+func TestCache_KeepsThePreviousFlagsOnError(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	var loadErr error
+	c := featureflag.NewCache(nil, func() (map[string]string, error) {
+		return map[string]string{featureflag.ConsumerWrites: ""}, loadErr
+	}, time.Minute, clk)
+	require.NoError(t, c.Load())
+
+	loadErr = errors.New("database unavailable")
+	clk.Advance(time.Minute)
+	disabled, _ := c.Disabled(featureflag.ConsumerWrites)
+	assert.True(t, disabled)
+
+	// A nil cache disables no feature
+	var nilCache *featureflag.Cache
+	disabled, _ = nilCache.Disabled(featureflag.ConsumerWrites)
+	assert.False(t, disabled)
+}
+
+func TestFeatureFlagService_GetFeatureFlags(t *testing.T) {
+	s, _, _ := newFeatureFlagService(t, []string{featureflag.Registration}, time.Minute)
+
+	flags, err := s.GetFeatureFlags(context.Background())
+	require.NoError(t, err)
+	require.Len(t, flags, len(featureflag.Features))
+
+	names := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		names = append(names, flag.Name)
+		assert.NotEmpty(t, flag.Description)
+		assert.False(t, flag.Disabled)
+		assert.Equal(t, flag.Name == featureflag.Registration, flag.DisabledByEnvironment, flag.Name)
+	}
+	assert.Equal(t, featureflag.Names(), names)
+}
+
+func TestFeatureFlagService_UpdateFeatureFlag(t *testing.T) {
+	s, repo, clk := newFeatureFlagService(t, nil, time.Minute)
+	router := newRouter(s.Flags())
+
+	flag, err := s.UpdateFeatureFlag(context.Background(), 1, featureflag.ConsumerWrites, disable("The consumers are being migrated"))
+	require.NoError(t, err)
+	assert.True(t, flag.Disabled)
+	assert.Equal(t, "The consumers are being migrated", *flag.Reason)
+	assert.Equal(t, int64(1), *flag.UpdatedBy)
+	assert.Equal(t, clk.Now(), *flag.UpdatedAt)
+
+	saved, err := repo.GetFeatureFlags(nil)
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+
+	// The instance applies its own change at once, the other features are still served
+	w := do(router, http.MethodPost)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, http.StatusOK, do(router, http.MethodGet).Code)
+
+	var resp struct {
+		Error []map[string]string `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []map[string]string{{"code": featureflag.DisabledCode, "feature": featureflag.ConsumerWrites, "message": "The consumers are being migrated"}}, resp.Error)
+
+	// Enabling the feature drops its reason
+	enabled := false
+	flag, err = s.UpdateFeatureFlag(context.Background(), 1, featureflag.ConsumerWrites, entity.FeatureFlagRequest{Disabled: &enabled, Reason: flag.Reason})
+	require.NoError(t, err)
+	assert.Nil(t, flag.Reason)
+	assert.Equal(t, http.StatusCreated, do(router, http.MethodPost).Code)
+}
+
+func TestFeatureFlagService_OtherInstancesReloadAfterTheTTL(t *testing.T) {
+	s, repo, clk := newFeatureFlagService(t, nil, time.Minute)
+	other := service.NewFeatureFlagService(database.DefaultStore(), repo, nil, time.Minute, clk)
+	require.NoError(t, other.LoadFlags())
+	router := newRouter(other.Flags())
+
+	_, err := s.UpdateFeatureFlag(context.Background(), 1, featureflag.ConsumerReads, disable(""))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, do(router, http.MethodGet).Code)
+
+	clk.Advance(time.Minute)
+	w := do(router, http.MethodGet)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "temporarily disabled")
+}
+
+func TestFeatureFlagService_EnvironmentCannotBeOverridden(t *testing.T) {
+	s, _, _ := newFeatureFlagService(t, []string{featureflag.ConsumerWrites}, time.Minute)
+	router := newRouter(s.Flags())
+
+	enabled := false
+	flag, err := s.UpdateFeatureFlag(context.Background(), 1, featureflag.ConsumerWrites, entity.FeatureFlagRequest{Disabled: &enabled})
+	require.NoError(t, err)
+	assert.True(t, flag.DisabledByEnvironment)
+	assert.Equal(t, http.StatusServiceUnavailable, do(router, http.MethodPost).Code)
+}
+
+func TestFeatureFlagService_UpdateFeatureFlag_Invalid(t *testing.T) {
+	s, _, _ := newFeatureFlagService(t, nil, time.Minute)
+
+	_, err := s.UpdateFeatureFlag(context.Background(), 1, "consumers.delete", disable(""))
+	assert.ErrorIs(t, err, service.ErrUnknownFeature)
+
+	_, err = s.UpdateFeatureFlag(context.Background(), 1, featureflag.ConsumerWrites, entity.FeatureFlagRequest{})
+	var ve validator.ValidationErrors
+	assert.True(t, errors.As(err, &ve))
+}
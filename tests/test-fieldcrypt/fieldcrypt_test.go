@@ -0,0 +1,194 @@
+package test_fieldcrypt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/fieldcrypt"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/secrets"
+)
+
+// newCipher creates a cipher with a key made of the given byte.
+func newCipher(t *testing.T, b byte) *fieldcrypt.Cipher {
+	c, err := fieldcrypt.NewCipher(bytes.Repeat([]byte{b}, fieldcrypt.KeySize))
+	require.NoError(t, err)
+	return c
+}
+
+// useCipher configures the cipher for the test, and disables the encryption afterward.
+func useCipher(t *testing.T, c *fieldcrypt.Cipher) {
+	fieldcrypt.Configure(c)
+	t.Cleanup(func() { fieldcrypt.Configure(nil) })
+}
+
+// consumerField returns the schema field of the consumer with the given column name.
+func consumerField(t *testing.T, column string) *schema.Field {
+	s, err := schema.Parse(&entity.Consumer{}, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+
+	field := s.LookUpField(column)
+	require.NotNil(t, field)
+	return field
+}
+
+func TestCipher_EncryptDecrypt(t *testing.T) {
+	c := newCipher(t, 1)
+
+	first, err := c.Encrypt([]byte("+6281234567890"))
+	require.NoError(t, err)
+	second, err := c.Encrypt([]byte("+6281234567890"))
+	require.NoError(t, err)
+
+	assert.True(t, fieldcrypt.IsEncrypted(first))
+	assert.NotContains(t, first, "6281234567890")
+	assert.NotEqual(t, first, second, "the nonces must be random")
+
+	plaintext, err := c.Decrypt(first)
+	require.NoError(t, err)
+	assert.Equal(t, "+6281234567890", string(plaintext))
+}
+
+func TestCipher_Decrypt_Rejected(t *testing.T) {
+	c := newCipher(t, 1)
+	encrypted, err := c.Encrypt([]byte("Jl. Sudirman 1"))
+	require.NoError(t, err)
+
+	// Another key
+	_, err = newCipher(t, 2).Decrypt(encrypted)
+	assert.Error(t, err)
+
+	// A tampered value
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, "enc:v1:"))
+	require.NoError(t, err)
+	sealed[len(sealed)-1] ^= 0xff
+	_, err = c.Decrypt("enc:v1:" + base64.StdEncoding.EncodeToString(sealed))
+	assert.Error(t, err)
+
+	_, err = c.Decrypt("Jl. Sudirman 1")
+	assert.Error(t, err)
+}
+
+func TestParseKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, fieldcrypt.KeySize)
+
+	parsed, err := fieldcrypt.ParseKey(" " + base64.StdEncoding.EncodeToString(key) + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	_, err = fieldcrypt.ParseKey("not base64!")
+	assert.Error(t, err)
+
+	_, err = fieldcrypt.ParseKey(base64.StdEncoding.EncodeToString(key[:16]))
+	assert.Error(t, err)
+}
+
+func TestBlindIndex(t *testing.T) {
+	unkeyed := fieldcrypt.BlindIndex("+6281234567890")
+	assert.Len(t, unkeyed, 64)
+
+	useCipher(t, newCipher(t, 1))
+	keyed := fieldcrypt.BlindIndex("+6281234567890")
+	assert.Equal(t, keyed, fieldcrypt.BlindIndex("+6281234567890"))
+	assert.NotEqual(t, unkeyed, keyed)
+	assert.NotEqual(t, keyed, newCipher(t, 2).BlindIndex("+6281234567890"))
+
+	// The emails are compared case-insensitively
+	assert.Equal(t, entity.ConsumerEmailIndex("John@Example.com"), entity.ConsumerEmailIndex("john@example.com"))
+}
+
+func TestSerializer_RoundTrip(t *testing.T) {
+	useCipher(t, newCipher(t, 1))
+	ctx := context.Background()
+	birthDate := customtype.Date{Time: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)}
+	consumer := entity.Consumer{Phone: "+6281234567890", BirthDate: &birthDate}
+
+	phone := consumerField(t, "phone")
+	stored, err := phone.Serializer.Value(ctx, phone, reflect.ValueOf(&consumer), consumer.Phone)
+	require.NoError(t, err)
+	assert.True(t, fieldcrypt.IsEncrypted(stored.(string)))
+
+	date := consumerField(t, "birth_date")
+	storedDate, err := date.Serializer.Value(ctx, date, reflect.ValueOf(&consumer), consumer.BirthDate)
+	require.NoError(t, err)
+	assert.True(t, fieldcrypt.IsEncrypted(storedDate.(string)))
+
+	var read entity.Consumer
+	require.NoError(t, phone.Serializer.Scan(ctx, phone, reflect.ValueOf(&read).Elem(), []byte(stored.(string))))
+	require.NoError(t, date.Serializer.Scan(ctx, date, reflect.ValueOf(&read).Elem(), storedDate))
+	assert.Equal(t, "+6281234567890", read.Phone)
+	require.NotNil(t, read.BirthDate)
+	assert.Equal(t, "1990-05-17", read.BirthDate.String())
+
+	// A nil birth date is stored as NULL
+	storedDate, err = date.Serializer.Value(ctx, date, reflect.ValueOf(&consumer), (*customtype.Date)(nil))
+	require.NoError(t, err)
+	assert.Nil(t, storedDate)
+}
+
+func TestSerializer_PlainText(t *testing.T) {
+	ctx := context.Background()
+	consumer := entity.Consumer{Address: "Jl. Sudirman 1"}
+
+	// Without a cipher, the values are stored in plain text
+	address := consumerField(t, "address")
+	stored, err := address.Serializer.Value(ctx, address, reflect.ValueOf(&consumer), consumer.Address)
+	require.NoError(t, err)
+	assert.Equal(t, "Jl. Sudirman 1", stored)
+
+	// The values stored in plain text are still read once a key is set
+	useCipher(t, newCipher(t, 1))
+	date := consumerField(t, "birth_date")
+	var read entity.Consumer
+	require.NoError(t, address.Serializer.Scan(ctx, address, reflect.ValueOf(&read).Elem(), stored))
+	require.NoError(t, date.Serializer.Scan(ctx, date, reflect.ValueOf(&read).Elem(), "1990-05-17"))
+	assert.Equal(t, "Jl. Sudirman 1", read.Address)
+	require.NotNil(t, read.BirthDate)
+	assert.Equal(t, "1990-05-17", read.BirthDate.String())
+
+	// The encrypted values cannot be read without the key
+	encrypted, err := address.Serializer.Value(ctx, address, reflect.ValueOf(&consumer), consumer.Address)
+	require.NoError(t, err)
+	fieldcrypt.Configure(nil)
+	assert.ErrorIs(t, address.Serializer.Scan(ctx, address, reflect.ValueOf(&read).Elem(), encrypted), fieldcrypt.ErrKeyNotConfigured)
+}
+
+func TestEnvProvider(t *testing.T) {
+	p := secrets.NewEnvProvider()
+
+	t.Setenv("TEST_SECRET", "")
+	t.Setenv("TEST_SECRET_FILE", "")
+	_, err := p.Get("TEST_SECRET")
+	assert.ErrorIs(t, err, secrets.ErrNotFound)
+
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0o600))
+	t.Setenv("TEST_SECRET_FILE", path)
+	value, err := p.Get("TEST_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", value)
+
+	t.Setenv("TEST_SECRET", "from-env")
+	value, err = p.Get("TEST_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", value)
+
+	t.Setenv("TEST_SECRET", "")
+	t.Setenv("TEST_SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err = p.Get("TEST_SECRET")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, secrets.ErrNotFound)
+}
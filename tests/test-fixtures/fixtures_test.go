@@ -0,0 +1,149 @@
+package test_fixtures
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/docs"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/fixtures"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/masking"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+)
+
+// useDefaults sets the default consumer listing policy and disables the data masking,
+// the settings the committed fixtures and the examples of the OpenAPI document are generated with.
+func useDefaults(t *testing.T) {
+	t.Setenv("DB_DRIVER", "memory")
+	t.Setenv("CONSUMER_LISTING_EXCLUDED_STATUSES", policyconfig.DefaultConsumerListingExclusions)
+	masking.Configure(masking.Config{})
+}
+
+// generate generates the fixtures with the default settings.
+func generate(t *testing.T) []fixtures.Fixture {
+	t.Helper()
+
+	useDefaults(t)
+	generated, err := fixtures.Generate(docs.OpenAPI, policyconfig.LoadConsumerListingPolicy(), pagination.LoadConfig())
+	require.NoError(t, err)
+	require.Len(t, generated, len(fixtures.Operations)*len(fixtures.Roles))
+	return generated
+}
+
+// fixture returns the generated fixture of the operation for the role.
+func fixture(t *testing.T, generated []fixtures.Fixture, operationID string, role string) fixtures.Fixture {
+	t.Helper()
+
+	for _, f := range generated {
+		if f.OperationID == operationID && f.Role == role {
+			return f
+		}
+	}
+	t.Fatalf("no fixture for %s as %s", operationID, role)
+	return fixtures.Fixture{}
+}
+
+// data decodes the data of the response of a fixture.
+func data(t *testing.T, f fixtures.Fixture) []map[string]any {
+	t.Helper()
+
+	var body struct {
+		Data []map[string]any `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(f.Body, &body))
+	return body.Data
+}
+
+func TestGenerate_PerRole(t *testing.T) {
+	generated := generate(t)
+
+	// The users do not see the suspended consumers in the listing, the administrators see all of them
+	asUser := data(t, fixture(t, generated, "getAllConsumers", "ROLE_USER"))
+	asAdmin := data(t, fixture(t, generated, "getAllConsumers", "ROLE_ADMIN"))
+	assert.Len(t, asUser, 2)
+	assert.Len(t, asAdmin, 3)
+	for _, c := range asUser {
+		assert.NotEqual(t, fixtures.SuspendedConsumerID, c["id"])
+	}
+
+	// The times are replaced, so the fixtures do not change from one generation to the next
+	assert.Equal(t, "2025-01-01T00:00:00Z", asAdmin[0]["createdAt"])
+}
+
+func TestGenerate_Masked(t *testing.T) {
+	useDefaults(t)
+	masking.Configure(masking.Config{Enabled: true, Fields: map[string]bool{masking.FieldEmail: true, masking.FieldPhone: true}})
+	t.Cleanup(func() { masking.Configure(masking.Config{}) })
+
+	generated, err := fixtures.Generate(docs.OpenAPI, policyconfig.LoadConsumerListingPolicy(), pagination.LoadConfig())
+	require.NoError(t, err)
+
+	// The examples show the redacted fields, as an environment with the data masking enabled serves them
+	for _, role := range fixtures.Roles {
+		consumer := data(t, fixture(t, generated, "getActiveConsumers", role))[0]
+		assert.Equal(t, "j*******@example.com", consumer["email"])
+		assert.Equal(t, "+*********7890", consumer["phone"])
+		assert.Equal(t, "John Doe", consumer["fullname"])
+	}
+}
+
+func TestFixtures_AreUpToDate(t *testing.T) {
+	for _, f := range generate(t) {
+		path := filepath.Join("..", "..", "docs", "fixtures", f.Role, f.OperationID+".json")
+		committed, err := os.ReadFile(path)
+		require.NoError(t, err, "run make fixtures")
+
+		var want fixtures.Fixture
+		require.NoError(t, json.Unmarshal(committed, &want))
+		assert.Equal(t, want.Status, f.Status, path)
+		assert.JSONEq(t, string(want.Body), string(f.Body), "%s is outdated, run make fixtures", path)
+	}
+}
+
+func TestOpenAPIExamples_MatchFixtures(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData(docs.OpenAPI)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+
+	// The examples of the document named after a role are the responses generated for the role
+	found := 0
+	for _, f := range generate(t) {
+		op := findOperation(doc, f.OperationID)
+		require.NotNil(t, op, "operation %s is not documented", f.OperationID)
+
+		response := op.Responses.Value(strconv.Itoa(f.Status))
+		require.NotNil(t, response, "%s does not document the status %d", f.OperationID, f.Status)
+		media := response.Value.Content.Get("application/json")
+		if media == nil || media.Examples[f.Role] == nil {
+			continue
+		}
+
+		value, err := json.Marshal(media.Examples[f.Role].Value.Value)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(f.Body), string(value), "the %s example of %s is outdated", f.Role, f.OperationID)
+		found++
+	}
+	assert.Positive(t, found, "no per-role example in the OpenAPI document")
+}
+
+// findOperation returns the operation of the document with the given ID.
+func findOperation(doc *openapi3.T, operationID string) *openapi3.Operation {
+	for _, item := range doc.Paths.Map() {
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			if op := item.GetOperation(method); op != nil && op.OperationID == operationID {
+				return op
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package test_geoip
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/geoip"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+)
+
+// fakeLocator returns the locations of a fixed table of IP addresses.
+type fakeLocator map[string]geoip.Location
+
+func (l fakeLocator) Lookup(ip string) geoip.Location {
+	return l[ip]
+}
+
+// postLogin sends a login request from the given IP address to the auth handler.
+func postLogin(h *handler.AuthHandler, ip string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/auth/login", h.Login)
+
+	payload, _ := json.Marshal(entity.LoginRequest{Username: "admin", Password: "P@ssw0rd"})
+	req, _ := http.NewRequest("POST", "/auth/login", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":40000"
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("GEOIP_DB_PATH", "/var/lib/geoip/GeoLite2-City.mmdb")
+	t.Setenv("GEOIP_ASN_DB_PATH", "/var/lib/geoip/GeoLite2-ASN.mmdb")
+	t.Setenv("GEOIP_BLOCKED_ADMIN_COUNTRIES", " kp, IR ,,")
+
+	cfg := geoip.LoadConfig()
+
+	assert.Equal(t, "/var/lib/geoip/GeoLite2-City.mmdb", cfg.DBPath)
+	assert.Equal(t, "/var/lib/geoip/GeoLite2-ASN.mmdb", cfg.ASNDBPath)
+	assert.Equal(t, []string{"KP", "IR"}, cfg.BlockedAdminCountries)
+}
+
+func TestOpen_InvalidDatabase(t *testing.T) {
+	_, err := geoip.Open(filepath.Join(t.TempDir(), "missing.mmdb"))
+
+	assert.Error(t, err)
+
+	_, err = geoip.OpenDatabases("", filepath.Join(t.TempDir(), "missing-asn.mmdb"))
+
+	assert.Error(t, err)
+}
+
+func TestLocation_String(t *testing.T) {
+	assert.Equal(t, "Jakarta, ID", geoip.Location{Country: "ID", City: "Jakarta"}.String())
+	assert.Equal(t, "ID", geoip.Location{Country: "ID"}.String())
+	assert.Equal(t, "Unknown", geoip.Location{}.String())
+}
+
+func TestLogin_AnnotatesRequestWithLocation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockAuthService(ctrl)
+	h := handler.NewAuthHandler(s)
+	h.Locator = fakeLocator{"203.0.113.7": {Country: "ID", City: "Jakarta"}}
+
+	s.EXPECT().Login(gomock.Any(), entity.LoginRequest{
+		Username:  "admin",
+		Password:  "P@ssw0rd",
+		IPAddress: "203.0.113.7",
+		Country:   "ID",
+		City:      "Jakarta",
+	}).Return(entity.LoginResponse{AccessToken: "access-token"}, nil)
+
+	w := postLogin(h, "203.0.113.7")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestLogin_AdminBlockedCountry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockAuthService(ctrl)
+	h := handler.NewAuthHandler(s)
+	h.Locator = fakeLocator{"198.51.100.9": {Country: "KP"}}
+
+	s.EXPECT().Login(gomock.Any(), gomock.Any()).Return(entity.LoginResponse{}, service.ErrAdminLoginBlocked)
+
+	w := postLogin(h, "198.51.100.9")
+
+	// The credentials are valid, the admin is told the location is not allowed
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var httpResponse httputil.HttpResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &httpResponse))
+	assert.Equal(t, "Login blocked", httpResponse.Message)
+}
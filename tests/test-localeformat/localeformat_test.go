@@ -0,0 +1,171 @@
+package test_localeformat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/localeformat"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/masking"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// useLocaleFormatting enables the formatting for all the supported locales for the test, and disables it afterward.
+func useLocaleFormatting(t *testing.T) {
+	t.Setenv("LOCALE_FORMATTING_ENABLED", "TRUE")
+	t.Setenv("LOCALE_FORMATTING_LOCALES", "")
+	require.True(t, localeformat.Init())
+	t.Cleanup(func() { localeformat.Configure(localeformat.Config{}) })
+}
+
+// negotiate returns the locale of the Accept-Language value, failing the test if none matches.
+func negotiate(t *testing.T, acceptLanguage string) localeformat.Locale {
+	locale, ok := localeformat.Negotiate(acceptLanguage)
+	require.True(t, ok, acceptLanguage)
+	return locale
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("LOCALE_FORMATTING_ENABLED", "")
+	t.Setenv("LOCALE_FORMATTING_LOCALES", "")
+	cfg, err := localeformat.LoadConfig()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled)
+	assert.Len(t, cfg.Locales, len(localeformat.SupportedLocales))
+
+	t.Setenv("LOCALE_FORMATTING_LOCALES", "id-ID, en-US")
+	cfg, err = localeformat.LoadConfig()
+	require.NoError(t, err)
+	assert.Len(t, cfg.Locales, 2)
+
+	for _, value := range []string{"fr-FR", "id-ID,xx_YY"} {
+		t.Setenv("LOCALE_FORMATTING_LOCALES", value)
+		_, err = localeformat.LoadConfig()
+		assert.Error(t, err, value)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	// Nothing is formatted while the formatting is disabled
+	localeformat.Configure(localeformat.Config{})
+	_, ok := localeformat.Negotiate("id-ID")
+	assert.False(t, ok)
+
+	useLocaleFormatting(t)
+	assert.Equal(t, "id-ID", negotiate(t, "id-ID,id;q=0.9,en;q=0.8").String())
+	assert.Equal(t, "id-ID", negotiate(t, "id").String())
+	assert.Equal(t, "en-GB", negotiate(t, "en-GB").String())
+	assert.Equal(t, "ja-JP", negotiate(t, "fr;q=0.9,ja;q=0.5").String())
+
+	// The languages without a matching locale are not formatted
+	for _, value := range []string{"", "fr-FR", "*", "not a language"} {
+		_, ok := localeformat.Negotiate(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestLocale_Phone(t *testing.T) {
+	useLocaleFormatting(t)
+	tests := []struct {
+		locale string
+		phone  string
+		want   string
+	}{
+		// In the national format of the locale for its own country
+		{"id-ID", "6281234567890", "0812-3456-7890"},
+		{"id-ID", "+62812345678", "0812-345-678"},
+		{"id-ID", "622112345678", "(021) 1234-5678"},
+		{"en-US", "+14155550123", "(415) 555-0123"},
+		{"en-GB", "447700900123", "07700 900123"},
+		{"en-GB", "442079460958", "020 7946 0958"},
+		{"en-SG", "6581234567", "8123 4567"},
+		{"ms-MY", "60123456789", "012-345 6789"},
+		{"en-AU", "61412345678", "0412 345 678"},
+		{"ja-JP", "819012345678", "090-1234-5678"},
+
+		// In the international format for the other countries
+		{"en-US", "6281234567890", "+62 812-3456-7890"},
+		{"id-ID", "14155550123", "+1 415-555-0123"},
+
+		// In E.164 for the countries or the numbers without a known plan
+		{"id-ID", "33612345678", "+33612345678"},
+		{"id-ID", "62812", "+62812"},
+		{"id-ID", "not a phone", "not a phone"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, negotiate(t, tt.locale).Phone(tt.phone), tt.locale+" "+tt.phone)
+	}
+}
+
+func TestLocale_Address(t *testing.T) {
+	useLocaleFormatting(t)
+
+	assert.Equal(t, "Jl. Merdeka No. 123, Jakarta", negotiate(t, "id-ID").Address("Jl. Merdeka  No. 123 ,Jakarta"))
+	assert.Equal(t, "1-1 Chiyoda, Tokyo, Japan", negotiate(t, "en-US").Address("1-1 Chiyoda, Tokyo, Japan"))
+
+	// From the largest part to the smallest in Japanese
+	assert.Equal(t, "Japan, Tokyo, 1-1 Chiyoda", negotiate(t, "ja-JP").Address("1-1 Chiyoda, Tokyo, Japan"))
+}
+
+func TestGetConsumerByID_FormattedForTheLocaleOfTheRequest(t *testing.T) {
+	r := repository.NewMemoryConsumerRepository(testsupport.UseMemoryDatabase(t))
+	created, err := r.CreateConsumer(context.Background(), nil, entity.Consumer{
+		Fullname: "John Doe", Username: "johndoe", Email: "john.doe@example.com",
+		Phone: "6281234567890", Address: "Jl. Merdeka No. 123, Jakarta", Status: entity.ConsumerStatusActive,
+	})
+	require.NoError(t, err)
+
+	h := handler.NewConsumerHandler(service.NewConsumerService(database.DefaultStore(), r, policyconfig.LoadConsumerListingPolicy()), pagination.LoadConfig())
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/consumers/:id", h.GetConsumerByID)
+	token := testsupport.NewTokenBuilder().Build(t)
+
+	get := func(acceptLanguage string) (*httptest.ResponseRecorder, entity.ConsumerResponse) {
+		req, _ := http.NewRequest("GET", "/api/v1/consumers/"+created.ID, nil)
+		req.Header.Set("Authorization", testsupport.DefaultTokenType+" "+token)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Data entity.ConsumerResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w, resp.Data
+	}
+
+	// The consumers are returned as they are stored while the formatting is disabled
+	w, consumer := get("id-ID")
+	assert.Equal(t, "6281234567890", consumer.Phone)
+	assert.Empty(t, w.Header().Get("Content-Language"))
+
+	useLocaleFormatting(t)
+	w, consumer = get("id-ID,id;q=0.9")
+	assert.Equal(t, "0812-3456-7890", consumer.Phone)
+	assert.Equal(t, "id-ID", w.Header().Get("Content-Language"))
+	assert.Contains(t, w.Header().Values("Vary"), "Accept-Language")
+
+	w, consumer = get("fr-FR")
+	assert.Equal(t, "6281234567890", consumer.Phone)
+	assert.Empty(t, w.Header().Get("Content-Language"))
+
+	// The masked fields are not formatted
+	masking.Configure(masking.Config{Enabled: true, Fields: map[string]bool{masking.FieldPhone: true}})
+	t.Cleanup(func() { masking.Configure(masking.Config{}) })
+	_, consumer = get("id-ID")
+	assert.Equal(t, "*********7890", consumer.Phone)
+}
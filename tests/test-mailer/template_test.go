@@ -0,0 +1,119 @@
+package test_mailer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/mailer"
+)
+
+// writeTemplate writes a template file of the locale into the directory.
+func writeTemplate(t *testing.T, dir string, locale string, file string, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, locale), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, locale, file), []byte(content), 0o644))
+}
+
+func TestTemplates_RenderTheEmbeddedTemplates(t *testing.T) {
+	templates, err := mailer.NewTemplates("", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"en", "id"}, templates.Locales())
+
+	data := mailer.SecurityEmail{Username: "alice", At: time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC), IPAddress: "203.0.113.7"}
+	for _, name := range []string{mailer.TemplateNewDeviceLogin, mailer.TemplatePasswordChanged, mailer.TemplateMFADisabled} {
+		for _, locale := range templates.Locales() {
+			msg, err := templates.Render(name, locale, data)
+			require.NoError(t, err, name+" "+locale)
+			assert.NotEmpty(t, msg.Subject)
+			assert.Contains(t, msg.Body, "203.0.113.7")
+			assert.Contains(t, msg.Body, "Wed, 15 Jan 2025 10:00:00 UTC")
+			assert.Contains(t, msg.HTML, "203.0.113.7")
+		}
+	}
+
+	msg, err := templates.Render(mailer.TemplatePasswordChanged, "", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Your password was changed", msg.Subject)
+	assert.Equal(t, "Hi alice,\n\nThe password of your account was changed on Wed, 15 Jan 2025 10:00:00 UTC from 203.0.113.7.\n\n"+
+		"If you did not change it, contact your administrator right away.\n", msg.Body)
+
+	_, err = templates.Render("welcome", "", data)
+	assert.ErrorIs(t, err, mailer.ErrUnknownTemplate)
+}
+
+func TestTemplates_PickTheLocale(t *testing.T) {
+	templates, err := mailer.NewTemplates("", "id")
+	require.NoError(t, err)
+	data := mailer.PasswordResetEmail{Username: "alice", Link: "https://app.example.com/reset-password?token=abc"}
+
+	for value, subject := range map[string]string{
+		"en":                        "Reset your password",
+		"en-GB":                     "Reset your password",
+		"fr-FR, en;q=0.8, id;q=0.9": "Atur ulang kata sandi Anda",
+		"ID_id":                     "Atur ulang kata sandi Anda",
+		"de, en;q=0":                "Atur ulang kata sandi Anda",
+		"":                          "Atur ulang kata sandi Anda",
+	} {
+		msg, err := templates.Render(mailer.TemplatePasswordReset, value, data)
+		require.NoError(t, err)
+		assert.Equal(t, subject, msg.Subject, value)
+	}
+
+	_, err = mailer.NewTemplates("", "fr")
+	assert.Error(t, err)
+}
+
+func TestTemplates_OverriddenFromTheDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "en", "password-changed.txt", `{{define "subject"}}Acme: password changed{{end -}}`+"\nHi {{.Username}}, your Acme password was changed.\n")
+	writeTemplate(t, dir, "fr", "password-reset.txt", `{{define "subject"}}Réinitialisez votre mot de passe{{end -}}`+"\n{{.Link}}\n")
+	writeTemplate(t, dir, "fr", "password-reset.html", `<a href="{{.Link}}">{{.Username}}</a>`)
+	writeTemplate(t, dir, "fr", "README.md", "ignored")
+
+	templates, err := mailer.NewTemplates(dir, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"en", "fr", "id"}, templates.Locales())
+
+	// The override replaces the text, and the embedded HTML of the template is kept
+	msg, err := templates.Render(mailer.TemplatePasswordChanged, "en", mailer.SecurityEmail{Username: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "Acme: password changed", msg.Subject)
+	assert.Equal(t, "Hi alice, your Acme password was changed.\n", msg.Body)
+	assert.Contains(t, msg.HTML, "alice")
+
+	// The values are escaped in the HTML, not in the plain text
+	msg, err = templates.Render(mailer.TemplatePasswordReset, "fr", mailer.PasswordResetEmail{Username: "<b>alice</b>", Link: "https://app.example.com/reset?token=a&b"})
+	require.NoError(t, err)
+	assert.Equal(t, "Réinitialisez votre mot de passe", msg.Subject)
+	assert.Equal(t, "https://app.example.com/reset?token=a&b\n", msg.Body)
+	assert.Equal(t, `<a href="https://app.example.com/reset?token=a&amp;b">&lt;b&gt;alice&lt;/b&gt;</a>`, msg.HTML)
+
+	// The templates missing from a locale fall back to the default locale
+	msg, err = templates.Render(mailer.TemplateMFADisabled, "fr", mailer.SecurityEmail{Username: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "Two-factor authentication was disabled", msg.Subject)
+}
+
+func TestTemplates_RejectInvalidOverrides(t *testing.T) {
+	for name, files := range map[string]map[string]string{
+		"no subject":     {"password-reset.txt": "{{.Link}}"},
+		"invalid syntax": {"password-reset.txt": `{{define "subject"}}Reset{{end}}{{.Link`},
+		"html only":      {"welcome.html": "<p>Welcome</p>"},
+		"invalid html":   {"password-reset.html": "{{if .Link}}"},
+	} {
+		dir := t.TempDir()
+		for file, content := range files {
+			writeTemplate(t, dir, "en", file, content)
+		}
+
+		_, err := mailer.NewTemplates(dir, "")
+		assert.Error(t, err, name)
+	}
+
+	_, err := mailer.NewTemplates(filepath.Join(t.TempDir(), "missing"), "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,153 @@
+package test_masking
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/masking"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// useMasking enables the masking of the given fields for the test, and disables it afterward.
+func useMasking(t *testing.T, fields ...string) {
+	cfg := masking.Config{Enabled: true, Fields: make(map[string]bool)}
+	for _, f := range fields {
+		cfg.Fields[f] = true
+	}
+
+	masking.Configure(cfg)
+	t.Cleanup(func() { masking.Configure(masking.Config{}) })
+}
+
+// newConsumer creates a consumer with all its personal data set.
+func newConsumer() entity.Consumer {
+	return entity.Consumer{
+		ID:        "consumer-1",
+		Fullname:  "John Doe",
+		Username:  "johndoe",
+		Email:     "john.doe@example.com",
+		Phone:     "+6281234567890",
+		Address:   "Jl. Sudirman No. 1, Jakarta",
+		BirthDate: &customtype.Date{Time: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)},
+		Status:    entity.ConsumerStatusActive,
+	}
+}
+
+// encode encodes the consumer as JSON and decodes it into a map.
+func encode(t *testing.T, c entity.Consumer) map[string]interface{} {
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	return decoded
+}
+
+func TestMaskingFunctions(t *testing.T) {
+	assert.Equal(t, "J*** D**", masking.Name("John Doe"))
+	assert.Equal(t, "Ö***", masking.Name("Ömer"))
+	assert.Equal(t, "j*******@example.com", masking.Email("john.doe@example.com"))
+	assert.Equal(t, "+*********7890", masking.Phone("+6281234567890"))
+	assert.Equal(t, "*********7890", masking.Phone("6281234567890"))
+	assert.Equal(t, "***", masking.Phone("123"))
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("DATA_MASKING_ENABLED", "TRUE")
+	t.Setenv("DATA_MASKING_FIELDS", "")
+	cfg, err := masking.LoadConfig()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Len(t, cfg.Fields, len(masking.DefaultFields))
+	assert.False(t, cfg.Fields[masking.FieldUsername])
+
+	t.Setenv("DATA_MASKING_FIELDS", "username, email")
+	cfg, err = masking.LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"username": true, "email": true}, cfg.Fields)
+
+	t.Setenv("DATA_MASKING_FIELDS", "email,ssn")
+	_, err = masking.LoadConfig()
+	assert.ErrorContains(t, err, `"ssn"`)
+}
+
+func TestConsumerJSON_Masked(t *testing.T) {
+	// Without masking, the consumer is encoded as it is
+	plain := encode(t, newConsumer())
+	assert.Equal(t, "John Doe", plain["fullname"])
+	assert.Equal(t, "1990-05-17", plain["birthDate"])
+
+	useMasking(t, masking.DefaultFields...)
+	masked := encode(t, newConsumer())
+	assert.Equal(t, "consumer-1", masked["id"])
+	assert.Equal(t, "J*** D**", masked["fullname"])
+	assert.Equal(t, "johndoe", masked["username"])
+	assert.Equal(t, "j*******@example.com", masked["email"])
+	assert.Equal(t, "+*********7890", masked["phone"])
+	assert.Equal(t, masking.Redacted, masked["address"])
+	assert.NotContains(t, masked, "birthDate")
+
+	// The consumer itself is not modified
+	c := newConsumer()
+	_, err := json.Marshal(&c)
+	require.NoError(t, err)
+	assert.Equal(t, "John Doe", c.Fullname)
+	require.NotNil(t, c.BirthDate)
+}
+
+func TestConsumerJSON_MaskedFields(t *testing.T) {
+	useMasking(t, masking.FieldEmail)
+
+	masked := encode(t, newConsumer())
+	assert.Equal(t, "j*******@example.com", masked["email"])
+	assert.Equal(t, "John Doe", masked["fullname"])
+	assert.Equal(t, "+6281234567890", masked["phone"])
+}
+
+func TestStreamConsumers_Masked(t *testing.T) {
+	r := repository.NewMemoryConsumerRepository(testsupport.UseMemoryDatabase(t))
+	_, err := r.CreateConsumer(context.Background(), nil, newConsumer())
+	require.NoError(t, err)
+
+	h := handler.NewConsumerHandler(service.NewConsumerService(database.DefaultStore(), r, policyconfig.LoadConsumerListingPolicy()), pagination.LoadConfig())
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/consumers/stream", h.StreamConsumers)
+	useMasking(t, masking.DefaultFields...)
+
+	// The exports are masked like the responses, without the handler doing anything
+	w := testsupport.Do(router, "GET", "/api/v1/consumers/stream", testsupport.NewTokenBuilder().Build(t))
+	require.Equal(t, http.StatusOK, w.Code)
+	body := strings.TrimSpace(w.Body.String())
+	assert.Contains(t, body, `"fullname":"J*** D**"`)
+	assert.NotContains(t, body, "john.doe@example.com")
+	assert.NotContains(t, body, "Sudirman")
+}
+
+func TestConsumerResponse_Masked(t *testing.T) {
+	useMasking(t, masking.FieldPhone, masking.FieldBirthDate)
+
+	c := newConsumer()
+	resp := entity.NewConsumerResponse(c)
+	assert.Equal(t, "John Doe", resp.Fullname)
+	assert.Equal(t, "+*********7890", resp.Phone)
+	assert.Nil(t, resp.BirthDate)
+
+	// The consumer itself is not modified
+	assert.Equal(t, "+6281234567890", c.Phone)
+	require.NotNil(t, c.BirthDate)
+}
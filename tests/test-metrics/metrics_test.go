@@ -0,0 +1,121 @@
+package test_metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// scrape returns the body of /metrics served for the given Accept header.
+func scrape(t *testing.T, accept string) string {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", accept)
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	return w.Body.String()
+}
+
+// value returns the value of the series in the scraped body, or 0 if it is not exported.
+func value(t *testing.T, body string, series string) float64 {
+	t.Helper()
+
+	m := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(series) + ` (\S+)`).FindStringSubmatch(body)
+	if m == nil {
+		return 0
+	}
+
+	v, err := strconv.ParseFloat(m[1], 64)
+	require.NoError(t, err)
+	return v
+}
+
+func TestMetrics_OpenMetricsFormat(t *testing.T) {
+	body := scrape(t, "application/openmetrics-text; version=1.0.0")
+
+	assert.Contains(t, body, "# TYPE consumers_created counter")
+	assert.Contains(t, body, `webhook_delivery_attempts_total{result="dead"}`)
+	assert.Contains(t, body, `consumer_cache_requests_total{result="hit"}`)
+	assert.Contains(t, body, `auth_logins_total{result="mfa_required"} 0`)
+	assert.Contains(t, body, "# EOF")
+}
+
+func TestMetrics_ConsumerCounters(t *testing.T) {
+	s := service.NewConsumerService(database.DefaultStore(), repository.NewMemoryConsumerRepository(testsupport.UseMemoryDatabase(t)), policyconfig.ConsumerListingPolicy{})
+
+	before := scrape(t, "text/plain")
+	created, err := s.CreateConsumer(context.Background(), entity.Consumer{
+		Fullname:  "John Doe",
+		Username:  "johndoe",
+		Email:     "john.doe@example.com",
+		Phone:     "081234567890",
+		Address:   "Jl. Sudirman No. 1, Jakarta",
+		BirthDate: &customtype.Date{Time: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)},
+	})
+	require.NoError(t, err)
+
+	_, err = s.UpdateConsumerStatus(context.Background(), created.ID, entity.ConsumerStatusActive)
+	require.NoError(t, err)
+
+	// Updating a consumer to its current status is not a transition
+	_, err = s.UpdateConsumerStatus(context.Background(), created.ID, entity.ConsumerStatusActive)
+	require.NoError(t, err)
+
+	after := scrape(t, "text/plain")
+	transition := `consumer_status_transitions_total{from="inactive",to="active"}`
+	assert.Equal(t, value(t, before, "consumers_created_total")+1, value(t, after, "consumers_created_total"))
+	assert.Equal(t, value(t, before, transition)+1, value(t, after, transition))
+	assert.Zero(t, value(t, after, `consumer_status_transitions_total{from="active",to="active"}`))
+}
+
+func TestMetrics_Enabled(t *testing.T) {
+	assert.True(t, metrics.Enabled())
+
+	t.Setenv("METRICS_ENABLED", "false")
+	assert.False(t, metrics.Enabled())
+}
+
+func TestMetrics_AuthCounters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserService(ctrl)
+	refresh := mocks.NewMockRefreshTokenService(ctrl)
+	s := service.NewAuthService(users, refresh, mocks.NewMockTokenIssuer(ctrl), mocks.NewMockLastLoginRecorder(ctrl), mocks.NewMockTokenUsageRecorder(ctrl),
+		mocks.NewMockSecurityNotifier(ctrl), mocks.NewMockTokenRevocationService(ctrl), clock.New())
+	before := scrape(t, "text/plain")
+
+	users.EXPECT().GetUserByUsername(gomock.Any(), "unknown").Return(entity.User{}, gorm.ErrRecordNotFound)
+	_, err := s.Login(context.Background(), entity.LoginRequest{Username: "unknown", Password: "P@ssw0rd"})
+	require.Error(t, err)
+
+	refresh.EXPECT().GetRefreshTokenByToken(gomock.Any(), "unknown").Return(entity.RefreshToken{}, gorm.ErrRecordNotFound)
+	_, err = s.RefreshToken(context.Background(), entity.RefreshTokenRequest{RefreshToken: "unknown"})
+	require.Error(t, err)
+
+	after := scrape(t, "text/plain")
+	assert.Equal(t, value(t, before, `auth_logins_total{result="failed"}`)+1, value(t, after, `auth_logins_total{result="failed"}`))
+	assert.Equal(t, value(t, before, `auth_logins_total{result="succeeded"}`), value(t, after, `auth_logins_total{result="succeeded"}`))
+	assert.Equal(t, value(t, before, `auth_token_refreshes_total{result="failed"}`)+1, value(t, after, `auth_token_refreshes_total{result="failed"}`))
+}
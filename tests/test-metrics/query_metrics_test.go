@@ -0,0 +1,84 @@
+package test_metrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+)
+
+// newDryRunDB opens a GORM connection with the query metrics which builds the statements without running them,
+// so that no database is needed. The tables without a TableName method are prefixed with a schema, like the tables of the application.
+func newDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost user=test dbname=test sslmode=disable"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		NamingStrategy:         schema.NamingStrategy{TablePrefix: "demo."},
+		Logger:                 gormLogger.Default.LogMode(gormLogger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(database.QueryMetrics{}))
+
+	return db
+}
+
+func TestQueryMetrics(t *testing.T) {
+	db := newDryRunDB(t)
+
+	before := scrape(t, "text/plain")
+	var consumers []entity.Consumer
+	require.NoError(t, db.Where("status = ?", entity.ConsumerStatusActive).Find(&consumers).Error)
+	require.NoError(t, db.Where("status = ?", entity.ConsumerStatusActive).Find(&consumers).Error)
+	require.NoError(t, db.Create(&entity.Consumer{ID: "c1"}).Error)
+	require.NoError(t, db.Exec("SELECT 1").Error)
+
+	// The schema is left out of the table
+	require.NoError(t, db.Table("demo.consumers").Where("status = ?", entity.ConsumerStatusActive).Find(&consumers).Error)
+
+	after := scrape(t, "text/plain")
+	queries := `db_query_duration_seconds_count{operation="query",table="consumers"}`
+	creates := `db_query_duration_seconds_count{operation="create",table="consumers"}`
+	raw := `db_query_duration_seconds_count{operation="raw",table="unknown"}`
+	assert.Equal(t, value(t, before, queries)+3, value(t, after, queries))
+	assert.Equal(t, value(t, before, creates)+1, value(t, after, creates))
+	assert.Equal(t, value(t, before, raw)+1, value(t, after, raw))
+	assert.Equal(t, value(t, before, `db_query_errors_total{operation="query",table="consumers"}`), value(t, after, `db_query_errors_total{operation="query",table="consumers"}`))
+}
+
+func TestQueryMetrics_Errors(t *testing.T) {
+	db := newDryRunDB(t)
+
+	// Fail the lookups of the users, then find no role
+	require.NoError(t, db.Callback().Query().Before("gorm:query").Register("test:fail", func(db *gorm.DB) {
+		switch db.Statement.Table {
+		case "users":
+			_ = db.AddError(errors.New("connection reset"))
+		case "roles":
+			_ = db.AddError(gorm.ErrRecordNotFound)
+		}
+	}))
+
+	before := scrape(t, "text/plain")
+	assert.Error(t, db.First(&entity.User{}, 1).Error)
+	assert.ErrorIs(t, db.First(&entity.Role{}, 1).Error, gorm.ErrRecordNotFound)
+
+	// The lookups finding no record are not errors
+	after := scrape(t, "text/plain")
+	users := `db_query_errors_total{operation="query",table="users"}`
+	roles := `db_query_errors_total{operation="query",table="roles"}`
+	assert.Equal(t, value(t, before, users)+1, value(t, after, users))
+	assert.Equal(t, value(t, before, roles), value(t, after, roles))
+	assert.Equal(t, value(t, before, `db_query_duration_seconds_count{operation="query",table="roles"}`)+1,
+		value(t, after, `db_query_duration_seconds_count{operation="query",table="roles"}`))
+}
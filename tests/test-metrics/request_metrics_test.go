@@ -0,0 +1,67 @@
+package test_metrics
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/monitoring"
+)
+
+// unreachableConnector is a database connector failing every connection, the pool statistics need none.
+type unreachableConnector struct{}
+
+func (unreachableConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("unreachable")
+}
+
+func (unreachableConnector) Driver() driver.Driver {
+	return nil
+}
+
+func TestRequestMetrics_LabelsTheRequestsByRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(monitoring.RequestMetrics())
+	r.GET("/api/v1/consumers/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	series := `http_requests_total{method="GET",route="/api/v1/consumers/:id",status="204"}`
+	unmatched := `http_requests_total{method="GET",route="unmatched",status="404"}`
+	before := scrape(t, "text/plain")
+
+	for _, path := range []string{"/api/v1/consumers/1", "/api/v1/consumers/2", "/wp-login.php"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	after := scrape(t, "text/plain")
+	assert.Equal(t, value(t, before, series)+2, value(t, after, series))
+	assert.Equal(t, value(t, before, unmatched)+1, value(t, after, unmatched))
+	assert.Equal(t, value(t, before, `http_request_duration_seconds_count{method="GET",route="/api/v1/consumers/:id",status="204"}`)+2,
+		value(t, after, `http_request_duration_seconds_count{method="GET",route="/api/v1/consumers/:id",status="204"}`))
+	assert.NotContains(t, after, "/api/v1/consumers/1")
+}
+
+func TestRegisterDatabase_ExportsTheConnectionPool(t *testing.T) {
+	db := sql.OpenDB(unreachableConnector{})
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(7)
+
+	require.NoError(t, metrics.RegisterDatabase("test", db))
+	require.NoError(t, metrics.RegisterDatabase("test", db))
+
+	body := scrape(t, "text/plain")
+	assert.Equal(t, float64(7), value(t, body, `go_sql_max_open_connections{db_name="test"}`))
+	assert.Contains(t, body, `go_sql_in_use_connections{db_name="test"} 0`)
+	assert.Contains(t, body, `go_sql_idle_connections{db_name="test"} 0`)
+}
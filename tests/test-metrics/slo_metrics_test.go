@@ -0,0 +1,102 @@
+package test_metrics
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/metrics"
+)
+
+// burnRate returns the series of the burn rate of the objective of the route group over the window.
+func burnRate(group, slo, window string) string {
+	return `slo_burn_rate{group="` + group + `",slo="` + slo + `",window="` + window + `"}`
+}
+
+func TestSLOMetrics_BurnRatesPerWindow(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 6, 1, 12, 0, 30, 0, time.UTC))
+	metrics.ConfigureSLO(metrics.SLOConfig{
+		AvailabilityTarget: 0.99,
+		LatencyTarget:      0.9,
+		LatencyThreshold:   time.Second,
+		LatencyThresholds:  map[string]time.Duration{metrics.SLOGroupConsumers: 100 * time.Millisecond},
+	}, clk)
+	t.Cleanup(func() { metrics.ConfigureSLO(metrics.LoadSLOConfig(), clock.New()) })
+
+	// 10% of the consumer requests fail and 20% are slow: 10 times the availability budget, twice the latency one
+	for i := 0; i < 100; i++ {
+		status, duration := http.StatusOK, 10*time.Millisecond
+		switch {
+		case i < 10:
+			status = http.StatusServiceUnavailable
+		case i < 30:
+			duration = 250 * time.Millisecond
+		}
+		metrics.HTTPRequest("/api/v1/consumers/:id", http.MethodGet, status, duration)
+	}
+
+	// The scans and the scrapes are not part of the objectives, the slow logins are within their threshold
+	metrics.HTTPRequest(metrics.UnmatchedRoute, http.MethodGet, http.StatusInternalServerError, 0)
+	metrics.HTTPRequest("/metrics", http.MethodGet, http.StatusInternalServerError, 0)
+	metrics.HTTPRequest("/auth/login", http.MethodPost, http.StatusOK, 250*time.Millisecond)
+
+	body := scrape(t, "text/plain")
+	assert.InDelta(t, 10.0, value(t, body, burnRate(metrics.SLOGroupConsumers, metrics.SLOAvailability, "5m")), 1e-9)
+	assert.InDelta(t, 2.0, value(t, body, burnRate(metrics.SLOGroupConsumers, metrics.SLOLatency, "5m")), 1e-9)
+	assert.InDelta(t, 10.0, value(t, body, burnRate(metrics.SLOGroupConsumers, metrics.SLOAvailability, "3d")), 1e-9)
+	assert.Equal(t, 100.0, value(t, body, `slo_window_requests{group="consumers",window="1h"}`))
+	assert.Equal(t, 0.0, value(t, body, burnRate(metrics.SLOGroupOther, metrics.SLOAvailability, "5m")))
+	assert.Equal(t, 0.0, value(t, body, burnRate(metrics.SLOGroupAuth, metrics.SLOLatency, "5m")))
+	assert.Equal(t, 1.0, value(t, body, `slo_window_requests{group="auth",window="5m"}`))
+	assert.Equal(t, 0.99, value(t, body, `slo_objective{group="consumers",slo="availability"}`))
+
+	// The requests leave the short windows first, then the longest one
+	clk.Advance(10 * time.Minute)
+	body = scrape(t, "text/plain")
+	assert.Equal(t, 0.0, value(t, body, burnRate(metrics.SLOGroupConsumers, metrics.SLOAvailability, "5m")))
+	assert.InDelta(t, 10.0, value(t, body, burnRate(metrics.SLOGroupConsumers, metrics.SLOAvailability, "30m")), 1e-9)
+
+	clk.Advance(72 * time.Hour)
+	body = scrape(t, "text/plain")
+	assert.Equal(t, 0.0, value(t, body, burnRate(metrics.SLOGroupConsumers, metrics.SLOAvailability, "3d")))
+	assert.Equal(t, 0.0, value(t, body, `slo_window_requests{group="consumers",window="3d"}`))
+}
+
+func TestSLOGroup(t *testing.T) {
+	assert.Equal(t, metrics.SLOGroupAuth, metrics.SLOGroup("/auth/login"))
+	assert.Equal(t, metrics.SLOGroupConsumers, metrics.SLOGroup("/api/v1/consumers/:id"))
+	assert.Equal(t, metrics.SLOGroupUsers, metrics.SLOGroup("/api/v1/users/me/sessions"))
+	assert.Equal(t, metrics.SLOGroupUsers, metrics.SLOGroup("/api/v1/roles"))
+	assert.Equal(t, metrics.SLOGroupAdmin, metrics.SLOGroup("/api/v1/admin/config-snapshot"))
+	assert.Equal(t, metrics.SLOGroupOther, metrics.SLOGroup("/.well-known/jwks.json"))
+}
+
+func TestSLOConfig(t *testing.T) {
+	t.Setenv("SLO_AVAILABILITY_TARGET", "0.995")
+	t.Setenv("SLO_LATENCY_TARGET", "")
+	t.Setenv("SLO_LATENCY_THRESHOLD_MS", "")
+	t.Setenv("SLO_LATENCY_THRESHOLDS_MS_BY_GROUP", "consumers=50, admin=2000")
+
+	cfg := metrics.LoadSLOConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, 0.995, cfg.AvailabilityTarget)
+	assert.Equal(t, metrics.DefaultSLOLatencyTarget, cfg.LatencyTarget)
+	assert.Equal(t, 50*time.Millisecond, cfg.Threshold(metrics.SLOGroupConsumers))
+	assert.Equal(t, 2*time.Second, cfg.Threshold(metrics.SLOGroupAdmin))
+	assert.Equal(t, metrics.DefaultSLOLatencyThresholds[metrics.SLOGroupAuth], cfg.Threshold(metrics.SLOGroupAuth))
+	assert.Equal(t, metrics.DefaultSLOLatencyThreshold, cfg.Threshold(metrics.SLOGroupOther))
+
+	_, err := metrics.ParseSLOLatencyThresholds("payments=100")
+	assert.Error(t, err)
+	_, err = metrics.ParseSLOLatencyThresholds("auth=0")
+	assert.Error(t, err)
+
+	t.Setenv("SLO_AVAILABILITY_TARGET", "1")
+	assert.Error(t, metrics.LoadSLOConfig().Validate())
+	t.Setenv("SLO_AVAILABILITY_TARGET", "")
+	t.Setenv("SLO_LATENCY_THRESHOLD_MS", "abc")
+	assert.Error(t, metrics.LoadSLOConfig().Validate())
+}
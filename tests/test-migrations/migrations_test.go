@@ -0,0 +1,214 @@
+package test_migrations
+
+import (
+	"io/fs"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/migrations"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"versions/0010_later.up.sql":   {Data: []byte("SELECT 10")},
+		"versions/0002_first.up.sql":   {Data: []byte("SELECT 2")},
+		"versions/0002_first.down.sql": {Data: []byte("SELECT -2")},
+	}
+
+	// The migrations are sorted by version, the one without down file cannot be reverted
+	migrations, err := database.LoadMigrations(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, int64(2), migrations[0].Version)
+	assert.Equal(t, "first", migrations[0].Name)
+	assert.NotNil(t, migrations[0].Up)
+	assert.NotNil(t, migrations[0].Down)
+	assert.Equal(t, int64(10), migrations[1].Version)
+	assert.Nil(t, migrations[1].Down)
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+	}{
+		{"malformed name", []string{"versions/2_First.up.sql"}},
+		{"missing direction", []string{"versions/0002_first.sql"}},
+		{"baseline version", []string{"versions/0001_first.up.sql"}},
+		{"two names", []string{"versions/0002_first.up.sql", "versions/0002_second.down.sql"}},
+		{"down file only", []string{"versions/0002_first.down.sql"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for _, name := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte("SELECT 1")}
+			}
+
+			_, err := database.LoadMigrations(fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMigrations_StartWithTheBaseline(t *testing.T) {
+	migrations, err := database.Migrations(database.Config{Schema: "public"})
+	require.NoError(t, err)
+
+	// The embedded migrations follow the baseline, in order, and can all be reverted
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, int64(database.BaselineVersion), migrations[0].Version)
+	assert.Equal(t, "baseline", migrations[0].Name)
+	for i, m := range migrations {
+		assert.NotNil(t, m.Down, m.Name)
+		if i > 0 {
+			assert.Greater(t, m.Version, migrations[i-1].Version)
+		}
+	}
+}
+
+// createTablePattern, addColumnPattern and dropColumnPattern match the statements of the migrations creating the tables,
+// and adding and dropping their columns.
+var (
+	createTablePattern = regexp.MustCompile(`(?s)CREATE TABLE (\w+) \((.*?)\n\);`)
+	addColumnPattern   = regexp.MustCompile(`ALTER TABLE (\w+) ADD COLUMN (\w+)`)
+	dropColumnPattern  = regexp.MustCompile(`ALTER TABLE (\w+) DROP COLUMN (\w+)`)
+)
+
+// migratedColumns returns the columns of the tables created by the baseline and migrated by the up files of the versions, by table.
+func migratedColumns(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+
+	baseline, err := fs.ReadFile(migrations.Baseline, "baseline/schema.sql")
+	require.NoError(t, err)
+	scripts := []string{string(baseline)}
+	ups, err := fs.Glob(migrations.Versions, "versions/*.up.sql")
+	require.NoError(t, err)
+	for _, name := range ups {
+		content, err := fs.ReadFile(migrations.Versions, name)
+		require.NoError(t, err)
+		scripts = append(scripts, string(content))
+	}
+
+	tables := make(map[string]map[string]bool)
+	for _, script := range scripts {
+		for _, match := range createTablePattern.FindAllStringSubmatch(script, -1) {
+			columns := make(map[string]bool)
+			for _, line := range strings.Split(match[2], "\n") {
+				name := strings.Fields(line + " ")
+				if len(name) > 0 && name[0] != "PRIMARY" && name[0] != "CONSTRAINT" {
+					columns[name[0]] = true
+				}
+			}
+			tables[match[1]] = columns
+		}
+		for _, match := range addColumnPattern.FindAllStringSubmatch(script, -1) {
+			require.Contains(t, tables, match[1], "column %s added to an unknown table", match[2])
+			tables[match[1]][match[2]] = true
+		}
+		for _, match := range dropColumnPattern.FindAllStringSubmatch(script, -1) {
+			require.Contains(t, tables[match[1]], match[2], "unknown column %s dropped from table %s", match[2], match[1])
+			delete(tables[match[1]], match[2])
+		}
+	}
+
+	return tables
+}
+
+func TestMigrations_CreateTheTablesOfTheEntities(t *testing.T) {
+	entities := []interface{}{
+		&entity.Role{}, &entity.Permission{}, &entity.RolePermission{}, &entity.User{}, &entity.RefreshToken{},
+		&entity.Consumer{}, &entity.TokenUsage{}, &entity.NotificationPreference{}, &entity.UserDevice{},
+		&entity.RevokedToken{}, &entity.PasswordResetToken{}, &entity.UserMFA{}, &entity.MFAChallenge{},
+		&entity.WebhookDelivery{}, &entity.SecuritySettings{}, &entity.FeatureFlag{}, &entity.ApiKey{},
+		&entity.OAuthClient{}, &entity.UserIdentity{}, &entity.RateLimitOverride{}, &entity.AuditEvent{},
+	}
+
+	// The columns of the entities and of their join tables, by table
+	want := make(map[string]map[string]bool)
+	cache := &sync.Map{}
+	for _, e := range entities {
+		s, err := schema.Parse(e, cache, schema.NamingStrategy{})
+		require.NoError(t, err)
+
+		want[s.Table] = make(map[string]bool)
+		for _, name := range s.DBNames {
+			want[s.Table][name] = true
+		}
+		for _, rel := range s.Relationships.Relations {
+			if rel.JoinTable != nil {
+				want[rel.JoinTable.Table] = make(map[string]bool)
+				for _, name := range rel.JoinTable.DBNames {
+					want[rel.JoinTable.Table][name] = true
+				}
+			}
+		}
+	}
+
+	// A change of the entities is released with the version migrating the schema, and the reverse
+	got := migratedColumns(t)
+	for table := range got {
+		assert.Contains(t, want, table, "table %s is not an entity", table)
+	}
+	for table, columns := range want {
+		assert.Equal(t, columns, got[table], "columns of table %s", table)
+	}
+}
+
+func TestBaseline_ExternalUsersDropTheForeignKeys(t *testing.T) {
+	baseline, err := fs.ReadFile(migrations.Baseline, "baseline/schema.sql")
+	require.NoError(t, err)
+	external, err := fs.ReadFile(migrations.Baseline, "baseline/external_users.sql")
+	require.NoError(t, err)
+
+	names := func(pattern string, script []byte) []string {
+		var found []string
+		for _, match := range regexp.MustCompile(pattern).FindAllStringSubmatch(string(script), -1) {
+			found = append(found, match[1])
+		}
+		return found
+	}
+
+	// With an external user store, the tables have no foreign key: the sessions and the roles reference users not in the users table
+	foreignKeys := names(`CONSTRAINT (\w+) FOREIGN KEY`, baseline)
+	require.NotEmpty(t, foreignKeys)
+	assert.ElementsMatch(t, foreignKeys, names(`DROP CONSTRAINT (\w+)`, external))
+}
+
+func TestVersions_RestoreTheDroppedColumns(t *testing.T) {
+	ups, err := fs.Glob(migrations.Versions, "versions/*.up.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, ups)
+
+	// A column dropped by a version, as the flags of the users replaced by their states, is added back by its down file
+	for _, name := range ups {
+		up, err := fs.ReadFile(migrations.Versions, name)
+		require.NoError(t, err)
+		down, err := fs.ReadFile(migrations.Versions, strings.TrimSuffix(name, ".up.sql")+".down.sql")
+		require.NoError(t, err)
+
+		added := make(map[string]bool)
+		for _, match := range addColumnPattern.FindAllStringSubmatch(string(up), -1) {
+			added[match[1]+"."+match[2]] = true
+		}
+		restored := make(map[string]bool)
+		for _, match := range addColumnPattern.FindAllStringSubmatch(string(down), -1) {
+			restored[match[1]+"."+match[2]] = true
+		}
+		for _, match := range dropColumnPattern.FindAllStringSubmatch(string(up), -1) {
+			if column := match[1] + "." + match[2]; !added[column] {
+				assert.True(t, restored[column], "%s drops %s without restoring it", name, column)
+			}
+		}
+	}
+}
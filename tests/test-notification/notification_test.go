@@ -0,0 +1,155 @@
+package test_notification
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/mailer"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// recordingMailer is a mailer keeping the sent messages in memory.
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *recordingMailer) messages() []mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]mailer.Message(nil), m.sent...)
+}
+
+// setupService returns a notification service backed by a store with the user 1.
+func setupService(t *testing.T) (service.NotificationService, *recordingMailer) {
+	store := testsupport.UseMemoryDatabase(t)
+	_, err := store.AddUser(entity.User{ID: 1, Username: "alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+
+	templates, err := mailer.NewTemplates("", "")
+	require.NoError(t, err)
+
+	m := &recordingMailer{}
+	return service.NewNotificationService(database.DefaultStore(), repository.NewMemoryNotificationRepository(store), m, templates), m
+}
+
+// loginEvent returns a login event of the user 1 from the given user agent.
+func loginEvent(userAgent string) entity.SecurityEvent {
+	return entity.SecurityEvent{
+		Type:      entity.SecurityEventLogin,
+		UserID:    1,
+		Username:  "alice",
+		Email:     "alice@example.com",
+		IPAddress: "203.0.113.7",
+		UserAgent: userAgent,
+		At:        time.Date(2025, 1, 15, 8, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestHandleSecurityEvent_NewDeviceLogin(t *testing.T) {
+	s, m := setupService(t)
+
+	// The first device is recorded without notification, a known device is not notified either
+	require.NoError(t, s.HandleSecurityEvent(loginEvent("laptop")))
+	require.NoError(t, s.HandleSecurityEvent(loginEvent("laptop")))
+	assert.Empty(t, m.messages())
+
+	require.NoError(t, s.HandleSecurityEvent(loginEvent("phone")))
+	sent := m.messages()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "alice@example.com", sent[0].To)
+	assert.Equal(t, "New sign-in to your account", sent[0].Subject)
+	assert.Contains(t, sent[0].Body, "203.0.113.7")
+	assert.Contains(t, sent[0].Body, "phone")
+}
+
+func TestHandleSecurityEvent_RespectsPreferences(t *testing.T) {
+	s, m := setupService(t)
+
+	off, on := false, true
+	_, err := s.UpdateNotificationPreference(context.Background(), 1, entity.NotificationPreferenceRequest{
+		NewDeviceLogin:  &off,
+		PasswordChanged: &on,
+		MFADisabled:     &off,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, s.HandleSecurityEvent(loginEvent("laptop")))
+	require.NoError(t, s.HandleSecurityEvent(loginEvent("phone")))
+
+	event := loginEvent("phone")
+	event.Type = entity.SecurityEventMFADisabled
+	require.NoError(t, s.HandleSecurityEvent(event))
+	assert.Empty(t, m.messages())
+
+	event.Type = entity.SecurityEventPasswordChanged
+	require.NoError(t, s.HandleSecurityEvent(event))
+	sent := m.messages()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "Your password was changed", sent[0].Subject)
+}
+
+func TestNotificationPreference_DefaultsAndValidation(t *testing.T) {
+	s, _ := setupService(t)
+
+	pref, err := s.GetNotificationPreference(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, entity.DefaultNotificationPreference(1), pref)
+
+	// All the preferences must be given
+	on := true
+	_, err = s.UpdateNotificationPreference(context.Background(), 1, entity.NotificationPreferenceRequest{NewDeviceLogin: &on})
+	assert.Error(t, err)
+}
+
+func TestAsyncSecurityNotifier_HandlesQueuedEventsOnClose(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	notificationService := mocks.NewMockNotificationService(ctrl)
+
+	n := service.NewAsyncSecurityNotifier(notificationService, nil, 0)
+	notificationService.EXPECT().HandleSecurityEvent(loginEvent("laptop")).Return(nil).Times(3)
+
+	for i := 0; i < 3; i++ {
+		n.Notify(loginEvent("laptop"))
+	}
+	n.Close()
+	n.Close()
+
+	// Events notified after Close are dropped
+	n.Notify(loginEvent("laptop"))
+}
+
+func TestNotificationPreference_Handler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockNotificationService(ctrl)
+	h := handler.NewNotificationHandler(s)
+
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/users/me/notification-preferences", h.GetNotificationPreference)
+	token := testsupport.NewTokenBuilder().WithUser(7, "bob", "bob@example.com").WithRoles("ROLE_USER").Build(t)
+
+	s.EXPECT().GetNotificationPreference(gomock.Any(), int64(7)).Return(entity.DefaultNotificationPreference(7), nil)
+
+	w := testsupport.Do(router, "GET", "/api/v1/users/me/notification-preferences", token)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
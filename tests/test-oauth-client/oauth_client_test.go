@@ -0,0 +1,199 @@
+package test_oauth_client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/headers"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newOAuthClientService creates an OAuth client service backed by a seeded in-memory store, with a service account
+// having the ROLE_USER role, and returns it with the store, the ID of the service account, and the clock of the service.
+// The issued tokens are recorded as issued to their client.
+func newOAuthClientService(t *testing.T) (service.OAuthClientService, *repository.MemoryStore, int64, *clock.FakeClock) {
+	store := testsupport.UseMemoryDatabase(t)
+	require.NoError(t, store.Seed())
+
+	account, err := store.AddUser(entity.User{Username: "billing", Email: "billing@example.com", Firstname: "Billing",
+		UserType: entity.UserTypeServiceAccount, State: entity.UserStateActive, Roles: []entity.Role{{ID: 1}}})
+	require.NoError(t, err)
+
+	testsupport.SetupJWTEnv(t)
+	clk := clock.NewFakeClock(time.Now().Truncate(time.Second))
+	roles := service.NewRoleService(database.DefaultStore(), repository.NewMemoryRoleRepository(store), repository.NewMemoryPermissionRepository(store))
+	issuer := service.NewJWTTokenIssuer(testsupport.NewJWTConfig(), service.WithScopeResolver(roles))
+	tokenUsage := mocks.NewMockTokenUsageRecorder(gomock.NewController(t))
+	tokenUsage.EXPECT().Record(service.TokenEventIssued, account.ID, gomock.Any(), clk.Now()).AnyTimes()
+
+	s := service.NewOAuthClientService(database.DefaultStore(), repository.NewMemoryOAuthClientRepository(store), repository.NewMemoryUserRepository(store), issuer, roles, tokenUsage, clk)
+
+	return s, store, account.ID, clk
+}
+
+// parseClaims verifies the access token and returns its claims.
+func parseClaims(t *testing.T, accessToken string) jwt.MapClaims {
+	t.Helper()
+
+	token, err := service.ParseJWTToken(testsupport.NewJWTConfig(), accessToken, time.Now())
+	require.NoError(t, err)
+
+	return token.Claims.(jwt.MapClaims)
+}
+
+func TestIssueClientCredentialsToken(t *testing.T) {
+	s, _, accountID, _ := newOAuthClientService(t)
+
+	client, err := s.CreateOAuthClient(context.Background(), accountID, entity.OAuthClientRequest{Name: "Billing integration"}, 1)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(client.ClientSecret, service.ClientSecretPrefix))
+	assert.Equal(t, service.HashApiKey(client.ClientSecret), client.SecretHash)
+
+	// The token carries every scope of the account when none is requested
+	resp, err := s.IssueClientCredentialsToken(context.Background(), entity.ClientCredentialsRequest{GrantType: entity.GrantTypeClientCredentials,
+		ClientID: client.ClientID, ClientSecret: client.ClientSecret})
+	require.NoError(t, err)
+	assert.Equal(t, testsupport.DefaultTokenType, resp.TokenType)
+	assert.Equal(t, int64(3600), resp.ExpiresIn)
+	assert.Equal(t, entity.PermissionConsumersRead, resp.Scope)
+
+	claims := parseClaims(t, resp.AccessToken)
+	assert.Equal(t, "billing", claims["username"])
+	assert.Equal(t, client.ClientID, claims["client"])
+	assert.Equal(t, []interface{}{entity.PermissionConsumersRead}, claims["scopes"])
+}
+
+func TestIssueClientCredentialsToken_Scopes(t *testing.T) {
+	s, store, accountID, _ := newOAuthClientService(t)
+	require.NoError(t, store.GrantPermissions(1, entity.PermissionConsumersRead, entity.PermissionUsersRead))
+
+	client, err := s.CreateOAuthClient(context.Background(), accountID, entity.OAuthClientRequest{Name: "Billing integration"}, 1)
+	require.NoError(t, err)
+	req := entity.ClientCredentialsRequest{GrantType: entity.GrantTypeClientCredentials, ClientID: client.ClientID, ClientSecret: client.ClientSecret}
+
+	// The token only carries the requested scopes
+	req.Scope = entity.PermissionUsersRead
+	resp, err := s.IssueClientCredentialsToken(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, entity.PermissionUsersRead, resp.Scope)
+	assert.Equal(t, []interface{}{entity.PermissionUsersRead}, parseClaims(t, resp.AccessToken)["scopes"])
+
+	// The scopes which are not granted to the account are rejected
+	req.Scope = entity.PermissionUsersRead + " " + entity.PermissionUsersWrite
+	_, err = s.IssueClientCredentialsToken(context.Background(), req)
+	assert.ErrorIs(t, err, service.ErrInvalidScope)
+}
+
+func TestIssueClientCredentialsToken_Rejected(t *testing.T) {
+	s, store, accountID, clk := newOAuthClientService(t)
+
+	client, err := s.CreateOAuthClient(context.Background(), accountID, entity.OAuthClientRequest{Name: "Billing integration"}, 1)
+	require.NoError(t, err)
+	valid := entity.ClientCredentialsRequest{GrantType: entity.GrantTypeClientCredentials, ClientID: client.ClientID, ClientSecret: client.ClientSecret}
+
+	cases := []struct {
+		name string
+		req  entity.ClientCredentialsRequest
+		err  error
+	}{
+		{"missing grant type", entity.ClientCredentialsRequest{ClientID: client.ClientID, ClientSecret: client.ClientSecret}, service.ErrInvalidTokenRequest},
+		{"missing secret", entity.ClientCredentialsRequest{GrantType: entity.GrantTypeClientCredentials, ClientID: client.ClientID}, service.ErrInvalidTokenRequest},
+		{"password grant", entity.ClientCredentialsRequest{GrantType: "password", ClientID: client.ClientID, ClientSecret: client.ClientSecret}, service.ErrUnsupportedGrantType},
+		{"unknown client", entity.ClientCredentialsRequest{GrantType: entity.GrantTypeClientCredentials, ClientID: "unknown", ClientSecret: client.ClientSecret}, service.ErrInvalidClient},
+		{"wrong secret", entity.ClientCredentialsRequest{GrantType: entity.GrantTypeClientCredentials, ClientID: client.ClientID, ClientSecret: "cs_wrong"}, service.ErrInvalidClient},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.IssueClientCredentialsToken(context.Background(), tc.req)
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+
+	// The clients of a disabled account cannot request tokens until it is activated again
+	users := repository.NewMemoryUserRepository(store)
+	require.NoError(t, users.UpdateUserState(context.Background(), nil, accountID, entity.UserStateDisabled, nil, clk.Now()))
+	_, err = s.IssueClientCredentialsToken(context.Background(), valid)
+	assert.ErrorIs(t, err, service.ErrInvalidClient)
+	require.NoError(t, users.UpdateUserState(context.Background(), nil, accountID, entity.UserStateActive, nil, clk.Now()))
+	_, err = s.IssueClientCredentialsToken(context.Background(), valid)
+	require.NoError(t, err)
+
+	// The revoked clients cannot request tokens
+	require.NoError(t, s.RevokeOAuthClient(context.Background(), client.ID))
+	_, err = s.IssueClientCredentialsToken(context.Background(), valid)
+	assert.ErrorIs(t, err, service.ErrInvalidClient)
+	assert.ErrorIs(t, s.RevokeOAuthClient(context.Background(), client.ID), service.ErrOAuthClientRevoked)
+}
+
+func TestCreateOAuthClient_NotServiceAccount(t *testing.T) {
+	s, _, _, _ := newOAuthClientService(t)
+
+	_, err := s.CreateOAuthClient(context.Background(), 2, entity.OAuthClientRequest{Name: "Billing integration"}, 1)
+	assert.ErrorIs(t, err, service.ErrNotServiceAccount)
+}
+
+func TestTokenHandler(t *testing.T) {
+	s, _, accountID, _ := newOAuthClientService(t)
+	client, err := s.CreateOAuthClient(context.Background(), accountID, entity.OAuthClientRequest{Name: "Billing integration"}, 1)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(headers.ContentType("/auth/token"))
+	router.POST("/auth/token", handler.NewOAuthClientHandler(s).Token)
+
+	post := func(form url.Values, contentType string, basicAuth bool) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/auth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", contentType)
+		if basicAuth {
+			req.SetBasicAuth(url.QueryEscape(client.ClientID), url.QueryEscape(client.ClientSecret))
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The client authenticates with the HTTP Basic authentication
+	w := post(url.Values{"grant_type": {"client_credentials"}}, headers.FormContentType, true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var resp entity.ClientCredentialsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, client.ClientID, parseClaims(t, resp.AccessToken)["client"])
+
+	// Or with the form parameters
+	w = post(url.Values{"grant_type": {"client_credentials"}, "client_id": {client.ClientID}, "client_secret": {client.ClientSecret}}, headers.FormContentType, false)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = post(url.Values{"grant_type": {"client_credentials"}, "client_id": {client.ClientID}, "client_secret": {"cs_wrong"}}, headers.FormContentType, false)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":"invalid_client","error_description":"invalid client credentials"}`, w.Body.String())
+
+	w = post(url.Values{"grant_type": {"client_credentials"}, "scope": {entity.PermissionUsersWrite}}, headers.FormContentType, true)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"error":"invalid_scope"`)
+
+	// The token requests are form-encoded
+	w = post(url.Values{"grant_type": {"client_credentials"}}, "application/json", true)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
@@ -0,0 +1,331 @@
+package test_oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/oidc"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+const clientID = "demo-client"
+
+// identityProvider is an OpenID Connect provider serving its discovery document, its key set,
+// and a token endpoint returning the ID token set by the test for the expected code.
+type identityProvider struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	code    string
+	idToken string
+}
+
+// newIdentityProvider starts an identity provider signing its ID tokens with a new RSA key.
+func newIdentityProvider(t *testing.T) *identityProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &identityProvider{key: key, code: "auth-code"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"kid": "key-1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != idp.code || r.PostFormValue("client_secret") != "secret" || r.PostFormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": idp.idToken, "token_type": "Bearer"})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+// sign returns an ID token signed by the provider with the given claims, on top of the issuer,
+// the audience, and the expiry of a valid token.
+func (idp *identityProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	all := jwt.MapClaims{
+		"iss": idp.server.URL,
+		"aud": clientID,
+		"sub": "108234567890",
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iat": time.Now().Unix(),
+	}
+	for k, v := range claims {
+		all[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, all)
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(idp.key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+// provider returns the relying party of the identity provider.
+func (idp *identityProvider) provider() *oidc.Provider {
+	return oidc.NewProvider(oidc.Config{
+		Name:         "demo",
+		IssuerURL:    idp.server.URL,
+		ClientID:     clientID,
+		ClientSecret: "secret",
+		RedirectURL:  "https://localhost:1000/auth/oidc/demo/callback",
+		Scopes:       oidc.DefaultScopes,
+	}, idp.server.Client(), clock.New())
+}
+
+func TestProvider_AuthCodeURL(t *testing.T) {
+	idp := newIdentityProvider(t)
+
+	authURL, err := idp.provider().AuthCodeURL(context.Background(), "the-state", "the-nonce", "the-verifier")
+	require.NoError(t, err)
+
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "/authorize", u.Path)
+	assert.Equal(t, clientID, u.Query().Get("client_id"))
+	assert.Equal(t, "the-state", u.Query().Get("state"))
+	assert.Equal(t, "the-nonce", u.Query().Get("nonce"))
+	assert.Equal(t, "openid email profile", u.Query().Get("scope"))
+	assert.Equal(t, oidc.CodeChallenge("the-verifier"), u.Query().Get("code_challenge"))
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+}
+
+func TestProvider_Authenticate(t *testing.T) {
+	idp := newIdentityProvider(t)
+	idp.idToken = idp.sign(t, jwt.MapClaims{"nonce": "the-nonce", "email": "jane.doe@gmail.com", "email_verified": true, "given_name": "Jane"})
+	p := idp.provider()
+
+	claims, err := p.Authenticate(context.Background(), "auth-code", "the-verifier", "the-nonce")
+	require.NoError(t, err)
+	assert.Equal(t, "108234567890", claims.Subject)
+	assert.Equal(t, "jane.doe@gmail.com", claims.Email)
+	require.NotNil(t, claims.EmailVerified)
+	assert.True(t, *claims.EmailVerified)
+	assert.Equal(t, "Jane", claims.GivenName)
+
+	// A code the provider does not know is rejected by the token endpoint
+	_, err = p.Authenticate(context.Background(), "other-code", "the-verifier", "the-nonce")
+	assert.ErrorIs(t, err, oidc.ErrTokenExchange)
+}
+
+func TestProvider_VerifyRejected(t *testing.T) {
+	idp := newIdentityProvider(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"iss": idp.server.URL, "aud": clientID, "sub": "1",
+		"nonce": "the-nonce", "exp": time.Now().Add(time.Hour).Unix()})
+	forged.Header["kid"] = "key-1"
+	forgedToken, err := forged.SignedString(otherKey)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"other nonce", idp.sign(t, jwt.MapClaims{"nonce": "other-nonce"})},
+		{"other audience", idp.sign(t, jwt.MapClaims{"nonce": "the-nonce", "aud": "other-client"})},
+		{"other issuer", idp.sign(t, jwt.MapClaims{"nonce": "the-nonce", "iss": "https://evil.example.com"})},
+		{"expired", idp.sign(t, jwt.MapClaims{"nonce": "the-nonce", "exp": time.Now().Add(-time.Hour).Unix()})},
+		{"several audiences for another party", idp.sign(t, jwt.MapClaims{"nonce": "the-nonce", "aud": []string{clientID, "other-client"}, "azp": "other-client"})},
+		{"missing subject", idp.sign(t, jwt.MapClaims{"nonce": "the-nonce", "sub": ""})},
+		{"forged signature", forgedToken},
+	}
+
+	p := idp.provider()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := p.Verify(context.Background(), tc.token, "the-nonce")
+			assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+		})
+	}
+}
+
+func TestProvider_Unavailable(t *testing.T) {
+	idp := newIdentityProvider(t)
+	p := idp.provider()
+	idp.server.Close()
+
+	_, err := p.AuthCodeURL(context.Background(), "the-state", "the-nonce", "the-verifier")
+	assert.ErrorIs(t, err, oidc.ErrProviderUnavailable)
+}
+
+// newOIDCService creates an OIDC service with a single provider "demo", backed by a seeded in-memory store,
+// and returns it with the store, the mocked provider, and the mocked auth service.
+func newOIDCService(t *testing.T) (service.OIDCService, *repository.MemoryStore, *mocks.MockOIDCProvider, *mocks.MockAuthService) {
+	store := testsupport.UseMemoryDatabase(t)
+	require.NoError(t, store.Seed())
+
+	ctrl := gomock.NewController(t)
+	provider := mocks.NewMockOIDCProvider(ctrl)
+	auth := mocks.NewMockAuthService(ctrl)
+
+	s := service.NewOIDCService(database.DefaultStore(), map[string]service.OIDCProvider{"demo": provider}, repository.NewMemoryUserIdentityRepository(store),
+		repository.NewMemoryUserRepository(store), repository.NewMemoryRoleRepository(store), auth, clock.New())
+
+	return s, store, provider, auth
+}
+
+// startLogin starts a login with the provider "demo" and returns the callback request and the login state of the browser.
+func startLogin(t *testing.T, s service.OIDCService, provider *mocks.MockOIDCProvider) (entity.OIDCCallbackRequest, entity.OIDCLoginState) {
+	provider.EXPECT().AuthCodeURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return("https://idp.example.com/authorize", nil)
+
+	authorization, err := s.StartLogin(context.Background(), "demo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://idp.example.com/authorize", authorization.URL)
+
+	return entity.OIDCCallbackRequest{Code: "auth-code", State: authorization.LoginState.State}, authorization.LoginState
+}
+
+func verified(v bool) *bool {
+	return &v
+}
+
+func TestCompleteLogin_ProvisionsAndLinksUser(t *testing.T) {
+	s, store, provider, auth := newOIDCService(t)
+	claims := oidc.Claims{Subject: "108234567890", Email: "Jane.Doe+test@gmail.com", EmailVerified: verified(true), GivenName: "Jane", FamilyName: "Doe"}
+
+	var loggedIn entity.User
+	auth.EXPECT().LoginExternal(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user entity.User, req entity.LoginRequest) (entity.LoginResponse, error) {
+		loggedIn = user
+		return entity.LoginResponse{AccessToken: "access"}, nil
+	}).Times(2)
+
+	// The first login provisions the user with the default role
+	req, loginState := startLogin(t, s, provider)
+	provider.EXPECT().Authenticate(gomock.Any(), "auth-code", loginState.CodeVerifier, loginState.Nonce).Return(claims, nil)
+	resp, err := s.CompleteLogin(context.Background(), "demo", req, loginState)
+	require.NoError(t, err)
+	assert.Equal(t, "access", resp.AccessToken)
+	assert.Equal(t, "jane.doetest", loggedIn.Username)
+	assert.Equal(t, "Jane", loggedIn.Firstname)
+	require.Len(t, loggedIn.Roles, 1)
+	assert.Equal(t, service.DefaultUserRole, loggedIn.Roles[0].Name)
+
+	identity, err := repository.NewMemoryUserIdentityRepository(store).GetUserIdentity(database.GetPostgres(), "demo", "108234567890")
+	require.NoError(t, err)
+	assert.Equal(t, loggedIn.ID, identity.UserID)
+	firstUserID := loggedIn.ID
+
+	// The next login resolves the linked user, even with another email at the provider
+	claims.Email = "jane@example.com"
+	req, loginState = startLogin(t, s, provider)
+	provider.EXPECT().Authenticate(gomock.Any(), "auth-code", loginState.CodeVerifier, loginState.Nonce).Return(claims, nil)
+	_, err = s.CompleteLogin(context.Background(), "demo", req, loginState)
+	require.NoError(t, err)
+	assert.Equal(t, firstUserID, loggedIn.ID)
+}
+
+func TestCompleteLogin_UsernameTaken(t *testing.T) {
+	s, _, provider, auth := newOIDCService(t)
+
+	var loggedIn entity.User
+	auth.EXPECT().LoginExternal(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user entity.User, req entity.LoginRequest) (entity.LoginResponse, error) {
+		loggedIn = user
+		return entity.LoginResponse{}, nil
+	})
+
+	// The seeded admin uses the username, not the email
+	req, loginState := startLogin(t, s, provider)
+	provider.EXPECT().Authenticate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(oidc.Claims{Subject: "42", Email: "admin@example.org", PreferredUsername: "admin"}, nil)
+	_, err := s.CompleteLogin(context.Background(), "demo", req, loginState)
+	require.NoError(t, err)
+	assert.Regexp(t, `^admin\d{4}$`, loggedIn.Username)
+}
+
+func TestCompleteLogin_Rejected(t *testing.T) {
+	cases := []struct {
+		name   string
+		claims oidc.Claims
+		err    error
+	}{
+		{"email of an existing user", oidc.Claims{Subject: "1", Email: "admin@mygmail.com", EmailVerified: verified(true)}, service.ErrOIDCAccountExists},
+		{"unverified email", oidc.Claims{Subject: "2", Email: "jane@gmail.com", EmailVerified: verified(false)}, service.ErrOIDCEmailNotVerified},
+		{"missing email", oidc.Claims{Subject: "3"}, service.ErrOIDCEmailNotVerified},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, store, provider, _ := newOIDCService(t)
+
+			req, loginState := startLogin(t, s, provider)
+			provider.EXPECT().Authenticate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(tc.claims, nil)
+			_, err := s.CompleteLogin(context.Background(), "demo", req, loginState)
+			assert.ErrorIs(t, err, tc.err)
+
+			// No account of the provider is linked
+			_, err = repository.NewMemoryUserIdentityRepository(store).GetUserIdentity(database.GetPostgres(), "demo", tc.claims.Subject)
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		})
+	}
+}
+
+func TestCompleteLogin_InvalidCallback(t *testing.T) {
+	s, _, provider, _ := newOIDCService(t)
+	req, loginState := startLogin(t, s, provider)
+
+	expired := loginState
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+
+	cases := []struct {
+		name       string
+		provider   string
+		req        entity.OIDCCallbackRequest
+		loginState entity.OIDCLoginState
+		err        error
+	}{
+		{"unknown provider", "other", req, loginState, service.ErrUnknownOIDCProvider},
+		{"denied", "demo", entity.OIDCCallbackRequest{Error: "access_denied", State: req.State}, loginState, service.ErrOIDCAuthorizationDenied},
+		{"other state", "demo", entity.OIDCCallbackRequest{Code: req.Code, State: "other"}, loginState, service.ErrInvalidOIDCCallback},
+		{"missing code", "demo", entity.OIDCCallbackRequest{State: req.State}, loginState, service.ErrInvalidOIDCCallback},
+		{"missing login state", "demo", req, entity.OIDCLoginState{}, service.ErrInvalidOIDCCallback},
+		{"expired login", "demo", req, expired, service.ErrInvalidOIDCCallback},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.CompleteLogin(context.Background(), tc.provider, tc.req, tc.loginState)
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package test_openapi_archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/docs"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/diagnostics"
+)
+
+// newRouter returns a router serving the archive of the OpenAPI document, mounted under the given base path.
+func newRouter(basePath string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/openapi.zip", handler.NewOpenAPIHandler(docs.OpenAPI, basePath).GetOpenAPIArchive)
+
+	return router
+}
+
+// readArchive returns the content of the entries of the zip archive, by name.
+func readArchive(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	entries := make(map[string][]byte)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		entries[f.Name] = content
+	}
+
+	return entries
+}
+
+func TestGetOpenAPIArchive_HoldsTheServedDocument(t *testing.T) {
+	original := diagnostics.Version
+	diagnostics.Version = "1.2.0"
+	t.Cleanup(func() { diagnostics.Version = original })
+
+	w := httptest.NewRecorder()
+	newRouter("/auth-svc").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.zip", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, handler.OpenAPIArchiveContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="go-jwt-auth-demo-openapi-1.0.0-1.2.0.zip"`, w.Header().Get("Content-Disposition"))
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+
+	// The archive holds the document as it is served, with the base path as its server URL, in YAML and in JSON
+	entries := readArchive(t, w.Body.Bytes())
+	require.Len(t, entries, 3)
+	assert.Contains(t, string(entries["openapi.yaml"]), "servers:\n  - url: /auth-svc\n")
+
+	var document struct {
+		OpenAPI string `json:"openapi"`
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(entries["openapi.json"], &document))
+	assert.Equal(t, "/auth-svc", document.Servers[0].URL)
+	assert.Contains(t, document.Paths, "/openapi.zip")
+
+	// The manifest identifies the contract and the build serving it
+	var manifest handler.OpenAPIManifest
+	require.NoError(t, json.Unmarshal(entries["manifest.json"], &manifest))
+	checksum := sha256.Sum256(entries["openapi.yaml"])
+	assert.Equal(t, "Go JWT Auth Demo API", manifest.Title)
+	assert.Equal(t, "1.0.0", manifest.APIVersion)
+	assert.Equal(t, "1.2.0", manifest.ServiceVersion)
+	assert.NotEmpty(t, manifest.Commit)
+	assert.Equal(t, "/auth-svc", manifest.ServerURL)
+	assert.Equal(t, hex.EncodeToString(checksum[:]), manifest.SHA256)
+}
+
+func TestGetOpenAPIArchive_NotModified(t *testing.T) {
+	router := newRouter("")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.zip", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+
+	// The archive is built the same way every time, so a client already holding it is answered with 304
+	req := httptest.NewRequest(http.MethodGet, "/openapi.zip", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+
+	// The archive of another base path is another archive
+	w = httptest.NewRecorder()
+	newRouter("/auth-svc").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.zip", nil))
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+}
@@ -0,0 +1,132 @@
+package test_openapi_validation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/docs"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	request_filter "github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/request-filter"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// newRouter creates a router validating the requests against the OpenAPI document,
+// with handlers echoing the login request they bound and the parameters they received.
+func newRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	validateRequests, err := request_filter.ValidateRequests(docs.OpenAPI)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(validateRequests)
+	router.POST("/auth/login", func(c *gin.Context) {
+		var req entity.LoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httputil.BadRequest(c, "Invalid request", err.Error())
+			return
+		}
+		httputil.Success(c, "Login successful", gin.H{"username": req.Username})
+	})
+	router.POST("/api/v1/admin/users/:id/revoke-tokens", func(c *gin.Context) {
+		httputil.Success(c, "Tokens revoked successfully", gin.H{"id": c.Param("id")})
+	})
+	router.GET("/api/v1/admin/token-stats", func(c *gin.Context) {
+		httputil.Success(c, "Token statistics retrieved successfully", nil)
+	})
+	router.GET("/undocumented", func(c *gin.Context) {
+		httputil.Success(c, "OK", nil)
+	})
+
+	return router
+}
+
+// send sends a request to the router and decodes the response envelope.
+func send(t *testing.T, router *gin.Engine, method string, path string, body string) (int, httputil.HttpResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp httputil.HttpResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+	return w.Code, resp
+}
+
+// fields returns the fields of the validation errors of a response.
+func fields(t *testing.T, resp httputil.HttpResponse) []string {
+	t.Helper()
+
+	raw, err := json.Marshal(resp.Error)
+	require.NoError(t, err)
+	var issues []map[string]string
+	require.NoError(t, json.Unmarshal(raw, &issues), string(raw))
+
+	var names []string
+	for _, issue := range issues {
+		assert.NotEmpty(t, issue["message"])
+		names = append(names, issue["field"])
+	}
+	return names
+}
+
+func TestValidateRequests_ValidRequestReachesTheHandler(t *testing.T) {
+	router := newRouter(t)
+
+	// The body is still readable by the handler after its validation
+	status, resp := send(t, router, http.MethodPost, "/auth/login", `{"username":"alice","password":"P@ssw0rd"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]any{"username": "alice"}, resp.Data)
+}
+
+func TestValidateRequests_RejectsTypeMismatches(t *testing.T) {
+	router := newRouter(t)
+
+	status, resp := send(t, router, http.MethodPost, "/auth/login", `{"username":42,"password":"P@ssw0rd"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, []string{"username"}, fields(t, resp))
+
+	status, resp = send(t, router, http.MethodPost, "/api/v1/admin/users/abc/revoke-tokens", "")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, []string{"id"}, fields(t, resp))
+
+	status, resp = send(t, router, http.MethodGet, "/api/v1/admin/token-stats?userId=abc", "")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, []string{"userId"}, fields(t, resp))
+}
+
+func TestValidateRequests_RejectsUnknownFields(t *testing.T) {
+	router := newRouter(t)
+
+	status, resp := send(t, router, http.MethodPost, "/auth/login", `{"username":"alice","password":"P@ssw0rd","isAdmin":true}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "Invalid request", resp.Message)
+	assert.Contains(t, resp.Error, map[string]any{"field": "body", "message": `property "isAdmin" is unsupported`})
+}
+
+func TestValidateRequests_ReportsEveryError(t *testing.T) {
+	router := newRouter(t)
+
+	status, resp := send(t, router, http.MethodPost, "/auth/login", `{"username":42}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.ElementsMatch(t, []string{"username", "password"}, fields(t, resp))
+}
+
+func TestValidateRequests_UndocumentedRoutesAreNotValidated(t *testing.T) {
+	router := newRouter(t)
+
+	status, _ := send(t, router, http.MethodGet, "/undocumented?anything=1", "")
+	assert.Equal(t, http.StatusOK, status)
+}
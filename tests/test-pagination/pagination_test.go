@@ -0,0 +1,118 @@
+package test_pagination
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	pagination "github.com/yoanesber/go-jwt-auth-demo/pkg/util/pagination-util"
+)
+
+// newContext creates a gin context for a request with the given query string.
+func newContext(query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/consumers?"+query, nil)
+	return c
+}
+
+func TestParseWithConfig(t *testing.T) {
+	reject := pagination.Config{MaxLimit: 100, Mode: pagination.ModeReject}
+	clamp := pagination.Config{MaxLimit: 100, Mode: pagination.ModeClamp}
+
+	tests := []struct {
+		name    string
+		query   string
+		cfg     pagination.Config
+		want    pagination.Params
+		wantErr string
+	}{
+		{"defaults", "", reject, pagination.Params{Page: 1, Limit: 10}, ""},
+		{"explicit", "page=3&limit=25", reject, pagination.Params{Page: 3, Limit: 25}, ""},
+		{"limit at maximum", "limit=100", reject, pagination.Params{Page: 1, Limit: 100}, ""},
+		{"limit over maximum rejected", "limit=101", reject, pagination.Params{}, "Limit must not exceed 100"},
+		{"limit over maximum clamped", "limit=100000", clamp, pagination.Params{Page: 1, Limit: 100}, ""},
+		{"default limit lowered to maximum", "", pagination.Config{MaxLimit: 5}, pagination.Params{Page: 1, Limit: 5}, ""},
+		{"zero page", "page=0", reject, pagination.Params{}, "Page must be a positive integer"},
+		{"invalid page", "page=abc", reject, pagination.Params{}, "Page must be a positive integer"},
+		{"negative limit", "limit=-1", clamp, pagination.Params{}, "Limit must be a positive integer"},
+		{"overflowing limit", "limit=99999999999999999999", clamp, pagination.Params{}, "Limit must be a positive integer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := pagination.Parse(newContext(tt.query), tt.cfg)
+
+			if tt.wantErr != "" {
+				require.NotNil(t, err)
+				assert.Equal(t, tt.wantErr, err.Detail)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, tt.want, params)
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("PAGINATION_MAX_LIMIT", "")
+	t.Setenv("PAGINATION_LIMIT_MODE", "")
+	assert.Equal(t, pagination.Config{MaxLimit: pagination.DefaultMaxLimit, Mode: pagination.ModeReject}, pagination.LoadConfig())
+
+	t.Setenv("PAGINATION_MAX_LIMIT", "250")
+	t.Setenv("PAGINATION_LIMIT_MODE", "clamp")
+	assert.Equal(t, pagination.Config{MaxLimit: 250, Mode: pagination.ModeClamp}, pagination.LoadConfig())
+
+	t.Setenv("PAGINATION_MAX_LIMIT", "-3")
+	assert.Equal(t, pagination.DefaultMaxLimit, pagination.LoadConfig().MaxLimit)
+}
+
+// withRoles returns the context with the roles of the caller, as set by the JWT validation middleware.
+func withRoles(c *gin.Context, roles ...string) *gin.Context {
+	c.Request = c.Request.WithContext(metacontext.InjectUserInformationMeta(c.Request.Context(), metacontext.UserInformationMeta{Roles: roles}))
+	return c
+}
+
+func TestParseWithConfig_CapsThePageSizePerRole(t *testing.T) {
+	cfg := pagination.Config{MaxLimit: 100, Mode: pagination.ModeReject, Roles: map[string]pagination.Limits{"ROLE_USER": {MaxLimit: 20}}}
+
+	_, err := pagination.Parse(withRoles(newContext("limit=50"), "ROLE_USER"), cfg)
+	require.NotNil(t, err)
+	assert.Equal(t, "Limit must not exceed 20", err.Detail)
+
+	// The roles without override, and the anonymous callers, have the default cap, the highest cap of the roles applies
+	for _, c := range []*gin.Context{withRoles(newContext("limit=50"), "ROLE_ADMIN"), withRoles(newContext("limit=50"), "ROLE_USER", "ROLE_ADMIN"), newContext("limit=50")} {
+		params, err := pagination.Parse(c, cfg)
+		require.Nil(t, err)
+		assert.Equal(t, 50, params.Limit)
+	}
+}
+
+func TestConfig_ForRoles(t *testing.T) {
+	cfg := pagination.Config{MaxLimit: 100, MaxExportRows: 0, Roles: map[string]pagination.Limits{
+		"ROLE_USER":    {MaxLimit: 20, MaxExportRows: 1000},
+		"ROLE_AUDITOR": {MaxLimit: 200, MaxExportRows: 5000},
+	}}
+
+	assert.Equal(t, pagination.Limits{MaxLimit: 20, MaxExportRows: 1000}, cfg.ForRoles([]string{"ROLE_USER"}))
+	assert.Equal(t, pagination.Limits{MaxLimit: 200, MaxExportRows: 5000}, cfg.ForRoles([]string{"ROLE_USER", "ROLE_AUDITOR"}))
+	assert.Equal(t, pagination.Limits{MaxLimit: 100, MaxExportRows: 0}, cfg.ForRoles([]string{"ROLE_USER", "ROLE_ADMIN"}))
+	assert.Equal(t, pagination.Limits{MaxLimit: 100, MaxExportRows: 0}, cfg.ForRoles(nil))
+}
+
+func TestParseRoleLimits(t *testing.T) {
+	roles, err := pagination.ParseRoleLimits("ROLE_USER=20/1000, ROLE_AUDITOR=200/0")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]pagination.Limits{
+		"ROLE_USER":    {MaxLimit: 20, MaxExportRows: 1000},
+		"ROLE_AUDITOR": {MaxLimit: 200},
+	}, roles)
+
+	for _, value := range []string{"ROLE_USER=20/1000/10", "ROLE_USER=20", "ROLE_USER=0/1000", "ROLE_USER=20/-1", "=20/1000", "ROLE_USER"} {
+		_, err := pagination.ParseRoleLimits(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,87 @@
+package test_proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	proxyconfig "github.com/yoanesber/go-jwt-auth-demo/config/proxy-config"
+)
+
+// newRouter creates a router configured with the proxy settings from the environment,
+// with a route returning the resolved client IP.
+func newRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := proxyconfig.Load()
+	require.NoError(t, err)
+
+	r := gin.New()
+	require.NoError(t, cfg.Apply(r))
+	r.GET("/ip", func(c *gin.Context) {
+		c.String(http.StatusOK, c.ClientIP())
+	})
+
+	return r
+}
+
+// clientIP returns the client IP resolved for a request from the given peer address with the given headers.
+func clientIP(r *gin.Engine, remoteAddr string, headers map[string]string) string {
+	req := httptest.NewRequest("GET", "/ip", nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	return w.Body.String()
+}
+
+func TestClientIP_NoTrustedProxiesIgnoresForwardingHeaders(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "")
+	r := newRouter(t)
+
+	ip := clientIP(r, "203.0.113.7:4321", map[string]string{
+		"X-Forwarded-For": "198.51.100.1",
+		"X-Real-IP":       "198.51.100.2",
+	})
+	assert.Equal(t, "203.0.113.7", ip)
+}
+
+func TestClientIP_TrustedProxyForwardsClientIP(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.10")
+	r := newRouter(t)
+
+	// The client IP is read from the header set by a trusted proxy
+	assert.Equal(t, "198.51.100.1", clientIP(r, "10.1.2.3:4321", map[string]string{"X-Forwarded-For": "198.51.100.1"}))
+	assert.Equal(t, "198.51.100.2", clientIP(r, "192.168.1.10:4321", map[string]string{"X-Real-IP": "198.51.100.2"}))
+
+	// Addresses prepended by the client are skipped, the rightmost untrusted address is the client
+	assert.Equal(t, "198.51.100.1", clientIP(r, "10.1.2.3:4321", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1, 10.0.0.5"}))
+
+	// The headers of an untrusted peer are ignored
+	assert.Equal(t, "203.0.113.7", clientIP(r, "203.0.113.7:4321", map[string]string{"X-Forwarded-For": "198.51.100.1"}))
+}
+
+func TestClientIP_RemoteIPHeaders(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8")
+	t.Setenv("REMOTE_IP_HEADERS", "x-real-ip")
+	r := newRouter(t)
+
+	// Only the configured header is used
+	assert.Equal(t, "10.1.2.3", clientIP(r, "10.1.2.3:4321", map[string]string{"X-Forwarded-For": "198.51.100.1"}))
+	assert.Equal(t, "198.51.100.2", clientIP(r, "10.1.2.3:4321", map[string]string{"X-Real-IP": "198.51.100.2"}))
+}
+
+func TestLoad_RejectsInvalidProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,load-balancer")
+
+	_, err := proxyconfig.Load()
+	assert.EqualError(t, err, `TRUSTED_PROXIES entry "load-balancer" is neither an IP nor a CIDR`)
+}
@@ -0,0 +1,127 @@
+package test_quota
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	request_filter "github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/request-filter"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/quota"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+func TestTracker_DailyBudgetResetsAtMidnight(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 22, 0, 0, 0, time.UTC))
+	tracker := quota.NewTracker(quota.Policy{Default: quota.Budget{Daily: 10}}, clk)
+
+	usage, ok := tracker.Consume("web", 8)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), usage.Daily.Remaining)
+	assert.True(t, usage.Monthly.Unlimited)
+
+	// A request costing more than the units left is rejected and spends nothing
+	usage, ok = tracker.Consume("web", 3)
+	assert.False(t, ok)
+	assert.Equal(t, int64(8), usage.Daily.Used)
+	assert.Equal(t, 2*time.Hour, usage.RetryAfter(3, clk.Now()))
+
+	// The clients have their own budgets
+	_, ok = tracker.Consume("mobile", 3)
+	assert.True(t, ok)
+
+	clk.Advance(2 * time.Hour)
+	usage, ok = tracker.Consume("web", 3)
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), usage.Daily.Used)
+	assert.Equal(t, time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC), usage.Daily.ResetsAt)
+}
+
+func TestTracker_MonthlyBudgetAndClientOverrides(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC))
+	tracker := quota.NewTracker(quota.Policy{
+		Default: quota.Budget{Daily: 100, Monthly: 5},
+		Clients: map[string]quota.Budget{"partner": {Daily: 100, Monthly: 50}},
+	}, clk)
+
+	_, ok := tracker.Consume("web", 5)
+	assert.True(t, ok)
+	usage, ok := tracker.Consume("web", 1)
+	assert.False(t, ok)
+	assert.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), usage.Monthly.ResetsAt)
+	assert.Equal(t, 14*time.Hour, usage.RetryAfter(1, clk.Now()))
+
+	_, ok = tracker.Consume("partner", 20)
+	assert.True(t, ok)
+
+	clk.Advance(14 * time.Hour)
+	usage = tracker.Usage("web")
+	assert.Equal(t, int64(0), usage.Monthly.Used)
+	assert.Equal(t, int64(5), usage.Monthly.Remaining)
+}
+
+func TestParseBudgetsAndCosts(t *testing.T) {
+	budgets, err := quota.ParseBudgets("web=1000/20000, partner=0/500")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]quota.Budget{"web": {Daily: 1000, Monthly: 20000}, "partner": {Daily: 0, Monthly: 500}}, budgets)
+
+	costs, err := quota.ParseCosts("get /api/v1/consumers/stream=20, POST /api/v1/consumers=5")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"GET /api/v1/consumers/stream": 20, "POST /api/v1/consumers": 5}, costs)
+
+	for _, value := range []string{"web", "web=1000", "web=-1/10", "=1/1"} {
+		_, err := quota.ParseBudgets(value)
+		assert.Error(t, err, value)
+	}
+	for _, value := range []string{"/api/v1/consumers=5", "GET api/v1=5", "GET /api/v1/consumers=x"} {
+		_, err := quota.ParseCosts(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestLoadPolicy_DefaultCosts(t *testing.T) {
+	t.Setenv("QUOTA_DAILY_LIMIT", "500")
+	t.Setenv("QUOTA_ENDPOINT_COSTS", "GET /api/v1/consumers=2")
+
+	policy := quota.LoadPolicy()
+	assert.Equal(t, quota.Budget{Daily: 500}, policy.Default)
+	assert.Equal(t, int64(10), policy.Cost("GET", "/api/v1/consumers/stream"))
+	assert.Equal(t, int64(2), policy.Cost("GET", "/api/v1/consumers"))
+	assert.Equal(t, int64(0), policy.Cost("GET", "/api/v1/me/quota"))
+	assert.Equal(t, quota.DefaultCost, policy.Cost("GET", "/api/v1/consumers/:id"))
+}
+
+func TestEnforceQuota_PerClient(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 23, 59, 0, 0, time.UTC))
+	tracker := quota.NewTracker(quota.Policy{
+		Default: quota.Budget{Daily: 3},
+		Costs:   map[string]int64{"GET /export": 3},
+	}, clk)
+
+	router := testsupport.NewRouter(t, request_filter.EnforceQuota(tracker))
+	for _, path := range []string{"/cheap", "/export"} {
+		router.GET(path, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	}
+
+	web := testsupport.NewTokenBuilder().WithUser(1, "alice", "alice@example.com").WithClaim("client", "web").Build(t)
+	webBob := testsupport.NewTokenBuilder().WithUser(2, "bob", "bob@example.com").WithClaim("client", "web").Build(t)
+	carol := testsupport.NewTokenBuilder().WithUser(3, "carol", "carol@example.com").Build(t)
+
+	w := testsupport.Do(router, "GET", "/cheap", web)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "3", w.Header().Get("X-Quota-Limit"))
+	assert.Equal(t, "2", w.Header().Get("X-Quota-Remaining"))
+
+	// The users of the same client share its budget, the export costs more than what is left
+	w = testsupport.Do(router, "GET", "/export", webBob)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	// A token issued without client has the budget of its user
+	assert.Equal(t, http.StatusNoContent, testsupport.Do(router, "GET", "/export", carol).Code)
+	assert.Equal(t, int64(3), tracker.Usage("user:3").Daily.Used)
+}
@@ -0,0 +1,193 @@
+package test_ratelimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/cache"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	request_filter "github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/request-filter"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/ratelimit"
+)
+
+// assertTokenBucket checks that the store allows a burst of 2 requests, then 1 request every 30 seconds.
+func assertTokenBucket(t *testing.T, store ratelimit.BucketStore, clk *clock.FakeClock) {
+	ctx := context.Background()
+	policy := ratelimit.BucketPolicy{Burst: 2, PerMinute: 2}
+
+	for range 2 {
+		ok, _ := store.Take(ctx, "alice", policy)
+		assert.True(t, ok)
+	}
+	ok, retryAfter := store.Take(ctx, "alice", policy)
+	assert.False(t, ok, "the burst is used up")
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	// The keys have their own bucket
+	ok, _ = store.Take(ctx, "bob", policy)
+	assert.True(t, ok)
+
+	// A token is added every 30 seconds
+	clk.Advance(20 * time.Second)
+	ok, retryAfter = store.Take(ctx, "alice", policy)
+	assert.False(t, ok)
+	assert.Equal(t, 10*time.Second, retryAfter)
+
+	clk.Advance(10 * time.Second)
+	ok, _ = store.Take(ctx, "alice", policy)
+	assert.True(t, ok)
+	ok, _ = store.Take(ctx, "alice", policy)
+	assert.False(t, ok)
+
+	// The bucket is refilled up to its burst
+	clk.Advance(time.Hour)
+	for range 2 {
+		ok, _ = store.Take(ctx, "alice", policy)
+		assert.True(t, ok)
+	}
+	ok, _ = store.Take(ctx, "alice", policy)
+	assert.False(t, ok)
+}
+
+func TestMemoryBuckets(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	assertTokenBucket(t, ratelimit.NewMemoryBuckets(clk), clk)
+}
+
+func TestRedisBuckets(t *testing.T) {
+	srv := miniredis.RunT(t)
+	cache.SetRedis(redis.NewClient(&redis.Options{Addr: srv.Addr()}))
+	t.Cleanup(cache.CloseRedis)
+
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	assertTokenBucket(t, ratelimit.NewRedisBuckets("test:", clk), clk)
+	assert.True(t, srv.Exists("test:alice"), "the buckets are stored with the prefix")
+
+	// Another instance shares the buckets
+	ok, _ := ratelimit.NewRedisBuckets("test:", clk).Take(context.Background(), "alice", ratelimit.BucketPolicy{Burst: 2, PerMinute: 2})
+	assert.False(t, ok)
+}
+
+func TestRedisBuckets_FallBackToTheProcessWithoutRedis(t *testing.T) {
+	cache.CloseRedis()
+
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	assertTokenBucket(t, ratelimit.NewRedisBuckets("test:", clk), clk)
+}
+
+// newAuthRouter creates a router with a login route limited by the token buckets of the given settings,
+// returning the username it was called with.
+func newAuthRouter(cfg ratelimit.AuthConfig, clk clock.Clock) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	limiter := ratelimit.NewAuthLimiter(cfg, ratelimit.NewMemoryBuckets(clk))
+
+	r := gin.New()
+	r.POST("/auth/login", request_filter.AuthRateLimit(limiter, request_filter.LoginSubject), func(c *gin.Context) {
+		var body struct {
+			Username string `json:"username"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, body.Username)
+	})
+	r.POST("/auth/refresh-token", request_filter.AuthRateLimit(limiter, request_filter.RefreshTokenSubject), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+
+	return r
+}
+
+// login sends a login request of the username from the client IP.
+func login(r *gin.Engine, ip string, username string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/auth/login", strings.NewReader(`{"username":"`+username+`","password":"P@ssw0rd"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":1234"
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestAuthRateLimit_LimitsTheUsernames(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	r := newAuthRouter(ratelimit.AuthConfig{Subject: ratelimit.BucketPolicy{Burst: 2, PerMinute: 1}}, clk)
+
+	// The body is given back to the handler
+	w := login(r, "192.0.2.1", "alice")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", w.Body.String())
+
+	// The usernames are case-insensitive, and limited whatever the client IP
+	assert.Equal(t, http.StatusOK, login(r, "192.0.2.2", "Alice").Code)
+	w = login(r, "192.0.2.3", "alice")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, login(r, "192.0.2.3", "bob").Code)
+}
+
+func TestAuthRateLimit_LimitsTheClientIPs(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	r := newAuthRouter(ratelimit.AuthConfig{IP: ratelimit.BucketPolicy{Burst: 2, PerMinute: 6}}, clk)
+
+	assert.Equal(t, http.StatusOK, login(r, "192.0.2.1", "alice").Code)
+	assert.Equal(t, http.StatusOK, login(r, "192.0.2.1", "bob").Code)
+	w := login(r, "192.0.2.1", "carol")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, login(r, "192.0.2.2", "carol").Code)
+
+	clk.Advance(10 * time.Second)
+	assert.Equal(t, http.StatusOK, login(r, "192.0.2.1", "carol").Code)
+}
+
+func TestAuthRateLimit_LimitsTheRefreshTokens(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	r := newAuthRouter(ratelimit.AuthConfig{Subject: ratelimit.BucketPolicy{Burst: 1, PerMinute: 1}}, clk)
+
+	refresh := func(ip string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/auth/refresh-token", strings.NewReader(`{"refreshToken":"`+token+`"}`))
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := refresh("192.0.2.1", "token-1")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"refreshToken":"token-1"}`, w.Body.String())
+	assert.Equal(t, http.StatusTooManyRequests, refresh("192.0.2.2", "token-1").Code)
+	assert.Equal(t, http.StatusOK, refresh("192.0.2.2", "token-2").Code)
+}
+
+func TestLoadAuthConfig(t *testing.T) {
+	assert.Equal(t, ratelimit.AuthConfig{IP: ratelimit.DefaultAuthIPPolicy, Subject: ratelimit.DefaultAuthSubjectPolicy, Backend: ratelimit.BackendMemory}, ratelimit.LoadAuthConfig())
+
+	t.Setenv("AUTH_RATE_LIMIT_IP_BURST", "50")
+	t.Setenv("AUTH_RATE_LIMIT_IP_PER_MINUTE", "0")
+	t.Setenv("AUTH_RATE_LIMIT_USERNAME_BURST", "-1")
+	t.Setenv("AUTH_RATE_LIMIT_USERNAME_PER_MINUTE", "3")
+	t.Setenv("AUTH_RATE_LIMIT_BACKEND", "REDIS")
+
+	cfg := ratelimit.LoadAuthConfig()
+	assert.Equal(t, ratelimit.BucketPolicy{Burst: 50, PerMinute: 0}, cfg.IP)
+	assert.False(t, cfg.IP.Enabled(), "a bucket that is not refilled is disabled")
+	assert.Equal(t, ratelimit.BucketPolicy{Burst: ratelimit.DefaultAuthSubjectPolicy.Burst, PerMinute: 3}, cfg.Subject)
+	assert.Equal(t, ratelimit.BackendRedis, cfg.Backend)
+}
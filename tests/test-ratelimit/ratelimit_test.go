@@ -0,0 +1,194 @@
+package test_ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	request_filter "github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/request-filter"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/ratelimit"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+func TestLimiter_SlidingWindow(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	limiter := ratelimit.NewLimiter(2, time.Minute, clk)
+
+	ok, _ := limiter.Allow("alice")
+	assert.True(t, ok)
+	clk.Advance(20 * time.Second)
+	ok, _ = limiter.Allow("alice")
+	assert.True(t, ok)
+
+	// The third request is rejected until the first one leaves the window
+	ok, retryAfter := limiter.Allow("alice")
+	assert.False(t, ok)
+	assert.Equal(t, 40*time.Second, retryAfter)
+
+	// The keys are limited independently
+	ok, _ = limiter.Allow("bob")
+	assert.True(t, ok)
+
+	clk.Advance(40 * time.Second)
+	ok, _ = limiter.Allow("alice")
+	assert.True(t, ok)
+	ok, retryAfter = limiter.Allow("alice")
+	assert.False(t, ok)
+	assert.Equal(t, 20*time.Second, retryAfter)
+}
+
+func TestRateLimit_PerUser(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	router := testsupport.NewRouter(t)
+	router.GET("/limited", request_filter.RateLimit(ratelimit.NewLimiter(1, time.Minute, clk)), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	alice := testsupport.NewTokenBuilder().WithUser(1, "alice", "alice@example.com").Build(t)
+	bob := testsupport.NewTokenBuilder().WithUser(2, "bob", "bob@example.com").Build(t)
+
+	assert.Equal(t, http.StatusNoContent, testsupport.Do(router, "GET", "/limited", alice).Code)
+
+	w := testsupport.Do(router, "GET", "/limited", alice)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusNoContent, testsupport.Do(router, "GET", "/limited", bob).Code)
+}
+
+func TestScale(t *testing.T) {
+	assert.Equal(t, 300, ratelimit.Scale(30, 10))
+	assert.Equal(t, 3, ratelimit.Scale(30, 0.1))
+	assert.Equal(t, 7, ratelimit.Scale(5, 1.5))
+
+	// A throttled client is always allowed one request per window
+	assert.Equal(t, 1, ratelimit.Scale(5, 0.01))
+}
+
+func TestOverrides(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	loaded := map[string]float64{"partner": 10}
+	var loadErr error
+	loads := 0
+	o := ratelimit.NewOverrides(func() (map[string]float64, error) {
+		loads++
+		return loaded, loadErr
+	}, time.Minute, clk)
+
+	// The factors apply once they are loaded
+	assert.Equal(t, 1.0, o.Factor("partner"))
+	assert.Equal(t, 0, loads)
+	require.NoError(t, o.Load())
+	assert.Equal(t, 10.0, o.Factor("partner"))
+	assert.Equal(t, 1.0, o.Factor("web"))
+
+	// The factors are reloaded once they are older than the TTL, or at once after Invalidate
+	loaded = map[string]float64{"partner": 0.5}
+	clk.Advance(30 * time.Second)
+	assert.Equal(t, 10.0, o.Factor("partner"))
+	o.Invalidate()
+	assert.Equal(t, 0.5, o.Factor("partner"))
+	assert.Equal(t, 2, loads)
+
+	// A failed reload keeps the previous factors
+	loaded, loadErr = nil, errors.New("database is down")
+	clk.Advance(time.Minute)
+	assert.Equal(t, 0.5, o.Factor("partner"))
+	assert.Equal(t, 3, loads)
+
+	var none *ratelimit.Overrides
+	assert.Equal(t, 1.0, none.Factor("partner"))
+	assert.NoError(t, none.Load())
+	none.Invalidate()
+}
+
+func TestRateLimitWithOverrides_PerClient(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	overrides := ratelimit.NewOverrides(func() (map[string]float64, error) {
+		return map[string]float64{"partner": 3, "user:3": 0.1}, nil
+	}, 0, clk)
+	require.NoError(t, overrides.Load())
+
+	router := testsupport.NewRouter(t)
+	router.GET("/limited", request_filter.RateLimitWithOverrides(ratelimit.NewLimiter(2, time.Minute, clk), overrides), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	// The client of the token is allowed three times the limit
+	partner := testsupport.NewTokenBuilder().WithUser(1, "alice", "alice@example.com").WithClaim("client", "partner").Build(t)
+	for i := 0; i < 6; i++ {
+		assert.Equal(t, http.StatusNoContent, testsupport.Do(router, "GET", "/limited", partner).Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, testsupport.Do(router, "GET", "/limited", partner).Code)
+
+	// The other clients are allowed the limit
+	web := testsupport.NewTokenBuilder().WithUser(2, "bob", "bob@example.com").WithClaim("client", "web").Build(t)
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusNoContent, testsupport.Do(router, "GET", "/limited", web).Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, testsupport.Do(router, "GET", "/limited", web).Code)
+
+	// The users of the tokens without client are overridden by user ID, and always allowed one request
+	carol := testsupport.NewTokenBuilder().WithUser(3, "carol", "carol@example.com").Build(t)
+	assert.Equal(t, http.StatusNoContent, testsupport.Do(router, "GET", "/limited", carol).Code)
+	assert.Equal(t, http.StatusTooManyRequests, testsupport.Do(router, "GET", "/limited", carol).Code)
+}
+
+// newRateLimitOverrideService creates a rate limit override service backed by an in-memory store, with its overrides loaded.
+func newRateLimitOverrideService(t *testing.T) service.RateLimitOverrideService {
+	store := testsupport.UseMemoryDatabase(t)
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+
+	s := service.NewRateLimitOverrideService(database.DefaultStore(), repository.NewMemoryRateLimitOverrideRepository(store), time.Hour, clk)
+	require.NoError(t, s.LoadOverrides())
+
+	return s
+}
+
+func TestRateLimitOverrideService(t *testing.T) {
+	s := newRateLimitOverrideService(t)
+	factor, reason := 10.0, "Onboarding of the partner"
+
+	override, err := s.SaveRateLimitOverride(context.Background(), 1, "partner", entity.RateLimitOverrideRequest{Factor: &factor, Reason: &reason})
+	require.NoError(t, err)
+	assert.Equal(t, "partner", override.Client)
+	assert.Equal(t, int64(1), *override.UpdatedBy)
+
+	// The instance applies its own changes at once, whatever the TTL
+	assert.Equal(t, 10.0, s.Overrides().Factor("partner"))
+
+	overrides, err := s.GetRateLimitOverrides(context.Background())
+	require.NoError(t, err)
+	require.Len(t, overrides, 1)
+	assert.Equal(t, reason, *overrides[0].Reason)
+
+	require.NoError(t, s.DeleteRateLimitOverride(context.Background(), 1, "partner"))
+	assert.Equal(t, 1.0, s.Overrides().Factor("partner"))
+	assert.ErrorIs(t, s.DeleteRateLimitOverride(context.Background(), 1, "partner"), service.ErrRateLimitOverrideNotFound)
+}
+
+func TestRateLimitOverrideService_InvalidRequest(t *testing.T) {
+	s := newRateLimitOverrideService(t)
+	factor, zero, tooHigh := 2.0, 0.0, 1001.0
+
+	_, err := s.SaveRateLimitOverride(context.Background(), 1, " ", entity.RateLimitOverrideRequest{Factor: &factor})
+	assert.ErrorIs(t, err, service.ErrInvalidRateLimitClient)
+
+	for _, req := range []entity.RateLimitOverrideRequest{{}, {Factor: &zero}, {Factor: &tooHigh}} {
+		_, err = s.SaveRateLimitOverride(context.Background(), 1, "partner", req)
+		var ve validator.ValidationErrors
+		assert.ErrorAs(t, err, &ve)
+	}
+}
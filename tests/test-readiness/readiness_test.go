@@ -0,0 +1,155 @@
+package test_readiness
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/readiness"
+)
+
+// get sends an unauthenticated GET request to the router, like the probes of the load balancers.
+func get(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestReadyz_NotReadyUntilWarmUpCompletes(t *testing.T) {
+	gate := readiness.NewGate(time.Millisecond)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/readyz", handler.NewReadinessHandler(gate).Readyz)
+
+	// Before the warm-up, the service is not ready
+	w := get(router, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	// A required step failing is retried, and the probe reports what the service is waiting for
+	var attempts atomic.Int32
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- gate.WarmUp(context.Background(), []readiness.Step{
+			{Name: "database", Run: func(context.Context) error {
+				attempts.Add(1)
+				select {
+				case <-release:
+					return nil
+				default:
+					return errors.New("connection refused")
+				}
+			}},
+		})
+	}()
+
+	require.Eventually(t, func() bool { return attempts.Load() >= 2 }, time.Second, time.Millisecond)
+	w = get(router, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "database (connection refused)")
+
+	close(release)
+	require.NoError(t, <-done)
+
+	w = get(router, "/readyz")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"ok"`)
+}
+
+func TestWarmUp_OptionalStepFailureDoesNotBlock(t *testing.T) {
+	gate := readiness.NewGate(time.Millisecond)
+
+	err := gate.WarmUp(context.Background(), []readiness.Step{
+		{Name: "validator", Run: func(context.Context) error { return nil }},
+		{Name: "role_cache", Optional: true, Run: func(context.Context) error { return errors.New("query failed") }},
+	})
+	require.NoError(t, err)
+	assert.True(t, gate.IsReady())
+
+	steps := gate.Steps()
+	require.Len(t, steps, 2)
+	assert.Equal(t, readiness.StepOK, steps[0].Status)
+	assert.Equal(t, readiness.StepFailed, steps[1].Status)
+	assert.Equal(t, "query failed", steps[1].Error)
+}
+
+func TestWarmUp_Canceled(t *testing.T) {
+	gate := readiness.NewGate(time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := gate.WarmUp(ctx, []readiness.Step{
+		{Name: "database", Run: func(context.Context) error { return errors.New("connection refused") }},
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, gate.IsReady())
+}
+
+func TestReadyz_NotReadyWhileDraining(t *testing.T) {
+	gate := readiness.NewGate(time.Millisecond)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/readyz", handler.NewReadinessHandler(gate).Readyz)
+
+	require.NoError(t, gate.WarmUp(context.Background(), nil))
+	assert.Equal(t, http.StatusOK, get(router, "/readyz").Code)
+
+	// The drain waits for the given period, the probe reports not ready meanwhile
+	done := make(chan struct{})
+	go func() {
+		gate.Drain(context.Background(), 50*time.Millisecond)
+		close(done)
+	}()
+
+	require.Eventually(t, gate.IsDraining, time.Second, time.Millisecond)
+	w := get(router, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "The service is shutting down")
+	assert.False(t, gate.IsReady())
+
+	select {
+	case <-done:
+		t.Fatal("the drain returned before its period")
+	default:
+	}
+	<-done
+}
+
+func TestDrain_CanceledAndWarmUpAfterwards(t *testing.T) {
+	gate := readiness.NewGate(time.Millisecond)
+
+	// A canceled drain returns right away
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	gate.Drain(ctx, time.Hour)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// A warm-up completing during the drain does not make the service ready again
+	require.NoError(t, gate.WarmUp(context.Background(), nil))
+	assert.False(t, gate.IsReady())
+}
+
+func TestLoadShutdownPolicy(t *testing.T) {
+	t.Setenv("SHUTDOWN_DRAIN_SECOND", "")
+	t.Setenv("SHUTDOWN_TIMEOUT_SECOND", "")
+	assert.Equal(t, readiness.ShutdownPolicy{DrainPeriod: readiness.DefaultDrainPeriod, Timeout: readiness.DefaultShutdownTimeout}, readiness.LoadShutdownPolicy())
+
+	t.Setenv("SHUTDOWN_DRAIN_SECOND", "0")
+	t.Setenv("SHUTDOWN_TIMEOUT_SECOND", "45")
+	assert.Equal(t, readiness.ShutdownPolicy{DrainPeriod: 0, Timeout: 45 * time.Second}, readiness.LoadShutdownPolicy())
+
+	// Invalid values fall back to the defaults
+	t.Setenv("SHUTDOWN_DRAIN_SECOND", "-5")
+	t.Setenv("SHUTDOWN_TIMEOUT_SECOND", "0")
+	assert.Equal(t, readiness.ShutdownPolicy{DrainPeriod: readiness.DefaultDrainPeriod, Timeout: readiness.DefaultShutdownTimeout}, readiness.LoadShutdownPolicy())
+}
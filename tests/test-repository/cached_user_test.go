@@ -0,0 +1,235 @@
+package test_repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newCachedUserRepository creates a cached user repository with a five-second TTL in front of a mocked repository.
+func newCachedUserRepository(t *testing.T) (repository.UserRepository, *mocks.MockUserRepository, *clock.FakeClock) {
+	ctrl := gomock.NewController(t)
+	backend := mocks.NewMockUserRepository(ctrl)
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+
+	return repository.NewCachedUserRepository(backend, 5*time.Second, clk), backend, clk
+}
+
+func TestCachedUserRepository_CachesFoundUsers(t *testing.T) {
+	repo, backend, clk := newCachedUserRepository(t)
+	user := entity.User{ID: 1, Username: "admin", Roles: []entity.Role{{ID: 3, Name: "ROLE_ADMIN"}}}
+	before := repository.GetUserCacheStats()
+
+	// The backend is queried once, then again only after the entry expired
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "admin").Return(user, nil).Times(2)
+
+	for _, username := range []string{"admin", "ADMIN", "Admin"} {
+		got, err := repo.GetUserByUsername(context.Background(), nil, username)
+		require.NoError(t, err)
+		assert.Equal(t, user, got)
+	}
+
+	clk.Advance(5 * time.Second)
+	_, err := repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+
+	after := repository.GetUserCacheStats()
+	assert.Equal(t, uint64(2), after.Hits-before.Hits)
+	assert.Equal(t, uint64(2), after.Misses-before.Misses)
+}
+
+func TestCachedUserRepository_CachesUnknownUsernames(t *testing.T) {
+	repo, backend, _ := newCachedUserRepository(t)
+	before := repository.GetUserCacheStats()
+
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "unknown").Return(entity.User{}, gorm.ErrRecordNotFound).Times(1)
+
+	for i := 0; i < 3; i++ {
+		_, err := repo.GetUserByUsername(context.Background(), nil, "unknown")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	}
+
+	after := repository.GetUserCacheStats()
+	assert.Equal(t, uint64(2), after.NegativeHits-before.NegativeHits)
+	assert.Greater(t, after.HitRate, 0.0)
+}
+
+func TestCachedUserRepository_DoesNotCacheErrors(t *testing.T) {
+	repo, backend, _ := newCachedUserRepository(t)
+
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "admin").Return(entity.User{}, errors.New("connection refused")).Times(2)
+
+	for i := 0; i < 2; i++ {
+		_, err := repo.GetUserByUsername(context.Background(), nil, "admin")
+		assert.EqualError(t, err, "connection refused")
+	}
+}
+
+func TestCachedUserRepository_InvalidatesOnUpdate(t *testing.T) {
+	repo, backend, _ := newCachedUserRepository(t)
+	user := entity.User{ID: 1, Username: "admin"}
+	renamed := entity.User{ID: 1, Username: "root"}
+
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "admin").Return(user, nil).Times(3)
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "root").Return(entity.User{}, gorm.ErrRecordNotFound).Times(1)
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "root").Return(renamed, nil).Times(1)
+	backend.EXPECT().UpdateUser(gomock.Any(), gomock.Any(), renamed).Return(renamed, nil)
+	backend.EXPECT().ReplaceUserRoles(gomock.Any(), gomock.Any(), int64(1), nil).Return(nil)
+
+	// Cache the user under its old username and the new username as unknown
+	_, err := repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+	_, err = repo.GetUserByUsername(context.Background(), nil, "root")
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// Renaming the user invalidates both entries
+	_, err = repo.UpdateUser(context.Background(), nil, renamed)
+	require.NoError(t, err)
+	got, err := repo.GetUserByUsername(context.Background(), nil, "root")
+	require.NoError(t, err)
+	assert.Equal(t, renamed, got)
+
+	// Changing the roles of the user invalidates its entries as well
+	_, err = repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+	_, err = repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+	require.NoError(t, repo.ReplaceUserRoles(context.Background(), nil, 1, nil))
+	_, err = repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+}
+
+func TestCachedUserRepository_InvalidatesOnCreate(t *testing.T) {
+	repo, backend, _ := newCachedUserRepository(t)
+	user := entity.User{Username: "bob"}
+	created := entity.User{ID: 2, Username: "bob"}
+
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "bob").Return(entity.User{}, gorm.ErrRecordNotFound).Times(1)
+	backend.EXPECT().CreateUser(gomock.Any(), gomock.Any(), user).Return(created, nil)
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "bob").Return(created, nil).Times(1)
+
+	_, err := repo.GetUserByUsername(context.Background(), nil, "bob")
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// The registered user can log in right away
+	_, err = repo.CreateUser(context.Background(), nil, user)
+	require.NoError(t, err)
+	got, err := repo.GetUserByUsername(context.Background(), nil, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, created, got)
+}
+
+func TestCachedUserRepository_InvalidatesOnTokenRevocation(t *testing.T) {
+	repo, backend, _ := newCachedUserRepository(t)
+	user := entity.User{ID: 1, Username: "admin"}
+	revoked := entity.User{ID: 1, Username: "admin", TokenVersion: 1}
+
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "admin").Return(user, nil).Times(1)
+	backend.EXPECT().IncrementTokenVersion(gomock.Any(), gomock.Any(), int64(1)).Return(int64(1), nil)
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "admin").Return(revoked, nil).Times(1)
+
+	_, err := repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+
+	// The next login must not issue a token with the revoked version
+	_, err = repo.IncrementTokenVersion(context.Background(), nil, 1)
+	require.NoError(t, err)
+	got, err := repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), got.TokenVersion)
+}
+
+func TestCachedUserRepository_InvalidatesAfterCommit(t *testing.T) {
+	testsupport.UseMemoryDatabase(t)
+	db := database.GetPostgres()
+	repo, backend, _ := newCachedUserRepository(t)
+	user := entity.User{ID: 1, Username: "admin", Password: "old-hash"}
+	changed := entity.User{ID: 1, Username: "admin", Password: "new-hash"}
+
+	backend.EXPECT().UpdatePassword(gomock.Any(), gomock.Any(), int64(1), "new-hash", nil).Return(nil).Times(2)
+
+	// A login between the write and the commit reads the old password, and caches it
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "admin").Return(user, nil).Times(1)
+	err := database.Transaction(db, func(tx *gorm.DB) error {
+		if err := repo.UpdatePassword(context.Background(), tx, 1, "new-hash", nil); err != nil {
+			return err
+		}
+
+		got, err := repo.GetUserByUsername(context.Background(), nil, "admin")
+		require.NoError(t, err)
+		assert.Equal(t, "old-hash", got.Password)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The entry is invalidated once the transaction is committed
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "admin").Return(changed, nil).Times(1)
+	got, err := repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, "new-hash", got.Password)
+
+	// A rolled back change keeps the entry
+	err = database.Transaction(db, func(tx *gorm.DB) error {
+		if err := repo.UpdatePassword(context.Background(), tx, 1, "new-hash", nil); err != nil {
+			return err
+		}
+		return errors.New("rolled back")
+	})
+	require.EqualError(t, err, "rolled back")
+	got, err = repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, "new-hash", got.Password)
+}
+
+func TestCachedUserRepository_DoesNotCacheALookupOverlappingAnInvalidation(t *testing.T) {
+	repo, backend, _ := newCachedUserRepository(t)
+	user := entity.User{ID: 1, Username: "admin", State: entity.UserStateActive}
+	suspended := entity.User{ID: 1, Username: "admin", State: entity.UserStateSuspended}
+
+	// The user is suspended while a login reads it
+	backend.EXPECT().UpdateUserState(gomock.Any(), gomock.Any(), int64(1), entity.UserStateSuspended, nil, gomock.Any()).Return(nil)
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "admin").DoAndReturn(func(context.Context, *gorm.DB, string) (entity.User, error) {
+		require.NoError(t, repo.UpdateUserState(context.Background(), nil, 1, entity.UserStateSuspended, nil, time.Now()))
+		return user, nil
+	})
+	_, err := repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+
+	// The next login does not get the user read before the change
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "admin").Return(suspended, nil)
+	got, err := repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, entity.UserStateSuspended, got.State)
+}
+
+func TestCachedUserRepository_ReturnsCopies(t *testing.T) {
+	repo, backend, _ := newCachedUserRepository(t)
+	backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "admin").Return(entity.User{ID: 1, Username: "admin", Roles: []entity.Role{{ID: 3, Name: "ROLE_ADMIN"}}}, nil)
+
+	got, err := repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+	got.Roles[0].Name = "ROLE_USER"
+
+	got, err = repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, "ROLE_ADMIN", got.Roles[0].Name)
+}
+
+func TestCachedUserRepository_DisabledWithoutTTL(t *testing.T) {
+	backend := mocks.NewMockUserRepository(gomock.NewController(t))
+
+	assert.Same(t, backend, repository.NewCachedUserRepository(backend, 0, clock.New()))
+}
@@ -0,0 +1,141 @@
+package test_repository
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+)
+
+// errConnRefused is the error of a database refusing the connections.
+var errConnRefused = fmt.Errorf("dial tcp 127.0.0.1:5432: %w", syscall.ECONNREFUSED)
+
+func TestReadConfig_CircuitBreaker(t *testing.T) {
+	t.Setenv("DB_DRIVER", database.DriverPostgres)
+	t.Setenv("DB_CIRCUIT_BREAKER_THRESHOLD", "")
+	t.Setenv("DB_CIRCUIT_BREAKER_OPEN_SECOND", "")
+	cfg := database.ReadConfig().CircuitBreaker
+	assert.Equal(t, database.DefaultCircuitBreakerThreshold, cfg.Threshold)
+	assert.Equal(t, database.DefaultCircuitBreakerOpenDuration, cfg.OpenDuration)
+	assert.True(t, cfg.Enabled())
+
+	t.Setenv("DB_CIRCUIT_BREAKER_THRESHOLD", "0")
+	t.Setenv("DB_CIRCUIT_BREAKER_OPEN_SECOND", "10")
+	cfg = database.ReadConfig().CircuitBreaker
+	assert.False(t, cfg.Enabled())
+	assert.Equal(t, 10*time.Second, cfg.OpenDuration)
+}
+
+func TestIsUnavailableError(t *testing.T) {
+	assert.True(t, database.IsUnavailableError(errConnRefused))
+	assert.True(t, database.IsUnavailableError(&pgconn.PgError{Code: "57P03"}))
+	assert.False(t, database.IsUnavailableError(&pgconn.PgError{Code: "40001"}))
+	assert.False(t, database.IsUnavailableError(&pgconn.PgError{Code: "23505"}))
+	assert.False(t, database.IsUnavailableError(gorm.ErrRecordNotFound))
+	assert.False(t, database.IsUnavailableError(database.ErrCircuitOpen))
+	assert.False(t, database.IsUnavailableError(nil))
+}
+
+func TestCircuitBreaker_OpensAfterConsecutiveFailuresAndProbesOnce(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := database.NewCircuitBreaker(database.CircuitBreakerConfig{Threshold: 3, OpenDuration: 30 * time.Second}, clk)
+
+	// The failures must be consecutive, the errors answered by the database reset them
+	b.Record(errConnRefused)
+	b.Record(errConnRefused)
+	b.Record(&pgconn.PgError{Code: "23505"})
+	b.Record(errConnRefused)
+	b.Record(errConnRefused)
+	assert.Equal(t, database.CircuitClosed, b.State())
+	require.NoError(t, b.Allow())
+
+	b.Record(errConnRefused)
+	assert.Equal(t, database.CircuitOpen, b.State())
+	assert.ErrorIs(t, b.Allow(), database.ErrCircuitOpen)
+
+	// Once open for its duration, a single statement probes the database
+	clk.Advance(30 * time.Second)
+	require.NoError(t, b.Allow())
+	assert.Equal(t, database.CircuitHalfOpen, b.State())
+	assert.ErrorIs(t, b.Allow(), database.ErrCircuitOpen)
+
+	// A failed probe opens the circuit again at once
+	b.Record(errConnRefused)
+	assert.Equal(t, database.CircuitOpen, b.State())
+	assert.ErrorIs(t, b.Allow(), database.ErrCircuitOpen)
+
+	// A probe answered by the database closes it
+	clk.Advance(30 * time.Second)
+	require.NoError(t, b.Allow())
+	b.Record(nil)
+	assert.Equal(t, database.CircuitClosed, b.State())
+	assert.NoError(t, b.Allow())
+}
+
+func TestCircuitBreaker_ReplacesALostProbe(t *testing.T) {
+	clk := clock.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := database.NewCircuitBreaker(database.CircuitBreakerConfig{Threshold: 1, OpenDuration: 10 * time.Second}, clk)
+
+	b.Record(errConnRefused)
+	clk.Advance(10 * time.Second)
+	require.NoError(t, b.Allow())
+
+	// The result of the probe is never recorded
+	clk.Advance(5 * time.Second)
+	assert.ErrorIs(t, b.Allow(), database.ErrCircuitOpen)
+	clk.Advance(5 * time.Second)
+	assert.NoError(t, b.Allow())
+	assert.ErrorIs(t, b.Allow(), database.ErrCircuitOpen)
+}
+
+func TestCircuitBreaker_FailsTheStatementsWhileTheDatabaseIsDown(t *testing.T) {
+	connector := &recordingConnector{err: errConnRefused}
+	conn, err := gorm.Open(openRecordingDB(t, connector), &gorm.Config{Logger: gormlogger.Discard})
+	require.NoError(t, err)
+
+	clk := clock.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, conn.Use(database.NewCircuitBreaker(database.CircuitBreakerConfig{Threshold: 2, OpenDuration: time.Minute}, clk)))
+
+	ctx := context.Background()
+	r := repository.NewConsumerRepository()
+
+	// The statements reach the database until the threshold is reached
+	for i := 0; i < 2; i++ {
+		_, err = r.GetConsumerByID(ctx, conn, "dummy-id")
+		assert.True(t, repository.IsTransientError(err), err)
+	}
+	assert.Len(t, connector.recorded(), 2)
+
+	// Then they fail at once, without being retried
+	delays := []time.Duration{}
+	retrying := repository.NewRetryingConsumerRepository(r, newRetryPolicy(&delays))
+	_, err = retrying.GetConsumerByID(ctx, conn, "dummy-id")
+	assert.ErrorIs(t, err, database.ErrCircuitOpen)
+	_, err = r.CreateConsumer(ctx, conn, entity.Consumer{Fullname: "John Doe", Username: "johndoe"})
+	assert.ErrorIs(t, err, database.ErrCircuitOpen)
+	assert.Len(t, connector.recorded(), 2)
+	assert.Empty(t, delays)
+
+	// The database is back: the probe closes the circuit
+	connector.mu.Lock()
+	connector.err = nil
+	connector.mu.Unlock()
+	clk.Advance(time.Minute)
+	_, err = r.GetAllConsumers(ctx, conn, 1, 10)
+	require.NoError(t, err)
+	_, err = r.GetAllConsumers(ctx, conn, 1, 10)
+	require.NoError(t, err)
+	assert.Len(t, connector.recorded(), 4)
+}
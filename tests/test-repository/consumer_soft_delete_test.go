@@ -0,0 +1,52 @@
+package test_repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+)
+
+func TestConsumerRepository_SoftDeleteScope(t *testing.T) {
+	connector := &recordingConnector{}
+	conn, err := gorm.Open(openRecordingDB(t, connector), &gorm.Config{Logger: gormlogger.Discard, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	r := repository.NewConsumerRepository()
+	last := func() string {
+		queries := connector.recorded()
+		require.NotEmpty(t, queries)
+		return queries[len(queries)-1]
+	}
+
+	// The lookups by ID and the listings leave out the deleted consumers, unless the connection is unscoped
+	_, _ = r.GetConsumerByID(ctx, conn, "dummy-id")
+	assert.Contains(t, last(), `"consumers"."deleted_at" IS NULL`)
+	_, err = r.GetAllConsumers(ctx, conn, 1, 10)
+	require.NoError(t, err)
+	assert.Contains(t, last(), `"consumers"."deleted_at" IS NULL`)
+	_, err = r.GetAllConsumers(ctx, conn.Unscoped(), 1, 10)
+	require.NoError(t, err)
+	assert.NotContains(t, last(), "deleted_at")
+
+	// The lookups of the unique values find the deleted consumers too
+	_, _ = r.GetConsumerByUsername(ctx, conn, "dummyuser")
+	assert.NotContains(t, last(), "deleted_at")
+	_, _ = r.GetConsumerByEmail(ctx, conn, "dummy-user@example.com")
+	assert.NotContains(t, last(), "deleted_at")
+
+	// The deletion only updates a consumer not deleted yet, the restore a deleted one
+	require.NoError(t, r.SoftDeleteConsumer(ctx, conn, "dummy-id", time.Now()))
+	assert.Contains(t, last(), `UPDATE "consumers" SET "deleted_at"=`)
+	assert.Contains(t, last(), `"consumers"."deleted_at" IS NULL`)
+	require.NoError(t, r.RestoreConsumer(ctx, conn, "dummy-id"))
+	assert.Contains(t, last(), `UPDATE "consumers" SET "deleted_at"=`)
+	assert.Contains(t, last(), "deleted_at IS NOT NULL")
+}
@@ -0,0 +1,68 @@
+package test_repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+func TestDataStore_ResolvesTheConnectionOnEveryCall(t *testing.T) {
+	ctx := context.Background()
+	var db *gorm.DB
+	store := database.StoreFunc(func() *gorm.DB { return db })
+	assert.Nil(t, store.DB(ctx))
+
+	// The connection is bound to the context of the call
+	testsupport.UseMemoryDatabase(t)
+	db = database.GetPostgres()
+	assert.Same(t, db.Statement.ConnPool, store.DB(ctx).Statement.ConnPool)
+	assert.Equal(t, ctx, store.DB(ctx).Statement.Context)
+
+	assert.Nil(t, database.NewStore(nil).DB(ctx))
+	assert.Same(t, db.Statement.ConnPool, database.NewStore(db).DB(ctx).Statement.ConnPool)
+}
+
+func TestDataStore_ReturnsTheTransactionOfTheRequest(t *testing.T) {
+	testsupport.UseMemoryDatabase(t)
+	db := database.GetPostgres()
+	tx := database.Begin(db)
+	t.Cleanup(func() { tx.Rollback() })
+
+	// The services of a request run in the transaction opened by the transaction middleware
+	ctx := database.WithTransaction(context.Background(), tx)
+	assert.Same(t, tx, database.NewStore(db).DB(ctx))
+	assert.Same(t, tx, database.DefaultStore().DB(ctx))
+	assert.Same(t, tx, database.GetPostgresContext(ctx))
+}
+
+func TestDataStore_ServiceUsesItsOwnStore(t *testing.T) {
+	memory := testsupport.UseMemoryDatabase(t)
+	repo := repository.NewMemoryRateLimitOverrideRepository(memory)
+	factor := 2.0
+
+	// The connection of the process is initialized, but the service only uses the store it was given
+	unavailable := service.NewRateLimitOverrideService(database.NewStore(nil), repo, 0, clock.New())
+	_, err := unavailable.GetRateLimitOverrides(context.Background())
+	assert.EqualError(t, err, "database connection is nil")
+	_, err = unavailable.SaveRateLimitOverride(context.Background(), 1, "client-a", entity.RateLimitOverrideRequest{Factor: &factor})
+	assert.EqualError(t, err, "database connection is nil")
+
+	svc := service.NewRateLimitOverrideService(database.NewStore(database.GetPostgres()), repo, 0, clock.New())
+	_, err = svc.SaveRateLimitOverride(context.Background(), 1, "client-a", entity.RateLimitOverrideRequest{Factor: &factor})
+	require.NoError(t, err)
+
+	overrides, err := svc.GetRateLimitOverrides(context.Background())
+	require.NoError(t, err)
+	require.Len(t, overrides, 1)
+	assert.Equal(t, "client-a", overrides[0].Client)
+}
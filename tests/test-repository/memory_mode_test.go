@@ -0,0 +1,86 @@
+package test_repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/routes"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+const testOrigin = "http://localhost:3000"
+
+// request performs a request against the router with the headers required by the middleware.
+func request(t *testing.T, router http.Handler, method string, path string, token string, body interface{}) map[string]interface{} {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Origin", testOrigin)
+	if token != "" {
+		req.Header.Set("Authorization", testsupport.DefaultTokenType+" "+token)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+	require.Less(t, w.Code, 300, "%s %s: %s", method, path, w.Body.String())
+
+	return resp
+}
+
+func TestMemoryMode_LoginAndConsumerFlow(t *testing.T) {
+	testsupport.UseMemoryDatabase(t)
+	testsupport.SetupJWTEnv(t)
+	t.Setenv("DB_SEED", "TRUE")
+	t.Setenv("FRONTEND_URL", testOrigin)
+	t.Setenv("JWT_ACCESS_TOKEN_TTL_MINUTES", "60")
+	t.Setenv("JWT_REFRESH_TOKEN_EXPIRATION_HOUR", "24")
+
+	router := routes.SetupRouter()
+
+	// Log in with the seeded admin user
+	login := request(t, router, "POST", "/auth/login", "", map[string]string{"username": "admin", "password": "P@ssw0rd"})
+	tokens := login["data"].(map[string]interface{})
+	accessToken := tokens["accessToken"].(string)
+
+	// Rotate the refresh token
+	refreshed := request(t, router, "POST", "/auth/refresh-token", "", map[string]string{"refreshToken": tokens["refreshToken"].(string)})
+	assert.NotEqual(t, tokens["refreshToken"], refreshed["data"].(map[string]interface{})["refreshToken"])
+
+	// The seeded consumers are listed
+	list := request(t, router, "GET", "/api/v1/consumers?page=1&limit=20", accessToken, nil)
+	assert.Len(t, list["data"], 10)
+
+	// Create a consumer and activate it
+	created := request(t, router, "POST", "/api/v1/consumers", accessToken, map[string]string{
+		"fullname":  "Memory Consumer",
+		"username":  "memoryconsumer",
+		"email":     "memory.consumer@example.com",
+		"phone":     "+6281299990000",
+		"address":   "Jl. Memori No. 1, Jakarta",
+		"birthDate": "1990-01-01",
+	})
+	id := created["data"].(map[string]interface{})["id"].(string)
+
+	updated := request(t, router, "PATCH", "/api/v1/consumers/"+id+"?status=active", accessToken, nil)
+	assert.Equal(t, "active", updated["data"].(map[string]interface{})["status"])
+
+	// The login and the refresh are counted once the background writes are flushed
+	routes.Shutdown()
+	stats := request(t, router, "GET", "/api/v1/admin/token-stats", accessToken, nil)
+	totals := stats["data"].(map[string]interface{})["totals"].(map[string]interface{})
+	assert.Equal(t, float64(1), totals["issued"])
+	assert.Equal(t, float64(1), totals["refreshed"])
+}
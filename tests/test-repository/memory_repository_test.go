@@ -0,0 +1,326 @@
+package test_repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+)
+
+// newConsumer creates a consumer with fields derived from n, so that consumers with different n are unique.
+func newConsumer(n int) entity.Consumer {
+	return entity.Consumer{
+		Fullname: fmt.Sprintf("Consumer %d", n),
+		Username: fmt.Sprintf("consumer%d", n),
+		Email:    fmt.Sprintf("consumer%d@example.com", n),
+		Phone:    fmt.Sprintf("62812000%05d", n),
+		Address:  "Jl. Merdeka No. 1, Jakarta",
+	}
+}
+
+// newSeededStore creates a store loaded with the seed data.
+func newSeededStore(t *testing.T) *repository.MemoryStore {
+	store := repository.NewMemoryStore()
+	require.NoError(t, store.Seed())
+	return store
+}
+
+func TestMemoryUserRepository_LookupsResolveRoles(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(newSeededStore(t))
+
+	user, err := repo.GetUserByUsername(context.Background(), nil, "ADMIN")
+	require.NoError(t, err)
+	assert.Equal(t, "admin", user.Username)
+	require.Len(t, user.Roles, 1)
+	assert.Equal(t, "ROLE_ADMIN", user.Roles[0].Name)
+
+	byEmail, err := repo.GetUserByEmail(context.Background(), nil, "UserOne@MyGmail.com")
+	require.NoError(t, err)
+	assert.Equal(t, "userone", byEmail.Username)
+
+	byID, err := repo.GetUserByID(context.Background(), nil, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.Username, byID.Username)
+
+	_, err = repo.GetUserByUsername(context.Background(), nil, "unknown")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestMemoryUserRepository_ReturnsCopies(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(newSeededStore(t))
+
+	// Mutating a returned user must not change the stored one until it is updated
+	user, err := repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+	lastLogin := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	*user.LastLogin = lastLogin
+
+	stored, err := repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+	assert.NotEqual(t, lastLogin, *stored.LastLogin)
+
+	_, err = repo.UpdateUser(context.Background(), nil, user)
+	require.NoError(t, err)
+
+	stored, err = repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, lastLogin, *stored.LastLogin)
+	assert.Len(t, stored.Roles, 1, "updating a user must keep its roles")
+}
+
+func TestMemoryUserRepository_UniqueConstraints(t *testing.T) {
+	store := newSeededStore(t)
+	repo := repository.NewMemoryUserRepository(store)
+
+	_, err := store.AddUser(entity.User{Username: "Admin", Email: "other@example.com"})
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+
+	user, err := repo.GetUserByUsername(context.Background(), nil, "userone")
+	require.NoError(t, err)
+	user.Email = "ADMIN@mygmail.com"
+	_, err = repo.UpdateUser(context.Background(), nil, user)
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+
+	_, err = repo.UpdateUser(context.Background(), nil, entity.User{ID: 999, Username: "ghost"})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestMemoryUserRepository_ReplaceUserRoles(t *testing.T) {
+	store := newSeededStore(t)
+	users := repository.NewMemoryUserRepository(store)
+	roles := repository.NewMemoryRoleRepository(store)
+
+	user, err := users.GetUserByUsername(context.Background(), nil, "userone")
+	require.NoError(t, err)
+	moderator, err := roles.GetRoleByName(nil, "ROLE_MODERATOR")
+	require.NoError(t, err)
+	admin, err := roles.GetRoleByName(nil, "ROLE_ADMIN")
+	require.NoError(t, err)
+
+	require.NoError(t, users.ReplaceUserRoles(context.Background(), nil, user.ID, []entity.Role{admin, moderator}))
+	user, err = users.GetUserByID(context.Background(), nil, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.Role{moderator, admin}, user.Roles)
+
+	assert.Error(t, users.ReplaceUserRoles(context.Background(), nil, user.ID, []entity.Role{{ID: 99, Name: "ROLE_UNKNOWN"}}))
+	assert.ErrorIs(t, users.ReplaceUserRoles(context.Background(), nil, 99, nil), gorm.ErrRecordNotFound)
+}
+
+func TestMemoryUserRepository_UpdateLastLogin(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(newSeededStore(t))
+	lastLogin := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	user, err := repo.GetUserByUsername(context.Background(), nil, "userone")
+	require.NoError(t, err)
+
+	require.NoError(t, repo.UpdateLastLogin(context.Background(), nil, user.ID, lastLogin))
+	updated, err := repo.GetUserByID(context.Background(), nil, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, updated.LastLogin)
+	assert.True(t, lastLogin.Equal(*updated.LastLogin))
+	assert.Equal(t, user.Roles, updated.Roles)
+
+	assert.ErrorIs(t, repo.UpdateLastLogin(context.Background(), nil, 99, lastLogin), gorm.ErrRecordNotFound)
+}
+
+func TestMemoryUserRepository_IncrementTokenVersion(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(newSeededStore(t))
+
+	user, err := repo.GetUserByUsername(context.Background(), nil, "userone")
+	require.NoError(t, err)
+
+	version, err := repo.IncrementTokenVersion(context.Background(), nil, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.TokenVersion+1, version)
+
+	versions, err := repo.GetRevokedTokenVersions(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int64{user.ID: version}, versions)
+
+	_, err = repo.IncrementTokenVersion(context.Background(), nil, 99)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestMemoryRoleRepository(t *testing.T) {
+	store := newSeededStore(t)
+	repo := repository.NewMemoryRoleRepository(store)
+
+	role, err := repo.GetRoleByName(nil, "role_admin")
+	require.NoError(t, err)
+
+	byID, err := repo.GetRoleByID(nil, role.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "ROLE_ADMIN", byID.Name)
+
+	_, err = store.AddRole(entity.Role{Name: "ROLE_ADMIN"})
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+
+	_, err = repo.GetRoleByID(nil, 999)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestMemoryRefreshTokenRepository(t *testing.T) {
+	repo := repository.NewMemoryRefreshTokenRepository(newSeededStore(t))
+	expiry := time.Now().Add(time.Hour)
+
+	_, err := repo.CreateRefreshToken(nil, entity.RefreshToken{Token: "token-1", UserID: 1, ExpiryDate: expiry})
+	require.NoError(t, err)
+
+	// The token is unique, a user may have several tokens
+	_, err = repo.CreateRefreshToken(nil, entity.RefreshToken{Token: "token-2", UserID: 1, ExpiryDate: expiry, CreatedAt: time.Now().Add(time.Second)})
+	require.NoError(t, err)
+	_, err = repo.CreateRefreshToken(nil, entity.RefreshToken{Token: "token-1", UserID: 2, ExpiryDate: expiry})
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+
+	// The user must exist
+	_, err = repo.CreateRefreshToken(nil, entity.RefreshToken{Token: "token-3", UserID: 999, ExpiryDate: expiry})
+	assert.Error(t, err)
+
+	byUser, err := repo.GetRefreshTokenByUserID(nil, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", byUser.Token)
+
+	tokens, err := repo.GetRefreshTokensByUserID(nil, 1)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, "token-1", tokens[0].Token)
+
+	removed, err := repo.RemoveRefreshToken(nil, "token-2")
+	require.NoError(t, err)
+	assert.True(t, removed)
+	removed, err = repo.RemoveRefreshToken(nil, "token-2")
+	require.NoError(t, err)
+	assert.False(t, removed)
+
+	removed, err = repo.RemoveRefreshTokenByUserID(nil, 1)
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	_, err = repo.GetRefreshTokenByToken(nil, "token-1")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestMemoryConsumerRepository_CreateAndLookup(t *testing.T) {
+	repo := repository.NewMemoryConsumerRepository(repository.NewMemoryStore())
+
+	created, err := repo.CreateConsumer(context.Background(), nil, newConsumer(1))
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, entity.ConsumerStatusInactive, created.Status)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	byUsername, err := repo.GetConsumerByUsername(context.Background(), nil, "CONSUMER1")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, byUsername.ID)
+
+	byEmail, err := repo.GetConsumerByEmail(context.Background(), nil, "Consumer1@Example.com")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, byEmail.ID)
+
+	byPhone, err := repo.GetConsumerByPhone(context.Background(), nil, created.Phone)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, byPhone.ID)
+
+	_, err = repo.GetConsumerByID(context.Background(), nil, "unknown")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestMemoryConsumerRepository_UniqueConstraints(t *testing.T) {
+	repo := repository.NewMemoryConsumerRepository(repository.NewMemoryStore())
+	_, err := repo.CreateConsumer(context.Background(), nil, newConsumer(1))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		mutate func(c *entity.Consumer)
+	}{
+		{"username", func(c *entity.Consumer) { c.Username = "Consumer1" }},
+		{"email", func(c *entity.Consumer) { c.Email = "CONSUMER1@example.com" }},
+		{"phone", func(c *entity.Consumer) { c.Phone = newConsumer(1).Phone }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConsumer(2)
+			tt.mutate(&c)
+
+			_, err := repo.CreateConsumer(context.Background(), nil, c)
+			assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+		})
+	}
+
+	// Updating a consumer to the values of another one is rejected as well
+	other, err := repo.CreateConsumer(context.Background(), nil, newConsumer(3))
+	require.NoError(t, err)
+	other.Username = "consumer1"
+	_, err = repo.UpdateConsumer(context.Background(), nil, other)
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+}
+
+func TestMemoryConsumerRepository_PaginationAndStatus(t *testing.T) {
+	repo := repository.NewMemoryConsumerRepository(repository.NewMemoryStore())
+	for i := 1; i <= 5; i++ {
+		c := newConsumer(i)
+		if i%2 == 0 {
+			c.Status = entity.ConsumerStatusActive
+		}
+		_, err := repo.CreateConsumer(context.Background(), nil, c)
+		require.NoError(t, err)
+	}
+
+	page, err := repo.GetAllConsumers(context.Background(), nil, 2, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "consumer3", page[0].Username)
+	assert.Equal(t, "consumer4", page[1].Username)
+
+	last, err := repo.GetAllConsumers(context.Background(), nil, 3, 2)
+	require.NoError(t, err)
+	assert.Len(t, last, 1)
+
+	beyond, err := repo.GetAllConsumers(context.Background(), nil, 4, 2)
+	require.NoError(t, err)
+	assert.Empty(t, beyond)
+
+	active, err := repo.GetConsumersByStatus(context.Background(), nil, entity.ConsumerStatusActive, 1, 10)
+	require.NoError(t, err)
+	assert.Len(t, active, 2)
+}
+
+func TestMemoryConsumerRepository_ConcurrentCreates(t *testing.T) {
+	repo := repository.NewMemoryConsumerRepository(repository.NewMemoryStore())
+
+	// Distinct consumers created concurrently must all be stored,
+	// and only one of the concurrent creations of the same consumer may succeed
+	var wg sync.WaitGroup
+	var duplicates atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(n int) {
+			defer wg.Done()
+			_, err := repo.CreateConsumer(context.Background(), nil, newConsumer(n))
+			assert.NoError(t, err)
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.CreateConsumer(context.Background(), nil, newConsumer(1000)); err == nil {
+				duplicates.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	all, err := repo.GetAllConsumers(context.Background(), nil, 1, 100)
+	require.NoError(t, err)
+	assert.Len(t, all, 51)
+	assert.Equal(t, int32(1), duplicates.Load())
+}
@@ -0,0 +1,147 @@
+package test_repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+)
+
+// recordingConnector is a database connector recording the queries it runs, answering them with no rows,
+// or failing them with err when it is set.
+type recordingConnector struct {
+	mu      sync.Mutex
+	queries []string
+	err     error
+}
+
+func (c *recordingConnector) record(query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, query)
+}
+
+func (c *recordingConnector) recorded() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.queries...)
+}
+
+func (c *recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return &recordingConn{connector: c}, nil
+}
+
+func (c *recordingConnector) Driver() driver.Driver {
+	return nil
+}
+
+type recordingConn struct {
+	connector *recordingConnector
+}
+
+func (c *recordingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	c.connector.record("BEGIN")
+	return c, nil
+}
+
+func (c *recordingConn) Commit() error {
+	c.connector.record("COMMIT")
+	return nil
+}
+
+func (c *recordingConn) Rollback() error {
+	c.connector.record("ROLLBACK")
+	return nil
+}
+
+func (c *recordingConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.connector.record(query)
+	if c.connector.err != nil {
+		return nil, c.connector.err
+	}
+	return emptyRows{}, nil
+}
+
+func (c *recordingConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.connector.record(query)
+	if c.connector.err != nil {
+		return nil, c.connector.err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string         { return nil }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
+
+// openRecordingDB returns a PostgreSQL connection over the given connector, issuing no query on its own.
+func openRecordingDB(t *testing.T, c *recordingConnector) gorm.Dialector {
+	sqlDB := sql.OpenDB(c)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return postgres.New(postgres.Config{Conn: sqlDB})
+}
+
+func TestReadReplicas_RouteTheListingsOnly(t *testing.T) {
+	primary, replica := &recordingConnector{}, &recordingConnector{}
+	conn, err := gorm.Open(openRecordingDB(t, primary), &gorm.Config{Logger: gormlogger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, database.UseReadReplicas(conn, openRecordingDB(t, replica)))
+
+	ctx := context.Background()
+	r := repository.NewConsumerRepository()
+
+	// The listings run on the replica
+	_, err = r.GetAllConsumers(ctx, conn, 1, 10)
+	require.NoError(t, err)
+	_, err = r.GetConsumersByStatus(ctx, conn, "active", 1, 10)
+	require.NoError(t, err)
+	assert.Len(t, replica.recorded(), 2)
+	assert.Empty(t, primary.recorded())
+
+	// The lookups stay on the primary
+	_, _ = r.GetConsumerByID(ctx, conn, "dummy-id")
+	assert.Len(t, primary.recorded(), 1)
+	assert.Len(t, replica.recorded(), 2)
+
+	// As do the listings of a transaction, which may follow its writes
+	require.NoError(t, conn.Transaction(func(tx *gorm.DB) error {
+		_, err := r.GetAllConsumers(ctx, tx, 1, 10)
+		return err
+	}))
+	queries := primary.recorded()
+	require.Len(t, queries, 4)
+	assert.Equal(t, "BEGIN", queries[1])
+	assert.Contains(t, queries[2], `FROM "consumers"`)
+	assert.Equal(t, "COMMIT", queries[3])
+	assert.Len(t, replica.recorded(), 2)
+}
+
+func TestReadConfig_ReplicaDSNs(t *testing.T) {
+	t.Setenv("DB_DRIVER", database.DriverPostgres)
+	t.Setenv("DB_REPLICA_DSN", "")
+	assert.Empty(t, database.ReadConfig().ReplicaDSNs)
+
+	t.Setenv("DB_REPLICA_DSN", " postgres://replica-1/app , ,postgres://replica-2/app")
+	assert.Equal(t, []string{"postgres://replica-1/app", "postgres://replica-2/app"}, database.ReadConfig().ReplicaDSNs)
+}
@@ -0,0 +1,192 @@
+package test_repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+)
+
+// newRetryPolicy returns a policy retrying twice, recording the delays instead of sleeping.
+func newRetryPolicy(delays *[]time.Duration) policyconfig.RetryPolicy {
+	return policyconfig.RetryPolicy{
+		Retries:  2,
+		Delay:    50 * time.Millisecond,
+		MaxDelay: time.Second,
+		Sleep:    func(d time.Duration) { *delays = append(*delays, d) },
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"deadlock", fmt.Errorf("query failed: %w", &pgconn.PgError{Code: "40P01"}), true},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, true},
+		{"admin shutdown", &pgconn.PgError{Code: "57P01"}, true},
+		{"bad connection", driver.ErrBadConn, true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"record not found", gorm.ErrRecordNotFound, false},
+		{"canceled", context.Canceled, false},
+		{"circuit open", database.ErrCircuitOpen, false},
+		{"other", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, repository.IsTransientError(tt.err))
+		})
+	}
+}
+
+func TestRetryPolicy_RetryDelayIsJitteredUpToTheDoubledDelay(t *testing.T) {
+	policy := policyconfig.RetryPolicy{Retries: 5, Delay: 50 * time.Millisecond, MaxDelay: 150 * time.Millisecond}
+
+	for i := 0; i < 100; i++ {
+		assert.LessOrEqual(t, policy.RetryDelay(1), 50*time.Millisecond)
+		assert.LessOrEqual(t, policy.RetryDelay(2), 100*time.Millisecond)
+		assert.LessOrEqual(t, policy.RetryDelay(5), 150*time.Millisecond)
+	}
+}
+
+func TestLoadRetryPolicy(t *testing.T) {
+	t.Setenv("DB_READ_RETRIES", "")
+	t.Setenv("DB_READ_RETRY_DELAY_MS", "")
+	assert.Equal(t, policyconfig.DefaultReadRetries, policyconfig.LoadRetryPolicy().Retries)
+
+	t.Setenv("DB_READ_RETRIES", "0")
+	t.Setenv("DB_READ_RETRY_DELAY_MS", "20")
+	policy := policyconfig.LoadRetryPolicy()
+	assert.False(t, policy.Enabled())
+	assert.Equal(t, 20*time.Millisecond, policy.Delay)
+}
+
+func TestRetryingUserRepository_RetriesTransientReadErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	backend := mocks.NewMockUserRepository(ctrl)
+	var delays []time.Duration
+	repo := repository.NewRetryingUserRepository(backend, newRetryPolicy(&delays))
+	user := entity.User{ID: 1, Username: "admin"}
+
+	gomock.InOrder(
+		backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "admin").Return(entity.User{}, &pgconn.PgError{Code: "40001"}),
+		backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "admin").Return(entity.User{}, driver.ErrBadConn),
+		backend.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any(), "admin").Return(user, nil),
+	)
+
+	got, err := repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, user, got)
+	require.Len(t, delays, 2)
+	assert.LessOrEqual(t, delays[0], 50*time.Millisecond)
+	assert.LessOrEqual(t, delays[1], 100*time.Millisecond)
+}
+
+func TestRetryingUserRepository_GivesUpAfterTheRetries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	backend := mocks.NewMockUserRepository(ctrl)
+	var delays []time.Duration
+	repo := repository.NewRetryingUserRepository(backend, newRetryPolicy(&delays))
+
+	deadlock := &pgconn.PgError{Code: "40P01"}
+	backend.EXPECT().GetUserByID(gomock.Any(), gomock.Any(), int64(1)).Return(entity.User{}, deadlock).Times(3)
+
+	_, err := repo.GetUserByID(context.Background(), nil, 1)
+	assert.ErrorIs(t, err, deadlock)
+	assert.Len(t, delays, 2)
+}
+
+func TestRetryingUserRepository_DoesNotRetryPermanentErrorsNorWrites(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	backend := mocks.NewMockUserRepository(ctrl)
+	var delays []time.Duration
+	repo := repository.NewRetryingUserRepository(backend, newRetryPolicy(&delays))
+
+	backend.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any(), "unknown@example.com").Return(entity.User{}, gorm.ErrRecordNotFound).Times(1)
+	backend.EXPECT().UpdateLastLogin(gomock.Any(), gomock.Any(), int64(1), gomock.Any()).Return(driver.ErrBadConn).Times(1)
+
+	_, err := repo.GetUserByEmail(context.Background(), nil, "unknown@example.com")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	err = repo.UpdateLastLogin(context.Background(), nil, 1, time.Now())
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.Empty(t, delays)
+}
+
+func TestRetryingUserRepository_StopsOnceTheContextIsDone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	backend := mocks.NewMockUserRepository(ctrl)
+	var delays []time.Duration
+	repo := repository.NewRetryingUserRepository(backend, newRetryPolicy(&delays))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The read is canceled while it fails, it is not retried for a request already gone
+	backend.EXPECT().GetUserByID(gomock.Any(), gomock.Any(), int64(1)).DoAndReturn(func(_ context.Context, _ *gorm.DB, _ int64) (entity.User, error) {
+		cancel()
+		return entity.User{}, driver.ErrBadConn
+	}).Times(1)
+
+	_, err := repo.GetUserByID(ctx, nil, 1)
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.Empty(t, delays)
+}
+
+func TestRetryingUserRepository_StopsWaitingOnceTheContextIsDone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	backend := mocks.NewMockUserRepository(ctrl)
+	repo := repository.NewRetryingUserRepository(backend, policyconfig.RetryPolicy{Retries: 2, Delay: time.Minute, MaxDelay: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// The request ends while the read waits before a retry, the wait of up to a minute (jittered) is cut short
+	backend.EXPECT().GetUserByID(gomock.Any(), gomock.Any(), int64(1)).Return(entity.User{}, driver.ErrBadConn).MinTimes(1)
+
+	start := time.Now()
+	_, err := repo.GetUserByID(ctx, nil, 1)
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestRetryingConsumerRepository_RetriesTransientReadErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	backend := mocks.NewMockConsumerRepository(ctrl)
+	var delays []time.Duration
+	repo := repository.NewRetryingConsumerRepository(backend, newRetryPolicy(&delays))
+	consumer := entity.Consumer{ID: "c-1", Username: "johndoe"}
+
+	gomock.InOrder(
+		backend.EXPECT().GetConsumerByID(gomock.Any(), gomock.Any(), "c-1").Return(entity.Consumer{}, &pgconn.PgError{Code: "40001"}),
+		backend.EXPECT().GetConsumerByID(gomock.Any(), gomock.Any(), "c-1").Return(consumer, nil),
+	)
+
+	got, err := repo.GetConsumerByID(context.Background(), nil, "c-1")
+	require.NoError(t, err)
+	assert.Equal(t, consumer, got)
+	assert.Len(t, delays, 1)
+}
+
+func TestNewRetryingRepositories_ReturnTheRepositoryWhenDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserRepository(ctrl)
+	consumers := mocks.NewMockConsumerRepository(ctrl)
+
+	assert.Same(t, users, repository.NewRetryingUserRepository(users, policyconfig.RetryPolicy{}))
+	assert.Same(t, consumers, repository.NewRetryingConsumerRepository(consumers, policyconfig.RetryPolicy{}))
+}
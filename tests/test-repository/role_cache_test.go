@@ -0,0 +1,102 @@
+package test_repository
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+)
+
+var testRoles = []entity.Role{{ID: 1, Name: "ROLE_USER"}, {ID: 3, Name: "ROLE_ADMIN"}}
+
+// newRoleCache creates a role cache with a one-minute TTL backed by a fake clock.
+func newRoleCache(t *testing.T) (*repository.RoleCache, *clock.FakeClock) {
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	cache := repository.NewRoleCache(time.Minute, clk)
+	require.NotNil(t, cache)
+
+	return cache, clk
+}
+
+func TestRoleCache_ExpiresAfterTTL(t *testing.T) {
+	cache, clk := newRoleCache(t)
+	cache.Set(1, testRoles)
+
+	clk.Advance(time.Minute - time.Second)
+	roles, ok := cache.Get(1)
+	require.True(t, ok)
+	assert.Equal(t, testRoles, roles)
+
+	clk.Advance(time.Second)
+	_, ok = cache.Get(1)
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestRoleCache_Invalidate(t *testing.T) {
+	cache, _ := newRoleCache(t)
+	cache.Set(1, testRoles)
+	cache.Set(2, testRoles[:1])
+
+	cache.Invalidate(1)
+	_, ok := cache.Get(1)
+	assert.False(t, ok)
+	_, ok = cache.Get(2)
+	assert.True(t, ok)
+
+	cache.InvalidateAll()
+	_, ok = cache.Get(2)
+	assert.False(t, ok)
+}
+
+func TestRoleCache_ReturnsCopies(t *testing.T) {
+	cache, _ := newRoleCache(t)
+	roles := append([]entity.Role{}, testRoles...)
+	cache.Set(1, roles)
+
+	// Mutating the cached or the returned roles must not change the cache
+	roles[0].Name = "ROLE_MODERATOR"
+	got, _ := cache.Get(1)
+	got[1].Name = "ROLE_MODERATOR"
+
+	got, _ = cache.Get(1)
+	assert.Equal(t, testRoles, got)
+}
+
+func TestRoleCache_DisabledWithoutTTL(t *testing.T) {
+	cache := repository.NewRoleCache(0, clock.New())
+	assert.Nil(t, cache)
+
+	// A nil cache is valid and caches nothing
+	cache.Set(1, testRoles)
+	_, ok := cache.Get(1)
+	assert.False(t, ok)
+	cache.Invalidate(1)
+	cache.InvalidateAll()
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestRoleCache_Concurrent(t *testing.T) {
+	cache, clk := newRoleCache(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			cache.Set(id, testRoles)
+			cache.Get(id)
+			clk.Advance(time.Millisecond)
+			cache.Invalidate(id)
+		}(int64(i))
+	}
+	wg.Wait()
+
+	assert.Equal(t, 0, cache.Len())
+}
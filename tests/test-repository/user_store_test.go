@@ -0,0 +1,198 @@
+package test_repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/secrets"
+)
+
+// newUserStore creates the external user store of the given kind backed by the handler, authenticated with the token.
+func newUserStore(t *testing.T, kind string, token string, handler http.HandlerFunc) repository.UserStore {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	t.Setenv("USER_STORE_TOKEN", token)
+
+	store, err := repository.NewUserStore(policyconfig.UserStoreConfig{Kind: kind, URL: server.URL, Timeout: time.Second}, secrets.NewEnvProvider(), nil)
+	require.NoError(t, err)
+
+	return store
+}
+
+// writeJSON responds with the status and the JSON of the value.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestUserStoreConfig(t *testing.T) {
+	t.Setenv("USER_STORE", "")
+	t.Setenv("USER_STORE_URL", "")
+	t.Setenv("USER_STORE_TIMEOUT_SECOND", "")
+
+	cfg := policyconfig.LoadUserStoreConfig()
+	assert.Equal(t, policyconfig.UserStoreDatabase, cfg.Kind)
+	assert.False(t, cfg.IsExternal())
+	assert.Equal(t, policyconfig.DefaultUserStoreTimeout, cfg.Timeout)
+	assert.NoError(t, cfg.Check())
+
+	t.Setenv("USER_STORE", " SCIM ")
+	t.Setenv("USER_STORE_URL", "https://idp.example.com/scim/v2/")
+	t.Setenv("USER_STORE_TIMEOUT_SECOND", "2")
+
+	cfg = policyconfig.LoadUserStoreConfig()
+	assert.Equal(t, policyconfig.UserStoreConfig{Kind: policyconfig.UserStoreSCIM, URL: "https://idp.example.com/scim/v2", Timeout: 2 * time.Second}, cfg)
+	assert.True(t, cfg.IsExternal())
+	assert.NoError(t, cfg.Check())
+
+	assert.Error(t, policyconfig.UserStoreConfig{Kind: "ldap", URL: "https://idp.example.com"}.Check())
+	assert.Error(t, policyconfig.UserStoreConfig{Kind: policyconfig.UserStoreREST}.Check())
+	assert.Error(t, policyconfig.UserStoreConfig{Kind: policyconfig.UserStoreREST, URL: "idp.example.com/users"}.Check())
+}
+
+func TestRESTUserStore_ReadsTheUsersOfTheIdentityService(t *testing.T) {
+	store := newUserStore(t, policyconfig.UserStoreREST, "store-token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer store-token", r.Header.Get("Authorization"))
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /users/by-username/admin":
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"id": 7, "username": "admin", "password": "$2a$10$hash", "email": "admin@example.com", "firstName": "Admin",
+				"state": "ACTIVE", "userType": "USER_ACCOUNT", "tokenVersion": 3, "roles": []map[string]interface{}{{"roleId": 1, "roleName": "ROLE_ADMIN"}},
+			})
+		case "GET /users/by-username/unknown":
+			w.WriteHeader(http.StatusNotFound)
+		case "POST /users":
+			w.WriteHeader(http.StatusConflict)
+		case "POST /users/7/token-version":
+			writeJSON(w, http.StatusOK, map[string]int64{"tokenVersion": 4})
+		case "GET /users/token-versions":
+			writeJSON(w, http.StatusOK, map[string]int64{"7": 4})
+		case "PATCH /users/7":
+			var fields map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&fields))
+			assert.Equal(t, "SUSPENDED", fields["state"])
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	repo := repository.NewUserStoreRepository(store)
+
+	user, err := repo.GetUserByUsername(context.Background(), nil, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), user.ID)
+	assert.Equal(t, "$2a$10$hash", user.Password)
+	assert.Equal(t, int64(3), user.TokenVersion)
+	assert.Equal(t, []entity.Role{{ID: 1, Name: "ROLE_ADMIN"}}, user.Roles)
+
+	// The errors of the store are the ones of the database, so the services handle them the same way
+	_, err = repo.GetUserByUsername(context.Background(), nil, "unknown")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = repo.CreateUser(context.Background(), nil, entity.User{Username: "admin"})
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+	_, err = repo.GetUserByID(context.Background(), nil, 8)
+	assert.ErrorIs(t, err, repository.ErrUserStoreUnavailable)
+
+	version, err := repo.IncrementTokenVersion(context.Background(), nil, 7)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), version)
+
+	versions, err := repo.GetRevokedTokenVersions(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int64{7: 4}, versions)
+
+	assert.NoError(t, repo.UpdateUserState(context.Background(), nil, 7, entity.UserStateSuspended, nil, time.Now()))
+}
+
+func TestSCIMUserStore_ReadsTheUsersOfTheSCIMServer(t *testing.T) {
+	const extension = "urn:yoanesber:params:scim:schemas:extension:jwt-auth:2.0:User"
+	suspended := map[string]interface{}{
+		"schemas":  []string{"urn:ietf:params:scim:schemas:core:2.0:User", extension},
+		"id":       "42",
+		"userName": "jdoe",
+		"name":     map[string]string{"givenName": "John", "familyName": "Doe"},
+		"emails":   []map[string]interface{}{{"value": "old@example.com"}, {"value": "jdoe@example.com", "primary": true}},
+		"active":   false,
+		"roles":    []map[string]string{{"value": "ROLE_USER"}},
+		extension:  map[string]interface{}{"state": "SUSPENDED", "tokenVersion": 2},
+	}
+
+	var patches []map[string]interface{}
+	store := newUserStore(t, policyconfig.UserStoreSCIM, "", func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /Users":
+			switch r.URL.Query().Get("filter") {
+			case `userName eq "jdoe"`:
+				writeJSON(w, http.StatusOK, map[string]interface{}{"totalResults": 1, "Resources": []interface{}{suspended}})
+			case `userName eq "uuid"`:
+				writeJSON(w, http.StatusOK, map[string]interface{}{"totalResults": 1, "Resources": []interface{}{map[string]interface{}{"id": "2819c223-7f76", "userName": "uuid"}}})
+			default:
+				writeJSON(w, http.StatusOK, map[string]interface{}{"totalResults": 0, "Resources": []interface{}{}})
+			}
+		case "GET /Users/42":
+			writeJSON(w, http.StatusOK, suspended)
+		case "PATCH /Users/42":
+			var patch map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&patch))
+			patches = append(patches, patch)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	user, err := store.GetUserByUsername(context.Background(), "jdoe")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), user.ID)
+	assert.Equal(t, "jdoe@example.com", user.Email)
+	assert.Equal(t, "Doe", *user.Lastname)
+	assert.Equal(t, entity.UserStateSuspended, user.State)
+	assert.Equal(t, entity.UserTypeUserAccount, user.UserType)
+	assert.Equal(t, []entity.Role{{Name: "ROLE_USER"}}, user.Roles)
+	assert.Empty(t, user.Password)
+
+	_, err = store.GetUserByUsername(context.Background(), "unknown")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// The IDs of the users must be numeric
+	_, err = store.GetUserByUsername(context.Background(), "uuid")
+	assert.ErrorIs(t, err, repository.ErrUserStoreUnavailable)
+
+	// The token version is kept in the extension of the user
+	version, err := store.IncrementTokenVersion(context.Background(), 42)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), version)
+	require.Len(t, patches, 1)
+	assert.Equal(t, []interface{}{"urn:ietf:params:scim:api:messages:2.0:PatchOp"}, patches[0]["schemas"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"op": "replace", "path": extension + ":tokenVersion", "value": float64(3)}}, patches[0]["Operations"])
+
+	// SCIM never returns the passwords, they cannot be changed through the store
+	assert.ErrorIs(t, store.UpdatePassword(context.Background(), 42, "$2a$10$hash", nil), repository.ErrUserStoreUnsupported)
+}
+
+func TestUserStore_CanceledWithTheContext(t *testing.T) {
+	store := newUserStore(t, policyconfig.UserStoreREST, "", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": 7, "username": "admin"})
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repository.NewUserStoreRepository(store).GetUserByID(ctx, nil, 7)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, repository.ErrUserStoreUnavailable)
+}
@@ -0,0 +1,110 @@
+package test_request_id
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/logger"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/headers"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+)
+
+// newRouter creates a router with the RequestID middleware, a route returning the request ID of the context,
+// and a route failing with a bad request.
+func newRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(headers.RequestID())
+	r.GET("/id", func(c *gin.Context) {
+		c.String(http.StatusOK, metacontext.ExtractRequestID(c.Request.Context()))
+	})
+	r.GET("/fail", func(c *gin.Context) {
+		httputil.BadRequest(c, "Invalid request", "Something is wrong")
+	})
+
+	return r
+}
+
+// send sends a GET request to the path with the given X-Request-Id header, if any.
+func send(r *gin.Engine, path string, requestID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	if requestID != "" {
+		req.Header.Set(headers.RequestIDHeader, requestID)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestRequestID_GeneratesAnID(t *testing.T) {
+	w := send(newRouter(), "/id", "")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	id := w.Header().Get(headers.RequestIDHeader)
+	_, err := uuid.Parse(id)
+	assert.NoError(t, err, "the generated ID should be a UUID")
+	assert.Equal(t, id, w.Body.String(), "the ID of the context should be the one returned")
+}
+
+func TestRequestID_KeepsTheIDOfTheCaller(t *testing.T) {
+	w := send(newRouter(), "/id", "gateway-42.abc_DEF:1")
+
+	assert.Equal(t, "gateway-42.abc_DEF:1", w.Header().Get(headers.RequestIDHeader))
+	assert.Equal(t, "gateway-42.abc_DEF:1", w.Body.String())
+}
+
+func TestRequestID_ReplacesAnInvalidID(t *testing.T) {
+	r := newRouter()
+
+	for name, id := range map[string]string{
+		"forged log line": "abc\" status=200",
+		"too long":        strings.Repeat("a", headers.MaxRequestIDLength+1),
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := send(r, "/id", id)
+
+			got := w.Header().Get(headers.RequestIDHeader)
+			assert.NotEqual(t, id, got)
+			_, err := uuid.Parse(got)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestRequestID_IsSetOnTheErrorResponses(t *testing.T) {
+	w := send(newRouter(), "/fail", "req-123")
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var resp httputil.HttpResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "req-123", resp.RequestID)
+}
+
+func TestLogger_AddsTheRequestIDOfTheContext(t *testing.T) {
+	logger.Init()
+	hook := logtest.NewLocal(logger.InfoLogger)
+	defer hook.Reset()
+
+	ctx := metacontext.InjectRequestID(t.Context(), "req-123")
+	logger.InfoContext(ctx, "Created user account", map[string]interface{}{"user_id": 1})
+	logger.InfoContext(t.Context(), "Created user account", nil)
+
+	entries := hook.AllEntries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "req-123", entries[0].Data["request_id"])
+	assert.Equal(t, 1, entries[0].Data["user_id"])
+	assert.NotContains(t, entries[1].Data, "request_id", "no request ID outside of a request")
+}
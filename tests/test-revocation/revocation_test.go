@@ -0,0 +1,193 @@
+package test_revocation
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/revocation"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+func TestDenylist(t *testing.T) {
+	d := revocation.NewDenylist()
+	assert.False(t, d.IsRevoked(1, 0))
+
+	d.Revoke(1, 2)
+	assert.True(t, d.IsRevoked(1, 0))
+	assert.True(t, d.IsRevoked(1, 1))
+	assert.False(t, d.IsRevoked(1, 2))
+	assert.False(t, d.IsRevoked(2, 0))
+
+	// An older version never lowers the recorded one
+	d.Load(map[int64]int64{1: 1, 2: 1})
+	assert.True(t, d.IsRevoked(1, 1))
+	assert.True(t, d.IsRevoked(2, 0))
+}
+
+func TestDenylist_Tokens(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	d := revocation.NewDenylist()
+	assert.False(t, d.IsTokenRevoked("a"))
+
+	d.RevokeToken("a", now.Add(time.Minute))
+	d.LoadTokens(map[string]time.Time{"b": now.Add(-time.Minute)})
+	assert.True(t, d.IsTokenRevoked("a"))
+	assert.True(t, d.IsTokenRevoked("b"))
+
+	// The expired tokens are forgotten
+	d.PruneTokens(now)
+	assert.True(t, d.IsTokenRevoked("a"))
+	assert.False(t, d.IsTokenRevoked("b"))
+}
+
+func TestJwtValidation_RejectsRevokedTokens(t *testing.T) {
+	// The middleware checks the denylist of the process, so the test uses a user of its own
+	const userID = 4090
+	router := testsupport.NewRouter(t)
+	router.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	old := testsupport.NewTokenBuilder().WithUser(userID, "revoked", "revoked@example.com").Build(t)
+	current := testsupport.NewTokenBuilder().WithUser(userID, "revoked", "revoked@example.com").WithClaim("tokenversion", 1).Build(t)
+
+	assert.Equal(t, http.StatusOK, testsupport.Do(router, "GET", "/protected", old).Code)
+
+	revocation.GetDenylist().Revoke(userID, 1)
+
+	w := testsupport.Do(router, "GET", "/protected", old)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Token has been revoked")
+	assert.Equal(t, http.StatusOK, testsupport.Do(router, "GET", "/protected", current).Code)
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	store := testsupport.UseMemoryDatabase(t)
+	user, err := store.AddUser(entity.User{Username: "alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+
+	userRepo := repository.NewMemoryUserRepository(store)
+	refreshTokenRepo := repository.NewMemoryRefreshTokenRepository(store)
+	revokedTokenRepo := repository.NewMemoryRevokedTokenRepository(store)
+	_, err = refreshTokenRepo.CreateRefreshToken(nil, entity.RefreshToken{Token: "refresh-token", UserID: user.ID, ExpiryDate: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	tokenUsage := mocks.NewMockTokenUsageRecorder(ctrl)
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	denylist := revocation.NewDenylist()
+	s := service.NewTokenRevocationService(database.DefaultStore(), userRepo, refreshTokenRepo, revokedTokenRepo, denylist, tokenUsage, clk)
+
+	tokenUsage.EXPECT().Record(service.TokenEventRevoked, user.ID, "", clk.Now()).Times(2)
+
+	revoked, err := s.RevokeUserTokens(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.TokenRevocation{UserID: user.ID, TokenVersion: 1, RevokedAt: clk.Now()}, revoked)
+	assert.True(t, denylist.IsRevoked(user.ID, 0))
+
+	// The refresh token is deleted, and the next logins issue tokens with the new version
+	_, err = refreshTokenRepo.GetRefreshTokenByToken(nil, "refresh-token")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	stored, err := userRepo.GetUserByID(context.Background(), nil, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stored.TokenVersion)
+
+	// Revoking again bumps the version again
+	revoked, err = s.RevokeUserTokens(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), revoked.TokenVersion)
+
+	_, err = s.RevokeUserTokens(context.Background(), 999)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// The revocations are loaded from the database at startup
+	restarted := revocation.NewDenylist()
+	require.NoError(t, service.NewTokenRevocationService(database.DefaultStore(), userRepo, refreshTokenRepo, revokedTokenRepo, restarted, tokenUsage, clk).LoadRevocations())
+	assert.True(t, restarted.IsRevoked(user.ID, 1))
+	assert.False(t, restarted.IsRevoked(user.ID, 2))
+}
+
+func TestJwtValidation_RejectsRevokedTokenIDs(t *testing.T) {
+	router := testsupport.NewRouter(t)
+	router.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	revoked := testsupport.NewTokenBuilder().WithUser(4091, "loggedout", "loggedout@example.com").WithClaim("jti", "revoked-token-id").Build(t)
+	other := testsupport.NewTokenBuilder().WithUser(4091, "loggedout", "loggedout@example.com").WithClaim("jti", "other-token-id").Build(t)
+
+	revocation.GetDenylist().RevokeToken("revoked-token-id", time.Now().Add(time.Hour))
+
+	// Only the revoked token is rejected, the other tokens of the user are still valid
+	w := testsupport.Do(router, "GET", "/protected", revoked)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Token has been revoked")
+	assert.Equal(t, http.StatusOK, testsupport.Do(router, "GET", "/protected", other).Code)
+}
+
+func TestRevokeSession(t *testing.T) {
+	store := testsupport.UseMemoryDatabase(t)
+	alice, err := store.AddUser(entity.User{Username: "alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+	bob, err := store.AddUser(entity.User{Username: "bob", Email: "bob@example.com"})
+	require.NoError(t, err)
+
+	userRepo := repository.NewMemoryUserRepository(store)
+	refreshTokenRepo := repository.NewMemoryRefreshTokenRepository(store)
+	revokedTokenRepo := repository.NewMemoryRevokedTokenRepository(store)
+	for _, token := range []entity.RefreshToken{
+		{Token: "alice-refresh-token", UserID: alice.ID, ExpiryDate: time.Now().Add(time.Hour)},
+		{Token: "bob-refresh-token", UserID: bob.ID, ExpiryDate: time.Now().Add(time.Hour)},
+	} {
+		_, err = refreshTokenRepo.CreateRefreshToken(nil, token)
+		require.NoError(t, err)
+	}
+
+	ctrl := gomock.NewController(t)
+	tokenUsage := mocks.NewMockTokenUsageRecorder(ctrl)
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	denylist := revocation.NewDenylist()
+	s := service.NewTokenRevocationService(database.DefaultStore(), userRepo, refreshTokenRepo, revokedTokenRepo, denylist, tokenUsage, clk)
+
+	tokenUsage.EXPECT().Record(service.TokenEventRevoked, alice.ID, "", clk.Now()).Times(2)
+
+	// The refresh token of another user is rejected and kept
+	req := entity.LogoutRequest{RefreshToken: "bob-refresh-token", UserID: alice.ID, TokenID: "alice-token-id", TokenExpiresAt: clk.Now().Add(time.Hour)}
+	assert.ErrorIs(t, s.RevokeSession(context.Background(), req), service.ErrTokenSubjectMismatch)
+	assert.False(t, denylist.IsTokenRevoked("alice-token-id"))
+	_, err = refreshTokenRepo.GetRefreshTokenByToken(nil, "bob-refresh-token")
+	assert.NoError(t, err)
+
+	// The refresh token is deleted and the access token is revoked
+	req.RefreshToken = "alice-refresh-token"
+	require.NoError(t, s.RevokeSession(context.Background(), req))
+	assert.True(t, denylist.IsTokenRevoked("alice-token-id"))
+	_, err = refreshTokenRepo.GetRefreshTokenByToken(nil, "alice-refresh-token")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// Logging out twice succeeds
+	require.NoError(t, s.RevokeSession(context.Background(), req))
+
+	// The revoked tokens are loaded from the database at startup until they expire
+	restarted := revocation.NewDenylist()
+	require.NoError(t, service.NewTokenRevocationService(database.DefaultStore(), userRepo, refreshTokenRepo, revokedTokenRepo, restarted, tokenUsage, clk).LoadRevocations())
+	assert.True(t, restarted.IsTokenRevoked("alice-token-id"))
+
+	clk.Advance(2 * time.Hour)
+	expired := revocation.NewDenylist()
+	require.NoError(t, service.NewTokenRevocationService(database.DefaultStore(), userRepo, refreshTokenRepo, revokedTokenRepo, expired, tokenUsage, clk).LoadRevocations())
+	assert.False(t, expired.IsTokenRevoked("alice-token-id"))
+	tokens, err := revokedTokenRepo.GetRevokedTokens(nil, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+}
@@ -0,0 +1,144 @@
+package test_revocation
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/cache"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/revocation"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// useRedis starts an in-memory Redis server used as the shared Redis client until the end of the test.
+func useRedis(t *testing.T) *miniredis.Miniredis {
+	srv := miniredis.RunT(t)
+	cache.SetRedis(redis.NewClient(&redis.Options{Addr: srv.Addr()}))
+	t.Cleanup(cache.CloseRedis)
+
+	return srv
+}
+
+func TestTokenBlacklistService(t *testing.T) {
+	srv := useRedis(t)
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	s := service.NewTokenBlacklistService("test:", time.Hour, clk)
+	ctx := context.Background()
+
+	revoked, err := s.IsRevoked(ctx, 1, 0, "token-id")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// The token is stored for its remaining lifetime
+	require.NoError(t, s.BlacklistToken("token-id", clk.Now().Add(10*time.Minute)))
+	assert.Equal(t, 10*time.Minute, srv.TTL("test:revoked:token:token-id"))
+	revoked, err = s.IsRevoked(ctx, 1, 0, "token-id")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = s.IsRevoked(ctx, 1, 0, "other-token-id")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// An expired token is not stored
+	require.NoError(t, s.BlacklistToken("expired-token-id", clk.Now().Add(-time.Minute)))
+	assert.False(t, srv.Exists("test:revoked:token:expired-token-id"))
+
+	// The version of the user is stored for the longest lifetime of the access tokens, an older one never lowers it
+	require.NoError(t, s.BlacklistUserTokens(1, 2))
+	require.NoError(t, s.BlacklistUserTokens(1, 1))
+	assert.Equal(t, time.Hour, srv.TTL("test:revoked:user:1"))
+	for version, want := range map[int64]bool{0: true, 1: true, 2: false} {
+		revoked, err = s.IsRevoked(ctx, 1, version, "")
+		require.NoError(t, err)
+		assert.Equal(t, want, revoked, version)
+	}
+	revoked, err = s.IsRevoked(ctx, 2, 0, "")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	srv.FastForward(time.Hour)
+	revoked, err = s.IsRevoked(ctx, 1, 0, "token-id")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestTokenBlacklistService_Unavailable(t *testing.T) {
+	srv := useRedis(t)
+	s := service.NewTokenBlacklistService("test:", time.Hour, clock.New())
+	srv.Close()
+
+	_, err := s.IsRevoked(context.Background(), 1, 0, "token-id")
+	assert.Error(t, err)
+
+	cache.CloseRedis()
+	assert.Error(t, s.BlacklistToken("token-id", time.Now().Add(time.Hour)))
+}
+
+func TestRevokeSession_SharesTheRevocation(t *testing.T) {
+	useRedis(t)
+	store := testsupport.UseMemoryDatabase(t)
+	alice, err := store.AddUser(entity.User{Username: "alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	tokenUsage := mocks.NewMockTokenUsageRecorder(ctrl)
+	tokenUsage.EXPECT().Record(service.TokenEventRevoked, alice.ID, "", gomock.Any()).Times(2)
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	blacklist := service.NewTokenBlacklistService("test:", time.Hour, clk)
+	s := service.NewTokenRevocationService(database.DefaultStore(), repository.NewMemoryUserRepository(store), repository.NewMemoryRefreshTokenRepository(store),
+		repository.NewMemoryRevokedTokenRepository(store), revocation.NewDenylist(), tokenUsage, clk, service.WithTokenBlacklist(blacklist))
+
+	require.NoError(t, s.RevokeSession(context.Background(), entity.LogoutRequest{RefreshToken: "unknown", UserID: alice.ID, TokenID: "alice-token-id", TokenExpiresAt: clk.Now().Add(time.Hour)}))
+	_, err = s.RevokeUserTokens(context.Background(), alice.ID)
+	require.NoError(t, err)
+
+	// Another instance, with a denylist of its own, learns of both revocations
+	revoked, err := blacklist.IsRevoked(context.Background(), alice.ID, 1, "alice-token-id")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = blacklist.IsRevoked(context.Background(), alice.ID, 0, "")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = blacklist.IsRevoked(context.Background(), alice.ID, 1, "")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestJwtValidation_RejectsBlacklistedTokens(t *testing.T) {
+	srv := useRedis(t)
+	blacklist := service.NewTokenBlacklistService("test:", time.Hour, clock.New())
+	revocation.SetBlacklist(blacklist)
+	t.Cleanup(func() { revocation.SetBlacklist(nil) })
+
+	router := testsupport.NewRouter(t)
+	router.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	revoked := testsupport.NewTokenBuilder().WithUser(4092, "elsewhere", "elsewhere@example.com").WithClaim("jti", "blacklisted-token-id").Build(t)
+	other := testsupport.NewTokenBuilder().WithUser(4092, "elsewhere", "elsewhere@example.com").WithClaim("jti", "valid-token-id").Build(t)
+
+	// The token was revoked by another instance, the denylist of the process does not know it
+	require.NoError(t, blacklist.BlacklistToken("blacklisted-token-id", time.Now().Add(time.Hour)))
+
+	w := testsupport.Do(router, "GET", "/protected", revoked)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Token has been revoked")
+	assert.Equal(t, http.StatusOK, testsupport.Do(router, "GET", "/protected", other).Code)
+
+	// An unreachable blacklist falls back to the denylist of the process
+	srv.Close()
+	assert.Equal(t, http.StatusOK, testsupport.Do(router, "GET", "/protected", revoked).Code)
+}
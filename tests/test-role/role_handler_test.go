@@ -0,0 +1,59 @@
+package test_role
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+func TestDeleteRole_RespondsWithTheErrorOfTheService(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantMessage string
+		wantError   string
+	}{
+		{"built-in role", fmt.Errorf("%w: ROLE_ADMIN", service.ErrBuiltInRole), http.StatusBadRequest, "Failed to delete role", "built-in roles cannot be renamed or deleted"},
+		{"role in use", service.ErrRoleInUse, http.StatusConflict, "Failed to delete role", service.ErrRoleInUse.Error()},
+		{"role not found", gorm.ErrRecordNotFound, http.StatusNotFound, "Role not found", "No role found with the given ID"},
+		{"unexpected error", errors.New("pq: connection refused to 10.0.0.5:5432"), http.StatusInternalServerError, "Failed to delete role", "An unexpected error occurred, please try again later"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			s := mocks.NewMockRoleService(ctrl)
+			h := handler.NewRoleHandler(s)
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.DELETE("/roles/:id", h.DeleteRole)
+
+			s.EXPECT().DeleteRole(gomock.Any(), uint(4)).Return(tt.err)
+
+			// The errors of the services are mapped in a single place, and the unknown ones are not returned
+			w := testsupport.Do(router, http.MethodDelete, "/roles/4", "")
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			var httpResponse httputil.HttpResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &httpResponse))
+			assert.Equal(t, tt.wantMessage, httpResponse.Message)
+			assert.Contains(t, httpResponse.Error, tt.wantError)
+			assert.NotContains(t, w.Body.String(), "10.0.0.5")
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package test_role
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/go-playground/validator.v9"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// invalidator counts the invalidations of the cached roles of the users.
+type invalidator struct {
+	calls int
+}
+
+// InvalidateRoleCache counts the invalidation.
+func (i *invalidator) InvalidateRoleCache() {
+	i.calls++
+}
+
+// newRoleService creates a role service backed by an in-memory store with the seeded roles and users.
+func newRoleService(t *testing.T) (service.RoleService, *repository.MemoryStore, *invalidator) {
+	store := testsupport.UseMemoryDatabase(t)
+	require.NoError(t, store.Seed())
+
+	inv := &invalidator{}
+	return service.NewRoleService(database.DefaultStore(), repository.NewMemoryRoleRepository(store), repository.NewMemoryPermissionRepository(store), service.WithRoleCacheInvalidator(inv)), store, inv
+}
+
+// strPtr returns a pointer to the string.
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestRoleService_CreateRole(t *testing.T) {
+	s, _, _ := newRoleService(t)
+
+	role, err := s.CreateRole(context.Background(), entity.RoleRequest{Name: "ROLE_AUDITOR", Description: strPtr("Read-only access to the audit reports")})
+	require.NoError(t, err)
+	assert.NotZero(t, role.ID)
+
+	roles, err := s.GetRoles(context.Background())
+	require.NoError(t, err)
+	names := make([]string, 0, len(roles))
+	for _, r := range roles {
+		names = append(names, r.Name)
+	}
+	assert.Equal(t, []string{"ROLE_USER", "ROLE_MODERATOR", "ROLE_ADMIN", "ROLE_AUDITOR"}, names)
+	assert.Equal(t, "Read-only access to the audit reports", *roles[3].Description)
+}
+
+func TestRoleService_CreateRole_Rejected(t *testing.T) {
+	s, _, _ := newRoleService(t)
+
+	_, err := s.CreateRole(context.Background(), entity.RoleRequest{Name: "ROLE_ADMIN"})
+	assert.ErrorIs(t, err, service.ErrRoleAlreadyExists)
+
+	for _, name := range []string{"auditor", "ROLE_", "ROLE_auditor", "ROLE__AUDITOR", "ROLE_AUDITOR_"} {
+		var ve validator.ValidationErrors
+		_, err = s.CreateRole(context.Background(), entity.RoleRequest{Name: name})
+		assert.True(t, errors.As(err, &ve), name)
+	}
+}
+
+func TestRoleService_UpdateRole(t *testing.T) {
+	s, _, inv := newRoleService(t)
+	created, err := s.CreateRole(context.Background(), entity.RoleRequest{Name: "ROLE_AUDITOR"})
+	require.NoError(t, err)
+
+	// A new description only keeps the cached roles
+	updated, err := s.UpdateRole(context.Background(), created.ID, entity.RoleRequest{Name: "ROLE_AUDITOR", Description: strPtr("Audit reports")})
+	require.NoError(t, err)
+	assert.Equal(t, "Audit reports", *updated.Description)
+	assert.Zero(t, inv.calls)
+
+	updated, err = s.UpdateRole(context.Background(), created.ID, entity.RoleRequest{Name: "ROLE_COMPLIANCE"})
+	require.NoError(t, err)
+	assert.Equal(t, "ROLE_COMPLIANCE", updated.Name)
+	assert.Nil(t, updated.Description)
+	assert.Equal(t, 1, inv.calls)
+
+	stored, err := s.GetRoleByName(context.Background(), "role_compliance")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, stored.ID)
+}
+
+func TestRoleService_UpdateRole_Rejected(t *testing.T) {
+	s, _, inv := newRoleService(t)
+	admin, err := s.GetRoleByName(context.Background(), "ROLE_ADMIN")
+	require.NoError(t, err)
+	created, err := s.CreateRole(context.Background(), entity.RoleRequest{Name: "ROLE_AUDITOR"})
+	require.NoError(t, err)
+
+	_, err = s.UpdateRole(context.Background(), admin.ID, entity.RoleRequest{Name: "ROLE_ROOT"})
+	assert.ErrorIs(t, err, service.ErrBuiltInRole)
+
+	_, err = s.UpdateRole(context.Background(), created.ID, entity.RoleRequest{Name: "ROLE_USER"})
+	assert.ErrorIs(t, err, service.ErrRoleAlreadyExists)
+
+	_, err = s.UpdateRole(context.Background(), 99, entity.RoleRequest{Name: "ROLE_ROOT"})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.Zero(t, inv.calls)
+
+	// The built-in roles can get a new description
+	updated, err := s.UpdateRole(context.Background(), admin.ID, entity.RoleRequest{Name: "ROLE_ADMIN", Description: strPtr("Full access")})
+	require.NoError(t, err)
+	assert.Equal(t, "Full access", *updated.Description)
+}
+
+func TestRoleService_DeleteRole(t *testing.T) {
+	s, store, _ := newRoleService(t)
+	created, err := s.CreateRole(context.Background(), entity.RoleRequest{Name: "ROLE_AUDITOR"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteRole(context.Background(), created.ID))
+
+	_, err = s.GetRoleByID(context.Background(), created.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// A role assigned to a user is kept
+	assigned, err := s.CreateRole(context.Background(), entity.RoleRequest{Name: "ROLE_SUPPORT"})
+	require.NoError(t, err)
+	_, err = store.AddUser(entity.User{Username: "bob", Email: "bob@example.com", State: entity.UserStateActive, Roles: []entity.Role{assigned}})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, s.DeleteRole(context.Background(), assigned.ID), service.ErrRoleInUse)
+
+	user, err := s.GetRoleByName(context.Background(), "ROLE_USER")
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.DeleteRole(context.Background(), user.ID), service.ErrBuiltInRole)
+	assert.ErrorIs(t, s.DeleteRole(context.Background(), 99), gorm.ErrRecordNotFound)
+}
+
+// permissionNames returns the names of the permissions.
+func permissionNames(permissions []entity.Permission) []string {
+	names := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestRoleService_RolePermissions(t *testing.T) {
+	s, _, _ := newRoleService(t)
+
+	permissions, err := s.GetPermissions(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, permissions, 6)
+
+	role, err := s.CreateRole(context.Background(), entity.RoleRequest{Name: "ROLE_AUDITOR"})
+	require.NoError(t, err)
+	permissions, err = s.GetRolePermissions(context.Background(), role.ID)
+	require.NoError(t, err)
+	assert.Empty(t, permissions)
+
+	permissions, err = s.SetRolePermissions(context.Background(), role.ID, entity.RolePermissionsRequest{Permissions: []string{entity.PermissionUsersRead, entity.PermissionConsumersRead}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{entity.PermissionConsumersRead, entity.PermissionUsersRead}, permissionNames(permissions))
+
+	scopes, err := s.GetScopes([]string{"ROLE_AUDITOR", entity.RoleUser})
+	require.NoError(t, err)
+	assert.Equal(t, []string{entity.PermissionConsumersRead, entity.PermissionUsersRead}, scopes)
+
+	// The permissions are replaced, not added
+	_, err = s.SetRolePermissions(context.Background(), role.ID, entity.RolePermissionsRequest{Permissions: []string{}})
+	require.NoError(t, err)
+	permissions, err = s.GetRolePermissions(context.Background(), role.ID)
+	require.NoError(t, err)
+	assert.Empty(t, permissions)
+}
+
+func TestRoleService_SetRolePermissions_Rejected(t *testing.T) {
+	s, _, _ := newRoleService(t)
+
+	// An unknown permission leaves the permissions of the role unchanged
+	_, err := s.SetRolePermissions(context.Background(), 1, entity.RolePermissionsRequest{Permissions: []string{entity.PermissionConsumersWrite, "consumers:delete"}})
+	assert.ErrorIs(t, err, service.ErrUnknownPermission)
+	permissions, err := s.GetRolePermissions(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{entity.PermissionConsumersRead}, permissionNames(permissions))
+
+	_, err = s.SetRolePermissions(context.Background(), 99, entity.RolePermissionsRequest{})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = s.GetRolePermissions(context.Background(), 99)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestRoleService_DeleteRole_DropsItsPermissions(t *testing.T) {
+	s, store, _ := newRoleService(t)
+
+	role, err := s.CreateRole(context.Background(), entity.RoleRequest{Name: "ROLE_AUDITOR"})
+	require.NoError(t, err)
+	_, err = s.SetRolePermissions(context.Background(), role.ID, entity.RolePermissionsRequest{Permissions: []string{entity.PermissionUsersWrite}})
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteRole(context.Background(), role.ID))
+
+	permissions, err := repository.NewMemoryPermissionRepository(store).GetRolePermissions(nil, role.ID)
+	require.NoError(t, err)
+	assert.Empty(t, permissions)
+}
@@ -0,0 +1,220 @@
+package test_security_settings
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/headers"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newSecuritySettingsService creates a security settings service backed by an in-memory store, with its overrides loaded.
+func newSecuritySettingsService(t *testing.T, ttl time.Duration) (service.SecuritySettingsService, repository.SecuritySettingsRepository, *clock.FakeClock) {
+	store := testsupport.UseMemoryDatabase(t)
+	repo := repository.NewMemorySecuritySettingsRepository(store)
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+
+	s := service.NewSecuritySettingsService(database.DefaultStore(), repo, ttl, clk)
+	require.NoError(t, s.LoadOverrides())
+
+	return s, repo, clk
+}
+
+// newRouter creates a router with the security and CORS headers middlewares applying the overrides of the cache.
+func newRouter(t *testing.T, overrides *headers.OverridesCache) *gin.Engine {
+	t.Setenv("NODE_ENV", "development")
+	t.Setenv("FRONTEND_URL", "http://localhost:3000")
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(headers.SecurityHeadersWithOverrides(overrides), headers.CorsHeadersWithOverrides(overrides))
+	r.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+
+	return r
+}
+
+// get performs a GET request from the given origin.
+func get(router http.Handler, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set("Origin", origin)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSecuritySettingsService_GetSecuritySettings_Empty(t *testing.T) {
+	s, _, _ := newSecuritySettingsService(t, time.Minute)
+
+	settings, err := s.GetSecuritySettings(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, settings.AllowedOrigins)
+	assert.Empty(t, settings.AllowedHeaders)
+	assert.Empty(t, settings.SecurityHeaders)
+	assert.Nil(t, settings.UpdatedAt)
+}
+
+func TestSecuritySettingsService_UpdateSecuritySettings(t *testing.T) {
+	s, _, clk := newSecuritySettingsService(t, time.Minute)
+
+	settings, err := s.UpdateSecuritySettings(context.Background(), 1, entity.SecuritySettingsRequest{
+		AllowedOrigins:  []string{"https://admin.example.com", "https://admin.example.com", "http://localhost:5173"},
+		AllowedHeaders:  []string{"x-tenant-id", "X-Tenant-ID"},
+		SecurityHeaders: map[string]string{"referrer-policy": "strict-origin-when-cross-origin", "X-Frame-Options": ""},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://admin.example.com", "http://localhost:5173"}, settings.AllowedOrigins)
+	assert.Equal(t, []string{"X-Tenant-Id"}, settings.AllowedHeaders)
+	assert.Equal(t, map[string]string{"Referrer-Policy": "strict-origin-when-cross-origin", "X-Frame-Options": ""}, settings.SecurityHeaders)
+	assert.Equal(t, int64(1), *settings.UpdatedBy)
+	assert.Equal(t, clk.Now(), *settings.UpdatedAt)
+
+	stored, err := s.GetSecuritySettings(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, settings, stored)
+
+	// The instance applies the new settings at once
+	overrides := s.Overrides().Get()
+	assert.Equal(t, settings.AllowedOrigins, overrides.AllowedOrigins)
+	assert.Equal(t, settings.SecurityHeaders, overrides.SecurityHeaders)
+}
+
+func TestSecuritySettingsService_UpdateSecuritySettings_Rejected(t *testing.T) {
+	s, _, _ := newSecuritySettingsService(t, time.Minute)
+
+	invalid := []entity.SecuritySettingsRequest{
+		{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{}, SecurityHeaders: map[string]string{}},
+		{AllowedOrigins: []string{"ftp://files.example.com"}, AllowedHeaders: []string{}, SecurityHeaders: map[string]string{}},
+		{AllowedOrigins: []string{"https://admin.example.com/login"}, AllowedHeaders: []string{}, SecurityHeaders: map[string]string{}},
+		{AllowedOrigins: []string{}, AllowedHeaders: []string{}, SecurityHeaders: map[string]string{"Strict-Transport-Security": "max-age=0"}},
+		{AllowedOrigins: []string{}, AllowedHeaders: []string{}, SecurityHeaders: map[string]string{"Set-Cookie": "session=1"}},
+	}
+	for _, req := range invalid {
+		_, err := s.UpdateSecuritySettings(context.Background(), 1, req)
+		assert.ErrorIs(t, err, service.ErrInvalidSecuritySettings, "%+v", req)
+	}
+
+	malformed := []entity.SecuritySettingsRequest{
+		{AllowedHeaders: []string{}, SecurityHeaders: map[string]string{}},
+		{AllowedOrigins: []string{}, AllowedHeaders: []string{"X Tenant"}, SecurityHeaders: map[string]string{}},
+	}
+	for _, req := range malformed {
+		var ve validator.ValidationErrors
+		_, err := s.UpdateSecuritySettings(context.Background(), 1, req)
+		assert.True(t, errors.As(err, &ve), "%+v", req)
+	}
+
+	settings, err := s.GetSecuritySettings(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, settings.UpdatedAt, "the rejected settings must not be saved")
+}
+
+func TestSecuritySettings_OtherInstancesReloadAfterTTL(t *testing.T) {
+	s, repo, clk := newSecuritySettingsService(t, 30*time.Second)
+
+	// Another instance changes the settings in the shared database
+	_, err := repo.SaveSecuritySettings(nil, entity.SecuritySettings{AllowedOrigins: []string{"https://admin.example.com"}})
+	require.NoError(t, err)
+
+	assert.Empty(t, s.Overrides().Get().AllowedOrigins)
+
+	clk.Advance(30 * time.Second)
+	assert.Equal(t, []string{"https://admin.example.com"}, s.Overrides().Get().AllowedOrigins)
+}
+
+func TestOverridesCache_NotLoaded(t *testing.T) {
+	calls := 0
+	cache := headers.NewOverridesCache(func() (headers.Overrides, error) {
+		calls++
+		return headers.Overrides{AllowedOrigins: []string{"https://admin.example.com"}}, nil
+	}, time.Minute, clock.NewFakeClock(time.Now()))
+
+	// Nothing is loaded before Load, e.g. before the database is initialized
+	cache.Invalidate()
+	assert.Empty(t, cache.Get().AllowedOrigins)
+	assert.Zero(t, calls)
+
+	require.NoError(t, cache.Load())
+	assert.Equal(t, []string{"https://admin.example.com"}, cache.Get().AllowedOrigins)
+	assert.Equal(t, 1, calls)
+}
+
+func TestOverridesCache_KeepsPreviousOnError(t *testing.T) {
+	var loadErr error
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	calls := 0
+	cache := headers.NewOverridesCache(func() (headers.Overrides, error) {
+		calls++
+		if loadErr != nil {
+			return headers.Overrides{}, loadErr
+		}
+		return headers.Overrides{AllowedOrigins: []string{"https://admin.example.com"}}, nil
+	}, time.Minute, clk)
+	require.NoError(t, cache.Load())
+
+	loadErr = errors.New("connection refused")
+	clk.Advance(time.Minute)
+	assert.Equal(t, []string{"https://admin.example.com"}, cache.Get().AllowedOrigins)
+	assert.Equal(t, 2, calls)
+
+	// The failed reload is retried after the TTL, not on every request
+	assert.Equal(t, []string{"https://admin.example.com"}, cache.Get().AllowedOrigins)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCorsHeaders_Overrides(t *testing.T) {
+	s, _, _ := newSecuritySettingsService(t, time.Minute)
+	router := newRouter(t, s.Overrides())
+
+	assert.Equal(t, http.StatusForbidden, get(router, "https://admin.example.com").Code)
+
+	_, err := s.UpdateSecuritySettings(context.Background(), 1, entity.SecuritySettingsRequest{
+		AllowedOrigins:  []string{"https://admin.example.com"},
+		AllowedHeaders:  []string{"X-Tenant-ID"},
+		SecurityHeaders: map[string]string{},
+	})
+	require.NoError(t, err)
+
+	w := get(router, "https://admin.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://admin.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "X-Tenant-Id")
+
+	// The origins of the environment stay allowed
+	assert.Equal(t, http.StatusOK, get(router, "http://localhost:3000").Code)
+}
+
+func TestSecurityHeaders_Overrides(t *testing.T) {
+	s, _, _ := newSecuritySettingsService(t, time.Minute)
+	router := newRouter(t, s.Overrides())
+
+	w := get(router, "http://localhost:3000")
+	assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+
+	_, err := s.UpdateSecuritySettings(context.Background(), 1, entity.SecuritySettingsRequest{
+		AllowedOrigins:  []string{},
+		AllowedHeaders:  []string{},
+		SecurityHeaders: map[string]string{"Referrer-Policy": "strict-origin-when-cross-origin", "X-Frame-Options": ""},
+	})
+	require.NoError(t, err)
+
+	w = get(router, "http://localhost:3000")
+	assert.Equal(t, "strict-origin-when-cross-origin", w.Header().Get("Referrer-Policy"))
+	assert.Empty(t, w.Header().Values("X-Frame-Options"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+}
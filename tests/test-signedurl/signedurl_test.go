@@ -0,0 +1,165 @@
+package test_signedurl
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	metacontext "github.com/yoanesber/go-jwt-auth-demo/pkg/context-data/meta-context"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/secrets"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/signedurl"
+)
+
+const secret = "0123456789abcdef0123456789abcdef"
+
+// newSigner creates a signer of URLs valid for 5 minutes, with a clock frozen at a fixed time.
+func newSigner(t *testing.T) (*signedurl.Signer, *clock.FakeClock) {
+	t.Helper()
+
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	signer := signedurl.NewSigner(signedurl.Config{Secret: []byte(secret), TTL: 5 * time.Minute}, clk)
+	require.NotNil(t, signer)
+
+	return signer, clk
+}
+
+// parse splits the signed URL into its path and its query.
+func parse(t *testing.T, signed string) (string, url.Values) {
+	t.Helper()
+
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+
+	return u.Path, u.Query()
+}
+
+func TestLoadConfig(t *testing.T) {
+	provider := secrets.NewEnvProvider()
+	t.Setenv(signedurl.SecretName, "")
+	t.Setenv(signedurl.SecretName+"_FILE", "")
+	t.Setenv("SIGNED_URL_TTL_SECOND", "")
+
+	cfg, err := signedurl.LoadConfig(provider)
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled())
+	assert.Equal(t, signedurl.DefaultTTL, cfg.TTL)
+	assert.Nil(t, signedurl.NewSigner(cfg, clock.New()))
+
+	t.Setenv(signedurl.SecretName, secret)
+	t.Setenv("SIGNED_URL_TTL_SECOND", "60")
+	cfg, err = signedurl.LoadConfig(provider)
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled())
+	assert.Equal(t, time.Minute, cfg.TTL)
+
+	for _, ttl := range []string{"0", "-5", "soon", "86401"} {
+		t.Setenv("SIGNED_URL_TTL_SECOND", ttl)
+		_, err = signedurl.LoadConfig(provider)
+		assert.Error(t, err, ttl)
+	}
+
+	t.Setenv("SIGNED_URL_TTL_SECOND", "")
+	t.Setenv(signedurl.SecretName, "too-short")
+	_, err = signedurl.LoadConfig(provider)
+	assert.Error(t, err)
+}
+
+func TestSigner_Verify(t *testing.T) {
+	signer, clk := newSigner(t)
+
+	signed, expiresAt := signer.Sign("/api/v1/downloads/consumers", url.Values{"format": {"ndjson"}})
+	assert.Equal(t, time.Date(2025, 1, 15, 10, 5, 0, 0, time.UTC), expiresAt.UTC())
+	path, query := parse(t, signed)
+	require.NoError(t, signer.Verify(path, query))
+
+	// Another path, or a changed or added parameter, invalidates the signature
+	assert.ErrorIs(t, signer.Verify("/api/v1/downloads/users", query), signedurl.ErrInvalidSignature)
+	for key, value := range map[string]string{"format": "csv", "expires": "1999999999", "extra": "1"} {
+		altered := url.Values{}
+		for k, v := range query {
+			altered[k] = v
+		}
+		altered.Set(key, value)
+		assert.ErrorIs(t, signer.Verify(path, altered), signedurl.ErrInvalidSignature, key)
+	}
+
+	duplicated := url.Values{}
+	for k, v := range query {
+		duplicated[k] = v
+	}
+	duplicated.Add(signedurl.ParamSignature, query.Get(signedurl.ParamSignature))
+	assert.ErrorIs(t, signer.Verify(path, duplicated), signedurl.ErrInvalidSignature)
+
+	query.Del(signedurl.ParamSignature)
+	assert.ErrorIs(t, signer.Verify(path, query), signedurl.ErrMissingSignature)
+
+	// A URL signed with another secret is rejected
+	other := signedurl.NewSigner(signedurl.Config{Secret: []byte(strings.Repeat("x", 32)), TTL: time.Minute}, clk)
+	path, query = parse(t, signed)
+	assert.ErrorIs(t, other.Verify(path, query), signedurl.ErrInvalidSignature)
+
+	// The URL expires after its lifetime
+	clk.Advance(5*time.Minute - time.Second)
+	assert.NoError(t, signer.Verify(path, query))
+	clk.Advance(time.Second)
+	assert.ErrorIs(t, signer.Verify(path, query), signedurl.ErrExpired)
+}
+
+func TestSignedURL_DownloadsAsTheIssuingUser(t *testing.T) {
+	signer, clk := newSigner(t)
+	user := metacontext.UserInformationMeta{UserID: 2, Username: "alice", Roles: []string{"ROLE_USER"}, ClientID: "web"}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/v1/consumers/stream/signed-url", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(metacontext.InjectUserInformationMeta(c.Request.Context(), user))
+	}, handler.NewSignedURLHandler(signer).Issue("/api/v1/downloads/consumers"))
+	r.GET("/api/v1/downloads/consumers", authorization.SignedURL(signer), authorization.RoleBasedAccessControl("ROLE_ADMIN", "ROLE_USER"), func(c *gin.Context) {
+		meta, _ := metacontext.ExtractUserInformationMeta(c.Request.Context())
+		c.JSON(http.StatusOK, meta)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/consumers/stream/signed-url", nil))
+	require.Equal(t, http.StatusCreated, w.Code)
+	var issued struct {
+		Data entity.SignedURLResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &issued))
+	assert.True(t, strings.HasPrefix(issued.Data.URL, "/api/v1/downloads/consumers?"))
+	assert.Equal(t, clk.Now().Add(5*time.Minute), issued.Data.ExpiresAt)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, issued.Data.URL, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var meta metacontext.UserInformationMeta
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &meta))
+	assert.Equal(t, user, meta)
+
+	// Granting the URL another role invalidates it
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, strings.Replace(issued.Data.URL, "ROLE_USER", "ROLE_ADMIN", 1), nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/downloads/consumers", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	clk.Advance(6 * time.Minute)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, issued.Data.URL, nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "expired")
+}
@@ -0,0 +1,162 @@
+package test_token_usage
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// date returns the given day at midnight UTC.
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestAsyncTokenUsageRecorder_AggregatesPerDayUserAndClient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	usageService := mocks.NewMockTokenUsageService(ctrl)
+	morning := time.Date(2025, 1, 15, 8, 0, 0, 0, time.UTC)
+
+	r := service.NewAsyncTokenUsageRecorder(usageService, time.Hour, 0)
+
+	var written []entity.TokenUsage
+	usageService.EXPECT().IncrementTokenUsage(gomock.Any()).DoAndReturn(func(usage []entity.TokenUsage) error {
+		written = usage
+		return nil
+	}).Times(1)
+
+	r.Record(service.TokenEventIssued, 1, "web", morning)
+	r.Record(service.TokenEventIssued, 1, "web", morning.Add(time.Hour))
+	r.Record(service.TokenEventRefreshed, 1, "web", morning.Add(2*time.Hour))
+	r.Record(service.TokenEventIssued, 1, "", morning)
+	r.Record(service.TokenEventRevoked, 2, "mobile", morning.Add(24*time.Hour))
+	r.Close()
+
+	day := customtype.Date{Time: date(2025, 1, 15)}
+	assert.ElementsMatch(t, []entity.TokenUsage{
+		{Day: day, UserID: 1, Client: "web", Issued: 2, Refreshed: 1},
+		{Day: day, UserID: 1, Client: entity.UnknownClient, Issued: 1},
+		{Day: customtype.Date{Time: date(2025, 1, 16)}, UserID: 2, Client: "mobile", Revoked: 1},
+	}, written)
+}
+
+func TestAsyncTokenUsageRecorder_DropsEventsWhenFull(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	usageService := mocks.NewMockTokenUsageService(ctrl)
+	now := time.Date(2025, 1, 15, 8, 0, 0, 0, time.UTC)
+
+	r := service.NewAsyncTokenUsageRecorder(usageService, time.Hour, 1)
+
+	var mu sync.Mutex
+	var written []entity.TokenUsage
+	usageService.EXPECT().IncrementTokenUsage(gomock.Any()).DoAndReturn(func(usage []entity.TokenUsage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		written = usage
+		return nil
+	}).Times(1)
+
+	// The pending row still counts its own events, the new row is dropped
+	r.Record(service.TokenEventIssued, 1, "web", now)
+	r.Record(service.TokenEventIssued, 2, "web", now)
+	r.Record(service.TokenEventIssued, 1, "web", now)
+	r.Close()
+
+	require.Len(t, written, 1)
+	assert.Equal(t, int64(1), written[0].UserID)
+	assert.Equal(t, int64(2), written[0].Issued)
+}
+
+func TestNormalizeClient(t *testing.T) {
+	assert.Equal(t, entity.UnknownClient, service.NormalizeClient("  "))
+	assert.Equal(t, "web", service.NormalizeClient(" web "))
+	assert.Len(t, service.NormalizeClient(strings.Repeat("x", 150)), 100)
+}
+
+func TestTokenStatsFilter_Validate(t *testing.T) {
+	valid := entity.TokenStatsFilter{From: date(2025, 1, 1), To: date(2025, 12, 31)}
+	assert.NoError(t, valid.Validate())
+
+	inverted := entity.TokenStatsFilter{From: date(2025, 1, 2), To: date(2025, 1, 1)}
+	assert.EqualError(t, inverted.Validate(), "from must not be after to")
+
+	tooLong := entity.TokenStatsFilter{From: date(2024, 1, 1), To: date(2025, 1, 1)}
+	assert.EqualError(t, tooLong.Validate(), "the date range must not exceed 366 days")
+
+	assert.Error(t, (&entity.TokenStatsFilter{}).Validate())
+}
+
+func TestMemoryTokenUsageRepository(t *testing.T) {
+	repo := repository.NewMemoryTokenUsageRepository(repository.NewMemoryStore())
+	jan15 := customtype.Date{Time: date(2025, 1, 15)}
+	jan16 := customtype.Date{Time: date(2025, 1, 16)}
+
+	require.NoError(t, repo.IncrementTokenUsage(nil, entity.TokenUsage{Day: jan16, UserID: 1, Client: "web", Issued: 1}))
+	require.NoError(t, repo.IncrementTokenUsage(nil, entity.TokenUsage{Day: jan15, UserID: 1, Client: "web", Issued: 2}))
+	require.NoError(t, repo.IncrementTokenUsage(nil, entity.TokenUsage{Day: jan15, UserID: 1, Client: "web", Refreshed: 1}))
+	require.NoError(t, repo.IncrementTokenUsage(nil, entity.TokenUsage{Day: jan15, UserID: 2, Client: "mobile", Revoked: 1}))
+
+	usage, err := repo.GetTokenUsage(nil, entity.TokenStatsFilter{From: jan15.Time, To: jan16.Time})
+	require.NoError(t, err)
+	assert.Equal(t, []entity.TokenUsage{
+		{Day: jan15, UserID: 1, Client: "web", Issued: 2, Refreshed: 1},
+		{Day: jan15, UserID: 2, Client: "mobile", Revoked: 1},
+		{Day: jan16, UserID: 1, Client: "web", Issued: 1},
+	}, usage)
+
+	usage, err = repo.GetTokenUsage(nil, entity.TokenStatsFilter{From: jan16.Time, To: jan16.Time, UserID: 1})
+	require.NoError(t, err)
+	assert.Len(t, usage, 1)
+
+	usage, err = repo.GetTokenUsage(nil, entity.TokenStatsFilter{From: jan15.Time, To: jan16.Time, Client: "mobile"})
+	require.NoError(t, err)
+	assert.Len(t, usage, 1)
+}
+
+func TestGetTokenStats_Handler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockTokenUsageService(ctrl)
+	h := handler.NewTokenStatsHandler(s)
+
+	router := testsupport.NewRouter(t)
+	router.GET("/api/v1/admin/token-stats", authorization.RoleBasedAccessControl("ROLE_ADMIN"), h.GetTokenStats)
+	admin := testsupport.NewTokenBuilder().Build(t)
+
+	filter := entity.TokenStatsFilter{From: date(2025, 1, 1), To: date(2025, 1, 31), UserID: 1, Client: "web"}
+	s.EXPECT().GetTokenStats(gomock.Any(), filter).Return(entity.TokenStats{
+		From:   customtype.Date{Time: filter.From},
+		To:     customtype.Date{Time: filter.To},
+		Totals: entity.TokenUsageTotals{Issued: 5},
+		Usage:  []entity.TokenUsage{},
+	}, nil)
+
+	w := testsupport.Do(router, "GET", "/api/v1/admin/token-stats?from=2025-01-01&to=2025-01-31&userId=1&client=web", admin)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Data entity.TokenStats `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(5), resp.Data.Totals.Issued)
+
+	// The range is validated before reaching the service
+	for _, query := range []string{"from=2025-13-01", "to=yesterday", "userId=abc", "from=2025-02-01&to=2025-01-01", "from=2020-01-01&to=2025-01-01"} {
+		w := testsupport.Do(router, "GET", "/api/v1/admin/token-stats?"+query, admin)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
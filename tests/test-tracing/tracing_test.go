@@ -0,0 +1,196 @@
+package test_tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/mock/gomock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/tracing"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// recordSpans sets a tracer provider recording the ended spans, in place of the exporter, for the duration of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+
+	return recorder
+}
+
+// spanNamed returns the ended span with the given name.
+func spanNamed(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	require.Failf(t, "missing span", "no span named %s", name)
+
+	return nil
+}
+
+// newDryRunDB opens a GORM connection with the query tracing which builds the statements without running them,
+// so that no database is needed.
+func newDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost user=test dbname=test sslmode=disable"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 gormLogger.Default.LogMode(gormLogger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(database.QueryTracing{}))
+
+	return db
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("TRACING_ENABLED", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	t.Setenv("TRACING_SAMPLE_RATIO", "")
+
+	cfg := tracing.LoadConfig()
+	assert.False(t, cfg.Enabled)
+	assert.Equal(t, tracing.DefaultEndpoint, cfg.Endpoint)
+	assert.Equal(t, tracing.DefaultServiceName, cfg.ServiceName)
+	assert.Equal(t, tracing.DefaultSampleRatio, cfg.SampleRatio)
+	assert.NoError(t, cfg.Validate())
+
+	t.Setenv("TRACING_ENABLED", "true")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "https://otel.example.com:4318")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+	cfg = tracing.LoadConfig()
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, 0.25, cfg.SampleRatio)
+	assert.NoError(t, cfg.Validate())
+
+	for name, value := range map[string]string{"not a number": "half", "above 1": "1.5", "negative": "-0.1"} {
+		t.Setenv("TRACING_SAMPLE_RATIO", value)
+		assert.Error(t, tracing.LoadConfig().Validate(), name)
+	}
+
+	t.Setenv("TRACING_SAMPLE_RATIO", "")
+	for _, endpoint := range []string{"localhost:4318", "grpc://localhost:4317", "http://"} {
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", endpoint)
+		assert.Error(t, tracing.LoadConfig().Validate(), endpoint)
+	}
+}
+
+func TestInit_Disabled(t *testing.T) {
+	shutdown, err := tracing.Init(context.Background(), tracing.Config{Enabled: false})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = tracing.Init(context.Background(), tracing.Config{Enabled: true, Endpoint: "localhost", SampleRatio: 1})
+	assert.Error(t, err)
+}
+
+func TestTrace_SpansTheServiceAndTheQueries(t *testing.T) {
+	recorder := recordSpans(t)
+	db := newDryRunDB(t)
+
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserService(ctrl)
+	users.EXPECT().GetUserByID(gomock.Any(), int64(1)).DoAndReturn(func(ctx context.Context, id int64) (entity.User, error) {
+		var user entity.User
+		err := db.WithContext(ctx).Where("id = ?", id).First(&user).Error
+		return user, err
+	})
+	users.EXPECT().GetUserByID(gomock.Any(), int64(2)).Return(entity.User{}, errors.New("user not found"))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(otelgin.Middleware("test"))
+	traced := service.NewTracedUserService(users)
+	r.GET("/users/:id", func(c *gin.Context) {
+		id := int64(1)
+		if c.Param("id") == "2" {
+			id = 2
+		}
+		if _, err := traced.GetUserByID(c.Request.Context(), id); err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+	request := spanNamed(t, recorder, "/users/:id")
+	call := spanNamed(t, recorder, "UserService.GetUserByID")
+	query := spanNamed(t, recorder, "db.query")
+	assert.Equal(t, request.SpanContext().TraceID(), query.SpanContext().TraceID())
+	assert.Equal(t, request.SpanContext().SpanID(), call.Parent().SpanID())
+	assert.Equal(t, call.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.NotEqual(t, codes.Error, call.Status().Code)
+
+	attrs := map[string]string{}
+	for _, kv := range query.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	assert.Equal(t, "postgresql", attrs["db.system"])
+	assert.Equal(t, "users", attrs["db.collection.name"])
+	assert.Contains(t, attrs["db.query.text"], "$1")
+	assert.NotContains(t, attrs["db.query.text"], "= 1")
+
+	// The errors of the service are recorded on its span
+	recorder.Reset()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/2", nil))
+	call = spanNamed(t, recorder, "UserService.GetUserByID")
+	assert.Equal(t, codes.Error, call.Status().Code)
+	assert.Equal(t, "user not found", call.Status().Description)
+}
+
+func TestTrace_SpansTheJWTValidation(t *testing.T) {
+	recorder := recordSpans(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(otelgin.Middleware("test"))
+	r.GET("/protected", authorization.JwtValidationWithConfig(testsupport.NewJWTConfig(), clock.New()), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/protected", nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	request := spanNamed(t, recorder, "/protected")
+	validation := spanNamed(t, recorder, "jwt.validate")
+	assert.Equal(t, request.SpanContext().SpanID(), validation.Parent().SpanID())
+	assert.Equal(t, codes.Error, validation.Status().Code)
+}
@@ -0,0 +1,169 @@
+package test_transaction
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/transaction"
+	httputil "github.com/yoanesber/go-jwt-auth-demo/pkg/util/http-util"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newRouter creates a router running the given handler in a request transaction, and records the transaction seen by the handler.
+func newRouter(t *testing.T, handle func(c *gin.Context)) (*gin.Engine, **gorm.DB) {
+	t.Helper()
+
+	testsupport.UseMemoryDatabase(t)
+	gin.SetMode(gin.TestMode)
+
+	var seen *gorm.DB
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.POST("/write", transaction.Transactional(), func(c *gin.Context) {
+		tx, ok := database.TransactionFromContext(c.Request.Context())
+		require.True(t, ok, "the handler must see the transaction of the request")
+		seen = tx
+		handle(c)
+	})
+
+	return router, &seen
+}
+
+func post(router *gin.Engine) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/write", nil))
+	return w
+}
+
+// assertEnded asserts the transaction was committed or rolled back by the middleware.
+func assertEnded(t *testing.T, tx *gorm.DB) {
+	t.Helper()
+
+	require.NotNil(t, tx)
+	assert.ErrorIs(t, tx.Commit().Error, sql.ErrTxDone)
+}
+
+func TestTransactional_SuccessfulResponseIsSentAfterTheTransaction(t *testing.T) {
+	router, seen := newRouter(t, func(c *gin.Context) {
+		// The transactions opened by the services are nested in the transaction of the request
+		db := database.GetPostgresContext(c.Request.Context())
+		require.NoError(t, db.Transaction(func(tx *gorm.DB) error { return nil }))
+
+		httputil.Created(c, "Created", gin.H{"id": 1})
+	})
+
+	w := post(router)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":1`)
+	assertEnded(t, *seen)
+}
+
+func TestTransactional_ErrorResponseRollsBack(t *testing.T) {
+	router, seen := newRouter(t, func(c *gin.Context) {
+		httputil.Conflict(c, "Conflict", "already exists")
+	})
+
+	w := post(router)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "already exists")
+	assertEnded(t, *seen)
+}
+
+func TestTransactional_AttachedErrorRollsBack(t *testing.T) {
+	router, seen := newRouter(t, func(c *gin.Context) {
+		_ = c.Error(sql.ErrConnDone)
+		c.Status(http.StatusNoContent)
+	})
+
+	w := post(router)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assertEnded(t, *seen)
+}
+
+func TestTransactional_PanicRollsBack(t *testing.T) {
+	router, seen := newRouter(t, func(c *gin.Context) {
+		panic("boom")
+	})
+
+	w := post(router)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assertEnded(t, *seen)
+}
+
+func TestGetPostgresContext_WithoutTransaction(t *testing.T) {
+	testsupport.UseMemoryDatabase(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, ok := database.TransactionFromContext(req.Context())
+	assert.False(t, ok)
+	assert.NotNil(t, database.GetPostgresContext(req.Context()))
+}
+
+func TestTransactional_RunsTheCommitHooksAfterTheCommit(t *testing.T) {
+	var ran []string
+	router, _ := newRouter(t, func(c *gin.Context) {
+		db := database.GetPostgresContext(c.Request.Context())
+		database.AfterCommit(db, func() { ran = append(ran, "request") })
+
+		// The hooks of a nested transaction run with the ones of the request, unless it is rolled back
+		require.NoError(t, database.Transaction(db, func(tx *gorm.DB) error {
+			database.AfterCommit(tx, func() { ran = append(ran, "nested") })
+			return nil
+		}))
+		require.Error(t, database.Transaction(db, func(tx *gorm.DB) error {
+			database.AfterCommit(tx, func() { ran = append(ran, "rolled back") })
+			return errors.New("rolled back")
+		}))
+		assert.Empty(t, ran, "the hooks must not run before the commit")
+
+		httputil.Created(c, "Created", gin.H{"id": 1})
+	})
+
+	assert.Equal(t, http.StatusCreated, post(router).Code)
+	assert.Equal(t, []string{"request", "nested"}, ran)
+}
+
+func TestTransactional_DropsTheCommitHooksOnRollback(t *testing.T) {
+	ran := false
+	router, _ := newRouter(t, func(c *gin.Context) {
+		database.AfterCommit(database.GetPostgresContext(c.Request.Context()), func() { ran = true })
+		httputil.Conflict(c, "Conflict", "already exists")
+	})
+
+	assert.Equal(t, http.StatusConflict, post(router).Code)
+	assert.False(t, ran)
+}
+
+func TestTransaction_RunsTheCommitHooksAfterTheCommit(t *testing.T) {
+	testsupport.UseMemoryDatabase(t)
+	db := database.GetPostgres()
+
+	committed := false
+	require.NoError(t, database.Transaction(db, func(tx *gorm.DB) error {
+		database.AfterCommit(tx, func() { committed = true })
+		assert.False(t, committed)
+		return nil
+	}))
+	assert.True(t, committed)
+
+	rolledBack := false
+	require.Error(t, database.Transaction(db, func(tx *gorm.DB) error {
+		database.AfterCommit(tx, func() { rolledBack = true })
+		return errors.New("rolled back")
+	}))
+	assert.False(t, rolledBack)
+
+	// Outside a transaction, the statements are committed when they return, so the hook runs at once
+	now := false
+	database.AfterCommit(db, func() { now = true })
+	assert.True(t, now)
+}
@@ -0,0 +1,162 @@
+package test_user_state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/handler"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// newUserStateService creates the user state service under test backed by the in-memory store,
+// with a user in the given state and a mocked token revocation service.
+func newUserStateService(t *testing.T, state entity.UserState) (service.UserStateService, repository.UserRepository, *mocks.MockTokenRevocationService, *clock.FakeClock, int64) {
+	store := testsupport.UseMemoryDatabase(t)
+	user, err := store.AddUser(entity.User{Username: "alice", Email: "alice@example.com", State: state})
+	require.NoError(t, err)
+
+	userRepo := repository.NewMemoryUserRepository(store)
+	revocations := mocks.NewMockTokenRevocationService(gomock.NewController(t))
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+
+	return service.NewUserStateService(database.DefaultStore(), userRepo, revocations, clk), userRepo, revocations, clk, user.ID
+}
+
+func TestUserState_Transitions(t *testing.T) {
+	tests := []struct {
+		from, to entity.UserState
+		allowed  bool
+	}{
+		{entity.UserStatePendingVerification, entity.UserStateActive, true},
+		{entity.UserStateActive, entity.UserStateDisabled, true},
+		{entity.UserStateActive, entity.UserStateSuspended, true},
+		{entity.UserStateDisabled, entity.UserStateActive, true},
+		{entity.UserStateSuspended, entity.UserStateActive, true},
+		{entity.UserStateActive, entity.UserStateActive, false},
+		{entity.UserStateActive, entity.UserStatePendingVerification, false},
+		{entity.UserStateSuspended, entity.UserStatePendingVerification, false},
+		{"LOCKED", entity.UserStateActive, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, tt.from.CanTransitionTo(tt.to), "%s -> %s", tt.from, tt.to)
+	}
+
+	assert.True(t, entity.UserStateSuspended.IsValid())
+	assert.False(t, entity.UserState("LOCKED").IsValid())
+}
+
+func TestChangeUserState_Disable(t *testing.T) {
+	s, userRepo, _, clk, userID := newUserStateService(t, entity.UserStateActive)
+
+	// Disabling does not revoke the tokens, the mocked revocation service fails the test if called
+	change, err := s.ChangeUserState(context.Background(), userID, entity.UserStateRequest{State: entity.UserStateDisabled, Reason: " Left the company "})
+
+	require.NoError(t, err)
+	assert.Equal(t, entity.UserStateActive, change.PreviousState)
+	assert.Equal(t, entity.UserStateDisabled, change.State)
+	require.NotNil(t, change.Reason)
+	assert.Equal(t, "Left the company", *change.Reason)
+
+	user, err := userRepo.GetUserByID(context.Background(), nil, userID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.UserStateDisabled, user.State)
+	assert.Equal(t, "Left the company", *user.StateReason)
+	assert.True(t, user.StateChangedAt.Equal(clk.Now()))
+}
+
+func TestChangeUserState_SuspendRevokesTokens(t *testing.T) {
+	s, _, revocations, _, userID := newUserStateService(t, entity.UserStateActive)
+
+	revocations.EXPECT().RevokeUserTokens(gomock.Any(), userID).Return(entity.TokenRevocation{UserID: userID, TokenVersion: 1}, nil)
+
+	change, err := s.ChangeUserState(context.Background(), userID, entity.UserStateRequest{State: entity.UserStateSuspended, Reason: "Account compromised"})
+
+	require.NoError(t, err)
+	assert.Equal(t, entity.UserStateSuspended, change.State)
+}
+
+func TestChangeUserState_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   entity.UserState
+		req     entity.UserStateRequest
+		wantErr error
+	}{
+		{"same state", entity.UserStateActive, entity.UserStateRequest{State: entity.UserStateActive}, service.ErrInvalidUserStateTransition},
+		{"back to pending verification", entity.UserStateActive, entity.UserStateRequest{State: entity.UserStatePendingVerification}, nil},
+		{"suspend without reason", entity.UserStateActive, entity.UserStateRequest{State: entity.UserStateSuspended, Reason: "  "}, service.ErrUserStateReasonRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, userRepo, _, _, userID := newUserStateService(t, tt.state)
+
+			_, err := s.ChangeUserState(context.Background(), userID, tt.req)
+
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+
+			// The state of the user is unchanged
+			user, err := userRepo.GetUserByID(context.Background(), nil, userID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.state, user.State)
+		})
+	}
+}
+
+func TestChangeUserState_UnknownUser(t *testing.T) {
+	s, _, _, _, _ := newUserStateService(t, entity.UserStateActive)
+
+	_, err := s.ChangeUserState(context.Background(), 999, entity.UserStateRequest{State: entity.UserStateActive})
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestChangeUserStateHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockUserStateService(ctrl)
+	h := handler.NewUserStateHandler(s)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PUT("/admin/users/:id/state", h.ChangeUserState)
+
+	s.EXPECT().ChangeUserState(gomock.Any(), int64(2), entity.UserStateRequest{State: entity.UserStateSuspended}).Return(entity.UserStateChange{}, service.ErrUserStateReasonRequired)
+	s.EXPECT().ChangeUserState(gomock.Any(), int64(3), entity.UserStateRequest{State: entity.UserStateActive}).Return(entity.UserStateChange{}, service.ErrInvalidUserStateTransition)
+
+	assert.Equal(t, http.StatusBadRequest, putJSON(router, "/admin/users/2/state", entity.UserStateRequest{State: entity.UserStateSuspended}).Code)
+	assert.Equal(t, http.StatusConflict, putJSON(router, "/admin/users/3/state", entity.UserStateRequest{State: entity.UserStateActive}).Code)
+	assert.Equal(t, http.StatusBadRequest, putJSON(router, "/admin/users/abc/state", entity.UserStateRequest{State: entity.UserStateActive}).Code)
+}
+
+// putJSON sends a PUT request with the given body to the router.
+func putJSON(router *gin.Engine, path string, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req, _ := http.NewRequest("PUT", path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w
+}
@@ -0,0 +1,268 @@
+package test_user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/go-playground/validator.v9"
+	"gorm.io/gorm"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// adminID is the ID of the administrator managing the users.
+const adminID int64 = 1
+
+// userServiceDeps holds the dependencies of the user service under test.
+type userServiceDeps struct {
+	users      repository.UserRepository
+	revocation *mocks.MockTokenRevocationService
+	clock      *clock.FakeClock
+	alice      entity.User
+}
+
+// newUserService creates a user service backed by an in-memory store with the seeded roles,
+// an administrator, and the alice user.
+func newUserService(t *testing.T) (service.UserService, userServiceDeps) {
+	store := testsupport.UseMemoryDatabase(t)
+	roles := map[string]entity.Role{}
+	for _, name := range []string{"ROLE_USER", "ROLE_ADMIN"} {
+		role, err := store.AddRole(entity.Role{Name: name})
+		require.NoError(t, err)
+		roles[name] = role
+	}
+
+	_, err := store.AddUser(entity.User{ID: adminID, Username: "admin", Email: "admin@example.com", State: entity.UserStateActive, Roles: []entity.Role{roles["ROLE_ADMIN"]}})
+	require.NoError(t, err)
+	alice, err := store.AddUser(entity.User{Username: "alice", Email: "alice@example.com", Firstname: "Alice", State: entity.UserStateActive,
+		UserType: entity.UserTypeUserAccount, Roles: []entity.Role{roles["ROLE_USER"]}})
+	require.NoError(t, err)
+
+	deps := userServiceDeps{
+		users:      repository.NewMemoryUserRepository(store),
+		revocation: mocks.NewMockTokenRevocationService(gomock.NewController(t)),
+		clock:      clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)),
+		alice:      alice,
+	}
+
+	return service.NewUserService(database.DefaultStore(), deps.users, repository.NewMemoryRoleRepository(store), deps.revocation, deps.clock), deps
+}
+
+// roleNames returns the names of the roles of the user.
+func roleNames(user entity.User) []string {
+	return entity.NewUserResponse(user).Roles
+}
+
+func TestUserService_CreateUserByAdmin(t *testing.T) {
+	s, deps := newUserService(t)
+
+	user, err := s.CreateUserByAdmin(context.Background(), entity.CreateUserRequest{
+		Username:  "bob",
+		Password:  "P@ssw0rd",
+		Email:     "bob@example.com",
+		Firstname: "Bob",
+		Roles:     []string{"ROLE_ADMIN", "role_user", "ROLE_ADMIN"},
+	}, adminID)
+	require.NoError(t, err)
+
+	stored, err := deps.users.GetUserByID(context.Background(), nil, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.UserStateActive, stored.State)
+	assert.Equal(t, entity.UserTypeUserAccount, stored.UserType)
+	assert.Equal(t, adminID, *stored.CreatedBy)
+	assert.ElementsMatch(t, []string{"ROLE_USER", "ROLE_ADMIN"}, roleNames(stored))
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte("P@ssw0rd")))
+}
+
+func TestUserService_CreateUserByAdmin_DefaultsToUserRole(t *testing.T) {
+	s, _ := newUserService(t)
+
+	user, err := s.CreateUserByAdmin(context.Background(), entity.CreateUserRequest{Username: "bob", Password: "P@ssw0rd", Email: "bob@example.com", Firstname: "Bob"}, adminID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ROLE_USER"}, roleNames(user))
+}
+
+func TestUserService_CreateUserByAdmin_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     entity.CreateUserRequest
+		wantErr error
+	}{
+		{"username taken", entity.CreateUserRequest{Username: "ALICE", Password: "P@ssw0rd", Email: "bob@example.com", Firstname: "Bob"}, service.ErrUserAlreadyExists},
+		{"email taken", entity.CreateUserRequest{Username: "bob", Password: "P@ssw0rd", Email: "Alice@Example.com", Firstname: "Bob"}, service.ErrUserAlreadyExists},
+		{"unknown role", entity.CreateUserRequest{Username: "bob", Password: "P@ssw0rd", Email: "bob@example.com", Firstname: "Bob", Roles: []string{"ROLE_ROOT"}}, service.ErrUnknownRole},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newUserService(t)
+
+			_, err := s.CreateUserByAdmin(context.Background(), tt.req, adminID)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUserService_CreateUserByAdmin_ValidatesRequest(t *testing.T) {
+	s, _ := newUserService(t)
+
+	_, err := s.CreateUserByAdmin(context.Background(), entity.CreateUserRequest{Username: "bob", Password: "password", Email: "bob@example.com", Firstname: "Bob", UserType: "ROBOT"}, adminID)
+
+	var ve validator.ValidationErrors
+	require.True(t, errors.As(err, &ve), err)
+	fields := []string{}
+	for _, fe := range ve {
+		fields = append(fields, fe.Field())
+	}
+	assert.ElementsMatch(t, []string{"password", "userType"}, fields)
+}
+
+func TestUserService_UpdateUser_ReplacesProfileAndRoles(t *testing.T) {
+	s, deps := newUserService(t)
+	lastname := "Smith"
+
+	updated, err := s.UpdateUser(context.Background(), deps.alice.ID, entity.UpdateUserRequest{
+		Email:     "alice.smith@example.com",
+		Firstname: "Alice",
+		Lastname:  &lastname,
+		UserType:  entity.UserTypeServiceAccount,
+		Roles:     []string{"ROLE_ADMIN"},
+	}, adminID)
+	require.NoError(t, err)
+
+	assert.Equal(t, "alice", updated.Username)
+	assert.Equal(t, "alice.smith@example.com", updated.Email)
+	assert.Equal(t, "Smith", *updated.Lastname)
+	assert.Equal(t, entity.UserTypeServiceAccount, updated.UserType)
+	assert.Equal(t, adminID, *updated.UpdatedBy)
+	assert.Equal(t, []string{"ROLE_ADMIN"}, roleNames(updated))
+}
+
+func TestUserService_UpdateUser_Rejected(t *testing.T) {
+	s, deps := newUserService(t)
+	req := entity.UpdateUserRequest{Email: "admin@example.com", Firstname: "Alice", UserType: entity.UserTypeUserAccount, Roles: []string{"ROLE_USER"}}
+
+	_, err := s.UpdateUser(context.Background(), deps.alice.ID, req, adminID)
+	assert.ErrorIs(t, err, service.ErrUserAlreadyExists)
+
+	// The user keeps their own email
+	req.Email = "ALICE@example.com"
+	_, err = s.UpdateUser(context.Background(), deps.alice.ID, req, adminID)
+	assert.NoError(t, err)
+
+	_, err = s.UpdateUser(context.Background(), 99, req, adminID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestUserService_ChangeUserRoles(t *testing.T) {
+	s, deps := newUserService(t)
+
+	// Assigning a role the user has is ignored
+	updated, err := s.ChangeUserRoles(context.Background(), deps.alice.ID, entity.UserRolesRequest{Add: []string{"role_admin", "ROLE_USER"}}, adminID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ROLE_USER", "ROLE_ADMIN"}, roleNames(updated))
+
+	// Removing a role the user does not have is ignored too
+	updated, err = s.ChangeUserRoles(context.Background(), deps.alice.ID, entity.UserRolesRequest{Remove: []string{"ROLE_USER"}}, adminID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ROLE_ADMIN"}, roleNames(updated))
+
+	stored, err := deps.users.GetUserByID(context.Background(), nil, deps.alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ROLE_ADMIN"}, roleNames(stored))
+}
+
+func TestUserService_ChangeUserRoles_Rejected(t *testing.T) {
+	s, deps := newUserService(t)
+
+	_, err := s.ChangeUserRoles(context.Background(), deps.alice.ID, entity.UserRolesRequest{Remove: []string{"ROLE_USER"}}, adminID)
+	assert.ErrorIs(t, err, service.ErrNoRolesLeft)
+
+	_, err = s.ChangeUserRoles(context.Background(), deps.alice.ID, entity.UserRolesRequest{Add: []string{"ROLE_UNKNOWN"}}, adminID)
+	assert.ErrorIs(t, err, service.ErrUnknownRole)
+
+	_, err = s.ChangeUserRoles(context.Background(), 99, entity.UserRolesRequest{Add: []string{"ROLE_USER"}}, adminID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	var ve validator.ValidationErrors
+	_, err = s.ChangeUserRoles(context.Background(), deps.alice.ID, entity.UserRolesRequest{}, adminID)
+	assert.True(t, errors.As(err, &ve))
+
+	// The user keeps their roles
+	stored, err := deps.users.GetUserByID(context.Background(), nil, deps.alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ROLE_USER"}, roleNames(stored))
+}
+
+func TestUserService_DeleteUser(t *testing.T) {
+	s, deps := newUserService(t)
+
+	deps.revocation.EXPECT().RevokeUserTokens(gomock.Any(), deps.alice.ID).Return(entity.TokenRevocation{UserID: deps.alice.ID}, nil)
+	require.NoError(t, s.DeleteUser(context.Background(), deps.alice.ID, adminID))
+
+	// The deleted user is no longer found, so they cannot log in anymore
+	_, err := s.GetUserByID(context.Background(), deps.alice.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = s.GetUserByUsername(context.Background(), "alice")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	users, err := s.GetUsers(context.Background(), 1, 10)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, adminID, users[0].ID)
+
+	err = s.DeleteUser(context.Background(), deps.alice.ID, adminID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestUserService_DeleteUser_RejectsOwnAccount(t *testing.T) {
+	s, _ := newUserService(t)
+
+	err := s.DeleteUser(context.Background(), adminID, adminID)
+	assert.ErrorIs(t, err, service.ErrCannotDeleteSelf)
+}
+
+func TestUserService_GetUsers_Paginates(t *testing.T) {
+	s, deps := newUserService(t)
+
+	page, err := s.GetUsers(context.Background(), 2, 1)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, deps.alice.ID, page[0].ID)
+
+	page, err = s.GetUsers(context.Background(), 3, 1)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+}
+
+func TestUserActionRequest_StateRequest(t *testing.T) {
+	tests := []struct {
+		action string
+		state  entity.UserState
+	}{
+		{entity.UserActionEnable, entity.UserStateActive},
+		{entity.UserActionDisable, entity.UserStateDisabled},
+		{entity.UserActionLock, entity.UserStateSuspended},
+	}
+
+	for _, tt := range tests {
+		req := entity.UserActionRequest{Action: tt.action, Reason: "Left the company"}
+		require.NoError(t, req.Validate())
+		assert.Equal(t, entity.UserStateRequest{State: tt.state, Reason: "Left the company"}, req.StateRequest())
+	}
+
+	req := entity.UserActionRequest{Action: "unlock"}
+	assert.Error(t, req.Validate())
+}
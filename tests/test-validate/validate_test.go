@@ -0,0 +1,187 @@
+package test_validate
+
+import (
+	"crypto/elliptic"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/config/validate"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// setupValidEnv sets a configuration passing the validation for the duration of the test,
+// with HS256 tokens and the in-memory database, so that each test only sets the values it checks.
+func setupValidEnv(t *testing.T) {
+	t.Helper()
+
+	testsupport.SetupJWTEnv(t)
+	t.Setenv("ENV", "DEVELOPMENT")
+	t.Setenv("PORT", "8080")
+	t.Setenv("IS_SSL", "FALSE")
+	t.Setenv("API_VERSION", "v1")
+	t.Setenv("JWT_ACCESS_TOKEN_TTL_MINUTES", "15")
+	t.Setenv("JWT_REFRESH_TOKEN_EXPIRATION_HOUR", "24")
+	t.Setenv("DB_DRIVER", database.DriverMemory)
+}
+
+// writeFile writes the content into a temporary file and returns its path.
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestRun_ValidConfiguration(t *testing.T) {
+	setupValidEnv(t)
+
+	assert.Empty(t, validate.Run(false))
+}
+
+func TestRun_ReportsTheMissingKeys(t *testing.T) {
+	setupValidEnv(t)
+	for _, key := range []string{"ENV", "PORT", "IS_SSL", "API_VERSION", "TOKEN_TYPE", "JWT_ALGORITHM"} {
+		t.Setenv(key, "")
+	}
+
+	problems := validate.Run(false)
+	for _, key := range []string{"ENV", "PORT", "IS_SSL", "API_VERSION", "TOKEN_TYPE", "JWT_ALGORITHM"} {
+		assert.Contains(t, problems, key+" is not set")
+	}
+}
+
+func TestRun_ReportsEveryProblemAtOnce(t *testing.T) {
+	setupValidEnv(t)
+	t.Setenv("PORT", "70000")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("PAGINATION_LIMIT_MODE", "truncate")
+
+	assert.Equal(t, validate.Problems{
+		`PORT must be a number between 1 and 65535, got "70000"`,
+		"JWT_SECRET is required when JWT_ALGORITHM is HS256",
+		`PAGINATION_LIMIT_MODE "TRUNCATE" is not supported, use REJECT or CLAMP`,
+	}, validate.Run(false))
+}
+
+func TestRun_ReportsTheInvalidJWTSettings(t *testing.T) {
+	cases := []struct {
+		name    string
+		env     map[string]string
+		problem string
+	}{
+		{"unsupported algorithm", map[string]string{"JWT_ALGORITHM": "none"}, `JWT_ALGORITHM "none" is not supported, use HS256, RS256, ES256, or EdDSA`},
+		{"short secret", map[string]string{"JWT_SECRET": "too-short"}, "JWT_SECRET must be at least 32 bytes long, got 9"},
+		{"RS256 without private key", map[string]string{"JWT_ALGORITHM": "RS256", "JWT_PRIVATE_KEY_PATH": ""}, "JWT_PRIVATE_KEY_PATH is not set"},
+		{"RS256 without public key", map[string]string{"JWT_ALGORITHM": "RS256", "JWT_PUBLIC_KEY_PATH": ""}, "JWT_PUBLIC_KEY_PATH is not set"},
+		{"invalid TTL", map[string]string{"JWT_ACCESS_TOKEN_TTL_MINUTES": "0"}, `JWT_ACCESS_TOKEN_TTL_MINUTES must be a positive integer, got "0"`},
+		{"refresh token outlived by the access token", map[string]string{"JWT_ACCESS_TOKEN_TTL_MINUTES": "120", "JWT_REFRESH_TOKEN_EXPIRATION_HOUR": "1"}, "JWT_REFRESH_TOKEN_EXPIRATION_HOUR (1) must be greater than the longest access token TTL (2h0m0s)"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			setupValidEnv(t)
+			testsupport.GenerateRSAKeyPair(t)
+			for key, value := range tc.env {
+				t.Setenv(key, value)
+			}
+
+			assert.Contains(t, validate.Run(false), tc.problem)
+		})
+	}
+}
+
+func TestRun_ReportsTheUnreadableAndInvalidKeyFiles(t *testing.T) {
+	setupValidEnv(t)
+	t.Setenv("JWT_ALGORITHM", "RS256")
+	t.Setenv("JWT_PRIVATE_KEY_PATH", filepath.Join(t.TempDir(), "missing.pem"))
+	t.Setenv("JWT_PUBLIC_KEY_PATH", writeFile(t, "publicKey.pem", "not a key"))
+
+	problems := validate.Run(false)
+	assert.Len(t, problems, 2)
+	assert.Contains(t, problems[0], "JWT_PRIVATE_KEY_PATH points to an unreadable file")
+	assert.Contains(t, problems[1], "JWT_PUBLIC_KEY_PATH does not contain a valid RSA public key")
+}
+
+func TestRun_ReportsTheKeysOfAnotherAlgorithm(t *testing.T) {
+	setupValidEnv(t)
+	testsupport.GenerateECKeyPair(t, elliptic.P256())
+	t.Setenv("JWT_ALGORITHM", "EdDSA")
+
+	problems := validate.Run(false)
+	assert.Len(t, problems, 2)
+	assert.Contains(t, problems[0], "JWT_PRIVATE_KEY_PATH does not contain a valid Ed25519 private key")
+	assert.Contains(t, problems[1], "JWT_PUBLIC_KEY_PATH does not contain a valid Ed25519 public key")
+}
+
+func TestRun_ReportsTheTLSFiles(t *testing.T) {
+	setupValidEnv(t)
+	t.Setenv("IS_SSL", "TRUE")
+	t.Setenv("SSL_CERT", "")
+	t.Setenv("SSL_KEYS", writeFile(t, "server.key", "not a key"))
+
+	assert.Equal(t, validate.Problems{"SSL_CERT is not set"}, validate.Run(false))
+
+	t.Setenv("SSL_CERT", writeFile(t, "server.crt", "not a certificate"))
+	problems := validate.Run(false)
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "SSL_CERT and SSL_KEYS must be a matching PEM certificate and key")
+}
+
+func TestRun_ReportsTheDatabaseSettings(t *testing.T) {
+	setupValidEnv(t)
+	t.Setenv("DB_DRIVER", "mysql")
+
+	assert.Equal(t, validate.Problems{`DB_DRIVER "mysql" is not supported, use postgres or memory`}, validate.Run(false))
+
+	t.Setenv("DB_DRIVER", database.DriverPostgres)
+	for _, key := range []string{"DB_HOST", "DB_PORT", "DB_USER", "DB_PASS", "DB_NAME", "DB_SCHEMA"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("DB_MAX_OPEN_CONNS", "5")
+	t.Setenv("DB_MAX_IDLE_CONNS", "10")
+	t.Setenv("DB_READ_RETRIES", "-1")
+
+	// The connectivity is not checked while settings are missing
+	problems := validate.Run(true)
+	for _, key := range []string{"DB_HOST", "DB_PORT", "DB_USER", "DB_PASS", "DB_NAME", "DB_SCHEMA"} {
+		assert.Contains(t, problems, key+" is not set")
+	}
+	assert.Contains(t, problems, "DB_MAX_IDLE_CONNS (10) must not be greater than DB_MAX_OPEN_CONNS (5)")
+	assert.Contains(t, problems, `DB_READ_RETRIES must be a non-negative integer, got "-1"`)
+	assert.Len(t, problems, 8)
+}
+
+func TestRun_ReportsTheInvalidValues(t *testing.T) {
+	cases := []struct {
+		key     string
+		value   string
+		problem string
+	}{
+		{"PAGINATION_MAX_LIMIT", "abc", `PAGINATION_MAX_LIMIT must be a positive integer, got "abc"`},
+		{"EXPORT_MAX_ROWS", "-5", `EXPORT_MAX_ROWS must be a non-negative integer, got "-5"`},
+		{"SESSION_LIMIT_MODE", "drop", `SESSION_LIMIT_MODE "DROP" is not supported, use EVICT_OLDEST or REJECT`},
+		{"PASSWORD_RESET_URL", "/reset-password", `PASSWORD_RESET_URL must be an absolute URL, got "/reset-password"`},
+		{"CREDENTIALS_TTL_DAYS", "-1", `CREDENTIALS_TTL_DAYS must be a non-negative integer, got "-1"`},
+		{"CONSUMER_WEBHOOK_URL", "ftp://example.com/hook", `CONSUMER_WEBHOOK_URL must be an absolute http or https URL, got "ftp://example.com/hook"`},
+		{"QUOTA_DAILY_LIMIT", "unlimited", `QUOTA_DAILY_LIMIT must be a non-negative integer, got "unlimited"`},
+		{"SHUTDOWN_TIMEOUT_SECOND", "0", `SHUTDOWN_TIMEOUT_SECOND must be a positive integer, got "0"`},
+		{"AUTH_RATE_LIMIT_BACKEND", "memcached", `AUTH_RATE_LIMIT_BACKEND must be memory or redis, got "memcached"`},
+		{"ALERT_DEDUP_MINUTES", "-1", `ALERT_DEDUP_MINUTES must be a non-negative integer, got "-1"`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.key, func(t *testing.T) {
+			setupValidEnv(t)
+			t.Setenv(tc.key, tc.value)
+
+			assert.Equal(t, validate.Problems{tc.problem}, validate.Run(false))
+		})
+	}
+}
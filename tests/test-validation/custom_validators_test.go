@@ -0,0 +1,123 @@
+package test_validation
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/go-playground/validator.v9"
+
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+	validation "github.com/yoanesber/go-jwt-auth-demo/pkg/util/validation-util"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// useValidator initializes the validator with a clock frozen at now, and resets it at the end of the test.
+func useValidator(t *testing.T) {
+	validation.ClearValidator()
+	require.True(t, validation.InitWithClock(clock.NewFakeClock(now)))
+	t.Cleanup(validation.ClearValidator)
+}
+
+func TestE164(t *testing.T) {
+	useValidator(t)
+
+	cases := []struct {
+		phone string
+		valid bool
+	}{
+		{"+6281234567890", true},
+		{"6281234567890", true},
+		{"+12345678", true},          // 8 digits, the shortest
+		{"+123456789012345", true},   // 15 digits, the longest
+		{"+1234567", false},          // 7 digits
+		{"+1234567890123456", false}, // 16 digits
+		{"+0812345678", false},       // leading zero
+		{"++6281234567", false},      // doubled +
+		{"+", false},                 // + only
+		{"6281234567+", false},       // trailing +
+		{"+62 812 3456 7890", false}, // spaces
+		{"+62-812-3456-7890", false}, // dashes
+		{"", false},
+		{"+62812345678a", false},
+		{"+" + strings.Repeat("9", 14), true},
+	}
+
+	for _, tc := range cases {
+		err := validation.GetValidator().Var(tc.phone, "e164")
+		assert.Equal(t, tc.valid, err == nil, tc.phone)
+	}
+}
+
+func TestConsumerStatus(t *testing.T) {
+	useValidator(t)
+
+	cases := []struct {
+		status string
+		valid  bool
+	}{
+		{"active", true},
+		{"inactive", true},
+		{"suspended", true},
+		{"Active", false},
+		{"ACTIVE", false},
+		{" active", false},
+		{"deleted", false},
+		{"", false},
+	}
+
+	for _, tc := range cases {
+		err := validation.GetValidator().Var(tc.status, "consumer_status")
+		assert.Equal(t, tc.valid, err == nil, tc.status)
+	}
+}
+
+func TestNotFuture(t *testing.T) {
+	useValidator(t)
+
+	cases := []struct {
+		name  string
+		value interface{}
+		valid bool
+	}{
+		{"past time", now.Add(-time.Hour), true},
+		{"exactly now", now, true},
+		{"a nanosecond later", now.Add(time.Nanosecond), false},
+		{"tomorrow", now.AddDate(0, 0, 1), false},
+		{"now in another zone", now.In(time.FixedZone("WIB", 7*60*60)), true},
+		{"past date", customtype.Date{Time: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)}, true},
+		{"today", customtype.Date{Time: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}, true},
+		{"tomorrow date", customtype.Date{Time: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)}, false},
+		{"pointer to a past date", &customtype.Date{Time: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)}, true},
+		{"unsupported type", "2020-01-01", false},
+	}
+
+	for _, tc := range cases {
+		err := validation.GetValidator().Var(tc.value, "notfuture")
+		assert.Equal(t, tc.valid, err == nil, tc.name)
+	}
+}
+
+func TestNotFuture_FollowsTheClock(t *testing.T) {
+	clk := clock.NewFakeClock(now)
+	validation.ClearValidator()
+	require.True(t, validation.InitWithClock(clk))
+	t.Cleanup(validation.ClearValidator)
+
+	// A consumer cannot be born tomorrow, until tomorrow comes
+	consumer := entity.Consumer{Fullname: "John Doe", Username: "johndoe", Email: "john.doe@example.com", Phone: "+6281234567890",
+		Address: "Jl. Merdeka No. 123, Jakarta", BirthDate: &customtype.Date{Time: now.AddDate(0, 0, 1)}}
+	err := consumer.Validate()
+	var validationErrors validator.ValidationErrors
+	require.ErrorAs(t, err, &validationErrors)
+	require.Len(t, validationErrors, 1)
+	assert.Equal(t, "notfuture", validationErrors[0].Tag())
+
+	clk.Advance(24 * time.Hour)
+	assert.NoError(t, consumer.Validate())
+}
@@ -0,0 +1,233 @@
+package test_webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	policyconfig "github.com/yoanesber/go-jwt-auth-demo/config/policy-config"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/entity"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/service"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/clock"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/customtype"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/mocks"
+	"github.com/yoanesber/go-jwt-auth-demo/tests/testsupport"
+)
+
+// receiver is a webhook receiver answering with the configured status and recording the requests it received.
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   []string
+}
+
+// newReceiver starts a webhook receiver answering with the given status.
+func newReceiver(t *testing.T, status int) (*receiver, *httptest.Server) {
+	rcv := &receiver{status: status}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		rcv.mu.Lock()
+		defer rcv.mu.Unlock()
+		rcv.requests = append(rcv.requests, r)
+		rcv.bodies = append(rcv.bodies, string(body))
+		w.WriteHeader(rcv.status)
+		_, _ = w.Write([]byte(`{"received":true}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	return rcv, srv
+}
+
+// setStatus changes the status the receiver answers with.
+func (r *receiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+// newWebhookService creates a webhook service sending to the given URL, with 3 attempts and a backoff of 30 seconds,
+// backed by an in-memory store.
+func newWebhookService(t *testing.T, url string) (service.WebhookService, *clock.FakeClock) {
+	store := testsupport.UseMemoryDatabase(t)
+	clk := clock.NewFakeClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	policy := policyconfig.WebhookPolicy{URL: url, MaxAttempts: 3, Backoff: 30 * time.Second, MaxBackoff: time.Hour, Timeout: 5 * time.Second}
+
+	return service.NewWebhookService(database.DefaultStore(), repository.NewMemoryWebhookDeliveryRepository(store), policy, nil, clk), clk
+}
+
+func TestWebhookPolicy_RetryDelay(t *testing.T) {
+	policy := policyconfig.WebhookPolicy{Backoff: 30 * time.Second, MaxBackoff: 5 * time.Minute}
+
+	assert.Equal(t, 30*time.Second, policy.RetryDelay(1))
+	assert.Equal(t, time.Minute, policy.RetryDelay(2))
+	assert.Equal(t, 4*time.Minute, policy.RetryDelay(4))
+	assert.Equal(t, 5*time.Minute, policy.RetryDelay(5))
+	assert.Equal(t, 5*time.Minute, policy.RetryDelay(100))
+}
+
+func TestWebhookService_Delivers(t *testing.T) {
+	rcv, srv := newReceiver(t, http.StatusNoContent)
+	s, clk := newWebhookService(t, srv.URL)
+
+	require.NoError(t, s.Enqueue(context.Background(), entity.WebhookEventConsumerCreated, map[string]string{"id": "c-1"}))
+
+	n, err := s.DeliverDue()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.Len(t, rcv.requests, 1)
+	assert.Equal(t, entity.WebhookEventConsumerCreated, rcv.requests[0].Header.Get(service.WebhookEventHeader))
+	assert.Equal(t, "1", rcv.requests[0].Header.Get(service.WebhookDeliveryHeader))
+
+	var event entity.WebhookEvent
+	require.NoError(t, json.Unmarshal([]byte(rcv.bodies[0]), &event))
+	assert.Equal(t, entity.WebhookEventConsumerCreated, event.Event)
+	assert.True(t, clk.Now().Equal(event.OccurredAt))
+	assert.Equal(t, map[string]interface{}{"id": "c-1"}, event.Data)
+
+	delivery, err := s.GetDelivery(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, entity.WebhookDeliverySucceeded, delivery.Status)
+	assert.Equal(t, http.StatusNoContent, delivery.ResponseStatus)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	// The delivery is not attempted again
+	n, err = s.DeliverDue()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestWebhookService_RetriesWithBackoffThenDeadLetters(t *testing.T) {
+	rcv, srv := newReceiver(t, http.StatusServiceUnavailable)
+	s, clk := newWebhookService(t, srv.URL)
+	require.NoError(t, s.Enqueue(context.Background(), entity.WebhookEventConsumerCreated, nil))
+
+	_, err := s.DeliverDue()
+	require.NoError(t, err)
+
+	delivery, err := s.GetDelivery(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, entity.WebhookDeliveryFailed, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.ResponseStatus)
+	assert.Equal(t, `{"received":true}`, delivery.ResponseBody)
+	assert.Equal(t, "unexpected response status 503", delivery.LastError)
+	assert.Equal(t, clk.Now().Add(30*time.Second), *delivery.NextAttemptAt)
+
+	// The retry waits for the backoff, which doubles after every attempt
+	n, err := s.DeliverDue()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	clk.Advance(30 * time.Second)
+	_, err = s.DeliverDue()
+	require.NoError(t, err)
+	delivery, err = s.GetDelivery(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, clk.Now().Add(time.Minute), *delivery.NextAttemptAt)
+
+	clk.Advance(time.Minute)
+	_, err = s.DeliverDue()
+	require.NoError(t, err)
+	assert.Len(t, rcv.requests, 3)
+
+	delivery, err = s.GetDelivery(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, entity.WebhookDeliveryDead, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	clk.Advance(time.Hour)
+	n, err = s.DeliverDue()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	dead, err := s.GetFailedDeliveries(context.Background(), entity.WebhookDeliveryDead, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, int64(1), dead[0].ID)
+
+	failed, err := s.GetFailedDeliveries(context.Background(), entity.WebhookDeliveryFailed, 1, 10)
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+
+	_, err = s.GetFailedDeliveries(context.Background(), "SUCCEEDED", 1, 10)
+	assert.ErrorIs(t, err, service.ErrInvalidWebhookStatus)
+}
+
+func TestWebhookService_Redeliver(t *testing.T) {
+	rcv, srv := newReceiver(t, http.StatusInternalServerError)
+	s, _ := newWebhookService(t, srv.URL)
+	require.NoError(t, s.Enqueue(context.Background(), entity.WebhookEventConsumerCreated, nil))
+	_, err := s.DeliverDue()
+	require.NoError(t, err)
+
+	// A redelivery failing again starts a new round of attempts
+	delivery, err := s.Redeliver(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, entity.WebhookDeliveryFailed, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+
+	rcv.setStatus(http.StatusOK)
+	delivery, err = s.Redeliver(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, entity.WebhookDeliverySucceeded, delivery.Status)
+	assert.Len(t, rcv.requests, 3)
+
+	_, err = s.Redeliver(context.Background(), 1)
+	assert.ErrorIs(t, err, service.ErrWebhookNotRedeliverable)
+
+	_, err = s.Redeliver(context.Background(), 99)
+	assert.Error(t, err)
+}
+
+func TestWebhookService_DisabledWithoutURL(t *testing.T) {
+	s, _ := newWebhookService(t, "")
+
+	require.NoError(t, s.Enqueue(context.Background(), entity.WebhookEventConsumerCreated, nil))
+
+	n, err := s.DeliverDue()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestConsumerService_SendsEvents(t *testing.T) {
+	webhooks := mocks.NewMockWebhookService(gomock.NewController(t))
+	repo := repository.NewMemoryConsumerRepository(testsupport.UseMemoryDatabase(t))
+	s := service.NewConsumerService(database.DefaultStore(), repo, policyconfig.LoadConsumerListingPolicy(), service.WithConsumerWebhooks(webhooks))
+
+	webhooks.EXPECT().Enqueue(gomock.Any(), entity.WebhookEventConsumerCreated, gomock.AssignableToTypeOf(entity.Consumer{})).Return(nil)
+	created, err := s.CreateConsumer(context.Background(), entity.Consumer{
+		Fullname:  "John Doe",
+		Username:  "johndoe",
+		Email:     "john.doe@example.com",
+		Phone:     "+6281234567890",
+		Address:   "Jl. Sudirman No. 1, Jakarta",
+		BirthDate: &customtype.Date{Time: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)},
+	})
+	require.NoError(t, err)
+
+	webhooks.EXPECT().Enqueue(gomock.Any(), entity.WebhookEventConsumerStatusChanged, gomock.Cond(func(data interface{}) bool {
+		change, ok := data.(entity.ConsumerStatusChange)
+		return ok && change.PreviousStatus == entity.ConsumerStatusInactive && change.Consumer.Status == entity.ConsumerStatusActive
+	})).Return(nil)
+	_, err = s.UpdateConsumerStatus(context.Background(), created.ID, entity.ConsumerStatusActive)
+	require.NoError(t, err)
+
+	// Keeping the same status is not an event
+	_, err = s.UpdateConsumerStatus(context.Background(), created.ID, entity.ConsumerStatusActive)
+	require.NoError(t, err)
+}
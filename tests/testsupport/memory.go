@@ -0,0 +1,22 @@
+package testsupport
+
+import (
+	"testing"
+
+	"github.com/yoanesber/go-jwt-auth-demo/config/database"
+	"github.com/yoanesber/go-jwt-auth-demo/internal/repository"
+)
+
+// UseMemoryDatabase switches the application to the in-memory database driver (DB_DRIVER=memory)
+// for the duration of the test and returns a new empty store for the in-memory repositories.
+// The services given database.DefaultStore() then run without PostgreSQL.
+func UseMemoryDatabase(t testing.TB) *repository.MemoryStore {
+	t.Helper()
+
+	t.Setenv("DB_DRIVER", database.DriverMemory)
+	if !database.InitMemory() {
+		t.Fatal("failed to initialize the memory database driver")
+	}
+
+	return repository.NewMemoryStore()
+}
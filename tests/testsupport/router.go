@@ -0,0 +1,69 @@
+package testsupport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	jwtconfig "github.com/yoanesber/go-jwt-auth-demo/config/jwt-config"
+	"github.com/yoanesber/go-jwt-auth-demo/pkg/middleware/authorization"
+)
+
+// SetupJWTEnv sets the environment variables read by the JWT middleware and the auth service
+// for the duration of the test, using HS256 with DefaultSecret.
+func SetupJWTEnv(t testing.TB) {
+	t.Helper()
+
+	t.Setenv("TOKEN_TYPE", DefaultTokenType)
+	t.Setenv("JWT_SECRET", DefaultSecret)
+	t.Setenv("JWT_ALGORITHM", "HS256")
+	t.Setenv("JWT_AUDIENCE", DefaultAudience)
+	t.Setenv("JWT_ISSUER", DefaultIssuer)
+}
+
+// NewJWTConfig returns the JWT settings matching SetupJWTEnv, using HS256 with DefaultSecret, and the key files
+// of the environment, set by GenerateRSAKeyPair and the like before. Components built with it do not read the environment,
+// so the tests using it can run in parallel.
+func NewJWTConfig() jwtconfig.JWTConfig {
+	return jwtconfig.JWTConfig{
+		Secret:        DefaultSecret,
+		TokenType:     DefaultTokenType,
+		SigningMethod: "HS256",
+		Audience:      DefaultAudience,
+		Issuer:        DefaultIssuer,
+		TTL:           jwtconfig.TTLPolicy{Default: time.Hour},
+		Keys:          jwtconfig.LoadKeyFiles(),
+	}
+}
+
+// NewRouter creates a Gin engine in test mode with the JWT validation middleware
+// and the given additional middleware applied to all routes.
+func NewRouter(t testing.TB, middleware ...gin.HandlerFunc) *gin.Engine {
+	t.Helper()
+
+	SetupJWTEnv(t)
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(authorization.JwtValidation())
+	router.Use(middleware...)
+
+	return router
+}
+
+// Do performs a request against the router with the given token in the Authorization header.
+// If token is empty, no Authorization header is sent.
+func Do(router http.Handler, method string, path string, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", DefaultTokenType+" "+token)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w
+}
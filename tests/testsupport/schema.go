@@ -0,0 +1,21 @@
+package testsupport
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EphemeralSchemaName returns a unique PostgreSQL schema name for a single test run,
+// such as e2e_20250115100000_1a2b3c4d, so that parallel runs can share one database.
+func EphemeralSchemaName(prefix string) string {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		// Fall back to the nanoseconds, which are unique enough for a test run
+		return fmt.Sprintf("%s_%d", strings.ToLower(prefix), time.Now().UnixNano())
+	}
+
+	return fmt.Sprintf("%s_%s_%s", strings.ToLower(prefix), time.Now().UTC().Format("20060102150405"), hex.EncodeToString(suffix))
+}
@@ -0,0 +1,289 @@
+package testsupport
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// DefaultSecret is the HMAC secret used by the token builder and the test environment
+	DefaultSecret = "test-secret-that-is-at-least-256-bits-long"
+
+	// DefaultTokenType is the token type expected by the JWT middleware in tests
+	DefaultTokenType = "Bearer"
+
+	// DefaultAudience and DefaultIssuer are the aud and iss claims of the generated tokens
+	DefaultAudience = "test_audience"
+	DefaultIssuer   = "test_issuer"
+)
+
+// TokenBuilder builds signed JWT tokens for tests.
+// It replaces hard-coded tokens that expire and break the tests over time.
+type TokenBuilder struct {
+	userID     int64
+	username   string
+	email      string
+	roles      []string
+	issuedAt   time.Time
+	expiresIn  time.Duration
+	method     jwt.SigningMethod
+	secret     []byte
+	privateKey interface{}
+	keyID      string
+	claims     jwt.MapClaims
+	omitted    []string
+}
+
+// NewTokenBuilder creates a new TokenBuilder with sensible defaults:
+// an admin user, HS256 signing with DefaultSecret, issued now, and valid for one hour.
+func NewTokenBuilder() *TokenBuilder {
+	return &TokenBuilder{
+		userID:    1,
+		username:  "admin",
+		email:     "admin@example.com",
+		roles:     []string{"ROLE_ADMIN"},
+		issuedAt:  time.Now(),
+		expiresIn: time.Hour,
+		method:    jwt.SigningMethodHS256,
+		secret:    []byte(DefaultSecret),
+		claims:    jwt.MapClaims{},
+	}
+}
+
+// WithUser sets the user ID, username, and email claims of the token.
+func (b *TokenBuilder) WithUser(id int64, username string, email string) *TokenBuilder {
+	b.userID = id
+	b.username = username
+	b.email = email
+	return b
+}
+
+// WithRoles sets the roles claim of the token.
+func (b *TokenBuilder) WithRoles(roles ...string) *TokenBuilder {
+	b.roles = roles
+	return b
+}
+
+// WithIssuedAt sets the issued at time of the token.
+func (b *TokenBuilder) WithIssuedAt(t time.Time) *TokenBuilder {
+	b.issuedAt = t
+	return b
+}
+
+// WithExpiry sets the lifetime of the token relative to its issued at time.
+// A negative duration creates a token that is already expired.
+func (b *TokenBuilder) WithExpiry(d time.Duration) *TokenBuilder {
+	b.expiresIn = d
+	return b
+}
+
+// Expired creates a token that expired one minute ago.
+func (b *TokenBuilder) Expired() *TokenBuilder {
+	b.issuedAt = time.Now().Add(-time.Hour)
+	b.expiresIn = time.Hour - time.Minute
+	return b
+}
+
+// WithSecret signs the token with HS256 and the given secret.
+func (b *TokenBuilder) WithSecret(secret string) *TokenBuilder {
+	b.method = jwt.SigningMethodHS256
+	b.secret = []byte(secret)
+	return b
+}
+
+// WithRSAKey signs the token with RS256 and the given private key.
+func (b *TokenBuilder) WithRSAKey(key *rsa.PrivateKey) *TokenBuilder {
+	b.method = jwt.SigningMethodRS256
+	b.privateKey = key
+	return b
+}
+
+// WithECKey signs the token with ES256 and the given P-256 private key.
+func (b *TokenBuilder) WithECKey(key *ecdsa.PrivateKey) *TokenBuilder {
+	b.method = jwt.SigningMethodES256
+	b.privateKey = key
+	return b
+}
+
+// WithEdKey signs the token with EdDSA and the given Ed25519 private key.
+func (b *TokenBuilder) WithEdKey(key ed25519.PrivateKey) *TokenBuilder {
+	b.method = jwt.SigningMethodEdDSA
+	b.privateKey = key
+	return b
+}
+
+// WithKeyID sets the kid header of the token, identifying the key verifying it.
+func (b *TokenBuilder) WithKeyID(kid string) *TokenBuilder {
+	b.keyID = kid
+	return b
+}
+
+// WithAlgorithm sets the signing method of the token, e.g. jwt.SigningMethodNone for alg-swap tests.
+func (b *TokenBuilder) WithAlgorithm(method jwt.SigningMethod) *TokenBuilder {
+	b.method = method
+	return b
+}
+
+// WithClaim sets an additional claim, or overrides a default one, in the token.
+func (b *TokenBuilder) WithClaim(key string, value interface{}) *TokenBuilder {
+	b.claims[key] = value
+	return b
+}
+
+// WithoutClaim removes a claim from the token, e.g. to test tokens missing a required claim.
+func (b *TokenBuilder) WithoutClaim(key string) *TokenBuilder {
+	b.omitted = append(b.omitted, key)
+	return b
+}
+
+// Build signs the token and returns it as a string.
+// It fails the test if the token cannot be signed.
+func (b *TokenBuilder) Build(t testing.TB) string {
+	t.Helper()
+
+	claims := jwt.MapClaims{
+		"sub":      b.username,
+		"aud":      DefaultAudience,
+		"iss":      DefaultIssuer,
+		"iat":      b.issuedAt.Unix(),
+		"exp":      b.issuedAt.Add(b.expiresIn).Unix(),
+		"email":    b.email,
+		"userid":   b.userID,
+		"username": b.username,
+		"roles":    b.roles,
+	}
+	for k, v := range b.claims {
+		claims[k] = v
+	}
+	for _, k := range b.omitted {
+		delete(claims, k)
+	}
+
+	var key interface{}
+	switch b.method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA, *jwt.SigningMethodEd25519:
+		key = b.privateKey
+	case *jwt.SigningMethodHMAC:
+		key = b.secret
+	default:
+		key = jwt.UnsafeAllowNoneSignatureType
+	}
+
+	unsigned := jwt.NewWithClaims(b.method, claims)
+	if b.keyID != "" {
+		unsigned.Header["kid"] = b.keyID
+	}
+
+	token, err := unsigned.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign test token: %v", err)
+	}
+
+	return token
+}
+
+// GenerateRSAKeyPair generates an RSA key pair, writes it as PEM files into a temporary directory,
+// and points JWT_PRIVATE_KEY_PATH and JWT_PUBLIC_KEY_PATH to those files for the duration of the test.
+func GenerateRSAKeyPair(t testing.TB) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal RSA public key: %v", err)
+	}
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "privateKey.pem")
+	publicPath := filepath.Join(dir, "publicKey.pem")
+	writePEM(t, privatePath, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+	writePEM(t, publicPath, "PUBLIC KEY", publicDER)
+
+	t.Setenv("JWT_PRIVATE_KEY_PATH", privatePath)
+	t.Setenv("JWT_PUBLIC_KEY_PATH", publicPath)
+
+	return key
+}
+
+// GenerateECKeyPair generates an ECDSA key pair on the given curve, writes it as PEM files into a temporary directory,
+// and points JWT_PRIVATE_KEY_PATH and JWT_PUBLIC_KEY_PATH to those files for the duration of the test.
+func GenerateECKeyPair(t testing.TB, curve elliptic.Curve) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ECDSA key: %v", err)
+	}
+
+	privateDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal ECDSA private key: %v", err)
+	}
+	writeKeyPair(t, "EC PRIVATE KEY", privateDER, &key.PublicKey)
+
+	return key
+}
+
+// GenerateEd25519KeyPair generates an Ed25519 key pair, writes it as PEM files into a temporary directory,
+// and points JWT_PRIVATE_KEY_PATH and JWT_PUBLIC_KEY_PATH to those files for the duration of the test.
+func GenerateEd25519KeyPair(t testing.TB) ed25519.PrivateKey {
+	t.Helper()
+
+	publicKey, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal Ed25519 private key: %v", err)
+	}
+	writeKeyPair(t, "PRIVATE KEY", privateDER, publicKey)
+
+	return key
+}
+
+// writeKeyPair writes the private key and the public key as PEM files into a temporary directory,
+// and points JWT_PRIVATE_KEY_PATH and JWT_PUBLIC_KEY_PATH to those files for the duration of the test.
+func writeKeyPair(t testing.TB, privateBlockType string, privateDER []byte, publicKey interface{}) {
+	t.Helper()
+
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "privateKey.pem")
+	publicPath := filepath.Join(dir, "publicKey.pem")
+	writePEM(t, privatePath, privateBlockType, privateDER)
+	writePEM(t, publicPath, "PUBLIC KEY", publicDER)
+
+	t.Setenv("JWT_PRIVATE_KEY_PATH", privatePath)
+	t.Setenv("JWT_PUBLIC_KEY_PATH", publicPath)
+}
+
+// writePEM writes the given DER bytes as a PEM block into the file at path.
+func writePEM(t testing.TB, path string, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}